@@ -1,15 +1,23 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Client struct {
 	baseURL    string
+	token      string
 	httpClient *http.Client
 }
 
@@ -24,6 +32,53 @@ func (c *Client) SetBaseURL(baseURL string) {
 	c.baseURL = baseURL
 }
 
+// SetToken sets the API token sent with every request (empty = none).
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetTLS trusts caFile for the server certificate and, when certFile/keyFile
+// are set, presents that client certificate (mutual TLS). Empty paths keep
+// the system defaults. Fails on unreadable or expired certificates.
+func (c *Client) SetTLS(caFile, certFile, keyFile string) error {
+	if caFile == "" && certFile == "" {
+		return nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("read CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" {
+		if keyFile == "" {
+			keyFile = certFile
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("load client certificate: %w", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("parse client certificate: %w", err)
+		}
+		if time.Now().After(leaf.NotAfter) {
+			return fmt.Errorf("client certificate expired on %s", leaf.NotAfter.Format("2006-01-02"))
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	c.httpClient.Transport = transport
+	return nil
+}
+
 // Response types
 
 type Health struct {
@@ -89,16 +144,103 @@ type Security struct {
 	Currency          string       `json:"currency"`
 	ExpectedReturn    float64      `json:"contrarian_score"`
 	Prices            []PricePoint `json:"prices"`
+	Thesis            *Thesis      `json:"thesis"`
+}
+
+// Thesis is the user's target price, stop level and investment thesis for a
+// position; Tags lists the conditions that hold now (target_reached,
+// stop_reached, review_due).
+type Thesis struct {
+	TargetPrice *float64 `json:"target_price"`
+	StopPrice   *float64 `json:"stop_price"`
+	Thesis      string   `json:"thesis"`
+	ReviewDueAt *int64   `json:"review_due_at"`
+	Tags        []string `json:"tags"`
+}
+
+type Notification struct {
+	ID          int64  `json:"id"`
+	Severity    string `json:"severity"`
+	Category    string `json:"category"`
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+	Link        string `json:"link"`
+	Occurrences int    `json:"occurrences"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+type NotificationInbox struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+}
+
+type ApprovalItem struct {
+	Symbol                 string  `json:"symbol"`
+	Action                 string  `json:"action"`
+	Quantity               float64 `json:"quantity"`
+	Price                  float64 `json:"price"`
+	Currency               string  `json:"currency"`
+	ValueEUR               float64 `json:"value_eur"`
+	EstimatedCostEUR       float64 `json:"estimated_cost_eur"`
+	CurrentAllocationPct   float64 `json:"current_allocation_pct"`
+	ProjectedAllocationPct float64 `json:"projected_allocation_pct"`
+	Reason                 string  `json:"reason"`
+	Status                 string  `json:"status"`
+}
+
+type ApprovalInbox struct {
+	ManualApproval bool           `json:"manual_approval"`
+	Pending        int            `json:"pending"`
+	Items          []ApprovalItem `json:"items"`
+}
+
+type ChartPoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+type ChartSeries struct {
+	Points []ChartPoint `json:"points"`
+}
+
+type LogEntry struct {
+	ID      int64   `json:"id"`
+	TS      float64 `json:"ts"`
+	Level   string  `json:"level"`
+	Module  string  `json:"module"`
+	Message string  `json:"message"`
 }
 
 // Internal helpers
 
+func (c *Client) newRequest(ctx context.Context, method, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Lets the server audit actions from the TUI as such
+	req.Header.Set("X-Sentinel-Client", "tui")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+func (c *Client) do(method, u string) (*http.Response, error) {
+	req, err := c.newRequest(context.Background(), method, u)
+	if err != nil {
+		return nil, err
+	}
+	return c.httpClient.Do(req)
+}
+
 func (c *Client) get(path string, params url.Values, target any) error {
 	u := c.baseURL + path
 	if params != nil {
 		u += "?" + params.Encode()
 	}
-	resp, err := c.httpClient.Get(u)
+	resp, err := c.do(http.MethodGet, u)
 	if err != nil {
 		return err
 	}
@@ -109,6 +251,21 @@ func (c *Client) get(path string, params url.Values, target any) error {
 	return json.NewDecoder(resp.Body).Decode(target)
 }
 
+func (c *Client) post(path string, target any) error {
+	resp, err := c.do(http.MethodPost, c.baseURL+path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned %d", resp.StatusCode)
+	}
+	if target == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
 // Endpoints
 
 func (c *Client) Health() (Health, error) {
@@ -138,3 +295,96 @@ func (c *Client) Unified() ([]Security, error) {
 	var s []Security
 	return s, c.get("/api/unified", nil, &s)
 }
+
+func (c *Client) UnreadNotifications() (NotificationInbox, error) {
+	var n NotificationInbox
+	return n, c.get("/api/notifications", url.Values{"unread_only": {"true"}, "limit": {"10"}}, &n)
+}
+
+func (c *Client) AckAllNotifications() error {
+	return c.post("/api/notifications/read-all", nil)
+}
+
+func (c *Client) Approvals() (ApprovalInbox, error) {
+	var a ApprovalInbox
+	return a, c.get("/api/approvals", nil, &a)
+}
+
+// DecideRecommendation approves, rejects or defers the current recommendation
+// for symbol and action (decision is "approve", "reject" or "defer").
+func (c *Client) DecideRecommendation(symbol, action, decision string) error {
+	return c.post("/api/approvals/"+url.PathEscape(symbol)+"/"+action+"/"+decision, nil)
+}
+
+// PortfolioChart returns portfolio value (EUR) over the last days, downsampled
+// server-side to at most points values.
+func (c *Client) PortfolioChart(days, points int) (ChartSeries, error) {
+	var s ChartSeries
+	params := url.Values{"days": {strconv.Itoa(days)}, "points": {strconv.Itoa(points)}}
+	return s, c.get("/api/charts/portfolio", params, &s)
+}
+
+// SecurityChart returns a security's "price" or "score" series over the last
+// days prices, downsampled server-side to at most points values.
+func (c *Client) SecurityChart(symbol, series string, days, points int) (ChartSeries, error) {
+	var s ChartSeries
+	params := url.Values{"days": {strconv.Itoa(days)}, "points": {strconv.Itoa(points)}}
+	return s, c.get("/api/charts/securities/"+url.PathEscape(symbol)+"/"+series, params, &s)
+}
+
+// StreamLogs tails server logs at or above level (empty = all) from loggers
+// under module (empty = all). Entries arrive on the returned channel, which is
+// closed when ctx is cancelled or the connection drops.
+func (c *Client) StreamLogs(ctx context.Context, level, module string) (<-chan LogEntry, error) {
+	params := url.Values{}
+	if level != "" {
+		params.Set("level", level)
+	}
+	if module != "" {
+		params.Set("module", module)
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, c.baseURL+"/api/logs/stream?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// Same transport (TLS), but no overall timeout on a long-lived stream
+	stream := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("API returned %d", resp.StatusCode)
+	}
+
+	entries := make(chan LogEntry)
+	go func() {
+		defer close(entries)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		event, data := "", ""
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				var entry LogEntry
+				if event == "log" && json.Unmarshal([]byte(data), &entry) == nil {
+					select {
+					case entries <- entry:
+					case <-ctx.Done():
+						return
+					}
+				}
+				event, data = "", ""
+			case strings.HasPrefix(line, "event:"):
+				event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			}
+		}
+	}()
+	return entries, nil
+}
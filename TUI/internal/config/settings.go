@@ -6,7 +6,11 @@ import (
 )
 
 type Settings struct {
-	APIURL string `json:"api_url"`
+	APIURL      string `json:"api_url"`
+	APIToken    string `json:"api_token,omitempty"`
+	TLSCAFile   string `json:"tls_ca_file,omitempty"`
+	TLSCertFile string `json:"tls_cert_file,omitempty"`
+	TLSKeyFile  string `json:"tls_key_file,omitempty"`
 }
 
 func Load(path string) (Settings, error) {
@@ -114,3 +114,90 @@ func downsample(data []float64, n int) []float64 {
 	}
 	return out
 }
+
+// Braille dot bits by [row][column] within a 2x4 cell.
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// RenderBrailleChart renders data as a line chart using braille dots, giving
+// 2 horizontal and 4 vertical points per character. Consecutive points are
+// joined vertically so steep moves stay connected. Returns height lines.
+func RenderBrailleChart(data []float64, width, height int, c color.Color) string {
+	if len(data) == 0 || width <= 0 || height <= 0 {
+		return ""
+	}
+
+	cols := downsample(data, width*2)
+	minVal, maxVal := cols[0], cols[0]
+	for _, v := range cols {
+		minVal = min(minVal, v)
+		maxVal = max(maxVal, v)
+	}
+	valRange := maxVal - minVal
+	if valRange == 0 {
+		valRange = 1
+	}
+
+	// Dot row per column, 0 = bottom.
+	dotRows := height * 4
+	levels := make([]int, len(cols))
+	for i, v := range cols {
+		levels[i] = int((v - minVal) / valRange * float64(dotRows-1))
+	}
+
+	cells := make([][]rune, height)
+	for row := range cells {
+		cells[row] = make([]rune, width)
+	}
+	set := func(x, level int) {
+		y := dotRows - 1 - level
+		cells[y/4][x/2] |= brailleDots[y%4][x%2]
+	}
+	for x, level := range levels {
+		from, to := level, level
+		if x > 0 {
+			from, to = min(level, levels[x-1]), max(level, levels[x-1])
+		}
+		for l := from; l <= to; l++ {
+			set(x, l)
+		}
+	}
+
+	style := lipgloss.NewStyle().Foreground(c)
+	rows := make([]string, height)
+	for row := range cells {
+		var sb strings.Builder
+		for _, bits := range cells[row] {
+			if bits == 0 {
+				sb.WriteRune(' ')
+			} else {
+				sb.WriteRune(0x2800 + bits)
+			}
+		}
+		rows[row] = style.Render(sb.String())
+	}
+	return strings.Join(rows, "\n")
+}
+
+// RenderSparkline renders data as a single row of block elements.
+func RenderSparkline(data []float64, width int, c color.Color) string {
+	if len(data) == 0 || width <= 0 {
+		return ""
+	}
+
+	cols := downsample(data, width)
+	minVal, maxVal := cols[0], cols[0]
+	for _, v := range cols {
+		minVal = min(minVal, v)
+		maxVal = max(maxVal, v)
+	}
+	valRange := maxVal - minVal
+	if valRange == 0 {
+		valRange = 1
+	}
+
+	var sb strings.Builder
+	for _, v := range cols {
+		sb.WriteRune(blockChars[1+int((v-minVal)/valRange*7)])
+	}
+	return lipgloss.NewStyle().Foreground(c).Render(sb.String())
+}
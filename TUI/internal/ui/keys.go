@@ -7,6 +7,17 @@ type keyMap struct {
 	Back         key.Binding
 	OpenSettings key.Binding
 	SaveSettings key.Binding
+	AckAll       key.Binding
+	OpenInbox    key.Binding
+	Up           key.Binding
+	Down         key.Binding
+	Approve      key.Binding
+	Reject       key.Binding
+	Defer        key.Binding
+	OpenLogs     key.Binding
+	LogLevel     key.Binding
+	FilterModule key.Binding
+	OpenCharts   key.Binding
 }
 
 var keys = keyMap{
@@ -14,4 +25,15 @@ var keys = keyMap{
 	Back:         key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "back")),
 	OpenSettings: key.NewBinding(key.WithKeys("s", "o"), key.WithHelp("s/o", "settings")),
 	SaveSettings: key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "save")),
+	AckAll:       key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "acknowledge notifications")),
+	OpenInbox:    key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "approval inbox")),
+	Up:           key.NewBinding(key.WithKeys("up", "k"), key.WithHelp("↑/k", "previous")),
+	Down:         key.NewBinding(key.WithKeys("down", "j"), key.WithHelp("↓/j", "next")),
+	Approve:      key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "approve")),
+	Reject:       key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "reject")),
+	Defer:        key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "defer")),
+	OpenLogs:     key.NewBinding(key.WithKeys("l"), key.WithHelp("l", "server logs")),
+	LogLevel:     key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "minimum level")),
+	FilterModule: key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "filter by module")),
+	OpenCharts:   key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "history charts")),
 }
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"sort"
 	"time"
 
@@ -22,6 +23,8 @@ type Model struct {
 	pnlHistory      *api.PnLHistory
 	recommendations []api.Recommendation
 	securities      []api.Security
+	inbox           *api.NotificationInbox
+	approvals       *api.ApprovalInbox
 
 	// UI state
 	width       int
@@ -30,9 +33,27 @@ type Model struct {
 	maxHeight   int
 	ready       bool
 	inSettings  bool
+	inInbox     bool
+	inboxCursor int
 	apiURLInput string
 	statusMsg   string
 
+	// Charts view
+	inCharts    bool
+	chartCursor int                         // index into heldSecurities()
+	charts      map[string][]api.ChartPoint // "nav", "price:SYM", "score:SYM"
+
+	// Log pane
+	inLogs        bool
+	logs          []api.LogEntry
+	logLevel      int // index into logLevels
+	logModule     string
+	logModuleEdit bool
+	logModuleIn   string
+	logStream     <-chan api.LogEntry
+	logCancel     context.CancelFunc
+	logGen        int // bumped on every (re)connect; stale streams are dropped
+
 	// Auto-scroll
 	scrolling    bool
 	scrollAccum  float64
@@ -70,6 +91,58 @@ type securitiesMsg struct {
 	err        error
 }
 
+type notificationsMsg struct {
+	inbox api.NotificationInbox
+	err   error
+}
+
+type ackAllMsg struct {
+	err error
+}
+
+type approvalsMsg struct {
+	inbox api.ApprovalInbox
+	err   error
+}
+
+type decisionMsg struct {
+	symbol   string
+	decision string
+	err      error
+}
+
+var decisionLabels = map[string]string{"approve": "approved", "reject": "rejected", "defer": "deferred"}
+
+type chartMsg struct {
+	key    string
+	points []api.ChartPoint
+	err    error
+}
+
+// History shown in the charts view
+const chartDays = 365
+
+type logStreamMsg struct {
+	gen    int
+	stream <-chan api.LogEntry
+	cancel context.CancelFunc
+	err    error
+}
+
+type logEntryMsg struct {
+	stream <-chan api.LogEntry
+	entry  api.LogEntry
+}
+
+type logStreamClosedMsg struct {
+	stream <-chan api.LogEntry
+}
+
+// Minimum levels the log pane cycles through; entries kept for scrollback.
+var logLevels = []string{"DEBUG", "INFO", "WARNING", "ERROR"}
+
+const maxLogEntries = 500
+
 // Scroll: ~43fps tick (matched to 43Hz display) with slow scroll for smooth kiosk viewing.
 const scrollLinesPerSec = 2.0
 const scrollInterval = 23 * time.Millisecond
@@ -87,6 +160,8 @@ func NewModel(client *api.Client, apiURL, settingsFile string, maxWidth, maxHeig
 		settingsFile: settingsFile,
 		maxWidth:     maxWidth,
 		maxHeight:    maxHeight,
+		logLevel:     1,
+		charts:       map[string][]api.ChartPoint{},
 	}
 }
 
@@ -105,6 +180,8 @@ func fetchAll(c *api.Client) []tea.Cmd {
 		fetchPnL(c),
 		fetchRecs(c),
 		fetchSecurities(c),
+		fetchNotifications(c),
+		fetchApprovals(c),
 	}
 }
 
@@ -148,6 +225,116 @@ func fetchSecurities(c *api.Client) tea.Cmd {
 	}
 }
 
+func fetchNotifications(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		n, err := c.UnreadNotifications()
+		return notificationsMsg{n, err}
+	}
+}
+
+func ackAllNotifications(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		return ackAllMsg{c.AckAllNotifications()}
+	}
+}
+
+func fetchApprovals(c *api.Client) tea.Cmd {
+	return func() tea.Msg {
+		a, err := c.Approvals()
+		return approvalsMsg{a, err}
+	}
+}
+
+func decideRecommendation(c *api.Client, item api.ApprovalItem, decision string) tea.Cmd {
+	return func() tea.Msg {
+		err := c.DecideRecommendation(item.Symbol, item.Action, decision)
+		return decisionMsg{item.Symbol, decision, err}
+	}
+}
+
+func fetchPortfolioChart(c *api.Client, points int) tea.Cmd {
+	return func() tea.Msg {
+		s, err := c.PortfolioChart(chartDays, points)
+		return chartMsg{"nav", s.Points, err}
+	}
+}
+
+func fetchSecurityChart(c *api.Client, symbol, series string, points int) tea.Cmd {
+	return func() tea.Msg {
+		s, err := c.SecurityChart(symbol, series, chartDays, points)
+		return chartMsg{series + ":" + symbol, s.Points, err}
+	}
+}
+
+// heldSecurities returns the securities with an open position, largest first.
+func (m Model) heldSecurities() []api.Security {
+	var held []api.Security
+	for _, sec := range m.securities {
+		if sec.HasPosition {
+			held = append(held, sec)
+		}
+	}
+	return held
+}
+
+// chartPoints is the number of values to request: two per column (braille).
+func (m Model) chartPoints() int {
+	return max(2, min(500, m.contentWidth()*2))
+}
+
+// fetchSelectedCharts loads the price and score series of the selected position.
+func (m Model) fetchSelectedCharts() []tea.Cmd {
+	held := m.heldSecurities()
+	if m.chartCursor >= len(held) {
+		return nil
+	}
+	symbol := held[m.chartCursor].Symbol
+	return []tea.Cmd{
+		fetchSecurityChart(m.client, symbol, "price", m.chartPoints()),
+		fetchSecurityChart(m.client, symbol, "score", m.chartPoints()),
+	}
+}
+
+func openLogStream(c *api.Client, gen int, level, module string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := c.StreamLogs(ctx, level, module)
+		if err != nil {
+			cancel()
+			return logStreamMsg{gen: gen, err: err}
+		}
+		return logStreamMsg{gen, stream, cancel, nil}
+	}
+}
+
+// startLogStream drops the current stream and its entries and reconnects
+// with the current filters (the server replays recent matching entries).
+func (m *Model) startLogStream() tea.Cmd {
+	m.stopLogStream()
+	m.logs = nil
+	m.logGen++
+	return openLogStream(m.client, m.logGen, logLevels[m.logLevel], m.logModule)
+}
+
+func (m *Model) stopLogStream() {
+	if m.logCancel != nil {
+		m.logCancel()
+	}
+	m.logStream = nil
+	m.logCancel = nil
+}
+
+// waitForLog delivers the next entry from stream; re-issued after each one.
+func waitForLog(stream <-chan api.LogEntry) tea.Cmd {
+	return func() tea.Msg {
+		entry, ok := <-stream
+		if !ok {
+			return logStreamClosedMsg{stream}
+		}
+		return logEntryMsg{stream, entry}
+	}
+}
+
 func tickCmd() tea.Cmd {
 	return tea.Tick(scrollInterval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
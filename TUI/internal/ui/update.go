@@ -9,6 +9,7 @@ import (
 	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
 
+	"sentinel-tui-go/internal/api"
 	"sentinel-tui-go/internal/config"
 )
 
@@ -30,7 +31,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.contentDirty = true
 
 	case tea.KeyPressMsg:
-		if !m.inSettings && key.Matches(msg, keys.OpenSettings) {
+		if !m.inSettings && !m.inInbox && !m.inLogs && !m.inCharts && key.Matches(msg, keys.OpenSettings) {
 			m.inSettings = true
 			m.apiURLInput = m.apiURL
 			m.statusMsg = ""
@@ -56,7 +57,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				m.apiURL = input
 				m.client.SetBaseURL(input)
-				if err := config.Save(m.settingsFile, config.Settings{APIURL: input}); err != nil {
+				cfg, _ := config.Load(m.settingsFile)
+				cfg.APIURL = input
+				if err := config.Save(m.settingsFile, cfg); err != nil {
 					m.statusMsg = fmt.Sprintf("API URL updated, but failed to save %s: %v", m.settingsFile, err)
 					break
 				}
@@ -81,16 +84,134 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			break
 		}
 
+		if m.inInbox {
+			var items []api.ApprovalItem
+			if m.approvals != nil {
+				items = m.approvals.Items
+			}
+			switch {
+			case key.Matches(msg, keys.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.Back), key.Matches(msg, keys.OpenInbox):
+				m.inInbox = false
+				m.statusMsg = ""
+			case key.Matches(msg, keys.Up):
+				m.inboxCursor = max(0, m.inboxCursor-1)
+			case key.Matches(msg, keys.Down):
+				m.inboxCursor = min(max(0, len(items)-1), m.inboxCursor+1)
+			case key.Matches(msg, keys.Approve), key.Matches(msg, keys.Reject), key.Matches(msg, keys.Defer):
+				if m.inboxCursor >= len(items) {
+					break
+				}
+				decision := "approve"
+				if key.Matches(msg, keys.Reject) {
+					decision = "reject"
+				} else if key.Matches(msg, keys.Defer) {
+					decision = "defer"
+				}
+				m.statusMsg = ""
+				cmds = append(cmds, decideRecommendation(m.client, items[m.inboxCursor], decision))
+			}
+			break
+		}
+
+		if m.inLogs && m.logModuleEdit {
+			switch msg.String() {
+			case "enter":
+				m.logModuleEdit = false
+				m.logModule = strings.TrimSpace(m.logModuleIn)
+				cmds = append(cmds, m.startLogStream())
+			case "esc":
+				m.logModuleEdit = false
+			case "backspace":
+				if len(m.logModuleIn) > 0 {
+					m.logModuleIn = m.logModuleIn[:len(m.logModuleIn)-1]
+				}
+			case "ctrl+u":
+				m.logModuleIn = ""
+			default:
+				k := msg.String()
+				if len(k) == 1 {
+					m.logModuleIn += k
+				}
+			}
+			break
+		}
+
+		if m.inLogs {
+			switch {
+			case key.Matches(msg, keys.Quit):
+				m.stopLogStream()
+				return m, tea.Quit
+			case key.Matches(msg, keys.Back), key.Matches(msg, keys.OpenLogs):
+				m.inLogs = false
+				m.statusMsg = ""
+				m.stopLogStream()
+			case key.Matches(msg, keys.LogLevel):
+				m.logLevel = (m.logLevel + 1) % len(logLevels)
+				cmds = append(cmds, m.startLogStream())
+			case key.Matches(msg, keys.FilterModule):
+				m.logModuleEdit = true
+				m.logModuleIn = m.logModule
+			}
+			break
+		}
+
+		if m.inCharts {
+			held := m.heldSecurities()
+			switch {
+			case key.Matches(msg, keys.Quit):
+				return m, tea.Quit
+			case key.Matches(msg, keys.Back), key.Matches(msg, keys.OpenCharts):
+				m.inCharts = false
+				m.statusMsg = ""
+			case key.Matches(msg, keys.Up), key.Matches(msg, keys.Down):
+				prev := m.chartCursor
+				if key.Matches(msg, keys.Up) {
+					m.chartCursor = max(0, m.chartCursor-1)
+				} else {
+					m.chartCursor = min(max(0, len(held)-1), m.chartCursor+1)
+				}
+				if m.chartCursor != prev {
+					cmds = append(cmds, m.fetchSelectedCharts()...)
+				}
+			}
+			break
+		}
+
 		switch {
 		case key.Matches(msg, keys.Quit):
 			return m, tea.Quit
+		case key.Matches(msg, keys.OpenCharts):
+			m.inCharts = true
+			m.statusMsg = ""
+			m.chartCursor = min(m.chartCursor, max(0, len(m.heldSecurities())-1))
+			cmds = append(cmds, fetchPortfolioChart(m.client, m.chartPoints()))
+			cmds = append(cmds, m.fetchSelectedCharts()...)
+		case key.Matches(msg, keys.OpenLogs):
+			m.inLogs = true
+			m.statusMsg = ""
+			cmds = append(cmds, m.startLogStream())
+		case key.Matches(msg, keys.OpenInbox):
+			m.inInbox = true
+			m.inboxCursor = 0
+			m.statusMsg = ""
+			cmds = append(cmds, fetchApprovals(m.client))
 		case key.Matches(msg, keys.Back):
 			// reserved
+		case key.Matches(msg, keys.AckAll):
+			if m.inbox != nil && m.inbox.UnreadCount > 0 {
+				cmds = append(cmds, ackAllNotifications(m.client))
+			}
 		}
 
 	case refreshMsg:
 		cmds = append(cmds, fetchAll(m.client)...)
 		cmds = append(cmds, scheduleRefresh())
+		if m.inLogs && m.logStream == nil {
+			// Reconnect a dropped or failed log stream
+			cmds = append(cmds, m.startLogStream())
+		}
 
 	case healthMsg:
 		if msg.err != nil {
@@ -128,6 +249,69 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.contentDirty = true
 		}
 
+	case notificationsMsg:
+		if msg.err == nil {
+			m.inbox = &msg.inbox
+			m.contentDirty = true
+		}
+
+	case approvalsMsg:
+		if msg.err == nil {
+			m.approvals = &msg.inbox
+			m.inboxCursor = min(m.inboxCursor, max(0, len(msg.inbox.Items)-1))
+			m.contentDirty = true
+		}
+
+	case decisionMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Could not %s %s: %v", msg.decision, msg.symbol, msg.err)
+		} else {
+			m.statusMsg = fmt.Sprintf("%s %s", msg.symbol, decisionLabels[msg.decision])
+		}
+		cmds = append(cmds, fetchApprovals(m.client))
+
+	case chartMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Could not load %s chart: %v", strings.SplitN(msg.key, ":", 2)[0], msg.err)
+		} else {
+			m.charts[msg.key] = msg.points
+		}
+
+	case logStreamMsg:
+		switch {
+		case msg.gen != m.logGen || !m.inLogs:
+			if msg.cancel != nil {
+				msg.cancel()
+			}
+		case msg.err != nil:
+			m.statusMsg = fmt.Sprintf("Could not open log stream: %v", msg.err)
+		default:
+			m.logStream = msg.stream
+			m.logCancel = msg.cancel
+			m.statusMsg = ""
+			cmds = append(cmds, waitForLog(msg.stream))
+		}
+
+	case logEntryMsg:
+		if msg.stream == m.logStream {
+			m.logs = append(m.logs, msg.entry)
+			if len(m.logs) > maxLogEntries {
+				m.logs = m.logs[len(m.logs)-maxLogEntries:]
+			}
+			cmds = append(cmds, waitForLog(msg.stream))
+		}
+
+	case logStreamClosedMsg:
+		if msg.stream == m.logStream {
+			m.stopLogStream()
+			m.statusMsg = "Log stream disconnected, reconnecting..."
+		}
+
+	case ackAllMsg:
+		if msg.err == nil {
+			cmds = append(cmds, fetchNotifications(m.client))
+		}
+
 	case tickMsg:
 		if m.scrolling {
 			m.scrollAccum += scrollLinesPerSec * scrollInterval.Seconds()
@@ -149,7 +333,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.contentDirty = false
 		}
 		// Only forward non-tick messages to viewport (resize, scroll keys, etc.)
-		if _, isTick := msg.(tickMsg); !isTick && !m.inSettings {
+		if _, isTick := msg.(tickMsg); !isTick && !m.inSettings && !m.inInbox && !m.inLogs && !m.inCharts {
 			var cmd tea.Cmd
 			m.viewport, cmd = m.viewport.Update(msg)
 			cmds = append(cmds, cmd)
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"image/color"
 	"math"
+	"slices"
 	"strings"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
@@ -21,6 +23,12 @@ func (m Model) View() tea.View {
 	content := m.viewMain()
 	if m.inSettings {
 		content = m.viewSettings()
+	} else if m.inInbox {
+		content = m.viewInbox()
+	} else if m.inLogs {
+		content = m.viewLogs()
+	} else if m.inCharts {
+		content = m.viewCharts()
 	}
 	v := tea.NewView(content)
 	v.AltScreen = true
@@ -71,6 +79,290 @@ func (m Model) viewSettings() string {
 		Render(strings.Join(body, "\n"))
 }
 
+// viewInbox lists the current recommendations for approve/reject/defer.
+func (m Model) viewInbox() string {
+	t := theme.Default
+
+	heading := "INBOX"
+	if m.approvals != nil && m.approvals.Pending > 0 {
+		heading = fmt.Sprintf("INBOX  %d PENDING", m.approvals.Pending)
+	}
+	body := []string{"", lipgloss.NewStyle().Foreground(t.Primary).Bold(true).Render(heading), ""}
+	if m.approvals != nil && !m.approvals.ManualApproval {
+		body = append(body, lipgloss.NewStyle().Foreground(t.Muted).Render(
+			"Manual approval is off: live execution does not wait for these decisions"), "")
+	}
+
+	switch {
+	case m.approvals == nil:
+		body = append(body, lipgloss.NewStyle().Foreground(t.Muted).Render("Loading..."))
+	case len(m.approvals.Items) == 0:
+		body = append(body, lipgloss.NewStyle().Foreground(t.Muted).Render("No recommendations"))
+	}
+
+	if m.approvals != nil {
+		for i, item := range m.approvals.Items {
+			c := t.Success
+			if item.Action == "sell" {
+				c = t.Warning
+			}
+			marker := "  "
+			if i == m.inboxCursor {
+				marker = "> "
+			}
+			status := ""
+			if item.Status != "pending" {
+				status = "  [" + strings.ToUpper(item.Status) + "]"
+			}
+			header := fmt.Sprintf("%s%s %s  %g @ %.2f %s  %s EUR%s", marker, strings.ToUpper(item.Action), item.Symbol,
+				item.Quantity, item.Price, item.Currency, formatWithSeparators(item.ValueEUR), status)
+			style := lipgloss.NewStyle().Foreground(c)
+			if i == m.inboxCursor {
+				style = style.Bold(true)
+			} else if item.Status != "pending" {
+				style = style.Foreground(t.Muted)
+			}
+			impact := fmt.Sprintf("    cost ~%.2f EUR   allocation %.1f%% -> %.1f%%",
+				item.EstimatedCostEUR, item.CurrentAllocationPct, item.ProjectedAllocationPct)
+			body = append(body,
+				style.Render(header),
+				lipgloss.NewStyle().Foreground(t.Subtext).Render(impact),
+				lipgloss.NewStyle().Foreground(t.Muted).Render("    "+item.Reason),
+				"")
+		}
+	}
+
+	body = append(body, lipgloss.NewStyle().Foreground(t.Subtext).Render(
+		"A approve   R reject   D defer   ↑/↓ select   ESC back"))
+	if m.statusMsg != "" {
+		color := t.Success
+		if strings.HasPrefix(m.statusMsg, "Could not") {
+			color = t.Error
+		}
+		body = append(body, "", lipgloss.NewStyle().Foreground(color).Render(m.statusMsg))
+	}
+
+	return lipgloss.NewStyle().
+		Width(m.width).
+		Height(m.height).
+		Padding(1, 2).
+		Render(strings.Join(body, "\n"))
+}
+
+// viewCharts shows one year of portfolio value, then price and score of the
+// selected position, from the downsampled chart endpoints.
+func (m Model) viewCharts() string {
+	t := theme.Default
+	w := m.contentWidth()
+	label := lipgloss.NewStyle().Foreground(t.Subtext)
+	muted := lipgloss.NewStyle().Foreground(t.Muted)
+
+	body := []string{"", lipgloss.NewStyle().Foreground(t.Primary).Bold(true).Render("CHARTS"), ""}
+
+	nav := chartValues(m.charts["nav"])
+	body = append(body, label.Render("PORTFOLIO VALUE  1Y"+chartSummary(nav, "%s EUR", formatWithSeparators)))
+	if len(nav) > 0 {
+		body = append(body, RenderBrailleChart(nav, w, 8, t.Primary))
+	} else {
+		body = append(body, muted.Render("No snapshots yet"))
+	}
+	body = append(body, "")
+
+	held := m.heldSecurities()
+	if m.chartCursor < len(held) {
+		sec := held[m.chartCursor]
+		body = append(body, lipgloss.NewStyle().Foreground(t.Text).Bold(true).Render(
+			fmt.Sprintf("%s  %s  (%d/%d)", sec.Symbol, sec.Name, m.chartCursor+1, len(held))), "")
+		if sec.Thesis != nil {
+			body = append(body, viewThesis(sec, w)...)
+			body = append(body, "")
+		}
+
+		price := chartValues(m.charts["price:"+sec.Symbol])
+		body = append(body, label.Render("PRICE"+chartSummary(price, "%s "+sec.Currency, func(v float64) string {
+			return fmt.Sprintf("%.2f", v)
+		})))
+		if len(price) > 0 {
+			body = append(body, RenderBrailleChart(price, w, 6, t.Accent))
+		}
+		body = append(body, "")
+
+		score := chartValues(m.charts["score:"+sec.Symbol])
+		body = append(body, label.Render("SCORE"+chartSummary(score, "%s", func(v float64) string {
+			return fmt.Sprintf("%.2f", v)
+		})))
+		if len(score) > 0 {
+			body = append(body, RenderSparkline(score, w, t.Warning))
+		}
+	} else {
+		body = append(body, muted.Render("No open positions"))
+	}
+
+	body = append(body, "", label.Render("↑/↓ position   ESC back"))
+	if m.statusMsg != "" {
+		body = append(body, "", lipgloss.NewStyle().Foreground(t.Error).Render(m.statusMsg))
+	}
+
+	return lipgloss.NewStyle().
+		Width(m.width).
+		Height(m.height).
+		Padding(1, 2).
+		Render(strings.Join(body, "\n"))
+}
+
+// Labels and colors of the thesis conditions, in display order
+var thesisTagLabels = []struct {
+	tag   string
+	label string
+}{
+	{"target_reached", "TARGET REACHED"},
+	{"stop_reached", "STOP REACHED"},
+	{"review_due", "REVIEW DUE"},
+}
+
+// viewThesisTags renders the conditions that hold for a position's thesis.
+func viewThesisTags(thesis *api.Thesis) string {
+	t := theme.Default
+	colors := map[string]color.Color{"target_reached": t.Success, "stop_reached": t.Error, "review_due": t.Warning}
+	var labels []string
+	for _, tl := range thesisTagLabels {
+		if slices.Contains(thesis.Tags, tl.tag) {
+			labels = append(labels, lipgloss.NewStyle().Foreground(colors[tl.tag]).Bold(true).Render(tl.label))
+		}
+	}
+	return strings.Join(labels, "  ")
+}
+
+// viewThesis shows a position's target, stop, review date and thesis text.
+func viewThesis(sec api.Security, width int) []string {
+	t := theme.Default
+	label := lipgloss.NewStyle().Foreground(t.Subtext)
+
+	var levels []string
+	if sec.Thesis.TargetPrice != nil {
+		levels = append(levels, fmt.Sprintf("TARGET %.2f %s", *sec.Thesis.TargetPrice, sec.Currency))
+	}
+	if sec.Thesis.StopPrice != nil {
+		levels = append(levels, fmt.Sprintf("STOP %.2f %s", *sec.Thesis.StopPrice, sec.Currency))
+	}
+	if sec.Thesis.ReviewDueAt != nil {
+		levels = append(levels, "REVIEW "+time.Unix(*sec.Thesis.ReviewDueAt, 0).Format("2006-01-02"))
+	}
+
+	lines := []string{label.Render(strings.Join(levels, "   "))}
+	if tags := viewThesisTags(sec.Thesis); tags != "" {
+		lines = append(lines, tags)
+	}
+	if sec.Thesis.Thesis != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(t.Muted).Width(width).Render(sec.Thesis.Thesis))
+	}
+	return lines
+}
+
+func chartValues(points []api.ChartPoint) []float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	return values
+}
+
+// chartSummary formats "   last X   low Y   high Z" for a chart heading.
+func chartSummary(values []float64, unit string, format func(float64) string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low = min(low, v)
+		high = max(high, v)
+	}
+	return fmt.Sprintf("   last %s   low %s   high %s",
+		fmt.Sprintf(unit, format(values[len(values)-1])), format(low), format(high))
+}
+
+// viewLogs tails server logs, newest at the bottom, colored by level.
+func (m Model) viewLogs() string {
+	t := theme.Default
+
+	module := m.logModule
+	if module == "" {
+		module = "all"
+	}
+	filters := fmt.Sprintf("level >= %s   module %s", logLevels[m.logLevel], module)
+	if m.logModuleEdit {
+		filters = fmt.Sprintf("level >= %s   module %s_", logLevels[m.logLevel], m.logModuleIn)
+	}
+	header := []string{
+		"",
+		lipgloss.NewStyle().Foreground(t.Primary).Bold(true).Render("LOGS"),
+		lipgloss.NewStyle().Foreground(t.Subtext).Render(filters),
+		"",
+	}
+
+	hints := "V level   / module   ESC back"
+	if m.logModuleEdit {
+		hints = "ENTER apply   ESC cancel   Ctrl+U clear"
+	}
+	footer := []string{"", lipgloss.NewStyle().Foreground(t.Subtext).Render(hints)}
+	if m.statusMsg != "" {
+		footer = append(footer, "", lipgloss.NewStyle().Foreground(t.Error).Render(m.statusMsg))
+	}
+
+	// Render newest entries that fit between header and footer
+	width := m.contentWidth()
+	room := m.height - 2 - len(header) - len(footer)
+	var lines []string
+	for i := len(m.logs) - 1; i >= 0 && len(lines) < room; i-- {
+		lines = append(m.renderLogEntry(m.logs[i], width), lines...)
+	}
+	if len(lines) > room {
+		lines = lines[len(lines)-room:]
+	}
+	if len(m.logs) == 0 {
+		lines = []string{lipgloss.NewStyle().Foreground(t.Muted).Render("Waiting for log entries...")}
+	}
+
+	body := append(header, lines...)
+	for i := len(lines); i < room; i++ {
+		body = append(body, "")
+	}
+	body = append(body, footer...)
+
+	return lipgloss.NewStyle().
+		Width(m.width).
+		Height(m.height).
+		Padding(1, 2).
+		Render(strings.Join(body, "\n"))
+}
+
+// renderLogEntry formats one entry; continuation lines (tracebacks) are indented.
+func (m Model) renderLogEntry(entry api.LogEntry, width int) []string {
+	t := theme.Default
+
+	c := t.Text
+	switch entry.Level {
+	case "DEBUG":
+		c = t.Muted
+	case "WARNING":
+		c = t.Warning
+	case "ERROR", "CRITICAL":
+		c = t.Error
+	}
+	ts := time.Unix(int64(entry.TS), 0).Format("15:04:05")
+	style := lipgloss.NewStyle().Foreground(c)
+	meta := lipgloss.NewStyle().Foreground(t.Muted)
+	fit := lipgloss.NewStyle().MaxWidth(width)
+
+	msgLines := strings.Split(strings.TrimRight(entry.Message, "\n"), "\n")
+	first := meta.Render(ts+" "+entry.Module) + " " + style.Render(fmt.Sprintf("%-7s %s", entry.Level, msgLines[0]))
+	lines := []string{fit.Render(first)}
+	for _, line := range msgLines[1:] {
+		lines = append(lines, fit.Render(style.Render("    "+line)))
+	}
+	return lines
+}
+
 // contentWidth returns the usable content width after outer padding.
 func (m Model) contentWidth() int {
 	return m.width - 4
@@ -88,18 +380,27 @@ func (m *Model) rebuildContent() {
 	sep := pad.Render(lipgloss.NewStyle().Foreground(t.Primary).Render(
 		strings.Repeat("/", w)))
 
-	oneBlock := strings.Join([]string{
+	parts := []string{
 		strings.Repeat("\n", m.height),
 		hero,
 		"", "",
 		sep,
 		"", "",
+	}
+	if m.inbox != nil && m.inbox.UnreadCount > 0 {
+		parts = append(parts, pad.Render(m.viewNotifications()), "", "", sep, "", "")
+	}
+	if m.approvals != nil && m.approvals.ManualApproval && m.approvals.Pending > 0 {
+		parts = append(parts, pad.Render(m.viewPendingApprovals()), "", "", sep, "", "")
+	}
+	parts = append(parts,
 		actions,
 		"", "",
 		sep,
 		"", "",
 		cards,
-	}, "\n")
+	)
+	oneBlock := strings.Join(parts, "\n")
 
 	oneBlock = strings.TrimRight(oneBlock, "\n")
 	m.contentLines = strings.Count(oneBlock, "\n") + 1
@@ -170,6 +471,43 @@ func (m Model) viewHero() string {
 	)
 }
 
+func (m Model) viewNotifications() string {
+	t := theme.Default
+
+	title := lipgloss.NewStyle().Foreground(t.Warning).
+		Render(bigtext.Render(fmt.Sprintf("%d UNREAD", m.inbox.UnreadCount)))
+
+	lines := []string{title, ""}
+	for _, n := range m.inbox.Notifications {
+		c := t.Subtext
+		switch n.Severity {
+		case "error":
+			c = t.Error
+		case "warning":
+			c = t.Warning
+		}
+		text := strings.ToUpper(n.Category) + "  " + n.Title
+		if n.Occurrences > 1 {
+			text += fmt.Sprintf(" (x%d)", n.Occurrences)
+		}
+		lines = append(lines, lipgloss.NewStyle().Foreground(c).Bold(true).Render(text))
+		if n.Message != "" {
+			lines = append(lines, lipgloss.NewStyle().Foreground(t.Muted).Render("  "+n.Message))
+		}
+	}
+	lines = append(lines, "", lipgloss.NewStyle().Foreground(t.Muted).Render("press A to acknowledge"))
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) viewPendingApprovals() string {
+	t := theme.Default
+
+	title := lipgloss.NewStyle().Foreground(t.Warning).
+		Render(bigtext.Render(fmt.Sprintf("%d TO APPROVE", m.approvals.Pending)))
+	hint := lipgloss.NewStyle().Foreground(t.Muted).Render("press I to open the inbox")
+	return strings.Join([]string{title, "", hint}, "\n")
+}
+
 func (m Model) viewActions() string {
 	t := theme.Default
 
@@ -218,12 +556,7 @@ func (m Model) viewCards() string {
 	t := theme.Default
 	w := m.contentWidth()
 
-	var positions []api.Security
-	for _, sec := range m.securities {
-		if sec.HasPosition {
-			positions = append(positions, sec)
-		}
-	}
+	positions := m.heldSecurities()
 	if len(positions) == 0 {
 		return ""
 	}
@@ -274,6 +607,11 @@ func (m Model) viewCards() string {
 
 		var cardLines []string
 		cardLines = append(cardLines, "", headerRow, nameBlock, "")
+		if sec.Thesis != nil {
+			if tags := viewThesisTags(sec.Thesis); tags != "" {
+				cardLines = append(cardLines, tags, "")
+			}
+		}
 		if chartBlock != "" {
 			cardLines = append(cardLines, chartBlock, "")
 		}
@@ -17,14 +17,34 @@ func main() {
 	settingsFile := flag.String("settings-file", "settings.json", "Path to TUI settings JSON")
 	maxWidth := flag.Int("max-width", 0, "Max columns (0 = no limit)")
 	maxHeight := flag.Int("max-height", 0, "Max rows (0 = no limit)")
+	apiToken := flag.String("api-token", os.Getenv("SENTINEL_API_TOKEN"), "API token (when server auth is enabled)")
+	tlsCA := flag.String("tls-ca", os.Getenv("SENTINEL_CA_CERT"), "CA certificate for an HTTPS API URL")
+	tlsCert := flag.String("tls-cert", os.Getenv("SENTINEL_CLIENT_CERT"), "Client certificate (mutual TLS)")
+	tlsKey := flag.String("tls-key", os.Getenv("SENTINEL_CLIENT_KEY"), "Client certificate key (mutual TLS)")
 	flag.Parse()
 
 	effectiveAPIURL := *apiURL
-	if cfg, err := config.Load(*settingsFile); err == nil && cfg.APIURL != "" {
-		effectiveAPIURL = cfg.APIURL
+	effectiveToken := *apiToken
+	if cfg, err := config.Load(*settingsFile); err == nil {
+		if cfg.APIURL != "" {
+			effectiveAPIURL = cfg.APIURL
+		}
+		if effectiveToken == "" {
+			effectiveToken = cfg.APIToken
+		}
+		if *tlsCA == "" {
+			*tlsCA = cfg.TLSCAFile
+		}
+		if *tlsCert == "" {
+			*tlsCert, *tlsKey = cfg.TLSCertFile, cfg.TLSKeyFile
+		}
 	}
 
 	client := api.NewClient(effectiveAPIURL)
+	client.SetToken(effectiveToken)
+	if err := client.SetTLS(*tlsCA, *tlsCert, *tlsKey); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: TLS settings ignored: %v\n", err)
+	}
 	m := ui.NewModel(client, effectiveAPIURL, *settingsFile, *maxWidth, *maxHeight)
 
 	p := tea.NewProgram(m)
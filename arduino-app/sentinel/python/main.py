@@ -51,6 +51,15 @@ SENTINEL_API_URL = (
 )
 
 _session = requests.Session()
+# HTTPS to sentinel: trust a private CA and/or present a client certificate (mutual TLS)
+if os.environ.get("SENTINEL_CA_CERT"):
+    _session.verify = os.environ["SENTINEL_CA_CERT"]
+if os.environ.get("SENTINEL_CLIENT_CERT"):
+    _session.cert = (
+        (os.environ["SENTINEL_CLIENT_CERT"], os.environ["SENTINEL_CLIENT_KEY"])
+        if os.environ.get("SENTINEL_CLIENT_KEY")
+        else os.environ["SENTINEL_CLIENT_CERT"]
+    )
 
 
 def _fetch(path: str) -> dict:
@@ -60,7 +69,7 @@ def _fetch(path: str) -> dict:
 
 
 def push_once() -> None:
-    """Fetch portfolio value, P/L, and recommendations, send to MCU."""
+    """Fetch portfolio value, P/L, recommendations and indicator states, send to MCU."""
     portfolio = _fetch("/api/portfolio")
     total_eur = portfolio.get("total_value_eur", 0)
     value = max(0, min(99999999, round(total_eur)))
@@ -78,6 +87,17 @@ def push_once() -> None:
     logger.info("Portfolio: EUR %d, P/L %d%%, recs=%d, sending to MCU", value, return_pct, has_recs)
     Bridge.call("hm.u", [value, return_pct, has_recs], timeout=10)
 
+    # Indicator column as configured on the server: [color, pattern] per LED
+    try:
+        leds = _fetch("/api/led/indicators").get("leds", [])
+    except Exception:  # noqa: BLE001
+        return  # Older servers: the MCU keeps its built-in indicator layout
+    indicators = []
+    for led in leds:
+        indicators += [int(led.get("color", 0)), int(led.get("code", 0))]
+    logger.info("Indicators: %s", ", ".join(str(led.get("state") or "-") for led in leds))
+    Bridge.call("hm.i", indicators, timeout=10)
+
 
 def loop() -> None:
     time.sleep(REFRESH_INTERVAL_SEC)
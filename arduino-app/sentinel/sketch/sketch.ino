@@ -6,10 +6,13 @@
 //   Row 0 (top): heaven bead (orange, worth 5)
 //   Rows 1-4: earth bead position marker (amber, worth 1-4)
 //   Only the single position-indicator bead is lit per earth section.
-// Column 0 indicators:
+// Column 0 indicators, built-in layout:
 //   r0: heartbeat (red, 200ms on / 1000ms off) — alive if RPC received recently
 //   r1-r3: P/L bar (green up / red down, 800ms blink)
 //   r4: recommendations (blue, 100ms on / 300ms off) — pending trades exist
+// Once the MPU sends Bridge.call("hm.i", [color0, pattern0, ... color4, pattern4])
+// (server-side state mapping, colors 0xRRGGBB), column 0 shows that instead.
+// Indicators go dark when no RPC arrived within HEARTBEAT_TIMEOUT_MS.
 //
 // Device-only patches (not in this repo):
 // - bridge.h UPDATE_THREAD_STACK_SIZE changed from 500 to 8192
@@ -86,6 +89,38 @@ static bool pnlBlinkOn = true;
 static bool heartbeatOn = false;
 static bool recBlinkOn = false;
 
+// --- Configured indicators (hm.i) ---
+#define INDICATOR_LEDS 5
+static int32_t indicatorColor[INDICATOR_LEDS];
+static uint8_t indicatorPattern[INDICATOR_LEDS];
+static bool indicatorsSet = false;
+static uint8_t indicatorMask = 0;  // indicators lit in the last render
+
+// Pattern codes match sentinel.led.display.PATTERNS.
+static bool patternOn(uint8_t pattern, unsigned long now) {
+  switch (pattern) {
+    case 1: return true;                // solid
+    case 2: return (now % 1600) < 800;  // blink
+    case 3: return (now % 400) < 100;   // fast_blink
+    case 4: return (now % 1200) < 200;  // heartbeat
+    default: return false;              // off
+  }
+}
+
+static uint8_t litIndicators(unsigned long now) {
+  if (!indicatorsSet || now - lastRpcMs >= HEARTBEAT_TIMEOUT_MS) return 0;
+  uint8_t mask = 0;
+  for (int i = 0; i < INDICATOR_LEDS; i++) {
+    if (patternOn(indicatorPattern[i], now)) mask |= (1 << i);
+  }
+  return mask;
+}
+
+// Scale a 0-255 channel to BRIGHTNESS, keeping any non-zero channel visible.
+static uint8_t dim(int32_t channel) {
+  return (uint8_t)((channel * BRIGHTNESS + 254) / 255);
+}
+
 static void renderDisplay() {
   pixels.clear();
 
@@ -119,8 +154,19 @@ static void renderDisplay() {
 
   // --- Column 0 indicators ---
 
-  // Heartbeat: c0r0, red, 50ms on / 1950ms off.
   unsigned long now = millis();
+  if (indicatorsSet) {
+    indicatorMask = litIndicators(now);
+    for (int i = 0; i < INDICATOR_LEDS; i++) {
+      if (!(indicatorMask & (1 << i))) continue;
+      int32_t c = indicatorColor[i];
+      pixels.setPixelColor(i * 8, pixels.Color(dim((c >> 16) & 0xFF), dim((c >> 8) & 0xFF), dim(c & 0xFF)));
+    }
+    ws2812_show(pixels);
+    return;
+  }
+
+  // Heartbeat: c0r0, red, 50ms on / 1950ms off.
   if (heartbeatOn && (now - lastRpcMs < HEARTBEAT_TIMEOUT_MS)) {
     pixels.setPixelColor(0, pixels.Color(BRIGHTNESS, 0, 0));
   }
@@ -171,6 +217,17 @@ static void hmUpdate(MsgPack::arr_t<int> data) {
   needsRedraw = true;
 }
 
+static void hmIndicators(MsgPack::arr_t<int> data) {
+  if ((int)data.size() < 2 * INDICATOR_LEDS) return;
+  for (int i = 0; i < INDICATOR_LEDS; i++) {
+    indicatorColor[i] = data[2 * i] & 0xFFFFFF;
+    indicatorPattern[i] = (uint8_t)data[2 * i + 1];
+  }
+  indicatorsSet = true;
+  lastRpcMs = millis();
+  needsRedraw = true;
+}
+
 void setup() {
   pixels.begin();
   pixels.clear();
@@ -178,6 +235,7 @@ void setup() {
 
   Bridge.begin();
   Bridge.provide("hm.u", hmUpdate);
+  Bridge.provide("hm.i", hmIndicators);
 }
 
 void loop() {
@@ -195,6 +253,7 @@ void loop() {
   if (newPnlBlink != pnlBlinkOn && displayPnl != 0) changed = true;
   if (newHeartbeat != heartbeatOn && (now - lastRpcMs < HEARTBEAT_TIMEOUT_MS)) changed = true;
   if (newRecBlink != recBlinkOn && hasRecs > 0) changed = true;
+  if (indicatorsSet && litIndicators(now) != indicatorMask) changed = true;
 
   pnlBlinkOn = newPnlBlink;
   heartbeatOn = newHeartbeat;
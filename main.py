@@ -5,17 +5,22 @@ Sentinel - Entry point for running the application.
 Usage:
     python main.py          # Run web server only
     python main.py --all    # Run web server + scheduler
+    python main.py --all --public-port 8080  # ... plus the read-only dashboard on its own port
 """
 
 import argparse
 import asyncio
 import logging
+import os
 
 import uvicorn
 
-from sentinel import Broker, Database, Settings
+from sentinel import Broker, Database, Settings, correlation
+from sentinel.config.schema import Problem, check_port, format_report
+from sentinel.tls import TLSConfig, TLSConfigError, server_ssl_options
 
-logging.basicConfig(level=logging.INFO, format="%(asctime)s - %(name)s - %(levelname)s - %(message)s")
+correlation.install_log_records()
+logging.basicConfig(level=logging.INFO, format=correlation.LOG_FORMAT)
 logger = logging.getLogger(__name__)
 
 
@@ -51,8 +56,37 @@ def main():
     parser.add_argument("--scheduler-only", action="store_true", help="Run scheduler only (no web server)")
     parser.add_argument("--host", default="::", help="Web server host")
     parser.add_argument("--port", type=int, default=8000, help="Web server port")
+    parser.add_argument("--tls-cert", help="TLS certificate (PEM); overrides SENTINEL_TLS_CERT")
+    parser.add_argument("--tls-key", help="TLS private key (PEM); overrides SENTINEL_TLS_KEY")
+    parser.add_argument("--tls-client-ca", help="Client certificate CA (mutual TLS); overrides SENTINEL_TLS_CLIENT_CA")
+    parser.add_argument(
+        "--public-port",
+        default=os.environ.get("SENTINEL_PUBLIC_PORT") or None,
+        help="Also serve only the read-only public dashboard on this port (SENTINEL_PUBLIC_PORT)",
+    )
     args = parser.parse_args()
 
+    tls_config = TLSConfig.from_env()
+    tls_config.cert_file = args.tls_cert or tls_config.cert_file
+    tls_config.key_file = args.tls_key or tls_config.key_file
+    tls_config.client_ca_file = args.tls_client_ca or tls_config.client_ca_file
+
+    # Report every bad option at once rather than failing on the first
+    problems = check_port("--port", args.port)
+    if args.public_port in (None, "0"):
+        args.public_port = None
+    else:
+        problems += check_port("--public-port", args.public_port)
+    try:
+        ssl_options = server_ssl_options(tls_config)
+    except TLSConfigError as e:
+        problems.append(Problem("tls", f"TLS is required but not usable: {e}"))
+    if problems:
+        logger.error(format_report(problems))
+        raise SystemExit(1)
+    if args.public_port is not None:
+        args.public_port = int(args.public_port)
+
     # Do not run init_services() here when starting the web server: uvicorn uses a
     # different event loop, so a DB connection created here would be invalid in
     # request handlers. The app's lifespan (sentinel.app) connects the DB in the
@@ -61,21 +95,30 @@ def main():
     if args.scheduler_only:
         logger.info("Running scheduler only")
         asyncio.run(run_scheduler())
-    elif args.all:
-        # Run both web server and scheduler
-        logger.info("Running web server and scheduler")
+        return
 
-        async def run_all():
-            # Note: Scheduler and LED controller are started by app.py's lifespan
-            config = uvicorn.Config("sentinel.app:app", host=args.host, port=args.port, log_level="info")
-            server = uvicorn.Server(config)
-            await server.serve()
-
-        asyncio.run(run_all())
+    if args.all:
+        # Note: Scheduler and LED controller are started by app.py's lifespan
+        logger.info("Running web server and scheduler")
     else:
-        # Web server only
         logger.info(f"Running web server on {args.host}:{args.port}")
-        uvicorn.run("sentinel.app:app", host=args.host, port=args.port, log_level="info")
+
+    configs = [uvicorn.Config("sentinel.app:app", host=args.host, port=args.port, log_level="info", **ssl_options)]
+    if args.public_port:
+        # Same process and event loop as the main app, so both share its database connection.
+        # Server TLS only: wall displays have no client certificate.
+        public_ssl = {k: v for k, v in ssl_options.items() if k in ("ssl_certfile", "ssl_keyfile")}
+        logger.info(f"Serving the public dashboard on {args.host}:{args.public_port}")
+        configs.append(
+            uvicorn.Config(
+                "sentinel.app:public_app", host=args.host, port=args.public_port, log_level="info", **public_ssl
+            )
+        )
+
+    async def serve_all():
+        await asyncio.gather(*(uvicorn.Server(config).serve() for config in configs))
+
+    asyncio.run(serve_all())
 
 
 if __name__ == "__main__":
@@ -1,19 +1,32 @@
 #!/bin/bash
-# Auto-deploy script for Sentinel.
-# Polls git for new commits on main, pulls, updates deps if needed, restarts.
+# Auto-deploy script for Sentinel (blue/green).
+# Polls git for new commits on main and stages each one as a release: a git
+# worktree under RELEASES_DIR with a virtualenv shared by releases with the same
+# pyproject.toml and uv.lock. A staged release must pass the smoke tests
+# (python -m sentinel.deploy smoke) before the CURRENT_LINK symlink is swapped
+# over to it atomically. If it is not ready (/api/readyz) within READY_WINDOW
+# seconds the link is swapped back to the previous release, and the commit is
+# skipped until main moves on. REPO_DIR is only fetched; it keeps the data
+# directory and serves as the first release on devices that predate blue/green
+# deploys.
 # Designed to run via systemd timer on the target device.
 
 set -euo pipefail
 
 REPO_DIR="/home/arduino/sentinel"
-VENV_DIR="$REPO_DIR/.venv"
-LED_APP_SRC="$REPO_DIR/arduino-app/sentinel"
-LED_APP_DEST="/home/arduino/ArduinoApps/sentinel"
 LOG_DIR="/home/arduino/logs"
 LOG_FILE="$LOG_DIR/auto-deploy.log"
 MAX_LOG_SIZE=$((10 * 1024 * 1024))
 MAX_LOG_FILES=3
 BRANCH="main"
+RELEASES_DIR="/home/arduino/sentinel-releases"
+CURRENT_LINK="/home/arduino/sentinel-current"
+DATA_DIR="$REPO_DIR/data"
+LED_APP_DEST="/home/arduino/ArduinoApps/sentinel"
+KEEP_RELEASES=3
+READY_URL="${SENTINEL_READY_URL:-http://127.0.0.1:8000/api/readyz}"
+READY_WINDOW="${SENTINEL_DEPLOY_READY_WINDOW:-300}"
+FAILED_FILE="$RELEASES_DIR/.failed"
 
 # SSH multiplexing to prevent connection exhaustion
 # Uses a control socket that auto-closes after 30s idle
@@ -49,75 +62,156 @@ rotate_logs() {
     mv "$LOG_FILE" "$LOG_FILE.1"
 }
 
-mkdir -p "$LOG_DIR" "$SSH_CONTROL_DIR"
+mkdir -p "$LOG_DIR" "$SSH_CONTROL_DIR" "$RELEASES_DIR"
 chmod 700 "$SSH_CONTROL_DIR"
 rotate_logs
 cd "$REPO_DIR"
 
-# Create venv if missing
-if [ ! -d "$VENV_DIR" ]; then
-    log "Creating virtual environment..."
-    python3 -m venv "$VENV_DIR"
-    "$VENV_DIR/bin/pip" install --upgrade pip --quiet
-    "$VENV_DIR/bin/pip" install . --quiet
-    log "Virtual environment created and dependencies installed"
-fi
+# Devices that predate blue/green deploys run straight from the checkout
+[ -e "$CURRENT_LINK" ] || ln -s "$REPO_DIR" "$CURRENT_LINK"
 
 # Fetch and compare
 git fetch origin "$BRANCH" --quiet
 
-LOCAL=$(git rev-parse HEAD)
+LIVE=$(readlink -f "$CURRENT_LINK")
+LOCAL=$(git -C "$LIVE" rev-parse HEAD)
 REMOTE=$(git rev-parse "origin/$BRANCH")
 
 [ "$LOCAL" = "$REMOTE" ] && exit 0
+# Do not retry a release that was already rolled back
+[ -f "$FAILED_FILE" ] && [ "$(cat "$FAILED_FILE")" = "$REMOTE" ] && exit 0
 
 log "New commits: ${LOCAL:0:7} -> ${REMOTE:0:7}"
 
-# Snapshot dependency file before pull
-DEPS_HASH_BEFORE=$(md5sum pyproject.toml | cut -d' ' -f1)
-
-git pull origin "$BRANCH" --quiet
-log "Pulled latest changes"
+fail() {
+    log "$1"
+    echo "$REMOTE" > "$FAILED_FILE"
+    exit 1
+}
 
-# Update deps if pyproject.toml changed
-DEPS_HASH_AFTER=$(md5sum pyproject.toml | cut -d' ' -f1)
-if [ "$DEPS_HASH_BEFORE" != "$DEPS_HASH_AFTER" ]; then
-    log "pyproject.toml changed, updating dependencies..."
-    "$VENV_DIR/bin/pip" install . --quiet
-    log "Dependencies updated"
+# Stage the release next to the live one
+RELEASE="$RELEASES_DIR/release-${REMOTE:0:12}"
+if [ -d "$RELEASE" ]; then
+    git worktree remove --force "$RELEASE"
 fi
+git worktree prune
+git worktree add --detach --quiet "$RELEASE" "$REMOTE"
+log "Staged $RELEASE"
 
-# Update systemd units if changed
-UNITS_CHANGED=false
-for unit in sentinel.service sentinel-deploy.service sentinel-deploy.timer; do
-    if ! diff -q "$REPO_DIR/systemd/$unit" "/etc/systemd/system/$unit" &>/dev/null; then
-        sudo cp "$REPO_DIR/systemd/$unit" "/etc/systemd/system/$unit"
-        UNITS_CHANGED=true
-        log "Updated $unit"
+# One virtualenv per pyproject.toml and uv.lock, so rolling back keeps the old dependencies
+DEPS_HASH=$(cat "$RELEASE/pyproject.toml" "$RELEASE/uv.lock" 2>/dev/null | md5sum | cut -d' ' -f1)
+VENV_DIR="$RELEASES_DIR/venv-$DEPS_HASH"
+if [ ! -d "$VENV_DIR" ]; then
+    log "Dependencies changed, creating virtual environment..."
+    if ! { python3 -m venv "$VENV_DIR" &&
+        "$VENV_DIR/bin/pip" install --upgrade pip --quiet &&
+        "$VENV_DIR/bin/pip" install "$RELEASE" --quiet; }; then
+        rm -rf "$VENV_DIR"
+        fail "Installing dependencies failed"
     fi
-done
-if [ "$UNITS_CHANGED" = true ]; then
-    sudo systemctl daemon-reload
-    log "Systemd daemon reloaded"
+    log "Virtual environment created and dependencies installed"
 fi
+ln -sfn "$VENV_DIR" "$RELEASE/.venv"
 
-# Update LED app if changed
-if git diff --name-only "$LOCAL" "$REMOTE" -- arduino-app/sentinel/ | grep -q .; then
-    log "LED app changed, updating..."
+# Smoke tests: migrations on a copy of the database, broker ping, planner run
+log "Running smoke tests..."
+if ! (cd "$RELEASE" && SENTINEL_DATA_DIR="$DATA_DIR" "$RELEASE/.venv/bin/python" -m sentinel.deploy smoke >> "$LOG_FILE" 2>&1); then
+    fail "Smoke tests failed, keeping ${LOCAL:0:7}"
+fi
+
+# Install the systemd units and LED app shipped with a release
+install_units() {
+    local src="$1" changed=false
+    for unit in sentinel.service sentinel-deploy.service sentinel-deploy.timer; do
+        if ! diff -q "$src/systemd/$unit" "/etc/systemd/system/$unit" &>/dev/null; then
+            sudo cp "$src/systemd/$unit" "/etc/systemd/system/$unit"
+            changed=true
+            log "Updated $unit"
+        fi
+    done
+    if [ "$changed" = true ]; then
+        sudo systemctl daemon-reload
+        log "Systemd daemon reloaded"
+    fi
+}
+
+install_led_app() {
+    local src="$1/arduino-app/sentinel"
     mkdir -p "$LED_APP_DEST"
     rm -rf "$LED_APP_DEST/python" "$LED_APP_DEST/sketch"
-    cp "$LED_APP_SRC/app.yaml" "$LED_APP_DEST/"
-    cp -R "$LED_APP_SRC/python" "$LED_APP_DEST/"
-    cp -R "$LED_APP_SRC/sketch" "$LED_APP_DEST/"
+    cp "$src/app.yaml" "$LED_APP_DEST/"
+    cp -R "$src/python" "$LED_APP_DEST/"
+    cp -R "$src/sketch" "$LED_APP_DEST/"
     # On-device, the running app id shows up as "user:sentinel".
     # Stop by id first (most reliable), then fall back to the short name.
     arduino-app-cli app stop user:sentinel 2>/dev/null || arduino-app-cli app stop sentinel 2>/dev/null || true
-    cd "$LED_APP_DEST" && arduino-app-cli app start .
-    cd "$REPO_DIR"
+    (cd "$LED_APP_DEST" && arduino-app-cli app start .)
+}
+
+# Point CURRENT_LINK at a release (rename(2) replaces the link atomically)
+switch_to() {
+    ln -sfn "$1" "$CURRENT_LINK.new"
+    mv -Tf "$CURRENT_LINK.new" "$CURRENT_LINK"
+}
+
+wait_ready() {
+    local deadline=$((SECONDS + READY_WINDOW))
+    while [ "$SECONDS" -lt "$deadline" ]; do
+        curl -fsk --max-time 5 "$READY_URL" >/dev/null 2>&1 && return 0
+        sleep 5
+    done
+    return 1
+}
+
+LED_CHANGED=false
+if git diff --name-only "$LOCAL" "$REMOTE" -- arduino-app/sentinel/ | grep -q .; then
+    LED_CHANGED=true
+fi
+
+# Switch over
+install_units "$RELEASE"
+if [ "$LED_CHANGED" = true ]; then
+    install_led_app "$RELEASE"
     log "LED app updated and restarted"
 fi
+switch_to "$RELEASE"
+log "Restarting sentinel on ${REMOTE:0:7}..."
+sudo systemctl restart sentinel
 
-# Restart the app
-log "Restarting sentinel..."
+if wait_ready; then
+    rm -f "$FAILED_FILE"
+    log "Deploy complete (${REMOTE:0:7})"
+
+    # Keep the live release, the one it replaced and the newest others
+    KEEP="$RELEASE $LIVE $(ls -dt "$RELEASES_DIR"/release-* | head -n "$KEEP_RELEASES" | tr '\n' ' ' || true)"
+    for dir in "$RELEASES_DIR"/release-*; do
+        case " $KEEP " in *" $dir "*) continue ;; esac
+        git worktree remove --force "$dir" && log "Removed $(basename "$dir")"
+    done
+    for venv in "$RELEASES_DIR"/venv-*; do
+        [ -d "$venv" ] || continue
+        in_use=false
+        for link in "$RELEASES_DIR"/release-*/.venv; do
+            [ "$(readlink -f "$link")" = "$venv" ] && in_use=true
+        done
+        [ "$in_use" = true ] || { rm -rf "$venv" && log "Removed $(basename "$venv")"; }
+    done
+    exit 0
+fi
+
+# Roll back to the previous release
+log "Not ready after ${READY_WINDOW}s, rolling back to ${LOCAL:0:7}"
+echo "$REMOTE" > "$FAILED_FILE"
+switch_to "$LIVE"
+install_units "$LIVE"
+if [ "$LED_CHANGED" = true ]; then
+    install_led_app "$LIVE"
+    log "LED app restored"
+fi
 sudo systemctl restart sentinel
-log "Deploy complete ($(git rev-parse --short HEAD))"
+if wait_ready; then
+    log "Rolled back to ${LOCAL:0:7}"
+else
+    log "Rollback to ${LOCAL:0:7} is not ready either"
+fi
+exit 1
@@ -0,0 +1,55 @@
+#!/usr/bin/env python3
+"""Backfill quality-gate outcomes for every security and historical month.
+
+Evaluates which buy quality-gate path (if any) each security would have passed
+at each month end with the current strategy settings, stores the matrix, and
+prints path usage and overlap statistics.
+
+Usage (from repo root with venv activated):
+    python scripts/backfill_quality_gates.py
+    python scripts/backfill_quality_gates.py --symbol AAPL.US
+    python scripts/backfill_quality_gates.py --stats-only --since 2020-01
+"""
+
+import argparse
+import asyncio
+import json
+import logging
+import sys
+from pathlib import Path
+
+# Ensure project root is on path
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent))
+
+from sentinel.database import Database
+from sentinel.services.quality_gates import QualityGateService
+from sentinel.settings import Settings
+
+logging.basicConfig(level=logging.INFO, format="%(asctime)s - %(levelname)s - %(message)s")
+logger = logging.getLogger(__name__)
+
+
+async def main() -> None:
+    parser = argparse.ArgumentParser(description="Backfill quality-gate outcomes across the universe")
+    parser.add_argument("--symbol", type=str, action="append", help="Only this symbol (repeatable)")
+    parser.add_argument("--stats-only", action="store_true", help="Print statistics of the stored matrix")
+    parser.add_argument("--since", type=str, help="First month (YYYY-MM) included in the statistics")
+    parser.add_argument("--until", type=str, help="Last month (YYYY-MM) included in the statistics")
+    args = parser.parse_args()
+
+    db = Database()
+    await db.connect()
+    settings = Settings()
+    await settings.init_defaults()
+
+    service = QualityGateService(db=db, settings=settings)
+    if not args.stats_only:
+        result = await service.backfill(symbols=args.symbol)
+        logger.info("Evaluated %d security-months across %d securities", result["evaluations"], result["securities"])
+    print(json.dumps(await service.stats(since=args.since, until=args.until), indent=2))
+
+    await db.close()
+
+
+if __name__ == "__main__":
+    asyncio.run(main())
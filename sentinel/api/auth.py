@@ -0,0 +1,133 @@
+"""Authentication and role-based access middleware.
+
+When the auth_enabled setting is on, every /api request must present a token
+(`Authorization: Bearer <token>` or `X-API-Token: <token>`) whose role covers
+the route. Required roles come from ROUTE_ROLES (first match wins); anything
+not listed needs viewer for reads and operator for mutations. Mutating calls
+and denied requests are written to the audit trail.
+
+The authenticated principal is exposed to handlers as request.state.principal,
+and actions a request performs are audited under its name (see sentinel.audit;
+"local" while auth is off).
+"""
+
+import re
+from typing import Callable
+
+from starlette.responses import JSONResponse
+from starlette.types import ASGIApp, Message, Receive, Scope, Send
+
+from sentinel.api.dry_run import MUTATING_METHODS
+from sentinel.audit import acting_as
+from sentinel.database import Database
+from sentinel.services.auth import AuthService, role_allows
+
+# Reachable without a token (health checks, version, logging in)
+PUBLIC_PATHS = frozenset({"/api/health", "/api/healthz", "/api/readyz", "/api/version", "/api/auth/login"})
+# Read-only public dashboard (404s itself unless public_dashboard_enabled is on)
+PUBLIC_PREFIX = "/api/public/"
+
+# (methods or None for all, path pattern, required role)
+ROUTE_ROLES: list[tuple[frozenset[str] | None, re.Pattern, str]] = [
+    (None, re.compile(r"^/api/auth/(tokens|users|audit)"), "admin"),
+    (None, re.compile(r"^/api/audit"), "admin"),
+    (None, re.compile(r"^/api/secrets"), "admin"),
+    (None, re.compile(r"^/api/webhooks"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/notifications/email/"), "admin"),  # Mails arbitrary addresses
+    (None, re.compile(r"^/api/debug"), "admin"),
+    (None, re.compile(r"^/api/logs"), "operator"),
+    (None, re.compile(r"^/api/jobs/runs/"), "operator"),  # Run details include captured logs
+    (MUTATING_METHODS, re.compile(r"^/api/settings"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/jobs/schedules"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/backup"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/planner/sleeve-funding/apply"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/reconciliations/\d+/sign-off"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/metadata/(overrides|reviews/)"), "admin"),
+    (MUTATING_METHODS, re.compile(r"^/api/shadow/trials/\d+/promote"), "admin"),  # Changes live settings
+    (MUTATING_METHODS, re.compile(r"^/api/portfolio/positions/"), "admin"),  # Overrides broker positions
+]
+
+
+def required_role(method: str, path: str) -> str:
+    """Minimum role for a request."""
+    for methods, pattern, role in ROUTE_ROLES:
+        if (methods is None or method in methods) and pattern.match(path):
+            return role
+    return "operator" if method in MUTATING_METHODS else "viewer"
+
+
+def _headers(scope: Scope) -> dict[str, str]:
+    return {name.decode("latin-1").lower(): value.decode("latin-1") for name, value in scope.get("headers", [])}
+
+
+def presented_token(scope: Scope) -> str | None:
+    """Token from the Authorization (Bearer) or X-API-Token header."""
+    headers = _headers(scope)
+    authorization = headers.get("authorization")
+    if authorization and authorization.lower().startswith("bearer "):
+        return authorization[7:].strip()
+    return headers.get("x-api-token")
+
+
+def client_type(scope: Scope) -> str:
+    """Audit actor type of a request: tui for the terminal UI (X-Sentinel-Client: tui), api otherwise."""
+    return "tui" if _headers(scope).get("x-sentinel-client", "").lower() == "tui" else "api"
+
+
+class AuthMiddleware:
+    """ASGI middleware enforcing token authentication and roles on /api routes."""
+
+    def __init__(self, app: ASGIApp, db_factory: Callable[[], Database] = Database):
+        self.app = app
+        self._db_factory = db_factory
+
+    async def __call__(self, scope: Scope, receive: Receive, send: Send) -> None:
+        path = scope.get("path", "")
+        method = scope.get("method", "")
+        if scope["type"] != "http" or method == "OPTIONS" or not path.startswith("/api") or path in PUBLIC_PATHS:
+            await self.app(scope, receive, send)
+            return
+        if path.startswith(PUBLIC_PREFIX) and method in ("GET", "HEAD"):
+            await self.app(scope, receive, send)
+            return
+
+        db = self._db_factory()
+        auth = AuthService(db=db)
+        if not await auth.enabled():
+            with acting_as(client_type(scope), "local"):
+                await self.app(scope, receive, send)
+            return
+
+        principal = await auth.authenticate(presented_token(scope))
+        if principal is None:
+            await db.record_auth_audit(None, None, method, path, 401)
+            await JSONResponse({"detail": "Authentication required"}, status_code=401)(scope, receive, send)
+            return
+
+        needed = required_role(method, path)
+        if not role_allows(principal["role"], needed):
+            await db.record_auth_audit(principal["name"], principal["role"], method, path, 403)
+            detail = f"Role '{principal['role']}' cannot access this endpoint (requires {needed})"
+            await JSONResponse({"detail": detail}, status_code=403)(scope, receive, send)
+            return
+
+        scope.setdefault("state", {})["principal"] = principal
+        actor = acting_as(client_type(scope), principal["name"])
+        if method not in MUTATING_METHODS:
+            with actor:
+                await self.app(scope, receive, send)
+            return
+
+        status_code = 500
+
+        async def capture_status(message: Message) -> None:
+            nonlocal status_code
+            if message["type"] == "http.response.start":
+                status_code = message["status"]
+            await send(message)
+
+        try:
+            with actor:
+                await self.app(scope, receive, capture_status)
+        finally:
+            await db.record_auth_audit(principal["name"], principal["role"], method, path, status_code)
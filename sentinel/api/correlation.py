@@ -0,0 +1,31 @@
+"""Correlation ID middleware.
+
+Runs each HTTP request under the caller's X-Correlation-ID (or a new ID) and
+echoes the ID in the response headers; see sentinel.correlation.
+"""
+
+from starlette.datastructures import Headers, MutableHeaders
+from starlette.types import ASGIApp, Message, Receive, Scope, Send
+
+from sentinel import correlation
+
+
+class CorrelationMiddleware:
+    """ASGI middleware binding a correlation ID to every request."""
+
+    def __init__(self, app: ASGIApp):
+        self.app = app
+
+    async def __call__(self, scope: Scope, receive: Receive, send: Send) -> None:
+        if scope["type"] != "http":
+            await self.app(scope, receive, send)
+            return
+
+        with correlation.bind(Headers(scope=scope).get(correlation.HEADER)) as correlation_id:
+
+            async def send_with_id(message: Message) -> None:
+                if message["type"] == "http.response.start":
+                    MutableHeaders(scope=message)[correlation.HEADER] = correlation_id
+                await send(message)
+
+            await self.app(scope, receive, send_with_id)
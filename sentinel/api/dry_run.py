@@ -0,0 +1,79 @@
+"""Dry-run middleware for mutating API calls.
+
+Any POST/PUT/PATCH/DELETE request with ?dry_run=true is executed inside a
+dry-run session: the handler runs normally, but database writes are rolled back
+and broker orders are not placed. The response wraps the handler's result with
+the would-be changes:
+
+    {
+        "dry_run": true,
+        "status_code": 200,
+        "result": {...},          # what the handler returned
+        "tables": {"settings": 1},
+        "changes": [...],         # one entry per write statement
+        "side_effects": [...],    # e.g. orders that would have been placed
+    }
+"""
+
+import json
+from typing import Callable
+from urllib.parse import parse_qs
+
+from fastapi.encoders import jsonable_encoder
+from starlette.responses import JSONResponse
+from starlette.types import ASGIApp, Message, Receive, Scope, Send
+
+from sentinel.database import Database
+from sentinel.dry_run import dry_run
+
+MUTATING_METHODS = frozenset({"POST", "PUT", "PATCH", "DELETE"})
+_TRUE_VALUES = frozenset({"1", "true", "yes", "on"})
+
+
+def dry_run_requested(scope: Scope) -> bool:
+    """Whether the request is a mutating call with ?dry_run=true."""
+    if scope["type"] != "http" or scope.get("method") not in MUTATING_METHODS:
+        return False
+    values = parse_qs(scope.get("query_string", b"").decode("latin-1")).get("dry_run")
+    return bool(values) and values[-1].strip().lower() in _TRUE_VALUES
+
+
+class DryRunMiddleware:
+    """ASGI middleware that previews mutating requests without committing them."""
+
+    def __init__(self, app: ASGIApp, db_factory: Callable[[], Database] = Database):
+        self.app = app
+        self._db_factory = db_factory
+
+    async def __call__(self, scope: Scope, receive: Receive, send: Send) -> None:
+        if not dry_run_requested(scope):
+            await self.app(scope, receive, send)
+            return
+
+        status_code = 500
+        content_type = ""
+        body = bytearray()
+
+        async def capture(message: Message) -> None:
+            nonlocal status_code, content_type
+            if message["type"] == "http.response.start":
+                status_code = message["status"]
+                for name, value in message.get("headers", []):
+                    if name.lower() == b"content-type":
+                        content_type = value.decode("latin-1")
+            elif message["type"] == "http.response.body":
+                body.extend(message.get("body", b""))
+
+        async with dry_run(self._db_factory()) as session:
+            await self.app(scope, receive, capture)
+
+        result = body.decode("utf-8", errors="replace")
+        if content_type.startswith("application/json") and body:
+            try:
+                result = json.loads(result)
+            except json.JSONDecodeError:
+                pass
+
+        payload = {"dry_run": True, "status_code": status_code, "result": result, **session.summary()}
+        response = JSONResponse(jsonable_encoder(payload), status_code=status_code)
+        await response(scope, receive, send)
@@ -0,0 +1,30 @@
+"""HTTP responses for domain errors.
+
+A SentinelError raised by a route (or anything it calls) becomes a response
+with the error class's status code - 400 for invalid input, 404 for missing
+entities, 409 for conflicts, 429/503 for rate-limited or unreachable
+dependencies, 502 for bad upstream answers - and a body keeping the usual
+"detail" message next to the structured error:
+
+    {
+        "detail": "Reconciliation 7 not found",
+        "error": {"code": "not_found", "category": "user", "message": "...", "context": {"id": 7}}
+    }
+"""
+
+from fastapi.encoders import jsonable_encoder
+from starlette.requests import Request
+from starlette.responses import JSONResponse
+
+from sentinel.errors import RateLimited, SentinelError
+
+
+async def sentinel_error_handler(request: Request, exc: Exception) -> JSONResponse:
+    """Exception handler turning a SentinelError into its HTTP response."""
+    assert isinstance(exc, SentinelError)
+    headers = {"Retry-After": "60"} if isinstance(exc, RateLimited) else None
+    return JSONResponse(
+        status_code=exc.status,
+        content=jsonable_encoder({"detail": exc.message, "error": exc.as_dict()}),
+        headers=headers,
+    )
@@ -3,12 +3,40 @@
 Each router handles a specific domain of the API.
 """
 
+from sentinel.api.routers.analytics import router as analytics_router
+from sentinel.api.routers.approvals import router as approvals_router
+from sentinel.api.routers.archive import router as archive_router
+from sentinel.api.routers.audit import router as audit_router
+from sentinel.api.routers.auth import router as auth_router
 from sentinel.api.routers.backup import router as backup_router
+from sentinel.api.routers.charts import router as charts_router
+from sentinel.api.routers.computed import router as computed_router
+from sentinel.api.routers.config import router as config_router
+from sentinel.api.routers.correlations import router as correlations_router
+from sentinel.api.routers.documents import corporate_actions_router
+from sentinel.api.routers.documents import router as documents_router
+from sentinel.api.routers.exports import router as exports_router
+from sentinel.api.routers.external_holdings import router as external_holdings_router
+from sentinel.api.routers.instruments import router as instruments_router
 from sentinel.api.routers.jobs import router as jobs_router
 from sentinel.api.routers.jobs import set_scheduler
+from sentinel.api.routers.journal import router as journal_router
+from sentinel.api.routers.lite import router as lite_router
+from sentinel.api.routers.logs import router as logs_router
+from sentinel.api.routers.metadata import router as metadata_router
+from sentinel.api.routers.news import router as news_router
+from sentinel.api.routers.notifications import router as notifications_router
 from sentinel.api.routers.planner import router as planner_router
 from sentinel.api.routers.portfolio import allocation_router, targets_router
 from sentinel.api.routers.portfolio import router as portfolio_router
+from sentinel.api.routers.profiling import router as profiling_router
+from sentinel.api.routers.public import router as public_router
+from sentinel.api.routers.reconciliation import router as reconciliation_router
+from sentinel.api.routers.regime import router as regime_router
+from sentinel.api.routers.reports import router as reports_router
+from sentinel.api.routers.risk import router as risk_router
+from sentinel.api.routers.scores import router as scores_router
+from sentinel.api.routers.secrets import router as secrets_router
 from sentinel.api.routers.securities import prices_router, unified_router
 from sentinel.api.routers.securities import router as securities_router
 from sentinel.api.routers.settings import led_router
@@ -24,25 +52,72 @@ from sentinel.api.routers.system import (
 from sentinel.api.routers.system import (
     router as system_router,
 )
-from sentinel.api.routers.trading import cashflows_router, trading_actions_router
+from sentinel.api.routers.shadow import router as shadow_router
+from sentinel.api.routers.telemetry import router as telemetry_router
+from sentinel.api.routers.trading import (
+    cashflows_router,
+    dividends_router,
+    protective_orders_router,
+    scheduled_orders_router,
+    sliced_orders_router,
+    trading_actions_router,
+)
 from sentinel.api.routers.trading import router as trading_router
+from sentinel.api.routers.watchlist import router as watchlist_router
+from sentinel.api.routers.webhooks import router as webhooks_router
 
 __all__ = [
     "settings_router",
     "led_router",
     "portfolio_router",
+    "external_holdings_router",
+    "exports_router",
+    "charts_router",
+    "computed_router",
     "allocation_router",
     "targets_router",
     "securities_router",
     "prices_router",
+    "watchlist_router",
+    "metadata_router",
+    "instruments_router",
+    "scores_router",
+    "shadow_router",
+    "journal_router",
+    "news_router",
     "unified_router",
     "trading_router",
     "cashflows_router",
+    "dividends_router",
     "trading_actions_router",
+    "scheduled_orders_router",
+    "protective_orders_router",
+    "sliced_orders_router",
     "planner_router",
+    "analytics_router",
+    "approvals_router",
     "jobs_router",
     "set_scheduler",
     "backup_router",
+    "archive_router",
+    "audit_router",
+    "auth_router",
+    "secrets_router",
+    "notifications_router",
+    "webhooks_router",
+    "regime_router",
+    "reports_router",
+    "reconciliation_router",
+    "risk_router",
+    "config_router",
+    "correlations_router",
+    "documents_router",
+    "corporate_actions_router",
+    "lite_router",
+    "logs_router",
+    "public_router",
+    "telemetry_router",
+    "profiling_router",
     "system_router",
     "cache_router",
     "backtest_router",
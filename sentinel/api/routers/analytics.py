@@ -0,0 +1,30 @@
+"""Analytics routes: execution quality of submitted orders."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.execution_quality import ExecutionQualityService
+
+router = APIRouter(prefix="/analytics", tags=["analytics"])
+
+
+@router.get("/execution-quality")
+async def get_execution_quality(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: Optional[int] = None,
+) -> dict:
+    """Slippage of fills vs recommendation and submission quote, per symbol, market and hour of day."""
+    return await ExecutionQualityService(db=deps.db, settings=deps.settings).report(days)
+
+
+@router.post("/execution-quality/calibrate")
+async def calibrate_limit_band(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    apply: bool = True,
+    days: Optional[int] = None,
+) -> dict:
+    """Set limit_depth_max_slippage_pct from recent fills now (apply=false only previews it)."""
+    return await ExecutionQualityService(db=deps.db, settings=deps.settings).calibrate(apply=apply, days=days)
@@ -0,0 +1,39 @@
+"""Recommendation approval inbox routes (manual approval mode)."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.approvals import ApprovalService
+
+router = APIRouter(prefix="/approvals", tags=["approvals"])
+
+
+@router.get("")
+async def get_approval_inbox(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """
+    Current recommendations with estimated cost, projected allocation and decision status.
+
+    Pending items come first. manual_approval tells whether live execution is
+    gated on these decisions.
+    """
+    return await ApprovalService(db=deps.db, settings=deps.settings, currency=deps.currency).inbox()
+
+
+@router.post("/{symbol}/{action}/{decision}")
+async def decide_recommendation(
+    symbol: str,
+    action: str,
+    decision: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Approve, reject or defer the current buy/sell recommendation for a symbol."""
+    if action not in ("buy", "sell"):
+        raise HTTPException(status_code=400, detail="action must be buy or sell")
+    service = ApprovalService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    try:
+        return await service.decide(symbol, action, decision)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
@@ -0,0 +1,90 @@
+"""Position and price history archive API routes."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.archive import ArchiveService
+from sentinel.services.retention import RetentionService
+
+router = APIRouter(prefix="/archive", tags=["archive"])
+
+
+@router.get("/positions")
+async def get_archived_positions(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbol: Optional[str] = None,
+    limit: int = 50,
+    offset: int = 0,
+) -> dict:
+    """
+    Browse archived (closed) round trips, most recently closed first.
+
+    Returns:
+        positions: Archived round-trip summaries
+        count: Number of positions in this response
+        total: Total number of archived positions matching filters
+    """
+    return await ArchiveService(db=deps.db).list_archived(symbol=symbol, limit=limit, offset=offset)
+
+
+@router.get("/positions/{archive_id}")
+async def get_archived_position(
+    archive_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Get one archived round trip with its trades and trade decisions."""
+    position = await ArchiveService(db=deps.db).get_archived(archive_id)
+    if not position:
+        raise HTTPException(status_code=404, detail="Archived position not found")
+    return position
+
+
+@router.post("/run")
+async def run_archive() -> dict:
+    """Trigger the closed-position archival job now."""
+    from sentinel.jobs import run_now
+
+    return await run_now("archive:positions")
+
+
+@router.get("/prices")
+async def get_price_tiers(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Bars and date range in the hot (main database) and cold (external storage) price tiers."""
+    return await deps.db.get_price_tier_stats()
+
+
+@router.post("/prices/run")
+async def run_price_tiering() -> dict:
+    """Trigger the price tiering job now (re-attaches the cold storage first)."""
+    from sentinel.jobs import run_now
+
+    return await run_now("archive:prices")
+
+
+@router.get("/retention")
+async def get_retention_status(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Retention policies with the last prune and vacuum reports (sizes before/after per database)."""
+    return await RetentionService(db=deps.db, settings=deps.settings).status()
+
+
+@router.post("/retention/run")
+async def run_retention() -> dict:
+    """Trigger the retention pruning job now."""
+    from sentinel.jobs import run_now
+
+    return await run_now("archive:retention")
+
+
+@router.post("/vacuum/run")
+async def run_vacuum() -> dict:
+    """Trigger the database vacuum job now."""
+    from sentinel.jobs import run_now
+
+    return await run_now("archive:vacuum")
@@ -0,0 +1,51 @@
+"""Audit log routes: who placed orders, changed settings or overrode the system, and how it ended."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.audit import ACTOR_TYPES, OUTCOMES
+from sentinel.errors import InvalidInput
+
+router = APIRouter(prefix="/audit", tags=["audit"])
+
+
+@router.get("")
+async def get_audit_log(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    actor_type: Optional[str] = None,
+    actor: Optional[str] = None,
+    action: Optional[str] = None,
+    target: Optional[str] = None,
+    outcome: Optional[str] = None,
+    start_ts: Optional[int] = None,
+    end_ts: Optional[int] = None,
+    limit: int = 100,
+    offset: int = 0,
+) -> dict:
+    """
+    Audit log entries, most recent first.
+
+    actor_type is api, tui, job or system; actor a token or user name, or a job
+    type; action an action (order.buy) or a group of them (order); target a
+    symbol, setting key, job type or scheduled order id; outcome success,
+    simulated, failed or rejected; start_ts/end_ts bound created_at (unix seconds).
+    """
+    if actor_type and actor_type not in ACTOR_TYPES:
+        raise InvalidInput(f"actor_type must be one of {', '.join(ACTOR_TYPES)}")
+    if outcome and outcome not in OUTCOMES:
+        raise InvalidInput(f"outcome must be one of {', '.join(OUTCOMES)}")
+    entries = await deps.db.get_audit_log(
+        actor_type=actor_type,
+        actor=actor,
+        action=action,
+        target=target,
+        outcome=outcome,
+        start_ts=start_ts,
+        end_ts=end_ts,
+        limit=max(0, min(limit, 1000)),
+        offset=max(0, offset),
+    )
+    return {"entries": entries}
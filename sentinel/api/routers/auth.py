@@ -0,0 +1,123 @@
+"""Authentication API routes: login, current principal, token and user management."""
+
+from fastapi import APIRouter, Depends, HTTPException, Request
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.auth import AuthService
+
+router = APIRouter(prefix="/auth", tags=["auth"])
+
+
+@router.post("/login")
+async def login(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Exchange a local username/password for a session token."""
+    username = str(data.get("username") or "")
+    session = await AuthService(db=deps.db, settings=deps.settings).login(username, str(data.get("password") or ""))
+    if session is None:
+        await deps.db.record_auth_audit(username or None, None, "POST", "/api/auth/login", 401)
+        raise HTTPException(status_code=401, detail="Invalid username or password")
+    await deps.db.record_auth_audit(username, session["role"], "POST", "/api/auth/login", 200)
+    return session
+
+
+@router.get("/me")
+async def get_current_principal(
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Describe the caller: whether auth is enabled and, if so, its name and role."""
+    enabled = await AuthService(db=deps.db, settings=deps.settings).enabled()
+    principal = getattr(request.state, "principal", None)
+    return {
+        "auth_enabled": enabled,
+        "name": principal["name"] if principal else None,
+        "role": principal["role"] if principal else ("admin" if not enabled else None),
+    }
+
+
+@router.get("/tokens")
+async def get_tokens(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    include_revoked: bool = False,
+) -> dict:
+    """List API tokens and login sessions (token values are never returned)."""
+    return {"tokens": await deps.db.get_api_tokens(include_revoked=include_revoked)}
+
+
+@router.post("/tokens")
+async def create_token(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """
+    Create an API token.
+
+    Body: {"name": str, "role": "viewer" | "operator" | "admin"}. The token value
+    is only included in this response.
+    """
+    try:
+        return await AuthService(db=deps.db, settings=deps.settings).create_token(
+            str(data.get("name") or ""), str(data.get("role") or "viewer")
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.delete("/tokens/{token_id}")
+async def revoke_token(
+    token_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Revoke an API token or login session."""
+    if not await deps.db.revoke_api_token(token_id):
+        raise HTTPException(status_code=404, detail="Token not found")
+    return {"status": "ok"}
+
+
+@router.get("/users")
+async def get_users(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """List local users."""
+    return {"users": await deps.db.get_auth_users()}
+
+
+@router.put("/users/{username}")
+async def set_user(
+    username: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Create or update a local user. Body: {"password": str, "role": str}."""
+    try:
+        await AuthService(db=deps.db, settings=deps.settings).set_user(
+            username, str(data.get("password") or ""), str(data.get("role") or "viewer")
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"status": "ok"}
+
+
+@router.delete("/users/{username}")
+async def delete_user(
+    username: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Delete a local user and revoke its sessions."""
+    if not await deps.db.delete_auth_user(username):
+        raise HTTPException(status_code=404, detail="User not found")
+    return {"status": "ok"}
+
+
+@router.get("/audit")
+async def get_audit(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 100,
+    offset: int = 0,
+) -> dict:
+    """Audit trail of authenticated mutations, denied requests and logins."""
+    return {"entries": await deps.db.get_auth_audit(limit=limit, offset=offset)}
@@ -4,6 +4,7 @@ from fastapi import APIRouter, Depends
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.vault import Vault
 
 router = APIRouter(prefix="/backup", tags=["backup"])
 
@@ -18,8 +19,9 @@ async def run_backup() -> dict:
 @router.get("/status")
 async def get_backup_status(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
     account_id = await deps.settings.get("r2_account_id", "")
-    access_key = await deps.settings.get("r2_access_key", "")
-    secret_key = await deps.settings.get("r2_secret_key", "")
+    vault = Vault(db=deps.db, settings=deps.settings)
+    access_key = await vault.get_secret("r2_access_key")
+    secret_key = await vault.get_secret("r2_secret_key")
     bucket_name = await deps.settings.get("r2_bucket_name", "")
 
     if not all([account_id, access_key, secret_key, bucket_name]):
@@ -0,0 +1,48 @@
+"""Chart routes: history series downsampled to a fixed number of time buckets."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.charts import ChartService
+
+router = APIRouter(prefix="/charts", tags=["charts"])
+
+
+@router.get("/portfolio")
+async def get_portfolio_chart(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: int = 365,
+    points: int = 120,
+) -> dict:
+    """Portfolio value (EUR) over the last `days`, reduced to at most `points` buckets."""
+    try:
+        series = await ChartService(db=deps.db).portfolio_nav(days, points)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"series": "nav", "days": days, "points": series}
+
+
+@router.get("/securities/{symbol}/{series}")
+async def get_security_chart(
+    symbol: str,
+    series: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: int = 365,
+    points: int = 120,
+) -> dict:
+    """
+    Daily close (series=price) or contrarian score (series=score) of a security
+    over the last `days` prices, reduced to at most `points` buckets.
+    """
+    service = ChartService(db=deps.db)
+    builders = {"price": service.price, "score": service.score}
+    if series not in builders:
+        raise HTTPException(status_code=404, detail=f"Unknown series: {series}")
+    try:
+        values = await builders[series](symbol, days, points)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"symbol": symbol, "series": series, "days": days, "points": values}
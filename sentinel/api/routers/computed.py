@@ -0,0 +1,50 @@
+"""Computed column routes: user-defined expressions over security metrics."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.computed_columns import ComputedColumnService
+from sentinel.utils.expressions import FUNCTIONS
+
+router = APIRouter(prefix="/computed-columns", tags=["computed-columns"])
+
+
+@router.get("")
+async def get_computed_columns(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """
+    List computed columns.
+
+    Returns:
+        columns: Definitions with the metric names they reference (and an error if one no longer compiles)
+        functions: Functions available in expressions
+    """
+    return {
+        "columns": await ComputedColumnService(db=deps.db).list_columns(),
+        "functions": list(FUNCTIONS),
+    }
+
+
+@router.put("/{name}")
+async def put_computed_column(
+    name: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """
+    Create or replace a computed column.
+
+    Body: {"expression": "dividendYield + cagr_raw", "description": optional}
+    """
+    try:
+        return await ComputedColumnService(db=deps.db).define(name, data.get("expression", ""), data.get("description"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.delete("/{name}")
+async def delete_computed_column(name: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Delete a computed column."""
+    if not await ComputedColumnService(db=deps.db).delete(name):
+        raise HTTPException(status_code=404, detail="Computed column not found")
+    return {"status": "ok"}
@@ -0,0 +1,32 @@
+"""Configuration drift routes: this device's bundle and the comparison with its peer."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.config_drift import ConfigDriftService
+
+router = APIRouter(prefix="/config", tags=["config"])
+
+
+@router.get("/bundle")
+async def get_config_bundle(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Configuration bundle (settings without secrets or per-device keys, job schedules) and its hash."""
+    return await ConfigDriftService(db=deps.db, settings=deps.settings).bundle()
+
+
+@router.get("/drift")
+async def get_config_drift(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Result of the last scheduled drift check against the peer."""
+    return await ConfigDriftService(db=deps.db, settings=deps.settings).status()
+
+
+@router.get("/drift/diff")
+async def get_config_drift_diff(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Fetch the peer's bundle now and list every setting and schedule that differs."""
+    try:
+        return await ConfigDriftService(db=deps.db, settings=deps.settings).diff()
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except Exception as e:
+        raise HTTPException(status_code=502, detail=f"Peer unavailable: {e}") from e
@@ -0,0 +1,40 @@
+"""Correlation routes: the stored pairwise matrix for the heatmap and its recomputation."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.correlations import CorrelationService
+from sentinel.utils.strings import parse_csv_field
+
+router = APIRouter(prefix="/correlations", tags=["correlations"])
+
+
+@router.get("")
+async def get_correlations(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbols: Optional[str] = None,
+) -> dict:
+    """Correlation matrix with the portfolio diversification score, optionally for comma-separated symbols."""
+    service = CorrelationService(db=deps.db, settings=deps.settings)
+    return await service.matrix(parse_csv_field(symbols) if symbols else None)
+
+
+@router.get("/diversification")
+async def get_diversification(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Weighted average correlation of the holdings and the diversification score derived from it."""
+    return await CorrelationService(db=deps.db, settings=deps.settings).diversification()
+
+
+@router.post("/compute")
+async def compute_correlations(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    window_days: Optional[int] = None,
+) -> dict:
+    """Recompute the matrix now instead of waiting for the scheduled job (window defaults to the setting)."""
+    try:
+        return await CorrelationService(db=deps.db, settings=deps.settings).compute(window_days)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
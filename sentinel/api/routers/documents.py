@@ -0,0 +1,106 @@
+"""Broker document and corporate action election routes."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from fastapi.responses import FileResponse
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.broker_documents import BrokerDocumentService
+
+router = APIRouter(prefix="/documents", tags=["documents"])
+corporate_actions_router = APIRouter(prefix="/corporate-actions", tags=["corporate-actions"])
+
+
+def _service(deps: CommonDependencies) -> BrokerDocumentService:
+    return BrokerDocumentService(db=deps.db, broker=deps.broker)
+
+
+@router.get("")
+async def get_documents(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    kind: Optional[str] = None,
+    entity_type: Optional[str] = None,
+    entity_id: Optional[str] = None,
+) -> dict:
+    """Stored documents, optionally of a kind (order_file, broker_report) or linked to a ledger entry."""
+    return {"documents": await _service(deps).list_documents(kind, entity_type, entity_id)}
+
+
+@router.get("/{document_id}")
+async def get_document(document_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Document metadata with the ledger entries it is linked to."""
+    try:
+        return await _service(deps).get_document(document_id)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.get("/{document_id}/file")
+async def download_document(
+    document_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]
+) -> FileResponse:
+    """Download the stored file."""
+    service = _service(deps)
+    try:
+        document = await service.get_document(document_id)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return FileResponse(
+        service.path_of(document),
+        media_type=document.get("mime") or "application/octet-stream",
+        filename=document["file_name"],
+    )
+
+
+@router.post("/orders/{order_id}")
+async def fetch_order_files(order_id: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Retrieve an order's files from the broker and link them to its trades."""
+    try:
+        return {"documents": await _service(deps).fetch_order_files(order_id)}
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.post("/broker-report")
+async def fetch_broker_report(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Retrieve the broker report for {start_date, end_date, format (default pdf)} and link it to the period."""
+    try:
+        return await _service(deps).fetch_broker_report(
+            data.get("start_date", ""), data.get("end_date", ""), data.get("format") or "pdf"
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@corporate_actions_router.get("/elections")
+async def get_elections(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    corporate_action_id: Optional[str] = None,
+) -> dict:
+    """Submitted elections, most recent first."""
+    return {"elections": await _service(deps).list_elections(corporate_action_id)}
+
+
+@corporate_actions_router.post("/{corporate_action_id}/elections")
+async def submit_election(
+    corporate_action_id: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Submit an election {option, quantity (optional, default whole holding), symbol (optional)}."""
+    quantity = data.get("quantity")
+    try:
+        return await _service(deps).submit_election(
+            corporate_action_id,
+            str(data.get("option") or ""),
+            float(quantity) if quantity is not None else None,
+            data.get("symbol"),
+        )
+    except (TypeError, ValueError) as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
@@ -0,0 +1,34 @@
+"""Export routes: trades, dividends, cash flows and portfolio snapshots as CSV, OFX or QIF downloads."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends
+from fastapi.responses import StreamingResponse
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.exports import ExportService
+
+router = APIRouter(prefix="/export", tags=["export"])
+
+
+@router.get("/{dataset}")
+async def export_dataset(
+    dataset: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    format: str = "csv",
+    start_date: Optional[str] = None,
+    end_date: Optional[str] = None,
+) -> StreamingResponse:
+    """
+    Download a dataset (trades, dividends, cash_flows or snapshots) over a date range.
+
+    format is csv, ofx or qif (snapshots: csv only); dates are YYYY-MM-DD, inclusive.
+    The file is generated while it is sent.
+    """
+    media_type, filename, stream = ExportService(db=deps.db).export(dataset, format, start_date, end_date)
+    return StreamingResponse(
+        stream,
+        media_type=media_type,
+        headers={"Content-Disposition": f'attachment; filename="{filename}"'},
+    )
@@ -0,0 +1,67 @@
+"""External holdings routes: assets held outside the broker account, their valuations and net worth."""
+
+from fastapi import APIRouter, Depends
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.external_holdings import ExternalHoldingService
+
+router = APIRouter(prefix="/external-holdings", tags=["external-holdings"])
+
+
+def _service(deps: CommonDependencies) -> ExternalHoldingService:
+    return ExternalHoldingService(db=deps.db, currency=deps.currency)
+
+
+@router.get("")
+async def get_external_holdings(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """External holdings with their latest value in EUR, largest first."""
+    return {"holdings": await _service(deps).list()}
+
+
+@router.get("/net-worth")
+async def get_net_worth(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Broker account value, external holdings and their sum, in EUR."""
+    return await _service(deps).net_worth()
+
+
+@router.post("")
+async def create_external_holding(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Register a holding (body: {"name", "kind", "currency", "value", "symbol", "geography", "industry", "note"})."""
+    return await _service(deps).create(data)
+
+
+@router.get("/{holding_id}")
+async def get_external_holding(holding_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """A holding with its valuation history."""
+    return await _service(deps).get(holding_id)
+
+
+@router.put("/{holding_id}")
+async def update_external_holding(
+    holding_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Change a holding's description; its value changes through valuations."""
+    return await _service(deps).update(holding_id, data)
+
+
+@router.post("/{holding_id}/valuations")
+async def add_valuation(
+    holding_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Record a valuation (body: {"value": ..., "source": "manual" or a feed name, "valued_at": unix seconds})."""
+    return await _service(deps).value(holding_id, data.get("value"), data.get("source"), data.get("valued_at"))
+
+
+@router.delete("/{holding_id}")
+async def delete_external_holding(
+    holding_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Remove a holding and its valuation history."""
+    await _service(deps).delete(holding_id)
+    return {"status": "ok"}
@@ -0,0 +1,58 @@
+"""Instrument ID routes: ISIN, Tradernet ticker and Yahoo symbol mappings and their collisions."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.instrument_ids import InstrumentIdService
+
+router = APIRouter(prefix="/instruments", tags=["instruments"])
+
+
+@router.get("/resolve")
+async def resolve_instrument(
+    id: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    scheme: Optional[str] = None,
+) -> dict:
+    """Current symbol for a symbol, ISIN, Yahoo symbol or former ticker."""
+    try:
+        symbol = await InstrumentIdService(db=deps.db).resolve(id, scheme)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if symbol is None:
+        raise HTTPException(status_code=404, detail=f"No single security matches {id}")
+    return {"id": id, "symbol": symbol}
+
+
+@router.get("/collisions")
+async def get_collisions(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """IDs currently mapped to more than one security."""
+    return {"collisions": await InstrumentIdService(db=deps.db).collisions()}
+
+
+@router.get("/{symbol}")
+async def get_instrument_ids(symbol: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Current IDs of a security per scheme, with retired IDs and ticker changes."""
+    try:
+        return await InstrumentIdService(db=deps.db).identifiers(symbol)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.put("/{symbol}/{scheme}")
+async def set_instrument_id(
+    symbol: str,
+    scheme: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Set a security's ISIN, Yahoo or Stooq symbol (body: {"value": ...}; empty clears it)."""
+    try:
+        return await InstrumentIdService(db=deps.db).set_id(symbol, scheme, data.get("value"))
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
@@ -7,7 +7,17 @@ from fastapi import APIRouter, Depends, HTTPException
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
-from sentinel.jobs import get_status, reschedule, run_now
+from sentinel.jobs import (
+    describe_job_params,
+    get_run,
+    get_status,
+    is_quarantined,
+    list_runs,
+    release_quarantine,
+    reschedule,
+    run_now,
+    start_run,
+)
 
 router = APIRouter(prefix="/jobs", tags=["jobs"])
 
@@ -44,6 +54,58 @@ async def run_job_endpoint(job_type: str) -> dict:
     return result
 
 
+@router.get("/runs")
+async def get_runs(job_type: Optional[str] = None) -> dict:
+    """Recent manual runs, newest first (without logs)."""
+    return {"runs": [run.as_dict() for run in list_runs(job_type)]}
+
+
+@router.get("/runs/{run_id}")
+async def get_run_endpoint(run_id: str, logs_since: int = 0) -> dict:
+    """Status, progress and log records (after logs_since) of a manual run."""
+    run = get_run(run_id)
+    if run is None:
+        raise HTTPException(status_code=404, detail=f"Unknown run: {run_id}")
+    return run.as_dict(logs_since=logs_since)
+
+
+@router.get("/{job_type:path}/params")
+async def get_job_params(job_type: str) -> dict:
+    """Parameters a manual run of the job accepts."""
+    return {"job_type": job_type, "params": describe_job_params(job_type)}
+
+
+@router.post("/{job_type:path}/runs")
+async def start_run_endpoint(job_type: str, data: Optional[dict] = None) -> dict:
+    """Start a job in the background with optional parameters and dry_run.
+
+    Body: {"params": {"symbols": ["AAPL.US"]}, "dry_run": true}. Returns the run
+    (with run_id) to poll at GET /api/jobs/runs/{run_id}.
+    """
+    data = data or {}
+    dry_run = data.get("dry_run", False)
+    if not isinstance(dry_run, bool):
+        raise HTTPException(status_code=400, detail="dry_run must be true or false")
+    try:
+        return start_run(job_type, data.get("params"), dry_run)
+    except KeyError:
+        raise HTTPException(status_code=404, detail=f"Unknown job type: {job_type}")
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+
+
+@router.post("/{job_type:path}/release")
+async def release_job_endpoint(
+    job_type: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Lift a job's quarantine so it runs on its schedule again."""
+    if not await deps.db.get_job_schedule(job_type):
+        raise HTTPException(status_code=404, detail=f"Unknown job type: {job_type}")
+    await release_quarantine(job_type)
+    return {"status": "ok"}
+
+
 @router.post("/refresh-all")
 async def refresh_all(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -81,9 +143,11 @@ async def get_job_schedules(
         if history:
             last_run = datetime.fromtimestamp(history[0]["executed_at"]).isoformat()
             last_status = history[0]["status"]
+            last_failure_class = history[0].get("failure_class")
         else:
             last_run = None
             last_status = None
+            last_failure_class = None
 
         result.append(
             {
@@ -96,6 +160,9 @@ async def get_job_schedules(
                 "category": s.get("category"),
                 "last_run": last_run,
                 "last_status": last_status,
+                "last_failure_class": last_failure_class,
+                "quarantined": is_quarantined(job_type),
+                "quarantine_reason": s.get("quarantine_reason"),
                 "next_run": next_run_times.get(job_type),
             }
         )
@@ -0,0 +1,117 @@
+"""Trade journal routes: notes, tags and conviction ratings on trades and recommendations."""
+
+from datetime import datetime, timedelta
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Request
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.journal import JournalService
+
+router = APIRouter(prefix="/journal", tags=["journal"])
+
+
+def _principal_name(request: Request) -> str:
+    principal = getattr(request.state, "principal", None)
+    return principal["name"] if principal else "local"
+
+
+def _day_start(value: str) -> int:
+    try:
+        return int(datetime.strptime(value, "%Y-%m-%d").timestamp())
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=f"Invalid date {value} (expected YYYY-MM-DD)") from e
+
+
+@router.get("")
+async def search_journal(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    q: Optional[str] = None,
+    symbol: Optional[str] = None,
+    tag: Optional[str] = None,
+    kind: Optional[str] = None,
+    trade_id: Optional[int] = None,
+    min_conviction: Optional[int] = None,
+    start_date: Optional[str] = None,
+    end_date: Optional[str] = None,
+    limit: int = 100,
+) -> dict:
+    """Search the journal (q matches note text; dates are YYYY-MM-DD, inclusive)."""
+    try:
+        entries = await JournalService(db=deps.db).search(
+            kind=kind,
+            tag=tag,
+            text=q,
+            symbol=symbol,
+            trade_id=trade_id,
+            min_conviction=min_conviction,
+            start_ts=_day_start(start_date) if start_date else None,
+            end_ts=_day_start(end_date) + int(timedelta(days=1).total_seconds()) - 1 if end_date else None,
+            limit=limit,
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"entries": entries}
+
+
+@router.post("/trades/{trade_id}")
+async def annotate_trade(
+    trade_id: int,
+    data: dict,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Annotate an executed trade (body: {"note": ..., "tags": [...], "conviction": 1-5})."""
+    try:
+        return await JournalService(db=deps.db).annotate_trade(
+            trade_id, data.get("note"), data.get("tags"), data.get("conviction"), _principal_name(request)
+        )
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/recommendations/{symbol}/{action}")
+async def annotate_recommendation(
+    symbol: str,
+    action: str,
+    data: dict,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Annotate a current recommendation (body: {"note": ..., "tags": [...], "conviction": 1-5})."""
+    try:
+        return await JournalService(db=deps.db).annotate_recommendation(
+            symbol, action, data.get("note"), data.get("tags"), data.get("conviction"), _principal_name(request)
+        )
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.put("/{entry_id}")
+async def update_entry(
+    entry_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Change an entry's note, tags or conviction."""
+    try:
+        return await JournalService(db=deps.db).update(entry_id, data)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.delete("/{entry_id}")
+async def delete_entry(entry_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Delete an entry."""
+    try:
+        await JournalService(db=deps.db).delete(entry_id)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"status": "ok"}
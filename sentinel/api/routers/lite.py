@@ -0,0 +1,61 @@
+"""Slim read-model routes for mobile and e-paper clients.
+
+Responses are compact JSON with an ETag; clients that send it back in
+If-None-Match get an empty 304 while nothing has changed.
+"""
+
+import hashlib
+import json
+
+from fastapi import APIRouter, Depends, Request, Response
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.lite import LITE_ITEMS, LiteService
+
+router = APIRouter(prefix="/lite", tags=["lite"])
+
+
+def _compact(request: Request, payload: dict) -> Response:
+    """Minified JSON, or 304 when the client already has this body."""
+    body = json.dumps(payload, separators=(",", ":"), ensure_ascii=False).encode()
+    etag = '"' + hashlib.sha1(body, usedforsecurity=False).hexdigest()[:16] + '"'
+    if request.headers.get("if-none-match") == etag:
+        return Response(status_code=304, headers={"ETag": etag})
+    return Response(body, media_type="application/json", headers={"ETag": etag, "Cache-Control": "no-cache"})
+
+
+def _service(deps: CommonDependencies) -> LiteService:
+    return LiteService(db=deps.db, settings=deps.settings, currency=deps.currency)
+
+
+@router.get("")
+async def get_lite_screen(request: Request, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> Response:
+    """Summary, alerts and pending trades in one response."""
+    return _compact(request, await _service(deps).screen())
+
+
+@router.get("/summary")
+async def get_lite_summary(request: Request, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> Response:
+    """Portfolio value, cash, day change, return and trading mode."""
+    return _compact(request, await _service(deps).summary())
+
+
+@router.get("/alerts")
+async def get_lite_alerts(
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = LITE_ITEMS,
+) -> Response:
+    """Unread notification count and the latest unread alerts."""
+    return _compact(request, await _service(deps).alerts(limit=min(limit, 20)))
+
+
+@router.get("/pending")
+async def get_lite_pending(
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = LITE_ITEMS,
+) -> Response:
+    """Trade recommendations waiting to be executed."""
+    return _compact(request, await _service(deps).pending(limit=min(limit, 20)))
@@ -0,0 +1,94 @@
+"""Server log routes: recent entries and a live SSE tail."""
+
+import asyncio
+import json
+
+from fastapi import APIRouter, HTTPException, Request
+from fastapi.responses import StreamingResponse
+
+from sentinel.services.logs import get_log_buffer, level_number, matches
+
+router = APIRouter(prefix="/logs", tags=["logs"])
+
+# Seconds between keepalive comments on an idle stream
+STREAM_KEEPALIVE_SECONDS = 15
+
+
+def _level(level: str | None) -> int:
+    try:
+        return level_number(level)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+def _public(entry: dict) -> dict:
+    return {k: v for k, v in entry.items() if k != "levelno"}
+
+
+@router.get("")
+async def get_logs(
+    level: str | None = None,
+    module: str | None = None,
+    limit: int = 100,
+    after: int = 0,
+    correlation_id: str | None = None,
+) -> dict:
+    """
+    Recent log entries, oldest first.
+
+    level is the minimum level (DEBUG, INFO, WARNING, ERROR, CRITICAL); module
+    matches a logger name and its children (e.g. sentinel.jobs); after only
+    returns entries with a higher id; correlation_id only returns entries logged
+    while handling that request or job run (the X-Correlation-ID response header).
+    """
+    entries = get_log_buffer().recent(
+        _level(level), module or None, limit=max(0, min(limit, 1000)), after=after, correlation_id=correlation_id
+    )
+    return {"entries": [_public(e) for e in entries]}
+
+
+@router.get("/stream")
+async def stream_logs(
+    request: Request,
+    level: str | None = None,
+    module: str | None = None,
+    backlog: int = 100,
+    correlation_id: str | None = None,
+) -> StreamingResponse:
+    """
+    Tail server logs as Server-Sent Events.
+
+    Sends up to `backlog` recent matching entries, then each new one, as
+    `log` events. Filters are the same as GET /logs.
+    """
+    min_level = _level(level)
+    module = module or None
+    buffer = get_log_buffer()
+
+    async def event_generator():
+        queue = buffer.subscribe()
+        try:
+            for entry in buffer.recent(
+                min_level, module, limit=max(0, min(backlog, 1000)), correlation_id=correlation_id
+            ):
+                yield f"event: log\ndata: {json.dumps(_public(entry))}\n\n"
+            while not await request.is_disconnected():
+                try:
+                    entry = await asyncio.wait_for(queue.get(), timeout=STREAM_KEEPALIVE_SECONDS)
+                except asyncio.TimeoutError:
+                    yield ": keepalive\n\n"
+                    continue
+                if matches(entry, min_level, module, correlation_id):
+                    yield f"event: log\ndata: {json.dumps(_public(entry))}\n\n"
+        finally:
+            buffer.unsubscribe(queue)
+
+    return StreamingResponse(
+        event_generator(),
+        media_type="text/event-stream",
+        headers={
+            "Cache-Control": "no-cache",
+            "Connection": "keep-alive",
+            "X-Accel-Buffering": "no",
+        },
+    )
@@ -0,0 +1,67 @@
+"""Security metadata enrichment routes: provenance, user overrides and the conflict review queue."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Request
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.metadata_enrichment import MetadataEnrichmentService
+
+router = APIRouter(prefix="/metadata", tags=["metadata"])
+
+
+def _service(deps: CommonDependencies) -> MetadataEnrichmentService:
+    return MetadataEnrichmentService(db=deps.db, settings=deps.settings)
+
+
+@router.post("/enrich")
+async def enrich_metadata(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Fetch and apply metadata from every provider now (body: {"symbols": [...]} optional)."""
+    return await _service(deps).enrich(data.get("symbols"))
+
+
+@router.post("/overrides")
+async def import_overrides(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Import user overrides (body: {"csv": "symbol,industry,country,exchange\\n..."})."""
+    try:
+        return await _service(deps).import_overrides(str(data.get("csv", "")))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.get("/reviews")
+async def get_reviews(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: Optional[str] = "open",
+    limit: int = 100,
+) -> dict:
+    """Conflicting provider values, most recent first (status: open, resolved or empty for all)."""
+    return {"reviews": await _service(deps).reviews(status or None, limit)}
+
+
+@router.post("/reviews/{review_id}/resolve")
+async def resolve_review(
+    review_id: int,
+    data: dict,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Settle a conflict (body: {"value": ...}); the value becomes a user override."""
+    principal = getattr(request.state, "principal", None)
+    resolved_by = principal["name"] if principal else "local"
+    try:
+        return await _service(deps).resolve_review(review_id, str(data.get("value") or ""), resolved_by)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.get("/{symbol}")
+async def get_metadata(symbol: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Industry, country and exchange of a security with their provenance and every provider's value."""
+    try:
+        return await _service(deps).report(symbol)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
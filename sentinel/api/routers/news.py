@@ -0,0 +1,27 @@
+"""News routes: classified headlines and the news_risk tag of held positions."""
+
+from fastapi import APIRouter, Depends
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.news import NewsService
+
+router = APIRouter(prefix="/news", tags=["news"])
+
+
+@router.get("")
+async def get_position_news(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> list[dict]:
+    """Sentiment summary and news_risk tag of every held position."""
+    return await NewsService(db=deps.db, broker=deps.broker, settings=deps.settings).positions()
+
+
+@router.post("/refresh")
+async def refresh_news(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Fetch and classify headlines of held positions now."""
+    return await NewsService(db=deps.db, broker=deps.broker, settings=deps.settings).refresh()
+
+
+@router.get("/{symbol}")
+async def get_symbol_news(symbol: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Classified headlines of one symbol with their sentiment summary."""
+    return await NewsService(db=deps.db, broker=deps.broker, settings=deps.settings).for_symbol(symbol)
@@ -0,0 +1,76 @@
+"""Notification inbox API routes."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.mail import MailService
+from sentinel.services.notifications import NotificationService
+
+router = APIRouter(prefix="/notifications", tags=["notifications"])
+
+
+@router.get("")
+async def get_notifications(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    unread_only: bool = False,
+    severity: Optional[str] = None,
+    limit: int = 50,
+    offset: int = 0,
+) -> dict:
+    """
+    List inbox notifications, most recent first.
+
+    Returns:
+        notifications: Notifications (severity, title, message, link, read_at, ...)
+        count: Number of notifications in this response
+        unread_count: Total number of unacknowledged notifications
+    """
+    try:
+        return await NotificationService(db=deps.db).list(
+            unread_only=unread_only, severity=severity, limit=limit, offset=offset
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/email/test")
+async def send_test_email(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict | None = None,
+) -> dict:
+    """Send a test email through the configured SMTP server now. Body: {"to": [...]} (default: report recipients)."""
+    delivery = await MailService(db=deps.db, settings=deps.settings).send_test((data or {}).get("to"))
+    return {"status": "delivered" if delivery["status"] == "sent" else "failed", "error": delivery["error"]}
+
+
+@router.get("/email/deliveries")
+async def get_email_deliveries(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    kind: Optional[str] = None,
+    limit: int = 50,
+) -> dict:
+    """Report emails sent or attempted, most recent first."""
+    return {"deliveries": await deps.db.get_email_deliveries(kind=kind, limit=limit)}
+
+
+@router.post("/read-all")
+async def acknowledge_all_notifications(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Acknowledge every unread notification."""
+    count = await NotificationService(db=deps.db).acknowledge_all()
+    return {"status": "ok", "acknowledged": count}
+
+
+@router.post("/{notification_id}/read")
+async def acknowledge_notification(
+    notification_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Acknowledge one notification."""
+    if not await NotificationService(db=deps.db).acknowledge(notification_id):
+        raise HTTPException(status_code=404, detail="Notification not found")
+    return {"status": "ok"}
@@ -1,13 +1,21 @@
 """Planner API routes for portfolio recommendations and rebalancing."""
 
+import json
 from typing import Optional
 
-from fastapi import APIRouter, Depends
+from fastapi import APIRouter, Depends, HTTPException
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
 from sentinel.planner import Planner
+from sentinel.planner.context import get_context_log
+from sentinel.planner.replay import replay_snapshot
+from sentinel.planner.state_hash import diff_state_checks
+from sentinel.planner.time_budget import STATS_CACHE_KEY
 from sentinel.portfolio import Portfolio
+from sentinel.services.liquidity import LiquidityService
+from sentinel.services.quality_gates import QualityGateService
+from sentinel.services.sleeve_funding import SleeveFundingService
 from sentinel.utils.fees import FeeCalculator
 
 router = APIRouter(prefix="/planner", tags=["planner"])
@@ -33,7 +41,10 @@ async def get_recommendations(
     # Calculate summary with transaction fees
     current_cash = await portfolio.total_cash_eur()
     fee_calc = FeeCalculator()
-    trades = [{"action": r.action, "value_eur": abs(r.value_delta_eur)} for r in recommendations]
+    trades = [
+        {"action": r.action, "value_eur": abs(r.value_delta_eur), "symbol": r.symbol, "currency": r.currency}
+        for r in recommendations
+    ]
     fee_summary = await fee_calc.calculate_batch(trades)
 
     total_sell_value = fee_summary["total_sell_value"]
@@ -63,6 +74,12 @@ async def get_recommendations(
                 "contrarian_score": r.contrarian_score,
                 "priority": r.priority,
                 "reason": r.reason,
+                "fee_eur": r.fee_eur,
+                "swap_group": r.swap_group,
+                "swap_net_cost_eur": r.swap_net_cost_eur,
+                "swap_score_delta": r.swap_score_delta,
+                "swap_tax_eur": r.swap_tax_eur,
+                "advisory_tags": r.advisory_tags or [],
             }
             for r in recommendations
         ],
@@ -77,15 +94,25 @@ async def get_recommendations(
 
 
 @router.get("/ideal")
-async def get_ideal_portfolio() -> dict:
-    """Get the calculated ideal portfolio allocations."""
+async def get_ideal_portfolio(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """
+    Get the calculated ideal portfolio allocations.
+
+    skipped_checks lists, per symbol, the signal checks that could not run for
+    missing inputs, so a zero target from insufficient data is distinguishable
+    from a security that genuinely does not qualify.
+    """
     planner = Planner()
     ideal = await planner.calculate_ideal_portfolio()
     current = await planner.get_current_allocations()
+    skipped_checks = await deps.db.cache_get("planner:skipped_checks")
 
     return {
         "ideal": {k: v * 100 for k, v in ideal.items()},
         "current": {k: v * 100 for k, v in current.items()},
+        "skipped_checks": json.loads(skipped_checks) if skipped_checks else {},
     }
 
 
@@ -94,3 +121,188 @@ async def get_rebalance_summary() -> dict:
     """Get summary of portfolio alignment with ideal allocations."""
     planner = Planner()
     return await planner.get_rebalance_summary()
+
+
+@router.get("/time-budget")
+async def get_time_budget_stats(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """How often live planner runs hit their time budget, with the last run's outcome."""
+    stats = await deps.db.cache_get(STATS_CACHE_KEY)
+    return json.loads(stats) if stats else {"runs": 0, "truncated": 0, "truncation_rate": 0.0, "last_run": None}
+
+
+@router.get("/scoring")
+async def get_scoring_state(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Cached and dirty security scores awaiting recomputation by the next planner run."""
+    return await deps.db.get_score_state_summary()
+
+
+@router.get("/liquidity")
+async def get_liquidity_ladder(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: int = 90,
+) -> dict:
+    """
+    Get the projected cash ladder for the next N days.
+
+    Combines current cash, pending recommendations, expected dividends and
+    recurring deposits into weekly buckets, with warnings when planned buys
+    exceed projected cash and a suggested deposit to cover the shortfall.
+    """
+    if days < 1 or days > 365:
+        raise HTTPException(status_code=400, detail="days must be between 1 and 365")
+    service = LiquidityService(db=deps.db, currency=deps.currency)
+    return await service.get_ladder(days=days)
+
+
+@router.get("/sleeve-funding")
+async def get_sleeve_funding(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    measure: Optional[str] = None,
+    lookback_days: Optional[int] = None,
+) -> dict:
+    """
+    Preview risk-parity funding of the core / opportunity sleeves.
+
+    Each sleeve is funded inversely to its historical risk (volatility or max
+    drawdown) so both contribute similar risk. Returns the current and suggested
+    split per sleeve with the difference in percentage points.
+    """
+    try:
+        return await SleeveFundingService(db=deps.db, settings=deps.settings).preview(
+            measure=measure, lookback_days=lookback_days
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/sleeve-funding/apply")
+async def apply_sleeve_funding(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    measure: Optional[str] = None,
+    lookback_days: Optional[int] = None,
+) -> dict:
+    """Save the risk-parity split as the core / opportunity sleeve targets."""
+    try:
+        return await SleeveFundingService(db=deps.db, settings=deps.settings).apply(
+            measure=measure, lookback_days=lookback_days
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.get("/snapshots")
+async def get_planner_snapshots(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 50,
+) -> dict:
+    """List recorded planner runs available for replay."""
+    return {"snapshots": await deps.db.get_planner_snapshots(limit=limit)}
+
+
+@router.get("/snapshots/{snapshot_id}/replay")
+async def replay_planner_snapshot(
+    snapshot_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """
+    Re-run a recorded planner cycle with the current code and settings.
+
+    Returns the recommendations the replay produced, how they differ from the
+    recorded ones (added / removed / changed per symbol and action), and which
+    settings changed since the snapshot was taken.
+    """
+    report = await replay_snapshot(snapshot_id, db=deps.db)
+    if report is None:
+        raise HTTPException(status_code=404, detail="Snapshot not found")
+    return report
+
+
+@router.get("/state-checks")
+async def get_state_checks(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 50,
+) -> dict:
+    """List recorded planner state checks with their component hashes."""
+    return {"checks": await deps.db.get_state_checks(limit=limit)}
+
+
+@router.get("/state-checks/diff")
+async def diff_planner_state_checks(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    from_id: Optional[int] = None,
+    to_id: Optional[int] = None,
+) -> dict:
+    """
+    Show what changed between two state checks (default: the latest two).
+
+    Lists the components whose hash differs (positions, prices, scores,
+    settings, allocations), the added / removed / modified items in each and
+    the securities involved.
+    """
+    if from_id is None or to_id is None:
+        recent = await deps.db.get_state_checks(limit=2)
+        if len(recent) < 2:
+            raise HTTPException(status_code=404, detail="Fewer than two state checks recorded")
+        from_id = recent[1]["id"] if from_id is None else from_id
+        to_id = recent[0]["id"] if to_id is None else to_id
+    old = await deps.db.get_state_check(from_id)
+    new = await deps.db.get_state_check(to_id)
+    if old is None or new is None:
+        raise HTTPException(status_code=404, detail="State check not found")
+    return diff_state_checks(old, new)
+
+
+@router.get("/context")
+async def get_planner_context(back: int = 0) -> dict:
+    """
+    Opportunity context of a recent live planner computation (back=0 is the latest).
+
+    Shows the enriched positions (EUR values, current and ideal weights), cash,
+    quotes, sleeve signals and recommendations the planner worked with, plus a
+    summary of every context still held in memory.
+    """
+    log = get_context_log()
+    context = log.get(back)
+    if context is None:
+        raise HTTPException(status_code=404, detail="No planner context recorded")
+    return {"context": context, "history": log.summaries()}
+
+
+@router.get("/quality-gates")
+async def get_quality_gate_stats(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    since: Optional[str] = None,
+    until: Optional[str] = None,
+) -> dict:
+    """
+    Aggregate statistics of the backfilled quality-gate matrix.
+
+    Per path: how often it passes, how often it is the only path that passes,
+    and pairwise overlap. since/until limit the months (YYYY-MM).
+    """
+    return await QualityGateService(db=deps.db, settings=deps.settings).stats(since=since, until=until)
+
+
+@router.get("/quality-gates/{symbol}")
+async def get_quality_gate_history(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Month-by-month gate outcomes for one security."""
+    return {"symbol": symbol, "months": await deps.db.get_quality_gate_backfill(symbol=symbol)}
+
+
+@router.post("/quality-gates/backfill")
+async def run_quality_gate_backfill(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: Optional[dict] = None,
+) -> dict:
+    """Re-evaluate every security (or body {"symbols": [...]}) at every historical month end."""
+    symbols = (data or {}).get("symbols")
+    if symbols is not None and not isinstance(symbols, list):
+        raise HTTPException(status_code=400, detail="symbols must be a list")
+    return await QualityGateService(db=deps.db, settings=deps.settings).backfill(symbols=symbols)
@@ -4,14 +4,19 @@ import bisect
 import logging
 from datetime import date as date_type
 from datetime import datetime, timedelta, timezone
-from typing import Any
+from typing import Any, Optional
 
-from fastapi import APIRouter, Depends
+from fastapi import APIRouter, Depends, HTTPException, Request
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
 from sentinel.portfolio import Portfolio
+from sentinel.services.allocation_optimizer import AllocationOptimizerService
+from sentinel.services.attribution import AttributionService
+from sentinel.services.currency_exposure import CurrencyExposureService
 from sentinel.services.portfolio import PortfolioService
+from sentinel.services.position_adjustments import PositionAdjustmentService
+from sentinel.services.position_theses import PositionThesisService
 
 logger = logging.getLogger(__name__)
 
@@ -40,6 +45,80 @@ async def sync_portfolio() -> dict[str, str]:
     return await service.sync_portfolio()
 
 
+def _principal_name(request: Request) -> str:
+    principal = getattr(request.state, "principal", None)
+    return principal["name"] if principal else "local"
+
+
+@router.get("/adjustments")
+async def get_position_adjustments(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbol: Optional[str] = None,
+    limit: int = 100,
+) -> dict[str, Any]:
+    """Manual position adjustments, newest first, with whether each still overrides the broker."""
+    return {"adjustments": await PositionAdjustmentService(db=deps.db).list(symbol, limit)}
+
+
+@router.post("/positions/{symbol}/adjust")
+async def adjust_position(
+    symbol: str,
+    data: dict,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Correct a position the broker sync got wrong (body: {"reason": ..., "quantity": ..., "avg_cost": ...})."""
+    return await PositionAdjustmentService(db=deps.db).adjust(
+        symbol, data.get("reason"), data.get("quantity"), data.get("avg_cost"), _principal_name(request)
+    )
+
+
+@router.get("/theses")
+async def get_position_theses(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Target prices, stop levels and theses of positions, with their current conditions."""
+    return {"theses": await PositionThesisService(db=deps.db, settings=deps.settings).list()}
+
+
+@router.get("/positions/{symbol}/thesis")
+async def get_position_thesis(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """A position's target price, stop level and thesis, with its current conditions."""
+    return await PositionThesisService(db=deps.db, settings=deps.settings).get(symbol)
+
+
+@router.put("/positions/{symbol}/thesis")
+async def set_position_thesis(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Set a position's thesis (body: any of target_price, stop_price, thesis, review_days)."""
+    return await PositionThesisService(db=deps.db, settings=deps.settings).set(symbol, data)
+
+
+@router.post("/positions/{symbol}/thesis/review")
+async def review_position_thesis(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Mark a position's thesis as reviewed, restarting its review interval."""
+    return await PositionThesisService(db=deps.db, settings=deps.settings).review(symbol)
+
+
+@router.delete("/positions/{symbol}/thesis")
+async def delete_position_thesis(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Remove a position's target price, stop level and thesis."""
+    await PositionThesisService(db=deps.db, settings=deps.settings).clear(symbol)
+    return {"status": "ok"}
+
+
 @router.get("/allocations")
 async def get_portfolio_allocations() -> dict[str, Any]:
     """Get current vs target allocations."""
@@ -47,6 +126,56 @@ async def get_portfolio_allocations() -> dict[str, Any]:
     return await service.get_allocation_comparison()
 
 
+@router.get("/attribution")
+async def get_portfolio_attribution(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    period: str = "1Y",
+    dimension: str = "geography",
+    end_date: str | None = None,
+) -> dict[str, Any]:
+    """
+    Attribute portfolio returns over a period.
+
+    Args:
+        period: 1M, 3M, 6M, YTD, 1Y, 3Y or 5Y
+        dimension: Grouping for allocation vs selection - geography or industry
+        end_date: Optional period end (YYYY-MM-DD), defaults to today
+
+    Returns factor attribution (allocation, selection, currency) and value added
+    per decision source (job + planner rule that generated each trade) and per
+    dominant scoring component of the executed recommendation.
+    """
+    service = AttributionService(db=deps.db, currency=deps.currency)
+    try:
+        return await service.get_attribution(period=period, dimension=dimension, end_date=end_date)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.get("/currency-exposure")
+async def get_currency_exposure(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    basis: str | None = None,
+    days: int = 90,
+) -> dict[str, Any]:
+    """
+    Portfolio value by currency, its drift and hedging suggestions.
+
+    Args:
+        basis: listing (trading currency) or revenue (from geography); defaults to
+            the currency_exposure_basis setting
+        days: Snapshot window for the exposure history and drift
+
+    Returns exposure per currency (value, share, drift, limit), the daily history
+    of shares and suggestions for currencies above currency_exposure_limits.
+    """
+    service = CurrencyExposureService(db=deps.db, currency=deps.currency, settings=deps.settings)
+    try:
+        return await service.get_exposure(basis=basis, days=days)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
 def _ts_to_iso(ts: int) -> str:
     """Convert unix timestamp to YYYY-MM-DD string."""
     return datetime.fromtimestamp(ts, tz=timezone.utc).strftime("%Y-%m-%d")
@@ -413,3 +542,123 @@ async def delete_industry_target(
     """
     await deps.db.delete_allocation_target("industry", name)
     return {"status": "ok"}
+
+
+@allocation_router.get("/proposals")
+async def get_allocation_proposals(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    target_type: str | None = None,
+    limit: int = 20,
+) -> dict[str, list]:
+    """Optimizer proposals (proposed vs. active targets), most recent first."""
+    service = AllocationOptimizerService(db=deps.db, settings=deps.settings)
+    return {"proposals": await service.proposals(target_type, limit)}
+
+
+@allocation_router.post("/proposals")
+async def create_allocation_proposal(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """
+    Propose geography or industry targets with the allocation optimizer.
+
+    Body: {"type": "geography", "method": "mean_variance"} (method defaults to
+    allocation_optimizer_method). The proposal is not used until accepted.
+    """
+    service = AllocationOptimizerService(db=deps.db, settings=deps.settings)
+    try:
+        return await service.propose(data.get("type", ""), data.get("method"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@allocation_router.post("/proposals/{proposal_id}/accept")
+async def accept_allocation_proposal(
+    proposal_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Make a pending proposal's weights the active allocation targets."""
+    proposal = await AllocationOptimizerService(db=deps.db, settings=deps.settings).decide(proposal_id, True)
+    if proposal is None:
+        raise HTTPException(status_code=404, detail="No pending proposal with that id")
+    return proposal
+
+
+@allocation_router.post("/proposals/{proposal_id}/reject")
+async def reject_allocation_proposal(
+    proposal_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Discard a pending proposal."""
+    proposal = await AllocationOptimizerService(db=deps.db, settings=deps.settings).decide(proposal_id, False)
+    if proposal is None:
+        raise HTTPException(status_code=404, detail="No pending proposal with that id")
+    return proposal
+
+
+@allocation_router.get("/sectors")
+async def get_sector_allocation(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, list]:
+    """
+    Get the GICS sector / industry group tree with caps and current exposure.
+
+    Returns:
+        sectors: Sectors with their industry groups, each with max_pct (or None) and current_pct
+        unclassified: Held symbols without a sector
+    """
+    from sentinel.planner.sector_caps import node_exposures, symbol_sector_paths
+
+    taxonomy = await deps.db.get_sector_taxonomy()
+    caps = await deps.db.get_sector_caps()
+    securities = await deps.db.get_all_securities(active_only=False)
+    current = (await Portfolio().get_allocations()).get("by_security", {})
+    paths = symbol_sector_paths(securities)
+    exposures = node_exposures(current, paths)
+
+    def node(row: dict) -> dict:
+        max_pct = caps.get(row["code"])
+        return {
+            "code": row["code"],
+            "name": row["name"],
+            "max_pct": max_pct * 100 if max_pct is not None else None,
+            "current_pct": exposures.get(row["code"], 0.0) * 100,
+        }
+
+    sectors = [{**node(r), "industry_groups": []} for r in taxonomy if r["level"] == "sector"]
+    by_code = {s["code"]: s for s in sectors}
+    for row in taxonomy:
+        if row["level"] == "industry_group" and row["parent_code"] in by_code:
+            by_code[row["parent_code"]]["industry_groups"].append(node(row))
+    unclassified = sorted(s for s, w in current.items() if w > 0 and s not in paths)
+    return {"sectors": sectors, "unclassified": unclassified}
+
+
+@allocation_router.put("/sector-caps/{code}")
+async def set_sector_cap(
+    code: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Cap a sector or industry group at max_pct (0-100) of portfolio value."""
+    if code not in {t["code"] for t in await deps.db.get_sector_taxonomy()}:
+        raise HTTPException(status_code=404, detail="Unknown sector code")
+    try:
+        max_pct = float(data["max_pct"])
+    except (KeyError, TypeError, ValueError):
+        raise HTTPException(status_code=400, detail="max_pct is required") from None
+    if not 0 <= max_pct <= 100:
+        raise HTTPException(status_code=400, detail="max_pct must be between 0 and 100")
+    await deps.db.set_sector_cap(code, max_pct / 100)
+    return {"status": "ok"}
+
+
+@allocation_router.delete("/sector-caps/{code}")
+async def delete_sector_cap(
+    code: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Remove the cap on a sector or industry group."""
+    await deps.db.delete_sector_cap(code)
+    return {"status": "ok"}
@@ -0,0 +1,67 @@
+"""Runtime profiling routes (admin only; 404 unless profiling_enabled is on)."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from fastapi.responses import FileResponse, PlainTextResponse
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.profiling import ProfilingService
+
+router = APIRouter(prefix="/debug/pprof", tags=["debug"])
+
+
+async def _profiling(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> ProfilingService:
+    service = ProfilingService(settings=deps.settings)
+    if not await service.enabled():
+        raise HTTPException(status_code=404, detail="Not found")
+    return service
+
+
+@router.get("")
+async def get_profiling_status(service: Annotated[ProfilingService, Depends(_profiling)]) -> dict:
+    """Capture in progress (if any) and stored profiles, newest first."""
+    return {"active": service.active(), "profiles": service.list_profiles()}
+
+
+@router.post("/{kind}")
+async def start_profile_capture(
+    kind: str,
+    service: Annotated[ProfilingService, Depends(_profiling)],
+    seconds: float = 30,
+) -> dict:
+    """
+    Start a CPU or heap capture running in the background for `seconds`.
+
+    The profile is downloadable from /debug/pprof/profiles/{name} once ready_at has passed.
+    """
+    try:
+        return service.start(kind, seconds)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except RuntimeError as e:
+        raise HTTPException(status_code=409, detail=str(e)) from e
+
+
+@router.get("/profiles/{name}")
+async def download_profile(
+    name: str,
+    service: Annotated[ProfilingService, Depends(_profiling)],
+    format: str = "raw",
+):
+    """Download a stored profile (format=raw) or its plain-text top list (format=text)."""
+    try:
+        if format == "text":
+            return PlainTextResponse(service.render_text(name))
+        return FileResponse(service.path(name), media_type="application/octet-stream", filename=name)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.delete("/profiles/{name}")
+async def delete_profile(name: str, service: Annotated[ProfilingService, Depends(_profiling)]) -> dict:
+    """Delete a stored profile."""
+    try:
+        service.delete(name)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"status": "ok"}
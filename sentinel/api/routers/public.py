@@ -0,0 +1,83 @@
+"""Read-only public dashboard routes.
+
+Served without authentication when public_dashboard_enabled is on (404
+otherwise), under /api/public and - when SENTINEL_PUBLIC_PORT is set - on a
+separate port that exposes nothing else.
+"""
+
+from fastapi import APIRouter, Depends, HTTPException
+from fastapi.responses import HTMLResponse
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.public_dashboard import PublicDashboardService
+
+router = APIRouter(prefix="/public", tags=["public"])
+
+# Self-contained wall display page polling the JSON endpoints
+DASHBOARD_HTML = """<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Portfolio</title>
+<style>
+body{background:#101418;color:#e6e6e6;font-family:sans-serif;margin:2rem}
+h1{font-size:3.5rem;margin:0}.muted{color:#8a949e}.row{display:flex;gap:3rem;flex-wrap:wrap}
+table{border-collapse:collapse}td{padding:.15rem .8rem .15rem 0}svg{width:100%;height:160px}
+</style></head><body>
+<div class="muted">Portfolio value</div><h1 id="value">-</h1>
+<div class="muted" id="meta"></div>
+<svg id="chart" viewBox="0 0 1000 160" preserveAspectRatio="none"><polyline id="line" fill="none"
+ stroke="#4dabf7" stroke-width="3"/></svg>
+<div class="row"><div><h3>Geography</h3><table id="geo"></table></div>
+<div><h3>Industry</h3><table id="ind"></table></div></div>
+<script>
+const eur = v => new Intl.NumberFormat(undefined, {style: "currency", currency: "EUR"}).format(v);
+const rows = (id, w) => document.getElementById(id).innerHTML = Object.entries(w).slice(0, 8)
+  .map(([k, v]) => `<tr><td>${k.replace(/[<>&]/g, "")}</td><td>${v.toFixed(1)}%</td></tr>`).join("");
+async function refresh() {
+  const get = p => fetch(p).then(r => r.json());
+  const [s, a, p] = await Promise.all([get("summary"), get("allocation"), get("performance")]);
+  document.getElementById("value").textContent = eur(s.total_value_eur);
+  const change = p.change_pct === null ? "" : ` · ${p.change_pct > 0 ? "+" : ""}${p.change_pct}% over ${p.days}d`;
+  document.getElementById("meta").textContent = `${s.positions} positions · ${s.cash_pct}% cash${change}`;
+  rows("geo", a.by_geography); rows("ind", a.by_industry);
+  const v = p.history.map(h => h.value_eur), lo = Math.min(...v), hi = Math.max(...v);
+  document.getElementById("line").setAttribute("points", v.map((x, i) =>
+    `${i / Math.max(v.length - 1, 1) * 1000},${150 - (hi > lo ? (x - lo) / (hi - lo) : 0.5) * 140}`).join(" "));
+}
+refresh(); setInterval(refresh, 300000);
+</script></body></html>
+"""
+
+
+async def _dashboard(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> PublicDashboardService:
+    service = PublicDashboardService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    if not await service.enabled():
+        raise HTTPException(status_code=404, detail="Not found")
+    return service
+
+
+@router.get("/dashboard", response_class=HTMLResponse)
+async def get_public_dashboard(service: Annotated[PublicDashboardService, Depends(_dashboard)]) -> str:
+    """Wall display page."""
+    return DASHBOARD_HTML
+
+
+@router.get("/summary")
+async def get_public_summary(service: Annotated[PublicDashboardService, Depends(_dashboard)]) -> dict:
+    """Total value, cash share and position count."""
+    return await service.summary()
+
+
+@router.get("/allocation")
+async def get_public_allocation(service: Annotated[PublicDashboardService, Depends(_dashboard)]) -> dict:
+    """Allocation by geography and industry (by security unless public_dashboard_hide_symbols is on)."""
+    return await service.allocation()
+
+
+@router.get("/performance")
+async def get_public_performance(
+    service: Annotated[PublicDashboardService, Depends(_dashboard)],
+    days: int = 365,
+) -> dict:
+    """Daily portfolio value history."""
+    return await service.performance(days=days)
@@ -0,0 +1,52 @@
+"""Broker reconciliation routes: runs, discrepancy reports and sign-off."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, Request
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.reconciliation import ReconciliationService
+
+router = APIRouter(prefix="/reconciliations", tags=["reconciliations"])
+
+
+def _service(deps: CommonDependencies) -> ReconciliationService:
+    return ReconciliationService(db=deps.db, broker=deps.broker, settings=deps.settings)
+
+
+@router.get("")
+async def get_reconciliations(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    period: Optional[str] = None,
+    limit: int = 24,
+) -> dict:
+    """Reconciliation runs with their discrepancy counts, most recent first."""
+    return {"reconciliations": await _service(deps).list(period, limit)}
+
+
+@router.post("")
+async def run_reconciliation(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Reconcile a past month (body: {"period": "YYYY-MM"}) with the broker report now."""
+    return await _service(deps).reconcile(str(data.get("period", "")))
+
+
+@router.get("/{reconciliation_id}")
+async def get_reconciliation(
+    reconciliation_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]
+) -> dict:
+    """A reconciliation run with its discrepancies."""
+    return await _service(deps).get(reconciliation_id)
+
+
+@router.post("/{reconciliation_id}/sign-off")
+async def sign_off_reconciliation(
+    reconciliation_id: int,
+    data: dict,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Sign off a run (body: {"note": ...}; required when it has discrepancies)."""
+    principal = getattr(request.state, "principal", None)
+    signed_off_by = principal["name"] if principal else "local"
+    return await _service(deps).sign_off(reconciliation_id, signed_off_by, data.get("note"))
@@ -0,0 +1,34 @@
+"""Market regime routes: the latest per-region classification, its recomputation and the market stress level."""
+
+from fastapi import APIRouter, Depends
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.market_stress import MarketStressService
+from sentinel.services.regime import RegimeService
+
+router = APIRouter(prefix="/regime", tags=["regime"])
+
+
+@router.get("")
+async def get_regime(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Regime, score, confidence and per-signal scores of every region."""
+    return await RegimeService(db=deps.db).status()
+
+
+@router.get("/detectors")
+async def get_regime_detectors(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Registered ensemble detectors with their weights, the vote policy and the threshold."""
+    return await RegimeService(db=deps.db).detectors()
+
+
+@router.post("/compute")
+async def compute_regime(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Reclassify every region now instead of waiting for the scheduled job."""
+    return {"regions": await RegimeService(db=deps.db).compute()}
+
+
+@router.get("/stress")
+async def get_market_stress(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Exposure-weighted market stress level and the buy limits in effect (base and throttled)."""
+    return await MarketStressService(db=deps.db, settings=deps.settings, currency=deps.currency).status()
@@ -0,0 +1,78 @@
+"""Digest report routes: list, download and generate on demand."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from fastapi.responses import HTMLResponse, PlainTextResponse, Response
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.reports import PERIODS, ReportService
+
+router = APIRouter(prefix="/reports", tags=["reports"])
+
+
+def _render(report: dict | None, format: str, download: bool) -> Response | dict:
+    """Report in the requested format (json, markdown or html)."""
+    if report is None:
+        raise HTTPException(status_code=404, detail="No report found")
+    if format == "json":
+        return report
+    if format not in ("markdown", "html"):
+        raise HTTPException(status_code=400, detail="format must be json, markdown or html")
+    filename = f"sentinel-{report['period']}-digest-{report['id']}.{'md' if format == 'markdown' else 'html'}"
+    headers = {"Content-Disposition": f'attachment; filename="{filename}"'} if download else None
+    if format == "html":
+        return HTMLResponse(report["html"], headers=headers)
+    return PlainTextResponse(report["markdown"], media_type="text/markdown", headers=headers)
+
+
+@router.get("")
+async def get_reports(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    period: Optional[str] = None,
+    limit: int = 50,
+) -> dict:
+    """List stored digest reports, most recent first."""
+    return {"reports": await deps.db.get_reports(period=period, limit=limit)}
+
+
+@router.get("/latest")
+async def get_latest_report(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    period: Optional[str] = None,
+    format: str = "json",
+    download: bool = False,
+):
+    """
+    Latest digest report (optionally of a period).
+
+    format=json returns the sections and both renderings; markdown/html return
+    the rendered document (as an attachment with download=true).
+    """
+    return _render(await deps.db.get_report(period=period), format, download)
+
+
+@router.post("/generate")
+async def generate_report(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict | None = None,
+) -> dict:
+    """Generate a report now. Body: {"period": "daily"|"weekly", "notify": bool} (both optional)."""
+    data = data or {}
+    period = data.get("period")
+    if period is not None and period not in PERIODS:
+        raise HTTPException(status_code=400, detail=f"period must be one of {', '.join(PERIODS)}")
+    service = ReportService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    return await service.generate(period=period, notify=data.get("notify", False))
+
+
+@router.get("/{report_id}")
+async def get_report(
+    report_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    format: str = "json",
+    download: bool = False,
+):
+    """One digest report, in the same formats as /latest."""
+    return _render(await deps.db.get_report(report_id), format, download)
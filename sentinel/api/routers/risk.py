@@ -0,0 +1,24 @@
+"""Risk control routes: the automated trading circuit breaker."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.circuit_breaker import CircuitBreakerService
+
+router = APIRouter(prefix="/risk", tags=["risk"])
+
+
+@router.get("/circuit-breaker")
+async def get_circuit_breaker(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Breaker state (tripped, reason, paused jobs), its limits and the current drawdown."""
+    return await CircuitBreakerService(db=deps.db, settings=deps.settings, currency=deps.currency).status()
+
+
+@router.post("/circuit-breaker/resume")
+async def resume_circuit_breaker(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Resume automated trading after a trip. The drawdown guard re-arms from the current value."""
+    try:
+        return await CircuitBreakerService(db=deps.db, settings=deps.settings, currency=deps.currency).resume()
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
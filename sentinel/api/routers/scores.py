@@ -0,0 +1,47 @@
+"""Score recalculation routes: recompute cached security scores now instead of on the next planner run."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.score_recalculation import ScoreRecalculationService
+
+router = APIRouter(prefix="/scores", tags=["scores"])
+
+
+@router.post("/recalculate")
+async def recalculate_scores(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: Optional[dict] = None,
+) -> dict:
+    """Recalculate scores in the background; poll the returned run_id.
+
+    Body (all optional): {"symbols": [...], "geography": "US", "industry": "Technology",
+    "asset_class": "equity", "dirty_only": true}. Without filters the whole active universe is rescored.
+    """
+    try:
+        return await ScoreRecalculationService(db=deps.db, settings=deps.settings).start(data or {})
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.get("/recalculate/{run_id}")
+async def get_recalculation(run_id: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Status, progress percentage, ETA and per-security errors of a recalculation."""
+    try:
+        return ScoreRecalculationService(db=deps.db, settings=deps.settings).status(run_id)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.post("/recalculate/{run_id}/cancel")
+async def cancel_recalculation(run_id: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Stop a recalculation after its current batch; scores computed so far are kept."""
+    try:
+        return ScoreRecalculationService(db=deps.db, settings=deps.settings).cancel(run_id)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
@@ -0,0 +1,93 @@
+"""Credentials vault routes: list, store/rotate, delete and re-key secrets."""
+
+import secrets
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.vault import KEY_LENGTH, SECRET_NAMES, Vault, VaultError, VaultKey, available
+
+router = APIRouter(prefix="/secrets", tags=["secrets"])
+
+
+def _vault(deps: CommonDependencies) -> Vault:
+    if not available():
+        raise HTTPException(status_code=400, detail="Credentials vault unavailable: install the cryptography package")
+    return Vault(db=deps.db, settings=deps.settings)
+
+
+@router.get("")
+async def get_secrets(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """List known secrets and whether each is stored in the vault (values are never returned)."""
+    return {"available": available(), "secrets": await Vault(db=deps.db, settings=deps.settings).status()}
+
+
+@router.put("/{name}")
+async def put_secret(
+    name: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Store or rotate a secret. Body: {"value": str}. Any plaintext settings row is blanked."""
+    if name not in SECRET_NAMES:
+        raise HTTPException(status_code=400, detail=f"Unknown secret: {name}")
+    value = str(data.get("value") or "").strip()
+    if not value:
+        raise HTTPException(status_code=400, detail="value is required")
+    vault = _vault(deps)
+    try:
+        await vault.put(name, value)
+    except (VaultError, OSError) as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    await deps.settings.set(name, "")
+    return {"status": "ok"}
+
+
+@router.delete("/{name}")
+async def delete_secret(
+    name: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Remove a secret from the vault."""
+    if not await Vault(db=deps.db, settings=deps.settings).delete(name):
+        raise HTTPException(status_code=404, detail="Secret not found")
+    return {"status": "ok"}
+
+
+@router.post("/migrate")
+async def migrate_secrets(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Move plaintext credential settings into the vault."""
+    try:
+        migrated = await _vault(deps).migrate_settings()
+    except (VaultError, OSError) as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"migrated": migrated}
+
+
+@router.post("/rekey")
+async def rekey_vault(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: dict | None = None,
+) -> dict:
+    """
+    Re-encrypt all secrets under a new master key.
+
+    With a keyfile, a new random key replaces it. With a passphrase, the body
+    must carry {"passphrase": str}; set SENTINEL_VAULT_PASSPHRASE to it before
+    the next restart.
+    """
+    vault = _vault(deps)
+    try:
+        current = vault.key
+        if current.source == "keyfile":
+            new_key = VaultKey(material=secrets.token_bytes(KEY_LENGTH), source="keyfile", path=current.path)
+        else:
+            passphrase = str((data or {}).get("passphrase") or "")
+            if len(passphrase) < 12:
+                raise HTTPException(status_code=400, detail="A new passphrase of at least 12 characters is required")
+            new_key = VaultKey(material=passphrase.encode(), source="passphrase")
+        count = await vault.rekey(new_key)
+    except (VaultError, OSError) as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    return {"rekeyed": count, "source": new_key.source}
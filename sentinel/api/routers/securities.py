@@ -2,19 +2,32 @@
 
 import inspect
 import json
-from typing import Any
+from typing import Any, Optional
 
-from fastapi import APIRouter, Depends, HTTPException
+from fastapi import APIRouter, Depends, HTTPException, Request
+from fastapi.responses import Response
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.planner.cash_equivalents import ASSET_CLASSES
 from sentinel.security import Security
-from sentinel.strategy import classify_lot_size, compute_contrarian_signal
+from sentinel.services.computed_columns import ComputedColumnService, rows_to_csv
+from sentinel.services.drip import DRIP_MODES
+from sentinel.services.intraday import IntradayService
+from sentinel.services.security_archive import SecurityArchiveService
+from sentinel.strategy import classify_lot_size, compute_contrarian_signal, contrarian_skipped_checks
+from sentinel.utils.identity import extract_isin
+from sentinel.utils.quantity import lot_step
 
 router = APIRouter(prefix="/securities", tags=["securities"])
 prices_router = APIRouter(prefix="/prices", tags=["prices"])
 
 
+def _principal_name(request: Request) -> str:
+    principal = getattr(request.state, "principal", None)
+    return principal["name"] if principal else "local"
+
+
 @router.get("")
 async def get_securities(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -54,6 +67,9 @@ async def add_security(
 
     # Save to database
     was_reenabled = bool(existing and int(existing.get("active", 0) or 0) == 0)
+    if was_reenabled:
+        # Reactivation restores the trading flags the security had when it was archived
+        existing = {**existing, **await SecurityArchiveService(db=deps.db).reactivate(symbol)}
     await deps.db.upsert_security(
         symbol,
         name=name,
@@ -61,16 +77,18 @@ async def add_security(
         market_id=market_id,
         min_lot=min_lot,
         active=True,
-        # If this is a previously-inactive security, restore trading flags by default.
         # If the caller explicitly provided allow_buy/allow_sell, honor those.
-        allow_buy=data.get("allow_buy", 1 if was_reenabled else existing.get("allow_buy", 1) if existing else 1),
-        allow_sell=data.get("allow_sell", 1 if was_reenabled else existing.get("allow_sell", 1) if existing else 1),
-        geography=data.get("geography", ""),
-        industry=data.get("industry", ""),
+        allow_buy=data.get("allow_buy", existing.get("allow_buy", 1) if existing else 1),
+        allow_sell=data.get("allow_sell", existing.get("allow_sell", 1) if existing else 1),
+        geography=data.get("geography", (existing or {}).get("geography") or ""),
+        industry=data.get("industry", (existing or {}).get("industry") or ""),
     )
 
     # Save full metadata
     await deps.db.update_security_metadata(symbol, info, market_id)
+    isin = extract_isin(info)
+    if isin:
+        await deps.db.set_security_isin(symbol, isin)
 
     # Fetch and save 20 years of historical prices (TraderNet getHloc has no documented max range)
     prices_data = await deps.broker.get_historical_prices_bulk([symbol], years=20)
@@ -90,13 +108,15 @@ async def add_security(
 @router.delete("/{symbol}")
 async def delete_security(
     symbol: str,
+    request: Request,
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
     sell_position: bool = True,
+    reason: Optional[str] = None,
 ) -> dict[str, Any]:
     """Remove a security from the active universe. Optionally sells any existing position first.
 
-    Soft-deletes: marks the security as inactive (active=0, allow_buy=0, allow_sell=0).
-    Deletes current-state data (positions) but preserves historical prices and trades.
+    Archives: marks the security as inactive (active=0, allow_buy=0, allow_sell=0) with the reason.
+    Deletes current-state data (positions) but preserves historical prices, scores and trades.
     """
     # Check if exists
     existing = await deps.db.get_security(symbol)
@@ -115,18 +135,31 @@ async def delete_security(
         if not order_id:
             raise HTTPException(status_code=400, detail="Failed to sell position")
 
-    # Soft-delete: mark inactive and disable trading, but preserve historical data
-    await deps.db.conn.execute(
-        "UPDATE securities SET active = 0, allow_buy = 0, allow_sell = 0 WHERE symbol = ?",
-        (symbol,),
-    )
-    # Delete current-state data (not historical)
-    await deps.db.conn.execute("DELETE FROM positions WHERE symbol = ?", (symbol,))
-    await deps.db.conn.commit()
+    try:
+        await SecurityArchiveService(db=deps.db).archive(symbol, reason, _principal_name(request))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
 
     return {"status": "ok", "sold_quantity": quantity if sell_position else 0}
 
 
+@router.get("/archived")
+async def get_archived_securities(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Archived securities with the reason, who archived them and the history they keep."""
+    return {"securities": await SecurityArchiveService(db=deps.db).archived()}
+
+
+@router.post("/{symbol}/reactivate")
+async def reactivate_security(symbol: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Bring an archived security back into the universe with its price history, scores and trades."""
+    try:
+        return await SecurityArchiveService(db=deps.db).reactivate(symbol)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
 @router.get("/aliases")
 async def get_all_aliases(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -144,14 +177,19 @@ async def get_all_aliases(
 
 
 @router.get("/{symbol}")
-async def get_security(symbol: str) -> dict[str, Any]:
-    """Get a specific security."""
-    security = Security(symbol)
-    if not await security.exists():
+async def get_security(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Get a specific security by symbol, ISIN or former symbol."""
+    resolved = await deps.db.resolve_symbol(symbol)
+    if resolved is None:
         raise HTTPException(status_code=404, detail="Security not found")
+    security = Security(resolved)
     await security.load()
     return {
         "symbol": security.symbol,
+        "isin": security.isin,
         "name": security.name,
         "currency": security.currency,
         "geography": security.geography,
@@ -177,13 +215,23 @@ async def update_security(
     allowed_fields = [
         "geography",
         "industry",
+        "country",
+        "exchange",
+        "gics_code",
         "aliases",
         "allow_buy",
         "allow_sell",
+        "supports_fractional",
         "user_multiplier",
         "active",
+        "asset_class",
+        "drip_mode",
     ]
     updates = {k: v for k, v in data.items() if k in allowed_fields}
+    if "asset_class" in updates and updates["asset_class"] not in ASSET_CLASSES:
+        raise HTTPException(status_code=400, detail=f"asset_class must be one of {', '.join(ASSET_CLASSES)}")
+    if updates.get("drip_mode") is not None and updates["drip_mode"] not in DRIP_MODES:
+        raise HTTPException(status_code=400, detail=f"drip_mode must be one of {', '.join(DRIP_MODES)}")
 
     if updates:
         await deps.db.upsert_security(symbol, **updates)
@@ -191,6 +239,39 @@ async def update_security(
     return {"status": "ok"}
 
 
+@router.post("/{symbol}/rename")
+async def rename_security(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """
+    Move a security to a new ticker (e.g. after a rename), keeping its history.
+
+    Positions, prices, trades, strategy state and decisions follow the new
+    symbol; the old symbol keeps resolving through GET /securities/{symbol}.
+    """
+    new_symbol = str(data.get("new_symbol") or "").strip().upper()
+    if not new_symbol:
+        raise HTTPException(status_code=400, detail="new_symbol is required")
+    try:
+        moved = await deps.db.rename_symbol(symbol, new_symbol, reason=data.get("reason") or "manual")
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"status": "ok", "symbol": new_symbol, "moved": moved}
+
+
+@router.get("/{symbol}/symbol-history")
+async def get_symbol_history(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Ticker changes involving a symbol, most recent first."""
+    return {"history": await deps.db.get_symbol_history(symbol)}
+
+
 @router.get("/{symbol}/prices")
 async def get_prices(
     symbol: str,
@@ -205,6 +286,21 @@ async def get_prices(
     return validator.validate_price_series_desc(raw_prices)
 
 
+@router.get("/{symbol}/intraday")
+async def get_intraday_prices(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    resolution: str | None = None,
+    days: int = 7,
+) -> list[dict]:
+    """Intraday bars (raw snapshots, hourly or daily) of a security over the last `days`, oldest first."""
+    service = IntradayService(db=deps.db, settings=deps.settings, broker=deps.broker)
+    try:
+        return await service.history(symbol, resolution=resolution, days=days)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
 @router.post("/{symbol}/sync-prices")
 async def sync_prices(
     symbol: str,
@@ -228,6 +324,14 @@ async def sync_all_prices(
     return {"status": "ok"}
 
 
+@prices_router.get("/intraday/stats")
+async def get_intraday_stats(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Intraday snapshot settings and the bars stored per resolution."""
+    return await IntradayService(db=deps.db, settings=deps.settings, broker=deps.broker).stats()
+
+
 # Unified view router (under /api/unified)
 unified_router = APIRouter(prefix="/unified", tags=["unified"])
 
@@ -245,10 +349,13 @@ async def get_unified_view(
         period: Price history period - 1M, 1Y, 5Y, 10Y
         as_of: Optional date (YYYY-MM-DD). When set, historical prices are scoped on or before that date.
 
-    Returns all securities with positions, prices, allocations, and recommendations.
+    Returns all securities with positions, prices, allocations, recommendations and
+    user-defined computed columns (`computed`: name -> value).
     """
     from sentinel.planner import Planner
     from sentinel.planner.analyzer import PortfolioAnalyzer
+    from sentinel.planner.rebalance_rules import RECOMMENDATION_HISTORY_DAYS, is_new_entry, planning_now
+    from sentinel.planner.theses import review_due_at, thesis_tags
     from sentinel.portfolio import Portfolio
     from sentinel.price_validator import PriceValidator, get_price_anomaly_warning
     from sentinel.utils.scoring import adjust_score_for_conviction
@@ -324,6 +431,13 @@ async def get_unified_view(
     lot_coarse_max_pct = float(0.30 if lot_coarse_raw is None else lot_coarse_raw)
     core_floor_pct = float(0.05 if core_floor_raw is None else core_floor_raw)
     min_opp_score = float(0.55 if min_opp_raw is None else min_opp_raw)
+    grace_days = float(await deps.settings.get("new_entry_grace_days", 14) or 0)
+    min_history_days = int(await deps.settings.get("new_entry_min_history_days", 200) or 0)
+    # History length as the planner sees it (the chart period may be shorter)
+    if as_of is None and days < RECOMMENDATION_HISTORY_DAYS:
+        history_rows = await deps.db.get_prices_bulk(all_symbols, days=RECOMMENDATION_HISTORY_DAYS)
+    else:
+        history_rows = all_prices_raw
     cache_getter = getattr(deps.db, "cache_get", None)
     sleeves_map = {}
     if callable(cache_getter):
@@ -333,6 +447,9 @@ async def get_unified_view(
         if isinstance(maybe_cache, (str, bytes, bytearray)):
             sleeves_map = json.loads(maybe_cache) if maybe_cache else {}
 
+    theses = await deps.db.get_position_theses()
+    now_ts = planning_now().timestamp()
+
     # Build unified response
     result = []
     for sec in securities:
@@ -368,7 +485,7 @@ async def get_unified_view(
 
         # Profit / loss
         sec_currency = sec.get("currency", "EUR")
-        min_lot = sec.get("min_lot", 1)
+        min_lot = lot_step(sec.get("min_lot", 1), sec.get("supports_fractional", 0))
         if has_position and avg_cost > 0:
             profit_pct = ((current_price - avg_cost) / avg_cost) * 100
             profit_value = (current_price - avg_cost) * quantity
@@ -420,9 +537,30 @@ async def get_unified_view(
             sleeve == "core" and has_position and total_value > 0 and ((value_eur / total_value) <= core_floor_pct)
         )
 
+        # Planner will not buy until the new-entry window has passed (live view only)
+        new_entry = as_of is None and is_new_entry(
+            sec,
+            len(history_rows.get(symbol) or []),
+            now_ts=planning_now().timestamp(),
+            grace_days=grace_days,
+            min_history_days=min_history_days,
+        )
+
         # Contrarian score with user conviction adjustment
         adjusted_contrarian_score = adjust_score_for_conviction(float(signal.get("opp_score", 0.0)), user_multiplier)
 
+        # The user's target price, stop level and thesis, with their current conditions
+        thesis_info = None
+        thesis = theses.get(symbol)
+        if thesis:
+            thesis_info = {
+                "target_price": thesis["target_price"],
+                "stop_price": thesis["stop_price"],
+                "thesis": thesis["thesis"],
+                "review_due_at": review_due_at(thesis),
+                "tags": thesis_tags(thesis, current_price, now_ts),
+            }
+
         # Recommendation info
         rec_info = None
         if recommendation:
@@ -433,6 +571,7 @@ async def get_unified_view(
                 "reason": recommendation.reason,
                 "reason_code": recommendation.reason_code,
                 "priority": recommendation.priority,
+                "advisory_tags": recommendation.advisory_tags or [],
             }
 
         result.append(
@@ -442,12 +581,18 @@ async def get_unified_view(
                 "currency": sec_currency,
                 "geography": sec.get("geography"),
                 "industry": sec.get("industry"),
+                "country": sec.get("country"),
+                "exchange": sec.get("exchange"),
+                "gics_code": sec.get("gics_code"),
                 "min_lot": sec.get("min_lot", 1),
+                "supports_fractional": sec.get("supports_fractional", 0),
                 "active": sec.get("active", 1),
                 "allow_buy": sec.get("allow_buy", 1),
                 "allow_sell": sec.get("allow_sell", 1),
                 "user_multiplier": sec.get("user_multiplier", 0.5),
                 "aliases": sec.get("aliases"),
+                "added_at": sec.get("added_at"),
+                "tags": ["new-entry"] if new_entry else [],
                 # Position data
                 "has_position": has_position,
                 "quantity": quantity,
@@ -476,11 +621,29 @@ async def get_unified_view(
                 "lot_class": lot_profile["lot_class"],
                 "sleeve": sleeve,
                 "core_floor_active": core_floor_active,
+                # Checks skipped for missing inputs (empty = all checks ran)
+                "skipped_checks": contrarian_skipped_checks(closes),
                 # Price history (simplified for charts, oldest first)
                 "prices": [{"date": p["date"], "close": p["close"]} for p in reversed(prices)],
+                "thesis": thesis_info,
                 # Recommendation
                 "recommendation": rec_info,
             }
         )
 
-    return result
+    # User-defined computed columns over the row's metrics
+    return await ComputedColumnService(db=deps.db).apply(result, {sec["symbol"]: sec for sec in securities})
+
+
+@unified_router.get("/export")
+async def export_unified_view(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    as_of: str | None = None,
+) -> Response:
+    """Download the unified view as CSV, one row per security, including computed columns."""
+    rows = await get_unified_view(deps, period="1M", as_of=as_of)
+    return Response(
+        rows_to_csv(rows),
+        media_type="text/csv",
+        headers={"Content-Disposition": 'attachment; filename="securities.csv"'},
+    )
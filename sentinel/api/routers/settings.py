@@ -6,7 +6,11 @@ from fastapi import APIRouter, Depends, HTTPException
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
-from sentinel.led import LEDController
+from sentinel.config.schema import validate_setting, validate_settings
+from sentinel.led import LEDController, StateManager, TradingRelay
+from sentinel.led.display import summary_lines
+from sentinel.vault import SECRET_NAMES, Vault, VaultError
+from sentinel.vault import available as vault_available
 
 router = APIRouter(prefix="/settings", tags=["settings"])
 STRATEGY_KEYS = {
@@ -16,8 +20,10 @@ STRATEGY_KEYS = {
     "strategy_core_floor_pct",
 }
 
-# Global LED controller reference (set by app lifespan)
+# Global LED controller, GPIO relay and summary screen references (set by app lifespan)
 _led_controller: LEDController | None = None
+_trading_relay: TradingRelay | None = None
+_screen_manager: StateManager | None = None
 
 
 def set_led_controller(controller: LEDController | None) -> None:
@@ -26,6 +32,18 @@ def set_led_controller(controller: LEDController | None) -> None:
     _led_controller = controller
 
 
+def set_trading_relay(relay: TradingRelay | None) -> None:
+    """Set the global GPIO relay reference."""
+    global _trading_relay
+    _trading_relay = relay
+
+
+def set_screen_manager(manager: StateManager | None) -> None:
+    """Set the global summary screen reference."""
+    global _screen_manager
+    _screen_manager = manager
+
+
 @router.get("")
 async def get_settings(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
@@ -34,6 +52,15 @@ async def get_settings(
     return await deps.settings.all()
 
 
+@router.get("/validation")
+async def get_settings_validation(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Every schema problem with the current settings (types, ranges, cross-field rules)."""
+    problems = validate_settings(await deps.settings.all())
+    return {"valid": not problems, "problems": [p.as_dict() for p in problems]}
+
+
 @router.put("/{key}")
 async def set_setting(
     key: str,
@@ -41,6 +68,24 @@ async def set_setting(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
 ) -> dict[str, str]:
     """Set a setting value."""
+    if key == "auth_enabled" and value.get("value") and not await deps.db.has_admin_credentials():
+        raise HTTPException(status_code=400, detail="Create an admin token or admin user before enabling auth")
+    problems = validate_setting(key, value.get("value"))
+    if problems:
+        raise HTTPException(status_code=400, detail="; ".join(p.message for p in problems))
+    if key in SECRET_NAMES and vault_available():
+        # Credentials go to the encrypted vault, never the plaintext settings row
+        secret = str(value.get("value") or "").strip()
+        vault = Vault(db=deps.db, settings=deps.settings)
+        try:
+            if secret:
+                await vault.put(key, secret)
+            else:
+                await vault.delete(key)
+        except (VaultError, OSError) as e:
+            raise HTTPException(status_code=400, detail=str(e)) from e
+        await deps.settings.set(key, "")
+        return {"status": "ok"}
     await deps.settings.set(key, value.get("value"))
     return {"status": "ok"}
 
@@ -86,11 +131,33 @@ async def set_settings_batch(
     if parsed_values["strategy_core_floor_pct"] < 0 or parsed_values["strategy_core_floor_pct"] > 1:
         raise HTTPException(status_code=400, detail="'strategy_core_floor_pct' must be in [0, 1]")
 
-    await deps.db.set_settings_batch({key: parsed_values[key] for key in sorted(STRATEGY_KEYS)})
+    batch = {key: parsed_values[key] for key in sorted(STRATEGY_KEYS)}
+    await deps.db.set_settings_batch(batch)
+    await deps.settings.notify(batch)
 
     return {"status": "ok"}
 
 
+@router.post("/reload")
+async def reload_settings(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Apply settings and job schedules edited outside the API without a restart.
+
+    Consumers that read settings once (LED loops, GPIO relay, summary screen,
+    cold price tier, broker credentials) are restarted or reconfigured for the
+    keys that changed since the last reload, and every job is rescheduled from
+    its stored interval.
+    """
+    from sentinel.jobs import reschedule
+
+    changed = await deps.settings.reload()
+    schedules = await deps.db.get_job_schedules()
+    for schedule in schedules:
+        await reschedule(schedule["job_type"], deps.db)
+    return {"changed": sorted(changed), "rescheduled": len(schedules)}
+
+
 # LED endpoints are under /api/led, not /api/settings
 led_router = APIRouter(prefix="/led", tags=["led"])
 
@@ -127,3 +194,52 @@ async def refresh_led_display() -> dict[str, Any]:
         return {"status": "not_running"}
     await _led_controller.force_refresh()
     return {"status": "refreshed", "trade_count": _led_controller.trade_count}
+
+
+@led_router.get("/indicators")
+async def get_led_indicators(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """
+    Active display states and what each indicator LED shows.
+
+    leds[i].code is the blink pattern code sent to the MCU; color is 0xRRGGBB.
+    """
+    from sentinel.jobs.market import BrokerMarketChecker
+    from sentinel.services.health import HealthService
+
+    manager = StateManager(
+        db=deps.db,
+        settings=deps.settings,
+        market_checker=BrokerMarketChecker(deps.broker),
+        health=HealthService(db=deps.db, broker=deps.broker, settings=deps.settings),
+    )
+    return {**await manager.frame(), "map": await manager.indicator_map()}
+
+
+@led_router.get("/relay")
+async def get_relay_status() -> dict[str, Any]:
+    """Get the GPIO trading indicator state."""
+    from sentinel.settings import Settings
+
+    enabled = await Settings().get("gpio_relay_enabled", False)
+    return {
+        "enabled": enabled,
+        "running": _trading_relay.is_running if _trading_relay else False,
+        **(_trading_relay.state if _trading_relay else {"state": None, "reason": "", "kill_switch": False}),
+    }
+
+
+@led_router.get("/screen")
+async def get_screen_status(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any]:
+    """Get the OLED / e-ink summary screen state and the lines it shows."""
+    manager = _screen_manager or StateManager(db=deps.db, settings=deps.settings)
+    summary = await manager.screen_summary()
+    return {
+        "enabled": await deps.settings.get("display_screen_enabled", False),
+        "running": manager.is_running,
+        "summary": summary,
+        "lines": summary_lines(summary),
+    }
@@ -0,0 +1,96 @@
+"""Shadow mode routes: trial proposed settings alongside the live configuration before promoting them."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException, Request
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.shadow import ShadowService
+
+router = APIRouter(prefix="/shadow", tags=["shadow"])
+
+
+def _service(deps: CommonDependencies) -> ShadowService:
+    return ShadowService(db=deps.db, settings=deps.settings)
+
+
+def _principal_name(request: Request) -> str:
+    principal = getattr(request.state, "principal", None)
+    return principal["name"] if principal else "local"
+
+
+@router.get("/trials")
+async def get_trials(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: Optional[str] = None,
+) -> dict:
+    """Shadow trials, newest first (status: active, stopped or promoted)."""
+    try:
+        return {"trials": await _service(deps).trials(status)}
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/trials")
+async def create_trial(
+    data: dict,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Start a trial (body: {"name": ..., "overrides": {"setting": value, ...}, "note": ...})."""
+    try:
+        return await _service(deps).create(
+            str(data.get("name") or ""), data.get("overrides"), _principal_name(request), data.get("note")
+        )
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/run")
+async def run_trials(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Run every active trial on the latest planner snapshot now (normally done after each planning refresh)."""
+    return await _service(deps).run_trials()
+
+
+@router.get("/trials/{trial_id}")
+async def get_trial_report(
+    trial_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 20,
+) -> dict:
+    """Divergence report: proposed vs live values, divergent runs and the securities they differed on."""
+    try:
+        return await _service(deps).report(trial_id, limit)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.post("/trials/{trial_id}/stop")
+async def stop_trial(
+    trial_id: int,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Stop a trial without changing the live settings."""
+    try:
+        return await _service(deps).stop(trial_id, _principal_name(request))
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@router.post("/trials/{trial_id}/promote")
+async def promote_trial(
+    trial_id: int,
+    request: Request,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Write a trial's proposed values to the live settings and close it."""
+    try:
+        return await _service(deps).promote(trial_id, _principal_name(request))
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
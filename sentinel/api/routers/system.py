@@ -4,8 +4,8 @@ import json
 from dataclasses import asdict
 from typing import Any
 
-from fastapi import APIRouter, Depends
-from fastapi.responses import StreamingResponse
+from fastapi import APIRouter, Depends, HTTPException
+from fastapi.responses import JSONResponse, StreamingResponse
 from typing_extensions import Annotated
 
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
@@ -19,6 +19,8 @@ from sentinel.backtester import (
 )
 from sentinel.cache import Cache
 from sentinel.currency import Currency
+from sentinel.services.health import HealthService
+from sentinel.strategy import SIZING_MODES
 from sentinel.version import VERSION
 
 router = APIRouter(tags=["system"])
@@ -30,18 +32,29 @@ meta_router = APIRouter(prefix="/meta", tags=["meta"])
 pulse_router = APIRouter(prefix="/pulse", tags=["pulse"])
 
 
+@router.get("/healthz")
+async def healthz() -> dict[str, Any]:
+    """Liveness: the process is up (never touches the database or the broker)."""
+    return HealthService.liveness()
+
+
+@router.get("/readyz", response_model=None)
+async def readyz(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, Any] | JSONResponse:
+    """Readiness: databases open, migrations applied and broker reachable (503 when not ready)."""
+    result = await HealthService(db=deps.db, broker=deps.broker, settings=deps.settings).readiness()
+    if not result["ready"]:
+        return JSONResponse(status_code=503, content=result)
+    return result
+
+
 @router.get("/health")
 async def health(
     deps: Annotated[CommonDependencies, Depends(get_common_deps)],
 ) -> dict[str, Any]:
-    """Health check endpoint."""
-    broker = deps.broker
-    trading_mode = await deps.settings.get("trading_mode", "research")
-    return {
-        "status": "healthy",
-        "broker_connected": broker.connected,
-        "trading_mode": trading_mode,
-    }
+    """Deep health check: readiness plus quarantined and failing jobs."""
+    return await HealthService(db=deps.db, broker=deps.broker, settings=deps.settings).deep()
 
 
 @router.get("/version")
@@ -90,6 +103,7 @@ async def run_backtest(
     pick_random: bool = True,
     random_count: int = 10,
     symbols: str = "",  # Comma-separated
+    sizing_mode: str = "",  # score or volatility_target ("" = current settings)
 ) -> StreamingResponse:
     """
     Run a backtest simulation via Server-Sent Events (SSE).
@@ -99,6 +113,9 @@ async def run_backtest(
     - result: Full backtest results when complete
     - error: Error message if something goes wrong
     """
+    if sizing_mode and sizing_mode not in SIZING_MODES:
+        raise HTTPException(status_code=400, detail=f"Sizing mode must be one of: {', '.join(SIZING_MODES)}")
+
     # Parse comma-separated symbols
     symbols_list = [s.strip() for s in symbols.split(",") if s.strip()] if symbols else []
 
@@ -112,6 +129,7 @@ async def run_backtest(
         pick_random=pick_random,
         random_count=random_count,
         symbols=symbols_list,
+        sizing_mode=sizing_mode or None,
     )
 
     backtester = Backtester(config)
@@ -158,6 +176,7 @@ async def run_backtest(
                                 "quantity": t.quantity,
                                 "price": t.price,
                                 "value": t.value,
+                                "fee": t.fee,
                             }
                             for t in update.trades
                         ],
@@ -169,6 +188,7 @@ async def run_backtest(
                         "cagr": update.cagr,
                         "max_drawdown": update.max_drawdown,
                         "sharpe_ratio": update.sharpe_ratio,
+                        "total_fees": update.total_fees,
                         "security_performance": [
                             {
                                 "symbol": sp.symbol,
@@ -0,0 +1,28 @@
+"""Anonymized telemetry export routes."""
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.telemetry import TelemetryService
+
+router = APIRouter(prefix="/telemetry", tags=["telemetry"])
+
+
+@router.get("/export")
+async def export_fingerprint(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    days: int = 365,
+) -> dict:
+    """
+    Anonymized strategy fingerprint for comparing outcomes with other users.
+
+    Contains only ratios, rates and counts (no symbols or amounts). Requires the
+    telemetry_export_enabled setting; nothing is sent anywhere by the server.
+    """
+    if days < 30 or days > 3650:
+        raise HTTPException(status_code=400, detail="days must be between 30 and 3650")
+    service = TelemetryService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    if not await service.enabled():
+        raise HTTPException(status_code=400, detail="Telemetry export is disabled (telemetry_export_enabled)")
+    return await service.export(days=days)
@@ -1,5 +1,6 @@
 """Trading API routes."""
 
+from datetime import datetime
 from typing import Optional
 
 from fastapi import APIRouter, Depends, HTTPException
@@ -8,10 +9,23 @@ from typing_extensions import Annotated
 from sentinel.api.dependencies import CommonDependencies, get_common_deps
 from sentinel.portfolio import Portfolio
 from sentinel.security import Security
+from sentinel.services.auto_invest import AutoInvestService
+from sentinel.services.dividends import DividendService
+from sentinel.services.drip import DripService
+from sentinel.services.order_slicing import OrderSlicingService
+from sentinel.services.protective_orders import ProtectiveOrderService, parse_protective_policies
+from sentinel.services.scheduled_orders import ScheduledOrderService
 
 router = APIRouter(prefix="/trades", tags=["trades"])
 cashflows_router = APIRouter(prefix="/cashflows", tags=["cashflows"])
+dividends_router = APIRouter(prefix="/dividends", tags=["dividends"])
 trading_actions_router = APIRouter(prefix="/securities", tags=["trading"])
+scheduled_orders_router = APIRouter(prefix="/scheduled-orders", tags=["trading"])
+protective_orders_router = APIRouter(prefix="/protective-orders", tags=["trading"])
+sliced_orders_router = APIRouter(prefix="/sliced-orders", tags=["trading"])
+
+# Status change endpoints -> status
+_STATUS_ACTIONS = {"pause": "paused", "resume": "active", "cancel": "cancelled"}
 
 
 @router.get("")
@@ -142,23 +156,244 @@ async def sync_cashflows_endpoint() -> dict:
     return result
 
 
+@cashflows_router.get("/auto-invest")
+async def get_auto_invest_runs(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    limit: int = 20,
+) -> dict:
+    """New deposits handled by the auto-invest policy and the buys chosen for them, most recent first."""
+    service = AutoInvestService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    return {"mode": await service.mode(), "runs": await service.runs(limit)}
+
+
+@dividends_router.get("")
+async def get_dividends(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    year: Optional[int] = None,
+    symbol: Optional[str] = None,
+) -> dict:
+    """Dividends with gross amount, withholding tax (amount and rate), net received and issuer country."""
+    return {"dividends": await DividendService(db=deps.db, settings=deps.settings).dividends(year, symbol)}
+
+
+@dividends_router.get("/withholding")
+async def get_withholding_summary(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    year: Optional[int] = None,
+) -> dict:
+    """
+    Yearly withholding tax summary for tax filing (default: last year).
+
+    Gross, withheld, net and reclaimable (withheld above the treaty rate)
+    dividend amounts in EUR per issuer country.
+    """
+    year = year or datetime.now().year - 1
+    return await DividendService(db=deps.db, settings=deps.settings).withholding_summary(year)
+
+
+@dividends_router.get("/drip")
+async def get_drip_modes(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Dividend reinvestment mode (same, redirect or cash) of every active security."""
+    service = DripService(db=deps.db, settings=deps.settings)
+    return {"default_mode": await deps.settings.get("drip_default_mode", "same"), "securities": await service.modes()}
+
+
+@dividends_router.put("/drip/{symbol}")
+async def set_drip_mode(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Set where a security's dividends are reinvested (body: {"mode": ...}; null for the default)."""
+    try:
+        return await DripService(db=deps.db, settings=deps.settings).set_mode(symbol, data.get("mode"))
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@dividends_router.get("/reinvestments")
+async def get_dividend_reinvestments(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbol: Optional[str] = None,
+    limit: int = 100,
+) -> dict:
+    """Audit trail of reinvestment decisions: mode, target security and reason per dividend."""
+    return {"decisions": await DripService(db=deps.db, settings=deps.settings).decisions(symbol, limit)}
+
+
 @trading_actions_router.post("/{symbol}/buy")
-async def buy_security(symbol: str, quantity: int) -> dict:
+async def buy_security(
+    symbol: str,
+    quantity: float,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
     """Buy a security."""
     security = Security(symbol)
     await security.load()
     order_id = await security.buy(quantity)
     if not order_id:
         raise HTTPException(status_code=400, detail="Buy order failed")
-    return {"order_id": order_id}
+    pricing = security.last_pricing or {}
+    await deps.db.record_trade_decision(
+        symbol,
+        "buy",
+        quantity,
+        "manual",
+        order_id=order_id,
+        currency=security.currency,
+        pricing_method=pricing.get("method"),
+        limit_price=pricing.get("limit_price"),
+    )
+    return {"order_id": order_id, "pricing": pricing}
 
 
 @trading_actions_router.post("/{symbol}/sell")
-async def sell_security(symbol: str, quantity: int) -> dict:
+async def sell_security(
+    symbol: str,
+    quantity: float,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
     """Sell a security."""
     security = Security(symbol)
     await security.load()
     order_id = await security.sell(quantity)
     if not order_id:
         raise HTTPException(status_code=400, detail="Sell order failed")
-    return {"order_id": order_id}
+    pricing = security.last_pricing or {}
+    await deps.db.record_trade_decision(
+        symbol,
+        "sell",
+        quantity,
+        "manual",
+        order_id=order_id,
+        currency=security.currency,
+        pricing_method=pricing.get("method"),
+        limit_price=pricing.get("limit_price"),
+    )
+    return {"order_id": order_id, "pricing": pricing}
+
+
+@scheduled_orders_router.get("")
+async def get_scheduled_orders(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: Optional[str] = None,
+) -> dict:
+    """Scheduled orders by next run date, with the outcome of their last run."""
+    service = ScheduledOrderService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    return {"orders": await service.orders(status)}
+
+
+@scheduled_orders_router.post("")
+async def create_scheduled_order(
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """
+    Schedule an order in advance.
+
+    Body examples:
+        {"frequency": "monthly", "day_of_month": 5, "symbols": ["VWCE.EU"], "amount_eur": 500}
+        {"frequency": "monthly", "day_of_month": 1, "group": {"type": "geography", "name": "US", "top_n": 2},
+         "amount_eur": 300, "timing": "open"}
+        {"frequency": "once", "run_date": "2026-11-02", "action": "sell", "symbols": ["AAPL.US"],
+         "quantity": 3, "timing": "close"}
+    """
+    service = ScheduledOrderService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    try:
+        return await service.create(data)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+
+
+@scheduled_orders_router.get("/{order_id}")
+async def get_scheduled_order(
+    order_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """One scheduled order, including the legs of a run in progress."""
+    order = await deps.db.get_scheduled_order(order_id)
+    if order is None:
+        raise HTTPException(status_code=404, detail="Scheduled order not found")
+    return order
+
+
+@scheduled_orders_router.post("/{order_id}/{action}")
+async def change_scheduled_order(
+    order_id: int,
+    action: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Pause, resume or cancel a scheduled order."""
+    if action not in _STATUS_ACTIONS:
+        raise HTTPException(status_code=400, detail="action must be pause, resume or cancel")
+    service = ScheduledOrderService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    try:
+        order = await service.set_status(order_id, _STATUS_ACTIONS[action])
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    if order is None:
+        raise HTTPException(status_code=404, detail="Scheduled order not found")
+    return order
+
+
+@protective_orders_router.get("")
+async def get_protective_orders(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Protective stop orders in force and the policies they follow."""
+    try:
+        policies = parse_protective_policies(await deps.settings.get("protective_order_policies", []) or [])
+    except ValueError:
+        policies = []
+    service = ProtectiveOrderService(db=deps.db, broker=deps.broker, settings=deps.settings)
+    return {
+        "enabled": bool(await deps.settings.get("protective_orders_enabled", False)),
+        "policies": policies,
+        "orders": await service.orders(),
+    }
+
+
+@protective_orders_router.get("/adjustments")
+async def get_protective_order_adjustments(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbol: Optional[str] = None,
+    limit: int = 100,
+) -> dict:
+    """Ledger of protective order placements, replacements and cancellations, newest first."""
+    service = ProtectiveOrderService(db=deps.db, broker=deps.broker, settings=deps.settings)
+    return {"adjustments": await service.adjustments(symbol, limit)}
+
+
+@protective_orders_router.post("/reconcile")
+async def reconcile_protective_orders(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Bring protective orders in line with the policies now instead of waiting for the daily job."""
+    service = ProtectiveOrderService(db=deps.db, broker=deps.broker, settings=deps.settings)
+    return await service.reconcile()
+
+
+@sliced_orders_router.get("")
+async def get_sliced_orders(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    status: Optional[str] = None,
+    limit: int = 100,
+) -> dict:
+    """Large orders sliced into child orders, newest first, with their aggregate execution status."""
+    service = OrderSlicingService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    return {
+        "enabled": bool(await deps.settings.get("order_slicing_enabled", False)),
+        "orders": await service.orders(status, limit),
+    }
+
+
+@sliced_orders_router.get("/{order_id}")
+async def get_sliced_order(order_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """One sliced order with its child orders."""
+    service = OrderSlicingService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    return await service.get(order_id)
+
+
+@sliced_orders_router.post("/{order_id}/cancel")
+async def cancel_sliced_order(order_id: int, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Cancel the child orders not placed yet; those already placed stay with the broker."""
+    service = OrderSlicingService(db=deps.db, settings=deps.settings, currency=deps.currency)
+    return await service.cancel(order_id)
@@ -0,0 +1,96 @@
+"""Watchlist routes: candidate securities, promotion into the universe and broker price alerts."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.watchlist import WatchlistService
+
+router = APIRouter(prefix="/watchlist", tags=["watchlist"])
+
+
+def _service(deps: CommonDependencies) -> WatchlistService:
+    return WatchlistService(db=deps.db, broker=deps.broker, settings=deps.settings)
+
+
+@router.get("")
+async def get_watchlist(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> list[dict]:
+    """Watched securities with their last opportunity score and signal tags."""
+    return await _service(deps).list()
+
+
+@router.post("")
+async def add_to_watchlist(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Watch a security: collect its metadata and price history and score it."""
+    try:
+        return await _service(deps).add(data.get("symbol", ""), note=data.get("note"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.post("/refresh")
+async def refresh_watchlist(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Update prices and signals now, notifying on score or tag changes."""
+    return await _service(deps).refresh()
+
+
+@router.get("/alerts")
+async def get_price_alerts(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    symbol: Optional[str] = None,
+) -> list[dict]:
+    """Price alerts set at the broker, optionally for one symbol."""
+    return await _service(deps).price_alerts(symbol)
+
+
+@router.delete("/alerts/{alert_id}")
+async def delete_price_alert(alert_id: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Delete a broker price alert."""
+    try:
+        await _service(deps).delete_price_alert(alert_id)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"status": "ok"}
+
+
+@router.post("/{symbol}/alerts")
+async def add_price_alert(
+    symbol: str,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Set a broker price alert (price, optional trigger_type) for a security."""
+    try:
+        alert_id = await _service(deps).add_price_alert(symbol, data.get("price"), data.get("trigger_type", "crossing"))
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e)) from e
+    except LookupError as e:
+        raise HTTPException(status_code=502, detail=str(e)) from e
+    return {"status": "ok", "alert_id": alert_id}
+
+
+@router.post("/{symbol}/promote")
+async def promote_watchlist_item(
+    symbol: str,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    data: Optional[dict] = None,
+) -> dict:
+    """Add a watched security to the universe with its collected metadata and history."""
+    try:
+        return await _service(deps).promote(symbol, data)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+
+
+@router.delete("/{symbol}")
+async def remove_from_watchlist(symbol: str, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Stop watching a security (collected prices are kept)."""
+    try:
+        await _service(deps).remove(symbol)
+    except LookupError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"status": "ok"}
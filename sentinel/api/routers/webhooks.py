@@ -0,0 +1,128 @@
+"""Webhook notification routes: endpoints, event switches and delivery log."""
+
+from typing import Optional
+
+from fastapi import APIRouter, Depends, HTTPException
+from typing_extensions import Annotated
+
+from sentinel.api.dependencies import CommonDependencies, get_common_deps
+from sentinel.services.webhooks import ENDPOINT_KINDS, EVENT_TYPES, WebhookService
+
+router = APIRouter(prefix="/webhooks", tags=["webhooks"])
+
+
+def _public(webhook: dict) -> dict:
+    """Endpoint without its secret."""
+    result = {k: v for k, v in webhook.items() if k != "secret"}
+    result["has_secret"] = bool(webhook.get("secret"))
+    return result
+
+
+def _validated(data: dict, partial: bool = False) -> dict:
+    """Endpoint fields from a request body, raising 400 on invalid input."""
+    fields = {k: data[k] for k in ("name", "kind", "url", "target", "secret", "events", "enabled") if k in data}
+    if not partial:
+        fields.setdefault("kind", "webhook")
+        if not fields.get("name"):
+            raise HTTPException(status_code=400, detail="name is required")
+    if "kind" in fields and fields["kind"] not in ENDPOINT_KINDS:
+        raise HTTPException(status_code=400, detail=f"kind must be one of {', '.join(ENDPOINT_KINDS)}")
+    kind = fields.get("kind")
+    if kind == "telegram":
+        fields.setdefault("url", "https://api.telegram.org")
+        if not partial and not (fields.get("target") and fields.get("secret")):
+            raise HTTPException(status_code=400, detail="telegram needs target (chat id) and secret (bot token)")
+    elif not partial and not fields.get("url"):
+        raise HTTPException(status_code=400, detail="url is required")
+    events = fields.get("events")
+    if events is not None:
+        if not isinstance(events, list) or any(e not in EVENT_TYPES for e in events):
+            raise HTTPException(status_code=400, detail=f"events must be a list of {', '.join(EVENT_TYPES)}")
+    return fields
+
+
+@router.get("")
+async def get_webhooks(deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """
+    List notification endpoints and the global event switches.
+
+    Returns:
+        webhooks: Endpoints (secrets are never returned, only has_secret)
+        events: Event type -> enabled
+    """
+    return {
+        "webhooks": [_public(w) for w in await deps.db.get_webhooks()],
+        "events": await WebhookService(db=deps.db, settings=deps.settings).event_switches(),
+    }
+
+
+@router.post("")
+async def create_webhook(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """
+    Add an endpoint.
+
+    Body: {"name", "kind": webhook|telegram|ntfy, "url", "target", "secret",
+    "events": [...] or null for all, "enabled"}
+    """
+    webhook_id = await deps.db.create_webhook(**_validated(data))
+    return _public(await deps.db.get_webhook(webhook_id))
+
+
+@router.put("/events")
+async def set_webhook_events(data: dict, deps: Annotated[CommonDependencies, Depends(get_common_deps)]) -> dict:
+    """Enable or disable event types for all endpoints. Body: {event_type: bool, ...}"""
+    unknown = [e for e in data if e not in EVENT_TYPES]
+    if unknown:
+        raise HTTPException(status_code=400, detail=f"Unknown event type: {', '.join(unknown)}")
+    service = WebhookService(db=deps.db, settings=deps.settings)
+    switches = {**await service.event_switches(), **{e: bool(v) for e, v in data.items()}}
+    await deps.settings.set("webhook_events", switches)
+    return {"events": switches}
+
+
+@router.get("/deliveries")
+async def get_webhook_deliveries(
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+    webhook_id: Optional[int] = None,
+    limit: int = 50,
+) -> dict:
+    """Recent deliveries with their status, attempts and last error, newest first."""
+    return {"deliveries": await deps.db.get_webhook_deliveries(webhook_id=webhook_id, limit=limit)}
+
+
+@router.put("/{webhook_id}")
+async def update_webhook(
+    webhook_id: int,
+    data: dict,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Update an endpoint. Omitted fields are left unchanged."""
+    fields = _validated(data, partial=True)
+    fields.pop("kind", None)
+    if not await deps.db.update_webhook(webhook_id, **fields):
+        raise HTTPException(status_code=404, detail="Webhook not found")
+    return _public(await deps.db.get_webhook(webhook_id))
+
+
+@router.delete("/{webhook_id}")
+async def delete_webhook(
+    webhook_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict[str, str]:
+    """Remove an endpoint and its pending deliveries."""
+    if not await deps.db.delete_webhook(webhook_id):
+        raise HTTPException(status_code=404, detail="Webhook not found")
+    return {"status": "ok"}
+
+
+@router.post("/{webhook_id}/test")
+async def test_webhook(
+    webhook_id: int,
+    deps: Annotated[CommonDependencies, Depends(get_common_deps)],
+) -> dict:
+    """Send a test notification to an endpoint now."""
+    try:
+        error = await WebhookService(db=deps.db, settings=deps.settings).send_test(webhook_id)
+    except ValueError as e:
+        raise HTTPException(status_code=404, detail=str(e)) from e
+    return {"status": "failed" if error else "delivered", "error": error}
@@ -14,41 +14,87 @@ from fastapi import FastAPI
 from fastapi.middleware.cors import CORSMiddleware
 from fastapi.staticfiles import StaticFiles
 
+from sentinel import correlation
+from sentinel.api.auth import AuthMiddleware
+from sentinel.api.correlation import CorrelationMiddleware
+from sentinel.api.dry_run import DryRunMiddleware
+from sentinel.api.errors import sentinel_error_handler
+
 # API routers
 from sentinel.api.routers import (
     allocation_router,
+    analytics_router,
+    approvals_router,
+    archive_router,
+    audit_router,
+    auth_router,
     backtest_router,
     backup_router,
     cache_router,
     cashflows_router,
+    charts_router,
+    computed_router,
+    config_router,
+    corporate_actions_router,
+    correlations_router,
+    dividends_router,
+    documents_router,
     exchange_rates_router,
+    exports_router,
+    external_holdings_router,
+    instruments_router,
     jobs_router,
+    journal_router,
     led_router,
+    lite_router,
+    logs_router,
     markets_router,
     meta_router,
+    metadata_router,
+    news_router,
+    notifications_router,
     planner_router,
     portfolio_router,
     prices_router,
+    profiling_router,
+    protective_orders_router,
+    public_router,
     pulse_router,
+    reconciliation_router,
+    regime_router,
+    reports_router,
+    risk_router,
+    scheduled_orders_router,
+    scores_router,
+    secrets_router,
     securities_router,
     set_scheduler,
     settings_router,
+    shadow_router,
+    sliced_orders_router,
     system_router,
     targets_router,
+    telemetry_router,
     trading_actions_router,
     trading_router,
     unified_router,
+    watchlist_router,
+    webhooks_router,
 )
-from sentinel.api.routers.settings import set_led_controller
+from sentinel.api.routers.settings import set_led_controller, set_screen_manager, set_trading_relay
 from sentinel.broker import Broker
 from sentinel.cache import Cache
 from sentinel.currency import Currency
 from sentinel.database import Database
+from sentinel.errors import SentinelError
 from sentinel.jobs import init as init_jobs
 from sentinel.jobs import stop as stop_jobs
 from sentinel.jobs.market import BrokerMarketChecker
 from sentinel.portfolio import Portfolio
+from sentinel.services.logs import get_log_buffer
 from sentinel.settings import Settings
+from sentinel.vault import Vault, VaultError
+from sentinel.vault import available as vault_available
 from sentinel.version import VERSION
 
 logger = logging.getLogger(__name__)
@@ -57,20 +103,45 @@ logger = logging.getLogger(__name__)
 _scheduler = None  # APScheduler instance
 _led_controller = None
 _led_task: asyncio.Task | None = None
+_relay = None
+_relay_task: asyncio.Task | None = None
+_screen = None
+_screen_task: asyncio.Task | None = None
+_health = None
+_watchdog_task: asyncio.Task | None = None
 
 
 @asynccontextmanager
 async def lifespan(app: FastAPI):
     """Initialize services on startup, cleanup on shutdown."""
-    global _scheduler, _led_controller, _led_task
+    global _scheduler, _led_controller, _led_task, _relay, _relay_task, _screen, _screen_task, _health, _watchdog_task
 
     # Startup
+    # Keep recent log records for /api/logs
+    get_log_buffer()
+
     db = Database()
     await db.connect()
 
     settings = Settings()
     await settings.init_defaults()
 
+    # Report every invalid setting at once (startup goes on so they can be fixed from the UI)
+    from sentinel.config.schema import format_report, validate_settings
+
+    problems = validate_settings(await settings.all())
+    if problems:
+        logger.error(format_report(problems))
+    # Baseline for POST /api/settings/reload to diff against
+    await settings.reload()
+
+    # Move plaintext credentials into the encrypted vault
+    if vault_available():
+        try:
+            await Vault(db=db, settings=settings).migrate_settings()
+        except (VaultError, OSError) as e:
+            logger.warning(f"Credentials vault unavailable, using plaintext settings: {e}")
+
     broker = Broker()
     await broker.connect()
 
@@ -118,9 +189,33 @@ async def lifespan(app: FastAPI):
     set_led_controller(_led_controller)
     _led_task = asyncio.create_task(_led_controller.start())
 
+    # Start GPIO trading indicator / kill switch (no-op unless gpio_relay_enabled)
+    from sentinel.led import TradingRelay
+
+    _relay = TradingRelay(broker=broker, db=db, settings=settings)
+    set_trading_relay(_relay)
+    _relay_task = asyncio.create_task(_relay.start())
+
+    # Start OLED / e-ink summary screen (no-op unless display_screen_enabled)
+    from sentinel.led import StateManager
+    from sentinel.services.health import HealthService
+
+    _health = HealthService(db=db, broker=broker, settings=settings)
+    _screen = StateManager(db=db, settings=settings, planner=planner, market_checker=market_checker, health=_health)
+    set_screen_manager(_screen)
+    _screen_task = asyncio.create_task(_screen.start())
+
+    # Apply settings that the loops above, the cold price tier and the broker read only once
+    unsubscribe_settings = _subscribe_to_settings(settings, db, broker)
+
+    # Tell systemd we are up and feed its watchdog while ready (no-op outside a Type=notify unit)
+    _watchdog_task = asyncio.create_task(_health.watchdog())
+
     yield
 
     # Shutdown
+    for unsubscribe in unsubscribe_settings:
+        unsubscribe()
     await stop_jobs()
     logger.info("Job scheduler stopped")
 
@@ -133,9 +228,87 @@ async def lifespan(app: FastAPI):
         except asyncio.CancelledError:
             pass
 
+    if _relay:
+        _relay.stop()
+    if _relay_task:
+        _relay_task.cancel()
+        try:
+            await _relay_task
+        except asyncio.CancelledError:
+            pass
+
+    if _screen:
+        _screen.stop()
+    if _screen_task:
+        _screen_task.cancel()
+        try:
+            await _screen_task
+        except asyncio.CancelledError:
+            pass
+
+    if _health:
+        _health.stop()
+    if _watchdog_task:
+        _watchdog_task.cancel()
+        try:
+            await _watchdog_task
+        except asyncio.CancelledError:
+            pass
+
     await db.close()
 
 
+async def _restart_loop(service, task: asyncio.Task | None) -> asyncio.Task:
+    """Stop a background loop and start it again, so it re-reads its settings."""
+    service.stop()
+    if task:
+        task.cancel()
+        try:
+            await task
+        except asyncio.CancelledError:
+            pass
+    return asyncio.create_task(service.start())
+
+
+def _subscribe_to_settings(settings: Settings, db: Database, broker: Broker) -> list:
+    """Restart or reconfigure long-lived consumers when the settings they cached change.
+
+    Returns:
+        Unsubscribe functions, called on shutdown
+    """
+    from sentinel.led import LEDController, StateManager, TradingRelay
+
+    async def restart_led(changed: dict) -> None:
+        global _led_task
+        logger.info("LED settings changed, restarting the LED controller")
+        _led_task = await _restart_loop(_led_controller, _led_task)
+
+    async def restart_relay(changed: dict) -> None:
+        global _relay_task
+        logger.info("GPIO settings changed, restarting the relay")
+        _relay_task = await _restart_loop(_relay, _relay_task)
+
+    async def restart_screen(changed: dict) -> None:
+        global _screen_task
+        logger.info("Summary screen settings changed, restarting the screen")
+        _screen_task = await _restart_loop(_screen, _screen_task)
+
+    async def reattach_cold_tier(changed: dict) -> None:
+        await db.configure_price_tiering()
+
+    async def reconnect_broker(changed: dict) -> None:
+        logger.info("Broker credentials changed, reconnecting")
+        await broker.reconnect()
+
+    return [
+        settings.subscribe(LEDController.SETTINGS_KEYS, restart_led),
+        settings.subscribe(TradingRelay.SETTINGS_KEYS, restart_relay),
+        settings.subscribe(StateManager.SCREEN_SETTINGS_KEYS, restart_screen),
+        settings.subscribe(("price_cold_storage_path", "price_hot_days"), reattach_cold_tier),
+        settings.subscribe(("tradernet_api_key", "tradernet_api_secret"), reconnect_broker),
+    ]
+
+
 async def _sync_missing_prices(db: Database, broker: Broker):
     """Sync historical prices for securities that don't have price data."""
     # Get all positions (these are the securities we care about)
@@ -149,7 +322,10 @@ async def _sync_missing_prices(db: Database, broker: Broker):
     # Check which symbols are missing price data
     missing = []
     for symbol in symbols:
-        cursor = await db.conn.execute("SELECT COUNT(*) as cnt FROM prices WHERE symbol = ?", (symbol,))
+        cursor = await db.conn.execute(
+            f"SELECT COUNT(*) as cnt FROM {db.price_source} WHERE symbol = ?",  # noqa: S608
+            (symbol,),
+        )
         row = await cursor.fetchone()
         if row is None or row["cnt"] < 100:  # Less than 100 days of data
             missing.append(symbol)
@@ -179,6 +355,12 @@ app = FastAPI(
     lifespan=lifespan,
 )
 
+# ?dry_run=true previews for mutating endpoints
+app.add_middleware(DryRunMiddleware)
+
+# Token auth and roles (no-op unless the auth_enabled setting is on)
+app.add_middleware(AuthMiddleware)
+
 # CORS for development
 app.add_middleware(
     CORSMiddleware,
@@ -186,23 +368,67 @@ app.add_middleware(
     allow_credentials=True,
     allow_methods=["*"],
     allow_headers=["*"],
+    expose_headers=[correlation.HEADER],
 )
 
+# Correlation ID per request, in response headers and log records (outermost, so every response has it)
+correlation.install_log_records()
+app.add_middleware(CorrelationMiddleware)
+
+# Domain errors become their HTTP status with the error code and category
+app.add_exception_handler(SentinelError, sentinel_error_handler)
+
 # Include API routers
 app.include_router(settings_router, prefix="/api")
 app.include_router(led_router, prefix="/api")
 app.include_router(portfolio_router, prefix="/api")
+app.include_router(external_holdings_router, prefix="/api")
 app.include_router(targets_router, prefix="/api")
 app.include_router(allocation_router, prefix="/api")
+app.include_router(charts_router, prefix="/api")
+app.include_router(computed_router, prefix="/api")
 app.include_router(securities_router, prefix="/api")
 app.include_router(prices_router, prefix="/api")
 app.include_router(unified_router, prefix="/api")
+app.include_router(watchlist_router, prefix="/api")
+app.include_router(metadata_router, prefix="/api")
+app.include_router(instruments_router, prefix="/api")
+app.include_router(scores_router, prefix="/api")
+app.include_router(shadow_router, prefix="/api")
+app.include_router(journal_router, prefix="/api")
+app.include_router(news_router, prefix="/api")
 app.include_router(trading_router, prefix="/api")
 app.include_router(cashflows_router, prefix="/api")
+app.include_router(exports_router, prefix="/api")
+app.include_router(dividends_router, prefix="/api")
 app.include_router(trading_actions_router, prefix="/api")
+app.include_router(scheduled_orders_router, prefix="/api")
+app.include_router(protective_orders_router, prefix="/api")
+app.include_router(sliced_orders_router, prefix="/api")
 app.include_router(planner_router, prefix="/api")
+app.include_router(approvals_router, prefix="/api")
 app.include_router(jobs_router, prefix="/api")
 app.include_router(backup_router, prefix="/api")
+app.include_router(archive_router, prefix="/api")
+app.include_router(audit_router, prefix="/api")
+app.include_router(auth_router, prefix="/api")
+app.include_router(secrets_router, prefix="/api")
+app.include_router(notifications_router, prefix="/api")
+app.include_router(webhooks_router, prefix="/api")
+app.include_router(reports_router, prefix="/api")
+app.include_router(reconciliation_router, prefix="/api")
+app.include_router(risk_router, prefix="/api")
+app.include_router(config_router, prefix="/api")
+app.include_router(correlations_router, prefix="/api")
+app.include_router(regime_router, prefix="/api")
+app.include_router(analytics_router, prefix="/api")
+app.include_router(documents_router, prefix="/api")
+app.include_router(corporate_actions_router, prefix="/api")
+app.include_router(lite_router, prefix="/api")
+app.include_router(logs_router, prefix="/api")
+app.include_router(public_router, prefix="/api")
+app.include_router(telemetry_router, prefix="/api")
+app.include_router(profiling_router, prefix="/api")
 app.include_router(system_router, prefix="/api")
 app.include_router(cache_router, prefix="/api")
 app.include_router(backtest_router, prefix="/api")
@@ -211,6 +437,38 @@ app.include_router(markets_router, prefix="/api")
 app.include_router(meta_router, prefix="/api")
 app.include_router(pulse_router, prefix="/api")
 
+# -----------------------------------------------------------------------------
+# Public dashboard app (separate port: only the read-only public routes)
+# -----------------------------------------------------------------------------
+
+
+@asynccontextmanager
+async def public_lifespan(app: FastAPI):
+    """Connect the shared database; the main app's lifespan owns everything else."""
+    await Database().connect()
+    yield
+
+
+public_app = FastAPI(
+    title="Sentinel dashboard",
+    version=VERSION,
+    lifespan=public_lifespan,
+    docs_url=None,
+    redoc_url=None,
+    openapi_url=None,
+)
+public_app.add_middleware(CorrelationMiddleware)
+public_app.include_router(public_router, prefix="/api")
+
+
+@public_app.get("/")
+async def public_root():
+    """Send wall displays straight to the dashboard page."""
+    from fastapi.responses import RedirectResponse
+
+    return RedirectResponse("/api/public/dashboard")
+
+
 # -----------------------------------------------------------------------------
 # Static Files (Web UI)
 # -----------------------------------------------------------------------------
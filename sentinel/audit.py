@@ -0,0 +1,79 @@
+"""Append-only audit log of trading actions.
+
+Order submissions, scheduled order changes, settings changes and manual
+overrides (position adjustments, approval decisions, circuit breaker resumes,
+job runs and quarantine releases) are written to the audit_log table with who
+did it, what, with which parameters and how it ended. The table rejects
+updates and deletes, and retention never prunes it.
+
+The actor comes from the running context:
+
+- api: an API request, with the token or user name (TUI requests, sent with
+  an X-Sentinel-Client: tui header, are recorded as tui)
+- job: a scheduled or manual job run, with the job type
+- system: anything else (startup, background loops)
+
+Actions in a dry-run session are not recorded, since nothing happened.
+"""
+
+from __future__ import annotations
+
+import logging
+from contextlib import contextmanager
+from contextvars import ContextVar
+from typing import Any, Iterator
+
+from sentinel import correlation
+
+logger = logging.getLogger(__name__)
+
+ACTOR_TYPES = ("api", "tui", "job", "system")
+OUTCOMES = ("success", "simulated", "failed", "rejected")
+
+_actor: ContextVar[tuple[str, str]] = ContextVar("sentinel_audit_actor", default=("system", "sentinel"))
+
+
+@contextmanager
+def acting_as(actor_type: str, actor: str) -> Iterator[None]:
+    """Attribute the actions of a block to an actor."""
+    token = _actor.set((actor_type, actor))
+    try:
+        yield
+    finally:
+        _actor.reset(token)
+
+
+def current_actor() -> tuple[str, str]:
+    """(actor_type, actor) of the running request or job."""
+    return _actor.get()
+
+
+async def record_action(
+    db,
+    action: str,
+    target: str | None = None,
+    params: dict[str, Any] | None = None,
+    outcome: str = "success",
+    error: str | None = None,
+) -> None:
+    """Append an entry for an action of the current actor. Never raises.
+
+    Args:
+        db: Database instance
+        action: Dotted action name (order.buy, settings.change, position.adjust, ...)
+        target: What it acted on (symbol, setting key, job type, order id)
+        params: Parameters of the action
+        outcome: success, simulated (research mode), failed or rejected
+        error: Why it failed or was rejected
+    """
+    from sentinel.dry_run import is_dry_run
+
+    if is_dry_run():
+        return
+    actor_type, actor = current_actor()
+    try:
+        await db.add_audit_entry(
+            actor_type, actor, action, target, params or {}, outcome, error, correlation.current()
+        )
+    except Exception as e:
+        logger.error(f"Failed to audit {action} on {target}: {e}")
@@ -32,6 +32,7 @@ from sentinel.broker import Broker
 from sentinel.database import Database
 from sentinel.database.simulation import SimulationDatabase
 from sentinel.price_validator import PriceValidator
+from sentinel.utils.fees import FeeCalculator, FeeSchedule
 
 
 def _calculate_max_drawdown(values: np.ndarray) -> float:
@@ -76,6 +77,9 @@ class BacktestConfig:
     pick_random: bool = True
     random_count: int = 10
     symbols: list[str] = field(default_factory=list)
+    # Position sizing mode for every sleeve (None = the live position_sizing_mode/overrides),
+    # so sizing models can be compared over the same period
+    sizing_mode: str | None = None
 
     def get_start_date(self) -> date:
         return datetime.strptime(self.start_date, "%Y-%m-%d").date()
@@ -117,9 +121,10 @@ class SimulatedTrade:
     date: str
     symbol: str
     action: str
-    quantity: int
+    quantity: float
     price: float
     value: float
+    fee: float = 0.0  # Commission and FX spread from the fee schedule (EUR)
 
 
 @dataclass
@@ -155,6 +160,7 @@ class BacktestResult:
     security_performance: list[SecurityPerformance]
     memory_entry_count: int = 0
     opportunity_buy_count: int = 0
+    total_fees: float = 0.0
 
 
 class BacktestDatabaseBuilder:
@@ -243,6 +249,10 @@ class BacktestDatabaseBuilder:
                 "INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", (row["key"], row["value"])
             )
 
+        if self.config.sizing_mode:
+            await self.temp_db.set_setting("position_sizing_mode", self.config.sizing_mode)
+            await self.temp_db.set_setting("position_sizing_overrides", {})
+
         # Copy allocation targets
         cursor = await self.real_db.conn.execute("SELECT type, name, weight FROM allocation_targets")
         rows = await cursor.fetchall()
@@ -496,10 +506,10 @@ class BacktestBroker:
 
         return {"positions": positions, "cash": cash}
 
-    async def buy(self, symbol: str, quantity: int) -> Optional[str]:
+    async def buy(self, symbol: str, quantity: float) -> Optional[str]:
         return f"BACKTEST-BUY-{symbol}-{quantity}"
 
-    async def sell(self, symbol: str, quantity: int) -> Optional[str]:
+    async def sell(self, symbol: str, quantity: float) -> Optional[str]:
         return f"BACKTEST-SELL-{symbol}-{quantity}"
 
 
@@ -520,6 +530,7 @@ class Backtester:
         self._planner = None
         self._portfolio = None
         self._currency = None
+        self._fee_schedule: Optional[FeeSchedule] = None
 
     def cancel(self):
         self._cancelled = True
@@ -573,6 +584,7 @@ class Backtester:
             from sentinel.portfolio import Portfolio
 
             self._currency = Currency()
+            self._fee_schedule = await FeeCalculator().get_schedule()
             self._portfolio = Portfolio(db=self._sim_db, broker=self._sim_broker)
             self._planner = Planner(
                 db=cast(Database, self._sim_db),
@@ -823,17 +835,18 @@ class Backtester:
 
         cost_local = quantity * price
         cost_eur = await currency.to_eur(cost_local, sec_currency)
+        fee = self._fee_schedule.cost(cost_eur, symbol, sec_currency) if self._fee_schedule else 0.0
 
         if action == "buy":
             # Check cash
             cash = await self._sim_db.get_cash_balances()
             cash_eur = cash.get("EUR", 0)
 
-            if cash_eur < cost_eur:
+            if cash_eur < cost_eur + fee:
                 return None
 
             # Deduct cash
-            await self._sim_db.set_cash_balance("EUR", cash_eur - cost_eur)
+            await self._sim_db.set_cash_balance("EUR", cash_eur - cost_eur - fee)
 
             # Update position
             pos = await self._sim_db.get_position(symbol)
@@ -850,7 +863,7 @@ class Backtester:
                     symbol, quantity=quantity, avg_cost=price, current_price=price, currency=sec_currency
                 )
 
-            tracking[symbol]["total_invested"] += cost_eur
+            tracking[symbol]["total_invested"] += cost_eur + fee
             tracking[symbol]["num_buys"] += 1
 
         elif action == "sell":
@@ -863,9 +876,9 @@ class Backtester:
 
             # Add proceeds
             cash = await self._sim_db.get_cash_balances()
-            await self._sim_db.set_cash_balance("EUR", cash.get("EUR", 0) + cost_eur)
+            await self._sim_db.set_cash_balance("EUR", cash.get("EUR", 0) + cost_eur - fee)
 
-            tracking[symbol]["total_sold"] += cost_eur
+            tracking[symbol]["total_sold"] += cost_eur - fee
             tracking[symbol]["num_sells"] += 1
 
         # Record trade in simulation database for cool-off tracking
@@ -885,6 +898,7 @@ class Backtester:
             quantity=quantity,
             price=price,
             executed_at=executed_at_ts,
+            commission=fee,
             raw_data={
                 "id": broker_trade_id,
                 "symbol": symbol,
@@ -905,6 +919,7 @@ class Backtester:
             quantity=quantity,
             price=price,
             value=cost_eur,
+            fee=fee,
         )
 
     async def _create_snapshot(self) -> PortfolioSnapshot:
@@ -1035,6 +1050,7 @@ class Backtester:
             security_performance=security_performance,
             memory_entry_count=memory_entry_count,
             opportunity_buy_count=opportunity_buy_count,
+            total_fees=sum(t.fee for t in trades),
         )
 
 
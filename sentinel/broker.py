@@ -8,17 +8,27 @@ Usage:
     await broker.buy('AAPL.US', quantity=10)
 """
 
+import asyncio
 import json
 import logging
 from datetime import datetime, timedelta
 from typing import Optional
 
+from sentinel.audit import record_action
 from sentinel.database import Database
+from sentinel.dry_run import is_dry_run, record_side_effect
 from sentinel.settings import Settings
 from sentinel.utils.decorators import singleton
+from sentinel.utils.identity import extract_isin
 
 logger = logging.getLogger(__name__)
 
+# Client request (CPS) type of corporate action elections
+CORPORATE_ACTION_CPS_TYPE = "corporate_actions"
+
+# Price alert triggers accepted by addPriceAlert
+PRICE_ALERT_TRIGGERS = ("crossing", "crossing_up", "crossing_down", "greater", "lower")
+
 
 @singleton
 class Broker:
@@ -59,8 +69,11 @@ class Broker:
         if self._api is not None:
             return True
 
-        api_key = await self._settings.get("tradernet_api_key")
-        api_secret = await self._settings.get("tradernet_api_secret")
+        from sentinel.vault import Vault
+
+        vault = Vault(db=self._db, settings=self._settings)
+        api_key = await vault.get_secret("tradernet_api_key")
+        api_secret = await vault.get_secret("tradernet_api_secret")
 
         if not api_key or not api_secret:
             return False
@@ -75,11 +88,27 @@ class Broker:
             logger.error(f"Failed to connect to Tradernet: {e}")
             return False
 
+    async def reconnect(self) -> bool:
+        """Drop the current API clients and connect again with freshly loaded credentials."""
+        self._api = None
+        self._trading = None
+        return await self.connect()
+
     @property
     def connected(self) -> bool:
         """Check if connected to broker."""
         return self._api is not None
 
+    async def ping(self) -> bool:
+        """Make a lightweight authenticated API call. False when not connected or the call fails."""
+        if not self._api:
+            return False
+        try:
+            return bool(self._api.account_summary())
+        except Exception as e:
+            logger.warning(f"Broker ping failed: {e}")
+            return False
+
     # -------------------------------------------------------------------------
     # Market Data
     # -------------------------------------------------------------------------
@@ -130,6 +159,37 @@ class Broker:
             logger.error(f"Failed to get quotes: {e}")
             return {}
 
+    async def get_order_book(self, symbol: str, timeout: float = 5.0) -> Optional[dict]:
+        """Get current market depth for a symbol.
+
+        Reads the first snapshot of the Tradernet orderBook websocket stream.
+        Returns None if not connected, on timeout or error, or if the book is empty.
+
+        Returns:
+            {"bids": [(price, qty), ...], "asks": [(price, qty), ...]}, best levels first
+        """
+        if not self._api:
+            return None
+        from sentinel.utils.order_pricing import parse_order_book
+
+        async def first_snapshot():
+            from tradernet import TraderNetWSAPI
+
+            async with TraderNetWSAPI(self._api) as ws:
+                async for data in ws.market_depth(symbol):
+                    return data
+            return None
+
+        try:
+            raw = await asyncio.wait_for(first_snapshot(), timeout=timeout)
+        except Exception as e:
+            logger.warning(f"Failed to get order book for {symbol}: {e}")
+            return None
+        book = parse_order_book(raw)
+        if not book["bids"] and not book["asks"]:
+            return None
+        return book
+
     async def get_historical_prices(self, symbol: str, days: int = 365) -> list[dict]:
         """Get historical prices for a symbol."""
         if not self._api:
@@ -231,6 +291,7 @@ class Broker:
                     positions.append(
                         {
                             "symbol": pos.get("i"),  # instrument
+                            "isin": extract_isin(pos),
                             "quantity": pos.get("q"),
                             "avg_cost": pos.get("bal_price_a"),  # average cost
                             "current_price": pos.get("mkt_price"),
@@ -260,22 +321,43 @@ class Broker:
         mode = await self._settings.get("trading_mode", "research")
         return mode == "live"
 
-    async def buy(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]:
+    async def _audit_order(
+        self,
+        side: str,
+        symbol: str,
+        quantity: float,
+        price: float | None,
+        order_id: str | None,
+        outcome: str,
+        error: str | None = None,
+    ) -> None:
+        params = {"quantity": quantity, "price": price, "order_id": order_id}
+        await record_action(self._db, f"order.{side}", symbol, params, outcome, error)
+
+    async def buy(self, symbol: str, quantity: float, price: float | None = None) -> Optional[str]:
         """Place a buy order. Returns order ID if successful.
 
         Args:
             symbol: The security symbol
-            quantity: Number of shares to buy
+            quantity: Number of shares to buy (may be fractional)
             price: Limit price (optional). If provided, places a limit order.
 
         In research mode, returns a simulated order ID without executing.
+        In a dry run, records the order and returns a placeholder ID.
         """
+        if is_dry_run():
+            record_side_effect("order", action="buy", symbol=symbol, quantity=quantity, price=price)
+            return f"DRY-RUN-BUY-{symbol}-{quantity}"
+
         if not await self._is_live_mode():
             price_info = f" @ {price}" if price else ""
             logger.debug(f"[RESEARCH MODE] Would buy {quantity} of {symbol}{price_info}")
-            return f"RESEARCH-BUY-{symbol}-{quantity}"
+            order_id = f"RESEARCH-BUY-{symbol}-{quantity}"
+            await self._audit_order("buy", symbol, quantity, price, order_id, "simulated")
+            return order_id
 
         if not self._trading:
+            await self._audit_order("buy", symbol, quantity, price, None, "failed", "Broker not connected")
             return None
         try:
             if price is not None:
@@ -283,27 +365,41 @@ class Broker:
             else:
                 response = self._trading.buy(symbol, quantity=quantity)
             logger.info(f"Buy {symbol} response: {response}")
-            return response.get("order_id") if response else None
         except Exception as e:
             logger.error(f"Failed to buy {symbol}: {e}")
+            await self._audit_order("buy", symbol, quantity, price, None, "failed", str(e))
             return None
-
-    async def sell(self, symbol: str, quantity: int, price: float | None = None) -> Optional[str]:
+        order_id = response.get("order_id") if response else None
+        if order_id:
+            await self._audit_order("buy", symbol, quantity, price, order_id, "success")
+        else:
+            await self._audit_order("buy", symbol, quantity, price, None, "rejected", str(response or "No response"))
+        return order_id
+
+    async def sell(self, symbol: str, quantity: float, price: float | None = None) -> Optional[str]:
         """Place a sell order. Returns order ID if successful.
 
         Args:
             symbol: The security symbol
-            quantity: Number of shares to sell
+            quantity: Number of shares to sell (may be fractional)
             price: Limit price (optional). If provided, places a limit order.
 
         In research mode, returns a simulated order ID without executing.
+        In a dry run, records the order and returns a placeholder ID.
         """
+        if is_dry_run():
+            record_side_effect("order", action="sell", symbol=symbol, quantity=quantity, price=price)
+            return f"DRY-RUN-SELL-{symbol}-{quantity}"
+
         if not await self._is_live_mode():
             price_info = f" @ {price}" if price else ""
             logger.debug(f"[RESEARCH MODE] Would sell {quantity} of {symbol}{price_info}")
-            return f"RESEARCH-SELL-{symbol}-{quantity}"
+            order_id = f"RESEARCH-SELL-{symbol}-{quantity}"
+            await self._audit_order("sell", symbol, quantity, price, order_id, "simulated")
+            return order_id
 
         if not self._trading:
+            await self._audit_order("sell", symbol, quantity, price, None, "failed", "Broker not connected")
             return None
         try:
             if price is not None:
@@ -311,10 +407,86 @@ class Broker:
             else:
                 response = self._trading.sell(symbol, quantity=quantity)
             logger.info(f"Sell {symbol} response: {response}")
-            return response.get("order_id") if response else None
         except Exception as e:
             logger.error(f"Failed to sell {symbol}: {e}")
+            await self._audit_order("sell", symbol, quantity, price, None, "failed", str(e))
             return None
+        order_id = response.get("order_id") if response else None
+        if order_id:
+            await self._audit_order("sell", symbol, quantity, price, order_id, "success")
+        else:
+            await self._audit_order("sell", symbol, quantity, price, None, "rejected", str(response or "No response"))
+        return order_id
+
+    async def _protective_order(self, kind: str, symbol: str, place, **params) -> Optional[str]:
+        """Place a stop-loss or trailing stop on a whole position (see stop_loss, trailing_stop)."""
+        if is_dry_run():
+            record_side_effect("order", action=kind, symbol=symbol, **params)
+            return f"DRY-RUN-{kind.upper()}-{symbol}"
+
+        if not await self._is_live_mode():
+            logger.debug(f"[RESEARCH MODE] Would place {kind} on {symbol}: {params}")
+            order_id = f"RESEARCH-{kind.upper()}-{symbol}"
+            await record_action(self._db, f"order.{kind}", symbol, {**params, "order_id": order_id}, "simulated")
+            return order_id
+
+        if not self._trading:
+            await record_action(self._db, f"order.{kind}", symbol, params, "failed", "Broker not connected")
+            return None
+        try:
+            response = place()
+            logger.info(f"{kind} {symbol} response: {response}")
+        except Exception as e:
+            logger.error(f"Failed to place {kind} on {symbol}: {e}")
+            await record_action(self._db, f"order.{kind}", symbol, params, "failed", str(e))
+            return None
+        order_id = response.get("order_id") if response else None
+        if order_id:
+            await record_action(self._db, f"order.{kind}", symbol, {**params, "order_id": order_id})
+        else:
+            await record_action(self._db, f"order.{kind}", symbol, params, "rejected", str(response or "No response"))
+        return str(order_id) if order_id else None
+
+    async def stop_loss(self, symbol: str, price: float) -> Optional[str]:
+        """Place a stop-loss selling the position when the price falls to `price`. Returns the order ID.
+
+        In research mode, returns a simulated order ID without placing anything.
+        In a dry run, records the order and returns a placeholder ID.
+        """
+        return await self._protective_order("stop_loss", symbol, lambda: self._trading.stop(symbol, price), price=price)
+
+    async def trailing_stop(self, symbol: str, percent: float) -> Optional[str]:
+        """Place a trailing stop selling the position once the price falls `percent` below its high.
+
+        In research mode, returns a simulated order ID without placing anything.
+        In a dry run, records the order and returns a placeholder ID.
+        """
+        return await self._protective_order(
+            "trailing_stop", symbol, lambda: self._trading.trailing_stop(symbol, percent), percent=percent
+        )
+
+    async def cancel_order(self, order_id: str) -> bool:
+        """Cancel a placed order. Simulated orders (research mode, dry runs) cancel trivially."""
+        if is_dry_run():
+            record_side_effect("order_cancel", order_id=order_id)
+            return True
+        if not await self._is_live_mode() or not str(order_id).isdigit():
+            await record_action(self._db, "order.cancel", order_id, {}, "simulated")
+            return True
+        if not self._trading:
+            await record_action(self._db, "order.cancel", order_id, {}, "failed", "Broker not connected")
+            return False
+        try:
+            response = self._trading.cancel(int(order_id))
+        except Exception as e:
+            logger.error(f"Failed to cancel order {order_id}: {e}")
+            await record_action(self._db, "order.cancel", order_id, {}, "failed", str(e))
+            return False
+        if not response or response.get("error") or response.get("errMsg"):
+            await record_action(self._db, "order.cancel", order_id, {}, "rejected", str(response or "No response"))
+            return False
+        await record_action(self._db, "order.cancel", order_id)
+        return True
 
     async def get_order_status(self, order_id: str) -> Optional[dict]:
         """Get status of an order."""
@@ -324,7 +496,7 @@ class Broker:
             placed = self._trading.get_placed()
             if placed:
                 for order in placed.get("orders", []):
-                    if order.get("id") == order_id:
+                    if str(order.get("id")) == str(order_id):
                         return order
             return None
         except Exception as e:
@@ -507,6 +679,195 @@ class Broker:
             logger.error(f"Failed to get corporate actions: {e}")
             return []
 
+    async def submit_corporate_action_election(
+        self,
+        corporate_action_id: str,
+        option: str,
+        quantity: float | None = None,
+    ) -> Optional[str]:
+        """Submit an election (chosen option) for a voluntary corporate action.
+
+        Elections are client requests (CPS) of the corporate actions type. Returns
+        the broker request ID if accepted. Like orders, research mode returns a
+        simulated ID and a dry run only records the election.
+
+        Args:
+            corporate_action_id: corporate_action_id from the corporate actions report
+            option: Option code offered by the action (e.g. 'cash', 'stock')
+            quantity: Shares the election applies to (None = the whole holding)
+        """
+        if is_dry_run():
+            record_side_effect("election", corporate_action_id=corporate_action_id, option=option, quantity=quantity)
+            return f"DRY-RUN-ELECTION-{corporate_action_id}"
+
+        if not await self._is_live_mode():
+            logger.debug(f"[RESEARCH MODE] Would elect '{option}' for corporate action {corporate_action_id}")
+            return f"RESEARCH-ELECTION-{corporate_action_id}"
+
+        if not self._api:
+            return None
+        params: dict = {
+            "type_doc_id": CORPORATE_ACTION_CPS_TYPE,
+            "corporate_action_id": corporate_action_id,
+            "option": option,
+        }
+        if quantity is not None:
+            params["quantity"] = quantity
+        try:
+            response = self._api.authorized_request("putCps", params)
+            logger.info(f"Corporate action {corporate_action_id} election response: {response}")
+            if not response or response.get("error") or response.get("errMsg"):
+                return None
+            return str(response.get("id") or response.get("cps_id") or "") or None
+        except Exception as e:
+            logger.error(f"Failed to submit election for corporate action {corporate_action_id}: {e}")
+            return None
+
+    async def get_order_files(self, order_id: str) -> list[dict]:
+        """
+        Fetch the files attached to an order or client request (getCpsFiles).
+
+        Returns:
+            List of {file_name, mime, extension, content (bytes)}
+        """
+        if not self._api:
+            return []
+        try:
+            response = self._api.authorized_request("getCpsFiles", {"id": int(order_id)})
+        except Exception as e:
+            logger.error(f"Failed to get files for order {order_id}: {e}")
+            return []
+        if not response or "files" not in response:
+            if response and response.get("error"):
+                logger.warning(f"No files for order {order_id}: {response['error']}")
+            return []
+
+        import base64
+
+        files = []
+        for entry in response.get("files") or []:
+            raw = str(entry.get("file") or "")
+            try:
+                content = base64.b64decode(raw.split("base64=", 1)[-1])
+            except ValueError:
+                logger.warning(f"Skipping undecodable file {entry.get('file_name')} of order {order_id}")
+                continue
+            files.append(
+                {
+                    "file_name": entry.get("file_name") or f"order_{order_id}.{entry.get('extension') or 'bin'}",
+                    "mime": entry.get("mime") or "application/octet-stream",
+                    "extension": entry.get("extension") or "",
+                    "content": content,
+                }
+            )
+        return files
+
+    async def get_broker_report_file(self, start_date: str, end_date: str, fmt: str = "pdf") -> Optional[bytes]:
+        """
+        Download the broker report for a period as a document.
+
+        Args:
+            start_date: Start date in YYYY-MM-DD format
+            end_date: End date in YYYY-MM-DD format
+            fmt: pdf, html, xml or xls
+        """
+        if not self._api:
+            return None
+        try:
+            response = self._api.authorized_request(
+                "getBrokerReport", {"date_start": start_date, "date_end": end_date, "format": fmt}
+            )
+        except Exception as e:
+            logger.error(f"Failed to get {fmt} broker report {start_date}..{end_date}: {e}")
+            return None
+        if isinstance(response, (bytes, bytearray)):
+            return bytes(response)
+        if isinstance(response, str):
+            return response.encode()
+        logger.warning(f"Broker report {start_date}..{end_date} not returned as a document: {response}")
+        return None
+
+    async def get_news(self, symbol: str, limit: int = 20) -> list[dict]:
+        """
+        Fetch recent news on a security (getNews).
+
+        Returns:
+            List of news entries as returned by Tradernet, newest first
+        """
+        if not self._api:
+            return []
+        try:
+            response = self._api.authorized_request("getNews", {"ticker": symbol, "limit": limit})
+        except Exception as e:
+            logger.error(f"Failed to get news for {symbol}: {e}")
+            return []
+        if isinstance(response, dict):
+            response = response.get("news", response.get("result", []))
+        return [entry for entry in response or [] if isinstance(entry, dict)] if isinstance(response, list) else []
+
+    async def get_price_alerts(self, symbol: str | None = None) -> list[dict]:
+        """
+        Fetch the price alerts set at the broker (getAlertsList), optionally for one symbol.
+
+        Returns:
+            List of alert entries as returned by Tradernet
+        """
+        if not self._api:
+            return []
+        try:
+            response = self._api.authorized_request("getAlertsList", {"ticker": symbol} if symbol else {})
+        except Exception as e:
+            logger.error(f"Failed to get price alerts: {e}")
+            return []
+        if isinstance(response, dict):
+            alerts = response.get("alerts", [])
+            return list(alerts.values()) if isinstance(alerts, dict) else list(alerts or [])
+        return []
+
+    async def add_price_alert(self, symbol: str, price: float, trigger_type: str = "crossing") -> Optional[str]:
+        """
+        Set a broker price alert (addPriceAlert) on the last trade price, notified by the broker app.
+
+        Returns the alert ID if accepted. A dry run only records the alert.
+        """
+        if is_dry_run():
+            record_side_effect("price_alert", symbol=symbol, price=price, trigger_type=trigger_type)
+            return f"DRY-RUN-ALERT-{symbol}"
+        if not self._api:
+            return None
+        params = {
+            "ticker": symbol,
+            "price": [price],
+            "trigger_type": trigger_type,
+            "quote_type": "ltp",
+            "notification_type": "push",
+            "alert_period": 0,
+            "expire": 0,
+        }
+        try:
+            response = self._api.authorized_request("addPriceAlert", params)
+        except Exception as e:
+            logger.error(f"Failed to add price alert for {symbol}: {e}")
+            return None
+        if not response or response.get("error") or response.get("errMsg"):
+            logger.warning(f"Price alert for {symbol} rejected: {response}")
+            return None
+        return str(response.get("id") or response.get("alert_id") or "") or None
+
+    async def delete_price_alert(self, alert_id: str) -> bool:
+        """Delete a broker price alert. A dry run only records the deletion."""
+        if is_dry_run():
+            record_side_effect("price_alert_delete", alert_id=alert_id)
+            return True
+        if not self._api:
+            return False
+        try:
+            response = self._api.authorized_request("addPriceAlert", {"id": alert_id, "del": True})
+        except Exception as e:
+            logger.error(f"Failed to delete price alert {alert_id}: {e}")
+            return False
+        return bool(response) and not response.get("error") and not response.get("errMsg")
+
     async def get_available_securities(self) -> list[str]:
         """
         Get list of top tradeable EU securities from Tradernet API.
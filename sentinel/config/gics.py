@@ -0,0 +1,154 @@
+"""GICS Configuration - Sector / industry group taxonomy.
+
+Two-level GICS hierarchy (sector -> industry group) used to classify securities
+and to express hierarchical allocation caps (e.g. Information Technology <= 25%,
+within it Semiconductors & Semiconductor Equipment <= 10%). Industry group codes
+start with their parent sector code.
+"""
+
+# (code, name)
+GICS_SECTORS = [
+    ("10", "Energy"),
+    ("15", "Materials"),
+    ("20", "Industrials"),
+    ("25", "Consumer Discretionary"),
+    ("30", "Consumer Staples"),
+    ("35", "Health Care"),
+    ("40", "Financials"),
+    ("45", "Information Technology"),
+    ("50", "Communication Services"),
+    ("55", "Utilities"),
+    ("60", "Real Estate"),
+]
+
+# (code, name); parent sector is code[:2]
+GICS_INDUSTRY_GROUPS = [
+    ("1010", "Energy"),
+    ("1510", "Materials"),
+    ("2010", "Capital Goods"),
+    ("2020", "Commercial & Professional Services"),
+    ("2030", "Transportation"),
+    ("2510", "Automobiles & Components"),
+    ("2520", "Consumer Durables & Apparel"),
+    ("2530", "Consumer Services"),
+    ("2550", "Consumer Discretionary Distribution & Retail"),
+    ("3010", "Consumer Staples Distribution & Retail"),
+    ("3020", "Food, Beverage & Tobacco"),
+    ("3030", "Household & Personal Products"),
+    ("3510", "Health Care Equipment & Services"),
+    ("3520", "Pharmaceuticals, Biotechnology & Life Sciences"),
+    ("4010", "Banks"),
+    ("4020", "Financial Services"),
+    ("4030", "Insurance"),
+    ("4510", "Software & Services"),
+    ("4520", "Technology Hardware & Equipment"),
+    ("4530", "Semiconductors & Semiconductor Equipment"),
+    ("5010", "Telecommunication Services"),
+    ("5020", "Media & Entertainment"),
+    ("5510", "Utilities"),
+    ("6010", "Equity Real Estate Investment Trusts (REITs)"),
+    ("6020", "Real Estate Management & Development"),
+]
+
+# Free-text industry keywords -> GICS code, most specific first.
+# Matched as lowercase substrings of the security's industry/sector labels.
+GICS_KEYWORDS = [
+    ("semiconductor", "4530"),
+    ("chip", "4530"),
+    ("software", "4510"),
+    ("it services", "4510"),
+    ("cloud", "4510"),
+    ("hardware", "4520"),
+    ("electronic", "4520"),
+    ("bank", "4010"),
+    ("insurance", "4030"),
+    ("asset management", "4020"),
+    ("payment", "4020"),
+    ("pharma", "3520"),
+    ("biotech", "3520"),
+    ("life science", "3520"),
+    ("medical", "3510"),
+    ("health care equipment", "3510"),
+    ("oil", "1010"),
+    ("energy", "1010"),
+    ("mining", "1510"),
+    ("chemical", "1510"),
+    ("metal", "1510"),
+    ("materials", "1510"),
+    ("aerospace", "2010"),
+    ("defense", "2010"),
+    ("defence", "2010"),
+    ("machinery", "2010"),
+    ("construction", "2010"),
+    ("airline", "2030"),
+    ("logistics", "2030"),
+    ("transport", "2030"),
+    ("automobile", "2510"),
+    ("automotive", "2510"),
+    ("apparel", "2520"),
+    ("luxury", "2520"),
+    ("restaurant", "2530"),
+    ("hotel", "2530"),
+    ("leisure", "2530"),
+    ("e-commerce", "2550"),
+    ("retail", "2550"),
+    ("beverage", "3020"),
+    ("food", "3020"),
+    ("tobacco", "3020"),
+    ("household", "3030"),
+    ("personal products", "3030"),
+    ("telecom", "5010"),
+    ("media", "5020"),
+    ("entertainment", "5020"),
+    ("interactive", "5020"),
+    ("utilit", "5510"),
+    ("reit", "6010"),
+    ("real estate", "6020"),
+    ("technology", "45"),
+    ("tech", "45"),
+    ("financ", "40"),
+    ("health", "35"),
+    ("industrial", "20"),
+    ("consumer staples", "30"),
+    ("consumer discretionary", "25"),
+    ("consumer", "25"),
+    ("communication", "50"),
+]
+
+_NAMES = {name.lower(): code for code, name in GICS_SECTORS + GICS_INDUSTRY_GROUPS}
+
+
+def gics_parent(code: str) -> str | None:
+    """Parent code of a GICS code (industry group -> sector), None for sectors."""
+    return code[:-2] if len(code) > 2 else None
+
+
+def gics_path(code: str | None) -> list[str]:
+    """All codes from the given code up to its sector, e.g. '4530' -> ['4530', '45']."""
+    path = []
+    while code:
+        path.append(code)
+        code = gics_parent(code)
+    return path
+
+
+def classify_gics(*labels: str | None) -> str | None:
+    """
+    Map free-text industry/sector labels to the most specific GICS code.
+
+    Each label may be comma-separated. Exact taxonomy names win over keywords.
+
+    Returns:
+        GICS code, or None if nothing matches
+    """
+    values = [v.strip().lower() for label in labels if label for v in str(label).split(",") if v.strip()]
+    for value in values:
+        # Exact name match prefers the industry group over a sector with the same name
+        if value in _NAMES:
+            matches = [code for code, name in GICS_INDUSTRY_GROUPS if name.lower() == value]
+            return matches[0] if matches else _NAMES[value]
+    for value in values:
+        for keyword, code in GICS_KEYWORDS:
+            if keyword in value:
+                return code
+    return None
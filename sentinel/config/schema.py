@@ -0,0 +1,422 @@
+"""
+Settings schema - types, ranges and cross-field rules for configuration.
+
+Settings live in the database with DEFAULTS as fallback, so a bad value
+(a negative interval, core and opportunity targets that do not add up to 100,
+an R2 account with the bucket missing) used to surface only when the job that
+reads it ran. validate_settings() checks every rule and returns all problems at
+once; the app logs them as one report at startup and serves them from
+GET /api/settings/validation, and PUT /api/settings/{key} rejects values that
+break a per-key rule.
+
+Usage:
+    problems = validate_settings(await settings.all())
+    if problems:
+        logger.error(format_report(problems))
+"""
+
+from __future__ import annotations
+
+from dataclasses import asdict, dataclass
+from typing import Any, Callable, Optional
+
+
+@dataclass
+class Rule:
+    """Type and range of one setting."""
+
+    kind: str  # int, number, bool, str, dict or list
+    min: Optional[float] = None
+    max: Optional[float] = None
+    choices: Optional[tuple] = None
+    nullable: bool = False
+
+
+@dataclass
+class Problem:
+    """A setting that breaks the schema, with the fix to apply."""
+
+    key: str
+    message: str
+
+    def as_dict(self) -> dict:
+        return asdict(self)
+
+
+def _int(lo: Optional[float] = None, hi: Optional[float] = None, nullable: bool = False) -> Rule:
+    return Rule("int", lo, hi, nullable=nullable)
+
+
+def _num(lo: Optional[float] = None, hi: Optional[float] = None, nullable: bool = False) -> Rule:
+    return Rule("number", lo, hi, nullable=nullable)
+
+
+def _choice(*choices: str) -> Rule:
+    return Rule("str", choices=choices)
+
+
+_BOOL = Rule("bool")
+_STR = Rule("str")
+_DICT = Rule("dict")
+_LIST = Rule("list")
+
+SCHEMA: dict[str, Rule] = {
+    "trading_mode": _choice("research", "live"),
+    "trading_manual_approval": _BOOL,
+    "approval_valid_hours": _num(0),
+    "approval_defer_hours": _num(0),
+    "scheduled_orders_window_minutes": _num(1, 720),
+    "scheduled_orders_grace_days": _int(0),
+    "auto_invest_mode": _choice("off", "plan", "execute"),
+    "auto_invest_deposits": _choice("all", "recurring"),
+    "auto_invest_cash_buffer_eur": _num(0),
+    "auto_invest_min_order_eur": _num(0),
+    "auto_invest_max_age_days": _int(0),
+    "transaction_fee_fixed": _num(0),
+    "transaction_fee_percent": _num(0, 100),
+    "fee_schedule": _DICT,
+    "fx_conversion_spread_pct": _num(0, 100),
+    "limit_pricing_depth_enabled": _BOOL,
+    "limit_depth_thin_value_eur": _num(0),
+    "limit_depth_max_slippage_pct": _num(0, 100),
+    "order_pricing_strategies": _DICT,
+    "order_slicing_enabled": _BOOL,
+    "order_slice_max_volume_pct": _num(0, 100),
+    "order_slice_max_notional_eur": _num(0),
+    "order_slice_max_children": _int(2, 50),
+    "order_slice_interval_minutes": _int(1),
+    "limit_slippage_auto_calibrate": _BOOL,
+    "execution_quality_lookback_days": _int(1),
+    "max_position_pct": _num(0, 100),
+    "min_position_pct": _num(0, 100),
+    "min_trade_value": _num(0),
+    "min_cash_buffer": _num(0, 1),
+    "target_cash_pct": _num(0, 100),
+    "simulated_cash_eur": _num(0, nullable=True),
+    "rebalance_strategy": _choice("continuous", "calendar", "threshold", "opportunistic"),
+    "rebalance_calendar_period": _choice("monthly", "quarterly", "semiannual", "annual"),
+    "rebalance_calendar_window_days": _int(1, 366),
+    "rebalance_threshold_pct": _num(0, 100),
+    "rebalance_band_mode": _choice("absolute", "relative"),
+    "planner_batch_cache_ttl_seconds": _int(0),
+    "planner_snapshot_retention": _int(0),
+    "planner_context_history": _int(0),
+    "planner_max_score_age_days": _num(0),
+    "planner_max_price_age_days": _num(0),
+    "planner_stale_penalty": _num(0, 1),
+    "planner_candidate_cap_max": _int(0),
+    "planner_min_free_memory_mb": _num(0),
+    "planner_target_eval_seconds": _num(0),
+    "planner_candidate_cap_floor": _int(0),
+    "planner_time_budget_seconds": _num(0),
+    "planner_scoring_workers": _int(0, 64),
+    "diversification_impact_pct": _num(0, 100),
+    "correlation_diversification_weight": _num(0, 1),
+    "correlation_window_days": _int(2),
+    "allocation_optimizer_method": _choice("risk_parity", "mean_variance"),
+    "allocation_optimizer_risk_aversion": _num(0),
+    "allocation_optimizer_min_weight_pct": _num(0, 100),
+    "allocation_optimizer_max_weight_pct": _num(0, 100),
+    "allocation_optimizer_group_bounds": _DICT,
+    "allocation_optimizer_turnover_pct": _num(0, 100),
+    "allocation_optimizer_history_days": _int(2),
+    "regime_impact_pct": _num(0, 100),
+    "regime_detector_weights": _DICT,
+    "regime_vote_policy": _choice("weighted", "majority"),
+    "regime_threshold": _num(0, 1),
+    "market_stress_enabled": _BOOL,
+    "market_stress_threshold": _num(0, 1),
+    "market_stress_buy_size_pct": _num(0, 100),
+    "market_stress_min_score_add": _num(0, 1),
+    "market_stress_cooloff_factor": _num(1),
+    "news_enabled": _BOOL,
+    "news_lookback_hours": _num(1),
+    "news_headlines_per_symbol": _int(1),
+    "news_risk_min_negative": _int(1),
+    "metadata_provider_priority": _LIST,
+    "metadata_yahoo_enabled": _BOOL,
+    "price_history_source": _choice("tradernet", "market_data"),
+    "market_data_providers": _LIST,
+    "market_data_symbol_providers": _DICT,
+    "market_data_stale_days": _int(0),
+    "market_data_concurrency": _int(1, 16),
+    "watchlist_score_alert_delta": _num(0, 1),
+    "thesis_review_days": _int(1),
+    "thesis_target_sell_boost": _num(0, 5),
+    "protective_orders_enabled": _BOOL,
+    "protective_order_policies": _LIST,
+    "protective_order_raise_pct": _num(0, 100),
+    "max_dividend_reinvestment_boost": _num(0, 1),
+    "drip_default_mode": _choice("same", "redirect", "cash"),
+    "trade_cooloff_days": _int(0),
+    "strategy_core_target_pct": _num(0, 100),
+    "strategy_opportunity_target_pct": _num(0, 100),
+    "strategy_opportunity_target_max_pct": _num(0, 100),
+    "strategy_min_opp_score": _num(0, 1),
+    "strategy_entry_t1_dd": _num(-1, 0),
+    "strategy_entry_t2_dd": _num(-1, 0),
+    "strategy_entry_t3_dd": _num(-1, 0),
+    "strategy_entry_memory_days": _int(0),
+    "strategy_memory_max_boost": _num(0, 1),
+    "strategy_opportunity_addon_threshold": _num(0, 1),
+    "strategy_max_opportunity_buys_per_cycle": _int(0),
+    "strategy_max_new_opportunity_buys_per_cycle": _int(0),
+    "strategy_lot_standard_max_pct": _num(0, 1),
+    "strategy_lot_coarse_max_pct": _num(0, 1),
+    "strategy_coarse_max_new_lots_per_cycle": _int(0),
+    "strategy_core_floor_pct": _num(0, 1),
+    "strategy_opportunity_cooloff_days": _int(0),
+    "strategy_core_cooloff_days": _int(0),
+    "strategy_rotation_time_stop_days": _int(0),
+    "strategy_core_new_min_score": _num(0, 1),
+    "strategy_core_new_min_dip_score": _num(0, 1),
+    "position_sizing_mode": _STR,
+    "position_sizing_overrides": _DICT,
+    "vol_target_annual_pct": _num(0.1, 200),
+    "vol_target_max_weight_pct": _num(0.1, 100),
+    "new_entry_grace_days": _int(0),
+    "new_entry_min_history_days": _int(0, 250),
+    "strategy_max_funding_sells_per_cycle": _int(0),
+    "strategy_max_funding_turnover_pct": _num(0, 1),
+    "strategy_funding_conviction_bias": _num(0),
+    "strategy_swaps_enabled": _BOOL,
+    "strategy_swap_max_sell_score": _num(0, 1),
+    "strategy_swap_min_score_delta": _num(0, 1),
+    "strategy_swap_max_cost_pct": _num(0, 1),
+    "capital_gains_tax_pct": _num(0, 100),
+    "strategy_sleeve_risk_measure": _choice("volatility", "drawdown"),
+    "strategy_sleeve_risk_lookback_days": _int(2),
+    "led_display_enabled": _BOOL,
+    "led_brightness": _int(0, 255),
+    "led_indicator_map": _DICT,
+    "display_screen_enabled": _BOOL,
+    "display_screen_driver": _choice("oled", "eink"),
+    "display_screen_i2c_port": _int(0),
+    "display_screen_i2c_address": _int(0x03, 0x77),
+    "display_screen_refresh_seconds": _num(1),
+    "gpio_relay_enabled": _BOOL,
+    "gpio_chip": _STR,
+    "gpio_relay_pin": _int(0, nullable=True),
+    "gpio_relay_active_low": _BOOL,
+    "gpio_kill_switch_pin": _int(0, nullable=True),
+    "gpio_kill_switch_active_low": _BOOL,
+    "r2_backup_retention_days": _int(1),
+    "archive_closed_after_days": _int(0),
+    "currency_exposure_basis": _choice("listing", "revenue"),
+    "currency_exposure_limits": _DICT,
+    "currency_exposure_soft_constraints": _BOOL,
+    "currency_exposure_penalty": _num(0, 1),
+    "watchdog_not_ready_grace_seconds": _num(0),
+    "price_hot_days": _int(1),
+    "intraday_snapshots_enabled": _BOOL,
+    "intraday_raw_retention_days": _int(1),
+    "intraday_hourly_retention_days": _int(1),
+    "retention_policies": _DICT,
+    "webhook_events": _DICT,
+    "report_digest_period": _choice("daily", "weekly"),
+    "report_digest_notify": _BOOL,
+    "report_keep": _int(1),
+    "smtp_host": _STR,
+    "smtp_port": _int(1, 65535),
+    "smtp_security": _choice("starttls", "ssl", "none"),
+    "smtp_username": _STR,
+    "smtp_from": _STR,
+    "smtp_timeout_seconds": _num(1),
+    "report_email_recipients": _LIST,
+    "report_email_weekly": _BOOL,
+    "report_email_reconciliation": _BOOL,
+    "dividend_treaty_rates": _DICT,
+    "dividend_treaty_rate_default": _num(0, 1),
+    "external_holdings_in_allocations": _BOOL,
+    "reconciliation_tolerance": _num(0),
+    "public_dashboard_enabled": _BOOL,
+    "public_dashboard_hide_symbols": _BOOL,
+    "profiling_enabled": _BOOL,
+    "auth_enabled": _BOOL,
+    "auth_session_hours": _num(0.1),
+    "telemetry_export_enabled": _BOOL,
+    "cash_equivalents_enabled": _BOOL,
+    "cash_equivalent_park_symbol": _STR,
+    "cash_equivalent_reserve_eur": _num(0),
+    "cash_equivalent_min_park_eur": _num(0),
+    "circuit_breaker_max_drawdown_pct": _num(0, 100),
+    "circuit_breaker_max_rejections": _int(0),
+    "config_peer_url": _STR,
+    "config_drift_ignore": _LIST,
+    "job_failure_remediation": _DICT,
+}
+
+
+def _structure_parsers() -> dict[str, Callable[[Any], Any]]:
+    """Parsers of structured settings (each raises ValueError on the first problem of its value)."""
+    from sentinel.led.display import parse_indicator_map
+    from sentinel.services.allocation_optimizer import parse_group_bounds
+    from sentinel.services.market_data import parse_provider_names, parse_symbol_providers
+    from sentinel.services.protective_orders import parse_protective_policies
+    from sentinel.services.retention import parse_retention_policies
+    from sentinel.strategy import SIZING_MODES, parse_detector_weights, validate_sizing_overrides
+    from sentinel.utils.fees import parse_fee_schedule
+    from sentinel.utils.order_pricing import parse_pricing_strategies
+
+    def sizing_mode(value: Any) -> None:
+        if value not in SIZING_MODES:
+            raise ValueError(f"must be one of: {', '.join(SIZING_MODES)}")
+
+    return {
+        "fee_schedule": parse_fee_schedule,
+        "retention_policies": parse_retention_policies,
+        "led_indicator_map": parse_indicator_map,
+        "position_sizing_mode": sizing_mode,
+        "position_sizing_overrides": validate_sizing_overrides,
+        "regime_detector_weights": parse_detector_weights,
+        "allocation_optimizer_group_bounds": parse_group_bounds,
+        "market_data_providers": parse_provider_names,
+        "market_data_symbol_providers": parse_symbol_providers,
+        "protective_order_policies": parse_protective_policies,
+        "order_pricing_strategies": parse_pricing_strategies,
+    }
+
+
+def _is_number(value: Any) -> bool:
+    return isinstance(value, (int, float)) and not isinstance(value, bool)
+
+
+def _check_rule(rule: Rule, value: Any) -> Optional[str]:
+    if value is None:
+        return None if rule.nullable else "is required"
+    if rule.kind == "bool" and not isinstance(value, bool):
+        return "must be true or false"
+    if rule.kind in ("int", "number"):
+        if not _is_number(value):
+            return "must be a number"
+        if rule.kind == "int" and int(value) != value:
+            return "must be a whole number"
+        if rule.min is not None and rule.max is not None and not rule.min <= value <= rule.max:
+            return f"must be between {rule.min:g} and {rule.max:g} (got {value})"
+        if rule.min is not None and value < rule.min:
+            return f"must be at least {rule.min:g} (got {value})"
+        if rule.max is not None and value > rule.max:
+            return f"must be at most {rule.max:g} (got {value})"
+    if rule.kind == "str":
+        if not isinstance(value, str):
+            return "must be a string"
+        if rule.choices and value not in rule.choices:
+            return f"must be one of: {', '.join(rule.choices)} (got {value!r})"
+    if rule.kind == "dict" and not isinstance(value, dict):
+        return "must be an object"
+    if rule.kind == "list" and not isinstance(value, list):
+        return "must be a list"
+    return None
+
+
+def validate_setting(key: str, value: Any) -> list[Problem]:
+    """Per-key rules (type, range, allowed values, structure) for one setting."""
+    problems = []
+    rule = SCHEMA.get(key)
+    message = _check_rule(rule, value) if rule else None
+    if message:
+        problems.append(Problem(key, f"{key} {message}"))
+    parser = _structure_parsers().get(key)
+    if parser and not message and value is not None:
+        try:
+            parser(value)
+        except ValueError as e:
+            problems.append(Problem(key, f"{key}: {e}"))
+    return problems
+
+
+def _cross_field(values: dict) -> list[Problem]:
+    problems = []
+
+    def number(key: str) -> Optional[float]:
+        value = values.get(key)
+        return float(value) if _is_number(value) else None
+
+    core, opportunity = number("strategy_core_target_pct"), number("strategy_opportunity_target_pct")
+    if core is not None and opportunity is not None and abs(core + opportunity - 100) > 1e-9:
+        problems.append(
+            Problem(
+                "strategy_core_target_pct",
+                "strategy_core_target_pct + strategy_opportunity_target_pct must sum to 100 "
+                f"(got {core + opportunity:g})",
+            )
+        )
+    opportunity_max = number("strategy_opportunity_target_max_pct")
+    if opportunity is not None and opportunity_max is not None and opportunity > opportunity_max:
+        problems.append(
+            Problem(
+                "strategy_opportunity_target_max_pct",
+                "strategy_opportunity_target_max_pct must be at least strategy_opportunity_target_pct",
+            )
+        )
+    low, high = number("min_position_pct"), number("max_position_pct")
+    if low is not None and high is not None and low > high:
+        problems.append(Problem("min_position_pct", "min_position_pct must not exceed max_position_pct"))
+    low, high = number("allocation_optimizer_min_weight_pct"), number("allocation_optimizer_max_weight_pct")
+    if low is not None and high is not None and low > high:
+        problems.append(
+            Problem(
+                "allocation_optimizer_min_weight_pct",
+                "allocation_optimizer_min_weight_pct must not exceed allocation_optimizer_max_weight_pct",
+            )
+        )
+    tiers = [number(f"strategy_entry_t{i}_dd") for i in (1, 2, 3)]
+    if None not in tiers and not tiers[0] > tiers[1] > tiers[2]:
+        problems.append(
+            Problem(
+                "strategy_entry_t1_dd",
+                "entry drawdown tiers must deepen: strategy_entry_t1_dd > strategy_entry_t2_dd > strategy_entry_t3_dd",
+            )
+        )
+    raw, hourly = number("intraday_raw_retention_days"), number("intraday_hourly_retention_days")
+    if raw is not None and hourly is not None and raw > hourly:
+        problems.append(
+            Problem(
+                "intraday_raw_retention_days",
+                "intraday_raw_retention_days must not exceed intraday_hourly_retention_days",
+            )
+        )
+    standard, coarse = number("strategy_lot_standard_max_pct"), number("strategy_lot_coarse_max_pct")
+    if standard is not None and coarse is not None and standard > coarse:
+        problems.append(
+            Problem(
+                "strategy_lot_standard_max_pct",
+                "strategy_lot_standard_max_pct must not exceed strategy_lot_coarse_max_pct",
+            )
+        )
+    # Access keys may live in the vault, so only the plaintext halves are compared
+    if bool(values.get("r2_account_id")) != bool(values.get("r2_bucket_name")):
+        missing = "r2_bucket_name" if values.get("r2_account_id") else "r2_account_id"
+        problems.append(Problem(missing, f"R2 backup is partly configured; also set {missing}"))
+    if values.get("gpio_relay_enabled") and values.get("gpio_relay_pin") is None:
+        problems.append(Problem("gpio_relay_pin", "gpio_relay_enabled needs gpio_relay_pin"))
+    eink = values.get("display_screen_driver") == "eink"
+    if values.get("display_screen_enabled") and eink and not values.get("display_screen_model"):
+        problems.append(Problem("display_screen_model", "the eink screen driver needs display_screen_model"))
+    return problems
+
+
+def validate_settings(values: dict) -> list[Problem]:
+    """Every problem with a full set of settings (per-key rules, then cross-field rules)."""
+    problems = []
+    for key in SCHEMA:
+        problems.extend(validate_setting(key, values.get(key)))
+    return problems + _cross_field(values)
+
+
+def check_port(name: str, value: Any) -> list[Problem]:
+    """A TCP port (1-65535)."""
+    if isinstance(value, str):
+        try:
+            value = int(value)
+        except ValueError:
+            return [Problem(name, f"{name} must be a port number (got {value!r})")]
+    message = _check_rule(_int(1, 65535), value)
+    return [Problem(name, f"{name} {message}")] if message else []
+
+
+def format_report(problems: list[Problem]) -> str:
+    """One block listing every problem, for the startup log."""
+    lines = [f"Configuration has {len(problems)} problem{'s' if len(problems) != 1 else ''}:"]
+    lines += [f"  - {p.message}" for p in problems]
+    return "\n".join(lines)
@@ -0,0 +1,80 @@
+"""Correlation IDs for tracing a request or job through logs and outbound calls.
+
+Every API request runs under a correlation ID: the caller's X-Correlation-ID
+header when it sends a usable one, a new ID otherwise. The ID is returned in
+the response's X-Correlation-ID header, stamped on every log record made while
+handling the request (as record.correlation_id, shown in the log format and in
+/api/logs entries) and sent along with outbound calls to other Sentinel devices
+and webhook endpoints. Scheduled job runs get an ID of their own; jobs started
+from the API keep the request's ID, so the request and the job it triggered
+share one trail.
+"""
+
+from __future__ import annotations
+
+import logging
+import re
+import uuid
+from contextlib import contextmanager
+from contextvars import ContextVar
+from typing import Iterator
+
+HEADER = "X-Correlation-ID"
+# Incoming IDs are kept only when they are short and safe to log
+_VALID_ID = re.compile(r"[A-Za-z0-9._:-]{1,64}")
+LOG_FORMAT = "%(asctime)s - %(name)s - %(levelname)s - [%(correlation_id)s] %(message)s"
+
+_current: ContextVar[str | None] = ContextVar("correlation_id", default=None)
+_installed = False
+
+
+def new_id() -> str:
+    """A fresh correlation ID."""
+    return uuid.uuid4().hex[:16]
+
+
+def is_valid(value: str | None) -> bool:
+    """Whether a caller-supplied ID can be reused as is."""
+    return bool(value) and _VALID_ID.fullmatch(value) is not None
+
+
+def current() -> str | None:
+    """The correlation ID of the running request or job, if any."""
+    return _current.get()
+
+
+@contextmanager
+def bind(correlation_id: str | None = None) -> Iterator[str]:
+    """Run a block under a correlation ID (a new one unless a valid ID is given)."""
+    correlation_id = correlation_id if is_valid(correlation_id) else new_id()
+    token = _current.set(correlation_id)
+    try:
+        yield correlation_id
+    finally:
+        _current.reset(token)
+
+
+def outbound_headers() -> dict[str, str]:
+    """Headers propagating the current ID to another service (empty outside a request or job)."""
+    correlation_id = current()
+    return {HEADER: correlation_id} if correlation_id else {}
+
+
+def install_log_records() -> None:
+    """Stamp every log record with the current correlation ID ("-" when there is none).
+
+    Uses the record factory rather than a filter so records of every logger and
+    handler carry the attribute, including handlers added later.
+    """
+    global _installed
+    if _installed:
+        return
+    factory = logging.getLogRecordFactory()
+
+    def record_factory(*args, **kwargs) -> logging.LogRecord:
+        record = factory(*args, **kwargs)
+        record.correlation_id = _current.get() or "-"
+        return record
+
+    logging.setLogRecordFactory(record_factory)
+    _installed = True
@@ -4,19 +4,32 @@ Base Database - Shared database operations.
 Contains methods that are identical between Database and SimulationDatabase.
 """
 
+import time
 from typing import Optional
 
 import aiosqlite
 
+from sentinel.dry_run import current_dry_run
+
+# Columns shared by trades and archived_trades
+_TRADE_COLUMNS = (
+    "id, broker_trade_id, symbol, side, quantity, price, commission, commission_currency, executed_at, raw_data"
+)
+
 
 class BaseDatabase:
     """Base class with shared database operations."""
 
     _connection: Optional[aiosqlite.Connection] = None
+    # Table or view price bars are read from (Database merges in the cold tier)
+    price_source = "prices"
 
     @property
     def conn(self) -> aiosqlite.Connection:
-        """Get database connection."""
+        """Get database connection (the dry-run connection inside a dry-run session)."""
+        session = current_dry_run()
+        if session is not None and session.db is self:
+            return session.connection
         if not self._connection:
             raise RuntimeError("Database not connected. Call connect() first.")
         return self._connection
@@ -51,6 +64,7 @@ class BaseDatabase:
             )
         else:
             data["symbol"] = symbol
+            data.setdefault("added_at", int(time.time()))
             cols = ", ".join(data.keys())
             placeholders = ", ".join("?" * len(data))
             await self.conn.execute(
@@ -156,7 +170,7 @@ class BaseDatabase:
         commission_currency: str = "EUR",
     ) -> int:
         """
-        Insert a trade or ignore if broker_trade_id already exists.
+        Insert a trade or ignore if broker_trade_id already exists (or was archived).
 
         Args:
             broker_trade_id: Unique trade ID from the broker
@@ -177,7 +191,8 @@ class BaseDatabase:
         cursor = await self.conn.execute(
             """INSERT OR IGNORE INTO trades
                (broker_trade_id, symbol, side, quantity, price, commission, commission_currency, executed_at, raw_data)
-               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+               SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?
+               WHERE NOT EXISTS (SELECT 1 FROM archived_trades WHERE broker_trade_id = ?)""",
             (
                 broker_trade_id,
                 symbol,
@@ -188,10 +203,11 @@ class BaseDatabase:
                 commission_currency,
                 executed_at,
                 json.dumps(raw_data),
+                broker_trade_id,
             ),
         )
         await self.conn.commit()
-        return cursor.lastrowid or 0
+        return (cursor.lastrowid or 0) if cursor.rowcount > 0 else 0
 
     def _build_trades_where(
         self,
@@ -240,6 +256,7 @@ class BaseDatabase:
         end_date: Optional[str] = None,
         limit: int = 100,
         offset: int = 0,
+        include_archived: bool = False,
     ) -> list[dict]:
         """
         Get trade history with optional filters.
@@ -251,6 +268,7 @@ class BaseDatabase:
             end_date: Filter trades on or before this date (YYYY-MM-DD)
             limit: Maximum number of trades to return
             offset: Number of trades to skip (for pagination)
+            include_archived: Also include trades of archived round trips
 
         Returns:
             List of trade dicts with parsed raw_data
@@ -258,7 +276,14 @@ class BaseDatabase:
         import json
 
         where, params = self._build_trades_where(symbol, side, start_date, end_date)
-        query = f"SELECT * FROM trades {where} ORDER BY executed_at DESC LIMIT ? OFFSET ?"  # noqa: S608
+        if include_archived:
+            query = f"""SELECT {_TRADE_COLUMNS} FROM trades {where}
+                        UNION ALL
+                        SELECT {_TRADE_COLUMNS} FROM archived_trades {where}
+                        ORDER BY executed_at DESC, id DESC LIMIT ? OFFSET ?"""  # noqa: S608
+            params = params * 2
+        else:
+            query = f"SELECT * FROM trades {where} ORDER BY executed_at DESC LIMIT ? OFFSET ?"  # noqa: S608
         params.extend([limit, offset])
 
         cursor = await self.conn.execute(query, params)
@@ -463,6 +488,10 @@ class BaseDatabase:
         currency: str,
         value: float,
         data: dict,
+        gross_amount: float | None = None,
+        withholding_amount: float | None = None,
+        withholding_rate: float | None = None,
+        country: str | None = None,
     ) -> int:
         """
         Insert a dividend or ignore if id already exists.
@@ -475,6 +504,10 @@ class BaseDatabase:
             currency: Original currency
             value: EUR-equivalent value (amount converted to EUR)
             data: Full raw JSON from corporate actions API
+            gross_amount: Amount before withholding tax, in original currency
+            withholding_amount: Tax withheld, in original currency
+            withholding_rate: withholding_amount / gross_amount
+            country: Issuer country code
 
         Returns:
             Row ID if inserted, 0 if already exists.
@@ -483,9 +516,22 @@ class BaseDatabase:
 
         cursor = await self.conn.execute(
             """INSERT OR IGNORE INTO dividends
-               (id, symbol, date, amount, currency, value, data)
-               VALUES (?, ?, ?, ?, ?, ?, ?)""",
-            (id, symbol, date, amount, currency, value, json.dumps(data)),
+               (id, symbol, date, amount, currency, value, data,
+                gross_amount, withholding_amount, withholding_rate, country)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                id,
+                symbol,
+                date,
+                amount,
+                currency,
+                value,
+                json.dumps(data),
+                gross_amount,
+                withholding_amount,
+                withholding_rate,
+                country,
+            ),
         )
         await self.conn.commit()
         return cursor.lastrowid or 0
@@ -524,30 +570,90 @@ class BaseDatabase:
 
     async def get_uninvested_dividends(self) -> dict[str, float]:
         """
-        For each symbol with dividends: sum value for dividends dated after the
-        most recent BUY trade on that symbol (or all-time if no BUY).
+        For each reinvestment target: sum value of the dividends going to it
+        that are dated after the most recent BUY trade of the target (or
+        all-time if no BUY).
+
+        The target is the one recorded in dividend_reinvestments (DRIP), or the
+        paying symbol for dividends without a decision. Dividends kept as cash
+        are left out.
 
         Returns:
             Dict mapping symbol -> uninvested EUR value
         """
         cursor = await self.conn.execute(
             """
-            SELECT d.symbol, SUM(d.value) as pool
+            SELECT COALESCE(r.target_symbol, d.symbol) as symbol, SUM(d.value) as pool
             FROM dividends d
+            LEFT JOIN dividend_reinvestments r ON r.dividend_id = d.id
             LEFT JOIN (
                 SELECT symbol, MAX(executed_at) as last_buy
                 FROM trades
                 WHERE side = 'BUY'
                 GROUP BY symbol
-            ) t ON d.symbol = t.symbol
-            WHERE d.date > COALESCE(date(t.last_buy, 'unixepoch'), '1970-01-01')
-            GROUP BY d.symbol
+            ) t ON COALESCE(r.target_symbol, d.symbol) = t.symbol
+            WHERE COALESCE(r.mode, 'same') != 'cash'
+              AND d.date > COALESCE(date(t.last_buy, 'unixepoch'), '1970-01-01')
+            GROUP BY COALESCE(r.target_symbol, d.symbol)
             HAVING pool > 0
             """
         )
         rows = await cursor.fetchall()
         return {row["symbol"]: row["pool"] for row in rows}
 
+    async def get_undecided_dividends(self) -> list[dict]:
+        """Dividends without a reinvestment decision, dated after the most recent BUY of their symbol."""
+        cursor = await self.conn.execute(
+            """
+            SELECT d.*
+            FROM dividends d
+            LEFT JOIN dividend_reinvestments r ON r.dividend_id = d.id
+            LEFT JOIN (
+                SELECT symbol, MAX(executed_at) as last_buy
+                FROM trades
+                WHERE side = 'BUY'
+                GROUP BY symbol
+            ) t ON d.symbol = t.symbol
+            WHERE r.dividend_id IS NULL
+              AND d.date > COALESCE(date(t.last_buy, 'unixepoch'), '1970-01-01')
+            ORDER BY d.date
+            """
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def save_dividend_reinvestment(
+        self,
+        dividend_id: str,
+        symbol: str,
+        mode: str,
+        target_symbol: str | None,
+        value_eur: float,
+        reason: str,
+    ) -> None:
+        """Record where a dividend is reinvested (DRIP decision)."""
+        import time
+
+        await self.conn.execute(
+            """INSERT OR IGNORE INTO dividend_reinvestments
+               (dividend_id, symbol, mode, target_symbol, value_eur, reason, decided_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (dividend_id, symbol, mode, target_symbol, value_eur, reason, int(time.time())),
+        )
+        await self.conn.commit()
+
+    async def get_dividend_reinvestments(self, symbol: str | None = None, limit: int = 100) -> list[dict]:
+        """DRIP decisions with their dividend's date, most recent first."""
+        query = """SELECT r.*, d.date FROM dividend_reinvestments r
+                   JOIN dividends d ON d.id = r.dividend_id"""
+        params: list = []
+        if symbol:
+            query += " WHERE r.symbol = ? OR r.target_symbol = ?"
+            params += [symbol, symbol]
+        query += " ORDER BY r.decided_at DESC, d.date DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
     # -------------------------------------------------------------------------
     # Prices (base implementation, can be overridden)
     # -------------------------------------------------------------------------
@@ -568,7 +674,7 @@ class BaseDatabase:
         Returns:
             List of price dicts, newest first (or oldest-first if end_date semantics needed by caller)
         """
-        query = "SELECT * FROM prices WHERE symbol = ?"
+        query = f"SELECT * FROM {self.price_source} WHERE symbol = ?"  # noqa: S608
         params: list[str | int] = [symbol]
         if end_date is not None:
             query += " AND date <= ?"
@@ -617,7 +723,7 @@ class BaseDatabase:
                     SELECT
                         p.*,
                         ROW_NUMBER() OVER (PARTITION BY p.symbol ORDER BY p.date DESC) AS rn
-                    FROM prices p
+                    FROM {self.price_source} p
                     WHERE {where_sql}
                 )
                 SELECT * FROM ranked
@@ -636,7 +742,7 @@ class BaseDatabase:
                 # Fallback for SQLite builds without window-function support.
                 pass
 
-        query = f"SELECT * FROM prices WHERE {where_sql} ORDER BY symbol ASC, date DESC"  # noqa: S608
+        query = f"SELECT * FROM {self.price_source} WHERE {where_sql} ORDER BY symbol ASC, date DESC"  # noqa: S608
         cursor = await self.conn.execute(query, params)
         rows = await cursor.fetchall()
         for row in rows:
@@ -0,0 +1,58 @@
+"""
+Intraday Prices - quote snapshots of held positions and their downsampling.
+
+Snapshots are stored as one-sample "raw" bars. As they age, the archive:intraday
+job folds them into coarser bars so storage stays bounded on the device:
+
+    raw     kept intraday_raw_retention_days, then folded into hourly bars
+    hourly  kept intraday_hourly_retention_days, then folded into daily bars
+    daily   kept forever
+
+A folded bar keeps the first open, highest high, lowest low, last close, last
+bid/ask and the number of snapshots it summarizes. Buckets are aligned to UTC
+hours and days.
+"""
+
+RAW = "raw"
+HOURLY = "hourly"
+DAILY = "daily"
+RESOLUTIONS = (RAW, HOURLY, DAILY)
+
+BUCKET_SECONDS = {HOURLY: 3600, DAILY: 86400}
+
+
+def bucket_start(ts: int, seconds: int) -> int:
+    """Start of the bucket of `seconds` containing ts."""
+    return int(ts) // seconds * seconds
+
+
+def downsample_bars(rows: list[dict], seconds: int) -> list[dict]:
+    """Fold bars (any resolution, any order) into buckets of `seconds` per symbol.
+
+    Returns:
+        One bar per (symbol, bucket) with ts at the bucket start, ordered by symbol and ts
+    """
+    buckets: dict[tuple[str, int], dict] = {}
+    for row in sorted(rows, key=lambda r: (r["symbol"], r["ts"])):
+        key = (row["symbol"], bucket_start(row["ts"], seconds))
+        bar = buckets.get(key)
+        if bar is None:
+            buckets[key] = {
+                "symbol": key[0],
+                "ts": key[1],
+                "open": row["open"],
+                "high": row["high"],
+                "low": row["low"],
+                "close": row["close"],
+                "bid": row.get("bid"),
+                "ask": row.get("ask"),
+                "samples": int(row.get("samples") or 1),
+            }
+            continue
+        bar["high"] = max(bar["high"], row["high"])
+        bar["low"] = min(bar["low"], row["low"])
+        bar["close"] = row["close"]
+        bar["bid"] = row.get("bid") if row.get("bid") is not None else bar["bid"]
+        bar["ask"] = row.get("ask") if row.get("ask") is not None else bar["ask"]
+        bar["samples"] += int(row.get("samples") or 1)
+    return [buckets[key] for key in sorted(buckets)]
@@ -10,13 +10,17 @@ Usage:
 
 import json
 import logging
+import re
 from datetime import datetime
 from pathlib import Path
 from typing import Any, Optional
 
 import aiosqlite
 
-from sentinel.database.base import BaseDatabase
+from sentinel.database.base import _TRADE_COLUMNS, BaseDatabase
+from sentinel.database.intraday import BUCKET_SECONDS, RAW, bucket_start, downsample_bars
+from sentinel.database.tiering import COLD_SCHEMA, DEFAULT_HOT_DAYS, MERGED_VIEW, PriceTiering
+from sentinel.errors import InvalidInput, NotFound
 
 logger = logging.getLogger(__name__)
 
@@ -47,6 +51,8 @@ class Database(BaseDatabase):
             instance = super().__new__(cls)
             instance._path = Path(path)
             instance._connection = None
+            instance._price_tiering = None
+            instance._cold_attached = False
             cls._instances[path] = instance
 
         return cls._instances[path]
@@ -64,11 +70,14 @@ class Database(BaseDatabase):
             await self._connection.execute("PRAGMA journal_mode=WAL")
             await self._connection.execute("PRAGMA busy_timeout=30000")
             await self._init_schema()
+            await self.configure_price_tiering()
         return self
 
     async def close(self):
         """Close database connection."""
         if self._connection:
+            self._price_tiering = None
+            self._cold_attached = False
             await self._connection.close()
             self._connection = None
 
@@ -127,6 +136,17 @@ class Database(BaseDatabase):
     # Securities (extended methods beyond BaseDatabase)
     # -------------------------------------------------------------------------
 
+    async def upsert_security(self, symbol: str, **data) -> None:
+        """Insert or update a security, keeping its instrument IDs (Tradernet ticker, ISIN) in step."""
+        is_new = await self.get_security(symbol) is None
+        await super().upsert_security(symbol, **data)
+        if is_new:
+            await self._put_instrument_id(symbol, "tradernet", symbol, "broker")
+        if "isin" in data:
+            await self._put_instrument_id(symbol, "isin", data["isin"], "broker")
+        if is_new or "isin" in data:
+            await self.conn.commit()
+
     async def update_quote_data(self, symbol: str, quote_data: dict) -> None:
         """Update quote data for a security."""
         import time
@@ -153,6 +173,53 @@ class Database(BaseDatabase):
     # Prices (extended methods beyond BaseDatabase)
     # -------------------------------------------------------------------------
 
+    @property
+    def price_source(self) -> str:
+        """Table or view to read price bars from (merged hot + cold view when tiered)."""
+        return MERGED_VIEW if self._cold_attached else "prices"
+
+    @property
+    def price_tiering(self) -> Optional[PriceTiering]:
+        """The configured cold price tier, or None."""
+        return self._price_tiering
+
+    async def configure_price_tiering(self) -> bool:
+        """
+        (Re)attach the cold price tier from the price_cold_storage_path setting.
+
+        Returns:
+            True if a cold tier is attached
+        """
+        path = await self.get_setting("price_cold_storage_path")
+        hot_days = await self.get_setting("price_hot_days", DEFAULT_HOT_DAYS)
+        current = self._price_tiering
+        if current is not None and (current.cold_path != path or not self._cold_attached or not current.available()):
+            await current.detach(self.conn)
+            self._cold_attached = False
+        if not path:
+            self._price_tiering = None
+            return False
+        self._price_tiering = PriceTiering(path, hot_days)
+        if not self._cold_attached:
+            self._cold_attached = await self._price_tiering.attach(self.conn)
+        return self._cold_attached
+
+    async def tier_prices(self) -> dict:
+        """Move aged bars to the cold tier, re-attaching it first (storage may have been replugged)."""
+        if not await self.configure_price_tiering():
+            return {"attached": False, "moved": 0}
+        moved = await self._price_tiering.migrate(self.conn)
+        return {"attached": True, "moved": moved, "cutoff": self._price_tiering.cutoff()}
+
+    async def get_price_tier_stats(self) -> dict:
+        """Bars and date range per price tier ({"attached": False} without a cold tier)."""
+        if self._price_tiering is None:
+            cursor = await self.conn.execute(
+                "SELECT COUNT(*) AS bars, MIN(date) AS oldest, MAX(date) AS newest FROM prices"
+            )
+            return {"cold_path": None, "attached": False, "hot": dict(await cursor.fetchone())}
+        return await self._price_tiering.stats(self.conn, self._cold_attached)
+
     async def save_prices(self, symbol: str, prices: list[dict]) -> None:
         """Save historical prices for a security (upsert)."""
         for price in prices:
@@ -171,6 +238,17 @@ class Database(BaseDatabase):
                 ),
             )
         await self.conn.commit()
+        if prices:
+            await self.mark_scores_dirty([symbol], "prices")
+
+    async def get_latest_prices(self) -> dict[str, dict]:
+        """Get the most recent stored close per symbol as symbol -> {date, close}."""
+        cursor = await self.conn.execute(
+            f"""SELECT p.symbol, p.date, p.close FROM {self.price_source} p
+               JOIN (SELECT symbol, MAX(date) AS date FROM {self.price_source} GROUP BY symbol) latest
+                 ON latest.symbol = p.symbol AND latest.date = p.date"""  # noqa: S608
+        )
+        return {row["symbol"]: {"date": row["date"], "close": row["close"]} for row in await cursor.fetchall()}
 
     async def get_prices_bulk(
         self,
@@ -205,7 +283,7 @@ class Database(BaseDatabase):
                 SELECT * FROM (
                     SELECT *,
                         ROW_NUMBER() OVER (PARTITION BY symbol ORDER BY date DESC) as rn
-                    FROM prices
+                    FROM {self.price_source}
                     {base_where}
                 )
                 WHERE rn <= ?
@@ -214,7 +292,7 @@ class Database(BaseDatabase):
             params = [*base_params, days]
         else:
             query = f"""
-                SELECT * FROM prices
+                SELECT * FROM {self.price_source}
                 {base_where}
                 ORDER BY symbol, date DESC
             """  # noqa: S608
@@ -232,6 +310,94 @@ class Database(BaseDatabase):
 
         return result
 
+    # -------------------------------------------------------------------------
+    # Intraday Prices
+    # -------------------------------------------------------------------------
+
+    async def save_intraday_snapshots(self, snapshots: list[dict], ts: int) -> int:
+        """Store quote snapshots ({symbol, price, bid, ask}) taken at ts as raw bars. Returns rows saved."""
+        rows = [
+            (s["symbol"], RAW, int(ts), s["price"], s["price"], s["price"], s["price"], s.get("bid"), s.get("ask"))
+            for s in snapshots
+            if s.get("price")
+        ]
+        await self.conn.executemany(
+            """INSERT OR REPLACE INTO intraday_prices
+                   (symbol, resolution, ts, open, high, low, close, bid, ask, samples)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1)""",
+            rows,
+        )
+        await self.conn.commit()
+        return len(rows)
+
+    async def get_intraday_prices(
+        self,
+        symbol: str,
+        resolution: str | None = None,
+        since: int | None = None,
+        until: int | None = None,
+    ) -> list[dict]:
+        """Intraday bars of a symbol oldest first, optionally of one resolution and within [since, until]."""
+        query = "SELECT * FROM intraday_prices WHERE symbol = ?"
+        params: list[Any] = [symbol]
+        if resolution:
+            query += " AND resolution = ?"
+            params.append(resolution)
+        if since is not None:
+            query += " AND ts >= ?"
+            params.append(since)
+        if until is not None:
+            query += " AND ts <= ?"
+            params.append(until)
+        cursor = await self.conn.execute(query + " ORDER BY ts, resolution", params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def downsample_intraday(self, source: str, target: str, before_ts: int) -> int:
+        """Fold source-resolution bars older than before_ts into target-resolution bars.
+
+        before_ts is rounded down to a target bucket boundary so only complete buckets
+        are folded. Returns the number of source bars removed.
+        """
+        seconds = BUCKET_SECONDS[target]
+        before_ts = bucket_start(before_ts, seconds)
+        cursor = await self.conn.execute(
+            "SELECT * FROM intraday_prices WHERE resolution = ? AND ts < ?", (source, before_ts)
+        )
+        rows = [dict(row) for row in await cursor.fetchall()]
+        if not rows:
+            return 0
+        cursor = await self.conn.execute(
+            "SELECT * FROM intraday_prices WHERE resolution = ? AND ts >= ? AND ts < ?",
+            (target, bucket_start(min(r["ts"] for r in rows), seconds), before_ts),
+        )
+        existing = [dict(row) for row in await cursor.fetchall()]
+        bars = downsample_bars(existing + rows, seconds)
+        try:
+            await self.conn.executemany(
+                """INSERT OR REPLACE INTO intraday_prices
+                       (symbol, resolution, ts, open, high, low, close, bid, ask, samples)
+                   VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+                [(b["symbol"], target, b["ts"], *(b[k] for k in _INTRADAY_BAR_COLUMNS)) for b in bars],
+            )
+            await self.conn.execute("DELETE FROM intraday_prices WHERE resolution = ? AND ts < ?", (source, before_ts))
+            await self.conn.commit()
+        except Exception:
+            await self.conn.rollback()
+            raise
+        return len(rows)
+
+    async def get_intraday_stats(self) -> dict[str, dict]:
+        """Bars, symbols and time range per intraday resolution."""
+        cursor = await self.conn.execute(
+            """SELECT resolution, COUNT(*) AS bars, COUNT(DISTINCT symbol) AS symbols,
+                      MIN(ts) AS oldest, MAX(ts) AS newest
+               FROM intraday_prices GROUP BY resolution"""
+        )
+        return {
+            row["resolution"]: {k: row[k] for k in ("bars", "symbols", "oldest", "newest")}
+            for row in await cursor.fetchall()
+        }
+
     # -------------------------------------------------------------------------
     # Trades (extended methods beyond BaseDatabase)
     # -------------------------------------------------------------------------
@@ -239,103 +405,3008 @@ class Database(BaseDatabase):
     # record_trade() removed - trades are now synced from broker via upsert_trade()
 
     # -------------------------------------------------------------------------
-    # Allocation Targets (extended methods beyond BaseDatabase)
+    # Trade Decisions
     # -------------------------------------------------------------------------
 
-    async def set_allocation_target(self, target_type: str, name: str, weight: float) -> None:
-        """Set an allocation target weight."""
-        await self.conn.execute(
-            """INSERT OR REPLACE INTO allocation_targets (type, name, weight)
-               VALUES (?, ?, ?)""",
-            (target_type, name, weight),
+    async def record_trade_decision(
+        self,
+        symbol: str,
+        action: str,
+        quantity: float,
+        source: str,
+        order_id: str | None = None,
+        price: float | None = None,
+        currency: str | None = None,
+        reason_code: str | None = None,
+        sleeve: str | None = None,
+        created_at: int | None = None,
+        dominant_component: str | None = None,
+        score_components: dict | None = None,
+        pricing_method: str | None = None,
+        limit_price: float | None = None,
+        quote_price: float | None = None,
+    ) -> int:
+        """
+        Persist the decision behind a submitted order.
+
+        Args:
+            symbol: Security symbol
+            action: 'buy' or 'sell'
+            quantity: Submitted quantity
+            source: Job type that submitted the order, or 'manual'
+            order_id: Broker order ID (used to join with synced trades)
+            price: Expected price at submission time
+            currency: Price currency
+            reason_code: Planner rule that generated the recommendation
+            sleeve: Strategy sleeve (core/opportunity)
+            created_at: Submission time as unix timestamp (defaults to now)
+            dominant_component: Evaluation component that contributed most to selection
+            score_components: Priority contribution per evaluation component
+            pricing_method: How the order was priced (one of utils.order_pricing.PRICING_METHODS)
+            limit_price: Limit price sent to the broker (None for market orders)
+            quote_price: Quoted ask (buy) / bid (sell), or last price, at submission
+
+        Returns:
+            Row ID of the inserted decision
+        """
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO trade_decisions
+               (order_id, symbol, action, quantity, price, currency, reason_code, sleeve, source, created_at,
+                dominant_component, score_components, pricing_method, limit_price, quote_price)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                str(order_id) if order_id is not None else None,
+                symbol,
+                action.lower(),
+                quantity,
+                price,
+                currency,
+                reason_code,
+                sleeve,
+                source,
+                created_at if created_at is not None else int(time.time()),
+                dominant_component,
+                json.dumps(score_components) if score_components else None,
+                pricing_method,
+                limit_price,
+                quote_price,
+            ),
         )
         await self.conn.commit()
+        return cursor.lastrowid or 0
 
-    async def delete_allocation_target(self, target_type: str, name: str) -> None:
-        """Delete an allocation target."""
-        await self.conn.execute("DELETE FROM allocation_targets WHERE type = ? AND name = ?", (target_type, name))
-        await self.conn.commit()
+    async def get_trade_decisions(
+        self, start_ts: int | None = None, end_ts: int | None = None, include_archived: bool = False
+    ) -> list[dict]:
+        """Get trade decisions ordered by submission time, optionally bounded by unix timestamps.
+
+        With include_archived, decisions of archived round trips are included too.
+        """
+        where: list[str] = []
+        params: list[int] = []
+        if start_ts is not None:
+            where.append("created_at >= ?")
+            params.append(start_ts)
+        if end_ts is not None:
+            where.append("created_at <= ?")
+            params.append(end_ts)
+        where_sql = f" WHERE {' AND '.join(where)}" if where else ""
+        if include_archived:
+            query = f"""SELECT {_DECISION_COLUMNS} FROM trade_decisions{where_sql}
+                        UNION ALL
+                        SELECT {_DECISION_COLUMNS} FROM archived_trade_decisions{where_sql}
+                        ORDER BY created_at ASC, id ASC"""  # noqa: S608
+            params = params * 2
+        else:
+            query = f"SELECT * FROM trade_decisions{where_sql} ORDER BY created_at ASC"  # noqa: S608
+        cursor = await self.conn.execute(query, tuple(params))
+        rows = await cursor.fetchall()
+        return [dict(row) for row in rows]
 
     # -------------------------------------------------------------------------
-    # Cache
+    # Position Archive
     # -------------------------------------------------------------------------
 
-    async def cache_get(self, key: str) -> Optional[str]:
-        """Get a cached value by key. Returns None if not found or expired."""
+    async def archive_position(self, summary: dict, trade_ids: list[int], decision_ids: list[int]) -> int:
+        """
+        Move a closed round trip into the archive atomically.
+
+        Copies the given trades and trade decisions into the archive tables, deletes
+        them from the hot tables and drops the symbol's zero-quantity position row.
+
+        Args:
+            summary: archived_positions columns (symbol, currency, opened_at, closed_at, ...);
+                     'data' may be a dict and is stored as JSON
+            trade_ids: trades.id values of the round trip
+            decision_ids: trade_decisions.id values of the round trip
+
+        Returns:
+            ID of the new archived_positions row
+        """
+        import json
         import time
 
-        cursor = await self.conn.execute("SELECT value, expires_at FROM cache WHERE key = ?", (key,))
+        row = dict(summary)
+        row.setdefault("archived_at", int(time.time()))
+        if isinstance(row.get("data"), dict):
+            row["data"] = json.dumps(row["data"])
+        cols = ", ".join(row.keys())
+        placeholders = ", ".join("?" * len(row))
+        trade_marks = ", ".join("?" * len(trade_ids))
+        decision_marks = ", ".join("?" * len(decision_ids))
+
+        await self.conn.execute("BEGIN")
+        try:
+            cursor = await self.conn.execute(
+                f"INSERT INTO archived_positions ({cols}) VALUES ({placeholders})",  # noqa: S608
+                tuple(row.values()),
+            )
+            archive_id = cursor.lastrowid
+            if trade_ids:
+                await self.conn.execute(
+                    f"""INSERT INTO archived_trades (archive_id, {_TRADE_COLUMNS})
+                        SELECT ?, {_TRADE_COLUMNS} FROM trades WHERE id IN ({trade_marks})""",  # noqa: S608
+                    (archive_id, *trade_ids),
+                )
+                await self.conn.execute(f"DELETE FROM trades WHERE id IN ({trade_marks})", tuple(trade_ids))  # noqa: S608
+            if decision_ids:
+                await self.conn.execute(
+                    f"""INSERT INTO archived_trade_decisions (archive_id, {_DECISION_COLUMNS})
+                        SELECT ?, {_DECISION_COLUMNS} FROM trade_decisions
+                        WHERE id IN ({decision_marks})""",  # noqa: S608
+                    (archive_id, *decision_ids),
+                )
+                await self.conn.execute(
+                    f"DELETE FROM trade_decisions WHERE id IN ({decision_marks})",  # noqa: S608
+                    tuple(decision_ids),
+                )
+            await self.conn.execute("DELETE FROM positions WHERE symbol = ? AND quantity <= 0", (row["symbol"],))
+            await self.conn.commit()
+        except Exception:
+            await self.conn.execute("ROLLBACK")
+            raise
+        return archive_id or 0
+
+    async def get_archived_positions(self, symbol: str | None = None, limit: int = 50, offset: int = 0) -> list[dict]:
+        """Get archived round trips, most recently closed first."""
+        import json
+
+        where = "WHERE symbol = ?" if symbol else ""
+        params: list = [symbol] if symbol else []
+        cursor = await self.conn.execute(
+            f"SELECT * FROM archived_positions {where} ORDER BY closed_at DESC, id DESC LIMIT ? OFFSET ?",  # noqa: S608
+            (*params, limit, offset),
+        )
+        result = []
+        for row in await cursor.fetchall():
+            item = dict(row)
+            item["data"] = json.loads(item["data"]) if item.get("data") else {}
+            result.append(item)
+        return result
+
+    async def get_archived_positions_count(self, symbol: str | None = None) -> int:
+        """Count archived round trips, optionally for one symbol."""
+        where = "WHERE symbol = ?" if symbol else ""
+        params = (symbol,) if symbol else ()
+        cursor = await self.conn.execute(f"SELECT COUNT(*) FROM archived_positions {where}", params)  # noqa: S608
+        row = await cursor.fetchone()
+        return row[0] if row else 0
+
+    async def get_archived_position(self, archive_id: int) -> dict | None:
+        """Get one archived round trip with its trades and trade decisions."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM archived_positions WHERE id = ?", (archive_id,))
         row = await cursor.fetchone()
         if not row:
             return None
+        result = dict(row)
+        result["data"] = json.loads(result["data"]) if result.get("data") else {}
+        result["trades"] = await self.get_archived_trades(archive_id=archive_id)
+        cursor = await self.conn.execute(
+            "SELECT * FROM archived_trade_decisions WHERE archive_id = ? ORDER BY created_at ASC",
+            (archive_id,),
+        )
+        result["decisions"] = [dict(r) for r in await cursor.fetchall()]
+        return result
 
-        # Check expiry
-        if row["expires_at"] is not None and row["expires_at"] < int(time.time()):
-            # Expired - delete and return None
-            await self.conn.execute("DELETE FROM cache WHERE key = ?", (key,))
-            await self.conn.commit()
-            return None
+    async def get_archived_trades(
+        self,
+        archive_id: int | None = None,
+        symbol: str | None = None,
+        limit: int = 10000,
+    ) -> list[dict]:
+        """Get archived trades (oldest first) with parsed raw_data."""
+        import json
 
-        return row["value"]
+        where: list[str] = []
+        params: list = []
+        if archive_id is not None:
+            where.append("archive_id = ?")
+            params.append(archive_id)
+        if symbol:
+            where.append("symbol = ?")
+            params.append(symbol)
+        where_sql = f"WHERE {' AND '.join(where)}" if where else ""
+        cursor = await self.conn.execute(
+            f"SELECT * FROM archived_trades {where_sql} ORDER BY executed_at ASC LIMIT ?",  # noqa: S608
+            (*params, limit),
+        )
+        result = []
+        for row in await cursor.fetchall():
+            trade = dict(row)
+            try:
+                trade["raw_data"] = json.loads(trade["raw_data"])
+            except (json.JSONDecodeError, TypeError):
+                pass
+            result.append(trade)
+        return result
 
-    async def cache_set(self, key: str, value: str, ttl_seconds: int | None = None) -> None:
-        """Set a cached value. TTL is optional (None = never expires)."""
+    # -------------------------------------------------------------------------
+    # Notifications
+    # -------------------------------------------------------------------------
+
+    async def add_notification(
+        self,
+        severity: str,
+        category: str,
+        title: str,
+        message: str | None = None,
+        entity_type: str | None = None,
+        entity_id: str | None = None,
+        link: str | None = None,
+        dedupe_key: str | None = None,
+    ) -> int:
+        """
+        Add a notification to the inbox.
+
+        If an unread notification with the same dedupe_key exists, it is updated
+        in place (latest message, occurrence count bumped) instead of adding a row.
+
+        Returns:
+            ID of the new or updated notification
+        """
         import time
 
-        expires_at = int(time.time()) + ttl_seconds if ttl_seconds else None
-        await self.conn.execute(
-            "INSERT OR REPLACE INTO cache (key, value, expires_at) VALUES (?, ?, ?)", (key, value, expires_at)
+        now = int(time.time())
+        if dedupe_key:
+            cursor = await self.conn.execute(
+                "SELECT id FROM notifications WHERE dedupe_key = ? AND read_at IS NULL ORDER BY id DESC LIMIT 1",
+                (dedupe_key,),
+            )
+            row = await cursor.fetchone()
+            if row:
+                await self.conn.execute(
+                    """UPDATE notifications
+                       SET severity = ?, title = ?, message = ?, updated_at = ?, occurrences = occurrences + 1
+                       WHERE id = ?""",
+                    (severity, title, message, now, row["id"]),
+                )
+                await self.conn.commit()
+                return row["id"]
+        cursor = await self.conn.execute(
+            """INSERT INTO notifications
+               (created_at, updated_at, severity, category, title, message,
+                entity_type, entity_id, link, dedupe_key)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            (now, now, severity, category, title, message, entity_type, entity_id, link, dedupe_key),
         )
         await self.conn.commit()
+        return cursor.lastrowid or 0
 
-    async def cache_delete(self, key: str) -> None:
-        """Delete a cached value."""
-        await self.conn.execute("DELETE FROM cache WHERE key = ?", (key,))
+    async def get_notifications(
+        self,
+        unread_only: bool = False,
+        severity: str | None = None,
+        limit: int = 50,
+        offset: int = 0,
+    ) -> list[dict]:
+        """Get notifications, most recent first."""
+        where: list[str] = []
+        params: list = []
+        if unread_only:
+            where.append("read_at IS NULL")
+        if severity:
+            where.append("severity = ?")
+            params.append(severity)
+        where_sql = f"WHERE {' AND '.join(where)}" if where else ""
+        cursor = await self.conn.execute(
+            f"SELECT * FROM notifications {where_sql} ORDER BY updated_at DESC, id DESC LIMIT ? OFFSET ?",  # noqa: S608
+            (*params, limit, offset),
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_unread_notification_count(self) -> int:
+        """Count notifications not yet acknowledged."""
+        cursor = await self.conn.execute("SELECT COUNT(*) FROM notifications WHERE read_at IS NULL")
+        row = await cursor.fetchone()
+        return row[0] if row else 0
+
+    async def mark_notification_read(self, notification_id: int) -> bool:
+        """Acknowledge one notification. Returns False if it does not exist."""
+        import time
+
+        cursor = await self.conn.execute(
+            "UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ?",
+            (int(time.time()), notification_id),
+        )
         await self.conn.commit()
+        return cursor.rowcount > 0
 
-    async def cache_clear(self, prefix: str | None = None) -> int:
-        """Clear cache entries. If prefix given, only clear keys starting with it."""
-        if prefix:
-            cursor = await self.conn.execute("DELETE FROM cache WHERE key LIKE ?", (f"{prefix}%",))
-        else:
-            cursor = await self.conn.execute("DELETE FROM cache")
+    async def mark_all_notifications_read(self) -> int:
+        """Acknowledge every unread notification. Returns the number acknowledged."""
+        import time
+
+        cursor = await self.conn.execute(
+            "UPDATE notifications SET read_at = ? WHERE read_at IS NULL",
+            (int(time.time()),),
+        )
         await self.conn.commit()
         return cursor.rowcount
 
-    async def cache_cleanup_expired(self) -> int:
-        """Remove all expired cache entries."""
+    # -------------------------------------------------------------------------
+    # Webhooks
+    # -------------------------------------------------------------------------
+
+    @staticmethod
+    def _webhook_row(row) -> dict:
+        import json
+
+        webhook = dict(row)
+        webhook["events"] = json.loads(webhook["events"]) if webhook.get("events") else None
+        webhook["enabled"] = bool(webhook["enabled"])
+        return webhook
+
+    async def create_webhook(
+        self,
+        name: str,
+        kind: str,
+        url: str,
+        target: str | None = None,
+        secret: str | None = None,
+        events: list[str] | None = None,
+        enabled: bool = True,
+    ) -> int:
+        """Register a notification endpoint. Returns its ID."""
+        import json
         import time
 
         cursor = await self.conn.execute(
-            "DELETE FROM cache WHERE expires_at IS NOT NULL AND expires_at < ?", (int(time.time()),)
+            """INSERT INTO webhooks (name, kind, url, target, secret, events, enabled, created_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                name,
+                kind,
+                url,
+                target,
+                secret,
+                json.dumps(events) if events is not None else None,
+                int(enabled),
+                int(time.time()),
+            ),
         )
         await self.conn.commit()
-        return cursor.rowcount
+        return cursor.lastrowid or 0
+
+    async def get_webhooks(self, enabled_only: bool = False) -> list[dict]:
+        """List notification endpoints (including their secrets)."""
+        query = "SELECT * FROM webhooks"
+        if enabled_only:
+            query += " WHERE enabled = 1"
+        cursor = await self.conn.execute(query + " ORDER BY id")
+        return [self._webhook_row(row) for row in await cursor.fetchall()]
+
+    async def get_webhook(self, webhook_id: int) -> dict | None:
+        """Get one notification endpoint."""
+        cursor = await self.conn.execute("SELECT * FROM webhooks WHERE id = ?", (webhook_id,))
+        row = await cursor.fetchone()
+        return self._webhook_row(row) if row else None
+
+    async def update_webhook(self, webhook_id: int, **fields) -> bool:
+        """Update name, url, target, secret, events and/or enabled. Returns False if it does not exist."""
+        import json
+
+        allowed = {"name", "url", "target", "secret", "events", "enabled"}
+        updates = {k: v for k, v in fields.items() if k in allowed}
+        if "events" in updates:
+            updates["events"] = json.dumps(updates["events"]) if updates["events"] is not None else None
+        if "enabled" in updates:
+            updates["enabled"] = int(bool(updates["enabled"]))
+        if not updates:
+            return await self.get_webhook(webhook_id) is not None
+        assignments = ", ".join(f"{key} = ?" for key in updates)
+        cursor = await self.conn.execute(
+            f"UPDATE webhooks SET {assignments} WHERE id = ?",  # noqa: S608
+            (*updates.values(), webhook_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_webhook(self, webhook_id: int) -> bool:
+        """Remove an endpoint and its delivery queue."""
+        cursor = await self.conn.execute("DELETE FROM webhooks WHERE id = ?", (webhook_id,))
+        await self.conn.execute("DELETE FROM webhook_deliveries WHERE webhook_id = ?", (webhook_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def add_webhook_delivery(self, webhook_id: int, event: str, payload: dict) -> int:
+        """Queue an event for delivery to an endpoint. Returns the delivery ID."""
+        import json
+        import time
+
+        now = int(time.time())
+        cursor = await self.conn.execute(
+            """INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at)
+               VALUES (?, ?, ?, ?, ?)""",
+            (webhook_id, event, json.dumps(payload), now, now),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_due_webhook_deliveries(self, now: int, limit: int = 100) -> list[dict]:
+        """Pending deliveries whose next attempt is due, oldest first."""
+        import json
+
+        cursor = await self.conn.execute(
+            """SELECT * FROM webhook_deliveries
+               WHERE status = 'pending' AND next_attempt_at <= ?
+               ORDER BY id LIMIT ?""",
+            (now, limit),
+        )
+        deliveries = []
+        for row in await cursor.fetchall():
+            delivery = dict(row)
+            delivery["payload"] = json.loads(delivery["payload"])
+            deliveries.append(delivery)
+        return deliveries
+
+    async def update_webhook_delivery(
+        self,
+        delivery_id: int,
+        status: str,
+        attempts: int,
+        next_attempt_at: int | None = None,
+        last_error: str | None = None,
+    ) -> None:
+        """Record the outcome of a delivery attempt."""
+        import time
+
+        now = int(time.time())
+        await self.conn.execute(
+            """UPDATE webhook_deliveries
+               SET status = ?, attempts = ?, next_attempt_at = COALESCE(?, next_attempt_at),
+                   last_error = ?, delivered_at = ?
+               WHERE id = ?""",
+            (status, attempts, next_attempt_at, last_error, now if status == "delivered" else None, delivery_id),
+        )
+        await self.conn.commit()
+
+    async def get_webhook_deliveries(self, webhook_id: int | None = None, limit: int = 50) -> list[dict]:
+        """Recent deliveries, newest first."""
+        import json
+
+        query = "SELECT * FROM webhook_deliveries"
+        params: list[int] = []
+        if webhook_id is not None:
+            query += " WHERE webhook_id = ?"
+            params.append(webhook_id)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC LIMIT ?", (*params, limit))
+        deliveries = []
+        for row in await cursor.fetchall():
+            delivery = dict(row)
+            delivery["payload"] = json.loads(delivery["payload"])
+            deliveries.append(delivery)
+        return deliveries
+
+    # -------------------------------------------------------------------------
+    # Reports
+    # -------------------------------------------------------------------------
+
+    async def save_report(
+        self,
+        period: str,
+        period_start: int,
+        period_end: int,
+        data: dict,
+        markdown: str,
+        html: str,
+        keep: int | None = None,
+    ) -> int:
+        """Store a generated digest. With `keep`, only the most recent `keep` reports are retained."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO reports (period, period_start, period_end, generated_at, data, markdown, html)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (period, period_start, period_end, int(time.time()), json.dumps(data), markdown, html),
+        )
+        if keep is not None:
+            await self.conn.execute(
+                "DELETE FROM reports WHERE id NOT IN (SELECT id FROM reports ORDER BY id DESC LIMIT ?)",
+                (max(int(keep), 1),),
+            )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_reports(self, period: str | None = None, limit: int = 50) -> list[dict]:
+        """List reports, most recent first (without the rendered bodies)."""
+        query = "SELECT id, period, period_start, period_end, generated_at FROM reports"
+        params: list = []
+        if period:
+            query += " WHERE period = ?"
+            params.append(period)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC LIMIT ?", (*params, limit))
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_report(self, report_id: int | None = None, period: str | None = None) -> dict | None:
+        """Get one report with its data and rendered bodies (default: the latest, optionally of a period)."""
+        import json
+
+        if report_id is not None:
+            cursor = await self.conn.execute("SELECT * FROM reports WHERE id = ?", (report_id,))
+        elif period:
+            cursor = await self.conn.execute(
+                "SELECT * FROM reports WHERE period = ? ORDER BY id DESC LIMIT 1",
+                (period,),
+            )
+        else:
+            cursor = await self.conn.execute("SELECT * FROM reports ORDER BY id DESC LIMIT 1")
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        report = dict(row)
+        report["data"] = json.loads(report["data"])
+        return report
+
+    async def add_email_delivery(
+        self,
+        kind: str,
+        period: str,
+        subject: str,
+        recipients: list[str],
+        status: str,
+        error: str | None = None,
+    ) -> int:
+        """Record an attempt to email a report."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO email_deliveries (kind, period, subject, recipients, status, error, created_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (kind, period, subject, json.dumps(recipients), status, error, int(time.time())),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_email_deliveries(self, kind: str | None = None, limit: int = 50) -> list[dict]:
+        """Email attempts, most recent first."""
+        import json
+
+        query = "SELECT * FROM email_deliveries"
+        params: list = []
+        if kind:
+            query += " WHERE kind = ?"
+            params.append(kind)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC LIMIT ?", (*params, limit))
+        deliveries = [dict(row) for row in await cursor.fetchall()]
+        for delivery in deliveries:
+            delivery["recipients"] = json.loads(delivery["recipients"])
+        return deliveries
+
+    async def email_sent(self, kind: str, period: str) -> bool:
+        """Whether a report of this kind and period was emailed successfully."""
+        cursor = await self.conn.execute(
+            "SELECT 1 FROM email_deliveries WHERE kind = ? AND period = ? AND status = 'sent' LIMIT 1",
+            (kind, period),
+        )
+        return await cursor.fetchone() is not None
+
+    # -------------------------------------------------------------------------
+    # Recommendation approvals
+    # -------------------------------------------------------------------------
+
+    async def set_recommendation_decision(
+        self,
+        symbol: str,
+        action: str,
+        status: str,
+        quantity: float,
+        expires_at: int,
+        decided_at: int | None = None,
+    ) -> None:
+        """Record an approve/reject/defer decision on the pending recommendation for symbol and side."""
+        import time
+
+        await self.conn.execute(
+            """INSERT OR REPLACE INTO recommendation_decisions
+               (symbol, action, status, quantity, decided_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)""",
+            (symbol, action, status, quantity, decided_at or int(time.time()), expires_at),
+        )
+        await self.conn.commit()
+
+    async def get_recommendation_decisions(self, now: int | None = None) -> list[dict]:
+        """Decisions still in force at `now` (default: current time)."""
+        import time
+
+        cursor = await self.conn.execute(
+            "SELECT * FROM recommendation_decisions WHERE expires_at > ? ORDER BY decided_at DESC",
+            (now if now is not None else int(time.time()),),
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def delete_recommendation_decision(self, symbol: str, action: str) -> None:
+        """Forget the decision on symbol and side (after the approved order was placed)."""
+        await self.conn.execute(
+            "DELETE FROM recommendation_decisions WHERE symbol = ? AND action = ?",
+            (symbol, action),
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Scheduled Orders
+    # -------------------------------------------------------------------------
+
+    @staticmethod
+    def _scheduled_order(row) -> dict:
+        import json
+
+        order = dict(row)
+        for column in ("symbols", "run_state", "last_result"):
+            order[column] = json.loads(order[column]) if order[column] else None
+        return order
+
+    async def create_scheduled_order(self, order: dict) -> int:
+        """Store a validated scheduled order. Returns its id."""
+        import json
+        import time
+
+        now = int(time.time())
+        cursor = await self.conn.execute(
+            """INSERT INTO scheduled_orders
+               (created_at, updated_at, action, frequency, symbols, group_type, group_name, top_n,
+                amount_eur, quantity, day_of_month, timing, next_run_date, note)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                now,
+                now,
+                order["action"],
+                order["frequency"],
+                json.dumps(order["symbols"]) if order.get("symbols") else None,
+                order.get("group_type"),
+                order.get("group_name"),
+                order.get("top_n"),
+                order.get("amount_eur"),
+                order.get("quantity"),
+                order.get("day_of_month"),
+                order["timing"],
+                order["next_run_date"],
+                order.get("note"),
+            ),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_scheduled_orders(self, status: str | None = None) -> list[dict]:
+        """Scheduled orders by next run date, optionally of one status."""
+        query = "SELECT * FROM scheduled_orders"
+        params: tuple = ()
+        if status:
+            query += " WHERE status = ?"
+            params = (status,)
+        cursor = await self.conn.execute(query + " ORDER BY next_run_date, id", params)
+        return [self._scheduled_order(row) for row in await cursor.fetchall()]
+
+    async def get_scheduled_order(self, order_id: int) -> dict | None:
+        """One scheduled order."""
+        cursor = await self.conn.execute("SELECT * FROM scheduled_orders WHERE id = ?", (order_id,))
+        row = await cursor.fetchone()
+        return self._scheduled_order(row) if row else None
+
+    async def get_due_scheduled_orders(self, today: str) -> list[dict]:
+        """Active orders whose next run date (YYYY-MM-DD) is today or earlier."""
+        cursor = await self.conn.execute(
+            "SELECT * FROM scheduled_orders WHERE status = 'active' AND next_run_date <= ? ORDER BY next_run_date, id",
+            (today,),
+        )
+        return [self._scheduled_order(row) for row in await cursor.fetchall()]
+
+    async def update_scheduled_order(self, order_id: int, **fields) -> None:
+        """Update status, next_run_date, run_state, last_run_at or last_result of a scheduled order."""
+        import json
+        import time
+
+        allowed = {"status", "next_run_date", "run_state", "last_run_at", "last_result"}
+        unknown = set(fields) - allowed
+        if unknown:
+            raise ValueError(f"Cannot update scheduled order fields: {', '.join(sorted(unknown))}")
+        values = {
+            key: json.dumps(value) if key in ("run_state", "last_result") and value is not None else value
+            for key, value in fields.items()
+        }
+        values["updated_at"] = int(time.time())
+        assignments = ", ".join(f"{key} = ?" for key in values)
+        await self.conn.execute(
+            f"UPDATE scheduled_orders SET {assignments} WHERE id = ?",  # noqa: S608
+            (*values.values(), order_id),
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Sliced Orders
+    # -------------------------------------------------------------------------
+
+    @staticmethod
+    def _sliced_order(row, slices: list[dict]) -> dict:
+        import json
+
+        order = dict(row)
+        order["recommendation"] = json.loads(order["recommendation"])
+        order["slices"] = slices
+        return order
+
+    async def create_sliced_order(self, order: dict, slices: list[dict]) -> int:
+        """
+        Store a sliced order and its child orders atomically.
+
+        Args:
+            order: sliced_orders columns (symbol, action, quantity, source, reason, recommendation)
+            slices: order_slices columns (seq, quantity, due_at) of each child
+
+        Returns:
+            ID of the sliced order
+        """
+        import json
+        import time
+
+        now = int(time.time())
+        await self.conn.execute("BEGIN")
+        try:
+            cursor = await self.conn.execute(
+                """INSERT INTO sliced_orders
+                   (symbol, action, quantity, source, reason, recommendation, created_at, updated_at)
+                   VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+                (
+                    order["symbol"],
+                    order["action"],
+                    order["quantity"],
+                    order["source"],
+                    order["reason"],
+                    json.dumps(order["recommendation"]),
+                    now,
+                    now,
+                ),
+            )
+            parent_id = cursor.lastrowid or 0
+            await self.conn.executemany(
+                "INSERT INTO order_slices (parent_id, seq, quantity, due_at) VALUES (?, ?, ?, ?)",
+                [(parent_id, s["seq"], s["quantity"], s["due_at"]) for s in slices],
+            )
+            await self.conn.commit()
+        except Exception:
+            await self.conn.execute("ROLLBACK")
+            raise
+        return parent_id
+
+    async def get_sliced_orders(self, status: str | None = None, limit: int = 100) -> list[dict]:
+        """Sliced orders with their child orders, newest first, optionally of one status."""
+        query = "SELECT * FROM sliced_orders"
+        params: list = []
+        if status:
+            query += " WHERE status = ?"
+            params.append(status)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC LIMIT ?", (*params, limit))
+        rows = await cursor.fetchall()
+        if not rows:
+            return []
+        ids = [row["id"] for row in rows]
+        placeholders = ", ".join("?" * len(ids))
+        cursor = await self.conn.execute(
+            f"SELECT * FROM order_slices WHERE parent_id IN ({placeholders}) ORDER BY parent_id, seq",  # noqa: S608
+            ids,
+        )
+        slices: dict[int, list[dict]] = {}
+        for row in await cursor.fetchall():
+            slices.setdefault(row["parent_id"], []).append(dict(row))
+        return [self._sliced_order(row, slices.get(row["id"], [])) for row in rows]
+
+    async def get_sliced_order(self, order_id: int) -> dict | None:
+        """One sliced order with its child orders."""
+        cursor = await self.conn.execute("SELECT * FROM sliced_orders WHERE id = ?", (order_id,))
+        row = await cursor.fetchone()
+        if row is None:
+            return None
+        cursor = await self.conn.execute("SELECT * FROM order_slices WHERE parent_id = ? ORDER BY seq", (order_id,))
+        return self._sliced_order(row, [dict(r) for r in await cursor.fetchall()])
+
+    async def update_sliced_order(self, order_id: int, status: str) -> None:
+        """Set the status of a sliced order."""
+        import time
+
+        await self.conn.execute(
+            "UPDATE sliced_orders SET status = ?, updated_at = ? WHERE id = ?", (status, int(time.time()), order_id)
+        )
+        await self.conn.commit()
+
+    async def update_order_slice(self, slice_id: int, **fields) -> None:
+        """Update status, order_id, placed_at or detail of a child order."""
+        allowed = {"status", "order_id", "placed_at", "detail"}
+        unknown = set(fields) - allowed
+        if unknown:
+            raise ValueError(f"Cannot update order slice fields: {', '.join(sorted(unknown))}")
+        assignments = ", ".join(f"{key} = ?" for key in fields)
+        await self.conn.execute(
+            f"UPDATE order_slices SET {assignments} WHERE id = ?",  # noqa: S608
+            (*fields.values(), slice_id),
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Auto-Invest Runs
+    # -------------------------------------------------------------------------
+
+    async def save_auto_invest_run(
+        self,
+        deposit_ids: list[int],
+        deposit_eur: float,
+        budget_eur: float,
+        status: str,
+        orders: list[dict],
+        reason: str | None = None,
+    ) -> int:
+        """Record how a batch of new deposits was handled by the auto-invest policy."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO auto_invest_runs (created_at, deposit_ids, deposit_eur, budget_eur, status, orders, reason)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (int(time.time()), json.dumps(deposit_ids), deposit_eur, budget_eur, status, json.dumps(orders), reason),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_auto_invest_runs(self, limit: int = 20) -> list[dict]:
+        """Auto-invest runs, most recent first."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM auto_invest_runs ORDER BY id DESC LIMIT ?", (limit,))
+        runs = []
+        for row in await cursor.fetchall():
+            run = dict(row)
+            run["deposit_ids"] = json.loads(run["deposit_ids"])
+            run["orders"] = json.loads(run["orders"])
+            runs.append(run)
+        return runs
+
+    # -------------------------------------------------------------------------
+    # Broker Reconciliations
+    # -------------------------------------------------------------------------
+
+    async def save_reconciliation(self, period: str, status: str, summary: dict, discrepancies: list[dict]) -> int:
+        """Store a reconciliation run of a month (YYYY-MM) against the broker report."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO reconciliations (period, created_at, status, summary, discrepancies)
+               VALUES (?, ?, ?, ?, ?)""",
+            (period, int(time.time()), status, json.dumps(summary), json.dumps(discrepancies)),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_reconciliations(self, period: str | None = None, limit: int = 24) -> list[dict]:
+        """Reconciliation runs without their discrepancies, most recent first."""
+        import json
+
+        query = """SELECT id, period, created_at, status, summary, signed_off_at, signed_off_by, note
+                   FROM reconciliations"""
+        params: list = []
+        if period:
+            query += " WHERE period = ?"
+            params.append(period)
+        query += " ORDER BY id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        runs = []
+        for row in await cursor.fetchall():
+            run = dict(row)
+            run["summary"] = json.loads(run["summary"])
+            runs.append(run)
+        return runs
+
+    async def get_reconciliation(self, reconciliation_id: int) -> dict | None:
+        """A reconciliation run with its discrepancies."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM reconciliations WHERE id = ?", (reconciliation_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        run = dict(row)
+        run["summary"] = json.loads(run["summary"])
+        run["discrepancies"] = json.loads(run["discrepancies"])
+        return run
+
+    async def sign_off_reconciliation(self, reconciliation_id: int, signed_off_by: str, note: str | None) -> None:
+        """Mark a reconciliation run as signed off."""
+        import time
+
+        await self.conn.execute(
+            """UPDATE reconciliations SET status = 'signed_off', signed_off_at = ?, signed_off_by = ?, note = ?
+               WHERE id = ?""",
+            (int(time.time()), signed_off_by, note, reconciliation_id),
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Position Adjustments
+    # -------------------------------------------------------------------------
+
+    async def add_position_adjustment(self, adjustment: dict) -> int:
+        """
+        Record a manual position correction and apply it to the positions row atomically.
+
+        Args:
+            adjustment: position_adjustments columns (symbol, broker_quantity, quantity_before,
+                        quantity_after, reason, ...); created_at defaults to now
+
+        Returns:
+            ID of the new position_adjustments row
+        """
+        import time
+
+        row = dict(adjustment)
+        row.setdefault("created_at", int(time.time()))
+        cols = ", ".join(row.keys())
+        placeholders = ", ".join("?" * len(row))
+
+        await self.conn.execute("BEGIN")
+        try:
+            cursor = await self.conn.execute(
+                f"INSERT INTO position_adjustments ({cols}) VALUES ({placeholders})",  # noqa: S608
+                tuple(row.values()),
+            )
+            cursor_update = await self.conn.execute(
+                "UPDATE positions SET quantity = ?, avg_cost = ?, updated_at = 'now' WHERE symbol = ?",
+                (row["quantity_after"], row.get("avg_cost_after"), row["symbol"]),
+            )
+            if cursor_update.rowcount == 0:
+                await self.conn.execute(
+                    "INSERT INTO positions (symbol, quantity, avg_cost, updated_at) VALUES (?, ?, ?, 'now')",
+                    (row["symbol"], row["quantity_after"], row.get("avg_cost_after")),
+                )
+            await self.conn.commit()
+        except Exception:
+            await self.conn.execute("ROLLBACK")
+            raise
+        return cursor.lastrowid or 0
+
+    async def get_position_adjustments(
+        self,
+        symbol: str | None = None,
+        start_ts: int | None = None,
+        end_ts: int | None = None,
+        limit: int = 100,
+    ) -> list[dict]:
+        """Manual position adjustments, newest first, optionally for one symbol or a created_at range."""
+        query = "SELECT * FROM position_adjustments WHERE 1 = 1"
+        params: list = []
+        if symbol:
+            query += " AND symbol = ?"
+            params.append(symbol)
+        if start_ts is not None:
+            query += " AND created_at >= ?"
+            params.append(start_ts)
+        if end_ts is not None:
+            query += " AND created_at <= ?"
+            params.append(end_ts)
+        query += " ORDER BY id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_latest_position_adjustments(self) -> dict[str, dict]:
+        """The most recent adjustment of every adjusted symbol."""
+        cursor = await self.conn.execute(
+            """SELECT * FROM position_adjustments
+               WHERE id IN (SELECT MAX(id) FROM position_adjustments GROUP BY symbol)"""
+        )
+        return {row["symbol"]: dict(row) for row in await cursor.fetchall()}
+
+    # -------------------------------------------------------------------------
+    # Protective Orders
+    # -------------------------------------------------------------------------
+
+    async def get_protective_orders(self) -> dict[str, dict]:
+        """Protective orders in force, by symbol."""
+        cursor = await self.conn.execute("SELECT * FROM protective_orders ORDER BY symbol")
+        return {row["symbol"]: dict(row) for row in await cursor.fetchall()}
+
+    async def add_protective_order_adjustment(self, adjustment: dict) -> int:
+        """
+        Record a protective order change and apply it to the protective_orders row atomically.
+
+        Args:
+            adjustment: protective_order_adjustments columns (symbol, action, reason, ...); a cancel
+                        removes the symbol's order, a place or replace sets it; created_at defaults to now
+
+        Returns:
+            ID of the new protective_order_adjustments row
+        """
+        import time
+
+        row = dict(adjustment)
+        row.setdefault("created_at", int(time.time()))
+        cols = ", ".join(row.keys())
+        placeholders = ", ".join("?" * len(row))
+
+        await self.conn.execute("BEGIN")
+        try:
+            cursor = await self.conn.execute(
+                f"INSERT INTO protective_order_adjustments ({cols}) VALUES ({placeholders})",  # noqa: S608
+                tuple(row.values()),
+            )
+            if row["action"] == "cancel":
+                await self.conn.execute("DELETE FROM protective_orders WHERE symbol = ?", (row["symbol"],))
+            else:
+                await self.conn.execute(
+                    """INSERT OR REPLACE INTO protective_orders
+                       (symbol, policy, kind, percent, stop_price, quantity, order_id, placed_at)
+                       VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+                    (
+                        row["symbol"],
+                        row["policy"],
+                        row["kind"],
+                        row["percent"],
+                        row.get("stop_price"),
+                        row["quantity"],
+                        row["order_id"],
+                        row["created_at"],
+                    ),
+                )
+            await self.conn.commit()
+        except Exception:
+            await self.conn.execute("ROLLBACK")
+            raise
+        return cursor.lastrowid or 0
+
+    async def get_protective_order_adjustments(self, symbol: str | None = None, limit: int = 100) -> list[dict]:
+        """Protective order changes, newest first, optionally for one symbol."""
+        query = "SELECT * FROM protective_order_adjustments"
+        params: list = []
+        if symbol:
+            query += " WHERE symbol = ?"
+            params.append(symbol)
+        query += " ORDER BY id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Position Theses
+    # -------------------------------------------------------------------------
+
+    async def set_position_thesis(self, symbol: str, **fields) -> None:
+        """Create or update a position's thesis; only the given position_theses columns change on update."""
+        import time
+
+        now = int(time.time())
+        row = {"symbol": symbol, "reviewed_at": now, "created_at": now, **fields, "updated_at": now}
+        cols = ", ".join(row)
+        placeholders = ", ".join("?" * len(row))
+        # An update keeps the creation time, and the last review unless a new one is given
+        kept = {"symbol", "created_at"} if "reviewed_at" in fields else {"symbol", "created_at", "reviewed_at"}
+        updates = ", ".join(f"{col} = excluded.{col}" for col in row if col not in kept)
+        await self.conn.execute(
+            f"""INSERT INTO position_theses ({cols}) VALUES ({placeholders})
+                ON CONFLICT(symbol) DO UPDATE SET {updates}""",  # noqa: S608
+            tuple(row.values()),
+        )
+        await self.conn.commit()
+
+    async def get_position_thesis(self, symbol: str) -> dict | None:
+        """One position's thesis, or None."""
+        cursor = await self.conn.execute("SELECT * FROM position_theses WHERE symbol = ?", (symbol,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def get_position_theses(self) -> dict[str, dict]:
+        """Every position thesis, by symbol."""
+        cursor = await self.conn.execute("SELECT * FROM position_theses ORDER BY symbol")
+        return {row["symbol"]: dict(row) for row in await cursor.fetchall()}
+
+    async def delete_position_thesis(self, symbol: str) -> bool:
+        """Remove a position's thesis. Returns False if it had none."""
+        cursor = await self.conn.execute("DELETE FROM position_theses WHERE symbol = ?", (symbol,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # External Holdings
+    # -------------------------------------------------------------------------
+
+    async def add_external_holding(self, holding: dict) -> int:
+        """Register a holding outside the broker account (external_holdings columns)."""
+        import time
+
+        row = dict(holding)
+        now = int(time.time())
+        row.setdefault("created_at", now)
+        row.setdefault("updated_at", now)
+        cols = ", ".join(row.keys())
+        placeholders = ", ".join("?" * len(row))
+        cursor = await self.conn.execute(
+            f"INSERT INTO external_holdings ({cols}) VALUES ({placeholders})",  # noqa: S608
+            tuple(row.values()),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def update_external_holding(self, holding_id: int, **fields) -> bool:
+        """Change columns of a holding. Returns False if it does not exist."""
+        import time
+
+        sets = "".join(f"{column} = ?, " for column in fields)
+        cursor = await self.conn.execute(
+            f"UPDATE external_holdings SET {sets}updated_at = ? WHERE id = ?",  # noqa: S608
+            (*fields.values(), int(time.time()), holding_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_external_holding(self, holding_id: int) -> bool:
+        """Delete a holding and its valuations. Returns False if it does not exist."""
+        await self.conn.execute("DELETE FROM external_valuations WHERE holding_id = ?", (holding_id,))
+        cursor = await self.conn.execute("DELETE FROM external_holdings WHERE id = ?", (holding_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def get_external_holdings(self) -> list[dict]:
+        """All external holdings, largest latest valuation first."""
+        cursor = await self.conn.execute("SELECT * FROM external_holdings ORDER BY value DESC, id ASC")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_external_holding(self, holding_id: int) -> dict | None:
+        """One external holding."""
+        cursor = await self.conn.execute("SELECT * FROM external_holdings WHERE id = ?", (holding_id,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def add_external_valuation(self, holding_id: int, value: float, source: str, valued_at: int) -> None:
+        """Record a valuation; it becomes the holding's value unless a later one is already recorded."""
+        import time
+
+        await self.conn.execute(
+            """INSERT INTO external_valuations (holding_id, value, source, valued_at, recorded_at)
+               VALUES (?, ?, ?, ?, ?)""",
+            (holding_id, value, source, valued_at, int(time.time())),
+        )
+        await self.conn.execute(
+            """UPDATE external_holdings SET value = ?, valued_at = ?, value_source = ?, updated_at = ?
+               WHERE id = ? AND (valued_at IS NULL OR valued_at <= ?)""",
+            (value, valued_at, source, int(time.time()), holding_id, valued_at),
+        )
+        await self.conn.commit()
+
+    async def get_external_valuations(self, holding_id: int, limit: int = 100) -> list[dict]:
+        """Valuation history of a holding, newest first."""
+        cursor = await self.conn.execute(
+            "SELECT * FROM external_valuations WHERE holding_id = ? ORDER BY valued_at DESC, id DESC LIMIT ?",
+            (holding_id, limit),
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Exports
+    # -------------------------------------------------------------------------
+
+    async def iter_export_rows(
+        self,
+        dataset: str,
+        start_date: str | None = None,
+        end_date: str | None = None,
+        batch_size: int = 500,
+    ):
+        """
+        Yield the rows of an export dataset oldest first, fetching batch_size rows at a time.
+
+        Args:
+            dataset: trades (including archived round trips), dividends, cash_flows or snapshots
+            start_date: First day included (YYYY-MM-DD)
+            end_date: Last day included (YYYY-MM-DD)
+        """
+        import json
+        from datetime import datetime, timezone
+
+        def day_ts(day: str, end: bool = False) -> int:
+            ts = int(datetime.strptime(day, "%Y-%m-%d").replace(tzinfo=timezone.utc).timestamp())
+            return ts + 86_399 if end else ts
+
+        # Trades and snapshots are dated by unix timestamp, dividends and cash flows by date text
+        if dataset in ("trades", "snapshots"):
+            column = "t.executed_at" if dataset == "trades" else "date"
+            start = day_ts(start_date) if start_date else None
+            end = day_ts(end_date, end=True) if end_date else None
+        else:
+            column, start, end = "substr(date, 1, 10)", start_date, end_date
+        where, params = ["1 = 1"], []
+        if start is not None:
+            where.append(f"{column} >= ?")
+            params.append(start)
+        if end is not None:
+            where.append(f"{column} <= ?")
+            params.append(end)
+        where_sql = " AND ".join(where)
+
+        if dataset == "trades":
+            columns = (
+                "t.id, t.broker_trade_id, t.symbol, t.side, t.quantity, t.price, "
+                "COALESCE(t.commission, 0) AS commission, t.commission_currency, t.executed_at, s.name, s.currency"
+            )
+            query = f"""SELECT {columns} FROM trades t LEFT JOIN securities s ON s.symbol = t.symbol
+                        WHERE {where_sql}
+                        UNION ALL
+                        SELECT {columns} FROM archived_trades t LEFT JOIN securities s ON s.symbol = t.symbol
+                        WHERE {where_sql}
+                        ORDER BY executed_at ASC, id ASC"""  # noqa: S608
+            params = params * 2
+        elif dataset == "dividends":
+            columns = "id, symbol, date, amount, currency, value, gross_amount, withholding_amount, country"
+            query = f"SELECT {columns} FROM dividends WHERE {where_sql} ORDER BY date ASC"  # noqa: S608
+        elif dataset == "cash_flows":
+            columns = "id, date, type_id, amount, currency, comment"
+            query = f"SELECT {columns} FROM cash_flows WHERE {where_sql} ORDER BY date ASC"  # noqa: S608
+        elif dataset == "snapshots":
+            query = f"SELECT date, data FROM portfolio_snapshots WHERE {where_sql} ORDER BY date ASC"  # noqa: S608
+        else:
+            raise InvalidInput(f"Unknown export dataset {dataset}", dataset=dataset)
+
+        cursor = await self.conn.execute(query, params)
+        try:
+            while rows := await cursor.fetchmany(batch_size):
+                for row in rows:
+                    item = dict(row)
+                    if dataset == "snapshots":
+                        item["data"] = json.loads(item["data"])
+                    yield item
+        finally:
+            await cursor.close()
+
+    # -------------------------------------------------------------------------
+    # Price history downloads
+    # -------------------------------------------------------------------------
+
+    async def start_price_downloads(self, symbols: list[str], start_date: str) -> None:
+        """Queue symbols for a history download, replacing the state of earlier downloads."""
+        import time
+
+        now = int(time.time())
+        await self.conn.executemany(
+            """INSERT OR REPLACE INTO price_downloads (symbol, start_date, status, updated_at)
+               VALUES (?, ?, 'pending', ?)""",
+            [(symbol, start_date, now) for symbol in symbols],
+        )
+        await self.conn.commit()
+
+    async def finish_price_download(
+        self,
+        symbol: str,
+        status: str,
+        source: str | None = None,
+        bars: int = 0,
+        last_date: str | None = None,
+        error: str | None = None,
+    ) -> None:
+        """Record the outcome of one symbol's download (done or failed) and the provider it came from."""
+        import time
+
+        await self.conn.execute(
+            """UPDATE price_downloads
+               SET status = ?, source = ?, bars = ?, last_date = ?, error = ?, updated_at = ?
+               WHERE symbol = ?""",
+            (status, source, bars, last_date, error, int(time.time()), symbol),
+        )
+        await self.conn.commit()
+
+    async def get_price_downloads(self, status: str | None = None) -> list[dict]:
+        """Download state per symbol (optionally only one status), in symbol order."""
+        query = "SELECT * FROM price_downloads"
+        params: list = []
+        if status:
+            query += " WHERE status = ?"
+            params.append(status)
+        cursor = await self.conn.execute(query + " ORDER BY symbol", params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Audit log
+    # -------------------------------------------------------------------------
+
+    async def add_audit_entry(
+        self,
+        actor_type: str,
+        actor: str,
+        action: str,
+        target: str | None,
+        params: dict,
+        outcome: str,
+        error: str | None = None,
+        correlation_id: str | None = None,
+    ) -> int:
+        """Append an audit log entry. Returns its id."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO audit_log
+               (created_at, actor_type, actor, action, target, params, outcome, error, correlation_id)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                int(time.time()),
+                actor_type,
+                actor,
+                action,
+                target,
+                json.dumps(params, default=str, sort_keys=True),
+                outcome,
+                error,
+                correlation_id,
+            ),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_audit_log(
+        self,
+        actor_type: str | None = None,
+        actor: str | None = None,
+        action: str | None = None,
+        target: str | None = None,
+        outcome: str | None = None,
+        start_ts: int | None = None,
+        end_ts: int | None = None,
+        limit: int = 100,
+        offset: int = 0,
+    ) -> list[dict]:
+        """Audit entries, most recent first.
+
+        action matches the action itself or every action under it ("order"
+        matches "order.buy" and "order.sell").
+        """
+        import json
+
+        conditions: list[str] = []
+        params: list = []
+        for column, value in (("actor_type", actor_type), ("actor", actor), ("target", target), ("outcome", outcome)):
+            if value:
+                conditions.append(f"{column} = ?")
+                params.append(value)
+        if action:
+            conditions.append("(action = ? OR action LIKE ? ESCAPE '\\')")
+            escaped = action.replace("\\", "\\\\").replace("%", "\\%").replace("_", "\\_")
+            params += [action, f"{escaped}.%"]
+        if start_ts is not None:
+            conditions.append("created_at >= ?")
+            params.append(start_ts)
+        if end_ts is not None:
+            conditions.append("created_at <= ?")
+            params.append(end_ts)
+        where = f" WHERE {' AND '.join(conditions)}" if conditions else ""
+        cursor = await self.conn.execute(
+            f"SELECT * FROM audit_log{where} ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",  # noqa: S608
+            (*params, limit, offset),
+        )
+        entries = [dict(row) for row in await cursor.fetchall()]
+        for entry in entries:
+            entry["params"] = json.loads(entry["params"])
+        return entries
+
+    # -------------------------------------------------------------------------
+    # Authentication
+    # -------------------------------------------------------------------------
+
+    async def create_api_token(
+        self,
+        name: str,
+        token_hash: str,
+        token_prefix: str,
+        role: str,
+        username: str | None = None,
+        expires_at: int | None = None,
+    ) -> int:
+        """Store a hashed API token. Returns its ID."""
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO api_tokens (name, token_hash, token_prefix, role, username, created_at, expires_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (name, token_hash, token_prefix, role, username, int(time.time()), expires_at),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_api_token_by_hash(self, token_hash: str) -> dict | None:
+        """Get an unrevoked, unexpired token by its hash."""
+        import time
+
+        cursor = await self.conn.execute(
+            """SELECT * FROM api_tokens
+               WHERE token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)""",
+            (token_hash, int(time.time())),
+        )
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def touch_api_token(self, token_id: int) -> None:
+        """Record that a token was just used."""
+        import time
+
+        await self.conn.execute("UPDATE api_tokens SET last_used_at = ? WHERE id = ?", (int(time.time()), token_id))
+        await self.conn.commit()
+
+    async def get_api_tokens(self, include_revoked: bool = False) -> list[dict]:
+        """List tokens (without hashes), newest first."""
+        where = "" if include_revoked else "WHERE revoked_at IS NULL"
+        cursor = await self.conn.execute(
+            f"""SELECT id, name, token_prefix, role, username, created_at, expires_at, last_used_at, revoked_at
+                FROM api_tokens {where} ORDER BY id DESC""",  # noqa: S608
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def revoke_api_token(self, token_id: int) -> bool:
+        """Revoke a token. Returns False if it does not exist or is already revoked."""
+        import time
+
+        cursor = await self.conn.execute(
+            "UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+            (int(time.time()), token_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def upsert_auth_user(self, username: str, password_hash: str, role: str) -> None:
+        """Create a local user or replace its password and role."""
+        import time
+
+        await self.conn.execute(
+            """INSERT INTO auth_users (username, password_hash, role, created_at) VALUES (?, ?, ?, ?)
+               ON CONFLICT(username) DO UPDATE SET password_hash = excluded.password_hash, role = excluded.role""",
+            (username, password_hash, role, int(time.time())),
+        )
+        await self.conn.commit()
+
+    async def get_auth_user(self, username: str) -> dict | None:
+        """Get a local user including its password hash."""
+        cursor = await self.conn.execute("SELECT * FROM auth_users WHERE username = ?", (username,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def get_auth_users(self) -> list[dict]:
+        """List local users (without password hashes)."""
+        cursor = await self.conn.execute("SELECT username, role, created_at FROM auth_users ORDER BY username")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def delete_auth_user(self, username: str) -> bool:
+        """Delete a local user and revoke its login sessions. Returns False if it does not exist."""
+        import time
+
+        cursor = await self.conn.execute("DELETE FROM auth_users WHERE username = ?", (username,))
+        await self.conn.execute(
+            "UPDATE api_tokens SET revoked_at = ? WHERE username = ? AND revoked_at IS NULL",
+            (int(time.time()), username),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def has_admin_credentials(self) -> bool:
+        """Whether any admin token or admin user exists (required before enabling auth)."""
+        import time
+
+        cursor = await self.conn.execute(
+            """SELECT
+                 EXISTS(SELECT 1 FROM api_tokens WHERE role = 'admin' AND username IS NULL
+                        AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?))
+                 OR EXISTS(SELECT 1 FROM auth_users WHERE role = 'admin')""",
+            (int(time.time()),),
+        )
+        row = await cursor.fetchone()
+        return bool(row[0]) if row else False
+
+    async def record_auth_audit(
+        self,
+        principal: str | None,
+        role: str | None,
+        method: str,
+        path: str,
+        status_code: int,
+    ) -> None:
+        """Append an entry to the authentication audit trail."""
+        import time
+
+        await self.conn.execute(
+            """INSERT INTO auth_audit (created_at, principal, role, method, path, status_code)
+               VALUES (?, ?, ?, ?, ?, ?)""",
+            (int(time.time()), principal, role, method, path, status_code),
+        )
+        await self.conn.commit()
+
+    async def get_auth_audit(self, limit: int = 100, offset: int = 0) -> list[dict]:
+        """Get audit entries, most recent first."""
+        cursor = await self.conn.execute(
+            "SELECT * FROM auth_audit ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+            (limit, offset),
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Planner Snapshots
+    # -------------------------------------------------------------------------
+
+    async def save_planner_snapshot(
+        self,
+        state_hash: str | None,
+        params: dict,
+        context: bytes,
+        recommendations: list[dict],
+        keep: int | None = None,
+    ) -> int:
+        """Store a planner run. With `keep`, only the most recent `keep` snapshots are retained."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO planner_snapshots (created_at, state_hash, params, context, recommendations)
+               VALUES (?, ?, ?, ?, ?)""",
+            (int(time.time()), state_hash, json.dumps(params), context, json.dumps(recommendations)),
+        )
+        if keep is not None:
+            await self.conn.execute(
+                """DELETE FROM planner_snapshots
+                   WHERE id NOT IN (SELECT id FROM planner_snapshots ORDER BY id DESC LIMIT ?)""",
+                (max(int(keep), 1),),
+            )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_planner_snapshots(self, limit: int = 50) -> list[dict]:
+        """List planner snapshots, most recent first (without the context blob)."""
+        import json
+
+        cursor = await self.conn.execute(
+            """SELECT id, created_at, state_hash, params, recommendations, LENGTH(context) AS context_bytes
+               FROM planner_snapshots ORDER BY id DESC LIMIT ?""",
+            (limit,),
+        )
+        snapshots = []
+        for row in await cursor.fetchall():
+            snapshot = dict(row)
+            snapshot["params"] = json.loads(snapshot["params"])
+            snapshot["recommendation_count"] = len(json.loads(snapshot.pop("recommendations")))
+            snapshots.append(snapshot)
+        return snapshots
+
+    async def get_planner_snapshot(self, snapshot_id: int) -> dict | None:
+        """Get one planner snapshot including its context blob and recommendations."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM planner_snapshots WHERE id = ?", (snapshot_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        snapshot = dict(row)
+        snapshot["params"] = json.loads(snapshot["params"])
+        snapshot["recommendations"] = json.loads(snapshot["recommendations"])
+        return snapshot
+
+    # -------------------------------------------------------------------------
+    # Shadow Trials
+    # -------------------------------------------------------------------------
+
+    async def create_shadow_trial(self, name: str, overrides: dict, created_by: str, note: str | None = None) -> int:
+        """Start trialling a settings change in shadow mode."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO shadow_trials (name, overrides, note, status, created_by, created_at)
+               VALUES (?, ?, ?, 'active', ?, ?)""",
+            (name, json.dumps(overrides, sort_keys=True), note, created_by, int(time.time())),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid
+
+    async def get_shadow_trials(self, status: str | None = None) -> list[dict]:
+        """Shadow trials, newest first, with overrides decoded."""
+        import json
+
+        query = "SELECT * FROM shadow_trials"
+        params: tuple = ()
+        if status:
+            query += " WHERE status = ?"
+            params = (status,)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC", params)
+        trials = []
+        for row in await cursor.fetchall():
+            trial = dict(row)
+            trial["overrides"] = json.loads(trial["overrides"])
+            trials.append(trial)
+        return trials
+
+    async def get_shadow_trial(self, trial_id: int) -> dict | None:
+        """One shadow trial with overrides decoded."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM shadow_trials WHERE id = ?", (trial_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        trial = dict(row)
+        trial["overrides"] = json.loads(trial["overrides"])
+        return trial
+
+    async def end_shadow_trial(self, trial_id: int, status: str, ended_by: str) -> None:
+        """Close an active trial as stopped or promoted."""
+        import time
+
+        await self.conn.execute(
+            "UPDATE shadow_trials SET status = ?, ended_by = ?, ended_at = ? WHERE id = ? AND status = 'active'",
+            (status, ended_by, int(time.time()), trial_id),
+        )
+        await self.conn.commit()
+
+    async def save_shadow_run(self, trial_id: int, snapshot_id: int, report: dict, recommendations: list[dict]) -> None:
+        """Record what a trial would have done on a planner run (ignored if already recorded)."""
+        import json
+        import time
+
+        diff = {key: report[key] for key in ("same_order", "added", "removed", "changed")}
+        await self.conn.execute(
+            """INSERT OR IGNORE INTO shadow_runs
+               (trial_id, snapshot_id, created_at, live_count, shadow_count, identical, diff, recommendations)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                trial_id,
+                snapshot_id,
+                int(time.time()),
+                report["recorded_count"],
+                report["replayed_count"],
+                1 if report["identical"] else 0,
+                json.dumps(diff),
+                json.dumps(recommendations),
+            ),
+        )
+        await self.conn.commit()
+
+    async def get_shadow_runs(self, trial_id: int, limit: int | None = None) -> list[dict]:
+        """Runs of a trial, newest first, with the diff and recommendations decoded."""
+        import json
+
+        query = "SELECT * FROM shadow_runs WHERE trial_id = ? ORDER BY snapshot_id DESC"
+        params: tuple = (trial_id,)
+        if limit is not None:
+            query += " LIMIT ?"
+            params = (trial_id, limit)
+        cursor = await self.conn.execute(query, params)
+        runs = []
+        for row in await cursor.fetchall():
+            run = dict(row)
+            run["diff"] = json.loads(run["diff"])
+            run["recommendations"] = json.loads(run["recommendations"])
+            runs.append(run)
+        return runs
+
+    # -------------------------------------------------------------------------
+    # Trade Journal
+    # -------------------------------------------------------------------------
+
+    async def get_trade_by_id(self, trade_id: int) -> dict | None:
+        """A trade by its ledger ID, including trades moved to the archive."""
+        for table in ("trades", "archived_trades"):
+            cursor = await self.conn.execute(
+                f"SELECT {_TRADE_COLUMNS} FROM {table} WHERE id = ?",  # noqa: S608
+                (trade_id,),
+            )
+            row = await cursor.fetchone()
+            if row:
+                return dict(row)
+        return None
+
+    async def add_journal_entry(
+        self,
+        kind: str,
+        symbol: str,
+        action: str,
+        created_by: str,
+        note: str | None = None,
+        tags: list[str] | None = None,
+        conviction: int | None = None,
+        trade_id: int | None = None,
+        recommendation: dict | None = None,
+    ) -> int:
+        """Annotate a trade or a recommendation."""
+        import json
+        import time
+
+        now = int(time.time())
+        cursor = await self.conn.execute(
+            """INSERT INTO journal_entries
+               (kind, trade_id, symbol, action, note, tags, conviction, recommendation,
+                created_by, created_at, updated_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)""",
+            (
+                kind,
+                trade_id,
+                symbol,
+                action,
+                note,
+                json.dumps(tags or []),
+                conviction,
+                json.dumps(recommendation) if recommendation is not None else None,
+                created_by,
+                now,
+                now,
+            ),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid
+
+    async def update_journal_entry(self, entry_id: int, **fields) -> bool:
+        """Change the note, tags or conviction of an entry. Returns False if it does not exist."""
+        import json
+        import time
+
+        if "tags" in fields:
+            fields["tags"] = json.dumps(fields["tags"] or [])
+        sets = "".join(f"{column} = ?, " for column in fields)
+        cursor = await self.conn.execute(
+            f"UPDATE journal_entries SET {sets}updated_at = ? WHERE id = ?",  # noqa: S608
+            (*fields.values(), int(time.time()), entry_id),
+        )
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def delete_journal_entry(self, entry_id: int) -> bool:
+        """Delete an entry. Returns False if it does not exist."""
+        cursor = await self.conn.execute("DELETE FROM journal_entries WHERE id = ?", (entry_id,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    async def search_journal(
+        self,
+        text: str | None = None,
+        symbol: str | None = None,
+        tag: str | None = None,
+        kind: str | None = None,
+        trade_id: int | None = None,
+        min_conviction: int | None = None,
+        start_ts: int | None = None,
+        end_ts: int | None = None,
+        entry_id: int | None = None,
+        limit: int = 100,
+    ) -> list[dict]:
+        """Journal entries matching every given filter, newest first, with tags and recommendation decoded."""
+        import json
+
+        query = "SELECT * FROM journal_entries WHERE 1 = 1"
+        params: list = []
+        for column, wanted in (("id", entry_id), ("symbol", symbol), ("kind", kind), ("trade_id", trade_id)):
+            if wanted is not None:
+                query += f" AND {column} = ?"
+                params.append(wanted)
+        if text:
+            query += " AND note LIKE ? ESCAPE '\\'"
+            escaped = text.replace("\\", "\\\\").replace("%", "\\%").replace("_", "\\_")
+            params.append(f"%{escaped}%")
+        if tag:
+            query += " AND EXISTS (SELECT 1 FROM json_each(journal_entries.tags) WHERE value = ?)"
+            params.append(tag)
+        if min_conviction is not None:
+            query += " AND conviction >= ?"
+            params.append(min_conviction)
+        if start_ts is not None:
+            query += " AND created_at >= ?"
+            params.append(start_ts)
+        if end_ts is not None:
+            query += " AND created_at <= ?"
+            params.append(end_ts)
+        query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        entries = []
+        for row in await cursor.fetchall():
+            entry = dict(row)
+            entry["tags"] = json.loads(entry["tags"] or "[]")
+            entry["recommendation"] = json.loads(entry["recommendation"]) if entry["recommendation"] else None
+            entries.append(entry)
+        return entries
+
+    # -------------------------------------------------------------------------
+    # State Checks
+    # -------------------------------------------------------------------------
+
+    async def record_state_check(self, state_hash: str, components: dict, items: dict) -> int:
+        """Record a planner state check; repeats of the latest state only bump its counters."""
+        import json
+        import time
+
+        now = int(time.time())
+        cursor = await self.conn.execute("SELECT id, state_hash FROM state_checks ORDER BY id DESC LIMIT 1")
+        latest = await cursor.fetchone()
+        if latest and latest["state_hash"] == state_hash:
+            await self.conn.execute(
+                "UPDATE state_checks SET last_checked_at = ?, checks = checks + 1 WHERE id = ?",
+                (now, latest["id"]),
+            )
+            await self.conn.commit()
+            return latest["id"]
+        cursor = await self.conn.execute(
+            """INSERT INTO state_checks (checked_at, last_checked_at, state_hash, components, items)
+               VALUES (?, ?, ?, ?, ?)""",
+            (now, now, state_hash, json.dumps(components), json.dumps(items)),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_state_checks(self, limit: int = 50) -> list[dict]:
+        """Recorded state checks, most recent first (component hashes only)."""
+        import json
+
+        cursor = await self.conn.execute(
+            """SELECT id, checked_at, last_checked_at, checks, state_hash, components
+               FROM state_checks ORDER BY id DESC LIMIT ?""",
+            (limit,),
+        )
+        checks = []
+        for row in await cursor.fetchall():
+            check = dict(row)
+            check["components"] = json.loads(check["components"])
+            checks.append(check)
+        return checks
+
+    async def get_state_check(self, check_id: int) -> dict | None:
+        """One state check including its per-item hashes."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM state_checks WHERE id = ?", (check_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        check = dict(row)
+        check["components"] = json.loads(check["components"])
+        check["items"] = json.loads(check["items"])
+        return check
+
+    # -------------------------------------------------------------------------
+    # Quality-Gate Backfill
+    # -------------------------------------------------------------------------
+
+    async def replace_quality_gate_backfill(self, symbol: str, rows: list[dict], settings: dict) -> None:
+        """Replace a security's backfilled gate outcomes."""
+        import json
+        import time
+
+        now = int(time.time())
+        settings_json = json.dumps(settings, sort_keys=True)
+        await self.conn.execute("DELETE FROM quality_gate_backfill WHERE symbol = ?", (symbol,))
+        await self.conn.executemany(
+            """INSERT INTO quality_gate_backfill
+               (symbol, month, paths, opp_score, opp_score_raw, settings, computed_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            [
+                (symbol, r["month"], json.dumps(r["paths"]), r["opp_score"], r["opp_score_raw"], settings_json, now)
+                for r in rows
+            ],
+        )
+        await self.conn.commit()
+
+    async def get_quality_gate_backfill(
+        self,
+        symbol: str | None = None,
+        since: str | None = None,
+        until: str | None = None,
+    ) -> list[dict]:
+        """Backfilled gate outcomes, optionally for one security and/or a YYYY-MM range."""
+        import json
+
+        query = "SELECT * FROM quality_gate_backfill WHERE 1 = 1"
+        params: list[str] = []
+        if symbol:
+            query += " AND symbol = ?"
+            params.append(symbol)
+        if since:
+            query += " AND month >= ?"
+            params.append(since)
+        if until:
+            query += " AND month <= ?"
+            params.append(until)
+        cursor = await self.conn.execute(query + " ORDER BY symbol, month", params)
+        rows = []
+        for row in await cursor.fetchall():
+            record = dict(row)
+            record["paths"] = json.loads(record["paths"])
+            rows.append(record)
+        return rows
+
+    # -------------------------------------------------------------------------
+    # Secrets
+    # -------------------------------------------------------------------------
+
+    async def put_secret(self, name: str, salt: bytes, nonce: bytes, ciphertext: bytes) -> None:
+        """Store an encrypted secret, keeping its original creation time when replacing it."""
+        import time
+
+        now = int(time.time())
+        await self.conn.execute(
+            """INSERT INTO secrets (name, salt, nonce, ciphertext, created_at, rotated_at)
+               VALUES (?, ?, ?, ?, ?, ?)
+               ON CONFLICT(name) DO UPDATE SET
+                   salt = excluded.salt, nonce = excluded.nonce,
+                   ciphertext = excluded.ciphertext, rotated_at = excluded.rotated_at""",
+            (name, salt, nonce, ciphertext, now, now),
+        )
+        await self.conn.commit()
+
+    async def get_secret(self, name: str) -> dict | None:
+        """Get one encrypted secret record."""
+        cursor = await self.conn.execute("SELECT * FROM secrets WHERE name = ?", (name,))
+        row = await cursor.fetchone()
+        return dict(row) if row else None
+
+    async def get_secrets(self) -> list[dict]:
+        """List stored secrets (names and timestamps only)."""
+        cursor = await self.conn.execute("SELECT name, created_at, rotated_at FROM secrets ORDER BY name")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def delete_secret(self, name: str) -> bool:
+        """Delete a secret. Returns whether it existed."""
+        cursor = await self.conn.execute("DELETE FROM secrets WHERE name = ?", (name,))
+        await self.conn.commit()
+        return (cursor.rowcount or 0) > 0
+
+    # -------------------------------------------------------------------------
+    # Broker Documents
+    # -------------------------------------------------------------------------
+
+    async def save_broker_document(
+        self,
+        kind: str,
+        file_name: str,
+        path: str,
+        size: int,
+        sha256: str,
+        mime: str | None = None,
+        order_id: str | None = None,
+        period_start: str | None = None,
+        period_end: str | None = None,
+    ) -> int:
+        """Record a stored document (replacing the record of a re-fetched one at the same path). Returns its id."""
+        import time
+
+        await self.conn.execute(
+            """INSERT INTO broker_documents
+                   (kind, file_name, path, mime, size, sha256, order_id, period_start, period_end, fetched_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+               ON CONFLICT(path) DO UPDATE SET
+                   mime = excluded.mime, size = excluded.size, sha256 = excluded.sha256,
+                   fetched_at = excluded.fetched_at""",
+            (kind, file_name, path, mime, size, sha256, order_id, period_start, period_end, int(time.time())),
+        )
+        cursor = await self.conn.execute("SELECT id FROM broker_documents WHERE path = ?", (path,))
+        row = await cursor.fetchone()
+        await self.conn.commit()
+        return row["id"]
+
+    async def link_broker_document(self, document_id: int, links: list[tuple[str, str]]) -> None:
+        """Link a document to ledger entries: (entity_type, entity_id) pairs."""
+        await self.conn.executemany(
+            "INSERT OR IGNORE INTO broker_document_links (document_id, entity_type, entity_id) VALUES (?, ?, ?)",
+            [(document_id, entity_type, str(entity_id)) for entity_type, entity_id in links],
+        )
+        await self.conn.commit()
+
+    async def get_broker_documents(
+        self, kind: str | None = None, entity_type: str | None = None, entity_id: str | None = None, limit: int = 100
+    ) -> list[dict]:
+        """List documents, most recent first, optionally of a kind or linked to a ledger entry."""
+        query = "SELECT d.* FROM broker_documents d"
+        conditions: list[str] = []
+        params: list = []
+        if entity_type:
+            query += " JOIN broker_document_links l ON l.document_id = d.id"
+            conditions.append("l.entity_type = ?")
+            params.append(entity_type)
+            if entity_id is not None:
+                conditions.append("l.entity_id = ?")
+                params.append(str(entity_id))
+        if kind:
+            conditions.append("d.kind = ?")
+            params.append(kind)
+        if conditions:
+            query += " WHERE " + " AND ".join(conditions)
+        cursor = await self.conn.execute(query + " ORDER BY d.id DESC LIMIT ?", (*params, limit))
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_broker_document(self, document_id: int) -> dict | None:
+        """Get one document with its ledger links."""
+        cursor = await self.conn.execute("SELECT * FROM broker_documents WHERE id = ?", (document_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        document = dict(row)
+        cursor = await self.conn.execute(
+            "SELECT entity_type, entity_id FROM broker_document_links WHERE document_id = ? ORDER BY 1, 2",
+            (document_id,),
+        )
+        document["links"] = [dict(r) for r in await cursor.fetchall()]
+        return document
+
+    async def get_ledger_entries(
+        self, order_id: str | None = None, start_date: str | None = None, end_date: str | None = None
+    ) -> list[tuple[str, str]]:
+        """
+        Ledger entries an order or a period covers, as (entity_type, entity_id) pairs.
+
+        An order covers its trades; a period (YYYY-MM-DD, inclusive) covers the
+        trades, cash flows and dividends dated within it.
+        """
+        from datetime import datetime, timedelta
+
+        entries: list[tuple[str, str]] = []
+        if order_id is not None:
+            cursor = await self.conn.execute(
+                "SELECT broker_trade_id FROM trades WHERE CAST(json_extract(raw_data, '$.order_id') AS TEXT) = ?",
+                (str(order_id),),
+            )
+            entries += [("trade", row["broker_trade_id"]) for row in await cursor.fetchall()]
+        if start_date and end_date:
+            start_ts = int(datetime.strptime(start_date, "%Y-%m-%d").timestamp())
+            end_ts = int((datetime.strptime(end_date, "%Y-%m-%d") + timedelta(days=1)).timestamp())
+            cursor = await self.conn.execute(
+                "SELECT broker_trade_id FROM trades WHERE executed_at >= ? AND executed_at < ?", (start_ts, end_ts)
+            )
+            entries += [("trade", row["broker_trade_id"]) for row in await cursor.fetchall()]
+            cursor = await self.conn.execute(
+                "SELECT id FROM cash_flows WHERE substr(date, 1, 10) BETWEEN ? AND ?", (start_date, end_date)
+            )
+            entries += [("cash_flow", str(row["id"])) for row in await cursor.fetchall()]
+            cursor = await self.conn.execute(
+                "SELECT id FROM dividends WHERE substr(date, 1, 10) BETWEEN ? AND ?", (start_date, end_date)
+            )
+            entries += [("dividend", row["id"]) for row in await cursor.fetchall()]
+        return list(dict.fromkeys(entries))
+
+    # -------------------------------------------------------------------------
+    # Corporate Action Elections
+    # -------------------------------------------------------------------------
+
+    async def add_corporate_action_election(
+        self,
+        corporate_action_id: str,
+        option: str,
+        status: str,
+        symbol: str | None = None,
+        quantity: float | None = None,
+        broker_request_id: str | None = None,
+    ) -> int:
+        """Record a submitted (or failed) election. Returns its id."""
+        import time
+
+        cursor = await self.conn.execute(
+            """INSERT INTO corporate_action_elections
+                   (corporate_action_id, symbol, option, quantity, status, broker_request_id, submitted_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?)""",
+            (corporate_action_id, symbol, option, quantity, status, broker_request_id, int(time.time())),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_corporate_action_elections(self, corporate_action_id: str | None = None) -> list[dict]:
+        """List elections, most recent first."""
+        query = "SELECT * FROM corporate_action_elections"
+        params: tuple = ()
+        if corporate_action_id:
+            query += " WHERE corporate_action_id = ?"
+            params = (corporate_action_id,)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC", params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Allocation Targets (extended methods beyond BaseDatabase)
+    # -------------------------------------------------------------------------
+
+    async def set_allocation_target(self, target_type: str, name: str, weight: float) -> None:
+        """Set an allocation target weight."""
+        await self.conn.execute(
+            """INSERT OR REPLACE INTO allocation_targets (type, name, weight)
+               VALUES (?, ?, ?)""",
+            (target_type, name, weight),
+        )
+        await self.conn.commit()
+
+    async def delete_allocation_target(self, target_type: str, name: str) -> None:
+        """Delete an allocation target."""
+        await self.conn.execute("DELETE FROM allocation_targets WHERE type = ? AND name = ?", (target_type, name))
+        await self.conn.commit()
+
+    async def save_allocation_proposal(
+        self, target_type: str, method: str, weights: dict, active: dict, stats: dict
+    ) -> int:
+        """Store an optimizer proposal; earlier undecided proposals of the type are superseded."""
+        import json
+        import time
+
+        now = int(time.time())
+        await self.conn.execute(
+            """UPDATE allocation_proposals SET status = 'superseded', decided_at = ?
+               WHERE target_type = ? AND status = 'proposed'""",
+            (now, target_type),
+        )
+        cursor = await self.conn.execute(
+            """INSERT INTO allocation_proposals (created_at, target_type, method, weights, active, stats)
+               VALUES (?, ?, ?, ?, ?, ?)""",
+            (now, target_type, method, json.dumps(weights), json.dumps(active), json.dumps(stats)),
+        )
+        await self.conn.commit()
+        return cursor.lastrowid or 0
+
+    async def get_allocation_proposals(self, target_type: str | None = None, limit: int = 20) -> list[dict]:
+        """Optimizer proposals, most recent first."""
+        import json
+
+        query = "SELECT * FROM allocation_proposals"
+        params: list = []
+        if target_type:
+            query += " WHERE target_type = ?"
+            params.append(target_type)
+        cursor = await self.conn.execute(query + " ORDER BY id DESC LIMIT ?", (*params, limit))
+        proposals = []
+        for row in await cursor.fetchall():
+            proposal = dict(row)
+            for column in ("weights", "active", "stats"):
+                proposal[column] = json.loads(proposal[column])
+            proposals.append(proposal)
+        return proposals
+
+    async def get_allocation_proposal(self, proposal_id: int) -> dict | None:
+        """One optimizer proposal."""
+        import json
+
+        cursor = await self.conn.execute("SELECT * FROM allocation_proposals WHERE id = ?", (proposal_id,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+        proposal = dict(row)
+        for column in ("weights", "active", "stats"):
+            proposal[column] = json.loads(proposal[column])
+        return proposal
+
+    async def decide_allocation_proposal(self, proposal_id: int, accept: bool) -> bool:
+        """Accept (replacing the type's allocation targets with the proposal) or reject an undecided proposal.
+
+        Groups with a target that the proposal leaves out are set to weight 0. Returns
+        False when the proposal does not exist or was already decided.
+        """
+        import time
+
+        proposal = await self.get_allocation_proposal(proposal_id)
+        if proposal is None or proposal["status"] != "proposed":
+            return False
+        await self.conn.execute("BEGIN")
+        try:
+            if accept:
+                target_type = proposal["target_type"]
+                await self.conn.execute("UPDATE allocation_targets SET weight = 0 WHERE type = ?", (target_type,))
+                for name, weight in proposal["weights"].items():
+                    await self.conn.execute(
+                        "INSERT OR REPLACE INTO allocation_targets (type, name, weight) VALUES (?, ?, ?)",
+                        (target_type, name, weight),
+                    )
+            await self.conn.execute(
+                "UPDATE allocation_proposals SET status = ?, decided_at = ? WHERE id = ?",
+                ("accepted" if accept else "rejected", int(time.time()), proposal_id),
+            )
+            await self.conn.commit()
+        except Exception:
+            await self.conn.execute("ROLLBACK")
+            raise
+        return True
+
+    # -------------------------------------------------------------------------
+    # Sector Taxonomy
+    # -------------------------------------------------------------------------
+
+    async def get_sector_taxonomy(self) -> list[dict]:
+        """Get all GICS taxonomy nodes (sectors and industry groups) ordered by code."""
+        cursor = await self.conn.execute("SELECT * FROM sector_taxonomy ORDER BY code")
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_sector_caps(self) -> dict[str, float]:
+        """Get maximum portfolio share per taxonomy code (fraction of portfolio value)."""
+        cursor = await self.conn.execute("SELECT code, max_pct FROM sector_allocation_caps")
+        return {row["code"]: row["max_pct"] for row in await cursor.fetchall()}
+
+    async def set_sector_cap(self, code: str, max_pct: float) -> None:
+        """Set the maximum portfolio share for a sector or industry group."""
+        await self.conn.execute(
+            "INSERT OR REPLACE INTO sector_allocation_caps (code, max_pct) VALUES (?, ?)",
+            (code, max_pct),
+        )
+        await self.conn.commit()
+
+    async def delete_sector_cap(self, code: str) -> None:
+        """Remove the cap for a sector or industry group."""
+        await self.conn.execute("DELETE FROM sector_allocation_caps WHERE code = ?", (code,))
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Correlations
+    # -------------------------------------------------------------------------
+
+    async def replace_correlations(self, pairs: list[dict], window_days: int) -> None:
+        """Replace the stored correlation matrix with freshly computed pairs."""
+        import time
+
+        now = int(time.time())
+        await self.conn.execute("DELETE FROM security_correlations")
+        await self.conn.executemany(
+            """INSERT OR REPLACE INTO security_correlations
+                   (symbol_a, symbol_b, correlation, observations, window_days, computed_at)
+               VALUES (?, ?, ?, ?, ?, ?)""",
+            [(p["symbol_a"], p["symbol_b"], p["correlation"], p["observations"], window_days, now) for p in pairs],
+        )
+        await self.conn.commit()
+
+    async def get_correlations(self, symbols: list[str] | None = None) -> list[dict]:
+        """Stored correlation pairs, optionally only those between the given symbols."""
+        cursor = await self.conn.execute("SELECT * FROM security_correlations ORDER BY symbol_a, symbol_b")
+        rows = [dict(row) for row in await cursor.fetchall()]
+        if symbols is None:
+            return rows
+        wanted = set(symbols)
+        return [row for row in rows if row["symbol_a"] in wanted and row["symbol_b"] in wanted]
+
+    # -------------------------------------------------------------------------
+    # Market Regime
+    # -------------------------------------------------------------------------
+
+    async def replace_regime_states(self, states: dict[str, dict]) -> None:
+        """Replace the stored regimes with a region -> classification mapping."""
+        import time
+
+        now = int(time.time())
+        await self.conn.execute("DELETE FROM regime_states")
+        await self.conn.executemany(
+            """INSERT INTO regime_states (region, regime, score, confidence, signals, computed_at)
+               VALUES (?, ?, ?, ?, ?, ?)""",
+            [
+                (region, s["regime"], s["score"], s["confidence"], json.dumps(s.get("signals", {})), now)
+                for region, s in states.items()
+            ],
+        )
+        await self.conn.commit()
+
+    async def get_regime_states(self) -> list[dict]:
+        """Stored regime per region, signals decoded."""
+        cursor = await self.conn.execute("SELECT * FROM regime_states ORDER BY region")
+        rows = []
+        for row in await cursor.fetchall():
+            state = dict(row)
+            state["signals"] = json.loads(state["signals"])
+            rows.append(state)
+        return rows
+
+    # -------------------------------------------------------------------------
+    # Watchlist
+    # -------------------------------------------------------------------------
+
+    async def add_watchlist_item(
+        self,
+        symbol: str,
+        name: str | None = None,
+        currency: str | None = None,
+        market_id: str | None = None,
+        min_lot: int = 1,
+        isin: str | None = None,
+        note: str | None = None,
+        data: dict | None = None,
+    ) -> None:
+        """Add a security to the watchlist, or refresh its details if already watched."""
+        import time
+
+        await self.conn.execute(
+            """INSERT INTO watchlist (symbol, name, currency, market_id, min_lot, isin, note, data, added_at)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+               ON CONFLICT(symbol) DO UPDATE SET
+                   name = excluded.name, currency = excluded.currency, market_id = excluded.market_id,
+                   min_lot = excluded.min_lot, isin = excluded.isin, note = COALESCE(excluded.note, note),
+                   data = excluded.data""",
+            (
+                symbol,
+                name,
+                currency,
+                market_id,
+                min_lot,
+                isin,
+                note,
+                json.dumps(data) if data is not None else None,
+                int(time.time()),
+            ),
+        )
+        await self.conn.commit()
+
+    async def get_watchlist(self) -> list[dict]:
+        """Watched securities, oldest first, with data and tags decoded."""
+        cursor = await self.conn.execute("SELECT * FROM watchlist ORDER BY added_at, symbol")
+        return [self._decode_watchlist_row(row) for row in await cursor.fetchall()]
+
+    async def get_watchlist_item(self, symbol: str) -> dict | None:
+        """One watched security, or None."""
+        cursor = await self.conn.execute("SELECT * FROM watchlist WHERE symbol = ?", (symbol,))
+        row = await cursor.fetchone()
+        return self._decode_watchlist_row(row) if row else None
+
+    @staticmethod
+    def _decode_watchlist_row(row) -> dict:
+        item = dict(row)
+        item["data"] = json.loads(item["data"]) if item["data"] else None
+        item["tags"] = json.loads(item["tags"]) if item["tags"] else []
+        return item
+
+    async def update_watchlist_signal(self, symbol: str, score: float, tags: list[str], checked_at: int) -> None:
+        """Store the score and tags of the latest watchlist refresh."""
+        await self.conn.execute(
+            "UPDATE watchlist SET score = ?, tags = ?, checked_at = ? WHERE symbol = ?",
+            (score, json.dumps(tags), checked_at, symbol),
+        )
+        await self.conn.commit()
+
+    async def remove_watchlist_item(self, symbol: str) -> bool:
+        """Stop watching a security. Returns False if it was not watched."""
+        cursor = await self.conn.execute("DELETE FROM watchlist WHERE symbol = ?", (symbol,))
+        await self.conn.commit()
+        return cursor.rowcount > 0
+
+    # -------------------------------------------------------------------------
+    # Score State
+    # -------------------------------------------------------------------------
+
+    async def mark_scores_dirty(self, symbols: list[str], reason: str) -> None:
+        """Flag the cached scores of symbols for recomputation, accumulating reasons."""
+        import time
+
+        if not symbols:
+            return
+        now = int(time.time())
+        placeholders = ",".join("?" * len(symbols))
+        cursor = await self.conn.execute(
+            f"SELECT symbol, dirty FROM score_state WHERE symbol IN ({placeholders})",  # noqa: S608
+            symbols,
+        )
+        existing = {row["symbol"]: row["dirty"] for row in await cursor.fetchall()}
+        for symbol in symbols:
+            reasons = set(filter(None, (existing.get(symbol) or "").split(",")))
+            reasons.add(reason)
+            await self.conn.execute(
+                """INSERT INTO score_state (symbol, dirty, dirty_at, dirty_rev)
+                   VALUES (?, ?, ?, (SELECT COALESCE(MAX(dirty_rev), 0) + 1 FROM score_state))
+                   ON CONFLICT(symbol) DO UPDATE SET
+                       dirty = excluded.dirty, dirty_at = excluded.dirty_at, dirty_rev = excluded.dirty_rev""",
+                (symbol, ",".join(sorted(reasons)), now),
+            )
+        await self.conn.commit()
+
+    async def get_score_states(self, symbols: list[str]) -> dict[str, dict]:
+        """Cached score state per symbol: signal (decoded), config_key, computed_at and dirty reasons."""
+        if not symbols:
+            return {}
+        placeholders = ",".join("?" * len(symbols))
+        cursor = await self.conn.execute(
+            f"SELECT * FROM score_state WHERE symbol IN ({placeholders})",  # noqa: S608
+            symbols,
+        )
+        states = {}
+        for row in await cursor.fetchall():
+            state = dict(row)
+            state["signal"] = json.loads(state["signal"]) if state["signal"] else None
+            state["dirty"] = state["dirty"].split(",") if state["dirty"] else []
+            states[state["symbol"]] = state
+        return states
+
+    async def save_score_states(
+        self, signals: dict[str, dict], config_key: str, seen_revs: dict[str, int] | None = None
+    ) -> None:
+        """Store freshly computed signals and clear their dirty flags.
+
+        seen_revs holds each symbol's dirty_rev as read before computing; a flag raised
+        after that (data changed mid-computation) is kept.
+        """
+        import time
+
+        now = int(time.time())
+        seen_revs = seen_revs or {}
+        for symbol, signal in signals.items():
+            await self.conn.execute(
+                """INSERT INTO score_state (symbol, signal, config_key, computed_at) VALUES (?, ?, ?, ?)
+                   ON CONFLICT(symbol) DO UPDATE SET
+                       signal = excluded.signal, config_key = excluded.config_key,
+                       computed_at = excluded.computed_at,
+                       dirty = CASE WHEN dirty_rev > ? THEN dirty ELSE NULL END""",
+                (symbol, json.dumps(signal), config_key, now, seen_revs.get(symbol, 0)),
+            )
+        await self.conn.commit()
+
+    async def get_score_state_summary(self) -> dict:
+        """Counts of cached and dirty scores, with dirty counts per reason."""
+        cursor = await self.conn.execute("SELECT signal IS NOT NULL AS cached, dirty FROM score_state")
+        rows = await cursor.fetchall()
+        reasons: dict[str, int] = {}
+        for row in rows:
+            for reason in filter(None, (row["dirty"] or "").split(",")):
+                reasons[reason] = reasons.get(reason, 0) + 1
+        return {
+            "cached": sum(1 for row in rows if row["cached"]),
+            "dirty": sum(1 for row in rows if row["dirty"]),
+            "dirty_reasons": reasons,
+        }
+
+    # -------------------------------------------------------------------------
+    # Retention
+    # -------------------------------------------------------------------------
+
+    async def prune_table(self, table: str, cutoff: int) -> int:
+        """Delete rows of a RETENTION_COLUMNS table older than the unix cutoff. Returns rows deleted."""
+        if table not in RETENTION_COLUMNS:
+            raise ValueError(f"No retention policy support for table {table}")
+        column, condition = RETENTION_COLUMNS[table]
+        where = f"{column} < ?" + (f" AND {condition}" if condition else "")
+        cursor = await self.conn.execute(f"DELETE FROM {table} WHERE {where}", (cutoff,))  # noqa: S608
+        await self.conn.commit()
+        return cursor.rowcount or 0
+
+    async def _schema_size(self, schema: str) -> dict:
+        sizes = {}
+        for pragma in ("page_size", "page_count", "freelist_count", "auto_vacuum"):
+            cursor = await self.conn.execute(f"PRAGMA {schema}.{pragma}")
+            sizes[pragma] = (await cursor.fetchone())[0]
+        sizes["bytes"] = sizes["page_size"] * sizes["page_count"]
+        return sizes
+
+    async def vacuum(self) -> list[dict]:
+        """Reclaim free pages and refresh planner statistics of the main database and the cold tier.
+
+        Databases not yet in incremental auto-vacuum mode are switched to it with a
+        one-off full VACUUM; after that only free pages are released.
+
+        Returns:
+            One dict per database: database, mode, bytes_before, bytes_after, freed_pages
+        """
+        await self.conn.commit()
+        schemas = ["main"] + ([COLD_SCHEMA] if self._cold_attached else [])
+        results = []
+        for schema in schemas:
+            before = await self._schema_size(schema)
+            if before["auto_vacuum"] == 2:
+                mode = "incremental"
+                cursor = await self.conn.execute(f"PRAGMA {schema}.incremental_vacuum")
+                await cursor.fetchall()
+            else:
+                mode = "full"
+                await self.conn.execute(f"PRAGMA {schema}.auto_vacuum = INCREMENTAL")
+                await self.conn.execute(f"VACUUM {schema}")
+            await self.conn.execute(f"ANALYZE {schema}")
+            await self.conn.commit()
+            after = await self._schema_size(schema)
+            results.append(
+                {
+                    "database": schema,
+                    "mode": mode,
+                    "bytes_before": before["bytes"],
+                    "bytes_after": after["bytes"],
+                    "freed_pages": before["page_count"] - after["page_count"],
+                }
+            )
+        return results
+
+    # -------------------------------------------------------------------------
+    # Cache
+    # -------------------------------------------------------------------------
+
+    async def cache_get(self, key: str) -> Optional[str]:
+        """Get a cached value by key. Returns None if not found or expired."""
+        import time
+
+        cursor = await self.conn.execute("SELECT value, expires_at FROM cache WHERE key = ?", (key,))
+        row = await cursor.fetchone()
+        if not row:
+            return None
+
+        # Check expiry
+        if row["expires_at"] is not None and row["expires_at"] < int(time.time()):
+            # Expired - delete and return None
+            await self.conn.execute("DELETE FROM cache WHERE key = ?", (key,))
+            await self.conn.commit()
+            return None
+
+        return row["value"]
+
+    async def cache_set(self, key: str, value: str, ttl_seconds: int | None = None) -> None:
+        """Set a cached value. TTL is optional (None = never expires)."""
+        import time
+
+        expires_at = int(time.time()) + ttl_seconds if ttl_seconds else None
+        await self.conn.execute(
+            "INSERT OR REPLACE INTO cache (key, value, expires_at) VALUES (?, ?, ?)", (key, value, expires_at)
+        )
+        await self.conn.commit()
+
+    async def cache_delete(self, key: str) -> None:
+        """Delete a cached value."""
+        await self.conn.execute("DELETE FROM cache WHERE key = ?", (key,))
+        await self.conn.commit()
+
+    async def cache_clear(self, prefix: str | None = None) -> int:
+        """Clear cache entries. If prefix given, only clear keys starting with it."""
+        if prefix:
+            cursor = await self.conn.execute("DELETE FROM cache WHERE key LIKE ?", (f"{prefix}%",))
+        else:
+            cursor = await self.conn.execute("DELETE FROM cache")
+        await self.conn.commit()
+        return cursor.rowcount
+
+    async def cache_cleanup_expired(self) -> int:
+        """Remove all expired cache entries."""
+        import time
+
+        cursor = await self.conn.execute(
+            "DELETE FROM cache WHERE expires_at IS NOT NULL AND expires_at < ?", (int(time.time()),)
+        )
+        await self.conn.commit()
+        return cursor.rowcount
+
+    # -------------------------------------------------------------------------
+    # Security Metadata
+    # -------------------------------------------------------------------------
+
+    async def update_security_metadata(self, symbol: str, data: dict, market_id: str | None = None) -> None:
+        """Update security with raw Tradernet metadata."""
+        import json
+        import time
+
+        updates = ["data = ?", "last_synced = ?"]
+        params: list[str | int] = [json.dumps(data), int(time.time())]
+
+        if market_id:
+            updates.append("market_id = ?")
+            params.append(market_id)
+
+        # Extract useful fields from data
+        if "lot" in data:
+            updates.append("min_lot = ?")
+            params.append(int(float(data["lot"])))
+
+        params.append(symbol)
+        await self.conn.execute(f"UPDATE securities SET {', '.join(updates)} WHERE symbol = ?", params)  # noqa: S608
+        await self.conn.commit()
+        await self.mark_scores_dirty([symbol], "metadata")
+
+    # -------------------------------------------------------------------------
+    # Security Archive
+    # -------------------------------------------------------------------------
+
+    async def archive_security(self, symbol: str, reason: str | None, archived_by: str) -> None:
+        """Take a security out of the universe, keeping its prices, scores and trades.
+
+        The trading flags are remembered in archive_state so reactivation restores them;
+        only current state (the positions row) is removed.
+        """
+        import time
+
+        await self.conn.execute(
+            """UPDATE securities SET
+                   archive_state = json_object('allow_buy', allow_buy, 'allow_sell', allow_sell),
+                   active = 0, allow_buy = 0, allow_sell = 0,
+                   archived_at = ?, archive_reason = ?, archived_by = ?
+               WHERE symbol = ?""",
+            (int(time.time()), reason, archived_by, symbol),
+        )
+        await self.conn.execute("DELETE FROM positions WHERE symbol = ?", (symbol,))
+        await self.conn.execute("DELETE FROM cache WHERE key LIKE 'planner:%'")
+        await self.conn.commit()
+
+    async def reactivate_security(self, symbol: str) -> dict:
+        """Bring an archived security back into the universe with its trading flags restored.
+
+        Returns:
+            The restored trading flags
+        """
+        import json
+
+        security = await self.get_security(symbol) or {}
+        state = json.loads(security.get("archive_state") or "{}")
+        flags = {"allow_buy": int(state.get("allow_buy", 1)), "allow_sell": int(state.get("allow_sell", 1))}
+        await self.conn.execute(
+            """UPDATE securities SET active = 1, allow_buy = ?, allow_sell = ?,
+                   archived_at = NULL, archive_reason = NULL, archived_by = NULL, archive_state = NULL
+               WHERE symbol = ?""",
+            (flags["allow_buy"], flags["allow_sell"], symbol),
+        )
+        await self.conn.execute("DELETE FROM cache WHERE key LIKE 'planner:%'")
+        await self.conn.commit()
+        # Prices may have moved while archived: the stored score is stale
+        await self.mark_scores_dirty([symbol], "reactivated")
+        return flags
+
+    async def get_archived_securities(self) -> list[dict]:
+        """Inactive securities, most recently archived first, with the history they keep."""
+        cursor = await self.conn.execute(
+            """SELECT s.symbol, s.name, s.currency, s.isin, s.archived_at, s.archive_reason, s.archived_by,
+                      (SELECT COUNT(*) FROM prices p WHERE p.symbol = s.symbol) AS price_count,
+                      (SELECT MAX(date) FROM prices p WHERE p.symbol = s.symbol) AS last_price_date,
+                      (SELECT COUNT(*) FROM trades t WHERE t.symbol = s.symbol) AS trade_count,
+                      (SELECT MAX(executed_at) FROM trades t WHERE t.symbol = s.symbol) AS last_trade_at
+               FROM securities s WHERE s.active = 0
+               ORDER BY s.archived_at IS NULL, s.archived_at DESC, s.symbol"""
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    # -------------------------------------------------------------------------
+    # Security Metadata Enrichment
+    # -------------------------------------------------------------------------
+
+    async def save_metadata_candidates(self, symbol: str, provider: str, values: dict[str, str]) -> None:
+        """Store a provider's metadata values (field -> value) for a security."""
+        import time
+
+        now = int(time.time())
+        await self.conn.executemany(
+            """INSERT INTO security_metadata (symbol, field, provider, value, fetched_at) VALUES (?, ?, ?, ?, ?)
+               ON CONFLICT(symbol, field, provider)
+               DO UPDATE SET value = excluded.value, fetched_at = excluded.fetched_at""",
+            [(symbol, field, provider, value, now) for field, value in values.items()],
+        )
+        await self.conn.commit()
+
+    async def get_metadata_candidates(self, symbol: str) -> list[dict]:
+        """Every provider's metadata values for a security."""
+        cursor = await self.conn.execute(
+            "SELECT * FROM security_metadata WHERE symbol = ? ORDER BY field, provider", (symbol,)
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def save_metadata_provenance(self, symbol: str, field: str, value: str, provider: str, rule: str) -> None:
+        """Record where the applied value of a security's metadata field came from."""
+        import time
+
+        await self.conn.execute(
+            """INSERT INTO metadata_provenance (symbol, field, value, provider, rule, applied_at)
+               VALUES (?, ?, ?, ?, ?, ?)
+               ON CONFLICT(symbol, field) DO UPDATE SET value = excluded.value, provider = excluded.provider,
+                   rule = excluded.rule, applied_at = excluded.applied_at
+               WHERE value IS NOT excluded.value OR provider IS NOT excluded.provider OR rule IS NOT excluded.rule""",
+            (symbol, field, value, provider, rule, int(time.time())),
+        )
+        await self.conn.commit()
+
+    async def get_metadata_provenance(self, symbol: str) -> dict[str, dict]:
+        """Provenance of a security's applied metadata, keyed by field."""
+        cursor = await self.conn.execute("SELECT * FROM metadata_provenance WHERE symbol = ?", (symbol,))
+        return {row["field"]: dict(row) for row in await cursor.fetchall()}
+
+    async def open_metadata_review(self, symbol: str, field: str, candidates: dict[str, str]) -> bool:
+        """Queue a metadata conflict, or refresh the values of the one already open. Returns True when new."""
+        import json
+        import time
+
+        cursor = await self.conn.execute(
+            "SELECT id FROM metadata_reviews WHERE symbol = ? AND field = ? AND status = 'open'", (symbol, field)
+        )
+        row = await cursor.fetchone()
+        if row:
+            await self.conn.execute(
+                "UPDATE metadata_reviews SET candidates = ? WHERE id = ?", (json.dumps(candidates), row["id"])
+            )
+        else:
+            await self.conn.execute(
+                """INSERT INTO metadata_reviews (symbol, field, candidates, status, created_at)
+                   VALUES (?, ?, ?, 'open', ?)""",
+                (symbol, field, json.dumps(candidates), int(time.time())),
+            )
+        await self.conn.commit()
+        return row is None
+
+    async def get_metadata_reviews(self, status: str | None = "open", limit: int = 100) -> list[dict]:
+        """Metadata conflicts, most recent first."""
+        query = "SELECT * FROM metadata_reviews"
+        params: list = []
+        if status:
+            query += " WHERE status = ?"
+            params.append(status)
+        query += " ORDER BY id DESC LIMIT ?"
+        params.append(limit)
+        cursor = await self.conn.execute(query, params)
+        return [self._decode_metadata_review(row) for row in await cursor.fetchall()]
+
+    async def get_metadata_review(self, review_id: int) -> dict | None:
+        """A metadata conflict by ID."""
+        cursor = await self.conn.execute("SELECT * FROM metadata_reviews WHERE id = ?", (review_id,))
+        row = await cursor.fetchone()
+        return self._decode_metadata_review(row) if row else None
+
+    @staticmethod
+    def _decode_metadata_review(row) -> dict:
+        import json
+
+        review = dict(row)
+        review["candidates"] = json.loads(review["candidates"])
+        return review
+
+    async def resolve_metadata_review(self, review_id: int, value: str, resolved_by: str | None) -> None:
+        """Mark a metadata conflict resolved with the chosen value."""
+        import time
+
+        await self.conn.execute(
+            """UPDATE metadata_reviews SET status = 'resolved', resolved_value = ?, resolved_by = ?, resolved_at = ?
+               WHERE id = ?""",
+            (value, resolved_by, int(time.time()), review_id),
+        )
+        await self.conn.commit()
+
+    async def close_metadata_reviews(self, symbol: str, field: str, value: str) -> None:
+        """Resolve the open conflicts of a field whose providers no longer disagree (or that was overridden)."""
+        import time
+
+        await self.conn.execute(
+            """UPDATE metadata_reviews SET status = 'resolved', resolved_value = ?, resolved_at = ?
+               WHERE symbol = ? AND field = ? AND status = 'open'""",
+            (value, int(time.time()), symbol, field),
+        )
+        await self.conn.commit()
+
+    # -------------------------------------------------------------------------
+    # Security Identity
+    # -------------------------------------------------------------------------
+
+    async def get_securities_by_isin(self, isin: str) -> list[dict]:
+        """All securities carrying an ISIN (several listings of one security share it)."""
+        cursor = await self.conn.execute(
+            """SELECT s.* FROM securities s
+               JOIN instrument_ids i ON i.symbol = s.symbol AND i.scheme = 'isin' AND i.retired_at IS NULL
+               WHERE i.value = ? ORDER BY s.symbol""",
+            (isin,),
+        )
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def set_security_isin(self, symbol: str, isin: str | None) -> None:
+        """Record a security's ISIN (its stable identity across ticker changes)."""
+        await self.conn.execute("UPDATE securities SET isin = ? WHERE symbol = ?", (isin, symbol))
+        await self._put_instrument_id(symbol, "isin", isin, "broker")
+        await self.conn.commit()
+
+    async def _put_instrument_id(self, symbol: str, scheme: str, value: str | None, source: str) -> None:
+        """Make `value` the current ID of a security in a scheme, retiring its previous one (no commit)."""
+        import time
+
+        now = int(time.time())
+        await self.conn.execute(
+            """UPDATE instrument_ids SET retired_at = ?
+               WHERE symbol = ? AND scheme = ? AND value IS NOT ? AND retired_at IS NULL""",
+            (now, symbol, scheme, value),
+        )
+        if value:
+            await self.conn.execute(
+                """INSERT INTO instrument_ids (scheme, value, symbol, source, created_at) VALUES (?, ?, ?, ?, ?)
+                   ON CONFLICT(scheme, value, symbol) DO UPDATE SET source = excluded.source, retired_at = NULL""",
+                (scheme, value, symbol, source, now),
+            )
+
+    async def set_instrument_id(self, symbol: str, scheme: str, value: str | None, source: str = "user") -> None:
+        """Set (or with None, retire) a security's ID in a scheme; the previous one is kept as history."""
+        await self._put_instrument_id(symbol, scheme, value, source)
+        await self.conn.commit()
+
+    async def get_instrument_ids(
+        self, symbol: str | None = None, scheme: str | None = None, value: str | None = None
+    ) -> list[dict]:
+        """Instrument ID mappings, current ones first, then history newest first."""
+        query = "SELECT * FROM instrument_ids WHERE 1 = 1"
+        params: list = []
+        for column, wanted in (("symbol", symbol), ("scheme", scheme), ("value", value)):
+            if wanted is not None:
+                query += f" AND {column} = ?"
+                params.append(wanted)
+        query += " ORDER BY retired_at IS NOT NULL, retired_at DESC, scheme, value, symbol"
+        cursor = await self.conn.execute(query, params)
+        return [dict(row) for row in await cursor.fetchall()]
+
+    async def get_instrument_id_collisions(self) -> list[dict]:
+        """Current IDs mapped to more than one security."""
+        cursor = await self.conn.execute(
+            """SELECT scheme, value, GROUP_CONCAT(symbol) AS symbols FROM instrument_ids
+               WHERE retired_at IS NULL GROUP BY scheme, value HAVING COUNT(*) > 1 ORDER BY scheme, value"""
+        )
+        return [
+            {"scheme": row["scheme"], "value": row["value"], "symbols": sorted(row["symbols"].split(","))}
+            for row in await cursor.fetchall()
+        ]
+
+    async def resolve_symbol(self, identifier: str, scheme: str | None = None) -> str | None:
+        """
+        Current symbol for a symbol, an ISIN, a Yahoo symbol or a former symbol.
+
+        Current instrument IDs are tried before retired ones, then the ticker
+        change history. `scheme` limits the lookup to one kind of ID.
+
+        Returns:
+            The current symbol, or None if nothing matches (or the ID maps to several securities)
+        """
+        if scheme is None and await self.get_security(identifier):
+            return identifier
+        from sentinel.utils.identity import normalize_isin
+
+        isin = normalize_isin(identifier)
+        value = isin if isin and scheme in (None, "isin") else identifier
+        query = "SELECT DISTINCT symbol, retired_at IS NULL AS current FROM instrument_ids WHERE value = ?"
+        params: list = [value]
+        if scheme is not None:
+            query += " AND scheme = ?"
+            params.append(scheme)
+        cursor = await self.conn.execute(query, params)
+        rows = await cursor.fetchall()
+        for current in (1, 0):
+            symbols = {row["symbol"] for row in rows if row["current"] == current}
+            if len(symbols) > 1:
+                return None
+            if symbols:
+                return symbols.pop()
+        if isin or scheme is not None:
+            return None
+        cursor = await self.conn.execute(
+            "SELECT new_symbol FROM symbol_history WHERE old_symbol = ? ORDER BY changed_at DESC, id DESC LIMIT 1",
+            (identifier,),
+        )
+        row = await cursor.fetchone()
+        if not row or row["new_symbol"] == identifier:
+            return None
+        return await self.resolve_symbol(row["new_symbol"])
+
+    async def rename_symbol(self, old_symbol: str, new_symbol: str, reason: str | None = None) -> dict[str, int]:
+        """
+        Move a security and everything keyed by its symbol to a new ticker.
+
+        If `new_symbol` already exists (a duplicate created under the new
+        ticker), the rows are merged: where both symbols have a row for the
+        same key, the new symbol's row is kept. The change is logged in
+        symbol_history so the old ticker keeps resolving.
+
+        Raises:
+            NotFound: old_symbol does not exist
+            InvalidInput: Both symbols are the same
+
+        Returns:
+            Rows moved per table
+        """
+        import time
+
+        if old_symbol == new_symbol:
+            raise InvalidInput("Old and new symbol are the same", symbol=old_symbol)
+        old = await self.get_security(old_symbol)
+        if old is None:
+            raise NotFound(f"Security not found: {old_symbol}", symbol=old_symbol)
+        new = await self.get_security(new_symbol)
+
+        moved: dict[str, int] = {}
+        try:
+            if new is None:
+                await self.conn.execute("UPDATE securities SET symbol = ? WHERE symbol = ?", (new_symbol, old_symbol))
+                moved["securities"] = 1
+            else:
+                if not new.get("isin") and old.get("isin"):
+                    await self.conn.execute(
+                        "UPDATE securities SET isin = ? WHERE symbol = ?", (old["isin"], new_symbol)
+                    )
+                await self.conn.execute("DELETE FROM securities WHERE symbol = ?", (old_symbol,))
+                moved["securities"] = 0
+            for table, column in SYMBOL_COLUMNS:
+                cursor = await self.conn.execute(
+                    f"UPDATE OR IGNORE {table} SET {column} = ? WHERE {column} = ?",  # noqa: S608
+                    (new_symbol, old_symbol),
+                )
+                moved[table] = cursor.rowcount
+                # Rows left behind collided with a row of the new symbol
+                await self.conn.execute(f"DELETE FROM {table} WHERE {column} = ?", (old_symbol,))  # noqa: S608
+            if self._cold_attached:
+                cursor = await self.conn.execute(
+                    f"UPDATE OR IGNORE {COLD_SCHEMA}.prices SET symbol = ? WHERE symbol = ?", (new_symbol, old_symbol)
+                )
+                moved["prices"] += cursor.rowcount
+                await self.conn.execute(f"DELETE FROM {COLD_SCHEMA}.prices WHERE symbol = ?", (old_symbol,))
+            # Scheduled orders keep their symbols (and the legs of a run in progress) as JSON
+            moved["scheduled_orders"] = 0
+            cursor = await self.conn.execute(
+                "SELECT * FROM scheduled_orders WHERE symbols IS NOT NULL OR run_state IS NOT NULL"
+            )
+            for order in [self._scheduled_order(row) for row in await cursor.fetchall()]:
+                symbols = order["symbols"] or []
+                legs = (order["run_state"] or {}).get("legs") or []
+                if old_symbol not in symbols and not any(leg.get("symbol") == old_symbol for leg in legs):
+                    continue
+                for leg in legs:
+                    if leg.get("symbol") == old_symbol:
+                        leg["symbol"] = new_symbol
+                symbols = list(dict.fromkeys(new_symbol if s == old_symbol else s for s in symbols))
+                await self.conn.execute(
+                    "UPDATE scheduled_orders SET symbols = ?, run_state = ? WHERE id = ?",
+                    (
+                        json.dumps(symbols) if symbols else None,
+                        json.dumps(order["run_state"]) if order["run_state"] is not None else None,
+                        order["id"],
+                    ),
+                )
+                moved["scheduled_orders"] += 1
+            await self._put_instrument_id(new_symbol, "tradernet", new_symbol, "broker")
+            await self._put_instrument_id(new_symbol, "isin", (new or {}).get("isin") or old.get("isin"), "broker")
+            await self.conn.execute(
+                "UPDATE notifications SET entity_id = ? WHERE entity_type = 'security' AND entity_id = ?",
+                (new_symbol, old_symbol),
+            )
+            await self.conn.execute(
+                """INSERT INTO symbol_history (isin, old_symbol, new_symbol, reason, changed_at)
+                   VALUES (?, ?, ?, ?, ?)""",
+                (old.get("isin") or (new or {}).get("isin"), old_symbol, new_symbol, reason, int(time.time())),
+            )
+            await self.conn.execute("DELETE FROM cache WHERE key LIKE 'planner:%'")
+            await self.conn.commit()
+        except Exception:
+            await self.conn.rollback()
+            raise
+        return moved
+
+    async def get_symbol_history(self, symbol: str | None = None) -> list[dict]:
+        """Ticker changes, most recent first; with `symbol`, only those involving it."""
+        query = "SELECT * FROM symbol_history"
+        params: tuple = ()
+        if symbol:
+            query += " WHERE old_symbol = ? OR new_symbol = ?"
+            params = (symbol, symbol)
+        cursor = await self.conn.execute(query + " ORDER BY changed_at DESC, id DESC", params)
+        return [dict(row) for row in await cursor.fetchall()]
 
     # -------------------------------------------------------------------------
-    # Security Metadata
+    # Computed Columns
     # -------------------------------------------------------------------------
 
-    async def update_security_metadata(self, symbol: str, data: dict, market_id: str | None = None) -> None:
-        """Update security with raw Tradernet metadata."""
-        import json
-        import time
-
-        updates = ["data = ?", "last_synced = ?"]
-        params: list[str | int] = [json.dumps(data), int(time.time())]
+    async def get_computed_columns(self) -> list[dict]:
+        """Computed column definitions, by name."""
+        cursor = await self.conn.execute("SELECT * FROM computed_columns ORDER BY name")
+        return [dict(row) for row in await cursor.fetchall()]
 
-        if market_id:
-            updates.append("market_id = ?")
-            params.append(market_id)
+    async def upsert_computed_column(self, name: str, expression: str, description: str | None = None) -> None:
+        """Create or replace a computed column definition."""
+        import time
 
-        # Extract useful fields from data
-        if "lot" in data:
-            updates.append("min_lot = ?")
-            params.append(int(float(data["lot"])))
+        now = int(time.time())
+        await self.conn.execute(
+            """INSERT INTO computed_columns (name, expression, description, created_at, updated_at)
+               VALUES (?, ?, ?, ?, ?)
+               ON CONFLICT(name) DO UPDATE SET
+                   expression = excluded.expression,
+                   description = excluded.description,
+                   updated_at = excluded.updated_at""",
+            (name, expression, description, now, now),
+        )
+        await self.conn.commit()
 
-        params.append(symbol)
-        await self.conn.execute(f"UPDATE securities SET {', '.join(updates)} WHERE symbol = ?", params)  # noqa: S608
+    async def delete_computed_column(self, name: str) -> bool:
+        """Delete a computed column. Returns False if it does not exist."""
+        cursor = await self.conn.execute("DELETE FROM computed_columns WHERE name = ?", (name,))
         await self.conn.commit()
+        return cursor.rowcount > 0
 
     # -------------------------------------------------------------------------
     # Categories
@@ -392,13 +3463,14 @@ class Database(BaseDatabase):
         error: Optional[str],
         duration_ms: int,
         retry_count: int,
+        failure_class: Optional[str] = None,
     ) -> None:
-        """Log a job execution to the job history."""
+        """Log a job execution to the job history (failure_class: see sentinel.jobs.failures)."""
         await self.conn.execute(
             """INSERT INTO job_history
-               (job_id, job_type, status, error, duration_ms, executed_at, retry_count)
-               VALUES (?, ?, ?, ?, ?, ?, ?)""",
-            (job_id, job_type, status, error, duration_ms, int(datetime.now().timestamp()), retry_count),
+               (job_id, job_type, status, error, duration_ms, executed_at, retry_count, failure_class)
+               VALUES (?, ?, ?, ?, ?, ?, ?, ?)""",
+            (job_id, job_type, status, error, duration_ms, int(datetime.now().timestamp()), retry_count, failure_class),
         )
         await self.conn.commit()
 
@@ -444,7 +3516,7 @@ class Database(BaseDatabase):
         """Get recent job execution history."""
         cursor = await self.conn.execute(
             """SELECT job_id, job_type, status, error, duration_ms,
-                      executed_at, retry_count
+                      executed_at, retry_count, failure_class
                FROM job_history
                ORDER BY executed_at DESC LIMIT ?""",
             (limit,),
@@ -509,10 +3581,13 @@ class Database(BaseDatabase):
         await self.conn.commit()
 
     async def mark_job_completed(self, job_type: str) -> None:
-        """Mark a job as completed (update last_run to now, reset failures)."""
+        """Mark a job as completed (update last_run to now, reset failures, lift any quarantine)."""
         now = int(datetime.now().timestamp())
         await self.conn.execute(
-            "UPDATE job_schedules SET last_run = ?, consecutive_failures = 0 WHERE job_type = ?", (now, job_type)
+            """UPDATE job_schedules
+               SET last_run = ?, consecutive_failures = 0, quarantined_at = NULL, quarantine_reason = NULL
+               WHERE job_type = ?""",
+            (now, job_type),
         )
         await self.conn.commit()
 
@@ -525,6 +3600,15 @@ class Database(BaseDatabase):
         )
         await self.conn.commit()
 
+    async def set_job_quarantine(self, job_type: str, reason: Optional[str]) -> None:
+        """Quarantine a job (scheduled runs are skipped), or lift it when reason is None."""
+        quarantined_at = int(datetime.now().timestamp()) if reason is not None else None
+        await self.conn.execute(
+            "UPDATE job_schedules SET quarantined_at = ?, quarantine_reason = ? WHERE job_type = ?",
+            (quarantined_at, reason, job_type),
+        )
+        await self.conn.commit()
+
     async def get_job_schedule(self, job_type: str) -> Optional[dict]:
         """Get a single job schedule by type."""
         cursor = await self.conn.execute("SELECT * FROM job_schedules WHERE job_type = ?", (job_type,))
@@ -600,11 +3684,14 @@ class Database(BaseDatabase):
             ("sync:portfolio", 30, 5, 0, "sync", "Sync portfolio positions from broker"),
             ("sync:prices", 30, 5, 0, "sync", "Sync historical prices for securities"),
             ("sync:quotes", 1440, 1440, 0, "sync", "Sync current quotes"),
+            ("sync:intraday", 15, 15, 2, "sync", "Snapshot quotes of held positions"),
             ("sync:metadata", 1440, 1440, 0, "sync", "Sync security metadata"),
+            ("sync:metadata_enrichment", 10080, 10080, 0, "sync", "Enrich security metadata from all providers"),
             ("sync:exchange_rates", 60, 60, 0, "sync", "Sync exchange rates"),
             ("sync:trades", 60, 60, 0, "sync", "Sync trade history from broker"),
             ("sync:cashflows", 1440, 1440, 0, "sync", "Sync cash flows from broker"),
             ("sync:dividends", 1440, 1440, 0, "sync", "Sync dividends from broker"),
+            ("sync:news", 120, 60, 0, "sync", "Fetch and classify news headlines of held positions"),
             (
                 "snapshot:backfill",
                 1440,
@@ -614,12 +3701,45 @@ class Database(BaseDatabase):
                 "Maintain portfolio snapshots by filling missing dates",
             ),
             ("aggregate:compute", 1440, 1440, 1, "sync", "Compute aggregate price series"),
+            ("aggregate:correlations", 1440, 1440, 1, "sync", "Recompute the pairwise correlation matrix"),
+            ("aggregate:regime", 1440, 1440, 1, "sync", "Classify the market regime of each region"),
+            ("sync:watchlist", 1440, 1440, 1, "sync", "Refresh watchlist prices and alert on signal changes"),
             ("trading:check_markets", 30, 30, 2, "trading", "Check which markets are open"),
             ("trading:execute", 30, 15, 2, "trading", "Execute pending trade recommendations"),
             ("trading:rebalance", 60, 60, 0, "trading", "Check portfolio rebalance needs"),
             ("trading:balance_fix", 15, 15, 0, "trading", "Fix negative currency balances"),
+            ("trading:scheduled_orders", 15, 5, 0, "trading", "Place scheduled orders that are due"),
+            ("trading:order_slices", 15, 5, 0, "trading", "Place the child orders of sliced large orders that are due"),
+            ("trading:dividend_reinvest", 1440, 1440, 0, "trading", "Decide where new dividends are reinvested"),
+            (
+                "trading:protective_orders",
+                1440,
+                1440,
+                0,
+                "trading",
+                "Keep stop-loss and trailing-stop orders in line with the protective order policies",
+            ),
+            (
+                "analytics:execution_quality",
+                1440,
+                1440,
+                1,
+                "trading",
+                "Measure fill slippage and calibrate the limit band",
+            ),
             ("planning:refresh", 60, 30, 0, "trading", "Refresh trading plan and recommendations"),
             ("backup:r2", 1440, 1440, 0, "backup", "Backup data folder to Cloudflare R2"),
+            ("archive:positions", 10080, 10080, 0, "backup", "Archive closed positions out of the hot tables"),
+            ("archive:prices", 1440, 1440, 1, "backup", "Move aged price bars to the cold storage tier"),
+            ("archive:intraday", 1440, 1440, 0, "backup", "Downsample aged intraday snapshots"),
+            ("archive:retention", 1440, 1440, 1, "backup", "Delete rows past their retention policy"),
+            ("archive:vacuum", 10080, 10080, 1, "backup", "Reclaim free database pages and run ANALYZE"),
+            ("notifications:deliver", 1, 1, 0, "notifications", "Retry pending webhook deliveries"),
+            ("thesis:check", 60, 15, 0, "notifications", "Notify on position target, stop and thesis review events"),
+            ("report:digest", 1440, 1440, 0, "notifications", "Compile and deliver the digest report"),
+            ("report:email", 60, 60, 0, "notifications", "Email the weekly report and monthly reconciliation"),
+            ("config:drift_check", 60, 60, 0, "system", "Compare the configuration with the paired device"),
+            ("reconcile:broker", 1440, 1440, 0, "sync", "Reconcile last month's broker report with the ledger"),
         ]
 
         for job_type, interval, interval_open, timing, cat, desc in defaults:
@@ -649,7 +3769,7 @@ class Database(BaseDatabase):
     async def get_job_history_for_type(self, job_type: str, limit: int = 50) -> list[dict]:
         """Get job history for jobs matching type prefix."""
         cursor = await self.conn.execute(
-            """SELECT job_id, job_type, status, error, duration_ms, executed_at, retry_count
+            """SELECT job_id, job_type, status, error, duration_ms, executed_at, retry_count, failure_class
                FROM job_history
                WHERE job_id LIKE ?
                ORDER BY executed_at DESC LIMIT ?""",
@@ -664,8 +3784,154 @@ class Database(BaseDatabase):
     async def _init_schema(self) -> None:
         """Initialize database schema."""
         await self.conn.executescript(SCHEMA)
+        await self._migrate_schema()
+        await self._seed_sector_taxonomy()
+        await self._seed_instrument_ids()
         await self.conn.commit()
 
+    async def _migrate_schema(self) -> None:
+        """Add columns introduced after a table was first created."""
+        for table, column, definition in COLUMN_MIGRATIONS:
+            cursor = await self.conn.execute(f"PRAGMA table_info({table})")
+            existing = {row["name"] for row in await cursor.fetchall()}
+            if column not in existing:
+                await self.conn.execute(f"ALTER TABLE {table} ADD COLUMN {column} {definition}")
+        for statement in INDEX_MIGRATIONS:
+            await self.conn.execute(statement)
+
+    async def pending_migrations(self) -> list[str]:
+        """Tables (and table.column for COLUMN_MIGRATIONS) the open database is still missing."""
+        cursor = await self.conn.execute("SELECT name FROM sqlite_master WHERE type = 'table'")
+        tables = {row["name"] for row in await cursor.fetchall()}
+        missing = [t for t in re.findall(r"CREATE TABLE IF NOT EXISTS (\w+)", SCHEMA) if t not in tables]
+        for table, column, _definition in COLUMN_MIGRATIONS:
+            if table not in tables:
+                continue
+            cursor = await self.conn.execute(f"PRAGMA table_info({table})")
+            if column not in {row["name"] for row in await cursor.fetchall()}:
+                missing.append(f"{table}.{column}")
+        return missing
+
+    async def _seed_instrument_ids(self) -> None:
+        """Map securities without instrument IDs to their Tradernet ticker and ISIN (idempotent)."""
+        import time
+
+        for scheme, column in (("tradernet", "symbol"), ("isin", "isin")):
+            await self.conn.execute(
+                f"""INSERT OR IGNORE INTO instrument_ids (scheme, value, symbol, source, created_at)
+                    SELECT ?, s.{column}, s.symbol, 'broker', ? FROM securities s
+                    WHERE s.{column} IS NOT NULL AND s.{column} != ''
+                      AND NOT EXISTS (
+                          SELECT 1 FROM instrument_ids i WHERE i.symbol = s.symbol AND i.scheme = ?
+                      )""",  # noqa: S608
+                (scheme, int(time.time()), scheme),
+            )
+
+    async def _seed_sector_taxonomy(self) -> None:
+        """Insert the built-in GICS sectors and industry groups (idempotent)."""
+        from sentinel.config.gics import GICS_INDUSTRY_GROUPS, GICS_SECTORS, gics_parent
+
+        rows = [(code, name, "sector", None) for code, name in GICS_SECTORS]
+        rows += [(code, name, "industry_group", gics_parent(code)) for code, name in GICS_INDUSTRY_GROUPS]
+        await self.conn.executemany(
+            "INSERT OR IGNORE INTO sector_taxonomy (code, name, level, parent_code) VALUES (?, ?, ?, ?)",
+            rows,
+        )
+
+
+# (table, column, definition) for columns added to existing tables; keep in sync with SCHEMA
+COLUMN_MIGRATIONS = [
+    ("securities", "supports_fractional", "INTEGER DEFAULT 0"),
+    ("securities", "gics_code", "TEXT"),
+    ("securities", "added_at", "INTEGER"),
+    ("job_schedules", "quarantined_at", "INTEGER"),
+    ("job_schedules", "quarantine_reason", "TEXT"),
+    ("job_history", "failure_class", "TEXT"),
+    ("trade_decisions", "dominant_component", "TEXT"),
+    ("trade_decisions", "score_components", "TEXT"),
+    ("archived_trade_decisions", "dominant_component", "TEXT"),
+    ("archived_trade_decisions", "score_components", "TEXT"),
+    ("trade_decisions", "pricing_method", "TEXT"),
+    ("trade_decisions", "limit_price", "REAL"),
+    ("archived_trade_decisions", "pricing_method", "TEXT"),
+    ("archived_trade_decisions", "limit_price", "REAL"),
+    ("securities", "isin", "TEXT"),
+    ("securities", "asset_class", "TEXT DEFAULT 'equity'"),
+    ("trade_decisions", "quote_price", "REAL"),
+    ("archived_trade_decisions", "quote_price", "REAL"),
+    ("dividends", "gross_amount", "REAL"),
+    ("dividends", "withholding_amount", "REAL"),
+    ("dividends", "withholding_rate", "REAL"),
+    ("dividends", "country", "TEXT"),
+    ("securities", "drip_mode", "TEXT"),
+    ("securities", "country", "TEXT"),
+    ("securities", "exchange", "TEXT"),
+    ("securities", "archived_at", "INTEGER"),
+    ("securities", "archive_reason", "TEXT"),
+    ("securities", "archived_by", "TEXT"),
+    ("securities", "archive_state", "TEXT"),
+]
+
+# Indexes on migrated columns, created after COLUMN_MIGRATIONS ran
+INDEX_MIGRATIONS = [
+    "CREATE INDEX IF NOT EXISTS idx_securities_isin ON securities(isin)",
+]
+
+# (table, column) holding a security symbol; rewritten when a ticker changes
+SYMBOL_COLUMNS = [
+    ("positions", "symbol"),
+    ("prices", "symbol"),
+    ("trades", "symbol"),
+    ("strategy_state", "symbol"),
+    ("dividends", "symbol"),
+    ("dividend_reinvestments", "symbol"),
+    ("dividend_reinvestments", "target_symbol"),
+    ("trade_decisions", "symbol"),
+    ("archived_positions", "symbol"),
+    ("archived_trades", "symbol"),
+    ("archived_trade_decisions", "symbol"),
+    ("recommendation_decisions", "symbol"),
+    ("quality_gate_backfill", "symbol"),
+    ("corporate_action_elections", "symbol"),
+    ("security_correlations", "symbol_a"),
+    ("security_correlations", "symbol_b"),
+    ("intraday_prices", "symbol"),
+    ("score_state", "symbol"),
+    ("watchlist", "symbol"),
+    ("security_metadata", "symbol"),
+    ("instrument_ids", "symbol"),
+    ("metadata_provenance", "symbol"),
+    ("metadata_reviews", "symbol"),
+    ("journal_entries", "symbol"),
+    ("position_adjustments", "symbol"),
+    ("position_theses", "symbol"),
+    ("protective_orders", "symbol"),
+    ("protective_order_adjustments", "symbol"),
+    ("sliced_orders", "symbol"),
+    ("external_holdings", "symbol"),
+    ("price_downloads", "symbol"),
+]
+
+# Tables a retention policy may prune: (unix timestamp column, extra condition on prunable rows)
+RETENTION_COLUMNS = {
+    "cache": ("expires_at", "expires_at IS NOT NULL"),  # Entries expired longer ago than the policy
+    "job_history": ("executed_at", None),
+    "notifications": ("updated_at", "read_at IS NOT NULL"),  # Unread notifications are kept
+    "webhook_deliveries": ("created_at", "status != 'pending'"),
+    "auth_audit": ("created_at", None),
+    "reports": ("generated_at", None),
+    "intraday_prices": ("ts", None),
+    "state_checks": ("last_checked_at", None),
+}
+
+# Bar values written when folding intraday bars, after symbol, resolution and ts
+_INTRADAY_BAR_COLUMNS = ("open", "high", "low", "close", "bid", "ask", "samples")
+
+# Columns copied verbatim when moving rows into the archive tables (_TRADE_COLUMNS lives in base)
+_DECISION_COLUMNS = (
+    "id, order_id, symbol, action, quantity, price, currency, reason_code, sleeve, source, created_at, "
+    "dominant_component, score_components, pricing_method, limit_price, quote_price"
+)
 
 SCHEMA = """
 -- Settings (key-value store)
@@ -686,12 +3952,89 @@ CREATE TABLE IF NOT EXISTS securities (
     active INTEGER DEFAULT 1,
     allow_buy INTEGER DEFAULT 1,
     allow_sell INTEGER DEFAULT 1,
+    supports_fractional INTEGER DEFAULT 0,  -- 1 if the broker accepts fractional quantities
+    gics_code TEXT,  -- Most specific GICS taxonomy code (sector_taxonomy.code)
     user_multiplier REAL DEFAULT 0.5,  -- User conviction (0.0 low conviction, 0.5 neutral, 1.0 high conviction)
     aliases TEXT,  -- Comma-separated alternative names for news/sentiment search
     data TEXT,  -- Raw Tradernet API response (JSON)
     last_synced INTEGER,
     quote_data TEXT,  -- Raw quote data from Tradernet API (JSON)
-    quote_updated_at INTEGER  -- When quote_data was last updated (unix timestamp)
+    quote_updated_at INTEGER,  -- When quote_data was last updated (unix timestamp)
+    added_at INTEGER,  -- When the security joined the universe (NULL for pre-existing entries)
+    isin TEXT,  -- Stable identity; the symbol changes on ticker renames (shared by listings of one security)
+    asset_class TEXT DEFAULT 'equity',  -- 'equity' or 'cash_equivalent' (money market ETFs: parked cash, no target)
+    drip_mode TEXT,  -- Dividend reinvestment: same, redirect or cash (NULL: drip_default_mode)
+    country TEXT,  -- Issuer country (metadata enrichment or manual)
+    exchange TEXT,  -- Listing exchange (metadata enrichment or manual)
+    archived_at INTEGER,  -- When the security was archived (NULL while active or for legacy deactivations)
+    archive_reason TEXT,
+    archived_by TEXT,
+    archive_state TEXT  -- JSON trading flags before archival, restored on reactivation
+);
+
+-- Metadata enrichment: each provider's values, where applied values came from, conflicts to review
+CREATE TABLE IF NOT EXISTS security_metadata (
+    symbol TEXT NOT NULL,
+    field TEXT NOT NULL,  -- industry, country, exchange
+    provider TEXT NOT NULL,  -- tradernet, yahoo, user
+    value TEXT NOT NULL,
+    fetched_at INTEGER NOT NULL,
+    PRIMARY KEY (symbol, field, provider)
+);
+
+CREATE TABLE IF NOT EXISTS metadata_provenance (
+    symbol TEXT NOT NULL,
+    field TEXT NOT NULL,
+    value TEXT,  -- Value applied to the securities row
+    provider TEXT NOT NULL,
+    rule TEXT NOT NULL,  -- override, agreement, single_source, priority
+    applied_at INTEGER NOT NULL,
+    PRIMARY KEY (symbol, field)
+);
+
+CREATE TABLE IF NOT EXISTS metadata_reviews (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    field TEXT NOT NULL,
+    candidates TEXT NOT NULL,  -- JSON: provider -> value
+    status TEXT NOT NULL,  -- open, resolved
+    created_at INTEGER NOT NULL,
+    resolved_value TEXT,
+    resolved_by TEXT,
+    resolved_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_metadata_reviews_status ON metadata_reviews(status, symbol);
+
+-- Canonical instrument IDs: ISIN, Tradernet ticker and Yahoo symbol of each security, with history
+CREATE TABLE IF NOT EXISTS instrument_ids (
+    scheme TEXT NOT NULL,  -- isin, tradernet, yahoo
+    value TEXT NOT NULL,
+    symbol TEXT NOT NULL,  -- Current securities.symbol
+    source TEXT,  -- broker or user
+    created_at INTEGER NOT NULL,
+    retired_at INTEGER,  -- Replaced by another ID of the same scheme (NULL = current)
+    PRIMARY KEY (scheme, value, symbol)
+);
+CREATE INDEX IF NOT EXISTS idx_instrument_ids_symbol ON instrument_ids(symbol);
+
+-- Ticker changes: old symbols keep resolving to the security's current symbol
+CREATE TABLE IF NOT EXISTS symbol_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    isin TEXT,
+    old_symbol TEXT NOT NULL,
+    new_symbol TEXT NOT NULL,
+    reason TEXT,
+    changed_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_symbol_history_old ON symbol_history(old_symbol);
+
+-- User-defined computed columns (expressions over security metrics)
+CREATE TABLE IF NOT EXISTS computed_columns (
+    name TEXT PRIMARY KEY,
+    expression TEXT NOT NULL,
+    description TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
 );
 
 -- Current positions
@@ -717,6 +4060,18 @@ CREATE TABLE IF NOT EXISTS prices (
     PRIMARY KEY (symbol, date)
 );
 
+-- Batch price history downloads; pending rows are what an interrupted download still has to fetch
+CREATE TABLE IF NOT EXISTS price_downloads (
+    symbol TEXT PRIMARY KEY,
+    source TEXT,  -- Market data provider the stored bars came from
+    start_date TEXT NOT NULL,  -- First day requested (YYYY-MM-DD)
+    status TEXT NOT NULL CHECK (status IN ('pending', 'done', 'failed')),
+    bars INTEGER NOT NULL DEFAULT 0,
+    last_date TEXT,
+    error TEXT,  -- Why the download failed, or which providers were skipped
+    updated_at INTEGER NOT NULL
+);
+
 -- Trade history (synced from broker)
 CREATE TABLE IF NOT EXISTS trades (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -740,6 +4095,33 @@ CREATE TABLE IF NOT EXISTS allocation_targets (
     PRIMARY KEY (type, name)
 );
 
+-- Optimizer proposals for allocation targets; only accepted ones are written to allocation_targets
+CREATE TABLE IF NOT EXISTS allocation_proposals (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK(target_type IN ('geography', 'industry')),
+    method TEXT NOT NULL,  -- risk_parity or mean_variance
+    weights TEXT NOT NULL,  -- JSON: group -> proposed weight (fractions summing to 1)
+    active TEXT NOT NULL,  -- JSON: group -> active weight (normalized) when proposed
+    stats TEXT NOT NULL,  -- JSON: expected score, volatility, turnover, constraints used
+    status TEXT NOT NULL DEFAULT 'proposed',  -- proposed, accepted, rejected, superseded
+    decided_at INTEGER
+);
+
+-- GICS-like sector taxonomy (sector -> industry group)
+CREATE TABLE IF NOT EXISTS sector_taxonomy (
+    code TEXT PRIMARY KEY,  -- 2-digit sector, 4-digit industry group
+    name TEXT NOT NULL,
+    level TEXT NOT NULL CHECK(level IN ('sector', 'industry_group')),
+    parent_code TEXT REFERENCES sector_taxonomy(code)
+);
+
+-- Hierarchical allocation caps on taxonomy nodes (fraction of portfolio value)
+CREATE TABLE IF NOT EXISTS sector_allocation_caps (
+    code TEXT PRIMARY KEY REFERENCES sector_taxonomy(code),
+    max_pct REAL NOT NULL CHECK(max_pct >= 0 AND max_pct <= 1)
+);
+
 -- Cash balances per currency
 CREATE TABLE IF NOT EXISTS cash_balances (
     currency TEXT PRIMARY KEY,
@@ -776,6 +4158,8 @@ CREATE TABLE IF NOT EXISTS job_schedules (
     category TEXT,
     last_run INTEGER DEFAULT 0,
     consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    quarantined_at INTEGER,  -- Set by the quarantine remediation; scheduled runs skip the job
+    quarantine_reason TEXT,
     created_at INTEGER NOT NULL,
     updated_at INTEGER NOT NULL
 );
@@ -789,7 +4173,8 @@ CREATE TABLE IF NOT EXISTS job_history (
     error TEXT,
     duration_ms INTEGER NOT NULL DEFAULT 0,
     executed_at INTEGER NOT NULL,
-    retry_count INTEGER NOT NULL DEFAULT 0
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    failure_class TEXT  -- timeout, db_locked, broker_auth, network, data_validation, unknown
 );
 
 -- Create indexes
@@ -832,11 +4217,26 @@ CREATE TABLE IF NOT EXISTS dividends (
     amount REAL NOT NULL,  -- Net credited amount in original currency (after taxes)
     currency TEXT NOT NULL,
     value REAL NOT NULL,  -- EUR-equivalent value (amount converted to EUR)
-    data TEXT NOT NULL  -- Full raw JSON from corporate actions API
+    data TEXT NOT NULL,  -- Full raw JSON from corporate actions API
+    gross_amount REAL,  -- Before withholding tax, in the original currency
+    withholding_amount REAL,  -- tax_amount + external_tax
+    withholding_rate REAL,  -- withholding_amount / gross_amount
+    country TEXT  -- Issuer country (ISIN prefix)
 );
 CREATE INDEX IF NOT EXISTS idx_dividends_symbol ON dividends(symbol);
 CREATE INDEX IF NOT EXISTS idx_dividends_date ON dividends(date);
 
+-- Where each dividend is reinvested (trading:dividend_reinvest job, per-security DRIP mode)
+CREATE TABLE IF NOT EXISTS dividend_reinvestments (
+    dividend_id TEXT PRIMARY KEY,  -- dividends.id
+    symbol TEXT NOT NULL,  -- Paying security
+    mode TEXT NOT NULL,  -- same, redirect or cash
+    target_symbol TEXT,  -- Security the dividend boosts (NULL when kept as cash)
+    value_eur REAL NOT NULL,
+    reason TEXT,
+    decided_at INTEGER NOT NULL
+);
+
 -- Historical FX rates cache
 CREATE TABLE IF NOT EXISTS fx_rates_history (
     date TEXT NOT NULL,
@@ -844,4 +4244,581 @@ CREATE TABLE IF NOT EXISTS fx_rates_history (
     rate_to_eur REAL NOT NULL,
     PRIMARY KEY (date, currency)
 );
+
+-- Trade decisions (which planner rule / job submitted each order, joined to trades for attribution)
+CREATE TABLE IF NOT EXISTS trade_decisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_id TEXT,  -- Broker order ID returned on submission
+    symbol TEXT NOT NULL,
+    action TEXT NOT NULL CHECK(action IN ('buy', 'sell')),
+    quantity REAL NOT NULL,
+    price REAL,
+    currency TEXT,
+    reason_code TEXT,  -- Planner rule that generated the recommendation (entry_t1, scaleout_10, ...)
+    sleeve TEXT,
+    source TEXT NOT NULL,  -- Job type that submitted the order, or 'manual'
+    created_at INTEGER NOT NULL,
+    dominant_component TEXT,  -- Evaluation component that contributed most to selection
+    score_components TEXT,  -- JSON: priority contribution per component
+    pricing_method TEXT,  -- How the order was priced: market, quote, depth, midpoint, last_buffer, vwap or adaptive
+    limit_price REAL,  -- Limit price sent to the broker (NULL for market orders)
+    quote_price REAL  -- Quoted ask (buy) / bid (sell), or last price, at submission
+);
+CREATE INDEX IF NOT EXISTS idx_trade_decisions_component ON trade_decisions(dominant_component);
+CREATE INDEX IF NOT EXISTS idx_trade_decisions_order_id ON trade_decisions(order_id);
+CREATE INDEX IF NOT EXISTS idx_trade_decisions_symbol_created ON trade_decisions(symbol, created_at);
+
+-- Archive of closed positions (one row per flat-to-flat round trip), moved out of the hot tables
+CREATE TABLE IF NOT EXISTS archived_positions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    currency TEXT,
+    opened_at INTEGER NOT NULL,  -- First trade of the round trip (unix timestamp)
+    closed_at INTEGER NOT NULL,  -- Trade that brought the position back to zero
+    bought_quantity REAL NOT NULL DEFAULT 0,
+    sold_quantity REAL NOT NULL DEFAULT 0,
+    cost_local REAL NOT NULL DEFAULT 0,
+    proceeds_local REAL NOT NULL DEFAULT 0,
+    realized_pnl_local REAL NOT NULL DEFAULT 0,
+    commission REAL NOT NULL DEFAULT 0,
+    trade_count INTEGER NOT NULL DEFAULT 0,
+    archived_at INTEGER NOT NULL,
+    data TEXT  -- JSON: security metadata and strategy state at archival time
+);
+CREATE INDEX IF NOT EXISTS idx_archived_positions_symbol ON archived_positions(symbol, closed_at);
+
+-- Trades belonging to archived round trips (same columns as trades, keyed by original id)
+CREATE TABLE IF NOT EXISTS archived_trades (
+    id INTEGER PRIMARY KEY,
+    archive_id INTEGER NOT NULL,
+    broker_trade_id TEXT UNIQUE NOT NULL,
+    symbol TEXT NOT NULL,
+    side TEXT NOT NULL,
+    quantity REAL NOT NULL,
+    price REAL NOT NULL,
+    commission REAL DEFAULT 0,
+    commission_currency TEXT DEFAULT 'EUR',
+    executed_at INTEGER NOT NULL,
+    raw_data TEXT NOT NULL,
+    FOREIGN KEY (archive_id) REFERENCES archived_positions(id)
+);
+CREATE INDEX IF NOT EXISTS idx_archived_trades_archive ON archived_trades(archive_id);
+
+-- Trade decisions belonging to archived round trips (kept for attribution history)
+CREATE TABLE IF NOT EXISTS archived_trade_decisions (
+    id INTEGER PRIMARY KEY,
+    archive_id INTEGER NOT NULL,
+    order_id TEXT,
+    symbol TEXT NOT NULL,
+    action TEXT NOT NULL,
+    quantity REAL NOT NULL,
+    price REAL,
+    currency TEXT,
+    reason_code TEXT,
+    sleeve TEXT,
+    source TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    dominant_component TEXT,
+    score_components TEXT,
+    pricing_method TEXT,
+    limit_price REAL,
+    quote_price REAL,
+    FOREIGN KEY (archive_id) REFERENCES archived_positions(id)
+);
+CREATE INDEX IF NOT EXISTS idx_archived_trade_decisions_archive ON archived_trade_decisions(archive_id);
+
+-- Notification inbox: alert events kept until acknowledged in the UI
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,  -- Last occurrence (bumped when a duplicate is folded in)
+    severity TEXT NOT NULL CHECK (severity IN ('info', 'warning', 'error')),
+    category TEXT NOT NULL,  -- e.g. 'trade', 'job', 'balance'
+    title TEXT NOT NULL,
+    message TEXT,
+    entity_type TEXT,  -- e.g. 'security', 'job'
+    entity_id TEXT,
+    link TEXT,  -- Dashboard path of the related entity
+    dedupe_key TEXT,  -- Unread notifications with the same key are merged
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    read_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications(read_at, created_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_dedupe ON notifications(dedupe_key, read_at);
+
+-- Outbound event notifications: webhook / Telegram / ntfy endpoints and their delivery queue
+CREATE TABLE IF NOT EXISTS webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL DEFAULT 'webhook' CHECK (kind IN ('webhook', 'telegram', 'ntfy')),
+    url TEXT NOT NULL,  -- Endpoint URL (ntfy topic URL; ignored for telegram)
+    target TEXT,  -- Telegram chat id
+    secret TEXT,  -- HMAC signing secret (webhook), bot token (telegram) or access token (ntfy)
+    events TEXT,  -- JSON list of subscribed event types (NULL = all)
+    enabled INTEGER NOT NULL DEFAULT 1,
+    created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id INTEGER NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,  -- JSON event payload
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at INTEGER NOT NULL,
+    last_error TEXT,
+    created_at INTEGER NOT NULL,
+    delivered_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+-- Generated daily/weekly digest reports
+CREATE TABLE IF NOT EXISTS reports (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    period TEXT NOT NULL CHECK (period IN ('daily', 'weekly')),
+    period_start INTEGER NOT NULL,
+    period_end INTEGER NOT NULL,
+    generated_at INTEGER NOT NULL,
+    data TEXT NOT NULL,  -- JSON report sections
+    markdown TEXT NOT NULL,
+    html TEXT NOT NULL
+);
+
+-- Scheduled report emails (report:email job); a kind/period with a 'sent' row is not sent again
+CREATE TABLE IF NOT EXISTS email_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,  -- weekly_report, monthly_reconciliation or test
+    period TEXT NOT NULL,  -- ISO week (2024-W10) or month (2024-03) covered
+    subject TEXT NOT NULL,
+    recipients TEXT NOT NULL,  -- JSON array
+    status TEXT NOT NULL CHECK (status IN ('sent', 'failed')),
+    error TEXT,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_email_deliveries_kind ON email_deliveries(kind, period);
+
+-- Decisions on pending recommendations (manual approval mode), one per symbol and side
+CREATE TABLE IF NOT EXISTS recommendation_decisions (
+    symbol TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('buy', 'sell')),
+    status TEXT NOT NULL CHECK (status IN ('approved', 'rejected', 'deferred')),
+    quantity REAL NOT NULL,  -- Recommended quantity at decision time; approval covers up to this
+    decided_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,  -- The recommendation returns to pending after this
+    PRIMARY KEY (symbol, action)
+);
+
+-- Orders scheduled in advance (trading:scheduled_orders job): monthly buys or one-off orders
+CREATE TABLE IF NOT EXISTS scheduled_orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('buy', 'sell')),
+    frequency TEXT NOT NULL CHECK (frequency IN ('once', 'monthly')),
+    symbols TEXT,  -- JSON list; NULL when picking the top scored securities of a group
+    group_type TEXT,  -- geography or industry
+    group_name TEXT,
+    top_n INTEGER,  -- Securities picked from the group
+    amount_eur REAL,  -- Order value, split evenly across the symbols
+    quantity REAL,  -- Fixed quantity (single-symbol orders instead of amount_eur)
+    day_of_month INTEGER,  -- monthly: 1-28
+    timing TEXT NOT NULL DEFAULT 'any' CHECK (timing IN ('any', 'open', 'close')),
+    next_run_date TEXT NOT NULL,  -- YYYY-MM-DD
+    status TEXT NOT NULL DEFAULT 'active',  -- active, paused, completed, cancelled
+    run_state TEXT,  -- JSON: legs of the current due date and their outcome so far
+    last_run_at INTEGER,
+    last_result TEXT,  -- JSON: outcome of the last completed run
+    note TEXT
+);
+
+-- New deposits handled by the auto-invest policy (sync:cashflows)
+CREATE TABLE IF NOT EXISTS auto_invest_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    deposit_ids TEXT NOT NULL,  -- JSON list of cash_flows ids
+    deposit_eur REAL NOT NULL,
+    budget_eur REAL NOT NULL,  -- Deposit value the buys could use after the cash buffer
+    status TEXT NOT NULL,  -- planned, executed, skipped, blocked
+    orders TEXT NOT NULL,  -- JSON: chosen buys and their outcome
+    reason TEXT
+);
+
+-- Monthly reconciliations of the ledger against the broker report (reconcile:broker job)
+CREATE TABLE IF NOT EXISTS reconciliations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    period TEXT NOT NULL,  -- YYYY-MM
+    created_at INTEGER NOT NULL,
+    status TEXT NOT NULL,  -- clean, open, signed_off
+    summary TEXT NOT NULL,  -- JSON: entry counts per section and discrepancy counts per severity
+    discrepancies TEXT NOT NULL,  -- JSON list
+    signed_off_at INTEGER,
+    signed_off_by TEXT,
+    note TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_reconciliations_period ON reconciliations(period);
+
+-- Manual position corrections when the broker sync is wrong (append-only)
+CREATE TABLE IF NOT EXISTS position_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    broker_quantity REAL NOT NULL,  -- Broker-reported figures the correction overrides
+    broker_avg_cost REAL,
+    quantity_before REAL NOT NULL,
+    quantity_after REAL NOT NULL,
+    avg_cost_before REAL,
+    avg_cost_after REAL,
+    reason TEXT NOT NULL,
+    created_by TEXT,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_position_adjustments_symbol ON position_adjustments(symbol, id);
+
+-- Broker-side stop-loss or trailing stop currently protecting each position (trading:protective_orders job)
+CREATE TABLE IF NOT EXISTS protective_orders (
+    symbol TEXT PRIMARY KEY,
+    policy TEXT NOT NULL,  -- Name of the protective_order_policies entry it was placed for
+    kind TEXT NOT NULL,  -- stop_loss or trailing_stop
+    percent REAL NOT NULL,  -- Distance below the price (stop_loss) or below the running high (trailing_stop)
+    stop_price REAL,  -- stop_loss only
+    quantity REAL NOT NULL,  -- Position size when it was placed
+    order_id TEXT NOT NULL,
+    placed_at INTEGER NOT NULL
+);
+
+-- Protective order placements, replacements and cancellations; entries are never changed or deleted
+CREATE TABLE IF NOT EXISTS protective_order_adjustments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    action TEXT NOT NULL,  -- place, replace or cancel
+    policy TEXT,
+    kind TEXT,
+    percent REAL,
+    stop_price REAL,
+    quantity REAL,
+    order_id TEXT,  -- Order now in force (NULL after a cancel)
+    previous_order_id TEXT,  -- Order replaced or cancelled
+    reason TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_protective_order_adjustments_symbol ON protective_order_adjustments(symbol, id);
+
+-- Large orders split into child orders placed across the session (trading:order_slices job)
+CREATE TABLE IF NOT EXISTS sliced_orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('buy', 'sell')),
+    quantity REAL NOT NULL,  -- Parent quantity, the sum of the slices
+    source TEXT NOT NULL,  -- Job that decided the order, or 'manual'
+    reason TEXT NOT NULL,  -- Why it was sliced
+    recommendation TEXT NOT NULL,  -- JSON: the TradeRecommendation each slice is placed with
+    status TEXT NOT NULL DEFAULT 'active',  -- active, completed, failed, expired or cancelled
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sliced_orders_status ON sliced_orders(status, id);
+
+-- Child orders of a sliced order
+CREATE TABLE IF NOT EXISTS order_slices (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    parent_id INTEGER NOT NULL REFERENCES sliced_orders(id),
+    seq INTEGER NOT NULL,  -- 1-based position in the sequence
+    quantity REAL NOT NULL,
+    due_at INTEGER NOT NULL,  -- Not placed before this time
+    status TEXT NOT NULL DEFAULT 'pending',  -- pending, placed, failed, expired or cancelled
+    order_id TEXT,  -- Broker order ID once placed (joins trade_decisions and synced trades)
+    placed_at INTEGER,
+    detail TEXT,
+    UNIQUE(parent_id, seq)
+);
+
+-- The user's target price, stop level and investment thesis for a position (prices in the security's currency)
+CREATE TABLE IF NOT EXISTS position_theses (
+    symbol TEXT PRIMARY KEY,
+    target_price REAL,
+    stop_price REAL,
+    thesis TEXT,
+    review_days INTEGER,  -- Days between thesis reviews (NULL = never due)
+    reviewed_at INTEGER NOT NULL,  -- Last review; set when the thesis is first written
+    target_reached_at INTEGER,  -- When the price reached the level (NULL while below it)
+    stop_reached_at INTEGER,
+    review_notified_at INTEGER,  -- When the current review was flagged as due
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+-- Holdings outside the broker account (employer stock plan, real estate, crypto, ...); never traded
+CREATE TABLE IF NOT EXISTS external_holdings (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,  -- stock_plan, real_estate, crypto, cash or other
+    symbol TEXT,  -- Security it is exposure to (e.g. the employer's stock), if any
+    currency TEXT NOT NULL DEFAULT 'EUR',
+    geography TEXT,  -- Comma-separated like securities.geography (falls back to the symbol's)
+    industry TEXT,
+    value REAL NOT NULL DEFAULT 0,  -- Latest valuation, in currency
+    valued_at INTEGER,
+    value_source TEXT,  -- manual, or the name of the feed that posted it
+    note TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS external_valuations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    holding_id INTEGER NOT NULL,
+    value REAL NOT NULL,
+    source TEXT NOT NULL,
+    valued_at INTEGER NOT NULL,
+    recorded_at INTEGER NOT NULL,
+    FOREIGN KEY (holding_id) REFERENCES external_holdings(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_external_valuations_holding ON external_valuations(holding_id, valued_at);
+
+-- API authentication: tokens (static API tokens and login sessions), local users, audit trail
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,  -- SHA-256 of the token; the token itself is shown once
+    token_prefix TEXT NOT NULL,  -- First characters, to recognise a token in listings
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'operator', 'admin')),
+    username TEXT,  -- Set for login sessions
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER,
+    last_used_at INTEGER,
+    revoked_at INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS auth_users (
+    username TEXT PRIMARY KEY,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'operator', 'admin')),
+    created_at INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS auth_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    principal TEXT,  -- token name or username; NULL for anonymous attempts
+    role TEXT,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    status_code INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_created ON auth_audit(created_at);
+
+-- Audit log of trading actions (orders, settings changes, manual overrides); append-only
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    actor_type TEXT NOT NULL CHECK (actor_type IN ('api', 'tui', 'job', 'system')),
+    actor TEXT NOT NULL,  -- token or user name, job type, or sentinel
+    action TEXT NOT NULL,  -- order.buy, settings.change, position.adjust, ...
+    target TEXT,  -- symbol, setting key, job type or order id
+    params TEXT NOT NULL,  -- JSON
+    outcome TEXT NOT NULL CHECK (outcome IN ('success', 'simulated', 'failed', 'rejected')),
+    error TEXT,
+    correlation_id TEXT  -- Request or job run that performed the action
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target, created_at);
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+-- Planner snapshots: inputs and output of past planner runs, for decision replay
+CREATE TABLE IF NOT EXISTS planner_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at INTEGER NOT NULL,
+    state_hash TEXT,
+    params TEXT NOT NULL,  -- JSON: batch parameters (min_trade_value)
+    context BLOB NOT NULL,  -- zlib-compressed JSON of the planner inputs
+    recommendations TEXT NOT NULL  -- JSON: recommendations produced by the run
+);
+CREATE INDEX IF NOT EXISTS idx_planner_snapshots_created ON planner_snapshots(created_at);
+
+-- Trade journal: the user's notes on executed trades and on recommendations
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL CHECK (kind IN ('trade', 'recommendation')),
+    trade_id INTEGER,  -- trades.id (kept when the trade moves to archived_trades)
+    symbol TEXT NOT NULL,
+    action TEXT NOT NULL,  -- buy or sell
+    note TEXT,
+    tags TEXT NOT NULL DEFAULT '[]',  -- JSON list
+    conviction INTEGER,  -- 1 (low) to 5 (high)
+    recommendation TEXT,  -- JSON: the recommendation as it was when annotated
+    created_by TEXT,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_symbol ON journal_entries(symbol, created_at);
+CREATE INDEX IF NOT EXISTS idx_journal_entries_trade ON journal_entries(trade_id);
+
+-- Shadow mode: proposed settings run alongside the live configuration without trading
+CREATE TABLE IF NOT EXISTS shadow_trials (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    overrides TEXT NOT NULL,  -- JSON: setting key -> proposed value
+    note TEXT,
+    status TEXT NOT NULL DEFAULT 'active',  -- active, stopped or promoted
+    created_by TEXT,
+    created_at INTEGER NOT NULL,
+    ended_by TEXT,
+    ended_at INTEGER
+);
+
+-- What a shadow trial would have done on a recorded planner run (planner_snapshots.id)
+CREATE TABLE IF NOT EXISTS shadow_runs (
+    trial_id INTEGER NOT NULL,
+    snapshot_id INTEGER NOT NULL,  -- Kept after the snapshot itself is pruned
+    created_at INTEGER NOT NULL,
+    live_count INTEGER NOT NULL,
+    shadow_count INTEGER NOT NULL,
+    identical INTEGER NOT NULL,
+    diff TEXT NOT NULL,  -- JSON: same_order, added, removed, changed (shadow vs live)
+    recommendations TEXT NOT NULL,  -- JSON: the shadow recommendations
+    PRIMARY KEY (trial_id, snapshot_id),
+    FOREIGN KEY (trial_id) REFERENCES shadow_trials(id)
+);
+
+-- Planner state checks: one row per distinct state hash, with its sub-hashes for diffing
+CREATE TABLE IF NOT EXISTS state_checks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    checked_at INTEGER NOT NULL,  -- First check that saw this state
+    last_checked_at INTEGER NOT NULL,
+    checks INTEGER NOT NULL DEFAULT 1,
+    state_hash TEXT NOT NULL,
+    components TEXT NOT NULL,  -- JSON: component -> hash
+    items TEXT NOT NULL  -- JSON: component -> item key -> hash
+);
+CREATE INDEX IF NOT EXISTS idx_state_checks_last ON state_checks(last_checked_at);
+
+-- Quality-gate backfill: which buy gate paths each security passed at each historical month end
+CREATE TABLE IF NOT EXISTS quality_gate_backfill (
+    symbol TEXT NOT NULL,
+    month TEXT NOT NULL,  -- YYYY-MM
+    paths TEXT NOT NULL,  -- JSON array of passed paths (empty = none)
+    opp_score REAL,
+    opp_score_raw REAL,
+    settings TEXT,  -- JSON: gate settings used
+    computed_at INTEGER NOT NULL,
+    PRIMARY KEY (symbol, month)
+);
+
+-- Credentials vault: AES-GCM encrypted secrets (broker and backup credentials)
+CREATE TABLE IF NOT EXISTS secrets (
+    name TEXT PRIMARY KEY,
+    salt BLOB NOT NULL,  -- Key derivation salt
+    nonce BLOB NOT NULL,
+    ciphertext BLOB NOT NULL,  -- Includes the GCM tag
+    created_at INTEGER NOT NULL,
+    rotated_at INTEGER NOT NULL
+);
+
+-- Broker documents (order files, broker report PDFs) stored under <data dir>/documents
+CREATE TABLE IF NOT EXISTS broker_documents (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL CHECK (kind IN ('order_file', 'broker_report')),
+    file_name TEXT NOT NULL,
+    path TEXT UNIQUE NOT NULL,  -- Relative to the documents directory
+    mime TEXT,
+    size INTEGER NOT NULL,
+    sha256 TEXT NOT NULL,
+    order_id TEXT,  -- order_file: the order or client request the file belongs to
+    period_start TEXT,  -- broker_report: YYYY-MM-DD
+    period_end TEXT,
+    fetched_at INTEGER NOT NULL
+);
+
+-- Ledger entries a document relates to (trades by broker_trade_id, cash flows and dividends by id)
+CREATE TABLE IF NOT EXISTS broker_document_links (
+    document_id INTEGER NOT NULL REFERENCES broker_documents(id) ON DELETE CASCADE,
+    entity_type TEXT NOT NULL CHECK (entity_type IN ('trade', 'cash_flow', 'dividend')),
+    entity_id TEXT NOT NULL,
+    PRIMARY KEY (document_id, entity_type, entity_id)
+);
+CREATE INDEX IF NOT EXISTS idx_broker_document_links_entity ON broker_document_links(entity_type, entity_id);
+
+-- Elections submitted for voluntary corporate actions
+CREATE TABLE IF NOT EXISTS corporate_action_elections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    corporate_action_id TEXT NOT NULL,
+    symbol TEXT,
+    option TEXT NOT NULL,
+    quantity REAL,  -- NULL = the whole holding
+    status TEXT NOT NULL CHECK (status IN ('submitted', 'failed')),
+    broker_request_id TEXT,
+    submitted_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_corporate_action_elections_action ON corporate_action_elections(corporate_action_id);
+
+-- Rolling pairwise return correlations (replaced as a whole on every computation)
+CREATE TABLE IF NOT EXISTS security_correlations (
+    symbol_a TEXT NOT NULL,
+    symbol_b TEXT NOT NULL,
+    correlation REAL NOT NULL,  -- Pearson, -1 to 1
+    observations INTEGER NOT NULL,  -- Common daily returns used
+    window_days INTEGER NOT NULL,
+    computed_at INTEGER NOT NULL,
+    PRIMARY KEY (symbol_a, symbol_b)
+);
+
+-- Latest ensemble regime per region (geography)
+CREATE TABLE IF NOT EXISTS regime_states (
+    region TEXT PRIMARY KEY,
+    regime TEXT NOT NULL CHECK (regime IN ('bull', 'neutral', 'bear')),
+    score REAL NOT NULL,  -- -1 (risk-off) to 1 (risk-on)
+    confidence REAL NOT NULL,  -- 0 to 1
+    signals TEXT NOT NULL,  -- JSON: signal name -> score, null when unavailable
+    computed_at INTEGER NOT NULL
+);
+
+-- Intraday quote snapshots of held positions, downsampled as they age (see database/intraday.py)
+CREATE TABLE IF NOT EXISTS intraday_prices (
+    symbol TEXT NOT NULL,
+    resolution TEXT NOT NULL CHECK (resolution IN ('raw', 'hourly', 'daily')),
+    ts INTEGER NOT NULL,  -- Snapshot time (raw) or UTC bucket start
+    open REAL NOT NULL,
+    high REAL NOT NULL,
+    low REAL NOT NULL,
+    close REAL NOT NULL,
+    bid REAL,
+    ask REAL,
+    samples INTEGER NOT NULL DEFAULT 1,
+    PRIMARY KEY (symbol, resolution, ts)
+);
+
+-- Candidate securities tracked outside the universe; prices are collected into the prices table
+CREATE TABLE IF NOT EXISTS watchlist (
+    symbol TEXT PRIMARY KEY,
+    name TEXT,
+    currency TEXT,
+    market_id TEXT,
+    min_lot INTEGER DEFAULT 1,
+    isin TEXT,
+    note TEXT,
+    data TEXT,  -- Raw Tradernet security info (JSON), carried into securities on promotion
+    score REAL,  -- Last opportunity score, compared on refresh for change alerts
+    tags TEXT,  -- JSON list of the last signal tags
+    checked_at INTEGER,
+    added_at INTEGER NOT NULL
+);
+
+-- Cached per-security contrarian signal; dirty lists why it must be recomputed (NULL when clean)
+CREATE TABLE IF NOT EXISTS score_state (
+    symbol TEXT PRIMARY KEY,
+    signal TEXT,  -- JSON raw signal and skipped checks of the last computation
+    config_key TEXT,  -- Scoring settings the signal was computed with
+    computed_at INTEGER,
+    dirty TEXT,  -- Comma-separated reasons: prices, dividends, metadata
+    dirty_at INTEGER,
+    dirty_rev INTEGER NOT NULL DEFAULT 0  -- Bumped on every mark, so a save only clears flags it has seen
+);
 """
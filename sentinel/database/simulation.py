@@ -58,8 +58,10 @@ class SimulationDatabase(BaseDatabase):
 
     async def _copy_table(self, source_db, table: str):
         """Copy table data from source (READ-ONLY operation on source)."""
+        # Prices are read across both tiers when the source has a cold tier attached
+        source = getattr(source_db, "price_source", table) if table == "prices" else table
         try:
-            cursor = await source_db.conn.execute(f"SELECT * FROM {table}")  # noqa: S608
+            cursor = await source_db.conn.execute(f"SELECT * FROM {source}")  # noqa: S608
             rows = await cursor.fetchall()
             if not rows:
                 return
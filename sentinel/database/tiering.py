@@ -0,0 +1,154 @@
+"""
+Price Tiering - hot local storage plus a cold external tier for price history.
+
+Daily bars are by far the largest data set. Bars from the last `hot_days` stay
+in the main database (on the SD card); older bars are moved into a cold SQLite
+file on an attached USB drive or network path. The cold file is ATTACHed to the
+connection and read through the `prices_all` TEMP view, which merges both tiers
+(the hot row wins when a date exists in both), so queries that need long
+lookbacks see the full history without knowing where it lives. Writes always go
+to the hot `prices` table; the archive:prices job moves them out as they age.
+
+Usage:
+    tiering = PriceTiering("/mnt/usb/sentinel-cold.db", hot_days=730)
+    if await tiering.attach(conn):
+        moved = await tiering.migrate(conn)
+"""
+
+import logging
+import os
+from datetime import date, timedelta
+from pathlib import Path
+
+import aiosqlite
+
+logger = logging.getLogger(__name__)
+
+COLD_SCHEMA = "cold"
+MERGED_VIEW = "prices_all"
+DEFAULT_HOT_DAYS = 730
+
+_PRICE_COLUMNS = "symbol, date, open, high, low, close, volume"
+
+_COLD_TABLE = f"""
+CREATE TABLE IF NOT EXISTS {COLD_SCHEMA}.prices (
+    symbol TEXT NOT NULL,
+    date TEXT NOT NULL,
+    open REAL,
+    high REAL,
+    low REAL,
+    close REAL NOT NULL,
+    volume INTEGER,
+    PRIMARY KEY (symbol, date)
+)
+"""
+
+_MERGED_VIEW_SQL = f"""
+CREATE TEMP VIEW {MERGED_VIEW} AS
+SELECT {_PRICE_COLUMNS} FROM main.prices
+UNION ALL
+SELECT {_PRICE_COLUMNS} FROM {COLD_SCHEMA}.prices c
+WHERE NOT EXISTS (SELECT 1 FROM main.prices h WHERE h.symbol = c.symbol AND h.date = c.date)
+"""
+
+# Used when the cold file cannot be attached, so the view name always resolves
+_HOT_ONLY_VIEW_SQL = f"CREATE TEMP VIEW {MERGED_VIEW} AS SELECT {_PRICE_COLUMNS} FROM main.prices"
+
+
+class PriceTiering:
+    """Attaches the cold price tier to a connection and moves aged bars into it."""
+
+    def __init__(self, cold_path: str, hot_days: int = DEFAULT_HOT_DAYS):
+        """
+        Args:
+            cold_path: SQLite file on external storage (created on first attach)
+            hot_days: Calendar days of bars kept in the main database
+        """
+        self.cold_path = cold_path
+        self.hot_days = max(1, int(hot_days))
+
+    def cutoff(self, today: date | None = None) -> str:
+        """Bars dated before this (YYYY-MM-DD) belong in the cold tier."""
+        return ((today or date.today()) - timedelta(days=self.hot_days)).isoformat()
+
+    def available(self) -> bool:
+        """Whether the cold storage location can be used right now."""
+        # Never create the file on the SD card under an unmounted mount point:
+        # the directory must exist and be writable (or the file already exist).
+        path = Path(self.cold_path)
+        if path.exists():
+            return path.is_file()
+        return path.parent.is_dir() and os.access(path.parent, os.W_OK)
+
+    async def attach(self, conn: aiosqlite.Connection) -> bool:
+        """
+        Attach the cold tier and create the merged view on a connection.
+
+        Returns:
+            True if the cold tier is attached. False if the storage is missing or
+            unreadable; the view then covers the hot tier only.
+        """
+        await conn.execute(f"DROP VIEW IF EXISTS temp.{MERGED_VIEW}")
+        attached = False
+        if self.available():
+            try:
+                await conn.commit()
+                await conn.execute(f"ATTACH DATABASE ? AS {COLD_SCHEMA}", (self.cold_path,))
+                # Network paths do not support WAL's shared memory
+                await conn.execute(f"PRAGMA {COLD_SCHEMA}.journal_mode=DELETE")
+                await conn.execute(_COLD_TABLE)
+                attached = True
+            except aiosqlite.Error as e:
+                logger.warning(f"Cannot attach cold price storage {self.cold_path}: {e}")
+                await self.detach(conn)
+        else:
+            logger.warning(f"Cold price storage {self.cold_path} is not available; using hot prices only")
+        await conn.execute(_MERGED_VIEW_SQL if attached else _HOT_ONLY_VIEW_SQL)
+        await conn.commit()
+        return attached
+
+    async def detach(self, conn: aiosqlite.Connection) -> None:
+        """Drop the merged view and detach the cold tier, if attached."""
+        await conn.execute(f"DROP VIEW IF EXISTS temp.{MERGED_VIEW}")
+        await conn.commit()
+        cursor = await conn.execute("PRAGMA database_list")
+        if any(row[1] == COLD_SCHEMA for row in await cursor.fetchall()):
+            await conn.execute(f"DETACH DATABASE {COLD_SCHEMA}")
+
+    async def migrate(self, conn: aiosqlite.Connection, today: date | None = None) -> int:
+        """
+        Move bars older than the hot window from the main database to the cold tier.
+
+        Copy and delete run in one transaction. In WAL mode SQLite does not commit
+        attached databases atomically, so a crash can leave a bar in both tiers;
+        the merged view prefers the hot copy and the next run removes it.
+
+        Returns:
+            Number of bars moved
+        """
+        cutoff = self.cutoff(today)
+        try:
+            await conn.execute(
+                f"""INSERT OR REPLACE INTO {COLD_SCHEMA}.prices ({_PRICE_COLUMNS})
+                    SELECT {_PRICE_COLUMNS} FROM main.prices WHERE date < ?""",  # noqa: S608
+                (cutoff,),
+            )
+            cursor = await conn.execute("DELETE FROM main.prices WHERE date < ?", (cutoff,))
+            await conn.commit()
+        except Exception:
+            await conn.rollback()
+            raise
+        return cursor.rowcount
+
+    async def stats(self, conn: aiosqlite.Connection, attached: bool) -> dict:
+        """Row counts and date ranges per tier."""
+        tiers = {"hot": "main.prices"}
+        if attached:
+            tiers["cold"] = f"{COLD_SCHEMA}.prices"
+        result: dict = {"cold_path": self.cold_path, "hot_days": self.hot_days, "attached": attached}
+        for tier, table in tiers.items():
+            cursor = await conn.execute(
+                f"SELECT COUNT(*) AS bars, MIN(date) AS oldest, MAX(date) AS newest FROM {table}"  # noqa: S608
+            )
+            result[tier] = dict(await cursor.fetchone())
+        return result
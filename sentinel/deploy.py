@@ -0,0 +1,137 @@
+"""
+Deployment smoke tests for blue/green releases.
+
+scripts/auto-deploy.sh stages every release in its own directory (a git
+worktree with its own virtualenv) next to the live one, and only switches the
+``current`` symlink over once the staged release passes these smoke tests:
+
+    migrations  the release's schema and migrations apply cleanly to a copy of
+                the live database (the live file is never touched)
+    broker      the broker credentials work and the API answers
+    planner     a full planner run over the copied database completes
+
+After the switch the script waits for /api/readyz and swaps the symlink back to
+the previous release if the new one is not ready within the deploy window.
+
+Usage (with the staged release's interpreter):
+    python -m sentinel.deploy smoke
+"""
+
+from __future__ import annotations
+
+import argparse
+import asyncio
+import json
+import logging
+import sqlite3
+import tempfile
+import time
+from pathlib import Path
+from typing import Optional
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.paths import DATA_DIR
+
+logger = logging.getLogger(__name__)
+
+
+def copy_database(source: Path, dest: Path) -> None:
+    """Consistent copy of a (possibly live, WAL-mode) SQLite database."""
+    src = sqlite3.connect(f"file:{source}?mode=ro", uri=True)
+    try:
+        dst = sqlite3.connect(dest)
+        try:
+            src.backup(dst)
+        finally:
+            dst.close()
+    finally:
+        src.close()
+
+
+async def smoke_test(db: Database, broker: Optional[Broker] = None, planner=None) -> dict:
+    """Run the smoke tests against a scratch database.
+
+    Args:
+        db: Database on a copy of the live file (connecting it applies the migrations)
+        broker: Broker instance (uses singleton if None)
+        planner: Planner instance (created on db and broker if None)
+
+    Returns:
+        dict with ok and checks (name -> ok, detail, seconds); later checks are
+        skipped once the migrations fail
+    """
+    checks = {"migrations": await _timed(_check_migrations(db))}
+    if not checks["migrations"]["ok"]:
+        return {"ok": False, "checks": checks}
+
+    broker = broker or Broker()
+    checks["broker"] = await _timed(_check_broker(broker))
+
+    if planner is None:
+        from sentinel.planner import Planner
+
+        planner = Planner(db=db, broker=broker)
+    checks["planner"] = await _timed(_check_planner(planner))
+    return {"ok": all(c["ok"] for c in checks.values()), "checks": checks}
+
+
+async def _timed(check) -> dict:
+    started = time.monotonic()
+    try:
+        ok, detail = await check
+    except Exception as e:
+        ok, detail = False, f"{type(e).__name__}: {e}"
+    return {"ok": ok, "detail": detail, "seconds": round(time.monotonic() - started, 2)}
+
+
+async def _check_migrations(db: Database) -> tuple[bool, str]:
+    await db.connect()
+    pending = await db.pending_migrations()
+    if pending:
+        return False, f"pending after migrating: {', '.join(pending)}"
+    return True, "applied"
+
+
+async def _check_broker(broker: Broker) -> tuple[bool, str]:
+    if not await broker.connect():
+        return False, "not connected (missing credentials?)"
+    if not await broker.ping():
+        return False, "API did not answer"
+    return True, "reachable"
+
+
+async def _check_planner(planner) -> tuple[bool, str]:
+    recommendations = await planner.get_recommendations()
+    return True, f"{len(recommendations)} recommendations"
+
+
+async def _smoke(db_path: Path) -> dict:
+    db = Database(str(db_path))
+    try:
+        return await smoke_test(db)
+    finally:
+        await db.close()
+
+
+def main(argv: Optional[list[str]] = None) -> int:
+    parser = argparse.ArgumentParser(description="Sentinel deployment tools")
+    commands = parser.add_subparsers(dest="command", required=True)
+    commands.add_parser("smoke", help="Smoke-test this release against a copy of the live database")
+    parser.parse_args(argv)
+
+    logging.basicConfig(level=logging.WARNING, format="%(asctime)s - %(name)s - %(levelname)s - %(message)s")
+    source = DATA_DIR / "sentinel.db"
+    with tempfile.TemporaryDirectory(prefix="sentinel-smoke-") as tmp:
+        copy = Path(tmp) / "sentinel.db"
+        if source.exists():
+            copy_database(source, copy)
+        # Every singleton (Settings, Broker, Portfolio, ...) must resolve to the copy
+        Database._default_path = str(copy)
+        result = asyncio.run(_smoke(copy))
+    print(json.dumps(result, indent=2))
+    return 0 if result["ok"] else 1
+
+
+if __name__ == "__main__":
+    raise SystemExit(main())
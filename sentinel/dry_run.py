@@ -0,0 +1,194 @@
+"""
+Dry-run sessions - preview mutations without committing them.
+
+A dry-run session opens a dedicated connection to the same database file, starts a
+write transaction and routes every Database call made from the current task (via
+a context variable) through that connection. Writes are applied so that later
+reads inside the request observe them, recorded as would-be changes, and rolled
+back when the session ends. Code with external side effects (orders, currency
+exchanges) checks is_dry_run() and records what it would have done instead.
+
+Usage:
+    async with dry_run(db) as session:
+        await db.set_setting("trading_mode", "live")
+    session.changes  # [{"table": "settings", "operation": "insert", ...}]
+"""
+
+from __future__ import annotations
+
+import logging
+import re
+from contextlib import asynccontextmanager
+from contextvars import ContextVar
+from typing import Any, AsyncIterator, Optional
+
+import aiosqlite
+
+logger = logging.getLogger(__name__)
+
+_NESTED_SAVEPOINT = "dry_run_nested"
+_WRITE_PATTERN = re.compile(
+    r"^\s*(?:(?P<insert>INSERT(?:\s+OR\s+\w+)?|REPLACE)\s+INTO"
+    r"|(?P<update>UPDATE(?:\s+OR\s+\w+)?)"
+    r"|(?P<delete>DELETE\s+FROM))"
+    r"\s+(?P<table>\w+)",
+    re.IGNORECASE,
+)
+
+_current: ContextVar[Optional["DryRunSession"]] = ContextVar("sentinel_dry_run", default=None)
+
+
+def _describe_write(sql: str) -> Optional[tuple[str, str]]:
+    """Return (operation, table) for a write statement, None for anything else."""
+    match = _WRITE_PATTERN.match(sql)
+    if not match:
+        return None
+    if match.group("insert"):
+        operation = "insert"
+    elif match.group("update"):
+        operation = "update"
+    else:
+        operation = "delete"
+    return operation, match.group("table")
+
+
+class DryRunConnection:
+    """aiosqlite connection proxy that records writes and never commits.
+
+    Nested transactions opened by Database methods (BEGIN/COMMIT/ROLLBACK) are
+    mapped onto a savepoint inside the outer dry-run transaction.
+    """
+
+    def __init__(self, connection: aiosqlite.Connection, session: "DryRunSession"):
+        self._connection = connection
+        self._session = session
+        self._nested = False
+
+    def __getattr__(self, name: str) -> Any:
+        return getattr(self._connection, name)
+
+    async def execute(self, sql: str, parameters: Any = None):
+        statement = sql.strip().rstrip(";").upper()
+        if statement in ("BEGIN", "BEGIN TRANSACTION", "BEGIN IMMEDIATE", "BEGIN DEFERRED"):
+            self._nested = True
+            return await self._connection.execute(f"SAVEPOINT {_NESTED_SAVEPOINT}")
+        if statement in ("ROLLBACK", "ROLLBACK TRANSACTION"):
+            await self.rollback()
+            return await self._connection.execute("SELECT 1")
+        if statement in ("COMMIT", "END", "END TRANSACTION"):
+            await self.commit()
+            return await self._connection.execute("SELECT 1")
+
+        cursor = await self._connection.execute(sql, parameters if parameters is not None else ())
+        self._session._record(sql, [parameters] if parameters is not None else [()], cursor.rowcount)
+        return cursor
+
+    async def executemany(self, sql: str, parameters):
+        rows = [tuple(p) if not isinstance(p, dict) else p for p in parameters]
+        cursor = await self._connection.executemany(sql, rows)
+        self._session._record(sql, rows, cursor.rowcount)
+        return cursor
+
+    async def commit(self) -> None:
+        """Release the nested savepoint; the outer transaction is never committed."""
+        if self._nested:
+            await self._connection.execute(f"RELEASE {_NESTED_SAVEPOINT}")
+            self._nested = False
+
+    async def rollback(self) -> None:
+        """Undo the nested savepoint only, keeping earlier dry-run writes visible."""
+        if self._nested:
+            await self._connection.execute(f"ROLLBACK TO {_NESTED_SAVEPOINT}")
+            await self._connection.execute(f"RELEASE {_NESTED_SAVEPOINT}")
+            self._nested = False
+
+
+class DryRunSession:
+    """Collects the would-be changes and side effects of one dry run."""
+
+    def __init__(self, db):
+        self.db = db
+        self.connection: Optional[DryRunConnection] = None
+        self.changes: list[dict] = []
+        self.side_effects: list[dict] = []
+
+    def _record(self, sql: str, parameter_rows: list, rowcount: int) -> None:
+        described = _describe_write(sql)
+        if described is None:
+            return
+        operation, table = described
+        self.changes.append(
+            {
+                "table": table,
+                "operation": operation,
+                "statement": " ".join(sql.split()),
+                "params": [list(p) if not isinstance(p, dict) else p for p in parameter_rows],
+                "rows_affected": rowcount,
+            }
+        )
+
+    def record_side_effect(self, kind: str, **details: Any) -> None:
+        self.side_effects.append({"kind": kind, **details})
+
+    def summary(self) -> dict:
+        """Would-be changes grouped for API responses."""
+        tables: dict[str, int] = {}
+        for change in self.changes:
+            tables[change["table"]] = tables.get(change["table"], 0) + max(change["rows_affected"], 0)
+        return {
+            "tables": tables,
+            "changes": self.changes,
+            "side_effects": self.side_effects,
+        }
+
+
+def current_dry_run() -> Optional[DryRunSession]:
+    """Active dry-run session for the current task, if any."""
+    return _current.get()
+
+
+def is_dry_run() -> bool:
+    """Whether the current task is running inside a dry-run session."""
+    return _current.get() is not None
+
+
+def record_side_effect(kind: str, **details: Any) -> None:
+    """Record an external action that was skipped because of a dry run."""
+    session = _current.get()
+    if session is not None:
+        session.record_side_effect(kind, **details)
+
+
+@asynccontextmanager
+async def dry_run(db) -> AsyncIterator[DryRunSession]:
+    """Run the enclosed block against db without committing anything.
+
+    Holds the SQLite write lock for the duration of the block, so concurrent
+    writers wait (up to busy_timeout) instead of being rolled back with it.
+
+    Args:
+        db: Connected Database instance
+    """
+    if current_dry_run() is not None:
+        raise RuntimeError("Dry-run sessions cannot be nested")
+
+    session = DryRunSession(db)
+    connection = await aiosqlite.connect(db._path)
+    connection.row_factory = aiosqlite.Row
+    await connection.execute("PRAGMA busy_timeout=30000")
+    # Price reads go through the merged hot/cold view when the cold tier is attached
+    tiering = getattr(db, "price_tiering", None)
+    if tiering is not None and getattr(db, "price_source", "prices") != "prices":
+        await tiering.attach(connection)
+    await connection.execute("BEGIN IMMEDIATE")
+    session.connection = DryRunConnection(connection, session)
+    token = _current.set(session)
+    try:
+        yield session
+    finally:
+        _current.reset(token)
+        try:
+            await connection.rollback()
+        finally:
+            await connection.close()
+        logger.debug(f"Dry run discarded {len(session.changes)} change(s)")
@@ -0,0 +1,148 @@
+"""Domain errors with codes and categories.
+
+Every error raised on purpose by a client, repository or service carries a
+stable code (invalid_input, not_found, rate_limited, ...) and one of three
+categories that decide how it is handled:
+
+- user: the request or configuration is wrong; fixing the input fixes it.
+  Never retried.
+- transient: a dependency is down, slow or rate limiting. Jobs retry it.
+- permanent: a dependency answered with something unusable, or a bug.
+  Retrying will not help.
+
+Each class subclasses the builtin exception its callers already catch
+(InvalidInput is a ValueError, NotFound a LookupError, Unavailable a
+ConnectionError), so existing handlers keep working. The API turns any
+SentinelError into its HTTP status with a JSON body of the code, category,
+message and context; the job runner maps categories to failure classes and so
+to the job_failure_remediation retry rules. classify() gives the same view of
+any other exception.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import sqlite3
+from typing import Any
+
+USER = "user"
+TRANSIENT = "transient"
+PERMANENT = "permanent"
+CATEGORIES = (USER, TRANSIENT, PERMANENT)
+
+# Whether a job failing with an error of the category is worth running again
+RETRYABLE = {USER: False, TRANSIENT: True, PERMANENT: False}
+
+
+class SentinelError(Exception):
+    """Base of the domain errors: a message, a code, a category and context."""
+
+    code = "internal"
+    category = PERMANENT
+    status = 500
+
+    def __init__(self, message: str, code: str | None = None, **context: Any):
+        super().__init__(message)
+        self.message = message
+        if code:
+            self.code = code
+        self.context = context
+
+    def with_context(self, **context: Any) -> SentinelError:
+        """Add context (symbol, job, provider, ...) while the error bubbles up."""
+        self.context.update(context)
+        return self
+
+    @property
+    def retryable(self) -> bool:
+        return RETRYABLE[self.category]
+
+    def as_dict(self) -> dict:
+        return {"code": self.code, "category": self.category, "message": self.message, "context": self.context}
+
+
+class InvalidInput(SentinelError, ValueError):
+    """A request or setting that cannot be accepted."""
+
+    code = "invalid_input"
+    category = USER
+    status = 400
+
+
+class NotConfigured(SentinelError, ValueError):
+    """A feature used before the settings it needs are set."""
+
+    code = "not_configured"
+    category = USER
+    status = 400
+
+
+class NotFound(SentinelError, LookupError):
+    """An entity that does not exist."""
+
+    code = "not_found"
+    category = USER
+    status = 404
+
+
+class Conflict(SentinelError, ValueError):
+    """A request that clashes with the entity's current state."""
+
+    code = "conflict"
+    category = USER
+    status = 409
+
+
+class Unavailable(SentinelError, ConnectionError):
+    """A dependency (broker, data provider, mail server) that cannot be reached right now."""
+
+    code = "unavailable"
+    category = TRANSIENT
+    status = 503
+
+
+class RateLimited(Unavailable):
+    """A dependency throttling our requests."""
+
+    code = "rate_limited"
+    status = 429
+
+
+class UpstreamError(SentinelError, RuntimeError):
+    """A dependency that answered with an error or data that cannot be used."""
+
+    code = "upstream_error"
+    category = PERMANENT
+    status = 502
+
+
+def http_error(error: Exception, source: str) -> SentinelError:
+    """Domain error of a failed HTTP call to an external source (httpx exceptions)."""
+    status = getattr(getattr(error, "response", None), "status_code", None)
+    if status == 429:
+        return RateLimited(f"{source} is rate limiting requests", source=source)
+    if status is not None and status >= 500:
+        return Unavailable(f"{source} returned HTTP {status}", source=source, http_status=status)
+    if status is not None:
+        return UpstreamError(f"{source} returned HTTP {status}", source=source, http_status=status)
+    return Unavailable(f"{source} unreachable: {error}", source=source)
+
+
+def classify(error: BaseException) -> SentinelError:
+    """The domain error of any exception (itself when it already is one)."""
+    if isinstance(error, SentinelError):
+        return error
+    if isinstance(error, (asyncio.TimeoutError, TimeoutError)):
+        wrapped: SentinelError = Unavailable(str(error) or "Timed out", code="timeout")
+    elif isinstance(error, sqlite3.OperationalError) and "locked" in str(error).lower():
+        wrapped = Unavailable(str(error), code="db_locked")
+    elif isinstance(error, (ConnectionError, OSError)):
+        wrapped = Unavailable(str(error))
+    elif isinstance(error, LookupError) and not isinstance(error, KeyError):
+        wrapped = NotFound(str(error))
+    elif isinstance(error, ValueError):
+        wrapped = InvalidInput(str(error))
+    else:
+        wrapped = SentinelError(str(error) or type(error).__name__)
+    wrapped.__cause__ = error
+    return wrapped
@@ -1,7 +1,18 @@
 """APScheduler-based job system."""
 
+from sentinel.jobs.manual import get_run, list_runs
 from sentinel.jobs.market import BrokerMarketChecker, MarketChecker
-from sentinel.jobs.runner import get_status, init, reschedule, run_now, stop
+from sentinel.jobs.params import describe_job_params
+from sentinel.jobs.runner import (
+    get_status,
+    init,
+    is_quarantined,
+    release_quarantine,
+    reschedule,
+    run_now,
+    start_run,
+    stop,
+)
 
 __all__ = [
     "BrokerMarketChecker",
@@ -10,5 +21,11 @@ __all__ = [
     "stop",
     "reschedule",
     "run_now",
+    "start_run",
+    "get_run",
+    "list_runs",
+    "describe_job_params",
     "get_status",
+    "is_quarantined",
+    "release_quarantine",
 ]
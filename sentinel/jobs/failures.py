@@ -0,0 +1,114 @@
+"""Job failure taxonomy and remediation rules.
+
+Failures are classified into a small set of classes so job history shows *why*
+a job failed, and each class can be mapped to a remediation action in the
+`job_failure_remediation` setting:
+
+    {
+        "broker_auth": "refresh_credentials",
+        "network": "retry",
+        "sync:prices": {"data_validation": "quarantine"}
+    }
+
+Top-level keys are failure classes; job-type keys hold per-job overrides.
+
+Domain errors (sentinel.errors) are classified by their code, then by their
+category: transient errors count as network failures (retried by default),
+user errors as data_validation and permanent ones as unknown.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import json
+import sqlite3
+
+from sentinel.errors import PERMANENT, TRANSIENT, SentinelError
+
+FAILURE_CLASSES = ("timeout", "db_locked", "broker_auth", "network", "data_validation", "unknown")
+
+# retry: run the job once more; refresh_credentials: reconnect the broker, then retry;
+# rerun_dependency: run the job that feeds this one, then retry; quarantine: pause
+# scheduled runs until released (manual runs still work and clear the quarantine)
+REMEDIATION_ACTIONS = ("none", "retry", "refresh_credentials", "rerun_dependency", "quarantine")
+
+# Job that produces the inputs of another job, used by rerun_dependency
+JOB_DEPENDENCIES = {
+    "sync:metadata_enrichment": "sync:metadata",
+    "trading:check_markets": "sync:quotes",
+    "trading:execute": "sync:portfolio",
+    "trading:rebalance": "planning:refresh",
+    "trading:balance_fix": "sync:portfolio",
+    "trading:scheduled_orders": "sync:portfolio",
+    "trading:dividend_reinvest": "sync:dividends",
+    "planning:refresh": "sync:prices",
+    "snapshot:backfill": "sync:prices",
+    "aggregate:compute": "sync:prices",
+    "archive:positions": "sync:trades",
+    "reconcile:broker": "sync:trades",
+}
+
+_AUTH_MARKERS = (
+    "unauthorized",
+    "forbidden",
+    "authentication",
+    "not authenticated",
+    "invalid api key",
+    "invalid signature",
+    "credentials",
+    "broker not connected",
+    " 401",
+    " 403",
+)
+_NETWORK_MARKERS = (
+    "connection",
+    "timed out",
+    "timeout",
+    "name resolution",
+    "network",
+    "unreachable",
+    "temporarily unavailable",
+)
+_DB_LOCKED_MARKERS = ("database is locked", "database is busy", "database table is locked")
+
+# Failure class of domain error codes; other codes fall back to their category
+_CODE_CLASSES = {
+    "timeout": "timeout",
+    "db_locked": "db_locked",
+    "broker_auth": "broker_auth",
+    "broker_unavailable": "broker_auth",
+    "upstream_error": "data_validation",
+}
+_CATEGORY_CLASSES = {TRANSIENT: "network", PERMANENT: "unknown"}
+
+
+def classify_failure(error: BaseException) -> str:
+    """Map an exception raised by a job to a failure class."""
+    if isinstance(error, SentinelError):
+        return _CODE_CLASSES.get(error.code) or _CATEGORY_CLASSES.get(error.category, "data_validation")
+    if isinstance(error, asyncio.TimeoutError):
+        return "timeout"
+    message = f" {error}".lower()
+    if isinstance(error, sqlite3.OperationalError) and any(m in message for m in _DB_LOCKED_MARKERS):
+        return "db_locked"
+    if isinstance(error, PermissionError) or any(m in message for m in _AUTH_MARKERS):
+        return "broker_auth"
+    if isinstance(error, (ConnectionError, OSError)) or any(m in message for m in _NETWORK_MARKERS):
+        return "network"
+    if isinstance(error, (ValueError, KeyError, TypeError, json.JSONDecodeError)):
+        return "data_validation"
+    return "unknown"
+
+
+def remediation_for(job_type: str, failure_class: str, rules: dict | None) -> str:
+    """Resolve the remediation action for a failed job (per-job override first)."""
+    rules = rules if isinstance(rules, dict) else {}
+    job_rules = rules.get(job_type)
+    action = job_rules.get(failure_class) if isinstance(job_rules, dict) else None
+    if action is None:
+        action = rules.get(failure_class)
+    if not isinstance(action, str) or action not in REMEDIATION_ACTIONS:
+        return "none"
+    if action == "rerun_dependency" and job_type not in JOB_DEPENDENCIES:
+        return "retry"
+    return action
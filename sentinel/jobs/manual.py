@@ -0,0 +1,182 @@
+"""Manually triggered job runs that can be polled.
+
+POST /api/jobs/{job_type}/runs starts a job in the background and returns a
+run ID; GET /api/jobs/runs/{run_id} reports its status, progress and the log
+records the job emitted. The current run lives in a context variable, so log
+records and report_progress() calls from the job (and tasks it spawns) are
+attributed to it, while other jobs running at the same time are not.
+
+Cancellation is cooperative: request_cancel() flags a run, and jobs that poll
+cancel_requested() stop at their next check and return status "cancelled".
+
+Only the most recent MAX_RUNS runs are kept, in memory.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import logging
+import uuid
+from collections import OrderedDict
+from contextvars import ContextVar
+from dataclasses import dataclass, field
+from datetime import datetime
+from typing import Awaitable, Callable, Optional
+
+MAX_RUNS = 50
+MAX_RUN_LOGS = 500
+
+_current: ContextVar[Optional["ManualRun"]] = ContextVar("sentinel_manual_run", default=None)
+_runs: OrderedDict[str, "ManualRun"] = OrderedDict()
+_tasks: set[asyncio.Task] = set()
+_handler: Optional[logging.Handler] = None
+
+
+@dataclass
+class ManualRun:
+    """One manual run of a job."""
+
+    id: str
+    job_type: str
+    params: dict
+    dry_run: bool
+    status: str = "queued"  # queued, running, completed, skipped, cancelled or failed
+    progress: Optional[dict] = None  # done, total, message
+    result: Optional[dict] = None
+    logs: list[dict] = field(default_factory=list)
+    logs_dropped: int = 0
+    cancel_requested: bool = False
+    created_at: str = field(default_factory=lambda: datetime.now().isoformat(timespec="seconds"))
+    started_at: Optional[str] = None
+    finished_at: Optional[str] = None
+
+    def log(self, record: logging.LogRecord) -> None:
+        if len(self.logs) >= MAX_RUN_LOGS:
+            self.logs.pop(0)
+            self.logs_dropped += 1
+        self.logs.append(
+            {
+                "seq": self.logs_dropped + len(self.logs) + 1,
+                "time": datetime.fromtimestamp(record.created).isoformat(timespec="seconds"),
+                "level": record.levelname,
+                "module": record.name,
+                "message": record.getMessage(),
+            }
+        )
+
+    def as_dict(self, logs_since: Optional[int] = None) -> dict:
+        """API view; logs are included when logs_since is given (entries with seq above it)."""
+        data = {
+            "run_id": self.id,
+            "job_type": self.job_type,
+            "params": self.params,
+            "dry_run": self.dry_run,
+            "status": self.status,
+            "progress": self.progress,
+            "result": self.result,
+            "cancel_requested": self.cancel_requested,
+            "created_at": self.created_at,
+            "started_at": self.started_at,
+            "finished_at": self.finished_at,
+        }
+        if logs_since is not None:
+            data["logs"] = [entry for entry in self.logs if entry["seq"] > logs_since]
+        return data
+
+
+class _RunLogHandler(logging.Handler):
+    """Root handler that copies records emitted inside a manual run onto it."""
+
+    def emit(self, record: logging.LogRecord) -> None:
+        run = _current.get()
+        if run is not None:
+            try:
+                run.log(record)
+            except Exception:
+                self.handleError(record)
+
+
+def report_progress(done: int, total: int, message: str = "") -> None:
+    """Record progress of the manual run in the current task (no-op for scheduled runs)."""
+    run = _current.get()
+    if run is not None:
+        run.progress = {"done": done, "total": total, "message": message}
+
+
+def cancel_requested() -> bool:
+    """Whether cancelling the manual run in the current task was requested (False for scheduled runs)."""
+    run = _current.get()
+    return run is not None and run.cancel_requested
+
+
+def start(
+    job_type: str,
+    params: dict,
+    dry_run: bool,
+    execute: Callable[[], Awaitable[dict]],
+) -> ManualRun:
+    """Run execute() in the background as a manual run of job_type.
+
+    Args:
+        job_type: Job being run
+        params: Validated parameters (for display)
+        dry_run: Whether the run is a dry run (for display)
+        execute: Coroutine function running the job, returning a dict with status
+
+    Returns:
+        The queued run
+    """
+    global _handler
+
+    if _handler is None:
+        _handler = _RunLogHandler(level=logging.INFO)
+        logging.getLogger().addHandler(_handler)
+
+    run = ManualRun(id=uuid.uuid4().hex[:12], job_type=job_type, params=params, dry_run=dry_run)
+    _runs[run.id] = run
+    while len(_runs) > MAX_RUNS:
+        _runs.popitem(last=False)
+
+    async def main() -> None:
+        _current.set(run)
+        run.status = "running"
+        run.started_at = datetime.now().isoformat(timespec="seconds")
+        try:
+            run.result = await execute()
+            run.status = run.result.get("status", "completed")
+        except Exception as e:
+            run.result = {"status": "failed", "error": str(e)}
+            run.status = "failed"
+        finally:
+            run.finished_at = datetime.now().isoformat(timespec="seconds")
+
+    # The task copies the current context, so setting _current inside it stays local to the run
+    task = asyncio.create_task(main())
+    _tasks.add(task)
+    task.add_done_callback(_tasks.discard)
+    return run
+
+
+def get_run(run_id: str) -> Optional[ManualRun]:
+    """A kept run by ID."""
+    return _runs.get(run_id)
+
+
+def list_runs(job_type: Optional[str] = None) -> list[ManualRun]:
+    """Kept runs, newest first."""
+    return [run for run in reversed(_runs.values()) if job_type is None or run.job_type == job_type]
+
+
+def request_cancel(run_id: str) -> Optional[ManualRun]:
+    """Ask a queued or running run to stop; None if the run is unknown.
+
+    Raises:
+        ValueError: The run already finished
+    """
+    run = _runs.get(run_id)
+    if run is None:
+        return None
+    if run.finished_at is not None:
+        raise ValueError(f"Run {run_id} already finished ({run.status})")
+    run.cancel_requested = True
+    return run
@@ -0,0 +1,105 @@
+"""Parameters accepted by manually triggered jobs.
+
+Scheduled runs never pass parameters. A manual run (POST /api/jobs/{job_type}/runs)
+may narrow or tune a job with the keyword arguments declared here; anything
+else is rejected before the job starts:
+
+    sync:prices      {"symbols": ["AAPL.US"], "years": 5}
+    planning:refresh {"min_trade_value": 250}
+
+Every job also accepts dry_run, which is handled by the runner rather than the
+task function (see sentinel.jobs.manual).
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+from datetime import date
+from typing import Any
+
+
+@dataclass(frozen=True)
+class JobParam:
+    """One keyword argument of a task function."""
+
+    kind: str  # symbols, int, number or date
+    description: str
+    min: float | None = None
+    max: float | None = None
+
+
+_SYMBOLS = JobParam("symbols", "Only these symbols (default: every active security)")
+
+JOB_PARAMS: dict[str, dict[str, JobParam]] = {
+    "sync:prices": {
+        "symbols": _SYMBOLS,
+        "years": JobParam("int", "Years of history to request", 1, 20),
+    },
+    "sync:quotes": {"symbols": _SYMBOLS},
+    "sync:metadata": {"symbols": _SYMBOLS},
+    "sync:metadata_enrichment": {"symbols": _SYMBOLS},
+    "sync:dividends": {"start_date": JobParam("date", "First corporate action date (YYYY-MM-DD)")},
+    "planning:refresh": {"min_trade_value": JobParam("number", "Minimum trade value in EUR", 0)},
+}
+
+
+def _check(name: str, spec: JobParam, value: Any) -> tuple[Any, str | None]:
+    if spec.kind == "symbols":
+        if isinstance(value, str):
+            value = [value]
+        if not isinstance(value, list) or not value or not all(isinstance(s, str) and s.strip() for s in value):
+            return None, f"{name} must be a non-empty list of symbols"
+        return [s.strip() for s in value], None
+    if spec.kind == "date":
+        try:
+            return date.fromisoformat(str(value)).isoformat(), None
+        except ValueError:
+            return None, f"{name} must be a date (YYYY-MM-DD)"
+    if isinstance(value, bool) or not isinstance(value, (int, float)):
+        return None, f"{name} must be a number"
+    if spec.kind == "int" and int(value) != value:
+        return None, f"{name} must be a whole number"
+    if spec.min is not None and value < spec.min:
+        return None, f"{name} must be at least {spec.min:g}"
+    if spec.max is not None and value > spec.max:
+        return None, f"{name} must be at most {spec.max:g}"
+    return (int(value) if spec.kind == "int" else float(value)), None
+
+
+def validate_job_params(job_type: str, params: dict | None) -> dict:
+    """Check manual run parameters against JOB_PARAMS.
+
+    Returns:
+        Normalized keyword arguments for the task function
+
+    Raises:
+        ValueError: Listing every invalid or unknown parameter
+    """
+    if not params:
+        return {}
+    if not isinstance(params, dict):
+        raise ValueError("params must be an object")
+    specs = JOB_PARAMS.get(job_type, {})
+    normalized, problems = {}, []
+    for name, value in params.items():
+        spec = specs.get(name)
+        if spec is None:
+            accepted = ", ".join(sorted(specs)) or "none"
+            problems.append(f"{job_type} does not accept {name} (accepted: {accepted})")
+            continue
+        value, problem = _check(name, spec, value)
+        if problem:
+            problems.append(problem)
+        else:
+            normalized[name] = value
+    if problems:
+        raise ValueError("; ".join(problems))
+    return normalized
+
+
+def describe_job_params(job_type: str) -> dict[str, dict]:
+    """Parameters of a job for the API (name -> kind, description, bounds)."""
+    return {
+        name: {"kind": spec.kind, "description": spec.description, "min": spec.min, "max": spec.max}
+        for name, spec in JOB_PARAMS.get(job_type, {}).items()
+    }
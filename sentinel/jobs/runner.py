@@ -12,7 +12,12 @@ from apscheduler.jobstores.memory import MemoryJobStore
 from apscheduler.schedulers.asyncio import AsyncIOScheduler
 from apscheduler.triggers.interval import IntervalTrigger
 
-from sentinel.jobs import tasks
+from sentinel import correlation
+from sentinel.audit import acting_as, record_action
+from sentinel.jobs import manual, tasks
+from sentinel.jobs.failures import JOB_DEPENDENCIES, classify_failure, remediation_for
+from sentinel.jobs.params import validate_job_params
+from sentinel.services.circuit_breaker import BREAKER_JOBS, CircuitBreakerService
 
 logger = logging.getLogger(__name__)
 
@@ -22,6 +27,7 @@ _deps: dict[str, Any] = {}
 _current_job: str | None = None
 _market_check_task: asyncio.Task | None = None
 _startup_catchup_task: asyncio.Task | None = None
+_quarantined: set[str] = set()
 
 # Job timeout in seconds (15 minutes)
 JOB_TIMEOUT = 15 * 60
@@ -29,24 +35,49 @@ JOB_TIMEOUT = 15 * 60
 # How often to check market status and adjust intervals (5 minutes)
 MARKET_CHECK_INTERVAL = 5 * 60
 
+# Pause before a remediation re-runs a failed job
+REMEDIATION_RETRY_DELAY = 5
+
 # Task registry: job_type -> (task_function, list of dependency keys)
 TASK_REGISTRY: dict[str, tuple[Callable, list[str]]] = {
     "sync:portfolio": (tasks.sync_portfolio, ["portfolio"]),
     "sync:prices": (tasks.sync_prices, ["db", "broker", "cache"]),
     "sync:quotes": (tasks.sync_quotes, ["db", "broker"]),
+    "sync:intraday": (tasks.sync_intraday, ["db", "broker"]),
     "sync:metadata": (tasks.sync_metadata, ["db", "broker"]),
+    "sync:metadata_enrichment": (tasks.sync_metadata_enrichment, ["db"]),
     "sync:exchange_rates": (tasks.sync_exchange_rates, []),
     "sync:trades": (tasks.sync_trades, ["db", "broker"]),
-    "sync:cashflows": (tasks.sync_cashflows, ["db", "broker"]),
+    "sync:cashflows": (tasks.sync_cashflows, ["db", "broker", "planner", "portfolio"]),
     "sync:dividends": (tasks.sync_dividends, ["db", "broker"]),
     "snapshot:backfill": (tasks.snapshot_backfill, ["db", "currency"]),
     "aggregate:compute": (tasks.aggregate_compute, ["db"]),
+    "aggregate:correlations": (tasks.aggregate_correlations, ["db"]),
+    "aggregate:regime": (tasks.aggregate_regime, ["db"]),
+    "sync:watchlist": (tasks.sync_watchlist, ["db", "broker"]),
+    "sync:news": (tasks.sync_news, ["db", "broker"]),
     "trading:check_markets": (tasks.trading_check_markets, ["broker", "db", "planner"]),
     "trading:execute": (tasks.trading_execute, ["broker", "db", "planner"]),
     "trading:rebalance": (tasks.trading_rebalance, ["planner"]),
     "trading:balance_fix": (tasks.trading_balance_fix, ["db", "broker"]),
+    "trading:scheduled_orders": (tasks.trading_scheduled_orders, ["broker", "db"]),
+    "trading:dividend_reinvest": (tasks.trading_dividend_reinvest, ["db", "planner"]),
+    "trading:protective_orders": (tasks.trading_protective_orders, ["db", "broker"]),
+    "trading:order_slices": (tasks.trading_order_slices, ["broker", "db"]),
+    "analytics:execution_quality": (tasks.analytics_execution_quality, ["db"]),
     "planning:refresh": (tasks.planning_refresh, ["db", "planner"]),
     "backup:r2": (tasks.backup_r2, ["db"]),
+    "archive:positions": (tasks.archive_positions, ["db"]),
+    "archive:prices": (tasks.archive_prices, ["db"]),
+    "archive:intraday": (tasks.archive_intraday, ["db"]),
+    "archive:retention": (tasks.archive_retention, ["db"]),
+    "archive:vacuum": (tasks.archive_vacuum, ["db"]),
+    "notifications:deliver": (tasks.notifications_deliver, ["db"]),
+    "thesis:check": (tasks.thesis_check, ["db"]),
+    "report:digest": (tasks.report_digest, ["db", "planner"]),
+    "report:email": (tasks.report_email, ["db", "planner"]),
+    "config:drift_check": (tasks.config_drift_check, ["db"]),
+    "reconcile:broker": (tasks.reconcile_broker, ["db", "broker"]),
 }
 
 # Market timing constants (matching database values)
@@ -78,7 +109,7 @@ async def init(
     Returns:
         The running AsyncIOScheduler instance
     """
-    global _scheduler, _deps, _current_job, _market_check_task
+    global _scheduler, _deps, _current_job, _market_check_task, _quarantined
 
     # Store dependencies for task execution
     _deps = {
@@ -110,6 +141,7 @@ async def init(
     # Load schedules from database
     schedules = await db.get_job_schedules()
     schedule_map = {s["job_type"]: s for s in schedules}
+    _quarantined = {s["job_type"] for s in schedules if s.get("quarantined_at")}
 
     # Check if any market is open (for interval selection)
     market_open = market_checker.is_any_market_open()
@@ -202,14 +234,17 @@ async def reschedule(job_type: str, db) -> None:
         logger.error(f"Failed to reschedule {job_type}: {e}")
 
 
-async def run_now(job_type: str) -> dict:
+async def run_now(job_type: str, params: dict | None = None, dry_run: bool = False) -> dict:
     """Execute a task immediately.
 
     Args:
         job_type: The job type to execute
+        params: Validated keyword arguments for the task (see sentinel.jobs.params)
+        dry_run: Run inside a dry-run session; the result then lists the
+            would-be changes instead of committing them, and failures are not remediated
 
     Returns:
-        Dict with status, duration_ms, and optional error
+        Dict with status, duration_ms, and optional error (and dry_run summary)
     """
     if job_type not in TASK_REGISTRY:
         return {"status": "failed", "error": f"Unknown job type: {job_type}", "duration_ms": 0}
@@ -223,16 +258,62 @@ async def run_now(job_type: str) -> dict:
 
     start = datetime.now()
     try:
-        result = await _run_task(job_type, schedule, skip_timing_check=True)
-        duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+        if dry_run:
+            from sentinel.dry_run import dry_run as dry_run_session
 
-        if result and result.get("skipped"):
-            return {"status": "skipped", "reason": result.get("reason", ""), "duration_ms": duration_ms}
+            async with dry_run_session(db) as session:
+                result = await _run_task(job_type, schedule, skip_timing_check=True, remediate=False, params=params)
+            return {**_manual_result(result, start), "dry_run": session.summary()}
 
-        return {"status": "completed", "duration_ms": duration_ms}
+        result = _manual_result(await _run_task(job_type, schedule, skip_timing_check=True, params=params), start)
     except Exception as e:
         duration_ms = int((datetime.now() - start).total_seconds() * 1000)
-        return {"status": "failed", "error": str(e), "duration_ms": duration_ms}
+        result = {"status": "failed", "error": str(e), "duration_ms": duration_ms}
+    if db and not dry_run:
+        outcome = {"completed": "success", "skipped": "rejected"}.get(result["status"], "failed")
+        error = result.get("error") or result.get("reason") or None
+        await record_action(db, "job.run", job_type, {"params": params or {}}, outcome, error)
+    return result
+
+
+def _manual_result(result: dict | None, start: datetime) -> dict:
+    duration_ms = int((datetime.now() - start).total_seconds() * 1000)
+
+    if result and result.get("skipped"):
+        return {"status": "skipped", "reason": result.get("reason", ""), "duration_ms": duration_ms}
+
+    if result and result.get("status") == "failed":
+        return {**result, "duration_ms": duration_ms}
+
+    return {"status": "completed", "duration_ms": duration_ms}
+
+
+def start_run(job_type: str, params: dict | None = None, dry_run: bool = False) -> dict:
+    """Start a manual run in the background and return it (poll with get_run).
+
+    Raises:
+        KeyError: Unknown job type
+        ValueError: Invalid parameters (every problem listed)
+    """
+    if job_type not in TASK_REGISTRY:
+        raise KeyError(f"Unknown job type: {job_type}")
+    params = validate_job_params(job_type, params)
+    run = manual.start(job_type, params, dry_run, lambda: run_now(job_type, params, dry_run))
+    return run.as_dict()
+
+
+async def release_quarantine(job_type: str) -> None:
+    """Let a quarantined job run on its schedule again."""
+    _quarantined.discard(job_type)
+    db = _deps.get("db")
+    if db:
+        await db.set_job_quarantine(job_type, None)
+        await record_action(db, "job.release", job_type)
+
+
+def is_quarantined(job_type: str) -> bool:
+    """Whether scheduled runs of a job are paused by the quarantine remediation."""
+    return job_type in _quarantined
 
 
 async def get_status() -> dict:
@@ -286,6 +367,7 @@ async def get_status() -> dict:
                     {
                         "job_type": job_type,
                         "status": entry["status"],
+                        "failure_class": entry.get("failure_class"),
                         "executed_at": datetime.fromtimestamp(entry["executed_at"]).isoformat(),
                     }
                 )
@@ -344,16 +426,31 @@ def _add_job(job_type: str, schedule: dict, market_open: bool) -> None:
 
 async def _job_executor(job_type: str, schedule: dict) -> None:
     """Executor function that APScheduler calls. Wraps _run_task for proper async handling."""
-    await _run_task(job_type, schedule)
-
-
-async def _run_task(job_type: str, schedule: dict, skip_timing_check: bool = False) -> dict | None:
+    # Each scheduled run (with its remediation re-runs) is one trail in the logs
+    with correlation.bind():
+        await _run_task(job_type, schedule)
+
+
+async def _run_task(
+    job_type: str,
+    schedule: dict,
+    skip_timing_check: bool = False,
+    remediate: bool = True,
+    retry_count: int = 0,
+    params: dict | None = None,
+) -> dict | None:
     """Wrapper that handles market timing, timeout, error handling, DB logging.
 
+    Failures are classified (see sentinel.jobs.failures) and the configured
+    remediation for the failure class runs afterwards.
+
     Args:
         job_type: The job type to execute
         schedule: Schedule configuration
-        skip_timing_check: If True, skip market timing check (for manual runs)
+        skip_timing_check: If True, skip market timing and quarantine checks (for manual runs)
+        remediate: If False, failures are only logged (used for remediation re-runs)
+        retry_count: Recorded in job history for remediation re-runs
+        params: Keyword arguments for the task function (manual runs only)
 
     Returns:
         Dict with result info, or None
@@ -373,6 +470,10 @@ async def _run_task(job_type: str, schedule: dict, skip_timing_check: bool = Fal
             logger.debug(f"Skipping {job_type}: market timing not satisfied")
             return {"skipped": True, "reason": "market_timing"}
 
+        if job_type in _quarantined:
+            logger.debug(f"Skipping {job_type}: quarantined")
+            return {"skipped": True, "reason": "quarantined"}
+
     # Get task function and dependencies
     if job_type not in TASK_REGISTRY:
         logger.error(f"Unknown job type: {job_type}")
@@ -380,6 +481,11 @@ async def _run_task(job_type: str, schedule: dict, skip_timing_check: bool = Fal
 
     task_func, dep_keys = TASK_REGISTRY[job_type]
 
+    # A tripped circuit breaker pauses trading and planning, manual runs included
+    if job_type in BREAKER_JOBS and await _circuit_breaker_tripped():
+        logger.warning(f"Skipping {job_type}: circuit breaker tripped")
+        return {"skipped": True, "reason": "circuit_breaker"}
+
     # Build arguments from dependencies
     args = []
     for key in dep_keys:
@@ -395,43 +501,116 @@ async def _run_task(job_type: str, schedule: dict, skip_timing_check: bool = Fal
     db = _deps.get("db")
 
     try:
-        # Execute with timeout
-        await asyncio.wait_for(task_func(*args), timeout=JOB_TIMEOUT)
+        # Execute with timeout; what the task does is audited as the job's action
+        with acting_as("job", job_type):
+            await asyncio.wait_for(task_func(*args, **(params or {})), timeout=JOB_TIMEOUT)
 
         duration_ms = int((datetime.now() - start).total_seconds() * 1000)
 
         # Log success to DB
         if db:
             await db.mark_job_completed(job_type)
-            await db.log_job_execution(job_type, job_type, "completed", None, duration_ms, 0)
+            await db.log_job_execution(job_type, job_type, "completed", None, duration_ms, retry_count)
+        _quarantined.discard(job_type)
 
         logger.info(f"Job {job_type} completed in {duration_ms}ms")
         return {"status": "completed", "duration_ms": duration_ms}
 
-    except asyncio.TimeoutError:
+    except asyncio.TimeoutError as e:
         duration_ms = int((datetime.now() - start).total_seconds() * 1000)
         error_msg = f"Job {job_type} timed out after {JOB_TIMEOUT}s"
+        failure_class = classify_failure(e)
         logger.error(error_msg)
 
-        if db:
-            await db.mark_job_failed(job_type)
-            await db.log_job_execution(job_type, job_type, "failed", error_msg, duration_ms, 0)
-
-        return {"status": "failed", "error": error_msg, "duration_ms": duration_ms}
-
     except Exception as e:
         duration_ms = int((datetime.now() - start).total_seconds() * 1000)
         error_msg = str(e)
-        logger.error(f"Job {job_type} failed: {error_msg}")
+        failure_class = classify_failure(e)
+        logger.error(f"Job {job_type} failed ({failure_class}): {error_msg}")
 
+    finally:
+        _current_job = None
+
+    if db:
+        await db.mark_job_failed(job_type)
+        await db.log_job_execution(
+            job_type, job_type, "failed", error_msg, duration_ms, retry_count, failure_class=failure_class
+        )
+        await _notify_job_failed(db, job_type, f"{failure_class}: {error_msg}")
+
+    result = {"status": "failed", "error": error_msg, "duration_ms": duration_ms, "failure_class": failure_class}
+    if remediate:
+        result["remediation"] = await _remediate(job_type, schedule, failure_class, error_msg)
+    return result
+
+
+async def _remediate(job_type: str, schedule: dict, failure_class: str, error_msg: str) -> dict:
+    """Run the remediation configured for a failure class (job_failure_remediation setting).
+
+    Returns:
+        Dict with the action taken and, for actions that re-run the job, the re-run status
+    """
+    from sentinel.settings import DEFAULTS
+
+    db = _deps.get("db")
+    rules = await db.get_setting("job_failure_remediation") if db else None
+    if not isinstance(rules, dict):
+        rules = DEFAULTS["job_failure_remediation"]
+    action = remediation_for(job_type, failure_class, rules)
+    if action == "none":
+        return {"action": action}
+    logger.info(f"Remediating {job_type} ({failure_class}) with {action}")
+
+    if action == "quarantine":
+        _quarantined.add(job_type)
         if db:
-            await db.mark_job_failed(job_type)
-            await db.log_job_execution(job_type, job_type, "failed", error_msg, duration_ms, 0)
+            await db.set_job_quarantine(job_type, f"{failure_class}: {error_msg}")
+        return {"action": action}
 
-        return {"status": "failed", "error": error_msg, "duration_ms": duration_ms}
+    if action == "refresh_credentials":
+        reconnect = getattr(_deps.get("broker"), "reconnect", None)
+        if not callable(reconnect) or not await reconnect():
+            logger.warning(f"Remediation for {job_type}: broker reconnect failed")
+            return {"action": action, "status": "failed"}
 
-    finally:
-        _current_job = None
+    if action == "rerun_dependency":
+        dependency = JOB_DEPENDENCIES[job_type]
+        dep_result = await _run_task(dependency, {"job_type": dependency}, skip_timing_check=True, remediate=False)
+        if not dep_result or dep_result.get("status") != "completed":
+            return {"action": action, "dependency": dependency, "status": "failed"}
+
+    await asyncio.sleep(REMEDIATION_RETRY_DELAY)
+    retry = await _run_task(job_type, schedule, skip_timing_check=True, remediate=False, retry_count=1)
+    return {"action": action, "status": (retry or {}).get("status", "skipped")}
+
+
+async def _circuit_breaker_tripped() -> bool:
+    """Run the drawdown check and report whether the breaker is tripped (a failing check never blocks)."""
+    db = _deps.get("db")
+    if db is None:
+        return False
+    try:
+        return await CircuitBreakerService(db=db, currency=_deps.get("currency")).check()
+    except Exception as e:
+        logger.warning(f"Circuit breaker check failed: {e}")
+        return False
+
+
+async def _notify_job_failed(db, job_type: str, error_msg: str) -> None:
+    """Put a job failure in the notification inbox (repeat failures are merged)."""
+    from sentinel.services.notifications import record_notification
+
+    await record_notification(
+        db,
+        "error",
+        "job",
+        f"Job {job_type} failed",
+        event="job_failed",
+        message=error_msg,
+        entity_type="job",
+        entity_id=job_type,
+        dedupe_key=f"job_failed:{job_type}",
+    )
 
 
 async def _startup_catchup() -> None:
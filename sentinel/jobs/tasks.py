@@ -9,11 +9,11 @@ import os
 import tarfile
 import tempfile
 from datetime import datetime, timedelta, timezone
-from pathlib import Path
 
-logger = logging.getLogger(__name__)
+from sentinel.jobs.manual import report_progress
+from sentinel.paths import DATA_DIR
 
-DATA_DIR = Path(__file__).parent.parent.parent / "data"
+logger = logging.getLogger(__name__)
 
 
 # -----------------------------------------------------------------------------
@@ -27,30 +27,44 @@ async def sync_portfolio(portfolio) -> None:
     logger.info("Portfolio sync complete")
 
 
-async def sync_prices(db, broker, cache) -> None:
-    """Sync historical prices for all securities."""
+async def sync_prices(db, broker, cache, symbols: list[str] | None = None, years: int = 20) -> None:
+    """Sync historical prices for all securities (or only the given symbols)."""
     # Clear analysis cache since prices are changing
     cleared = cache.clear()
     logger.info(f"Cleared {cleared} cached analyses before price sync")
 
-    securities = await db.get_all_securities(active_only=True)
-    symbols = [s["symbol"] for s in securities]
+    if await db.get_setting("price_history_source") == "market_data":
+        from sentinel.services.market_data import MarketDataService
 
-    prices = await broker.get_historical_prices_bulk(symbols, years=20)
+        result = await MarketDataService(db=db).backfill(symbols, years=years)
+        logger.info(
+            f"Market data price sync{' (resumed)' if result['resumed'] else ''}: "
+            f"{result['done']}/{result['requested']} securities updated {result['by_provider']}, "
+            f"{result['stale']} stale, {result['failed']} failed, {result['pending']} left pending"
+        )
+        return
+
+    if symbols is None:
+        securities = await db.get_all_securities(active_only=True)
+        symbols = [s["symbol"] for s in securities]
+
+    prices = await broker.get_historical_prices_bulk(symbols, years=years)
     synced = 0
 
-    for symbol, data in prices.items():
+    for done, (symbol, data) in enumerate(prices.items(), 1):
         if data:
             await db.save_prices(symbol, data)
             synced += 1
+        report_progress(done, len(prices), symbol)
 
     logger.info(f"Price sync complete: {synced}/{len(symbols)} securities updated")
 
 
-async def sync_quotes(db, broker) -> None:
-    """Sync quote data for all securities."""
-    securities = await db.get_all_securities(active_only=True)
-    symbols = [s["symbol"] for s in securities]
+async def sync_quotes(db, broker, symbols: list[str] | None = None) -> None:
+    """Sync quote data for all securities (or only the given symbols)."""
+    if symbols is None:
+        securities = await db.get_all_securities(active_only=True)
+        symbols = [s["symbol"] for s in securities]
 
     if not symbols:
         logger.info("No securities to sync quotes for")
@@ -64,20 +78,58 @@ async def sync_quotes(db, broker) -> None:
         logger.warning("No quotes returned from broker")
 
 
-async def sync_metadata(db, broker) -> None:
-    """Sync security metadata from broker."""
+async def sync_intraday(db, broker) -> None:
+    """Snapshot the quotes of held positions (when intraday snapshots are enabled)."""
+    from sentinel.services.intraday import IntradayService
+
+    result = await IntradayService(db=db, broker=broker).snapshot()
+    if not result["enabled"]:
+        logger.debug("Intraday snapshots disabled")
+    else:
+        logger.info(f"Intraday snapshot: {result['saved']} quotes saved")
+
+
+async def sync_metadata(db, broker, symbols: list[str] | None = None) -> None:
+    """Sync security metadata from broker and classify unclassified securities into the GICS taxonomy."""
+    from sentinel.config.gics import classify_gics
+    from sentinel.utils.identity import extract_isin
+
     securities = await db.get_all_securities(active_only=True)
+    if symbols is not None:
+        securities = [s for s in securities if s["symbol"] in symbols]
     synced = 0
+    classified = 0
 
-    for sec in securities:
+    for done, sec in enumerate(securities, 1):
+        report_progress(done, len(securities), sec["symbol"])
         symbol = sec["symbol"]
         info = await broker.get_security_info(symbol)
         if info:
             market_id = str(info.get("mrkt", {}).get("mkt_id", ""))
             await db.update_security_metadata(symbol, info, market_id)
+            isin = extract_isin(info)
+            if isin and isin != sec.get("isin"):
+                await db.set_security_isin(symbol, isin)
             synced += 1
+        if not sec.get("gics_code"):
+            info = info or {}
+            gics_code = classify_gics(info.get("sector"), info.get("industry"), sec.get("industry"))
+            if gics_code:
+                await db.upsert_security(symbol, gics_code=gics_code)
+                classified += 1
+
+    logger.info(f"Metadata sync complete: {synced} securities, {classified} newly classified")
 
-    logger.info(f"Metadata sync complete: {synced} securities")
+
+async def sync_metadata_enrichment(db, symbols: list[str] | None = None) -> None:
+    """Collect industry, country and exchange from every metadata provider and apply them."""
+    from sentinel.services.metadata_enrichment import MetadataEnrichmentService
+
+    result = await MetadataEnrichmentService(db=db).enrich(symbols)
+    logger.info(
+        f"Metadata enrichment complete: {result['applied']} fields applied to {result['securities']} securities, "
+        f"{result['conflicts']} new conflicts, {result['errors']} provider errors"
+    )
 
 
 async def sync_exchange_rates() -> None:
@@ -94,8 +146,11 @@ async def sync_trades(db, broker) -> None:
     Sync trade history from broker.
 
     Fetches all trades from Tradernet since 2020-01-01 and upserts them.
-    Existing trades (by broker_trade_id) are skipped.
+    Existing trades (by broker_trade_id) are skipped. Trades reported under a
+    former ticker are stored under the security's current symbol.
     """
+    from sentinel.services.instrument_ids import InstrumentIdService
+
     if not broker.connected:
         logger.warning("Broker not connected, skipping trades sync")
         return
@@ -109,10 +164,14 @@ async def sync_trades(db, broker) -> None:
 
     new_count = 0
     skipped_count = 0
+    canonical = await InstrumentIdService(db).canonical_symbols(
+        trade.get("symbol", trade.get("instr_nm", "")) for trade in trades
+    )
 
     for trade in trades:
         trade_id = str(trade.get("id", ""))
         symbol = trade.get("symbol", trade.get("instr_nm", ""))
+        symbol = canonical.get(symbol, symbol)
         side = trade.get("side", "BUY")
         quantity = float(trade.get("q", 0))
         price = float(trade.get("p", 0))
@@ -155,12 +214,13 @@ async def sync_trades(db, broker) -> None:
     logger.info(f"Trades sync complete: {new_count} new, {skipped_count} existing")
 
 
-async def sync_cashflows(db, broker) -> None:
+async def sync_cashflows(db, broker, planner=None, portfolio=None) -> None:
     """
     Sync cash flow history (deposits, withdrawals, dividends, taxes) from broker.
 
     Fetches all cash flows from Tradernet since 2020-01-01 and upserts them.
     Existing entries are deduplicated using a content hash of the raw data.
+    New deposits go to the auto-invest policy (see services/auto_invest.py).
     """
     if not broker.connected:
         logger.warning("Broker not connected, skipping cashflows sync")
@@ -175,6 +235,7 @@ async def sync_cashflows(db, broker) -> None:
 
     new_count = 0
     skipped_count = 0
+    new_flows = []
 
     for flow in cash_flows:
         try:
@@ -198,6 +259,9 @@ async def sync_cashflows(db, broker) -> None:
 
             if row_id and row_id > 0:
                 new_count += 1
+                new_flows.append(
+                    {"id": row_id, "date": date, "type_id": type_id, "amount": amount, "currency": currency}
+                )
             else:
                 skipped_count += 1
         except (ValueError, TypeError) as e:
@@ -206,21 +270,48 @@ async def sync_cashflows(db, broker) -> None:
 
     logger.info(f"Cash flows sync complete: {new_count} new, {skipped_count} existing")
 
+    if new_flows and planner is not None:
+        await _auto_invest_deposits(db, broker, planner, portfolio, new_flows)
+
 
-async def sync_dividends(db, broker) -> None:
+async def _auto_invest_deposits(db, broker, planner, portfolio, new_flows: list[dict]) -> None:
+    """Hand new deposits to the auto-invest policy, with cash refreshed from the broker first."""
+    from sentinel.services.auto_invest import DEPOSIT_TYPE, AutoInvestService
+
+    service = AutoInvestService(db=db, portfolio=portfolio)
+    if await service.mode() == "off" or not any(f["type_id"] == DEPOSIT_TYPE for f in new_flows):
+        return
+    if portfolio is not None:
+        # The deposit only shows in the cash balances after a portfolio sync
+        await portfolio.sync()
+
+    async def place(rec, source: str) -> bool:
+        success = await _execute_trade(broker, rec, db, source=source)
+        if success:
+            await _update_strategy_state_after_execution(db, rec)
+        return success
+
+    await service.handle(new_flows, planner, broker, place)
+
+
+async def sync_dividends(db, broker, start_date: str = "2020-01-01") -> None:
     """
     Sync dividend history from broker corporate actions report.
 
-    Fetches all corporate actions, filters to dividends, computes net EUR value,
-    and upserts into the dividends table. Deduplicates by corporate_action_id.
+    Fetches all corporate actions, filters to dividends, computes net EUR value
+    and the withholding tax breakdown, and upserts into the dividends table.
+    Deduplicates by corporate_action_id. Dividends reported under a former
+    ticker are stored under the security's current symbol.
     """
     from sentinel.currency import Currency
+    from sentinel.services.dividends import dividend_country, withholding_breakdown
+    from sentinel.services.instrument_ids import InstrumentIdService
 
     if not broker.connected:
         logger.warning("Broker not connected, skipping dividends sync")
         return
 
-    actions = await broker.get_corporate_actions(start_date="2020-01-01")
+    actions = await broker.get_corporate_actions(start_date=start_date)
 
     if not actions:
         logger.info("No corporate actions returned from broker")
@@ -229,6 +320,9 @@ async def sync_dividends(db, broker) -> None:
     currency_svc = Currency()
     new_count = 0
     skipped_count = 0
+    canonical = await InstrumentIdService(db).canonical_symbols(
+        action.get("ticker", "") for action in actions if action.get("type_id") == "dividend"
+    )
 
     for action in actions:
         try:
@@ -236,7 +330,7 @@ async def sync_dividends(db, broker) -> None:
                 continue
 
             ca_id = action.get("corporate_action_id", "")
-            symbol = action.get("ticker", "")
+            symbol = canonical.get(action.get("ticker", ""), "")
             date = action.get("date", "")
             amount = float(action.get("amount", 0) or 0)
             cur = action.get("currency", "EUR")
@@ -252,6 +346,7 @@ async def sync_dividends(db, broker) -> None:
             else:
                 value_eur = amount
 
+            breakdown = withholding_breakdown(action)
             row_id = await db.upsert_dividend(
                 id=ca_id,
                 symbol=symbol,
@@ -260,10 +355,15 @@ async def sync_dividends(db, broker) -> None:
                 currency=cur,
                 value=value_eur,
                 data=action,
+                gross_amount=breakdown["gross_amount"],
+                withholding_amount=breakdown["withholding_amount"],
+                withholding_rate=breakdown["withholding_rate"],
+                country=await dividend_country(db, symbol, action),
             )
 
             if row_id and row_id > 0:
                 new_count += 1
+                await db.mark_scores_dirty([symbol], "dividends")
             else:
                 skipped_count += 1
 
@@ -291,6 +391,42 @@ async def aggregate_compute(db) -> None:
     logger.info(f"Aggregate computation complete: {result['country']} country, {result['industry']} industry")
 
 
+async def aggregate_correlations(db) -> None:
+    """Recompute the rolling correlation matrix of holdings and buy candidates."""
+    from sentinel.services.correlations import CorrelationService
+
+    result = await CorrelationService(db=db).compute()
+    logger.info(f"Correlation matrix updated: {result['pairs']} pairs across {result['symbols']} securities")
+
+
+async def aggregate_regime(db) -> None:
+    """Classify the market regime of every region from its aggregate index and breadth."""
+    from sentinel.services.regime import RegimeService
+
+    states = await RegimeService(db=db).compute()
+    summary = ", ".join(f"{region}: {s['regime']} ({s['confidence']:.0%})" for region, s in states.items())
+    logger.info(f"Market regimes updated: {summary or 'no regions with price history'}")
+
+
+async def sync_news(db, broker) -> None:
+    """Fetch and classify news headlines of held positions."""
+    from sentinel.services.news import NewsService
+
+    result = await NewsService(db=db, broker=broker).refresh()
+    if not result["enabled"]:
+        logger.debug("News ingestion disabled")
+    else:
+        logger.info(f"News: {result['new_headlines']} new headlines for {result['symbols']} held symbols")
+
+
+async def sync_watchlist(db, broker) -> None:
+    """Refresh prices and signals of watched securities, notifying on score or tag changes."""
+    from sentinel.services.watchlist import WatchlistService
+
+    result = await WatchlistService(db=db, broker=broker).refresh()
+    logger.info(f"Watchlist refreshed: {result['checked']} securities, {len(result['alerts'])} signal changes")
+
+
 # Trading Tasks
 # -----------------------------------------------------------------------------
 
@@ -372,12 +508,37 @@ async def trading_execute(broker, db, planner) -> None:
         logger.info("No trade recommendations")
         return
 
-    # Filter to actionable (open markets only)
-    actionable = [r for r in recommendations if r.symbol in open_symbols]
+    # Filter to actionable (open markets only); a swap needs both legs tradable
+    closed_swaps = {r.swap_group for r in recommendations if r.swap_group and r.symbol not in open_symbols}
+    actionable = [r for r in recommendations if r.symbol in open_symbols and r.swap_group not in closed_swaps]
     if not actionable:
         logger.info("No actionable trades for open markets")
         return
 
+    # Manual approval mode: only recommendations approved in the inbox are placed
+    approvals = None
+    if await settings.get("trading_manual_approval", False):
+        from sentinel.services.approvals import ApprovalService
+
+        approvals = ApprovalService(db=db, settings=settings, planner=planner)
+        actionable = await approvals.approved(actionable)
+        if not actionable:
+            logger.info("No approved trades awaiting execution")
+            return
+
+    # Symbols with a sliced order in progress are left to its remaining child orders
+    slicer = None
+    if await settings.get("order_slicing_enabled", False):
+        from sentinel.planner.swaps import drop_orphaned_swap_legs
+        from sentinel.services.order_slicing import OrderSlicingService
+
+        slicer = OrderSlicingService(db=db, settings=settings)
+        slicing = await slicer.active_symbols()
+        if slicing:
+            logger.info(f"Sliced orders in progress for {', '.join(sorted(slicing))}")
+            # A swap loses both legs when either one is held back
+            actionable = drop_orphaned_swap_legs([r for r in actionable if r.symbol not in slicing])
+
     # Sort by priority (highest first) and execute sells before buys
     sells = sorted([r for r in actionable if r.action == "sell"], key=lambda x: -x.priority)
     buys = sorted([r for r in actionable if r.action == "buy"], key=lambda x: -x.priority)
@@ -385,21 +546,43 @@ async def trading_execute(broker, db, planner) -> None:
     executed = []
     failed = []
 
+    failed_swaps: set[str] = set()
+
+    from sentinel.services.circuit_breaker import CircuitBreakerService
+
+    breaker = CircuitBreakerService(db=db, settings=settings)
+
     # Execute sells first (to free up cash for buys)
     for rec in sells:
-        success = await _execute_trade(broker, rec)
+        if await breaker.is_tripped():
+            logger.warning("Circuit breaker tripped, stopping trade execution")
+            break
+        success = await _execute_or_slice(broker, rec, db, slicer)
         if success:
             executed.append(rec)
             await _update_strategy_state_after_execution(db, rec)
+            if approvals:
+                await approvals.consume(rec)
         else:
             failed.append(rec)
+            if rec.swap_group:
+                failed_swaps.add(rec.swap_group)
 
-    # Then execute buys
+    # Then execute buys (swap buys only once their funding sell went through)
     for rec in buys:
-        success = await _execute_trade(broker, rec)
+        if await breaker.is_tripped():
+            logger.warning("Circuit breaker tripped, stopping trade execution")
+            break
+        if rec.swap_group in failed_swaps:
+            logger.warning(f"Skipping {rec.symbol} buy: funding sell for {rec.swap_group} failed")
+            failed.append(rec)
+            continue
+        success = await _execute_or_slice(broker, rec, db, slicer)
         if success:
             executed.append(rec)
             await _update_strategy_state_after_execution(db, rec)
+            if approvals:
+                await approvals.consume(rec)
         else:
             failed.append(rec)
 
@@ -410,6 +593,76 @@ async def trading_execute(broker, db, planner) -> None:
         logger.warning(f"Failed to execute {len(failed)} trades")
 
 
+async def trading_scheduled_orders(broker, db) -> None:
+    """Place the scheduled orders that are due, through the same order path as trading:execute."""
+    from sentinel.services.scheduled_orders import ScheduledOrderService
+
+    if not broker.connected:
+        logger.warning("Broker not connected, skipping scheduled orders")
+        return
+
+    async def place(rec, source: str) -> bool:
+        success = await _execute_trade(broker, rec, db, source=source)
+        if success:
+            await _update_strategy_state_after_execution(db, rec)
+        return success
+
+    summary = await ScheduledOrderService(db=db).run_due(broker, place)
+    counts = ", ".join(f"{count} {outcome}" for outcome, count in summary.items() if count)
+    if counts:
+        logger.info(f"Scheduled orders: {counts}")
+
+
+async def trading_order_slices(broker, db) -> None:
+    """Place the child orders of sliced large orders that are due, and report each sliced order's progress."""
+    from sentinel.services.order_slicing import OrderSlicingService, progress
+
+    if not broker.connected:
+        logger.warning("Broker not connected, skipping sliced orders")
+        return
+
+    slicer = OrderSlicingService(db=db)
+    if not await slicer.active_symbols():
+        return
+    summary = await slicer.run_due(_slice_placer(broker, db), await _get_open_market_symbols(broker, db))
+    counts = ", ".join(f"{count} {outcome}" for outcome, count in summary.items() if count)
+    if counts:
+        logger.info(f"Sliced orders: {counts}")
+    for order in await slicer.orders("active"):
+        status = progress(order)
+        logger.info(
+            f"Sliced order {order['id']} ({order['action']} {order['symbol']}): "
+            f"{status['placed']}/{status['slices']} slices placed ({status['placed_pct']:.0f}%)"
+        )
+
+
+async def trading_protective_orders(db, broker) -> None:
+    """Place, replace and cancel protective stop orders following the protective order policies."""
+    from sentinel.services.protective_orders import ProtectiveOrderService
+
+    if not broker.connected:
+        logger.warning("Broker not connected, skipping protective orders")
+        return
+
+    summary = await ProtectiveOrderService(db=db, broker=broker).reconcile()
+    if not summary["enabled"]:
+        logger.info("Protective orders disabled")
+        return
+    counts = ", ".join(f"{count} {outcome}" for outcome, count in summary.items() if outcome != "enabled" and count)
+    logger.info(f"Protective orders: {counts or 'nothing to do'}")
+
+
+async def trading_dividend_reinvest(db, planner) -> None:
+    """Decide where new dividends are reinvested, following each security's DRIP mode."""
+    from sentinel.services.drip import DripService
+
+    decisions = await DripService(db=db).decide_pending(planner)
+    if decisions:
+        logger.info(f"Recorded reinvestment decisions for {len(decisions)} dividends")
+    else:
+        logger.info("No new dividends to reinvest")
+
+
 async def trading_rebalance(planner) -> None:
     """Check if portfolio needs rebalancing and generate recommendations."""
     summary = await planner.get_rebalance_summary()
@@ -424,6 +677,19 @@ async def trading_rebalance(planner) -> None:
         logger.info("Portfolio is balanced")
 
 
+async def analytics_execution_quality(db) -> None:
+    """Measure fill slippage and recalibrate the depth limit band when auto-calibration is on."""
+    from sentinel.services.execution_quality import ExecutionQualityService
+
+    result = await ExecutionQualityService(db=db).calibrate()
+    if result["suggested_pct"] is None:
+        logger.info(f"Not enough fills to calibrate the limit band ({result['fills']} with a submission quote)")
+    elif result["applied"]:
+        logger.info(f"Limit band set to {result['suggested_pct']}% (was {result['current_pct']}%)")
+    else:
+        logger.info(f"Suggested limit band {result['suggested_pct']}% (current {result['current_pct']}%)")
+
+
 async def trading_balance_fix(db, broker) -> None:
     """Fix negative currency balances by converting from positive balances.
 
@@ -433,6 +699,7 @@ async def trading_balance_fix(db, broker) -> None:
     """
     from sentinel.currency import Currency
     from sentinel.currency_exchange import CurrencyExchangeService
+    from sentinel.services.notifications import record_notification
 
     if not broker.connected:
         logger.warning("Broker not connected, skipping balance fix")
@@ -521,9 +788,20 @@ async def trading_balance_fix(db, broker) -> None:
 
         if deficit_eur > 0:
             logger.warning(f"Could not fully cover {neg_currency} deficit. Remaining: {deficit_eur:.2f} EUR")
+            await record_notification(
+                db,
+                "warning",
+                "balance",
+                f"Negative {neg_currency} balance not fully covered",
+                event="risk_limit_breached",
+                message=f"Remaining deficit: {deficit_eur:.2f} EUR",
+                entity_type="currency",
+                entity_id=neg_currency,
+                dedupe_key=f"negative_balance:{neg_currency}",
+            )
 
 
-async def planning_refresh(db, planner) -> None:
+async def planning_refresh(db, planner, min_trade_value: float | None = None) -> None:
     """Refresh trading plan by clearing caches and regenerating recommendations."""
     # Clear planner-related caches
     cleared = await db.cache_clear("planner:")
@@ -534,11 +812,24 @@ async def planning_refresh(db, planner) -> None:
     logger.info(f"Recalculated ideal portfolio with {len(ideal)} securities")
 
     # Regenerate recommendations (this will cache the result)
-    recommendations = await planner.get_recommendations()
+    recommendations = await planner.get_recommendations(min_trade_value=min_trade_value)
     buys = [r for r in recommendations if r.action == "buy"]
     sells = [r for r in recommendations if r.action == "sell"]
     logger.info(f"Generated {len(recommendations)} recommendations: {len(buys)} buys, {len(sells)} sells")
 
+    # Keep the run's inputs so the decision can be replayed against future code
+    from sentinel.planner.replay import record_planner_run
+
+    snapshot_id = await record_planner_run(db, planner, recommendations)
+
+    # Active shadow trials replay the same run with their proposed settings
+    if snapshot_id is not None:
+        from sentinel.services.shadow import ShadowService
+
+        shadow = await ShadowService(db=db).run_trials(snapshot_id)
+        if shadow["runs"]:
+            logger.info(f"Ran {shadow['runs']} shadow trial(s) on planner snapshot {snapshot_id}")
+
 
 # -----------------------------------------------------------------------------
 # Backup Tasks
@@ -546,13 +837,20 @@ async def planning_refresh(db, planner) -> None:
 
 
 async def backup_r2(db) -> None:
-    """Backup data folder to Cloudflare R2."""
+    """Backup data folder to Cloudflare R2.
+
+    In a dry run nothing is uploaded or pruned: the upload is recorded as a side effect.
+    """
+    from sentinel.dry_run import is_dry_run, record_side_effect
+    from sentinel.services.notifications import record_notification
     from sentinel.settings import Settings
+    from sentinel.vault import Vault
 
     settings = Settings()
+    vault = Vault(db=db, settings=settings)
     account_id = await settings.get("r2_account_id", "")
-    access_key = await settings.get("r2_access_key", "")
-    secret_key = await settings.get("r2_secret_key", "")
+    access_key = await vault.get_secret("r2_access_key")
+    secret_key = await vault.get_secret("r2_secret_key")
     bucket_name = await settings.get("r2_bucket_name", "")
     retention_days = await settings.get("r2_backup_retention_days", 30)
 
@@ -563,6 +861,9 @@ async def backup_r2(db) -> None:
     # Create tar.gz archive
     timestamp = datetime.now(timezone.utc).strftime("%Y-%m-%d-%H%M%S")
     archive_key = f"backups/sentinel-{timestamp}.tar.gz"
+    if is_dry_run():
+        record_side_effect("backup_upload", bucket=bucket_name, key=archive_key, retention_days=retention_days)
+        return
 
     with tempfile.NamedTemporaryFile(suffix=".tar.gz", delete=False) as tmp:
         tmp_path = tmp.name
@@ -572,6 +873,15 @@ async def backup_r2(db) -> None:
         client = _get_r2_client(account_id, access_key, secret_key)
         _upload_archive(client, bucket_name, archive_key, tmp_path)
         logger.info(f"Backup uploaded: {archive_key}")
+        await record_notification(
+            db,
+            "info",
+            "backup",
+            "Backup completed",
+            event="backup_completed",
+            message=f"Uploaded {archive_key} to {bucket_name}",
+            dedupe_key="backup_completed",
+        )
 
         if retention_days > 0:
             _prune_old_backups(client, bucket_name, retention_days)
@@ -580,24 +890,191 @@ async def backup_r2(db) -> None:
             os.unlink(tmp_path)
 
 
+async def archive_positions(db) -> None:
+    """Move closed round trips older than archive_closed_after_days into the archive tables."""
+    from sentinel.services.archive import ArchiveService
+
+    result = await ArchiveService(db=db).archive_closed_positions()
+    if not result["archived"]:
+        logger.info("No closed positions to archive")
+
+
+async def archive_prices(db) -> None:
+    """Move price bars older than price_hot_days to the cold storage tier, if one is configured."""
+    result = await db.tier_prices()
+    if not result["attached"]:
+        logger.info("No cold price storage attached")
+    elif result["moved"]:
+        logger.info(f"Moved {result['moved']} price bars before {result['cutoff']} to cold storage")
+
+
+async def archive_intraday(db) -> None:
+    """Downsample aged intraday snapshots to hourly and daily bars."""
+    from sentinel.services.intraday import IntradayService
+
+    result = await IntradayService(db=db).compact()
+    if result["raw"] or result["hourly"]:
+        logger.info(f"Folded {result['raw']} raw snapshots and {result['hourly']} hourly bars")
+
+
+async def archive_retention(db) -> None:
+    """Delete rows older than their table's retention policy."""
+    from sentinel.services.retention import RetentionService
+
+    result = await RetentionService(db=db).prune()
+    logger.info(f"Retention pruned {result['deleted']} rows: {result['tables']}")
+
+
+async def archive_vacuum(db) -> None:
+    """Release free database pages and refresh query planner statistics."""
+    from sentinel.services.retention import RetentionService
+
+    result = await RetentionService(db=db).vacuum()
+    for database in result["databases"]:
+        logger.info(
+            f"Vacuumed {database['database']} ({database['mode']}): "
+            f"{database['bytes_before']} -> {database['bytes_after']} bytes"
+        )
+
+
+async def notifications_deliver(db) -> None:
+    """Deliver queued webhook notifications, retrying failures with backoff."""
+    from sentinel.services.webhooks import WebhookService
+
+    result = await WebhookService(db=db).deliver_due()
+    if any(result.values()):
+        logger.info(
+            f"Webhook deliveries: {result['delivered']} delivered, "
+            f"{result['retrying']} retrying, {result['failed']} failed"
+        )
+
+
+async def thesis_check(db) -> None:
+    """Notify on positions at their target price or stop level, and on thesis reviews that are due."""
+    from sentinel.services.position_theses import PositionThesisService
+
+    result = await PositionThesisService(db=db).check()
+    if result["events"]:
+        logger.info(f"Position theses: {len(result['events'])} events for {result['checked']} theses")
+
+
+async def report_digest(db, planner) -> None:
+    """Compile the digest report for the configured period once the previous one is a period old."""
+    from sentinel.services.reports import ReportService
+
+    report = await ReportService(db=db, planner=planner).generate_if_due()
+    if report:
+        logger.info(f"Generated {report['period']} digest report {report['id']}")
+    else:
+        logger.info("Digest report not due yet")
+
+
+async def report_email(db, planner) -> None:
+    """Email the weekly report and last month's reconciliation summary unless already sent."""
+    from sentinel.errors import NotConfigured
+    from sentinel.services.mail import MailService
+
+    try:
+        deliveries = await MailService(db=db, planner=planner).send_due()
+    except NotConfigured as e:
+        logger.info(f"Report emails not configured, skipping: {e}")
+        return
+    for delivery in deliveries:
+        if delivery["status"] == "sent":
+            logger.info(f"Emailed {delivery['subject']} to {len(delivery['recipients'])} recipients")
+        else:
+            logger.warning(f"Failed to email {delivery['subject']}: {delivery['error']}")
+    if not deliveries:
+        logger.info("No report emails due")
+
+
+async def config_drift_check(db) -> None:
+    """Compare the configuration bundle with the paired device and alert on drift."""
+    from sentinel.services.config_drift import ConfigDriftService
+
+    service = ConfigDriftService(db=db)
+    try:
+        state = await service.check()
+    except ValueError:
+        logger.info("No config peer configured, skipping drift check")
+        return
+    if state.get("error"):
+        logger.warning(f"Config drift check against {state.get('peer') or 'peer'} failed: {state['error']}")
+    elif not state["in_sync"]:
+        logger.warning(f"Configuration differs from peer in {len(state['differing_keys'])} settings")
+    else:
+        logger.info("Configuration in sync with peer")
+
+
+async def reconcile_broker(db, broker) -> None:
+    """Reconcile last month's broker report with the ledger unless it already has a run."""
+    from sentinel.services.reconciliation import ReconciliationService
+
+    if not broker.connected:
+        logger.warning("Broker not connected, skipping reconciliation")
+        return
+
+    reconciliation = await ReconciliationService(db=db, broker=broker).reconcile_if_due()
+    if reconciliation is None:
+        logger.info("Last month already reconciled")
+    else:
+        logger.info(
+            f"Reconciled {reconciliation['period']}: {reconciliation['status']}, "
+            f"{len(reconciliation['discrepancies'])} discrepancies"
+        )
+
+
 # -----------------------------------------------------------------------------
 # Helper Functions (for trading)
 # -----------------------------------------------------------------------------
 
 
-async def _execute_trade(broker, rec) -> bool:
-    """Execute a single trade recommendation. Returns True if successful."""
+async def _execute_trade(broker, rec, db=None, source: str = "trading:execute") -> bool:
+    """Execute a single trade recommendation. Returns True if successful.
+
+    When a database is given, the decision behind the order (source job, reason
+    code, sleeve) is persisted so synced trades can be attributed to it, and the
+    outcome feeds the circuit breaker's consecutive-rejection count.
+    """
+    return await _place_trade(broker, rec, db, source) is not None
+
+
+async def _execute_or_slice(broker, rec, db, slicer, source: str = "trading:execute") -> bool:
+    """Execute a trade recommendation, slicing it into child orders first when it is too large to place at once.
+
+    Swap legs are never sliced, so a funding sell completes before its buy.
+    """
+    plan = await slicer.plan(rec) if slicer is not None and not rec.swap_group else None
+    if plan is None:
+        return await _execute_trade(broker, rec, db, source=source)
+    order = await slicer.start(rec, plan, source)
+    summary = await slicer.run_due(_slice_placer(broker, db), {rec.symbol}, order_id=order["id"])
+    return summary["placed"] > 0
+
+
+def _slice_placer(broker, db):
+    """Child order placement for OrderSlicingService.run_due (children skip the same-symbol cool-off)."""
+
+    async def place(rec, source: str) -> str | None:
+        return await _place_trade(broker, rec, db, source, check_cooloff=False)
+
+    return place
+
+
+async def _place_trade(broker, rec, db, source: str, check_cooloff: bool = True) -> str | None:
+    """Place the order of a trade recommendation (see _execute_trade). Returns the order ID, None on failure."""
     from sentinel.security import Security
+    from sentinel.services.notifications import record_notification
 
     try:
         security = Security(rec.symbol)
         await security.load()
 
         if rec.action == "sell":
-            order_id = await security.sell(rec.quantity)
+            order_id = await security.sell(rec.quantity, check_cooloff=check_cooloff)
             action_str = "SELL"
         else:
-            order_id = await security.buy(rec.quantity)
+            order_id = await security.buy(rec.quantity, check_cooloff=check_cooloff)
             action_str = "BUY"
 
         if order_id:
@@ -605,14 +1082,88 @@ async def _execute_trade(broker, rec) -> bool:
                 f"Executed {action_str}: {rec.quantity} x {rec.symbol} "
                 f"@ {rec.price:.2f} {rec.currency} (order: {order_id})"
             )
-            return True
+            await _record_trade_decision(db, rec, order_id, source, getattr(security, "last_pricing", None))
+            await record_notification(
+                db,
+                "info",
+                "trade",
+                f"{action_str} {rec.quantity} x {rec.symbol}",
+                event="trade_executed",
+                message=f"@ {rec.price:.2f} {rec.currency} (order: {order_id})",
+                entity_type="security",
+                entity_id=rec.symbol,
+            )
+            await _record_order_outcome(db, True)
+            return str(order_id)
         else:
             logger.error(f"Failed to {action_str} {rec.symbol}: no order ID returned")
-            return False
+            await _notify_trade_failed(db, rec, "no order ID returned")
+            await _record_order_outcome(db, False)
+            return None
 
     except Exception as e:
         logger.error(f"Failed to execute {rec.action} {rec.symbol}: {e}")
-        return False
+        await _notify_trade_failed(db, rec, str(e))
+        await _record_order_outcome(db, False)
+        return None
+
+
+async def _record_order_outcome(db, accepted: bool) -> None:
+    """Feed an order outcome to the circuit breaker. Never raises."""
+    if db is None:
+        return
+    from sentinel.services.circuit_breaker import CircuitBreakerService
+
+    try:
+        await CircuitBreakerService(db=db).record_order_result(accepted)
+    except Exception as e:
+        logger.warning(f"Failed to record order outcome for circuit breaker: {e}")
+
+
+async def _notify_trade_failed(db, rec, error_msg: str) -> None:
+    """Put a failed order in the notification inbox."""
+    from sentinel.services.notifications import record_notification
+
+    await record_notification(
+        db,
+        "error",
+        "trade",
+        f"Failed to {rec.action} {rec.symbol}",
+        event="order_rejected",
+        message=error_msg,
+        entity_type="security",
+        entity_id=rec.symbol,
+        dedupe_key=f"trade_failed:{rec.action}:{rec.symbol}",
+    )
+
+
+async def _record_trade_decision(db, rec, order_id, source: str, pricing: dict | None = None) -> None:
+    """Persist which job/rule produced a submitted order and how it was priced. Never fails the trade."""
+    recorder = getattr(db, "record_trade_decision", None)
+    if not callable(recorder):
+        return
+    pricing = pricing if isinstance(pricing, dict) else {}
+    try:
+        result = recorder(
+            rec.symbol,
+            rec.action,
+            rec.quantity,
+            source,
+            order_id=order_id,
+            price=rec.price,
+            currency=rec.currency,
+            reason_code=rec.reason_code,
+            sleeve=rec.sleeve,
+            dominant_component=getattr(rec, "dominant_component", None),
+            score_components=getattr(rec, "score_components", None),
+            pricing_method=pricing.get("method"),
+            limit_price=pricing.get("limit_price"),
+            quote_price=pricing.get("quote_price"),
+        )
+        if inspect.isawaitable(result):
+            await result
+    except Exception as e:
+        logger.warning(f"Failed to record trade decision for {rec.symbol}: {e}")
 
 
 async def _update_strategy_state_after_execution(db, rec) -> None:
@@ -730,13 +1281,21 @@ def _get_r2_client(account_id: str, access_key: str, secret_key: str):
     )
 
 
+def _exclude_vault_keys(info: tarfile.TarInfo) -> tarfile.TarInfo | None:
+    """Keep vault key material (and staged rekey files) out of backups stored next to the encrypted secrets."""
+    name = os.path.basename(info.name)
+    if name.startswith("vault.key") or name.endswith(".pending"):
+        return None
+    return info
+
+
 def _create_archive(dest_path: str) -> None:
     """Create a tar.gz archive of the data directory."""
     if not DATA_DIR.exists():
         raise FileNotFoundError(f"Data directory not found: {DATA_DIR}")
 
     with tarfile.open(dest_path, "w:gz") as tar:
-        tar.add(str(DATA_DIR), arcname="data")
+        tar.add(str(DATA_DIR), arcname="data", filter=_exclude_vault_keys)
 
     size_mb = os.path.getsize(dest_path) / (1024 * 1024)
     logger.info(f"Archive created: {size_mb:.1f} MB")
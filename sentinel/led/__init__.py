@@ -9,5 +9,7 @@ Usage:
 """
 
 from sentinel.led.controller import LEDController
+from sentinel.led.display import StateManager
+from sentinel.led.relay import TradingRelay
 
-__all__ = ["LEDController"]
+__all__ = ["LEDController", "StateManager", "TradingRelay"]
@@ -25,6 +25,7 @@ class LEDController:
     """
 
     SYNC_INTERVAL = 300  # Refetch recommendations every 5 minutes
+    SETTINGS_KEYS = ("led_display_enabled",)  # Read by start(); the app restarts the loop on change
 
     def __init__(self):
         self._planner = Planner()
@@ -33,7 +34,6 @@ class LEDController:
         self._trades: list[Trade] = []
         self._running = False
         self._task: Optional[asyncio.Task] = None
-
     async def start(self) -> None:
         """Start the LED controller.
 
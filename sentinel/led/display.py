@@ -0,0 +1,508 @@
+"""
+Display configuration for the indicator LEDs.
+
+Column 0 of the NeoPixel shield holds five indicator LEDs (0 = top). Instead
+of a fixed meaning per LED, each system state is mapped to an LED, a color,
+a blink pattern and a priority. The `led_indicator_map` setting overrides
+DEFAULT_INDICATOR_MAP per state (an entry of null unmaps the state). When
+several active states share an LED, the highest priority wins.
+
+States:
+    heartbeat: always active (the MCU blanks the indicators when updates stop)
+    pnl_up / pnl_up_strong: portfolio return above 0% / above 10%
+    pnl_down / pnl_down_strong: portfolio return below 0% / below -10%
+    recommendations: the planner has pending trades
+    pending_approval: manual approval is on and trades wait in the inbox
+    risk_breach: an unread notification in a risk category
+    sync_failure: the last run of a sync job failed
+    market_open: any market with securities in the universe is open
+    not_ready: the readiness check fails (database, migrations or broker)
+    degraded: ready, but scheduled jobs are quarantined or failing
+
+Summary screen: an optional I2C OLED (SSD1306/SH1106 through luma.oled) or
+Waveshare e-ink HAT shows portfolio value, day change and the last trade. The
+drivers implement DisplayDriver; StateManager.start() redraws the screen when
+display_screen_enabled is set. luma.oled, waveshare_epd and Pillow are only
+imported when a driver is opened.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import importlib
+import logging
+import time
+from typing import Any, Optional
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+INDICATOR_LEDS = 5
+
+DISPLAY_STATES = (
+    "heartbeat",
+    "pnl_up",
+    "pnl_up_strong",
+    "pnl_down",
+    "pnl_down_strong",
+    "recommendations",
+    "pending_approval",
+    "risk_breach",
+    "sync_failure",
+    "market_open",
+    "not_ready",
+    "degraded",
+)
+
+# Pattern name -> code understood by the MCU sketch
+PATTERNS = {"off": 0, "solid": 1, "blink": 2, "fast_blink": 3, "heartbeat": 4}
+
+COLORS = {
+    "red": 0xFF0000,
+    "green": 0x00FF00,
+    "blue": 0x0000FF,
+    "amber": 0xFFAA00,
+    "orange": 0xFF5500,
+    "yellow": 0xFFFF00,
+    "cyan": 0x00FFFF,
+    "purple": 0xAA00FF,
+    "white": 0xFFFFFF,
+}
+
+# Matches the layout the sketch hard-coded before indicators were configurable
+DEFAULT_INDICATOR_MAP: dict[str, dict | None] = {
+    "heartbeat": {"led": 0, "color": "red", "pattern": "heartbeat", "priority": 0},
+    "sync_failure": {"led": 0, "color": "amber", "pattern": "fast_blink", "priority": 50},
+    "pnl_up_strong": {"led": 1, "color": "green", "pattern": "blink", "priority": 10},
+    "pnl_up": {"led": 2, "color": "green", "pattern": "blink", "priority": 10},
+    "pnl_down": {"led": 2, "color": "red", "pattern": "blink", "priority": 10},
+    "pnl_down_strong": {"led": 3, "color": "red", "pattern": "blink", "priority": 10},
+    "risk_breach": {"led": 3, "color": "red", "pattern": "solid", "priority": 60},
+    "recommendations": {"led": 4, "color": "blue", "pattern": "fast_blink", "priority": 10},
+    "pending_approval": {"led": 4, "color": "orange", "pattern": "fast_blink", "priority": 40},
+    "market_open": None,
+    "not_ready": {"led": 0, "color": "red", "pattern": "fast_blink", "priority": 70},
+    "degraded": {"led": 0, "color": "amber", "pattern": "blink", "priority": 45},
+}
+
+# Notification categories that count as a risk breach
+RISK_CATEGORIES = ("balance", "risk")
+PNL_STRONG_PCT = 10.0
+
+
+def _parse_color(value: Any) -> int:
+    if isinstance(value, str):
+        name = value.strip().lower()
+        if name in COLORS:
+            return COLORS[name]
+        if name.startswith("#") and len(name) == 7:
+            try:
+                return int(name[1:], 16)
+            except ValueError:
+                pass
+    raise ValueError(f"Unknown color: {value!r} (use a name from COLORS or #rrggbb)")
+
+
+def parse_indicator_map(overrides: dict | None) -> dict[str, dict | None]:
+    """Merge overrides into DEFAULT_INDICATOR_MAP and validate the result.
+
+    Each override is merged field by field into the state's default entry, so
+    {"market_open": {"led": 1, "color": "cyan"}} only needs what differs.
+
+    Raises:
+        ValueError: Unknown state, LED out of range, unknown color or pattern
+    """
+    if overrides is None:
+        overrides = {}
+    if not isinstance(overrides, dict):
+        raise ValueError("Indicator map must be an object of state -> mapping")
+    unknown = sorted(set(overrides) - set(DISPLAY_STATES))
+    if unknown:
+        raise ValueError(f"Unknown display state(s): {', '.join(unknown)}")
+
+    resolved: dict[str, dict | None] = {}
+    for state in DISPLAY_STATES:
+        default = DEFAULT_INDICATOR_MAP.get(state)
+        if state in overrides and overrides[state] is None:
+            resolved[state] = None
+            continue
+        entry = {**(default or {"pattern": "solid", "priority": 0}), **(overrides.get(state) or {})}
+        if "led" not in entry:
+            resolved[state] = None
+            continue
+        led = entry["led"]
+        if not isinstance(led, int) or isinstance(led, bool) or not 0 <= led < INDICATOR_LEDS:
+            raise ValueError(f"{state}: led must be 0-{INDICATOR_LEDS - 1}")
+        if entry.get("pattern") not in PATTERNS:
+            raise ValueError(f"{state}: unknown pattern {entry.get('pattern')!r}")
+        resolved[state] = {
+            "led": led,
+            "color": _parse_color(entry.get("color", "white")),
+            "pattern": entry["pattern"],
+            "priority": int(entry.get("priority", 0)),
+        }
+    return resolved
+
+
+def resolve_indicators(active: set[str], mapping: dict[str, dict | None]) -> list[dict]:
+    """Winning state per indicator LED (highest priority, then DISPLAY_STATES order)."""
+    leds: list[dict] = [
+        {"led": i, "state": None, "color": 0, "pattern": "off", "code": PATTERNS["off"]} for i in range(INDICATOR_LEDS)
+    ]
+    best: dict[int, int] = {}
+    for state in DISPLAY_STATES:
+        entry = mapping.get(state)
+        if state not in active or entry is None:
+            continue
+        led = entry["led"]
+        if led in best and best[led] >= entry["priority"]:
+            continue
+        best[led] = entry["priority"]
+        leds[led] = {
+            "led": led,
+            "state": state,
+            "color": entry["color"],
+            "pattern": entry["pattern"],
+            "code": PATTERNS[entry["pattern"]],
+        }
+    return leds
+
+
+SCREEN_DRIVERS = ("oled", "eink")
+
+
+def _format_eur(value: float) -> str:
+    return f"EUR {value:,.0f}" if abs(value) >= 10000 else f"EUR {value:,.2f}"
+
+
+def summary_lines(summary: dict) -> list[str]:
+    """Text lines for the summary screen: value, day change and last action."""
+    lines = [_format_eur(summary.get("value_eur") or 0.0)]
+    change = summary.get("day_change_eur")
+    if change is None:
+        lines.append("Day: n/a")
+    else:
+        pct = summary.get("day_change_pct") or 0.0
+        lines.append(f"Day: {change:+,.2f} ({pct:+.2f}%)")
+    lines.append(summary.get("last_action") or "No trades yet")
+    return lines
+
+
+class DisplayDriver:
+    """A small monochrome screen. Subclasses open the device and push 1-bit images."""
+
+    width = 128
+    height = 64
+
+    def open(self) -> None:
+        """Initialize the device (called once before the first show)."""
+
+    def show(self, lines: list[str]) -> None:
+        """Draw text lines, top to bottom, replacing the previous content."""
+        self._push(self.render(lines))
+
+    def close(self) -> None:
+        """Blank or power down the device."""
+
+    def render(self, lines: list[str]):
+        """Render lines into a 1-bit Pillow image of the screen size."""
+        from PIL import Image, ImageDraw, ImageFont  # type: ignore[import-not-found]
+
+        image = Image.new("1", (self.width, self.height), 0)
+        draw = ImageDraw.Draw(image)
+        font = ImageFont.load_default()
+        row = self.height // max(len(lines), 1)
+        for i, line in enumerate(lines):
+            draw.text((0, i * row), line, fill=1, font=font)
+        return image
+
+    def _push(self, image) -> None:
+        raise NotImplementedError
+
+
+class OledDriver(DisplayDriver):
+    """SSD1306 / SH1106 OLED on I2C through luma.oled."""
+
+    def __init__(self, model: str = "ssd1306", port: int = 1, address: int = 0x3C):
+        self._model = model
+        self._port = port
+        self._address = address
+        self._device = None
+
+    def open(self) -> None:
+        from luma.core.interface.serial import i2c  # type: ignore[import-not-found]
+
+        device_class = getattr(importlib.import_module("luma.oled.device"), self._model)
+        self._device = device_class(i2c(port=self._port, address=self._address))
+        self.width, self.height = self._device.width, self._device.height
+
+    def _push(self, image) -> None:
+        if self._device is not None:
+            self._device.display(image.convert(self._device.mode))
+
+    def close(self) -> None:
+        if self._device is not None:
+            self._device.clear()
+            self._device = None
+
+
+class EinkDriver(DisplayDriver):
+    """Waveshare e-ink HAT through its waveshare_epd module (e.g. epd2in13_V3), in landscape."""
+
+    def __init__(self, model: str = "epd2in13_V3"):
+        self._model = model
+        self._epd = None
+
+    def open(self) -> None:
+        self._epd = importlib.import_module(f"waveshare_epd.{self._model}").EPD()
+        self._epd.init()
+        self._epd.Clear(0xFF)
+        self.width, self.height = self._epd.height, self._epd.width
+
+    def render(self, lines: list[str]):
+        # E-ink draws black on white
+        from PIL import ImageOps  # type: ignore[import-not-found]
+
+        return ImageOps.invert(super().render(lines).convert("L")).convert("1")
+
+    def _push(self, image) -> None:
+        if self._epd is not None:
+            self._epd.display(self._epd.getbuffer(image))
+
+    def close(self) -> None:
+        if self._epd is not None:
+            self._epd.sleep()
+            self._epd = None
+
+
+def open_screen_driver(kind: str, model: str | None = None, i2c_port: int = 1, i2c_address: int = 0x3C):
+    """Create and open a summary screen driver.
+
+    Raises:
+        ValueError: Unknown driver kind
+        ImportError: The driver's library is not installed
+    """
+    if kind == "oled":
+        driver: DisplayDriver = OledDriver(model or "ssd1306", port=i2c_port, address=i2c_address)
+    elif kind == "eink":
+        driver = EinkDriver(model or "epd2in13_V3")
+    else:
+        raise ValueError(f"Unknown screen driver: {kind!r} (use one of {', '.join(SCREEN_DRIVERS)})")
+    driver.open()
+    return driver
+
+
+class StateManager:
+    """Evaluates system states and resolves them onto the indicator LEDs."""
+
+    # Read by start() when it opens the summary screen; the app restarts the loop on change
+    SCREEN_SETTINGS_KEYS = (
+        "display_screen_enabled",
+        "display_screen_driver",
+        "display_screen_model",
+        "display_screen_i2c_port",
+        "display_screen_i2c_address",
+    )
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        planner=None,
+        market_checker=None,
+        screen: DisplayDriver | None = None,
+        health=None,
+    ):
+        """Initialize with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+            market_checker: MarketChecker for market_open (state is off if None)
+            screen: Summary screen driver (opened from settings by start() if None)
+            health: HealthService for not_ready/degraded (states are off if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._planner = planner
+        self._market_checker = market_checker
+        self._screen = screen
+        self._health = health
+        self._screen_lines: Optional[list[str]] = None
+        self._running = False
+
+    async def start(self) -> None:
+        """Redraw the summary screen periodically (no-op unless display_screen_enabled)."""
+        if not await self._settings.get("display_screen_enabled", False):
+            logger.info("Summary screen disabled by setting")
+            return
+        opened = self._screen is None
+        if opened:
+            try:
+                self._screen = open_screen_driver(
+                    await self._settings.get("display_screen_driver", "oled"),
+                    await self._settings.get("display_screen_model"),
+                    i2c_port=int(await self._settings.get("display_screen_i2c_port", 1)),
+                    i2c_address=int(await self._settings.get("display_screen_i2c_address", 0x3C)),
+                )
+            except Exception as e:
+                logger.warning(f"Summary screen unavailable: {e}")
+                return
+
+        logger.info("Summary screen starting")
+        self._running = True
+        try:
+            while self._running:
+                try:
+                    await self.refresh_screen()
+                except Exception as e:
+                    logger.error(f"Error refreshing summary screen: {e}")
+                interval = await self._settings.get("display_screen_refresh_seconds", 60)
+                await asyncio.sleep(max(int(interval), 5))
+        finally:
+            try:
+                self._screen.close()
+            except Exception as e:
+                logger.warning(f"Failed to close summary screen: {e}")
+            # Reopened from the (possibly changed) settings on the next start()
+            if opened:
+                self._screen = None
+
+    def stop(self) -> None:
+        """Stop the screen loop; the screen is blanked on exit."""
+        self._running = False
+
+    @property
+    def is_running(self) -> bool:
+        """Check if the screen loop is running."""
+        return self._running
+
+    async def refresh_screen(self) -> list[str]:
+        """Redraw the summary screen if its content changed (e-ink refreshes are slow)."""
+        lines = summary_lines(await self.screen_summary())
+        if self._screen is not None and lines != self._screen_lines:
+            self._screen.show(lines)
+            self._screen_lines = lines
+        return lines
+
+    async def screen_summary(self) -> dict:
+        """Portfolio value, change since the last snapshot before today, and the last trade."""
+        from sentinel.services.portfolio import PortfolioService
+
+        state = await PortfolioService(db=self._db).get_portfolio_state()
+        value = float(state.get("total_value_eur", 0) or 0)
+
+        previous = None
+        today_start = int(time.time()) // 86400 * 86400
+        for snap in await self._db.get_portfolio_snapshots(days=7):
+            if snap["date"] < today_start:
+                data = snap["data"]
+                positions = data.get("positions", {})
+                previous = sum(p.get("value_eur", 0) for p in positions.values()) + (data.get("cash_eur", 0.0) or 0.0)
+        change = value - previous if previous else None
+
+        last_action = None
+        trades = await self._db.get_trades(limit=1)
+        if trades:
+            trade = trades[0]
+            last_action = f"{str(trade['side']).upper()} {float(trade['quantity']):g} {trade['symbol']}"
+
+        return {
+            "value_eur": round(value, 2),
+            "day_change_eur": round(change, 2) if change is not None else None,
+            "day_change_pct": round(change / previous * 100, 2) if change is not None and previous else None,
+            "last_action": last_action,
+        }
+
+    async def indicator_map(self) -> dict[str, dict | None]:
+        """Effective mapping; falls back to the defaults if the setting is invalid."""
+        try:
+            return parse_indicator_map(await self._settings.get("led_indicator_map", {}))
+        except ValueError as e:
+            logger.warning(f"Invalid led_indicator_map, using defaults: {e}")
+            return parse_indicator_map({})
+
+    async def active_states(self) -> set[str]:
+        """States that currently hold. A state whose source fails counts as inactive."""
+        active = {"heartbeat"}
+        checks = {
+            "pnl": self._pnl_states,
+            "recommendations": self._recommendation_states,
+            "risk_breach": self._risk_breach,
+            "sync_failure": self._sync_failure,
+            "market_open": self._market_open,
+            "health": self._health_states,
+        }
+        for name, check in checks.items():
+            try:
+                active |= await check()
+            except Exception as e:
+                logger.warning(f"LED state check '{name}' failed: {e}")
+        return active
+
+    async def frame(self) -> dict:
+        """Active states and the resolved indicator LEDs."""
+        active = await self.active_states()
+        leds = resolve_indicators(active, await self.indicator_map())
+        return {"active": [s for s in DISPLAY_STATES if s in active], "leds": leds}
+
+    async def _pnl_states(self) -> set[str]:
+        from sentinel.services.portfolio import PortfolioService
+
+        state = await PortfolioService(db=self._db).get_portfolio_state()
+        pnl = float(state.get("portfolio_return_pct", 0) or 0)
+        states = set()
+        if pnl > 0:
+            states.add("pnl_up")
+            if pnl > PNL_STRONG_PCT:
+                states.add("pnl_up_strong")
+        elif pnl < 0:
+            states.add("pnl_down")
+            if pnl < -PNL_STRONG_PCT:
+                states.add("pnl_down_strong")
+        return states
+
+    async def _recommendation_states(self) -> set[str]:
+        from sentinel.services.approvals import ApprovalService
+
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db)
+        if not await self._planner.get_recommendations():
+            return set()
+        states = {"recommendations"}
+        approvals = ApprovalService(db=self._db, settings=self._settings, planner=self._planner)
+        if await approvals.enabled() and (await approvals.inbox())["pending"] > 0:
+            states.add("pending_approval")
+        return states
+
+    async def _risk_breach(self) -> set[str]:
+        from sentinel.services.circuit_breaker import CircuitBreakerService
+
+        unread = await self._db.get_notifications(unread_only=True, limit=100)
+        if any(n.get("category") in RISK_CATEGORIES for n in unread):
+            return {"risk_breach"}
+        # A tripped circuit breaker stays lit after its notification is read
+        tripped = await CircuitBreakerService(db=self._db, settings=self._settings).is_tripped()
+        return {"risk_breach"} if tripped else set()
+
+    async def _sync_failure(self) -> set[str]:
+        latest: dict[str, str] = {}
+        for run in await self._db.get_job_history_for_type("sync:", limit=50):
+            latest.setdefault(run["job_type"], run["status"])
+        return {"sync_failure"} if any(status == "failed" for status in latest.values()) else set()
+
+    async def _market_open(self) -> set[str]:
+        if self._market_checker is None:
+            return set()
+        await self._market_checker.ensure_fresh()
+        return {"market_open"} if self._market_checker.is_any_market_open() else set()
+
+    async def _health_states(self) -> set[str]:
+        if self._health is None:
+            return set()
+        return await self._health.display_states()
@@ -0,0 +1,248 @@
+"""GPIO relay for a physical "trading enabled" indicator and kill switch.
+
+Drives an output pin that is asserted while autonomous trading is live and
+healthy, and de-asserted when trading is paused (research mode) or in a failure
+state (broker disconnected, or the trading job failing / quarantined). An
+optional input pin acts as a kill switch: each press toggles trading_mode
+between 'live' and 'research'.
+
+Pins are Linux GPIO lines, driven through the gpiod package (libgpiod v2) when
+installed and the sysfs interface otherwise.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import logging
+from pathlib import Path
+from typing import Optional
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+SYSFS_ROOT = Path("/sys/class/gpio")
+
+
+def trading_indicator_state(trading_mode: str, broker_connected: bool, schedule: dict | None) -> tuple[str, str]:
+    """Classify trading as 'on', 'paused' or 'failure', with a reason."""
+    if trading_mode != "live":
+        return "paused", f"trading mode is {trading_mode}"
+    if not broker_connected:
+        return "failure", "broker not connected"
+    if schedule and schedule.get("quarantined_at"):
+        return "failure", f"trading job quarantined: {schedule.get('quarantine_reason') or 'unknown'}"
+    if schedule and (schedule.get("consecutive_failures") or 0) > 0:
+        return "failure", f"trading job failed {schedule['consecutive_failures']} time(s)"
+    return "on", "live trading enabled"
+
+
+class SysfsPin:
+    """A GPIO line through /sys/class/gpio."""
+
+    def __init__(self, number: int, output: bool, root: Path = SYSFS_ROOT):
+        self._path = root / f"gpio{number}"
+        if not self._path.exists():
+            (root / "export").write_text(str(number))
+        (self._path / "direction").write_text("out" if output else "in")
+
+    def write(self, value: bool) -> None:
+        (self._path / "value").write_text("1" if value else "0")
+
+    def read(self) -> bool:
+        return (self._path / "value").read_text().strip() == "1"
+
+    def close(self) -> None:
+        pass
+
+
+class GpiodPin:
+    """A GPIO line through libgpiod (gpiod >= 2.0)."""
+
+    def __init__(self, chip: str, line: int, output: bool):
+        import gpiod  # type: ignore[import-not-found]
+        from gpiod.line import Direction, Value  # type: ignore[import-not-found]
+
+        self._line = line
+        self._value = Value
+        direction = Direction.OUTPUT if output else Direction.INPUT
+        self._request = gpiod.request_lines(
+            chip, consumer="sentinel", config={line: gpiod.LineSettings(direction=direction)}
+        )
+
+    def write(self, value: bool) -> None:
+        self._request.set_value(self._line, self._value.ACTIVE if value else self._value.INACTIVE)
+
+    def read(self) -> bool:
+        return self._request.get_value(self._line) == self._value.ACTIVE
+
+    def close(self) -> None:
+        self._request.release()
+
+
+def open_pin(chip: str, line: int, output: bool):
+    """Open a GPIO line with gpiod if available, falling back to sysfs."""
+    try:
+        return GpiodPin(chip, line, output)
+    except ImportError:
+        return SysfsPin(line, output)
+
+
+class TradingRelay:
+    """Keeps the indicator pin in sync with the trading state and watches the kill switch."""
+
+    POLL_INTERVAL = 0.1  # Kill switch sampling (seconds)
+    REFRESH_INTERVAL = 10  # Trading state re-evaluation (seconds)
+    DEBOUNCE_SAMPLES = 3  # Consecutive identical samples before a press counts
+    # Read by start(); the app restarts the loop on change
+    SETTINGS_KEYS = (
+        "gpio_relay_enabled",
+        "gpio_chip",
+        "gpio_relay_pin",
+        "gpio_relay_active_low",
+        "gpio_kill_switch_pin",
+        "gpio_kill_switch_active_low",
+    )
+
+    def __init__(self, broker=None, db: Database | None = None, settings: Settings | None = None, pin_factory=None):
+        self._broker = broker
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._open_pin = pin_factory or open_pin
+        self._output = None
+        self._input = None
+        self._output_active_low = False
+        self._input_active_low = True
+        self._running = False
+        self._state: Optional[str] = None
+        self._reason = ""
+        self._pressed = False
+        self._samples = 0
+
+    async def start(self) -> None:
+        """Open the configured pins and run the update loop (no-op unless gpio_relay_enabled)."""
+        if not await self._settings.get("gpio_relay_enabled", False):
+            logger.info("GPIO relay disabled by setting")
+            return
+        if not await self._open_pins():
+            return
+
+        logger.info("GPIO relay starting")
+        self._running = True
+        ticks_per_refresh = max(int(self.REFRESH_INTERVAL / self.POLL_INTERVAL), 1)
+        tick = 0
+        try:
+            while self._running:
+                try:
+                    if tick % ticks_per_refresh == 0:
+                        await self.refresh()
+                    await self.poll_kill_switch()
+                except Exception as e:
+                    logger.error(f"Error in GPIO relay loop: {e}")
+                tick += 1
+                await asyncio.sleep(self.POLL_INTERVAL)
+        finally:
+            self._close_pins()
+
+    def stop(self) -> None:
+        """Stop the update loop; the indicator is de-asserted on exit."""
+        self._running = False
+        logger.info("GPIO relay stopped")
+
+    async def _open_pins(self) -> bool:
+        chip = await self._settings.get("gpio_chip", "/dev/gpiochip0")
+        output_line = await self._settings.get("gpio_relay_pin")
+        input_line = await self._settings.get("gpio_kill_switch_pin")
+        self._output_active_low = bool(await self._settings.get("gpio_relay_active_low", False))
+        self._input_active_low = bool(await self._settings.get("gpio_kill_switch_active_low", True))
+        if output_line is None:
+            logger.warning("GPIO relay enabled but gpio_relay_pin is not set")
+            return False
+        try:
+            self._output = self._open_pin(chip, int(output_line), True)
+            if input_line is not None:
+                self._input = self._open_pin(chip, int(input_line), False)
+        except Exception as e:
+            logger.warning(f"GPIO unavailable: {e}")
+            self._close_pins()
+            return False
+        return True
+
+    def _close_pins(self) -> None:
+        if self._output is not None:
+            try:
+                self._output.write(self._output_active_low)
+                self._output.close()
+            except Exception as e:
+                logger.warning(f"Failed to release GPIO relay pin: {e}")
+        if self._input is not None:
+            try:
+                self._input.close()
+            except Exception as e:
+                logger.warning(f"Failed to release GPIO kill switch pin: {e}")
+        self._output = None
+        self._input = None
+
+    async def refresh(self) -> str:
+        """Re-evaluate the trading state and drive the indicator pin."""
+        trading_mode = await self._settings.get("trading_mode", "research")
+        connected = bool(getattr(self._broker, "connected", False))
+        schedule = await self._db.get_job_schedule("trading:execute")
+        state, reason = trading_indicator_state(trading_mode, connected, schedule)
+        if state != self._state:
+            logger.info(f"Trading indicator {state}: {reason}")
+        self._state, self._reason = state, reason
+        if self._output is not None:
+            try:
+                self._output.write((state == "on") != self._output_active_low)
+            except Exception as e:
+                logger.error(f"Failed to drive GPIO relay: {e}")
+        return state
+
+    async def poll_kill_switch(self) -> None:
+        """Sample the kill switch and toggle trading on a debounced press."""
+        if self._input is None:
+            return
+        try:
+            pressed = self._input.read() != self._input_active_low
+        except Exception as e:
+            logger.error(f"Failed to read GPIO kill switch: {e}")
+            return
+        if pressed == self._pressed:
+            self._samples = 0
+            return
+        self._samples += 1
+        if self._samples < self.DEBOUNCE_SAMPLES:
+            return
+        self._pressed, self._samples = pressed, 0
+        if pressed:
+            await self.toggle_trading()
+
+    async def toggle_trading(self) -> str:
+        """Flip trading_mode between live and research (the physical kill switch)."""
+        from sentinel.services.notifications import record_notification
+
+        current = await self._settings.get("trading_mode", "research")
+        new_mode = "research" if current == "live" else "live"
+        await self._settings.set("trading_mode", new_mode)
+        logger.warning(f"Kill switch pressed: trading mode {current} -> {new_mode}")
+        await record_notification(
+            self._db,
+            "warning",
+            "trading",
+            "Trading paused by kill switch" if new_mode == "research" else "Trading resumed by kill switch",
+            message=f"Trading mode changed from {current} to {new_mode} with the physical switch",
+        )
+        await self.refresh()
+        return new_mode
+
+    @property
+    def is_running(self) -> bool:
+        """Check if the relay loop is running."""
+        return self._running
+
+    @property
+    def state(self) -> dict:
+        """Last evaluated indicator state."""
+        return {"state": self._state, "reason": self._reason, "kill_switch": self._input is not None}
@@ -7,3 +7,9 @@ from pathlib import Path
 _PROJECT_ROOT = Path(__file__).parent.parent
 
 DATA_DIR = Path(os.environ.get("SENTINEL_DATA_DIR", _PROJECT_ROOT / "data"))
+
+# On-demand profiler captures (kept out of DATA_DIR so backups skip them)
+PROFILE_DIR = Path(os.environ.get("SENTINEL_PROFILE_DIR", _PROJECT_ROOT / "profiles"))
+
+# Broker documents (order files, broker report PDFs); inside DATA_DIR so backups include them
+DOCUMENTS_DIR = DATA_DIR / "documents"
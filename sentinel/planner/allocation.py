@@ -10,13 +10,17 @@ from datetime import datetime, timezone
 from sentinel.currency import Currency
 from sentinel.database import Database
 from sentinel.planner.analyzer import PortfolioAnalyzer
+from sentinel.planner.cash_equivalents import is_cash_equivalent
+from sentinel.planner.correlation import blend_diversification, candidate_diversification_score, correlation_lookup
+from sentinel.planner.scoring import ScoringParams, score_securities
+from sentinel.planner.sector_caps import apply_sector_caps, symbol_sector_paths
 from sentinel.portfolio import Portfolio
 from sentinel.settings import Settings
 from sentinel.strategy import (
-    compute_contrarian_signal,
+    SLEEVES,
+    PositionSizer,
     compute_symbol_targets,
-    effective_opportunity_score,
-    recent_dd252_min,
+    make_sizer,
 )
 from sentinel.utils.strings import parse_csv_field
 
@@ -117,6 +121,8 @@ class AllocationCalculator:
     async def _load_strategy_settings(self) -> dict[str, float]:
         keys_defaults: dict[str, float] = {
             "diversification_impact_pct": 10,
+            "correlation_diversification_weight": 0.5,
+            "regime_impact_pct": 10,
             "strategy_entry_t1_dd": -0.10,
             "strategy_entry_t3_dd": -0.22,
             "strategy_entry_memory_days": 42,
@@ -128,11 +134,83 @@ class AllocationCalculator:
             "strategy_min_opp_score": 0.55,
             "max_position_pct": 35,
             "min_position_pct": 1,
+            "planner_scoring_workers": 0,
         }
         keys = list(keys_defaults.keys())
         values = await asyncio.gather(*[self._settings.get(k, keys_defaults[k]) for k in keys])
         return {k: float(v if v is not None else keys_defaults[k]) for k, v in zip(keys, values, strict=False)}
 
+    @staticmethod
+    def _scoring_params(config: dict[str, float]) -> ScoringParams:
+        return ScoringParams(
+            entry_t1_dd=config["strategy_entry_t1_dd"],
+            entry_t3_dd=config["strategy_entry_t3_dd"],
+            entry_memory_days=int(config["strategy_entry_memory_days"]),
+            memory_max_boost=config["strategy_memory_max_boost"],
+        )
+
+    async def load_scoring_params(self) -> tuple[ScoringParams, int]:
+        """Settings the cached security scores depend on, and the scoring worker count."""
+        config = await self._load_strategy_settings()
+        return self._scoring_params(config), int(config["planner_scoring_workers"])
+
+    async def _load_sizers(self) -> dict[str, PositionSizer]:
+        """Sizer per sleeve: position_sizing_mode, overridden per sleeve by position_sizing_overrides."""
+        mode = await self._settings.get("position_sizing_mode", "score")
+        overrides = await self._settings.get("position_sizing_overrides", {})
+        if not isinstance(overrides, dict):
+            overrides = {}
+        target_vol_pct = float(await self._settings.get("vol_target_annual_pct", 15.0) or 15.0)
+        max_weight_pct = float(await self._settings.get("vol_target_max_weight_pct", 25.0) or 25.0)
+        return {
+            sleeve: make_sizer(overrides.get(sleeve) or mode, target_vol_pct, max_weight_pct)
+            for sleeve in SLEEVES
+        }
+
+    async def _apply_sector_caps(
+        self, weights: dict[str, float], securities: list[dict], max_weight: float
+    ) -> dict[str, float]:
+        """Enforce hierarchical sector / industry group caps on target weights."""
+        caps_getter = getattr(self._db, "get_sector_caps", None)
+        if not callable(caps_getter):
+            return weights
+        caps = caps_getter()
+        if inspect.isawaitable(caps):
+            caps = await caps
+        if not isinstance(caps, dict) or not caps:
+            return weights
+        return apply_sector_caps(weights, symbol_sector_paths(securities), caps, max_weight=max_weight)
+
+    async def _load_correlations(self) -> dict[tuple[str, str], float]:
+        """Stored pairwise correlations as a symmetric lookup (empty when none are stored)."""
+        getter = getattr(self._db, "get_correlations", None)
+        if not callable(getter):
+            return {}
+        pairs = getter()
+        if inspect.isawaitable(pairs):
+            pairs = await pairs
+        if not isinstance(pairs, list):
+            return {}
+        return correlation_lookup(pairs)
+
+    async def _load_regimes(self) -> dict[str, float]:
+        """Stored regime per region as a confidence-weighted score (-1 risk-off to 1 risk-on)."""
+        getter = getattr(self._db, "get_regime_states", None)
+        if not callable(getter):
+            return {}
+        states = getter()
+        if inspect.isawaitable(states):
+            states = await states
+        if not isinstance(states, list):
+            return {}
+        return {s["region"]: float(s["score"]) * float(s["confidence"]) for s in states}
+
+    @staticmethod
+    def _regime_score(security: dict, regimes: dict[str, float]) -> float:
+        """Average regime score over the security's geographies (0 where none is known)."""
+        scores = [regimes[geo] for geo in parse_csv_field(security.get("geography")) if geo in regimes]
+        return sum(scores) / len(scores) if scores else 0.0
+
     async def calculate_ideal_portfolio(self, as_of_date: str | None = None) -> dict[str, float]:
         """Calculate ideal portfolio allocations using deterministic contrarian strategy.
 
@@ -144,8 +222,15 @@ class AllocationCalculator:
         Diversification adjustment:
         - Securities in underweight categories get a boost
         - Securities in overweight categories get a reduction
+        - Securities that correlate little with the current holdings get a boost, ones
+          moving with them a reduction (blended in by correlation_diversification_weight;
+          live runs only, as the stored matrix is not point-in-time)
         - Max impact is configurable via diversification_impact_pct setting
 
+        Regime adjustment (live runs only):
+        - Securities in regions classified risk-on get a boost, risk-off a reduction,
+          scaled by the classification's confidence and regime_impact_pct
+
         Returns:
             dict: symbol -> target allocation percentage (0-1)
         """
@@ -159,8 +244,8 @@ class AllocationCalculator:
                 if isinstance(maybe_cached, (str, bytes, bytearray)):
                     return json.loads(maybe_cached)
 
-        # Get all securities with user conviction values
-        securities = await self._db.get_all_securities(active_only=True)
+        # Get all securities with user conviction values (cash equivalents are parked cash, not targets)
+        securities = [s for s in await self._db.get_all_securities(active_only=True) if not is_cash_equivalent(s)]
         if not securities:
             return {}
 
@@ -178,65 +263,59 @@ class AllocationCalculator:
         target_allocs = await self._portfolio.get_target_allocations()
         config = await self._load_strategy_settings()
         div_impact = config["diversification_impact_pct"] / 100.0
-        entry_t1_dd = config["strategy_entry_t1_dd"]
-        entry_t3_dd = config["strategy_entry_t3_dd"]
-        entry_memory_days = int(config["strategy_entry_memory_days"])
-        memory_max_boost = config["strategy_memory_max_boost"]
+        scoring_params = self._scoring_params(config)
+        correlation_weight = config["correlation_diversification_weight"]
+        correlations = await self._load_correlations() if as_of_date is None and correlation_weight > 0 else {}
+        holdings = current_allocs.get("by_security", {})
+        regime_impact = config["regime_impact_pct"] / 100.0
+        regimes = await self._load_regimes() if as_of_date is None and regime_impact > 0 else {}
 
         symbol_signals: dict[str, dict[str, float | int]] = {}
         rebalance_signals: dict[str, dict[str, float | int]] = {}
         user_multipliers: dict[str, float] = {}
+        skipped_checks: dict[str, list[dict]] = {}
         symbols = [sec["symbol"] for sec in securities]
-        prices_by_symbol: dict[str, list[dict]] | None = None
-        get_prices_multi = getattr(self._db, "get_prices_for_symbols", None)
-        if callable(get_prices_multi):
-            maybe_prices = get_prices_multi(symbols, days=300, end_date=as_of_date)
-            if inspect.isawaitable(maybe_prices):
-                resolved = await maybe_prices
-                if isinstance(resolved, dict):
-                    prices_by_symbol = resolved
-            elif isinstance(maybe_prices, dict):
-                prices_by_symbol = maybe_prices
-        if prices_by_symbol is None:
-            all_prices = await asyncio.gather(
-                *[self._db.get_prices(symbol, days=300, end_date=as_of_date) for symbol in symbols]
-            )
-            prices_by_symbol = {symbol: prices for symbol, prices in zip(symbols, all_prices, strict=False)}
-        for sec in securities:
-            symbol = sec["symbol"]
+        securities_map = {sec["symbol"]: sec for sec in securities}
+        # Raw signals are cached per security and only recomputed for dirty ones (see scoring.py).
+        scored, scoring_stats = await score_securities(
+            self._db,
+            symbols,
+            scoring_params,
+            as_of_date=as_of_date,
+            workers=int(config["planner_scoring_workers"]),
+        )
+        for symbol in symbols:
+            sec = securities_map[symbol]
             conviction = self._normalize_conviction(sec.get("user_multiplier", 0.5))
             # Continuous preference multiplier (no binary cutoff).
             user_multipliers[symbol] = 0.2 + (1.8 * conviction)
 
-            raw = prices_by_symbol.get(symbol, [])
-            closes = [float(p["close"]) for p in reversed(raw) if p.get("close") is not None]
-            signal = compute_contrarian_signal(closes)
-            raw_opp = float(signal.get("opp_score", 0.0) or 0.0)
-            recent_min = recent_dd252_min(closes, window_days=entry_memory_days)
-            effective_opp = effective_opportunity_score(
-                raw_opp_score=raw_opp,
-                cycle_turn=int(signal.get("cycle_turn", 0) or 0),
-                freefall_block=int(signal.get("freefall_block", 0) or 0),
-                recent_dd252_min_value=recent_min,
-                entry_t1_dd=entry_t1_dd,
-                entry_t3_dd=entry_t3_dd,
-                max_boost=memory_max_boost,
-            )
-            signal["opp_score_raw"] = raw_opp
-            signal["dd252_recent_min"] = recent_min
-            signal["opp_score"] = effective_opp
-            signal["memory_boosted"] = 1 if effective_opp > raw_opp else 0
+            signal = dict(scored[symbol]["signal"])
+            if scored[symbol]["skipped"]:
+                skipped_checks[symbol] = scored[symbol]["skipped"]
             rebalance_signals[symbol] = dict(signal)
             # Conviction influences tactical opportunity intensity continuously.
             signal["opp_score"] = max(0.0, min(1.0, float(signal["opp_score"]) * (0.2 + (0.8 * conviction))))
 
             # Apply diversification multiplier
             if div_impact > 0:
-                div_score = self._calculate_diversification_score(sec, current_allocs, target_allocs)
+                div_score = blend_diversification(
+                    self._calculate_diversification_score(sec, current_allocs, target_allocs),
+                    candidate_diversification_score(symbol, holdings, correlations),
+                    correlation_weight,
+                )
                 div_multiplier = 1.0 + (div_score * div_impact)
                 signal["core_rank"] = float(signal.get("core_rank", 0.0)) * div_multiplier
                 signal["opp_score"] = max(0.0, min(1.0, float(signal.get("opp_score", 0.0)) * div_multiplier))
 
+            # Apply market regime multiplier
+            regime_score = self._regime_score(sec, regimes)
+            if regime_score:
+                regime_multiplier = 1.0 + (regime_score * regime_impact)
+                signal["core_rank"] = float(signal.get("core_rank", 0.0)) * regime_multiplier
+                signal["opp_score"] = max(0.0, min(1.0, float(signal.get("opp_score", 0.0)) * regime_multiplier))
+                signal["regime_score"] = regime_score
+
             symbol_signals[symbol] = signal
 
         # Apply dividend reinvestment boost
@@ -255,6 +334,7 @@ class AllocationCalculator:
         opportunity_target = config["strategy_opportunity_target_pct"] / 100.0
         max_opportunity_target = config["strategy_opportunity_target_max_pct"] / 100.0
         min_opp_score = config["strategy_min_opp_score"]
+        sizers = await self._load_sizers()
 
         allocations, sleeves = compute_symbol_targets(
             symbol_signals,
@@ -263,20 +343,29 @@ class AllocationCalculator:
             opportunity_target=opportunity_target,
             min_opp_score=min_opp_score,
             max_opportunity_target=max_opportunity_target,
+            core_sizer=sizers["core"],
+            opportunity_sizer=sizers["opportunity"],
         )
         self._last_signal_bundle = {
             "as_of_date": as_of_date,
             "rebalance_signals": rebalance_signals,
             "sleeves": sleeves,
+            "skipped_checks": skipped_checks,
+            "sizing": {sleeve: sizer.mode for sleeve, sizer in sizers.items()},
+            "scoring": scoring_stats,
         }
 
-        # Enforce position bounds and renormalize to 100% invested
+        # Enforce position bounds and renormalize to the invested share: 100%, or less when
+        # volatility targeting holds back cash
+        invested = sum(allocations.values())
+        invested = 1.0 if invested >= 1.0 - 1e-9 else invested
         max_position = config["max_position_pct"] / 100.0
         min_position = config["min_position_pct"] / 100.0
         bounded = {s: max(min_position, min(max_position, w)) for s, w in allocations.items() if w > 0}
         total = sum(bounded.values())
         if total > 0:
-            bounded = {s: w / total for s, w in bounded.items()}
+            bounded = {s: w / total * invested for s, w in bounded.items()}
+        bounded = await self._apply_sector_caps(bounded, securities, max_position)
 
         # Cache live allocations/diagnostics for downstream APIs/rebalance.
         # Do not cache as-of signals to avoid polluting live state.
@@ -293,6 +382,9 @@ class AllocationCalculator:
                 if inspect.isawaitable(maybe_set):
                     await maybe_set
                 maybe_set = cache_setter("planner:contrarian_sleeves", json.dumps(sleeves), ttl_seconds=600)
+                if inspect.isawaitable(maybe_set):
+                    await maybe_set
+                maybe_set = cache_setter("planner:skipped_checks", json.dumps(skipped_checks), ttl_seconds=600)
                 if inspect.isawaitable(maybe_set):
                     await maybe_set
                 maybe_set = cache_setter(
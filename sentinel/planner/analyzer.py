@@ -176,11 +176,17 @@ class PortfolioAnalyzer:
         """Get summary of portfolio alignment with ideal allocations.
 
         Returns:
-            dict with alignment metrics and status
+            dict with alignment metrics and status, plus cash_equivalents_pct: the
+            share held in cash equivalents, which is left out of the deviations
         """
         from sentinel.planner.allocation import AllocationCalculator
+        from sentinel.planner.cash_equivalents import cash_equivalent_symbols
 
         current = await self.get_current_allocations()
+        # Cash equivalents are tracked apart from the equity targets
+        cash_equivalents = cash_equivalent_symbols(await self._db.get_all_securities(active_only=False))
+        cash_equivalents_pct = sum(pct for symbol, pct in current.items() if symbol in cash_equivalents)
+        current = {symbol: pct for symbol, pct in current.items() if symbol not in cash_equivalents}
 
         calculator = AllocationCalculator(
             db=self._db,
@@ -198,6 +204,7 @@ class PortfolioAnalyzer:
                 "max_deviation": 0.0,
                 "average_deviation": 0.0,
                 "status": "aligned",
+                "cash_equivalents_pct": cash_equivalents_pct,
             }
 
         # Calculate deviations
@@ -219,6 +226,7 @@ class PortfolioAnalyzer:
                 "max_deviation": 0.0,
                 "average_deviation": 0.0,
                 "status": "aligned",
+                "cash_equivalents_pct": cash_equivalents_pct,
             }
 
         total_deviation = sum(deviations)
@@ -245,6 +253,7 @@ class PortfolioAnalyzer:
             "max_deviation": max_deviation,
             "average_deviation": avg_deviation,
             "status": status,
+            "cash_equivalents_pct": cash_equivalents_pct,
         }
 
     async def get_position_details(self) -> list[dict]:
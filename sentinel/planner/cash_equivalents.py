@@ -0,0 +1,144 @@
+"""Cash-equivalent instruments (money market ETFs and similar).
+
+A security with asset_class 'cash_equivalent' is treated as parked cash rather
+than an equity holding: it gets no ideal allocation, never shows up as drift
+against equity targets and is not scored or rebalanced like the equity
+universe. With cash_equivalents_enabled the planner
+
+- parks idle cash above cash_equivalent_reserve_eur in the park instrument when
+  no buy cleared the bar this cycle, and
+- liquidates cash-equivalent holdings first when cash plus sell proceeds cannot
+  cover the planned buys, before any equity is sold to fund them.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+
+from sentinel.utils.quantity import ceil_to_lot, floor_to_lot
+
+from .models import TradeRecommendation
+from .rebalance_rules import calculate_transaction_cost
+
+EQUITY = "equity"
+CASH_EQUIVALENT = "cash_equivalent"
+ASSET_CLASSES = (EQUITY, CASH_EQUIVALENT)
+
+
+def is_cash_equivalent(security: dict | None) -> bool:
+    """Whether a security row is a cash-equivalent instrument."""
+    return (security or {}).get("asset_class") == CASH_EQUIVALENT
+
+
+def cash_equivalent_symbols(securities: list[dict]) -> set[str]:
+    """Symbols of the cash-equivalent securities in a securities list."""
+    return {s["symbol"] for s in securities if is_cash_equivalent(s)}
+
+
+@dataclass
+class CashEquivalentSettings:
+    """Parking and liquidation parameters."""
+
+    reserve_eur: float = 500.0  # Cash kept uninvested
+    min_park_eur: float = 250.0  # Smallest idle amount worth parking
+    fee_fixed: float = 2.0
+    fee_pct: float = 0.002
+
+
+def _recommendation(
+    instrument: dict, action: str, quantity: float, reason: str, reason_code: str
+) -> TradeRecommendation:
+    price = instrument["price"]
+    fx_rate = instrument.get("fx_rate") or 1.0
+    current_value = instrument.get("current_value_eur", 0.0)
+    delta = quantity * price * fx_rate * (1 if action == "buy" else -1)
+    total = instrument.get("total_value_eur") or 0.0
+    current_allocation = current_value / total if total > 0 else 0.0
+    target_allocation = (current_value + delta) / total if total > 0 else 0.0
+    return TradeRecommendation(
+        symbol=instrument["symbol"],
+        action=action,
+        current_allocation=current_allocation,
+        target_allocation=target_allocation,
+        allocation_delta=target_allocation - current_allocation,
+        current_value_eur=current_value,
+        target_value_eur=current_value + delta,
+        value_delta_eur=delta,
+        quantity=quantity,
+        price=price,
+        currency=instrument.get("currency", "EUR"),
+        lot_size=instrument.get("lot_size", 1),
+        contrarian_score=0.0,
+        priority=0.0,
+        reason=reason,
+        reason_code=reason_code,
+        sleeve="cash",
+    )
+
+
+def plan_liquidation(
+    shortfall_eur: float,
+    holdings: list[dict],
+    config: CashEquivalentSettings,
+) -> list[TradeRecommendation]:
+    """
+    Sells of cash-equivalent holdings covering a funding shortfall, largest holding first.
+
+    Args:
+        shortfall_eur: Buy costs not covered by cash and sell proceeds
+        holdings: Cash-equivalent positions: symbol, quantity, price, fx_rate, currency,
+            lot_size, allow_sell, current_value_eur, total_value_eur
+        config: Fee parameters
+    """
+    sells = []
+    remaining = shortfall_eur
+    for holding in sorted(holdings, key=lambda h: -h.get("current_value_eur", 0.0)):
+        if remaining <= 0:
+            break
+        unit_eur = holding["price"] * (holding.get("fx_rate") or 1.0)
+        if not holding.get("allow_sell", 1) or holding["quantity"] <= 0 or unit_eur <= 0:
+            continue
+        needed = remaining / (1.0 - config.fee_pct) + config.fee_fixed
+        quantity = min(holding["quantity"], ceil_to_lot(needed / unit_eur, holding.get("lot_size", 1)))
+        if quantity <= 0:
+            continue
+        value = quantity * unit_eur
+        remaining -= value - calculate_transaction_cost(value, config.fee_fixed, config.fee_pct)
+        sells.append(
+            _recommendation(
+                holding,
+                "sell",
+                quantity,
+                f"Liquidate cash equivalent to fund buys ({value:.0f} EUR)",
+                "cash_equivalent_liquidation",
+            )
+        )
+    return sells
+
+
+def plan_parking(idle_cash_eur: float, instrument: dict, config: CashEquivalentSettings) -> TradeRecommendation | None:
+    """
+    Buy of the park instrument for idle cash above the reserve, or None if too small.
+
+    Args:
+        idle_cash_eur: Uninvested cash
+        instrument: symbol, price, fx_rate, currency, lot_size, current_value_eur, total_value_eur
+        config: Reserve, minimum amount and fee parameters
+    """
+    excess = idle_cash_eur - config.reserve_eur
+    if excess < config.min_park_eur:
+        return None
+    unit_eur = instrument["price"] * (instrument.get("fx_rate") or 1.0)
+    if unit_eur <= 0:
+        return None
+    budget = (excess - config.fee_fixed) / (1.0 + config.fee_pct)
+    quantity = floor_to_lot(budget / unit_eur, instrument.get("lot_size", 1))
+    if quantity <= 0 or quantity * unit_eur < config.min_park_eur:
+        return None
+    return _recommendation(
+        instrument,
+        "buy",
+        quantity,
+        f"Park idle cash in cash equivalent ({quantity * unit_eur:.0f} EUR above {config.reserve_eur:.0f} EUR reserve)",
+        "cash_equivalent_park",
+    )
@@ -0,0 +1,123 @@
+"""In-memory history of the planner's opportunity context.
+
+Each live planner computation assembles a context - portfolio positions
+enriched with EUR values and current/ideal weights, cash per currency, the
+broker quotes and sleeve signals it used - that is otherwise discarded once
+the recommendations are built. The last `planner_context_history` contexts are
+kept here (per process, lost on restart) so /api/planner/context can show
+exactly what the planner saw.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import threading
+import time
+from collections import deque
+from dataclasses import asdict
+
+from .models import TradeRecommendation
+
+logger = logging.getLogger(__name__)
+
+DEFAULT_HISTORY = 10
+MAX_HISTORY = 100
+
+
+class ContextLog:
+    """Bounded, thread-safe list of planner contexts, most recent last."""
+
+    def __init__(self, keep: int = DEFAULT_HISTORY):
+        self._lock = threading.Lock()
+        self._contexts: deque[dict] = deque(maxlen=max(1, min(keep, MAX_HISTORY)))
+        self._next_id = 1
+
+    def record(self, context: dict, keep: int | None = None) -> int:
+        """Store a copy of a context (JSON round-tripped, so later mutation cannot leak in)."""
+        snapshot = json.loads(json.dumps(context, default=str))
+        with self._lock:
+            if keep is not None and keep != self._contexts.maxlen:
+                self._contexts = deque(self._contexts, maxlen=max(1, min(int(keep), MAX_HISTORY)))
+            snapshot["id"] = self._next_id
+            self._next_id += 1
+            self._contexts.append(snapshot)
+            return snapshot["id"]
+
+    def get(self, back: int = 0) -> dict | None:
+        """Context `back` runs ago (0 = latest), or None if not retained."""
+        with self._lock:
+            if back < 0 or back >= len(self._contexts):
+                return None
+            return self._contexts[-1 - back]
+
+    def summaries(self) -> list[dict]:
+        """Id, capture time and size of each retained context, most recent first."""
+        with self._lock:
+            contexts = list(self._contexts)
+        return [
+            {
+                "id": c["id"],
+                "captured_at": c["captured_at"],
+                "positions": len(c.get("positions", [])),
+                "recommendations": len(c.get("recommendations", [])),
+            }
+            for c in reversed(contexts)
+        ]
+
+    def clear(self) -> None:
+        with self._lock:
+            self._contexts.clear()
+
+
+_context_log: ContextLog | None = None
+
+
+def get_context_log() -> ContextLog:
+    """The process-wide planner context log."""
+    global _context_log
+    if _context_log is None:
+        _context_log = ContextLog()
+    return _context_log
+
+
+def build_context(
+    inputs: dict,
+    positions: list[dict],
+    cash: dict[str, float],
+    cash_eur: float,
+    quotes: dict[str, dict],
+    recommendations: list[TradeRecommendation],
+    min_trade_value: float | None = None,
+) -> dict:
+    """Assemble the opportunity context of one planner computation.
+
+    Args:
+        inputs: {"ideal", "current", "total_value", "sleeves"} from the computation
+        positions: Position details with EUR values (PortfolioAnalyzer.get_position_details)
+        cash: Cash balance per currency
+        cash_eur: Total cash in EUR
+        quotes: Broker quotes the rebalance engine used
+        recommendations: Resulting recommendations
+        min_trade_value: Minimum trade value requested (None = setting)
+    """
+    ideal = inputs.get("ideal") or {}
+    current = inputs.get("current") or {}
+    enriched = []
+    for pos in positions:
+        symbol = pos["symbol"]
+        enriched.append({**pos, "current_pct": current.get(symbol, 0.0), "ideal_pct": ideal.get(symbol, 0.0)})
+    held = {pos["symbol"] for pos in positions}
+    for symbol in sorted(set(ideal) - held):
+        enriched.append({"symbol": symbol, "quantity": 0, "current_pct": 0.0, "ideal_pct": ideal[symbol]})
+
+    return {
+        "captured_at": int(time.time()),
+        "min_trade_value": min_trade_value,
+        "total_value_eur": inputs.get("total_value"),
+        "cash": {"balances": cash, "total_eur": cash_eur},
+        "positions": enriched,
+        "quotes": quotes,
+        "sleeves": inputs.get("sleeves"),
+        "recommendations": [asdict(r) for r in recommendations],
+    }
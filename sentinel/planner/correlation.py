@@ -0,0 +1,148 @@
+"""Pairwise return correlations and correlation-based diversification scores.
+
+Correlations are Pearson coefficients of daily close-to-close returns over the
+dates both securities have a close for. The planner blends the per-candidate
+score into its diversification multiplier next to the geography/industry
+heuristic: two holdings in different countries and industries can still move
+together, and the heuristic alone cannot see that.
+"""
+
+from __future__ import annotations
+
+import math
+from itertools import combinations
+
+# Fewer common return observations than this and a pair is left out
+MIN_CORRELATION_OBSERVATIONS = 60
+
+
+def daily_returns(rows: list[dict]) -> dict[str, float]:
+    """date -> close-to-close return, from price rows in any order."""
+    closes = sorted((row["date"], float(row["close"])) for row in rows if row.get("close"))
+    return {
+        date: close / prev - 1.0
+        for (_, prev), (date, close) in zip(closes, closes[1:], strict=False)
+        if prev > 0
+    }
+
+
+def pearson(xs: list[float], ys: list[float]) -> float | None:
+    """Pearson correlation of two equally long series; None when either is flat."""
+    n = len(xs)
+    if n < 2 or n != len(ys):
+        return None
+    mean_x = sum(xs) / n
+    mean_y = sum(ys) / n
+    cov = sum((x - mean_x) * (y - mean_y) for x, y in zip(xs, ys, strict=True))
+    var_x = sum((x - mean_x) ** 2 for x in xs)
+    var_y = sum((y - mean_y) ** 2 for y in ys)
+    if var_x <= 0 or var_y <= 0:
+        return None
+    return max(-1.0, min(1.0, cov / math.sqrt(var_x * var_y)))
+
+
+def pairwise_correlations(
+    returns: dict[str, dict[str, float]],
+    min_observations: int = MIN_CORRELATION_OBSERVATIONS,
+) -> list[dict]:
+    """Correlation of every pair of symbols over their common return dates.
+
+    Args:
+        returns: symbol -> {date: daily return}
+        min_observations: Pairs with fewer common dates are skipped
+
+    Returns:
+        List of {symbol_a, symbol_b, correlation, observations} with symbol_a < symbol_b
+    """
+    pairs = []
+    for a, b in combinations(sorted(returns), 2):
+        dates = sorted(returns[a].keys() & returns[b].keys())
+        if len(dates) < min_observations:
+            continue
+        value = pearson([returns[a][d] for d in dates], [returns[b][d] for d in dates])
+        if value is None:
+            continue
+        pairs.append({"symbol_a": a, "symbol_b": b, "correlation": value, "observations": len(dates)})
+    return pairs
+
+
+def correlation_lookup(pairs: list[dict]) -> dict[tuple[str, str], float]:
+    """(symbol, symbol) -> correlation in both orders, from stored pair rows."""
+    lookup: dict[tuple[str, str], float] = {}
+    for pair in pairs:
+        a, b, value = pair["symbol_a"], pair["symbol_b"], float(pair["correlation"])
+        lookup[(a, b)] = value
+        lookup[(b, a)] = value
+    return lookup
+
+
+def average_correlation(
+    symbol: str,
+    weights: dict[str, float],
+    lookup: dict[tuple[str, str], float],
+) -> float | None:
+    """Weighted average correlation of a symbol with the other weighted symbols; None if none is known."""
+    total = 0.0
+    weighted = 0.0
+    for other, weight in weights.items():
+        if other == symbol or weight <= 0 or (symbol, other) not in lookup:
+            continue
+        total += weight
+        weighted += weight * lookup[(symbol, other)]
+    return weighted / total if total > 0 else None
+
+
+def candidate_diversification_score(
+    symbol: str,
+    holdings: dict[str, float],
+    lookup: dict[tuple[str, str], float],
+) -> float | None:
+    """Diversification a candidate adds to the current holdings, from -1 to +1.
+
+    An average correlation of 0.5 with the holdings is neutral (0); uncorrelated
+    or negatively correlated candidates score up to +1, ones moving in lockstep
+    with the portfolio down to -1. None when no correlation with a holding is known.
+    """
+    avg = average_correlation(symbol, holdings, lookup)
+    if avg is None:
+        return None
+    return max(-1.0, min(1.0, 1.0 - 2.0 * avg))
+
+
+def portfolio_diversification(weights: dict[str, float], lookup: dict[tuple[str, str], float]) -> dict:
+    """Weighted average pairwise correlation of the holdings and the derived score.
+
+    The score is 1 minus the average correlation, floored at 0 and capped at 1:
+    1 = uncorrelated (or hedged) holdings, 0 = everything moves together.
+
+    Returns:
+        dict with average_correlation, diversification_score (None when no pair
+        is known) and the number of pairs used
+    """
+    total = 0.0
+    weighted = 0.0
+    pairs = 0
+    held = sorted(s for s, w in weights.items() if w > 0)
+    for a, b in combinations(held, 2):
+        if (a, b) not in lookup:
+            continue
+        pair_weight = weights[a] * weights[b]
+        total += pair_weight
+        weighted += pair_weight * lookup[(a, b)]
+        pairs += 1
+    if total <= 0:
+        return {"average_correlation": None, "diversification_score": None, "pairs": 0}
+    avg = weighted / total
+    return {
+        "average_correlation": round(avg, 4),
+        "diversification_score": round(max(0.0, min(1.0, 1.0 - avg)), 4),
+        "pairs": pairs,
+    }
+
+
+def blend_diversification(category_score: float, correlation_score: float | None, weight: float) -> float:
+    """Blend the geography/industry score with the correlation score (weight = correlation share, 0-1)."""
+    if correlation_score is None or weight <= 0:
+        return category_score
+    weight = min(1.0, weight)
+    return (1.0 - weight) * category_score + weight * correlation_score
@@ -0,0 +1,112 @@
+"""Currency exposure decomposition and soft currency limits for the planner.
+
+A security's value is attributed to the currency it is listed in, or - on the
+revenue basis - to the currencies of the geographies it earns in. Currencies
+above their configured share (currency_exposure_limits) are a soft constraint:
+buys that add to them are deprioritized and sells that reduce them are
+preferred, but nothing is blocked.
+"""
+
+from __future__ import annotations
+
+from sentinel.utils.strings import parse_csv_field
+
+from .models import TradeRecommendation
+
+EXPOSURE_BASES = ("listing", "revenue")
+
+# Geography (as entered on securities, case-insensitive) -> revenue currency
+GEOGRAPHY_CURRENCIES = {
+    "us": "USD",
+    "usa": "USD",
+    "united states": "USD",
+    "north america": "USD",
+    "europe": "EUR",
+    "eu": "EUR",
+    "eurozone": "EUR",
+    "germany": "EUR",
+    "france": "EUR",
+    "netherlands": "EUR",
+    "italy": "EUR",
+    "spain": "EUR",
+    "greece": "EUR",
+    "uk": "GBP",
+    "united kingdom": "GBP",
+    "switzerland": "CHF",
+    "japan": "JPY",
+    "china": "CNY",
+    "hong kong": "HKD",
+    "canada": "CAD",
+    "australia": "AUD",
+}
+
+
+def security_currency_split(security: dict, basis: str = "listing") -> dict[str, float]:
+    """
+    Fraction of a security's value attributed to each currency.
+
+    The revenue basis splits evenly across the security's geographies; a
+    geography without a known currency falls back to the listing currency.
+    """
+    listing = security.get("currency") or "EUR"
+    if basis != "revenue":
+        return {listing: 1.0}
+    geographies = parse_csv_field(security.get("geography"))
+    if not geographies:
+        return {listing: 1.0}
+    split: dict[str, float] = {}
+    for geography in geographies:
+        ccy = GEOGRAPHY_CURRENCIES.get(geography.lower(), listing)
+        split[ccy] = split.get(ccy, 0.0) + 1.0 / len(geographies)
+    return split
+
+
+def decompose(values: dict[str, float], securities: dict[str, dict], basis: str = "listing") -> dict[str, float]:
+    """Currency -> value for symbol -> value (EUR amounts or weights; unknown symbols count as EUR)."""
+    exposure: dict[str, float] = {}
+    for symbol, value in values.items():
+        for ccy, fraction in security_currency_split(securities.get(symbol) or {}, basis).items():
+            exposure[ccy] = exposure.get(ccy, 0.0) + value * fraction
+    return exposure
+
+
+def to_pct(exposure: dict[str, float]) -> dict[str, float]:
+    """Currency -> share of the total (0-1)."""
+    total = sum(exposure.values())
+    if total <= 0:
+        return {}
+    return {ccy: value / total for ccy, value in exposure.items()}
+
+
+def over_limit_currencies(exposure_pct: dict[str, float], limits: dict[str, float]) -> set[str]:
+    """Currencies whose share exceeds their limit."""
+    return {ccy for ccy, limit in limits.items() if exposure_pct.get(ccy, 0.0) > float(limit)}
+
+
+def apply_currency_soft_limits(
+    recommendations: list[TradeRecommendation],
+    securities: dict[str, dict],
+    over_limit: set[str],
+    basis: str = "listing",
+    penalty: float = 0.5,
+) -> None:
+    """
+    Reweight recommendations in place for over-limit currencies.
+
+    A buy's priority is scaled by (1 - penalty x share of its value in over-limit
+    currencies); a sell's by (1 + penalty x that share).
+    """
+    if not over_limit or penalty <= 0:
+        return
+    for rec in recommendations:
+        split = security_currency_split(securities.get(rec.symbol) or {}, basis)
+        share = sum(fraction for ccy, fraction in split.items() if ccy in over_limit)
+        if share <= 0:
+            continue
+        currencies = "/".join(sorted(c for c in split if c in over_limit))
+        if rec.action == "buy":
+            rec.priority *= 1.0 - min(1.0, penalty) * share
+            rec.reason = f"{rec.reason} (adds to over-limit {currencies} exposure)"
+        elif rec.action == "sell":
+            rec.priority *= 1.0 + penalty * share
+            rec.reason = f"{rec.reason} (reduces over-limit {currencies} exposure)"
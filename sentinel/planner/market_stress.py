@@ -0,0 +1,94 @@
+"""Market-stress buy throttling for the planner.
+
+The stress score is the risk-off reading of the regime detector weighted by
+exposure: each region's confidence-weighted regime score (-1 risk-off to +1
+risk-on), weighted by the share of held value whose primary geography it is,
+negated and floored at 0. Without held positions in a classified region every
+classified region counts equally. At or above market_stress_threshold the
+market is in HIGH_STRESS, and while market_stress_enabled is on the planner
+throttles buys:
+
+- each buy shrinks to market_stress_buy_size_pct of its normal size
+- opportunity entries and new core positions need market_stress_min_score_add
+  more opportunity score
+- the cool-off before buying a recently traded security is stretched by
+  market_stress_cooloff_factor
+
+Sells are never throttled.
+"""
+
+from __future__ import annotations
+
+from sentinel.utils.strings import parse_csv_field
+
+NORMAL = "normal"
+HIGH_STRESS = "high_stress"
+
+# No throttling (NORMAL, or stress mode disabled)
+NO_THROTTLE = {"buy_size_factor": 1.0, "min_score_add": 0.0, "cooloff_factor": 1.0}
+
+
+def stress_score(states: list[dict], exposure: dict[str, float], securities: dict[str, dict]) -> tuple[float, dict]:
+    """Exposure-weighted risk-off score (0 calm to 1 fully risk-off) and each region's weight.
+
+    Args:
+        states: Stored regime states (region, score, confidence)
+        exposure: Symbol -> held value or allocation
+        securities: Symbol -> security (its first geography is its region)
+    """
+    regimes = {s["region"]: float(s["score"]) * float(s["confidence"]) for s in states}
+    weights: dict[str, float] = {}
+    for symbol, value in exposure.items():
+        geographies = parse_csv_field((securities.get(symbol) or {}).get("geography"))
+        if value > 0 and geographies and geographies[0] in regimes:
+            weights[geographies[0]] = weights.get(geographies[0], 0.0) + value
+    if not weights:
+        weights = {region: 1.0 for region in regimes}
+    total = sum(weights.values())
+    if total <= 0:
+        return 0.0, {}
+    shares = {region: weight / total for region, weight in weights.items()}
+    score = sum(regimes[region] * share for region, share in shares.items())
+    return round(max(0.0, -score), 4), {region: round(share, 4) for region, share in sorted(shares.items())}
+
+
+def stress_level(score: float, threshold: float) -> str:
+    """HIGH_STRESS at or above the threshold, NORMAL below it."""
+    return HIGH_STRESS if score >= threshold else NORMAL
+
+
+def buy_throttle(level: str, config: dict) -> dict:
+    """Buy size factor, minimum score add-on and cool-off factor for a stress level."""
+    if level != HIGH_STRESS or not config["market_stress_enabled"]:
+        return dict(NO_THROTTLE)
+    return {
+        "buy_size_factor": max(0.0, min(1.0, float(config["market_stress_buy_size_pct"]) / 100.0)),
+        "min_score_add": max(0.0, float(config["market_stress_min_score_add"])),
+        "cooloff_factor": max(1.0, float(config["market_stress_cooloff_factor"])),
+    }
+
+
+def effective_limits(throttle: dict, config: dict) -> dict:
+    """Base and throttled buy limits: buy size (% of normal), minimum scores and cool-off days."""
+
+    def limit(base: float, effective: float) -> dict:
+        return {"base": base, "effective": round(effective, 4)}
+
+    return {
+        "buy_size_pct": limit(100.0, throttle["buy_size_factor"] * 100),
+        "strategy_min_opp_score": limit(
+            config["strategy_min_opp_score"], min(1.0, config["strategy_min_opp_score"] + throttle["min_score_add"])
+        ),
+        "strategy_core_new_min_score": limit(
+            config["strategy_core_new_min_score"],
+            min(1.0, config["strategy_core_new_min_score"] + throttle["min_score_add"]),
+        ),
+        "strategy_opportunity_cooloff_days": limit(
+            config["strategy_opportunity_cooloff_days"],
+            round(config["strategy_opportunity_cooloff_days"] * throttle["cooloff_factor"]),
+        ),
+        "strategy_core_cooloff_days": limit(
+            config["strategy_core_cooloff_days"],
+            round(config["strategy_core_cooloff_days"] * throttle["cooloff_factor"]),
+        ),
+    }
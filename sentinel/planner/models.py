@@ -16,10 +16,10 @@ class TradeRecommendation:
     current_value_eur: float
     target_value_eur: float
     value_delta_eur: float  # Amount to buy (+) or sell (-)
-    quantity: int  # Number of shares/units to trade (rounded to lot size)
+    quantity: float  # Number of shares/units to trade (rounded to lot size; fractional if supported)
     price: float  # Current price per share
     currency: str  # Security's trading currency
-    lot_size: int  # Minimum lot size
+    lot_size: float  # Minimum lot size (fractional step for fractional securities)
     contrarian_score: float  # Deterministic contrarian signal strength
     priority: float  # Higher = more urgent to act on
     reason: str  # Human-readable explanation
@@ -29,6 +29,14 @@ class TradeRecommendation:
     ticket_pct: Optional[float] = None
     core_floor_active: Optional[bool] = None
     memory_entry: Optional[bool] = None
+    swap_group: Optional[str] = None  # Shared by both legs of a swap; the buy waits for the sell
+    swap_net_cost_eur: Optional[float] = None  # Fees on both legs plus tax on the sold lot
+    swap_score_delta: Optional[float] = None  # Buy score minus sold holding score
+    swap_tax_eur: Optional[float] = None  # Capital gains tax on the sold lot
+    score_components: Optional[dict] = None  # Priority contribution per evaluation component
+    dominant_component: Optional[str] = None  # Component that contributed most to selection
+    fee_eur: Optional[float] = None  # Estimated commission and FX spread from the fee schedule
+    advisory_tags: Optional[list] = None  # Position thesis conditions (target_reached, stop_reached, review_due)
 
 
 @dataclass
@@ -8,6 +8,10 @@ This is a facade that delegates to specialized components:
 
 from __future__ import annotations
 
+import inspect
+import json
+import logging
+from dataclasses import asdict
 from typing import Optional
 
 from sentinel.broker import Broker
@@ -20,6 +24,9 @@ from .allocation import AllocationCalculator
 from .analyzer import PortfolioAnalyzer
 from .models import TradeRecommendation
 from .rebalance import RebalanceEngine
+from .state_hash import batch_cache_key, compute_state_hashes, record_state_check
+
+logger = logging.getLogger(__name__)
 
 
 class Planner:
@@ -30,6 +37,8 @@ class Planner:
         db: Database | None = None,
         broker: Broker | None = None,
         portfolio: Portfolio | None = None,
+        currency: Currency | None = None,
+        settings: Settings | None = None,
     ):
         """Initialize planner with optional dependency injection.
 
@@ -37,12 +46,14 @@ class Planner:
             db: Database instance (uses singleton if None)
             broker: Broker instance (uses singleton if None)
             portfolio: Portfolio instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
         """
         self._db = db or Database()
         self._broker = broker or Broker()
         self._portfolio = portfolio or Portfolio()
-        self._currency = Currency()
-        self._settings = Settings()
+        self._currency = currency or Currency()
+        self._settings = settings or Settings()
 
         # Initialize specialized components
         self._allocation_calculator = AllocationCalculator(
@@ -63,6 +74,7 @@ class Planner:
             settings=self._settings,
             currency=self._currency,
         )
+        self._last_inputs: dict = {}
 
     async def calculate_ideal_portfolio(self, as_of_date: Optional[str] = None) -> dict[str, float]:
         """Calculate ideal portfolio allocations.
@@ -95,10 +107,49 @@ class Planner:
         Returns:
             List of TradeRecommendation, sorted by priority
         """
+        # Live batches are cached by input state, so unchanged inputs skip the whole pipeline.
+        cache_key = None
+        if as_of_date is None:
+            hashes = await compute_state_hashes(self._db)
+            await record_state_check(self._db, hashes)
+            cache_key = batch_cache_key(hashes.state_hash, {"min_trade_value": min_trade_value})
+            maybe_cached = self._db.cache_get(cache_key)
+            if inspect.isawaitable(maybe_cached):
+                cached = await maybe_cached
+                if isinstance(cached, str):
+                    return [TradeRecommendation(**r) for r in json.loads(cached)]
+
+        recommendations = await self._compute_recommendations(min_trade_value, as_of_date)
+
+        if cache_key is not None:
+            await self._record_context(recommendations, min_trade_value)
+            # A batch cut short by the time budget is only the best plan found so far: the next run recomputes it.
+            budget = self._rebalance_engine.last_run_summary.get("time_budget") or {}
+            if not budget.get("truncated"):
+                ttl = await self._settings.get("planner_batch_cache_ttl_seconds", 86400)
+                maybe_set = self._db.cache_set(
+                    cache_key, json.dumps([asdict(r) for r in recommendations]), ttl_seconds=int(ttl or 86400)
+                )
+                if inspect.isawaitable(maybe_set):
+                    await maybe_set
+        return recommendations
+
+    async def _compute_recommendations(
+        self,
+        min_trade_value: Optional[float],
+        as_of_date: Optional[str],
+    ) -> list[TradeRecommendation]:
+        """Run allocation and rebalance for one batch (no batch-level caching)."""
         ideal = await self.calculate_ideal_portfolio(as_of_date=as_of_date)
         current = await self.get_current_allocations(as_of_date=as_of_date)
         total_value = await self._portfolio_analyzer.get_total_value(as_of_date=as_of_date)
         signal_bundle = self._allocation_calculator.get_last_signal_bundle(as_of_date=as_of_date) or {}
+        self._last_inputs = {
+            "ideal": ideal,
+            "current": current,
+            "total_value": total_value,
+            "sleeves": signal_bundle.get("sleeves"),
+        }
 
         return await self._rebalance_engine.get_recommendations(
             ideal=ideal,
@@ -110,10 +161,46 @@ class Planner:
             precomputed_sleeves=signal_bundle.get("sleeves"),
         )
 
+    async def _record_context(self, recommendations: list[TradeRecommendation], min_trade_value) -> None:
+        """Keep the opportunity context of a live computation for /api/planner/context. Never raises."""
+        from .context import DEFAULT_HISTORY, build_context, get_context_log
+
+        try:
+            keep = int(await self._settings.get("planner_context_history", DEFAULT_HISTORY) or 0)
+            if keep <= 0:
+                return
+            context = build_context(
+                self._last_inputs,
+                await self._portfolio_analyzer.get_position_details(),
+                await self._portfolio.get_cash_balances(),
+                await self._portfolio.total_cash_eur(),
+                self.last_quotes,
+                recommendations,
+                min_trade_value=min_trade_value,
+            )
+            context["run_summary"] = self._rebalance_engine.last_run_summary
+            get_context_log().record(context, keep=keep)
+        except Exception as e:
+            logger.warning(f"Failed to record planner context: {e}")
+
+    @property
+    def last_quotes(self) -> dict[str, dict]:
+        """Broker quotes used by the last computed live batch."""
+        return self._rebalance_engine.last_quotes
+
     async def get_rebalance_summary(self) -> dict:
         """Get summary of portfolio alignment with ideal allocations.
 
         Returns:
-            dict with alignment metrics and status
+            dict with alignment metrics and status, plus `last_run`: counts of the
+            last live planner run (recommendations, buys excluded or penalized for
+            stale scores/prices), or None if none is cached
         """
-        return await self._portfolio_analyzer.get_rebalance_summary()
+        summary = await self._portfolio_analyzer.get_rebalance_summary()
+        last_run = None
+        maybe_cached = self._db.cache_get("planner:run_summary")
+        if inspect.isawaitable(maybe_cached):
+            cached = await maybe_cached
+            if isinstance(cached, str):
+                last_run = json.loads(cached)
+        return {**summary, "last_run": last_run}
@@ -6,6 +6,7 @@ import asyncio
 import inspect
 import json
 import logging
+import time
 from dataclasses import asdict
 from datetime import datetime, timezone
 
@@ -21,17 +22,48 @@ from sentinel.strategy import (
     effective_opportunity_score,
     recent_dd252_min,
 )
+from sentinel.utils.fees import FeeSchedule, parse_fee_schedule
+from sentinel.utils.quantity import floor_to_lot, lot_step
 from sentinel.utils.scoring import adjust_score_for_conviction
 
+from .cash_equivalents import (
+    CashEquivalentSettings,
+    cash_equivalent_symbols,
+    plan_liquidation,
+    plan_parking,
+)
+from .currency_exposure import apply_currency_soft_limits, decompose, over_limit_currencies, to_pct
+from .market_stress import NO_THROTTLE, buy_throttle, stress_level, stress_score
 from .models import TradeRecommendation
 from .rebalance_cash import apply_cash_constraint, generate_deficit_sells, get_deficit_sells
 from .rebalance_rules import (
+    RECOMMENDATION_HISTORY_DAYS,
     calculate_priority,
+    calculate_transaction_cost,
     desired_tranche_stage,
+    dominant_component,
     generate_buy_reason,
     generate_sell_reason,
     get_forced_opportunity_exit,
+    is_new_entry,
+    planning_now,
+    score_components,
+    stale_input_reason,
+)
+from .rebalance_strategy import RebalanceStrategySettings, apply_rebalance_strategy
+from .resource_cap import (
+    CapDecision,
+    CapSettings,
+    LatencyTracker,
+    available_memory_mb,
+    decide_candidate_cap,
+    select_candidates,
 )
+from .sector_caps import limit_buys_to_sector_caps, symbol_sector_paths
+from .swaps import SwapSettings, drop_orphaned_swap_legs, plan_swaps
+from .streaming import stream_price_history
+from .theses import apply_thesis_tags
+from .time_budget import STATS_CACHE_KEY, TimeBudget, budget_seconds, evaluation_order, update_stats
 
 logger = logging.getLogger(__name__)
 
@@ -78,11 +110,24 @@ class RebalanceEngine:
         self._portfolio = portfolio or Portfolio()
         self._settings = settings or Settings()
         self._currency = currency or Currency()
+        # Broker quotes used by the last live run (recorded in planner snapshots)
+        self.last_quotes: dict[str, dict] = {}
+        # Counts and reasons of the last live run (stale-input exclusions etc.)
+        self.last_run_summary: dict = {}
+        # Per-symbol evaluation time of recent live runs, for the candidate cap
+        self._latency = LatencyTracker()
+        # Evaluated/pruned swap pair counts of the last run
+        self._swap_search: dict = {}
+        # Rebalance-only trades the rebalancing strategy held back in the last run
+        self._rebalance_held: dict[str, str] = {}
+        # Market stress level and buy throttle of the last live run
+        self._market_stress: dict = {}
 
     async def _load_runtime_settings(self) -> dict[str, float]:
         defaults: dict[str, float] = {
             "transaction_fee_fixed": 2.0,
             "transaction_fee_percent": 0.2,
+            "fx_conversion_spread_pct": 0.0,
             "strategy_lot_standard_max_pct": 0.08,
             "strategy_lot_coarse_max_pct": 0.30,
             "strategy_min_opp_score": 0.55,
@@ -102,6 +147,21 @@ class RebalanceEngine:
             "strategy_core_floor_pct": 0.05,
             "strategy_max_opportunity_buys_per_cycle": 4,
             "strategy_max_new_opportunity_buys_per_cycle": 2,
+            "new_entry_grace_days": 14,
+            "new_entry_min_history_days": 200,
+            "planner_max_score_age_days": 7,
+            "planner_max_price_age_days": 3,
+            "planner_stale_penalty": 1.0,
+            "planner_candidate_cap_max": 0,
+            "planner_min_free_memory_mb": 512,
+            "planner_target_eval_seconds": 120,
+            "planner_candidate_cap_floor": 20,
+            "planner_time_budget_seconds": 0,
+            "market_stress_enabled": 0.0,
+            "market_stress_threshold": 0.4,
+            "market_stress_buy_size_pct": 50.0,
+            "market_stress_min_score_add": 0.1,
+            "market_stress_cooloff_factor": 2.0,
         }
         keys = list(defaults.keys())
         values = await asyncio.gather(*[self._settings.get(k, defaults[k]) for k in keys])
@@ -155,12 +215,23 @@ class RebalanceEngine:
         security_data = {}
 
         all_symbols = list(set(list(ideal.keys()) + list(current.keys())))
+        # Bound live runs by free memory and recent latency so universe growth degrades gracefully.
+        cap_decision = None
+        budget = None
+        if as_of_date is None:
+            all_symbols, cap_decision = self._cap_candidates(all_symbols, ideal, current, settings_ctx)
+            # Anytime planning: most important symbols first, so an expired budget only drops the tail.
+            budget = TimeBudget(
+                budget_seconds(settings_ctx["planner_time_budget_seconds"], await self._planning_interval_minutes())
+            )
+            all_symbols = evaluation_order(all_symbols, set(current), ideal)
 
         # Fetch all data in parallel for performance
         if as_of_date is not None:
             current_quotes = {}
         else:
             current_quotes = await self._broker.get_quotes(all_symbols)
+            self.last_quotes = dict(current_quotes or {})
 
         # Batch-fetch securities and positions
         all_securities = await self._db.get_all_securities(active_only=False)
@@ -169,8 +240,15 @@ class RebalanceEngine:
         all_positions = await self._get_positions_for_context(as_of_date=as_of_date, securities_map=securities_map)
         positions_map = {p["symbol"]: p for p in all_positions}
 
-        fee_fixed = settings_ctx["transaction_fee_fixed"]
-        fee_pct = settings_ctx["transaction_fee_percent"] / 100.0
+        # Cash equivalents are parked cash, not equity holdings: keep them out of per-symbol rebalancing.
+        cash_equivalents = cash_equivalent_symbols(all_securities)
+        all_symbols = [s for s in all_symbols if s not in cash_equivalents]
+
+        fee_schedule = await self._fee_schedule(settings_ctx)
+        self._market_stress = {}
+        if as_of_date is None and settings_ctx["market_stress_enabled"]:
+            self._market_stress = await self._load_market_stress(current, securities_map, settings_ctx)
+        settings_ctx.update({f"stress_{k}": v for k, v in (self._market_stress.get("throttle") or NO_THROTTLE).items()})
         lot_standard_max_pct = settings_ctx["strategy_lot_standard_max_pct"]
         lot_coarse_max_pct = settings_ctx["strategy_lot_coarse_max_pct"]
         min_opp_score = settings_ctx["strategy_min_opp_score"]
@@ -179,47 +257,12 @@ class RebalanceEngine:
         entry_memory_days = int(settings_ctx["strategy_entry_memory_days"])
         memory_max_boost = settings_ctx["strategy_memory_max_boost"]
 
-        # Fetch historical prices: single path via get_prices(end_date=as_of_date).
-        # When as_of_date is None we get latest 250; when set we get only data on or before that date.
+        # Historical prices are streamed per security (get_prices(end_date=as_of_date) semantics):
+        # latest 250 rows when as_of_date is None, otherwise only data on or before that date.
         # As-of/backtest mode uses trusted DB snapshots, so skip expensive validator passes.
         use_price_validation = as_of_date is None
         price_validator = PriceValidator()
 
-        get_prices_multi = getattr(self._db, "get_prices_for_symbols", None)
-        raw_hist_map: dict[str, list[dict]] | None = None
-        if callable(get_prices_multi):
-            maybe_hist = get_prices_multi(all_symbols, days=250, end_date=as_of_date)
-            if inspect.isawaitable(maybe_hist):
-                resolved = await maybe_hist
-                if isinstance(resolved, dict):
-                    raw_hist_map = resolved
-            elif isinstance(maybe_hist, dict):
-                raw_hist_map = maybe_hist
-        if raw_hist_map is None:
-
-            async def get_historical_rows(symbol: str) -> list[dict]:
-                return await self._db.get_prices(symbol, days=250, end_date=as_of_date)
-
-            hist_prices_list = await asyncio.gather(*[get_historical_rows(s) for s in all_symbols])
-            raw_hist_map = {all_symbols[i]: hist_prices_list[i] for i in range(len(all_symbols))}
-
-        hist_prices_map: dict[str, list[dict]] = {}
-        for symbol in all_symbols:
-            raw = raw_hist_map.get(symbol, [])
-            if not raw:
-                hist_prices_map[symbol] = []
-                continue
-            for price in raw:
-                if price.get("close") is not None:
-                    try:
-                        price["close"] = float(price["close"])
-                    except (ValueError, TypeError):
-                        price["close"] = 0.0
-            if use_price_validation:
-                hist_prices_map[symbol] = price_validator.validate_price_series_desc(raw)
-            else:
-                hist_prices_map[symbol] = raw
-
         symbol_signals: dict[str, dict[str, float | int | str]] = {}
         sleeves_map = dict(precomputed_sleeves or {})
         rebalance_signals_map: dict[str, dict[str, float | int | str]] = dict(precomputed_rebalance_signals or {})
@@ -255,13 +298,37 @@ class RebalanceEngine:
         currencies = {(securities_map.get(symbol) or {}).get("currency", "EUR") for symbol in all_symbols}
         fx_values = await asyncio.gather(*[self._currency.get_rate(currency) for currency in currencies])
         fx_rates = {currency: rate for currency, rate in zip(currencies, fx_values, strict=False)}
-        # Process each symbol
-        for symbol in all_symbols:
+        recommendations = []
+        now_ts = planning_now().timestamp()
+        stale_penalty = max(0.0, settings_ctx["planner_stale_penalty"])
+        stale_excluded: dict[str, str] = {}
+        stale_penalized: dict[str, str] = {}
+
+        # Stream each symbol through signal -> market context -> recommendation so that only
+        # one chunk of price history is resident at a time.
+        evaluation_started = time.monotonic()
+        evaluated = 0
+        async for symbol, raw in stream_price_history(
+            self._db, all_symbols, days=RECOMMENDATION_HISTORY_DAYS, end_date=as_of_date
+        ):
+            if budget is not None and budget.expired():
+                budget.truncate("symbols", evaluated, len(all_symbols))
+                break
+            evaluated += 1
             sec = securities_map.get(symbol)
             pos = positions_map.get(symbol)
             conviction = self._normalize_conviction(sec.get("user_multiplier", 0.5) if sec else 0.5)
 
-            hist_rows = hist_prices_map.get(symbol, [])
+            for price_row in raw:
+                if price_row.get("close") is not None:
+                    try:
+                        price_row["close"] = float(price_row["close"])
+                    except (ValueError, TypeError):
+                        price_row["close"] = 0.0
+            if raw and use_price_validation:
+                hist_rows = price_validator.validate_price_series_desc(raw)
+            else:
+                hist_rows = raw
             closes = [float(r["close"]) for r in reversed(hist_rows) if r.get("close") is not None]
             cached_signal = rebalance_signals_map.get(symbol)
             if isinstance(cached_signal, dict):
@@ -297,9 +364,11 @@ class RebalanceEngine:
 
             symbol_currency = sec.get("currency", "EUR") if sec else "EUR"
             fx_rate = fx_rates.get(symbol_currency, 1.0)
+            lot_size = lot_step(sec.get("min_lot", 1), sec.get("supports_fractional", 0)) if sec else 1
+            fee_fixed, fee_pct = fee_schedule.rates(lot_size * price * fx_rate, symbol, symbol_currency)
             lot_profile = classify_lot_size(
                 price=price,
-                lot_size=sec.get("min_lot", 1) if sec else 1,
+                lot_size=lot_size,
                 fx_rate_to_eur=fx_rate,
                 portfolio_value_eur=total_value,
                 fee_fixed_eur=fee_fixed,
@@ -309,7 +378,7 @@ class RebalanceEngine:
             )
             signal["ticket_pct"] = float(lot_profile["ticket_pct"])
             signal["lot_class"] = str(lot_profile["lot_class"])
-            signal["lot_size"] = int(sec.get("min_lot", 1) if sec else 1)
+            signal["lot_size"] = lot_size
             cached_sleeve = sleeves_map.get(symbol)
             if cached_sleeve is None:
                 cached_sleeve = "opportunity" if effective_score >= min_opp_score else "core"
@@ -322,7 +391,7 @@ class RebalanceEngine:
                 "price": price,
                 "currency": sec.get("currency", "EUR") if sec else "EUR",
                 "fx_rate": fx_rate,
-                "lot_size": sec.get("min_lot", 1) if sec else 1,
+                "lot_size": lot_size,
                 "current_qty": pos.get("quantity", 0) if pos else 0,
                 "avg_cost": pos.get("avg_cost", 0) if pos else 0,
                 "allow_buy": sec.get("allow_buy", 1) if sec else 1,
@@ -333,12 +402,17 @@ class RebalanceEngine:
                 "ticket_pct": lot_profile["ticket_pct"],
                 "min_ticket_eur": lot_profile["min_ticket_eur"],
                 "state": strategy_states.get(symbol) or {},
+                # Backtests and as-of views replay history, so only live planning quarantines new entries
+                "new_entry": as_of_date is None
+                and is_new_entry(
+                    sec,
+                    len(closes),
+                    now_ts=planning_now().timestamp(),
+                    grace_days=settings_ctx["new_entry_grace_days"],
+                    min_history_days=int(settings_ctx["new_entry_min_history_days"]),
+                ),
             }
 
-        # Generate recommendations
-        recommendations = []
-
-        for symbol in all_symbols:
             rec = await self._build_recommendation(
                 symbol,
                 ideal,
@@ -351,9 +425,39 @@ class RebalanceEngine:
                 settings_ctx=settings_ctx,
                 latest_trade=latest_trades_map.get(symbol),
                 as_of_date=as_of_date,
+                fee_schedule=fee_schedule,
             )
+            # Live buys must not act on a score or price that stopped updating
+            if rec and rec.action == "buy" and as_of_date is None:
+                quote = current_quotes.get(symbol) or {}
+                stale_reason = stale_input_reason(
+                    hist_rows[0].get("date") if hist_rows else None,
+                    now_ts if float(quote.get("price") or 0) > 0 else (sec or {}).get("quote_updated_at"),
+                    now_ts=now_ts,
+                    max_score_age_days=settings_ctx["planner_max_score_age_days"],
+                    max_price_age_days=settings_ctx["planner_max_price_age_days"],
+                )
+                if stale_reason and stale_penalty >= 1.0:
+                    stale_excluded[symbol] = stale_reason
+                    rec = None
+                elif stale_reason:
+                    stale_penalized[symbol] = stale_reason
+                    rec.priority *= 1.0 - stale_penalty
+                    rec.reason = f"{rec.reason} (stale: {stale_reason})"
             if rec:
                 recommendations.append(rec)
+        if as_of_date is None:
+            self._latency.record(time.monotonic() - evaluation_started, evaluated)
+
+        # The rebalancing strategy decides whether drift alone justifies a trade this run
+        recommendations = await self._apply_rebalance_strategy(recommendations, as_of_date=as_of_date)
+
+        # Soft currency limits: prefer trades that reduce an over-limit currency
+        await self._apply_currency_soft_limits(recommendations, current, securities_map)
+
+        # Position theses: advisory tags, and sells at the target price come first
+        if as_of_date is None:
+            await self._apply_thesis_tags(recommendations, now_ts)
 
         # Sort: SELL first, then by priority
         recommendations.sort(key=lambda x: (0 if x.action == "sell" else 1, -x.priority))
@@ -377,6 +481,35 @@ class RebalanceEngine:
             max_new_opp_buys=int(settings_ctx["strategy_max_new_opportunity_buys_per_cycle"]),
         )
 
+        # Keep buys within hierarchical sector / industry group caps.
+        recommendations = await self._apply_sector_caps(
+            recommendations,
+            current=current,
+            total_value=total_value,
+            securities=all_securities,
+            min_trade_value=min_trade_value,
+        )
+
+        # Pair unfunded buys with weak holdings to sell (swap trades).
+        recommendations = await self._apply_swaps(
+            recommendations,
+            positions=all_positions,
+            security_data=security_data,
+            contrarian_scores=contrarian_scores,
+            current=current,
+            as_of_date=as_of_date,
+            max_evaluations=cap_decision.cap if cap_decision and cap_decision.capped else 0,
+            budget=budget,
+        )
+
+        # Sell cash equivalents before any equity when cash cannot cover the buys.
+        cash_equivalent_instruments = await self._cash_equivalent_instruments(
+            cash_equivalents, securities_map, positions_map, current_quotes, total_value
+        )
+        recommendations = await self._liquidate_cash_equivalents(
+            recommendations, cash_equivalent_instruments, as_of_date=as_of_date
+        )
+
         # Apply cash constraint (including optional funding sells)
         recommendations = await self._apply_cash_constraint(
             recommendations,
@@ -400,8 +533,17 @@ class RebalanceEngine:
             },
         )
 
+        # A swap is a dependent basket: never keep one leg without the other.
+        recommendations = drop_orphaned_swap_legs(recommendations)
+
+        # Nothing cleared the bar: park idle cash in a cash equivalent.
+        recommendations = await self._park_idle_cash(
+            recommendations, cash_equivalent_instruments, as_of_date=as_of_date
+        )
+
         # Cache result only when live (not as_of_date)
         if as_of_date is None:
+            await self._record_run_summary(recommendations, stale_excluded, stale_penalized, cap_decision, budget)
             cache_key = self._recommendation_cache_key(min_trade_value)
             cache_setter = getattr(self._db, "cache_set", None)
             if callable(cache_setter):
@@ -414,6 +556,236 @@ class RebalanceEngine:
                     await maybe_set
         return recommendations
 
+    async def _record_run_summary(
+        self,
+        recommendations: list[TradeRecommendation],
+        stale_excluded: dict[str, str],
+        stale_penalized: dict[str, str],
+        cap_decision: CapDecision | None = None,
+        budget: TimeBudget | None = None,
+    ) -> None:
+        """Keep counts of the last live run for the planner summary."""
+        if stale_excluded:
+            logger.info(f"Excluded {len(stale_excluded)} buy candidate(s) for stale inputs: {stale_excluded}")
+        self.last_run_summary = {
+            "generated_at": int(planning_now().timestamp()),
+            "recommendations": len(recommendations),
+            "excluded_stale": len(stale_excluded),
+            "penalized_stale": len(stale_penalized),
+            "stale": {**stale_excluded, **stale_penalized},
+            "candidate_cap": cap_decision.as_dict() if cap_decision else None,
+            "time_budget": budget.as_dict() if budget else None,
+            "swap_search": self._swap_search or None,
+            "rebalance_held": self._rebalance_held or None,
+            "market_stress": self._market_stress or None,
+        }
+        cache_setter = getattr(self._db, "cache_set", None)
+        if callable(cache_setter):
+            maybe_set = cache_setter("planner:run_summary", json.dumps(self.last_run_summary), ttl_seconds=86400)
+            if inspect.isawaitable(maybe_set):
+                await maybe_set
+        if budget is not None:
+            await self._record_time_budget(budget)
+
+    async def _load_market_stress(
+        self, current: dict[str, float], securities_map: dict[str, dict], settings_ctx: dict
+    ) -> dict:
+        """Stress level from the stored regimes, weighted by current allocations, and the buy throttle it calls for."""
+        getter = getattr(self._db, "get_regime_states", None)
+        states = getter() if callable(getter) else []
+        if inspect.isawaitable(states):
+            states = await states
+        if not isinstance(states, list) or not states:
+            return {}
+        score, regions = stress_score(states, current, securities_map)
+        level = stress_level(score, settings_ctx["market_stress_threshold"])
+        throttle = buy_throttle(level, settings_ctx)
+        if throttle != NO_THROTTLE:
+            logger.info(f"Market stress {score:.2f} ({level}): throttling buys {throttle}")
+        return {"level": level, "score": score, "regions": regions, "throttle": throttle}
+
+    async def _record_time_budget(self, budget: TimeBudget) -> None:
+        """Fold this run's budget outcome into the truncation counters."""
+        run = budget.as_dict()
+        if budget.truncated:
+            logger.warning(
+                f"Planner time budget of {budget.seconds:.0f}s expired during {budget.truncated_stage} "
+                f"({run['evaluated']}/{run['total']} evaluated); using the best plan found so far"
+            )
+        cache_getter = getattr(self._db, "cache_get", None)
+        cache_setter = getattr(self._db, "cache_set", None)
+        if not callable(cache_getter) or not callable(cache_setter):
+            return
+        previous = cache_getter(STATS_CACHE_KEY)
+        if inspect.isawaitable(previous):
+            previous = await previous
+        stats = update_stats(json.loads(previous) if isinstance(previous, str) else None, run, int(time.time()))
+        maybe_set = cache_setter(STATS_CACHE_KEY, json.dumps(stats), ttl_seconds=30 * 86400)
+        if inspect.isawaitable(maybe_set):
+            await maybe_set
+
+    async def _planning_interval_minutes(self) -> float | None:
+        """Shortest configured interval of the planning:refresh job (None when unknown)."""
+        getter = getattr(self._db, "get_job_schedule", None)
+        if not callable(getter):
+            return None
+        schedule = getter("planning:refresh")
+        if inspect.isawaitable(schedule):
+            schedule = await schedule
+        if not isinstance(schedule, dict):
+            return None
+        intervals = [
+            float(v)
+            for v in (schedule.get("interval_minutes"), schedule.get("interval_market_open_minutes"))
+            if isinstance(v, (int, float)) and v > 0
+        ]
+        return min(intervals) if intervals else None
+
+    async def _fee_schedule(self, settings_ctx: dict[str, float]) -> FeeSchedule:
+        """Flat fees from the runtime settings plus the per-market schedule (ignored when malformed)."""
+        try:
+            markets = parse_fee_schedule(await self._settings.get("fee_schedule", {}))
+        except ValueError:
+            markets = {}
+        return FeeSchedule(
+            fixed=settings_ctx["transaction_fee_fixed"],
+            pct=settings_ctx["transaction_fee_percent"] / 100.0,
+            markets=markets,
+            fx_spread_pct=settings_ctx["fx_conversion_spread_pct"] / 100.0,
+        )
+
+    async def _cash_equivalent_settings(self) -> CashEquivalentSettings | None:
+        """Parking/liquidation parameters, or None while cash_equivalents_enabled is off."""
+        if not await self._settings.get("cash_equivalents_enabled", False):
+            return None
+        return CashEquivalentSettings(
+            reserve_eur=float(await self._settings.get("cash_equivalent_reserve_eur", 500.0)),
+            min_park_eur=float(await self._settings.get("cash_equivalent_min_park_eur", 250.0)),
+            fee_fixed=float(await self._settings.get("transaction_fee_fixed", 2.0)),
+            fee_pct=float(await self._settings.get("transaction_fee_percent", 0.2)) / 100.0,
+        )
+
+    async def _cash_equivalent_instruments(
+        self,
+        symbols: set[str],
+        securities_map: dict[str, dict],
+        positions_map: dict[str, dict],
+        quotes: dict[str, dict],
+        total_value: float,
+    ) -> list[dict]:
+        """Price, lot and holding data of each cash-equivalent security (held ones first, by value)."""
+        instruments = []
+        for symbol in sorted(symbols):
+            sec = securities_map.get(symbol) or {}
+            pos = positions_map.get(symbol) or {}
+            price = float((quotes.get(symbol) or {}).get("price") or pos.get("current_price") or 0.0)
+            if price <= 0:
+                continue
+            currency = sec.get("currency", "EUR")
+            fx_rate = await self._currency.get_rate(currency) if currency != "EUR" else 1.0
+            quantity = float(pos.get("quantity", 0) or 0)
+            instruments.append(
+                {
+                    "symbol": symbol,
+                    "quantity": quantity,
+                    "price": price,
+                    "fx_rate": fx_rate,
+                    "currency": currency,
+                    "lot_size": lot_step(sec.get("min_lot", 1), sec.get("supports_fractional", 0)),
+                    "allow_buy": sec.get("allow_buy", 1),
+                    "allow_sell": sec.get("allow_sell", 1),
+                    "current_value_eur": quantity * price * fx_rate,
+                    "total_value_eur": total_value,
+                }
+            )
+        instruments.sort(key=lambda i: -i["current_value_eur"])
+        return instruments
+
+    async def _cash_eur(self, as_of_date: str | None = None) -> float:
+        total = 0.0
+        for currency, amount in (await self._get_cash_balances_for_context(as_of_date=as_of_date)).items():
+            total += await self._currency.to_eur(float(amount), currency)
+        return total
+
+    async def _liquidate_cash_equivalents(
+        self,
+        recommendations: list[TradeRecommendation],
+        instruments: list[dict],
+        as_of_date: str | None = None,
+    ) -> list[TradeRecommendation]:
+        """Prepend cash-equivalent sells covering buys that cash and sell proceeds cannot fund."""
+        config = await self._cash_equivalent_settings()
+        buys = [r for r in recommendations if r.action == "buy"]
+        if config is None or not buys or not instruments:
+            return recommendations
+        fee_fixed, fee_pct = config.fee_fixed, config.fee_pct
+        costs = sum(r.value_delta_eur + calculate_transaction_cost(r.value_delta_eur, fee_fixed, fee_pct) for r in buys)
+        proceeds = sum(
+            abs(r.value_delta_eur) - calculate_transaction_cost(abs(r.value_delta_eur), fee_fixed, fee_pct)
+            for r in recommendations
+            if r.action == "sell"
+        )
+        shortfall = costs - (await self._cash_eur(as_of_date=as_of_date) + proceeds)
+        if shortfall <= 0:
+            return recommendations
+        traded = {r.symbol for r in recommendations}
+        holdings = [i for i in instruments if i["quantity"] > 0 and i["symbol"] not in traded]
+        sells = plan_liquidation(shortfall + self.BALANCE_BUFFER_EUR, holdings, config)
+        if sells:
+            logger.info(f"Liquidating {len(sells)} cash equivalent(s) to cover a {shortfall:.0f} EUR funding gap")
+        return sells + recommendations
+
+    async def _park_idle_cash(
+        self,
+        recommendations: list[TradeRecommendation],
+        instruments: list[dict],
+        as_of_date: str | None = None,
+    ) -> list[TradeRecommendation]:
+        """Append a cash-equivalent buy for idle cash when no buy was planned."""
+        config = await self._cash_equivalent_settings()
+        if config is None or any(r.action == "buy" for r in recommendations):
+            return recommendations
+        park_symbol = await self._settings.get("cash_equivalent_park_symbol", "") or ""
+        traded = {r.symbol for r in recommendations}
+        candidates = [i for i in instruments if i["allow_buy"] and i["symbol"] not in traded]
+        if park_symbol:
+            candidates = [i for i in candidates if i["symbol"] == park_symbol]
+        if not candidates:
+            return recommendations
+        park = plan_parking(await self._cash_eur(as_of_date=as_of_date), candidates[0], config)
+        return recommendations + [park] if park else recommendations
+
+    def _cap_candidates(
+        self,
+        symbols: list[str],
+        ideal: dict[str, float],
+        current: dict[str, float],
+        settings_ctx: dict[str, float],
+    ) -> tuple[list[str], CapDecision]:
+        """Drop the lowest-weighted buy candidates when memory or time is short (held symbols stay)."""
+        held = set(current)
+        decision = decide_candidate_cap(
+            sum(1 for s in symbols if s not in held),
+            CapSettings(
+                max_candidates=int(settings_ctx["planner_candidate_cap_max"]),
+                min_free_memory_mb=settings_ctx["planner_min_free_memory_mb"],
+                target_seconds=settings_ctx["planner_target_eval_seconds"],
+                floor=int(settings_ctx["planner_candidate_cap_floor"]),
+            ),
+            available_mb=available_memory_mb(),
+            seconds_per_symbol=self._latency.seconds_per_symbol(),
+            held=len(held & set(symbols)),
+        )
+        if not decision.capped:
+            logger.debug(f"Planner candidate cap not needed: {decision.as_dict()}")
+            return symbols, decision
+        kept, dropped = select_candidates(symbols, held, ideal, decision.cap)
+        logger.warning(
+            f"Capping planner candidates at {decision.cap} of {decision.candidates} "
+            f"({'; '.join(decision.reasons)}); skipped {len(dropped)} lowest-weighted"
+        )
+        return kept, decision
+
     async def _apply_opportunity_buy_throttle(
         self,
         recommendations: list[TradeRecommendation],
@@ -452,6 +824,134 @@ class RebalanceEngine:
         buys.sort(key=lambda r: float(r.priority), reverse=True)
         return sells + buys
 
+    async def _apply_rebalance_strategy(
+        self, recommendations: list[TradeRecommendation], as_of_date: str | None = None
+    ) -> list[TradeRecommendation]:
+        """Drop rebalance-only trades outside the configured calendar window or threshold band."""
+        self._rebalance_held = {}
+        config = RebalanceStrategySettings(
+            strategy=await self._settings.get("rebalance_strategy", "continuous"),
+            calendar_period=await self._settings.get("rebalance_calendar_period", "quarterly"),
+            calendar_window_days=int(await self._settings.get("rebalance_calendar_window_days", 7)),
+            threshold_pct=float(await self._settings.get("rebalance_threshold_pct", 5)),
+            band_mode=await self._settings.get("rebalance_band_mode", "absolute"),
+        )
+        today = datetime.strptime(as_of_date, "%Y-%m-%d").date() if as_of_date else planning_now().date()
+        recommendations, held = apply_rebalance_strategy(recommendations, today, config)
+        if held:
+            logger.info(f"Rebalancing strategy {config.strategy} held back {len(held)} trade(s): {held}")
+        self._rebalance_held = held
+        return recommendations
+
+    async def _apply_currency_soft_limits(
+        self,
+        recommendations: list[TradeRecommendation],
+        current: dict[str, float],
+        securities_map: dict[str, dict],
+    ) -> None:
+        """Reweight priorities for currencies above currency_exposure_limits (opt-in soft constraint)."""
+        if not recommendations or not await self._settings.get("currency_exposure_soft_constraints", False):
+            return
+        limits = await self._settings.get("currency_exposure_limits", {}) or {}
+        if not limits:
+            return
+        basis = await self._settings.get("currency_exposure_basis", "listing") or "listing"
+        penalty = float(await self._settings.get("currency_exposure_penalty", 0.5) or 0.0)
+        over_limit = over_limit_currencies(to_pct(decompose(current, securities_map, basis)), limits)
+        apply_currency_soft_limits(recommendations, securities_map, over_limit, basis=basis, penalty=penalty)
+
+    async def _apply_thesis_tags(self, recommendations: list[TradeRecommendation], now_ts: float) -> None:
+        """Tag recommendations with their position thesis conditions and boost sells at target."""
+        theses_getter = getattr(self._db, "get_position_theses", None)
+        if not recommendations or not callable(theses_getter):
+            return
+        theses = theses_getter()
+        if inspect.isawaitable(theses):
+            theses = await theses
+        if not isinstance(theses, dict) or not theses:
+            return
+        boost = float(await self._settings.get("thesis_target_sell_boost", 0.5) or 0.0)
+        apply_thesis_tags(recommendations, theses, now_ts, sell_boost=boost)
+
+    async def _apply_sector_caps(
+        self,
+        recommendations: list[TradeRecommendation],
+        current: dict[str, float],
+        total_value: float,
+        securities: list[dict],
+        min_trade_value: float,
+    ) -> list[TradeRecommendation]:
+        """Shrink or drop buys that would breach a sector / industry group cap."""
+        caps_getter = getattr(self._db, "get_sector_caps", None)
+        if not callable(caps_getter):
+            return recommendations
+        caps = caps_getter()
+        if inspect.isawaitable(caps):
+            caps = await caps
+        if not isinstance(caps, dict) or not caps:
+            return recommendations
+        return limit_buys_to_sector_caps(
+            recommendations,
+            current,
+            total_value,
+            symbol_sector_paths(securities),
+            caps,
+            min_trade_value=min_trade_value,
+        )
+
+    async def _apply_swaps(
+        self,
+        recommendations: list[TradeRecommendation],
+        positions: list[dict],
+        security_data: dict[str, dict],
+        contrarian_scores: dict[str, float],
+        current: dict[str, float],
+        as_of_date: str | None = None,
+        max_evaluations: int = 0,
+        budget: TimeBudget | None = None,
+    ) -> list[TradeRecommendation]:
+        """Propose swap sells for buys that available cash cannot cover."""
+        self._swap_search = {}
+        if not await self._settings.get("strategy_swaps_enabled", False):
+            return recommendations
+        if budget is not None and budget.stop_check("swaps")():
+            return recommendations
+        config = SwapSettings(
+            max_sell_score=float(await self._settings.get("strategy_swap_max_sell_score", 0.2)),
+            min_score_delta=float(await self._settings.get("strategy_swap_min_score_delta", 0.25)),
+            max_cost_pct=float(await self._settings.get("strategy_swap_max_cost_pct", 0.02)),
+            tax_rate=float(await self._settings.get("capital_gains_tax_pct", 0.0)) / 100.0,
+            fee_fixed=float(await self._settings.get("transaction_fee_fixed", 2.0)),
+            fee_pct=float(await self._settings.get("transaction_fee_percent", 0.2)) / 100.0,
+            max_evaluations=max_evaluations,
+            should_stop=budget.stop_check("swaps") if budget is not None else None,
+        )
+
+        holdings = []
+        for pos in positions:
+            data = security_data.get(pos["symbol"])
+            if not data or data.get("trade_blocked"):
+                continue
+            holdings.append(
+                {
+                    "symbol": pos["symbol"],
+                    "quantity": float(pos.get("quantity", 0) or 0),
+                    "price": float(data.get("price", 0.0) or 0.0),
+                    "avg_cost": float(pos.get("avg_cost", 0) or 0),
+                    "fx_rate": float(data.get("fx_rate", 1.0) or 1.0),
+                    "currency": data.get("currency", "EUR"),
+                    "lot_size": data.get("lot_size", 1),
+                    "score": float(contrarian_scores.get(pos["symbol"], 0.0)),
+                    "allow_sell": data.get("allow_sell", 1),
+                    "current_allocation": float(current.get(pos["symbol"], 0.0)),
+                }
+            )
+
+        cash_eur = 0.0
+        for currency, amount in (await self._get_cash_balances_for_context(as_of_date=as_of_date)).items():
+            cash_eur += await self._currency.to_eur(float(amount), currency)
+        return plan_swaps(recommendations, holdings, cash_eur, config, stats=self._swap_search)
+
     def _get_price(
         self,
         symbol: str,
@@ -509,6 +1009,7 @@ class RebalanceEngine:
         settings_ctx: dict[str, float],
         latest_trade: dict | None = None,
         as_of_date: str | None = None,
+        fee_schedule: FeeSchedule | None = None,
     ) -> TradeRecommendation | None:
         """Build a single trade recommendation for a symbol."""
         current_alloc = current.get(symbol, 0)
@@ -577,6 +1078,9 @@ class RebalanceEngine:
         else:
             cooloff_days = int(settings_ctx["strategy_core_cooloff_days"])
         action_for_cooloff = "sell" if forced_sell_qty > 0 else ("buy" if delta > 0 else "sell")
+        if action_for_cooloff == "buy":
+            # Market stress stretches the time between buys
+            cooloff_days = round(cooloff_days * settings_ctx.get("stress_cooloff_factor", 1.0))
         is_blocked, _ = await self._check_cooloff_violation(
             symbol,
             action_for_cooloff,
@@ -587,7 +1091,7 @@ class RebalanceEngine:
         if is_blocked:
             return None
 
-        if delta > 0 and forced_sell_qty <= 0 and not allow_buy:
+        if delta > 0 and forced_sell_qty <= 0 and (not allow_buy or sec_data.get("new_entry")):
             return None
         if (delta < 0 or forced_sell_qty > 0) and not allow_sell:
             return None
@@ -603,7 +1107,7 @@ class RebalanceEngine:
             rounded_qty = forced_sell_qty
         else:
             raw_qty = abs(local_value_delta) / price
-            rounded_qty = (int(raw_qty) // lot_size) * lot_size
+            rounded_qty = floor_to_lot(raw_qty, lot_size)
 
         if rounded_qty < lot_size:
             return None
@@ -614,9 +1118,13 @@ class RebalanceEngine:
                 return None
 
         if delta > 0 and forced_sell_qty <= 0:
+            # Market stress raises the score a buy needs
+            score_add = settings_ctx.get("stress_min_score_add", 0.0)
+            if score_add and sleeve == "opportunity" and opp_score < min_opp_score + score_add:
+                return None
             # For new core names, require minimum contrarian quality to avoid pure drift-driven churn.
             if sleeve == "core" and current_alloc <= 1e-6 and lot_class == "standard":
-                core_new_min_score = settings_ctx["strategy_core_new_min_score"]
+                core_new_min_score = settings_ctx["strategy_core_new_min_score"] + score_add
                 core_new_min_dip = settings_ctx["strategy_core_new_min_dip_score"]
                 dip_score = float(signal.get("dip_score", 0.0) or 0.0)
                 cycle_turn = int(signal.get("cycle_turn", 0) or 0)
@@ -656,9 +1164,15 @@ class RebalanceEngine:
                 # Allow stacking for very strong opportunities.
                 if opp_score < 0.8:
                     max_new_lots = int(settings_ctx["strategy_coarse_max_new_lots_per_cycle"])
-                    rounded_qty = min(rounded_qty, max_new_lots * lot_size)
+                    rounded_qty = min(rounded_qty, floor_to_lot(max_new_lots * lot_size, lot_size))
                     if rounded_qty < lot_size:
                         return None
+            # Market stress shrinks buys
+            size_factor = settings_ctx.get("stress_buy_size_factor", 1.0)
+            if size_factor < 1.0:
+                rounded_qty = floor_to_lot(rounded_qty * size_factor, lot_size)
+                if rounded_qty < lot_size:
+                    return None
 
         core_floor_active = False
         if delta < 0 or forced_sell_qty > 0:
@@ -674,10 +1188,10 @@ class RebalanceEngine:
                     max_sell_local = max_sell_value_eur / fx_rate if fx_rate > 0 else max_sell_value_eur
                 else:
                     max_sell_local = max_sell_value_eur
-                max_sell_qty = (int(max_sell_local / price) // lot_size) * lot_size
+                max_sell_qty = floor_to_lot(max_sell_local / price, lot_size)
                 # Keep at least one lot for held core positions.
                 if current_qty >= lot_size:
-                    max_sell_qty = min(max_sell_qty, max(0, current_qty - lot_size))
+                    max_sell_qty = min(max_sell_qty, floor_to_lot(current_qty - lot_size, lot_size))
                 rounded_qty = min(rounded_qty, max_sell_qty)
                 if rounded_qty < lot_size:
                     return None
@@ -704,7 +1218,7 @@ class RebalanceEngine:
                     max_buy_local = max_buy_eur / fx_rate if fx_rate > 0 else max_buy_eur
                 else:
                     max_buy_local = max_buy_eur
-                capped_qty = (int(max_buy_local / price) // lot_size) * lot_size
+                capped_qty = floor_to_lot(max_buy_local / price, lot_size)
                 if capped_qty < lot_size:
                     return None
                 rounded_qty = capped_qty
@@ -758,6 +1272,16 @@ class RebalanceEngine:
             allocation_delta=delta,
             contrarian_score=contrarian_score,
         )
+        components = score_components(
+            action=action,
+            allocation_delta=delta,
+            contrarian_score=contrarian_score,
+            signal=signal,
+        )
+        # Rank net of fees: a trade whose fees eat a visible share of its value is worth less
+        fee_eur = fee_schedule.cost(actual_value_eur, symbol, currency) if fee_schedule else None
+        if fee_eur is not None and actual_value_eur > 0:
+            priority *= max(0.0, 1.0 - fee_eur / actual_value_eur)
 
         return TradeRecommendation(
             symbol=symbol,
@@ -781,6 +1305,9 @@ class RebalanceEngine:
             ticket_pct=ticket_pct,
             core_floor_active=core_floor_active,
             memory_entry=memory_entry,
+            score_components=components,
+            dominant_component=dominant_component(components),
+            fee_eur=round(fee_eur, 2) if fee_eur is not None else None,
         )
 
     async def _check_cooloff_violation(
@@ -820,7 +1347,7 @@ class RebalanceEngine:
         if as_of_date is not None:
             current_date = datetime.strptime(as_of_date, "%Y-%m-%d")
         else:
-            current_date = datetime.now()
+            current_date = planning_now()
 
         days_since = (current_date - last_date).days
 
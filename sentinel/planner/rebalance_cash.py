@@ -3,10 +3,10 @@
 from __future__ import annotations
 
 import inspect
-import math
 from typing import TYPE_CHECKING
 
 from sentinel.strategy import compute_contrarian_signal
+from sentinel.utils.quantity import ceil_to_lot, floor_to_lot, lot_step
 
 from .models import TradeRecommendation
 from .rebalance_rules import calculate_transaction_cost
@@ -195,7 +195,7 @@ async def apply_cash_constraint(
             continue
         else:
             lots_needed = int(min_trade_value / one_lot_eur) + 1
-            min_qty = lots_needed * buy.lot_size
+            min_qty = ceil_to_lot(lots_needed * buy.lot_size, buy.lot_size)
             min_eur = lots_needed * one_lot_eur
 
         if min_qty > buy.quantity:
@@ -256,7 +256,7 @@ async def apply_cash_constraint(
             local_value = allocated_eur
 
         raw_qty = local_value / buy.price
-        rounded_qty = (int(raw_qty) // buy.lot_size) * buy.lot_size
+        rounded_qty = floor_to_lot(raw_qty, buy.lot_size)
 
         if rounded_qty < buy.lot_size:
             continue
@@ -317,10 +317,16 @@ async def apply_cash_constraint(
                 )
             else:
                 one_lot_eur = one_lot_local
+            increment = buy.lot_size
+            if not float(buy.lot_size).is_integer() and one_lot_eur > 0:
+                # Fractional: top up by as many steps as the leftover affords at once
+                affordable_eur = (leftover - fixed_fee) / (1 + pct_fee)
+                increment = max(buy.lot_size, floor_to_lot(affordable_eur / one_lot_eur * buy.lot_size, buy.lot_size))
+                one_lot_eur = one_lot_eur * increment / buy.lot_size
             one_lot_cost = one_lot_eur + calculate_transaction_cost(one_lot_eur, fixed_fee, pct_fee)
 
             if one_lot_cost <= leftover:
-                new_qty = buy.quantity + buy.lot_size
+                new_qty = floor_to_lot(buy.quantity + increment, buy.lot_size)
                 new_local_value = new_qty * buy.price
                 if buy.currency != "EUR":
                     rate = fx_rates.get(buy.currency, 0.0)
@@ -469,7 +475,7 @@ async def generate_deficit_sells(
             continue
 
         currency = sec.get("currency", "EUR")
-        lot_size = sec.get("min_lot", 1)
+        lot_size = lot_step(sec.get("min_lot", 1), sec.get("supports_fractional", 0))
         if preloaded_symbol_scores is not None and symbol in preloaded_symbol_scores:
             score = float(preloaded_symbol_scores[symbol])
         else:
@@ -544,7 +550,7 @@ async def generate_deficit_sells(
         score = pos["score"]
 
         if eur_value <= remaining_deficit:
-            sell_qty = (qty // lot_size) * lot_size
+            sell_qty = floor_to_lot(qty, lot_size)
         else:
             rate = await engine._currency.get_rate(currency)
             if rate > 0:
@@ -552,7 +558,7 @@ async def generate_deficit_sells(
             else:
                 local_needed = remaining_deficit
             shares_needed = local_needed / price
-            sell_qty = math.ceil(shares_needed / lot_size) * lot_size
+            sell_qty = ceil_to_lot(shares_needed, lot_size)
             sell_qty = min(sell_qty, qty)
 
         if sell_qty < lot_size:
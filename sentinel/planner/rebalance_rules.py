@@ -2,8 +2,30 @@
 
 from __future__ import annotations
 
+from contextlib import contextmanager
+from contextvars import ContextVar
 from datetime import datetime
-from typing import Any
+from typing import Any, Iterator
+
+from sentinel.utils.quantity import floor_to_lot
+
+# Overrides "now" for live-mode planning (set while replaying a past planner run)
+_planning_now: ContextVar[datetime | None] = ContextVar("planning_now", default=None)
+
+
+def planning_now() -> datetime:
+    """Current time as seen by live-mode planning rules."""
+    return _planning_now.get() or datetime.now()
+
+
+@contextmanager
+def frozen_planning_now(moment: datetime) -> Iterator[None]:
+    """Evaluate live-mode planning rules as if it were `moment`."""
+    token = _planning_now.set(moment)
+    try:
+        yield
+    finally:
+        _planning_now.reset(token)
 
 
 def desired_tranche_stage(dd252: float, t1: float = -0.12, t2: float = -0.20, t3: float = -0.28) -> int:
@@ -21,7 +43,7 @@ def get_forced_opportunity_exit(
     *,
     signal: dict[str, float | int | str],
     state: dict[str, Any],
-    current_qty: int,
+    current_qty: float,
     price: float,
     avg_cost: float,
     as_of_date: str | None,
@@ -39,25 +61,25 @@ def get_forced_opportunity_exit(
     scaleout_stage = int(state.get("scaleout_stage", 0) or 0)
     mom20 = float(signal.get("mom20", 0.0) or 0.0)
     mom60 = float(signal.get("mom60", 0.0) or 0.0)
-    lot_size = int(signal.get("lot_size", 1) or 1)
+    lot_size = signal.get("lot_size", 1) or 1
 
     if scaleout_stage < 1 and gain >= 0.10:
         return {
-            "quantity": max(lot_size, int((int(current_qty * 0.30) // lot_size) * lot_size)),
+            "quantity": max(lot_size, floor_to_lot(current_qty * 0.30, lot_size)),
             "reason": "Opportunity scale-out T1 (+10% from entry)",
             "reason_code": "scaleout_10",
         }
 
     if scaleout_stage < 2 and gain >= 0.18:
         return {
-            "quantity": max(lot_size, int((int(current_qty * 0.30) // lot_size) * lot_size)),
+            "quantity": max(lot_size, floor_to_lot(current_qty * 0.30, lot_size)),
             "reason": "Opportunity scale-out T2 (+18% from entry)",
             "reason_code": "scaleout_18",
         }
 
     if scaleout_stage >= 1 and gain > 0 and mom20 < mom60:
         return {
-            "quantity": (int(current_qty) // lot_size) * lot_size,
+            "quantity": floor_to_lot(current_qty, lot_size),
             "reason": "Opportunity exit on momentum rollover after recovery",
             "reason_code": "exit_momentum",
         }
@@ -67,11 +89,11 @@ def get_forced_opportunity_exit(
         if as_of_date is not None:
             now_dt = datetime.strptime(as_of_date, "%Y-%m-%d")
         else:
-            now_dt = datetime.now()
+            now_dt = planning_now()
         age_days = (now_dt - datetime.fromtimestamp(int(last_entry_ts))).days
         if age_days >= time_stop_days and gain < 0.10:
             return {
-                "quantity": (int(current_qty) // lot_size) * lot_size,
+                "quantity": floor_to_lot(current_qty, lot_size),
                 "reason": f"Opportunity time-stop rotation ({time_stop_days} days without progress)",
                 "reason_code": "time_stop_rotation",
             }
@@ -87,6 +109,108 @@ def calculate_priority(action: str, allocation_delta: float, contrarian_score: f
     return base - contrarian_score
 
 
+# Price rows loaded per security for live recommendations (caps the minimum-history check)
+RECOMMENDATION_HISTORY_DAYS = 250
+
+
+def is_new_entry(
+    security: dict | None,
+    history_days: int,
+    *,
+    now_ts: float,
+    grace_days: float,
+    min_history_days: int,
+) -> bool:
+    """Whether a security is still inside its new-entry window and must not be bought yet.
+
+    The window lasts `grace_days` after the security was added to the universe
+    (entries without an added_at timestamp predate the feature and are exempt)
+    and until at least `min_history_days` daily prices are available.
+    """
+    added_at = (security or {}).get("added_at")
+    if added_at and now_ts - float(added_at) < grace_days * 86400:
+        return True
+    return history_days < min(int(min_history_days), RECOMMENDATION_HISTORY_DAYS)
+
+
+def stale_input_reason(
+    last_close_date: Any,
+    price_ts: float | None,
+    *,
+    now_ts: float,
+    max_score_age_days: float,
+    max_price_age_days: float,
+) -> str | None:
+    """Why a buy candidate's inputs are too old to act on, or None if they are fresh.
+
+    The score is as old as the newest close it was computed from. `price_ts` is
+    when the price used for sizing was observed (None = the newest close).
+    """
+    if last_close_date is None or last_close_date == "":
+        return "no price history"
+    try:
+        close_ts = datetime.strptime(str(last_close_date)[:10], "%Y-%m-%d").timestamp()
+    except ValueError:
+        return None  # Not a calendar date: age cannot be judged
+    score_age = (now_ts - close_ts) / 86400
+    if score_age > max_score_age_days:
+        return f"score based on closes up to {str(last_close_date)[:10]} ({score_age:.0f}d old)"
+    if price_ts is None:
+        price_ts = close_ts
+    price_age = (now_ts - price_ts) / 86400
+    if price_age > max_price_age_days:
+        return f"price {price_age:.0f}d old"
+    return None
+
+
+# Evaluation components a recommendation's selection is attributed to
+SCORING_COMPONENTS = ("opportunity", "quality", "risk_adjusted", "diversification", "regime")
+
+
+def score_components(
+    *,
+    action: str,
+    allocation_delta: float,
+    contrarian_score: float,
+    signal: dict[str, Any],
+) -> dict[str, float]:
+    """Split a recommendation's priority into per-component contributions.
+
+    opportunity (dip, capitulation and entry memory boost), regime (cycle turn),
+    quality (user conviction adjustment) and diversification (allocation drift)
+    add up to calculate_priority(). risk_adjusted is the core-sleeve ranking
+    score (120-day momentum net of volatility) that set the target weight.
+    Contributions are signed towards the action: for sells a weak score counts
+    in favour.
+    """
+    sign = 1.0 if action == "buy" else -1.0
+    dip = 0.5 * float(signal.get("dip_score", 0.0) or 0.0)
+    cap = 0.3 * float(signal.get("capitulation_score", 0.0) or 0.0)
+    turn = 0.2 * float(signal.get("cycle_turn", 0) or 0)
+    raw = float(signal.get("opp_score_raw", signal.get("opp_score", 0.0)) or 0.0)
+    effective = float(signal.get("opp_score", raw) or 0.0)
+    # Share raw opp_score between its terms (it is clipped, and zeroed by the freefall block)
+    terms = dip + cap + turn
+    scale = raw / terms if terms > 0 else 0.0
+    core = str(signal.get("sleeve", "core")) == "core"
+    components = {
+        "opportunity": sign * ((dip + cap) * scale + (effective - raw)),
+        "quality": sign * (contrarian_score - effective),
+        "risk_adjusted": sign * float(signal.get("core_rank", 0.0) or 0.0) if core else 0.0,
+        "diversification": abs(allocation_delta) * 10,
+        "regime": sign * turn * scale,
+    }
+    return {name: round(components[name], 6) for name in SCORING_COMPONENTS}
+
+
+def dominant_component(components: dict[str, float] | None) -> str | None:
+    """Component with the largest positive contribution, or None if nothing contributed."""
+    if not components:
+        return None
+    name, value = max(components.items(), key=lambda kv: kv[1])
+    return name if value > 0 else None
+
+
 def generate_buy_reason(
     *,
     symbol: str,
@@ -0,0 +1,101 @@
+"""Rebalancing strategies: when drift alone justifies a trade.
+
+A buy or sell whose only motive is moving a holding back to its target
+allocation (reason codes rebalance_buy and rebalance_sell) is kept or dropped
+according to rebalance_strategy:
+
+- continuous: every run may rebalance (the default)
+- calendar: only during the first rebalance_calendar_window_days of each
+  rebalance_calendar_period (monthly, quarterly, semiannual or annual)
+- threshold: only holdings whose drift from target exceeds
+  rebalance_threshold_pct, in percentage points of the portfolio (absolute
+  bands) or as a share of the target itself (relative bands)
+- opportunistic: never; only opportunity entries, forced exits and funding
+  sells trade
+
+Trades with any other motive (opportunity entries, forced exits, deficit and
+funding sells, swaps, cash parking) are not affected.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass
+from datetime import date
+
+from .models import TradeRecommendation
+
+REBALANCE_STRATEGIES = ("continuous", "calendar", "threshold", "opportunistic")
+CALENDAR_PERIODS = {"monthly": 1, "quarterly": 3, "semiannual": 6, "annual": 12}
+BAND_MODES = ("absolute", "relative")
+REBALANCE_REASON_CODES = ("rebalance_buy", "rebalance_sell")
+
+
+@dataclass
+class RebalanceStrategySettings:
+    """Strategy and its parameters."""
+
+    strategy: str = "continuous"
+    calendar_period: str = "quarterly"
+    calendar_window_days: int = 7  # Days from the start of each period during which rebalancing is allowed
+    threshold_pct: float = 5.0  # Band width in percent
+    band_mode: str = "absolute"  # absolute: percentage points of the portfolio; relative: percent of the target
+
+
+def period_start(today: date, period: str) -> date:
+    """First day of the calendar period containing today."""
+    months = CALENDAR_PERIODS.get(period, 3)
+    return today.replace(month=(today.month - 1) // months * months + 1, day=1)
+
+
+def in_calendar_window(today: date, config: RebalanceStrategySettings) -> bool:
+    """Whether today falls in the rebalancing window at the start of its period."""
+    return (today - period_start(today, config.calendar_period)).days < config.calendar_window_days
+
+
+def outside_band(current: float, target: float, config: RebalanceStrategySettings) -> bool:
+    """Whether a holding's drift from its target (allocations as fractions) exceeds the band."""
+    drift = abs(current - target)
+    if config.band_mode == "relative":
+        if target <= 0:
+            return drift > 0
+        return drift / target * 100 > config.threshold_pct
+    return drift * 100 > config.threshold_pct
+
+
+def rebalance_allowed(
+    rec: TradeRecommendation, today: date, config: RebalanceStrategySettings
+) -> tuple[bool, str | None]:
+    """Whether a recommendation may trade under the strategy, with the reason when it may not."""
+    if rec.reason_code not in REBALANCE_REASON_CODES or config.strategy == "continuous":
+        return True, None
+    if config.strategy == "opportunistic":
+        return False, "opportunistic strategy: no rebalance-only trades"
+    if config.strategy == "calendar":
+        if in_calendar_window(today, config):
+            return True, None
+        start = period_start(today, config.calendar_period)
+        return False, f"outside the {config.calendar_period} rebalancing window (period started {start.isoformat()})"
+    if config.strategy == "threshold":
+        if outside_band(rec.current_allocation, rec.target_allocation, config):
+            return True, None
+        return False, f"drift within the {config.threshold_pct:g}% {config.band_mode} band"
+    return True, None
+
+
+def apply_rebalance_strategy(
+    recommendations: list[TradeRecommendation], today: date, config: RebalanceStrategySettings
+) -> tuple[list[TradeRecommendation], dict[str, str]]:
+    """Drop the rebalance-only trades the strategy does not allow.
+
+    Returns:
+        (kept recommendations, symbol -> reason for each dropped one)
+    """
+    kept: list[TradeRecommendation] = []
+    dropped: dict[str, str] = {}
+    for rec in recommendations:
+        allowed, reason = rebalance_allowed(rec, today, config)
+        if allowed:
+            kept.append(rec)
+        else:
+            dropped[rec.symbol] = reason or config.strategy
+    return kept, dropped
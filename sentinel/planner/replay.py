@@ -0,0 +1,319 @@
+"""Decision replay: re-run a past planner cycle with the current code.
+
+Every planning refresh stores a snapshot of the planner's inputs (securities,
+positions, cash, strategy state, recent price history, the broker quotes and FX
+rates it used) together with the recommendations it produced. Replaying a
+snapshot loads those inputs into a scratch database, runs the planner against
+it with the current code and current settings, and diffs the new
+recommendations against the recorded ones - a quick way to verify that a
+refactor did not change trading behavior, or to see what a config change would
+have done to a past decision.
+
+Time-dependent rules (cool-offs, time stops) are evaluated at the snapshot's
+capture time, so a replay of unchanged code against unchanged settings
+reproduces the recorded batch.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import shutil
+import tempfile
+import time
+import zlib
+from dataclasses import asdict
+from datetime import datetime
+from pathlib import Path
+
+from sentinel.database import Database
+from sentinel.vault import SECRET_NAMES
+
+from .models import TradeRecommendation
+from .rebalance_rules import frozen_planning_now
+
+logger = logging.getLogger(__name__)
+
+SNAPSHOT_VERSION = 1
+
+# Longest price lookback the planner reads (allocation signals)
+PRICE_HISTORY_DAYS = 300
+
+# table -> query; raw broker payloads are blanked to keep snapshots small
+CONTEXT_TABLES = {
+    "securities": "SELECT * FROM securities",
+    "positions": "SELECT * FROM positions",
+    "cash_balances": "SELECT * FROM cash_balances",
+    "allocation_targets": "SELECT * FROM allocation_targets",
+    "sector_allocation_caps": "SELECT * FROM sector_allocation_caps",
+    "strategy_state": "SELECT * FROM strategy_state",
+    "trades": (
+        "SELECT id, broker_trade_id, symbol, side, quantity, price, commission, commission_currency, "
+        "executed_at, '{}' AS raw_data FROM trades"
+    ),
+    "dividends": "SELECT id, symbol, date, amount, currency, value, '{}' AS data FROM dividends",
+}
+
+# Settings never copied into snapshots
+SECRET_SETTINGS = frozenset(SECRET_NAMES)
+
+# Recommendation fields compared when diffing a replay against the recorded run
+DIFF_FIELDS = ("quantity", "price", "value_delta_eur", "priority", "reason_code", "sleeve", "swap_group")
+_FLOAT_TOLERANCE = 1e-6
+
+
+class ReplayBroker:
+    """Broker stand-in that answers quote requests from a snapshot."""
+
+    def __init__(self, quotes: dict[str, dict]):
+        self._quotes = quotes
+
+    @property
+    def connected(self) -> bool:
+        return False
+
+    async def get_quote(self, symbol: str) -> dict | None:
+        return self._quotes.get(symbol)
+
+    async def get_quotes(self, symbols: list[str]) -> dict[str, dict]:
+        return {symbol: self._quotes[symbol] for symbol in symbols if symbol in self._quotes}
+
+
+class SnapshotRates:
+    """Currency stand-in that converts with the FX rates recorded in a snapshot."""
+
+    def __init__(self, rates: dict[str, float]):
+        self._rates = {currency.upper(): float(rate) for currency, rate in rates.items()}
+
+    async def get_rates(self) -> dict:
+        return dict(self._rates)
+
+    async def get_rate(self, currency: str) -> float:
+        return self._rates.get(currency.upper(), 1.0)
+
+    async def to_eur(self, amount: float, currency: str) -> float:
+        if currency.upper() == "EUR":
+            return amount
+        return amount * await self.get_rate(currency)
+
+
+def encode_context(context: dict) -> bytes:
+    """Serialize a planner context for storage."""
+    return zlib.compress(json.dumps(context, separators=(",", ":"), default=str).encode())
+
+
+def decode_context(blob: bytes) -> dict:
+    """Inverse of encode_context()."""
+    return json.loads(zlib.decompress(blob).decode())
+
+
+async def capture_context(
+    db: Database,
+    quotes: dict[str, dict] | None = None,
+    rates: dict[str, float] | None = None,
+) -> dict:
+    """Collect everything the live planner reads from the database.
+
+    Args:
+        db: Database instance
+        quotes: Broker quotes the run used (falls back to stored securities.quote_data)
+        rates: FX rates to EUR the run used
+
+    Returns:
+        JSON-serializable planner context
+    """
+    tables = {}
+    for table, query in CONTEXT_TABLES.items():
+        cursor = await db.conn.execute(query)
+        tables[table] = [dict(row) for row in await cursor.fetchall()]
+
+    symbols = [sec["symbol"] for sec in tables["securities"]]
+    prices = await db.get_prices_for_symbols(symbols, days=PRICE_HISTORY_DAYS)
+
+    if not quotes:
+        quotes = {}
+        for sec in tables["securities"]:
+            try:
+                quote = json.loads(sec.get("quote_data") or "null")
+            except (json.JSONDecodeError, TypeError):
+                quote = None
+            if isinstance(quote, dict):
+                quotes[sec["symbol"]] = quote
+
+    settings = await db.get_all_settings()
+    return {
+        "version": SNAPSHOT_VERSION,
+        "captured_at": int(time.time()),
+        "tables": tables,
+        "prices": {symbol: rows for symbol, rows in prices.items() if rows},
+        "quotes": quotes,
+        "rates": rates or {},
+        "settings": {key: value for key, value in settings.items() if key not in SECRET_SETTINGS},
+    }
+
+
+async def record_planner_run(
+    db,
+    planner,
+    recommendations: list[TradeRecommendation],
+    min_trade_value: float | None = None,
+) -> int | None:
+    """Store a snapshot of a live planner run for later replay. Never raises.
+
+    Keeps the most recent `planner_snapshot_retention` snapshots; 0 disables recording.
+
+    Returns:
+        The snapshot id, or None if nothing was recorded
+    """
+    if not isinstance(db, Database):
+        return None
+    try:
+        from sentinel.settings import DEFAULTS
+
+        keep = int(await db.get_setting("planner_snapshot_retention", DEFAULTS["planner_snapshot_retention"]) or 0)
+        if keep <= 0:
+            return None
+
+        from sentinel.currency import Currency
+
+        from .state_hash import compute_state_hash
+
+        context = await capture_context(db, quotes=planner.last_quotes, rates=await Currency().get_rates())
+        return await db.save_planner_snapshot(
+            await compute_state_hash(db),
+            {"min_trade_value": min_trade_value},
+            encode_context(context),
+            [asdict(r) for r in recommendations],
+            keep=keep,
+        )
+    except Exception as e:
+        logger.warning(f"Failed to record planner snapshot: {e}")
+        return None
+
+
+async def _load_context(context: dict, path: str) -> Database:
+    """Create a scratch database at `path` holding a snapshot's planner inputs."""
+    db = Database(path)
+    await db.connect()
+    for table, rows in context.get("tables", {}).items():
+        if not rows:
+            continue
+        cursor = await db.conn.execute(f"PRAGMA table_info({table})")
+        columns = [row["name"] for row in await cursor.fetchall()]
+        # Columns added after the snapshot keep their defaults; dropped ones are ignored
+        present = [column for column in columns if column in rows[0]]
+        placeholders = ",".join("?" for _ in present)
+        await db.conn.executemany(
+            f"INSERT OR REPLACE INTO {table} ({','.join(present)}) VALUES ({placeholders})",  # noqa: S608
+            [tuple(row.get(column) for column in present) for row in rows],
+        )
+    for symbol, rows in context.get("prices", {}).items():
+        await db.save_prices(symbol, rows)
+    await db.conn.commit()
+    return db
+
+
+def _recommendation_key(rec: dict) -> tuple[str, str]:
+    return rec["symbol"], rec["action"]
+
+
+def _differs(recorded: object, replayed: object) -> bool:
+    if isinstance(recorded, (int, float)) and isinstance(replayed, (int, float)):
+        return abs(float(recorded) - float(replayed)) > _FLOAT_TOLERANCE
+    return recorded != replayed
+
+
+def diff_recommendations(recorded: list[dict], replayed: list[dict]) -> dict:
+    """Compare two recommendation batches keyed by (symbol, action).
+
+    Returns:
+        dict with added, removed and changed recommendations and an identical flag
+    """
+    recorded_map = {_recommendation_key(r): r for r in recorded}
+    replayed_map = {_recommendation_key(r): r for r in replayed}
+
+    changed = []
+    for key in recorded_map.keys() & replayed_map.keys():
+        before, after = recorded_map[key], replayed_map[key]
+        changes = {
+            field: {"recorded": before.get(field), "replayed": after.get(field)}
+            for field in DIFF_FIELDS
+            if _differs(before.get(field), after.get(field))
+        }
+        if changes:
+            changed.append({"symbol": key[0], "action": key[1], "changes": changes})
+
+    added = [replayed_map[key] for key in replayed_map.keys() - recorded_map.keys()]
+    removed = [recorded_map[key] for key in recorded_map.keys() - replayed_map.keys()]
+    # Sequencing matters to execution, so a reordered batch is a difference too
+    same_order = [_recommendation_key(r) for r in recorded] == [_recommendation_key(r) for r in replayed]
+    return {
+        "identical": not added and not removed and not changed and same_order,
+        "same_order": same_order,
+        "added": sorted(added, key=_recommendation_key),
+        "removed": sorted(removed, key=_recommendation_key),
+        "changed": sorted(changed, key=lambda c: (c["symbol"], c["action"])),
+    }
+
+
+def _config_changes(recorded: dict, current: dict) -> dict:
+    """Settings whose value differs between the snapshot and now (secrets excluded)."""
+    keys = (recorded.keys() | current.keys()) - SECRET_SETTINGS
+    return {
+        key: {"recorded": recorded.get(key), "current": current.get(key)}
+        for key in sorted(keys)
+        if recorded.get(key) != current.get(key)
+    }
+
+
+async def replay_snapshot(snapshot_id: int, db: Database | None = None, settings=None) -> dict | None:
+    """Re-run the planner against a stored snapshot and diff the decisions.
+
+    The planner runs with the current code and the current settings (or
+    `settings`, e.g. with proposed values overlaid); the snapshot supplies
+    portfolio state, prices, quotes and FX rates.
+
+    Returns:
+        Replay report, or None if the snapshot does not exist
+    """
+    db = db or Database()
+    snapshot = await db.get_planner_snapshot(snapshot_id)
+    if snapshot is None:
+        return None
+    context = decode_context(snapshot["context"])
+
+    from sentinel.portfolio import Portfolio
+
+    from .planner import Planner
+
+    temp_dir = tempfile.mkdtemp()
+    replay_db = None
+    try:
+        replay_db = await _load_context(context, str(Path(temp_dir) / "replay.db"))
+        broker = ReplayBroker(context.get("quotes", {}))
+        rates = SnapshotRates(context.get("rates", {}))
+        planner = Planner(
+            db=replay_db,
+            broker=broker,  # type: ignore[arg-type]
+            portfolio=Portfolio(db=replay_db, broker=broker, currency=rates),
+            currency=rates,  # type: ignore[arg-type]
+            settings=settings,
+        )
+        with frozen_planning_now(datetime.fromtimestamp(context["captured_at"])):
+            replayed = await planner._compute_recommendations(snapshot["params"].get("min_trade_value"), None)
+    finally:
+        if replay_db is not None:
+            await replay_db.close()
+            replay_db.remove_from_cache()
+        shutil.rmtree(temp_dir, ignore_errors=True)
+
+    replayed_dicts = [asdict(r) for r in replayed]
+    return {
+        "snapshot_id": snapshot_id,
+        "captured_at": context["captured_at"],
+        "recorded_count": len(snapshot["recommendations"]),
+        "replayed_count": len(replayed_dicts),
+        **diff_recommendations(snapshot["recommendations"], replayed_dicts),
+        "config_changes": _config_changes(context.get("settings", {}), await db.get_all_settings()),
+        "replayed": replayed_dicts,
+    }
@@ -0,0 +1,157 @@
+"""Resource-aware cap on the number of symbols the planner evaluates.
+
+Every symbol in the ideal portfolio is streamed through signal computation and
+recommendation building, so a sudden growth of the universe grows memory use
+and planning time with it. Before a live run the engine decides a cap on the
+buy candidates (symbols not currently held) from three limits:
+
+- a fixed maximum (planner_candidate_cap_max, 0 = none),
+- free memory: below planner_min_free_memory_mb the candidate count shrinks in
+  proportion to the memory that is left,
+- latency: the recent seconds-per-symbol times the candidate count must fit in
+  planner_target_eval_seconds.
+
+Held symbols are never capped (their sells and trims still get planned); the
+candidates kept are the ones with the largest ideal weight. The same cap bounds
+the number of swap pairs evaluated. A cap never goes below
+planner_candidate_cap_floor, so planning quality degrades instead of stopping.
+"""
+
+from __future__ import annotations
+
+from collections import deque
+from dataclasses import dataclass, field
+from pathlib import Path
+
+MEMINFO_PATH = Path("/proc/meminfo")
+
+
+def available_memory_mb(meminfo_path: Path = MEMINFO_PATH) -> float | None:
+    """MemAvailable from /proc/meminfo in MB (None where it cannot be read)."""
+    try:
+        with open(meminfo_path) as f:
+            for line in f:
+                if line.startswith("MemAvailable:"):
+                    return int(line.split()[1]) / 1024.0
+    except (OSError, ValueError, IndexError):
+        return None
+    return None
+
+
+class LatencyTracker:
+    """Rolling mean of per-symbol evaluation time over the last few runs."""
+
+    def __init__(self, window: int = 5):
+        self._samples: deque[float] = deque(maxlen=window)
+
+    def record(self, seconds: float, symbols: int) -> None:
+        """Record one run that evaluated `symbols` symbols in `seconds`."""
+        if symbols > 0 and seconds >= 0:
+            self._samples.append(seconds / symbols)
+
+    def seconds_per_symbol(self) -> float | None:
+        """Mean seconds per symbol (None before the first run)."""
+        if not self._samples:
+            return None
+        return sum(self._samples) / len(self._samples)
+
+
+@dataclass
+class CapSettings:
+    """Limits for the candidate cap."""
+
+    max_candidates: int = 0  # 0 = no fixed maximum
+    min_free_memory_mb: float = 512.0  # Below this much free memory the candidate count shrinks
+    target_seconds: float = 120.0  # Evaluation time budget for one run (0 = no latency limit)
+    floor: int = 20  # Never cap below this many candidates
+
+
+@dataclass
+class CapDecision:
+    """Outcome of one cap decision."""
+
+    candidates: int
+    cap: int | None = None  # None = uncapped
+    available_mb: float | None = None
+    seconds_per_symbol: float | None = None
+    reasons: list[str] = field(default_factory=list)
+
+    @property
+    def capped(self) -> bool:
+        return self.cap is not None and self.cap < self.candidates
+
+    def as_dict(self) -> dict:
+        return {
+            "candidates": self.candidates,
+            "cap": self.cap,
+            "capped": self.capped,
+            "available_mb": round(self.available_mb, 1) if self.available_mb is not None else None,
+            "seconds_per_symbol": round(self.seconds_per_symbol, 4) if self.seconds_per_symbol is not None else None,
+            "reasons": list(self.reasons),
+        }
+
+
+def decide_candidate_cap(
+    candidates: int,
+    settings: CapSettings,
+    available_mb: float | None = None,
+    seconds_per_symbol: float | None = None,
+    held: int = 0,
+) -> CapDecision:
+    """
+    Decide how many buy candidates to evaluate.
+
+    Args:
+        candidates: Number of candidate (not held) symbols
+        settings: Cap limits
+        available_mb: Free memory (None = unknown, no memory limit)
+        seconds_per_symbol: Recent evaluation latency (None = unknown, no latency limit)
+        held: Number of held symbols, which are evaluated regardless and count against the time budget
+    """
+    decision = CapDecision(candidates=candidates, available_mb=available_mb, seconds_per_symbol=seconds_per_symbol)
+    floor = max(0, int(settings.floor))
+    limits: list[tuple[int, str]] = []
+    if settings.max_candidates > 0:
+        limits.append((int(settings.max_candidates), f"max {int(settings.max_candidates)} candidates"))
+    if available_mb is not None and settings.min_free_memory_mb > 0 and available_mb < settings.min_free_memory_mb:
+        share = max(0.0, available_mb) / settings.min_free_memory_mb
+        limits.append(
+            (
+                max(floor, int(candidates * share)),
+                f"free memory {available_mb:.0f}MB < {settings.min_free_memory_mb:.0f}MB",
+            )
+        )
+    expected_seconds = (seconds_per_symbol or 0.0) * (candidates + held)
+    if seconds_per_symbol and settings.target_seconds > 0 and expected_seconds > settings.target_seconds:
+        limits.append(
+            (
+                max(floor, int(settings.target_seconds / seconds_per_symbol) - held),
+                f"{seconds_per_symbol:.3f}s/symbol exceeds {settings.target_seconds:.0f}s budget",
+            )
+        )
+
+    binding = [(cap, reason) for cap, reason in limits if cap < candidates]
+    if binding:
+        decision.cap = min(cap for cap, _ in binding)
+        decision.reasons = [reason for _, reason in binding]
+    return decision
+
+
+def select_candidates(
+    symbols: list[str],
+    held: set[str],
+    ideal: dict[str, float],
+    cap: int | None,
+) -> tuple[list[str], list[str]]:
+    """
+    Keep every held symbol plus the `cap` candidates with the largest ideal weight.
+
+    Returns:
+        (kept symbols, dropped candidates)
+    """
+    if cap is None:
+        return list(symbols), []
+    candidates = sorted((s for s in symbols if s not in held), key=lambda s: (-float(ideal.get(s, 0.0)), s))
+    keep = set(candidates[: max(0, cap)])
+    dropped = candidates[max(0, cap) :]
+    return [s for s in symbols if s in held or s in keep], dropped
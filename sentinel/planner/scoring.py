@@ -0,0 +1,148 @@
+"""Incremental per-security scoring for the ideal portfolio.
+
+The raw contrarian signal of a security depends only on its recent closes and
+the entry-memory settings, so live runs cache it per security (score_state
+table) and recompute it only when the security was marked dirty (new prices,
+dividends or metadata changed), when those settings changed, or when it was
+never scored. The remaining work runs on a process pool of
+planner_scoring_workers (0 = inline); results are assembled in symbol order, so
+the output never depends on which worker finished first. As-of runs always
+recompute and never touch the cache.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import inspect
+import json
+import logging
+from concurrent.futures import Executor, ProcessPoolExecutor
+from dataclasses import dataclass
+
+from sentinel.strategy import (
+    compute_contrarian_signal,
+    contrarian_skipped_checks,
+    effective_opportunity_score,
+    recent_dd252_min,
+)
+
+from .streaming import stream_price_history
+
+logger = logging.getLogger(__name__)
+
+# Bump when the signal computation changes so every cached score is recomputed
+SIGNAL_VERSION = 1
+# Price rows per security fed into the signal
+SCORING_HISTORY_DAYS = 300
+
+
+@dataclass(frozen=True)
+class ScoringParams:
+    """Settings a cached signal depends on."""
+
+    entry_t1_dd: float = -0.10
+    entry_t3_dd: float = -0.22
+    entry_memory_days: int = 42
+    memory_max_boost: float = 0.18
+
+    def key(self) -> str:
+        return json.dumps(
+            [SIGNAL_VERSION, self.entry_t1_dd, self.entry_t3_dd, self.entry_memory_days, self.memory_max_boost]
+        )
+
+
+def score_closes(closes: list[float], params: ScoringParams) -> dict:
+    """Raw signal (with entry memory applied) and skipped checks for closes, oldest first."""
+    signal = compute_contrarian_signal(closes)
+    raw_opp = float(signal.get("opp_score", 0.0) or 0.0)
+    recent_min = recent_dd252_min(closes, window_days=params.entry_memory_days)
+    effective_opp = effective_opportunity_score(
+        raw_opp_score=raw_opp,
+        cycle_turn=int(signal.get("cycle_turn", 0) or 0),
+        freefall_block=int(signal.get("freefall_block", 0) or 0),
+        recent_dd252_min_value=recent_min,
+        entry_t1_dd=params.entry_t1_dd,
+        entry_t3_dd=params.entry_t3_dd,
+        max_boost=params.memory_max_boost,
+    )
+    signal["opp_score_raw"] = raw_opp
+    signal["dd252_recent_min"] = recent_min
+    signal["opp_score"] = effective_opp
+    signal["memory_boosted"] = 1 if effective_opp > raw_opp else 0
+    return {"signal": signal, "skipped": contrarian_skipped_checks(closes)}
+
+
+async def _cached_states(db, symbols: list[str]) -> dict[str, dict]:
+    getter = getattr(db, "get_score_states", None)
+    if not callable(getter):
+        return {}
+    states = getter(symbols)
+    if inspect.isawaitable(states):
+        states = await states
+    return states if isinstance(states, dict) else {}
+
+
+async def score_securities(
+    db,
+    symbols: list[str],
+    params: ScoringParams,
+    as_of_date: str | None = None,
+    workers: int = 0,
+    executor: Executor | None = None,
+) -> tuple[dict[str, dict], dict]:
+    """Score symbols, reusing clean cached signals on live runs.
+
+    Args:
+        db: Database instance
+        symbols: Symbols to score
+        params: Settings the signal depends on
+        as_of_date: Point-in-time run (no cache) when set
+        workers: Worker processes for recomputation (0 = inline)
+        executor: Executor to use instead of a fresh process pool (tests)
+
+    Returns:
+        (symbol -> {"signal", "skipped"} in symbols order, stats with cached/recomputed/workers)
+    """
+    key = params.key()
+    live = as_of_date is None
+    states = await _cached_states(db, symbols) if live else {}
+    results: dict[str, dict] = {}
+    stale: list[str] = []
+    for symbol in symbols:
+        state = states.get(symbol) or {}
+        if state.get("signal") and not state.get("dirty") and state.get("config_key") == key:
+            results[symbol] = state["signal"]
+        else:
+            stale.append(symbol)
+
+    computed: dict[str, dict] = {}
+    pool = executor
+    if pool is None and workers > 0 and stale:
+        pool = ProcessPoolExecutor(max_workers=workers)
+    try:
+        loop = asyncio.get_running_loop()
+        pending: dict[str, asyncio.Future] = {}
+        async for symbol, raw in stream_price_history(db, stale, days=SCORING_HISTORY_DAYS, end_date=as_of_date):
+            closes = [float(p["close"]) for p in reversed(raw) if p.get("close") is not None]
+            if pool is None:
+                computed[symbol] = score_closes(closes, params)
+            else:
+                pending[symbol] = loop.run_in_executor(pool, score_closes, closes, params)
+        for symbol, future in pending.items():
+            computed[symbol] = await future
+    finally:
+        if pool is not None and executor is None:
+            pool.shutdown()
+
+    if live and computed:
+        saver = getattr(db, "save_score_states", None)
+        if callable(saver):
+            seen_revs = {symbol: int((states.get(symbol) or {}).get("dirty_rev") or 0) for symbol in computed}
+            maybe_saved = saver(computed, key, seen_revs)
+            if inspect.isawaitable(maybe_saved):
+                await maybe_saved
+
+    results.update(computed)
+    stats = {"cached": len(symbols) - len(stale), "recomputed": len(stale), "workers": workers if stale else 0}
+    logger.debug(f"Scored {len(symbols)} securities: {stats}")
+    return {symbol: results[symbol] for symbol in symbols}, stats
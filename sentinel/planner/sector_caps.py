@@ -0,0 +1,148 @@
+"""Hierarchical sector cap enforcement for the planner.
+
+Caps are set on GICS taxonomy nodes (sectors and industry groups) as a maximum
+fraction of portfolio value. A security counts towards every node on its path,
+so a 10% Semiconductors cap and a 25% Information Technology cap both apply to a
+chip maker.
+"""
+
+from __future__ import annotations
+
+from dataclasses import replace
+
+from sentinel.config.gics import classify_gics, gics_path
+from sentinel.utils.quantity import floor_to_lot
+
+from .models import TradeRecommendation
+
+# Tolerance when comparing exposures against caps
+CAP_EPSILON = 1e-9
+# Water-filling rounds; each round either satisfies every cap or saturates another node
+MAX_CAP_ITERATIONS = 25
+
+
+def symbol_sector_paths(securities: list[dict]) -> dict[str, list[str]]:
+    """Map each symbol to its taxonomy path (most specific code first).
+
+    Uses the stored gics_code, falling back to classifying the free-text industry.
+    Symbols that cannot be classified are omitted (they are never capped).
+    """
+    paths = {}
+    for sec in securities:
+        code = sec.get("gics_code") or classify_gics(sec.get("industry"))
+        if code:
+            paths[sec["symbol"]] = gics_path(code)
+    return paths
+
+
+def node_exposures(weights: dict[str, float], symbol_paths: dict[str, list[str]]) -> dict[str, float]:
+    """Sum symbol weights into every taxonomy node on each symbol's path."""
+    exposures: dict[str, float] = {}
+    for symbol, weight in weights.items():
+        for code in symbol_paths.get(symbol, []):
+            exposures[code] = exposures.get(code, 0.0) + weight
+    return exposures
+
+
+def apply_sector_caps(
+    weights: dict[str, float],
+    symbol_paths: dict[str, list[str]],
+    caps: dict[str, float],
+    max_weight: float = 1.0,
+) -> dict[str, float]:
+    """Scale down capped nodes and hand the freed weight to uncapped symbols.
+
+    Deeper nodes are scaled first so an industry group cap is applied before its
+    sector cap. Freed weight goes pro rata to symbols not under a saturated node,
+    bounded by `max_weight`. If no symbol can absorb it, the weights sum to less
+    than 1 and the remainder stays in cash.
+
+    Args:
+        weights: symbol -> target weight (summing to ~1)
+        symbol_paths: symbol -> taxonomy path from symbol_sector_paths()
+        caps: taxonomy code -> maximum weight
+        max_weight: per-symbol upper bound used when redistributing
+
+    Returns:
+        New symbol -> weight mapping
+    """
+    result = dict(weights)
+    if not caps or not result:
+        return result
+    target_total = sum(result.values())
+
+    for _ in range(MAX_CAP_ITERATIONS):
+        for code in sorted(caps, key=len, reverse=True):
+            members = [s for s in result if code in symbol_paths.get(s, [])]
+            exposure = sum(result[s] for s in members)
+            if exposure > caps[code] + CAP_EPSILON:
+                factor = caps[code] / exposure
+                for symbol in members:
+                    result[symbol] *= factor
+
+        missing = target_total - sum(result.values())
+        if missing <= CAP_EPSILON:
+            break
+        exposures = node_exposures(result, symbol_paths)
+        saturated = {code for code, cap in caps.items() if exposures.get(code, 0.0) >= cap - CAP_EPSILON}
+        free = {
+            s: w
+            for s, w in result.items()
+            if w > 0 and w < max_weight - CAP_EPSILON and not saturated.intersection(symbol_paths.get(s, []))
+        }
+        free_total = sum(free.values())
+        if free_total <= 0:
+            break
+        for symbol, weight in free.items():
+            result[symbol] = min(max_weight, weight + missing * weight / free_total)
+
+    return {s: w for s, w in result.items() if w > 0}
+
+
+def limit_buys_to_sector_caps(
+    recommendations: list[TradeRecommendation],
+    current: dict[str, float],
+    total_value: float,
+    symbol_paths: dict[str, list[str]],
+    caps: dict[str, float],
+    min_trade_value: float = 0.0,
+) -> list[TradeRecommendation]:
+    """Shrink or drop buys that would push a capped node over its cap.
+
+    Sells are applied first (they free headroom), then buys in list order consume
+    the remaining headroom of every node on their path. Buys that shrink below
+    one lot or `min_trade_value` are dropped.
+    """
+    if not caps or total_value <= 0:
+        return recommendations
+
+    exposures = node_exposures(current, symbol_paths)
+    for rec in recommendations:
+        if rec.action == "sell":
+            for code in symbol_paths.get(rec.symbol, []):
+                exposures[code] = exposures.get(code, 0.0) + rec.value_delta_eur / total_value
+
+    result = []
+    for rec in recommendations:
+        capped = [code for code in symbol_paths.get(rec.symbol, []) if code in caps]
+        if rec.action != "buy" or not capped or rec.value_delta_eur <= 0:
+            result.append(rec)
+            continue
+        headroom = min(caps[code] - exposures.get(code, 0.0) for code in capped) * total_value
+        if headroom < rec.value_delta_eur:
+            quantity = floor_to_lot(rec.quantity * max(0.0, headroom) / rec.value_delta_eur, rec.lot_size)
+            if quantity <= 0:
+                continue
+            value = rec.value_delta_eur * quantity / rec.quantity
+            if value < min_trade_value:
+                continue
+            rec = replace(
+                rec,
+                quantity=quantity,
+                value_delta_eur=value,
+                reason=f"{rec.reason} (limited by sector cap)",
+            )
+        for code in symbol_paths.get(rec.symbol, []):
+            exposures[code] = exposures.get(code, 0.0) + rec.value_delta_eur / total_value
+        result.append(rec)
+    return result
@@ -0,0 +1,230 @@
+"""Content-addressed caching of planner batches.
+
+A recommendation batch depends only on the planner's inputs: positions and their
+theses, cash, external holdings, prices and quotes, security settings, strategy
+settings, allocation targets, market regimes and security correlations.
+Hashing those inputs gives a state hash; together with a fingerprint of the batch
+parameters it forms a cache key, so repeated planner runs while nothing changes
+(e.g. while markets are closed) are served from cache instead of recomputed.
+
+The hash is built from component sub-hashes (positions, prices, scores,
+settings, allocations, market), each built from per-item hashes. Planner checks are
+recorded in state_checks, so two checks can be diffed to see which component
+changed and which securities were involved.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import inspect
+import json
+import logging
+from dataclasses import dataclass
+from datetime import date
+
+logger = logging.getLogger(__name__)
+
+# Bump when the planner's output for the same inputs changes (invalidates old entries)
+STATE_HASH_VERSION = 3
+BATCH_CACHE_PREFIX = "planner:batch:"
+
+# Security columns that influence planning (sync timestamps and raw payloads excluded)
+_SECURITY_FIELDS = (
+    "active",
+    "allow_buy",
+    "allow_sell",
+    "asset_class",
+    "currency",
+    "geography",
+    "gics_code",
+    "industry",
+    "min_lot",
+    "supports_fractional",
+    "user_multiplier",
+)
+
+# Position thesis columns behind the planner's advisory tags and target sell boost
+_THESIS_FIELDS = ("target_price", "stop_price", "review_days", "reviewed_at")
+
+# External holding columns that count towards allocations
+_EXTERNAL_FIELDS = ("symbol", "currency", "geography", "industry", "value")
+
+
+def fingerprint(payload: object) -> str:
+    """Stable sha256 of a JSON-serializable payload."""
+    encoded = json.dumps(payload, sort_keys=True, separators=(",", ":"), default=str)
+    return hashlib.sha256(encoded.encode()).hexdigest()
+
+
+def _quote_price(quote_data: str | None) -> float | None:
+    try:
+        quote = json.loads(quote_data) if quote_data else {}
+    except (json.JSONDecodeError, TypeError):
+        return None
+    return quote.get("price", quote.get("ltp")) if isinstance(quote, dict) else None
+
+
+async def _call(db, name: str, *args):
+    getter = getattr(db, name, None)
+    if not callable(getter):
+        return None
+    value = getter(*args)
+    if inspect.isawaitable(value):
+        value = await value
+    return value
+
+
+async def collect_state(db) -> dict[str, dict[str, object]]:
+    """Planner inputs currently stored in the database, split into components.
+
+    Each component maps item keys to values. Per-security items are keyed by
+    symbol; everything else uses a "kind:name" key (cash:EUR, setting:max_position_pct).
+    """
+    securities = await _call(db, "get_all_securities", False) or []
+    symbols = [sec["symbol"] for sec in securities]
+    latest_trades = await _call(db, "get_trades", None, None, None, None, 1) or []
+    positions = {
+        p["symbol"]: [p.get("quantity"), p.get("avg_cost"), p.get("current_price")]
+        for p in await _call(db, "get_all_positions") or []
+    }
+    dividends = await _call(db, "get_uninvested_dividends") or {}
+    strategy_states = await _call(db, "get_strategy_states") or {}
+    theses = await _call(db, "get_position_theses") or {}
+    latest_prices = await _call(db, "get_latest_prices") or {}
+    quotes = {sec["symbol"]: _quote_price(sec.get("quote_data")) for sec in securities}
+    scores = await _call(db, "get_score_states", symbols) or {}
+    correlations: dict[str, dict[str, float]] = {}
+    for pair in await _call(db, "get_correlations") or []:
+        correlations.setdefault(pair["symbol_a"], {})[pair["symbol_b"]] = pair["correlation"]
+
+    held = set(positions) | set(dividends) | set(strategy_states) | set(theses)
+    return {
+        "positions": {
+            **{
+                symbol: {
+                    "position": positions.get(symbol),
+                    "uninvested_dividends": dividends.get(symbol),
+                    "strategy_state": strategy_states.get(symbol),
+                    "thesis": [theses[symbol].get(field) for field in _THESIS_FIELDS] if symbol in theses else None,
+                }
+                for symbol in held
+            },
+            **{f"cash:{currency}": amount for currency, amount in (await _call(db, "get_cash_balances") or {}).items()},
+            **{
+                f"external:{h['id']}": [h.get(field) for field in _EXTERNAL_FIELDS]
+                for h in await _call(db, "get_external_holdings") or []
+            },
+            "trade:latest": latest_trades[0].get("id") if latest_trades else None,
+        },
+        "prices": {
+            symbol: {"close": latest_prices.get(symbol), "quote": quotes.get(symbol)}
+            for symbol in set(latest_prices) | set(quotes)
+        },
+        # Dirty flags are left out: they only mean a recomputation is due, not that inputs changed
+        "scores": {symbol: [state.get("config_key"), state.get("signal")] for symbol, state in scores.items()},
+        "settings": {
+            **{sec["symbol"]: {field: sec.get(field) for field in _SECURITY_FIELDS} for sec in securities},
+            **{f"setting:{key}": value for key, value in (await _call(db, "get_all_settings") or {}).items()},
+        },
+        "allocations": {
+            **{
+                f"target:{t.get('type')}:{t.get('name')}": t.get("weight")
+                for t in await _call(db, "get_allocation_targets") or []
+            },
+            **{f"sector_cap:{code}": cap for code, cap in (await _call(db, "get_sector_caps") or {}).items()},
+        },
+        "market": {
+            **{
+                f"regime:{s['region']}": [s.get("regime"), s.get("score"), s.get("confidence")]
+                for s in await _call(db, "get_regime_states") or []
+            },
+            **{f"correlation:{symbol}": pairs for symbol, pairs in correlations.items()},
+        },
+    }
+
+
+@dataclass
+class StateHashes:
+    """Root state hash with its per-component and per-item sub-hashes."""
+
+    state_hash: str
+    components: dict[str, str]
+    items: dict[str, dict[str, str]]
+
+
+async def compute_state_hashes(db, today: date | None = None) -> StateHashes:
+    """Hash every planner input currently stored in the database, component by component.
+
+    Args:
+        db: Database instance
+        today: Planning date (cool-offs and time stops depend on it; defaults to today)
+    """
+    items = {
+        component: {key: fingerprint(value)[:16] for key, value in values.items()}
+        for component, values in (await collect_state(db)).items()
+    }
+    components = {component: fingerprint(hashes) for component, hashes in items.items()}
+    state_hash = fingerprint(
+        {
+            "version": STATE_HASH_VERSION,
+            "today": (today or date.today()).isoformat(),
+            "components": components,
+        }
+    )
+    return StateHashes(state_hash, components, items)
+
+
+async def compute_state_hash(db, today: date | None = None) -> str:
+    """Hash every planner input currently stored in the database.
+
+    Returns:
+        Hex sha256 of the planner state
+    """
+    return (await compute_state_hashes(db, today)).state_hash
+
+
+async def record_state_check(db, hashes: StateHashes) -> None:
+    """Persist a state check so later changes can be diffed (failures are only logged)."""
+    try:
+        await _call(db, "record_state_check", hashes.state_hash, hashes.components, hashes.items)
+    except Exception as e:
+        logger.warning(f"Failed to record state check: {e}")
+
+
+def diff_state_checks(old: dict, new: dict) -> dict:
+    """Which components changed between two recorded state checks, and which items in them.
+
+    Args:
+        old: Earlier state check (as returned by Database.get_state_check)
+        new: Later state check
+
+    Returns:
+        Dict with the changed components (added / removed / modified item keys each)
+        and the securities involved across all of them
+    """
+    components = {}
+    securities: set[str] = set()
+    for component in sorted(set(old["components"]) | set(new["components"])):
+        if old["components"].get(component) == new["components"].get(component):
+            continue
+        before = old["items"].get(component, {})
+        after = new["items"].get(component, {})
+        changes = {
+            "added": sorted(set(after) - set(before)),
+            "removed": sorted(set(before) - set(after)),
+            "modified": sorted(key for key in set(before) & set(after) if before[key] != after[key]),
+        }
+        components[component] = changes
+        securities.update(key for keys in changes.values() for key in keys if ":" not in key)
+    return {
+        "from": {"id": old["id"], "state_hash": old["state_hash"], "checked_at": old["checked_at"]},
+        "to": {"id": new["id"], "state_hash": new["state_hash"], "checked_at": new["checked_at"]},
+        "changed": sorted(components),
+        "components": components,
+        "securities": sorted(securities),
+    }
+
+
+def batch_cache_key(state_hash: str, params: dict) -> str:
+    """Cache key for a planner batch: state hash plus fingerprint of the batch parameters."""
+    return f"{BATCH_CACHE_PREFIX}{state_hash}:{fingerprint(params)[:16]}"
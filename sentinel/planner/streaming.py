@@ -0,0 +1,55 @@
+"""Streaming helpers for planner passes.
+
+Planner passes load price history for every security in the universe. Instead of
+materializing the whole universe up front, history is fetched in small chunks and
+handed out one security at a time, so only one chunk of rows is resident while
+signals and recommendations are computed.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import inspect
+from typing import AsyncIterator
+
+# Securities fetched per query when streaming price history
+PRICE_STREAM_CHUNK_SIZE = 25
+
+
+async def stream_price_history(
+    db,
+    symbols: list[str],
+    days: int,
+    end_date: str | None = None,
+    chunk_size: int = PRICE_STREAM_CHUNK_SIZE,
+) -> AsyncIterator[tuple[str, list[dict]]]:
+    """Yield (symbol, price rows newest first) for each symbol, fetching in chunks.
+
+    Uses the batched get_prices_for_symbols query when the database supports it,
+    falling back to per-symbol get_prices calls.
+
+    Args:
+        db: Database instance
+        symbols: Symbols to stream, yielded in this order
+        days: Number of most recent rows per symbol
+        end_date: If set, only rows on or before this date (YYYY-MM-DD)
+        chunk_size: Number of symbols fetched per query
+    """
+    chunk_size = max(1, int(chunk_size))
+    get_prices_multi = getattr(db, "get_prices_for_symbols", None)
+    for start in range(0, len(symbols), chunk_size):
+        chunk = symbols[start : start + chunk_size]
+        rows_by_symbol: dict[str, list[dict]] | None = None
+        if callable(get_prices_multi):
+            maybe_rows = get_prices_multi(chunk, days=days, end_date=end_date)
+            if inspect.isawaitable(maybe_rows):
+                resolved = await maybe_rows
+                if isinstance(resolved, dict):
+                    rows_by_symbol = resolved
+            elif isinstance(maybe_rows, dict):
+                rows_by_symbol = maybe_rows
+        if rows_by_symbol is None:
+            fetched = await asyncio.gather(*[db.get_prices(symbol, days=days, end_date=end_date) for symbol in chunk])
+            rows_by_symbol = {symbol: rows for symbol, rows in zip(chunk, fetched, strict=False)}
+        for symbol in chunk:
+            yield symbol, rows_by_symbol.get(symbol) or []
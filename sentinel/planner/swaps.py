@@ -0,0 +1,257 @@
+"""Swap trades: sell a weak holding specifically to fund a stronger buy.
+
+Funding rotation sells (rebalance_cash) raise cash for the buy list as a whole.
+A swap instead pairs one below-threshold holding with one higher-scored buy that
+cash cannot cover, evaluates the pair jointly (fees on both legs, capital gains
+tax on the sold lot, score improvement) and tags both legs with a shared
+swap_group so execution treats them as a dependent basket: the buy only runs
+after its sell has filled.
+
+Pairing is pruned so large universes stay cheap: holdings are tried in score
+order, so once one fails the score-delta test every later one is dominated and
+the search for that buy stops; holdings whose evaluation inputs match one
+already rejected for the same buy are skipped as duplicates; and sell-leg
+costs are memoized per (holding, quantity) across buys.
+"""
+
+from __future__ import annotations
+
+from dataclasses import dataclass, replace
+from typing import Callable, Optional
+
+from sentinel.utils.quantity import ceil_to_lot
+
+from .models import TradeRecommendation
+from .rebalance_rules import calculate_transaction_cost
+
+
+@dataclass
+class SwapSettings:
+    """Thresholds for accepting a swap pair."""
+
+    max_sell_score: float = 0.2  # Only holdings scoring at or below this are swap candidates
+    min_score_delta: float = 0.25  # Buy score must beat the sold holding by at least this
+    max_cost_pct: float = 0.02  # Fees + tax must stay below this share of the swapped value
+    tax_rate: float = 0.0  # Capital gains tax rate applied to realized gains on the sold lot
+    fee_fixed: float = 2.0
+    fee_pct: float = 0.002
+    max_evaluations: int = 0  # Cap on swap pairs evaluated per run (0 = unlimited)
+    should_stop: Optional[Callable[[], bool]] = None  # Stop pairing once this returns True (time budget)
+
+
+def swap_group_id(sell_symbol: str, buy_symbol: str) -> str:
+    """Stable identifier shared by both legs of a swap."""
+    return f"swap:{sell_symbol}->{buy_symbol}"
+
+
+def sell_leg_cost(holding: dict, sell_quantity: float, config: SwapSettings) -> dict:
+    """Value, fees and capital gains tax of selling `sell_quantity` of a holding."""
+    fx_rate = holding.get("fx_rate") or 1.0
+    sell_value = sell_quantity * holding["price"] * fx_rate
+    gain = (holding["price"] - float(holding.get("avg_cost") or 0.0)) * sell_quantity * fx_rate
+    return {
+        "sell_value_eur": sell_value,
+        "fees_eur": calculate_transaction_cost(sell_value, config.fee_fixed, config.fee_pct),
+        "tax_eur": max(0.0, gain) * config.tax_rate,
+    }
+
+
+def evaluate_swap(
+    holding: dict,
+    buy: TradeRecommendation,
+    sell_quantity: float,
+    config: SwapSettings,
+    sell_leg: dict | None = None,
+) -> dict:
+    """Evaluate a sell/buy pair jointly.
+
+    Args:
+        holding: Position data (price, avg_cost, fx_rate, score)
+        buy: Buy leg
+        sell_quantity: Quantity of the holding to sell
+        config: Swap thresholds and cost parameters
+        sell_leg: Precomputed sell_leg_cost() of the holding and quantity (memoized by plan_swaps)
+
+    Returns:
+        dict with sell_value_eur, fees_eur, tax_eur, net_cost_eur, score_delta, accepted
+    """
+    sell_leg = sell_leg or sell_leg_cost(holding, sell_quantity, config)
+    sell_value = sell_leg["sell_value_eur"]
+    fees = sell_leg["fees_eur"] + calculate_transaction_cost(buy.value_delta_eur, config.fee_fixed, config.fee_pct)
+    tax = sell_leg["tax_eur"]
+    net_cost = fees + tax
+    score_delta = float(buy.contrarian_score) - float(holding["score"])
+    accepted = (
+        sell_value > 0
+        and score_delta >= config.min_score_delta
+        and net_cost <= config.max_cost_pct * max(sell_value, buy.value_delta_eur)
+    )
+    return {
+        "sell_value_eur": sell_value,
+        "fees_eur": fees,
+        "tax_eur": tax,
+        "net_cost_eur": net_cost,
+        "score_delta": score_delta,
+        "accepted": accepted,
+    }
+
+
+def _evaluation_signature(holding: dict, sell_quantity: float) -> tuple:
+    """Inputs that fully determine a swap evaluation for a given buy (the symbol does not)."""
+    return (
+        holding["score"],
+        holding["price"] * (holding.get("fx_rate") or 1.0),
+        float(holding.get("avg_cost") or 0.0) * (holding.get("fx_rate") or 1.0),
+        sell_quantity,
+    )
+
+
+def plan_swaps(
+    recommendations: list[TradeRecommendation],
+    holdings: list[dict],
+    available_cash_eur: float,
+    config: SwapSettings,
+    stats: dict | None = None,
+) -> list[TradeRecommendation]:
+    """Pair unfunded buys with weak holdings to sell.
+
+    Buys are funded in priority order from cash plus existing sell proceeds; each
+    buy left (partly) unfunded is matched with the lowest-scored eligible holding
+    whose swap evaluates as worthwhile. A holding is used for at most one swap.
+    With config.max_evaluations set, pairing stops once that many pairs were
+    evaluated (the planner's resource cap); pruned pairs do not count.
+
+    Args:
+        recommendations: Current recommendation list
+        holdings: Position data per symbol: symbol, quantity, price, avg_cost, fx_rate,
+            currency, lot_size, score, allow_sell, current_allocation
+        available_cash_eur: Cash available before any trades
+        config: Swap thresholds and cost parameters
+        stats: Optional dict filled with evaluated, pruned_dominated, deduplicated and memo_hits counts
+
+    Returns:
+        Recommendations with swap sells prepended and paired buys tagged
+    """
+    sells = [r for r in recommendations if r.action == "sell"]
+    buys = [r for r in recommendations if r.action == "buy"]
+    if not buys:
+        return recommendations
+
+    traded = {r.symbol for r in recommendations}
+    candidates = sorted(
+        (
+            h
+            for h in holdings
+            if h["symbol"] not in traded
+            and h.get("allow_sell", 1)
+            and h["quantity"] > 0
+            and h["price"] > 0
+            and h["score"] <= config.max_sell_score
+        ),
+        key=lambda h: h["score"],
+    )
+    budget = available_cash_eur + sum(
+        abs(r.value_delta_eur) - calculate_transaction_cost(abs(r.value_delta_eur), config.fee_fixed, config.fee_pct)
+        for r in sells
+    )
+
+    swap_sells: list[TradeRecommendation] = []
+    paired: dict[str, TradeRecommendation] = {}
+    evaluations = pruned = duplicates = memo_hits = 0
+    sell_legs: dict[tuple[str, float], dict] = {}
+    for buy in sorted(buys, key=lambda r: -r.priority):
+        if config.max_evaluations and evaluations >= config.max_evaluations:
+            break
+        if config.should_stop is not None and config.should_stop():
+            break
+        cost = buy.value_delta_eur + calculate_transaction_cost(buy.value_delta_eur, config.fee_fixed, config.fee_pct)
+        shortfall = min(cost, cost - budget)
+        budget -= cost
+        if shortfall <= 0:
+            continue
+        rejected: set[tuple] = set()
+        for position, holding in enumerate(candidates):
+            # Candidates are sorted by score: once the score delta is too small, all later ones fail too
+            if float(buy.contrarian_score) - float(holding["score"]) < config.min_score_delta:
+                pruned += len(candidates) - position
+                break
+            fx_rate = holding.get("fx_rate") or 1.0
+            needed = shortfall / (1.0 - config.fee_pct) + config.fee_fixed
+            raw_quantity = needed / (holding["price"] * fx_rate)
+            sell_quantity = min(holding["quantity"], ceil_to_lot(raw_quantity, holding["lot_size"]))
+            if sell_quantity <= 0:
+                continue
+            signature = _evaluation_signature(holding, sell_quantity)
+            if signature in rejected:
+                duplicates += 1
+                continue
+            if config.max_evaluations and evaluations >= config.max_evaluations:
+                break
+            evaluations += 1
+            leg_key = (holding["symbol"], sell_quantity)
+            if leg_key in sell_legs:
+                memo_hits += 1
+            else:
+                sell_legs[leg_key] = sell_leg_cost(holding, sell_quantity, config)
+            evaluation = evaluate_swap(holding, buy, sell_quantity, config, sell_legs[leg_key])
+            if not evaluation["accepted"]:
+                rejected.add(signature)
+                continue
+            candidates.remove(holding)
+            group = swap_group_id(holding["symbol"], buy.symbol)
+            swap_fields = {
+                "swap_group": group,
+                "swap_net_cost_eur": round(evaluation["net_cost_eur"], 2),
+                "swap_score_delta": round(evaluation["score_delta"], 4),
+                "swap_tax_eur": round(evaluation["tax_eur"], 2),
+            }
+            sell_value = evaluation["sell_value_eur"]
+            current_value = holding["quantity"] * holding["price"] * fx_rate
+            swap_sells.append(
+                TradeRecommendation(
+                    symbol=holding["symbol"],
+                    action="sell",
+                    current_allocation=holding.get("current_allocation", 0.0),
+                    target_allocation=holding.get("current_allocation", 0.0) * (1 - sell_value / current_value),
+                    allocation_delta=-holding.get("current_allocation", 0.0) * sell_value / current_value,
+                    current_value_eur=current_value,
+                    target_value_eur=current_value - sell_value,
+                    value_delta_eur=-sell_value,
+                    quantity=sell_quantity,
+                    price=holding["price"],
+                    currency=holding.get("currency", "EUR"),
+                    lot_size=holding["lot_size"],
+                    contrarian_score=holding["score"],
+                    priority=buy.priority,
+                    reason=(
+                        f"Swap: sell weak holding (score {holding['score']:.2f}) to fund {buy.symbol} "
+                        f"(score {buy.contrarian_score:.2f}, net cost {evaluation['net_cost_eur']:.0f} EUR)"
+                    ),
+                    reason_code="swap_sell",
+                    sleeve="core",
+                    **swap_fields,
+                )
+            )
+            paired[buy.symbol] = replace(
+                buy, reason=f"{buy.reason} (funded by swap from {holding['symbol']})", **swap_fields
+            )
+            budget += sell_value - calculate_transaction_cost(sell_value, config.fee_fixed, config.fee_pct)
+            break
+
+    if stats is not None:
+        stats.update(
+            {"evaluated": evaluations, "pruned_dominated": pruned, "deduplicated": duplicates, "memo_hits": memo_hits}
+        )
+    if not swap_sells:
+        return recommendations
+    return swap_sells + [paired.get(r.symbol, r) if r.action == "buy" else r for r in recommendations]
+
+
+def drop_orphaned_swap_legs(recommendations: list[TradeRecommendation]) -> list[TradeRecommendation]:
+    """Remove swap legs whose counterpart was trimmed away (e.g. by the cash constraint)."""
+    legs: dict[str, set[str]] = {}
+    for rec in recommendations:
+        if rec.swap_group:
+            legs.setdefault(rec.swap_group, set()).add(rec.action)
+    complete = {group for group, actions in legs.items() if actions == {"buy", "sell"}}
+    return [r for r in recommendations if not r.swap_group or r.swap_group in complete]
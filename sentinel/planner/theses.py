@@ -0,0 +1,61 @@
+"""Position theses as advisory tags for the planner.
+
+A position can carry the user's target price, stop level and investment
+thesis (position_theses). thesis_tags() names the conditions that hold at a
+price: target_reached, stop_reached and review_due. The planner attaches them
+to the symbol's recommendations as advisory tags and boosts the priority of
+sells at or above the target; a thesis alone never creates or blocks a trade.
+"""
+
+from __future__ import annotations
+
+from .models import TradeRecommendation
+
+THESIS_TAGS = ("target_reached", "stop_reached", "review_due")
+
+DAY_SECONDS = 86400
+
+
+def review_due_at(thesis: dict) -> int | None:
+    """Unix time the thesis is next due for review (None when it has no review interval)."""
+    if not thesis.get("review_days"):
+        return None
+    return int(thesis["reviewed_at"]) + int(thesis["review_days"]) * DAY_SECONDS
+
+
+def thesis_tags(thesis: dict, price: float, now: float) -> list[str]:
+    """Conditions of a thesis at a price (no price tags when the price is unknown)."""
+    tags = []
+    if price > 0 and thesis.get("target_price") and price >= float(thesis["target_price"]):
+        tags.append("target_reached")
+    if price > 0 and thesis.get("stop_price") and price <= float(thesis["stop_price"]):
+        tags.append("stop_reached")
+    due_at = review_due_at(thesis)
+    if due_at is not None and now >= due_at:
+        tags.append("review_due")
+    return tags
+
+
+def apply_thesis_tags(
+    recommendations: list[TradeRecommendation],
+    theses: dict[str, dict],
+    now: float,
+    sell_boost: float = 0.5,
+) -> None:
+    """
+    Tag recommendations in place with their thesis conditions at the recommended price.
+
+    A sell of a position at or above its target price has its priority scaled
+    by (1 + sell_boost).
+    """
+    for rec in recommendations:
+        thesis = theses.get(rec.symbol)
+        if not thesis:
+            continue
+        tags = thesis_tags(thesis, rec.price, now)
+        if not tags:
+            continue
+        rec.advisory_tags = tags
+        if rec.action == "sell" and "target_reached" in tags and sell_boost > 0:
+            rec.priority *= 1.0 + sell_boost
+            rec.reason = f"{rec.reason} (target price {float(thesis['target_price']):g} reached)"
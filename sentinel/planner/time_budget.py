@@ -0,0 +1,100 @@
+"""Wall-clock time budget for one live planner run (anytime planning).
+
+Symbols are evaluated in order of importance (held positions first, so their
+sells and trims are always planned, then buy candidates by ideal weight) and
+swap pairs in order of buy priority. When the budget runs out, evaluation
+stops and the run continues with what it has: the best plan found so far
+instead of none. Backtests and as-of runs are never budgeted, so replays stay
+deterministic.
+
+The budget is planner_time_budget_seconds, or when that is 0 a share of the
+planning:refresh job interval so a run always finishes well before the next one
+is due. Each live run updates truncation counters kept in the cache.
+"""
+
+from __future__ import annotations
+
+import time
+from typing import Callable
+
+# Share of the planning:refresh interval a run may take when no budget is configured
+DEFAULT_INTERVAL_SHARE = 0.5
+STATS_CACHE_KEY = "planner:time_budget_stats"
+
+
+def budget_seconds(configured: float, interval_minutes: float | None) -> float:
+    """Budget for a run: the configured seconds, else a share of the job interval (0 = unlimited)."""
+    if configured > 0:
+        return float(configured)
+    if interval_minutes and interval_minutes > 0:
+        return float(interval_minutes) * 60.0 * DEFAULT_INTERVAL_SHARE
+    return 0.0
+
+
+def evaluation_order(symbols: list[str], held: set[str], ideal: dict[str, float]) -> list[str]:
+    """Held symbols first, then the others by descending ideal weight (ties by symbol)."""
+    return sorted(symbols, key=lambda s: (s not in held, -ideal.get(s, 0.0), s))
+
+
+class TimeBudget:
+    """Deadline for one run that records where, if anywhere, it cut evaluation short."""
+
+    def __init__(self, seconds: float, clock: Callable[[], float] = time.monotonic):
+        self.seconds = max(0.0, float(seconds))
+        self._clock = clock
+        self._started = clock()
+        self.truncated_stage: str | None = None
+        self.evaluated: int | None = None
+        self.total: int | None = None
+
+    @property
+    def elapsed(self) -> float:
+        return self._clock() - self._started
+
+    def expired(self) -> bool:
+        """True once the budget is spent (never for an unlimited budget)."""
+        return self.seconds > 0 and self.elapsed >= self.seconds
+
+    def truncate(self, stage: str, evaluated: int | None = None, total: int | None = None) -> None:
+        """Record that `stage` stopped after `evaluated` of `total` items (the first stage cut is kept)."""
+        if self.truncated_stage is None:
+            self.truncated_stage = stage
+            self.evaluated = evaluated
+            self.total = total
+
+    def stop_check(self, stage: str) -> Callable[[], bool]:
+        """Callback for an inner loop: True (and the cut recorded against `stage`) once expired."""
+
+        def should_stop() -> bool:
+            if self.expired():
+                self.truncate(stage)
+                return True
+            return False
+
+        return should_stop
+
+    @property
+    def truncated(self) -> bool:
+        return self.truncated_stage is not None
+
+    def as_dict(self) -> dict:
+        return {
+            "budget_seconds": self.seconds,
+            "elapsed_seconds": round(self.elapsed, 3),
+            "truncated": self.truncated,
+            "truncated_stage": self.truncated_stage,
+            "evaluated": self.evaluated,
+            "total": self.total,
+        }
+
+
+def update_stats(stats: dict | None, run: dict, now: int) -> dict:
+    """Fold one run's budget outcome into the running counters."""
+    stats = dict(stats or {"runs": 0, "truncated": 0})
+    stats["runs"] = int(stats.get("runs", 0)) + 1
+    if run["truncated"]:
+        stats["truncated"] = int(stats.get("truncated", 0)) + 1
+        stats["last_truncated_at"] = now
+    stats["truncation_rate"] = round(stats["truncated"] / stats["runs"], 4)
+    stats["last_run"] = run
+    return stats
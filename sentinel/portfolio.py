@@ -9,6 +9,7 @@ Usage:
     allocations = await portfolio.get_allocations()
 """
 
+import logging
 from typing import Optional
 
 from sentinel.broker import Broker
@@ -16,57 +17,89 @@ from sentinel.currency import Currency
 from sentinel.database import Database
 from sentinel.security import Security
 from sentinel.settings import Settings
-from sentinel.utils.positions import PositionCalculator
+from sentinel.utils.positions import PositionCalculator, adjustment_overrides
 from sentinel.utils.strings import parse_csv_field
 
+logger = logging.getLogger(__name__)
+
+
+def _spread(weights: dict, names: str | None, pct: float) -> None:
+    """Add pct to weights, split equally over comma-separated names ("Unknown" when none)."""
+    parts = parse_csv_field(names) or ["Unknown"]
+    for name in parts:
+        weights[name] = weights.get(name, 0) + pct / len(parts)
+
 
 class Portfolio:
     """Represents the entire portfolio with all operations."""
 
-    def __init__(self, db=None, broker=None):
+    def __init__(self, db=None, broker=None, currency=None):
         """
         Initialize portfolio with optional dependency injection.
 
         Args:
             db: Database instance (uses singleton if None)
             broker: Broker instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
         """
         self._db = db or Database()
         self._broker = broker or Broker()
         self._settings = Settings()
-        self._currency = Currency()
+        self._currency = currency or Currency()
         self._cash: dict[str, float] = {}
 
     async def sync(self) -> "Portfolio":
         """Sync portfolio state from broker to database."""
         data = await self._broker.get_portfolio()
 
+        broker_symbols = {pos["symbol"] for pos in data.get("positions", [])}
+        adjustments = await self._db.get_latest_position_adjustments()
+
         # Update positions and securities
         for pos in data.get("positions", []):
             symbol = pos["symbol"]
+            isin = pos.get("isin")
 
-            # Ensure security exists in database
             existing = await self._db.get_security(symbol)
+            if not existing and isin:
+                # Same ISIN under a ticker the broker no longer reports: the ticker changed
+                previous = [
+                    sec for sec in await self._db.get_securities_by_isin(isin) if sec["symbol"] not in broker_symbols
+                ]
+                if len(previous) == 1:
+                    logger.info(f"Ticker change detected for {isin}: {previous[0]['symbol']} -> {symbol}")
+                    await self._db.rename_symbol(previous[0]["symbol"], symbol, reason="broker ticker change")
+                    existing = await self._db.get_security(symbol)
+
+            # Ensure security exists in database
             if not existing:
                 await self._db.upsert_security(
                     symbol, name=pos.get("name", symbol), currency=pos.get("currency", "EUR"), active=1
                 )
-
-            # Update position
+            if isin and (existing or {}).get("isin") != isin:
+                await self._db.set_security_isin(symbol, isin)
+
+            # Update position, keeping a manual correction of the figures the broker still reports
+            quantity, avg_cost = pos["quantity"], pos.get("avg_cost")
+            adjustment = adjustments.get(symbol)
+            if adjustment and adjustment_overrides(adjustment, quantity, avg_cost):
+                quantity, avg_cost = adjustment["quantity_after"], adjustment["avg_cost_after"]
             await self._db.upsert_position(
                 symbol,
-                quantity=pos["quantity"],
-                avg_cost=pos.get("avg_cost"),
+                quantity=quantity,
+                avg_cost=avg_cost,
                 current_price=pos.get("current_price"),
                 currency=pos.get("currency", "EUR"),
                 updated_at="now",
             )
 
         # Zero out positions that no longer exist in the broker account
-        broker_symbols = {pos["symbol"] for pos in data.get("positions", [])}
         db_positions = await self._db.get_all_positions()
         for pos in db_positions:
-            if pos["symbol"] not in broker_symbols:
+            if pos["symbol"] in broker_symbols:
+                continue
+            adjustment = adjustments.get(pos["symbol"])
+            if not (adjustment and adjustment_overrides(adjustment, 0, None)):
                 await self._db.upsert_position(pos["symbol"], quantity=0, updated_at="now")
 
         # Store cash balances in memory and database
@@ -156,6 +189,31 @@ class Portfolio:
             result.append(sec)
         return result
 
+    # -------------------------------------------------------------------------
+    # External Holdings
+    # -------------------------------------------------------------------------
+
+    async def external_holdings(self) -> list[dict]:
+        """Holdings outside the broker account, with value_eur and the linked security's geography/industry."""
+        holdings = await self._db.get_external_holdings()
+        if not holdings:
+            return []
+        securities_map = {s["symbol"]: s for s in await self._db.get_all_securities(active_only=False)}
+        for holding in holdings:
+            security = securities_map.get(holding["symbol"]) or {}
+            holding["value_eur"] = await self._currency.to_eur(float(holding["value"] or 0), holding["currency"])
+            holding["geography"] = holding["geography"] or security.get("geography")
+            holding["industry"] = holding["industry"] or security.get("industry")
+        return holdings
+
+    async def external_value(self) -> float:
+        """Total value of the external holdings in EUR."""
+        return sum(h["value_eur"] for h in await self.external_holdings())
+
+    async def net_worth(self) -> float:
+        """Broker account plus external holdings, in EUR."""
+        return await self.total_value() + await self.external_value()
+
     # -------------------------------------------------------------------------
     # Allocations
     # -------------------------------------------------------------------------
@@ -164,11 +222,18 @@ class Portfolio:
         """
         Get current allocation percentages (all values converted to EUR).
         Returns: {'by_security': {...}, 'by_geography': {...}, 'by_industry': {...}}
+
+        by_security covers the broker account (what the planner trades). With
+        external_holdings_in_allocations on, geography and industry cover net
+        worth: external holdings count towards them as well.
         """
         positions = await self._db.get_all_positions()
         total = await self.total_value()
+        counted = await self._settings.get("external_holdings_in_allocations", True)
+        external = await self.external_holdings() if counted else []
+        exposure_total = total + sum(h["value_eur"] for h in external)
 
-        if total == 0:
+        if exposure_total == 0:
             return {"by_security": {}, "by_geography": {}, "by_industry": {}}
 
         by_security = {}
@@ -187,28 +252,19 @@ class Portfolio:
             pos_currency = pos.get("currency", "EUR")
 
             value_eur = await pos_calc.calculate_value_eur(qty, price, pos_currency)
-            pct = value_eur / total
-
-            by_security[symbol] = pct
+            by_security[symbol] = value_eur / total if total else 0.0
 
             # Get security metadata
             sec_data = securities_map.get(symbol)
             if sec_data:
-                # Handle comma-separated geographies (split equally)
-                geos = parse_csv_field(sec_data.get("geography"))
-                if not geos:
-                    geos = ["Unknown"]
-                geo_weight = pct / len(geos)
-                for geo in geos:
-                    by_geography[geo] = by_geography.get(geo, 0) + geo_weight
-
-                # Handle comma-separated industries (split equally)
-                inds = parse_csv_field(sec_data.get("industry"))
-                if not inds:
-                    inds = ["Unknown"]
-                ind_weight = pct / len(inds)
-                for ind in inds:
-                    by_industry[ind] = by_industry.get(ind, 0) + ind_weight
+                pct = value_eur / exposure_total
+                _spread(by_geography, sec_data.get("geography"), pct)
+                _spread(by_industry, sec_data.get("industry"), pct)
+
+        for holding in external:
+            pct = holding["value_eur"] / exposure_total
+            _spread(by_geography, holding["geography"], pct)
+            _spread(by_industry, holding["industry"], pct)
 
         return {
             "by_security": by_security,
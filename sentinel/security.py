@@ -8,15 +8,35 @@ Usage:
     await security.buy(10)
 """
 
+import logging
 from datetime import datetime, timedelta
 from typing import Optional
 
 from sentinel.broker import Broker
 from sentinel.database import Database
 from sentinel.settings import Settings
+from sentinel.utils.fees import market_of
+from sentinel.utils.order_pricing import (
+    DEFAULT_BUFFER_PCT,
+    DEFAULT_TIGHT_SPREAD_PCT,
+    DEFAULT_VWAP_WINDOW_DAYS,
+    adaptive_price,
+    buffered_price,
+    depth_limit_price,
+    midpoint_price,
+    needs_tier,
+    parse_pricing_strategies,
+    pick_strategy,
+    vwap,
+)
+from sentinel.utils.quantity import floor_to_lot, lot_step
+
+logger = logging.getLogger(__name__)
 
 # Duplicate trade protection: skip if traded within this many minutes
 TRADE_COOLOFF_MINUTES = 60
+# Daily prices averaged when deciding whether a security is thinly traded
+THIN_VOLUME_DAYS = 20
 
 
 class Security:
@@ -29,6 +49,9 @@ class Security:
         self._settings = Settings()
         self._data: Optional[dict] = None
         self._position: Optional[dict] = None
+        # How the last order was priced:
+        # {"method": one of PRICING_METHODS, "limit_price": float|None, "quote_price": float|None}
+        self.last_pricing: Optional[dict] = None
 
     async def load(self) -> "Security":
         """Load security data from database."""
@@ -66,10 +89,23 @@ class Security:
     def aliases(self) -> Optional[str]:
         return self._data.get("aliases") if self._data else None
 
+    @property
+    def isin(self) -> Optional[str]:
+        return self._data.get("isin") if self._data else None
+
     @property
     def min_lot(self) -> int:
         return self._data.get("min_lot", 1) if self._data else 1
 
+    @property
+    def supports_fractional(self) -> bool:
+        return bool(self._data.get("supports_fractional", 0)) if self._data else False
+
+    @property
+    def lot_step(self) -> float:
+        """Smallest tradable quantity increment (min_lot, or a fractional step)."""
+        return lot_step(self.min_lot, self.supports_fractional)
+
     @property
     def active(self) -> bool:
         return bool(self._data.get("active", 1)) if self._data else False
@@ -185,24 +221,140 @@ class Security:
             return None
         return quote.get("bid") or quote.get("bbp")
 
-    async def buy(self, quantity: int, auto_convert: bool = True) -> Optional[str]:
+    async def _is_thinly_traded(self, threshold_eur: float) -> bool:
+        """Check if the average daily traded value is below threshold_eur (or unknown)."""
+        rows = await self._db.get_prices(self.symbol, days=THIN_VOLUME_DAYS)
+        values = [float(r["close"]) * float(r["volume"]) for r in rows if r.get("close") and r.get("volume")]
+        if not values:
+            return True
+        from sentinel.currency import Currency
+
+        avg_value_eur = await Currency().to_eur(sum(values) / len(values), self.currency)
+        return avg_value_eur < threshold_eur
+
+    async def _get_depth_price(self, action: str, quantity: float) -> Optional[float]:
+        """Get a limit price from order book depth, or None if depth pricing does not apply."""
+        if not await self._settings.get("limit_pricing_depth_enabled", True):
+            return None
+        threshold = float(await self._settings.get("limit_depth_thin_value_eur", 250000))
+        if not await self._is_thinly_traded(threshold):
+            return None
+        book = await self._broker.get_order_book(self.symbol)
+        if not book:
+            return None
+        max_slippage_pct = float(await self._settings.get("limit_depth_max_slippage_pct", 2.0))
+        return depth_limit_price(action, quantity, book, max_slippage_pct)
+
+    async def _pricing_strategy(self) -> Optional[dict]:
+        """Strategy configured for this security's market and liquidity tier (order_pricing_strategies)."""
+        try:
+            strategies = parse_pricing_strategies(await self._settings.get("order_pricing_strategies", {}))
+        except ValueError as e:
+            logger.warning(f"Ignoring invalid order_pricing_strategies: {e}")
+            return None
+        if not strategies:
+            return None
+        tier = None
+        if needs_tier(strategies):
+            threshold = float(await self._settings.get("limit_depth_thin_value_eur", 250000))
+            tier = "thin" if await self._is_thinly_traded(threshold) else "liquid"
+        return pick_strategy(strategies, market_of(self.symbol), tier)
+
+    async def _strategy_price(self, action: str, quantity: float, strategy: dict) -> Optional[float]:
+        """Limit price from a pricing strategy, or None when it has nothing to price from (or is market)."""
+        name = strategy["strategy"]
+        bid, ask = self._get_bid_price(), self._get_ask_price()
+        if name == "quote":
+            return ask if action == "buy" else bid
+        if name == "depth":
+            book = await self._broker.get_order_book(self.symbol)
+            if not book:
+                return None
+            max_slippage_pct = float(await self._settings.get("limit_depth_max_slippage_pct", 2.0))
+            return depth_limit_price(action, quantity, book, max_slippage_pct)
+        if name == "midpoint":
+            return midpoint_price(bid, ask)
+        if name == "adaptive":
+            return adaptive_price(action, bid, ask, strategy.get("tight_spread_pct", DEFAULT_TIGHT_SPREAD_PCT))
+        if name == "vwap":
+            window_days = strategy.get("window_days", DEFAULT_VWAP_WINDOW_DAYS)
+            reference = vwap(await self._db.get_prices(self.symbol, days=window_days))
+        elif name == "last_buffer":
+            quote = self._get_quote_data() or {}
+            reference = quote.get("price") or quote.get("ltp") or self.current_price
+        else:
+            return None
+        return buffered_price(action, reference, strategy.get("buffer_pct", DEFAULT_BUFFER_PCT[name]))
+
+    async def _get_limit_price(self, action: str, quantity: float) -> Optional[float]:
+        """Choose the limit price for an order (None = market order) and store it in last_pricing.
+
+        A strategy configured for the security's market and liquidity tier
+        (order_pricing_strategies) is used first. Without one, or when it has no
+        data to price from, thinly traded names are priced from order book depth.
+        Otherwise, or when depth data is unavailable, Asian markets use the quoted
+        ask/bid (market orders not supported) and everything else goes as a
+        market order.
+        """
+        limit_price = None
+        method = None
+        try:
+            strategy = await self._pricing_strategy()
+            if strategy is not None:
+                limit_price = await self._strategy_price(action, quantity, strategy)
+                if limit_price or (strategy["strategy"] == "market" and not self._is_asian_market()):
+                    method = strategy["strategy"]
+        except Exception as e:
+            logger.warning(f"Pricing strategy unavailable for {self.symbol}, using fallback: {e}")
+            limit_price = None
+
+        if method is None:
+            try:
+                limit_price = await self._get_depth_price(action, quantity)
+            except Exception as e:
+                logger.warning(f"Depth pricing unavailable for {self.symbol}, using fallback: {e}")
+                limit_price = None
+
+            if limit_price:
+                method = "depth"
+            elif self._is_asian_market():
+                side = "ask" if action == "buy" else "bid"
+                limit_price = self._get_ask_price() if action == "buy" else self._get_bid_price()
+                if not limit_price:
+                    raise ValueError(f"Cannot {action} {self.symbol}: no {side} price available for limit order")
+                method = "quote"
+            else:
+                method = "market"
+
+        self.last_pricing = {"method": method, "limit_price": limit_price, "quote_price": self._quoted_price(action)}
+        return limit_price
+
+    def _quoted_price(self, action: str) -> Optional[float]:
+        """Quoted price an order is measured against: the ask for a buy, the bid for a sell, else the last price."""
+        quote = self._get_quote_data() or {}
+        side = self._get_ask_price() if action == "buy" else self._get_bid_price()
+        return side or quote.get("price") or quote.get("ltp") or None
+
+    async def buy(self, quantity: float, auto_convert: bool = True, check_cooloff: bool = True) -> Optional[str]:
         """Buy this security. Returns order ID if successful.
 
         Args:
-            quantity: Number of shares to buy
+            quantity: Number of shares to buy (fractional if the security supports it)
             auto_convert: If True, automatically converts EUR to target currency if needed
+            check_cooloff: Refuse when the symbol traded in the last TRADE_COOLOFF_MINUTES
+                (off for the child orders of a sliced order)
         """
         if not self.allow_buy:
             raise ValueError(f"Buying {self.symbol} is not allowed")
 
         # Duplicate trade protection
-        if await self._has_recent_trade():
+        if check_cooloff and await self._has_recent_trade():
             raise ValueError(f"Trade on {self.symbol} already submitted within last {TRADE_COOLOFF_MINUTES} minutes")
 
-        # Round to lot size
-        quantity = (quantity // self.min_lot) * self.min_lot
-        if quantity < self.min_lot or quantity == 0:
-            raise ValueError(f"Quantity must be at least {self.min_lot}")
+        # Round to lot size (or fractional step)
+        quantity = floor_to_lot(quantity, self.lot_step)
+        if quantity < self.lot_step or quantity == 0:
+            raise ValueError(f"Quantity must be at least {self.lot_step}")
 
         # Get price to calculate trade value
         price = await self.get_price()
@@ -283,40 +435,30 @@ class Security:
                             f"{total_expected_eur:.2f} EUR, but need {trade_value:.2f} EUR."
                         )
 
-        # For Asian markets, use limit order at ask price (market orders not supported)
-        limit_price = None
-        if self._is_asian_market():
-            limit_price = self._get_ask_price()
-            if not limit_price:
-                raise ValueError(f"Cannot buy {self.symbol}: no ask price available for limit order")
+        limit_price = await self._get_limit_price("buy", quantity)
 
         order_id = await self._broker.buy(self.symbol, quantity, price=limit_price)
         # Note: Trades are synced from broker, not recorded locally
         return order_id
 
-    async def sell(self, quantity: int) -> Optional[str]:
-        """Sell this security. Returns order ID if successful."""
+    async def sell(self, quantity: float, check_cooloff: bool = True) -> Optional[str]:
+        """Sell this security. Returns order ID if successful (check_cooloff as for buy)."""
         if not self.allow_sell:
             raise ValueError(f"Selling {self.symbol} is not allowed")
 
         # Duplicate trade protection
-        if await self._has_recent_trade():
+        if check_cooloff and await self._has_recent_trade():
             raise ValueError(f"Trade on {self.symbol} already submitted within last {TRADE_COOLOFF_MINUTES} minutes")
 
         if quantity > self.quantity:
             raise ValueError(f"Cannot sell {quantity}, only own {self.quantity}")
 
-        # Round to lot size
-        quantity = (quantity // self.min_lot) * self.min_lot
-        if quantity < self.min_lot or quantity == 0:
-            raise ValueError(f"Quantity must be at least {self.min_lot}")
+        # Round to lot size (or fractional step)
+        quantity = floor_to_lot(quantity, self.lot_step)
+        if quantity < self.lot_step or quantity == 0:
+            raise ValueError(f"Quantity must be at least {self.lot_step}")
 
-        # For Asian markets, use limit order at bid price (market orders not supported)
-        limit_price = None
-        if self._is_asian_market():
-            limit_price = self._get_bid_price()
-            if not limit_price:
-                raise ValueError(f"Cannot sell {self.symbol}: no bid price available for limit order")
+        limit_price = await self._get_limit_price("sell", quantity)
 
         order_id = await self._broker.sell(self.symbol, quantity, price=limit_price)
         # Note: Trades are synced from broker, not recorded locally
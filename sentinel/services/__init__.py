@@ -4,6 +4,108 @@ Services encapsulate business operations that span multiple domain objects
 or require complex orchestration beyond what individual models provide.
 """
 
+from sentinel.services.allocation_optimizer import AllocationOptimizerService
+from sentinel.services.approvals import ApprovalService
+from sentinel.services.archive import ArchiveService
+from sentinel.services.attribution import AttributionService
+from sentinel.services.auto_invest import AutoInvestService
+from sentinel.services.auth import AuthService
+from sentinel.services.broker_documents import BrokerDocumentService
+from sentinel.services.charts import ChartService
+from sentinel.services.circuit_breaker import CircuitBreakerService
+from sentinel.services.computed_columns import ComputedColumnService
+from sentinel.services.config_drift import ConfigDriftService
+from sentinel.services.correlations import CorrelationService
+from sentinel.services.currency_exposure import CurrencyExposureService
+from sentinel.services.dividends import DividendService
+from sentinel.services.drip import DripService
+from sentinel.services.execution_quality import ExecutionQualityService
+from sentinel.services.exports import ExportService
+from sentinel.services.external_holdings import ExternalHoldingService
+from sentinel.services.health import HealthService
+from sentinel.services.instrument_ids import InstrumentIdService
+from sentinel.services.intraday import IntradayService
+from sentinel.services.journal import JournalService
+from sentinel.services.liquidity import LiquidityService
+from sentinel.services.lite import LiteService
+from sentinel.services.logs import LogBuffer
+from sentinel.services.mail import MailService
+from sentinel.services.market_data import MarketDataService
+from sentinel.services.market_stress import MarketStressService
+from sentinel.services.metadata_enrichment import MetadataEnrichmentService
+from sentinel.services.news import NewsService
+from sentinel.services.notifications import NotificationService
+from sentinel.services.order_slicing import OrderSlicingService
 from sentinel.services.portfolio import PortfolioService
+from sentinel.services.position_adjustments import PositionAdjustmentService
+from sentinel.services.position_theses import PositionThesisService
+from sentinel.services.profiling import ProfilingService
+from sentinel.services.protective_orders import ProtectiveOrderService
+from sentinel.services.public_dashboard import PublicDashboardService
+from sentinel.services.quality_gates import QualityGateService
+from sentinel.services.reconciliation import ReconciliationService
+from sentinel.services.regime import RegimeService
+from sentinel.services.reports import ReportService
+from sentinel.services.retention import RetentionService
+from sentinel.services.scheduled_orders import ScheduledOrderService
+from sentinel.services.score_recalculation import ScoreRecalculationService
+from sentinel.services.security_archive import SecurityArchiveService
+from sentinel.services.shadow import ShadowService
+from sentinel.services.sleeve_funding import SleeveFundingService
+from sentinel.services.telemetry import TelemetryService
+from sentinel.services.watchlist import WatchlistService
+from sentinel.services.webhooks import WebhookService
 
-__all__ = ["PortfolioService"]
+__all__ = [
+    "AllocationOptimizerService",
+    "ApprovalService",
+    "ArchiveService",
+    "AttributionService",
+    "AutoInvestService",
+    "AuthService",
+    "BrokerDocumentService",
+    "ChartService",
+    "CircuitBreakerService",
+    "ComputedColumnService",
+    "ConfigDriftService",
+    "CorrelationService",
+    "CurrencyExposureService",
+    "DividendService",
+    "DripService",
+    "ExecutionQualityService",
+    "ExportService",
+    "ExternalHoldingService",
+    "HealthService",
+    "InstrumentIdService",
+    "IntradayService",
+    "JournalService",
+    "LiquidityService",
+    "LiteService",
+    "LogBuffer",
+    "MailService",
+    "MarketDataService",
+    "MarketStressService",
+    "MetadataEnrichmentService",
+    "NewsService",
+    "NotificationService",
+    "OrderSlicingService",
+    "PortfolioService",
+    "PositionAdjustmentService",
+    "PositionThesisService",
+    "ProfilingService",
+    "ProtectiveOrderService",
+    "PublicDashboardService",
+    "QualityGateService",
+    "ReconciliationService",
+    "RegimeService",
+    "ReportService",
+    "RetentionService",
+    "ScheduledOrderService",
+    "ScoreRecalculationService",
+    "SecurityArchiveService",
+    "ShadowService",
+    "SleeveFundingService",
+    "TelemetryService",
+    "WatchlistService",
+    "WebhookService",
+]
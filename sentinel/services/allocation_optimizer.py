@@ -0,0 +1,177 @@
+"""Optimizer proposals for the geography and industry allocation targets.
+
+A proposal weights the groups of one target type from the scored universe: the
+active securities open for buying, split across the groups they list (like
+Portfolio.get_allocations). Each group's expected score is the average core
+rank of its members' cached signals, and its return series the average daily
+return of its members. The optimizer (allocation_optimizer_method) runs within
+the per-group bounds and the move away from the active targets is capped by
+allocation_optimizer_turnover_pct.
+
+Proposals are stored next to the targets that were active when they were made.
+The planner keeps using allocation_targets until a proposal is accepted.
+"""
+
+from __future__ import annotations
+
+import logging
+
+from sentinel.database import Database
+from sentinel.planner.correlation import daily_returns
+from sentinel.settings import Settings
+from sentinel.strategy.optimizer import (
+    Bounds,
+    apply_turnover_budget,
+    covariance,
+    make_optimizer,
+    turnover,
+)
+from sentinel.utils.strings import parse_csv_field
+
+logger = logging.getLogger(__name__)
+
+TARGET_TYPES = ("geography", "industry")
+
+
+def parse_group_bounds(raw) -> dict[str, dict[str, tuple[float, float]]]:
+    """Validate an allocation_optimizer_group_bounds value: type -> group -> [min_pct, max_pct]."""
+    if raw is None:
+        return {}
+    if not isinstance(raw, dict):
+        raise ValueError("allocation_optimizer_group_bounds must be an object of type -> group -> [min, max]")
+    parsed: dict[str, dict[str, tuple[float, float]]] = {}
+    for target_type, groups in raw.items():
+        if target_type not in TARGET_TYPES:
+            raise ValueError(f"unknown target type {target_type} (expected geography or industry)")
+        if not isinstance(groups, dict):
+            raise ValueError(f"bounds of {target_type} must map groups to [min, max]")
+        for group, bounds in groups.items():
+            if (
+                not isinstance(bounds, list)
+                or len(bounds) != 2
+                or not all(isinstance(b, (int, float)) and not isinstance(b, bool) for b in bounds)
+                or not 0 <= bounds[0] <= bounds[1] <= 100
+            ):
+                raise ValueError(f"bounds of {target_type} {group} must be [min, max] percentages, min <= max")
+            parsed.setdefault(target_type, {})[group] = (bounds[0] / 100, bounds[1] / 100)
+    return parsed
+
+
+class AllocationOptimizerService:
+    """Proposes allocation targets and applies the ones the user accepts."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def universe(self, target_type: str) -> dict[str, dict[str, float]]:
+        """Group -> symbol -> membership share of the active securities open for buying."""
+        groups: dict[str, dict[str, float]] = {}
+        for sec in await self._db.get_all_securities(active_only=True):
+            names = parse_csv_field(sec.get(target_type))
+            if sec["symbol"].startswith("_AGG_") or not names or not sec.get("allow_buy", 1):
+                continue
+            for name in names:
+                groups.setdefault(name, {})[sec["symbol"]] = 1.0 / len(names)
+        return groups
+
+    async def active_targets(self, target_type: str) -> dict[str, float]:
+        """Active targets of the type, normalized to fractions."""
+        weights = {t["name"]: t["weight"] for t in await self._db.get_allocation_targets(target_type)}
+        total = sum(weights.values())
+        return {name: weight / total for name, weight in weights.items()} if total > 0 else {}
+
+    async def _bounds(self, target_type: str, groups: list[str]) -> Bounds:
+        low = float(await self._settings.get("allocation_optimizer_min_weight_pct", 0)) / 100
+        high = float(await self._settings.get("allocation_optimizer_max_weight_pct", 50)) / 100
+        overrides = parse_group_bounds(await self._settings.get("allocation_optimizer_group_bounds", {}))
+        bounds = {group: (low, high) for group in groups}
+        bounds.update({g: b for g, b in overrides.get(target_type, {}).items() if g in bounds})
+        return bounds
+
+    async def propose(self, target_type: str, method: str | None = None) -> dict:
+        """Optimize the groups of a target type and store the proposal.
+
+        Args:
+            target_type: geography or industry
+            method: risk_parity or mean_variance (defaults to allocation_optimizer_method)
+
+        Returns:
+            The stored proposal
+
+        Raises:
+            ValueError: Unknown type or method, too few groups, or bounds that cannot be met
+        """
+        if target_type not in TARGET_TYPES:
+            raise ValueError(f"Unknown target type {target_type} (expected geography or industry)")
+        method = method or await self._settings.get("allocation_optimizer_method", "risk_parity")
+        optimizer = make_optimizer(method, float(await self._settings.get("allocation_optimizer_risk_aversion", 5)))
+        members = await self.universe(target_type)
+        if len(members) < 2:
+            raise ValueError(f"Need at least two {target_type} groups with buyable securities to optimize")
+
+        symbols = sorted({symbol for group in members.values() for symbol in group})
+        days = int(await self._settings.get("allocation_optimizer_history_days", 252))
+        prices = await self._db.get_prices_bulk(symbols, days=days + 1)
+        returns = {symbol: daily_returns(prices.get(symbol, [])) for symbol in symbols}
+        states = await self._db.get_score_states(symbols)
+
+        expected, series = {}, {}
+        for group, shares in sorted(members.items()):
+            ranks = {
+                s: float(states[s]["signal"]["signal"].get("core_rank", 0.0))
+                for s in shares
+                if (states.get(s) or {}).get("signal")
+            }
+            total = sum(shares[s] for s in ranks)
+            expected[group] = sum(shares[s] * rank for s, rank in ranks.items()) / total if total > 0 else 0.0
+            by_date: dict[str, list[float]] = {}
+            for symbol in shares:
+                for day, value in returns[symbol].items():
+                    by_date.setdefault(day, []).append(value)
+            series[group] = {day: sum(values) / len(values) for day, values in by_date.items()}
+
+        common = sorted(set.intersection(*(set(s) for s in series.values())))
+        cov = covariance({group: [series[group][day] for day in common] for group in series})
+        bounds = await self._bounds(target_type, list(expected))
+        optimal = optimizer.optimize(expected, cov, bounds)
+
+        active = await self.active_targets(target_type)
+        budget = float(await self._settings.get("allocation_optimizer_turnover_pct", 25)) / 100
+        weights = {g: round(w, 6) for g, w in apply_turnover_budget(optimal, active, budget).items() if w > 1e-6}
+        variance = sum(weights.get(a, 0) * weights.get(b, 0) * cov[a][b] for a in cov for b in cov)
+        stats = {
+            "expected_score": round(sum(weights.get(g, 0) * expected[g] for g in expected), 6),
+            "volatility": round(max(variance, 0.0) ** 0.5, 6),
+            "turnover": round(turnover(weights, active), 6),
+            "unconstrained_turnover": round(turnover(optimal, active), 6),
+            "observations": len(common),
+            "group_scores": {g: round(v, 6) for g, v in expected.items()},
+            "bounds": {g: list(b) for g, b in bounds.items()},
+        }
+        proposal_id = await self._db.save_allocation_proposal(target_type, optimizer.method, weights, active, stats)
+        logger.info(f"Allocation proposal {proposal_id} ({target_type}, {optimizer.method}): {weights}")
+        return await self._db.get_allocation_proposal(proposal_id)
+
+    async def proposals(self, target_type: str | None = None, limit: int = 20) -> list[dict]:
+        """Stored proposals, most recent first."""
+        return await self._db.get_allocation_proposals(target_type, limit)
+
+    async def decide(self, proposal_id: int, accept: bool) -> dict | None:
+        """Accept (making its weights the active targets) or reject a pending proposal.
+
+        Returns:
+            The updated proposal, or None when it is unknown or already decided
+        """
+        if not await self._db.decide_allocation_proposal(proposal_id, accept):
+            return None
+        if accept:
+            await self._db.cache_clear("planner:")
+        return await self._db.get_allocation_proposal(proposal_id)
+
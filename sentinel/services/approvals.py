@@ -0,0 +1,153 @@
+"""Manual approval of trade recommendations.
+
+With trading_manual_approval on, live execution only places recommendations
+that were approved in the inbox. Decisions are keyed by symbol and side, since
+recommendations are recomputed on every planner run:
+
+- approve: may be executed while the recommended quantity does not exceed the
+  approved one, until approval_valid_hours pass or the order is placed
+- reject: kept out of execution for approval_valid_hours
+- defer: hidden from the pending list for approval_defer_hours
+
+Once a decision expires the recommendation is pending again.
+"""
+
+from __future__ import annotations
+
+import time
+
+from sentinel.audit import record_action
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.portfolio import Portfolio
+from sentinel.settings import Settings
+from sentinel.utils.fees import FeeCalculator
+
+DECISIONS = {"approve": "approved", "reject": "rejected", "defer": "deferred"}
+
+
+class ApprovalService:
+    """Inbox of pending recommendations and the approval gate for execution."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        planner=None,
+        portfolio: Portfolio | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+            portfolio: Portfolio instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+        self._portfolio = portfolio or Portfolio(db=self._db, currency=self._currency)
+        self._planner = planner
+
+    async def enabled(self) -> bool:
+        return bool(await self._settings.get("trading_manual_approval", False))
+
+    async def _recommendations(self) -> list:
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db, currency=self._currency)
+        return await self._planner.get_recommendations()
+
+    async def _decisions(self, now: int) -> dict[tuple[str, str], dict]:
+        return {(d["symbol"], d["action"]): d for d in await self._db.get_recommendation_decisions(now=now)}
+
+    @staticmethod
+    def _status(rec, decision: dict | None) -> str:
+        if decision is None:
+            return "pending"
+        if decision["status"] == "approved" and rec.quantity > float(decision["quantity"]) + 1e-9:
+            # The planner now wants more than was approved
+            return "pending"
+        return decision["status"]
+
+    async def inbox(self, now: int | None = None) -> dict:
+        """Current recommendations with cost, allocation impact and decision status (pending first)."""
+        now = now or int(time.time())
+        recommendations = await self._recommendations()
+        decisions = await self._decisions(now)
+        total_value = await self._portfolio.total_value()
+        fees = FeeCalculator(self._settings)
+
+        items = []
+        for rec in recommendations:
+            decision = decisions.get((rec.symbol, rec.action))
+            status = self._status(rec, decision)
+            value_eur = abs(rec.value_delta_eur)
+            projected = (rec.current_value_eur + rec.value_delta_eur) / total_value * 100 if total_value else 0.0
+            items.append(
+                {
+                    "symbol": rec.symbol,
+                    "action": rec.action,
+                    "quantity": rec.quantity,
+                    "price": rec.price,
+                    "currency": rec.currency,
+                    "value_eur": round(value_eur, 2),
+                    "estimated_cost_eur": round(await fees.calculate(value_eur, rec.symbol, rec.currency), 2),
+                    "current_allocation_pct": round(rec.current_allocation * 100, 2),
+                    "projected_allocation_pct": round(projected, 2),
+                    "target_allocation_pct": round(rec.target_allocation * 100, 2),
+                    "reason": rec.reason,
+                    "reason_code": rec.reason_code,
+                    "priority": rec.priority,
+                    "status": status,
+                    "decided_at": decision["decided_at"] if decision and status != "pending" else None,
+                    "expires_at": decision["expires_at"] if decision and status != "pending" else None,
+                }
+            )
+        items.sort(key=lambda item: item["status"] != "pending")
+        return {
+            "manual_approval": await self.enabled(),
+            "pending": sum(1 for item in items if item["status"] == "pending"),
+            "items": items,
+        }
+
+    async def decide(self, symbol: str, action: str, decision: str, now: int | None = None) -> dict:
+        """Approve, reject or defer the current recommendation for symbol and side.
+
+        Raises:
+            ValueError: Unknown decision
+            LookupError: No such recommendation right now
+        """
+        if decision not in DECISIONS:
+            raise ValueError(f"Unknown decision: {decision}")
+        now = now or int(time.time())
+        rec = next((r for r in await self._recommendations() if r.symbol == symbol and r.action == action), None)
+        if rec is None:
+            raise LookupError(f"No pending {action} recommendation for {symbol}")
+
+        hours_key = "approval_defer_hours" if decision == "defer" else "approval_valid_hours"
+        hours = float(await self._settings.get(hours_key, 24) or 24)
+        status = DECISIONS[decision]
+        expires_at = now + int(hours * 3600)
+        await self._db.set_recommendation_decision(
+            symbol, action, status, rec.quantity, expires_at=expires_at, decided_at=now
+        )
+        params = {"side": action, "status": status, "quantity": rec.quantity, "expires_at": expires_at}
+        await record_action(self._db, f"approval.{decision}", symbol, params)
+        return {"symbol": symbol, "action": action, "status": status, "expires_at": expires_at}
+
+    async def approved(self, recommendations: list, now: int | None = None) -> list:
+        """Recommendations cleared for execution; a swap needs both legs approved."""
+        decisions = await self._decisions(now or int(time.time()))
+        cleared = [r for r in recommendations if self._status(r, decisions.get((r.symbol, r.action))) == "approved"]
+        cleared_ids = {id(r) for r in cleared}
+        blocked_swaps = {r.swap_group for r in recommendations if r.swap_group and id(r) not in cleared_ids}
+        return [r for r in cleared if not r.swap_group or r.swap_group not in blocked_swaps]
+
+    async def consume(self, rec) -> None:
+        """Drop the approval once its order has been placed."""
+        await self._db.delete_recommendation_decision(rec.symbol, rec.action)
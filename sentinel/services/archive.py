@@ -0,0 +1,156 @@
+"""Closed position archival service.
+
+Fully closed positions keep their trades and trade decisions in the hot tables
+forever. This service finds completed round trips (a symbol's trades running
+from flat back to flat) that closed long enough ago, and moves them into the
+archive tables together with a summary row, so live queries stay small while the
+history remains browsable through the archive API.
+"""
+
+from __future__ import annotations
+
+import logging
+import time
+from datetime import datetime
+
+from sentinel.database import Database
+from sentinel.services.attribution import DECISION_MATCH_WINDOW_SECONDS
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+# Only round trips closed at least this long ago are archived (overridable via settings)
+DEFAULT_ARCHIVE_AFTER_DAYS = 365
+# Remaining quantity below this is treated as flat (float noise from fractional fills)
+FLAT_QUANTITY_EPSILON = 1e-6
+
+
+def find_closed_round_trips(trades: list[dict]) -> list[list[dict]]:
+    """Split one symbol's trades into completed flat-to-flat round trips.
+
+    Trades after the last time the position was flat (i.e. an open position) are
+    not returned. If the history goes short (sells without matching buys, e.g.
+    trades from before the broker sync window), nothing is returned for the
+    symbol, since the round-trip boundaries cannot be trusted.
+
+    Args:
+        trades: Trades for a single symbol, any order
+
+    Returns:
+        List of round trips, each a list of trades oldest first
+    """
+    round_trips: list[list[dict]] = []
+    current: list[dict] = []
+    quantity = 0.0
+    for trade in sorted(trades, key=lambda t: (t["executed_at"], t["id"])):
+        signed = float(trade["quantity"]) * (1 if trade["side"] == "BUY" else -1)
+        quantity += signed
+        current.append(trade)
+        if quantity < -FLAT_QUANTITY_EPSILON:
+            return []
+        if abs(quantity) <= FLAT_QUANTITY_EPSILON:
+            round_trips.append(current)
+            current = []
+            quantity = 0.0
+    return round_trips
+
+
+def summarize_round_trip(symbol: str, trades: list[dict]) -> dict:
+    """Build the archived_positions summary columns for one round trip."""
+    buys = [t for t in trades if t["side"] == "BUY"]
+    sells = [t for t in trades if t["side"] == "SELL"]
+    cost = sum(float(t["quantity"]) * float(t["price"]) for t in buys)
+    proceeds = sum(float(t["quantity"]) * float(t["price"]) for t in sells)
+    return {
+        "symbol": symbol,
+        "opened_at": int(trades[0]["executed_at"]),
+        "closed_at": int(trades[-1]["executed_at"]),
+        "bought_quantity": sum(float(t["quantity"]) for t in buys),
+        "sold_quantity": sum(float(t["quantity"]) for t in sells),
+        "cost_local": round(cost, 6),
+        "proceeds_local": round(proceeds, 6),
+        "realized_pnl_local": round(proceeds - cost, 6),
+        "commission": round(sum(float(t.get("commission") or 0) for t in trades), 6),
+        "trade_count": len(trades),
+    }
+
+
+class ArchiveService:
+    """Moves closed positions into the archive store and reads them back."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional database.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def archive_closed_positions(self, older_than_days: int | None = None, now: int | None = None) -> dict:
+        """Archive every completed round trip that closed more than `older_than_days` ago.
+
+        Args:
+            older_than_days: Minimum age of the closing trade (defaults to the
+                archive_closed_after_days setting)
+            now: Reference unix timestamp (defaults to current time)
+
+        Returns:
+            dict with counts of archived round trips, trades and decisions, and the symbols touched
+        """
+        if older_than_days is None:
+            older_than_days = int(
+                await Settings().get("archive_closed_after_days", DEFAULT_ARCHIVE_AFTER_DAYS)
+                or DEFAULT_ARCHIVE_AFTER_DAYS
+            )
+        now = now if now is not None else int(time.time())
+        cutoff = now - older_than_days * 86400
+
+        open_symbols = {p["symbol"] for p in await self._db.get_all_positions()}
+        cutoff_date = datetime.fromtimestamp(cutoff).strftime("%Y-%m-%d")
+        trades_by_symbol: dict[str, list[dict]] = {}
+        for trade in await self._db.get_trades(end_date=cutoff_date, limit=1000000):
+            trades_by_symbol.setdefault(trade["symbol"], []).append(trade)
+
+        result = {"archived": 0, "trades": 0, "decisions": 0, "symbols": []}
+        for symbol, trades in sorted(trades_by_symbol.items()):
+            round_trips = [rt for rt in find_closed_round_trips(trades) if rt[-1]["executed_at"] <= cutoff]
+            if not round_trips:
+                continue
+            security = await self._db.get_security(symbol) or {}
+            for round_trip in round_trips:
+                summary = summarize_round_trip(symbol, round_trip)
+                summary["currency"] = security.get("currency")
+                summary["data"] = {
+                    "name": security.get("name"),
+                    "geography": security.get("geography"),
+                    "industry": security.get("industry"),
+                    "still_held": symbol in open_symbols,
+                }
+                decisions = await self._db.get_trade_decisions(
+                    start_ts=summary["opened_at"] - DECISION_MATCH_WINDOW_SECONDS,
+                    end_ts=summary["closed_at"],
+                )
+                decision_ids = [d["id"] for d in decisions if d["symbol"] == symbol]
+                trade_ids = [t["id"] for t in round_trip]
+                await self._db.archive_position(summary, trade_ids, decision_ids)
+                result["archived"] += 1
+                result["trades"] += len(trade_ids)
+                result["decisions"] += len(decision_ids)
+            result["symbols"].append(symbol)
+
+        if result["archived"]:
+            logger.info(
+                f"Archived {result['archived']} closed round trips "
+                f"({result['trades']} trades, {result['decisions']} decisions)"
+            )
+        return result
+
+    async def list_archived(self, symbol: str | None = None, limit: int = 50, offset: int = 0) -> dict:
+        """Page through archived round trips, most recently closed first."""
+        positions = await self._db.get_archived_positions(symbol=symbol, limit=limit, offset=offset)
+        total = await self._db.get_archived_positions_count(symbol=symbol)
+        return {"positions": positions, "count": len(positions), "total": total}
+
+    async def get_archived(self, archive_id: int) -> dict | None:
+        """One archived round trip with its trades and decisions."""
+        return await self._db.get_archived_position(archive_id)
@@ -0,0 +1,377 @@
+"""Return attribution service.
+
+Decomposes portfolio returns over a period two ways:
+
+- By factor: allocation vs selection (Brinson-Fachler against the allocation
+  targets, using the equal-weighted universe as the benchmark inside each group)
+  plus the currency effect of holding non-EUR securities.
+- By decision source: which planner rule / job produced each trade, by joining
+  synced broker trades with the decisions persisted at order submission. The
+  same trades are also grouped by the evaluation component (opportunity,
+  quality, risk-adjusted, diversification, regime) that dominated each
+  recommendation's selection.
+"""
+
+from __future__ import annotations
+
+from collections import defaultdict
+from datetime import date as date_type
+from datetime import datetime, timedelta, timezone
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.utils.strings import parse_csv_field
+
+PERIOD_DAYS = {"1M": 30, "3M": 91, "6M": 182, "1Y": 365, "3Y": 1095, "5Y": 1825}
+ATTRIBUTION_DIMENSIONS = ("geography", "industry")
+
+# Trades without a matching order ID are paired with the nearest decision on the
+# same symbol/side submitted within this window.
+DECISION_MATCH_WINDOW_SECONDS = 3 * 86400
+
+
+def resolve_period(period: str, end_date: str | None = None) -> tuple[str, str]:
+    """Resolve a period label (1M, 3M, 6M, YTD, 1Y, 3Y, 5Y) into (start_date, end_date) ISO strings."""
+    end = datetime.strptime(end_date, "%Y-%m-%d").date() if end_date else date_type.today()
+    key = period.upper()
+    if key == "YTD":
+        start = date_type(end.year, 1, 1)
+    elif key in PERIOD_DAYS:
+        start = end - timedelta(days=PERIOD_DAYS[key])
+    else:
+        raise ValueError(f"Unknown period: {period}")
+    return start.isoformat(), end.isoformat()
+
+
+def _midnight_utc_ts(iso_date: str) -> int:
+    return int(datetime.strptime(iso_date, "%Y-%m-%d").replace(tzinfo=timezone.utc).timestamp())
+
+
+def _trade_order_id(trade: dict) -> str | None:
+    raw = trade.get("raw_data")
+    if not isinstance(raw, dict):
+        return None
+    order_id = raw.get("order_id") or raw.get("orderId")
+    return str(order_id) if order_id else None
+
+
+class AttributionService:
+    """Attributes portfolio returns to factors and decision sources."""
+
+    def __init__(self, db: Database | None = None, currency: Currency | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._currency = currency or Currency()
+
+    async def get_attribution(
+        self,
+        period: str = "1Y",
+        dimension: str = "geography",
+        end_date: str | None = None,
+    ) -> dict:
+        """Get factor and decision-source attribution for a period.
+
+        Args:
+            period: 1M, 3M, 6M, YTD, 1Y, 3Y or 5Y
+            dimension: Grouping used for allocation/selection (geography or industry)
+            end_date: Optional period end (YYYY-MM-DD), defaults to today
+
+        Returns:
+            dict with period bounds, factor attribution and decision-source attribution
+        """
+        if dimension not in ATTRIBUTION_DIMENSIONS:
+            raise ValueError(f"Unknown dimension: {dimension}")
+        start_date, end_date = resolve_period(period, end_date)
+
+        securities = await self._db.get_all_securities(active_only=False)
+        sec_map = {s["symbol"]: s for s in securities}
+
+        return {
+            "period": period.upper(),
+            "start_date": start_date,
+            "end_date": end_date,
+            "dimension": dimension,
+            "factors": await self._factor_attribution(start_date, end_date, dimension, sec_map),
+            "decision_sources": await self._decision_attribution(start_date, end_date, sec_map),
+        }
+
+    # -------------------------------------------------------------------------
+    # Factor attribution
+    # -------------------------------------------------------------------------
+
+    async def _factor_attribution(self, start_date: str, end_date: str, dimension: str, sec_map: dict) -> dict:
+        """Brinson-Fachler allocation/selection plus currency effect over the period."""
+        empty = {
+            "portfolio_return": 0.0,
+            "local_return": 0.0,
+            "benchmark_return": 0.0,
+            "excess_return": 0.0,
+            "allocation_effect": 0.0,
+            "selection_effect": 0.0,
+            "currency_effect": 0.0,
+            "groups": [],
+            "securities": [],
+            "excluded": [],
+        }
+
+        snapshot = await self._db.get_portfolio_snapshot_as_of(_midnight_utc_ts(start_date))
+        if not snapshot:
+            return empty
+        holdings = {
+            symbol: float(pos.get("value_eur", 0) or 0)
+            for symbol, pos in snapshot["data"].get("positions", {}).items()
+            if float(pos.get("value_eur", 0) or 0) > 0
+        }
+        if not holdings:
+            return empty
+
+        universe = sorted({s for s, sec in sec_map.items() if sec.get("active", 1)} | set(holdings))
+        returns = await self._security_returns(universe, start_date, end_date, sec_map)
+
+        excluded = sorted(s for s in holdings if s not in returns)
+        invested = sum(v for s, v in holdings.items() if s in returns)
+        if invested <= 0:
+            return {**empty, "excluded": excluded}
+
+        def groups_for(symbol: str) -> list[str]:
+            return parse_csv_field((sec_map.get(symbol) or {}).get(dimension)) or ["Unknown"]
+
+        # Portfolio side: weights and local returns per group
+        port_weight: dict[str, float] = defaultdict(float)
+        port_return_num: dict[str, float] = defaultdict(float)
+        security_rows = []
+        local_return = 0.0
+        total_return = 0.0
+        currency_effect = 0.0
+        for symbol, value in holdings.items():
+            if symbol not in returns:
+                continue
+            r = returns[symbol]
+            weight = value / invested
+            local_return += weight * r["local_return"]
+            total_return += weight * r["return_eur"]
+            currency_effect += weight * (r["return_eur"] - r["local_return"])
+            groups = groups_for(symbol)
+            for group in groups:
+                port_weight[group] += weight / len(groups)
+                port_return_num[group] += weight / len(groups) * r["local_return"]
+            security_rows.append(
+                {
+                    "symbol": symbol,
+                    "weight": round(weight, 6),
+                    "local_return": round(r["local_return"], 6),
+                    "currency_return": round(r["currency_return"], 6),
+                    "return_eur": round(r["return_eur"], 6),
+                    "contribution": round(weight * r["return_eur"], 6),
+                }
+            )
+
+        # Benchmark side: equal-weighted universe return inside each group
+        bench_members: dict[str, list[float]] = defaultdict(list)
+        for symbol, r in returns.items():
+            for group in groups_for(symbol):
+                bench_members[group].append(r["local_return"])
+        bench_return = {g: sum(v) / len(v) for g, v in bench_members.items() if v}
+
+        targets = await self._db.get_allocation_targets(dimension)
+        target_total = sum(float(t["weight"]) for t in targets)
+        if target_total > 0:
+            bench_weight = {t["name"]: float(t["weight"]) / target_total for t in targets}
+        else:
+            # No targets configured: benchmark weights equal portfolio weights (pure selection)
+            bench_weight = dict(port_weight)
+        benchmark_total = sum(w * bench_return.get(g, 0.0) for g, w in bench_weight.items())
+
+        group_rows = []
+        allocation_total = 0.0
+        selection_total = 0.0
+        for group in sorted(set(port_weight) | set(bench_weight)):
+            wp = port_weight.get(group, 0.0)
+            wb = bench_weight.get(group, 0.0)
+            rp = port_return_num[group] / wp if wp > 0 else 0.0
+            rb = bench_return.get(group, rp)
+            allocation = (wp - wb) * (rb - benchmark_total)
+            selection = wp * (rp - rb)
+            allocation_total += allocation
+            selection_total += selection
+            group_rows.append(
+                {
+                    "name": group,
+                    "portfolio_weight": round(wp, 6),
+                    "benchmark_weight": round(wb, 6),
+                    "portfolio_return": round(rp, 6),
+                    "benchmark_return": round(rb, 6),
+                    "allocation_effect": round(allocation, 6),
+                    "selection_effect": round(selection, 6),
+                }
+            )
+
+        security_rows.sort(key=lambda row: -abs(row["contribution"]))
+        return {
+            "portfolio_return": round(total_return, 6),
+            "local_return": round(local_return, 6),
+            "benchmark_return": round(benchmark_total, 6),
+            "excess_return": round(total_return - benchmark_total, 6),
+            "allocation_effect": round(allocation_total, 6),
+            "selection_effect": round(selection_total, 6),
+            "currency_effect": round(currency_effect, 6),
+            "groups": group_rows,
+            "securities": security_rows,
+            "excluded": excluded,
+        }
+
+    async def _security_returns(self, symbols: list[str], start_date: str, end_date: str, sec_map: dict) -> dict:
+        """Local, currency and EUR returns per symbol. Symbols without both prices are omitted."""
+        start_prices = await self._db.get_prices_bulk(symbols, days=1, end_date=start_date)
+        end_prices = await self._db.get_prices_bulk(symbols, days=1, end_date=end_date)
+
+        fx_cache: dict[str, float] = {}
+        result = {}
+        for symbol in symbols:
+            start_rows = start_prices.get(symbol) or []
+            end_rows = end_prices.get(symbol) or []
+            if not start_rows or not end_rows:
+                continue
+            p0 = float(start_rows[0].get("close") or 0)
+            p1 = float(end_rows[0].get("close") or 0)
+            if p0 <= 0 or p1 <= 0:
+                continue
+
+            currency = (sec_map.get(symbol) or {}).get("currency") or "EUR"
+            if currency not in fx_cache:
+                fx0 = await self._currency.get_rate_for_date(currency, start_date)
+                fx1 = await self._currency.get_rate_for_date(currency, end_date)
+                fx_cache[currency] = (fx1 / fx0 - 1.0) if fx0 > 0 else 0.0
+            currency_return = fx_cache[currency]
+
+            local_return = p1 / p0 - 1.0
+            result[symbol] = {
+                "local_return": local_return,
+                "currency_return": currency_return,
+                "return_eur": (1.0 + local_return) * (1.0 + currency_return) - 1.0,
+            }
+        return result
+
+    # -------------------------------------------------------------------------
+    # Decision-source attribution
+    # -------------------------------------------------------------------------
+
+    async def _decision_attribution(self, start_date: str, end_date: str, sec_map: dict) -> dict:
+        """Value added by each decision source, measured against the period-end price.
+
+        Buys add value when the price rose after the fill; sells add value when the
+        price fell after the fill (loss avoided).
+        """
+        trades = await self._db.get_trades(
+            start_date=start_date, end_date=end_date, limit=100000, include_archived=True
+        )
+        start_ts = _midnight_utc_ts(start_date)
+        end_ts = _midnight_utc_ts(end_date) + 86400
+        decisions = await self._db.get_trade_decisions(
+            start_ts - DECISION_MATCH_WINDOW_SECONDS, end_ts + DECISION_MATCH_WINDOW_SECONDS, include_archived=True
+        )
+
+        by_order = {d["order_id"]: d for d in decisions if d.get("order_id")}
+        used: set[int] = set()
+
+        symbols = sorted({t["symbol"] for t in trades})
+        end_prices = await self._db.get_prices_bulk(symbols, days=1, end_date=end_date) if symbols else {}
+        fx_end: dict[str, float] = {}
+
+        buckets: dict[tuple[str, str | None], dict] = {}
+        component_buckets: dict[str, dict] = {}
+        matched = 0
+        for trade in sorted(trades, key=lambda t: t["executed_at"]):
+            decision = self._match_decision(trade, by_order, decisions, used)
+            if decision:
+                matched += 1
+                used.add(decision["id"])
+                key = (decision["source"], decision.get("reason_code"))
+            else:
+                key = ("unattributed", None)
+
+            symbol = trade["symbol"]
+            qty = float(trade["quantity"])
+            fill = float(trade["price"])
+            end_rows = end_prices.get(symbol) or []
+            end_price = float(end_rows[0]["close"]) if end_rows and end_rows[0].get("close") else fill
+
+            currency = (sec_map.get(symbol) or {}).get("currency") or "EUR"
+            if currency not in fx_end:
+                fx_end[currency] = await self._currency.get_rate_for_date(currency, end_date)
+            rate = fx_end[currency]
+
+            if trade["side"] == "BUY":
+                value_add = qty * (end_price - fill) * rate
+            else:
+                value_add = qty * (fill - end_price) * rate
+
+            bucket = buckets.setdefault(
+                key,
+                {
+                    "source": key[0],
+                    "reason_code": key[1],
+                    "trades": 0,
+                    "buys": 0,
+                    "sells": 0,
+                    "traded_value_eur": 0.0,
+                    "value_add_eur": 0.0,
+                },
+            )
+            bucket["trades"] += 1
+            bucket["buys" if trade["side"] == "BUY" else "sells"] += 1
+            bucket["traded_value_eur"] += qty * fill * rate
+            bucket["value_add_eur"] += value_add
+
+            component = (decision or {}).get("dominant_component") or "unscored"
+            component_bucket = component_buckets.setdefault(
+                component,
+                {"component": component, "trades": 0, "winners": 0, "traded_value_eur": 0.0, "value_add_eur": 0.0},
+            )
+            component_bucket["trades"] += 1
+            component_bucket["winners"] += 1 if value_add > 0 else 0
+            component_bucket["traded_value_eur"] += qty * fill * rate
+            component_bucket["value_add_eur"] += value_add
+
+        sources = sorted(buckets.values(), key=lambda b: -b["value_add_eur"])
+        for bucket in sources:
+            bucket["traded_value_eur"] = round(bucket["traded_value_eur"], 2)
+            bucket["value_add_eur"] = round(bucket["value_add_eur"], 2)
+
+        # Realized performance by the evaluation component that dominated each selection
+        components = sorted(component_buckets.values(), key=lambda b: -b["value_add_eur"])
+        for bucket in components:
+            traded = bucket["traded_value_eur"]
+            bucket["return_pct"] = round(bucket["value_add_eur"] / traded * 100, 2) if traded > 0 else 0.0
+            bucket["hit_rate"] = round(bucket["winners"] / bucket["trades"], 4)
+            bucket["traded_value_eur"] = round(traded, 2)
+            bucket["value_add_eur"] = round(bucket["value_add_eur"], 2)
+
+        return {
+            "sources": sources,
+            "components": components,
+            "matched_trades": matched,
+            "unmatched_trades": len(trades) - matched,
+        }
+
+    @staticmethod
+    def _match_decision(trade: dict, by_order: dict, decisions: list[dict], used: set[int]) -> dict | None:
+        order_id = _trade_order_id(trade)
+        if order_id and order_id in by_order and by_order[order_id]["id"] not in used:
+            return by_order[order_id]
+
+        action = "buy" if trade["side"] == "BUY" else "sell"
+        best = None
+        best_gap = DECISION_MATCH_WINDOW_SECONDS + 1
+        for decision in decisions:
+            if decision["id"] in used or decision["symbol"] != trade["symbol"] or decision["action"] != action:
+                continue
+            gap = abs(int(decision["created_at"]) - int(trade["executed_at"]))
+            if gap < best_gap:
+                best, best_gap = decision, gap
+        return best
@@ -0,0 +1,140 @@
+"""API authentication service.
+
+Credentials are API tokens (long-lived, created by an admin) and optional local
+users who log in with a password and receive a session token. Each credential
+carries one of three roles, ordered by privilege:
+
+- viewer: read-only access
+- operator: day-to-day actions (sync, run jobs, place trades, acknowledge notifications)
+- admin: settings, job schedules, backups, and credential management
+
+Tokens and passwords are only stored hashed.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import hmac
+import secrets
+import time
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+ROLES = ("viewer", "operator", "admin")
+TOKEN_PREFIX_LENGTH = 8
+PASSWORD_ITERATIONS = 200_000
+
+
+def role_allows(role: str | None, required: str) -> bool:
+    """Whether `role` is at least as privileged as `required`."""
+    if role not in ROLES:
+        return False
+    return ROLES.index(role) >= ROLES.index(required)
+
+
+def hash_token(token: str) -> str:
+    """SHA-256 hex digest used to look up a token."""
+    return hashlib.sha256(token.encode()).hexdigest()
+
+
+def hash_password(password: str, salt: bytes | None = None) -> str:
+    """PBKDF2-SHA256 hash in the form pbkdf2$<iterations>$<salt hex>$<hash hex>."""
+    salt = salt or secrets.token_bytes(16)
+    digest = hashlib.pbkdf2_hmac("sha256", password.encode(), salt, PASSWORD_ITERATIONS)
+    return f"pbkdf2${PASSWORD_ITERATIONS}${salt.hex()}${digest.hex()}"
+
+
+def verify_password(password: str, stored: str) -> bool:
+    """Check a password against a hash from hash_password()."""
+    try:
+        _, iterations, salt, expected = stored.split("$")
+        digest = hashlib.pbkdf2_hmac("sha256", password.encode(), bytes.fromhex(salt), int(iterations))
+    except ValueError:
+        return False
+    return hmac.compare_digest(digest.hex(), expected)
+
+
+class AuthService:
+    """Issues, verifies and revokes API credentials."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def enabled(self) -> bool:
+        """Whether requests must be authenticated (auth_enabled setting)."""
+        return bool(await self._settings.get("auth_enabled", False))
+
+    async def create_token(
+        self,
+        name: str,
+        role: str,
+        username: str | None = None,
+        expires_at: int | None = None,
+    ) -> dict:
+        """Create a token. The plaintext token is only returned here.
+
+        Raises:
+            ValueError: If the role is unknown or the name is empty
+        """
+        if role not in ROLES:
+            raise ValueError(f"Unknown role: {role}")
+        if not name or not name.strip():
+            raise ValueError("Token name is required")
+        token = secrets.token_urlsafe(32)
+        token_id = await self._db.create_api_token(
+            name.strip(),
+            hash_token(token),
+            token[:TOKEN_PREFIX_LENGTH],
+            role,
+            username=username,
+            expires_at=expires_at,
+        )
+        return {"id": token_id, "name": name.strip(), "role": role, "token": token, "expires_at": expires_at}
+
+    async def authenticate(self, token: str | None) -> dict | None:
+        """Resolve a presented token to its principal, or None if invalid."""
+        if not token:
+            return None
+        record = await self._db.get_api_token_by_hash(hash_token(token))
+        if not record:
+            return None
+        await self._db.touch_api_token(record["id"])
+        return {
+            "token_id": record["id"],
+            "name": record["username"] or record["name"],
+            "role": record["role"],
+            "username": record["username"],
+        }
+
+    async def login(self, username: str, password: str) -> dict | None:
+        """Verify a local user's password and issue a session token."""
+        user = await self._db.get_auth_user(username)
+        if not user or not verify_password(password, user["password_hash"]):
+            return None
+        hours = float(await self._settings.get("auth_session_hours", 24))
+        return await self.create_token(
+            f"login:{username}",
+            user["role"],
+            username=username,
+            expires_at=int(time.time() + hours * 3600),
+        )
+
+    async def set_user(self, username: str, password: str, role: str) -> None:
+        """Create or update a local user.
+
+        Raises:
+            ValueError: If the role is unknown or username/password are empty
+        """
+        if role not in ROLES:
+            raise ValueError(f"Unknown role: {role}")
+        if not username or not username.strip() or not password:
+            raise ValueError("Username and password are required")
+        await self._db.upsert_auth_user(username.strip(), hash_password(password), role)
@@ -0,0 +1,228 @@
+"""Auto-investment policy for new deposits.
+
+When sync:cashflows stores new deposits (card top-ups) and auto_invest_mode is
+not off, the planner runs right away instead of waiting for the next batch. Its
+buy recommendations (swap legs excluded, since they wait for a sell) are taken
+in priority order until the deposit is used up; the last one is scaled down to
+the remaining budget when that still leaves at least auto_invest_min_order_eur.
+
+The budget is the deposit value, but never more than the cash on hand minus
+auto_invest_cash_buffer_eur. Only deposits dated within auto_invest_max_age_days
+count, so the first sync of an account's history does not invest it all. With
+auto_invest_deposits = recurring, only deposits close to the usual amount
+(within RECURRING_AMOUNT_TOLERANCE of the median of earlier deposits) trigger.
+
+- plan: the chosen buys are recorded and announced, trading:execute places
+  orders as usual
+- execute: in live trading mode, the buys whose market is open are placed right
+  away through the trading:execute order path, unless manual approval is on or
+  the circuit breaker is tripped
+
+Every handled batch is stored in auto_invest_runs.
+"""
+
+from __future__ import annotations
+
+import logging
+from dataclasses import replace
+from datetime import date, datetime, timedelta
+from statistics import median
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.planner.models import TradeRecommendation
+from sentinel.portfolio import Portfolio
+from sentinel.services.notifications import record_notification
+from sentinel.services.scheduled_orders import PlaceOrder, market_id, timing_allows
+from sentinel.settings import Settings
+from sentinel.utils.quantity import floor_to_lot
+
+logger = logging.getLogger(__name__)
+
+AUTO_INVEST_MODES = ("off", "plan", "execute")
+DEPOSIT_SCOPES = ("all", "recurring")
+DEPOSIT_TYPE = "card"
+RECURRING_AMOUNT_TOLERANCE = 0.25
+# Earlier deposits looked at, and needed, to tell the usual deposit amount
+RECURRING_LOOKBACK_DAYS = 365
+MIN_RECURRING_DEPOSITS = 2
+
+
+def is_recurring(amount: float, earlier: list[float]) -> bool:
+    """Whether a deposit matches the usual amount of the earlier ones."""
+    if len(earlier) < MIN_RECURRING_DEPOSITS:
+        return False
+    usual = median(earlier)
+    return usual > 0 and abs(amount - usual) <= RECURRING_AMOUNT_TOLERANCE * usual
+
+
+def fit_buys(recommendations: list[TradeRecommendation], budget: float, min_order: float) -> list[TradeRecommendation]:
+    """Buys in priority order that fit the budget, scaling the last one down to what is left."""
+    chosen = []
+    remaining = budget
+    for rec in sorted(recommendations, key=lambda r: -r.priority):
+        if rec.action != "buy" or rec.swap_group or rec.price <= 0:
+            continue
+        value = rec.value_delta_eur
+        if value > remaining:
+            quantity = floor_to_lot(rec.quantity * remaining / value, rec.lot_size) if value > 0 else 0
+            value = value * quantity / rec.quantity if rec.quantity else 0
+            rec = replace(rec, quantity=quantity, value_delta_eur=value)
+        if rec.quantity <= 0 or value < min_order:
+            continue
+        chosen.append(rec)
+        remaining -= value
+    return chosen
+
+
+class AutoInvestService:
+    """Applies the auto-invest policy to newly synced deposits."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        portfolio: Portfolio | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            portfolio: Portfolio instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+        self._portfolio = portfolio or Portfolio(db=self._db, currency=self._currency)
+
+    async def mode(self) -> str:
+        return await self._settings.get("auto_invest_mode", "off")
+
+    async def eligible(self, deposits: list[dict], today: date | None = None) -> list[dict]:
+        """New deposits the policy applies to (recent enough, and recurring if so configured)."""
+        today = today or date.today()
+        max_age = int(await self._settings.get("auto_invest_max_age_days", 3))
+        scope = await self._settings.get("auto_invest_deposits", "all")
+        ids = {d["id"] for d in deposits}
+        recent = [
+            d
+            for d in deposits
+            if d["type_id"] == DEPOSIT_TYPE
+            and d["amount"] > 0
+            and (today - date.fromisoformat(d["date"][:10])).days <= max_age
+        ]
+        if scope != "recurring":
+            return recent
+
+        since = (today - timedelta(days=RECURRING_LOOKBACK_DAYS)).isoformat()
+        history = [
+            cf
+            for cf in await self._db.get_cash_flows(type_id=DEPOSIT_TYPE, start_date=since)
+            if cf["id"] not in ids and cf["amount"] > 0
+        ]
+        earlier = [await self._value(cf) for cf in history]
+        return [d for d in recent if is_recurring(await self._value(d), earlier)]
+
+    async def _value(self, flow: dict) -> float:
+        return await self._currency.to_eur_for_date(flow["amount"], flow["currency"], flow["date"][:10])
+
+    async def handle(
+        self, deposits: list[dict], planner, broker, place: PlaceOrder, today: date | None = None
+    ) -> dict | None:
+        """Run the policy for newly stored deposits.
+
+        Args:
+            deposits: New cash_flows rows
+            planner: Planner producing the recommendations
+            broker: Broker for market status (execute mode)
+            place: Places one buy (the trading:execute order path)
+            today: Current date (default: today)
+
+        Returns:
+            The stored run, or None when the policy is off or no deposit qualifies
+        """
+        if await self.mode() == "off":
+            return None
+        eligible = await self.eligible(deposits, today)
+        if not eligible:
+            return None
+
+        deposit_eur = sum([await self._value(d) for d in eligible])
+        buffer = float(await self._settings.get("auto_invest_cash_buffer_eur", 0) or 0)
+        min_order = float(await self._settings.get("auto_invest_min_order_eur", 100) or 0)
+        cash = await self._portfolio.total_cash_eur()
+        budget = max(0.0, min(deposit_eur, cash - buffer))
+        ids = [d["id"] for d in eligible]
+
+        if budget < min_order:
+            reason = f"budget {budget:.2f} EUR (cash {cash:.2f}, buffer {buffer:.2f}) is below the minimum order"
+            return await self._save(ids, deposit_eur, budget, "skipped", [], reason)
+
+        recommendations = await planner.get_recommendations(min_trade_value=min_order)
+        chosen = fit_buys(recommendations, budget, min_order)
+        orders = [
+            {"symbol": r.symbol, "quantity": r.quantity, "value_eur": round(r.value_delta_eur, 2), "status": "planned"}
+            for r in chosen
+        ]
+        status, reason = await self._execute(chosen, orders, broker, place)
+        run = await self._save(ids, deposit_eur, budget, status, orders, reason)
+        await record_notification(
+            self._db,
+            "info",
+            "trade",
+            f"Deposit of {deposit_eur:.2f} EUR: {len(chosen)} buys for {sum(o['value_eur'] for o in orders):.2f} EUR",
+            message=reason or ", ".join(f"{o['symbol']} ({o['status']})" for o in orders) or "No buys fit the deposit",
+        )
+        return run
+
+    async def _execute(self, chosen, orders, broker, place: PlaceOrder) -> tuple[str, str | None]:
+        if not chosen:
+            return "planned", "no buy recommendation fits the deposit"
+        if await self.mode() != "execute":
+            return "planned", None
+        if await self._settings.get("trading_mode", "research") != "live":
+            return "planned", "not in live trading mode"
+        if await self._settings.get("trading_manual_approval", False):
+            return "planned", "manual approval is on: buys wait in the approval inbox"
+
+        from sentinel.services.circuit_breaker import CircuitBreakerService
+
+        breaker = CircuitBreakerService(db=self._db, settings=self._settings, currency=self._currency)
+        if await breaker.is_tripped():
+            return "blocked", "circuit breaker is tripped"
+
+        status = await broker.get_market_status("*") or {}
+        markets = {str(m.get("i")): m for m in status.get("m", [])}
+        now = datetime.now().astimezone()
+        for rec, order in zip(chosen, orders, strict=True):
+            security = await self._db.get_security(rec.symbol) or {}
+            if not timing_allows(markets.get(market_id(security) or ""), "any", now, 0):
+                order["status"] = "market closed"
+                continue
+            if await breaker.is_tripped():
+                order["status"] = "blocked"
+                continue
+            order["status"] = "placed" if await place(rec, "auto_invest") else "failed"
+        return "executed", None
+
+    async def _save(self, ids, deposit_eur, budget, status, orders, reason) -> dict:
+        logger.info(f"Auto-invest of deposits {ids}: {status} ({len(orders)} buys){f' - {reason}' if reason else ''}")
+        run_id = await self._db.save_auto_invest_run(
+            ids, round(deposit_eur, 2), round(budget, 2), status, orders, reason
+        )
+        return {
+            "id": run_id,
+            "deposit_ids": ids,
+            "deposit_eur": round(deposit_eur, 2),
+            "budget_eur": round(budget, 2),
+            "status": status,
+            "orders": orders,
+            "reason": reason,
+        }
+
+    async def runs(self, limit: int = 20) -> list[dict]:
+        """Handled deposit batches, most recent first."""
+        return await self._db.get_auto_invest_runs(limit)
@@ -0,0 +1,197 @@
+"""Broker documents and corporate action elections.
+
+Order files (getCpsFiles) and broker report documents (PDF by default) are
+downloaded through the broker, stored under DOCUMENTS_DIR and recorded in the
+broker_documents table. Each document is linked to the ledger entries it
+concerns: an order file to the trades of its order, a broker report to the
+trades, cash flows and dividends dated within its period.
+
+Elections for voluntary corporate actions are submitted through the broker and
+recorded whether or not the broker accepted them.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import logging
+import re
+from datetime import datetime
+from pathlib import Path
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.paths import DOCUMENTS_DIR
+
+logger = logging.getLogger(__name__)
+
+REPORT_FORMATS = {
+    "pdf": "application/pdf",
+    "html": "text/html",
+    "xml": "application/xml",
+    "xls": "application/vnd.ms-excel",
+}
+
+_UNSAFE_CHARS = re.compile(r"[^A-Za-z0-9._-]+")
+
+
+def safe_file_name(name: str) -> str:
+    """Broker-supplied file name reduced to a safe basename."""
+    base = _UNSAFE_CHARS.sub("_", Path(name).name).strip("._")
+    return base or "document"
+
+
+def _parse_date(value: str) -> str:
+    try:
+        return datetime.strptime(value, "%Y-%m-%d").strftime("%Y-%m-%d")
+    except (TypeError, ValueError) as e:
+        raise ValueError(f"Invalid date '{value}' (expected YYYY-MM-DD)") from e
+
+
+class BrokerDocumentService:
+    """Retrieves, stores and lists broker documents; submits corporate action elections."""
+
+    def __init__(self, db: Database | None = None, broker: Broker | None = None, root: Path | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+            root: Documents directory (uses DOCUMENTS_DIR if None)
+        """
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._root = Path(root) if root is not None else DOCUMENTS_DIR
+
+    def path_of(self, document: dict) -> Path:
+        """Absolute path of a stored document."""
+        return self._root / document["path"]
+
+    async def _store(self, relative: str, content: bytes, **record) -> dict:
+        target = self._root / relative
+        target.parent.mkdir(parents=True, exist_ok=True)
+        target.write_bytes(content)
+        document_id = await self._db.save_broker_document(
+            path=relative, size=len(content), sha256=hashlib.sha256(content).hexdigest(), **record
+        )
+        return {"id": document_id, **record, "path": relative, "size": len(content)}
+
+    async def fetch_order_files(self, order_id: str) -> list[dict]:
+        """
+        Download the files of an order, store them and link them to the order's trades.
+
+        Raises:
+            ValueError: order_id is not numeric
+            LookupError: The broker returned no files
+        """
+        order_id = str(order_id).strip()
+        if not order_id.isdigit():
+            raise ValueError(f"Invalid order id '{order_id}'")
+        files = await self._broker.get_order_files(order_id)
+        if not files:
+            raise LookupError(f"No files for order {order_id}")
+        links = await self._db.get_ledger_entries(order_id=order_id)
+        stored = []
+        for f in files:
+            name = safe_file_name(f["file_name"])
+            document = await self._store(
+                f"orders/{order_id}/{name}",
+                f["content"],
+                kind="order_file",
+                file_name=name,
+                mime=f.get("mime"),
+                order_id=order_id,
+            )
+            await self._db.link_broker_document(document["id"], links)
+            stored.append({**document, "links": len(links)})
+        logger.info(f"Stored {len(stored)} files for order {order_id} ({len(links)} ledger links)")
+        return stored
+
+    async def fetch_broker_report(self, start_date: str, end_date: str, fmt: str = "pdf") -> dict:
+        """
+        Download the broker report for a period, store it and link it to the period's ledger entries.
+
+        Raises:
+            ValueError: Invalid dates or format
+            LookupError: The broker returned no document
+        """
+        start, end = _parse_date(start_date), _parse_date(end_date)
+        if start > end:
+            raise ValueError("start_date must not be after end_date")
+        if fmt not in REPORT_FORMATS:
+            raise ValueError(f"Format must be one of: {', '.join(REPORT_FORMATS)}")
+        content = await self._broker.get_broker_report_file(start, end, fmt)
+        if not content:
+            raise LookupError(f"Broker returned no {fmt} report for {start}..{end}")
+        name = f"broker_report_{start}_{end}.{fmt}"
+        document = await self._store(
+            f"reports/{name}",
+            content,
+            kind="broker_report",
+            file_name=name,
+            mime=REPORT_FORMATS[fmt],
+            period_start=start,
+            period_end=end,
+        )
+        links = await self._db.get_ledger_entries(start_date=start, end_date=end)
+        await self._db.link_broker_document(document["id"], links)
+        logger.info(f"Stored broker report {start}..{end} ({len(links)} ledger links)")
+        return {**document, "links": len(links)}
+
+    async def list_documents(
+        self, kind: str | None = None, entity_type: str | None = None, entity_id: str | None = None
+    ) -> list[dict]:
+        """Stored documents, optionally of a kind or linked to a ledger entry."""
+        return await self._db.get_broker_documents(kind=kind, entity_type=entity_type, entity_id=entity_id)
+
+    async def get_document(self, document_id: int) -> dict:
+        """
+        One document with its ledger links.
+
+        Raises:
+            LookupError: Unknown document or its file is gone
+        """
+        document = await self._db.get_broker_document(document_id)
+        if not document:
+            raise LookupError(f"Document {document_id} not found")
+        if not self.path_of(document).is_file():
+            raise LookupError(f"File of document {document_id} is missing")
+        return document
+
+    async def submit_election(
+        self, corporate_action_id: str, option: str, quantity: float | None = None, symbol: str | None = None
+    ) -> dict:
+        """
+        Submit an election for a voluntary corporate action and record it.
+
+        Raises:
+            ValueError: Missing option or non-positive quantity
+        """
+        option = (option or "").strip()
+        if not corporate_action_id or not option:
+            raise ValueError("corporate_action_id and option are required")
+        if quantity is not None and quantity <= 0:
+            raise ValueError("quantity must be positive")
+        request_id = await self._broker.submit_corporate_action_election(corporate_action_id, option, quantity)
+        status = "submitted" if request_id else "failed"
+        election_id = await self._db.add_corporate_action_election(
+            corporate_action_id,
+            option,
+            status,
+            symbol=symbol,
+            quantity=quantity,
+            broker_request_id=request_id,
+        )
+        if not request_id:
+            logger.warning(f"Election '{option}' for corporate action {corporate_action_id} was not accepted")
+        return {
+            "id": election_id,
+            "corporate_action_id": corporate_action_id,
+            "option": option,
+            "quantity": quantity,
+            "status": status,
+            "broker_request_id": request_id,
+        }
+
+    async def list_elections(self, corporate_action_id: str | None = None) -> list[dict]:
+        """Recorded elections, most recent first."""
+        return await self._db.get_corporate_action_elections(corporate_action_id)
@@ -0,0 +1,106 @@
+"""Downsampled history series for charts.
+
+Series are reduced server-side to at most `points` equal time buckets, so
+small clients (the TUI over a slow link) get a few hundred values instead of
+years of daily rows. Each point is the last observation in its bucket:
+
+- nav: total portfolio value (positions + cash, EUR) from daily snapshots
+- price: validated daily close in the security's currency
+- score: contrarian opportunity score (0..1) as of each bucket's last day
+"""
+
+from __future__ import annotations
+
+from datetime import date, datetime, timezone
+
+from sentinel.database import Database
+from sentinel.price_validator import PriceValidator
+from sentinel.strategy.contrarian import compute_contrarian_signal
+
+MAX_POINTS = 500
+MAX_DAYS = 3650
+# Closes behind each scored day (252-day drawdown plus the 42-day recent-low window)
+SCORE_LOOKBACK_DAYS = 252 + 42
+
+
+def bucket_series(series: list[tuple[str, float]], points: int) -> list[dict]:
+    """Reduce a date-ordered (oldest first) series to at most `points` time buckets."""
+    if not series:
+        return []
+    first = date.fromisoformat(series[0][0]).toordinal()
+    span = date.fromisoformat(series[-1][0]).toordinal() - first + 1
+    size = max(1.0, span / points)
+    buckets: dict[int, tuple[str, float]] = {}
+    for day, value in series:
+        buckets[int((date.fromisoformat(day).toordinal() - first) / size)] = (day, value)
+    return [{"date": day, "value": round(value, 6)} for _, (day, value) in sorted(buckets.items())]
+
+
+def _check_range(days: int, points: int) -> None:
+    if not 1 <= days <= MAX_DAYS:
+        raise ValueError(f"days must be between 1 and {MAX_DAYS}")
+    if not 2 <= points <= MAX_POINTS:
+        raise ValueError(f"points must be between 2 and {MAX_POINTS}")
+
+
+class ChartService:
+    """Builds time-bucketed chart series from stored history."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def portfolio_nav(self, days: int = 365, points: int = 120) -> list[dict]:
+        """Portfolio value in EUR over the last `days`.
+
+        Raises:
+            ValueError: days or points out of range
+        """
+        _check_range(days, points)
+        series = []
+        for snap in await self._db.get_portfolio_snapshots(days):
+            data = snap["data"]
+            positions = data.get("positions", {})
+            total = sum(p.get("value_eur", 0) for p in positions.values()) + (data.get("cash_eur", 0.0) or 0.0)
+            series.append((datetime.fromtimestamp(snap["date"], tz=timezone.utc).strftime("%Y-%m-%d"), total))
+        return bucket_series(series, points)
+
+    async def _closes(self, symbol: str, days: int) -> list[tuple[str, float]]:
+        if await self._db.get_security(symbol) is None:
+            raise LookupError(f"Security not found: {symbol}")
+        rows = PriceValidator().validate_price_series_desc(await self._db.get_prices(symbol, days=days))
+        return [(p["date"][:10], float(p["close"])) for p in reversed(rows) if p.get("close") is not None]
+
+    async def price(self, symbol: str, days: int = 365, points: int = 120) -> list[dict]:
+        """Daily closes over the last `days`.
+
+        Raises:
+            ValueError: days or points out of range
+            LookupError: Unknown security
+        """
+        _check_range(days, points)
+        return bucket_series(await self._closes(symbol, days), points)
+
+    async def score(self, symbol: str, days: int = 365, points: int = 120) -> list[dict]:
+        """Contrarian opportunity score over the last `days`, evaluated once per bucket.
+
+        Raises:
+            ValueError: days or points out of range
+            LookupError: Unknown security
+        """
+        _check_range(days, points)
+        closes = await self._closes(symbol, days + SCORE_LOOKBACK_DAYS)
+        start = max(0, len(closes) - days)
+        # Bucket first, then score only the days that are kept
+        kept = {p["date"] for p in bucket_series(closes[start:], points)}
+        series = []
+        for i in range(start, len(closes)):
+            day = closes[i][0]
+            if day in kept:
+                window = [c for _, c in closes[max(0, i + 1 - SCORE_LOOKBACK_DAYS) : i + 1]]
+                series.append((day, float(compute_contrarian_signal(window)["opp_score"])))
+        return [{"date": day, "value": round(value, 6)} for day, value in series]
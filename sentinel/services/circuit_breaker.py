@@ -0,0 +1,177 @@
+"""Drawdown guard / circuit breaker for automated trading.
+
+The breaker trips when the portfolio falls more than circuit_breaker_max_drawdown_pct
+below its high-water mark (the highest daily snapshot value, or the current
+value if higher), or when circuit_breaker_max_rejections orders in a row are
+rejected. A tripped breaker pauses the trading and planning jobs (BREAKER_JOBS),
+keeps the risk LED lit and sends a risk_limit_breached notification. It stays
+tripped until resumed through the API; resuming re-arms the drawdown guard from
+the current value so the same drawdown does not trip it again immediately.
+
+State lives in the settings table under STATE_KEY, like the cached exchange rates.
+"""
+
+from __future__ import annotations
+
+import logging
+from datetime import datetime, timezone
+
+from sentinel.audit import record_action
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.services.notifications import record_notification
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+STATE_KEY = "circuit_breaker_state"
+
+# Jobs that place orders or regenerate the plan orders come from
+BREAKER_JOBS = (
+    "trading:execute",
+    "trading:check_markets",
+    "trading:rebalance",
+    "trading:scheduled_orders",
+    "planning:refresh",
+)
+
+
+def drawdown_from_peak(values: list[float]) -> tuple[float, float]:
+    """(high-water mark, drawdown of the last value below it as a 0-1 fraction)."""
+    if not values:
+        return 0.0, 0.0
+    peak = max(values)
+    if peak <= 0:
+        return peak, 0.0
+    return peak, max(0.0, (peak - values[-1]) / peak)
+
+
+class CircuitBreakerService:
+    """Trips, reports and resumes the automated trading kill switch."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+
+    async def _state(self) -> dict:
+        state = await self._db.get_setting(STATE_KEY)
+        return dict(state) if isinstance(state, dict) else {}
+
+    async def _save(self, state: dict) -> None:
+        await self._db.set_setting(STATE_KEY, state)
+
+    async def is_tripped(self) -> bool:
+        """Whether automated trading is paused."""
+        return bool((await self._state()).get("tripped_at"))
+
+    async def current_value_eur(self) -> float:
+        """Positions at their current price plus cash, in EUR."""
+        total = 0.0
+        for pos in await self._db.get_all_positions():
+            local = float(pos.get("current_price") or 0) * float(pos.get("quantity") or 0)
+            total += await self._currency.to_eur(local, pos.get("currency") or "EUR")
+        for ccy, amount in (await self._db.get_cash_balances()).items():
+            total += await self._currency.to_eur(float(amount), ccy)
+        return total
+
+    async def drawdown(self) -> dict:
+        """High-water mark, current value and drawdown since the breaker was last armed."""
+        armed_at = int((await self._state()).get("armed_at") or 0)
+        values = []
+        for snapshot in await self._db.get_portfolio_snapshots():
+            if snapshot["date"] < armed_at:
+                continue
+            data = snapshot["data"]
+            positions = sum(float(p.get("value_eur") or 0) for p in data.get("positions", {}).values())
+            values.append(positions + float(data.get("cash_eur") or 0))
+        current = await self.current_value_eur()
+        peak, drawdown = drawdown_from_peak(values + [current])
+        return {"high_water_mark_eur": round(peak, 2), "value_eur": round(current, 2), "drawdown_pct": drawdown * 100}
+
+    async def check(self) -> bool:
+        """Trip on excessive drawdown. Returns whether the breaker is tripped."""
+        if await self.is_tripped():
+            return True
+        limit = float(await self._settings.get("circuit_breaker_max_drawdown_pct", 20.0) or 0)
+        if limit <= 0:
+            return False
+        dd = await self.drawdown()
+        # No positions or cash yet (e.g. before the first sync) is missing data, not a drawdown
+        if dd["value_eur"] <= 0 or dd["drawdown_pct"] < limit:
+            return False
+        await self.trip(
+            f"Drawdown {dd['drawdown_pct']:.1f}% from high-water mark EUR {dd['high_water_mark_eur']:,.0f} "
+            f"exceeds {limit:.0f}%"
+        )
+        return True
+
+    async def record_order_result(self, accepted: bool) -> None:
+        """Count consecutive rejected orders and trip once the limit is reached."""
+        state = await self._state()
+        rejections = 0 if accepted else int(state.get("consecutive_rejections") or 0) + 1
+        if rejections == int(state.get("consecutive_rejections") or 0):
+            return
+        state["consecutive_rejections"] = rejections
+        await self._save(state)
+        limit = int(await self._settings.get("circuit_breaker_max_rejections", 5) or 0)
+        if limit > 0 and rejections >= limit and not state.get("tripped_at"):
+            await self.trip(f"{rejections} consecutive orders rejected")
+
+    async def trip(self, reason: str) -> None:
+        """Pause automated trading and notify."""
+        state = await self._state()
+        state.update({"tripped_at": int(datetime.now(timezone.utc).timestamp()), "reason": reason})
+        await self._save(state)
+        logger.error(f"Circuit breaker tripped, pausing {', '.join(BREAKER_JOBS)}: {reason}")
+        await record_notification(
+            self._db,
+            "error",
+            "risk",
+            "Automated trading paused by circuit breaker",
+            event="risk_limit_breached",
+            message=f"{reason}. Resume via POST /api/risk/circuit-breaker/resume.",
+            dedupe_key="circuit_breaker",
+        )
+
+    async def resume(self) -> dict:
+        """
+        Clear the breaker and re-arm the drawdown guard from now.
+
+        Raises:
+            ValueError: Breaker is not tripped
+        """
+        state = await self._state()
+        if not state.get("tripped_at"):
+            raise ValueError("Circuit breaker is not tripped")
+        logger.warning(f"Circuit breaker resumed (was: {state.get('reason')})")
+        await self._save({"armed_at": int(datetime.now(timezone.utc).timestamp()), "consecutive_rejections": 0})
+        await record_action(self._db, "circuit_breaker.resume", params={"reason": state.get("reason")})
+        return await self.status()
+
+    async def status(self) -> dict:
+        """Breaker state, limits and the current drawdown."""
+        state = await self._state()
+        return {
+            "tripped": bool(state.get("tripped_at")),
+            "tripped_at": state.get("tripped_at"),
+            "reason": state.get("reason"),
+            "armed_at": state.get("armed_at"),
+            "consecutive_rejections": int(state.get("consecutive_rejections") or 0),
+            "max_rejections": int(await self._settings.get("circuit_breaker_max_rejections", 5) or 0),
+            "max_drawdown_pct": float(await self._settings.get("circuit_breaker_max_drawdown_pct", 20.0) or 0),
+            "paused_jobs": list(BREAKER_JOBS) if state.get("tripped_at") else [],
+            **await self.drawdown(),
+        }
@@ -0,0 +1,158 @@
+"""User-defined computed columns.
+
+A computed column is a named expression over existing metrics, e.g.
+`yield_plus_cagr = dividendYield + cagr_raw`. Definitions are stored in the
+computed_columns table and evaluated per security by the unified screener, its
+CSV export and the digest report, so simple derived metrics need no code
+change. The metrics an expression can use are the numeric fields of the row
+being evaluated, plus the numeric top-level fields of the security's broker
+metadata (`securities.data`) for names the row does not have.
+"""
+
+from __future__ import annotations
+
+import csv
+import io
+import json
+import logging
+import math
+from typing import Any
+
+from sentinel.database import Database
+from sentinel.utils.expressions import CompiledExpression, compile_expression, validate_column_name
+
+logger = logging.getLogger(__name__)
+
+
+def _numeric(value: Any) -> float | None:
+    if isinstance(value, bool) or value is None:
+        return None
+    if isinstance(value, (int, float)):
+        return value if math.isfinite(value) else None
+    if isinstance(value, str):
+        try:
+            number = float(value)
+        except ValueError:
+            return None
+        return number if math.isfinite(number) else None
+    return None
+
+
+def row_metrics(row: dict, security: dict | None = None) -> dict[str, float]:
+    """Metric name -> value available to expressions for one row."""
+    metrics: dict[str, float] = {}
+    raw = (security or {}).get("data")
+    if raw:
+        try:
+            data = json.loads(raw) if isinstance(raw, str) else raw
+        except ValueError:
+            data = {}
+        if isinstance(data, dict):
+            for key, value in data.items():
+                number = _numeric(value)
+                if number is not None:
+                    metrics[key] = number
+    for key, value in row.items():
+        if isinstance(value, (int, float)) and not isinstance(value, bool):
+            metrics[key] = value
+    return metrics
+
+
+def evaluate_columns(columns: list[tuple[str, CompiledExpression]], metrics: dict[str, Any]) -> dict:
+    """Column name -> value (None where a metric is missing) for one row."""
+    return {name: expr.evaluate(metrics) for name, expr in columns}
+
+
+def rows_to_csv(rows: list[dict]) -> str:
+    """
+    CSV export of screener rows: scalar fields, the recommendation and the computed columns.
+
+    Nested fields (price history, skipped checks) are left out; tags are joined
+    with commas and each computed column becomes its own column.
+    """
+    if not rows:
+        return ""
+    scalar = (str, int, float, bool, type(None))
+    fields = [key for key, value in rows[0].items() if isinstance(value, scalar)]
+    fields += ["tags", "recommendation_action", "recommendation_quantity", "recommendation_value_delta_eur"]
+    computed = list((rows[0].get("computed") or {}).keys())
+    out = io.StringIO()
+    writer = csv.writer(out)
+    writer.writerow(fields + computed)
+    for row in rows:
+        recommendation = row.get("recommendation") or {}
+        values = {
+            **row,
+            "tags": ",".join(row.get("tags") or []),
+            "recommendation_action": recommendation.get("action"),
+            "recommendation_quantity": recommendation.get("quantity"),
+            "recommendation_value_delta_eur": recommendation.get("value_delta_eur"),
+        }
+        computed_values = row.get("computed") or {}
+        writer.writerow([values.get(f) for f in fields] + [computed_values.get(c) for c in computed])
+    return out.getvalue()
+
+
+class ComputedColumnService:
+    """Stores computed column definitions and evaluates them over rows."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def list_columns(self) -> list[dict]:
+        """All definitions, with the metric names each one references."""
+        columns = []
+        for row in await self._db.get_computed_columns():
+            try:
+                names = sorted(compile_expression(row["expression"]).names)
+                error = None
+            except ValueError as e:
+                names, error = [], str(e)
+            columns.append({**row, "metrics": names, "error": error})
+        return columns
+
+    async def define(self, name: str, expression: str, description: str | None = None) -> dict:
+        """
+        Create or replace a computed column.
+
+        Raises:
+            ValueError: Invalid name or expression
+        """
+        validate_column_name(name)
+        compiled = compile_expression(expression)
+        await self._db.upsert_computed_column(name, compiled.source, description)
+        return {"name": name, "expression": compiled.source, "description": description}
+
+    async def delete(self, name: str) -> bool:
+        """Remove a computed column. Returns False if it does not exist."""
+        return await self._db.delete_computed_column(name)
+
+    async def compiled(self) -> list[tuple[str, CompiledExpression]]:
+        """(name, expression) of every stored column that still compiles."""
+        columns = []
+        for row in await self._db.get_computed_columns():
+            try:
+                columns.append((row["name"], compile_expression(row["expression"])))
+            except ValueError as e:
+                logger.warning(f"Skipping computed column {row['name']}: {e}")
+        return columns
+
+    async def apply(self, rows: list[dict], securities: dict[str, dict] | None = None) -> list[dict]:
+        """
+        Add a `computed` dict (column name -> value) to each row.
+
+        Args:
+            rows: Rows with a `symbol` key (screener rows, report movers, ...)
+            securities: symbol -> security row, for metrics from broker metadata
+        """
+        columns = await self.compiled()
+        securities = securities or {}
+        for row in rows:
+            metrics = row_metrics(row, securities.get(row.get("symbol")))
+            row["computed"] = evaluate_columns(columns, metrics)
+        return rows
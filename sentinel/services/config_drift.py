@@ -0,0 +1,221 @@
+"""Configuration drift detection between paired devices.
+
+Each device exposes its configuration bundle (effective settings and job
+schedules) at GET /api/config/bundle together with a sha256 of its canonical
+JSON. The config:drift_check job fetches the peer's bundle from config_peer_url,
+compares the hashes and raises a config_drift notification when they differ.
+GET /api/config/drift/diff lists exactly which settings and schedules differ.
+
+Credentials, internal state kept in the settings table and settings that are
+expected to differ per device (DEVICE_LOCAL_SETTINGS, DEVICE_LOCAL_PREFIXES and
+config_drift_ignore) are left out of the bundle.
+"""
+
+from __future__ import annotations
+
+import logging
+from datetime import datetime, timezone
+from typing import Awaitable, Callable
+
+from sentinel.correlation import outbound_headers
+from sentinel.database import Database
+from sentinel.planner.state_hash import fingerprint
+from sentinel.services.circuit_breaker import STATE_KEY as CIRCUIT_BREAKER_STATE_KEY
+from sentinel.services.notifications import record_notification
+from sentinel.settings import Settings
+from sentinel.vault import SECRET_NAMES, Vault
+
+logger = logging.getLogger(__name__)
+
+STATE_KEY = "config_drift_state"
+BUNDLE_PATH = "/api/config/bundle"
+REQUEST_TIMEOUT = 10.0
+
+# Internal state and per-device settings that never count as drift
+DEVICE_LOCAL_SETTINGS = frozenset(
+    {
+        STATE_KEY,
+        CIRCUIT_BREAKER_STATE_KEY,
+        "exchange_rates",
+        "trading_mode",  # The standby runs in research mode
+        "config_peer_url",
+        "config_drift_ignore",
+        "price_cold_storage_path",
+    }
+)
+# Attached hardware (LED matrix, status screen) differs per device
+DEVICE_LOCAL_PREFIXES = ("led_", "display_screen_")
+
+# Job schedule columns that are configuration (run state is per device)
+_SCHEDULE_FIELDS = ("interval_minutes", "interval_market_open_minutes", "market_timing")
+
+# Fetches a peer's bundle: (url, token) -> bundle dict
+Fetcher = Callable[[str, str], Awaitable[dict]]
+
+
+async def http_get_json(url: str, token: str) -> dict:
+    """Default fetcher (httpx)."""
+    import httpx
+
+    headers = {"Authorization": f"Bearer {token}"} if token else {}
+    headers.update(outbound_headers())
+    async with httpx.AsyncClient(timeout=REQUEST_TIMEOUT) as client:
+        response = await client.get(url, headers=headers)
+        response.raise_for_status()
+        return response.json()
+
+
+def bundle_hash(bundle: dict) -> str:
+    """Hash of a bundle's settings and schedules (ignores its hash and metadata fields)."""
+    return fingerprint({"settings": bundle.get("settings", {}), "job_schedules": bundle.get("job_schedules", {})})
+
+
+def diff_bundles(local: dict, remote: dict) -> list[dict]:
+    """Entries that differ between two bundles, sorted by section and key.
+
+    Each entry has section ('settings' or 'job_schedules'), key and the local and
+    remote values; a value of None with missing_locally/missing_remotely set means
+    the key only exists on one side.
+    """
+    differences = []
+    for section in ("settings", "job_schedules"):
+        ours = local.get(section) or {}
+        theirs = remote.get(section) or {}
+        for key in sorted(set(ours) | set(theirs)):
+            if key in ours and key in theirs and fingerprint(ours[key]) == fingerprint(theirs[key]):
+                continue
+            differences.append(
+                {
+                    "section": section,
+                    "key": key,
+                    "local": ours.get(key),
+                    "remote": theirs.get(key),
+                    "missing_locally": key not in ours,
+                    "missing_remotely": key not in theirs,
+                }
+            )
+    return differences
+
+
+class ConfigDriftService:
+    """Builds this device's configuration bundle and compares it with the peer's."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        fetcher: Fetcher | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            fetcher: Peer bundle fetch function (uses httpx if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._fetch = fetcher or http_get_json
+
+    async def _comparable(self, settings: dict) -> dict:
+        ignore = set(await self._settings.get("config_drift_ignore", []) or [])
+        excluded = set(SECRET_NAMES) | DEVICE_LOCAL_SETTINGS | ignore
+        return {k: v for k, v in settings.items() if k not in excluded and not k.startswith(DEVICE_LOCAL_PREFIXES)}
+
+    async def bundle(self) -> dict:
+        """This device's configuration bundle with its hash."""
+        settings = await self._comparable(await self._settings.all())
+        schedules = {
+            row["job_type"]: {field: row.get(field) for field in _SCHEDULE_FIELDS}
+            for row in await self._db.get_job_schedules()
+        }
+        bundle = {"settings": settings, "job_schedules": schedules}
+        bundle["hash"] = bundle_hash(bundle)
+        return bundle
+
+    async def _peer(self) -> tuple[str, str]:
+        url = (await self._settings.get("config_peer_url", "") or "").rstrip("/")
+        if not url:
+            raise ValueError("No peer configured (set config_peer_url)")
+        token = await Vault(db=self._db, settings=self._settings).get_secret("config_peer_token")
+        return url, token
+
+    async def _compare(self, url: str, token: str) -> dict:
+        remote = await self._fetch(url + BUNDLE_PATH, token)
+        if not isinstance(remote, dict) or not isinstance(remote.get("settings"), dict):
+            raise RuntimeError(f"Peer at {url} did not return a configuration bundle")
+        # Apply this device's exclusions too and recompute the hash, so both sides hash the same keys
+        remote["settings"] = await self._comparable(remote["settings"])
+        remote["hash"] = bundle_hash(remote)
+        local = await self.bundle()
+        differences = diff_bundles(local, remote)
+        return {
+            "peer": url,
+            "local_hash": local["hash"],
+            "remote_hash": remote["hash"],
+            "in_sync": not differences,
+            "differences": differences,
+        }
+
+    async def diff(self) -> dict:
+        """
+        Fetch the peer's bundle and list what differs.
+
+        Raises:
+            ValueError: No peer configured
+        """
+        url, token = await self._peer()
+        return await self._compare(url, token)
+
+    async def check(self) -> dict:
+        """
+        Compare with the peer, store the result and notify on divergence.
+
+        A peer that cannot be reached is recorded as an error, not as drift.
+        Returns the stored state.
+
+        Raises:
+            ValueError: No peer configured
+        """
+        url, token = await self._peer()
+        previous = await self.status()
+        checked_at = int(datetime.now(timezone.utc).timestamp())
+        try:
+            result = await self._compare(url, token)
+        except Exception as e:
+            logger.warning(f"Config drift check failed: {e}")
+            state = {**previous, "checked_at": checked_at, "error": str(e)}
+            await self._db.set_setting(STATE_KEY, state)
+            return state
+
+        keys = [d["key"] for d in result["differences"]]
+        state = {
+            "checked_at": checked_at,
+            "peer": result["peer"],
+            "in_sync": result["in_sync"],
+            "local_hash": result["local_hash"],
+            "remote_hash": result["remote_hash"],
+            "differing_keys": keys,
+            "error": None,
+        }
+        await self._db.set_setting(STATE_KEY, state)
+        if keys and keys != previous.get("differing_keys"):
+            shown = ", ".join(keys[:10]) + (f" and {len(keys) - 10} more" if len(keys) > 10 else "")
+            logger.warning(f"Configuration drift with {result['peer']}: {shown}")
+            await record_notification(
+                self._db,
+                "warning",
+                "system",
+                f"Configuration differs from peer ({len(keys)} setting{'s' if len(keys) != 1 else ''})",
+                event="config_drift",
+                message=f"{shown}. See GET /api/config/drift/diff.",
+                dedupe_key="config_drift",
+            )
+        elif not keys and previous.get("differing_keys"):
+            logger.info(f"Configuration back in sync with {result['peer']}")
+        return state
+
+    async def status(self) -> dict:
+        """Result of the last drift check (empty before the first one)."""
+        state = await self._db.get_setting(STATE_KEY)
+        return dict(state) if isinstance(state, dict) else {}
@@ -0,0 +1,102 @@
+"""Rolling correlation matrix across holdings and buy candidates.
+
+The matrix covers every held security plus the active securities the planner
+may buy, computed from daily returns over correlation_window_days and stored
+so the planner and the UI heatmap read it without touching price history.
+"""
+
+from __future__ import annotations
+
+from sentinel.database import Database
+from sentinel.planner.cash_equivalents import is_cash_equivalent
+from sentinel.planner.correlation import (
+    MIN_CORRELATION_OBSERVATIONS,
+    correlation_lookup,
+    daily_returns,
+    pairwise_correlations,
+    portfolio_diversification,
+)
+from sentinel.portfolio import Portfolio
+from sentinel.settings import Settings
+
+
+class CorrelationService:
+    """Computes, stores and serves pairwise return correlations."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        portfolio: Portfolio | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            portfolio: Portfolio instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._portfolio = portfolio or Portfolio()
+
+    async def universe(self) -> list[str]:
+        """Held securities plus active securities open for buying (cash equivalents excluded)."""
+        securities = await self._db.get_all_securities(active_only=False)
+        by_symbol = {s["symbol"]: s for s in securities}
+        held = {p["symbol"] for p in await self._db.get_all_positions() if (p.get("quantity") or 0) > 0}
+        candidates = {s["symbol"] for s in securities if s.get("active") and s.get("allow_buy", 1)}
+        return sorted(symbol for symbol in held | candidates if not is_cash_equivalent(by_symbol.get(symbol)))
+
+    async def compute(self, window_days: int | None = None) -> dict:
+        """Recompute the matrix from price history and replace the stored one.
+
+        Args:
+            window_days: Daily returns used per pair (defaults to correlation_window_days)
+
+        Returns:
+            dict with the number of symbols and of stored pairs, and the window used
+        """
+        window_days = int(window_days or await self._settings.get("correlation_window_days", 252))
+        if window_days < 2:
+            raise ValueError("correlation_window_days must be at least 2")
+        symbols = await self.universe()
+        prices = await self._db.get_prices_bulk(symbols, days=window_days + 1) if symbols else {}
+        returns = {symbol: daily_returns(prices.get(symbol, [])) for symbol in symbols}
+        pairs = pairwise_correlations(returns, min(MIN_CORRELATION_OBSERVATIONS, window_days))
+        await self._db.replace_correlations(pairs, window_days)
+        await self._db.cache_clear("planner:")
+        return {"symbols": len(symbols), "pairs": len(pairs), "window_days": window_days}
+
+    async def matrix(self, symbols: list[str] | None = None) -> dict:
+        """Stored matrix for the heatmap, with the portfolio's diversification score.
+
+        Args:
+            symbols: Restrict to these symbols (defaults to every symbol with a stored pair)
+
+        Returns:
+            dict with symbols, a square matrix (None where no pair is stored, 1.0 on
+            the diagonal), the held symbols, window_days, computed_at and diversification
+        """
+        pairs = await self._db.get_correlations(symbols)
+        lookup = correlation_lookup(pairs)
+        if symbols is None:
+            symbols = sorted({p["symbol_a"] for p in pairs} | {p["symbol_b"] for p in pairs})
+        weights = (await self._portfolio.get_allocations()).get("by_security", {})
+        return {
+            "symbols": symbols,
+            "matrix": [
+                [1.0 if a == b else (round(lookup[(a, b)], 4) if (a, b) in lookup else None) for b in symbols]
+                for a in symbols
+            ],
+            "held": sorted(s for s in symbols if weights.get(s, 0) > 0),
+            "window_days": pairs[0]["window_days"] if pairs else None,
+            "computed_at": max((p["computed_at"] for p in pairs), default=None),
+            "diversification": await self.diversification(),
+        }
+
+    async def diversification(self) -> dict:
+        """Diversification of the current holdings from the stored matrix."""
+        weights = (await self._portfolio.get_allocations()).get("by_security", {})
+        pairs = await self._db.get_correlations([s for s, w in weights.items() if w > 0])
+        return portfolio_diversification(weights, correlation_lookup(pairs))
@@ -0,0 +1,150 @@
+"""Currency exposure and hedging suggestions.
+
+Decomposes portfolio value by currency - either the listing (trading) currency
+of each security, or the currency of its underlying revenue, approximated from
+the security's geography - and tracks how that mix drifted over the portfolio
+snapshots. When per-currency limits are configured (currency_exposure_limits),
+currencies above their limit get a hedging suggestion ("reduce USD exposure"),
+and with currency_exposure_soft_constraints on the planner applies the same
+limits as a soft constraint (see sentinel.planner.currency_exposure).
+"""
+
+from __future__ import annotations
+
+from datetime import datetime, timezone
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.planner.currency_exposure import EXPOSURE_BASES, decompose, to_pct
+from sentinel.settings import Settings
+
+
+def hedging_suggestions(exposure_pct: dict[str, float], limits: dict[str, float], total_eur: float) -> list[dict]:
+    """
+    Suggestions to reduce currencies above their limit, largest excess first.
+
+    Args:
+        exposure_pct: Currency -> share of the portfolio (0-1)
+        limits: Currency -> maximum share (0-1)
+        total_eur: Portfolio value the shares refer to
+    """
+    suggestions = []
+    for ccy, limit in limits.items():
+        pct = exposure_pct.get(ccy, 0.0)
+        if pct <= float(limit):
+            continue
+        excess_eur = (pct - float(limit)) * total_eur
+        suggestions.append(
+            {
+                "currency": ccy,
+                "action": "reduce",
+                "exposure_pct": round(pct, 4),
+                "limit_pct": float(limit),
+                "excess_eur": round(excess_eur, 2),
+                "message": f"Reduce {ccy} exposure by EUR {excess_eur:,.0f} ({pct:.1%} vs {float(limit):.0%} limit)",
+            }
+        )
+    suggestions.sort(key=lambda s: s["excess_eur"], reverse=True)
+    return suggestions
+
+
+class CurrencyExposureService:
+    """Current and historical currency exposure of the portfolio."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        currency: Currency | None = None,
+        settings: Settings | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._currency = currency or Currency()
+        self._settings = settings or Settings()
+
+    async def limits(self) -> dict[str, float]:
+        """Configured currency -> maximum share of the portfolio (0-1)."""
+        return dict(await self._settings.get("currency_exposure_limits", {}) or {})
+
+    async def basis(self) -> str:
+        """Configured default exposure basis (listing or revenue)."""
+        return await self._settings.get("currency_exposure_basis", "listing") or "listing"
+
+    async def _securities(self) -> dict[str, dict]:
+        return {s["symbol"]: s for s in await self._db.get_all_securities(active_only=False)}
+
+    async def get_exposure(self, basis: str | None = None, days: int = 90) -> dict:
+        """
+        Current exposure, drift over the last `days` of snapshots and hedging suggestions.
+
+        Cash counts as exposure to its own currency, and so do external holdings
+        (unless external_holdings_in_allocations is off) in the currency they are
+        valued in. Snapshots only store cash in EUR, so historical points
+        attribute cash to EUR.
+
+        Args:
+            basis: listing or revenue (None = currency_exposure_basis setting)
+            days: Snapshot window for drift and history
+
+        Raises:
+            ValueError: Unknown basis
+        """
+        basis = basis or await self.basis()
+        if basis not in EXPOSURE_BASES:
+            raise ValueError(f"basis must be one of {', '.join(EXPOSURE_BASES)}")
+        securities = await self._securities()
+
+        values: dict[str, float] = {}
+        for pos in await self._db.get_all_positions():
+            local = float(pos.get("current_price") or 0) * float(pos.get("quantity") or 0)
+            values[pos["symbol"]] = await self._currency.to_eur(local, pos.get("currency") or "EUR")
+        exposure = decompose(values, securities, basis)
+        for ccy, amount in (await self._db.get_cash_balances()).items():
+            exposure[ccy] = exposure.get(ccy, 0.0) + await self._currency.to_eur(amount, ccy)
+        if await self._settings.get("external_holdings_in_allocations", True):
+            for holding in await self._db.get_external_holdings():
+                ccy = holding["currency"]
+                value_eur = await self._currency.to_eur(float(holding["value"] or 0), ccy)
+                exposure[ccy] = exposure.get(ccy, 0.0) + value_eur
+        total = sum(exposure.values())
+        current_pct = to_pct(exposure)
+
+        history = []
+        for snapshot in await self._db.get_portfolio_snapshots(days=days):
+            data = snapshot["data"]
+            point = decompose(
+                {s: float(p.get("value_eur") or 0) for s, p in data.get("positions", {}).items()}, securities, basis
+            )
+            point["EUR"] = point.get("EUR", 0.0) + float(data.get("cash_eur") or 0.0)
+            history.append(
+                {
+                    "date": datetime.fromtimestamp(snapshot["date"], tz=timezone.utc).strftime("%Y-%m-%d"),
+                    "exposure": {ccy: round(pct, 4) for ccy, pct in to_pct(point).items()},
+                }
+            )
+        start_pct = history[0]["exposure"] if history else {}
+
+        limits = await self.limits()
+        currencies = sorted(set(exposure) | set(limits), key=lambda c: -exposure.get(c, 0.0))
+        return {
+            "basis": basis,
+            "total_eur": round(total, 2),
+            "exposure": [
+                {
+                    "currency": ccy,
+                    "value_eur": round(exposure.get(ccy, 0.0), 2),
+                    "pct": round(current_pct.get(ccy, 0.0), 4),
+                    "drift_pct": round(current_pct.get(ccy, 0.0) - start_pct.get(ccy, 0.0), 4) if history else None,
+                    "limit_pct": limits.get(ccy),
+                }
+                for ccy in currencies
+            ],
+            "history": history,
+            "suggestions": hedging_suggestions(current_pct, limits, total),
+        }
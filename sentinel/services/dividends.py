@@ -0,0 +1,161 @@
+"""Dividend withholding tax.
+
+Tradernet credits dividends net of tax: a corporate action's amount is what
+reached the account, with the tax withheld at source (external_tax) and by the
+broker (tax_amount) reported alongside. sync:dividends stores the gross amount,
+the withholding amount and rate, and the issuer country (the ISIN prefix) with
+each dividend; rows synced before that are broken down from their raw data.
+
+Tax withheld above the treaty rate of the issuer country can be reclaimed from
+that country's tax office. Treaty rates come from dividend_treaty_rates
+(country -> rate, e.g. {"CH": 0.15}), falling back to
+dividend_treaty_rate_default. The yearly summary aggregates gross, withheld,
+net and reclaimable amounts in EUR per country, for the tax return.
+"""
+
+from __future__ import annotations
+
+import json
+from collections import defaultdict
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+from sentinel.utils.identity import extract_isin
+
+UNKNOWN_COUNTRY = "unknown"
+
+
+def _num(value) -> float:
+    try:
+        return float(value or 0)
+    except (TypeError, ValueError):
+        return 0.0
+
+
+def withholding_tax(action: dict) -> float:
+    """Tax withheld on a dividend: the broker's tax plus the tax withheld at source."""
+    return _num(action.get("tax_amount")) + _num(action.get("external_tax"))
+
+
+def withholding_breakdown(action: dict) -> dict:
+    """Gross, withheld and net amounts (dividend currency) and the withholding rate of a corporate action."""
+    net = _num(action.get("amount"))
+    withheld = withholding_tax(action)
+    gross = net + withheld
+    return {
+        "gross_amount": round(gross, 6),
+        "withholding_amount": round(withheld, 6),
+        "withholding_rate": round(withheld / gross, 6) if gross > 0 else 0.0,
+        "net_amount": net,
+    }
+
+
+def issuer_country(isin: str | None) -> str | None:
+    """Country code of the issuer (ISIN prefix)."""
+    return isin[:2] if isin else None
+
+
+def reclaimable(gross: float, withheld: float, treaty_rate: float) -> float:
+    """Tax withheld above the treaty rate."""
+    return max(0.0, withheld - gross * treaty_rate)
+
+
+async def dividend_country(db: Database, symbol: str, action: dict) -> str | None:
+    """Issuer country of a dividend, from the security's ISIN or the one in the broker data."""
+    security = await db.get_security(symbol) or {}
+    return issuer_country(security.get("isin") or extract_isin(action))
+
+
+class DividendService:
+    """Dividends with their withholding tax, and yearly withholding summaries."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def treaty_rate(self, country: str) -> float:
+        rates = await self._settings.get("dividend_treaty_rates", {}) or {}
+        default = await self._settings.get("dividend_treaty_rate_default", 0.15)
+        return float(rates.get(country, default))
+
+    async def dividends(self, year: int | None = None, symbol: str | None = None) -> list[dict]:
+        """Dividends (most recent first) with gross, withholding, net and EUR amounts."""
+        start = f"{year:04d}-01-01" if year else None
+        rows = await self._db.get_dividends(symbol=symbol, start_date=start)
+        if year:
+            rows = [r for r in rows if r["date"][:4] == f"{year:04d}"]
+
+        result = []
+        for row in rows:
+            data = json.loads(row["data"]) if isinstance(row.get("data"), str) else row.get("data") or {}
+            if row.get("gross_amount") is None:
+                breakdown = withholding_breakdown(data)
+                country = await dividend_country(self._db, row["symbol"], data)
+            else:
+                breakdown = {
+                    "gross_amount": row["gross_amount"],
+                    "withholding_amount": row["withholding_amount"],
+                    "withholding_rate": row["withholding_rate"],
+                    "net_amount": row["amount"],
+                }
+                country = row.get("country")
+            # value is the net amount in EUR on the payment date; the same rate applies to gross and tax
+            eur_rate = row["value"] / row["amount"] if row["amount"] else 1.0
+            result.append(
+                {
+                    "id": row["id"],
+                    "symbol": row["symbol"],
+                    "date": row["date"],
+                    "currency": row["currency"],
+                    "country": country or UNKNOWN_COUNTRY,
+                    **breakdown,
+                    "gross_eur": round(breakdown["gross_amount"] * eur_rate, 2),
+                    "withholding_eur": round(breakdown["withholding_amount"] * eur_rate, 2),
+                    "net_eur": round(row["value"], 2),
+                }
+            )
+        return result
+
+    async def withholding_summary(self, year: int) -> dict:
+        """Gross, withheld, net and reclaimable dividend tax in EUR per issuer country for a year."""
+        by_country: dict[str, dict] = defaultdict(
+            lambda: {"dividends": 0, "gross_eur": 0.0, "withholding_eur": 0.0, "net_eur": 0.0, "reclaimable_eur": 0.0}
+        )
+        for dividend in await self.dividends(year):
+            totals = by_country[dividend["country"]]
+            treaty_rate = await self.treaty_rate(dividend["country"])
+            totals["dividends"] += 1
+            totals["gross_eur"] += dividend["gross_eur"]
+            totals["withholding_eur"] += dividend["withholding_eur"]
+            totals["net_eur"] += dividend["net_eur"]
+            totals["reclaimable_eur"] += reclaimable(dividend["gross_eur"], dividend["withholding_eur"], treaty_rate)
+
+        countries = []
+        for country, totals in sorted(by_country.items()):
+            gross = totals["gross_eur"]
+            countries.append(
+                {
+                    "country": country,
+                    "dividends": totals["dividends"],
+                    "gross_eur": round(gross, 2),
+                    "withholding_eur": round(totals["withholding_eur"], 2),
+                    "net_eur": round(totals["net_eur"], 2),
+                    "effective_rate": round(totals["withholding_eur"] / gross, 4) if gross > 0 else 0.0,
+                    "treaty_rate": await self.treaty_rate(country),
+                    "reclaimable_eur": round(totals["reclaimable_eur"], 2),
+                }
+            )
+        return {
+            "year": year,
+            "countries": countries,
+            "totals": {
+                key: round(sum(c[key] for c in countries), 2)
+                for key in ("gross_eur", "withholding_eur", "net_eur", "reclaimable_eur")
+            },
+        }
@@ -0,0 +1,127 @@
+"""Dividend reinvestment (DRIP) per security.
+
+Dividends are reinvested through the planner: a dividend not yet followed by a
+buy of its reinvestment target adds to that target's opportunity score (up to
+max_dividend_reinvestment_boost, see get_uninvested_dividends). Each security
+decides where its dividends go (drip_mode, NULL for drip_default_mode):
+
+- same: back into the paying security
+- redirect: into the highest-scored underweight security (current allocation
+  below the planner's ideal), or the paying security if none is underweight
+- cash: not reinvested; the dividend stays as cash
+
+The trading:dividend_reinvest job decides each new dividend once, recording the
+mode, target and reason in dividend_reinvestments for audit. Dividends without
+a decision yet are treated as same.
+"""
+
+from __future__ import annotations
+
+import logging
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+DRIP_MODES = ("same", "redirect", "cash")
+
+
+class DripService:
+    """Per-security dividend reinvestment decisions."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def mode_of(self, security: dict | None) -> str:
+        """Effective DRIP mode of a security."""
+        mode = (security or {}).get("drip_mode")
+        return mode if mode in DRIP_MODES else await self._settings.get("drip_default_mode", "same")
+
+    async def set_mode(self, symbol: str, mode: str | None) -> dict:
+        """Set a security's DRIP mode (None falls back to drip_default_mode).
+
+        Raises:
+            LookupError: Unknown security
+            ValueError: Unknown mode
+        """
+        if mode is not None and mode not in DRIP_MODES:
+            raise ValueError(f"drip_mode must be one of {', '.join(DRIP_MODES)}")
+        security = await self._db.get_security(symbol)
+        if security is None:
+            raise LookupError(f"Security {symbol} not found")
+        await self._db.upsert_security(symbol, drip_mode=mode)
+        return {"symbol": symbol, "drip_mode": mode, "effective_mode": await self.mode_of({"drip_mode": mode})}
+
+    async def modes(self) -> list[dict]:
+        """Configured and effective DRIP mode of every active security."""
+        return [
+            {"symbol": s["symbol"], "drip_mode": s.get("drip_mode"), "effective_mode": await self.mode_of(s)}
+            for s in await self._db.get_all_securities(active_only=True)
+        ]
+
+    async def redirect_target(self, planner) -> tuple[str | None, str]:
+        """Highest-scored buyable security below its ideal allocation, with the reason."""
+        ideal = await planner.calculate_ideal_portfolio()
+        current = await planner.get_current_allocations()
+        underweight = [s for s, target in ideal.items() if target > current.get(s, 0.0)]
+        buyable = {
+            sec["symbol"] for sec in await self._db.get_all_securities(active_only=True) if sec.get("allow_buy", 1)
+        }
+        states = await self._db.get_score_states([s for s in underweight if s in buyable])
+        ranked = sorted(
+            ((float((state.get("signal") or {}).get("core_rank", 0.0)), s) for s, state in states.items()),
+            key=lambda r: (-r[0], r[1]),
+        )
+        if not ranked:
+            return None, "no underweight security to redirect to"
+        rank, symbol = ranked[0]
+        gap = ideal[symbol] - current.get(symbol, 0.0)
+        return symbol, f"highest-scored underweight security (core rank {rank:.2f}, {gap:.1%} below target)"
+
+    async def decide_pending(self, planner) -> list[dict]:
+        """Decide where each undecided, not yet reinvested dividend goes, and record the decisions."""
+        pending = await self._db.get_undecided_dividends()
+        if not pending:
+            return []
+
+        securities = {s["symbol"]: s for s in await self._db.get_all_securities()}
+        redirect: tuple[str | None, str] | None = None
+        decisions = []
+        for dividend in pending:
+            symbol = dividend["symbol"]
+            mode = await self.mode_of(securities.get(symbol))
+            if mode == "cash":
+                target, reason = None, "kept as cash"
+            elif mode == "redirect":
+                if redirect is None:
+                    redirect = await self.redirect_target(planner)
+                target, reason = redirect
+                if target is None:
+                    target, reason = symbol, f"{reason}; reinvested in {symbol}"
+            else:
+                target, reason = symbol, "reinvested in the paying security"
+
+            decision = {
+                "dividend_id": dividend["id"],
+                "symbol": symbol,
+                "mode": mode,
+                "target_symbol": target,
+                "value_eur": dividend["value"],
+                "reason": reason,
+            }
+            await self._db.save_dividend_reinvestment(**decision)
+            logger.info(f"Dividend {dividend['id']} of {symbol} ({dividend['value']:.2f} EUR): {mode} -> {reason}")
+            decisions.append(decision)
+        return decisions
+
+    async def decisions(self, symbol: str | None = None, limit: int = 100) -> list[dict]:
+        """Recorded reinvestment decisions, most recent first."""
+        return await self._db.get_dividend_reinvestments(symbol, limit)
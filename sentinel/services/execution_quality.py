@@ -0,0 +1,242 @@
+"""Slippage and execution-quality analytics.
+
+Each submitted order records two reference prices with its trade decision: the
+recommendation price the planner sized it with, and the quoted ask (buy) / bid
+(sell) at submission. Synced trades carry the order ID, so fills are matched to
+their decision and slippage is measured against both references, in basis
+points and signed so positive is a cost (paid more on a buy, got less on a sell).
+
+The slippage of fills against the submission quote also calibrates the depth
+limit band (limit_depth_max_slippage_pct): the band is set to cover the 90th
+percentile of observed slippage with some headroom, so limits are wide enough
+to fill but no wider than the market has needed.
+
+Commissions (as reported on the fills, or estimated from the fee schedule when
+the broker reports none) are added to slippage for the net cost of execution.
+
+Each decision also records how its order was priced (market, depth, midpoint,
+vwap, ... see utils/order_pricing.py), so the report compares the pricing
+strategies by slippage and by fill rate: the share of orders submitted with a
+strategy that were filled (simulated research-mode orders are not counted).
+"""
+
+from __future__ import annotations
+
+import math
+import time
+from datetime import datetime, timezone
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.settings import Settings
+from sentinel.utils.fees import FeeCalculator, market_of
+
+# Fewer matched fills with a submission quote than this and no calibration is made
+MIN_CALIBRATION_FILLS = 10
+CALIBRATION_PERCENTILE = 0.9
+CALIBRATION_HEADROOM = 1.25
+MIN_LIMIT_BAND_PCT = 0.25
+MAX_LIMIT_BAND_PCT = 5.0
+# Order IDs of orders that never reach the broker
+SIMULATED_ORDER_PREFIXES = ("RESEARCH-", "DRY-RUN-")
+
+
+def slippage_bps(action: str, reference: float | None, fill: float) -> float | None:
+    """Slippage of a fill against a reference price in basis points (positive = cost)."""
+    if not reference or reference <= 0:
+        return None
+    sign = 1.0 if action == "buy" else -1.0
+    return sign * (fill - reference) / reference * 10_000
+
+
+def percentile(values: list[float], q: float) -> float:
+    """Nearest-rank percentile (q in 0..1) of a non-empty list."""
+    ordered = sorted(values)
+    return ordered[max(0, math.ceil(q * len(ordered)) - 1)]
+
+
+def _order_id(trade: dict) -> str | None:
+    raw = trade.get("raw_data") if isinstance(trade.get("raw_data"), dict) else {}
+    order_id = raw.get("order_id") or raw.get("orderId")
+    return str(order_id) if order_id else None
+
+
+def _summary(fills: list[dict]) -> dict:
+    """Fill count, traded value, fees and value-weighted average slippage, fee and net cost."""
+    summary: dict = {
+        "fills": len(fills),
+        "traded_value": round(sum(f["value"] for f in fills), 2),
+        "fees_eur": round(sum(f["fee_eur"] for f in fills), 2),
+    }
+    for key in ("vs_recommendation_bps", "vs_quote_bps", "fee_bps", "net_cost_bps"):
+        measured = [f for f in fills if f[key] is not None]
+        weight = sum(f["value"] for f in measured)
+        summary[key] = round(sum(f[key] * f["value"] for f in measured) / weight, 2) if weight > 0 else None
+    return summary
+
+
+class ExecutionQualityService:
+    """Matches fills to their decisions, aggregates slippage and calibrates the limit band."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+
+    async def _to_eur(self, amount: float, currency: str | None) -> float:
+        if not amount or not currency or currency == "EUR":
+            return amount
+        return await self._currency.to_eur(amount, currency)
+
+    async def _start_ts(self, days: int | None, now: int | None) -> int:
+        days = int(days or await self._settings.get("execution_quality_lookback_days", 90))
+        return int(now or time.time()) - max(1, days) * 86400
+
+    async def fills(self, days: int | None = None, now: int | None = None) -> list[dict]:
+        """Orders submitted in the last `days` that have fills, with their slippage.
+
+        Fills of one order are combined at their quantity-weighted average price.
+        """
+        start_ts = await self._start_ts(days, now)
+        decisions = {
+            d["order_id"]: d
+            for d in await self._db.get_trade_decisions(start_ts, include_archived=True)
+            if d.get("order_id")
+        }
+        start_date = datetime.fromtimestamp(start_ts, tz=timezone.utc).strftime("%Y-%m-%d")
+        trades = await self._db.get_trades(start_date=start_date, limit=100000, include_archived=True)
+
+        by_order: dict[str, list[dict]] = {}
+        for trade in trades:
+            order_id = _order_id(trade)
+            if order_id in decisions:
+                by_order.setdefault(order_id, []).append(trade)
+
+        currencies = {s["symbol"]: s.get("currency") for s in await self._db.get_all_securities(active_only=False)}
+        schedule = await FeeCalculator(self._settings).get_schedule()
+
+        fills = []
+        for order_id, order_trades in by_order.items():
+            decision = decisions[order_id]
+            quantity = sum(float(t["quantity"]) for t in order_trades)
+            if quantity <= 0:
+                continue
+            price = sum(float(t["quantity"]) * float(t["price"]) for t in order_trades) / quantity
+            executed_at = min(int(t["executed_at"]) for t in order_trades)
+            currency = currencies.get(decision["symbol"]) or "EUR"
+            value_eur = await self._to_eur(quantity * price, currency)
+            fee_eur = 0.0
+            for t in order_trades:
+                fee_eur += await self._to_eur(float(t.get("commission") or 0), t.get("commission_currency") or "EUR")
+            fee_estimated = fee_eur <= 0
+            if fee_estimated:
+                fee_eur = schedule.cost(value_eur, decision["symbol"], currency)
+            fee_bps = fee_eur / value_eur * 10_000 if value_eur > 0 else None
+            vs_recommendation = slippage_bps(decision["action"], decision.get("price"), price)
+            vs_quote = slippage_bps(decision["action"], decision.get("quote_price"), price)
+            slippage = vs_quote if vs_quote is not None else vs_recommendation
+            fills.append(
+                {
+                    "order_id": order_id,
+                    "symbol": decision["symbol"],
+                    "market": market_of(decision["symbol"]),
+                    "action": decision["action"],
+                    "quantity": quantity,
+                    "fill_price": round(price, 6),
+                    "recommendation_price": decision.get("price"),
+                    "quote_price": decision.get("quote_price"),
+                    "pricing_method": decision.get("pricing_method"),
+                    "executed_at": executed_at,
+                    "hour_utc": datetime.fromtimestamp(executed_at, tz=timezone.utc).hour,
+                    "value": quantity * price,
+                    "fee_eur": round(fee_eur, 4),
+                    "fee_estimated": fee_estimated,
+                    "vs_recommendation_bps": vs_recommendation,
+                    "vs_quote_bps": vs_quote,
+                    "fee_bps": fee_bps,
+                    # Slippage against the quote (else the recommendation) plus commission
+                    "net_cost_bps": slippage + fee_bps if slippage is not None and fee_bps is not None else None,
+                }
+            )
+        return sorted(fills, key=lambda f: f["executed_at"])
+
+    async def report(self, days: int | None = None, now: int | None = None) -> dict:
+        """Slippage overall and per symbol, market, hour (UTC) and pricing method, with the calibration suggestion."""
+        fills = await self.fills(days, now)
+        groups: dict[str, dict[str, list[dict]]] = {"symbols": {}, "markets": {}, "hours": {}}
+        for fill in fills:
+            groups["symbols"].setdefault(fill["symbol"], []).append(fill)
+            groups["markets"].setdefault(fill["market"], []).append(fill)
+            groups["hours"].setdefault(f"{fill['hour_utc']:02d}:00", []).append(fill)
+        return {
+            "overall": _summary(fills),
+            **{name: {key: _summary(group[key]) for key in sorted(group)} for name, group in groups.items()},
+            "pricing_methods": await self._pricing_methods(fills, days, now),
+            "calibration": await self._calibration(fills),
+        }
+
+    async def _pricing_methods(self, fills: list[dict], days: int | None, now: int | None) -> dict:
+        """Slippage and fill rate of the orders priced with each method, to compare pricing strategies.
+
+        Orders submitted recently and not yet synced as trades count as unfilled.
+        """
+        submitted: dict[str, int] = {}
+        for decision in await self._db.get_trade_decisions(await self._start_ts(days, now), include_archived=True):
+            order_id = str(decision.get("order_id") or "")
+            if order_id and not order_id.startswith(SIMULATED_ORDER_PREFIXES):
+                method = decision.get("pricing_method") or "unknown"
+                submitted[method] = submitted.get(method, 0) + 1
+        filled: dict[str, list[dict]] = {}
+        for fill in fills:
+            filled.setdefault(fill["pricing_method"] or "unknown", []).append(fill)
+        methods = {}
+        for method in sorted(set(submitted) | set(filled)):
+            orders = submitted.get(method, 0)
+            methods[method] = {
+                **_summary(filled.get(method, [])),
+                "orders": orders,
+                "fill_rate": round(min(1.0, len(filled.get(method, [])) / orders), 4) if orders else None,
+            }
+        return methods
+
+    async def calibrate(self, apply: bool | None = None, days: int | None = None, now: int | None = None) -> dict:
+        """Suggest a depth limit band from recent fills, and store it when applying.
+
+        Args:
+            apply: Store the suggestion (defaults to limit_slippage_auto_calibrate)
+        """
+        if apply is None:
+            apply = bool(await self._settings.get("limit_slippage_auto_calibrate", False))
+        calibration = await self._calibration(await self.fills(days, now))
+        calibration["applied"] = False
+        if apply and calibration["suggested_pct"] is not None:
+            await self._settings.set("limit_depth_max_slippage_pct", calibration["suggested_pct"])
+            calibration["applied"] = True
+        return calibration
+
+    async def _calibration(self, fills: list[dict]) -> dict:
+        """Band covering the 90th percentile of slippage against the submission quote, with headroom."""
+        current = float(await self._settings.get("limit_depth_max_slippage_pct", 2.0))
+        measured = [max(0.0, f["vs_quote_bps"]) for f in fills if f["vs_quote_bps"] is not None]
+        if len(measured) < MIN_CALIBRATION_FILLS:
+            return {"current_pct": current, "suggested_pct": None, "fills": len(measured), "p90_bps": None}
+        p90 = percentile(measured, CALIBRATION_PERCENTILE)
+        suggested = min(MAX_LIMIT_BAND_PCT, max(MIN_LIMIT_BAND_PCT, p90 / 100 * CALIBRATION_HEADROOM))
+        return {
+            "current_pct": current,
+            "suggested_pct": round(suggested, 2),
+            "fills": len(measured),
+            "p90_bps": round(p90, 2),
+        }
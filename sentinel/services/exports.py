@@ -0,0 +1,289 @@
+"""Ledger and portfolio exports for GnuCash, Portfolio Performance and spreadsheets.
+
+Trades (including archived round trips), dividends and cash flows export as
+CSV, OFX (2.x investment statement) or QIF (investment account); portfolio
+snapshots (one row per position per day) as CSV. Output is generated while
+rows are read from the database a batch at a time, so an export of years of
+history keeps memory flat on the device.
+
+OFX lists trades as BUYSTOCK/SELLSTOCK, dividends as INCOME (DIV) and cash
+flows as INVBANKTRAN, followed by a SECLIST of the securities referenced.
+QIF writes trades as Buy/Sell, dividends as Div, deposits and withdrawals as
+XIn/XOut and other cash flows as MiscInc/MiscExp. Amounts are in each entry's
+own currency; QIF has no currency field, so it is noted in the memo.
+"""
+
+from __future__ import annotations
+
+import csv
+import io
+from datetime import datetime, timezone
+from typing import AsyncIterator
+from xml.sax.saxutils import escape
+
+from sentinel.database import Database
+from sentinel.errors import InvalidInput
+
+FORMATS: dict[str, tuple[str, ...]] = {
+    "trades": ("csv", "ofx", "qif"),
+    "dividends": ("csv", "ofx", "qif"),
+    "cash_flows": ("csv", "ofx", "qif"),
+    "snapshots": ("csv",),
+}
+MEDIA_TYPES = {"csv": "text/csv", "ofx": "application/x-ofx", "qif": "application/qif"}
+CSV_COLUMNS = {
+    "trades": [
+        "date",
+        "broker_trade_id",
+        "symbol",
+        "name",
+        "side",
+        "quantity",
+        "price",
+        "currency",
+        "value",
+        "commission",
+        "commission_currency",
+    ],
+    "dividends": ["date", "symbol", "amount", "currency", "value_eur", "gross_amount", "withholding_amount", "country"],
+    "cash_flows": ["date", "type", "amount", "currency", "comment"],
+    "snapshots": ["date", "symbol", "quantity", "value_eur"],
+}
+# Cash flow types that move money in or out of the account
+DEPOSIT_TYPES = ("card", "card_payout")
+BATCH_ROWS = 500
+
+
+def _iso_day(ts: int) -> str:
+    return datetime.fromtimestamp(ts, tz=timezone.utc).strftime("%Y-%m-%d")
+
+
+def _ofx_date(day: str) -> str:
+    return day[:10].replace("-", "") + "000000"
+
+
+def _qif_date(day: str) -> str:
+    year, month, dom = day[:10].split("-")
+    return f"{month}/{dom}/{year}"
+
+
+def _csv_rows(dataset: str, row: dict) -> list[list]:
+    """CSV rows of one database row (a snapshot gives one row per position plus cash)."""
+    if dataset == "trades":
+        return [
+            [
+                _iso_day(row["executed_at"]),
+                row["broker_trade_id"],
+                row["symbol"],
+                row["name"],
+                row["side"],
+                row["quantity"],
+                row["price"],
+                row["currency"] or "EUR",
+                round(row["quantity"] * row["price"], 2),
+                row["commission"],
+                row["commission_currency"],
+            ]
+        ]
+    if dataset == "dividends":
+        return [
+            [
+                row["date"][:10],
+                row["symbol"],
+                row["amount"],
+                row["currency"],
+                row["value"],
+                row["gross_amount"],
+                row["withholding_amount"],
+                row["country"],
+            ]
+        ]
+    if dataset == "cash_flows":
+        return [[row["date"][:10], row["type_id"], row["amount"], row["currency"], row["comment"]]]
+    day = _iso_day(row["date"])
+    positions = row["data"].get("positions", {})
+    rows = [[day, symbol, p.get("quantity"), p.get("value_eur")] for symbol, p in sorted(positions.items())]
+    rows.append([day, "CASH", None, row["data"].get("cash_eur")])
+    return rows
+
+
+def _ofx_transaction(dataset: str, row: dict) -> str:
+    if dataset == "trades":
+        buy = row["side"] == "BUY"
+        value = row["quantity"] * row["price"]
+        total = -(value + row["commission"]) if buy else value - row["commission"]
+        kind, inner, action = ("BUYSTOCK", "INVBUY", "BUY") if buy else ("SELLSTOCK", "INVSELL", "SELL")
+        units = row["quantity"] if buy else -row["quantity"]
+        return (
+            f"<{kind}><{inner}><INVTRAN><FITID>{escape(str(row['broker_trade_id']))}</FITID>"
+            f"<DTTRADE>{_ofx_date(_iso_day(row['executed_at']))}</DTTRADE></INVTRAN>"
+            f"<SECID><UNIQUEID>{escape(row['symbol'])}</UNIQUEID><UNIQUEIDTYPE>TICKER</UNIQUEIDTYPE></SECID>"
+            f"<UNITS>{units}</UNITS><UNITPRICE>{row['price']}</UNITPRICE>"
+            f"<COMMISSION>{row['commission']}</COMMISSION><TOTAL>{round(total, 2)}</TOTAL>"
+            f"<CURRENCY><CURRATE>1</CURRATE><CURSYM>{row['currency'] or 'EUR'}</CURSYM></CURRENCY>"
+            f"<SUBACCTSEC>CASH</SUBACCTSEC><SUBACCTFUND>CASH</SUBACCTFUND></{inner}>"
+            f"<{action}TYPE>{action}</{action}TYPE></{kind}>\n"
+        )
+    if dataset == "dividends":
+        return (
+            f"<INCOME><INVTRAN><FITID>{escape(str(row['id']))}</FITID>"
+            f"<DTTRADE>{_ofx_date(row['date'])}</DTTRADE></INVTRAN>"
+            f"<SECID><UNIQUEID>{escape(row['symbol'])}</UNIQUEID><UNIQUEIDTYPE>TICKER</UNIQUEIDTYPE></SECID>"
+            f"<INCOMETYPE>DIV</INCOMETYPE><TOTAL>{row['amount']}</TOTAL>"
+            f"<SUBACCTSEC>CASH</SUBACCTSEC><SUBACCTFUND>CASH</SUBACCTFUND>"
+            f"<CURRENCY><CURRATE>1</CURRATE><CURSYM>{row['currency']}</CURSYM></CURRENCY></INCOME>\n"
+        )
+    memo = escape(row["comment"] or row["type_id"])
+    return (
+        f"<INVBANKTRAN><STMTTRN><TRNTYPE>{'CREDIT' if row['amount'] >= 0 else 'DEBIT'}</TRNTYPE>"
+        f"<DTPOSTED>{_ofx_date(row['date'])}</DTPOSTED><TRNAMT>{row['amount']}</TRNAMT>"
+        f"<FITID>{escape(str(row['id']))}</FITID><MEMO>{memo}</MEMO>"
+        f"<CURRENCY><CURRATE>1</CURRATE><CURSYM>{row['currency']}</CURSYM></CURRENCY></STMTTRN>"
+        f"<SUBACCTFUND>CASH</SUBACCTFUND></INVBANKTRAN>\n"
+    )
+
+
+def _qif_entry(dataset: str, row: dict) -> str:
+    if dataset == "trades":
+        value = row["quantity"] * row["price"]
+        total = value + row["commission"] if row["side"] == "BUY" else value - row["commission"]
+        lines = [
+            f"D{_qif_date(_iso_day(row['executed_at']))}",
+            f"N{'Buy' if row['side'] == 'BUY' else 'Sell'}",
+            f"Y{row['symbol']}",
+            f"I{row['price']}",
+            f"Q{row['quantity']}",
+            f"O{row['commission']}",
+            f"T{round(total, 2)}",
+            f"M{row['broker_trade_id']} ({row['currency'] or 'EUR'})",
+        ]
+    elif dataset == "dividends":
+        lines = [
+            f"D{_qif_date(row['date'])}",
+            "NDiv",
+            f"Y{row['symbol']}",
+            f"T{row['amount']}",
+            f"M{row['currency']}",
+        ]
+    else:
+        amount = row["amount"]
+        if row["type_id"] in DEPOSIT_TYPES:
+            action = "XIn" if amount >= 0 else "XOut"
+        else:
+            action = "MiscInc" if amount >= 0 else "MiscExp"
+        lines = [
+            f"D{_qif_date(row['date'])}",
+            f"N{action}",
+            f"T{abs(amount)}",
+            f"M{row['comment'] or row['type_id']} ({row['currency']})",
+        ]
+    return "\n".join(lines) + "\n^\n"
+
+
+class ExportService:
+    """Streams ledger and portfolio exports."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    def export(
+        self, dataset: str, fmt: str, start_date: str | None = None, end_date: str | None = None
+    ) -> tuple[str, str, AsyncIterator[str]]:
+        """
+        Check an export request and return its media type, file name and chunk stream.
+
+        Args:
+            dataset: trades, dividends, cash_flows or snapshots
+            fmt: csv, ofx or qif
+            start_date: First day included (YYYY-MM-DD)
+            end_date: Last day included (YYYY-MM-DD)
+
+        Raises:
+            InvalidInput: Unknown dataset, a format the dataset does not support, or a bad date range
+        """
+        if dataset not in FORMATS:
+            raise InvalidInput(f"dataset must be one of {', '.join(FORMATS)}")
+        if fmt not in FORMATS[dataset]:
+            raise InvalidInput(f"{dataset} can be exported as {', '.join(FORMATS[dataset])}")
+        for day in (start_date, end_date):
+            if day:
+                try:
+                    datetime.strptime(day, "%Y-%m-%d")
+                except ValueError as e:
+                    raise InvalidInput(f"Invalid date {day} (expected YYYY-MM-DD)") from e
+        if start_date and end_date and start_date > end_date:
+            raise InvalidInput("start_date is after end_date")
+
+        filename = "_".join([dataset, *(day for day in (start_date, end_date) if day)]) + f".{fmt}"
+        rows = self._db.iter_export_rows(dataset, start_date, end_date, batch_size=BATCH_ROWS)
+        stream = {"csv": self._csv, "ofx": self._ofx, "qif": self._qif}[fmt](dataset, rows, start_date, end_date)
+        return MEDIA_TYPES[fmt], filename, stream
+
+    async def _csv(self, dataset: str, rows, start_date, end_date) -> AsyncIterator[str]:
+        out = io.StringIO()
+        writer = csv.writer(out)
+        writer.writerow(CSV_COLUMNS[dataset])
+        count = 0
+        async for row in rows:
+            writer.writerows(_csv_rows(dataset, row))
+            count += 1
+            if count % BATCH_ROWS == 0:
+                yield out.getvalue()
+                out.seek(0)
+                out.truncate()
+        if out.getvalue():
+            yield out.getvalue()
+
+    async def _ofx(self, dataset: str, rows, start_date, end_date) -> AsyncIterator[str]:
+        now = datetime.now(timezone.utc).strftime("%Y%m%d%H%M%S")
+        first = _ofx_date(start_date) if start_date else "19700101000000"
+        last = _ofx_date(end_date) if end_date else now
+        status = "<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>"
+        yield (
+            '<?xml version="1.0" encoding="UTF-8" standalone="no"?>\n'
+            '<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>\n'
+            f"<OFX>\n<SIGNONMSGSRSV1><SONRS>{status}<DTSERVER>{now}</DTSERVER><LANGUAGE>ENG</LANGUAGE>"
+            "</SONRS></SIGNONMSGSRSV1>\n"
+            f"<INVSTMTMSGSRSV1><INVSTMTTRNRS><TRNUID>1</TRNUID>{status}\n"
+            f"<INVSTMTRS><DTASOF>{now}</DTASOF><CURDEF>EUR</CURDEF>"
+            "<INVACCTFROM><BROKERID>sentinel</BROKERID><ACCTID>sentinel</ACCTID></INVACCTFROM>\n"
+            f"<INVTRANLIST><DTSTART>{first}</DTSTART><DTEND>{last}</DTEND>\n"
+        )
+        symbols: set[str] = set()
+        chunk: list[str] = []
+        async for row in rows:
+            chunk.append(_ofx_transaction(dataset, row))
+            if row.get("symbol"):
+                symbols.add(row["symbol"])
+            if len(chunk) >= BATCH_ROWS:
+                yield "".join(chunk)
+                chunk = []
+        chunk.append("</INVTRANLIST></INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1>\n")
+        if symbols:
+            chunk.append("<SECLISTMSGSRSV1><SECLIST>\n")
+            for symbol in sorted(symbols):
+                security = await self._db.get_security(symbol) or {}
+                chunk.append(
+                    f"<STOCKINFO><SECINFO><SECID><UNIQUEID>{escape(symbol)}</UNIQUEID>"
+                    f"<UNIQUEIDTYPE>TICKER</UNIQUEIDTYPE></SECID>"
+                    f"<SECNAME>{escape(security.get('name') or symbol)}</SECNAME>"
+                    f"<TICKER>{escape(symbol)}</TICKER></SECINFO></STOCKINFO>\n"
+                )
+            chunk.append("</SECLIST></SECLISTMSGSRSV1>\n")
+        chunk.append("</OFX>\n")
+        yield "".join(chunk)
+
+    async def _qif(self, dataset: str, rows, start_date, end_date) -> AsyncIterator[str]:
+        chunk = ["!Type:Invst\n"]
+        async for row in rows:
+            chunk.append(_qif_entry(dataset, row))
+            if len(chunk) >= BATCH_ROWS:
+                yield "".join(chunk)
+                chunk = []
+        if chunk:
+            yield "".join(chunk)
@@ -0,0 +1,160 @@
+"""Holdings outside the broker account.
+
+An employer stock plan, real estate or a crypto wallet is registered as a line
+item with a currency and a value. Values come from manual valuations or from
+an external feed posting them through the API (with its name as the source);
+every valuation is kept, and the latest one is the holding's value.
+
+External holdings are never traded. They count towards net worth and, with
+external_holdings_in_allocations on, towards the geography/industry
+allocations compared with the targets and towards currency exposure limits.
+A holding linked to a security (the employer's stock) falls back to that
+security's geography and industry.
+"""
+
+from __future__ import annotations
+
+import re
+import time
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.errors import InvalidInput, NotFound
+from sentinel.portfolio import Portfolio
+
+KINDS = ("stock_plan", "real_estate", "crypto", "cash", "other")
+# Fields a holding can be created with or changed to; the value changes through valuations
+FIELDS = ("name", "kind", "symbol", "currency", "geography", "industry", "note")
+
+
+def _value(value) -> float:
+    if isinstance(value, bool) or not isinstance(value, (int, float)):
+        raise InvalidInput("value must be a number")
+    if value < 0:
+        raise InvalidInput("value cannot be negative")
+    return float(value)
+
+
+class ExternalHoldingService:
+    """Registers external holdings, records their valuations and reports net worth."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        currency: Currency | None = None,
+        portfolio: Portfolio | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+            portfolio: Portfolio instance (created from db and currency if None)
+        """
+        self._db = db or Database()
+        self._currency = currency or Currency()
+        self._portfolio = portfolio or Portfolio(db=self._db, currency=self._currency)
+
+    async def create(self, data: dict) -> dict:
+        """Register a holding (name, kind, currency, optional symbol/geography/industry/note/value).
+
+        Raises:
+            InvalidInput: Missing name, unknown kind, field or security, or an invalid value
+        """
+        fields = await self._fields({k: v for k, v in data.items() if k != "value"}, creating=True)
+        holding_id = await self._db.add_external_holding(fields)
+        if data.get("value") is not None:
+            await self._db.add_external_valuation(holding_id, _value(data["value"]), "manual", int(time.time()))
+        return await self.get(holding_id)
+
+    async def update(self, holding_id: int, changes: dict) -> dict:
+        """Change a holding's description (not its value; record a valuation for that).
+
+        Raises:
+            NotFound: Unknown holding
+            InvalidInput: Unknown field or security, or an invalid kind or currency
+        """
+        await self.get(holding_id)
+        fields = await self._fields(changes, creating=False)
+        if fields:
+            await self._db.update_external_holding(holding_id, **fields)
+        return await self.get(holding_id)
+
+    async def delete(self, holding_id: int) -> None:
+        """Remove a holding and its valuation history.
+
+        Raises:
+            NotFound: Unknown holding
+        """
+        if not await self._db.delete_external_holding(holding_id):
+            raise NotFound(f"External holding {holding_id} not found", id=holding_id)
+
+    async def value(self, holding_id: int, value, source: str | None = None, valued_at: int | None = None) -> dict:
+        """Record a valuation (source: manual, or the name of the feed posting it).
+
+        Raises:
+            NotFound: Unknown holding
+            InvalidInput: Invalid value or valuation date
+        """
+        await self.get(holding_id)
+        now = int(time.time())
+        if valued_at is not None and (isinstance(valued_at, bool) or not isinstance(valued_at, int)):
+            raise InvalidInput("valued_at must be unix seconds")
+        if valued_at is not None and valued_at > now:
+            raise InvalidInput("valued_at cannot be in the future")
+        await self._db.add_external_valuation(
+            holding_id, _value(value), (source or "").strip() or "manual", valued_at or now
+        )
+        return await self.get(holding_id)
+
+    async def get(self, holding_id: int) -> dict:
+        """A holding with its value in EUR and its valuation history.
+
+        Raises:
+            NotFound: Unknown holding
+        """
+        holding = next((h for h in await self._portfolio.external_holdings() if h["id"] == holding_id), None)
+        if holding is None:
+            raise NotFound(f"External holding {holding_id} not found", id=holding_id)
+        holding["valuations"] = await self._db.get_external_valuations(holding_id)
+        return holding
+
+    async def list(self) -> list[dict]:
+        """All holdings with their value in EUR, largest first."""
+        return await self._portfolio.external_holdings()
+
+    async def net_worth(self) -> dict:
+        """Broker account, external holdings and their sum, in EUR."""
+        broker_eur = await self._portfolio.total_value()
+        holdings = await self._portfolio.external_holdings()
+        external_eur = sum(h["value_eur"] for h in holdings)
+        by_kind: dict[str, float] = {}
+        for holding in holdings:
+            by_kind[holding["kind"]] = by_kind.get(holding["kind"], 0.0) + holding["value_eur"]
+        return {
+            "broker_eur": round(broker_eur, 2),
+            "external_eur": round(external_eur, 2),
+            "net_worth_eur": round(broker_eur + external_eur, 2),
+            "external_by_kind": {kind: round(value, 2) for kind, value in by_kind.items()},
+            "holdings": holdings,
+        }
+
+    async def _fields(self, data: dict, creating: bool) -> dict:
+        unknown = sorted(set(data) - set(FIELDS))
+        if unknown:
+            raise InvalidInput(f"Unknown field {unknown[0]} (accepted: {', '.join(FIELDS)})")
+        fields = {k: (v.strip() if isinstance(v, str) else v) or None for k, v in data.items()}
+        if creating or "name" in fields:
+            if not fields.get("name"):
+                raise InvalidInput("name is required")
+        if creating or "kind" in fields:
+            fields["kind"] = fields.get("kind") or "other"
+            if fields["kind"] not in KINDS:
+                raise InvalidInput(f"kind must be one of {', '.join(KINDS)}")
+        if creating or "currency" in fields:
+            fields["currency"] = str(fields.get("currency") or "EUR").upper()
+            if not re.fullmatch(r"[A-Z]{3}", fields["currency"]):
+                raise InvalidInput("currency must be a three-letter code")
+        if fields.get("symbol") and await self._db.get_security(fields["symbol"]) is None:
+            raise InvalidInput(f"Security {fields['symbol']} not found")
+        return fields
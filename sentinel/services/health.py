@@ -0,0 +1,192 @@
+"""Liveness, readiness and deep health checks.
+
+Three tiers, from cheapest to most thorough:
+
+    /api/healthz  the process is up and serving requests (liveness)
+    /api/readyz   the databases are open, migrations are applied and the
+                  broker is reachable (readiness, 503 when not ready)
+    /api/health   readiness plus job health: quarantined or failing
+                  scheduled jobs make the system "degraded" (deep check)
+
+The systemd watchdog and the auto-deploy script consume readiness: the
+watchdog loop stops pinging once the app has been not ready for longer than
+watchdog_not_ready_grace_seconds (0 = never), so systemd restarts it, and
+auto-deploy rolls back a release that never becomes ready. The LED StateManager maps the
+results onto the not_ready and degraded display states.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import logging
+import time
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.settings import Settings
+from sentinel.utils.systemd import sd_notify, watchdog_interval
+from sentinel.version import VERSION
+
+logger = logging.getLogger(__name__)
+
+_STARTED_AT = time.monotonic()
+
+
+class HealthService:
+    """Runs the health check tiers and feeds the systemd watchdog."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        broker: Broker | None = None,
+        settings: Settings | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._settings = settings or Settings()
+        self._migrations_ok = False
+        self._running = False
+
+    @staticmethod
+    def liveness() -> dict:
+        """The process is up; never touches the database or the broker."""
+        return {"status": "ok", "version": VERSION, "uptime_seconds": int(time.monotonic() - _STARTED_AT)}
+
+    async def readiness(self) -> dict:
+        """Databases open, migrations applied and broker reachable.
+
+        Returns:
+            dict with ready and checks (name -> ok, detail, required); only
+            required checks decide readiness
+        """
+        checks = {
+            "database": await self._check_database(),
+            "migrations": await self._check_migrations(),
+            "broker": self._check_broker(),
+        }
+        tiering = self._db.price_tiering
+        if tiering is not None:
+            available = tiering.available()
+            checks["cold_storage"] = {
+                "ok": available,
+                "detail": tiering.cold_path if available else f"{tiering.cold_path} unavailable",
+                "required": False,
+            }
+        ready = all(c["ok"] for c in checks.values() if c["required"])
+        return {"ready": ready, "checks": checks}
+
+    async def deep(self) -> dict:
+        """Readiness plus scheduled job health.
+
+        Returns:
+            dict with status (healthy, degraded or unhealthy), ready, checks,
+            jobs (quarantined and failing job types), broker_connected and
+            trading_mode
+        """
+        readiness = await self.readiness()
+        jobs = {"quarantined": [], "failing": []}
+        try:
+            for schedule in await self._db.get_job_schedules():
+                if schedule.get("quarantined_at"):
+                    jobs["quarantined"].append(schedule["job_type"])
+                elif (schedule.get("consecutive_failures") or 0) > 0:
+                    jobs["failing"].append(schedule["job_type"])
+        except Exception as e:
+            logger.warning(f"Deep health check could not read job schedules: {e}")
+        if not readiness["ready"]:
+            status = "unhealthy"
+        elif jobs["quarantined"] or jobs["failing"] or not all(c["ok"] for c in readiness["checks"].values()):
+            status = "degraded"
+        else:
+            status = "healthy"
+        return {
+            "status": status,
+            **readiness,
+            "jobs": jobs,
+            "broker_connected": bool(self._broker.connected),
+            "trading_mode": await self._settings.get("trading_mode", "research"),
+        }
+
+    async def display_states(self) -> set[str]:
+        """LED display states for the current health (not_ready or degraded)."""
+        deep = await self.deep()
+        if deep["status"] == "unhealthy":
+            return {"not_ready"}
+        return {"degraded"} if deep["status"] == "degraded" else set()
+
+    async def watchdog(self) -> None:
+        """Notify systemd of readiness and ping its watchdog.
+
+        No-op unless started by a Type=notify unit. Once the app has been not
+        ready for longer than watchdog_not_ready_grace_seconds the pings stop,
+        so systemd restarts the service. The grace period only starts after
+        the app was ready once, so an unconfigured device (no broker
+        credentials yet) is not restarted in a loop.
+        """
+        if not sd_notify("READY=1"):
+            return
+        interval = watchdog_interval()
+        if interval is None:
+            logger.info("systemd notified; no watchdog configured")
+            return
+        logger.info(f"systemd watchdog active, pinging every {interval:.0f}s")
+        self._running = True
+        was_ready = False
+        not_ready_since = None
+        while self._running:
+            try:
+                readiness = await self.readiness()
+            except Exception as e:
+                logger.error(f"Readiness check failed: {e}")
+                readiness = {"ready": False, "checks": {}}
+            if readiness["ready"]:
+                was_ready = True
+                not_ready_since = None
+                sd_notify("WATCHDOG=1\nSTATUS=Ready")
+            else:
+                failing = ", ".join(sorted(n for n, c in readiness["checks"].items() if c["required"] and not c["ok"]))
+                now = time.monotonic()
+                if was_ready and not_ready_since is None:
+                    not_ready_since = now
+                grace = float(await self._settings.get("watchdog_not_ready_grace_seconds", 600))
+                if not_ready_since is None or grace <= 0 or now - not_ready_since <= grace:
+                    sd_notify(f"WATCHDOG=1\nSTATUS=Not ready: {failing or 'unknown'}")
+                else:
+                    logger.error(f"Not ready for over {grace:.0f}s ({failing}); withholding watchdog ping")
+            await asyncio.sleep(interval)
+
+    def stop(self) -> None:
+        """Stop the watchdog loop."""
+        self._running = False
+
+    async def _check_database(self) -> dict:
+        try:
+            cursor = await self._db.conn.execute("SELECT 1")
+            await cursor.fetchone()
+        except Exception as e:
+            return {"ok": False, "detail": str(e), "required": True}
+        return {"ok": True, "detail": "open", "required": True}
+
+    async def _check_migrations(self) -> dict:
+        # Migrations only run at connect, so a passing check stays passed
+        if self._migrations_ok:
+            return {"ok": True, "detail": "up to date", "required": True}
+        try:
+            pending = await self._db.pending_migrations()
+        except Exception as e:
+            return {"ok": False, "detail": str(e), "required": True}
+        if pending:
+            return {"ok": False, "detail": f"pending: {', '.join(pending)}", "required": True}
+        self._migrations_ok = True
+        return {"ok": True, "detail": "up to date", "required": True}
+
+    def _check_broker(self) -> dict:
+        connected = bool(self._broker.connected)
+        return {"ok": connected, "detail": "connected" if connected else "not connected", "required": True}
@@ -0,0 +1,129 @@
+"""Canonical instrument IDs.
+
+A security is keyed by its current symbol (the Tradernet ticker). Every other
+identifier that refers to it is mapped to that symbol in instrument_ids:
+
+- tradernet: the broker ticker; a ticker change retires the old one, which
+  keeps resolving to the renamed security
+- isin: the stable identity, shared by the listings of one security
+- yahoo: the Yahoo Finance symbol, where it is not the symbol without .US
+- stooq: the Stooq symbol, where it is not the lowercased symbol (aapl.us)
+
+The ISIN and Tradernet mappings follow the securities table (new securities,
+ISIN updates and ticker changes); Yahoo and Stooq symbols are set by the user. Data
+keyed by a broker symbol (trades, dividends) is joined through resolve(), so
+rows reported under a former ticker land on the current security instead of
+an orphaned symbol.
+
+A collision is an ID currently mapped to more than one security. It never
+resolves to an arbitrary one: resolve() returns None and collisions() lists it.
+Setting a Yahoo or Stooq symbol already used by another security is refused;
+an ISIN may be shared (several listings), but is then ambiguous on its own.
+"""
+
+from __future__ import annotations
+
+from typing import Iterable
+
+from sentinel.database import Database
+from sentinel.utils.identity import normalize_isin
+
+SCHEMES = ("isin", "tradernet", "yahoo", "stooq")
+# Schemes the user may set (the Tradernet ticker is the symbol itself)
+USER_SCHEMES = ("isin", "yahoo", "stooq")
+
+
+def default_yahoo_symbol(symbol: str) -> str:
+    """Yahoo Finance symbol of a Tradernet symbol without an explicit mapping."""
+    return symbol.removesuffix(".US")
+
+
+def default_stooq_symbol(symbol: str) -> str:
+    """Stooq symbol of a Tradernet symbol without an explicit mapping."""
+    return symbol.lower()
+
+
+class InstrumentIdService:
+    """Maps ISINs, Tradernet tickers and Yahoo symbols to securities."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def resolve(self, identifier: str, scheme: str | None = None) -> str | None:
+        """Current symbol for any known ID (None if unknown or colliding)."""
+        if scheme is not None and scheme not in SCHEMES:
+            raise ValueError(f"scheme must be one of {', '.join(SCHEMES)}")
+        return await self._db.resolve_symbol(identifier.strip(), scheme)
+
+    async def canonical_symbols(self, symbols: Iterable[str]) -> dict[str, str]:
+        """Current symbol for each broker symbol; unknown or colliding symbols map to themselves."""
+        return {symbol: await self._db.resolve_symbol(symbol) or symbol for symbol in set(symbols) if symbol}
+
+    async def identifiers(self, symbol: str) -> dict:
+        """Current ID per scheme and the retired ones of a security.
+
+        Raises:
+            LookupError: Unknown security
+        """
+        if await self._db.get_security(symbol) is None:
+            raise LookupError(f"Security {symbol} not found")
+        rows = await self._db.get_instrument_ids(symbol=symbol)
+        current = {row["scheme"]: row["value"] for row in rows if row["retired_at"] is None}
+        current.setdefault("yahoo", default_yahoo_symbol(symbol))
+        return {
+            "symbol": symbol,
+            "ids": current,
+            "history": [row for row in rows if row["retired_at"] is not None],
+            "symbol_history": await self._db.get_symbol_history(symbol),
+        }
+
+    async def set_id(self, symbol: str, scheme: str, value: str | None) -> dict:
+        """Set (or with an empty value, clear) a security's ISIN, Yahoo or Stooq symbol.
+
+        Raises:
+            LookupError: Unknown security
+            ValueError: Scheme not settable, invalid ISIN, or a Yahoo/Stooq symbol used by another security
+        """
+        if scheme not in USER_SCHEMES:
+            raise ValueError(f"scheme must be one of {', '.join(USER_SCHEMES)}")
+        if await self._db.get_security(symbol) is None:
+            raise LookupError(f"Security {symbol} not found")
+        value = (value or "").strip() or None
+        if value and scheme == "isin":
+            value = normalize_isin(value)
+            if value is None:
+                raise ValueError("Invalid ISIN")
+        if value and scheme in ("yahoo", "stooq"):
+            rows = await self._db.get_instrument_ids(scheme=scheme, value=value)
+            taken = sorted(row["symbol"] for row in rows if row["retired_at"] is None and row["symbol"] != symbol)
+            if taken:
+                raise ValueError(f"{scheme.capitalize()} symbol {value} is already mapped to {', '.join(taken)}")
+
+        if scheme == "isin":
+            await self._db.set_security_isin(symbol, value)
+        else:
+            await self._db.set_instrument_id(symbol, scheme, value)
+        return await self.identifiers(symbol)
+
+    async def yahoo_symbols(self, symbols: Iterable[str]) -> dict[str, str]:
+        """Yahoo Finance symbol of each symbol (explicit mapping, else the symbol without .US)."""
+        mapped = await self._mapped("yahoo")
+        return {symbol: mapped.get(symbol) or default_yahoo_symbol(symbol) for symbol in symbols}
+
+    async def stooq_symbols(self, symbols: Iterable[str]) -> dict[str, str]:
+        """Stooq symbol of each symbol (explicit mapping, else the lowercased symbol)."""
+        mapped = await self._mapped("stooq")
+        return {symbol: mapped.get(symbol) or default_stooq_symbol(symbol) for symbol in symbols}
+
+    async def _mapped(self, scheme: str) -> dict[str, str]:
+        rows = await self._db.get_instrument_ids(scheme=scheme)
+        return {row["symbol"]: row["value"] for row in rows if row["retired_at"] is None}
+
+    async def collisions(self) -> list[dict]:
+        """IDs currently mapped to more than one security."""
+        return await self._db.get_instrument_id_collisions()
@@ -0,0 +1,95 @@
+"""Intraday quote snapshots of held positions and their retention.
+
+Optional (intraday_snapshots_enabled): the sync:intraday job snapshots the
+quotes of held positions while markets are open, and archive:intraday folds
+aged snapshots into hourly and then daily bars (see database/intraday.py).
+"""
+
+from __future__ import annotations
+
+import time
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.database.intraday import DAILY, HOURLY, RAW, RESOLUTIONS
+from sentinel.settings import Settings
+
+DAY_SECONDS = 86400
+
+
+class IntradayService:
+    """Takes, compacts and serves intraday price snapshots."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        broker: Broker | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._broker = broker or Broker()
+
+    async def snapshot(self, now: int | None = None) -> dict:
+        """Store the current quote of every held position.
+
+        Returns:
+            dict with enabled and the number of snapshots saved
+        """
+        if not await self._settings.get("intraday_snapshots_enabled", False):
+            return {"enabled": False, "saved": 0}
+        symbols = sorted(p["symbol"] for p in await self._db.get_all_positions() if (p.get("quantity") or 0) > 0)
+        quotes = await self._broker.get_quotes(symbols) if symbols else {}
+        snapshots = [
+            {"symbol": symbol, "price": quote.get("price"), "bid": quote.get("bid"), "ask": quote.get("ask")}
+            for symbol, quote in quotes.items()
+            if symbol in symbols and isinstance(quote, dict)
+        ]
+        saved = await self._db.save_intraday_snapshots(snapshots, int(now or time.time()))
+        return {"enabled": True, "saved": saved}
+
+    async def compact(self, now: int | None = None) -> dict:
+        """Fold raw snapshots past their retention into hourly bars, and hourly bars into daily ones.
+
+        Returns:
+            dict with the number of raw and hourly bars folded
+        """
+        now = int(now or time.time())
+        raw_days = max(1, int(await self._settings.get("intraday_raw_retention_days", 7) or 7))
+        hourly_days = max(raw_days, int(await self._settings.get("intraday_hourly_retention_days", 365) or 365))
+        raw = await self._db.downsample_intraday(RAW, HOURLY, now - raw_days * DAY_SECONDS)
+        hourly = await self._db.downsample_intraday(HOURLY, DAILY, now - hourly_days * DAY_SECONDS)
+        return {"raw": raw, "hourly": hourly}
+
+    async def history(
+        self,
+        symbol: str,
+        resolution: str | None = None,
+        days: int = 7,
+        now: int | None = None,
+    ) -> list[dict]:
+        """Bars of a symbol over the last `days`, of one resolution or all of them.
+
+        Raises:
+            ValueError: Unknown resolution
+        """
+        if resolution is not None and resolution not in RESOLUTIONS:
+            raise ValueError(f"Unknown resolution '{resolution}' (expected one of {', '.join(RESOLUTIONS)})")
+        since = int(now or time.time()) - max(1, int(days)) * DAY_SECONDS
+        return await self._db.get_intraday_prices(symbol, resolution=resolution, since=since)
+
+    async def stats(self) -> dict:
+        """Whether snapshots are enabled, retention settings and bars stored per resolution."""
+        return {
+            "enabled": bool(await self._settings.get("intraday_snapshots_enabled", False)),
+            "raw_retention_days": await self._settings.get("intraday_raw_retention_days", 7),
+            "hourly_retention_days": await self._settings.get("intraday_hourly_retention_days", 365),
+            "resolutions": await self._db.get_intraday_stats(),
+        }
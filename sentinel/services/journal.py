@@ -0,0 +1,153 @@
+"""Trade journal: the user's notes on executed trades and recommendations.
+
+An entry attaches a note, tags and a conviction rating (1-5) to a trade in the
+ledger (by trade ID) or to a current recommendation. Recommendations are
+recomputed on every planner run, so an entry keeps a copy of the
+recommendation as it was when annotated. The journal is searchable by text,
+symbol, tag, conviction and date, so the reasons behind approving or
+overriding system decisions can be found later.
+"""
+
+from __future__ import annotations
+
+from dataclasses import asdict
+
+from sentinel.database import Database
+
+KINDS = ("trade", "recommendation")
+CONVICTION_RANGE = (1, 5)
+
+
+def _clean_tags(tags) -> list[str]:
+    if tags is None:
+        return []
+    if isinstance(tags, str):
+        tags = tags.split(",")
+    if not isinstance(tags, list) or not all(isinstance(tag, str) for tag in tags):
+        raise ValueError("tags must be a list of strings")
+    return sorted({tag.strip().lower() for tag in tags if tag.strip()})
+
+
+def _clean_conviction(conviction) -> int | None:
+    if conviction is None:
+        return None
+    lo, hi = CONVICTION_RANGE
+    if isinstance(conviction, bool) or not isinstance(conviction, int) or not lo <= conviction <= hi:
+        raise ValueError(f"conviction must be an integer from {lo} to {hi}")
+    return conviction
+
+
+class JournalService:
+    """Annotates trades and recommendations and searches the annotations."""
+
+    def __init__(self, db: Database | None = None, planner=None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+        """
+        self._db = db or Database()
+        self._planner = planner
+
+    async def annotate_trade(
+        self, trade_id: int, note: str | None, tags=None, conviction=None, created_by: str = "local"
+    ) -> dict:
+        """Attach a note to an executed trade.
+
+        Raises:
+            LookupError: Unknown trade
+            ValueError: Nothing to record, or invalid tags or conviction
+        """
+        trade = await self._db.get_trade_by_id(trade_id)
+        if trade is None:
+            raise LookupError(f"Trade {trade_id} not found")
+        fields = self._fields(note, tags, conviction)
+        entry_id = await self._db.add_journal_entry(
+            "trade", trade["symbol"], trade["side"].lower(), created_by, trade_id=trade_id, **fields
+        )
+        return await self._entry(entry_id)
+
+    async def annotate_recommendation(
+        self, symbol: str, action: str, note: str | None, tags=None, conviction=None, created_by: str = "local"
+    ) -> dict:
+        """Attach a note to a current recommendation, keeping a copy of it.
+
+        Raises:
+            LookupError: No current recommendation for the symbol and action
+            ValueError: Nothing to record, or invalid tags or conviction
+        """
+        fields = self._fields(note, tags, conviction)
+        recommendation = next(
+            (rec for rec in await self._recommendations() if rec.symbol == symbol and rec.action == action), None
+        )
+        if recommendation is None:
+            raise LookupError(f"No current {action} recommendation for {symbol}")
+        entry_id = await self._db.add_journal_entry(
+            "recommendation", symbol, action, created_by, recommendation=asdict(recommendation), **fields
+        )
+        return await self._entry(entry_id)
+
+    async def update(self, entry_id: int, changes: dict) -> dict:
+        """Change the note, tags or conviction of an entry.
+
+        Raises:
+            LookupError: Unknown entry
+            ValueError: Unknown field, or invalid tags or conviction
+        """
+        unknown = sorted(set(changes) - {"note", "tags", "conviction"})
+        if unknown:
+            raise ValueError(f"Cannot change {unknown[0]} (accepted: note, tags, conviction)")
+        fields = {}
+        if "note" in changes:
+            fields["note"] = (changes["note"] or "").strip() or None
+        if "tags" in changes:
+            fields["tags"] = _clean_tags(changes["tags"])
+        if "conviction" in changes:
+            fields["conviction"] = _clean_conviction(changes["conviction"])
+        if fields:
+            await self._db.update_journal_entry(entry_id, **fields)
+        return await self._entry(entry_id)
+
+    async def delete(self, entry_id: int) -> None:
+        """Delete an entry.
+
+        Raises:
+            LookupError: Unknown entry
+        """
+        if not await self._db.delete_journal_entry(entry_id):
+            raise LookupError(f"Journal entry {entry_id} not found")
+
+    async def search(self, kind: str | None = None, tag: str | None = None, **filters) -> list[dict]:
+        """Entries matching every filter (text, symbol, tag, kind, trade_id, min_conviction, start_ts, end_ts).
+
+        Raises:
+            ValueError: Unknown kind
+        """
+        if kind is not None and kind not in KINDS:
+            raise ValueError(f"kind must be one of {', '.join(KINDS)}")
+        return await self._db.search_journal(kind=kind, tag=tag.strip().lower() if tag else None, **filters)
+
+    @staticmethod
+    def _fields(note: str | None, tags, conviction) -> dict:
+        fields = {
+            "note": (note or "").strip() or None,
+            "tags": _clean_tags(tags),
+            "conviction": _clean_conviction(conviction),
+        }
+        if fields["note"] is None and not fields["tags"] and fields["conviction"] is None:
+            raise ValueError("A note, tags or a conviction rating is required")
+        return fields
+
+    async def _recommendations(self) -> list:
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db)
+        return await self._planner.get_recommendations()
+
+    async def _entry(self, entry_id: int) -> dict:
+        entries = await self._db.search_journal(entry_id=entry_id, limit=1)
+        if not entries:
+            raise LookupError(f"Journal entry {entry_id} not found")
+        return entries[0]
@@ -0,0 +1,235 @@
+"""Liquidity planning service.
+
+Projects cash in and out over a horizon (expected dividends, recurring deposits
+inferred from cash_flows, pending trade recommendations) as a weekly ladder, and
+warns when planned buys exceed the cash that will actually be available.
+"""
+
+from __future__ import annotations
+
+import math
+from datetime import date, datetime, timedelta
+from statistics import median
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.portfolio import Portfolio
+from sentinel.utils.fees import FeeCalculator
+
+LADDER_BUCKET_DAYS = 7
+# Look-back used to infer dividend schedules and recurring deposits
+HISTORY_LOOKBACK_DAYS = 365
+MIN_RECURRING_DEPOSITS = 2
+
+
+def _parse_date(value: str) -> date:
+    return datetime.strptime(value[:10], "%Y-%m-%d").date()
+
+
+def _one_year_later(d: date) -> date:
+    try:
+        return d.replace(year=d.year + 1)
+    except ValueError:
+        # Feb 29 -> Feb 28
+        return d.replace(year=d.year + 1, day=28)
+
+
+class LiquidityService:
+    """Builds a projected cash ladder for upcoming inflows and planned trades."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        portfolio: Portfolio | None = None,
+        planner=None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            portfolio: Portfolio instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._portfolio = portfolio or Portfolio()
+        self._planner = planner
+        self._currency = currency or Currency()
+
+    async def get_ladder(self, days: int = 90, today: date | None = None) -> dict:
+        """Project cash over the next `days` days.
+
+        Args:
+            days: Projection horizon in days
+            today: Projection start date (defaults to today)
+
+        Returns:
+            dict with starting cash, projected events, weekly buckets, warnings and
+            a suggested deposit (amount/date) when the plan is underfunded
+        """
+        today = today or date.today()
+        horizon_end = today + timedelta(days=days - 1)
+
+        starting_cash = await self._portfolio.total_cash_eur()
+        events = await self._pending_trade_events(today)
+        events += await self._expected_dividend_events(today, horizon_end)
+        events += await self._expected_deposit_events(today, horizon_end)
+        events.sort(key=lambda e: (e["date"], e["type"]))
+
+        buckets = []
+        running = starting_cash
+        for i in range(math.ceil(days / LADDER_BUCKET_DAYS)):
+            start = today + timedelta(days=i * LADDER_BUCKET_DAYS)
+            end = min(start + timedelta(days=LADDER_BUCKET_DAYS - 1), horizon_end)
+            in_bucket = [e for e in events if start.isoformat() <= e["date"] <= end.isoformat()]
+            inflows = sum(e["amount_eur"] for e in in_bucket if e["amount_eur"] > 0)
+            outflows = sum(e["amount_eur"] for e in in_bucket if e["amount_eur"] < 0)
+            running += inflows + outflows
+            buckets.append(
+                {
+                    "start_date": start.isoformat(),
+                    "end_date": end.isoformat(),
+                    "inflows_eur": round(inflows, 2),
+                    "outflows_eur": round(outflows, 2),
+                    "net_eur": round(inflows + outflows, 2),
+                    "closing_cash_eur": round(running, 2),
+                }
+            )
+
+        warnings = []
+        planned_buys = -sum(e["amount_eur"] for e in events if e["type"] in ("buy", "fees"))
+        immediate_funding = starting_cash + sum(e["amount_eur"] for e in events if e["type"] == "sell")
+        if planned_buys > immediate_funding:
+            warnings.append(
+                {
+                    "code": "planned_buys_exceed_cash",
+                    "date": today.isoformat(),
+                    "shortfall_eur": round(planned_buys - immediate_funding, 2),
+                    "message": (
+                        f"Planned buys (EUR {planned_buys:.0f} incl. fees) exceed available cash "
+                        f"(EUR {immediate_funding:.0f} incl. pending sells)"
+                    ),
+                }
+            )
+
+        lowest = min(buckets, key=lambda b: b["closing_cash_eur"]) if buckets else None
+        suggested_deposit = None
+        if lowest and lowest["closing_cash_eur"] < 0:
+            first_negative = next(b for b in buckets if b["closing_cash_eur"] < 0)
+            warnings.append(
+                {
+                    "code": "projected_cash_negative",
+                    "date": first_negative["start_date"],
+                    "shortfall_eur": round(-lowest["closing_cash_eur"], 2),
+                    "message": f"Projected cash turns negative in the week of {first_negative['start_date']}",
+                }
+            )
+            suggested_deposit = {
+                "amount_eur": round(-lowest["closing_cash_eur"], 2),
+                "by_date": first_negative["start_date"],
+            }
+
+        return {
+            "start_date": today.isoformat(),
+            "end_date": horizon_end.isoformat(),
+            "starting_cash_eur": round(starting_cash, 2),
+            "ending_cash_eur": round(running, 2),
+            "events": events,
+            "buckets": buckets,
+            "warnings": warnings,
+            "suggested_deposit": suggested_deposit,
+        }
+
+    async def _pending_trade_events(self, today: date) -> list[dict]:
+        """Pending recommendations settle today: sells add cash, buys and fees consume it."""
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db, portfolio=self._portfolio)
+
+        recommendations = await self._planner.get_recommendations()
+        events = []
+        trades = []
+        for rec in recommendations:
+            value = abs(rec.value_delta_eur)
+            trades.append({"action": rec.action, "value_eur": value})
+            events.append(
+                {
+                    "date": today.isoformat(),
+                    "type": rec.action,
+                    "symbol": rec.symbol,
+                    "amount_eur": round(value if rec.action == "sell" else -value, 2),
+                    "description": f"Pending {rec.action} {rec.quantity} x {rec.symbol}",
+                }
+            )
+        if trades:
+            fees = await FeeCalculator().calculate_batch(trades)
+            events.append(
+                {
+                    "date": today.isoformat(),
+                    "type": "fees",
+                    "symbol": None,
+                    "amount_eur": round(-fees["total_fees"], 2),
+                    "description": "Transaction fees for pending trades",
+                }
+            )
+        return events
+
+    async def _expected_dividend_events(self, today: date, horizon_end: date) -> list[dict]:
+        """Project last year's dividends of currently held securities one year forward."""
+        positions = await self._db.get_all_positions()
+        held = {p["symbol"] for p in positions if (p.get("quantity") or 0) > 0}
+        if not held:
+            return []
+
+        since = (today - timedelta(days=HISTORY_LOOKBACK_DAYS)).isoformat()
+        events = []
+        for dividend in await self._db.get_dividends(start_date=since):
+            if dividend["symbol"] not in held:
+                continue
+            expected = _one_year_later(_parse_date(dividend["date"]))
+            if today <= expected <= horizon_end:
+                events.append(
+                    {
+                        "date": expected.isoformat(),
+                        "type": "dividend",
+                        "symbol": dividend["symbol"],
+                        "amount_eur": round(float(dividend["value"]), 2),
+                        "description": f"Expected dividend from {dividend['symbol']}",
+                    }
+                )
+        return events
+
+    async def _expected_deposit_events(self, today: date, horizon_end: date) -> list[dict]:
+        """Extrapolate recurring deposits using the median interval and amount of recent deposits."""
+        since = (today - timedelta(days=HISTORY_LOOKBACK_DAYS)).isoformat()
+        deposits = sorted(await self._db.get_cash_flows(type_id="card", start_date=since), key=lambda cf: cf["date"])
+        if len(deposits) < MIN_RECURRING_DEPOSITS:
+            return []
+
+        dates = [_parse_date(cf["date"]) for cf in deposits]
+        intervals = [(b - a).days for a, b in zip(dates, dates[1:], strict=False) if (b - a).days > 0]
+        if not intervals:
+            return []
+        interval = int(median(intervals))
+        amounts = [await self._currency.to_eur_for_date(cf["amount"], cf["currency"], cf["date"]) for cf in deposits]
+        amount = float(median(amounts))
+        if interval <= 0 or amount <= 0:
+            return []
+
+        events = []
+        next_date = dates[-1] + timedelta(days=interval)
+        while next_date <= horizon_end:
+            if next_date >= today:
+                events.append(
+                    {
+                        "date": next_date.isoformat(),
+                        "type": "deposit",
+                        "symbol": None,
+                        "amount_eur": round(amount, 2),
+                        "description": f"Expected recurring deposit (every {interval} days)",
+                    }
+                )
+            next_date += timedelta(days=interval)
+        return events
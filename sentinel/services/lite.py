@@ -0,0 +1,120 @@
+"""Compact read models for low-bandwidth clients.
+
+Each screen is a small, pre-aggregated JSON object (a few hundred bytes) meant
+for a phone widget or an e-paper companion device polling over a mobile
+connection. Values are rounded, lists are capped and keys are short:
+
+- summary: value/cash/day/day_pct/ret_pct in EUR and percent, n positions,
+  mode (trading mode), ts (unix time)
+- alerts: unread count and the latest unread items as {s: severity initial,
+  t: title, at: unix time}
+- pending: recommendations awaiting execution as {a: B/S, sym, qty, eur}
+"""
+
+from __future__ import annotations
+
+import time
+from datetime import datetime, timezone
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.portfolio import Portfolio
+from sentinel.services.reports import snapshot_value
+from sentinel.settings import Settings
+
+# Items per list screen
+LITE_ITEMS = 5
+# Titles are cut to fit a small display
+TITLE_CHARS = 48
+
+
+def _short(text: str) -> str:
+    return text if len(text) <= TITLE_CHARS else text[: TITLE_CHARS - 1] + "…"
+
+
+class LiteService:
+    """Builds the compact summary, alerts and pending screens."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        portfolio: Portfolio | None = None,
+        planner=None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            portfolio: Portfolio instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+        self._portfolio = portfolio or Portfolio(db=self._db, currency=self._currency)
+        self._planner = planner
+
+    async def summary(self, now: int | None = None) -> dict:
+        """Portfolio value, cash, change since the last snapshot before today, and return on cost."""
+        now = now or int(time.time())
+        value = await self._portfolio.total_value()
+        cash = await self._portfolio.total_cash_eur()
+        positions = await self._db.get_all_positions()
+
+        midnight = int(datetime.fromtimestamp(now, tz=timezone.utc).replace(hour=0, minute=0, second=0).timestamp())
+        previous = [s for s in await self._db.get_portfolio_snapshots(days=7) if s["date"] < midnight]
+        day = value - snapshot_value(previous[-1]) if previous else None
+
+        invested = 0.0
+        for pos in positions:
+            invested += await self._currency.to_eur(
+                (pos.get("avg_cost") or 0) * (pos.get("quantity") or 0), pos.get("currency") or "EUR"
+            )
+        return {
+            "value": round(value),
+            "cash": round(cash),
+            "day": round(day) if day is not None else None,
+            "day_pct": round(day / (value - day) * 100, 2) if day is not None and value - day else None,
+            "ret_pct": round(((value - cash) / invested - 1) * 100, 1) if invested else None,
+            "n": len(positions),
+            "mode": await self._settings.get("trading_mode", "research"),
+            "ts": now,
+        }
+
+    async def alerts(self, limit: int = LITE_ITEMS) -> dict:
+        """Unread notification count and the most recent unread items."""
+        notifications = await self._db.get_notifications(unread_only=True, limit=limit)
+        return {
+            "unread": await self._db.get_unread_notification_count(),
+            "items": [
+                {"s": n["severity"][0], "t": _short(n["title"]), "at": n["updated_at"]} for n in notifications
+            ],
+        }
+
+    async def pending(self, limit: int = LITE_ITEMS) -> dict:
+        """Trade recommendations waiting to be executed, highest priority first."""
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db, currency=self._currency)
+        recommendations = await self._planner.get_recommendations()
+        return {
+            "count": len(recommendations),
+            "items": [
+                {
+                    "a": "B" if rec.action == "buy" else "S",
+                    "sym": rec.symbol,
+                    "qty": round(rec.quantity, 4),
+                    "eur": round(abs(rec.value_delta_eur)),
+                }
+                for rec in recommendations[:limit]
+            ],
+        }
+
+    async def screen(self) -> dict:
+        """All three screens in one response, for clients that poll a single URL."""
+        return {"summary": await self.summary(), "alerts": await self.alerts(), "pending": await self.pending()}
@@ -0,0 +1,130 @@
+"""In-process log buffer for remote debugging.
+
+A logging handler on the root logger keeps the most recent LOG_BUFFER_SIZE
+records as structured entries and fans new ones out to live subscribers (the
+/api/logs/stream endpoint). Entries can be filtered by minimum level and by
+logger name prefix ("sentinel.jobs" matches "sentinel.jobs.tasks"), and by
+correlation ID to follow one request or job run.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import itertools
+import logging
+from collections import deque
+
+from sentinel.correlation import current
+
+LOG_BUFFER_SIZE = 1000
+# Entries queued per live subscriber before the oldest are dropped
+SUBSCRIBER_QUEUE_SIZE = 500
+LOG_LEVELS = ("DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL")
+
+
+def level_number(level: str | None) -> int:
+    """Numeric value of a level name (DEBUG if empty).
+
+    Raises:
+        ValueError: Unknown level name
+    """
+    if not level:
+        return logging.DEBUG
+    name = level.upper()
+    if name not in LOG_LEVELS:
+        raise ValueError(f"Unknown log level: {level}")
+    return logging.getLevelName(name)
+
+
+def matches(
+    entry: dict,
+    min_level: int = logging.DEBUG,
+    module: str | None = None,
+    correlation_id: str | None = None,
+) -> bool:
+    """Whether an entry passes the level, module and correlation ID filters."""
+    if entry["levelno"] < min_level:
+        return False
+    if module and entry["module"] != module and not entry["module"].startswith(module + "."):
+        return False
+    if correlation_id and entry["correlation_id"] != correlation_id:
+        return False
+    return True
+
+
+class LogBuffer(logging.Handler):
+    """Ring buffer of recent log records with live subscribers."""
+
+    def __init__(self, size: int = LOG_BUFFER_SIZE):
+        super().__init__(level=logging.DEBUG)
+        self._entries: deque[dict] = deque(maxlen=size)
+        self._seq = itertools.count(1)
+        self._subscribers: set[tuple[asyncio.AbstractEventLoop, asyncio.Queue]] = set()
+
+    def emit(self, record: logging.LogRecord) -> None:
+        try:
+            message = record.getMessage()
+            if record.exc_info:
+                message += "\n" + logging.Formatter().formatException(record.exc_info)
+            entry = {
+                "id": next(self._seq),
+                "ts": record.created,
+                "level": record.levelname,
+                "levelno": record.levelno,
+                "module": record.name,
+                # Handlers run in the logging call's context, so this is the record's request or job
+                "correlation_id": current(),
+                "message": message,
+            }
+        except Exception:
+            self.handleError(record)
+            return
+        self._entries.append(entry)
+        # Records may come from worker threads; queues belong to the event loop
+        for loop, queue in list(self._subscribers):
+            try:
+                loop.call_soon_threadsafe(self._deliver, queue, entry)
+            except RuntimeError:
+                # Loop closed
+                self._subscribers.discard((loop, queue))
+
+    @staticmethod
+    def _deliver(queue: asyncio.Queue, entry: dict) -> None:
+        if queue.full():
+            queue.get_nowait()
+        queue.put_nowait(entry)
+
+    def recent(
+        self,
+        min_level: int = logging.DEBUG,
+        module: str | None = None,
+        limit: int = 100,
+        after: int = 0,
+        correlation_id: str | None = None,
+    ) -> list[dict]:
+        """Most recent matching entries (oldest first), optionally only those after an id."""
+        entries = [
+            e for e in list(self._entries) if e["id"] > after and matches(e, min_level, module, correlation_id)
+        ]
+        return entries[-limit:] if limit > 0 else []
+
+    def subscribe(self) -> asyncio.Queue:
+        """Queue receiving every new entry until unsubscribe()."""
+        queue: asyncio.Queue = asyncio.Queue(maxsize=SUBSCRIBER_QUEUE_SIZE)
+        self._subscribers.add((asyncio.get_running_loop(), queue))
+        return queue
+
+    def unsubscribe(self, queue: asyncio.Queue) -> None:
+        self._subscribers = {(loop, q) for loop, q in self._subscribers if q is not queue}
+
+
+_buffer: LogBuffer | None = None
+
+
+def get_log_buffer() -> LogBuffer:
+    """The process-wide buffer, attached to the root logger on first use."""
+    global _buffer
+    if _buffer is None:
+        _buffer = LogBuffer()
+        logging.getLogger().addHandler(_buffer)
+    return _buffer
@@ -0,0 +1,316 @@
+"""Report emails over SMTP.
+
+The report:email job mails two reports to report_email_recipients:
+
+- the weekly performance report, once per ISO week, covering the previous
+  Monday-to-Monday (the stored weekly digest for that week is reused, or
+  generated without pushing it to the inbox)
+- the monthly reconciliation summary, once the reconciliation run of the
+  previous month exists (the reconcile:broker job produces it)
+
+Every attempt is recorded in email_deliveries. A report of a week or month is
+sent once; a failed attempt is retried on the next run and raises a warning in
+the notification inbox.
+
+The server is configured with the smtp_* settings: smtp_security is starttls
+(upgrade a plain connection, usually port 587), ssl (implicit TLS, usually
+port 465) or none. With smtp_username set, the client logs in with the
+smtp_password secret from the vault.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import html
+import logging
+import smtplib
+import ssl
+import time
+from datetime import date, datetime, timedelta, timezone
+from email.message import EmailMessage
+from email.utils import formatdate, make_msgid
+from typing import Awaitable, Callable
+
+from sentinel.database import Database
+from sentinel.dry_run import is_dry_run, record_side_effect
+from sentinel.errors import InvalidInput, NotConfigured
+from sentinel.services.reconciliation import previous_period
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+SECURITY_MODES = ("starttls", "ssl", "none")
+KINDS = ("weekly_report", "monthly_reconciliation", "test")
+
+# Sends one message: (smtp config, message) -> None, raising on failure
+Mailer = Callable[[dict, EmailMessage], Awaitable[None]]
+
+
+def build_message(
+    sender: str, recipients: list[str], subject: str, text: str, html_body: str | None = None
+) -> EmailMessage:
+    """Plain-text message, with an HTML alternative when given."""
+    message = EmailMessage()
+    message["From"] = sender
+    message["To"] = ", ".join(recipients)
+    message["Subject"] = subject
+    message["Date"] = formatdate(localtime=False, usegmt=True)
+    message["Message-ID"] = make_msgid(domain=sender.rpartition("@")[2] or None)
+    message.set_content(text)
+    if html_body:
+        message.add_alternative(html_body, subtype="html")
+    return message
+
+
+def _smtp_send(config: dict, message: EmailMessage) -> None:
+    context = ssl.create_default_context()
+    timeout = config["timeout"]
+    if config["security"] == "ssl":
+        client = smtplib.SMTP_SSL(config["host"], config["port"], timeout=timeout, context=context)
+    else:
+        client = smtplib.SMTP(config["host"], config["port"], timeout=timeout)
+    with client:
+        if config["security"] == "starttls":
+            client.starttls(context=context)
+        if config["username"]:
+            client.login(config["username"], config["password"])
+        client.send_message(message)
+
+
+async def smtp_send(config: dict, message: EmailMessage) -> None:
+    """Send a message with smtplib (in a worker thread)."""
+    await asyncio.to_thread(_smtp_send, config, message)
+
+
+def week_bounds(now: int) -> tuple[str, int]:
+    """(ISO week, end) of the last full week before `now`; the week ends Monday 00:00 UTC."""
+    today = datetime.fromtimestamp(now, tz=timezone.utc).date()
+    monday = today - timedelta(days=today.weekday())
+    year, week, _ = (monday - timedelta(days=7)).isocalendar()
+    end = int(datetime(monday.year, monday.month, monday.day, tzinfo=timezone.utc).timestamp())
+    return f"{year}-W{week:02d}", end
+
+
+def reconciliation_sections(reconciliation: dict) -> list[tuple[str, list[str]]]:
+    """(heading, lines) of a reconciliation summary, shared by both renderings."""
+    summary = reconciliation["summary"]
+    counts = [
+        f"{section.replace('_', ' ').capitalize()}: {summary[section]['broker']} in the broker report, "
+        f"{summary[section]['ledger']} in the ledger"
+        for section in ("trades", "cash_flows", "dividends")
+        if section in summary
+    ]
+    if summary.get("adjustments"):
+        counts.append(f"Manual position adjustments: {summary['adjustments']}")
+    severity = summary.get("severity") or {}
+    discrepancies = [f"[{d['severity']}] {d['message']}" for d in reconciliation["discrepancies"]]
+    status = [f"Status: {reconciliation['status'].replace('_', ' ')}"]
+    if reconciliation.get("signed_off_by"):
+        note = f": {reconciliation['note']}" if reconciliation.get("note") else ""
+        status.append(f"Signed off by {reconciliation['signed_off_by']}{note}")
+    return [
+        ("Status", status),
+        ("Compared", counts),
+        (
+            "Discrepancies (" + (", ".join(f"{n} {s}" for s, n in severity.items() if n) or "none") + ")",
+            discrepancies,
+        ),
+    ]
+
+
+def render_reconciliation(reconciliation: dict) -> tuple[str, str, str]:
+    """(subject, text, html) of a reconciliation summary email."""
+    subject = f"Broker reconciliation {reconciliation['period']}: {reconciliation['status'].replace('_', ' ')}"
+    lines = [subject]
+    parts = [f"<!DOCTYPE html><html><body><h1>{html.escape(subject)}</h1>"]
+    for heading, items in reconciliation_sections(reconciliation):
+        lines += ["", heading, ""]
+        lines += [f"- {item}" for item in items] or ["None"]
+        parts.append(f"<h2>{html.escape(heading)}</h2>")
+        if items:
+            parts.append("<ul>" + "".join(f"<li>{html.escape(item)}</li>" for item in items) + "</ul>")
+        else:
+            parts.append("<p>None</p>")
+    parts.append("</body></html>")
+    return subject, "\n".join(lines) + "\n", "\n".join(parts)
+
+
+class MailService:
+    """Sends report emails over SMTP and schedules the recurring ones."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        mailer: Mailer | None = None,
+        planner=None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            mailer: Send function (uses smtplib if None)
+            planner: Planner instance for generating weekly reports (created on first use if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._send = mailer or smtp_send
+        self._planner = planner
+
+    async def config(self) -> dict:
+        """SMTP settings with the password from the vault.
+
+        Raises:
+            NotConfigured: If no server or sender address is configured
+            InvalidInput: If smtp_security is not a known mode
+        """
+        from sentinel.vault import Vault
+
+        host = (await self._settings.get("smtp_host", "") or "").strip()
+        sender = (await self._settings.get("smtp_from", "") or "").strip()
+        if not host or not sender:
+            raise NotConfigured("SMTP is not configured (set smtp_host and smtp_from)")
+        security = await self._settings.get("smtp_security", "starttls")
+        if security not in SECURITY_MODES:
+            raise InvalidInput(f"smtp_security must be one of {', '.join(SECURITY_MODES)}")
+        username = (await self._settings.get("smtp_username", "") or "").strip()
+        password = await Vault(db=self._db, settings=self._settings).get_secret("smtp_password") if username else ""
+        return {
+            "host": host,
+            "port": int(await self._settings.get("smtp_port", 587)),
+            "security": security,
+            "username": username,
+            "password": password,
+            "sender": sender,
+            "timeout": float(await self._settings.get("smtp_timeout_seconds", 30)),
+        }
+
+    async def recipients(self, to: list[str] | None = None) -> list[str]:
+        """Given addresses, or report_email_recipients.
+
+        Raises:
+            NotConfigured: If there are none
+            InvalidInput: If one is not an email address
+        """
+        addresses = to if to is not None else await self._settings.get("report_email_recipients", []) or []
+        if isinstance(addresses, str):
+            addresses = [addresses]
+        addresses = [str(a).strip() for a in addresses if str(a).strip()]
+        if not addresses:
+            raise NotConfigured("No recipients (set report_email_recipients)")
+        invalid = next((a for a in addresses if "@" not in a or any(c.isspace() for c in a)), None)
+        if invalid:
+            raise InvalidInput(f"Not an email address: {invalid}")
+        return addresses
+
+    async def send(
+        self,
+        kind: str,
+        period: str,
+        subject: str,
+        text: str,
+        html_body: str | None = None,
+        to: list[str] | None = None,
+    ) -> dict:
+        """Send one email and record the attempt. Returns the recorded delivery.
+
+        In a dry run nothing is sent: the email is recorded as a side effect.
+
+        Raises:
+            NotConfigured: SMTP or recipients not configured
+        """
+        config = await self.config()
+        recipients = await self.recipients(to)
+        message = build_message(config["sender"], recipients, subject, text, html_body)
+        error = None
+        try:
+            if is_dry_run():
+                record_side_effect("email", email=kind, subject=subject, recipients=recipients)
+            else:
+                await self._send(config, message)
+        except (smtplib.SMTPException, OSError) as e:
+            error = f"{type(e).__name__}: {e}"
+            logger.warning(f"Failed to email {kind} {period}: {error}")
+        status = "failed" if error else "sent"
+        delivery_id = await self._db.add_email_delivery(kind, period, subject, recipients, status, error)
+        return {
+            "id": delivery_id,
+            "kind": kind,
+            "period": period,
+            "subject": subject,
+            "recipients": recipients,
+            "status": status,
+            "error": error,
+        }
+
+    async def send_test(self, to: list[str] | None = None) -> dict:
+        """Send a test email now (to the given addresses or the report recipients).
+
+        Raises:
+            NotConfigured: SMTP or recipients not configured
+        """
+        config = await self.config()
+        text = (
+            f"Sentinel can send email through {config['host']}:{config['port']} ({config['security']}).\n"
+            "Weekly reports and monthly reconciliation summaries will arrive at this address.\n"
+        )
+        period = datetime.now(timezone.utc).strftime("%Y-%m-%d")
+        return await self.send("test", period, "Sentinel test email", text, to=to)
+
+    async def send_due(self, now: int | None = None) -> list[dict]:
+        """Email the weekly report and the monthly reconciliation summary that have not been sent yet.
+
+        Returns:
+            The deliveries attempted (empty when nothing was due)
+
+        Raises:
+            NotConfigured: SMTP or recipients not configured
+        """
+        now = now or int(time.time())
+        deliveries = []
+        if await self._settings.get("report_email_weekly", True):
+            delivery = await self._send_weekly_report(now)
+            if delivery:
+                deliveries.append(delivery)
+        if await self._settings.get("report_email_reconciliation", True):
+            delivery = await self._send_reconciliation(datetime.fromtimestamp(now, tz=timezone.utc).date())
+            if delivery:
+                deliveries.append(delivery)
+        failed = [d for d in deliveries if d["status"] == "failed"]
+        if failed:
+            from sentinel.services.notifications import record_notification
+
+            await record_notification(
+                self._db,
+                "warning",
+                "report",
+                f"Report email failed: {failed[0]['subject']}",
+                message=failed[0]["error"],
+                link="/api/notifications/email/deliveries",
+                dedupe_key="email:failed",
+            )
+        return deliveries
+
+    async def _send_weekly_report(self, now: int) -> dict | None:
+        from sentinel.services.reports import ReportService
+
+        week, end = week_bounds(now)
+        if await self._db.email_sent("weekly_report", week):
+            return None
+        await self.config()
+        report = await self._db.get_report(period="weekly")
+        if report is None or report["period_end"] != end:
+            service = ReportService(db=self._db, settings=self._settings, planner=self._planner)
+            report = await service.generate("weekly", notify=False, now=end)
+        subject = f"Sentinel weekly report {week}"
+        return await self.send("weekly_report", week, subject, report["markdown"], report["html"])
+
+    async def _send_reconciliation(self, today: date) -> dict | None:
+        period = previous_period(today)
+        runs = await self._db.get_reconciliations(period=period, limit=1)
+        if not runs or await self._db.email_sent("monthly_reconciliation", period):
+            return None
+        reconciliation = await self._db.get_reconciliation(runs[0]["id"])
+        subject, text, html_body = render_reconciliation(reconciliation)
+        return await self.send("monthly_reconciliation", period, subject, text, html_body)
@@ -0,0 +1,333 @@
+"""Market data providers for price history, with failover.
+
+With price_history_source set to market_data, the sync:prices job downloads
+split/dividend-adjusted daily bars through the providers named in
+market_data_providers, in that order:
+
+- yahoo: the Yahoo Finance chart API (sentinel.services.yahoo_history)
+- stooq: Stooq's daily CSV download (adjusted for splits and dividends)
+
+A security listed in market_data_symbol_providers tries its own providers
+first, then the remaining ones. The first provider returning bars whose last
+day is at most market_data_stale_days old wins; missing data, errors and stale
+bars fail over to the next provider. When every provider is stale, the
+freshest bars are kept. A provider that throttles (HTTP 429) is skipped for
+the rest of the run, so one rate-limited source no longer stalls the cycle.
+
+Progress is kept per symbol in price_downloads, with the provider the bars
+came from. A download that is cancelled or interrupted leaves its remaining
+symbols pending, and the next full run resumes with those instead of starting
+over.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import csv
+import logging
+from datetime import date, timedelta
+from typing import Any, Awaitable, Callable, Protocol
+
+from sentinel.database import Database
+from sentinel.errors import InvalidInput, NotFound, RateLimited, UpstreamError, http_error
+from sentinel.jobs import manual
+from sentinel.services.instrument_ids import InstrumentIdService, default_stooq_symbol, default_yahoo_symbol
+from sentinel.services.yahoo_history import YahooHistoryClient
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+PROVIDERS = ("yahoo", "stooq")
+STOOQ_CSV_URL = "https://stooq.com/q/d/l/?s={ticker}&d1={start}&d2={end}&i=d"
+REQUEST_TIMEOUT = 30.0
+
+# Fetches a text document: url -> body
+TextFetcher = Callable[[str], Awaitable[str]]
+
+
+async def http_get_text(url: str) -> str:
+    """Default text fetcher (httpx).
+
+    Raises:
+        Unavailable: Stooq unreachable or failing (RateLimited when throttling)
+        UpstreamError: Stooq rejected the request
+    """
+    import httpx
+
+    async with httpx.AsyncClient(timeout=REQUEST_TIMEOUT, headers={"User-Agent": "Mozilla/5.0"}) as client:
+        try:
+            response = await client.get(url)
+            response.raise_for_status()
+        except httpx.HTTPError as e:
+            raise http_error(e, "Stooq") from e
+        return response.text
+
+
+def parse_provider_names(value: Any) -> list[str]:
+    """Validate a list of provider names.
+
+    Raises:
+        InvalidInput: If it is not a list of known providers
+    """
+    if not isinstance(value, list):
+        raise InvalidInput("must be a list of providers")
+    unknown = [name for name in value if name not in PROVIDERS]
+    if unknown:
+        raise InvalidInput(f"unknown provider {unknown[0]} (known: {', '.join(PROVIDERS)})")
+    return value
+
+
+def parse_symbol_providers(value: Any) -> dict[str, list[str]]:
+    """Validate per-symbol provider preferences (symbol -> provider names).
+
+    Raises:
+        InvalidInput: If a value is not a list of known providers
+    """
+    if not isinstance(value, dict):
+        raise InvalidInput("must map symbols to lists of providers")
+    for symbol, names in value.items():
+        try:
+            parse_provider_names(names)
+        except ValueError as e:
+            raise InvalidInput(f"{symbol}: {e}", symbol=symbol) from e
+    return value
+
+
+def parse_stooq_csv(text: str) -> list[dict]:
+    """Daily bars of a Stooq CSV download, oldest first.
+
+    Raises:
+        UpstreamError: If the body is not a price CSV (Stooq answers "No data" for unknown symbols)
+    """
+    lines = text.strip().splitlines()
+    if not lines or not lines[0].lower().startswith("date,"):
+        raise UpstreamError(f"Stooq returned no prices: {text.strip()[:80] or 'empty response'}")
+    bars = []
+    for row in csv.DictReader(lines):
+        if not row.get("Close"):
+            continue
+        bars.append(
+            {
+                "date": row["Date"],
+                "open": float(row["Open"]) if row.get("Open") else None,
+                "high": float(row["High"]) if row.get("High") else None,
+                "low": float(row["Low"]) if row.get("Low") else None,
+                "close": float(row["Close"]),
+                "volume": int(float(row["Volume"])) if row.get("Volume") else 0,
+            }
+        )
+    return sorted(bars, key=lambda bar: bar["date"])
+
+
+class MarketDataProvider(Protocol):
+    """A source of daily price history."""
+
+    name: str
+
+    async def history(self, symbol: str, start_date: str, end_date: str) -> list[dict]:
+        """Adjusted daily bars of a security from start_date to end_date, oldest first."""
+        ...
+
+
+class YahooProvider:
+    """Price history from the Yahoo Finance chart API."""
+
+    name = "yahoo"
+
+    def __init__(self, client: YahooHistoryClient | None = None, symbols: dict[str, str] | None = None):
+        self._client = client or YahooHistoryClient()
+        self._symbols = symbols or {}  # symbol -> Yahoo symbol
+
+    async def history(self, symbol: str, start_date: str, end_date: str) -> list[dict]:
+        ticker = self._symbols.get(symbol) or default_yahoo_symbol(symbol)
+        return await self._client.history(ticker, start_date, end_date)
+
+
+class StooqProvider:
+    """Price history from Stooq's CSV download."""
+
+    name = "stooq"
+
+    def __init__(
+        self,
+        fetcher: TextFetcher | None = None,
+        symbols: dict[str, str] | None = None,
+        concurrency: int = 4,
+    ):
+        self._fetch = fetcher or http_get_text
+        self._symbols = symbols or {}  # symbol -> Stooq symbol
+        self._semaphore = asyncio.Semaphore(max(int(concurrency), 1))
+
+    async def history(self, symbol: str, start_date: str, end_date: str) -> list[dict]:
+        ticker = self._symbols.get(symbol) or default_stooq_symbol(symbol)
+        url = STOOQ_CSV_URL.format(ticker=ticker, start=start_date.replace("-", ""), end=end_date.replace("-", ""))
+        async with self._semaphore:
+            return parse_stooq_csv(await self._fetch(url))
+
+
+class ProviderChain:
+    """Asks providers in preference order until one has fresh bars."""
+
+    def __init__(
+        self,
+        providers: list[MarketDataProvider],
+        preferences: dict[str, list[str]] | None = None,
+        stale_days: int = 5,
+    ):
+        self._providers = {provider.name: provider for provider in providers}
+        self._preferences = preferences or {}
+        self._stale_days = stale_days
+        # Providers that throttled during this run
+        self.throttled: set[str] = set()
+
+    def order(self, symbol: str) -> list[str]:
+        """Provider names to try for a symbol: its preferred ones, then the rest."""
+        preferred = [name for name in self._preferences.get(symbol) or [] if name in self._providers]
+        return preferred + [name for name in self._providers if name not in preferred]
+
+    async def history(self, symbol: str, start_date: str, end_date: str) -> dict:
+        """Bars of a symbol from the first provider with fresh data.
+
+        Returns:
+            {"provider", "bars", "stale", "errors": {provider: why it was skipped}}
+
+        Raises:
+            NotFound: If no provider returned any bars
+        """
+        cutoff = (date.fromisoformat(end_date) - timedelta(days=self._stale_days)).isoformat()
+        errors: dict[str, str] = {}
+        freshest = None
+        for name in self.order(symbol):
+            if name in self.throttled:
+                errors[name] = "throttled"
+                continue
+            try:
+                bars = await self._providers[name].history(symbol, start_date, end_date)
+            except Exception as e:
+                if isinstance(e, RateLimited) and name not in self.throttled:
+                    self.throttled.add(name)
+                    logger.warning(f"Market data provider {name} is throttling, skipping it for the rest of the run")
+                errors[name] = f"{type(e).__name__}: {e}"
+                continue
+            if not bars:
+                errors[name] = "no data"
+                continue
+            if bars[-1]["date"] >= cutoff:
+                return {"provider": name, "bars": bars, "stale": False, "errors": errors}
+            errors[name] = f"stale (last bar {bars[-1]['date']})"
+            if freshest is None or bars[-1]["date"] > freshest["bars"][-1]["date"]:
+                freshest = {"provider": name, "bars": bars, "stale": True}
+        if freshest is not None:
+            return {**freshest, "errors": errors}
+        raise NotFound(
+            "; ".join(f"{name}: {error}" for name, error in errors.items()) or "no providers", symbol=symbol
+        )
+
+
+class MarketDataService:
+    """Backfills the prices table through the market data providers with resumable progress."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        providers: list[MarketDataProvider] | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            providers: Providers in priority order (built from market_data_providers if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._providers = providers
+
+    async def providers(self, symbols: list[str]) -> list[MarketDataProvider]:
+        """The configured providers, with the symbol mappings of the given securities."""
+        if self._providers is not None:
+            return self._providers
+        concurrency = int(await self._settings.get("market_data_concurrency", 4))
+        ids = InstrumentIdService(self._db)
+        providers: list[MarketDataProvider] = []
+        for name in await self._settings.get("market_data_providers", list(PROVIDERS)) or []:
+            if name == "yahoo":
+                client = YahooHistoryClient(concurrency=concurrency)
+                providers.append(YahooProvider(client, await ids.yahoo_symbols(symbols)))
+            elif name == "stooq":
+                providers.append(StooqProvider(symbols=await ids.stooq_symbols(symbols), concurrency=concurrency))
+            else:
+                logger.warning(f"Unknown market data provider '{name}', skipping")
+        return providers
+
+    async def backfill(self, symbols: list[str] | None = None, years: int = 20, today: date | None = None) -> dict:
+        """Download and store adjusted history.
+
+        Without symbols, symbols left pending by an interrupted download are
+        resumed (with the range they were queued with); when none are pending,
+        every active security is downloaded.
+
+        Returns:
+            {"requested", "done", "stale", "failed", "pending", "resumed", "by_provider", "errors"}
+        """
+        today = today or date.today()
+        resumed = False
+        if symbols is None:
+            pending = await self._db.get_price_downloads(status="pending")
+            if pending:
+                queue = [(row["symbol"], row["start_date"]) for row in pending]
+                resumed = True
+                logger.info(f"Resuming price history download: {len(queue)} symbols pending")
+            else:
+                symbols = [s["symbol"] for s in await self._db.get_all_securities(active_only=True)]
+        if symbols is not None:
+            start = (today - timedelta(days=years * 365)).isoformat()
+            queue = [(symbol, start) for symbol in dict.fromkeys(symbols)]
+            await self._db.start_price_downloads([symbol for symbol, _ in queue], start)
+
+        chain = ProviderChain(
+            await self.providers([symbol for symbol, _ in queue]),
+            await self._settings.get("market_data_symbol_providers", {}) or {},
+            int(await self._settings.get("market_data_stale_days", 5)),
+        )
+        result = {
+            "requested": len(queue),
+            "done": 0,
+            "stale": 0,
+            "failed": 0,
+            "pending": 0,
+            "resumed": resumed,
+            "by_provider": {},
+            "errors": {},
+        }
+        chunk_size = int(await self._settings.get("market_data_concurrency", 4)) * 4
+        for offset in range(0, len(queue), chunk_size):
+            if manual.cancel_requested():
+                result["pending"] = len(queue) - offset
+                logger.info(f"Price history download cancelled, {result['pending']} symbols left pending")
+                break
+            chunk = queue[offset : offset + chunk_size]
+            downloads = await asyncio.gather(
+                *(chain.history(symbol, start, today.isoformat()) for symbol, start in chunk), return_exceptions=True
+            )
+            for (symbol, _), download in zip(chunk, downloads, strict=True):
+                await self._store(symbol, download, result)
+            manual.report_progress(offset + len(chunk), len(queue), chunk[-1][0])
+        return result
+
+    async def _store(self, symbol: str, download: dict | BaseException, result: dict) -> None:
+        if isinstance(download, BaseException):
+            error = str(download) if isinstance(download, LookupError) else f"{type(download).__name__}: {download}"
+            logger.warning(f"No price history for {symbol}: {error}")
+            await self._db.finish_price_download(symbol, "failed", error=error)
+            result["failed"] += 1
+            result["errors"][symbol] = error
+            return
+        bars, provider = download["bars"], download["provider"]
+        await self._db.save_prices(symbol, bars)
+        skipped = "; ".join(f"{name}: {error}" for name, error in download["errors"].items()) or None
+        await self._db.finish_price_download(symbol, "done", provider, len(bars), bars[-1]["date"], skipped)
+        result["done"] += 1
+        result["stale"] += download["stale"]
+        result["by_provider"][provider] = result["by_provider"].get(provider, 0) + 1
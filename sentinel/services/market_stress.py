@@ -0,0 +1,76 @@
+"""Market-stress level and the buy limits it puts in effect.
+
+Reports what the planner applies on its next run (see
+sentinel.planner.market_stress): the exposure-weighted stress score of the
+stored regimes, the resulting level, the weight of each region, and the base
+and effective buy limits.
+"""
+
+from __future__ import annotations
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.planner.market_stress import NORMAL, buy_throttle, effective_limits, stress_level, stress_score
+from sentinel.settings import DEFAULTS, Settings
+
+# Settings the stress level and the effective limits are computed from
+CONFIG_KEYS = (
+    "market_stress_enabled",
+    "market_stress_threshold",
+    "market_stress_buy_size_pct",
+    "market_stress_min_score_add",
+    "market_stress_cooloff_factor",
+    "strategy_min_opp_score",
+    "strategy_core_new_min_score",
+    "strategy_opportunity_cooloff_days",
+    "strategy_core_cooloff_days",
+)
+
+
+class MarketStressService:
+    """Serves the current market stress level and the throttled buy limits."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+
+    async def status(self) -> dict:
+        """Stress score and level, region weights, the buy throttle and the effective limits."""
+        config = {}
+        for key in CONFIG_KEYS:
+            value = await self._settings.get(key, DEFAULTS[key])
+            config[key] = float(value if value is not None else DEFAULTS[key])
+
+        exposure: dict[str, float] = {}
+        for pos in await self._db.get_all_positions():
+            local = float(pos.get("current_price") or 0) * float(pos.get("quantity") or 0)
+            exposure[pos["symbol"]] = await self._currency.to_eur(local, pos.get("currency") or "EUR")
+        securities = {s["symbol"]: s for s in await self._db.get_all_securities(active_only=False)}
+
+        states = await self._db.get_regime_states()
+        score, regions = stress_score(states, exposure, securities)
+        level = stress_level(score, config["market_stress_threshold"])
+        throttle = buy_throttle(level, config)
+        return {
+            "enabled": bool(config["market_stress_enabled"]),
+            "level": level,
+            "score": score,
+            "threshold": config["market_stress_threshold"],
+            "regions": regions,
+            "throttled": bool(config["market_stress_enabled"]) and level != NORMAL,
+            "throttle": throttle,
+            "limits": effective_limits(throttle, config),
+        }
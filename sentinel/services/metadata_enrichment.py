@@ -0,0 +1,324 @@
+"""Security metadata enrichment from several providers.
+
+Industry, country and exchange of each active security are collected from
+pluggable providers, each reporting the values it knows:
+
+- tradernet: the SecurityInfo response stored by sync:metadata
+- yahoo: the Yahoo Finance asset profile (metadata_yahoo_enabled; the Yahoo
+  symbol comes from the instrument IDs, see services/instrument_ids.py)
+- user: overrides imported from a CSV (symbol plus any of the field columns)
+
+Every provider's value is kept in security_metadata. One value per field is
+then applied to the securities row, and where it came from is recorded in
+metadata_provenance:
+
+1. a user override always wins
+2. a value entered by hand (one the pipeline did not apply) is kept
+3. when the providers agree, or only one has a value, that value is applied
+4. when they disagree, the value of the first provider in
+   metadata_provider_priority is applied and the conflict is queued in
+   metadata_reviews; resolving a review stores the chosen value as a user
+   override
+
+Values are compared case-insensitively and ignoring surrounding whitespace.
+"""
+
+from __future__ import annotations
+
+import csv
+import io
+import json
+import logging
+from typing import Awaitable, Callable, Protocol
+
+from sentinel.database import Database
+from sentinel.errors import http_error
+from sentinel.services.instrument_ids import InstrumentIdService, default_yahoo_symbol
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+FIELDS = ("industry", "country", "exchange")
+USER = "user"
+MANUAL = "manual"
+YAHOO_PROFILE_URL = "https://query2.finance.yahoo.com/v10/finance/quoteSummary/{ticker}?modules=assetProfile,price"
+REQUEST_TIMEOUT = 10.0
+
+# Fetches a JSON document: url -> parsed body
+Fetcher = Callable[[str], Awaitable[dict]]
+
+
+async def http_get_json(url: str) -> dict:
+    """Default fetcher (httpx).
+
+    Raises:
+        Unavailable: Yahoo unreachable or failing (RateLimited when throttling)
+        UpstreamError: Yahoo rejected the request
+    """
+    import httpx
+
+    async with httpx.AsyncClient(timeout=REQUEST_TIMEOUT, headers={"User-Agent": "Mozilla/5.0"}) as client:
+        try:
+            response = await client.get(url)
+            response.raise_for_status()
+        except httpx.HTTPError as e:
+            raise http_error(e, "Yahoo") from e
+        return response.json()
+
+
+class MetadataProvider(Protocol):
+    """A source of security metadata."""
+
+    name: str
+
+    async def fetch(self, security: dict) -> dict[str, str]:
+        """Field -> value for the fields this provider knows (missing or empty fields are left out)."""
+        ...
+
+
+def _clean(values: dict) -> dict[str, str]:
+    cleaned = {field: str(value or "").strip() for field, value in values.items() if field in FIELDS}
+    return {field: value for field, value in cleaned.items() if value}
+
+
+class TradernetProvider:
+    """Industry, country and exchange from the stored Tradernet SecurityInfo."""
+
+    name = "tradernet"
+
+    async def fetch(self, security: dict) -> dict[str, str]:
+        raw = security.get("data")
+        info = json.loads(raw) if isinstance(raw, str) and raw else raw or {}
+        market = info.get("mrkt") or {}
+        return _clean(
+            {
+                "industry": info.get("industry") or info.get("sector"),
+                "country": info.get("issuer_country_code") or info.get("country"),
+                "exchange": market.get("mkt_short_code") or info.get("exchange"),
+            }
+        )
+
+
+class YahooProfileProvider:
+    """Industry, country and exchange from the Yahoo Finance asset profile."""
+
+    name = "yahoo"
+
+    def __init__(self, fetcher: Fetcher | None = None, symbols: dict[str, str] | None = None):
+        self._fetch = fetcher or http_get_json
+        self._symbols = symbols or {}  # symbol -> Yahoo symbol
+
+    async def fetch(self, security: dict) -> dict[str, str]:
+        symbol = security["symbol"]
+        ticker = self._symbols.get(symbol) or default_yahoo_symbol(symbol)
+        body = await self._fetch(YAHOO_PROFILE_URL.format(ticker=ticker))
+        results = (body.get("quoteSummary") or {}).get("result") or [{}]
+        profile = results[0].get("assetProfile") or {}
+        price = results[0].get("price") or {}
+        return _clean(
+            {
+                "industry": profile.get("industry"),
+                "country": profile.get("country"),
+                "exchange": price.get("exchangeName"),
+            }
+        )
+
+
+def _norm(value: str | None) -> str:
+    return (value or "").strip().casefold()
+
+
+def resolve_field(
+    current: str | None, provenance: dict | None, candidates: dict[str, str], priority: list[str]
+) -> dict | None:
+    """The value to apply to one field.
+
+    Args:
+        current: Value on the securities row
+        provenance: Provenance of the field's last applied value (None when never applied)
+        candidates: provider -> value
+        priority: Providers in order of preference (after user overrides)
+
+    Returns:
+        dict with value, provider, rule and conflict (provider -> value when
+        providers disagree), or None when there is nothing to apply
+    """
+    if candidates.get(USER):
+        return {"value": candidates[USER], "provider": USER, "rule": "override", "conflict": None}
+    if (current or "").strip() and (provenance is None or provenance.get("value") != current):
+        return {"value": current, "provider": MANUAL, "rule": "manual", "conflict": None}
+
+    ranked = sorted(candidates, key=lambda p: (priority.index(p) if p in priority else len(priority), p))
+    if not ranked:
+        return None
+    provider = ranked[0]
+    distinct = {_norm(v) for v in candidates.values()}
+    if len(distinct) == 1:
+        rule = "agreement" if len(candidates) > 1 else "single_source"
+        return {"value": candidates[provider], "provider": provider, "rule": rule, "conflict": None}
+    return {"value": candidates[provider], "provider": provider, "rule": "priority", "conflict": dict(candidates)}
+
+
+class MetadataEnrichmentService:
+    """Collects metadata from the providers, applies one value per field and queues conflicts."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        providers: list[MetadataProvider] | None = None,
+        fetcher: Fetcher | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            providers: Metadata providers (built from settings if None)
+            fetcher: JSON fetch function for the Yahoo provider (uses httpx if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._providers = providers
+        self._fetcher = fetcher
+
+    async def providers(self) -> list[MetadataProvider]:
+        if self._providers is not None:
+            return self._providers
+        providers: list[MetadataProvider] = [TradernetProvider()]
+        if await self._settings.get("metadata_yahoo_enabled", False):
+            securities = await self._db.get_all_securities(active_only=True)
+            symbols = await InstrumentIdService(self._db).yahoo_symbols(s["symbol"] for s in securities)
+            providers.append(YahooProfileProvider(self._fetcher, symbols))
+        return providers
+
+    async def enrich(self, symbols: list[str] | None = None) -> dict:
+        """Fetch every provider's metadata for the active securities and apply it.
+
+        Returns:
+            Counts of securities, applied fields, new conflicts and provider errors
+        """
+        securities = await self._db.get_all_securities(active_only=True)
+        if symbols is not None:
+            securities = [s for s in securities if s["symbol"] in symbols]
+        providers = await self.providers()
+        result = {"securities": len(securities), "applied": 0, "conflicts": 0, "errors": 0}
+        for security in securities:
+            for provider in providers:
+                try:
+                    values = await provider.fetch(security)
+                except Exception as e:
+                    logger.warning(f"Metadata provider {provider.name} failed for {security['symbol']}: {e}")
+                    result["errors"] += 1
+                    continue
+                await self._db.save_metadata_candidates(security["symbol"], provider.name, values)
+            applied = await self.apply(security["symbol"])
+            result["applied"] += applied["applied"]
+            result["conflicts"] += applied["conflicts"]
+        logger.info(f"Metadata enrichment: {result}")
+        return result
+
+    async def apply(self, symbol: str) -> dict:
+        """Resolve each field of a security from its stored candidates and apply the result."""
+        security = await self._db.get_security(symbol)
+        if security is None:
+            raise LookupError(f"Security {symbol} not found")
+        priority = list(await self._settings.get("metadata_provider_priority", ["tradernet", "yahoo"]) or [])
+        candidates = await self._db.get_metadata_candidates(symbol)
+        provenance = await self._db.get_metadata_provenance(symbol)
+
+        counts = {"applied": 0, "conflicts": 0}
+        for field in FIELDS:
+            values = {c["provider"]: c["value"] for c in candidates if c["field"] == field}
+            decision = resolve_field(security.get(field), provenance.get(field), values, priority)
+            if decision is None or decision["rule"] == "manual":
+                continue
+            if decision["value"] != security.get(field):
+                await self._db.upsert_security(symbol, **{field: decision["value"]})
+                counts["applied"] += 1
+            await self._db.save_metadata_provenance(
+                symbol, field, decision["value"], decision["provider"], decision["rule"]
+            )
+            if not decision["conflict"]:
+                await self._db.close_metadata_reviews(symbol, field, decision["value"])
+            elif await self._db.open_metadata_review(symbol, field, decision["conflict"]):
+                counts["conflicts"] += 1
+        return counts
+
+    async def report(self, symbol: str) -> dict:
+        """Each field's value with its provenance and every provider's value.
+
+        Raises:
+            LookupError: Unknown security
+        """
+        security = await self._db.get_security(symbol)
+        if security is None:
+            raise LookupError(f"Security {symbol} not found")
+        candidates = await self._db.get_metadata_candidates(symbol)
+        provenance = await self._db.get_metadata_provenance(symbol)
+        fields = {}
+        for field in FIELDS:
+            value = security.get(field)
+            source = provenance.get(field)
+            if (value or "").strip() and (source is None or source["value"] != value):
+                source = {"value": value, "provider": MANUAL, "rule": "manual"}
+            fields[field] = {
+                "value": value,
+                "provenance": source,
+                "candidates": {c["provider"]: c["value"] for c in candidates if c["field"] == field},
+            }
+        return {"symbol": symbol, "fields": fields}
+
+    async def import_overrides(self, text: str) -> dict:
+        """Store user overrides from CSV text (a symbol column and any of the field columns).
+
+        Empty cells are ignored; rows of unknown securities are skipped.
+
+        Raises:
+            ValueError: No symbol column, or none of the field columns
+        """
+        reader = csv.DictReader(io.StringIO(text))
+        columns = [c.strip() for c in reader.fieldnames or []]
+        fields = [f for f in FIELDS if f in columns]
+        if "symbol" not in columns or not fields:
+            raise ValueError(f"CSV needs a symbol column and at least one of {', '.join(FIELDS)}")
+
+        imported, unknown = 0, []
+        for row in reader:
+            row = {(k or "").strip(): v for k, v in row.items()}
+            symbol = (row.get("symbol") or "").strip()
+            if not symbol:
+                continue
+            if await self._db.get_security(symbol) is None:
+                unknown.append(symbol)
+                continue
+            values = _clean({f: row.get(f) for f in fields})
+            if values:
+                await self._db.save_metadata_candidates(symbol, USER, values)
+                await self.apply(symbol)
+                imported += 1
+        return {"imported": imported, "unknown": unknown}
+
+    async def reviews(self, status: str | None = "open", limit: int = 100) -> list[dict]:
+        """Queued conflicts, most recent first."""
+        return await self._db.get_metadata_reviews(status, limit)
+
+    async def resolve_review(self, review_id: int, value: str, resolved_by: str | None = None) -> dict:
+        """Settle a conflict with a value, stored as a user override.
+
+        Raises:
+            LookupError: Unknown review
+            ValueError: Already resolved, or empty value
+        """
+        review = await self._db.get_metadata_review(review_id)
+        if review is None:
+            raise LookupError(f"Review {review_id} not found")
+        if review["status"] != "open":
+            raise ValueError(f"Review {review_id} is already resolved")
+        value = (value or "").strip()
+        if not value:
+            raise ValueError("value is required")
+        await self._db.save_metadata_candidates(review["symbol"], USER, {review["field"]: value})
+        await self._db.resolve_metadata_review(review_id, value, resolved_by)
+        await self.apply(review["symbol"])
+        return await self._db.get_metadata_review(review_id)
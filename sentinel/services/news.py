@@ -0,0 +1,143 @@
+"""News headlines and sentiment for held positions.
+
+The sync:news job fetches recent headlines for every held symbol from the
+broker, classifies each locally (utils/sentiment.py) and keeps them in the
+cache table for news_lookback_hours. A position is tagged news_risk when any
+recent headline is severe (fraud, bankruptcy, ...) or at least
+news_risk_min_negative headlines are negative, so the tag can be shown next to
+the position and weighed by anything that assigns tags to securities.
+"""
+
+from __future__ import annotations
+
+import json
+import time
+from datetime import datetime, timezone
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.settings import Settings
+from sentinel.utils.sentiment import classify_headline
+
+CACHE_PREFIX = "news:"
+NEWS_RISK_TAG = "news_risk"
+
+
+def _headline_ts(entry: dict, default: int) -> int:
+    """Publication time of a broker news entry (unix seconds or a date string), else default."""
+    raw = entry.get("date") or entry.get("publish_date") or entry.get("time")
+    if isinstance(raw, (int, float)) and raw > 0:
+        return int(raw)
+    if isinstance(raw, str) and raw:
+        try:
+            parsed = datetime.fromisoformat(raw.replace("Z", "+00:00"))
+        except ValueError:
+            return default
+        if parsed.tzinfo is None:
+            parsed = parsed.replace(tzinfo=timezone.utc)
+        return int(parsed.timestamp())
+    return default
+
+
+def assess_headlines(headlines: list[dict], min_negative: int) -> dict:
+    """Counts, average sentiment and the news_risk tag of classified headlines."""
+    negative = sum(1 for h in headlines if h["label"] == "negative")
+    severe = sum(1 for h in headlines if h["severe"])
+    risk = severe > 0 or (min_negative > 0 and negative >= min_negative)
+    return {
+        "headlines_count": len(headlines),
+        "negative": negative,
+        "positive": sum(1 for h in headlines if h["label"] == "positive"),
+        "severe": severe,
+        "sentiment": round(sum(h["score"] for h in headlines) / len(headlines), 3) if headlines else None,
+        "news_risk": risk,
+        "tags": [NEWS_RISK_TAG] if risk else [],
+    }
+
+
+class NewsService:
+    """Fetches, classifies and serves news headlines of held positions."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        broker: Broker | None = None,
+        settings: Settings | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._settings = settings or Settings()
+
+    async def _held_symbols(self) -> list[str]:
+        return sorted(p["symbol"] for p in await self._db.get_all_positions() if (p.get("quantity") or 0) > 0)
+
+    async def _lookback_seconds(self) -> int:
+        return max(1, int(await self._settings.get("news_lookback_hours", 72) or 72)) * 3600
+
+    async def refresh(self, now: int | None = None) -> dict:
+        """Fetch and classify headlines of every held symbol, merged with the ones already cached.
+
+        Returns:
+            dict with enabled, the number of symbols fetched and new headlines stored
+        """
+        if not await self._settings.get("news_enabled", True):
+            return {"enabled": False, "symbols": 0, "new_headlines": 0}
+        now = int(now or time.time())
+        lookback = await self._lookback_seconds()
+        limit = int(await self._settings.get("news_headlines_per_symbol", 20) or 20)
+        symbols = await self._held_symbols()
+        added = 0
+        for symbol in symbols:
+            known = {h["id"]: h for h in await self.headlines(symbol, now)}
+            for entry in await self._broker.get_news(symbol, limit=limit):
+                title = str(entry.get("title") or entry.get("headline") or "").strip()
+                if not title:
+                    continue
+                headline_id = str(entry.get("id") or entry.get("storyId") or title)
+                published_at = _headline_ts(entry, now)
+                if headline_id in known or published_at < now - lookback:
+                    continue
+                known[headline_id] = {
+                    "id": headline_id,
+                    "title": title,
+                    "url": entry.get("url") or entry.get("link"),
+                    "published_at": published_at,
+                    **classify_headline(title),
+                }
+                added += 1
+            ordered = sorted(known.values(), key=lambda h: -h["published_at"])
+            await self._db.cache_set(f"{CACHE_PREFIX}{symbol}", json.dumps(ordered), ttl_seconds=lookback)
+        return {"enabled": True, "symbols": len(symbols), "new_headlines": added}
+
+    async def headlines(self, symbol: str, now: int | None = None) -> list[dict]:
+        """Cached classified headlines of a symbol within the lookback window, newest first."""
+        cached = await self._db.cache_get(f"{CACHE_PREFIX}{symbol}")
+        if not cached:
+            return []
+        since = int(now or time.time()) - await self._lookback_seconds()
+        return [h for h in json.loads(cached) if h["published_at"] >= since]
+
+    async def for_symbol(self, symbol: str, now: int | None = None) -> dict:
+        """Headlines of one symbol with their sentiment summary and news_risk tag."""
+        headlines = await self.headlines(symbol, now)
+        min_negative = int(await self._settings.get("news_risk_min_negative", 2) or 0)
+        return {"symbol": symbol, **assess_headlines(headlines, min_negative), "headlines": headlines}
+
+    async def positions(self, now: int | None = None) -> list[dict]:
+        """Sentiment summary and news_risk tag of every held position (headlines left out)."""
+        min_negative = int(await self._settings.get("news_risk_min_negative", 2) or 0)
+        return [
+            {"symbol": symbol, **assess_headlines(await self.headlines(symbol, now), min_negative)}
+            for symbol in await self._held_symbols()
+        ]
+
+    async def risk_symbols(self, now: int | None = None) -> set[str]:
+        """Held symbols currently tagged news_risk."""
+        return {p["symbol"] for p in await self.positions(now) if p["news_risk"]}
@@ -0,0 +1,131 @@
+"""Notification inbox service.
+
+Alert events (failed jobs, executed or failed trades, uncovered negative
+balances) are persisted as notifications with a severity and a deep link to the
+related entity, so anything missed while away from the dashboard stays visible
+until it is acknowledged.
+"""
+
+from __future__ import annotations
+
+import inspect
+import logging
+from urllib.parse import quote
+
+from sentinel.database import Database
+
+logger = logging.getLogger(__name__)
+
+SEVERITIES = ("info", "warning", "error")
+
+# entity_type -> dashboard path template for the entity's deep link
+ENTITY_LINKS = {
+    "security": "/?search={id}",
+    "job": "/?job={id}",
+    "currency": "/?cash={id}",
+}
+
+
+def entity_link(entity_type: str | None, entity_id: str | None) -> str | None:
+    """Dashboard path for an entity, or None if the type has no page."""
+    template = ENTITY_LINKS.get(entity_type or "")
+    if not template or not entity_id:
+        return None
+    return template.format(id=quote(str(entity_id), safe=""))
+
+
+async def record_notification(
+    db, severity: str, category: str, title: str, event: str | None = None, **kwargs
+) -> None:
+    """Persist a notification from an alerting code path. Never raises.
+
+    Works with any db object; does nothing if it has no notification support.
+    If event is given (one of webhooks.EVENT_TYPES), it is also sent to the
+    configured webhook endpoints.
+    """
+    adder = getattr(db, "add_notification", None)
+    if not callable(adder):
+        return
+    try:
+        kwargs.setdefault("link", entity_link(kwargs.get("entity_type"), kwargs.get("entity_id")))
+        result = adder(severity, category, title, **kwargs)
+        if inspect.isawaitable(result):
+            await result
+    except Exception as e:
+        logger.warning(f"Failed to record notification '{title}': {e}")
+    if event:
+        from sentinel.services.webhooks import emit_event
+
+        data = {k: kwargs[k] for k in ("entity_type", "entity_id", "link") if kwargs.get(k)}
+        await emit_event(db, event, title, message=kwargs.get("message"), severity=severity, data=data)
+
+
+class NotificationService:
+    """Reads and acknowledges the notification inbox."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional database.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def notify(
+        self,
+        severity: str,
+        category: str,
+        title: str,
+        message: str | None = None,
+        entity_type: str | None = None,
+        entity_id: str | None = None,
+        dedupe_key: str | None = None,
+    ) -> int:
+        """Add a notification, deriving its deep link from the entity.
+
+        Raises:
+            ValueError: If severity is not one of SEVERITIES
+        """
+        if severity not in SEVERITIES:
+            raise ValueError(f"Unknown severity: {severity}")
+        return await self._db.add_notification(
+            severity,
+            category,
+            title,
+            message=message,
+            entity_type=entity_type,
+            entity_id=entity_id,
+            link=entity_link(entity_type, entity_id),
+            dedupe_key=dedupe_key,
+        )
+
+    async def list(
+        self,
+        unread_only: bool = False,
+        severity: str | None = None,
+        limit: int = 50,
+        offset: int = 0,
+    ) -> dict:
+        """List notifications, most recent first.
+
+        Returns:
+            dict with notifications, count and unread_count
+        """
+        if severity and severity not in SEVERITIES:
+            raise ValueError(f"Unknown severity: {severity}")
+        notifications = await self._db.get_notifications(
+            unread_only=unread_only, severity=severity, limit=limit, offset=offset
+        )
+        return {
+            "notifications": notifications,
+            "count": len(notifications),
+            "unread_count": await self._db.get_unread_notification_count(),
+        }
+
+    async def acknowledge(self, notification_id: int) -> bool:
+        """Mark one notification as read. Returns False if it does not exist."""
+        return await self._db.mark_notification_read(notification_id)
+
+    async def acknowledge_all(self) -> int:
+        """Mark every unread notification as read. Returns the number acknowledged."""
+        return await self._db.mark_all_notifications_read()
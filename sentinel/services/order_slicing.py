@@ -0,0 +1,283 @@
+"""Order slicing: large orders split into child orders placed across the session.
+
+With order_slicing_enabled, an order is sliced when it is more than
+order_slice_max_volume_pct of the security's 20-day average daily volume or
+worth more than order_slice_max_notional_eur. It is split into as many
+lot-aligned child orders as it takes to bring each under both limits (at most
+order_slice_max_children), due order_slice_interval_minutes apart.
+
+trading:execute places the first child right away and the trading:order_slices
+job places the others once they are due and the market is open, all through the
+same order path (circuit breaker, Security.buy / sell and its limit pricing).
+Each child is recorded as a trade decision with source slice:<id> and its broker
+order ID, so its fills are matched like any other order. A rejected child stops
+the sequence and cancels the children after it; children still pending at the
+end of the UTC day the order was sliced on expire, leaving the rest to the
+planner's next recommendations. While a sliced order is active, trading:execute
+leaves its symbol to the slices.
+"""
+
+from __future__ import annotations
+
+import logging
+import math
+import time
+from dataclasses import asdict
+from datetime import datetime, timezone
+from typing import Awaitable, Callable
+
+from sentinel.audit import record_action
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.errors import Conflict, NotFound
+from sentinel.planner.models import TradeRecommendation
+from sentinel.settings import Settings
+from sentinel.utils.quantity import lot_step
+
+logger = logging.getLogger(__name__)
+
+# Daily bars averaged for the typical volume
+VOLUME_DAYS = 20
+
+# Places one child order, returning its broker order ID (None when rejected)
+PlaceSlice = Callable[[TradeRecommendation, str], Awaitable[str | None]]
+
+
+def slice_count(
+    quantity: float,
+    value_eur: float,
+    avg_volume: float | None,
+    max_volume_pct: float,
+    max_notional_eur: float,
+    max_children: int,
+) -> int:
+    """Child orders needed to keep each within max_volume_pct of the average volume and max_notional_eur.
+
+    1 means the order is placed whole. Unknown volume only applies the notional ceiling.
+    """
+    count = 1
+    if avg_volume and avg_volume > 0 and max_volume_pct > 0:
+        count = max(count, math.ceil(quantity / (avg_volume * max_volume_pct / 100)))
+    if max_notional_eur > 0:
+        count = max(count, math.ceil(value_eur / max_notional_eur))
+    return max(1, min(count, max_children))
+
+
+def slice_quantities(quantity: float, count: int, step: float) -> list[float]:
+    """Split a lot-aligned quantity into up to `count` lot-aligned children, larger ones first."""
+    lots = int(round(quantity / step))
+    count = max(1, min(count, lots))
+    base, extra = divmod(lots, count)
+    return [round((base + (1 if i < extra else 0)) * step, 8) for i in range(count)]
+
+
+def progress(order: dict) -> dict:
+    """Aggregate execution status of a sliced order from its children."""
+    counts: dict[str, int] = {}
+    for child in order["slices"]:
+        counts[child["status"]] = counts.get(child["status"], 0) + 1
+    placed = sum(c["quantity"] for c in order["slices"] if c["status"] == "placed")
+    pending = sum(c["quantity"] for c in order["slices"] if c["status"] == "pending")
+    return {
+        "slices": len(order["slices"]),
+        **{status: counts.get(status, 0) for status in ("placed", "pending", "failed", "expired", "cancelled")},
+        "placed_quantity": round(placed, 8),
+        "pending_quantity": round(pending, 8),
+        "placed_pct": round(placed / order["quantity"] * 100, 2) if order["quantity"] else 0.0,
+    }
+
+
+class OrderSlicingService:
+    """Splits large orders into child orders and places them as they fall due."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+
+    async def plan(self, rec: TradeRecommendation) -> dict | None:
+        """Child quantities for a recommendation too large to place at once (None to place it whole)."""
+        if not await self._settings.get("order_slicing_enabled", False):
+            return None
+        security = await self._db.get_security(rec.symbol)
+        if not security or rec.quantity <= 0 or rec.price <= 0:
+            return None
+        step = lot_step(security.get("min_lot", 1), security.get("supports_fractional", 0))
+        rows = await self._db.get_prices(rec.symbol, days=VOLUME_DAYS)
+        volumes = [float(r["volume"]) for r in rows if r.get("volume")]
+        avg_volume = sum(volumes) / len(volumes) if volumes else None
+        value_eur = await self._currency.to_eur(rec.quantity * rec.price, rec.currency)
+        max_volume_pct = float(await self._settings.get("order_slice_max_volume_pct", 5.0))
+        max_notional_eur = float(await self._settings.get("order_slice_max_notional_eur", 25000.0) or 0)
+        count = slice_count(
+            rec.quantity,
+            value_eur,
+            avg_volume,
+            max_volume_pct,
+            max_notional_eur,
+            int(await self._settings.get("order_slice_max_children", 6)),
+        )
+        quantities = slice_quantities(rec.quantity, count, step)
+        if len(quantities) < 2:
+            return None
+        reasons = []
+        if avg_volume and rec.quantity > avg_volume * max_volume_pct / 100:
+            reasons.append(f"{rec.quantity / avg_volume * 100:.1f}% of the average daily volume")
+        if max_notional_eur > 0 and value_eur > max_notional_eur:
+            reasons.append(f"EUR {value_eur:,.0f} above the EUR {max_notional_eur:,.0f} ceiling")
+        return {"quantities": quantities, "reason": " and ".join(reasons)}
+
+    async def start(self, rec: TradeRecommendation, plan: dict, source: str, now: int | None = None) -> dict:
+        """Store a sliced order with its children, the first due now."""
+        now = int(now or time.time())
+        interval = int(await self._settings.get("order_slice_interval_minutes", 30)) * 60
+        order_id = await self._db.create_sliced_order(
+            {
+                "symbol": rec.symbol,
+                "action": rec.action,
+                "quantity": rec.quantity,
+                "source": source,
+                "reason": plan["reason"],
+                "recommendation": asdict(rec),
+            },
+            [{"seq": i + 1, "quantity": q, "due_at": now + i * interval} for i, q in enumerate(plan["quantities"])],
+        )
+        await record_action(
+            self._db,
+            "order.slice",
+            rec.symbol,
+            {"sliced_order": order_id, "action": rec.action, "quantity": rec.quantity, "slices": plan["quantities"]},
+        )
+        logger.info(f"Sliced {rec.action} {rec.quantity} x {rec.symbol} into {len(plan['quantities'])} orders")
+        return await self.get(order_id)
+
+    async def orders(self, status: str | None = None, limit: int = 100) -> list[dict]:
+        """Sliced orders with their children and progress, newest first."""
+        return [{**order, "progress": progress(order)} for order in await self._db.get_sliced_orders(status, limit)]
+
+    async def get(self, order_id: int) -> dict:
+        """One sliced order with its children and progress.
+
+        Raises:
+            NotFound: Unknown sliced order
+        """
+        order = await self._db.get_sliced_order(order_id)
+        if order is None:
+            raise NotFound(f"Sliced order {order_id} not found")
+        return {**order, "progress": progress(order)}
+
+    async def active_symbols(self) -> set[str]:
+        """Symbols with a sliced order still placing children."""
+        return {order["symbol"] for order in await self._db.get_sliced_orders("active", limit=1000)}
+
+    async def cancel(self, order_id: int) -> dict:
+        """Cancel the children of a sliced order not placed yet (placed children stay with the broker).
+
+        Raises:
+            NotFound: Unknown sliced order
+            Conflict: The order is no longer active
+        """
+        order = await self.get(order_id)
+        if order["status"] != "active":
+            raise Conflict(f"Sliced order {order_id} is {order['status']}")
+        await self._close(order, "cancelled", "cancelled", "cancelled by user")
+        await record_action(self._db, "order.slice_cancel", order["symbol"], {"sliced_order": order_id})
+        return await self.get(order_id)
+
+    async def run_due(
+        self,
+        place: PlaceSlice,
+        open_symbols: set[str],
+        now: int | None = None,
+        order_id: int | None = None,
+    ) -> dict:
+        """Place the due children of active sliced orders whose market is open.
+
+        Args:
+            place: Places one child (the trading:execute order path), given the
+                recommendation and the decision source
+            open_symbols: Symbols whose market is open
+            now: Current unix time (default: now)
+            order_id: Only this sliced order
+
+        Returns:
+            Counts of children placed, failed and waiting, and of orders completed, stopped by a
+            rejected child and expired
+        """
+        from sentinel.services.circuit_breaker import CircuitBreakerService
+
+        now = int(now or time.time())
+        today = datetime.fromtimestamp(now, tz=timezone.utc).date()
+        summary = {"placed": 0, "failed": 0, "waiting": 0, "completed": 0, "stopped": 0, "expired": 0}
+        active = await self._db.get_sliced_orders("active", limit=1000)
+        breaker = CircuitBreakerService(db=self._db, settings=self._settings, currency=self._currency)
+
+        for order in active:
+            if order_id is not None and order["id"] != order_id:
+                continue
+            # The first child is due when the order was sliced
+            started = min(c["due_at"] for c in order["slices"])
+            if datetime.fromtimestamp(started, tz=timezone.utc).date() < today:
+                await self._close(order, "expired", "expired", "not placed during the session it was sliced in")
+                summary["expired"] += 1
+                continue
+            pending = [c for c in order["slices"] if c["status"] == "pending"]
+            for child in pending:
+                if child["due_at"] > now or order["symbol"] not in open_symbols:
+                    summary["waiting"] += 1
+                    break
+                if await breaker.is_tripped():
+                    logger.warning("Circuit breaker tripped, holding sliced orders")
+                    summary["waiting"] += 1
+                    break
+                broker_order_id = await place(self._child(order, child), f"slice:{order['id']}")
+                if broker_order_id:
+                    child.update(status="placed", order_id=str(broker_order_id), placed_at=now)
+                    await self._db.update_order_slice(
+                        child["id"], status="placed", order_id=str(broker_order_id), placed_at=now
+                    )
+                    summary["placed"] += 1
+                    continue
+                child.update(status="failed")
+                await self._db.update_order_slice(child["id"], status="failed", detail="order rejected")
+                summary["failed"] += 1
+                await self._close(order, "failed", "cancelled", f"slice {child['seq']} was rejected")
+                summary["stopped"] += 1
+                break
+            else:
+                await self._db.update_sliced_order(order["id"], "completed")
+                summary["completed"] += 1
+                status = progress(order)
+                logger.info(
+                    f"Sliced order {order['id']} ({order['action']} {order['symbol']}) completed: "
+                    f"{status['placed']}/{status['slices']} slices placed"
+                )
+        return summary
+
+    def _child(self, order: dict, child: dict) -> TradeRecommendation:
+        rec = TradeRecommendation(**order["recommendation"])
+        share = child["quantity"] / order["quantity"]
+        rec.quantity = child["quantity"]
+        rec.value_delta_eur *= share
+        rec.reason = f"{rec.reason} (slice {child['seq']}/{len(order['slices'])})"
+        return rec
+
+    async def _close(self, order: dict, status: str, pending_status: str, detail: str) -> None:
+        for child in order["slices"]:
+            if child["status"] == "pending":
+                child["status"] = pending_status
+                await self._db.update_order_slice(child["id"], status=pending_status, detail=detail)
+        await self._db.update_sliced_order(order["id"], status)
+        logger.info(f"Sliced order {order['id']} ({order['action']} {order['symbol']}) {status}: {detail}")
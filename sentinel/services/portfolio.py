@@ -79,6 +79,7 @@ class PortfolioService:
         # Get cash balances
         cash = await self._portfolio.get_cash_balances()
         total_cash_eur = await self._portfolio.total_cash_eur()
+        external_eur = await self._portfolio.external_value()
 
         return {
             "positions": positions,
@@ -87,6 +88,8 @@ class PortfolioService:
             "portfolio_return_pct": portfolio_return_pct,
             "cash": cash,
             "total_cash_eur": total_cash_eur,
+            "external_value_eur": external_eur,
+            "net_worth_eur": total + external_eur,
             "allocations": allocations,
         }
 
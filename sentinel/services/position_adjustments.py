@@ -0,0 +1,121 @@
+"""Manual position adjustments.
+
+When the broker sync gets a position wrong (shares transferred in from another
+broker, a mishandled corporate action), the quantity or cost basis can be
+corrected by hand. Every correction needs a reason and is recorded in the
+append-only position_adjustments ledger with the values before and after and
+who made it; entries are never changed or deleted.
+
+A correction overrides the figures the broker reported when it was made. The
+portfolio sync keeps applying it for as long as the broker keeps reporting
+those figures; once the broker reports anything else (it caught up, or the
+position really changed) the broker wins again. Adjustments made during a
+month are listed as discrepancies by that month's reconciliation.
+"""
+
+from __future__ import annotations
+
+from sentinel.audit import record_action
+from sentinel.database import Database
+from sentinel.errors import Conflict, InvalidInput, NotFound
+from sentinel.utils.positions import same_figure
+
+def _number(name: str, value) -> float | None:
+    if value is None:
+        return None
+    if isinstance(value, bool) or not isinstance(value, (int, float)):
+        raise InvalidInput(f"{name} must be a number")
+    if value < 0:
+        raise InvalidInput(f"{name} cannot be negative")
+    return float(value)
+
+
+class PositionAdjustmentService:
+    """Corrects positions by hand and keeps the ledger of corrections."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def adjust(
+        self,
+        symbol: str,
+        reason: str | None,
+        quantity=None,
+        avg_cost=None,
+        created_by: str = "local",
+    ) -> dict:
+        """Set a position's quantity and/or average cost, recording why.
+
+        Raises:
+            NotFound: Unknown security
+            InvalidInput: No reason, nothing to change, or a negative or non-numeric value
+            Conflict: The position already has those figures
+        """
+        reason = (reason or "").strip()
+        if not reason:
+            raise InvalidInput("A reason is required to adjust a position")
+        quantity = _number("quantity", quantity)
+        avg_cost = _number("avg_cost", avg_cost)
+        if quantity is None and avg_cost is None:
+            raise InvalidInput("quantity or avg_cost is required")
+        if await self._db.get_security(symbol) is None:
+            raise NotFound(f"Security {symbol} not found", symbol=symbol)
+
+        position = await self._db.get_position(symbol) or {}
+        quantity_before = float(position.get("quantity") or 0)
+        avg_cost_before = position.get("avg_cost")
+        quantity_after = quantity_before if quantity is None else quantity
+        avg_cost_after = avg_cost_before if avg_cost is None else avg_cost
+        if same_figure(quantity_after, quantity_before) and same_figure(avg_cost_after, avg_cost_before):
+            raise Conflict(f"{symbol} already has that quantity and average cost", symbol=symbol)
+
+        # A correction on top of one still in force overrides the same broker figures
+        previous = (await self._db.get_latest_position_adjustments()).get(symbol)
+        in_force = previous is not None and same_figure(previous["quantity_after"], quantity_before)
+        if in_force and same_figure(previous["avg_cost_after"], avg_cost_before):
+            broker_quantity, broker_avg_cost = previous["broker_quantity"], previous["broker_avg_cost"]
+        else:
+            broker_quantity, broker_avg_cost = quantity_before, avg_cost_before
+
+        await self._db.add_position_adjustment(
+            {
+                "symbol": symbol,
+                "broker_quantity": broker_quantity,
+                "broker_avg_cost": broker_avg_cost,
+                "quantity_before": quantity_before,
+                "quantity_after": quantity_after,
+                "avg_cost_before": avg_cost_before,
+                "avg_cost_after": avg_cost_after,
+                "reason": reason,
+                "created_by": created_by,
+            }
+        )
+        params = {
+            "reason": reason,
+            "quantity": [quantity_before, quantity_after],
+            "avg_cost": [avg_cost_before, avg_cost_after],
+        }
+        await record_action(self._db, "position.adjust", symbol, params)
+        return (await self._db.get_position_adjustments(symbol=symbol, limit=1))[0]
+
+    async def list(self, symbol: str | None = None, limit: int = 100) -> list[dict]:
+        """Recorded adjustments, newest first, each marked with whether it still overrides the broker."""
+        adjustments = await self._db.get_position_adjustments(symbol=symbol, limit=limit)
+        latest = await self._db.get_latest_position_adjustments()
+        positions: dict[str, dict] = {}
+        for adjustment in adjustments:
+            held = adjustment["symbol"]
+            if held not in positions:
+                positions[held] = await self._db.get_position(held) or {}
+            position = positions[held]
+            adjustment["in_force"] = (
+                latest[held]["id"] == adjustment["id"]
+                and same_figure(float(position.get("quantity") or 0), adjustment["quantity_after"])
+                and same_figure(position.get("avg_cost"), adjustment["avg_cost_after"])
+            )
+        return adjustments
@@ -0,0 +1,206 @@
+"""Position-level target prices, stop levels and investment theses.
+
+A position can carry the user's target price and stop level (in the security's
+currency) and a free-text thesis with a review interval. The thesis:check job
+compares them with the current price: reaching the target or the stop, and a
+thesis review falling due, each raise one notification until the condition
+clears (the price moves back, or the thesis is reviewed or edited). The
+planner reads the same conditions as advisory tags; see
+sentinel.planner.theses.
+"""
+
+from __future__ import annotations
+
+import time
+
+from sentinel.audit import record_action
+from sentinel.database import Database
+from sentinel.errors import InvalidInput, NotFound
+from sentinel.planner.theses import review_due_at, thesis_tags
+from sentinel.services.notifications import record_notification
+from sentinel.settings import Settings
+
+FIELDS = ("target_price", "stop_price", "thesis", "review_days")
+
+
+def _price(name: str, value) -> float | None:
+    if value is None:
+        return None
+    if isinstance(value, bool) or not isinstance(value, (int, float)):
+        raise InvalidInput(f"{name} must be a number")
+    if value <= 0:
+        raise InvalidInput(f"{name} must be positive")
+    return float(value)
+
+
+def _review_days(value) -> int | None:
+    if value is None:
+        return None
+    if isinstance(value, bool) or not isinstance(value, int) or value < 1:
+        raise InvalidInput("review_days must be a whole number of days, at least 1")
+    return value
+
+
+class PositionThesisService:
+    """Stores position theses and raises their target, stop and review events."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def set(self, symbol: str, data: dict) -> dict:
+        """Create or update a position's thesis; only the fields present in data change.
+
+        A new thesis without review_days gets the thesis_review_days setting.
+        Changing a level re-arms its notification; rewriting the thesis counts as a review.
+
+        Raises:
+            NotFound: Unknown security
+            InvalidInput: No known field, a non-positive price or review interval, or a stop at or
+                above the target
+        """
+        changes = {field: data[field] for field in FIELDS if field in data}
+        if not changes:
+            raise InvalidInput(f"Nothing to set (fields: {', '.join(FIELDS)})")
+        if await self._db.get_security(symbol) is None:
+            raise NotFound(f"Security {symbol} not found", symbol=symbol)
+        for field in ("target_price", "stop_price"):
+            if field in changes:
+                changes[field] = _price(field, changes[field])
+        if "review_days" in changes:
+            changes["review_days"] = _review_days(changes["review_days"])
+        if "thesis" in changes:
+            changes["thesis"] = str(changes["thesis"] or "").strip() or None
+
+        existing = await self._db.get_position_thesis(symbol)
+        merged = {**(existing or {}), **changes}
+        target, stop = merged.get("target_price"), merged.get("stop_price")
+        if target is not None and stop is not None and stop >= target:
+            raise InvalidInput("stop_price must be below target_price", symbol=symbol)
+        if existing is None and "review_days" not in changes:
+            changes["review_days"] = int(await self._settings.get("thesis_review_days", 90))
+        if "thesis" in changes:
+            # Rewriting the thesis is a review
+            changes.update(reviewed_at=int(time.time()), review_notified_at=None)
+        if existing is not None:
+            if "target_price" in changes and changes["target_price"] != existing["target_price"]:
+                changes["target_reached_at"] = None
+            if "stop_price" in changes and changes["stop_price"] != existing["stop_price"]:
+                changes["stop_reached_at"] = None
+
+        await self._db.set_position_thesis(symbol, **changes)
+        await record_action(self._db, "position.thesis", symbol, {k: changes[k] for k in FIELDS if k in changes})
+        return await self.get(symbol)
+
+    async def review(self, symbol: str) -> dict:
+        """Mark a position's thesis as reviewed now, restarting its review interval.
+
+        Raises:
+            NotFound: The position has no thesis
+        """
+        if await self._db.get_position_thesis(symbol) is None:
+            raise NotFound(f"{symbol} has no thesis", symbol=symbol)
+        await self._db.set_position_thesis(symbol, reviewed_at=int(time.time()), review_notified_at=None)
+        await record_action(self._db, "position.thesis_review", symbol)
+        return await self.get(symbol)
+
+    async def clear(self, symbol: str) -> None:
+        """Remove a position's thesis.
+
+        Raises:
+            NotFound: The position has no thesis
+        """
+        if not await self._db.delete_position_thesis(symbol):
+            raise NotFound(f"{symbol} has no thesis", symbol=symbol)
+        await record_action(self._db, "position.thesis_clear", symbol)
+
+    async def get(self, symbol: str) -> dict:
+        """A position's thesis with its current price and conditions.
+
+        Raises:
+            NotFound: The position has no thesis
+        """
+        thesis = await self._db.get_position_thesis(symbol)
+        if thesis is None:
+            raise NotFound(f"{symbol} has no thesis", symbol=symbol)
+        return self._describe(thesis, await self._price(symbol), time.time())
+
+    async def list(self) -> list[dict]:
+        """Every position thesis with its current price and conditions."""
+        now = time.time()
+        return [
+            self._describe(thesis, await self._price(symbol), now)
+            for symbol, thesis in (await self._db.get_position_theses()).items()
+        ]
+
+    async def check(self, now: int | None = None) -> dict:
+        """Notify on theses whose target or stop was reached or whose review fell due.
+
+        Returns:
+            dict with the number of theses checked and the events raised
+        """
+        now = int(now or time.time())
+        theses = await self._db.get_position_theses()
+        events = []
+        for symbol, thesis in theses.items():
+            price = await self._price(symbol)
+            tags = thesis_tags(thesis, price, now)
+            updates: dict = {}
+            raised = []
+            for tag, column in (("target_reached", "target_reached_at"), ("stop_reached", "stop_reached_at")):
+                if tag in tags and thesis[column] is None:
+                    updates[column] = now
+                    raised.append(tag)
+                elif tag not in tags and thesis[column] is not None and price > 0:
+                    # Moved back across the level: notify again on the next crossing
+                    updates[column] = None
+            if "review_due" in tags and thesis["review_notified_at"] is None:
+                updates["review_notified_at"] = now
+                raised.append("review_due")
+            if updates:
+                await self._db.set_position_thesis(symbol, **updates)
+            for event in raised:
+                await self._notify(event, thesis, price)
+                events.append({"symbol": symbol, "event": event, "price": price})
+        return {"checked": len(theses), "events": events}
+
+    async def _notify(self, event: str, thesis: dict, price: float) -> None:
+        symbol = thesis["symbol"]
+        if event == "target_reached":
+            severity, title = "info", f"{symbol} reached its target price"
+            message = f"Price {price:g} is at or above the target of {thesis['target_price']:g}"
+        elif event == "stop_reached":
+            severity, title = "warning", f"{symbol} reached its stop level"
+            message = f"Price {price:g} is at or below the stop of {thesis['stop_price']:g}"
+        else:
+            severity, title = "info", f"{symbol} thesis review due"
+            message = thesis.get("thesis") or "No thesis written yet"
+        await record_notification(
+            self._db,
+            severity,
+            "thesis",
+            title,
+            message=message,
+            entity_type="security",
+            entity_id=symbol,
+            dedupe_key=f"thesis:{event}:{symbol}",
+        )
+
+    async def _price(self, symbol: str) -> float:
+        position = await self._db.get_position(symbol) or {}
+        return float(position.get("current_price") or 0)
+
+    @staticmethod
+    def _describe(thesis: dict, price: float, now: float) -> dict:
+        return {
+            **thesis,
+            "current_price": price or None,
+            "review_due_at": review_due_at(thesis),
+            "tags": thesis_tags(thesis, price, now),
+        }
@@ -0,0 +1,176 @@
+"""On-demand runtime profiling.
+
+Captures a CPU profile (cProfile on the event loop thread, where all request
+handling and jobs run) or a heap profile (tracemalloc allocations made during
+the window) for a fixed number of seconds, and stores it under PROFILE_DIR:
+
+- cpu-<ts>.pstats: load with pstats.Stats or snakeviz
+- heap-<ts>.tracemalloc: load with tracemalloc.Snapshot.load
+
+Both can also be rendered as a plain-text top list. Only one capture runs at a
+time, and only the most recent KEEP_PROFILES files are kept.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import cProfile
+import io
+import logging
+import pstats
+import re
+import time
+import tracemalloc
+from pathlib import Path
+
+from sentinel.paths import PROFILE_DIR
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+PROFILE_KINDS = {"cpu": "pstats", "heap": "tracemalloc"}
+MAX_CAPTURE_SECONDS = 300
+KEEP_PROFILES = 10
+TOP_ENTRIES = 50
+# Frames kept per heap allocation
+HEAP_TRACEBACK_FRAMES = 10
+
+_NAME_RE = re.compile(r"^(cpu|heap)-\d+\.(pstats|tracemalloc)$")
+
+# Capture in progress (one at a time across the process)
+_active: dict | None = None
+_capture_task: asyncio.Task | None = None
+
+
+class ProfilingService:
+    """Starts captures and serves stored profiles."""
+
+    def __init__(self, settings: Settings | None = None, directory: Path | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            settings: Settings instance (uses singleton if None)
+            directory: Where profiles are written (PROFILE_DIR if None)
+        """
+        self._settings = settings or Settings()
+        self._dir = directory or PROFILE_DIR
+
+    async def enabled(self) -> bool:
+        return bool(await self._settings.get("profiling_enabled", False))
+
+    def active(self) -> dict | None:
+        """The capture in progress, if any."""
+        return dict(_active) if _active else None
+
+    def list_profiles(self) -> list[dict]:
+        """Stored profiles, newest first."""
+        if not self._dir.exists():
+            return []
+        profiles = []
+        for path in self._dir.iterdir():
+            if _NAME_RE.match(path.name):
+                stat = path.stat()
+                profiles.append(
+                    {
+                        "name": path.name,
+                        "kind": path.name.split("-", 1)[0],
+                        "size": stat.st_size,
+                        "created_at": int(stat.st_mtime),
+                    }
+                )
+        # Names carry the capture start in milliseconds
+        return sorted(profiles, key=lambda p: int(p["name"].split("-")[1].split(".")[0]), reverse=True)
+
+    def path(self, name: str) -> Path:
+        """Path of a stored profile.
+
+        Raises:
+            LookupError: Unknown or malformed name
+        """
+        path = self._dir / name
+        if not _NAME_RE.match(name) or not path.exists():
+            raise LookupError(f"Profile not found: {name}")
+        return path
+
+    def render_text(self, name: str) -> str:
+        """Plain-text top list of a stored profile."""
+        path = self.path(name)
+        if name.startswith("cpu-"):
+            out = io.StringIO()
+            stats = pstats.Stats(str(path), stream=out)
+            stats.sort_stats("cumulative").print_stats(TOP_ENTRIES)
+            return out.getvalue()
+        snapshot = tracemalloc.Snapshot.load(str(path))
+        top = snapshot.statistics("lineno")
+        total = sum(stat.size for stat in top)
+        lines = [f"Allocated during capture: {total / 1024:.1f} KiB in {len(top)} locations", ""]
+        lines += [str(stat) for stat in top[:TOP_ENTRIES]]
+        return "\n".join(lines) + "\n"
+
+    def delete(self, name: str) -> None:
+        self.path(name).unlink()
+
+    def start(self, kind: str, seconds: float) -> dict:
+        """Start a capture in the background.
+
+        Raises:
+            ValueError: Unknown kind or duration out of range
+            RuntimeError: Another capture is running
+        """
+        global _active, _capture_task
+        if kind not in PROFILE_KINDS:
+            raise ValueError(f"Unknown profile kind: {kind}")
+        if not 0 < seconds <= MAX_CAPTURE_SECONDS:
+            raise ValueError(f"seconds must be above 0 and at most {MAX_CAPTURE_SECONDS}")
+        if _active is not None:
+            raise RuntimeError(f"A {_active['kind']} capture is already running")
+
+        started_at = time.time()
+        name = f"{kind}-{int(started_at * 1000)}.{PROFILE_KINDS[kind]}"
+        _active = {"name": name, "kind": kind, "started_at": int(started_at), "ready_at": int(started_at + seconds)}
+        _capture_task = asyncio.get_running_loop().create_task(self._capture(kind, seconds, name))
+        return dict(_active)
+
+    async def capture(self, kind: str, seconds: float) -> dict:
+        """Run a capture to completion and return the stored profile's details."""
+        name = self.start(kind, seconds)["name"]
+        if _capture_task is not None:
+            await _capture_task
+        profile = next((p for p in self.list_profiles() if p["name"] == name), None)
+        if profile is None:
+            raise RuntimeError("Profile capture failed")
+        return profile
+
+    async def _capture(self, kind: str, seconds: float, name: str) -> None:
+        global _active
+        try:
+            self._dir.mkdir(parents=True, exist_ok=True)
+            path = self._dir / name
+            if kind == "cpu":
+                profiler = cProfile.Profile()
+                profiler.enable()
+                try:
+                    await asyncio.sleep(seconds)
+                finally:
+                    profiler.disable()
+                profiler.dump_stats(str(path))
+            else:
+                started = not tracemalloc.is_tracing()
+                if started:
+                    tracemalloc.start(HEAP_TRACEBACK_FRAMES)
+                try:
+                    await asyncio.sleep(seconds)
+                    tracemalloc.take_snapshot().dump(str(path))
+                finally:
+                    if started:
+                        tracemalloc.stop()
+            self._prune()
+            logger.info(f"Profile captured: {path}")
+        except Exception as e:
+            logger.error(f"Profile capture failed: {e}")
+        finally:
+            _active = None
+
+    def _prune(self) -> None:
+        for profile in self.list_profiles()[KEEP_PROFILES:]:
+            (self._dir / profile["name"]).unlink(missing_ok=True)
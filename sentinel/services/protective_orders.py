@@ -0,0 +1,273 @@
+"""Protective orders: broker-side stop-losses and trailing stops on held positions.
+
+The protective_order_policies setting lists policies tried in order; the first
+one whose match keys (symbols, industries, geographies, min_volatility) all
+hold for a position applies, and a policy without match keys applies to every
+position. A policy asks for a stop_loss `percent` below the current price or a
+trailing_stop `percent` below the running high, which the broker maintains.
+
+The trading:protective_orders job reconciles the orders daily while
+protective_orders_enabled is on: it places orders for newly matching
+positions, replaces an order when its policy or the position size changed, the
+broker no longer has it, or a stop-loss can move up by more than
+protective_order_raise_pct (stop-losses are never lowered), and cancels orders
+of positions that were closed or no longer match a policy. Every change is
+recorded in the append-only protective_order_adjustments ledger, and the
+orders themselves in the audit log.
+"""
+
+from __future__ import annotations
+
+import logging
+import math
+from statistics import pstdev
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.planner.cash_equivalents import is_cash_equivalent
+from sentinel.settings import Settings
+from sentinel.utils.strings import parse_csv_field
+
+logger = logging.getLogger(__name__)
+
+KINDS = ("stop_loss", "trailing_stop")
+MATCH_KEYS = ("symbols", "industries", "geographies", "min_volatility")
+
+# Daily closes used for the annualized volatility of min_volatility policies
+VOLATILITY_DAYS = 60
+TRADING_DAYS_PER_YEAR = 252
+
+
+def parse_protective_policies(raw) -> list[dict]:
+    """Validate a protective_order_policies value: a list of named policies.
+
+    Raises:
+        ValueError: On a malformed policy
+    """
+    if not isinstance(raw, list):
+        raise ValueError("protective_order_policies must be a list of policies")
+    policies = []
+    names = set()
+    for i, policy in enumerate(raw):
+        if not isinstance(policy, dict):
+            raise ValueError(f"Policy {i + 1} must be an object")
+        name = str(policy.get("name") or f"policy-{i + 1}")
+        if name in names:
+            raise ValueError(f"Duplicate policy name {name}")
+        names.add(name)
+        unknown = set(policy) - {"name", "kind", "percent", *MATCH_KEYS}
+        if unknown:
+            raise ValueError(f"Policy {name}: unknown keys {', '.join(sorted(unknown))}")
+        if policy.get("kind") not in KINDS:
+            raise ValueError(f"Policy {name}: kind must be one of {', '.join(KINDS)}")
+        percent = policy.get("percent")
+        if isinstance(percent, bool) or not isinstance(percent, (int, float)) or not 0 < percent < 100:
+            raise ValueError(f"Policy {name}: percent must be between 0 and 100")
+        for key in ("symbols", "industries", "geographies"):
+            values = policy.get(key)
+            if values is not None and (not isinstance(values, list) or not all(isinstance(v, str) for v in values)):
+                raise ValueError(f"Policy {name}: {key} must be a list of strings")
+        min_volatility = policy.get("min_volatility")
+        if min_volatility is not None and (
+            isinstance(min_volatility, bool) or not isinstance(min_volatility, (int, float)) or min_volatility < 0
+        ):
+            raise ValueError(f"Policy {name}: min_volatility must be a number >= 0")
+        policies.append({**policy, "name": name, "percent": float(percent)})
+    return policies
+
+
+def annualized_volatility(closes: list[float]) -> float | None:
+    """Annualized standard deviation of daily log returns (None with fewer than 20 returns)."""
+    returns = [math.log(b / a) for a, b in zip(closes, closes[1:], strict=False) if a > 0 and b > 0]
+    if len(returns) < 20:
+        return None
+    return pstdev(returns) * math.sqrt(TRADING_DAYS_PER_YEAR)
+
+
+def matching_policy(policies: list[dict], security: dict, volatility: float | None) -> dict | None:
+    """The first policy whose match keys all hold for a security, or None."""
+    industries = {v.lower() for v in parse_csv_field(security.get("industry"))}
+    geographies = {v.lower() for v in parse_csv_field(security.get("geography"))}
+    for policy in policies:
+        if policy.get("symbols") is not None and security["symbol"] not in policy["symbols"]:
+            continue
+        if policy.get("industries") is not None and not industries & {v.lower() for v in policy["industries"]}:
+            continue
+        if policy.get("geographies") is not None and not geographies & {v.lower() for v in policy["geographies"]}:
+            continue
+        if policy.get("min_volatility") is not None and (volatility is None or volatility < policy["min_volatility"]):
+            continue
+        return policy
+    return None
+
+
+def replace_reason(existing: dict, desired: dict, raise_pct: float) -> str | None:
+    """Why an order in force must be replaced by the desired one (None to keep it)."""
+    if (existing["policy"], existing["kind"], existing["percent"]) != (
+        desired["policy"],
+        desired["kind"],
+        desired["percent"],
+    ):
+        return f"policy {desired['policy']} now asks for a {desired['kind']} at {desired['percent']:g}%"
+    if not math.isclose(existing["quantity"], desired["quantity"], rel_tol=1e-9):
+        return f"position size changed from {existing['quantity']:g} to {desired['quantity']:g}"
+    if desired["kind"] == "stop_loss" and existing["stop_price"]:
+        if desired["stop_price"] > existing["stop_price"] * (1 + raise_pct / 100):
+            return f"price rose: stop raised from {existing['stop_price']:g} to {desired['stop_price']:g}"
+    return None
+
+
+class ProtectiveOrderService:
+    """Keeps protective stop orders on held positions in line with the policies."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        broker: Broker | None = None,
+        settings: Settings | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._settings = settings or Settings()
+
+    async def orders(self) -> list[dict]:
+        """Protective orders in force."""
+        return list((await self._db.get_protective_orders()).values())
+
+    async def adjustments(self, symbol: str | None = None, limit: int = 100) -> list[dict]:
+        """Ledger of protective order changes, newest first."""
+        return await self._db.get_protective_order_adjustments(symbol=symbol, limit=limit)
+
+    async def reconcile(self) -> dict:
+        """Place, replace and cancel protective orders so every held position matches its policy.
+
+        Returns:
+            dict with whether the automation is enabled and the number of orders kept, placed,
+            replaced, cancelled and failed
+        """
+        summary = {"enabled": False, "kept": 0, "placed": 0, "replaced": 0, "cancelled": 0, "failed": 0}
+        if not await self._settings.get("protective_orders_enabled", False):
+            return summary
+        try:
+            policies = parse_protective_policies(await self._settings.get("protective_order_policies", []) or [])
+        except ValueError as e:
+            # Cancelling every order over a typo would leave positions unprotected
+            logger.warning(f"Skipping protective orders, invalid protective_order_policies: {e}")
+            return summary
+        summary["enabled"] = True
+        raise_pct = float(await self._settings.get("protective_order_raise_pct", 2.0) or 0.0)
+        live = await self._settings.get("trading_mode", "research") == "live"
+
+        positions = {p["symbol"]: p for p in await self._db.get_all_positions()}
+        securities = {s["symbol"]: s for s in await self._db.get_all_securities(active_only=False)}
+        current = await self._db.get_protective_orders()
+        needs_volatility = any(p.get("min_volatility") is not None for p in policies)
+
+        for symbol in sorted(set(positions) | set(current)):
+            position, existing = positions.get(symbol), current.get(symbol)
+            security = securities.get(symbol)
+            desired = None
+            if position and security and not is_cash_equivalent(security):
+                volatility = await self._volatility(symbol) if needs_volatility else None
+                policy = matching_policy(policies, security, volatility)
+                price = float(position.get("current_price") or 0)
+                if policy and price > 0:
+                    desired = {
+                        "policy": policy["name"],
+                        "kind": policy["kind"],
+                        "percent": policy["percent"],
+                        "stop_price": round(price * (1 - policy["percent"] / 100), 4)
+                        if policy["kind"] == "stop_loss"
+                        else None,
+                        "quantity": float(position["quantity"]),
+                    }
+
+            if existing is None and desired is None:
+                continue
+            if desired is None:
+                reason = "position closed" if position is None else "no policy matches the position"
+                outcome = await self._cancel(existing, reason)
+            elif existing is None:
+                outcome = await self._place(symbol, desired, f"matches policy {desired['policy']}")
+            else:
+                reason = replace_reason(existing, desired, raise_pct)
+                if reason is None and live and existing["order_id"].isdigit():
+                    if await self._broker.get_order_status(existing["order_id"]) is None:
+                        reason = "order no longer placed at the broker"
+                if reason is None:
+                    summary["kept"] += 1
+                    continue
+                if desired["kind"] == "stop_loss" and existing["stop_price"]:
+                    # Never lower a stop-loss
+                    desired["stop_price"] = max(desired["stop_price"], existing["stop_price"])
+                outcome = await self._replace(existing, desired, reason)
+            summary[outcome] += 1
+        return summary
+
+    async def _volatility(self, symbol: str) -> float | None:
+        rows = await self._db.get_prices(symbol, days=VOLATILITY_DAYS + 1)
+        return annualized_volatility([float(r["close"]) for r in reversed(rows) if r.get("close")])
+
+    async def _submit(self, symbol: str, desired: dict) -> str | None:
+        if desired["kind"] == "stop_loss":
+            return await self._broker.stop_loss(symbol, desired["stop_price"])
+        return await self._broker.trailing_stop(symbol, desired["percent"])
+
+    async def _place(self, symbol: str, desired: dict, reason: str) -> str:
+        order_id = await self._submit(symbol, desired)
+        if not order_id:
+            logger.warning(f"Protective {desired['kind']} for {symbol} was not placed")
+            return "failed"
+        await self._db.add_protective_order_adjustment(
+            {"symbol": symbol, "action": "place", **desired, "order_id": order_id, "reason": reason}
+        )
+        logger.info(f"Placed protective {desired['kind']} for {symbol}: {reason}")
+        return "placed"
+
+    async def _cancel(self, existing: dict, reason: str) -> str:
+        symbol = existing["symbol"]
+        if not await self._broker.cancel_order(existing["order_id"]):
+            logger.warning(f"Could not cancel protective order {existing['order_id']} of {symbol}")
+            return "failed"
+        await self._db.add_protective_order_adjustment(
+            {"symbol": symbol, "action": "cancel", "previous_order_id": existing["order_id"], "reason": reason}
+        )
+        logger.info(f"Cancelled protective order of {symbol}: {reason}")
+        return "cancelled"
+
+    async def _replace(self, existing: dict, desired: dict, reason: str) -> str:
+        symbol = existing["symbol"]
+        if not await self._broker.cancel_order(existing["order_id"]):
+            logger.warning(f"Could not cancel protective order {existing['order_id']} of {symbol} to replace it")
+            return "failed"
+        order_id = await self._submit(symbol, desired)
+        if not order_id:
+            # The old order is gone either way: record that, so the next run places a new one
+            await self._db.add_protective_order_adjustment(
+                {
+                    "symbol": symbol,
+                    "action": "cancel",
+                    "previous_order_id": existing["order_id"],
+                    "reason": f"{reason}; the replacement was not placed",
+                }
+            )
+            return "failed"
+        await self._db.add_protective_order_adjustment(
+            {
+                "symbol": symbol,
+                "action": "replace",
+                **desired,
+                "order_id": order_id,
+                "previous_order_id": existing["order_id"],
+                "reason": reason,
+            }
+        )
+        logger.info(f"Replaced protective order of {symbol}: {reason}")
+        return "replaced"
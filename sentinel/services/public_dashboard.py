@@ -0,0 +1,92 @@
+"""Read-only public dashboard data.
+
+A restricted view for wall displays: portfolio value, allocation and
+performance history, with no trading controls. With
+public_dashboard_hide_symbols on (the default), nothing at security level is
+included - allocation is shown by geography and industry only.
+"""
+
+from __future__ import annotations
+
+import time
+from datetime import datetime, timezone
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.portfolio import Portfolio
+from sentinel.services.reports import snapshot_value
+from sentinel.settings import Settings
+
+MAX_HISTORY_DAYS = 1825
+
+
+class PublicDashboardService:
+    """Builds the public dashboard views."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        portfolio: Portfolio | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            portfolio: Portfolio instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+        self._portfolio = portfolio or Portfolio(db=self._db, currency=self._currency)
+
+    async def enabled(self) -> bool:
+        return bool(await self._settings.get("public_dashboard_enabled", False))
+
+    async def hide_symbols(self) -> bool:
+        return bool(await self._settings.get("public_dashboard_hide_symbols", True))
+
+    async def summary(self) -> dict:
+        """Total value, cash share and position count."""
+        value = await self._portfolio.total_value()
+        cash = await self._portfolio.total_cash_eur()
+        return {
+            "total_value_eur": round(value, 2),
+            "cash_pct": round(cash / value * 100, 2) if value else 0.0,
+            "positions": len(await self._db.get_all_positions()),
+            "updated_at": int(time.time()),
+        }
+
+    async def allocation(self) -> dict:
+        """Allocation percentages by geography and industry (and by security unless hidden)."""
+        allocations = await self._portfolio.get_allocations()
+
+        def pct(weights: dict) -> dict:
+            return {k: round(v * 100, 2) for k, v in sorted(weights.items(), key=lambda kv: kv[1], reverse=True)}
+
+        result = {"by_geography": pct(allocations["by_geography"]), "by_industry": pct(allocations["by_industry"])}
+        if not await self.hide_symbols():
+            result["by_security"] = pct(allocations["by_security"])
+        return result
+
+    async def performance(self, days: int = 365) -> dict:
+        """Daily total value over the last `days` days and the change over the period."""
+        days = max(1, min(days, MAX_HISTORY_DAYS))
+        snapshots = await self._db.get_portfolio_snapshots(days=days)
+        history = [
+            {
+                "date": datetime.fromtimestamp(s["date"], tz=timezone.utc).strftime("%Y-%m-%d"),
+                "value_eur": round(snapshot_value(s), 2),
+            }
+            for s in snapshots
+        ]
+        first = history[0]["value_eur"] if history else 0.0
+        last = history[-1]["value_eur"] if history else 0.0
+        return {
+            "days": days,
+            "history": history,
+            "change_pct": round((last / first - 1) * 100, 2) if first else None,
+        }
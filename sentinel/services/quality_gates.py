@@ -0,0 +1,197 @@
+"""Historical backfill of buy quality-gate outcomes.
+
+The planner admits a new buy through one of seven quality-gate paths:
+
+- core_dip: core entry on score plus a minimum dip (strategy_core_new_min_score/_min_dip_score)
+- core_turn: core entry on score plus a cycle turn
+- opportunity_t1 / _t2 / _t3: opportunity entry at tranche 1/2/3 drawdown depth
+- opportunity_rebound: opportunity entry after a rebound from a recent qualifying dip (event memory)
+- opportunity_memory_boost: opportunity score only clears strategy_min_opp_score thanks to the memory boost
+
+The backfill evaluates every security at every historical month end against
+the current settings and stores the resulting matrix. Each path is evaluated
+independently of sleeve assignment, so the aggregate statistics show how often
+each path fires, how often it is the only one that fires, and how much the
+paths overlap - the evidence for keeping or pruning a path.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+
+from sentinel.database import Database
+from sentinel.planner.rebalance_rules import desired_tranche_stage
+from sentinel.settings import DEFAULTS, Settings
+from sentinel.strategy.contrarian import (
+    MIN_SIGNAL_HISTORY,
+    compute_contrarian_signal,
+    effective_opportunity_score,
+    recent_dd252_min,
+)
+
+logger = logging.getLogger(__name__)
+
+GATE_PATHS = (
+    "core_dip",
+    "core_turn",
+    "opportunity_t1",
+    "opportunity_t2",
+    "opportunity_t3",
+    "opportunity_rebound",
+    "opportunity_memory_boost",
+)
+
+# Settings the gates read
+GATE_SETTINGS = (
+    "strategy_min_opp_score",
+    "strategy_entry_t1_dd",
+    "strategy_entry_t2_dd",
+    "strategy_entry_t3_dd",
+    "strategy_entry_memory_days",
+    "strategy_memory_max_boost",
+    "strategy_core_new_min_score",
+    "strategy_core_new_min_dip_score",
+)
+
+# Closes kept per evaluation: a 252-day drawdown window plus the event-memory lookback
+EVALUATION_WINDOW = 320
+
+
+def evaluate_gate_paths(closes_oldest_first: list[float], settings: dict) -> dict:
+    """Quality-gate paths a security passes given its closes up to the evaluation date."""
+    signal = compute_contrarian_signal(closes_oldest_first)
+    t1, t2, t3 = (settings["strategy_entry_t1_dd"], settings["strategy_entry_t2_dd"], settings["strategy_entry_t3_dd"])
+    recent_min = recent_dd252_min(closes_oldest_first, window_days=int(settings["strategy_entry_memory_days"]))
+    raw = float(signal["opp_score"])
+    effective = effective_opportunity_score(
+        raw_opp_score=raw,
+        cycle_turn=int(signal["cycle_turn"]),
+        freefall_block=int(signal["freefall_block"]),
+        recent_dd252_min_value=recent_min,
+        entry_t1_dd=t1,
+        entry_t3_dd=t3,
+        max_boost=settings["strategy_memory_max_boost"],
+    )
+    min_opp = settings["strategy_min_opp_score"]
+
+    paths = []
+    if effective >= settings["strategy_core_new_min_score"]:
+        if signal["dip_score"] >= settings["strategy_core_new_min_dip_score"]:
+            paths.append("core_dip")
+        if signal["cycle_turn"] == 1:
+            paths.append("core_turn")
+    if effective >= min_opp:
+        stage = desired_tranche_stage(float(signal["dd252"]), t1, t2, t3)
+        if stage > 0:
+            paths.append(f"opportunity_t{stage}")
+        elif signal["cycle_turn"] == 1 and desired_tranche_stage(recent_min, t1, t2, t3) > 0:
+            paths.append("opportunity_rebound")
+        if raw < min_opp:
+            paths.append("opportunity_memory_boost")
+    return {"paths": paths, "opp_score": effective, "opp_score_raw": raw}
+
+
+def month_end_indices(dates: list[str]) -> list[tuple[str, int]]:
+    """(YYYY-MM, index of the month's last row) for date-sorted YYYY-MM-DD strings."""
+    ends: list[tuple[str, int]] = []
+    for i, day in enumerate(dates):
+        month = day[:7]
+        if ends and ends[-1][0] == month:
+            ends[-1] = (month, i)
+        else:
+            ends.append((month, i))
+    return ends
+
+
+def gate_statistics(rows: list[dict]) -> dict:
+    """Path usage frequency and overlap over backfill rows ({'paths': [...]}, ...)."""
+    total = len(rows)
+    passing = [set(r["paths"]) for r in rows if r["paths"]]
+    counts = {path: sum(path in p for p in passing) for path in GATE_PATHS}
+    exclusive = {path: sum(p == {path} for p in passing) for path in GATE_PATHS}
+
+    usage = {
+        path: {
+            "count": counts[path],
+            "share_of_evaluations": round(counts[path] / total, 4) if total else 0.0,
+            "share_of_passes": round(counts[path] / len(passing), 4) if passing else 0.0,
+            "exclusive": exclusive[path],
+        }
+        for path in GATE_PATHS
+    }
+    overlap: dict[str, dict[str, dict]] = {}
+    for i, a in enumerate(GATE_PATHS):
+        for b in GATE_PATHS[i + 1 :]:
+            both = sum(a in p and b in p for p in passing)
+            if not both:
+                continue
+            union = counts[a] + counts[b] - both
+            overlap.setdefault(a, {})[b] = {"count": both, "jaccard": round(both / union, 4)}
+    return {
+        "evaluations": total,
+        "passing": len(passing),
+        "pass_rate": round(len(passing) / total, 4) if total else 0.0,
+        "paths": usage,
+        "overlap": overlap,
+        # Paths that never fire alone add nothing another path does not already admit
+        "redundant": [path for path in GATE_PATHS if counts[path] and not exclusive[path]],
+        "unused": [path for path in GATE_PATHS if not counts[path]],
+    }
+
+
+class QualityGateService:
+    """Backfills and summarizes historical quality-gate outcomes."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def _gate_settings(self) -> dict:
+        return {key: float(await self._settings.get(key, DEFAULTS[key])) for key in GATE_SETTINGS}
+
+    async def evaluate_symbol(self, symbol: str, settings: dict) -> list[dict]:
+        """Evaluate one security at every month end with enough price history."""
+        rows = [r for r in reversed(await self._db.get_prices(symbol)) if r.get("close") is not None]
+        dates = [str(r["date"])[:10] for r in rows]
+        closes = [float(r["close"]) for r in rows]
+        results = []
+        for month, end in month_end_indices(dates):
+            if end + 1 < MIN_SIGNAL_HISTORY:
+                continue
+            window = closes[max(0, end + 1 - EVALUATION_WINDOW) : end + 1]
+            results.append({"symbol": symbol, "month": month, **evaluate_gate_paths(window, settings)})
+        return results
+
+    async def backfill(self, symbols: list[str] | None = None) -> dict:
+        """Recompute the matrix for the given securities (default: the whole universe).
+
+        Returns:
+            Securities and evaluations processed, and the aggregate statistics
+        """
+        if symbols is None:
+            symbols = [s["symbol"] for s in await self._db.get_all_securities(active_only=False)]
+        settings = await self._gate_settings()
+        evaluated = 0
+        for symbol in symbols:
+            rows = await self.evaluate_symbol(symbol, settings)
+            await self._db.replace_quality_gate_backfill(symbol, rows, settings)
+            evaluated += len(rows)
+        logger.info(f"Quality-gate backfill: {evaluated} evaluations across {len(symbols)} securities")
+        return {"securities": len(symbols), "evaluations": evaluated, "stats": await self.stats()}
+
+    async def stats(self, since: str | None = None, until: str | None = None) -> dict:
+        """Aggregate path statistics over the stored matrix, optionally limited to a month range."""
+        rows = await self._db.get_quality_gate_backfill(since=since, until=until)
+        result = gate_statistics(rows)
+        result["securities"] = len({r["symbol"] for r in rows})
+        months = [r["month"] for r in rows]
+        result["months"] = {"from": min(months, default=None), "to": max(months, default=None)}
+        result["settings"] = json.loads(rows[0]["settings"]) if rows and rows[0].get("settings") else None
+        return result
@@ -0,0 +1,322 @@
+"""Monthly reconciliation of the ledger against the broker report.
+
+For a calendar month, the broker report (trades, the in_outs cash block and the
+corporate actions block of GetBrokerReport) is compared line by line with the
+trades, cash_flows and dividends tables:
+
+- trades are matched on the broker trade id; side, quantity, price and
+  commission must agree
+- cash flows (deposits, withdrawals, fees, taxes) are matched on date, type,
+  currency and amount
+- dividends are matched on the corporate action id; the net amount and the
+  withholding tax (tax_amount plus external_tax) must agree
+
+Every difference becomes a discrepancy with a severity: high when it changes
+positions or cash (a missing entry, a different quantity, price or amount),
+medium for fees and withholding tax, low for dates. Numbers within
+reconciliation_tolerance are equal.
+
+Manual position adjustments made during the month are listed as well (high
+when they change a quantity, medium for a cost basis only), so a hand
+correction of what the broker reported is never signed off unseen.
+
+Each run is stored in reconciliations. A run without discrepancies is clean;
+otherwise it stays open until someone signs it off (with a note), which keeps
+the report and who accepted it for audit. Re-running a month adds a new run.
+"""
+
+from __future__ import annotations
+
+import calendar
+import logging
+from collections import defaultdict
+from datetime import date, datetime
+
+from sentinel.broker import Broker
+from sentinel.database import Database
+from sentinel.errors import Conflict, InvalidInput, NotFound, Unavailable
+from sentinel.services.dividends import withholding_tax
+from sentinel.services.notifications import record_notification
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+SEVERITIES = ("high", "medium", "low")
+STATUSES = ("clean", "open", "signed_off")
+# Cash flow types that move money in or out of the account
+MONEY_MOVEMENTS = ("card", "card_payout")
+
+
+def month_bounds(period: str) -> tuple[str, str]:
+    """First and last day (YYYY-MM-DD) of a YYYY-MM period."""
+    try:
+        first = datetime.strptime(period, "%Y-%m").date()
+    except ValueError as e:
+        raise InvalidInput(f"period must be YYYY-MM, got {period!r}", period=period) from e
+    last = first.replace(day=calendar.monthrange(first.year, first.month)[1])
+    return first.isoformat(), last.isoformat()
+
+
+def previous_period(today: date) -> str:
+    """The calendar month before today's, as YYYY-MM."""
+    year, month = (today.year, today.month - 1) if today.month > 1 else (today.year - 1, 12)
+    return f"{year:04d}-{month:02d}"
+
+
+def _num(value) -> float:
+    try:
+        return float(value or 0)
+    except (TypeError, ValueError):
+        return 0.0
+
+
+def _issue(section: str, key: str, severity: str, message: str, field: str | None = None, broker=None, ledger=None):
+    return {
+        "section": section,
+        "key": key,
+        "severity": severity,
+        "field": field,
+        "broker": broker,
+        "ledger": ledger,
+        "message": message,
+    }
+
+
+def _check(section: str, key: str, label: str, checks: list[tuple], tolerance: float) -> list[dict]:
+    """Discrepancies among (field, severity, broker value, ledger value) checks of one matched entry."""
+    issues = []
+    for field, severity, broker_value, ledger_value in checks:
+        if isinstance(broker_value, str):
+            differs = broker_value != ledger_value
+        else:
+            differs = abs(_num(broker_value) - _num(ledger_value)) > tolerance
+        if differs:
+            message = f"{label} {field.replace('_', ' ')} differs"
+            issues.append(_issue(section, key, severity, message, field, broker_value, ledger_value))
+    return issues
+
+
+def compare_trades(broker_trades: list[dict], ledger_trades: list[dict], tolerance: float) -> list[dict]:
+    """Discrepancies between broker trades and trades rows, matched on the broker trade id."""
+    issues = []
+    ledger = {str(t["broker_trade_id"]): t for t in ledger_trades}
+    for trade in broker_trades:
+        trade_id = str(trade.get("id", ""))
+        label = f"{trade.get('symbol') or trade.get('instr_nm', '')} trade {trade_id}"
+        local = ledger.pop(trade_id, None)
+        if local is None:
+            issues.append(_issue("trades", trade_id, "high", f"{label} is not in the ledger", broker=trade))
+            continue
+        local_day = datetime.fromtimestamp(local["executed_at"]).strftime("%Y-%m-%d")
+        checks = [
+            ("side", "high", trade.get("side") or local["side"], local["side"]),
+            ("quantity", "high", _num(trade.get("q")), local["quantity"]),
+            ("price", "high", _num(trade.get("p")), local["price"]),
+            ("commission", "medium", _num(trade.get("commission")), local["commission"]),
+            ("date", "low", str(trade.get("date") or local_day)[:10], local_day),
+        ]
+        issues += _check("trades", trade_id, label, checks, tolerance)
+    for trade_id, local in ledger.items():
+        row = {k: v for k, v in local.items() if k != "raw_data"}
+        message = f"{local['symbol']} trade {trade_id} is not in the broker report"
+        issues.append(_issue("trades", trade_id, "high", message, ledger=row))
+    return issues
+
+
+def _flow_key(flow: dict) -> tuple[str, str, str, float]:
+    day = str(flow.get("date", ""))[:10]
+    return day, flow.get("type_id", ""), flow.get("currency", ""), round(_num(flow.get("amount")), 2)
+
+
+def compare_cash_flows(broker_flows: list[dict], ledger_flows: list[dict]) -> list[dict]:
+    """Broker cash flows without a ledger row and ledger rows without a broker entry."""
+    unmatched: dict[tuple, list[dict]] = defaultdict(list)
+    for flow in ledger_flows:
+        unmatched[_flow_key(flow)].append(flow)
+
+    issues = []
+    for flow in broker_flows:
+        key = _flow_key(flow)
+        if unmatched[key]:
+            unmatched[key].pop()
+            continue
+        day, type_id, currency, amount = key
+        severity = "high" if type_id in MONEY_MOVEMENTS else "medium"
+        message = f"{type_id} of {amount:.2f} {currency} on {day} is not in the ledger"
+        issues.append(_issue("cash_flows", f"{day}/{type_id}", severity, message, broker=flow))
+    for (day, type_id, currency, amount), flows in unmatched.items():
+        for flow in flows:
+            severity = "high" if type_id in MONEY_MOVEMENTS else "medium"
+            message = f"{type_id} of {amount:.2f} {currency} on {day} is not in the broker report"
+            row = {k: v for k, v in flow.items() if k != "raw_data"}
+            issues.append(_issue("cash_flows", f"{day}/{type_id}", severity, message, ledger=row))
+    return issues
+
+
+def compare_dividends(broker_actions: list[dict], ledger_dividends: list[dict], tolerance: float) -> list[dict]:
+    """Discrepancies between broker dividends and dividends rows, matched on the corporate action id."""
+    import json
+
+    issues = []
+    ledger = {str(d["id"]): d for d in ledger_dividends}
+    for action in broker_actions:
+        if action.get("type_id") != "dividend":
+            continue
+        action_id = str(action.get("corporate_action_id", ""))
+        label = f"{action.get('ticker', '')} dividend {action_id}"
+        local = ledger.pop(action_id, None)
+        if local is None:
+            issues.append(_issue("dividends", action_id, "high", f"{label} is not in the ledger", broker=action))
+            continue
+        data = json.loads(local["data"]) if isinstance(local.get("data"), str) else local.get("data") or {}
+        checks = [
+            ("currency", "high", action.get("currency") or local["currency"], local["currency"]),
+            ("amount", "high", _num(action.get("amount")), local["amount"]),
+            ("withholding_tax", "medium", withholding_tax(action), withholding_tax(data)),
+            ("date", "low", str(action.get("date") or local["date"])[:10], str(local["date"])[:10]),
+        ]
+        issues += _check("dividends", action_id, label, checks, tolerance)
+    for action_id, local in ledger.items():
+        row = {k: v for k, v in local.items() if k != "data"}
+        message = f"{local['symbol']} dividend {action_id} is not in the broker report"
+        issues.append(_issue("dividends", action_id, "high", message, ledger=row))
+    return issues
+
+
+def flag_adjustments(adjustments: list[dict]) -> list[dict]:
+    """A discrepancy for every manual position adjustment."""
+    issues = []
+    for adjustment in adjustments:
+        changes = []
+        severity = "medium"
+        if _num(adjustment["quantity_before"]) != _num(adjustment["quantity_after"]):
+            changes.append(f"quantity {adjustment['quantity_before']:g} -> {adjustment['quantity_after']:g}")
+            severity = "high"
+        if adjustment["avg_cost_before"] != adjustment["avg_cost_after"]:
+            changes.append(f"average cost {adjustment['avg_cost_before']} -> {adjustment['avg_cost_after']}")
+        message = (
+            f"{adjustment['symbol']} position adjusted by hand by {adjustment['created_by']} "
+            f"({', '.join(changes)}): {adjustment['reason']}"
+        )
+        issues.append(_issue("adjustments", str(adjustment["id"]), severity, message, ledger=adjustment))
+    return issues
+
+
+class ReconciliationService:
+    """Reconciles the ledger with the broker report month by month."""
+
+    def __init__(self, db: Database | None = None, broker: Broker | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._settings = settings or Settings()
+
+    async def reconcile(self, period: str) -> dict:
+        """Compare one month of the broker report with the ledger and store the result.
+
+        Args:
+            period: Month as YYYY-MM
+
+        Returns:
+            The stored reconciliation
+
+        Raises:
+            InvalidInput: Bad period, or a month that has not ended yet
+            Unavailable: Broker not connected
+        """
+        start, end = month_bounds(period)
+        if end >= date.today().isoformat():
+            raise InvalidInput(f"{period} has not ended yet", period=period)
+        if not self._broker.connected:
+            raise Unavailable("Broker not connected", code="broker_unavailable")
+        tolerance = float(await self._settings.get("reconciliation_tolerance", 0.01) or 0)
+
+        broker_trades = await self._broker.get_trades_history(start_date=start, end_date=end)
+        broker_flows = await self._broker.get_cash_flows(start_date=start, end_date=end)
+        broker_actions = await self._broker.get_corporate_actions(start_date=start, end_date=end)
+        # Archived round trips are still part of the ledger
+        ledger_trades = await self._db.get_trades(start_date=start, end_date=end, limit=100_000, include_archived=True)
+        ledger_flows = await self._db.get_cash_flows(start_date=start, end_date=end)
+        ledger_dividends = [d for d in await self._db.get_dividends(start_date=start) if d["date"][:10] <= end]
+        adjustments = await self._db.get_position_adjustments(
+            start_ts=int(datetime.fromisoformat(start).timestamp()),
+            end_ts=int(datetime.fromisoformat(end).timestamp()) + 86_399,
+            limit=100_000,
+        )
+
+        discrepancies = (
+            compare_trades(broker_trades, ledger_trades, tolerance)
+            + compare_cash_flows(broker_flows, ledger_flows)
+            + compare_dividends(broker_actions, ledger_dividends, tolerance)
+            + flag_adjustments(adjustments)
+        )
+        summary = {
+            "trades": {"broker": len(broker_trades), "ledger": len(ledger_trades)},
+            "cash_flows": {"broker": len(broker_flows), "ledger": len(ledger_flows)},
+            "dividends": {
+                "broker": sum(1 for a in broker_actions if a.get("type_id") == "dividend"),
+                "ledger": len(ledger_dividends),
+            },
+            "adjustments": len(adjustments),
+            "severity": {s: sum(1 for d in discrepancies if d["severity"] == s) for s in SEVERITIES},
+        }
+        status = "open" if discrepancies else "clean"
+        reconciliation_id = await self._db.save_reconciliation(period, status, summary, discrepancies)
+        logger.info(f"Reconciliation of {period}: {len(discrepancies)} discrepancies ({summary['severity']})")
+
+        if discrepancies:
+            counts = ", ".join(f"{n} {s}" for s, n in summary["severity"].items() if n)
+            await record_notification(
+                self._db,
+                "warning" if summary["severity"]["high"] else "info",
+                "reconciliation",
+                f"Broker report for {period} differs from the ledger: {counts}",
+                message="; ".join(d["message"] for d in discrepancies[:5]),
+            )
+        return await self.get(reconciliation_id)
+
+    async def reconcile_if_due(self, today: date | None = None) -> dict | None:
+        """Reconcile the previous month unless it already has a run."""
+        period = previous_period(today or date.today())
+        if await self._db.get_reconciliations(period=period, limit=1):
+            return None
+        return await self.reconcile(period)
+
+    async def list(self, period: str | None = None, limit: int = 24) -> list[dict]:
+        """Stored runs (without their discrepancies), most recent first."""
+        return await self._db.get_reconciliations(period=period, limit=limit)
+
+    async def get(self, reconciliation_id: int) -> dict:
+        """A stored run with its discrepancies.
+
+        Raises:
+            NotFound: Unknown id
+        """
+        reconciliation = await self._db.get_reconciliation(reconciliation_id)
+        if reconciliation is None:
+            raise NotFound(f"Reconciliation {reconciliation_id} not found", id=reconciliation_id)
+        return reconciliation
+
+    async def sign_off(self, reconciliation_id: int, signed_off_by: str, note: str | None = None) -> dict:
+        """Accept a run's discrepancies (or confirm a clean run) for audit.
+
+        Raises:
+            NotFound: Unknown id
+            Conflict: Already signed off
+            InvalidInput: Open discrepancies without a note
+        """
+        reconciliation = await self.get(reconciliation_id)
+        if reconciliation["status"] == "signed_off":
+            raise Conflict(f"Reconciliation {reconciliation_id} is already signed off", id=reconciliation_id)
+        if reconciliation["status"] == "open" and not (note or "").strip():
+            raise InvalidInput(
+                "A note is required to sign off a reconciliation with discrepancies", id=reconciliation_id
+            )
+        await self._db.sign_off_reconciliation(reconciliation_id, signed_off_by, (note or "").strip() or None)
+        return await self.get(reconciliation_id)
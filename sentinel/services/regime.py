@@ -0,0 +1,115 @@
+"""Per-region market regime from the ensemble detector.
+
+Each region is a primary geography of the tracked securities. Its index is the
+equal-weighted country aggregate computed by the aggregate:compute job, and its
+breadth comes from the securities listed in it. Results are stored so the planner
+and the API read the latest classification without recomputing it.
+
+Which detectors count, and how, comes from the regime_detector_weights,
+regime_vote_policy and regime_threshold settings.
+"""
+
+from __future__ import annotations
+
+import logging
+
+from sentinel.database import Database
+from sentinel.strategy.regime import DETECTORS, VOTE_POLICIES, detect_regime, parse_detector_weights
+from sentinel.utils.strings import parse_csv_field
+
+logger = logging.getLogger(__name__)
+
+# Same as sentinel.aggregates.COUNTRY_AGG_PREFIX (not imported: that module pulls in pandas)
+REGION_INDEX_PREFIX = "_AGG_COUNTRY_"
+AGGREGATE_PREFIX = "_AGG_"
+# Enough daily closes for a 200-day average and a year of 20-day realized volatility
+HISTORY_DAYS = 300
+
+
+def region_index_symbol(region: str) -> str:
+    """Aggregate price series standing in for a region's index."""
+    return f"{REGION_INDEX_PREFIX}{region.upper().replace(' ', '_')}"
+
+
+def _closes(rows: list[dict]) -> list[float]:
+    """Closes oldest first from price rows stored newest first."""
+    return [float(row["close"]) for row in reversed(rows) if row.get("close") is not None]
+
+
+class RegimeService:
+    """Computes and serves the market regime of every tracked region."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def regions(self) -> dict[str, list[str]]:
+        """Region -> symbols of the active securities whose primary geography it is."""
+        members: dict[str, list[str]] = {}
+        for sec in await self._db.get_all_securities(active_only=True):
+            geographies = parse_csv_field(sec.get("geography"))
+            if sec["symbol"].startswith(AGGREGATE_PREFIX) or not geographies:
+                continue
+            members.setdefault(geographies[0], []).append(sec["symbol"])
+        return members
+
+    async def ensemble(self) -> dict:
+        """Configured detector weights, vote policy and threshold (defaults where a setting is invalid)."""
+        from sentinel.settings import DEFAULTS
+
+        raw = await self._db.get_setting("regime_detector_weights", DEFAULTS["regime_detector_weights"])
+        try:
+            weights = parse_detector_weights(raw)
+        except ValueError as e:
+            logger.warning(f"Invalid regime_detector_weights ({e}), using defaults")
+            weights = dict(DEFAULTS["regime_detector_weights"])
+        policy = await self._db.get_setting("regime_vote_policy", DEFAULTS["regime_vote_policy"])
+        if policy not in VOTE_POLICIES:
+            policy = DEFAULTS["regime_vote_policy"]
+        threshold = await self._db.get_setting("regime_threshold", DEFAULTS["regime_threshold"])
+        if isinstance(threshold, bool) or not isinstance(threshold, (int, float)):
+            threshold = DEFAULTS["regime_threshold"]
+        return {"weights": weights, "policy": policy, "threshold": float(threshold)}
+
+    async def detectors(self) -> dict:
+        """Registered detectors with their configured weight, plus the vote policy and threshold."""
+        ensemble = await self.ensemble()
+        return {
+            "policy": ensemble["policy"],
+            "threshold": ensemble["threshold"],
+            "detectors": [
+                {"name": name, "description": detector.description, "weight": ensemble["weights"].get(name, 0.0)}
+                for name, detector in DETECTORS.items()
+            ],
+        }
+
+    async def compute(self) -> dict[str, dict]:
+        """Classify every region and replace the stored regimes.
+
+        Returns:
+            region -> {regime, score, confidence, signals}
+        """
+        ensemble = await self.ensemble()
+        members = await self.regions()
+        symbols = [region_index_symbol(r) for r in members] + [s for group in members.values() for s in group]
+        prices = await self._db.get_prices_bulk(symbols, days=HISTORY_DAYS) if symbols else {}
+        states = {}
+        for region, group in sorted(members.items()):
+            index = _closes(prices.get(region_index_symbol(region), []))
+            constituents = [_closes(prices.get(symbol, [])) for symbol in group]
+            if not index and not any(constituents):
+                continue
+            states[region] = detect_regime(
+                index, constituents, ensemble["weights"], ensemble["threshold"], ensemble["policy"]
+            )
+        await self._db.replace_regime_states(states)
+        await self._db.cache_clear("planner:")
+        return states
+
+    async def status(self) -> dict:
+        """Stored regimes, one entry per region."""
+        return {"regions": await self._db.get_regime_states()}
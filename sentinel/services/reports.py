@@ -0,0 +1,341 @@
+"""Daily/weekly digest report.
+
+Compiles what happened over the period - portfolio value change, trades
+executed, dividends received, current recommendations (flagging the ones not
+in the previous digest), risk warnings from the notification inbox and the
+biggest movers among held positions, with any user-defined computed columns -
+and renders it as Markdown and HTML.
+
+Reports are stored (downloadable at /api/reports/latest) and, when
+report_digest_notify is on, pushed to the notification inbox and to webhooks
+subscribed to the digest_report event.
+"""
+
+from __future__ import annotations
+
+import html
+import logging
+import time
+from datetime import datetime, timezone
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.services.computed_columns import ComputedColumnService, evaluate_columns, row_metrics
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+# period -> days covered
+PERIODS = {"daily": 1, "weekly": 7}
+TOP_MOVERS = 5
+RISK_SEVERITIES = ("warning", "error")
+# A scheduled digest is due once the previous one is this close to a full period old
+DUE_TOLERANCE_SECONDS = 3600
+
+
+def _date(ts: int) -> str:
+    return datetime.fromtimestamp(ts, tz=timezone.utc).strftime("%Y-%m-%d")
+
+
+def _eur(value: float) -> str:
+    return f"EUR {value:,.2f}"
+
+
+def _signed(value: float, suffix: str = "") -> str:
+    return f"{value:+,.2f}{suffix}"
+
+
+def snapshot_value(snapshot: dict) -> float:
+    """Total EUR value (positions plus cash) of a portfolio snapshot."""
+    data = snapshot["data"]
+    positions = sum(float(p.get("value_eur") or 0.0) for p in data.get("positions", {}).values())
+    return positions + float(data.get("cash_eur") or 0.0)
+
+
+def _computed(row: dict) -> str:
+    values = row.get("computed") or {}
+    if not values:
+        return ""
+    return " (" + ", ".join(f"{k}={'n/a' if v is None else f'{v:,.4g}'}" for k, v in values.items()) + ")"
+
+
+def report_sections(data: dict) -> list[tuple[str, list[str]]]:
+    """(heading, lines) of a compiled report, shared by both renderings."""
+    portfolio = data["portfolio"]
+    if portfolio:
+        value_lines = [
+            f"Value: {_eur(portfolio['end_value_eur'])} "
+            f"({_signed(portfolio['change_eur'])} EUR, {_signed(portfolio['change_pct'], '%')})"
+        ]
+    else:
+        value_lines = []
+
+    trades = [
+        f"{t['side']} {t['quantity']:g} x {t['symbol']} @ {t['price']:.2f} {t['currency']} ({_eur(t['value_eur'])})"
+        for t in data["trades"]
+    ]
+    dividends = [
+        f"{d['symbol']}: {d['amount']:.2f} {d['currency']} ({_eur(d['value_eur'])})" for d in data["dividends"]
+    ]
+    recommendations = [
+        f"{r['action'].upper()} {r['quantity']:g} x {r['symbol']} ({_eur(r['value_eur'])}): {r['reason']}"
+        + (" [new]" if r["new"] else "")
+        for r in data["recommendations"]
+    ]
+    warnings = [
+        f"[{w['severity']}] {w['title']}"
+        + (f" ({w['occurrences']}x)" if w["occurrences"] > 1 else "")
+        + (f": {w['message']}" if w["message"] else "")
+        for w in data["risk_warnings"]
+    ]
+    movers = [f"{m['symbol']}: {_signed(m['change_pct'], '%')}{_computed(m)}" for m in data["top_movers"]]
+
+    dividend_total = sum(d["value_eur"] for d in data["dividends"])
+    return [
+        ("Portfolio", value_lines),
+        (f"Trades executed ({len(trades)})", trades),
+        (f"Dividends received ({_eur(dividend_total)})", dividends),
+        (f"Recommendations ({sum(r['new'] for r in data['recommendations'])} new)", recommendations),
+        (f"Risk warnings ({len(warnings)})", warnings),
+        ("Top movers", movers),
+    ]
+
+
+def report_title(data: dict) -> str:
+    end = _date(data["period_end"])
+    if data["period"] == "daily":
+        return f"Daily digest {end}"
+    return f"Weekly digest {_date(data['period_start'])} to {end}"
+
+
+def render_markdown(data: dict) -> str:
+    """Markdown rendering of a compiled report."""
+    lines = [f"# {report_title(data)}"]
+    for heading, items in report_sections(data):
+        lines += ["", f"## {heading}", ""]
+        lines += [f"- {item}" for item in items] or ["None"]
+    return "\n".join(lines) + "\n"
+
+
+def render_html(data: dict) -> str:
+    """Standalone HTML rendering of a compiled report."""
+    title = html.escape(report_title(data))
+    parts = [f"<!DOCTYPE html><html><head><meta charset='utf-8'><title>{title}</title></head><body><h1>{title}</h1>"]
+    for heading, items in report_sections(data):
+        parts.append(f"<h2>{html.escape(heading)}</h2>")
+        if items:
+            parts.append("<ul>" + "".join(f"<li>{html.escape(item)}</li>" for item in items) + "</ul>")
+        else:
+            parts.append("<p>None</p>")
+    parts.append("</body></html>")
+    return "\n".join(parts)
+
+
+class ReportService:
+    """Compiles, stores and delivers digest reports."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+        planner=None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+        self._planner = planner
+
+    async def compile(self, period: str = "daily", now: int | None = None) -> dict:
+        """Collect the report sections for the period ending now.
+
+        Raises:
+            ValueError: If period is not one of PERIODS
+        """
+        if period not in PERIODS:
+            raise ValueError(f"Unknown period: {period}")
+        end = now or int(time.time())
+        start = end - PERIODS[period] * 86400
+        return {
+            "period": period,
+            "period_start": start,
+            "period_end": end,
+            "portfolio": await self._portfolio_change(start, period),
+            "trades": await self._trades(start, end),
+            "dividends": await self._dividends(start, end),
+            "recommendations": await self._recommendations(period),
+            "risk_warnings": await self._risk_warnings(start),
+            "top_movers": await self._top_movers(start, end),
+        }
+
+    async def generate(self, period: str | None = None, notify: bool | None = None, now: int | None = None) -> dict:
+        """Compile, render and store a report, then deliver it if enabled. Returns the stored report."""
+        period = period or await self._settings.get("report_digest_period", "daily")
+        data = await self.compile(period, now)
+        report_id = await self._db.save_report(
+            period,
+            data["period_start"],
+            data["period_end"],
+            data,
+            render_markdown(data),
+            render_html(data),
+            keep=int(await self._settings.get("report_keep", 60)),
+        )
+        report = await self._db.get_report(report_id)
+        if notify is None:
+            notify = bool(await self._settings.get("report_digest_notify", True))
+        if notify:
+            await self.deliver(report)
+        return report
+
+    async def generate_if_due(self, now: int | None = None) -> dict | None:
+        """Generate the configured digest unless the previous one still covers this period."""
+        now = now or int(time.time())
+        period = await self._settings.get("report_digest_period", "daily")
+        if period not in PERIODS:
+            logger.warning(f"Unknown report_digest_period '{period}', using daily")
+            period = "daily"
+        latest = await self._db.get_report(period=period)
+        if latest and now - latest["period_end"] < PERIODS[period] * 86400 - DUE_TOLERANCE_SECONDS:
+            return None
+        return await self.generate(period, now=now)
+
+    async def deliver(self, report: dict) -> None:
+        """Push a report to the notification inbox and digest_report webhooks."""
+        from sentinel.services.notifications import record_notification
+
+        await record_notification(
+            self._db,
+            "info",
+            "report",
+            report_title(report["data"]),
+            event="digest_report",
+            message=report["markdown"],
+            link=f"/api/reports/{report['id']}?format=html",
+            dedupe_key=f"digest:{report['period']}",
+        )
+
+    async def _portfolio_change(self, start: int, period: str) -> dict | None:
+        snapshots = await self._db.get_portfolio_snapshots(days=PERIODS[period] + 7)
+        if not snapshots:
+            return None
+        baseline = next((s for s in reversed(snapshots) if s["date"] <= start), snapshots[0])
+        start_value, end_value = snapshot_value(baseline), snapshot_value(snapshots[-1])
+        change = end_value - start_value
+        return {
+            "start_value_eur": round(start_value, 2),
+            "end_value_eur": round(end_value, 2),
+            "change_eur": round(change, 2),
+            "change_pct": round(change / start_value * 100, 2) if start_value else 0.0,
+        }
+
+    async def _trades(self, start: int, end: int) -> list[dict]:
+        rows = await self._db.get_trades(start_date=_date(start - 86400), limit=1000)
+        trades = []
+        for row in reversed(rows):
+            if not start <= row["executed_at"] <= end:
+                continue
+            security = await self._db.get_security(row["symbol"]) or {}
+            currency = security.get("currency") or "EUR"
+            value = row["quantity"] * row["price"]
+            trades.append(
+                {
+                    "symbol": row["symbol"],
+                    "side": row["side"],
+                    "quantity": row["quantity"],
+                    "price": row["price"],
+                    "currency": currency,
+                    "value_eur": round(await self._currency.to_eur(value, currency), 2),
+                    "executed_at": row["executed_at"],
+                }
+            )
+        return trades
+
+    async def _dividends(self, start: int, end: int) -> list[dict]:
+        rows = await self._db.get_dividends(start_date=_date(start))
+        return [
+            {
+                "symbol": row["symbol"],
+                "date": row["date"],
+                "amount": row["amount"],
+                "currency": row["currency"],
+                "value_eur": round(row["value"], 2),
+            }
+            for row in reversed(rows)
+            if str(row["date"])[:10] <= _date(end)
+        ]
+
+    async def _recommendations(self, period: str) -> list[dict]:
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db, currency=self._currency)
+        try:
+            recommendations = await self._planner.get_recommendations()
+        except Exception as e:
+            logger.warning(f"Digest report: recommendations unavailable: {e}")
+            return []
+        previous = await self._db.get_report(period=period)
+        seen = {(r["symbol"], r["action"]) for r in (previous["data"]["recommendations"] if previous else [])}
+        return [
+            {
+                "symbol": rec.symbol,
+                "action": rec.action,
+                "quantity": rec.quantity,
+                "value_eur": round(abs(rec.value_delta_eur), 2),
+                "reason": rec.reason,
+                "new": (rec.symbol, rec.action) not in seen,
+            }
+            for rec in recommendations
+        ]
+
+    async def _risk_warnings(self, start: int) -> list[dict]:
+        notifications = await self._db.get_notifications(limit=200)
+        return [
+            {
+                "severity": n["severity"],
+                "category": n["category"],
+                "title": n["title"],
+                "message": n["message"],
+                "occurrences": n["occurrences"],
+                "link": n["link"],
+            }
+            for n in notifications
+            if n["severity"] in RISK_SEVERITIES and n["updated_at"] >= start
+        ]
+
+    async def _top_movers(self, start: int, end: int) -> list[dict]:
+        positions = {p["symbol"]: p for p in await self._db.get_all_positions()}
+        symbols = list(positions)
+        start_date, end_date = _date(start), _date(end)
+        days = (end - start) // 86400
+        prices = await self._db.get_prices_for_symbols(symbols, days=days + 10, end_date=end_date)
+        movers = []
+        for symbol, rows in prices.items():
+            # Rows are newest first
+            closes = [(str(r["date"])[:10], r["close"]) for r in rows if r.get("close")]
+            if not closes:
+                continue
+            before = next((close for day, close in closes if day <= start_date), None)
+            if before is None or before <= 0:
+                continue
+            change = (closes[0][1] / before - 1) * 100
+            movers.append({"symbol": symbol, "change_pct": round(change, 2), "price": closes[0][1]})
+        movers.sort(key=lambda m: abs(m["change_pct"]), reverse=True)
+        movers = movers[:TOP_MOVERS]
+        # Computed columns over the mover, its position and the security's metadata
+        columns = await ComputedColumnService(db=self._db).compiled()
+        if columns and movers:
+            securities = {s["symbol"]: s for s in await self._db.get_all_securities(active_only=False)}
+            for mover in movers:
+                metrics = row_metrics({**positions[mover["symbol"]], **mover}, securities.get(mover["symbol"]))
+                mover["computed"] = evaluate_columns(columns, metrics)
+        return movers
@@ -0,0 +1,106 @@
+"""Data retention and database vacuuming.
+
+The archive:retention job deletes rows older than the retention_policies
+setting allows (table -> days, see RETENTION_COLUMNS for the supported tables
+and which rows are prunable), and the archive:vacuum job releases the freed
+pages of the main database and the cold price tier and refreshes their planner
+statistics, so the SD card does not fill up with history nobody reads. The last
+report of each job is kept in the cache for /api/archive/retention.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import time
+
+from sentinel.database import Database
+from sentinel.database.main import RETENTION_COLUMNS
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+RETENTION_REPORT_KEY = "archive:retention_report"
+VACUUM_REPORT_KEY = "archive:vacuum_report"
+
+
+def parse_retention_policies(raw) -> dict[str, int]:
+    """Validate a retention_policies value: supported table -> non-negative whole days.
+
+    Raises:
+        ValueError: On an unknown table or invalid number of days
+    """
+    if not isinstance(raw, dict):
+        raise ValueError("retention_policies must be an object of table -> days")
+    policies = {}
+    for table, days in raw.items():
+        if table not in RETENTION_COLUMNS:
+            raise ValueError(f"Unsupported retention table {table} (supported: {', '.join(sorted(RETENTION_COLUMNS))})")
+        if isinstance(days, bool) or not isinstance(days, (int, float)) or days < 0 or int(days) != days:
+            raise ValueError(f"Retention days of {table} must be a whole number >= 0")
+        policies[table] = int(days)
+    return policies
+
+
+class RetentionService:
+    """Prunes tables by their retention policy and vacuums the databases."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def policies(self) -> dict[str, int]:
+        """Configured policies (a malformed setting prunes nothing)."""
+        try:
+            return parse_retention_policies(await self._settings.get("retention_policies", {}) or {})
+        except ValueError as e:
+            logger.warning(f"Ignoring invalid retention_policies: {e}")
+            return {}
+
+    async def prune(self, now: int | None = None) -> dict:
+        """Delete rows past their table's retention period.
+
+        Returns:
+            dict with run_at, per-table days and deleted rows, and the total deleted
+        """
+        now = int(now or time.time())
+        tables = {}
+        for table, days in sorted((await self.policies()).items()):
+            if days <= 0:
+                continue
+            tables[table] = {"days": days, "deleted": await self._db.prune_table(table, now - days * 86400)}
+        report = {"run_at": now, "tables": tables, "deleted": sum(t["deleted"] for t in tables.values())}
+        await self._db.cache_set(RETENTION_REPORT_KEY, json.dumps(report))
+        return report
+
+    async def vacuum(self) -> dict:
+        """Reclaim free pages and run ANALYZE on every database.
+
+        Returns:
+            dict with run_at, per-database sizes before/after, and the total bytes freed
+        """
+        databases = await self._db.vacuum()
+        report = {
+            "run_at": int(time.time()),
+            "databases": databases,
+            "freed_bytes": sum(d["bytes_before"] - d["bytes_after"] for d in databases),
+        }
+        await self._db.cache_set(VACUUM_REPORT_KEY, json.dumps(report))
+        return report
+
+    async def status(self) -> dict:
+        """Policies, supported tables and the last prune and vacuum reports."""
+        retention = await self._db.cache_get(RETENTION_REPORT_KEY)
+        vacuum = await self._db.cache_get(VACUUM_REPORT_KEY)
+        return {
+            "policies": await self.policies(),
+            "supported_tables": sorted(RETENTION_COLUMNS),
+            "last_retention": json.loads(retention) if retention else None,
+            "last_vacuum": json.loads(vacuum) if vacuum else None,
+        }
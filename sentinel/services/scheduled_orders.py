@@ -0,0 +1,410 @@
+"""Orders scheduled in advance: monthly buys and one-off future-dated orders.
+
+A scheduled order names its securities directly (symbols, value split evenly)
+or picks the top_n highest scored buyable securities of a geography/industry
+group when it runs. Monthly orders fall due on day_of_month, one-off orders on
+their run date; the trading:scheduled_orders job places due orders leg by leg
+once each security's market is open:
+
+- any: any time the market is open
+- open: within scheduled_orders_window_minutes after the session opens
+- close: within scheduled_orders_window_minutes before the session closes
+
+Every leg goes through the same checks as planner trades at execution time: live
+trading mode, circuit breaker, the security being active and tradable, lot
+rounding, and Security.buy / sell (cool-off, cash and currency checks). Legs and
+their outcome are kept in run_state, so a leg is never placed twice for the same
+due date. Once every leg has run the order moves to its next month (or completes);
+an order still not placed scheduled_orders_grace_days after its due date is
+skipped as missed.
+"""
+
+from __future__ import annotations
+
+import json
+import logging
+import time
+from datetime import date, datetime, timedelta, timezone
+from typing import Awaitable, Callable
+
+from sentinel.audit import record_action
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.planner.models import TradeRecommendation
+from sentinel.settings import Settings
+from sentinel.utils.quantity import floor_to_lot, lot_step
+from sentinel.utils.strings import parse_csv_field
+
+logger = logging.getLogger(__name__)
+
+ACTIONS = ("buy", "sell")
+FREQUENCIES = ("once", "monthly")
+TIMINGS = ("any", "open", "close")
+GROUP_TYPES = ("geography", "industry")
+STATUSES = ("active", "paused", "completed", "cancelled")
+# Statuses the API may set; completed is reached by running
+SETTABLE_STATUSES = ("active", "paused", "cancelled")
+
+# Tradernet reports session times (o, c) in the market's local time and dt as the
+# market's offset in minutes from Moscow time (UTC+3)
+BROKER_CLOCK_OFFSET = timedelta(hours=3)
+
+# Places one order leg, returning True when the broker accepted it
+PlaceOrder = Callable[[TradeRecommendation, str], Awaitable[bool]]
+
+
+def next_monthly_date(day_of_month: int, after: date, inclusive: bool = True) -> date:
+    """First date on day_of_month on or after (inclusive) / strictly after `after`."""
+    candidate = after.replace(day=day_of_month)
+    if candidate < after or (candidate == after and not inclusive):
+        month = after.month % 12 + 1
+        candidate = candidate.replace(year=after.year + (after.month == 12), month=month)
+    return candidate
+
+
+def _clock(value) -> int | None:
+    """Minutes after midnight of an HH:MM[:SS] string."""
+    try:
+        parts = str(value).split(":")
+        return int(parts[0]) * 60 + int(parts[1])
+    except (IndexError, TypeError, ValueError):
+        return None
+
+
+def timing_allows(market: dict | None, timing: str, now: datetime, window_minutes: float) -> bool:
+    """Whether an order with the timing preference may be placed on the market now (UTC)."""
+    if not market or market.get("s") != "OPEN":
+        return False
+    if timing == "any":
+        return True
+    opens, closes = _clock(market.get("o")), _clock(market.get("c"))
+    if opens is None or closes is None:
+        # Without session times an open market is the best available signal
+        return True
+    try:
+        offset = timedelta(minutes=float(market.get("dt") or 0))
+    except (TypeError, ValueError):
+        offset = timedelta(0)
+    local = now.astimezone(timezone.utc) + BROKER_CLOCK_OFFSET + offset
+    minute = local.hour * 60 + local.minute
+    if timing == "open":
+        return opens <= minute < opens + window_minutes
+    return closes - window_minutes <= minute < closes
+
+
+def market_id(security: dict) -> str | None:
+    """Broker market id from the security's stored metadata."""
+    data = security.get("data")
+    if not data:
+        return None
+    try:
+        info = json.loads(data) if isinstance(data, str) else data
+        value = info.get("mrkt", {}).get("mkt_id")
+    except (json.JSONDecodeError, AttributeError, TypeError, ValueError):
+        return None
+    return str(value) if value is not None else None
+
+
+def validate_order(spec: dict, today: date) -> dict:
+    """Normalize a scheduled order request.
+
+    Raises:
+        ValueError: Listing every problem with the request
+    """
+    errors = []
+    action = spec.get("action", "buy")
+    if action not in ACTIONS:
+        errors.append("action must be buy or sell")
+    frequency = spec.get("frequency", "once")
+    if frequency not in FREQUENCIES:
+        errors.append("frequency must be once or monthly")
+    timing = spec.get("timing", "any")
+    if timing not in TIMINGS:
+        errors.append("timing must be any, open or close")
+
+    symbols = spec.get("symbols")
+    if isinstance(symbols, str):
+        symbols = parse_csv_field(symbols)
+    group = spec.get("group")
+    if symbols and group:
+        errors.append("give either symbols or group, not both")
+    elif symbols:
+        if not isinstance(symbols, list) or not all(isinstance(s, str) and s.strip() for s in symbols):
+            errors.append("symbols must be a list of symbols")
+        else:
+            symbols = list(dict.fromkeys(s.strip() for s in symbols))
+    elif group:
+        if not isinstance(group, dict) or group.get("type") not in GROUP_TYPES or not group.get("name"):
+            errors.append("group must be {type: geography or industry, name, top_n}")
+        elif action != "buy":
+            errors.append("group orders can only buy")
+        top_n = group.get("top_n", 1) if isinstance(group, dict) else 1
+        if not isinstance(top_n, int) or isinstance(top_n, bool) or top_n < 1:
+            errors.append("group top_n must be a positive integer")
+    else:
+        errors.append("symbols or group is required")
+
+    amount, quantity = spec.get("amount_eur"), spec.get("quantity")
+    if (amount is None) == (quantity is None):
+        errors.append("give either amount_eur or quantity")
+    elif amount is not None and (not isinstance(amount, (int, float)) or isinstance(amount, bool) or amount <= 0):
+        errors.append("amount_eur must be a positive number")
+    elif quantity is not None:
+        if not isinstance(quantity, (int, float)) or isinstance(quantity, bool) or quantity <= 0:
+            errors.append("quantity must be a positive number")
+        elif not symbols or len(symbols) != 1:
+            errors.append("quantity needs exactly one symbol")
+
+    day_of_month, next_run = None, None
+    if frequency == "monthly":
+        day_of_month = spec.get("day_of_month")
+        if not isinstance(day_of_month, int) or isinstance(day_of_month, bool) or not 1 <= day_of_month <= 28:
+            errors.append("day_of_month must be between 1 and 28")
+        else:
+            next_run = next_monthly_date(day_of_month, today)
+    elif frequency == "once":
+        try:
+            next_run = date.fromisoformat(str(spec.get("run_date")))
+        except ValueError:
+            errors.append("run_date must be a YYYY-MM-DD date")
+        else:
+            if next_run < today:
+                errors.append("run_date must not be in the past")
+
+    if errors:
+        raise ValueError("; ".join(errors))
+    order = {
+        "action": action,
+        "frequency": frequency,
+        "timing": timing,
+        "symbols": symbols or None,
+        "amount_eur": float(amount) if amount is not None else None,
+        "quantity": float(quantity) if quantity is not None else None,
+        "day_of_month": day_of_month,
+        "next_run_date": next_run.isoformat(),
+        "note": spec.get("note"),
+    }
+    if group:
+        order.update(group_type=group["type"], group_name=group["name"], top_n=group.get("top_n", 1))
+    return order
+
+
+class ScheduledOrderService:
+    """Creates scheduled orders and places the ones that are due."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+
+    async def create(self, spec: dict, today: date | None = None) -> dict:
+        """Validate and store a scheduled order.
+
+        Raises:
+            ValueError: Invalid request or unknown symbols
+        """
+        order = validate_order(spec, today or date.today())
+        unknown = [s for s in order["symbols"] or [] if await self._db.get_security(s) is None]
+        if unknown:
+            raise ValueError(f"Unknown symbols: {', '.join(unknown)}")
+        order_id = await self._db.create_scheduled_order(order)
+        await record_action(self._db, "scheduled_order.create", str(order_id), order)
+        return await self._db.get_scheduled_order(order_id)
+
+    async def orders(self, status: str | None = None) -> list[dict]:
+        """Scheduled orders by next run date."""
+        return await self._db.get_scheduled_orders(status)
+
+    async def set_status(self, order_id: int, status: str) -> dict | None:
+        """Pause, resume or cancel an order. Returns None when it is unknown.
+
+        Raises:
+            ValueError: Unknown status, or the order already completed or was cancelled
+        """
+        if status not in SETTABLE_STATUSES:
+            raise ValueError(f"status must be one of {', '.join(SETTABLE_STATUSES)}")
+        order = await self._db.get_scheduled_order(order_id)
+        if order is None:
+            return None
+        if order["status"] in ("completed", "cancelled"):
+            raise ValueError(f"Scheduled order {order_id} is {order['status']}")
+        fields: dict = {"status": status}
+        if status == "active" and order["frequency"] == "monthly" and order["status"] == "paused":
+            # Resuming does not catch up the months spent paused
+            today = date.today()
+            if date.fromisoformat(order["next_run_date"]) < today:
+                fields.update(next_run_date=next_monthly_date(order["day_of_month"], today).isoformat(), run_state=None)
+        await self._db.update_scheduled_order(order_id, **fields)
+        await record_action(self._db, "scheduled_order.status", str(order_id), {"from": order["status"], **fields})
+        return await self._db.get_scheduled_order(order_id)
+
+    async def top_scored(self, group_type: str, group_name: str, top_n: int) -> list[str]:
+        """Highest cached core rank among the active, buyable securities of a group."""
+        members = [
+            sec["symbol"]
+            for sec in await self._db.get_all_securities(active_only=True)
+            if group_name in parse_csv_field(sec.get(group_type)) and sec.get("allow_buy", 1)
+        ]
+        states = await self._db.get_score_states(members)
+        ranked = [
+            (float(states[s]["signal"].get("core_rank", 0.0)), s)
+            for s in members
+            if (states.get(s) or {}).get("signal")
+        ]
+        return [s for _, s in sorted(ranked, key=lambda r: (-r[0], r[1]))[:top_n]]
+
+    async def _legs(self, order: dict) -> list[dict]:
+        symbols = order["symbols"] or await self.top_scored(order["group_type"], order["group_name"], order["top_n"])
+        return [{"symbol": s, "status": "pending"} for s in symbols]
+
+    async def _size(self, order: dict, legs: int, security: dict, price: float) -> float:
+        step = lot_step(security.get("min_lot", 1), security.get("supports_fractional", 0))
+        if order["quantity"] is not None:
+            return floor_to_lot(order["quantity"], step)
+        price_eur = await self._currency.to_eur(price, security.get("currency") or "EUR")
+        return floor_to_lot(order["amount_eur"] / legs / price_eur, step) if price_eur > 0 else 0
+
+    async def _price(self, broker, symbol: str) -> float | None:
+        quote = await broker.get_quote(symbol)
+        if quote and quote.get("price"):
+            return float(quote["price"])
+        position = await self._db.get_position(symbol)
+        return position.get("current_price") if position else None
+
+    async def run_due(self, broker, place: PlaceOrder, now: datetime | None = None) -> dict:
+        """Place the legs of due orders whose market timing allows it.
+
+        Args:
+            broker: Broker for market status and quotes
+            place: Places one leg (the trading:execute order path), given the
+                recommendation and the decision source
+            now: Current time (UTC, default: now)
+
+        Returns:
+            Counts of placed, failed, simulated, skipped and waiting legs and of missed orders
+        """
+        from sentinel.services.circuit_breaker import CircuitBreakerService
+
+        now = now or datetime.now(timezone.utc)
+        today = now.astimezone(timezone.utc).date()
+        summary = {"placed": 0, "failed": 0, "simulated": 0, "skipped": 0, "waiting": 0, "missed": 0}
+        due = await self._db.get_due_scheduled_orders(today.isoformat())
+        if not due:
+            return summary
+
+        live = await self._settings.get("trading_mode", "research") == "live"
+        window = float(await self._settings.get("scheduled_orders_window_minutes", 30))
+        grace = int(await self._settings.get("scheduled_orders_grace_days", 5))
+        status = await broker.get_market_status("*") or {}
+        markets = {str(m.get("i")): m for m in status.get("m", [])}
+        breaker = CircuitBreakerService(db=self._db, settings=self._settings, currency=self._currency)
+
+        for order in due:
+            due_date = date.fromisoformat(order["next_run_date"])
+            legs = (order["run_state"] or {}).get("legs") or await self._legs(order)
+            if (today - due_date).days > grace:
+                for leg in legs:
+                    if leg["status"] == "pending":
+                        leg.update(status="missed", detail=f"not placed within {grace} days of {due_date}")
+                summary["missed"] += 1
+                logger.warning(f"Scheduled order {order['id']} missed its {due_date} run")
+                await self._advance(order, due_date, legs)
+                continue
+
+            for leg in legs:
+                if leg["status"] != "pending":
+                    continue
+                if await breaker.is_tripped():
+                    logger.warning("Circuit breaker tripped, holding scheduled orders")
+                    summary["waiting"] += 1
+                    continue
+                outcome = await self._run_leg(order, leg, len(legs), broker, markets, now, window, live, place)
+                summary[outcome] += 1
+                if outcome != "waiting":
+                    await self._db.update_scheduled_order(order["id"], run_state={"legs": legs})
+
+            if not legs or all(leg["status"] != "pending" for leg in legs):
+                await self._advance(order, due_date, legs)
+            elif order["run_state"] is None:
+                # Keep the group's picks for the rest of this due date
+                await self._db.update_scheduled_order(order["id"], run_state={"legs": legs})
+        return summary
+
+    async def _run_leg(self, order, leg, count, broker, markets, now, window, live, place: PlaceOrder) -> str:
+        symbol = leg["symbol"]
+        security = await self._db.get_security(symbol)
+        if not security or not security.get("active", 1):
+            leg.update(status="skipped", detail="security is not active")
+            return "skipped"
+        allowed = security.get("allow_buy" if order["action"] == "buy" else "allow_sell", 1)
+        if not allowed:
+            leg.update(status="skipped", detail=f"{order['action']} not allowed")
+            return "skipped"
+        if not timing_allows(markets.get(market_id(security) or ""), order["timing"], now, window):
+            return "waiting"
+
+        price = await self._price(broker, symbol)
+        if not price or price <= 0:
+            leg.update(status="failed", detail="no valid price")
+            return "failed"
+        quantity = await self._size(order, count, security, price)
+        if quantity <= 0:
+            leg.update(status="skipped", detail="value below one lot")
+            return "skipped"
+
+        value = price * quantity
+        rec = TradeRecommendation(
+            symbol=symbol,
+            action=order["action"],
+            current_allocation=0.0,
+            target_allocation=0.0,
+            allocation_delta=0.0,
+            current_value_eur=0.0,
+            target_value_eur=0.0,
+            value_delta_eur=value if order["action"] == "buy" else -value,
+            quantity=quantity,
+            price=price,
+            currency=security.get("currency") or "EUR",
+            lot_size=lot_step(security.get("min_lot", 1), security.get("supports_fractional", 0)),
+            contrarian_score=0.0,
+            priority=0.0,
+            reason=f"Scheduled order #{order['id']}",
+            reason_code="scheduled",
+        )
+        leg.update(quantity=quantity, price=price, at=int(time.time()))
+        if not live:
+            logger.info(f"[research] Scheduled order {order['id']}: would {order['action']} {quantity} x {symbol}")
+            leg.update(status="simulated")
+            return "simulated"
+        if await place(rec, f"scheduled:{order['id']}"):
+            leg["status"] = "placed"
+            return "placed"
+        leg.update(status="failed", detail="order rejected (see notifications)")
+        return "failed"
+
+    async def _advance(self, order: dict, due_date: date, legs: list[dict]) -> None:
+        result = {"due_date": due_date.isoformat(), "legs": legs}
+        if order["frequency"] == "monthly":
+            await self._db.update_scheduled_order(
+                order["id"],
+                next_run_date=next_monthly_date(order["day_of_month"], due_date, inclusive=False).isoformat(),
+                run_state=None,
+                last_run_at=int(time.time()),
+                last_result=result,
+            )
+        else:
+            await self._db.update_scheduled_order(
+                order["id"], status="completed", run_state=None, last_run_at=int(time.time()), last_result=result
+            )
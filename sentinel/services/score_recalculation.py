@@ -0,0 +1,151 @@
+"""Bulk score recalculation.
+
+After scoring settings change, the cached security scores (score_state) are
+only recomputed by the next planner run. A recalculation recomputes them now,
+for the whole active universe or a filtered part of it, as a manual run of
+scores:recalculate: it is polled like any other run (with a percentage and
+ETA on top of the progress), collects per-security errors instead of failing
+the run, and can be cancelled between batches. Selected securities are marked
+dirty first, so the ones a cancelled run did not reach are still recomputed by
+the next planner run.
+"""
+
+from __future__ import annotations
+
+import logging
+from datetime import datetime
+
+from sentinel.database import Database
+from sentinel.jobs import manual
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.planner.scoring import score_securities
+from sentinel.settings import Settings
+from sentinel.utils.strings import parse_csv_field
+
+logger = logging.getLogger(__name__)
+
+JOB_TYPE = "scores:recalculate"
+# Securities scored between progress updates and cancellation checks
+BATCH_SIZE = 20
+FILTERS = ("symbols", "geography", "industry", "asset_class", "dirty_only")
+
+
+class ScoreRecalculationService:
+    """Recomputes cached security scores in the background with progress and cancellation."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def select(self, filters: dict | None = None) -> list[str]:
+        """Active securities matching the filters, by symbol.
+
+        Raises:
+            ValueError: Unknown filter or a filter of the wrong type
+        """
+        filters = filters or {}
+        unknown = sorted(set(filters) - set(FILTERS))
+        if unknown:
+            raise ValueError(f"Unknown filter {unknown[0]} (accepted: {', '.join(FILTERS)})")
+        symbols = filters.get("symbols")
+        if symbols is not None and (not isinstance(symbols, list) or not all(isinstance(s, str) for s in symbols)):
+            raise ValueError("symbols must be a list of symbols")
+        if not isinstance(filters.get("dirty_only", False), bool):
+            raise ValueError("dirty_only must be true or false")
+
+        selected = []
+        for security in await self._db.get_all_securities():
+            if symbols is not None and security["symbol"] not in symbols:
+                continue
+            if filters.get("geography") and filters["geography"] not in parse_csv_field(security.get("geography")):
+                continue
+            if filters.get("industry") and filters["industry"] not in parse_csv_field(security.get("industry")):
+                continue
+            if filters.get("asset_class") and (security.get("asset_class") or "equity") != filters["asset_class"]:
+                continue
+            selected.append(security["symbol"])
+        if filters.get("dirty_only"):
+            states = await self._db.get_score_states(selected)
+            selected = [s for s in selected if not (states.get(s) or {}).get("signal") or states[s]["dirty"]]
+        return sorted(selected)
+
+    async def start(self, filters: dict | None = None) -> dict:
+        """Start recalculating the selected securities; poll the returned run with status().
+
+        Raises:
+            ValueError: Invalid filters, or no security matches them
+        """
+        symbols = await self.select(filters)
+        if not symbols:
+            raise ValueError("No active security matches the filters")
+        run = manual.start(JOB_TYPE, dict(filters or {}), False, lambda: self._recalculate(symbols))
+        return self._view(run)
+
+    def status(self, run_id: str) -> dict:
+        """Status, progress, percentage and ETA of a recalculation.
+
+        Raises:
+            LookupError: Unknown run
+        """
+        return self._view(self._run(run_id))
+
+    def cancel(self, run_id: str) -> dict:
+        """Stop a recalculation after its current batch.
+
+        Raises:
+            LookupError: Unknown run
+            ValueError: The run already finished
+        """
+        self._run(run_id)
+        return self._view(manual.request_cancel(run_id))
+
+    def _run(self, run_id: str) -> manual.ManualRun:
+        run = manual.get_run(run_id)
+        if run is None or run.job_type != JOB_TYPE:
+            raise LookupError(f"Unknown recalculation: {run_id}")
+        return run
+
+    @staticmethod
+    def _view(run: manual.ManualRun) -> dict:
+        data = run.as_dict()
+        progress = run.progress or {}
+        done, total = int(progress.get("done", 0)), int(progress.get("total", 0))
+        data["percent"] = round(done / total * 100.0, 1) if total else 0.0
+        data["eta_seconds"] = None
+        if run.started_at and run.finished_at is None and 0 < done < total:
+            elapsed = (datetime.now() - datetime.fromisoformat(run.started_at)).total_seconds()
+            data["eta_seconds"] = round(elapsed / done * (total - done))
+        return data
+
+    async def _recalculate(self, symbols: list[str]) -> dict:
+        params, workers = await AllocationCalculator(db=self._db, settings=self._settings).load_scoring_params()
+        await self._db.mark_scores_dirty(symbols, "recalculate")
+        total = len(symbols)
+        errors: list[dict] = []
+        done = 0
+        manual.report_progress(0, total)
+        for start in range(0, total, BATCH_SIZE):
+            if manual.cancel_requested():
+                logger.info(f"Score recalculation cancelled after {done}/{total} securities")
+                return {"status": "cancelled", "total": total, "done": done, "errors": errors}
+            batch = symbols[start : start + BATCH_SIZE]
+            try:
+                await score_securities(self._db, batch, params, workers=workers)
+            except Exception:
+                # Retry one by one so a single bad security does not cost the batch
+                for symbol in batch:
+                    try:
+                        await score_securities(self._db, [symbol], params)
+                    except Exception as e:
+                        logger.warning(f"Score recalculation failed for {symbol}: {e}")
+                        errors.append({"symbol": symbol, "error": str(e)})
+            done += len(batch)
+            manual.report_progress(done, total, batch[-1])
+        logger.info(f"Recalculated scores of {total - len(errors)}/{total} securities")
+        return {"status": "completed", "total": total, "done": done, "errors": errors}
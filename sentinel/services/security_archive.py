@@ -0,0 +1,61 @@
+"""Archival of securities.
+
+Archiving takes a security out of the universe without losing its context:
+prices, scores, trades and dividends stay keyed by the symbol, only the
+current position row is removed. Archived securities are inactive, so
+planning and the sync jobs skip them. Reactivation restores the trading flags
+the security had when it was archived and marks its score for recomputation.
+"""
+
+from __future__ import annotations
+
+from sentinel.database import Database
+
+
+class SecurityArchiveService:
+    """Archives and reactivates securities, recording who did it and why."""
+
+    def __init__(self, db: Database | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+        """
+        self._db = db or Database()
+
+    async def archive(self, symbol: str, reason: str | None = None, archived_by: str = "local") -> dict:
+        """Archive a security.
+
+        Raises:
+            LookupError: Unknown security
+            ValueError: The security is already archived
+        """
+        security = await self._db.get_security(symbol)
+        if security is None:
+            raise LookupError(f"Security {symbol} not found")
+        if int(security.get("active", 0) or 0) == 0:
+            raise ValueError(f"{symbol} is already archived")
+        await self._db.archive_security(symbol, (reason or "").strip() or None, archived_by)
+        return await self._entry(symbol)
+
+    async def reactivate(self, symbol: str) -> dict:
+        """Bring an archived security back into the universe with its full history.
+
+        Raises:
+            LookupError: Unknown security
+            ValueError: The security is active
+        """
+        security = await self._db.get_security(symbol)
+        if security is None:
+            raise LookupError(f"Security {symbol} not found")
+        if int(security.get("active", 0) or 0) == 1:
+            raise ValueError(f"{symbol} is not archived")
+        flags = await self._db.reactivate_security(symbol)
+        return {"symbol": symbol, "active": 1, **flags}
+
+    async def archived(self) -> list[dict]:
+        """Archived securities with their reason and retained history."""
+        return await self._db.get_archived_securities()
+
+    async def _entry(self, symbol: str) -> dict:
+        return next(item for item in await self._db.get_archived_securities() if item["symbol"] == symbol)
@@ -0,0 +1,189 @@
+"""Shadow mode: trial a settings change alongside the live configuration.
+
+A shadow trial holds proposed values for some settings. Every live planning
+refresh records a planner snapshot; each active trial then replays that
+snapshot with its proposed values overlaid on the live settings and stores
+what it would have recommended and how that diverges from what the live
+configuration recommended. Shadow runs happen in a scratch database with a
+replay broker, so they never trade and never touch live state.
+
+After a trial has run for a while, its report shows how often and where it
+diverged; promoting it writes the proposed values to the live settings.
+"""
+
+from __future__ import annotations
+
+import logging
+from typing import Any
+
+from sentinel.config.schema import validate_settings
+from sentinel.database import Database
+from sentinel.planner.replay import SECRET_SETTINGS, replay_snapshot
+from sentinel.settings import DEFAULTS, Settings
+
+logger = logging.getLogger(__name__)
+
+TRIAL_STATUSES = ("active", "stopped", "promoted")
+
+
+class ShadowSettings:
+    """Live settings with a trial's proposed values overlaid (read-only)."""
+
+    def __init__(self, base: Settings, overrides: dict):
+        self._base = base
+        self._overrides = overrides
+
+    async def get(self, key: str, default: Any = None) -> Any:
+        if key in self._overrides:
+            return self._overrides[key]
+        return await self._base.get(key, default)
+
+    async def all(self) -> dict:
+        return {**await self._base.all(), **self._overrides}
+
+
+class ShadowService:
+    """Creates shadow trials, runs them on live planner snapshots and reports their divergence."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+
+    async def create(self, name: str, overrides: dict, created_by: str = "local", note: str | None = None) -> dict:
+        """Start a trial of proposed setting values.
+
+        Raises:
+            ValueError: Missing name, no overrides, unknown or secret settings, or values breaking the schema
+        """
+        name = (name or "").strip()
+        if not name:
+            raise ValueError("name is required")
+        if not isinstance(overrides, dict) or not overrides:
+            raise ValueError("overrides must be a non-empty object of setting values")
+        unknown = sorted(key for key in overrides if key not in DEFAULTS or key in SECRET_SETTINGS)
+        if unknown:
+            raise ValueError(f"Cannot trial setting {unknown[0]}")
+        await self._validate(overrides)
+        trial_id = await self._db.create_shadow_trial(name, overrides, created_by, note)
+        return await self._db.get_shadow_trial(trial_id)
+
+    async def trials(self, status: str | None = None) -> list[dict]:
+        """Trials, newest first (status: active, stopped or promoted; None for all)."""
+        if status is not None and status not in TRIAL_STATUSES:
+            raise ValueError(f"status must be one of {', '.join(TRIAL_STATUSES)}")
+        return await self._db.get_shadow_trials(status)
+
+    async def run_trials(self, snapshot_id: int | None = None) -> dict:
+        """Replay a planner snapshot (default: the latest) for every active trial. Never raises.
+
+        Returns:
+            {"snapshot_id", "runs", "errors"}
+        """
+        trials = await self._db.get_shadow_trials("active")
+        if not trials:
+            return {"snapshot_id": snapshot_id, "runs": 0, "errors": []}
+        if snapshot_id is None:
+            latest = await self._db.get_planner_snapshots(limit=1)
+            if not latest:
+                return {"snapshot_id": None, "runs": 0, "errors": ["No planner snapshot recorded yet"]}
+            snapshot_id = latest[0]["id"]
+        runs = 0
+        errors = []
+        for trial in trials:
+            try:
+                await self._run(trial, snapshot_id)
+                runs += 1
+            except Exception as e:
+                logger.warning(f"Shadow trial {trial['name']} failed on snapshot {snapshot_id}: {e}")
+                errors.append(f"{trial['name']}: {e}")
+        return {"snapshot_id": snapshot_id, "runs": runs, "errors": errors}
+
+    async def report(self, trial_id: int, limit: int = 20) -> dict:
+        """Divergence report of a trial: how often and where it differed from the live configuration.
+
+        Raises:
+            LookupError: Unknown trial
+        """
+        trial = await self._trial(trial_id)
+        runs = await self._db.get_shadow_runs(trial_id)
+        symbols: dict[str, dict[str, int]] = {}
+        for run in runs:
+            for kind in ("added", "removed"):
+                for rec in run["diff"][kind]:
+                    counts = symbols.setdefault(rec["symbol"], {"added": 0, "removed": 0, "changed": 0})
+                    counts[kind] += 1
+            for change in run["diff"]["changed"]:
+                counts = symbols.setdefault(change["symbol"], {"added": 0, "removed": 0, "changed": 0})
+                counts["changed"] += 1
+        divergent = sum(1 for run in runs if not run["identical"])
+        live = await self._settings.all()
+        return {
+            **trial,
+            "changes": {key: {"live": live.get(key), "proposed": value} for key, value in trial["overrides"].items()},
+            "runs": len(runs),
+            "divergent_runs": divergent,
+            "divergence_rate": round(divergent / len(runs), 4) if runs else None,
+            # added: the trial would recommend it, removed: only the live configuration does
+            "symbols": dict(sorted(symbols.items(), key=lambda item: -sum(item[1].values()))),
+            "recent": runs[:limit],
+        }
+
+    async def stop(self, trial_id: int, ended_by: str = "local") -> dict:
+        """Stop an active trial; its runs are kept.
+
+        Raises:
+            LookupError: Unknown trial
+            ValueError: The trial is not active
+        """
+        await self._active(trial_id)
+        await self._db.end_shadow_trial(trial_id, "stopped", ended_by)
+        return await self._trial(trial_id)
+
+    async def promote(self, trial_id: int, ended_by: str = "local") -> dict:
+        """Write an active trial's proposed values to the live settings.
+
+        Raises:
+            LookupError: Unknown trial
+            ValueError: The trial is not active, or its values no longer pass validation
+        """
+        trial = await self._active(trial_id)
+        await self._validate(trial["overrides"])
+        for key, value in trial["overrides"].items():
+            await self._settings.set(key, value)
+        await self._db.end_shadow_trial(trial_id, "promoted", ended_by)
+        logger.info(f"Promoted shadow trial {trial['name']}: {', '.join(sorted(trial['overrides']))}")
+        return await self._trial(trial_id)
+
+    async def _validate(self, overrides: dict) -> None:
+        """Refuse values that break the schema, or cross-field rules the live settings satisfy."""
+        live = await self._settings.all()
+        before = validate_settings(live)
+        problems = [p for p in validate_settings({**live, **overrides}) if p not in before]
+        if problems:
+            raise ValueError("; ".join(p.message for p in problems))
+
+    async def _run(self, trial: dict, snapshot_id: int) -> None:
+        report = await replay_snapshot(
+            snapshot_id, db=self._db, settings=ShadowSettings(self._settings, trial["overrides"])
+        )
+        if report is None:
+            raise LookupError(f"Planner snapshot {snapshot_id} not found")
+        await self._db.save_shadow_run(trial["id"], snapshot_id, report, report["replayed"])
+
+    async def _trial(self, trial_id: int) -> dict:
+        trial = await self._db.get_shadow_trial(trial_id)
+        if trial is None:
+            raise LookupError(f"Shadow trial {trial_id} not found")
+        return trial
+
+    async def _active(self, trial_id: int) -> dict:
+        trial = await self._trial(trial_id)
+        if trial["status"] != "active":
+            raise ValueError(f"Shadow trial {trial_id} is {trial['status']}")
+        return trial
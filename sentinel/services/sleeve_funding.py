@@ -0,0 +1,180 @@
+"""Risk-parity sleeve funding suggestions.
+
+The planner splits capital between the core and opportunity sleeves using fixed
+percentages (strategy_core_target_pct / strategy_opportunity_target_pct). This
+service suggests an alternative split where each sleeve contributes similar risk:
+sleeve weights are inversely proportional to the sleeve's historical volatility
+or maximum drawdown. Suggestions are previewed as a diff against the current
+split and only take effect when explicitly applied.
+"""
+
+from __future__ import annotations
+
+import inspect
+import json
+import math
+from statistics import pstdev
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+SLEEVES = ("core", "opportunity")
+RISK_MEASURES = ("volatility", "drawdown")
+TRADING_DAYS_PER_YEAR = 252
+# Fewer common price observations than this and a sleeve's risk is not estimated
+MIN_RISK_OBSERVATIONS = 20
+
+
+def sleeve_returns(weights: dict[str, float], prices: dict[str, list[dict]]) -> list[float]:
+    """Daily returns of a weighted basket, over dates every member has a close for.
+
+    Args:
+        weights: symbol -> weight within the sleeve (renormalized here)
+        prices: symbol -> price rows (any order)
+
+    Returns:
+        Basket daily returns, oldest first
+    """
+    total = sum(weights.values())
+    if total <= 0:
+        return []
+    closes = {
+        symbol: {row["date"]: float(row["close"]) for row in prices.get(symbol, []) if row.get("close")}
+        for symbol in weights
+    }
+    dates = sorted(set.intersection(*(set(c) for c in closes.values()))) if closes else []
+    returns = []
+    for prev, cur in zip(dates, dates[1:], strict=False):
+        returns.append(sum((w / total) * (closes[s][cur] / closes[s][prev] - 1.0) for s, w in weights.items()))
+    return returns
+
+
+def sleeve_risk(returns: list[float], measure: str = "volatility") -> float | None:
+    """Annualized volatility or absolute maximum drawdown of a return series."""
+    if len(returns) < MIN_RISK_OBSERVATIONS:
+        return None
+    if measure == "drawdown":
+        value, peak, max_dd = 1.0, 1.0, 0.0
+        for r in returns:
+            value *= 1.0 + r
+            peak = max(peak, value)
+            max_dd = max(max_dd, 1.0 - value / peak)
+        return max_dd
+    return pstdev(returns) * math.sqrt(TRADING_DAYS_PER_YEAR)
+
+
+def risk_parity_split(risks: dict[str, float]) -> dict[str, float]:
+    """Inverse-risk weights (fractions summing to 1) so each sleeve contributes equal risk."""
+    inverse = {name: 1.0 / risk for name, risk in risks.items() if risk and risk > 0}
+    total = sum(inverse.values())
+    if len(inverse) != len(risks) or total <= 0:
+        return {}
+    return {name: value / total for name, value in inverse.items()}
+
+
+class SleeveFundingService:
+    """Suggests and applies risk-parity funding across planner sleeves."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None, planner=None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            planner: Planner instance (created on first use if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._planner = planner
+
+    async def preview(self, measure: str | None = None, lookback_days: int | None = None) -> dict:
+        """Compare the configured sleeve split with the risk-parity suggestion.
+
+        Args:
+            measure: 'volatility' or 'drawdown' (defaults to strategy_sleeve_risk_measure)
+            lookback_days: Price history window (defaults to strategy_sleeve_risk_lookback_days)
+
+        Returns:
+            dict with the risk measure, per-sleeve risk / member count / current_pct /
+            suggested_pct / diff_pct, and whether a suggestion could be computed
+        """
+        measure = measure or await self._settings.get("strategy_sleeve_risk_measure", "volatility")
+        if measure not in RISK_MEASURES:
+            raise ValueError(f"Unknown risk measure: {measure}")
+        lookback_days = int(lookback_days or await self._settings.get("strategy_sleeve_risk_lookback_days", 252))
+
+        current = {
+            "core": float(await self._settings.get("strategy_core_target_pct", 80)),
+            "opportunity": float(await self._settings.get("strategy_opportunity_target_pct", 20)),
+        }
+        members = await self._sleeve_members()
+        symbols = [s for sleeve in members.values() for s in sleeve]
+        prices = await self._db.get_prices_bulk(symbols, days=lookback_days + 1) if symbols else {}
+
+        risks = {name: sleeve_risk(sleeve_returns(members[name], prices), measure) for name in SLEEVES}
+        split = risk_parity_split(risks)
+        suggested = self._suggested_pcts(split, await self._settings.get("strategy_opportunity_target_max_pct", 30))
+
+        sleeves = {}
+        for name in SLEEVES:
+            sleeves[name] = {
+                "members": len(members[name]),
+                "risk": round(risks[name], 6) if risks[name] is not None else None,
+                "current_pct": current[name],
+                "suggested_pct": suggested.get(name),
+                "diff_pct": round(suggested[name] - current[name], 1) if suggested else None,
+            }
+        return {
+            "measure": measure,
+            "lookback_days": lookback_days,
+            "available": bool(suggested),
+            "sleeves": sleeves,
+        }
+
+    async def apply(self, measure: str | None = None, lookback_days: int | None = None) -> dict:
+        """Persist the risk-parity split as the sleeve targets.
+
+        Raises:
+            ValueError: If sleeve risk could not be estimated
+        """
+        preview = await self.preview(measure=measure, lookback_days=lookback_days)
+        if not preview["available"]:
+            raise ValueError("Not enough price history to estimate sleeve risk")
+        batch = {
+            "strategy_core_target_pct": preview["sleeves"]["core"]["suggested_pct"],
+            "strategy_opportunity_target_pct": preview["sleeves"]["opportunity"]["suggested_pct"],
+        }
+        await self._db.set_settings_batch(batch)
+        await self._settings.notify(batch)
+        await self._db.cache_clear("planner:")
+        return preview
+
+    @staticmethod
+    def _suggested_pcts(split: dict[str, float], opportunity_max_pct) -> dict[str, float]:
+        """Round the split to 0.1% and keep the opportunity sleeve within its configured maximum."""
+        if not split:
+            return {}
+        opportunity = min(round(split["opportunity"] * 100, 1), float(opportunity_max_pct or 100))
+        return {"core": round(100.0 - opportunity, 1), "opportunity": opportunity}
+
+    async def _sleeve_members(self) -> dict[str, dict[str, float]]:
+        """Ideal-portfolio weights grouped by sleeve (symbol -> weight)."""
+        if self._planner is None:
+            from sentinel.planner import Planner
+
+            self._planner = Planner(db=self._db)
+
+        ideal = await self._planner.calculate_ideal_portfolio()
+        sleeves_map: dict[str, str] = {}
+        cached = self._db.cache_get("planner:contrarian_sleeves")
+        if inspect.isawaitable(cached):
+            cached = await cached
+        if isinstance(cached, (str, bytes, bytearray)):
+            sleeves_map = json.loads(cached)
+
+        members: dict[str, dict[str, float]] = {name: {} for name in SLEEVES}
+        for symbol, weight in ideal.items():
+            sleeve = sleeves_map.get(symbol, "core")
+            if sleeve in members and weight > 0:
+                members[sleeve][symbol] = weight
+        return members
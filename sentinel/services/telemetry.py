@@ -0,0 +1,216 @@
+"""Anonymized strategy fingerprint for community comparison.
+
+Produces a shareable summary of how this instance is configured and how it has
+performed, without anything that identifies the owner or the holdings: no
+symbols, no names, no amounts - only ratios, rates and counts. Exporting is
+opt-in (telemetry_export_enabled) and nothing is ever sent anywhere; the owner
+downloads the JSON and shares it by hand.
+
+Sections:
+- temperament: strategy settings projected onto a few 0..1 axes
+- config: the ratio/threshold strategy settings themselves
+- structure: concentration, cash ratio and sleeve split of the latest snapshot
+- performance: time-weighted return, volatility, Sharpe, max drawdown, turnover
+"""
+
+from __future__ import annotations
+
+import math
+from datetime import date, datetime, timedelta, timezone
+from statistics import mean, pstdev
+
+from sentinel.currency import Currency
+from sentinel.database import Database
+from sentinel.settings import DEFAULTS, Settings
+
+TELEMETRY_VERSION = 1
+TRADING_DAYS_PER_YEAR = 252
+# Fewer daily returns than this and risk metrics are not reported
+MIN_RETURN_OBSERVATIONS = 20
+# Ratios are rounded so the export cannot be matched back to exact values
+PRECISION = 3
+
+# Settings exported verbatim: ratios, thresholds, day counts and enums (never EUR amounts)
+CONFIG_KEYS = tuple(key for key in DEFAULTS if key.startswith("strategy_")) + (
+    "max_position_pct",
+    "min_position_pct",
+    "min_cash_buffer",
+    "target_cash_pct",
+    "rebalance_strategy",
+    "rebalance_threshold_pct",
+    "rebalance_band_mode",
+    "trade_cooloff_days",
+    "transaction_fee_percent",
+)
+
+# axis -> (setting, value that maps to 1.0); each axis is clamped to 0..1
+TEMPERAMENT_AXES = {
+    "opportunism": ("strategy_opportunity_target_pct", 100),
+    "dip_depth": ("strategy_entry_t1_dd", -0.30),
+    "selectivity": ("strategy_min_opp_score", 1.0),
+    "concentration": ("max_position_pct", 100),
+    "patience": ("strategy_rotation_time_stop_days", 365),
+    "churn_tolerance": ("strategy_max_funding_turnover_pct", 1.0),
+}
+
+# Cash flow types that count as deposits / withdrawals (as in the P&L history)
+DEPOSIT_TYPES = ("card", "card_payout")
+
+
+def _round(value: float | None) -> float | None:
+    return None if value is None else round(value, PRECISION)
+
+
+def temperament_vector(settings: dict) -> dict[str, float]:
+    """Project strategy settings onto the TEMPERAMENT_AXES."""
+    vector = {}
+    for axis, (key, full_scale) in TEMPERAMENT_AXES.items():
+        try:
+            value = float(settings.get(key, DEFAULTS.get(key)) or 0.0)
+        except (TypeError, ValueError):
+            value = 0.0
+        vector[axis] = _round(max(0.0, min(1.0, value / full_scale)))
+    return vector
+
+
+def risk_metrics(returns: list[float]) -> dict:
+    """Annualized return, volatility, Sharpe (zero risk-free rate) and max drawdown of daily returns."""
+    if len(returns) < MIN_RETURN_OBSERVATIONS:
+        return {"annualized_return": None, "volatility": None, "sharpe": None, "max_drawdown": None}
+    growth, peak, max_dd = 1.0, 1.0, 0.0
+    for r in returns:
+        growth *= 1.0 + r
+        peak = max(peak, growth)
+        max_dd = max(max_dd, 1.0 - growth / peak)
+    annualized = growth ** (TRADING_DAYS_PER_YEAR / len(returns)) - 1.0 if growth > 0 else -1.0
+    volatility = pstdev(returns) * math.sqrt(TRADING_DAYS_PER_YEAR)
+    sharpe = mean(returns) / pstdev(returns) * math.sqrt(TRADING_DAYS_PER_YEAR) if volatility > 0 else None
+    return {
+        "annualized_return": _round(annualized),
+        "volatility": _round(volatility),
+        "sharpe": _round(sharpe),
+        "max_drawdown": _round(max_dd),
+    }
+
+
+class TelemetryService:
+    """Builds the anonymized strategy fingerprint."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        settings: Settings | None = None,
+        currency: Currency | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            currency: Currency instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._currency = currency or Currency()
+
+    async def enabled(self) -> bool:
+        """Whether the owner opted in to exporting (telemetry_export_enabled setting)."""
+        return bool(await self._settings.get("telemetry_export_enabled", False))
+
+    async def export(self, days: int = 365, today: date | None = None) -> dict:
+        """Build the fingerprint over the last `days` days."""
+        today = today or date.today()
+        all_settings = await self._db.get_all_settings()
+        settings = {key: all_settings.get(key, DEFAULTS.get(key)) for key in CONFIG_KEYS}
+        start = today - timedelta(days=days)
+        daily = await self._daily_values(start, today)
+        return {
+            "version": TELEMETRY_VERSION,
+            "period": {"month": today.strftime("%Y-%m"), "days": days, "observations": len(daily)},
+            "temperament": temperament_vector(settings),
+            "config": {key: _round(v) if isinstance(v, float) else v for key, v in settings.items()},
+            "structure": await self._structure(),
+            "performance": {
+                **risk_metrics(self._daily_returns(daily)),
+                **await self._trading_activity(daily, start, today),
+            },
+        }
+
+    async def _daily_values(self, start: date, end: date) -> list[dict]:
+        """Daily total value and cumulative net deposits (EUR) from portfolio snapshots."""
+        snapshots = await self._db.get_portfolio_snapshots((end - start).days + 1)
+        flows: dict[str, float] = {}
+        for cf in await self._db.get_cash_flows():
+            if cf["type_id"] in DEPOSIT_TYPES:
+                amount = await self._currency.to_eur_for_date(cf["amount"], cf["currency"], cf["date"])
+                flows[cf["date"]] = flows.get(cf["date"], 0.0) + amount
+        daily = []
+        for snap in snapshots:
+            day = datetime.fromtimestamp(snap["date"], tz=timezone.utc).date().isoformat()
+            data = snap["data"]
+            value = sum(p.get("value_eur", 0) for p in data.get("positions", {}).values())
+            value += data.get("cash_eur", 0.0) or 0.0
+            daily.append({"date": day, "value": value, "flow": 0.0})
+        # Attribute each deposit to the first snapshot on or after its date
+        for flow_date, amount in sorted(flows.items()):
+            for point in daily:
+                if point["date"] >= flow_date[:10]:
+                    point["flow"] += amount
+                    break
+        return daily
+
+    @staticmethod
+    def _daily_returns(daily: list[dict]) -> list[float]:
+        """Time-weighted daily returns with deposits and withdrawals stripped out."""
+        returns = []
+        for prev, cur in zip(daily, daily[1:], strict=False):
+            if prev["value"] > 0:
+                returns.append((cur["value"] - prev["value"] - cur["flow"]) / prev["value"])
+        return returns
+
+    async def _structure(self) -> dict:
+        """Concentration, cash ratio and sleeve split of the latest snapshot."""
+        snapshots = await self._db.get_portfolio_snapshots(7)
+        if not snapshots:
+            return {"positions": 0, "cash_ratio": None, "top_weight": None, "hhi": None, "sleeves": {}}
+        data = snapshots[-1]["data"]
+        values = {s: p.get("value_eur", 0) for s, p in data.get("positions", {}).items() if p.get("value_eur", 0) > 0}
+        cash = max(data.get("cash_eur", 0.0) or 0.0, 0.0)
+        total = sum(values.values()) + cash
+        if total <= 0:
+            return {"positions": 0, "cash_ratio": None, "top_weight": None, "hhi": None, "sleeves": {}}
+
+        invested = sum(values.values())
+        weights = [v / invested for v in values.values()] if invested > 0 else []
+        states = await self._db.get_strategy_states(list(values))
+        sleeves: dict[str, float] = {}
+        for symbol, value in values.items():
+            sleeve = (states.get(symbol) or {}).get("sleeve") or "core"
+            sleeves[sleeve] = sleeves.get(sleeve, 0.0) + value / total
+        return {
+            "positions": len(values),
+            "cash_ratio": _round(cash / total),
+            "top_weight": _round(max(weights)) if weights else None,
+            "hhi": _round(sum(w * w for w in weights)) if weights else None,
+            "sleeves": {name: _round(share) for name, share in sorted(sleeves.items())},
+        }
+
+    async def _trading_activity(self, daily: list[dict], start: date, end: date) -> dict:
+        """Annualized turnover (one-way, share of average value) and trade frequency."""
+        trades = await self._db.get_trades(
+            start_date=start.isoformat(), end_date=end.isoformat(), limit=100000, include_archived=True
+        )
+        currencies = {s["symbol"]: s.get("currency") or "EUR" for s in await self._db.get_all_securities(False)}
+        traded = 0.0
+        for trade in trades:
+            traded += await self._currency.to_eur(
+                abs(trade["quantity"] * trade["price"]), currencies.get(trade["symbol"], "EUR")
+            )
+        period_years = max((end - start).days, 1) / 365.0
+        average_value = mean(p["value"] for p in daily) if daily else 0.0
+        turnover = traded / 2 / average_value / period_years if average_value > 0 else None
+        return {
+            "turnover": _round(turnover),
+            "trades_per_month": _round(len(trades) / (period_years * 12)),
+            "buy_share": _round(sum(t["side"] == "BUY" for t in trades) / len(trades)) if trades else None,
+        }
@@ -0,0 +1,230 @@
+"""Watchlist of candidate securities tracked outside the universe.
+
+Watched securities get price history collected into the prices table and their
+contrarian signal recomputed by the sync:watchlist job. A change of the
+opportunity score by at least watchlist_score_alert_delta, or of the signal
+tags, raises a notification. Promoting an item adds it to the universe with
+the broker metadata and price history already collected. Price alerts are set
+and listed through the broker's own price-alert endpoints.
+"""
+
+from __future__ import annotations
+
+import time
+
+from sentinel.broker import PRICE_ALERT_TRIGGERS, Broker
+from sentinel.database import Database
+from sentinel.services.notifications import record_notification
+from sentinel.settings import Settings
+from sentinel.strategy import compute_contrarian_signal
+from sentinel.utils.identity import extract_isin
+
+# Price history fetched when a security is first watched (matches adding it to the universe)
+HISTORY_YEARS = 20
+# Daily bars refetched on each refresh, and closes used for the signal
+REFRESH_DAYS = 10
+SIGNAL_DAYS = 260
+
+# Component scores at or above this earn their tag
+TAG_THRESHOLD = 0.5
+
+
+def signal_tags(signal: dict, min_opp_score: float) -> list[str]:
+    """Named conditions of a contrarian signal, compared between refreshes for alerts."""
+    tags = []
+    if float(signal.get("opp_score", 0.0) or 0.0) >= min_opp_score:
+        tags.append("opportunity")
+    if float(signal.get("dip_score", 0.0) or 0.0) >= TAG_THRESHOLD:
+        tags.append("dip")
+    if float(signal.get("capitulation_score", 0.0) or 0.0) >= TAG_THRESHOLD:
+        tags.append("capitulation")
+    if int(signal.get("cycle_turn", 0) or 0):
+        tags.append("cycle_turn")
+    if int(signal.get("freefall_block", 0) or 0):
+        tags.append("freefall")
+    return tags
+
+
+class WatchlistService:
+    """Tracks, refreshes, alerts on and promotes watchlist securities."""
+
+    def __init__(
+        self,
+        db: Database | None = None,
+        broker: Broker | None = None,
+        settings: Settings | None = None,
+    ):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            broker: Broker instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+        """
+        self._db = db or Database()
+        self._broker = broker or Broker()
+        self._settings = settings or Settings()
+
+    async def list(self) -> list[dict]:
+        """Watched securities with their last score and tags (raw broker data left out)."""
+        return [{k: v for k, v in item.items() if k != "data"} for item in await self._db.get_watchlist()]
+
+    async def add(self, symbol: str, note: str | None = None) -> dict:
+        """Watch a security: store its broker metadata, collect its price history and score it.
+
+        Raises:
+            ValueError: Missing symbol, or the security is already in the active universe
+            LookupError: The broker does not know the symbol
+        """
+        symbol = (symbol or "").strip()
+        if not symbol:
+            raise ValueError("Symbol is required")
+        existing = await self._db.get_security(symbol)
+        if existing and int(existing.get("active", 0) or 0) == 1:
+            raise ValueError(f"{symbol} is already in the universe")
+        info = await self._broker.get_security_info(symbol)
+        if not info:
+            raise LookupError(f"{symbol} not found in broker")
+
+        await self._db.add_watchlist_item(
+            symbol,
+            name=info.get("short_name", info.get("name", symbol)),
+            currency=info.get("currency", info.get("curr", "EUR")),
+            market_id=str(info.get("mrkt", {}).get("mkt_id", "")),
+            min_lot=int(float(info.get("lot", 1))),
+            isin=extract_isin(info),
+            note=note,
+            data=info,
+        )
+        prices = (await self._broker.get_historical_prices_bulk([symbol], years=HISTORY_YEARS)).get(symbol, [])
+        if prices:
+            await self._db.save_prices(symbol, prices)
+        # The first score is the baseline later refreshes are compared with
+        score, tags = await self._signal(symbol)
+        await self._db.update_watchlist_signal(symbol, score, tags, int(time.time()))
+        return {"symbol": symbol, "prices_count": len(prices), "score": score, "tags": tags}
+
+    async def remove(self, symbol: str) -> None:
+        """Stop watching a security (its collected prices are kept).
+
+        Raises:
+            LookupError: The security is not watched
+        """
+        if not await self._db.remove_watchlist_item(symbol):
+            raise LookupError(f"{symbol} is not on the watchlist")
+
+    async def refresh(self, now: int | None = None) -> dict:
+        """Update prices and signals of every watched security, alerting on score or tag changes.
+
+        Returns:
+            dict with the number of securities checked and the alerts raised
+        """
+        now = int(now or time.time())
+        delta = float(await self._settings.get("watchlist_score_alert_delta", 0.1))
+        items = await self._db.get_watchlist()
+        alerts = []
+        for item in items:
+            symbol = item["symbol"]
+            prices = await self._broker.get_historical_prices(symbol, days=REFRESH_DAYS)
+            if prices:
+                await self._db.save_prices(symbol, prices)
+            score, tags = await self._signal(symbol)
+            changes = []
+            previous = item.get("score")
+            if previous is not None and abs(score - float(previous)) >= delta:
+                changes.append(f"score {float(previous):.2f} -> {score:.2f}")
+            added = sorted(set(tags) - set(item["tags"]))
+            removed = sorted(set(item["tags"]) - set(tags))
+            if item.get("checked_at") is not None and (added or removed):
+                changes.append(", ".join([f"+{t}" for t in added] + [f"-{t}" for t in removed]))
+            if changes:
+                alert = {"symbol": symbol, "score": score, "tags": tags, "changes": changes}
+                alerts.append(alert)
+                await record_notification(
+                    self._db,
+                    "info",
+                    "watchlist",
+                    f"Watchlist: {symbol} signal changed",
+                    message="; ".join(changes),
+                    entity_type="security",
+                    entity_id=symbol,
+                    dedupe_key=f"watchlist:{symbol}",
+                )
+            await self._db.update_watchlist_signal(symbol, score, tags, now)
+        return {"checked": len(items), "alerts": alerts}
+
+    async def promote(self, symbol: str, data: dict | None = None) -> dict:
+        """Move a watched security into the universe with its collected metadata and history.
+
+        Args:
+            data: Optional allow_buy, allow_sell, geography and industry for the new security
+
+        Raises:
+            LookupError: The security is not watched
+        """
+        item = await self._db.get_watchlist_item(symbol)
+        if item is None:
+            raise LookupError(f"{symbol} is not on the watchlist")
+        data = data or {}
+        existing = await self._db.get_security(symbol)
+        if existing and int(existing.get("active", 0) or 0) == 0:
+            # A watched archived security comes back with its history, not as a new one
+            await self._db.reactivate_security(symbol)
+        await self._db.upsert_security(
+            symbol,
+            name=item["name"],
+            currency=item["currency"] or "EUR",
+            market_id=item["market_id"],
+            min_lot=item["min_lot"] or 1,
+            active=True,
+            allow_buy=data.get("allow_buy", 1),
+            allow_sell=data.get("allow_sell", 1),
+            geography=data.get("geography", ""),
+            industry=data.get("industry", ""),
+        )
+        if item["data"]:
+            await self._db.update_security_metadata(symbol, item["data"], item["market_id"])
+        if item["isin"]:
+            await self._db.set_security_isin(symbol, item["isin"])
+        await self._db.remove_watchlist_item(symbol)
+        return {
+            "symbol": symbol,
+            "name": item["name"],
+            "prices_count": len(await self._db.get_prices(symbol)),
+        }
+
+    async def price_alerts(self, symbol: str | None = None) -> list[dict]:
+        """Price alerts set at the broker, optionally for one symbol."""
+        return await self._broker.get_price_alerts(symbol)
+
+    async def add_price_alert(self, symbol: str, price: float, trigger_type: str = "crossing") -> str:
+        """Set a broker price alert for a security.
+
+        Raises:
+            ValueError: Non-positive price or unknown trigger type
+            LookupError: The broker rejected the alert
+        """
+        if price is None or float(price) <= 0:
+            raise ValueError("Alert price must be positive")
+        if trigger_type not in PRICE_ALERT_TRIGGERS:
+            raise ValueError(f"Trigger type must be one of: {', '.join(PRICE_ALERT_TRIGGERS)}")
+        alert_id = await self._broker.add_price_alert(symbol, float(price), trigger_type)
+        if not alert_id:
+            raise LookupError(f"Broker rejected the price alert for {symbol}")
+        return alert_id
+
+    async def delete_price_alert(self, alert_id: str) -> None:
+        """Delete a broker price alert.
+
+        Raises:
+            LookupError: The broker could not delete it
+        """
+        if not await self._broker.delete_price_alert(alert_id):
+            raise LookupError(f"Price alert {alert_id} could not be deleted")
+
+    async def _signal(self, symbol: str) -> tuple[float, list[str]]:
+        rows = await self._db.get_prices(symbol, days=SIGNAL_DAYS)
+        closes = [float(r["close"]) for r in reversed(rows) if r.get("close") is not None]
+        signal = compute_contrarian_signal(closes)
+        min_opp_score = float(await self._settings.get("strategy_min_opp_score", 0.55))
+        return round(float(signal.get("opp_score", 0.0) or 0.0), 4), signal_tags(signal, min_opp_score)
@@ -0,0 +1,258 @@
+"""Outbound event notifications: signed webhooks, Telegram and ntfy.
+
+Events are queued per subscribed endpoint and delivered in the background;
+failed deliveries are retried with exponential backoff by the
+notifications:deliver job until MAX_ATTEMPTS is reached.
+
+Webhook requests are JSON POSTs signed with the endpoint secret:
+
+    X-Sentinel-Event: trade_executed
+    X-Sentinel-Timestamp: 1760000000
+    X-Sentinel-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+
+Each event type can be switched off globally (webhook_events setting) and
+each endpoint can subscribe to a subset of events.
+"""
+
+from __future__ import annotations
+
+import asyncio
+import contextvars
+import hashlib
+import hmac
+import inspect
+import json
+import logging
+import time
+from typing import Awaitable, Callable
+
+from sentinel.correlation import outbound_headers
+from sentinel.database import Database
+from sentinel.dry_run import is_dry_run, record_side_effect
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+EVENT_TYPES = (
+    "trade_executed",
+    "order_rejected",
+    "risk_limit_breached",
+    "job_failed",
+    "backup_completed",
+    "digest_report",
+    "config_drift",
+)
+ENDPOINT_KINDS = ("webhook", "telegram", "ntfy")
+
+MAX_ATTEMPTS = 6
+BACKOFF_BASE_SECONDS = 60  # 1, 2, 4, 8, 16 minutes between attempts
+REQUEST_TIMEOUT = 10.0
+
+# Sends one HTTP POST: (url, headers, body) -> status code
+Sender = Callable[[str, dict, bytes], Awaitable[int]]
+
+# Background delivery tasks (kept referenced until done)
+_pending_tasks: set[asyncio.Task] = set()
+# Serializes queue processing so the job and background sends never deliver twice
+_delivery_lock = asyncio.Lock()
+
+
+def sign_payload(secret: str, timestamp: int, body: bytes) -> str:
+    """Hex HMAC-SHA256 of '<timestamp>.<body>'."""
+    return hmac.new(secret.encode(), f"{timestamp}.".encode() + body, hashlib.sha256).hexdigest()
+
+
+def backoff_seconds(attempts: int) -> int:
+    """Delay before the next attempt after `attempts` failures."""
+    return BACKOFF_BASE_SECONDS * 2 ** max(attempts - 1, 0)
+
+
+def build_request(webhook: dict, payload: dict, timestamp: int | None = None) -> tuple[str, dict, bytes]:
+    """(url, headers, body) delivering a payload to an endpoint of any kind."""
+    timestamp = timestamp or int(time.time())
+    kind = webhook["kind"]
+    text = payload["title"] + (f"\n{payload['message']}" if payload.get("message") else "")
+    if kind == "telegram":
+        url = f"https://api.telegram.org/bot{webhook.get('secret') or ''}/sendMessage"
+        body = json.dumps({"chat_id": webhook.get("target"), "text": text}).encode()
+        return url, {"Content-Type": "application/json"}, body
+    if kind == "ntfy":
+        headers = {"Title": payload["title"], "Tags": payload["event"]}
+        if payload.get("severity") == "error":
+            headers["Priority"] = "high"
+        if webhook.get("secret"):
+            headers["Authorization"] = f"Bearer {webhook['secret']}"
+        return webhook["url"], headers, (payload.get("message") or payload["title"]).encode()
+
+    body = json.dumps(payload, separators=(",", ":"), sort_keys=True).encode()
+    headers = {
+        "Content-Type": "application/json",
+        "X-Sentinel-Event": payload["event"],
+        "X-Sentinel-Timestamp": str(timestamp),
+        **outbound_headers(),
+    }
+    if webhook.get("secret"):
+        headers["X-Sentinel-Signature"] = "sha256=" + sign_payload(webhook["secret"], timestamp, body)
+    return webhook["url"], headers, body
+
+
+async def http_post(url: str, headers: dict, body: bytes) -> int:
+    """Default sender (httpx)."""
+    import httpx
+
+    async with httpx.AsyncClient(timeout=REQUEST_TIMEOUT) as client:
+        response = await client.post(url, headers=headers, content=body)
+        return response.status_code
+
+
+async def _maybe_await(value):
+    return await value if inspect.isawaitable(value) else value
+
+
+async def emit_event(
+    db,
+    event: str,
+    title: str,
+    message: str | None = None,
+    severity: str = "info",
+    data: dict | None = None,
+) -> int:
+    """Queue an event for every subscribed endpoint and start delivering. Never raises.
+
+    Works with any db object; does nothing if it has no webhook support. In a dry
+    run nothing is queued: each subscribed endpoint is recorded as a side effect.
+
+    Returns:
+        Number of deliveries queued
+    """
+    getter = getattr(db, "get_webhooks", None)
+    if not callable(getter):
+        return 0
+    try:
+        webhooks = await _maybe_await(getter(enabled_only=True))
+        if not isinstance(webhooks, list) or not webhooks:
+            return 0
+        switches = await _maybe_await(db.get_setting("webhook_events"))
+        if isinstance(switches, dict) and switches.get(event) is False:
+            return 0
+        payload = {
+            "event": event,
+            "title": title,
+            "message": message,
+            "severity": severity,
+            "data": data or {},
+            "occurred_at": int(time.time()),
+        }
+        queued = 0
+        for webhook in webhooks:
+            if webhook["events"] is not None and event not in webhook["events"]:
+                continue
+            if is_dry_run():
+                record_side_effect("webhook", webhook=webhook["name"], event=event, title=title)
+                continue
+            await db.add_webhook_delivery(webhook["id"], event, payload)
+            queued += 1
+    except Exception as e:
+        logger.warning(f"Failed to queue '{event}' notifications: {e}")
+        return 0
+    if queued:
+        try:
+            # A clean context: the delivery outlives the request and must not inherit its state
+            task = asyncio.get_running_loop().create_task(
+                WebhookService(db=db).deliver_due(), context=contextvars.Context()
+            )
+            _pending_tasks.add(task)
+            task.add_done_callback(_pending_tasks.discard)
+        except RuntimeError:
+            pass  # No running loop: the notifications:deliver job picks them up
+    return queued
+
+
+class WebhookService:
+    """Delivers queued events and manages endpoints."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None, sender: Sender | None = None):
+        """Initialize service with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance (uses singleton if None)
+            sender: HTTP POST function (uses httpx if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._send = sender or http_post
+
+    async def deliver(self, webhook: dict, payload: dict) -> str | None:
+        """Send one payload. Returns an error description, or None on success.
+
+        In a dry run nothing is sent: the request is recorded as a side effect.
+        """
+        url, headers, body = build_request(webhook, payload)
+        if is_dry_run():
+            record_side_effect("webhook", webhook=webhook["name"], event=payload["event"], url=url)
+            return None
+        try:
+            status = await self._send(url, headers, body)
+        except Exception as e:
+            return f"{type(e).__name__}: {e}"
+        if status >= 300:
+            return f"HTTP {status}"
+        return None
+
+    async def deliver_due(self, now: int | None = None) -> dict:
+        """Attempt every pending delivery that is due, rescheduling failures with backoff.
+
+        Returns:
+            Counts of delivered, retrying and failed (given up) deliveries
+        """
+        async with _delivery_lock:
+            return await self._deliver_due(now or int(time.time()))
+
+    async def _deliver_due(self, now: int) -> dict:
+        webhooks = {w["id"]: w for w in await self._db.get_webhooks()}
+        result = {"delivered": 0, "retrying": 0, "failed": 0}
+        for delivery in await self._db.get_due_webhook_deliveries(now):
+            webhook = webhooks.get(delivery["webhook_id"])
+            attempts = delivery["attempts"] + 1
+            if webhook is None or not webhook["enabled"]:
+                await self._db.update_webhook_delivery(delivery["id"], "failed", attempts, last_error="disabled")
+                result["failed"] += 1
+                continue
+            error = await self.deliver(webhook, delivery["payload"])
+            if error is None:
+                await self._db.update_webhook_delivery(delivery["id"], "delivered", attempts)
+                result["delivered"] += 1
+            elif attempts >= MAX_ATTEMPTS:
+                logger.warning(f"Giving up on {delivery['event']} to {webhook['name']}: {error}")
+                await self._db.update_webhook_delivery(delivery["id"], "failed", attempts, last_error=error)
+                result["failed"] += 1
+            else:
+                retry_at = now + backoff_seconds(attempts)
+                await self._db.update_webhook_delivery(delivery["id"], "pending", attempts, retry_at, error)
+                result["retrying"] += 1
+        return result
+
+    async def send_test(self, webhook_id: int) -> str | None:
+        """Deliver a test event to one endpoint immediately (not queued).
+
+        Raises:
+            ValueError: If the endpoint does not exist
+        """
+        webhook = await self._db.get_webhook(webhook_id)
+        if webhook is None:
+            raise ValueError("Webhook not found")
+        payload = {
+            "event": "test",
+            "title": "Sentinel test notification",
+            "message": f"Endpoint '{webhook['name']}' is configured correctly",
+            "severity": "info",
+            "data": {},
+            "occurred_at": int(time.time()),
+        }
+        return await self.deliver(webhook, payload)
+
+    async def event_switches(self) -> dict[str, bool]:
+        """Global per-event enable flags."""
+        stored = await self._settings.get("webhook_events", {}) or {}
+        return {event: bool(stored.get(event, True)) for event in EVENT_TYPES}
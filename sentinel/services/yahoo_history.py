@@ -0,0 +1,112 @@
+"""Daily price history from the Yahoo Finance chart API.
+
+Bars are adjusted for splits and dividends: Yahoo's close is split-adjusted
+only, so open, high, low and close are scaled by adjclose / close of the same
+day, which makes returns computed from consecutive closes total returns for
+scoring.
+
+The client downloads many symbols market_data_concurrency at a time and
+coalesces requests: securities mapped to the same Yahoo symbol, and
+concurrent callers asking for the same symbol and range, share a single HTTP
+request. It is the yahoo provider of sentinel.services.market_data.
+"""
+
+from __future__ import annotations
+
+import asyncio
+from datetime import datetime, timezone
+
+from sentinel.errors import UpstreamError
+from sentinel.services.metadata_enrichment import Fetcher, http_get_json
+
+YAHOO_CHART_URL = (
+    "https://query2.finance.yahoo.com/v8/finance/chart/{ticker}"
+    "?period1={start}&period2={end}&interval=1d&events=div%2Csplit"
+)
+
+
+def _day_ts(day: str) -> int:
+    return int(datetime.fromisoformat(day).replace(tzinfo=timezone.utc).timestamp())
+
+
+def _at(values: list | None, i: int):
+    return values[i] if values and i < len(values) else None
+
+
+def parse_chart(body: dict) -> list[dict]:
+    """Split/dividend-adjusted daily bars of a chart response, oldest first.
+
+    Raises:
+        UpstreamError: If the response carries an error or no result
+    """
+    chart = body.get("chart") or {}
+    if chart.get("error"):
+        error = chart["error"]
+        raise UpstreamError(error.get("description") or error.get("code") or "Yahoo chart error")
+    results = chart.get("result") or []
+    if not results:
+        raise UpstreamError("Yahoo chart returned no result")
+    result = results[0]
+    offset = int((result.get("meta") or {}).get("gmtoffset") or 0)
+    quote = ((result.get("indicators") or {}).get("quote") or [{}])[0]
+    adjusted = ((result.get("indicators") or {}).get("adjclose") or [{}])[0].get("adjclose") or []
+
+    bars: dict[str, dict] = {}
+    for i, ts in enumerate(result.get("timestamp") or []):
+        close = _at(quote.get("close"), i)
+        if not close:
+            continue
+        adjclose = _at(adjusted, i) or close
+        factor = adjclose / close
+        day = datetime.fromtimestamp(ts + offset, tz=timezone.utc).strftime("%Y-%m-%d")
+        # Later rows of the same day (the live bar) replace earlier ones
+        bars[day] = {
+            "date": day,
+            **{
+                field: round(value * factor, 6) if (value := _at(quote.get(field), i)) is not None else None
+                for field in ("open", "high", "low")
+            },
+            "close": round(adjclose, 6),
+            "volume": _at(quote.get("volume"), i) or 0,
+        }
+    return [bars[day] for day in sorted(bars)]
+
+
+class YahooHistoryClient:
+    """Downloads daily history for many Yahoo symbols with bounded, coalesced requests."""
+
+    def __init__(self, fetcher: Fetcher | None = None, concurrency: int = 4):
+        self._fetch = fetcher or http_get_json
+        self._semaphore = asyncio.Semaphore(max(int(concurrency), 1))
+        self._inflight: dict[tuple[str, str, str], asyncio.Future] = {}
+
+    async def history(self, ticker: str, start_date: str, end_date: str) -> list[dict]:
+        """Adjusted bars of one Yahoo symbol from start_date to end_date (inclusive).
+
+        A request for a ticker and range already being downloaded waits for
+        that download instead of sending another one.
+        """
+        key = (ticker, start_date, end_date)
+        pending = self._inflight.get(key)
+        if pending is not None:
+            return await asyncio.shield(pending)
+        future = asyncio.get_running_loop().create_future()
+        self._inflight[key] = future
+        try:
+            async with self._semaphore:
+                url = YAHOO_CHART_URL.format(
+                    ticker=ticker, start=_day_ts(start_date), end=_day_ts(end_date) + 86_400
+                )
+                bars = parse_chart(await self._fetch(url))
+            future.set_result(bars)
+            return bars
+        except asyncio.CancelledError:
+            future.cancel()
+            raise
+        except Exception as e:
+            future.set_exception(e)
+            # Mark it retrieved so a future nobody else awaited does not log "exception never retrieved"
+            future.exception()
+            raise
+        finally:
+            del self._inflight[key]
@@ -7,23 +7,70 @@ Usage:
     await settings.set('transaction_fee_fixed', 2.5)
     all_settings = await settings.all()
 
+    # Long-lived consumers that read settings once (hardware loops, the cold price
+    # tier, broker clients) subscribe to the keys they depend on
+    unsubscribe = settings.subscribe({"led_display_enabled"}, on_change)
+    changed = await settings.reload()  # Re-read after edits that bypassed set()
+
 All settings are stored in the database and editable via the web UI.
 No hardcoded magic numbers.
 """
 
-from typing import Any
+import logging
+from typing import Any, Awaitable, Callable, Iterable, Optional
 
 from sentinel.database import Database
 from sentinel.utils.decorators import singleton
 
+logger = logging.getLogger(__name__)
+
+# Called with {key: new value} for the subscribed keys that changed
+SettingsListener = Callable[[dict[str, Any]], Awaitable[None]]
+
+# Settings maintained by jobs rather than people (exchange rate sync, circuit breaker, drift check): not audited
+UNAUDITED_KEYS = frozenset({"exchange_rates", "circuit_breaker_state", "config_drift_state"})
+
 # Default settings - applied on first run, then configurable via UI
 DEFAULTS = {
     # Trading mode: 'research' or 'live'
     # In research mode, no actual trades are executed
     "trading_mode": "research",
+    # Manual approval: live execution only places recommendations approved in the inbox
+    "trading_manual_approval": False,
+    "approval_valid_hours": 24,  # How long an approval or rejection stays in force
+    "approval_defer_hours": 24,  # Deferred recommendations return to the inbox after this
+    # Scheduled orders (trading:scheduled_orders job)
+    "scheduled_orders_window_minutes": 30,  # open / close timing: minutes after the open or before the close
+    "scheduled_orders_grace_days": 5,  # A due order not placed within this many days is skipped as missed
+    # Auto-invest new deposits (on sync:cashflows): off, plan (choose the buys) or execute (also place them)
+    "auto_invest_mode": "off",
+    "auto_invest_deposits": "all",  # all, or recurring: only deposits close to the usual amount
+    "auto_invest_cash_buffer_eur": 0.0,  # Cash left uninvested
+    "auto_invest_min_order_eur": 100.0,  # Smallest buy placed from a deposit
+    "auto_invest_max_age_days": 3,  # Older deposits (e.g. on the first history sync) are left alone
     # Transaction costs
     "transaction_fee_fixed": 2.0,  # Fixed fee per trade (EUR)
     "transaction_fee_percent": 0.2,  # Percentage fee (0.2%)
+    # Per-market overrides of the flat fees, by symbol suffix (see utils/fees.py)
+    "fee_schedule": {},
+    "fx_conversion_spread_pct": 0.0,  # Currency conversion spread on non-EUR trades (%)
+    # Order pricing
+    "limit_pricing_depth_enabled": True,  # Price thinly traded names from order book depth
+    "limit_depth_thin_value_eur": 250000,  # Thin below this 20-day average daily traded value (EUR)
+    "limit_depth_max_slippage_pct": 2.0,  # Depth limit never more than 2% past the best bid/ask
+    # Limit pricing strategy by market suffix, liquidity tier (thin, liquid), market:tier or * (see
+    # utils/order_pricing.py), e.g. {"US": "midpoint", "thin": {"strategy": "vwap", "window_days": 10}};
+    # unmatched orders use depth for thin names, the quote on Asian markets and market orders elsewhere
+    "order_pricing_strategies": {},
+    # Order slicing: split orders above either limit into child orders placed across the session
+    "order_slicing_enabled": False,
+    "order_slice_max_volume_pct": 5.0,  # Of the 20-day average daily volume, per child order
+    "order_slice_max_notional_eur": 25000.0,  # Per child order (0 = no ceiling)
+    "order_slice_max_children": 6,
+    "order_slice_interval_minutes": 30,  # Between child orders
+    # Recalibrate limit_depth_max_slippage_pct from observed fills (analytics:execution_quality job)
+    "limit_slippage_auto_calibrate": False,
+    "execution_quality_lookback_days": 90,
     # Position limits (for planner)
     "max_position_pct": 25,  # Hard cap per security
     "min_position_pct": 2,  # Min 2% position size
@@ -33,11 +80,82 @@ DEFAULTS = {
     "target_cash_pct": 0,  # Fully invested strategy
     "simulated_cash_eur": None,  # Override cash in research mode (None = use real)
     # Rebalancing
-    "rebalance_threshold_pct": 5,  # Rebalance when 5% off target
+    "rebalance_strategy": "continuous",  # When drift alone trades: continuous, calendar, threshold, opportunistic
+    "rebalance_calendar_period": "quarterly",  # calendar: monthly, quarterly, semiannual or annual
+    "rebalance_calendar_window_days": 7,  # calendar: rebalance during the first N days of each period
+    "rebalance_threshold_pct": 5,  # threshold: rebalance when 5% off target
+    "rebalance_band_mode": "absolute",  # threshold: absolute (percentage points) or relative (% of the target)
+    "planner_batch_cache_ttl_seconds": 86400,  # Reuse a recommendation batch while planner inputs are unchanged
+    "planner_snapshot_retention": 30,  # Planner runs kept for decision replay (0 = don't record)
+    "planner_context_history": 10,  # Opportunity contexts kept in memory for /api/planner/context (0 = off)
+    # Stale-input guardrail for live buy candidates
+    "planner_max_score_age_days": 7,  # Score computed from closes older than this is stale
+    "planner_max_price_age_days": 3,  # Sizing price (quote, else last close) older than this is stale
+    "planner_stale_penalty": 1.0,  # 1 = exclude stale buys; below 1 = multiply their priority by (1 - penalty)
+    "planner_candidate_cap_max": 0,  # Max buy candidates (not held) evaluated per live run (0 = no fixed cap)
+    "planner_min_free_memory_mb": 512,  # Below this free memory, buy candidates shrink in proportion
+    "planner_target_eval_seconds": 120,  # Time budget per run; recent latency above it caps buy candidates
+    "planner_candidate_cap_floor": 20,  # Resource caps never go below this many buy candidates
+    "planner_time_budget_seconds": 0,  # Live planner run budget in seconds (0 = half the planning:refresh interval)
+    "planner_scoring_workers": 0,  # Processes recomputing dirty security scores (0 = inline)
     # Diversification
     "diversification_impact_pct": 10,  # Max ±10% score adjustment for diversification
+    "correlation_diversification_weight": 0.5,  # Share of the correlation score vs geography/industry (0-1)
+    "correlation_window_days": 252,  # Daily returns per pair in the stored correlation matrix
+    # Allocation optimizer (proposes geography / industry targets; applied only once accepted)
+    "allocation_optimizer_method": "risk_parity",  # risk_parity or mean_variance
+    "allocation_optimizer_risk_aversion": 5.0,  # mean_variance: penalty on variance vs expected score
+    "allocation_optimizer_min_weight_pct": 0,  # Default bounds of every group
+    "allocation_optimizer_max_weight_pct": 50,
+    "allocation_optimizer_group_bounds": {},  # Per group overrides, e.g. {"geography": {"US": [20, 60]}}
+    "allocation_optimizer_turnover_pct": 25,  # Max share of the active targets a proposal may move
+    "allocation_optimizer_history_days": 252,  # Daily returns used for the group covariance
+    # Market regime
+    "regime_impact_pct": 10,  # Max ±10% score adjustment from the confidence-weighted regime of a security's region
+    # Ensemble detectors (detector -> weight; 0 = scored for comparison only), how they are combined and the
+    # score beyond which a region (or a detector's vote) is bull / bear
+    "regime_detector_weights": {"trend": 0.35, "volatility": 0.2, "drawdown": 0.25, "breadth": 0.2},
+    "regime_vote_policy": "weighted",  # weighted (average score) or majority (weighted vote)
+    "regime_threshold": 0.2,
+    # Market stress: at or above market_stress_threshold (exposure-weighted risk-off regime score, 0-1) the
+    # planner throttles buys - smaller buys, higher minimum scores and longer buy cool-offs
+    "market_stress_enabled": False,
+    "market_stress_threshold": 0.4,
+    "market_stress_buy_size_pct": 50.0,  # Buys shrink to this % of their normal size
+    "market_stress_min_score_add": 0.1,  # Added to strategy_min_opp_score and strategy_core_new_min_score for buys
+    "market_stress_cooloff_factor": 2.0,  # Buy cool-off days (strategy_*_cooloff_days) are multiplied by this
+    # News sentiment of held positions (sync:news job)
+    "news_enabled": True,
+    "news_lookback_hours": 72,  # Headlines older than this are dropped
+    "news_headlines_per_symbol": 20,  # Headlines requested per symbol on each fetch
+    "news_risk_min_negative": 2,  # Negative headlines in the lookback that tag a position news_risk
+    # Metadata enrichment (sync:metadata_enrichment job): user overrides win, then providers in this order
+    "metadata_provider_priority": ["tradernet", "yahoo"],
+    "metadata_yahoo_enabled": False,  # Also fetch the Yahoo Finance asset profile
+    # Price history (sync:prices job): tradernet (raw closes) or market_data (split/dividend-adjusted bars
+    # from market_data_providers, first one with fresh bars wins)
+    "price_history_source": "tradernet",
+    "market_data_providers": ["yahoo", "stooq"],
+    "market_data_symbol_providers": {},  # Per-symbol preference, e.g. {"SAP.EU": ["stooq"]}; others follow
+    "market_data_stale_days": 5,  # A last bar older than this fails over to the next provider
+    "market_data_concurrency": 4,  # Requests in flight at once per provider
+    # Watchlist
+    "watchlist_score_alert_delta": 0.1,  # Notify when a watched security's opportunity score moves this much
+    # Position theses: default review interval of a new thesis, and the planner's sell priority boost
+    # for a position at or above its target price (priority x (1 + boost))
+    "thesis_review_days": 90,
+    "thesis_target_sell_boost": 0.5,
+    # Protective orders: broker-side stop-losses and trailing stops kept on held positions by the
+    # trading:protective_orders job. Policies are tried in order, the first match applies, e.g.
+    # [{"name": "volatile", "min_volatility": 0.35, "kind": "trailing_stop", "percent": 15},
+    #  {"name": "default", "kind": "stop_loss", "percent": 20}]
+    # Match keys (all optional): symbols, industries, geographies, min_volatility (annualized)
+    "protective_orders_enabled": False,
+    "protective_order_policies": [],
+    "protective_order_raise_pct": 2.0,  # Raise a stop-loss once its level would move up by more than this
     # Dividend reinvestment
     "max_dividend_reinvestment_boost": 0.15,  # Max score boost for uninvested dividends
+    "drip_default_mode": "same",  # same, redirect (top-scored underweight security) or cash; per security: drip_mode
     # Trade cool-off
     "trade_cooloff_days": 30,  # Days to wait before opposite action after trade
     # API
@@ -65,18 +183,146 @@ DEFAULTS = {
     "strategy_rotation_time_stop_days": 90,
     "strategy_core_new_min_score": 0.30,
     "strategy_core_new_min_dip_score": 0.20,
+    # Position sizing per sleeve: score (by signal strength) or volatility_target (inverse realized vol)
+    "position_sizing_mode": "score",
+    "position_sizing_overrides": {},  # Sleeve (core/opportunity) -> mode, overriding position_sizing_mode
+    "vol_target_annual_pct": 15.0,  # Target annualized volatility of a volatility-targeted sleeve
+    "vol_target_max_weight_pct": 25.0,  # Largest share of a volatility-targeted sleeve in one position
+    # New universe entries are not bought until both the grace period has passed and enough history exists
+    "new_entry_grace_days": 14,  # Days after being added before the planner may buy
+    "new_entry_min_history_days": 200,  # Minimum daily price rows before the planner may buy (max 250)
     "strategy_max_funding_sells_per_cycle": 2,
     "strategy_max_funding_turnover_pct": 0.12,
     "strategy_funding_conviction_bias": 1.0,
+    # Swap trades: sell a weak holding specifically to fund a stronger buy
+    "strategy_swaps_enabled": False,
+    "strategy_swap_max_sell_score": 0.2,  # Holdings scoring at or below this may be swapped out
+    "strategy_swap_min_score_delta": 0.25,  # Buy must outscore the sold holding by this much
+    "strategy_swap_max_cost_pct": 0.02,  # Max fees + tax as a share of the swapped value
+    "capital_gains_tax_pct": 0,  # Tax on realized gains, used to cost swap sells
+    # Risk-parity sleeve funding suggestions: 'volatility' or 'drawdown'
+    "strategy_sleeve_risk_measure": "volatility",
+    "strategy_sleeve_risk_lookback_days": 252,
     # LED Display (Arduino UNO Q orbital visualization)
     "led_display_enabled": False,  # Disabled by default for dev environments
     "led_brightness": 200,  # Global LED brightness 0-255
+    # Indicator LEDs: state -> {led, color, pattern, priority} or null, merged over led.display defaults
+    "led_indicator_map": {},
+    # Summary screen: I2C OLED (luma.oled) or Waveshare e-ink HAT with value, day change and last trade
+    "display_screen_enabled": False,
+    "display_screen_driver": "oled",  # oled or eink
+    "display_screen_model": None,  # luma device (ssd1306, sh1106) or waveshare_epd module (epd2in13_V3)
+    "display_screen_i2c_port": 1,
+    "display_screen_i2c_address": 0x3C,
+    "display_screen_refresh_seconds": 60,  # Redrawn only when the content changed
+    # GPIO relay: indicator pin asserted while live trading is healthy, optional kill switch input
+    "gpio_relay_enabled": False,
+    "gpio_chip": "/dev/gpiochip0",
+    "gpio_relay_pin": None,  # Output line offset (None = not wired)
+    "gpio_relay_active_low": False,
+    "gpio_kill_switch_pin": None,  # Input line offset; each press toggles live/research
+    "gpio_kill_switch_active_low": True,  # Button pulling the line to ground
     # Cloudflare R2 Backup
     "r2_account_id": "",
     "r2_access_key": "",
     "r2_secret_key": "",
     "r2_bucket_name": "",
     "r2_backup_retention_days": 30,
+    # Position archive: move round trips closed longer ago than this out of the hot tables
+    "archive_closed_after_days": 365,
+    # Currency exposure: basis for /api/portfolio/currency-exposure (listing or revenue currency)
+    "currency_exposure_basis": "listing",
+    # Currency -> maximum share of the portfolio (0-1); above it a hedging suggestion is made
+    "currency_exposure_limits": {},
+    # Let the planner deprioritize buys (and prefer sells) in over-limit currencies
+    "currency_exposure_soft_constraints": False,
+    "currency_exposure_penalty": 0.5,  # Priority scaling per unit of over-limit currency share
+    # systemd watchdog: stop pinging (so systemd restarts the service) after the app has not been
+    # ready (database, migrations, broker) for this long (0 = keep pinging)
+    "watchdog_not_ready_grace_seconds": 600,
+    # Price tiering: bars older than price_hot_days move to this SQLite file on a USB drive or
+    # network path (None = keep all prices in the main database)
+    "price_cold_storage_path": None,
+    "price_hot_days": 730,
+    # Intraday snapshots: quotes of held positions every sync:intraday run while markets are open,
+    # kept raw for intraday_raw_retention_days, then hourly until intraday_hourly_retention_days, then daily
+    "intraday_snapshots_enabled": False,
+    "intraday_raw_retention_days": 7,
+    "intraday_hourly_retention_days": 365,
+    # Data retention (archive:retention job): table -> days to keep (0 = forever); intraday_prices is
+    # left to the downsampling above unless listed. archive:vacuum then reclaims the freed space
+    "retention_policies": {
+        "cache": 30,
+        "job_history": 90,
+        "notifications": 180,
+        "webhook_deliveries": 30,
+        "auth_audit": 365,
+        "state_checks": 30,
+    },
+    # Webhook notifications: event type -> enabled (endpoints are managed under /api/webhooks)
+    "webhook_events": {
+        "trade_executed": True,
+        "order_rejected": True,
+        "risk_limit_breached": True,
+        "job_failed": True,
+        "backup_completed": True,
+        "digest_report": True,
+        "config_drift": True,
+    },
+    # Digest report (report:digest job): period covered and whether it is pushed to the inbox/webhooks
+    "report_digest_period": "daily",  # daily or weekly
+    "report_digest_notify": True,
+    "report_keep": 60,  # Stored reports kept
+    # Outgoing mail (SMTP) for scheduled report emails; the password is stored in the vault
+    "smtp_host": "",
+    "smtp_port": 587,
+    "smtp_security": "starttls",  # starttls, ssl (implicit TLS, usually port 465) or none
+    "smtp_username": "",  # Empty to send without authentication
+    "smtp_password": "",
+    "smtp_from": "",
+    "smtp_timeout_seconds": 30,
+    # Report emails (report:email job): recipients, weekly performance report, monthly reconciliation summary
+    "report_email_recipients": [],
+    "report_email_weekly": True,
+    "report_email_reconciliation": True,
+    # Dividend withholding tax: treaty rate per issuer country (e.g. {"CH": 0.15}); withholding above it is reclaimable
+    "dividend_treaty_rates": {},
+    "dividend_treaty_rate_default": 0.15,
+    # Count holdings outside the broker account (see /api/external-holdings) in geography/industry
+    # allocations and currency exposure limits; the planner still only trades broker-held assets
+    "external_holdings_in_allocations": True,
+    # Broker reconciliation (reconcile:broker job): amounts, prices and quantities this close count as equal
+    "reconciliation_tolerance": 0.01,
+    # Read-only public dashboard at /api/public/dashboard (no auth; SENTINEL_PUBLIC_PORT serves it alone)
+    "public_dashboard_enabled": False,
+    "public_dashboard_hide_symbols": True,  # Allocation by geography/industry only
+    # On-demand CPU/heap profiling under /api/debug/pprof (admin only; 404 while off)
+    "profiling_enabled": False,
+    # HTTP API authentication (create an admin token or user before enabling)
+    "auth_enabled": False,
+    "auth_session_hours": 24,  # Lifetime of tokens issued by username/password login
+    # Opt in to the anonymized strategy fingerprint export (ratios only, never sent automatically)
+    "telemetry_export_enabled": False,
+    # Cash equivalents (securities with asset_class 'cash_equivalent', e.g. money market ETFs)
+    "cash_equivalents_enabled": False,  # Park idle cash in them and sell them first to fund buys
+    "cash_equivalent_park_symbol": "",  # Instrument to park in ("" = largest held cash equivalent)
+    "cash_equivalent_reserve_eur": 500.0,  # Cash kept uninvested when parking
+    "cash_equivalent_min_park_eur": 250.0,  # Smallest idle amount worth parking
+    # Circuit breaker: pause trading/planning jobs until resumed via POST /api/risk/circuit-breaker/resume
+    "circuit_breaker_max_drawdown_pct": 20.0,  # Trip when value falls this far below its high-water mark (0 = off)
+    "circuit_breaker_max_rejections": 5,  # Trip after this many consecutive rejected orders (0 = off)
+    # Configuration drift between paired devices (config:drift_check job; "" = no peer)
+    "config_peer_url": "",  # Base URL of the peer, e.g. http://sentinel-standby:8000
+    "config_peer_token": "",  # API token for the peer (stored in the vault)
+    "config_drift_ignore": [],  # Extra settings expected to differ between the devices
+    # Job failure remediation: failure class -> action, job type -> per-class overrides
+    # (see sentinel/jobs/failures.py for classes and actions)
+    "job_failure_remediation": {
+        "network": "retry",
+        "db_locked": "retry",
+        "broker_auth": "refresh_credentials",
+        "trading:execute": {"network": "none", "db_locked": "none"},
+    },
 }
 
 
@@ -88,6 +334,8 @@ class Settings:
 
     def __init__(self):
         self._db = Database()
+        self._listeners: list[tuple[Optional[frozenset[str]], SettingsListener]] = []
+        self._snapshot: Optional[dict] = None
 
     async def get(self, key: str, default: Any = None) -> Any:
         """Get a setting value."""
@@ -97,8 +345,9 @@ class Settings:
         return value
 
     async def set(self, key: str, value: Any) -> None:
-        """Set a setting value."""
+        """Set a setting value and notify the listeners of key."""
         await self._db.set_setting(key, value)
+        await self.notify({key: value})
 
     async def all(self) -> dict:
         """Get all settings with defaults applied."""
@@ -113,3 +362,63 @@ class Settings:
             existing = await self._db.get_setting(key)
             if existing is None:
                 await self._db.set_setting(key, value)
+
+    def subscribe(self, keys: Optional[Iterable[str]], listener: SettingsListener) -> Callable[[], None]:
+        """Call listener after any of keys changes (None = every key).
+
+        Returns:
+            Function that removes the subscription
+        """
+        entry = (frozenset(keys) if keys is not None else None, listener)
+        self._listeners.append(entry)
+
+        def unsubscribe() -> None:
+            if entry in self._listeners:
+                self._listeners.remove(entry)
+
+        return unsubscribe
+
+    async def reload(self) -> dict[str, Any]:
+        """Re-read every setting and notify listeners of values changed since the last reload.
+
+        The first call only records the baseline.
+
+        Returns:
+            Changed keys with their new values
+        """
+        current = await self.all()
+        previous, self._snapshot = self._snapshot, current
+        if previous is None:
+            return {}
+        changed = {k: v for k, v in current.items() if k not in previous or previous[k] != v}
+        await self.notify(changed, record=False)
+        return changed
+
+    async def notify(self, changed: dict[str, Any], record: bool = True) -> None:
+        """Tell the listeners of the changed keys (for writes that bypass set(), e.g. batches).
+
+        Each change except those of UNAUDITED_KEYS is also written to the audit
+        log (secrets masked). A listener that fails is logged and skipped.
+        Nothing is notified inside a dry-run session, whose writes are rolled back.
+        """
+        from sentinel.audit import record_action
+        from sentinel.dry_run import is_dry_run
+        from sentinel.vault import SECRET_NAMES
+
+        if not changed or is_dry_run():
+            return
+        if record and self._snapshot is not None:
+            self._snapshot.update(changed)
+        for key, value in changed.items():
+            if key in UNAUDITED_KEYS:
+                continue
+            params = {"value": "***" if key in SECRET_NAMES and value else value, "reload": not record}
+            await record_action(self._db, "settings.change", key, params)
+        for keys, listener in list(self._listeners):
+            relevant = changed if keys is None else {k: v for k, v in changed.items() if k in keys}
+            if not relevant:
+                continue
+            try:
+                await listener(relevant)
+            except Exception as e:
+                logger.error(f"Settings listener for {', '.join(sorted(relevant))} failed: {e}")
@@ -66,6 +66,8 @@ class SnapshotService:
             logger.info("Backfilling portfolio snapshots...")
 
             trades = await self._db.get_trades(limit=10000)
+            # Archived round trips still shaped historical positions and cash
+            trades += await self._db.get_archived_trades()
             cash_flows = await self._db.get_cash_flows()
             if not trades and not cash_flows:
                 logger.info("No trades or cash flows found, skipping backfill")
@@ -4,14 +4,50 @@ from .contrarian import (
     classify_lot_size,
     compute_contrarian_signal,
     compute_symbol_targets,
+    contrarian_skipped_checks,
     effective_opportunity_score,
     recent_dd252_min,
 )
+from .regime import (
+    DETECTORS,
+    REGIMES,
+    VOTE_POLICIES,
+    SignalDetector,
+    classify_regime,
+    detect_regime,
+    parse_detector_weights,
+    register_detector,
+)
+from .sizing import (
+    SIZING_MODES,
+    SLEEVES,
+    PositionSizer,
+    ScoreSizer,
+    VolatilityTargetSizer,
+    make_sizer,
+    validate_sizing_overrides,
+)
 
 __all__ = [
+    "DETECTORS",
+    "REGIMES",
+    "SIZING_MODES",
+    "SLEEVES",
+    "VOTE_POLICIES",
+    "PositionSizer",
+    "ScoreSizer",
+    "SignalDetector",
+    "VolatilityTargetSizer",
     "classify_lot_size",
+    "classify_regime",
     "compute_contrarian_signal",
     "compute_symbol_targets",
+    "contrarian_skipped_checks",
+    "detect_regime",
     "effective_opportunity_score",
+    "make_sizer",
+    "parse_detector_weights",
     "recent_dd252_min",
+    "register_detector",
+    "validate_sizing_overrides",
 ]
@@ -4,6 +4,13 @@ from __future__ import annotations
 
 import math
 
+from .sizing import PositionSizer, ScoreSizer
+
+# Closes needed before any contrarian check runs (mom120 plus a small buffer)
+MIN_SIGNAL_HISTORY = 130
+# Checks that make up the contrarian signal; all are skipped on short histories
+SIGNAL_CHECKS = ("dip_score", "capitulation_score", "cycle_turn", "freefall_block", "core_rank")
+
 
 def _clip(value: float, min_value: float, max_value: float) -> float:
     return max(min_value, min(max_value, value))
@@ -76,9 +83,28 @@ def _rsi14(closes: list[float]) -> float:
     return 100.0 - (100.0 / (1.0 + rs))
 
 
+def contrarian_skipped_checks(closes_oldest_first: list[float]) -> list[dict[str, str | int]]:
+    """List signal checks that cannot run because of missing inputs.
+
+    A short history yields the same neutral signal as a security that genuinely
+    does not qualify; this makes the difference explicit.
+
+    Returns:
+        One entry per skipped check with the missing input, required and available
+        observation counts (empty when every check ran)
+    """
+    available = len(closes_oldest_first)
+    if available >= MIN_SIGNAL_HISTORY:
+        return []
+    return [
+        {"check": check, "input": "price_history", "required": MIN_SIGNAL_HISTORY, "available": available}
+        for check in SIGNAL_CHECKS
+    ]
+
+
 def compute_contrarian_signal(closes_oldest_first: list[float]) -> dict[str, float | int]:
     """Compute deterministic contrarian metrics from close series."""
-    if len(closes_oldest_first) < 130:
+    if len(closes_oldest_first) < MIN_SIGNAL_HISTORY:
         return {
             "dd252": 0.0,
             "dd252_recent_min": 0.0,
@@ -181,11 +207,17 @@ def compute_symbol_targets(
     opportunity_target: float,
     min_opp_score: float,
     max_opportunity_target: float | None = None,
+    core_sizer: PositionSizer | None = None,
+    opportunity_sizer: PositionSizer | None = None,
 ) -> tuple[dict[str, float], dict[str, str]]:
     """Build target allocations and sleeve mapping from deterministic signals.
 
     `user_multipliers` are caller-provided preference weights derived from conviction.
+    Each sleeve's candidates are sized by its sizer (score sizing if None); sizers
+    that hold back part of a sleeve leave it in cash instead of renormalizing.
     """
+    core_sizer = core_sizer or ScoreSizer()
+    opportunity_sizer = opportunity_sizer or ScoreSizer()
     core_candidates = {}
     opp_candidates = {}
 
@@ -221,29 +253,31 @@ def compute_symbol_targets(
     sleeves: dict[str, str] = {}
 
     # Core sleeve
-    core_weight_sum = sum(core_candidates.values())
-    if core_weight_sum > 0:
-        for symbol, weight in core_candidates.items():
-            allocations[symbol] = allocations.get(symbol, 0.0) + (weight / core_weight_sum) * effective_core_target
+    core_sizes = core_sizer.size(core_candidates, symbol_signals)
+    opp_sizes = opportunity_sizer.size(opp_candidates, symbol_signals)
+    budget = 0.0
+    if core_sizes:
+        budget += effective_core_target
+        for symbol, size in core_sizes.items():
+            allocations[symbol] = allocations.get(symbol, 0.0) + size * effective_core_target
             sleeves.setdefault(symbol, "core")
 
     # Opportunity sleeve
-    opp_weight_sum = sum(opp_candidates.values())
-    if opp_weight_sum > 0:
-        for symbol, weight in opp_candidates.items():
-            allocations[symbol] = allocations.get(symbol, 0.0) + (
-                (weight / opp_weight_sum) * effective_opportunity_target
-            )
+    if opp_sizes:
+        budget += effective_opportunity_target
+        for symbol, size in opp_sizes.items():
+            allocations[symbol] = allocations.get(symbol, 0.0) + size * effective_opportunity_target
             sleeves[symbol] = "opportunity"
     else:
         # Keep portfolio fully invested if no tactical candidates
-        core_weight_sum = sum(core_candidates.values())
-        if core_weight_sum > 0:
-            for symbol, weight in core_candidates.items():
-                allocations[symbol] = weight / core_weight_sum
+        budget = 1.0 if core_sizes else 0.0
+        for symbol, size in core_sizes.items():
+            allocations[symbol] = size
 
     total = sum(allocations.values())
     if total <= 0:
         return {}, {}
-    allocations = {symbol: value / total for symbol, value in allocations.items() if value > 0}
+    # Fully invested sizing renormalizes to 100%; cash held back by a sizer stays uninvested
+    divisor = total if total >= budget - 1e-9 else budget
+    allocations = {symbol: value / divisor for symbol, value in allocations.items() if value > 0}
     return allocations, sleeves
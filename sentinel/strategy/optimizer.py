@@ -0,0 +1,182 @@
+"""Allocation optimizers proposing target weights for allocation groups.
+
+An optimizer turns per-group expected scores and the covariance of the groups'
+daily returns into weights (fractions summing to 1) within per-group bounds:
+
+- risk_parity: every group contributes the same share of portfolio variance
+  (expected scores are ignored)
+- mean_variance: maximizes expected score - risk_aversion / 2 x variance
+
+Both are plain Python on a handful of groups (geographies or industries), so
+simple iterative solvers are fast enough. The result can be limited to a
+turnover budget relative to the active targets (apply_turnover_budget).
+"""
+
+from __future__ import annotations
+
+import math
+from typing import Protocol
+
+RISK_PARITY = "risk_parity"
+MEAN_VARIANCE = "mean_variance"
+OPTIMIZER_METHODS = (RISK_PARITY, MEAN_VARIANCE)
+
+TRADING_DAYS = 252
+_ITERATIONS = 500
+_TOLERANCE = 1e-10
+
+Bounds = dict[str, tuple[float, float]]
+
+
+def covariance(returns: dict[str, list[float]]) -> dict[str, dict[str, float]]:
+    """Annualized sample covariance of aligned daily return series (group -> returns)."""
+    names = list(returns)
+    length = min((len(r) for r in returns.values()), default=0)
+    if length < 2:
+        return {a: {b: 0.0 for b in names} for a in names}
+    series = {name: returns[name][-length:] for name in names}
+    means = {name: sum(values) / length for name, values in series.items()}
+    return {
+        a: {
+            b: sum((x - means[a]) * (y - means[b]) for x, y in zip(series[a], series[b], strict=True))
+            / (length - 1)
+            * TRADING_DAYS
+            for b in names
+        }
+        for a in names
+    }
+
+
+def _variance(weights: dict[str, float], cov: dict[str, dict[str, float]]) -> float:
+    return sum(weights[a] * weights[b] * cov[a][b] for a in weights for b in weights)
+
+
+def _marginal(weights: dict[str, float], cov: dict[str, dict[str, float]]) -> dict[str, float]:
+    return {a: sum(cov[a][b] * weights[b] for b in weights) for a in weights}
+
+
+def check_bounds(names: list[str], bounds: Bounds) -> None:
+    """Raise ValueError unless weights summing to 1 can satisfy the bounds."""
+    low = sum(bounds.get(n, (0.0, 1.0))[0] for n in names)
+    high = sum(bounds.get(n, (0.0, 1.0))[1] for n in names)
+    if low > 1.0 + 1e-9 or high < 1.0 - 1e-9:
+        raise ValueError(f"Group bounds cannot be met: minimums sum to {low:.0%}, maximums to {high:.0%}")
+
+
+def project(values: dict[str, float], bounds: Bounds) -> dict[str, float]:
+    """Closest weights to values that sum to 1 and respect the bounds (bisection on a common shift)."""
+    limits = {n: bounds.get(n, (0.0, 1.0)) for n in values}
+
+    def shifted(tau: float) -> dict[str, float]:
+        return {n: min(max(v - tau, limits[n][0]), limits[n][1]) for n, v in values.items()}
+
+    low = min(v - limits[n][1] for n, v in values.items()) - 1.0
+    high = max(v - limits[n][0] for n, v in values.items()) + 1.0
+    for _ in range(100):
+        mid = (low + high) / 2
+        if sum(shifted(mid).values()) > 1.0:
+            low = mid
+        else:
+            high = mid
+    return shifted((low + high) / 2)
+
+
+class AllocationOptimizer(Protocol):
+    """Proposes group weights."""
+
+    method: str
+
+    def optimize(
+        self, expected: dict[str, float], cov: dict[str, dict[str, float]], bounds: Bounds
+    ) -> dict[str, float]:
+        """group -> weight (0-1, summing to 1)."""
+        ...
+
+
+class RiskParityOptimizer:
+    """Equal risk contribution weights, projected onto the bounds."""
+
+    method = RISK_PARITY
+
+    def optimize(
+        self, expected: dict[str, float], cov: dict[str, dict[str, float]], bounds: Bounds
+    ) -> dict[str, float]:
+        names = list(expected)
+        check_bounds(names, bounds)
+        vols = {n: math.sqrt(max(cov[n][n], 0.0)) for n in names}
+        if any(v <= 0 for v in vols.values()):
+            # Without a volatility for every group there is nothing to balance: equal weights
+            return project({n: 1.0 / len(names) for n in names}, bounds)
+        inverse = {n: 1.0 / vols[n] for n in names}
+        weights = {n: w / sum(inverse.values()) for n, w in inverse.items()}
+        for _ in range(_ITERATIONS):
+            variance = _variance(weights, cov)
+            marginal = _marginal(weights, cov)
+            if variance <= 0:
+                break
+            target = variance / len(names)
+            updated = {n: weights[n] * math.sqrt(target / max(weights[n] * marginal[n], 1e-18)) for n in names}
+            total = sum(updated.values())
+            updated = {n: w / total for n, w in updated.items()}
+            moved = max(abs(updated[n] - weights[n]) for n in names)
+            weights = updated
+            if moved < _TOLERANCE:
+                break
+        return project(weights, bounds)
+
+
+class MeanVarianceOptimizer:
+    """Maximizes expected score minus risk_aversion / 2 x variance (projected gradient ascent)."""
+
+    method = MEAN_VARIANCE
+
+    def __init__(self, risk_aversion: float = 5.0):
+        self.risk_aversion = risk_aversion
+
+    def optimize(
+        self, expected: dict[str, float], cov: dict[str, dict[str, float]], bounds: Bounds
+    ) -> dict[str, float]:
+        names = list(expected)
+        check_bounds(names, bounds)
+        # Step below 1 / Lipschitz constant of the gradient (bounded by the largest absolute row sum)
+        lipschitz = self.risk_aversion * max(sum(abs(v) for v in cov[n].values()) for n in names)
+        step = 1.0 / lipschitz if lipschitz > 0 else 1.0
+        weights = project({n: 1.0 / len(names) for n in names}, bounds)
+        for _ in range(_ITERATIONS):
+            marginal = _marginal(weights, cov)
+            gradient = {n: expected[n] - self.risk_aversion * marginal[n] for n in names}
+            updated = project({n: weights[n] + step * gradient[n] for n in names}, bounds)
+            moved = max(abs(updated[n] - weights[n]) for n in names)
+            weights = updated
+            if moved < _TOLERANCE:
+                break
+        return weights
+
+
+def make_optimizer(method: str | None, risk_aversion: float = 5.0) -> AllocationOptimizer:
+    """Optimizer for a method name.
+
+    Raises:
+        ValueError: Unknown method
+    """
+    if method == MEAN_VARIANCE:
+        return MeanVarianceOptimizer(risk_aversion)
+    if method in (None, "", RISK_PARITY):
+        return RiskParityOptimizer()
+    raise ValueError(f"Unknown optimizer method '{method}' (expected one of {', '.join(OPTIMIZER_METHODS)})")
+
+
+def turnover(proposed: dict[str, float], active: dict[str, float]) -> float:
+    """Share of the allocation that moves between two weightings (half the total absolute change)."""
+    names = set(proposed) | set(active)
+    return sum(abs(proposed.get(n, 0.0) - active.get(n, 0.0)) for n in names) / 2
+
+
+def apply_turnover_budget(proposed: dict[str, float], active: dict[str, float], budget: float) -> dict[str, float]:
+    """Move from the active weights toward the proposal only as far as the turnover budget allows."""
+    moved = turnover(proposed, active)
+    if not active or moved <= budget or moved <= 0:
+        return dict(proposed)
+    share = budget / moved
+    names = set(proposed) | set(active)
+    return {n: active.get(n, 0.0) + share * (proposed.get(n, 0.0) - active.get(n, 0.0)) for n in names}
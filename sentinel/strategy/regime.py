@@ -0,0 +1,240 @@
+"""Ensemble market regime detection.
+
+Registered detectors each score a region from -1 (risk-off) to +1 (risk-on);
+their scores are combined by a policy into bull, neutral or bear. Four
+detectors are built in:
+
+- trend: index close versus its 200-day average, and the 50-day versus the 200-day
+- volatility: percentile of current 20-day realized volatility within the last year
+  (calm markets score high, the most volatile of the year low)
+- drawdown: index distance from its 252-day high (above -5% is +1, -20% or worse -1)
+- breadth: share of the region's securities trading above their own 200-day average
+
+Confidence is how much of the ensemble backs the classification: the weight of the
+signals pointing the same way as the score (bull/bear) or the closeness of the
+score to zero (neutral), scaled down when signals are missing for lack of history.
+
+Policies (regime_vote_policy setting):
+
+- weighted: the weighted average score is compared with the threshold
+- majority: each detector votes bull / bear / neutral by its own score and the
+  threshold, and the regime with the most vote weight wins (ties are neutral)
+
+Further detectors are added with register_detector(); a detector with weight 0
+in regime_detector_weights is still scored and reported with the others, so it
+can be compared before it is given a say.
+"""
+
+from __future__ import annotations
+
+import math
+from dataclasses import dataclass
+from statistics import pstdev
+from typing import Callable, Protocol
+
+BULL = "bull"
+NEUTRAL = "neutral"
+BEAR = "bear"
+REGIMES = (BULL, NEUTRAL, BEAR)
+
+SIGNAL_WEIGHTS = {"trend": 0.35, "volatility": 0.2, "drawdown": 0.25, "breadth": 0.2}
+# Scores beyond +/- this are classified as bull / bear
+CLASSIFY_THRESHOLD = 0.2
+
+WEIGHTED = "weighted"
+MAJORITY = "majority"
+VOTE_POLICIES = (WEIGHTED, MAJORITY)
+
+TREND_LONG = 200
+TREND_SHORT = 50
+VOL_WINDOW = 20
+VOL_LOOKBACK = 252
+# Fewer realized-vol observations than this and the percentile is not meaningful
+MIN_VOL_HISTORY = 60
+DRAWDOWN_LOOKBACK = 252
+DRAWDOWN_MILD = -0.05
+DRAWDOWN_SEVERE = -0.20
+
+
+def _clamp(value: float) -> float:
+    return max(-1.0, min(1.0, value))
+
+
+def trend_signal(closes: list[float]) -> float | None:
+    """Average of close vs 200-day SMA (+/-10% saturates) and 50-day vs 200-day SMA direction."""
+    if len(closes) < TREND_LONG:
+        return None
+    long_ma = sum(closes[-TREND_LONG:]) / TREND_LONG
+    short_ma = sum(closes[-TREND_SHORT:]) / TREND_SHORT
+    if long_ma <= 0:
+        return None
+    distance = _clamp((closes[-1] / long_ma - 1.0) / 0.10)
+    cross = 1.0 if short_ma > long_ma else -1.0
+    return (distance + cross) / 2.0
+
+
+def volatility_signal(closes: list[float]) -> float | None:
+    """1 - 2 x percentile of the current 20-day realized vol within the lookback."""
+    returns = [cur / prev - 1.0 for prev, cur in zip(closes, closes[1:], strict=False) if prev > 0]
+    returns = returns[-(VOL_LOOKBACK + VOL_WINDOW) :]
+    vols = [pstdev(returns[i - VOL_WINDOW : i]) for i in range(VOL_WINDOW, len(returns) + 1)]
+    if len(vols) < MIN_VOL_HISTORY:
+        return None
+    current = vols[-1]
+    percentile = sum(1 for v in vols if v <= current) / len(vols)
+    return _clamp(1.0 - 2.0 * percentile)
+
+
+def drawdown_signal(closes: list[float]) -> float | None:
+    """+1 within 5% of the 252-day high, -1 at 20% below it or worse, linear in between."""
+    if not closes:
+        return None
+    peak = max(closes[-DRAWDOWN_LOOKBACK:])
+    if peak <= 0:
+        return None
+    drawdown = closes[-1] / peak - 1.0
+    if drawdown >= DRAWDOWN_MILD:
+        return 1.0
+    if drawdown <= DRAWDOWN_SEVERE:
+        return -1.0
+    return 1.0 - 2.0 * (DRAWDOWN_MILD - drawdown) / (DRAWDOWN_MILD - DRAWDOWN_SEVERE)
+
+
+def breadth_signal(constituents: list[list[float]]) -> float | None:
+    """2 x share of constituents above their 200-day SMA - 1 (those with shorter history are left out)."""
+    eligible = [closes for closes in constituents if len(closes) >= TREND_LONG]
+    if not eligible:
+        return None
+    above = sum(1 for closes in eligible if closes[-1] > sum(closes[-TREND_LONG:]) / TREND_LONG)
+    return 2.0 * above / len(eligible) - 1.0
+
+
+class RegimeDetector(Protocol):
+    """Scores a region from its index closes and its constituents' closes (oldest first)."""
+
+    name: str
+    description: str
+
+    def score(self, index_closes: list[float], constituents: list[list[float]]) -> float | None: ...
+
+
+@dataclass(frozen=True)
+class SignalDetector:
+    """Detector wrapping a signal function of the index closes (or of the constituents)."""
+
+    name: str
+    description: str
+    signal: Callable[[list], float | None]
+    uses_constituents: bool = False
+
+    def score(self, index_closes: list[float], constituents: list[list[float]]) -> float | None:
+        return self.signal(constituents if self.uses_constituents else index_closes)
+
+
+DETECTORS: dict[str, RegimeDetector] = {}
+
+
+def register_detector(detector: RegimeDetector) -> None:
+    """Add (or replace) a detector of the ensemble."""
+    DETECTORS[detector.name] = detector
+
+
+register_detector(SignalDetector("trend", "Index versus its 200-day average and 50/200-day cross", trend_signal))
+register_detector(SignalDetector("volatility", "Current 20-day volatility within the last year", volatility_signal))
+register_detector(SignalDetector("drawdown", "Index distance from its 252-day high", drawdown_signal))
+register_detector(
+    SignalDetector("breadth", "Share of constituents above their 200-day average", breadth_signal, True)
+)
+
+
+def parse_detector_weights(raw) -> dict[str, float]:
+    """Validate a regime_detector_weights value: registered detector -> non-negative weight, some positive."""
+    if not isinstance(raw, dict):
+        raise ValueError("regime_detector_weights must be an object of detector -> weight")
+    weights = {}
+    for name, weight in raw.items():
+        if name not in DETECTORS:
+            raise ValueError(f"unknown detector {name} (known: {', '.join(sorted(DETECTORS))})")
+        if isinstance(weight, bool) or not isinstance(weight, (int, float)) or weight < 0:
+            raise ValueError(f"weight of {name} must be a non-negative number")
+        weights[name] = float(weight)
+    if not any(weights.values()):
+        raise ValueError("at least one detector needs a positive weight")
+    return weights
+
+
+def _vote(score: float, threshold: float) -> str:
+    if score > threshold:
+        return BULL
+    if score < -threshold:
+        return BEAR
+    return NEUTRAL
+
+
+def classify_regime(
+    signals: dict[str, float | None],
+    weights: dict[str, float] | None = None,
+    threshold: float = CLASSIFY_THRESHOLD,
+    policy: str = WEIGHTED,
+) -> dict:
+    """Combine signal scores into a regime with a confidence.
+
+    Args:
+        signals: signal name -> score (-1..1), None when it could not be computed
+        weights: signal name -> weight (defaults to SIGNAL_WEIGHTS)
+        threshold: |score| above which the regime (or a majority vote) is bull / bear
+        policy: weighted or majority (see module docstring)
+
+    Returns:
+        dict with regime, score (-1..1), confidence (0..1) and the rounded signals;
+        neutral with zero confidence when no signal is available
+    """
+    weights = weights or SIGNAL_WEIGHTS
+    total_weight = sum(weights.get(name, 0.0) for name in signals)
+    available = {name: s for name, s in signals.items() if s is not None and weights.get(name, 0.0) > 0}
+    available_weight = sum(weights[name] for name in available)
+    rounded = {name: (round(s, 4) if s is not None else None) for name, s in signals.items()}
+    if available_weight <= 0 or total_weight <= 0:
+        return {"regime": NEUTRAL, "score": 0.0, "confidence": 0.0, "signals": rounded}
+
+    score = sum(weights[name] * s for name, s in available.items()) / available_weight
+    coverage = available_weight / total_weight
+    if policy == MAJORITY:
+        votes = {regime: 0.0 for regime in REGIMES}
+        for name, s in available.items():
+            votes[_vote(s, threshold)] += weights[name]
+        top = max(votes.values())
+        winners = [regime for regime, weight in votes.items() if weight == top]
+        regime = winners[0] if len(winners) == 1 else NEUTRAL
+        backing = votes[regime] / available_weight
+    elif score > threshold:
+        regime = BULL
+        backing = sum(weights[name] for name, s in available.items() if s > 0) / available_weight
+    elif score < -threshold:
+        regime = BEAR
+        backing = sum(weights[name] for name, s in available.items() if s < 0) / available_weight
+    else:
+        regime = NEUTRAL
+        backing = 1.0 - abs(score) / threshold if threshold > 0 else 1.0
+    return {
+        "regime": regime,
+        "score": round(score, 4),
+        "confidence": round(max(0.0, min(1.0, backing * coverage)), 4),
+        "signals": rounded,
+    }
+
+
+def detect_regime(
+    index_closes: list[float],
+    constituents: list[list[float]],
+    weights: dict[str, float] | None = None,
+    threshold: float = CLASSIFY_THRESHOLD,
+    policy: str = WEIGHTED,
+) -> dict:
+    """Regime of one region from its index closes and its constituents' closes (oldest first).
+
+    Every registered detector is scored; weights (default SIGNAL_WEIGHTS) decide which count.
+    """
+    closes = [c for c in index_closes if c is not None and not math.isnan(c)]
+    signals = {name: detector.score(closes, constituents) for name, detector in DETECTORS.items()}
+    return classify_regime(signals, weights, threshold, policy)
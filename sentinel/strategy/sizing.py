@@ -0,0 +1,118 @@
+"""Position sizing models for the strategy sleeves.
+
+A sizer turns a sleeve's candidates (symbol -> score weight) into position sizes
+as fractions of the sleeve. Sizes may sum to less than 1: the remainder of the
+sleeve stays in cash.
+
+- score: sizes proportional to the candidate score weights (fully invested)
+- volatility_target: sizes inversely proportional to realized volatility, each
+  capped at max_weight of the sleeve (the excess goes to the other positions,
+  or cash once all are capped), and the whole sleeve scaled down so its
+  estimated volatility does not exceed target_vol
+
+The mode is chosen globally (position_sizing_mode) and can be overridden per
+sleeve (position_sizing_overrides, e.g. {"opportunity": "volatility_target"}).
+"""
+
+from __future__ import annotations
+
+import math
+from dataclasses import dataclass
+from typing import Protocol
+
+SCORE = "score"
+VOLATILITY_TARGET = "volatility_target"
+SIZING_MODES = (SCORE, VOLATILITY_TARGET)
+SLEEVES = ("core", "opportunity")
+
+TRADING_DAYS = 252
+
+
+def _capped_shares(raw: dict[str, float], cap: float) -> dict[str, float]:
+    """Shares proportional to raw, none above cap; the excess goes to the uncapped (cash once all are)."""
+    shares: dict[str, float] = {}
+    free = dict(raw)
+    remaining = 1.0
+    while free:
+        total = sum(free.values())
+        over = {s for s, w in free.items() if w / total * remaining > cap}
+        if not over:
+            shares.update({s: w / total * remaining for s, w in free.items()})
+            break
+        for symbol in over:
+            shares[symbol] = cap
+            del free[symbol]
+        remaining = max(0.0, 1.0 - sum(shares.values()))
+    return shares
+
+
+class PositionSizer(Protocol):
+    """Sizes the positions of one sleeve."""
+
+    mode: str
+
+    def size(self, candidates: dict[str, float], signals: dict[str, dict]) -> dict[str, float]:
+        """symbol -> fraction of the sleeve (0-1, summing to at most 1)."""
+        ...
+
+
+class ScoreSizer:
+    """Sizes proportional to the candidates' score weights."""
+
+    mode = SCORE
+
+    def size(self, candidates: dict[str, float], signals: dict[str, dict]) -> dict[str, float]:
+        total = sum(w for w in candidates.values() if w > 0)
+        if total <= 0:
+            return {}
+        return {symbol: w / total for symbol, w in candidates.items() if w > 0}
+
+
+@dataclass
+class VolatilityTargetSizer:
+    """Inverse-volatility sizes scaled to a target annualized sleeve volatility."""
+
+    target_vol: float = 0.15  # Annualized, 0-1
+    max_weight: float = 0.25  # Largest share of the sleeve in one position
+    min_vol: float = 0.05  # Annualized floor so near-flat histories do not get huge sizes
+    mode: str = VOLATILITY_TARGET
+
+    def annualized_vol(self, signal: dict) -> float:
+        """Realized volatility from the signal's daily vol20, annualized and floored."""
+        daily = float(signal.get("vol20", 0.0) or 0.0)
+        return max(self.min_vol, daily * math.sqrt(TRADING_DAYS))
+
+    def size(self, candidates: dict[str, float], signals: dict[str, dict]) -> dict[str, float]:
+        vols = {s: self.annualized_vol(signals.get(s, {})) for s, w in candidates.items() if w > 0}
+        if not vols:
+            return {}
+        weights = _capped_shares({s: 1.0 / v for s, v in vols.items()}, self.max_weight)
+        # Positions assumed perfectly correlated: a conservative (upper bound) sleeve volatility
+        sleeve_vol = sum(weights[s] * vols[s] for s in weights)
+        scale = min(1.0, self.target_vol / sleeve_vol) if sleeve_vol > 0 and self.target_vol > 0 else 1.0
+        return {s: w * scale for s, w in weights.items()}
+
+
+def validate_sizing_overrides(value: object) -> dict[str, str]:
+    """Check a position_sizing_overrides value.
+
+    Raises:
+        ValueError: Not a sleeve -> mode mapping
+    """
+    if value is None:
+        return {}
+    if not isinstance(value, dict):
+        raise ValueError("position_sizing_overrides must map sleeves to sizing modes")
+    for sleeve, mode in value.items():
+        if sleeve not in SLEEVES:
+            raise ValueError(f"Unknown sleeve '{sleeve}' (expected one of {', '.join(SLEEVES)})")
+        if mode not in SIZING_MODES:
+            raise ValueError(f"Unknown sizing mode '{mode}' (expected one of {', '.join(SIZING_MODES)})")
+    return dict(value)
+
+
+def make_sizer(mode: str | None, target_vol_pct: float = 15.0, max_weight_pct: float = 25.0) -> PositionSizer:
+    """Sizer for a mode name; unknown or empty modes fall back to score sizing."""
+    if mode == VOLATILITY_TARGET:
+        return VolatilityTargetSizer(target_vol=target_vol_pct / 100.0, max_weight=max_weight_pct / 100.0)
+    return ScoreSizer()
@@ -0,0 +1,154 @@
+"""TLS and mutual TLS for the HTTP server.
+
+Configured from the environment (or the matching main.py flags):
+
+    SENTINEL_TLS_CERT          server certificate (PEM)
+    SENTINEL_TLS_KEY           server private key (PEM)
+    SENTINEL_TLS_CLIENT_CA     CA bundle for client certificates; enables mutual TLS
+    SENTINEL_TLS_CLIENT_AUTH   'required' (default) or 'optional' client certificates
+    SENTINEL_TLS_REQUIRED      '1' to refuse to start instead of falling back to HTTP
+    SENTINEL_TLS_EXPIRY_WARN_DAYS  warn when a certificate expires within this many days (default 14)
+
+Certificates are validated at startup: missing files, a key that does not match
+the certificate, or an expired certificate disable TLS with a warning (plain
+HTTP fallback) unless SENTINEL_TLS_REQUIRED is set, in which case startup fails.
+"""
+
+from __future__ import annotations
+
+import logging
+import os
+import ssl
+from dataclasses import dataclass
+from datetime import datetime, timezone
+
+logger = logging.getLogger(__name__)
+
+CLIENT_AUTH_MODES = {"required": ssl.CERT_REQUIRED, "optional": ssl.CERT_OPTIONAL}
+DEFAULT_EXPIRY_WARN_DAYS = 14
+
+
+class TLSConfigError(Exception):
+    """TLS is required but the configuration is unusable."""
+
+
+@dataclass
+class TLSConfig:
+    """Server TLS settings."""
+
+    cert_file: str | None = None
+    key_file: str | None = None
+    client_ca_file: str | None = None
+    client_auth: str = "required"
+    required: bool = False
+    expiry_warn_days: int = DEFAULT_EXPIRY_WARN_DAYS
+
+    @classmethod
+    def from_env(cls, env: dict[str, str] | None = None) -> TLSConfig:
+        """Read the SENTINEL_TLS_* variables."""
+        env = os.environ if env is None else env
+        try:
+            warn_days = int(env.get("SENTINEL_TLS_EXPIRY_WARN_DAYS", DEFAULT_EXPIRY_WARN_DAYS))
+        except ValueError:
+            warn_days = DEFAULT_EXPIRY_WARN_DAYS
+        return cls(
+            cert_file=env.get("SENTINEL_TLS_CERT") or None,
+            key_file=env.get("SENTINEL_TLS_KEY") or None,
+            client_ca_file=env.get("SENTINEL_TLS_CLIENT_CA") or None,
+            client_auth=(env.get("SENTINEL_TLS_CLIENT_AUTH") or "required").lower(),
+            required=env.get("SENTINEL_TLS_REQUIRED", "").lower() in ("1", "true", "yes"),
+            expiry_warn_days=warn_days,
+        )
+
+    @property
+    def requested(self) -> bool:
+        """Whether any TLS option is set."""
+        return bool(self.cert_file or self.key_file or self.client_ca_file or self.required)
+
+
+def certificate_expiry(cert_file: str) -> datetime | None:
+    """Expiry (notAfter, UTC) of the first certificate in a PEM file, or None if unreadable."""
+    decode = getattr(getattr(ssl, "_ssl", None), "_test_decode_cert", None)
+    if decode is None:
+        return None
+    try:
+        not_after = decode(cert_file).get("notAfter")
+    except (OSError, ssl.SSLError, ValueError):
+        return None
+    if not not_after:
+        return None
+    return datetime.fromtimestamp(ssl.cert_time_to_seconds(not_after), tz=timezone.utc)
+
+
+def validate(config: TLSConfig, now: datetime | None = None) -> tuple[list[str], list[str]]:
+    """Check a TLS configuration.
+
+    Returns:
+        (errors, warnings); any error means TLS cannot be enabled
+    """
+    now = now or datetime.now(timezone.utc)
+    errors: list[str] = []
+    warnings: list[str] = []
+    if not config.cert_file or not config.key_file:
+        errors.append("Both a certificate and a private key are required for TLS")
+        return errors, warnings
+    if config.client_auth not in CLIENT_AUTH_MODES:
+        errors.append(f"Unknown client auth mode '{config.client_auth}' (use required or optional)")
+    for label, path in (
+        ("certificate", config.cert_file),
+        ("private key", config.key_file),
+        ("client CA", config.client_ca_file),
+    ):
+        if path and not os.path.isfile(path):
+            errors.append(f"TLS {label} not found: {path}")
+    if errors:
+        return errors, warnings
+
+    try:
+        context = ssl.SSLContext(ssl.PROTOCOL_TLS_SERVER)
+        context.load_cert_chain(config.cert_file, config.key_file)
+        if config.client_ca_file:
+            context.load_verify_locations(cafile=config.client_ca_file)
+    except (ssl.SSLError, OSError) as e:
+        errors.append(f"Unable to load TLS certificates: {e}")
+        return errors, warnings
+
+    expiry = certificate_expiry(config.cert_file)
+    if expiry is None:
+        warnings.append("Could not read the certificate expiry date")
+    elif expiry <= now:
+        errors.append(f"TLS certificate expired on {expiry:%Y-%m-%d}")
+    elif (expiry - now).days < config.expiry_warn_days:
+        warnings.append(f"TLS certificate expires in {(expiry - now).days} days ({expiry:%Y-%m-%d})")
+    return errors, warnings
+
+
+def server_ssl_options(config: TLSConfig, now: datetime | None = None) -> dict:
+    """uvicorn keyword arguments for a TLS configuration.
+
+    Returns an empty dict (plain HTTP) when TLS is not configured or, unless
+    required, when the configuration is invalid.
+
+    Raises:
+        TLSConfigError: If TLS is required and the configuration is invalid
+    """
+    if not config.requested:
+        return {}
+    errors, warnings = validate(config, now=now)
+    for warning in warnings:
+        logger.warning(warning)
+    if errors:
+        message = "; ".join(errors)
+        if config.required:
+            raise TLSConfigError(message)
+        logger.warning(f"TLS disabled, serving plain HTTP: {message}")
+        return {}
+
+    options: dict = {"ssl_certfile": config.cert_file, "ssl_keyfile": config.key_file}
+    if config.client_ca_file:
+        options["ssl_ca_certs"] = config.client_ca_file
+        options["ssl_cert_reqs"] = CLIENT_AUTH_MODES[config.client_auth]
+        logger.info(f"TLS enabled, client certificates {config.client_auth}")
+    else:
+        logger.info("TLS enabled")
+    return options
@@ -5,13 +5,14 @@ Contains shared utility classes and functions for eliminating code duplication.
 """
 
 from sentinel.price_validator import PriceValidator
-from sentinel.utils.fees import FeeCalculator
+from sentinel.utils.fees import FeeCalculator, FeeSchedule
 from sentinel.utils.positions import PositionCalculator
 from sentinel.utils.scoring import adjust_score_for_conviction
 from sentinel.utils.strings import parse_csv_field
 
 __all__ = [
     "FeeCalculator",
+    "FeeSchedule",
     "adjust_score_for_conviction",
     "parse_csv_field",
     "PriceValidator",
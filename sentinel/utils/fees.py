@@ -1,12 +1,95 @@
 """
 Fee Calculator - Single source of truth for transaction fee calculations.
 
+The flat transaction_fee_fixed / transaction_fee_percent pair applies to every
+trade unless fee_schedule overrides it for the trade's market (the exchange
+suffix of the symbol, AAPL.US -> US):
+
+    {"US": {"fixed": 1.0, "tiers": [{"up_to": 5000, "percent": 0.25}, {"up_to": null, "percent": 0.1}]}}
+
+The rate of the first tier whose up_to (EUR) covers the trade value applies to
+the whole value; a market without tiers uses the flat percentage. Trades in a
+currency other than EUR also pay fx_conversion_spread_pct on their value.
+
 Usage:
     calculator = FeeCalculator(settings)
     fee = await calculator.calculate(1000.0)
+    fee = await calculator.calculate(1000.0, symbol="AAPL.US", currency="USD")
+    schedule = await calculator.get_schedule()  # sync FeeSchedule for loops
     breakdown = await calculator.calculate_batch(trades)
 """
 
+from dataclasses import dataclass, field
+
+
+def market_of(symbol: str) -> str:
+    """Market of a Tradernet symbol from its exchange suffix (AAPL.US -> US)."""
+    return symbol.rsplit(".", 1)[1] if "." in symbol else "other"
+
+
+def parse_fee_schedule(raw) -> dict[str, dict]:
+    """Validate a fee_schedule setting value.
+
+    Returns:
+        market -> {"fixed": float | None, "tiers": [(up_to | None, pct_decimal), ...]} with tiers ascending
+
+    Raises:
+        ValueError: Malformed schedule
+    """
+    if raw in (None, ""):
+        return {}
+    if not isinstance(raw, dict):
+        raise ValueError("Fee schedule must be an object of market -> {fixed, tiers}")
+    schedule = {}
+    for market, entry in raw.items():
+        if not isinstance(entry, dict):
+            raise ValueError(f"Fee schedule for {market} must be an object")
+        fixed = entry.get("fixed")
+        if fixed is not None and (not isinstance(fixed, (int, float)) or fixed < 0):
+            raise ValueError(f"Fixed fee for {market} must be a non-negative number")
+        tiers = []
+        for tier in entry.get("tiers") or []:
+            if not isinstance(tier, dict) or not isinstance(tier.get("percent"), (int, float)) or tier["percent"] < 0:
+                raise ValueError(f"Each fee tier for {market} needs a non-negative percent")
+            up_to = tier.get("up_to")
+            if up_to is not None and (not isinstance(up_to, (int, float)) or up_to <= 0):
+                raise ValueError(f"Fee tier up_to for {market} must be a positive number or null")
+            tiers.append((None if up_to is None else float(up_to), float(tier["percent"]) / 100))
+        tiers.sort(key=lambda t: float("inf") if t[0] is None else t[0])
+        schedule[str(market)] = {"fixed": None if fixed is None else float(fixed), "tiers": tiers}
+    return schedule
+
+
+@dataclass
+class FeeSchedule:
+    """Fee configuration resolved from settings, for synchronous use in loops."""
+
+    fixed: float = 2.0
+    pct: float = 0.002  # decimal
+    markets: dict[str, dict] = field(default_factory=dict)  # parse_fee_schedule() output
+    fx_spread_pct: float = 0.0  # decimal
+
+    def rates(self, value_eur: float, symbol: str | None = None, currency: str | None = None) -> tuple[float, float]:
+        """Fixed fee and percentage (decimal, FX spread included) for a trade of value_eur."""
+        fixed, pct = self.fixed, self.pct
+        entry = self.markets.get(market_of(symbol)) if symbol else None
+        if entry:
+            if entry["fixed"] is not None:
+                fixed = entry["fixed"]
+            tier = next((p for up_to, p in entry["tiers"] if up_to is None or abs(value_eur) <= up_to), None)
+            if tier is not None:
+                pct = tier
+            elif entry["tiers"]:
+                pct = entry["tiers"][-1][1]
+        if currency and currency != "EUR":
+            pct += self.fx_spread_pct
+        return fixed, pct
+
+    def cost(self, value_eur: float, symbol: str | None = None, currency: str | None = None) -> float:
+        """Total transaction cost in EUR of one trade."""
+        fixed, pct = self.rates(value_eur, symbol, currency)
+        return fixed + abs(value_eur) * pct
+
 
 class FeeCalculator:
     """Calculates transaction fees for trades."""
@@ -46,18 +129,34 @@ class FeeCalculator:
         pct_fee = await settings.get("transaction_fee_percent", 0.2) / 100
         return fixed_fee, pct_fee
 
-    async def calculate(self, trade_value_eur: float) -> float:
+    async def get_schedule(self) -> FeeSchedule:
+        """
+        Get the flat fees, per-market schedule and FX spread as one FeeSchedule.
+
+        A malformed fee_schedule setting is ignored (flat fees apply).
+        """
+        settings = await self._get_settings()
+        fixed_fee, pct_fee = await self.get_fee_config()
+        try:
+            markets = parse_fee_schedule(await settings.get("fee_schedule", {}))
+        except ValueError:
+            markets = {}
+        fx_spread = float(await settings.get("fx_conversion_spread_pct", 0.0) or 0.0) / 100
+        return FeeSchedule(fixed=fixed_fee, pct=pct_fee, markets=markets, fx_spread_pct=fx_spread)
+
+    async def calculate(self, trade_value_eur: float, symbol: str | None = None, currency: str | None = None) -> float:
         """
         Calculate transaction cost for a given trade value.
 
         Args:
             trade_value_eur: Trade value in EUR
+            symbol: Security symbol (selects the market's fee schedule)
+            currency: Trading currency (non-EUR trades pay the FX spread)
 
         Returns:
             Total transaction cost in EUR
         """
-        fixed_fee, pct_fee = await self.get_fee_config()
-        return fixed_fee + (trade_value_eur * pct_fee)
+        return (await self.get_schedule()).cost(trade_value_eur, symbol, currency)
 
     def calculate_with_config(self, trade_value_eur: float, fixed_fee: float, pct_fee: float) -> float:
         """
@@ -81,7 +180,8 @@ class FeeCalculator:
         Calculate fees for a batch of trades.
 
         Args:
-            trades: List of trade dicts with 'action' and 'value_eur' keys
+            trades: List of trade dicts with 'action' and 'value_eur' keys,
+                    and optionally 'symbol' and 'currency'
 
         Returns:
             Dict with fee breakdown:
@@ -95,26 +195,28 @@ class FeeCalculator:
                 'total_sell_value': float,
             }
         """
-        fixed_fee, pct_fee = await self.get_fee_config()
+        schedule = await self.get_schedule()
 
         num_buys = 0
         num_sells = 0
         total_buy_value = 0.0
         total_sell_value = 0.0
+        buy_fees = 0.0
+        sell_fees = 0.0
 
         for trade in trades:
             action = trade.get("action", "")
             value = abs(trade.get("value_eur", 0))
+            fee = schedule.cost(value, trade.get("symbol"), trade.get("currency"))
 
             if action == "buy":
                 num_buys += 1
                 total_buy_value += value
+                buy_fees += fee
             elif action == "sell":
                 num_sells += 1
                 total_sell_value += value
-
-        buy_fees = num_buys * fixed_fee + total_buy_value * pct_fee
-        sell_fees = num_sells * fixed_fee + total_sell_value * pct_fee
+                sell_fees += fee
 
         return {
             "total_fees": buy_fees + sell_fees,
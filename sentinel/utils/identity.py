@@ -0,0 +1,52 @@
+"""
+Security identity - ISIN helpers.
+
+A security's ISIN is its stable identity; the ticker symbol is a mutable
+attribute that changes on renames, re-listings and mergers.
+
+Usage:
+    isin = extract_isin(broker_info)          # "US0378331005" or None
+    assert normalize_isin(" us0378331005 ") == "US0378331005"
+"""
+
+import re
+from typing import Optional
+
+ISIN_PATTERN = re.compile(r"^[A-Z]{2}[A-Z0-9]{9}[0-9]$")
+
+# Broker payload fields that carry the ISIN (Tradernet uses issue_nb)
+ISIN_FIELDS = ("isin", "issue_nb", "ISIN")
+
+
+def _checksum_ok(isin: str) -> bool:
+    digits = "".join(str(int(ch, 36)) for ch in isin[:-1])
+    total = 0
+    for i, ch in enumerate(reversed(digits)):
+        n = int(ch)
+        if i % 2 == 0:
+            n *= 2
+            if n > 9:
+                n -= 9
+        total += n
+    return (10 - total % 10) % 10 == int(isin[-1])
+
+
+def normalize_isin(value) -> Optional[str]:
+    """Upper-cased ISIN if `value` is a well-formed ISIN with a valid check digit, else None."""
+    if not isinstance(value, str):
+        return None
+    isin = value.strip().upper()
+    if not ISIN_PATTERN.match(isin) or not _checksum_ok(isin):
+        return None
+    return isin
+
+
+def extract_isin(payload: Optional[dict]) -> Optional[str]:
+    """First valid ISIN found in a broker payload (security info, quote or position)."""
+    if not payload:
+        return None
+    for field in ISIN_FIELDS:
+        isin = normalize_isin(payload.get(field))
+        if isin:
+            return isin
+    return None
@@ -0,0 +1,202 @@
+"""
+Order pricing - limit prices from order book depth, quotes and recent volume.
+
+Usage:
+    book = parse_order_book(raw_snapshot)
+    price = depth_limit_price("buy", 500, book, max_slippage_pct=2.0)
+
+    strategies = parse_pricing_strategies({"US": "midpoint", "thin": {"strategy": "vwap", "window_days": 10}})
+    strategy = pick_strategy(strategies, "US", "thin")  # {"strategy": "midpoint"}
+"""
+
+from typing import Optional
+
+# How an order was priced, recorded with each trade decision:
+#   market: no limit price (market order)
+#   quote: best bid/ask from the cached quote
+#   depth: price level that covers the order quantity in the order book
+#   midpoint: halfway between the quoted bid and ask
+#   last_buffer: last price plus (buy) or minus (sell) buffer_pct
+#   vwap: volume-weighted average price of the last window_days daily bars, +/- buffer_pct
+#   adaptive: the ask/bid when the spread is tight, moving toward the midpoint as it widens
+PRICING_METHODS = ("market", "quote", "depth", "midpoint", "last_buffer", "vwap", "adaptive")
+
+# Liquidity tiers of order_pricing_strategies keys; thin is below limit_depth_thin_value_eur
+LIQUIDITY_TIERS = ("thin", "liquid")
+
+# Parameter defaults of the strategies that take them
+DEFAULT_BUFFER_PCT = {"last_buffer": 0.5, "vwap": 0.0}
+DEFAULT_VWAP_WINDOW_DAYS = 5
+DEFAULT_TIGHT_SPREAD_PCT = 0.1
+
+
+def parse_order_book(raw: Optional[dict]) -> dict[str, list[tuple[float, float]]]:
+    """Normalize a Tradernet orderBook snapshot.
+
+    Tradernet sends rows as {"p": price, "q": quantity, "s": "S"|"B", "k": position}
+    under "ins" (snapshot) and "upd" (changes). Rows without a positive price
+    and quantity are dropped.
+
+    Returns:
+        {"bids": [(price, qty), ...] best (highest) first,
+         "asks": [(price, qty), ...] best (lowest) first}
+    """
+    bids: dict[float, float] = {}
+    asks: dict[float, float] = {}
+    for key in ("ins", "upd"):
+        for row in (raw or {}).get(key) or []:
+            try:
+                price = float(row["p"])
+                qty = float(row["q"])
+            except (KeyError, TypeError, ValueError):
+                continue
+            if price <= 0 or qty <= 0:
+                continue
+            side = asks if row.get("s") == "S" else bids
+            side[price] = qty
+    return {
+        "bids": sorted(bids.items(), key=lambda level: -level[0]),
+        "asks": sorted(asks.items(), key=lambda level: level[0]),
+    }
+
+
+def depth_limit_price(
+    action: str,
+    quantity: float,
+    book: dict[str, list[tuple[float, float]]],
+    max_slippage_pct: float = 2.0,
+) -> Optional[float]:
+    """Limit price that should fill `quantity` against the visible book.
+
+    Walks the opposite side (asks for a buy, bids for a sell) until the
+    cumulative quantity covers the order and prices at that level. The price
+    never moves more than max_slippage_pct past the best level; if the book is
+    too thin to cover the order within that band, the band edge is used and
+    the order may fill partially.
+
+    Returns:
+        Limit price, or None if the opposite side of the book is empty
+    """
+    buying = action.lower() == "buy"
+    levels = book.get("asks" if buying else "bids") or []
+    if not levels:
+        return None
+
+    best = levels[0][0]
+    cap = best * (1 + max_slippage_pct / 100) if buying else best * (1 - max_slippage_pct / 100)
+    price = cap
+    filled = 0.0
+    for level_price, level_qty in levels:
+        filled += level_qty
+        if filled >= quantity:
+            price = level_price
+            break
+
+    return min(price, cap) if buying else max(price, cap)
+
+
+def parse_pricing_strategies(raw) -> dict[str, dict]:
+    """Validate an order_pricing_strategies setting value.
+
+    Keys are a market suffix (US), a liquidity tier (thin, liquid), both (US:thin)
+    or * for everything; values are a strategy name or {"strategy": name, ...}
+    with buffer_pct, window_days (vwap) or tight_spread_pct (adaptive).
+
+    Returns:
+        key -> {"strategy": name, **parameters}
+
+    Raises:
+        ValueError: Malformed strategies
+    """
+    if raw in (None, ""):
+        return {}
+    if not isinstance(raw, dict):
+        raise ValueError("order_pricing_strategies must be an object keyed by market, tier or market:tier")
+    strategies = {}
+    for key, value in raw.items():
+        market, _, tier = str(key).partition(":")
+        if not market or (tier and tier not in LIQUIDITY_TIERS) or (market == "*" and tier):
+            raise ValueError(f"{key}: keys are a market, a tier ({', '.join(LIQUIDITY_TIERS)}), market:tier or *")
+        entry = {"strategy": value} if isinstance(value, str) else value
+        if not isinstance(entry, dict) or entry.get("strategy") not in PRICING_METHODS:
+            raise ValueError(f"{key}: strategy must be one of {', '.join(PRICING_METHODS)}")
+        unknown = set(entry) - {"strategy", "buffer_pct", "window_days", "tight_spread_pct"}
+        if unknown:
+            raise ValueError(f"{key}: unknown parameters {', '.join(sorted(unknown))}")
+        for name in ("buffer_pct", "tight_spread_pct"):
+            param = entry.get(name)
+            if param is not None and (isinstance(param, bool) or not isinstance(param, (int, float)) or param < 0):
+                raise ValueError(f"{key}: {name} must be a number >= 0")
+        window = entry.get("window_days")
+        if window is not None and (isinstance(window, bool) or not isinstance(window, int) or window < 1):
+            raise ValueError(f"{key}: window_days must be a whole number of days, at least 1")
+        strategies[str(key)] = dict(entry)
+    return strategies
+
+
+def pick_strategy(strategies: dict[str, dict], market: str, tier: Optional[str]) -> Optional[dict]:
+    """Most specific strategy for a market and liquidity tier: market:tier, market, tier, then *."""
+    keys = [f"{market}:{tier}", market, tier, "*"] if tier else [market, "*"]
+    for key in keys:
+        if key in strategies:
+            return strategies[key]
+    return None
+
+
+def needs_tier(strategies: dict[str, dict]) -> bool:
+    """Whether picking from the strategies depends on the liquidity tier."""
+    return any(key in LIQUIDITY_TIERS or ":" in key for key in strategies)
+
+
+def midpoint_price(bid: Optional[float], ask: Optional[float]) -> Optional[float]:
+    """Halfway between bid and ask, or None without a valid two-sided quote."""
+    if not bid or not ask or bid <= 0 or ask < bid:
+        return None
+    return round((bid + ask) / 2, 6)
+
+
+def buffered_price(action: str, reference: Optional[float], buffer_pct: float) -> Optional[float]:
+    """Reference price moved buffer_pct against us: up for a buy, down for a sell."""
+    if not reference or reference <= 0:
+        return None
+    factor = 1 + buffer_pct / 100 if action.lower() == "buy" else 1 - buffer_pct / 100
+    return round(reference * factor, 6)
+
+
+def vwap(bars: list[dict]) -> Optional[float]:
+    """Volume-weighted average of the typical price (high + low + close) / 3 of daily bars."""
+    weighted = 0.0
+    volume = 0.0
+    for bar in bars:
+        close, bar_volume = bar.get("close"), bar.get("volume")
+        if not close or not bar_volume or close <= 0 or bar_volume <= 0:
+            continue
+        typical = (float(bar.get("high") or close) + float(bar.get("low") or close) + float(close)) / 3
+        weighted += typical * float(bar_volume)
+        volume += float(bar_volume)
+    return weighted / volume if volume > 0 else None
+
+
+def adaptive_price(
+    action: str,
+    bid: Optional[float],
+    ask: Optional[float],
+    tight_spread_pct: float = DEFAULT_TIGHT_SPREAD_PCT,
+) -> Optional[float]:
+    """Limit price between the midpoint and the far side of the spread, by how wide the spread is.
+
+    Up to tight_spread_pct (of the midpoint) the order crosses the spread at the
+    ask (buy) or bid (sell). Wider spreads are crossed proportionally less, so a
+    spread twice the tight width is priced halfway between the midpoint and the
+    far side, and very wide spreads approach the midpoint.
+    """
+    mid = midpoint_price(bid, ask)
+    if mid is None:
+        return None
+    spread_pct = (ask - bid) / mid * 100
+    if spread_pct <= 0:
+        return mid
+    aggression = min(1.0, tight_spread_pct / spread_pct)
+    half_spread = (ask - bid) / 2
+    price = mid + aggression * half_spread if action.lower() == "buy" else mid - aggression * half_spread
+    return round(price, 6)
@@ -8,6 +8,26 @@ Usage:
     totals = await calculator.calculate_portfolio_values(positions)
 """
 
+# Quantities and costs closer than this are the same figure
+EPSILON = 1e-9
+
+
+def same_figure(a: float | None, b: float | None) -> bool:
+    """Whether two quantities or costs (None when unknown) are equal."""
+    if a is None or b is None:
+        return a is None and b is None
+    return abs(float(a) - float(b)) <= EPSILON
+
+
+def adjustment_overrides(adjustment: dict, quantity: float, avg_cost: float | None) -> bool:
+    """Whether a manual position adjustment still applies to what the broker reports (quantity 0 when nothing)."""
+    if not same_figure(adjustment["broker_quantity"], quantity):
+        return False
+    # A position the broker does not hold has no cost to compare
+    if not quantity or adjustment["broker_avg_cost"] is None:
+        return True
+    return same_figure(adjustment["broker_avg_cost"], avg_cost)
+
 
 class PositionCalculator:
     """Calculates position values and allocations."""
@@ -0,0 +1,81 @@
+"""
+Quantity helpers - Single source of truth for rounding order quantities.
+
+Whole-share securities trade in multiples of their min_lot. Securities flagged
+with supports_fractional trade in steps of FRACTIONAL_QUANTITY_STEP instead, so
+quantities stay floats end to end.
+
+Usage:
+    step = lot_step(sec["min_lot"], sec["supports_fractional"])
+    quantity = floor_to_lot(value / price, step)
+"""
+
+import math
+
+FRACTIONAL_QUANTITY_DECIMALS = 4
+FRACTIONAL_QUANTITY_STEP = 10**-FRACTIONAL_QUANTITY_DECIMALS
+
+# Tolerance for float division noise (e.g. 0.3 / 0.0001 = 2999.9999999999995)
+_EPSILON = 1e-9
+
+
+def lot_step(min_lot, supports_fractional=False) -> float:
+    """
+    Smallest tradable quantity increment for a security.
+
+    Args:
+        min_lot: Exchange lot size
+        supports_fractional: Whether the security accepts fractional orders
+
+    Returns:
+        FRACTIONAL_QUANTITY_STEP for fractional securities, otherwise the lot size (int)
+    """
+    if supports_fractional:
+        return FRACTIONAL_QUANTITY_STEP
+    return max(1, int(min_lot or 1))
+
+
+def _is_whole(step) -> bool:
+    return float(step) >= 1 and float(step).is_integer()
+
+
+def floor_to_lot(quantity: float, step) -> float:
+    """
+    Round a quantity down to a multiple of step.
+
+    Whole-lot steps return ints so existing integer sizing is unchanged.
+
+    Args:
+        quantity: Raw quantity
+        step: Lot step from lot_step()
+
+    Returns:
+        Largest multiple of step not above quantity (0 if quantity < step)
+    """
+    if quantity <= 0:
+        return 0
+    if _is_whole(step):
+        step = int(step)
+        return (int(quantity + _EPSILON) // step) * step
+    steps = math.floor(quantity / step + _EPSILON)
+    return round(steps * step, FRACTIONAL_QUANTITY_DECIMALS)
+
+
+def ceil_to_lot(quantity: float, step) -> float:
+    """
+    Round a quantity up to a multiple of step.
+
+    Args:
+        quantity: Raw quantity
+        step: Lot step from lot_step()
+
+    Returns:
+        Smallest multiple of step not below quantity
+    """
+    if quantity <= 0:
+        return 0
+    if _is_whole(step):
+        step = int(step)
+        return math.ceil(quantity / step - _EPSILON) * step
+    steps = math.ceil(quantity / step - _EPSILON)
+    return round(steps * step, FRACTIONAL_QUANTITY_DECIMALS)
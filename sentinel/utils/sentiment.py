@@ -0,0 +1,116 @@
+"""
+Headline Sentiment - lightweight local keyword classification of news headlines.
+
+No model or network call: headlines are tokenized and matched against small
+positive/negative lexicons. A negation word ("not", "no", ...) directly before
+a keyword flips it. Severe keywords (fraud, bankruptcy, ...) mark a headline
+as a risk on their own, whatever the balance of the other words.
+"""
+
+import re
+
+POSITIVE_WORDS = {
+    "beat": 1.0,
+    "beats": 1.0,
+    "record": 0.5,
+    "growth": 0.5,
+    "grows": 0.5,
+    "surge": 1.0,
+    "surges": 1.0,
+    "jumps": 0.75,
+    "rally": 0.75,
+    "rallies": 0.75,
+    "upgrade": 1.0,
+    "upgraded": 1.0,
+    "raises": 0.5,
+    "raised": 0.5,
+    "strong": 0.5,
+    "profit": 0.5,
+    "wins": 0.75,
+    "approval": 0.75,
+    "approved": 0.75,
+    "buyback": 0.75,
+    "dividend": 0.25,
+    "outperform": 0.75,
+}
+
+NEGATIVE_WORDS = {
+    "miss": 1.0,
+    "misses": 1.0,
+    "falls": 0.75,
+    "drops": 0.75,
+    "plunge": 1.0,
+    "plunges": 1.0,
+    "slump": 1.0,
+    "slumps": 1.0,
+    "downgrade": 1.0,
+    "downgraded": 1.0,
+    "cuts": 0.75,
+    "cut": 0.75,
+    "weak": 0.5,
+    "loss": 0.75,
+    "losses": 0.75,
+    "warning": 1.0,
+    "layoffs": 0.75,
+    "delay": 0.5,
+    "delayed": 0.5,
+    "probe": 1.0,
+    "lawsuit": 1.0,
+    "recall": 1.0,
+    "underperform": 0.75,
+}
+
+# Any of these makes the headline a risk by itself
+SEVERE_WORDS = {
+    "fraud",
+    "bankruptcy",
+    "insolvency",
+    "default",
+    "delisting",
+    "delisted",
+    "investigation",
+    "sanctions",
+    "scandal",
+    "restatement",
+}
+
+NEGATIONS = {"not", "no", "never", "without"}
+
+# Net score at or beyond which a headline is positive / negative
+LABEL_THRESHOLD = 0.25
+
+_TOKEN = re.compile(r"[a-z]+")
+
+
+def classify_headline(text: str) -> dict:
+    """Classify one headline.
+
+    Returns:
+        dict with score (-1..1), label ('positive', 'neutral', 'negative') and severe (bool)
+    """
+    tokens = _TOKEN.findall((text or "").lower())
+    positive = negative = 0.0
+    for i, token in enumerate(tokens):
+        negated = i > 0 and tokens[i - 1] in NEGATIONS
+        if token in POSITIVE_WORDS:
+            if negated:
+                negative += POSITIVE_WORDS[token]
+            else:
+                positive += POSITIVE_WORDS[token]
+        elif token in NEGATIVE_WORDS:
+            if negated:
+                positive += NEGATIVE_WORDS[token]
+            else:
+                negative += NEGATIVE_WORDS[token]
+    severe = any(token in SEVERE_WORDS for token in tokens)
+    total = positive + negative
+    score = (positive - negative) / total if total > 0 else 0.0
+    if severe:
+        score = -1.0
+    if score >= LABEL_THRESHOLD:
+        label = "positive"
+    elif score <= -LABEL_THRESHOLD:
+        label = "negative"
+    else:
+        label = "neutral"
+    return {"score": round(score, 3), "label": label, "severe": severe}
@@ -0,0 +1,38 @@
+"""
+systemd notify protocol helpers.
+
+Under a Type=notify unit systemd passes NOTIFY_SOCKET (and WATCHDOG_USEC when
+WatchdogSec is set). Messages are newline-separated KEY=VALUE pairs sent as a
+single datagram. Outside systemd both helpers are no-ops.
+
+Usage:
+    sd_notify("READY=1")
+    interval = watchdog_interval()  # seconds between WATCHDOG=1 pings, or None
+"""
+
+import os
+import socket
+from typing import Optional
+
+
+def sd_notify(message: str) -> bool:
+    """Send a notify message to systemd. Returns False when not running under a notify unit."""
+    address = os.environ.get("NOTIFY_SOCKET")
+    if not address:
+        return False
+    # A leading @ denotes a socket in the abstract namespace
+    if address.startswith("@"):
+        address = "\0" + address[1:]
+    with socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM) as sock:
+        sock.connect(address)
+        sock.sendall(message.encode())
+    return True
+
+
+def watchdog_interval() -> Optional[float]:
+    """Half the unit's watchdog timeout in seconds, or None without a watchdog."""
+    try:
+        usec = int(os.environ.get("WATCHDOG_USEC", "0"))
+    except ValueError:
+        return None
+    return usec / 2_000_000 if usec > 0 else None
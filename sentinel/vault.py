@@ -0,0 +1,248 @@
+"""Credentials vault - encrypted at-rest storage for broker and backup secrets.
+
+Secrets are encrypted with AES-GCM and kept in the `secrets` table. Each secret
+has its own random salt and nonce; the AES key is derived from a master key
+with scrypt, and the secret name is bound in as associated data so rows cannot
+be swapped. The master key comes from the environment:
+
+    SENTINEL_VAULT_PASSPHRASE  device passphrase (takes precedence)
+    SENTINEL_VAULT_KEYFILE     keyfile path (default: <data dir>/vault.key, created on first use)
+
+Clients read credentials through Vault.get_secret(), which falls back to the
+legacy plaintext settings row while a secret has not been migrated (or when the
+cryptography package is unavailable). migrate_settings() moves those rows into
+the vault and blanks them.
+"""
+
+from __future__ import annotations
+
+import hashlib
+import logging
+import os
+import secrets
+from dataclasses import dataclass
+from pathlib import Path
+
+from sentinel.database import Database
+from sentinel.paths import DATA_DIR
+from sentinel.settings import Settings
+
+logger = logging.getLogger(__name__)
+
+# Settings that hold credentials and are stored in the vault instead
+SECRET_NAMES = (
+    "tradernet_api_key",
+    "tradernet_api_secret",
+    "r2_access_key",
+    "r2_secret_key",
+    "config_peer_token",
+    "smtp_password",
+)
+
+KEY_LENGTH = 32
+SALT_LENGTH = 16
+NONCE_LENGTH = 12
+SCRYPT_N = 2**14
+SCRYPT_R = 8
+SCRYPT_P = 1
+
+
+class VaultError(Exception):
+    """The vault cannot be used (no cryptography package, no key, or wrong key)."""
+
+
+@dataclass
+class VaultKey:
+    """Master key material and where it came from."""
+
+    material: bytes
+    source: str  # 'passphrase' or 'keyfile'
+    path: str | None = None
+
+    @classmethod
+    def from_env(cls, env: dict[str, str] | None = None, create: bool = True) -> VaultKey:
+        """Load the master key from SENTINEL_VAULT_PASSPHRASE or the keyfile.
+
+        Raises:
+            VaultError: If there is no passphrase and the keyfile is missing (and not created)
+        """
+        env = os.environ if env is None else env
+        passphrase = env.get("SENTINEL_VAULT_PASSPHRASE")
+        if passphrase:
+            return cls(material=passphrase.encode(), source="passphrase")
+        path = Path(env.get("SENTINEL_VAULT_KEYFILE") or DATA_DIR / "vault.key")
+        if not path.exists():
+            if not create:
+                raise VaultError(f"Vault keyfile not found: {path}")
+            write_keyfile(path, secrets.token_bytes(KEY_LENGTH))
+            logger.info(f"Created vault keyfile {path}")
+        return cls(material=path.read_bytes(), source="keyfile", path=str(path))
+
+
+def write_keyfile(path: Path, material: bytes) -> None:
+    """Write a keyfile readable only by the owner, replacing any existing one atomically."""
+    path.parent.mkdir(parents=True, exist_ok=True)
+    tmp = path.with_name(path.name + ".new")
+    fd = os.open(tmp, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+    with os.fdopen(fd, "wb") as f:
+        f.write(material)
+    os.replace(tmp, path)
+
+
+def _aesgcm():
+    try:
+        from cryptography.hazmat.primitives.ciphers.aead import AESGCM
+    except ImportError as e:
+        raise VaultError("The cryptography package is required for the credentials vault") from e
+    return AESGCM
+
+
+def available() -> bool:
+    """Whether AES-GCM is available (the cryptography package is installed)."""
+    try:
+        _aesgcm()
+    except VaultError:
+        return False
+    return True
+
+
+class Vault:
+    """Encrypts, stores and rotates secrets."""
+
+    def __init__(self, db: Database | None = None, settings: Settings | None = None, key: VaultKey | None = None):
+        """Initialize vault with optional dependencies.
+
+        Args:
+            db: Database instance (uses singleton if None)
+            settings: Settings instance for the plaintext fallback (uses singleton if None)
+            key: Master key (loaded from the environment on first use if None)
+        """
+        self._db = db or Database()
+        self._settings = settings or Settings()
+        self._key = key
+        self._derived: dict[bytes, bytes] = {}
+
+    @property
+    def key(self) -> VaultKey:
+        """The master key, loaded on first use."""
+        if self._key is None:
+            self._key = VaultKey.from_env()
+        return self._key
+
+    def _derive(self, salt: bytes) -> bytes:
+        if salt not in self._derived:
+            self._derived[salt] = hashlib.scrypt(
+                self.key.material, salt=salt, n=SCRYPT_N, r=SCRYPT_R, p=SCRYPT_P, dklen=KEY_LENGTH
+            )
+        return self._derived[salt]
+
+    def _encrypt(self, name: str, value: str) -> tuple[bytes, bytes, bytes]:
+        aesgcm = _aesgcm()
+        salt, nonce = secrets.token_bytes(SALT_LENGTH), secrets.token_bytes(NONCE_LENGTH)
+        ciphertext = aesgcm(self._derive(salt)).encrypt(nonce, value.encode(), name.encode())
+        return salt, nonce, ciphertext
+
+    def _decrypt(self, name: str, record: dict) -> str:
+        aesgcm = _aesgcm()
+        from cryptography.exceptions import InvalidTag
+
+        try:
+            plaintext = aesgcm(self._derive(record["salt"])).decrypt(
+                record["nonce"], record["ciphertext"], name.encode()
+            )
+        except InvalidTag as e:
+            raise VaultError(f"Cannot decrypt secret '{name}': wrong vault key or corrupted data") from e
+        return plaintext.decode()
+
+    async def put(self, name: str, value: str) -> None:
+        """Store or rotate a secret.
+
+        Raises:
+            VaultError: If the vault is unavailable
+        """
+        salt, nonce, ciphertext = self._encrypt(name, value)
+        await self._db.put_secret(name, salt, nonce, ciphertext)
+
+    async def get(self, name: str) -> str | None:
+        """Decrypt a secret, or None if it is not stored.
+
+        Raises:
+            VaultError: If the secret is stored but cannot be decrypted
+        """
+        record = await self._db.get_secret(name)
+        if not isinstance(record, dict):
+            return None
+        return self._decrypt(name, record)
+
+    async def delete(self, name: str) -> bool:
+        """Remove a secret. Returns whether it existed."""
+        return await self._db.delete_secret(name)
+
+    async def get_secret(self, name: str, default: str = "") -> str:
+        """Read a credential: the vault first, then the legacy settings row."""
+        try:
+            value = await self.get(name)
+        except VaultError as e:
+            logger.error(str(e))
+            value = None
+        if value:
+            return value
+        return await self._settings.get(name, default) or default
+
+    async def status(self) -> list[dict]:
+        """Known secrets with where they are stored (never their values)."""
+        stored = {row["name"]: row for row in await self._db.get_secrets()}
+        result = []
+        for name in sorted(set(SECRET_NAMES) | set(stored)):
+            row = stored.get(name)
+            in_settings = name in SECRET_NAMES and bool(await self._db.get_setting(name))
+            result.append(
+                {
+                    "name": name,
+                    "stored": row is not None,
+                    "plaintext_setting": in_settings,
+                    "created_at": row["created_at"] if row else None,
+                    "rotated_at": row["rotated_at"] if row else None,
+                }
+            )
+        return result
+
+    async def migrate_settings(self) -> list[str]:
+        """Move plaintext credential settings into the vault and blank them.
+
+        Returns:
+            Names of the migrated secrets
+        """
+        migrated = []
+        for name in SECRET_NAMES:
+            value = await self._db.get_setting(name)
+            if not value:
+                continue
+            await self.put(name, str(value))
+            await self._db.set_setting(name, "")
+            migrated.append(name)
+        if migrated:
+            logger.info(f"Moved {len(migrated)} credential(s) from settings into the vault")
+        return migrated
+
+    async def rekey(self, new_key: VaultKey) -> int:
+        """Re-encrypt every secret under a new master key.
+
+        For a keyfile-backed vault the new key is written to the keyfile once
+        all secrets are re-encrypted (the new key is staged beside it first).
+
+        Returns:
+            Number of secrets re-encrypted
+        """
+        values = {row["name"]: await self.get(row["name"]) for row in await self._db.get_secrets()}
+        staged = None
+        if new_key.source == "keyfile" and new_key.path:
+            staged = Path(new_key.path).with_name(Path(new_key.path).name + ".pending")
+            write_keyfile(staged, new_key.material)
+        self._key, self._derived = new_key, {}
+        for name, value in values.items():
+            await self.put(name, value or "")
+        if staged is not None:
+            os.replace(staged, new_key.path)
+        logger.info(f"Vault re-keyed ({len(values)} secrets)")
+        return len(values)
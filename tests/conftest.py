@@ -0,0 +1,27 @@
+"""Shared test fixtures."""
+
+import os
+import tempfile
+
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.settings import Settings
+
+
+@pytest_asyncio.fixture
+async def temp_db():
+    """Fresh database in a temporary file, also used by the Settings singleton."""
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    previous, settings._db = settings._db, db
+    yield db
+    settings._db = previous
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        if os.path.exists(path + ext):
+            os.unlink(path + ext)
@@ -8,6 +8,7 @@ from unittest.mock import AsyncMock, MagicMock, patch
 
 import pytest
 
+from sentinel.dry_run import DryRunSession, _current
 from sentinel.jobs.tasks import (
     _create_archive,
     _prune_old_backups,
@@ -36,6 +37,27 @@ def test_create_archive():
             assert any("test.txt" in n for n in names)
 
 
+def test_create_archive_excludes_vault_key():
+    """The vault keyfile must never travel with the encrypted secrets it unlocks."""
+    with tempfile.TemporaryDirectory() as tmpdir:
+        data_dir = Path(tmpdir) / "data"
+        data_dir.mkdir()
+
+        (data_dir / "sentinel.db").write_text("db")
+        (data_dir / "vault.key").write_text("key")
+        (data_dir / "vault.key.pending").write_text("staged")
+
+        dest = os.path.join(tmpdir, "backup.tar.gz")
+
+        with patch("sentinel.jobs.tasks.DATA_DIR", data_dir):
+            _create_archive(dest)
+
+        with tarfile.open(dest, "r:gz") as tar:
+            names = tar.getnames()
+        assert "data/sentinel.db" in names
+        assert not any("vault.key" in n for n in names)
+
+
 def test_create_archive_missing_dir():
     """_create_archive should raise if data dir doesn't exist."""
     with tempfile.TemporaryDirectory() as tmpdir:
@@ -130,3 +152,33 @@ async def test_backup_full_flow():
         await backup_r2(mock_db)
 
         mock_upload.assert_called_once()
+
+
+@pytest.mark.asyncio
+async def test_backup_dry_run_records_instead_of_uploading():
+    """backup_r2 should neither upload nor prune inside a dry run."""
+    values = {
+        "r2_account_id": "test-account",
+        "r2_access_key": "test-key",
+        "r2_secret_key": "test-secret",
+        "r2_bucket_name": "test-bucket",
+        "r2_backup_retention_days": 30,
+    }
+    session = DryRunSession(db=None)
+    token = _current.set(session)
+
+    try:
+        with (
+            patch("sentinel.settings.Settings") as MockSettings,
+            patch("sentinel.jobs.tasks._get_r2_client") as mock_client,
+            patch("sentinel.jobs.tasks._create_archive") as mock_archive,
+        ):
+            MockSettings.return_value.get = AsyncMock(side_effect=lambda key, default="": values.get(key, default))
+
+            await backup_r2(AsyncMock())
+
+            mock_archive.assert_not_called()
+            mock_client.assert_not_called()
+    finally:
+        _current.reset(token)
+    assert [(e["kind"], e["bucket"]) for e in session.side_effects] == [("backup_upload", "test-bucket")]
@@ -0,0 +1,105 @@
+"""Tests for job failure classification and remediation."""
+
+import asyncio
+import sqlite3
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.jobs.failures import classify_failure, remediation_for
+
+
+@pytest.mark.parametrize(
+    "error,expected",
+    [
+        (asyncio.TimeoutError(), "timeout"),
+        (sqlite3.OperationalError("database is locked"), "db_locked"),
+        (RuntimeError("API returned 401 Unauthorized"), "broker_auth"),
+        (ConnectionResetError("peer reset"), "network"),
+        (RuntimeError("Read timed out"), "network"),
+        (ValueError("price must be positive"), "data_validation"),
+        (KeyError("close"), "data_validation"),
+        (RuntimeError("Test error"), "unknown"),
+    ],
+)
+def test_classify_failure(error, expected):
+    assert classify_failure(error) == expected
+
+
+def test_remediation_rules_prefer_job_overrides():
+    rules = {
+        "network": "retry",
+        "data_validation": "quarantine",
+        "trading:execute": {"network": "none"},
+        "sync:prices": {"network": "rerun_dependency"},
+    }
+    assert remediation_for("sync:quotes", "network", rules) == "retry"
+    assert remediation_for("trading:execute", "network", rules) == "none"
+    assert remediation_for("trading:execute", "data_validation", rules) == "quarantine"
+    # No dependency known for sync:prices, falls back to a plain retry
+    assert remediation_for("sync:prices", "network", rules) == "retry"
+    assert remediation_for("sync:quotes", "unknown", rules) == "none"
+    assert remediation_for("sync:quotes", "network", {"network": "reboot"}) == "none"
+
+
+def _runner_with(db, task, monkeypatch, **deps):
+    from sentinel.jobs import runner
+
+    monkeypatch.setattr(runner, "REMEDIATION_RETRY_DELAY", 0)
+    monkeypatch.setitem(runner.TASK_REGISTRY, "test:job", (task, []))
+    monkeypatch.setattr(runner, "_quarantined", set())
+    runner._deps = {"db": db, **deps}
+    return runner
+
+
+@pytest.mark.asyncio
+async def test_network_failure_is_logged_and_retried(monkeypatch):
+    db = AsyncMock()
+    db.get_setting = AsyncMock(return_value={"network": "retry"})
+    task = AsyncMock(side_effect=[ConnectionError("connection refused"), None])
+    runner = _runner_with(db, task, monkeypatch)
+
+    result = await runner._run_task("test:job", {"job_type": "test:job"}, skip_timing_check=True)
+
+    assert result["failure_class"] == "network"
+    assert result["remediation"] == {"action": "retry", "status": "completed"}
+    failed_call = db.log_job_execution.await_args_list[0]
+    assert failed_call.kwargs["failure_class"] == "network"
+    retried_call = db.log_job_execution.await_args_list[1]
+    assert retried_call.args[2] == "completed"
+    assert retried_call.args[5] == 1  # retry_count
+
+
+@pytest.mark.asyncio
+async def test_quarantine_skips_scheduled_runs_until_released(monkeypatch):
+    db = AsyncMock()
+    db.get_setting = AsyncMock(return_value={"data_validation": "quarantine"})
+    task = AsyncMock(side_effect=ValueError("bad payload"))
+    runner = _runner_with(db, task, monkeypatch)
+
+    result = await runner._run_task("test:job", {"job_type": "test:job"})
+    assert result["remediation"] == {"action": "quarantine"}
+    db.set_job_quarantine.assert_awaited_with("test:job", "data_validation: bad payload")
+
+    skipped = await runner._run_task("test:job", {"job_type": "test:job"})
+    assert skipped == {"skipped": True, "reason": "quarantined"}
+    assert task.await_count == 1
+
+    await runner.release_quarantine("test:job")
+    assert not runner.is_quarantined("test:job")
+
+
+@pytest.mark.asyncio
+async def test_auth_failure_reconnects_broker_before_retry(monkeypatch):
+    db = AsyncMock()
+    db.get_setting = AsyncMock(return_value=None)  # Falls back to default rules
+    broker = MagicMock()
+    broker.reconnect = AsyncMock(return_value=False)
+    task = AsyncMock(side_effect=RuntimeError("invalid api key"))
+    runner = _runner_with(db, task, monkeypatch, broker=broker)
+
+    result = await runner._run_task("test:job", {"job_type": "test:job"}, skip_timing_check=True)
+
+    assert result["remediation"] == {"action": "refresh_credentials", "status": "failed"}
+    broker.reconnect.assert_awaited_once()
+    assert task.await_count == 1
@@ -0,0 +1,114 @@
+"""Tests for parameterized manual job runs."""
+
+import asyncio
+import logging
+import os
+import tempfile
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.jobs import get_run, runner, start_run
+from sentinel.jobs.params import validate_job_params
+
+
+@pytest_asyncio.fixture
+async def db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+    yield db
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        if os.path.exists(path + ext):
+            os.unlink(path + ext)
+
+
+async def finished(run: dict) -> dict:
+    manual_run = get_run(run["run_id"])
+    while manual_run.status in ("queued", "running"):
+        await asyncio.sleep(0.01)
+    return manual_run.as_dict(logs_since=0)
+
+
+def test_validate_job_params():
+    assert validate_job_params("sync:prices", None) == {}
+    assert validate_job_params("sync:prices", {"symbols": " AAPL.US ", "years": 5.0}) == {
+        "symbols": ["AAPL.US"],
+        "years": 5,
+    }
+    assert validate_job_params("sync:dividends", {"start_date": "2024-01-31"}) == {"start_date": "2024-01-31"}
+
+    with pytest.raises(ValueError) as exc:
+        validate_job_params("sync:prices", {"symbols": [], "years": 50, "market": "US"})
+    message = str(exc.value)
+    assert "symbols must be a non-empty list of symbols" in message
+    assert "years must be at most 20" in message
+    assert "sync:prices does not accept market (accepted: symbols, years)" in message
+
+    with pytest.raises(ValueError, match="does not accept symbols"):
+        validate_job_params("backup:r2", {"symbols": ["AAPL.US"]})
+
+
+@pytest.mark.asyncio
+async def test_run_with_params_reports_progress_and_logs(caplog):
+    caplog.set_level(logging.INFO)
+    mock_db = AsyncMock()
+    mock_db.get_job_schedule = AsyncMock(return_value=None)
+    broker = AsyncMock()
+    broker.get_historical_prices_bulk = AsyncMock(return_value={"AAPL.US": [{"close": 1}], "MSFT.US": []})
+    cache = MagicMock()
+    cache.clear = MagicMock(return_value=0)
+    runner._deps = {"db": mock_db, "broker": broker, "cache": cache}
+
+    run = start_run("sync:prices", {"symbols": ["AAPL.US", "MSFT.US"], "years": 2})
+    assert run["status"] == "queued"
+    run = await finished(run)
+
+    broker.get_historical_prices_bulk.assert_awaited_once_with(["AAPL.US", "MSFT.US"], years=2)
+    assert run["status"] == "completed"
+    assert run["params"] == {"symbols": ["AAPL.US", "MSFT.US"], "years": 2}
+    assert run["progress"] == {"done": 2, "total": 2, "message": "MSFT.US"}
+    messages = [entry["message"] for entry in run["logs"]]
+    assert "Price sync complete: 1/2 securities updated" in messages
+    assert run["logs"][-1]["seq"] == len(run["logs"])
+    assert get_run(run["run_id"]).as_dict(logs_since=len(run["logs"]))["logs"] == []
+
+
+@pytest.mark.asyncio
+async def test_dry_run_discards_writes(db, monkeypatch):
+    async def write_setting(db, symbols=None):
+        await db.set_setting("led_brightness", 7)
+
+    monkeypatch.setitem(runner.TASK_REGISTRY, "sync:quotes", (write_setting, ["db"]))
+    runner._deps = {"db": db}
+
+    run = await finished(start_run("sync:quotes", {"symbols": ["AAPL.US"]}, dry_run=True))
+    assert run["status"] == "completed"
+    assert run["dry_run"] is True
+    assert run["result"]["dry_run"]["tables"]["settings"] == 1
+    assert await db.get_setting("led_brightness") is None
+
+
+@pytest.mark.asyncio
+async def test_run_endpoints():
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.jobs import get_run_endpoint, start_run_endpoint
+
+    with pytest.raises(HTTPException) as exc:
+        await start_run_endpoint("unknown:job", {})
+    assert exc.value.status_code == 404
+
+    with pytest.raises(HTTPException) as exc:
+        await start_run_endpoint("planning:refresh", {"params": {"min_trade_value": -5}})
+    assert exc.value.status_code == 400
+    assert exc.value.detail == "min_trade_value must be at least 0"
+
+    with pytest.raises(HTTPException) as exc:
+        await get_run_endpoint("missing")
+    assert exc.value.status_code == 404
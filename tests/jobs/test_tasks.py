@@ -4,6 +4,9 @@ from unittest.mock import AsyncMock, MagicMock, patch
 
 import pytest
 
+# Settings for live trading without manual approval
+LIVE = {"trading_mode": "live"}
+
 
 @pytest.fixture
 def mock_portfolio():
@@ -157,6 +160,23 @@ class TestSyncMetadata:
         assert mock_broker.get_security_info.await_count == 3
         assert mock_db.update_security_metadata.await_count == 3
 
+    @pytest.mark.asyncio
+    async def test_sync_metadata_classifies_unclassified_securities(self, mock_db, mock_broker):
+        """Verify securities without a GICS code get one from their industry."""
+        from sentinel.jobs.tasks import sync_metadata
+
+        mock_db.get_all_securities = AsyncMock(
+            return_value=[
+                {"symbol": "NVDA.US", "industry": "Semiconductors"},
+                {"symbol": "JPM.US", "industry": "Banks", "gics_code": "4010"},
+                {"symbol": "XYZ.US", "industry": None},
+            ]
+        )
+
+        await sync_metadata(mock_db, mock_broker)
+
+        mock_db.upsert_security.assert_awaited_once_with("NVDA.US", gics_code="4530")
+
 
 class TestSyncExchangeRates:
     """Tests for sync_exchange_rates task."""
@@ -235,7 +255,7 @@ class TestTradingExecute:
 
         with patch("sentinel.settings.Settings") as MockSettings:
             mock_settings = AsyncMock()
-            mock_settings.get = AsyncMock(return_value="live")
+            mock_settings.get = AsyncMock(side_effect=lambda key, default=None: LIVE.get(key, default))
             MockSettings.return_value = mock_settings
 
             with patch("sentinel.security.Security") as MockSecurity:
@@ -248,6 +268,112 @@ class TestTradingExecute:
 
                 mock_security.buy.assert_awaited()
 
+    @pytest.mark.asyncio
+    async def test_swap_buy_skipped_when_funding_sell_fails(self, mock_broker, mock_db, mock_planner):
+        """Verify a swap buy does not run without its funding sell."""
+        from sentinel.jobs.tasks import trading_execute
+
+        mock_broker.connected = True
+        sell = MagicMock(symbol="OLD.US", action="sell", quantity=5, price=50.0, currency="USD", priority=1)
+        sell.swap_group = "swap:OLD.US->AAPL.US"
+        buy = MagicMock(symbol="AAPL.US", action="buy", quantity=1, price=100.0, currency="USD", priority=1)
+        buy.swap_group = "swap:OLD.US->AAPL.US"
+        mock_planner.get_recommendations = AsyncMock(return_value=[sell, buy])
+        mock_db.get_all_securities = AsyncMock(
+            return_value=[
+                {"symbol": "AAPL.US", "data": '{"mrkt": {"mkt_id": 1}}'},
+                {"symbol": "OLD.US", "data": '{"mrkt": {"mkt_id": 1}}'},
+            ]
+        )
+        mock_broker.get_market_status = AsyncMock(return_value={"m": [{"i": 1, "n2": "NASDAQ", "s": "OPEN"}]})
+
+        with patch("sentinel.settings.Settings") as MockSettings:
+            mock_settings = AsyncMock()
+            mock_settings.get = AsyncMock(side_effect=lambda key, default=None: LIVE.get(key, default))
+            MockSettings.return_value = mock_settings
+
+            with patch("sentinel.security.Security") as MockSecurity:
+                mock_security = AsyncMock()
+                mock_security.sell = AsyncMock(return_value=None)
+                mock_security.buy = AsyncMock(return_value="order123")
+                MockSecurity.return_value = mock_security
+
+                await trading_execute(mock_broker, mock_db, mock_planner)
+
+                mock_security.sell.assert_awaited()
+                mock_security.buy.assert_not_awaited()
+
+    @pytest.mark.asyncio
+    async def test_swap_skipped_when_one_leg_is_being_sliced(self, mock_broker, mock_db, mock_planner):
+        """Verify a swap whose sell leg has a sliced order in progress does not run its buy alone."""
+        from sentinel.jobs.tasks import trading_execute
+
+        mock_broker.connected = True
+        sell = MagicMock(symbol="OLD.US", action="sell", quantity=5, price=50.0, currency="USD", priority=1)
+        sell.swap_group = "swap:OLD.US->AAPL.US"
+        buy = MagicMock(symbol="AAPL.US", action="buy", quantity=1, price=100.0, currency="USD", priority=1)
+        buy.swap_group = "swap:OLD.US->AAPL.US"
+        mock_planner.get_recommendations = AsyncMock(return_value=[sell, buy])
+        mock_db.get_all_securities = AsyncMock(
+            return_value=[
+                {"symbol": "AAPL.US", "data": '{"mrkt": {"mkt_id": 1}}'},
+                {"symbol": "OLD.US", "data": '{"mrkt": {"mkt_id": 1}}'},
+            ]
+        )
+        mock_broker.get_market_status = AsyncMock(return_value={"m": [{"i": 1, "n2": "NASDAQ", "s": "OPEN"}]})
+        values = {**LIVE, "order_slicing_enabled": True}
+
+        with (
+            patch("sentinel.settings.Settings") as MockSettings,
+            patch("sentinel.services.order_slicing.OrderSlicingService") as MockSlicer,
+            patch("sentinel.security.Security") as MockSecurity,
+        ):
+            mock_settings = AsyncMock()
+            mock_settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+            MockSettings.return_value = mock_settings
+            MockSlicer.return_value.active_symbols = AsyncMock(return_value={"OLD.US"})
+            mock_security = AsyncMock()
+            MockSecurity.return_value = mock_security
+
+            await trading_execute(mock_broker, mock_db, mock_planner)
+
+            mock_security.sell.assert_not_awaited()
+            mock_security.buy.assert_not_awaited()
+
+
+    @pytest.mark.asyncio
+    @pytest.mark.parametrize("approved", [False, True])
+    async def test_manual_approval_gates_execution(self, mock_broker, mock_db, mock_planner, approved):
+        """Verify only approved recommendations are placed in manual approval mode."""
+        from sentinel.jobs.tasks import trading_execute
+
+        mock_broker.connected = True
+        rec = MagicMock(symbol="AAPL.US", action="buy", quantity=1, price=100.0, currency="USD", priority=1)
+        rec.swap_group = None
+        mock_planner.get_recommendations = AsyncMock(return_value=[rec])
+        mock_db.get_all_securities = AsyncMock(return_value=[{"symbol": "AAPL.US", "data": '{"mrkt": {"mkt_id": 1}}'}])
+        mock_broker.get_market_status = AsyncMock(return_value={"m": [{"i": 1, "n2": "NASDAQ", "s": "OPEN"}]})
+        values = {**LIVE, "trading_manual_approval": True}
+
+        with (
+            patch("sentinel.settings.Settings") as MockSettings,
+            patch("sentinel.services.approvals.ApprovalService") as MockApprovals,
+            patch("sentinel.security.Security") as MockSecurity,
+        ):
+            MockSettings.return_value.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+            approvals = MockApprovals.return_value
+            approvals.approved = AsyncMock(return_value=[rec] if approved else [])
+            approvals.consume = AsyncMock()
+            mock_security = AsyncMock()
+            mock_security.buy = AsyncMock(return_value="order123")
+            MockSecurity.return_value = mock_security
+
+            await trading_execute(mock_broker, mock_db, mock_planner)
+
+            approvals.approved.assert_awaited_once_with([rec])
+            assert mock_security.buy.await_count == (1 if approved else 0)
+            assert approvals.consume.await_count == (1 if approved else 0)
+
 
 class TestTradingRebalance:
     """Tests for trading_rebalance task."""
@@ -0,0 +1,100 @@
+"""Tests for the allocation optimizer and its proposal workflow."""
+
+import math
+from datetime import date, timedelta
+
+import pytest
+
+from sentinel.services.allocation_optimizer import AllocationOptimizerService, parse_group_bounds
+from sentinel.strategy.optimizer import (
+    MeanVarianceOptimizer,
+    RiskParityOptimizer,
+    apply_turnover_budget,
+    make_optimizer,
+    project,
+)
+
+COV = {"A": {"A": 0.04, "B": 0.0}, "B": {"A": 0.0, "B": 0.16}}
+
+
+def test_risk_parity_balances_risk_within_bounds():
+    weights = RiskParityOptimizer().optimize({"A": 0.0, "B": 0.0}, COV, {})
+    # Volatility 20% vs 40%: inverse-volatility weights are equal-risk for uncorrelated groups
+    assert weights == {"A": pytest.approx(2 / 3), "B": pytest.approx(1 / 3)}
+    capped = RiskParityOptimizer().optimize({"A": 0.0, "B": 0.0}, COV, {"A": (0.0, 0.5)})
+    assert capped == {"A": pytest.approx(0.5), "B": pytest.approx(0.5)}
+
+
+def test_mean_variance_trades_score_against_risk():
+    weights = MeanVarianceOptimizer(risk_aversion=5).optimize({"A": 0.05, "B": 0.3}, COV, {})
+    assert weights == {"A": pytest.approx(0.55, abs=1e-6), "B": pytest.approx(0.45, abs=1e-6)}
+    # More risk aversion moves toward the calmer group
+    assert MeanVarianceOptimizer(risk_aversion=20).optimize({"A": 0.05, "B": 0.3}, COV, {})["A"] > 0.55
+
+    with pytest.raises(ValueError, match="cannot be met"):
+        MeanVarianceOptimizer().optimize({"A": 0.0, "B": 0.0}, COV, {"A": (0.0, 0.3), "B": (0.0, 0.3)})
+    with pytest.raises(ValueError, match="Unknown optimizer method"):
+        make_optimizer("black_litterman")
+
+
+def test_projection_and_turnover_budget():
+    projected = project({"A": 0.9, "B": 0.9, "C": -0.5}, {"A": (0.0, 0.6)})
+    assert sum(projected.values()) == pytest.approx(1.0)
+    assert projected["A"] <= 0.6 + 1e-9 and projected["C"] == 0.0
+
+    limited = apply_turnover_budget({"A": 0.8, "B": 0.2}, {"A": 0.2, "B": 0.8}, 0.3)
+    assert limited == {"A": pytest.approx(0.5), "B": pytest.approx(0.5)}
+    # Without active targets there is nothing to limit against
+    assert apply_turnover_budget({"A": 1.0}, {}, 0.1) == {"A": 1.0}
+
+
+def test_group_bounds_setting():
+    assert parse_group_bounds({"geography": {"US": [20, 60]}}) == {"geography": {"US": (0.2, 0.6)}}
+    for bad in ({"sector": {}}, {"geography": {"US": [70, 60]}}, {"geography": {"US": 50}}):
+        with pytest.raises(ValueError):
+            parse_group_bounds(bad)
+
+
+async def _save_series(db, symbol: str, amplitude: float) -> None:
+    start = date(2026, 1, 1)
+    closes = [100.0 * (1 + amplitude * math.sin(i * 1.3)) for i in range(80)]
+    await db.save_prices(
+        symbol, [{"date": (start + timedelta(days=i)).isoformat(), "close": c} for i, c in enumerate(closes)]
+    )
+
+
+@pytest.mark.asyncio
+async def test_proposals_need_acceptance_before_targets_change(temp_db):
+    for symbol, geography, amplitude in (("US1", "US", 0.01), ("US2", "US", 0.01), ("EU1", "Europe", 0.05)):
+        await temp_db.upsert_security(symbol, name=symbol, currency="EUR", active=1, geography=geography)
+        await _save_series(temp_db, symbol, amplitude)
+    await temp_db.set_allocation_target("geography", "Europe", 1.0)
+    await temp_db.set_allocation_target("geography", "US", 1.0)
+    await temp_db.set_setting("allocation_optimizer_max_weight_pct", 100)
+    service = AllocationOptimizerService(db=temp_db)
+
+    proposal = await service.propose("geography")
+    assert proposal["status"] == "proposed"
+    assert proposal["method"] == "risk_parity"
+    assert proposal["active"] == {"Europe": 0.5, "US": 0.5}
+    # The calmer US group gets more weight, but the move is capped at 25% turnover
+    assert proposal["weights"]["US"] > 0.5
+    assert proposal["stats"]["turnover"] <= 0.25 + 1e-6
+    assert proposal["stats"]["unconstrained_turnover"] > 0.25
+    assert {t["name"]: t["weight"] for t in await temp_db.get_allocation_targets("geography")} == {
+        "Europe": 1.0,
+        "US": 1.0,
+    }
+
+    # A newer proposal supersedes the pending one
+    latest = await service.propose("geography", "mean_variance")
+    assert (await temp_db.get_allocation_proposal(proposal["id"]))["status"] == "superseded"
+    assert await service.decide(proposal["id"], True) is None
+
+    accepted = await service.decide(latest["id"], True)
+    assert accepted["status"] == "accepted"
+    targets = {t["name"]: t["weight"] for t in await temp_db.get_allocation_targets("geography")}
+    assert targets == pytest.approx(latest["weights"])
+
+    with pytest.raises(ValueError, match="at least two industry groups"):
+        await service.propose("industry")
@@ -39,7 +39,7 @@ async def test_get_job_schedules_returns_all(db):
     """GET /api/jobs/schedules should return all schedules."""
     schedules = await db.get_job_schedules()
 
-    assert len(schedules) == 16
+    assert len(schedules) == 38
 
     # Check structure (no longer has enabled, dependencies, is_parameterized fields)
     schedule = schedules[0]
@@ -206,5 +206,5 @@ async def test_categories_present(db):
     schedules = await db.get_job_schedules()
     categories = set(s["category"] for s in schedules)
 
-    expected = {"sync", "trading", "backup"}
+    expected = {"sync", "trading", "backup", "notifications", "system"}
     assert categories == expected
@@ -60,6 +60,8 @@ def _make_unified_mocks(one_security=True):
     mock_deps.db.get_prices_bulk = AsyncMock(return_value={"AAPL": []})
     mock_deps.currency.to_eur = AsyncMock(return_value=0.0)
     mock_deps.settings.get = AsyncMock(return_value=None)
+    mock_deps.db.get_computed_columns = AsyncMock(return_value=[])
+    mock_deps.db.get_position_theses = AsyncMock(return_value={})
     return mock_deps
 
 
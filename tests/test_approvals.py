@@ -0,0 +1,99 @@
+"""Tests for the recommendation approval inbox."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.services.approvals import ApprovalService
+
+NOW = 1_760_000_000
+
+
+def _rec(symbol: str, action: str = "buy", quantity: float = 10, swap_group: str | None = None):
+    value = quantity * 50.0 * (1 if action == "buy" else -1)
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.1,
+        target_allocation=0.15,
+        allocation_delta=0.05,
+        current_value_eur=1000.0,
+        target_value_eur=1500.0,
+        value_delta_eur=value,
+        quantity=quantity,
+        price=50.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=1.0,
+        reason="Underweight",
+        swap_group=swap_group,
+    )
+
+
+def _service(db, recs):
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=recs)
+    portfolio = MagicMock()
+    portfolio.total_value = AsyncMock(return_value=10_000.0)
+    return ApprovalService(db=db, planner=planner, portfolio=portfolio, currency=MagicMock())
+
+
+@pytest.mark.asyncio
+async def test_inbox_lists_cost_and_allocation_impact(temp_db):
+    service = _service(temp_db, [_rec("AAA.EU"), _rec("BBB.EU", "sell", 4)])
+    await service.decide("BBB.EU", "sell", "defer", now=NOW)
+
+    inbox = await service.inbox(now=NOW)
+
+    assert inbox["manual_approval"] is False
+    assert inbox["pending"] == 1
+    first, second = inbox["items"]
+    assert first["symbol"] == "AAA.EU"
+    assert first["status"] == "pending"
+    assert first["value_eur"] == 500.0
+    # Default fees: 2 EUR + 0.2%
+    assert first["estimated_cost_eur"] == 3.0
+    assert first["current_allocation_pct"] == 10.0
+    assert first["projected_allocation_pct"] == 15.0
+    assert second["status"] == "deferred"
+    assert second["projected_allocation_pct"] == 8.0
+    assert second["expires_at"] == NOW + 24 * 3600
+
+
+@pytest.mark.asyncio
+async def test_only_approved_recommendations_are_cleared(temp_db):
+    recs = [_rec("AAA.EU"), _rec("BBB.EU"), _rec("CCC.EU")]
+    service = _service(temp_db, recs)
+    await service.decide("AAA.EU", "buy", "approve", now=NOW)
+    await service.decide("BBB.EU", "buy", "reject", now=NOW)
+
+    assert [r.symbol for r in await service.approved(recs, now=NOW)] == ["AAA.EU"]
+    # Approvals expire
+    assert await service.approved(recs, now=NOW + 25 * 3600) == []
+    # A larger quantity than approved needs a new approval
+    assert await service.approved([_rec("AAA.EU", quantity=12)], now=NOW) == []
+
+    await service.consume(recs[0])
+    assert await service.approved(recs, now=NOW) == []
+
+
+@pytest.mark.asyncio
+async def test_swap_needs_both_legs_approved(temp_db):
+    recs = [_rec("OLD.EU", "sell", swap_group="swap:OLD.EU->NEW.EU"), _rec("NEW.EU", swap_group="swap:OLD.EU->NEW.EU")]
+    service = _service(temp_db, recs)
+    await service.decide("NEW.EU", "buy", "approve", now=NOW)
+    assert await service.approved(recs, now=NOW) == []
+
+    await service.decide("OLD.EU", "sell", "approve", now=NOW)
+    assert len(await service.approved(recs, now=NOW)) == 2
+
+
+@pytest.mark.asyncio
+async def test_decide_validates_input(temp_db):
+    service = _service(temp_db, [_rec("AAA.EU")])
+    with pytest.raises(ValueError):
+        await service.decide("AAA.EU", "buy", "maybe")
+    with pytest.raises(LookupError):
+        await service.decide("AAA.EU", "sell", "approve")
@@ -0,0 +1,106 @@
+"""Tests for closed-position archival."""
+
+from datetime import datetime, timezone
+
+import pytest
+
+from sentinel.services.archive import ArchiveService, find_closed_round_trips
+
+NOW = int(datetime(2026, 6, 1, tzinfo=timezone.utc).timestamp())
+
+
+def _ts(iso_date: str) -> int:
+    return int(datetime.strptime(iso_date, "%Y-%m-%d").replace(tzinfo=timezone.utc).timestamp()) + 12 * 3600
+
+
+async def _trade(db, trade_id: str, symbol: str, side: str, qty: float, price: float, iso_date: str):
+    await db.upsert_trade(
+        broker_trade_id=trade_id,
+        symbol=symbol,
+        side=side,
+        quantity=qty,
+        price=price,
+        executed_at=_ts(iso_date),
+        raw_data={"order_id": f"ORD-{trade_id}"},
+    )
+
+
+def test_round_trips_split_on_flat_and_skip_open_tail():
+    trades = [
+        {"id": 1, "side": "BUY", "quantity": 10, "executed_at": 1},
+        {"id": 2, "side": "SELL", "quantity": 4, "executed_at": 2},
+        {"id": 3, "side": "SELL", "quantity": 6, "executed_at": 3},
+        {"id": 4, "side": "BUY", "quantity": 5, "executed_at": 4},
+    ]
+    assert [[t["id"] for t in rt] for rt in find_closed_round_trips(trades)] == [[1, 2, 3]]
+    assert find_closed_round_trips([{"id": 1, "side": "SELL", "quantity": 1, "executed_at": 1}]) == []
+
+
+@pytest.mark.asyncio
+async def test_archives_old_round_trips_with_decisions(temp_db):
+    await temp_db.upsert_security("OLD.EU", currency="EUR", name="Old Co")
+    await _trade(temp_db, "T1", "OLD.EU", "BUY", 10, 10.0, "2024-01-10")
+    await _trade(temp_db, "T2", "OLD.EU", "SELL", 10, 12.0, "2024-06-10")
+    await temp_db.record_trade_decision("OLD.EU", "buy", 10, "manual", order_id="ORD-T1", created_at=_ts("2024-01-09"))
+    # Closed too recently to archive
+    await _trade(temp_db, "T3", "NEW.EU", "BUY", 1, 50.0, "2026-03-01")
+    await _trade(temp_db, "T4", "NEW.EU", "SELL", 1, 55.0, "2026-04-01")
+
+    result = await ArchiveService(db=temp_db).archive_closed_positions(now=NOW)
+
+    assert result == {"archived": 1, "trades": 2, "decisions": 1, "symbols": ["OLD.EU"]}
+    assert [t["broker_trade_id"] for t in await temp_db.get_trades(limit=100)] == ["T4", "T3"]
+    assert await temp_db.get_trade_decisions() == []
+    # Historical readers still see the archived round trip
+    everything = await temp_db.get_trades(limit=100, include_archived=True)
+    assert [t["broker_trade_id"] for t in everything] == ["T4", "T3", "T2", "T1"]
+    assert everything[-1]["raw_data"] == {"order_id": "ORD-T1"}
+    old_trades = await temp_db.get_trades(end_date="2024-12-31", include_archived=True)
+    assert [t["broker_trade_id"] for t in old_trades] == ["T2", "T1"]
+    assert [d["order_id"] for d in await temp_db.get_trade_decisions(include_archived=True)] == ["ORD-T1"]
+
+    listing = await ArchiveService(db=temp_db).list_archived(symbol="OLD.EU")
+    assert listing["total"] == 1
+    archived = listing["positions"][0]
+    assert archived["realized_pnl_local"] == pytest.approx(20.0)
+    assert archived["data"]["name"] == "Old Co"
+
+    detail = await temp_db.get_archived_position(archived["id"])
+    assert [t["broker_trade_id"] for t in detail["trades"]] == ["T1", "T2"]
+    assert detail["trades"][0]["raw_data"] == {"order_id": "ORD-T1"}
+    assert detail["decisions"][0]["order_id"] == "ORD-T1"
+
+
+@pytest.mark.asyncio
+async def test_resync_does_not_resurrect_archived_trades(temp_db):
+    await _trade(temp_db, "T1", "OLD.EU", "BUY", 10, 10.0, "2024-01-10")
+    await _trade(temp_db, "T2", "OLD.EU", "SELL", 10, 12.0, "2024-06-10")
+    await ArchiveService(db=temp_db).archive_closed_positions(older_than_days=30, now=NOW)
+
+    row_id = await temp_db.upsert_trade(
+        broker_trade_id="T1",
+        symbol="OLD.EU",
+        side="BUY",
+        quantity=10,
+        price=10.0,
+        executed_at=_ts("2024-01-10"),
+        raw_data={},
+    )
+
+    assert row_id == 0
+    assert await temp_db.get_trades(limit=100) == []
+
+
+@pytest.mark.asyncio
+async def test_archive_endpoint_returns_404_for_unknown_id(temp_db):
+    from unittest.mock import MagicMock
+
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.archive import get_archived_position
+
+    deps = MagicMock()
+    deps.db = temp_db
+    with pytest.raises(HTTPException) as exc:
+        await get_archived_position(999, deps)
+    assert exc.value.status_code == 404
@@ -0,0 +1,212 @@
+"""Tests for portfolio return attribution (factors and decision sources)."""
+
+from datetime import datetime, timezone
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.rebalance_rules import calculate_priority, dominant_component, score_components
+from sentinel.services.attribution import AttributionService, resolve_period
+
+
+def _midnight_utc(iso_date: str) -> int:
+    return int(datetime.strptime(iso_date, "%Y-%m-%d").replace(tzinfo=timezone.utc).timestamp())
+
+
+def _currency(rates: dict[tuple[str, str], float] | None = None):
+    rates = rates or {}
+    currency = MagicMock()
+    currency.get_rate_for_date = AsyncMock(side_effect=lambda c, d: 1.0 if c == "EUR" else rates.get((c, d), 1.0))
+    return currency
+
+
+async def _seed(db):
+    await db.upsert_security("A.EU", currency="EUR", geography="Europe", active=1)
+    await db.upsert_security("B.US", currency="USD", geography="US", active=1)
+    await db.upsert_security("C.US", currency="USD", geography="US", active=1)
+    await db.save_prices("A.EU", [{"date": "2025-01-01", "close": 100.0}, {"date": "2025-12-31", "close": 110.0}])
+    await db.save_prices("B.US", [{"date": "2025-01-01", "close": 50.0}, {"date": "2025-12-31", "close": 60.0}])
+    await db.save_prices("C.US", [{"date": "2025-01-01", "close": 20.0}, {"date": "2025-12-31", "close": 20.0}])
+    await db.upsert_portfolio_snapshot(
+        _midnight_utc("2025-01-01"),
+        {"positions": {"A.EU": {"quantity": 6, "value_eur": 600.0}, "B.US": {"quantity": 8, "value_eur": 400.0}}},
+    )
+
+
+def test_resolve_period():
+    assert resolve_period("1M", "2025-03-31") == ("2025-03-01", "2025-03-31")
+    assert resolve_period("ytd", "2025-06-15") == ("2025-01-01", "2025-06-15")
+    with pytest.raises(ValueError):
+        resolve_period("2W", "2025-06-15")
+
+
+@pytest.mark.asyncio
+async def test_factor_effects_reconcile_with_excess_return(temp_db):
+    await _seed(temp_db)
+    await temp_db.set_allocation_target("geography", "Europe", 1.0)
+    await temp_db.set_allocation_target("geography", "US", 1.0)
+    currency = _currency({("USD", "2025-01-01"): 0.90, ("USD", "2026-01-01"): 0.99})
+
+    service = AttributionService(db=temp_db, currency=currency)
+    result = await service.get_attribution(period="1Y", end_date="2026-01-01")
+    factors = result["factors"]
+
+    # Local: 0.6 * 10% + 0.4 * 20% = 14%; USD gained 10% against EUR.
+    assert factors["local_return"] == pytest.approx(0.14)
+    assert factors["currency_effect"] == pytest.approx(0.4 * (1.2 * 1.1 - 1.0 - 0.2))
+    # Benchmark: 50% Europe (10%) + 50% US (equal-weighted B and C: 10%) = 10%
+    assert factors["benchmark_return"] == pytest.approx(0.10)
+    assert factors["allocation_effect"] + factors["selection_effect"] == pytest.approx(
+        factors["local_return"] - factors["benchmark_return"]
+    )
+    us = next(g for g in factors["groups"] if g["name"] == "US")
+    assert us["selection_effect"] == pytest.approx(0.4 * (0.20 - 0.10))
+
+
+@pytest.mark.asyncio
+async def test_factor_attribution_without_snapshot_is_empty(temp_db):
+    service = AttributionService(db=temp_db, currency=_currency())
+    result = await service.get_attribution(period="1Y", end_date="2026-01-01")
+    assert result["factors"]["groups"] == []
+    assert result["factors"]["portfolio_return"] == 0.0
+
+
+@pytest.mark.asyncio
+async def test_decision_sources_join_trades_by_order_id(temp_db):
+    await _seed(temp_db)
+    decision_id = await temp_db.record_trade_decision(
+        "B.US",
+        "buy",
+        5,
+        "trading:execute",
+        order_id="ORD-1",
+        price=50.0,
+        reason_code="entry_t1",
+        created_at=_midnight_utc("2025-06-01") - 60,
+    )
+    assert decision_id > 0
+
+    await temp_db.upsert_trade(
+        broker_trade_id="T1",
+        symbol="B.US",
+        side="BUY",
+        quantity=5,
+        price=50.0,
+        executed_at=_midnight_utc("2025-06-01"),
+        raw_data={"order_id": "ORD-1"},
+    )
+    await temp_db.upsert_trade(
+        broker_trade_id="T2",
+        symbol="A.EU",
+        side="SELL",
+        quantity=1,
+        price=120.0,
+        executed_at=_midnight_utc("2025-06-02"),
+        raw_data={},
+    )
+
+    service = AttributionService(db=temp_db, currency=_currency())
+    result = await service.get_attribution(period="1Y", end_date="2026-01-01")
+    decisions = result["decision_sources"]
+
+    assert decisions["matched_trades"] == 1
+    assert decisions["unmatched_trades"] == 1
+    by_key = {(s["source"], s["reason_code"]): s for s in decisions["sources"]}
+    assert by_key[("trading:execute", "entry_t1")]["value_add_eur"] == pytest.approx(50.0)
+    # Sold at 120, period ends at 110: 10 EUR loss avoided
+    assert by_key[("unattributed", None)]["value_add_eur"] == pytest.approx(10.0)
+
+
+def test_score_components_split_priority():
+    signal = {"dip_score": 1.0, "capitulation_score": 0.0, "cycle_turn": 1, "opp_score": 0.7, "sleeve": "opportunity"}
+    components = score_components(action="buy", allocation_delta=0.01, contrarian_score=0.7, signal=signal)
+
+    assert components == {
+        "opportunity": pytest.approx(0.5),
+        "quality": pytest.approx(0.0),
+        "risk_adjusted": 0.0,
+        "diversification": pytest.approx(0.1),
+        "regime": pytest.approx(0.2),
+    }
+    assert sum(components.values()) == pytest.approx(calculate_priority("buy", 0.01, 0.7))
+    assert dominant_component(components) == "opportunity"
+
+    # A core holding sold for weak risk-adjusted momentum
+    sell = score_components(
+        action="sell", allocation_delta=-0.005, contrarian_score=0.0, signal={"core_rank": -0.2, "sleeve": "core"}
+    )
+    assert dominant_component(sell) == "risk_adjusted"
+    assert dominant_component({"opportunity": 0.0, "regime": -0.1}) is None
+
+
+@pytest.mark.asyncio
+async def test_realized_performance_by_dominant_component(temp_db):
+    await _seed(temp_db)
+    for order_id, symbol, component in (("ORD-1", "B.US", "opportunity"), ("ORD-2", "C.US", "diversification")):
+        await temp_db.record_trade_decision(
+            symbol,
+            "buy",
+            5,
+            "trading:execute",
+            order_id=order_id,
+            created_at=_midnight_utc("2025-06-01") - 60,
+            dominant_component=component,
+            score_components={component: 0.5},
+        )
+    await temp_db.upsert_trade(
+        broker_trade_id="T1",
+        symbol="B.US",
+        side="BUY",
+        quantity=5,
+        price=50.0,
+        executed_at=_midnight_utc("2025-06-01"),
+        raw_data={"order_id": "ORD-1"},
+    )
+    await temp_db.upsert_trade(
+        broker_trade_id="T2",
+        symbol="C.US",
+        side="BUY",
+        quantity=5,
+        price=25.0,
+        executed_at=_midnight_utc("2025-06-01"),
+        raw_data={"order_id": "ORD-2"},
+    )
+    await temp_db.upsert_trade(
+        broker_trade_id="T3",
+        symbol="A.EU",
+        side="BUY",
+        quantity=1,
+        price=100.0,
+        executed_at=_midnight_utc("2025-06-02"),
+        raw_data={},
+    )
+
+    service = AttributionService(db=temp_db, currency=_currency())
+    result = await service.get_attribution(period="1Y", end_date="2026-01-01")
+    components = {c["component"]: c for c in result["decision_sources"]["components"]}
+
+    # B.US bought at 50, period ends at 60
+    assert components["opportunity"]["value_add_eur"] == pytest.approx(50.0)
+    assert components["opportunity"]["return_pct"] == pytest.approx(20.0)
+    assert components["opportunity"]["hit_rate"] == 1.0
+    # C.US bought at 25, period ends at 20
+    assert components["diversification"]["value_add_eur"] == pytest.approx(-25.0)
+    assert components["diversification"]["hit_rate"] == 0.0
+    assert components["unscored"]["trades"] == 1
+    decisions = await temp_db.get_trade_decisions()
+    assert decisions[0]["score_components"] == '{"opportunity": 0.5}'
+
+
+@pytest.mark.asyncio
+async def test_attribution_endpoint_rejects_unknown_period(temp_db):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.portfolio import get_portfolio_attribution
+
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.currency = _currency()
+
+    with pytest.raises(HTTPException) as exc:
+        await get_portfolio_attribution(deps, period="2W")
+    assert exc.value.status_code == 400
@@ -0,0 +1,79 @@
+"""Tests for the audit log of trading actions."""
+
+import sqlite3
+
+import pytest
+
+from sentinel import correlation
+from sentinel.api.auth import client_type
+from sentinel.audit import acting_as, record_action
+from sentinel.broker import Broker
+from sentinel.dry_run import dry_run
+from sentinel.settings import Settings
+
+
+@pytest.mark.asyncio
+async def test_entries_record_actor_and_filter(temp_db):
+    await record_action(temp_db, "circuit_breaker.resume")
+    with acting_as("api", "ops-token"), correlation.bind("req-9"):
+        await record_action(temp_db, "order.buy", "AAPL.US", {"quantity": 3}, "simulated")
+    with acting_as("job", "trading:execute"):
+        await record_action(temp_db, "order.sell", "MSFT.US", {"quantity": 1}, "failed", "Broker not connected")
+        await record_action(temp_db, "order_book.refresh", "MSFT.US")
+
+    entries = await temp_db.get_audit_log()
+    assert [(e["actor_type"], e["actor"], e["action"]) for e in entries][::-1] == [
+        ("system", "sentinel", "circuit_breaker.resume"),
+        ("api", "ops-token", "order.buy"),
+        ("job", "trading:execute", "order.sell"),
+        ("job", "trading:execute", "order_book.refresh"),
+    ]
+    [buy] = await temp_db.get_audit_log(actor="ops-token")
+    assert (buy["params"], buy["outcome"], buy["correlation_id"]) == ({"quantity": 3}, "simulated", "req-9")
+    assert [e["action"] for e in await temp_db.get_audit_log(action="order")] == ["order.sell", "order.buy"]
+    assert [e["target"] for e in await temp_db.get_audit_log(outcome="failed")] == ["MSFT.US"]
+
+
+@pytest.mark.asyncio
+async def test_log_is_append_only_and_skips_dry_runs(temp_db):
+    await record_action(temp_db, "job.release", "sync:prices")
+    with pytest.raises(sqlite3.IntegrityError, match="append-only"):
+        await temp_db.conn.execute("UPDATE audit_log SET actor = 'someone else'")
+    with pytest.raises(sqlite3.IntegrityError, match="append-only"):
+        await temp_db.conn.execute("DELETE FROM audit_log")
+    await temp_db.conn.rollback()
+
+    async with dry_run(temp_db):
+        await record_action(temp_db, "job.release", "sync:trades")
+    assert [e["target"] for e in await temp_db.get_audit_log()] == ["sync:prices"]
+
+
+@pytest.mark.asyncio
+async def test_orders_and_settings_changes_are_audited(temp_db):
+    broker = Broker()
+    previous_db, broker._db = broker._db, temp_db
+    try:
+        await temp_db.set_setting("trading_mode", "research")
+        with acting_as("tui", "alice"):
+            order_id = await broker.sell("AAPL.US", 2, price=190.0)
+            await Settings().set("max_positions", 12)
+            await Settings().set("smtp_password", "hunter2")
+            await Settings().set("exchange_rates", {"EUR": 1.0, "USD": 0.9})
+            await Settings().set("circuit_breaker_state", {"tripped": False})
+    finally:
+        broker._db = previous_db
+
+    entries = {e["action"] + ":" + e["target"]: e for e in await temp_db.get_audit_log(actor="alice")}
+    order = entries["order.sell:AAPL.US"]
+    assert order["outcome"] == "simulated"
+    assert order["params"] == {"quantity": 2, "price": 190.0, "order_id": order_id}
+    assert entries["settings.change:max_positions"]["params"] == {"value": 12, "reload": False}
+    assert entries["settings.change:smtp_password"]["params"]["value"] == "***"
+    # Machine-maintained settings are not audited
+    assert "settings.change:exchange_rates" not in entries
+    assert "settings.change:circuit_breaker_state" not in entries
+
+
+def test_client_type_of_requests():
+    assert client_type({"headers": [(b"x-sentinel-client", b"TUI")]}) == "tui"
+    assert client_type({"headers": [(b"authorization", b"Bearer abc")]}) == "api"
@@ -0,0 +1,131 @@
+"""Tests for API authentication and role-based access."""
+
+import json
+
+import pytest
+
+from sentinel.api.auth import AuthMiddleware, required_role
+from sentinel.services.auth import AuthService, hash_password, role_allows, verify_password
+from sentinel.settings import Settings
+
+
+async def _call(db, method: str, path: str, token: str | None = None) -> tuple[int, dict, bool]:
+    """Send one request through the middleware; returns (status, body, reached_handler)."""
+    reached = []
+
+    async def app(scope, receive, send):
+        reached.append(scope.get("state", {}).get("principal"))
+        await send({"type": "http.response.start", "status": 200, "headers": []})
+        await send({"type": "http.response.body", "body": b"{}"})
+
+    sent = []
+
+    async def receive():
+        return {"type": "http.request", "body": b"", "more_body": False}
+
+    async def send(message):
+        sent.append(message)
+
+    headers = [(b"authorization", f"Bearer {token}".encode())] if token else []
+    scope = {"type": "http", "method": method, "path": path, "query_string": b"", "headers": headers}
+    await AuthMiddleware(app, db_factory=lambda: db)(scope, receive, send)
+    body = b"".join(m.get("body", b"") for m in sent[1:])
+    return sent[0]["status"], json.loads(body or b"{}"), bool(reached)
+
+
+def test_roles_and_route_requirements():
+    assert role_allows("admin", "operator")
+    assert not role_allows("viewer", "operator")
+    assert not role_allows("root", "viewer")
+    assert required_role("GET", "/api/portfolio") == "viewer"
+    assert required_role("POST", "/api/securities/AAPL.US/buy") == "operator"
+    assert required_role("PUT", "/api/settings/trading_mode") == "admin"
+    assert required_role("GET", "/api/auth/tokens") == "admin"
+    assert required_role("GET", "/api/debug/pprof/profiles/cpu-1.pstats") == "admin"
+    assert required_role("GET", "/api/logs/stream") == "operator"
+
+
+def test_password_hashing():
+    stored = hash_password("hunter2")
+    assert verify_password("hunter2", stored)
+    assert not verify_password("hunter3", stored)
+    assert not verify_password("hunter2", "garbage")
+
+
+@pytest.mark.asyncio
+async def test_middleware_is_open_until_enabled(temp_db):
+    status, _, reached = await _call(temp_db, "POST", "/api/securities/AAPL.US/buy")
+    assert status == 200 and reached
+
+
+@pytest.mark.asyncio
+async def test_roles_gate_endpoints_and_mutations_are_audited(temp_db):
+    auth = AuthService(db=temp_db)
+    viewer = await auth.create_token("kiosk", "viewer")
+    operator = await auth.create_token("ops", "operator")
+    await temp_db.set_setting("auth_enabled", True)
+
+    assert (await _call(temp_db, "GET", "/api/portfolio"))[0] == 401
+    assert (await _call(temp_db, "GET", "/api/health"))[0] == 200
+    assert (await _call(temp_db, "GET", "/api/portfolio", viewer["token"]))[0] == 200
+    status, body, reached = await _call(temp_db, "POST", "/api/securities/AAPL.US/buy", viewer["token"])
+    assert status == 403 and not reached
+    assert "requires operator" in body["detail"]
+    assert (await _call(temp_db, "POST", "/api/securities/AAPL.US/buy", operator["token"]))[0] == 200
+    assert (await _call(temp_db, "PUT", "/api/settings/trading_mode", operator["token"]))[0] == 403
+
+    audit = await temp_db.get_auth_audit()
+    assert [(e["principal"], e["status_code"]) for e in audit] == [
+        ("ops", 403),
+        ("ops", 200),
+        ("kiosk", 403),
+        (None, 401),
+    ]
+
+    await temp_db.revoke_api_token(operator["id"])
+    assert (await _call(temp_db, "GET", "/api/portfolio", operator["token"]))[0] == 401
+
+
+@pytest.mark.asyncio
+async def test_login_issues_session_for_local_user(temp_db):
+    auth = AuthService(db=temp_db)
+    await auth.set_user("alice", "s3cret", "admin")
+
+    assert await auth.login("alice", "wrong") is None
+    session = await auth.login("alice", "s3cret")
+    principal = await auth.authenticate(session["token"])
+    assert principal["name"] == "alice"
+    assert principal["role"] == "admin"
+    assert await temp_db.has_admin_credentials()
+
+    await temp_db.delete_auth_user("alice")
+    assert await auth.authenticate(session["token"]) is None
+    assert not await temp_db.has_admin_credentials()
+
+
+@pytest.mark.asyncio
+async def test_enabling_auth_requires_admin_credentials(temp_db):
+    from unittest.mock import MagicMock
+
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.settings import set_setting
+
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.settings = Settings()
+    with pytest.raises(HTTPException) as exc:
+        await set_setting("auth_enabled", {"value": True}, deps)
+    assert exc.value.status_code == 400
+
+    await AuthService(db=temp_db).create_token("root", "admin")
+    await set_setting("auth_enabled", {"value": True}, deps)
+    assert await AuthService(db=temp_db).enabled()
+
+
+@pytest.mark.asyncio
+async def test_public_dashboard_reads_need_no_token(temp_db):
+    await temp_db.set_setting("auth_enabled", True)
+    assert (await _call(temp_db, "GET", "/api/public/summary"))[0] == 200
+    assert (await _call(temp_db, "POST", "/api/public/summary"))[0] == 401
+    assert (await _call(temp_db, "GET", "/api/publicity"))[0] == 401
@@ -0,0 +1,138 @@
+"""Tests for the auto-invest policy on new deposits."""
+
+import json
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.services.auto_invest import AutoInvestService, fit_buys, is_recurring
+
+TODAY = date(2026, 11, 5)
+
+
+def _rec(symbol: str, quantity: float, priority: float, action: str = "buy", swap_group: str | None = None):
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.0,
+        target_allocation=0.05,
+        allocation_delta=0.05,
+        current_value_eur=0.0,
+        target_value_eur=quantity * 50.0,
+        value_delta_eur=quantity * 50.0 * (1 if action == "buy" else -1),
+        quantity=quantity,
+        price=50.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=priority,
+        reason="Underweight",
+        swap_group=swap_group,
+    )
+
+
+async def _deposit(db, day: str, amount: float) -> dict:
+    row_id = await db.upsert_cash_flow(day, "card", amount, "EUR", None, {"date": day, "amount": amount})
+    return {"id": row_id, "date": day, "type_id": "card", "amount": amount, "currency": "EUR"}
+
+
+def _service(db, cash: float = 5000.0):
+    currency = MagicMock()
+    currency.to_eur_for_date = AsyncMock(side_effect=lambda amount, ccy, day: amount)
+    portfolio = MagicMock()
+    portfolio.total_cash_eur = AsyncMock(return_value=cash)
+    return AutoInvestService(db=db, portfolio=portfolio, currency=currency)
+
+
+def _planner(recs):
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=recs)
+    return planner
+
+
+def _broker(open_market: bool = True):
+    broker = AsyncMock()
+    broker.get_market_status = AsyncMock(return_value={"m": [{"i": 7, "s": "OPEN" if open_market else "CLOSE"}]})
+    return broker
+
+
+def test_fit_buys_scales_the_last_buy_to_the_budget():
+    recs = [_rec("LOW.EU", 4, 1.0), _rec("TOP.EU", 10, 3.0), _rec("SWAP.EU", 2, 5.0, swap_group="s1")]
+    chosen = fit_buys(recs, budget=700, min_order=100)
+    assert [(r.symbol, r.quantity, r.value_delta_eur) for r in chosen] == [("TOP.EU", 10, 500.0), ("LOW.EU", 4, 200.0)]
+
+    chosen = fit_buys([_rec("TOP.EU", 10, 3.0), _rec("LOW.EU", 4, 1.0)], budget=560, min_order=100)
+    # 60 EUR left after the first buy is below the minimum order
+    assert [(r.symbol, r.quantity) for r in chosen] == [("TOP.EU", 10)]
+    assert [r.quantity for r in fit_buys([_rec("TOP.EU", 10, 3.0)], budget=330, min_order=100)] == [6]
+
+
+def test_recurring_deposits():
+    assert is_recurring(500, [480, 520, 500])
+    assert not is_recurring(2000, [480, 520, 500])
+    assert not is_recurring(500, [500])
+
+
+@pytest.mark.asyncio
+async def test_policy_off_and_old_deposits_are_ignored(temp_db):
+    service = _service(temp_db)
+    planner = _planner([_rec("TOP.EU", 10, 3.0)])
+    deposit = await _deposit(temp_db, "2026-11-04", 500)
+    assert await service.handle([deposit], planner, _broker(), AsyncMock(), today=TODAY) is None
+
+    await temp_db.set_setting("auto_invest_mode", "plan")
+    old = await _deposit(temp_db, "2026-09-01", 500)
+    assert await service.handle([old], planner, _broker(), AsyncMock(), today=TODAY) is None
+    planner.get_recommendations.assert_not_awaited()
+
+
+@pytest.mark.asyncio
+async def test_plan_mode_records_buys_within_the_deposit(temp_db):
+    await temp_db.set_setting("auto_invest_mode", "plan")
+    await temp_db.set_setting("auto_invest_cash_buffer_eur", 100)
+    service = _service(temp_db, cash=600)
+    planner = _planner([_rec("TOP.EU", 10, 3.0), _rec("LOW.EU", 4, 1.0)])
+    deposit = await _deposit(temp_db, "2026-11-04", 1000)
+    place = AsyncMock(return_value=True)
+
+    run = await service.handle([deposit], planner, _broker(), place, today=TODAY)
+
+    # Cash minus the buffer caps the budget below the deposit
+    assert run["budget_eur"] == 500
+    assert run["status"] == "planned"
+    assert run["orders"] == [{"symbol": "TOP.EU", "quantity": 10, "value_eur": 500.0, "status": "planned"}]
+    planner.get_recommendations.assert_awaited_once_with(min_trade_value=100.0)
+    place.assert_not_awaited()
+    assert (await service.runs())[0]["deposit_ids"] == [deposit["id"]]
+
+
+@pytest.mark.asyncio
+async def test_execute_mode_places_buys_with_open_markets(temp_db):
+    await temp_db.set_setting("auto_invest_mode", "execute")
+    await temp_db.set_setting("auto_invest_deposits", "recurring")
+    await temp_db.set_setting("trading_mode", "live")
+    for symbol in ("TOP.EU", "LOW.EU"):
+        await temp_db.upsert_security(symbol, name=symbol, currency="EUR", data=json.dumps({"mrkt": {"mkt_id": 7}}))
+    service = _service(temp_db)
+    planner = _planner([_rec("TOP.EU", 10, 3.0), _rec("LOW.EU", 4, 1.0)])
+    place = AsyncMock(return_value=True)
+
+    # A one-off deposit far from the usual amount does not trigger in recurring scope
+    for day in ("2026-08-04", "2026-09-04", "2026-10-04"):
+        await _deposit(temp_db, day, 700)
+    windfall = await _deposit(temp_db, "2026-11-03", 5000)
+    assert await service.handle([windfall], planner, _broker(), place, today=TODAY) is None
+
+    monthly = await _deposit(temp_db, "2026-11-04", 700)
+    run = await service.handle([monthly], planner, _broker(), place, today=TODAY)
+    assert run["status"] == "executed"
+    assert [(o["symbol"], o["status"]) for o in run["orders"]] == [("TOP.EU", "placed"), ("LOW.EU", "placed")]
+    assert [call.args[1] for call in place.await_args_list] == ["auto_invest", "auto_invest"]
+
+    place.reset_mock()
+    later = await _deposit(temp_db, "2026-11-05", 700)
+    run = await service.handle([later], planner, _broker(False), place, today=TODAY)
+    assert {o["status"] for o in run["orders"]} == {"market closed"}
+    place.assert_not_awaited()
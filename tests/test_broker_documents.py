@@ -0,0 +1,118 @@
+"""Tests for broker document retrieval and corporate action elections."""
+
+import base64
+from datetime import datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.broker import Broker
+from sentinel.services.broker_documents import BrokerDocumentService, safe_file_name
+
+
+async def _ledger(db) -> None:
+    executed = int(datetime(2026, 3, 10, 12, 0).timestamp())
+    await db.upsert_trade("T1", "AAA.EU", "BUY", 5, 10.0, executed, {"order_id": 777})
+    await db.upsert_trade("T2", "AAA.EU", "BUY", 5, 10.0, executed, {"order_id": 778})
+    await db.upsert_cash_flow("2026-03-02", "card", 1000.0, "EUR", None, {"id": 1})
+    await db.upsert_cash_flow("2026-04-01", "card", 500.0, "EUR", None, {"id": 2})
+    await db.upsert_dividend("CA-1", "AAA.EU", "2026-03-20", 3.5, "EUR", 3.5, {})
+
+
+def test_safe_file_name():
+    assert safe_file_name("../../etc/passwd") == "passwd"
+    assert safe_file_name("order confirmation (1).pdf") == "order_confirmation_1_.pdf"
+    assert safe_file_name("..") == "document"
+
+
+@pytest.mark.asyncio
+async def test_order_files_are_stored_and_linked_to_the_order_trades(temp_db, tmp_path):
+    await _ledger(temp_db)
+    broker = MagicMock()
+    broker.get_order_files = AsyncMock(
+        return_value=[{"file_name": "../confirm.pdf", "mime": "application/pdf", "content": b"%PDF-1.5"}]
+    )
+    service = BrokerDocumentService(db=temp_db, broker=broker, root=tmp_path)
+
+    [stored] = await service.fetch_order_files("777")
+
+    assert stored["path"] == "orders/777/confirm.pdf"
+    assert (tmp_path / "orders/777/confirm.pdf").read_bytes() == b"%PDF-1.5"
+    document = await service.get_document(stored["id"])
+    assert document["links"] == [{"entity_type": "trade", "entity_id": "T1"}]
+    assert [d["id"] for d in await service.list_documents(entity_type="trade", entity_id="T1")] == [stored["id"]]
+    assert await service.list_documents(entity_type="trade", entity_id="T2") == []
+
+    with pytest.raises(ValueError):
+        await service.fetch_order_files("../1")
+    broker.get_order_files = AsyncMock(return_value=[])
+    with pytest.raises(LookupError):
+        await service.fetch_order_files("999")
+
+
+@pytest.mark.asyncio
+async def test_broker_report_links_period_ledger_entries(temp_db, tmp_path):
+    await _ledger(temp_db)
+    broker = MagicMock()
+    broker.get_broker_report_file = AsyncMock(return_value=b"%PDF-report")
+    service = BrokerDocumentService(db=temp_db, broker=broker, root=tmp_path)
+
+    report = await service.fetch_broker_report("2026-03-01", "2026-03-31")
+
+    broker.get_broker_report_file.assert_awaited_once_with("2026-03-01", "2026-03-31", "pdf")
+    assert report["links"] == 4
+    links = (await service.get_document(report["id"]))["links"]
+    assert {(link["entity_type"], link["entity_id"]) for link in links} == {
+        ("trade", "T1"),
+        ("trade", "T2"),
+        ("dividend", "CA-1"),
+        ("cash_flow", "1"),
+    }
+    # Re-fetching the same period replaces the file and keeps one record
+    again = await service.fetch_broker_report("2026-03-01", "2026-03-31")
+    assert again["id"] == report["id"]
+    assert len(await service.list_documents(kind="broker_report")) == 1
+
+    with pytest.raises(ValueError):
+        await service.fetch_broker_report("2026-03-31", "2026-03-01")
+    with pytest.raises(ValueError):
+        await service.fetch_broker_report("2026-03-01", "2026-03-31", fmt="docx")
+    (tmp_path / report["path"]).unlink()
+    with pytest.raises(LookupError):
+        await service.get_document(report["id"])
+
+
+@pytest.mark.asyncio
+async def test_elections_are_recorded_whether_accepted_or_not(temp_db, tmp_path):
+    broker = MagicMock()
+    broker.submit_corporate_action_election = AsyncMock(side_effect=["REQ-1", None])
+    service = BrokerDocumentService(db=temp_db, broker=broker, root=tmp_path)
+
+    accepted = await service.submit_election("CA-9", "stock", 10, symbol="AAA.EU")
+    failed = await service.submit_election("CA-9", "cash")
+
+    assert (accepted["status"], accepted["broker_request_id"]) == ("submitted", "REQ-1")
+    assert failed["status"] == "failed"
+    assert [e["option"] for e in await service.list_elections("CA-9")] == ["cash", "stock"]
+    with pytest.raises(ValueError):
+        await service.submit_election("CA-9", " ")
+
+
+@pytest.mark.asyncio
+async def test_broker_decodes_order_files_and_simulates_elections_outside_live_mode():
+    broker = Broker()
+    original_api, original_settings = broker._api, broker._settings
+    broker._api = MagicMock()
+    broker._api.authorized_request.return_value = {
+        "files": [{"file": "base64=" + base64.b64encode(b"%PDF").decode(), "file_name": "a.pdf", "extension": "pdf"}]
+    }
+    broker._settings = MagicMock()
+    broker._settings.get = AsyncMock(return_value="research")
+    try:
+        files = await broker.get_order_files("123")
+        election = await broker.submit_corporate_action_election("CA-1", "cash")
+    finally:
+        broker._api, broker._settings = original_api, original_settings
+
+    assert files == [{"file_name": "a.pdf", "mime": "application/octet-stream", "extension": "pdf", "content": b"%PDF"}]
+    assert election == "RESEARCH-ELECTION-CA-1"
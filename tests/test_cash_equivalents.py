@@ -0,0 +1,143 @@
+"""Tests for cash-equivalent instruments (money market ETFs)."""
+
+from datetime import datetime, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.cash_equivalents import (
+    CashEquivalentSettings,
+    cash_equivalent_symbols,
+    plan_liquidation,
+    plan_parking,
+)
+from sentinel.planner.rebalance_rules import frozen_planning_now
+
+NOW = datetime(2026, 3, 10, 12, 0)
+# Slow climb, then a 30% slide: deep dip with oversold RSI (oldest first)
+CRASH = [100.0 + i * 0.1 for i in range(275)] + [127.5 * (0.985**i) for i in range(25)]
+NO_FEES = CashEquivalentSettings(reserve_eur=500.0, min_park_eur=250.0, fee_fixed=0.0, fee_pct=0.0)
+
+
+def _instrument(symbol: str, quantity: float = 0, price: float = 100.0) -> dict:
+    return {
+        "symbol": symbol,
+        "quantity": quantity,
+        "price": price,
+        "fx_rate": 1.0,
+        "currency": "EUR",
+        "lot_size": 1,
+        "allow_buy": 1,
+        "allow_sell": 1,
+        "current_value_eur": quantity * price,
+        "total_value_eur": 20_000.0,
+    }
+
+
+def test_cash_equivalent_symbols():
+    securities = [{"symbol": "MMF.EU", "asset_class": "cash_equivalent"}, {"symbol": "AAA.EU", "asset_class": "equity"}]
+    assert cash_equivalent_symbols(securities) == {"MMF.EU"}
+
+
+def test_parking_keeps_reserve_and_skips_small_amounts():
+    park = plan_parking(2_050.0, _instrument("MMF.EU"), NO_FEES)
+    assert (park.action, park.quantity, park.reason_code, park.sleeve) == ("buy", 15, "cash_equivalent_park", "cash")
+    assert park.value_delta_eur == 1_500.0
+
+    assert plan_parking(700.0, _instrument("MMF.EU"), NO_FEES) is None
+    # Excess above the minimum, but a single unit costs more than the excess
+    assert plan_parking(900.0, _instrument("MMF.EU", price=500.0), NO_FEES) is None
+
+
+def test_liquidation_sells_largest_holding_first_in_whole_lots():
+    holdings = [_instrument("SMALL.EU", quantity=5), _instrument("BIG.EU", quantity=30)]
+
+    sells = plan_liquidation(3_450.0, holdings, NO_FEES)
+
+    assert [(s.symbol, s.quantity) for s in sells] == [("BIG.EU", 30), ("SMALL.EU", 5)]
+    assert sells[0].value_delta_eur == -3_000.0
+    assert sells[0].reason_code == "cash_equivalent_liquidation"
+    assert plan_liquidation(0.0, holdings, NO_FEES) == []
+
+
+def _engine(last_close: datetime, cash_eur: float, **settings) -> RebalanceEngine:
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(
+        return_value=[{"symbol": "MMF.EU", "quantity": 50, "current_price": 100.0, "currency": "EUR"}]
+    )
+    db.get_all_securities = AsyncMock(
+        return_value=[
+            {"symbol": "DIP.EU", "currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1, "added_at": None},
+            {
+                "symbol": "MMF.EU",
+                "currency": "EUR",
+                "min_lot": 1,
+                "allow_buy": 1,
+                "allow_sell": 1,
+                "asset_class": "cash_equivalent",
+            },
+        ]
+    )
+    db.get_prices = AsyncMock(
+        return_value=[
+            {"date": (last_close - timedelta(days=i)).strftime("%Y-%m-%d"), "close": c}
+            for i, c in enumerate(reversed(CRASH))
+        ]
+    )
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+
+    engine = RebalanceEngine(db=db)
+    engine._broker = MagicMock()
+    engine._broker.get_quotes = AsyncMock(return_value={"DIP.EU": {"price": CRASH[-1]}, "MMF.EU": {"price": 100.0}})
+    engine._settings = MagicMock()
+    values = {"min_trade_value": 100.0, "trade_cooloff_days": 0, "cash_equivalents_enabled": True, **settings}
+    engine._settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=cash_eur)
+    engine._portfolio.get_cash_balances = AsyncMock(return_value={"EUR": cash_eur})
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=lambda amt, curr: amt)
+    engine._get_deficit_sells = AsyncMock(return_value=[])
+    engine._generate_deficit_sells = AsyncMock(return_value=[])
+    return engine
+
+
+async def _recommendations(engine: RebalanceEngine) -> list:
+    with frozen_planning_now(NOW):
+        return await engine.get_recommendations(
+            ideal={"DIP.EU": 0.1}, current={"DIP.EU": 0.0, "MMF.EU": 0.25}, total_value=20_000.0
+        )
+
+
+@pytest.mark.asyncio
+async def test_buys_are_funded_by_liquidating_cash_equivalents():
+    recs = await _recommendations(_engine(NOW - timedelta(days=1), cash_eur=0.0))
+
+    buy = next(r for r in recs if r.action == "buy")
+    sell = next(r for r in recs if r.action == "sell")
+    assert buy.symbol == "DIP.EU"
+    assert (sell.symbol, sell.reason_code) == ("MMF.EU", "cash_equivalent_liquidation")
+    assert abs(sell.value_delta_eur) >= buy.value_delta_eur
+    # The held money market fund has no target, but is never rebalanced away as an equity
+    assert [r.symbol for r in recs if r.action == "sell"] == ["MMF.EU"]
+
+
+@pytest.mark.asyncio
+async def test_idle_cash_is_parked_when_no_buy_clears_the_bar():
+    # Stale closes exclude the only equity candidate
+    engine = _engine(NOW - timedelta(days=20), cash_eur=3_000.0, cash_equivalent_reserve_eur=1_000.0)
+
+    recs = await _recommendations(engine)
+
+    assert [(r.symbol, r.action, r.quantity, r.reason_code) for r in recs] == [
+        ("MMF.EU", "buy", 19, "cash_equivalent_park")
+    ]
+
+
+@pytest.mark.asyncio
+async def test_disabled_cash_equivalents_are_left_alone():
+    engine = _engine(NOW - timedelta(days=20), cash_eur=3_000.0, cash_equivalents_enabled=False)
+    assert await _recommendations(engine) == []
@@ -0,0 +1,70 @@
+"""Tests for downsampled chart series."""
+
+import time
+from datetime import date, timedelta
+
+import pytest
+
+from sentinel.services.charts import ChartService, bucket_series
+
+DAY = 86400
+START = date(2025, 1, 1)
+
+
+def _days(n: int) -> list[str]:
+    return [(START + timedelta(days=i)).isoformat() for i in range(n)]
+
+
+def test_bucket_series_keeps_last_value_per_time_bucket():
+    series = list(zip(_days(10), range(10)))
+
+    assert bucket_series(series, 5) == [{"date": d, "value": v} for d, v in zip(_days(10)[1::2], range(1, 10, 2))]
+    # Short series come back whole
+    assert [p["value"] for p in bucket_series(series[:3], 120)] == [0, 1, 2]
+    assert bucket_series([], 10) == []
+
+
+def test_bucket_series_buckets_by_time_not_by_count():
+    # A dense month followed by a single point a year later
+    series = list(zip(_days(30), range(30))) + [((START + timedelta(days=365)).isoformat(), 99)]
+
+    points = bucket_series(series, 12)
+
+    assert [p["value"] for p in points] == [29, 99]
+
+
+@pytest.mark.asyncio
+async def test_price_and_score_series(temp_db):
+    # Slow climb, then a 30% slide
+    closes = [100.0 + i * 0.1 for i in range(370)] + [137.0 * (0.985**i) for i in range(30)]
+    await temp_db.upsert_security("AAA.EU", currency="EUR")
+    rows = [{"date": d, "open": c, "high": c, "low": c, "close": c} for d, c in zip(_days(400), closes)]
+    await temp_db.save_prices("AAA.EU", rows)
+    service = ChartService(db=temp_db)
+
+    prices = await service.price("AAA.EU", days=100, points=20)
+    assert len(prices) <= 20
+    assert prices[-1] == {"date": _days(400)[-1], "value": round(closes[-1], 6)}
+
+    scores = await service.score("AAA.EU", days=100, points=20)
+    assert [p["date"] for p in scores] == [p["date"] for p in prices]
+    assert scores[0]["value"] == 0.0
+    assert scores[-1]["value"] > 0.3
+
+    with pytest.raises(LookupError):
+        await service.price("NOPE.EU")
+    with pytest.raises(ValueError):
+        await service.price("AAA.EU", points=1)
+
+
+@pytest.mark.asyncio
+async def test_portfolio_nav_adds_positions_and_cash(temp_db):
+    today = int(time.time()) // DAY * DAY
+    for i in range(3):
+        await temp_db.upsert_portfolio_snapshot(
+            today - (2 - i) * DAY, {"positions": {"AAA.EU": {"value_eur": 1000.0 + i}}, "cash_eur": 50.0}
+        )
+
+    nav = await ChartService(db=temp_db).portfolio_nav(days=30, points=10)
+
+    assert [p["value"] for p in nav] == [1050.0, 1051.0, 1052.0]
@@ -0,0 +1,101 @@
+"""Tests for the automated trading circuit breaker."""
+
+import time
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from sentinel.services.circuit_breaker import CircuitBreakerService, drawdown_from_peak
+
+DAY = 86400
+
+
+def _service(db, **values) -> CircuitBreakerService:
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, ccy: amount)
+    return CircuitBreakerService(db=db, settings=settings, currency=currency)
+
+
+async def _portfolio(db, peak: float, current: float) -> None:
+    await db.upsert_portfolio_snapshot(int(time.time()) - 5 * DAY, {"positions": {}, "cash_eur": peak})
+    await db.upsert_position("AAA.EU", quantity=1, current_price=current, currency="EUR")
+
+
+def test_drawdown_from_peak():
+    assert drawdown_from_peak([]) == (0.0, 0.0)
+    assert drawdown_from_peak([100.0, 120.0, 90.0]) == (120.0, 0.25)
+    assert drawdown_from_peak([100.0, 130.0]) == (130.0, 0.0)
+
+
+@pytest.mark.asyncio
+async def test_drawdown_trips_notifies_and_resume_rearms(temp_db):
+    await _portfolio(temp_db, peak=10_000.0, current=7_500.0)
+    service = _service(temp_db, circuit_breaker_max_drawdown_pct=20.0)
+
+    assert await service.check() is True
+
+    status = await service.status()
+    assert status["tripped"] is True
+    assert status["high_water_mark_eur"] == 10_000.0
+    assert status["drawdown_pct"] == pytest.approx(25.0)
+    assert "trading:execute" in status["paused_jobs"]
+    notes = await temp_db.get_notifications(unread_only=True)
+    assert [(n["category"], n["severity"]) for n in notes] == [("risk", "error")]
+
+    resumed = await service.resume()
+    assert resumed["tripped"] is False
+    # Re-armed from the current value: the same drawdown no longer trips
+    assert resumed["drawdown_pct"] == 0.0
+    assert await service.check() is False
+    with pytest.raises(ValueError):
+        await service.resume()
+
+
+@pytest.mark.asyncio
+async def test_small_drawdown_or_missing_data_does_not_trip(temp_db):
+    await temp_db.upsert_portfolio_snapshot(int(time.time()) - DAY, {"positions": {}, "cash_eur": 10_000.0})
+    service = _service(temp_db, circuit_breaker_max_drawdown_pct=20.0)
+    # Nothing synced yet: a zero current value is not a 100% drawdown
+    assert await service.check() is False
+
+    await temp_db.upsert_position("AAA.EU", quantity=1, current_price=9_000.0, currency="EUR")
+    assert await service.check() is False
+    assert await _service(temp_db, circuit_breaker_max_drawdown_pct=0).check() is False
+
+
+@pytest.mark.asyncio
+async def test_consecutive_rejections_trip_and_fills_reset(temp_db):
+    service = _service(temp_db, circuit_breaker_max_rejections=3)
+
+    await service.record_order_result(False)
+    await service.record_order_result(False)
+    await service.record_order_result(True)
+    await service.record_order_result(False)
+    await service.record_order_result(False)
+    assert not await service.is_tripped()
+    assert (await service.status())["consecutive_rejections"] == 2
+
+    await service.record_order_result(False)
+    status = await service.status()
+    assert status["tripped"] is True
+    assert status["reason"] == "3 consecutive orders rejected"
+
+
+@pytest.mark.asyncio
+async def test_runner_skips_paused_jobs_even_when_run_manually(temp_db):
+    from sentinel.jobs import runner
+
+    await _service(temp_db).trip("test")
+    task = AsyncMock()
+    runner._deps = {"db": temp_db}
+    runner._current_job = None
+
+    with patch.dict(runner.TASK_REGISTRY, {"trading:execute": (task, []), "sync:quotes": (task, [])}):
+        paused = await runner._run_task("trading:execute", {"job_type": "trading:execute"}, skip_timing_check=True)
+        other = await runner._run_task("sync:quotes", {"job_type": "sync:quotes"}, skip_timing_check=True)
+
+    assert paused == {"skipped": True, "reason": "circuit_breaker"}
+    assert other["status"] == "completed"
+    task.assert_awaited_once()
@@ -0,0 +1,129 @@
+"""Tests for user-defined computed columns."""
+
+import csv
+import io
+import json
+from datetime import datetime, timezone
+from unittest.mock import MagicMock
+
+import pytest
+
+from sentinel.services.computed_columns import ComputedColumnService, row_metrics, rows_to_csv
+from sentinel.services.reports import ReportService, render_markdown
+from sentinel.utils.expressions import compile_expression, validate_column_name
+
+
+def test_expression_evaluates_over_metrics():
+    expr = compile_expression("dividendYield + cagr_raw")
+    assert expr.names == {"dividendYield", "cagr_raw"}
+    assert expr.evaluate({"dividendYield": 2.5, "cagr_raw": 7.5}) == 10.0
+    assert compile_expression("max(profit_pct, 0) / 100").evaluate({"profit_pct": 12.0}) == 0.12
+    assert compile_expression("-x ** 2 % 7").evaluate({"x": 3}) == pytest.approx(-9 % 7)
+
+
+def test_missing_metrics_and_undefined_math_give_none():
+    assert compile_expression("a + b").evaluate({"a": 1.0}) is None
+    assert compile_expression("a / b").evaluate({"a": 1.0, "b": 0}) is None
+    assert compile_expression("sqrt(a)").evaluate({"a": -1.0}) is None
+    assert compile_expression("a * 2").evaluate({"a": "n/a"}) is None
+
+
+@pytest.mark.parametrize(
+    "source",
+    ["", "__import__('os')", "a.b", "a if b else c", "a < b", "open(1)", "'text'", "a[0]", "lambda: 1", "max(x=1)"],
+)
+def test_unsafe_or_unsupported_expressions_are_rejected(source):
+    with pytest.raises(ValueError):
+        compile_expression(source)
+
+
+def test_column_names_are_identifiers():
+    assert validate_column_name("yield_plus_cagr") == "yield_plus_cagr"
+    for name in ["Yield", "1st", "a-b", "max", "x" * 41]:
+        with pytest.raises(ValueError):
+            validate_column_name(name)
+
+
+def test_row_metrics_merge_broker_metadata():
+    security = {"data": json.dumps({"dividendYield": "2.5", "name": "Apple", "profit_pct": 99})}
+    metrics = row_metrics({"symbol": "AAPL", "profit_pct": 5.0, "has_position": True}, security)
+    assert metrics == {"dividendYield": 2.5, "profit_pct": 5.0}
+
+
+@pytest.mark.asyncio
+async def test_define_list_apply_and_delete(temp_db):
+    service = ComputedColumnService(db=temp_db)
+    await service.define("yield_plus_cagr", " dividendYield + cagr_raw ", "Total return proxy")
+    with pytest.raises(ValueError):
+        await service.define("broken", "dividendYield +")
+
+    columns = await service.list_columns()
+    assert [(c["name"], c["expression"], c["metrics"]) for c in columns] == [
+        ("yield_plus_cagr", "dividendYield + cagr_raw", ["cagr_raw", "dividendYield"])
+    ]
+
+    rows = await service.apply(
+        [{"symbol": "AAA.EU", "cagr_raw": 6.0}, {"symbol": "BBB.EU"}],
+        {"AAA.EU": {"data": json.dumps({"dividendYield": 3.0})}},
+    )
+    assert rows[0]["computed"] == {"yield_plus_cagr": 9.0}
+    assert rows[1]["computed"] == {"yield_plus_cagr": None}
+
+    assert await service.delete("yield_plus_cagr")
+    assert not await service.delete("yield_plus_cagr")
+    assert await service.list_columns() == []
+
+
+def test_csv_export_flattens_rows():
+    rows = [
+        {
+            "symbol": "AAA.EU",
+            "value_eur": 100.0,
+            "tags": ["new-entry"],
+            "prices": [{"date": "2026-01-02", "close": 1.0}],
+            "recommendation": {"action": "buy", "quantity": 3, "value_delta_eur": 30.0},
+            "computed": {"yield_plus_cagr": 9.0},
+        }
+    ]
+    parsed = list(csv.DictReader(io.StringIO(rows_to_csv(rows))))
+    assert parsed == [
+        {
+            "symbol": "AAA.EU",
+            "value_eur": "100.0",
+            "tags": "new-entry",
+            "recommendation_action": "buy",
+            "recommendation_quantity": "3",
+            "recommendation_value_delta_eur": "30.0",
+            "yield_plus_cagr": "9.0",
+        }
+    ]
+
+
+@pytest.mark.asyncio
+async def test_report_top_movers_carry_computed_columns(temp_db):
+    now = int(datetime(2026, 3, 10, 18, 0, tzinfo=timezone.utc).timestamp())
+    await temp_db.upsert_security("AAA.EU", currency="EUR")
+    await temp_db.upsert_position("AAA.EU", quantity=10, avg_cost=80.0)
+    await temp_db.save_prices(
+        "AAA.EU", [{"date": "2026-03-09", "close": 100.0}, {"date": "2026-03-10", "close": 110.0}]
+    )
+    await ComputedColumnService(db=temp_db).define("gain_vs_cost", "price / avg_cost - 1")
+
+    service = ReportService(db=temp_db, currency=MagicMock(), planner=MagicMock())
+    movers = await service._top_movers(now - 86400, now)
+
+    assert movers[0]["computed"] == {"gain_vs_cost": pytest.approx(0.375)}
+    markdown = render_markdown(
+        {
+            "period": "daily",
+            "period_start": now - 86400,
+            "period_end": now,
+            "portfolio": None,
+            "trades": [],
+            "dividends": [],
+            "recommendations": [],
+            "risk_warnings": [],
+            "top_movers": movers,
+        }
+    )
+    assert "- AAA.EU: +10.00% (gain_vs_cost=0.375)" in markdown
@@ -0,0 +1,105 @@
+"""Tests for configuration drift detection between paired devices."""
+
+import pytest
+import pytest_asyncio
+
+from sentinel.services.config_drift import ConfigDriftService, bundle_hash, diff_bundles
+from sentinel.settings import Settings
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    await temp_db.seed_default_job_schedules()
+    return temp_db
+
+
+def _service(db, peer_bundle: dict | Exception, calls: list | None = None) -> ConfigDriftService:
+    settings = Settings()
+    settings._db = db
+
+    async def fetch(url: str, token: str) -> dict:
+        if calls is not None:
+            calls.append((url, token))
+        if isinstance(peer_bundle, Exception):
+            raise peer_bundle
+        return dict(peer_bundle)
+
+    return ConfigDriftService(db=db, settings=settings, fetcher=fetch)
+
+
+def test_diff_bundles_reports_changed_and_one_sided_keys():
+    local = {"settings": {"a": 1, "b": {"x": 1}, "only_here": True}, "job_schedules": {"sync:prices": {"m": 30}}}
+    remote = {"settings": {"a": 1, "b": {"x": 2}, "only_there": 0}, "job_schedules": {"sync:prices": {"m": 30}}}
+
+    differences = diff_bundles(local, remote)
+
+    assert [(d["section"], d["key"]) for d in differences] == [
+        ("settings", "b"),
+        ("settings", "only_here"),
+        ("settings", "only_there"),
+    ]
+    assert differences[1]["missing_remotely"] and not differences[1]["missing_locally"]
+    assert differences[2]["missing_locally"] and differences[2]["remote"] == 0
+    assert bundle_hash(local) == bundle_hash({**local, "hash": "stale"})
+
+
+@pytest.mark.asyncio
+async def test_bundle_excludes_secrets_state_and_device_settings(temp_db):
+    await temp_db.set_setting("tradernet_api_key", "key")
+    await temp_db.set_setting("trading_mode", "live")
+    await temp_db.set_setting("led_brightness", 50)
+    await temp_db.set_setting("config_drift_ignore", ["max_positions"])
+
+    bundle = await _service(temp_db, {}).bundle()
+
+    for key in ("tradernet_api_key", "trading_mode", "led_brightness", "exchange_rates", "max_positions"):
+        assert key not in bundle["settings"]
+    assert "min_trade_value" in bundle["settings"]
+    assert bundle["job_schedules"]["sync:prices"] == {
+        "interval_minutes": 30,
+        "interval_market_open_minutes": 5,
+        "market_timing": 0,
+    }
+    assert bundle["hash"] == bundle_hash(bundle)
+
+
+@pytest.mark.asyncio
+async def test_check_alerts_on_divergence_with_peer(temp_db):
+    await temp_db.set_setting("config_peer_url", "http://standby:8000/")
+    await temp_db.set_setting("config_peer_token", "peer-token")
+    peer = await _service(temp_db, {}).bundle()
+    # The peer's device-local settings are ignored, a changed strategy setting is drift
+    peer["settings"] = {**peer["settings"], "led_brightness": 10, "min_trade_value": 1.0}
+    calls = []
+    service = _service(temp_db, peer, calls)
+
+    state = await service.check()
+
+    assert calls == [("http://standby:8000/api/config/bundle", "peer-token")]
+    assert state["in_sync"] is False
+    assert state["differing_keys"] == ["min_trade_value"]
+    assert await service.status() == state
+    notes = await temp_db.get_notifications(unread_only=True)
+    assert [(n["category"], n["severity"]) for n in notes] == [("system", "warning")]
+
+    diff = await service.diff()
+    assert diff["local_hash"] != diff["remote_hash"]
+    assert diff["differences"][0]["remote"] == 1.0
+
+
+@pytest.mark.asyncio
+async def test_check_in_sync_unreachable_and_unconfigured(temp_db):
+    with pytest.raises(ValueError):
+        await _service(temp_db, {}).check()
+
+    await temp_db.set_setting("config_peer_url", "http://standby:8000")
+    peer = await _service(temp_db, {}).bundle()
+    state = await _service(temp_db, peer).check()
+    assert state["in_sync"] is True
+    assert state["local_hash"] == state["remote_hash"]
+
+    failed = await _service(temp_db, ConnectionError("refused")).check()
+    # Unreachable is recorded as an error; the last comparison is kept
+    assert failed["error"] == "refused"
+    assert failed["in_sync"] is True
+    assert await temp_db.get_notifications(unread_only=True) == []
@@ -0,0 +1,93 @@
+"""Tests for the settings schema and startup validation report."""
+
+from types import SimpleNamespace
+from unittest.mock import AsyncMock
+
+import pytest
+from fastapi import HTTPException
+
+from sentinel.config.schema import check_port, format_report, validate_setting, validate_settings
+from sentinel.settings import DEFAULTS
+
+
+def test_defaults_are_valid():
+    assert validate_settings(DEFAULTS) == []
+
+
+def test_every_problem_is_reported_at_once():
+    values = {
+        **DEFAULTS,
+        "trading_mode": "paper",
+        "max_position_pct": 150,
+        "led_brightness": 3.5,
+        "strategy_core_target_pct": 70,
+        "r2_account_id": "acct",
+        "fee_schedule": {".US": {"fixed": -1}},
+    }
+    values.pop("price_hot_days")
+    problems = validate_settings(values)
+    messages = [p.message for p in problems]
+    assert {p.key for p in problems} == {
+        "trading_mode",
+        "max_position_pct",
+        "led_brightness",
+        "price_hot_days",
+        "fee_schedule",
+        "strategy_core_target_pct",
+        "r2_bucket_name",
+    }
+    assert "max_position_pct must be between 0 and 100 (got 150)" in messages
+    assert "price_hot_days is required" in messages
+    assert any("must sum to 100 (got 90)" in m for m in messages)
+
+    report = format_report(problems)
+    assert report.startswith("Configuration has 7 problems:")
+    assert report.count("\n  - ") == 7
+
+
+def test_cross_field_rules():
+    values = {
+        **DEFAULTS,
+        "min_position_pct": 30,
+        "strategy_entry_t2_dd": -0.05,
+        "intraday_raw_retention_days": 400,
+        "gpio_relay_enabled": True,
+    }
+    keys = [p.key for p in validate_settings(values)]
+    assert keys == ["min_position_pct", "strategy_entry_t1_dd", "intraday_raw_retention_days", "gpio_relay_pin"]
+
+
+def test_single_setting_rules():
+    assert validate_setting("simulated_cash_eur", None) == []
+    assert validate_setting("tradernet_api_key", "anything") == []
+    assert validate_setting("position_sizing_mode", "volatility_target") == []
+    assert [p.message for p in validate_setting("auth_enabled", "yes")] == ["auth_enabled must be true or false"]
+    sizing = validate_setting("position_sizing_mode", "kelly")
+    assert sizing[0].message.startswith("position_sizing_mode: must be one of")
+
+
+def test_ports():
+    assert check_port("--port", 8000) == []
+    assert check_port("--public-port", "8080") == []
+    assert check_port("--port", 0)[0].message == "--port must be between 1 and 65535 (got 0)"
+    assert check_port("--public-port", "web")[0].message == "--public-port must be a port number (got 'web')"
+
+
+@pytest.mark.asyncio
+async def test_put_rejects_values_that_break_the_schema():
+    from sentinel.api.routers.settings import get_settings_validation, set_setting
+
+    settings = SimpleNamespace(set=AsyncMock(), all=AsyncMock(return_value={**DEFAULTS, "report_keep": 0}))
+    deps = SimpleNamespace(db=None, settings=settings)
+    with pytest.raises(HTTPException) as exc:
+        await set_setting("led_brightness", {"value": 300}, deps)
+    assert exc.value.status_code == 400
+    assert exc.value.detail == "led_brightness must be between 0 and 255 (got 300)"
+    settings.set.assert_not_called()
+
+    await set_setting("led_brightness", {"value": 120}, deps)
+    settings.set.assert_awaited_once_with("led_brightness", 120)
+
+    result = await get_settings_validation(deps)
+    assert result["valid"] is False
+    assert result["problems"] == [{"key": "report_keep", "message": "report_keep must be at least 1 (got 0)"}]
@@ -0,0 +1,137 @@
+"""Tests for the rolling correlation matrix and correlation-based diversification."""
+
+import math
+from datetime import date, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.planner.correlation import (
+    blend_diversification,
+    candidate_diversification_score,
+    correlation_lookup,
+    daily_returns,
+    pairwise_correlations,
+    pearson,
+    portfolio_diversification,
+)
+from sentinel.services.correlations import CorrelationService
+from sentinel.settings import Settings
+
+
+def _rows(returns: list[float]) -> list[dict]:
+    """Price rows (newest first, as stored) following the given daily returns."""
+    start = date(2026, 1, 1)
+    close = 100.0
+    rows = [{"date": start.isoformat(), "close": close}]
+    for i, r in enumerate(returns, start=1):
+        close *= 1.0 + r
+        rows.append({"date": (start + timedelta(days=i)).isoformat(), "close": close})
+    return list(reversed(rows))
+
+
+WAVE = [0.01 * math.sin(i) for i in range(80)]
+
+
+def test_pearson_and_daily_returns():
+    assert pearson([1, 2, 3], [2, 4, 6]) == pytest.approx(1.0)
+    assert pearson([1, 2, 3], [3, 2, 1]) == pytest.approx(-1.0)
+    assert pearson([1, 1, 1], [1, 2, 3]) is None
+
+    returns = daily_returns(_rows([0.1, -0.5]))
+    assert list(returns.values()) == pytest.approx([0.1, -0.5])
+
+
+def test_pairwise_correlations_skip_short_overlaps():
+    returns = {
+        "B": daily_returns(_rows(WAVE)),
+        "A": daily_returns(_rows([2 * r for r in WAVE])),
+        "C": daily_returns(_rows([-r for r in WAVE[:30]])),
+    }
+
+    pairs = pairwise_correlations(returns, min_observations=60)
+
+    assert [(p["symbol_a"], p["symbol_b"]) for p in pairs] == [("A", "B")]
+    assert pairs[0]["correlation"] == pytest.approx(1.0)
+    assert pairs[0]["observations"] == 80
+    assert len(pairwise_correlations(returns, min_observations=20)) == 3
+
+
+def test_diversification_scores():
+    lookup = correlation_lookup(
+        [
+            {"symbol_a": "A", "symbol_b": "B", "correlation": 0.9},
+            {"symbol_a": "A", "symbol_b": "C", "correlation": -0.2},
+            {"symbol_a": "B", "symbol_b": "C", "correlation": 0.5},
+        ]
+    )
+    holdings = {"A": 0.75, "B": 0.25}
+
+    # C hedges the main holding: (0.75 * -0.2 + 0.25 * 0.5) = -0.025 average correlation
+    assert candidate_diversification_score("C", holdings, lookup) == pytest.approx(1.0)
+    # B moves with A, the rest of the holdings
+    assert candidate_diversification_score("B", holdings, lookup) == pytest.approx(1 - 1.8)
+    assert candidate_diversification_score("D", holdings, lookup) is None
+
+    summary = portfolio_diversification({"A": 0.5, "B": 0.3, "C": 0.2}, lookup)
+    expected = (0.15 * 0.9 + 0.10 * -0.2 + 0.06 * 0.5) / 0.31
+    assert summary["average_correlation"] == pytest.approx(expected, abs=1e-4)
+    assert summary["diversification_score"] == pytest.approx(1 - expected, abs=1e-4)
+    assert summary["pairs"] == 3
+    assert portfolio_diversification({"A": 1.0}, lookup)["diversification_score"] is None
+
+    assert blend_diversification(0.2, None, 0.5) == 0.2
+    assert blend_diversification(0.2, -0.6, 0.5) == pytest.approx(-0.2)
+    assert blend_diversification(0.2, -0.6, 0.0) == 0.2
+
+
+@pytest.mark.asyncio
+async def test_service_computes_persists_and_serves_the_matrix(temp_db):
+    for symbol in ("AAA.EU", "BBB.EU", "CCC.EU"):
+        await temp_db.upsert_security(symbol, name=symbol, currency="EUR", active=1)
+    await temp_db.upsert_security("OFF.EU", name="Off", currency="EUR", active=1, allow_buy=0)
+    await temp_db.upsert_position("AAA.EU", quantity=10, avg_cost=100.0, current_price=100.0)
+    price_rows = {
+        "AAA.EU": _rows(WAVE),
+        "BBB.EU": _rows([0.5 * r for r in WAVE]),
+        "CCC.EU": _rows([-r for r in WAVE]),
+        "OFF.EU": _rows(WAVE),
+    }
+    for symbol, rows in price_rows.items():
+        await temp_db.save_prices(symbol, rows)
+    portfolio = MagicMock()
+    portfolio.get_allocations = AsyncMock(return_value={"by_security": {"AAA.EU": 0.6, "CCC.EU": 0.4}})
+    settings = Settings()
+    settings._db = temp_db
+    service = CorrelationService(db=temp_db, settings=settings, portfolio=portfolio)
+
+    result = await service.compute()
+
+    assert result == {"symbols": 3, "pairs": 3, "window_days": 252}
+    matrix = await service.matrix()
+    assert matrix["symbols"] == ["AAA.EU", "BBB.EU", "CCC.EU"]
+    assert matrix["matrix"][0] == pytest.approx([1.0, 1.0, -1.0])
+    assert matrix["held"] == ["AAA.EU", "CCC.EU"]
+    assert matrix["window_days"] == 252
+    # The two holdings offset each other perfectly
+    assert matrix["diversification"]["diversification_score"] == 1.0
+
+    subset = await service.matrix(["BBB.EU", "CCC.EU"])
+    assert subset["matrix"] == [[1.0, -1.0], [-1.0, 1.0]]
+
+    with pytest.raises(ValueError):
+        await service.compute(window_days=1)
+
+
+@pytest.mark.asyncio
+async def test_calculator_loads_stored_correlations():
+    db = MagicMock()
+    db.get_correlations = AsyncMock(return_value=[{"symbol_a": "A", "symbol_b": "B", "correlation": 0.3}])
+    calculator = AllocationCalculator(db=db, portfolio=MagicMock(), currency=MagicMock(), settings=MagicMock())
+
+    lookup = await calculator._load_correlations()
+
+    assert lookup == {("A", "B"): 0.3, ("B", "A"): 0.3}
+    calculator._db = MagicMock(spec=[])
+    assert await calculator._load_correlations() == {}
@@ -0,0 +1,77 @@
+"""Tests for correlation IDs on requests, log records and outbound calls."""
+
+import logging
+
+from fastapi import FastAPI
+from fastapi.testclient import TestClient
+
+from sentinel import correlation
+from sentinel.api.correlation import CorrelationMiddleware
+from sentinel.services.logs import LogBuffer
+from sentinel.services.webhooks import build_request
+
+
+def _logger(buffer: LogBuffer) -> logging.Logger:
+    logger = logging.getLogger("sentinel.test.correlation")
+    logger.setLevel(logging.DEBUG)
+    logger.propagate = False
+    logger.handlers = [buffer]
+    return logger
+
+
+def test_bind_reuses_valid_ids_only():
+    assert correlation.current() is None
+    with correlation.bind("req-42") as outer:
+        assert (outer, correlation.current()) == ("req-42", "req-42")
+        assert correlation.outbound_headers() == {"X-Correlation-ID": "req-42"}
+        with correlation.bind("bad id\nwith newline") as inner:
+            assert inner != "req-42" and correlation.is_valid(inner)
+        assert correlation.current() == "req-42"
+    assert correlation.current() is None
+    assert correlation.outbound_headers() == {}
+    assert not correlation.is_valid("x" * 65)
+
+
+def test_log_records_carry_the_correlation_id():
+    correlation.install_log_records()
+    buffer = LogBuffer(size=10)
+    logger = _logger(buffer)
+    records = []
+    buffer.addFilter(lambda record: records.append(record.correlation_id) or True)
+
+    logger.info("startup")
+    with correlation.bind("req-1"):
+        logger.info("handling request")
+        logger.warning("broker slow")
+    with correlation.bind("req-2"):
+        logger.info("other request")
+
+    assert records == ["-", "req-1", "req-1", "req-2"]
+    assert [e["message"] for e in buffer.recent(correlation_id="req-1")] == ["handling request", "broker slow"]
+    assert buffer.recent()[0]["correlation_id"] is None
+
+
+def test_webhooks_forward_the_correlation_id():
+    webhook = {"kind": "generic", "url": "https://example.com/hook", "secret": None}
+    payload = {"event": "trade.executed", "title": "Bought AAPL"}
+    with correlation.bind("req-7"):
+        _, headers, _ = build_request(webhook, payload, 1_700_000_000)
+    assert headers["X-Correlation-ID"] == "req-7"
+
+
+def test_middleware_echoes_or_generates_the_id():
+    app = FastAPI()
+    app.add_middleware(CorrelationMiddleware)
+
+    @app.get("/ping")
+    async def ping():
+        return {"correlation_id": correlation.current()}
+
+    client = TestClient(app)
+    response = client.get("/ping", headers={"X-Correlation-ID": "client-abc"})
+    assert response.headers["X-Correlation-ID"] == "client-abc"
+    assert response.json() == {"correlation_id": "client-abc"}
+
+    generated = client.get("/ping")
+    assert generated.headers["X-Correlation-ID"] == generated.json()["correlation_id"]
+    assert len(generated.headers["X-Correlation-ID"]) == 16
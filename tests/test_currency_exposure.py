@@ -0,0 +1,103 @@
+"""Tests for currency exposure, hedging suggestions and soft currency limits."""
+
+import time
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.currency_exposure import (
+    apply_currency_soft_limits,
+    decompose,
+    over_limit_currencies,
+    security_currency_split,
+    to_pct,
+)
+from sentinel.planner.models import TradeRecommendation
+from sentinel.services.currency_exposure import CurrencyExposureService, hedging_suggestions
+
+DAY = 86400
+SECURITIES = {
+    "AAPL.US": {"symbol": "AAPL.US", "currency": "USD", "geography": "US"},
+    "SAP.EU": {"symbol": "SAP.EU", "currency": "EUR", "geography": "Europe, US"},
+    "ODD.EU": {"symbol": "ODD.EU", "currency": "EUR", "geography": "Atlantis"},
+}
+
+
+def test_listing_and_revenue_split():
+    assert security_currency_split(SECURITIES["SAP.EU"]) == {"EUR": 1.0}
+    assert security_currency_split(SECURITIES["SAP.EU"], "revenue") == {"EUR": 0.5, "USD": 0.5}
+    # Unknown geography falls back to the listing currency
+    assert security_currency_split(SECURITIES["ODD.EU"], "revenue") == {"EUR": 1.0}
+
+    values = {"AAPL.US": 600.0, "SAP.EU": 400.0}
+    assert decompose(values, SECURITIES) == {"USD": 600.0, "EUR": 400.0}
+    assert decompose(values, SECURITIES, "revenue") == {"USD": 800.0, "EUR": 200.0}
+    assert to_pct({"USD": 800.0, "EUR": 200.0}) == {"USD": 0.8, "EUR": 0.2}
+
+
+def test_hedging_suggestions_for_currencies_over_limit():
+    suggestions = hedging_suggestions({"USD": 0.7, "EUR": 0.3}, {"USD": 0.5, "EUR": 0.6}, 10_000.0)
+    assert [(s["currency"], s["excess_eur"]) for s in suggestions] == [("USD", 2000.0)]
+    assert suggestions[0]["message"].startswith("Reduce USD exposure by EUR 2,000")
+
+
+def _rec(symbol: str, action: str, priority: float = 1.0) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=100.0,
+        value_delta_eur=100.0,
+        quantity=1,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=priority,
+        reason="Underweight",
+    )
+
+
+def test_soft_limits_reweight_but_never_drop():
+    over = over_limit_currencies({"USD": 0.8, "EUR": 0.2}, {"USD": 0.5})
+    assert over == {"USD"}
+    recs = [_rec("AAPL.US", "buy"), _rec("SAP.EU", "buy"), _rec("AAPL.US", "sell")]
+
+    apply_currency_soft_limits(recs, SECURITIES, over, basis="revenue", penalty=0.5)
+
+    assert [r.priority for r in recs] == [0.5, 0.75, 1.5]
+    assert recs[0].reason == "Underweight (adds to over-limit USD exposure)"
+    assert recs[2].reason.endswith("(reduces over-limit USD exposure)")
+
+
+@pytest.mark.asyncio
+async def test_exposure_report_with_drift(temp_db):
+    for sec in SECURITIES.values():
+        await temp_db.upsert_security(sec["symbol"], currency=sec["currency"], geography=sec["geography"])
+    await temp_db.upsert_position("AAPL.US", quantity=10, current_price=100.0, currency="USD")
+    await temp_db.upsert_position("SAP.EU", quantity=5, current_price=100.0, currency="EUR")
+    await temp_db.set_cash_balances({"EUR": 500.0})
+    start = {"positions": {"SAP.EU": {"value_eur": 1000}}, "cash_eur": 0}
+    await temp_db.upsert_portfolio_snapshot(int(time.time()) - 10 * DAY, start)
+
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, ccy: amount * (0.5 if ccy == "USD" else 1.0))
+    settings = MagicMock()
+    values = {"currency_exposure_limits": {"USD": 0.2}}
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+
+    result = await CurrencyExposureService(db=temp_db, currency=currency, settings=settings).get_exposure()
+
+    assert result["basis"] == "listing"
+    assert result["total_eur"] == 1500.0
+    exposure = {e["currency"]: e for e in result["exposure"]}
+    assert exposure["EUR"]["pct"] == pytest.approx(0.6667, abs=1e-4)
+    assert exposure["USD"]["drift_pct"] == pytest.approx(0.3333, abs=1e-4)
+    assert exposure["USD"]["limit_pct"] == 0.2
+    assert [s["currency"] for s in result["suggestions"]] == ["USD"]
+
+    with pytest.raises(ValueError):
+        await CurrencyExposureService(db=temp_db, currency=currency, settings=settings).get_exposure(basis="moon")
@@ -0,0 +1,84 @@
+"""Tests for the blue/green deployment smoke tests."""
+
+import os
+import tempfile
+from pathlib import Path
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.deploy import copy_database, smoke_test
+
+
+@pytest_asyncio.fixture
+async def db_copy():
+    with tempfile.TemporaryDirectory() as tmp:
+        live = Database(os.path.join(tmp, "live.db"))
+        await live.connect()
+        await live.set_setting("trading_mode", "live")
+        await live.close()
+        live.remove_from_cache()
+
+        copy = Path(tmp) / "copy.db"
+        copy_database(Path(tmp) / "live.db", copy)
+        db = Database(str(copy))
+        yield db
+        await db.close()
+        db.remove_from_cache()
+
+
+def _broker(connects: bool = True, answers: bool = True):
+    broker = MagicMock()
+    broker.connect = AsyncMock(return_value=connects)
+    broker.ping = AsyncMock(return_value=answers)
+    return broker
+
+
+def _planner(recommendations=None, error: Exception | None = None):
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=recommendations or [], side_effect=error)
+    return planner
+
+
+@pytest.mark.asyncio
+async def test_smoke_test_passes_on_a_migrated_copy(db_copy):
+    result = await smoke_test(db_copy, broker=_broker(), planner=_planner([MagicMock(), MagicMock()]))
+    assert result["ok"] is True
+    assert list(result["checks"]) == ["migrations", "broker", "planner"]
+    assert result["checks"]["planner"]["detail"] == "2 recommendations"
+    # The copy carries the live data
+    assert await db_copy.get_setting("trading_mode") == "live"
+
+
+@pytest.mark.asyncio
+async def test_smoke_test_fails_on_broker_or_planner(db_copy):
+    result = await smoke_test(db_copy, broker=_broker(connects=False), planner=_planner())
+    assert result["ok"] is False
+    assert result["checks"]["broker"]["detail"] == "not connected (missing credentials?)"
+
+    result = await smoke_test(db_copy, broker=_broker(answers=False), planner=_planner())
+    assert result["checks"]["broker"]["ok"] is False
+    assert result["checks"]["broker"]["detail"] == "API did not answer"
+
+    result = await smoke_test(db_copy, broker=_broker(), planner=_planner(error=ValueError("no prices")))
+    assert result["ok"] is False
+    assert result["checks"]["planner"]["detail"] == "ValueError: no prices"
+
+
+@pytest.mark.asyncio
+async def test_failed_migrations_skip_the_remaining_checks():
+    with tempfile.TemporaryDirectory() as tmp:
+        path = Path(tmp) / "broken.db"
+        path.write_bytes(b"not a database" * 512)
+
+        db = Database(str(path))
+        broker = _broker()
+        result = await smoke_test(db, broker=broker, planner=_planner())
+        await db.close()
+        db.remove_from_cache()
+
+    assert result["ok"] is False
+    assert list(result["checks"]) == ["migrations"]
+    broker.connect.assert_not_called()
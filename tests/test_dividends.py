@@ -0,0 +1,60 @@
+"""Tests for dividend withholding tax tracking."""
+
+import pytest
+
+from sentinel.services.dividends import DividendService, reclaimable, withholding_breakdown
+
+
+def _action(amount: float, external_tax: float, tax_amount: float = 0.0) -> dict:
+    return {"type_id": "dividend", "amount": amount, "external_tax": external_tax, "tax_amount": tax_amount}
+
+
+def test_withholding_breakdown():
+    breakdown = withholding_breakdown(_action(65.0, 35.0))
+    assert breakdown == {
+        "gross_amount": 100.0,
+        "withholding_amount": 35.0,
+        "withholding_rate": 0.35,
+        "net_amount": 65.0,
+    }
+    assert withholding_breakdown(_action(0, 0))["withholding_rate"] == 0.0
+    assert reclaimable(100.0, 35.0, 0.15) == 20.0
+    assert reclaimable(100.0, 10.0, 0.15) == 0.0
+
+
+@pytest.mark.asyncio
+async def test_yearly_withholding_summary_by_country(temp_db):
+    await temp_db.set_setting("dividend_treaty_rates", {"US": 0.15})
+    await temp_db.set_setting("dividend_treaty_rate_default", 0.10)
+    await temp_db.upsert_security("NESN.EU", name="Nestle", currency="CHF", isin="CH0038863350")
+    await temp_db.upsert_security("AAPL.US", name="Apple", currency="USD", isin="US0378331005")
+
+    # Stored with its breakdown (as sync:dividends does) and, for CHF, a legacy row broken down from raw data
+    await temp_db.upsert_dividend(
+        "d1",
+        "AAPL.US",
+        "2025-05-15",
+        85.0,
+        "USD",
+        80.0,
+        _action(85.0, 15.0),
+        gross_amount=100.0,
+        withholding_amount=15.0,
+        withholding_rate=0.15,
+        country="US",
+    )
+    await temp_db.upsert_dividend("d2", "NESN.EU", "2025-04-20", 65.0, "CHF", 65.0, _action(65.0, 35.0))
+    await temp_db.upsert_dividend("d3", "NESN.EU", "2024-04-20", 65.0, "CHF", 65.0, _action(65.0, 35.0))
+
+    service = DividendService(db=temp_db)
+    dividends = {d["id"]: d for d in await service.dividends(2025)}
+    assert set(dividends) == {"d1", "d2"}
+    assert dividends["d1"]["gross_eur"] == pytest.approx(94.12, abs=0.01)
+    assert (dividends["d2"]["country"], dividends["d2"]["withholding_rate"]) == ("CH", 0.35)
+
+    summary = await service.withholding_summary(2025)
+    countries = {c["country"]: c for c in summary["countries"]}
+    assert countries["CH"]["reclaimable_eur"] == 25.0  # 35% withheld, 10% treaty rate
+    assert countries["US"]["reclaimable_eur"] == 0.0
+    assert countries["CH"]["effective_rate"] == 0.35
+    assert summary["totals"]["net_eur"] == 145.0
@@ -0,0 +1,64 @@
+"""Tests for per-security dividend reinvestment (DRIP) decisions."""
+
+from datetime import datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.drip import DripService
+
+
+def _planner(ideal: dict, current: dict):
+    planner = MagicMock()
+    planner.calculate_ideal_portfolio = AsyncMock(return_value=ideal)
+    planner.get_current_allocations = AsyncMock(return_value=current)
+    return planner
+
+
+@pytest.mark.asyncio
+async def test_dividends_follow_each_security_mode(temp_db):
+    for symbol in ("SAME.EU", "REDIR.EU", "CASH.EU", "TOP.EU", "LOW.EU"):
+        await temp_db.upsert_security(symbol, name=symbol, currency="EUR")
+    service = DripService(db=temp_db)
+    await service.set_mode("REDIR.EU", "redirect")
+    await service.set_mode("CASH.EU", "cash")
+    await temp_db.save_score_states({"TOP.EU": {"core_rank": 0.9}, "LOW.EU": {"core_rank": 0.4}}, "key")
+    for dividend_id, symbol, value in (("d1", "SAME.EU", 10.0), ("d2", "REDIR.EU", 20.0), ("d3", "CASH.EU", 30.0)):
+        await temp_db.upsert_dividend(dividend_id, symbol, "2026-09-15", value, "EUR", value, {})
+
+    # Before the job decides, dividends go back into the paying security
+    assert await temp_db.get_uninvested_dividends() == {"SAME.EU": 10.0, "REDIR.EU": 20.0, "CASH.EU": 30.0}
+
+    planner = _planner({"TOP.EU": 0.3, "LOW.EU": 0.3, "SAME.EU": 0.2}, {"TOP.EU": 0.1, "LOW.EU": 0.1, "SAME.EU": 0.3})
+    decisions = {d["dividend_id"]: d for d in await service.decide_pending(planner)}
+    assert {k: (d["mode"], d["target_symbol"]) for k, d in decisions.items()} == {
+        "d1": ("same", "SAME.EU"),
+        "d2": ("redirect", "TOP.EU"),
+        "d3": ("cash", None),
+    }
+    assert decisions["d2"]["reason"].startswith("highest-scored underweight security")
+    assert await temp_db.get_uninvested_dividends() == {"SAME.EU": 10.0, "TOP.EU": 20.0}
+
+    # Decided once; buying the target reinvests the redirected dividend
+    assert await service.decide_pending(planner) == []
+    executed_at = int(datetime(2026, 9, 20, 12).timestamp())
+    await temp_db.upsert_trade("t1", "TOP.EU", "BUY", 1, 20.0, executed_at, {})
+    assert await temp_db.get_uninvested_dividends() == {"SAME.EU": 10.0}
+    assert [d["dividend_id"] for d in await service.decisions("TOP.EU")] == ["d2"]
+
+
+@pytest.mark.asyncio
+async def test_redirect_without_underweight_and_validation(temp_db):
+    await temp_db.upsert_security("REDIR.EU", name="R", currency="EUR")
+    await temp_db.set_setting("drip_default_mode", "redirect")
+    await temp_db.upsert_dividend("d1", "REDIR.EU", "2026-09-15", 5.0, "EUR", 5.0, {})
+    service = DripService(db=temp_db)
+
+    decisions = await service.decide_pending(_planner({"REDIR.EU": 1.0}, {"REDIR.EU": 1.0}))
+    assert decisions[0]["target_symbol"] == "REDIR.EU"
+    assert decisions[0]["reason"] == "no underweight security to redirect to; reinvested in REDIR.EU"
+
+    with pytest.raises(ValueError, match="drip_mode must be one of"):
+        await service.set_mode("REDIR.EU", "bonds")
+    with pytest.raises(LookupError):
+        await service.set_mode("NOPE.EU", "cash")
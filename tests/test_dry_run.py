@@ -0,0 +1,75 @@
+"""Tests for dry-run sessions and the ?dry_run=true middleware."""
+
+import json
+
+import pytest
+
+from sentinel.api.dry_run import DryRunMiddleware, dry_run_requested
+from sentinel.broker import Broker
+from sentinel.dry_run import dry_run, is_dry_run
+
+
+@pytest.mark.asyncio
+async def test_writes_are_visible_inside_and_rolled_back_after(temp_db):
+    await temp_db.set_setting("trading_mode", "research")
+
+    async with dry_run(temp_db) as session:
+        assert is_dry_run()
+        await temp_db.set_setting("trading_mode", "live")
+        await temp_db.set_settings_batch({"max_positions": 5, "min_trade_value": 250})
+        assert await temp_db.get_setting("trading_mode") == "live"
+        assert await temp_db.get_setting("max_positions") == 5
+
+    assert not is_dry_run()
+    assert await temp_db.get_setting("trading_mode") == "research"
+    assert await temp_db.get_setting("max_positions") is None
+    assert [c["table"] for c in session.changes] == ["settings", "settings", "settings"]
+    assert session.summary()["tables"] == {"settings": 3}
+
+
+@pytest.mark.asyncio
+async def test_broker_orders_are_recorded_not_placed(temp_db):
+    async with dry_run(temp_db) as session:
+        order_id = await Broker().buy("AAPL.US", 3, price=150.0)
+
+    assert order_id == "DRY-RUN-BUY-AAPL.US-3"
+    assert session.side_effects == [
+        {"kind": "order", "action": "buy", "symbol": "AAPL.US", "quantity": 3, "price": 150.0}
+    ]
+
+
+def test_dry_run_requested_only_for_mutating_methods():
+    assert dry_run_requested({"type": "http", "method": "PUT", "query_string": b"dry_run=true"})
+    assert not dry_run_requested({"type": "http", "method": "GET", "query_string": b"dry_run=true"})
+    assert not dry_run_requested({"type": "http", "method": "POST", "query_string": b"dry_run=false"})
+    assert not dry_run_requested({"type": "http", "method": "POST", "query_string": b""})
+
+
+@pytest.mark.asyncio
+async def test_middleware_wraps_result_with_would_be_changes(temp_db):
+    async def app(scope, receive, send):
+        await temp_db.set_setting("trading_mode", "live")
+        body = json.dumps({"status": "ok"}).encode()
+        await send(
+            {"type": "http.response.start", "status": 200, "headers": [(b"content-type", b"application/json")]}
+        )
+        await send({"type": "http.response.body", "body": body})
+
+    sent = []
+
+    async def receive():
+        return {"type": "http.request", "body": b"", "more_body": False}
+
+    async def send(message):
+        sent.append(message)
+
+    scope = {"type": "http", "method": "PUT", "path": "/api/settings/trading_mode", "query_string": b"dry_run=1"}
+    await DryRunMiddleware(app, db_factory=lambda: temp_db)(scope, receive, send)
+
+    assert sent[0]["status"] == 200
+    payload = json.loads(b"".join(m.get("body", b"") for m in sent[1:]))
+    assert payload["dry_run"] is True
+    assert payload["result"] == {"status": "ok"}
+    assert payload["tables"] == {"settings": 1}
+    assert payload["changes"][0]["params"] == [["trading_mode", "live"]]
+    assert await temp_db.get_setting("trading_mode") is None
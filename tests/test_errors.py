@@ -0,0 +1,97 @@
+"""Tests for the domain error taxonomy and its HTTP and job mappings."""
+
+import asyncio
+import sqlite3
+
+import httpx
+from fastapi import FastAPI
+from fastapi.testclient import TestClient
+
+from sentinel.api.errors import sentinel_error_handler
+from sentinel.errors import (
+    Conflict,
+    InvalidInput,
+    NotFound,
+    RateLimited,
+    SentinelError,
+    Unavailable,
+    UpstreamError,
+    classify,
+    http_error,
+)
+from sentinel.jobs.failures import classify_failure
+
+
+def _status_error(status: int) -> httpx.HTTPStatusError:
+    request = httpx.Request("GET", "https://example.com")
+    return httpx.HTTPStatusError("failed", request=request, response=httpx.Response(status, request=request))
+
+
+def test_errors_keep_builtin_bases_and_categories():
+    assert isinstance(InvalidInput("bad"), ValueError)
+    assert isinstance(NotFound("missing"), LookupError)
+    assert isinstance(RateLimited("slow down"), ConnectionError)
+    assert (Conflict("taken").status, Conflict("taken").retryable) == (409, False)
+    assert (Unavailable("down").category, Unavailable("down").retryable) == ("transient", True)
+
+    error = NotFound("Security X not found", symbol="X").with_context(job="sync:prices")
+    assert error.as_dict() == {
+        "code": "not_found",
+        "category": "user",
+        "message": "Security X not found",
+        "context": {"symbol": "X", "job": "sync:prices"},
+    }
+
+
+def test_http_error_and_classify():
+    assert isinstance(http_error(_status_error(429), "Yahoo"), RateLimited)
+    assert isinstance(http_error(_status_error(503), "Yahoo"), Unavailable)
+    rejected = http_error(_status_error(404), "Stooq")
+    assert (type(rejected), rejected.context) == (UpstreamError, {"source": "Stooq", "http_status": 404})
+    assert isinstance(http_error(httpx.ConnectError("refused"), "Yahoo"), Unavailable)
+
+    assert classify(asyncio.TimeoutError()).code == "timeout"
+    assert classify(sqlite3.OperationalError("database is locked")).code == "db_locked"
+    assert type(classify(LookupError("gone"))) is NotFound
+    assert type(classify(KeyError("field"))) is SentinelError
+    wrapped = classify(ValueError("bad"))
+    assert (type(wrapped), wrapped.__cause__.args) == (InvalidInput, ("bad",))
+
+
+def test_job_failure_classes_of_domain_errors():
+    assert classify_failure(Unavailable("Broker not connected", code="broker_unavailable")) == "broker_auth"
+    assert classify_failure(RateLimited("Yahoo is rate limiting requests")) == "network"
+    assert classify_failure(Unavailable("Timed out", code="timeout")) == "timeout"
+    assert classify_failure(UpstreamError("Yahoo chart returned no result")) == "data_validation"
+    assert classify_failure(InvalidInput("period must be YYYY-MM")) == "data_validation"
+    assert classify_failure(SentinelError("bug")) == "unknown"
+
+
+def test_api_maps_errors_to_status_codes():
+    app = FastAPI()
+    app.add_exception_handler(SentinelError, sentinel_error_handler)
+    errors = {
+        "conflict": Conflict("Reconciliation 7 is already signed off", id=7),
+        "missing": NotFound("Reconciliation 8 not found", id=8),
+        "throttled": RateLimited("Yahoo is rate limiting requests", source="Yahoo"),
+    }
+
+    @app.get("/{name}")
+    async def fail(name: str):
+        raise errors[name]
+
+    client = TestClient(app)
+    response = client.get("/conflict")
+    assert response.status_code == 409
+    assert response.json() == {
+        "detail": "Reconciliation 7 is already signed off",
+        "error": {
+            "code": "conflict",
+            "category": "user",
+            "message": "Reconciliation 7 is already signed off",
+            "context": {"id": 7},
+        },
+    }
+    assert client.get("/missing").status_code == 404
+    throttled = client.get("/throttled")
+    assert (throttled.status_code, throttled.headers["Retry-After"]) == (429, "60")
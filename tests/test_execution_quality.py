@@ -0,0 +1,130 @@
+"""Tests for slippage and execution-quality analytics."""
+
+from datetime import datetime, timezone
+
+import pytest
+
+from sentinel.services.execution_quality import ExecutionQualityService, percentile, slippage_bps
+from sentinel.settings import Settings
+from sentinel.utils.fees import market_of
+
+NOW = int(datetime(2026, 3, 31, 12, 0, tzinfo=timezone.utc).timestamp())
+SUBMITTED = int(datetime(2026, 3, 10, 9, 0, tzinfo=timezone.utc).timestamp())
+
+
+def _service(db) -> ExecutionQualityService:
+    settings = Settings()
+    settings._db = db
+    return ExecutionQualityService(db=db, settings=settings)
+
+
+async def _order(
+    db, order_id: str, symbol: str, action: str, rec: float, quote: float | None, fills: list, method: str | None = None
+) -> None:
+    quantity = sum(q for q, _ in fills)
+    await db.record_trade_decision(
+        symbol,
+        action,
+        quantity,
+        "trading:execute",
+        order_id=order_id,
+        price=rec,
+        quote_price=quote,
+        pricing_method=method,
+        created_at=SUBMITTED,
+    )
+    for i, (qty, price) in enumerate(fills):
+        side = "BUY" if action == "buy" else "SELL"
+        await db.upsert_trade(f"{order_id}-{i}", symbol, side, qty, price, SUBMITTED + 60, {"order_id": order_id})
+
+
+def test_slippage_is_signed_as_a_cost():
+    assert slippage_bps("buy", 10.0, 10.1) == pytest.approx(100)
+    assert slippage_bps("sell", 10.0, 9.9) == pytest.approx(100)
+    assert slippage_bps("sell", 10.0, 10.05) == pytest.approx(-50)
+    assert slippage_bps("buy", None, 10.0) is None
+    assert percentile([5, 1, 3, 2, 4], 0.9) == 5
+    assert (market_of("AAPL.US"), market_of("CASH")) == ("US", "other")
+
+
+@pytest.mark.asyncio
+async def test_report_groups_fills_by_symbol_market_and_hour(temp_db):
+    # Two partial fills at 10.0 and 10.4 average 10.2 against a 10.0 recommendation and a 10.1 ask
+    await _order(temp_db, "1", "AAA.EU", "buy", 10.0, 10.1, [(5, 10.0), (5, 10.4)])
+    await _order(temp_db, "2", "BBB.US", "sell", 20.0, None, [(10, 19.8)])
+    # A fill without a recorded decision is not measured
+    await temp_db.upsert_trade("X", "AAA.EU", "BUY", 1, 50.0, SUBMITTED, {"order_id": "999"})
+
+    report = await _service(temp_db).report(days=30, now=NOW)
+
+    assert report["symbols"]["AAA.EU"]["fills"] == 1
+    assert report["symbols"]["AAA.EU"]["vs_recommendation_bps"] == pytest.approx(200)
+    assert report["symbols"]["AAA.EU"]["vs_quote_bps"] == pytest.approx(99.01, abs=0.01)
+    assert report["symbols"]["BBB.US"]["vs_quote_bps"] is None
+    assert set(report["markets"]) == {"EU", "US"}
+    assert list(report["hours"]) == ["09:00"]
+    assert report["overall"]["fills"] == 2
+    assert report["overall"]["traded_value"] == pytest.approx(102 + 198)
+    # Value-weighted: 102 EUR at 200 bps and 198 EUR at 100 bps
+    assert report["overall"]["vs_recommendation_bps"] == pytest.approx((102 * 200 + 198 * 100) / 300, abs=0.01)
+    assert report["calibration"]["suggested_pct"] is None
+
+    assert await _service(temp_db).report(days=5, now=NOW) == {
+        "overall": {
+            "fills": 0,
+            "traded_value": 0,
+            "fees_eur": 0,
+            "vs_recommendation_bps": None,
+            "vs_quote_bps": None,
+            "fee_bps": None,
+            "net_cost_bps": None,
+        },
+        "symbols": {},
+        "markets": {},
+        "hours": {},
+        "pricing_methods": {},
+        "calibration": {"current_pct": 2.0, "suggested_pct": None, "fills": 0, "p90_bps": None},
+    }
+
+
+@pytest.mark.asyncio
+async def test_calibration_sets_the_limit_band_from_observed_slippage(temp_db):
+    # Slippage against the ask of 0, 10, ..., 90 bps plus one outlier of 300 bps
+    for i in range(10):
+        await _order(temp_db, f"o{i}", "AAA.EU", "buy", 100.0, 100.0, [(1, 100.0 + i * 0.1)])
+    await _order(temp_db, "outlier", "AAA.EU", "buy", 100.0, 100.0, [(1, 103.0)])
+    service = _service(temp_db)
+
+    preview = await service.calibrate(apply=False, days=30, now=NOW)
+
+    assert preview["fills"] == 11
+    assert preview["p90_bps"] == pytest.approx(90)
+    # 0.9% with 25% headroom
+    assert preview["suggested_pct"] == pytest.approx(1.12, abs=0.01)
+    assert preview["applied"] is False
+    assert await temp_db.get_setting("limit_depth_max_slippage_pct") is None
+
+    # The job applies it only with auto-calibration on
+    assert (await service.calibrate(days=30, now=NOW))["applied"] is False
+    await temp_db.set_setting("limit_slippage_auto_calibrate", True)
+    applied = await service.calibrate(days=30, now=NOW)
+    assert applied["applied"] is True
+    assert await temp_db.get_setting("limit_depth_max_slippage_pct") == applied["suggested_pct"]
+
+
+@pytest.mark.asyncio
+async def test_report_compares_pricing_methods_by_slippage_and_fill_rate(temp_db):
+    await _order(temp_db, "1", "AAA.EU", "buy", 10.0, 10.0, [(10, 10.1)], method="market")
+    await _order(temp_db, "2", "AAA.EU", "buy", 10.0, 10.0, [(10, 10.0)], method="midpoint")
+    # Unfilled midpoint order, and a research-mode order that never reached the broker
+    await _order(temp_db, "3", "AAA.EU", "buy", 10.0, 10.0, [], method="midpoint")
+    await _order(temp_db, "RESEARCH-BUY-AAA.EU-10", "AAA.EU", "buy", 10.0, 10.0, [], method="midpoint")
+
+    methods = (await _service(temp_db).report(days=30, now=NOW))["pricing_methods"]
+
+    assert list(methods) == ["market", "midpoint"]
+    assert (methods["market"]["orders"], methods["market"]["fill_rate"]) == (1, 1.0)
+    assert methods["market"]["vs_quote_bps"] == pytest.approx(100)
+    midpoint = methods["midpoint"]
+    assert (midpoint["orders"], midpoint["fills"], midpoint["fill_rate"]) == (2, 1, 0.5)
+    assert midpoint["vs_quote_bps"] == pytest.approx(0)
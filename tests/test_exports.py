@@ -0,0 +1,95 @@
+"""Tests for ledger and portfolio exports."""
+
+import csv
+import io
+from datetime import datetime, timezone
+
+import pytest
+
+from sentinel.services import exports
+from sentinel.services.exports import ExportService
+
+
+def _ts(day: str) -> int:
+    return int(datetime.strptime(day, "%Y-%m-%d").replace(tzinfo=timezone.utc).timestamp())
+
+
+async def _download(db, dataset: str, fmt: str, start: str | None = None, end: str | None = None) -> tuple:
+    media_type, filename, stream = ExportService(db=db).export(dataset, fmt, start, end)
+    chunks = [chunk async for chunk in stream]
+    return media_type, filename, chunks
+
+
+async def _seed(db):
+    await db.upsert_security("AAPL.US", name="Apple & Co", currency="USD")
+    await db.upsert_trade("T1", "AAPL.US", "BUY", 10, 150.0, _ts("2024-01-15"), {}, commission=1.5)
+    await db.upsert_trade("T2", "AAPL.US", "SELL", 4, 170.0, _ts("2024-03-01"), {})
+    await db.upsert_trade("T3", "AAPL.US", "BUY", 1, 160.0, _ts("2024-05-01"), {})
+    await db.upsert_dividend("D1", "AAPL.US", "2024-02-10", 2.4, "USD", 2.2, {})
+    await db.upsert_cash_flow("2024-01-02", "card", 2000.0, "EUR", "Deposit", {"id": 1})
+    await db.upsert_cash_flow("2024-02-20", "tax", -0.36, "USD", None, {"id": 2})
+
+
+@pytest.mark.asyncio
+async def test_csv_export_of_trades_in_range(temp_db):
+    await _seed(temp_db)
+    media_type, filename, chunks = await _download(temp_db, "trades", "csv", "2024-01-01", "2024-03-31")
+
+    assert (media_type, filename) == ("text/csv", "trades_2024-01-01_2024-03-31.csv")
+    rows = list(csv.DictReader(io.StringIO("".join(chunks))))
+    assert [(r["date"], r["broker_trade_id"], r["side"]) for r in rows] == [
+        ("2024-01-15", "T1", "BUY"),
+        ("2024-03-01", "T2", "SELL"),
+    ]
+    assert (rows[0]["name"], rows[0]["currency"], rows[0]["value"]) == ("Apple & Co", "USD", "1500.0")
+
+
+@pytest.mark.asyncio
+async def test_export_streams_in_batches(temp_db, monkeypatch):
+    await _seed(temp_db)
+    monkeypatch.setattr(exports, "BATCH_ROWS", 1)
+
+    _, _, chunks = await _download(temp_db, "trades", "csv")
+
+    assert len(chunks) == 3  # The header goes out with the first trade
+    assert len("".join(chunks).splitlines()) == 4
+
+
+@pytest.mark.asyncio
+async def test_ofx_and_qif_exports(temp_db):
+    await _seed(temp_db)
+
+    _, _, chunks = await _download(temp_db, "trades", "ofx", None, "2024-03-31")
+    ofx = "".join(chunks)
+    assert ofx.count("<BUYSTOCK>") == 1 and ofx.count("<SELLSTOCK>") == 1
+    assert "<TOTAL>-1501.5</TOTAL>" in ofx
+    assert "<SECNAME>Apple &amp; Co</SECNAME>" in ofx
+    assert ofx.rstrip().endswith("</OFX>")
+
+    _, _, chunks = await _download(temp_db, "dividends", "ofx")
+    assert "<INCOMETYPE>DIV</INCOMETYPE><TOTAL>2.4</TOTAL>" in "".join(chunks)
+
+    _, _, chunks = await _download(temp_db, "cash_flows", "qif")
+    qif = "".join(chunks)
+    assert qif.startswith("!Type:Invst\n")
+    assert "D01/02/2024\nNXIn\nT2000.0\nMDeposit (EUR)\n^" in qif
+    assert "D02/20/2024\nNMiscExp\nT0.36\nMtax (USD)\n^" in qif
+
+
+@pytest.mark.asyncio
+async def test_snapshot_export_and_invalid_requests(temp_db):
+    await temp_db.upsert_portfolio_snapshot(
+        _ts("2024-01-15"), {"positions": {"AAPL.US": {"quantity": 10, "value_eur": 1400.0}}, "cash_eur": 50.0}
+    )
+    _, _, chunks = await _download(temp_db, "snapshots", "csv")
+    assert "".join(chunks).splitlines()[1:] == ["2024-01-15,AAPL.US,10,1400.0", "2024-01-15,CASH,,50.0"]
+
+    service = ExportService(db=temp_db)
+    with pytest.raises(ValueError, match="dataset must be"):
+        service.export("orders", "csv")
+    with pytest.raises(ValueError, match="snapshots can be exported as csv"):
+        service.export("snapshots", "ofx")
+    with pytest.raises(ValueError, match="Invalid date"):
+        service.export("trades", "csv", "2024-13-01")
+    with pytest.raises(ValueError, match="after end_date"):
+        service.export("trades", "csv", "2024-02-01", "2024-01-01")
@@ -0,0 +1,81 @@
+"""Tests for holdings outside the broker account."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.portfolio import Portfolio
+from sentinel.services.external_holdings import ExternalHoldingService
+
+
+def _currency():
+    currency = MagicMock()
+    # 1 USD = 0.5 EUR keeps the arithmetic readable
+    currency.to_eur = AsyncMock(side_effect=lambda amount, ccy: amount * (0.5 if ccy == "USD" else 1.0))
+    return currency
+
+
+async def _seed(db):
+    await db.upsert_security("ACME.US", currency="USD", geography="US", industry="Technology")
+    await db.upsert_position("ACME.US", quantity=10, current_price=100.0, currency="USD")
+    await db.set_cash_balances({"EUR": 500.0})
+
+
+@pytest.mark.asyncio
+async def test_holdings_valuations_and_net_worth(temp_db):
+    await _seed(temp_db)
+    service = ExternalHoldingService(db=temp_db, currency=_currency())
+
+    plan = await service.create({"name": "Employer plan", "kind": "stock_plan", "symbol": "ACME.US", "currency": "usd"})
+    flat = await service.create({"name": "Flat", "kind": "real_estate", "value": 1000, "geography": "Europe"})
+    await service.value(plan["id"], 400, source="payroll-feed", valued_at=1_700_000_000)
+    # An older valuation is kept in the history but does not replace the latest one
+    plan = await service.value(plan["id"], 300, valued_at=1_600_000_000)
+
+    assert (plan["currency"], plan["value"], plan["value_source"]) == ("USD", 400, "payroll-feed")
+    assert (plan["geography"], plan["industry"]) == ("US", "Technology")
+    assert [v["value"] for v in plan["valuations"]] == [400, 300]
+    assert flat["value_eur"] == 1000
+
+    worth = await service.net_worth()
+    assert worth["broker_eur"] == 1000  # 10 x 100 USD + 500 EUR cash
+    assert worth["external_eur"] == 1200
+    assert worth["net_worth_eur"] == 2200
+    assert worth["external_by_kind"] == {"real_estate": 1000, "stock_plan": 200}
+
+
+@pytest.mark.asyncio
+async def test_allocations_count_external_exposure(temp_db):
+    await _seed(temp_db)
+    service = ExternalHoldingService(db=temp_db, currency=_currency())
+    await service.create({"name": "Flat", "kind": "real_estate", "value": 1000, "geography": "Europe"})
+    portfolio = Portfolio(db=temp_db, broker=MagicMock(), currency=_currency())
+
+    allocations = await portfolio.get_allocations()
+    # Broker account stays the basis of by_security; geography covers net worth (2000 EUR)
+    assert allocations["by_security"] == {"ACME.US": 0.5}
+    assert allocations["by_geography"] == {"US": 0.25, "Europe": 0.5}
+
+    await temp_db.set_setting("external_holdings_in_allocations", False)
+    assert (await portfolio.get_allocations())["by_geography"] == {"US": 0.5}
+
+
+@pytest.mark.asyncio
+async def test_invalid_holdings(temp_db):
+    service = ExternalHoldingService(db=temp_db, currency=_currency())
+    with pytest.raises(ValueError, match="name is required"):
+        await service.create({"kind": "crypto"})
+    with pytest.raises(ValueError, match="kind must be"):
+        await service.create({"name": "Boat", "kind": "boat"})
+    with pytest.raises(ValueError, match="Security NOPE.US not found"):
+        await service.create({"name": "Plan", "symbol": "NOPE.US"})
+
+    holding = await service.create({"name": "Wallet", "kind": "crypto"})
+    with pytest.raises(ValueError, match="Unknown field value"):
+        await service.update(holding["id"], {"value": 5})
+    with pytest.raises(ValueError, match="negative"):
+        await service.value(holding["id"], -1)
+    with pytest.raises(LookupError):
+        await service.value(holding["id"] + 1, 1)
+    await service.delete(holding["id"])
+    assert await service.list() == []
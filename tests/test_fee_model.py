@@ -0,0 +1,124 @@
+"""Tests for the per-market fee schedule and its use in execution-quality analytics."""
+
+from unittest.mock import AsyncMock
+
+import pytest
+
+from sentinel.services.execution_quality import ExecutionQualityService
+from sentinel.settings import Settings
+from sentinel.utils.fees import FeeCalculator, FeeSchedule, parse_fee_schedule
+
+SCHEDULE = {
+    "US": {
+        "fixed": 1.0,
+        "tiers": [{"up_to": None, "percent": 0.05}, {"up_to": 5000, "percent": 0.25}],
+    },
+    "EU": {"fixed": 3.0},
+}
+
+
+def _settings(values: dict) -> AsyncMock:
+    settings = AsyncMock()
+    settings.get = AsyncMock(side_effect=lambda key, default: values.get(key, default))
+    return settings
+
+
+def test_schedule_applies_market_tiers_and_fx_spread():
+    schedule = FeeSchedule(fixed=2.0, pct=0.002, markets=parse_fee_schedule(SCHEDULE), fx_spread_pct=0.001)
+
+    # Tiers are sorted by up_to; the band's rate applies to the whole value
+    assert schedule.rates(1000, "AAPL.US", "EUR") == (1.0, 0.0025)
+    assert schedule.rates(10000, "AAPL.US", "EUR") == (1.0, 0.0005)
+    # A market with only a fixed fee keeps the flat percentage
+    assert schedule.rates(1000, "SAP.EU", "EUR") == (3.0, 0.002)
+    # Unlisted markets and unknown symbols use the flat fees
+    assert schedule.cost(1000, "7203.JP", "EUR") == pytest.approx(4.0)
+    assert schedule.cost(1000) == pytest.approx(4.0)
+    # Non-EUR trades pay the conversion spread on top
+    assert schedule.cost(1000, "AAPL.US", "USD") == pytest.approx(1.0 + 1000 * 0.0035)
+
+
+def test_parse_fee_schedule_rejects_malformed_values():
+    assert parse_fee_schedule(None) == {}
+    for bad in (
+        ["US"],
+        {"US": 1.0},
+        {"US": {"fixed": -1}},
+        {"US": {"tiers": [{"up_to": 100}]}},
+        {"US": {"tiers": [{"up_to": 0, "percent": 0.1}]}},
+    ):
+        with pytest.raises(ValueError):
+            parse_fee_schedule(bad)
+
+
+@pytest.mark.asyncio
+async def test_calculator_uses_the_schedule_per_trade():
+    calculator = FeeCalculator(
+        settings=_settings(
+            {
+                "transaction_fee_fixed": 2.0,
+                "transaction_fee_percent": 0.2,
+                "fee_schedule": SCHEDULE,
+                "fx_conversion_spread_pct": 0.1,
+            }
+        )
+    )
+
+    assert await calculator.calculate(1000.0) == pytest.approx(4.0)
+    assert await calculator.calculate(1000.0, "AAPL.US", "USD") == pytest.approx(4.5)
+
+    batch = await calculator.calculate_batch(
+        [
+            {"action": "buy", "value_eur": 1000.0, "symbol": "AAPL.US", "currency": "USD"},
+            {"action": "sell", "value_eur": -1000.0, "symbol": "SAP.EU", "currency": "EUR"},
+        ]
+    )
+    assert batch["buy_fees"] == pytest.approx(4.5)
+    assert batch["sell_fees"] == pytest.approx(5.0)
+    assert batch["total_fees"] == pytest.approx(9.5)
+
+
+@pytest.mark.asyncio
+async def test_malformed_stored_schedule_falls_back_to_flat_fees():
+    calculator = FeeCalculator(settings=_settings({"fee_schedule": "not a schedule"}))
+
+    assert await calculator.calculate(1000.0, "AAPL.US") == pytest.approx(4.0)
+
+
+@pytest.mark.asyncio
+async def test_execution_quality_adds_commission_to_slippage(temp_db):
+    submitted = 1773133200
+    for order_id, commission in (("1", 3.0), ("2", 0.0)):
+        await temp_db.record_trade_decision(
+            "AAA.EU",
+            "buy",
+            10,
+            "trading:execute",
+            order_id=order_id,
+            price=100.0,
+            quote_price=100.0,
+            created_at=submitted,
+        )
+        await temp_db.upsert_trade(
+            f"t{order_id}",
+            "AAA.EU",
+            "BUY",
+            10,
+            100.5,
+            submitted + 60,
+            {"order_id": order_id},
+            commission=commission,
+        )
+    settings = Settings()
+    settings._db = temp_db
+    service = ExecutionQualityService(db=temp_db, settings=settings)
+
+    fills = {f["order_id"]: f for f in await service.fills(days=30, now=submitted + 86400)}
+
+    # Reported commission: 3 EUR on 1005 EUR is 29.85 bps on top of 50 bps slippage
+    assert fills["1"]["fee_estimated"] is False
+    assert fills["1"]["fee_bps"] == pytest.approx(29.85, abs=0.01)
+    assert fills["1"]["net_cost_bps"] == pytest.approx(79.85, abs=0.01)
+    # No commission reported: estimated from the flat fees (2 EUR + 0.2%)
+    assert fills["2"]["fee_estimated"] is True
+    assert fills["2"]["fee_eur"] == pytest.approx(4.01)
@@ -0,0 +1,110 @@
+"""Tests for fractional share support (quantity rounding, Security orders, schema)."""
+
+import os
+import sqlite3
+import tempfile
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.database import Database
+from sentinel.planner.rebalance_rules import get_forced_opportunity_exit
+from sentinel.security import Security
+from sentinel.utils.quantity import FRACTIONAL_QUANTITY_STEP, ceil_to_lot, floor_to_lot, lot_step
+
+
+def test_lot_step_uses_fractional_step_only_when_flagged():
+    assert lot_step(10, supports_fractional=False) == 10
+    assert lot_step(None) == 1
+    assert lot_step(10, supports_fractional=1) == FRACTIONAL_QUANTITY_STEP
+
+
+def test_whole_lot_rounding_keeps_integers():
+    assert floor_to_lot(25.9, 10) == 20
+    assert isinstance(floor_to_lot(25.9, 10), int)
+    assert ceil_to_lot(21, 10) == 30
+    assert floor_to_lot(-3, 1) == 0
+
+
+def test_fractional_rounding_to_step():
+    step = lot_step(1, supports_fractional=True)
+    assert floor_to_lot(1.23456, step) == pytest.approx(1.2345)
+    assert floor_to_lot(0.3, step) == pytest.approx(0.3)
+    assert ceil_to_lot(1.23451, step) == pytest.approx(1.2346)
+
+
+def _security(data: dict, position: dict | None = None) -> Security:
+    db = MagicMock()
+    db.get_trades = AsyncMock(return_value=[])
+    db.upsert_position = AsyncMock()
+    db.get_cash_balances = AsyncMock(return_value={"EUR": 10000.0})
+    broker = MagicMock()
+    broker.get_quote = AsyncMock(return_value={"price": 100.00})
+    broker.buy = AsyncMock(return_value="ORDER123")
+    broker.sell = AsyncMock(return_value="ORDER456")
+    security = Security("TEST", db=db, broker=broker)
+    security._data = {"currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1, **data}
+    security._position = position or {"current_price": 100.00}
+    return security
+
+
+@pytest.mark.asyncio
+async def test_fractional_security_buys_fractional_quantity():
+    security = _security({"supports_fractional": 1})
+    await security.buy(0.123456)
+    security._broker.buy.assert_called_with("TEST", pytest.approx(0.1234), price=None)
+
+
+@pytest.mark.asyncio
+async def test_whole_share_security_rejects_fractional_quantity():
+    security = _security({"supports_fractional": 0})
+    with pytest.raises(ValueError, match="at least"):
+        await security.buy(0.5)
+
+
+@pytest.mark.asyncio
+async def test_fractional_sell_checks_owned_quantity():
+    security = _security({"supports_fractional": 1}, {"quantity": 1.5, "current_price": 100.0})
+    with pytest.raises(ValueError, match="only own"):
+        await security.sell(1.75)
+    await security.sell(1.5)
+    security._broker.sell.assert_called_with("TEST", pytest.approx(1.5), price=None)
+
+
+def test_forced_exit_sizes_fractional_positions():
+    exit_spec = get_forced_opportunity_exit(
+        signal={"lot_size": FRACTIONAL_QUANTITY_STEP},
+        state={},
+        current_qty=2.5,
+        price=110.0,
+        avg_cost=100.0,
+        as_of_date=None,
+        time_stop_days=90,
+    )
+    assert exit_spec["reason_code"] == "scaleout_10"
+    assert exit_spec["quantity"] == pytest.approx(0.75)
+
+
+@pytest.mark.asyncio
+async def test_migration_adds_supports_fractional_to_existing_db():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    legacy = sqlite3.connect(path)
+    legacy.execute("CREATE TABLE securities (symbol TEXT PRIMARY KEY, name TEXT, min_lot INTEGER DEFAULT 1)")
+    legacy.execute("INSERT INTO securities (symbol, name) VALUES ('OLD.EU', 'Legacy')")
+    legacy.commit()
+    legacy.close()
+
+    db = Database(path)
+    try:
+        await db.connect()
+        security = await db.get_security("OLD.EU")
+        assert security["supports_fractional"] == 0
+        await db.upsert_security("OLD.EU", supports_fractional=1)
+        assert (await db.get_security("OLD.EU"))["supports_fractional"] == 1
+    finally:
+        await db.close()
+        db.remove_from_cache()
+        for ext in ["", "-wal", "-shm"]:
+            if os.path.exists(path + ext):
+                os.unlink(path + ext)
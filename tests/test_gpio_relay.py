@@ -0,0 +1,112 @@
+"""Tests for the GPIO trading indicator relay and kill switch."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.led.relay import SysfsPin, TradingRelay, trading_indicator_state
+
+
+class FakePin:
+    def __init__(self):
+        self.value = False
+        self.writes = []
+        self.closed = False
+
+    def write(self, value):
+        self.value = value
+        self.writes.append(value)
+
+    def read(self):
+        return self.value
+
+    def close(self):
+        self.closed = True
+
+
+def _relay(settings: dict, schedule: dict | None = None, connected: bool = True):
+    store = dict(settings)
+    mock_settings = MagicMock()
+    mock_settings.get = AsyncMock(side_effect=lambda key, default=None: store.get(key, default))
+    mock_settings.set = AsyncMock(side_effect=lambda key, value: store.__setitem__(key, value))
+    db = MagicMock()
+    db.get_job_schedule = AsyncMock(return_value=schedule)
+    db.add_notification = AsyncMock()
+    pins = {}
+
+    def factory(chip, line, output):
+        pins[line] = FakePin()
+        return pins[line]
+
+    relay = TradingRelay(broker=MagicMock(connected=connected), db=db, settings=mock_settings, pin_factory=factory)
+    return relay, pins, store, db
+
+
+def test_indicator_state():
+    assert trading_indicator_state("research", True, None)[0] == "paused"
+    assert trading_indicator_state("live", False, None) == ("failure", "broker not connected")
+    assert trading_indicator_state("live", True, {"consecutive_failures": 2})[0] == "failure"
+    assert trading_indicator_state("live", True, {"quarantined_at": 1, "quarantine_reason": "auth"})[1].endswith("auth")
+    assert trading_indicator_state("live", True, {"consecutive_failures": 0}) == ("on", "live trading enabled")
+
+
+@pytest.mark.asyncio
+async def test_relay_follows_trading_state():
+    relay, pins, store, _ = _relay({"gpio_relay_pin": 17, "gpio_relay_active_low": True, "trading_mode": "live"})
+    assert await relay._open_pins()
+    assert await relay.refresh() == "on"
+    assert pins[17].value is False  # active low: asserted
+
+    store["trading_mode"] = "research"
+    assert await relay.refresh() == "paused"
+    assert pins[17].value is True
+
+    relay._close_pins()
+    assert pins[17].closed
+
+
+@pytest.mark.asyncio
+async def test_kill_switch_press_is_debounced_and_toggles_mode():
+    relay, pins, store, db = _relay({"gpio_relay_pin": 17, "gpio_kill_switch_pin": 27, "trading_mode": "live"})
+    assert await relay._open_pins()
+    button = pins[27]
+    button.value = True  # active low: released
+
+    await relay.poll_kill_switch()
+    button.value = False  # pressed, but a single sample is a bounce
+    await relay.poll_kill_switch()
+    button.value = True
+    await relay.poll_kill_switch()
+    assert store["trading_mode"] == "live"
+
+    button.value = False
+    for _ in range(TradingRelay.DEBOUNCE_SAMPLES):
+        await relay.poll_kill_switch()
+    assert store["trading_mode"] == "research"
+    assert pins[17].value is False
+    assert db.add_notification.await_args.args[2] == "Trading paused by kill switch"
+
+    # Holding the button does not toggle again
+    for _ in range(10):
+        await relay.poll_kill_switch()
+    assert store["trading_mode"] == "research"
+
+
+@pytest.mark.asyncio
+async def test_disabled_or_unwired_relay_does_nothing():
+    relay, pins, _, _ = _relay({"gpio_relay_enabled": False, "gpio_relay_pin": 17})
+    await relay.start()
+    assert not relay.is_running and not pins
+
+    relay, pins, _, _ = _relay({"gpio_relay_enabled": True})
+    await relay.start()
+    assert not relay.is_running and not pins
+
+
+def test_sysfs_pin(tmp_path):
+    (tmp_path / "export").write_text("")
+    (tmp_path / "gpio5").mkdir()
+    pin = SysfsPin(5, output=True, root=tmp_path)
+    assert (tmp_path / "gpio5" / "direction").read_text() == "out"
+    pin.write(True)
+    assert pin.read()
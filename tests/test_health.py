@@ -0,0 +1,86 @@
+"""Tests for the liveness, readiness and deep health checks."""
+
+import os
+import socket
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+
+from sentinel.led.display import StateManager, parse_indicator_map, resolve_indicators
+from sentinel.services.health import HealthService
+from sentinel.settings import Settings
+from sentinel.utils.systemd import sd_notify, watchdog_interval
+
+
+def _service(db, connected: bool = True) -> HealthService:
+    settings = Settings()
+    settings._db = db
+    broker = MagicMock()
+    broker.connected = connected
+    return HealthService(db=db, broker=broker, settings=settings)
+
+
+def test_liveness_needs_no_dependencies():
+    live = HealthService.liveness()
+    assert live["status"] == "ok"
+    assert live["uptime_seconds"] >= 0
+
+
+@pytest.mark.asyncio
+async def test_readiness_requires_database_migrations_and_broker(temp_db):
+    assert await temp_db.pending_migrations() == []
+    ready = await _service(temp_db).readiness()
+    assert ready["ready"] is True
+    assert set(ready["checks"]) == {"database", "migrations", "broker"}
+
+    not_ready = await _service(temp_db, connected=False).readiness()
+    assert not_ready["ready"] is False
+    assert not_ready["checks"]["broker"] == {"ok": False, "detail": "not connected", "required": True}
+
+    await temp_db.conn.execute("DROP TABLE score_state")
+    await temp_db.conn.execute("ALTER TABLE job_schedules DROP COLUMN quarantined_at")
+    assert await temp_db.pending_migrations() == ["score_state", "job_schedules.quarantined_at"]
+    migrations = (await _service(temp_db).readiness())["checks"]["migrations"]
+    assert migrations["ok"] is False
+    assert "score_state" in migrations["detail"]
+
+
+@pytest.mark.asyncio
+async def test_deep_check_degrades_on_failing_jobs_and_maps_to_leds(temp_db):
+    await temp_db.seed_default_job_schedules()
+    service = _service(temp_db)
+    deep = await service.deep()
+    assert deep["status"] == "healthy"
+    assert (deep["broker_connected"], deep["trading_mode"]) == (True, "research")
+    assert await service.display_states() == set()
+
+    await temp_db.conn.execute("UPDATE job_schedules SET consecutive_failures = 2 WHERE job_type = 'sync:prices'")
+    await temp_db.conn.commit()
+    deep = await service.deep()
+    assert deep["status"] == "degraded"
+    assert deep["jobs"] == {"quarantined": [], "failing": ["sync:prices"]}
+    assert await service.display_states() == {"degraded"}
+    assert await _service(temp_db, connected=False).display_states() == {"not_ready"}
+
+    manager = StateManager(db=temp_db, settings=Settings(), health=_service(temp_db, connected=False))
+    assert "not_ready" in await manager._health_states()
+    leds = resolve_indicators({"heartbeat", "sync_failure", "degraded", "not_ready"}, parse_indicator_map({}))
+    assert leds[0]["state"] == "not_ready"
+
+
+def test_sd_notify_sends_a_datagram_to_the_notify_socket(monkeypatch):
+    monkeypatch.delenv("NOTIFY_SOCKET", raising=False)
+    monkeypatch.delenv("WATCHDOG_USEC", raising=False)
+    assert sd_notify("READY=1") is False
+    assert watchdog_interval() is None
+
+    with tempfile.TemporaryDirectory() as tmp:
+        path = os.path.join(tmp, "notify")
+        with socket.socket(socket.AF_UNIX, socket.SOCK_DGRAM) as server:
+            server.bind(path)
+            monkeypatch.setenv("NOTIFY_SOCKET", path)
+            monkeypatch.setenv("WATCHDOG_USEC", "120000000")
+            assert sd_notify("WATCHDOG=1\nSTATUS=Ready") is True
+            assert server.recv(1024) == b"WATCHDOG=1\nSTATUS=Ready"
+    assert watchdog_interval() == 60.0
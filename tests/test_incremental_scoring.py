@@ -0,0 +1,87 @@
+"""Tests for dirty-tracked, parallel security scoring."""
+
+from concurrent.futures import ThreadPoolExecutor
+
+import pytest
+
+from sentinel.planner.scoring import ScoringParams, score_closes, score_securities
+
+SYMBOLS = ["CCC.EU", "AAA.EU", "BBB.EU"]
+
+
+def _bars(start: float, step: float, count: int = 200) -> list[dict]:
+    return [{"date": f"2025-{1 + i // 28:02d}-{1 + i % 28:02d}", "close": start + step * i} for i in range(count)]
+
+
+async def _seed(db):
+    for i, symbol in enumerate(SYMBOLS):
+        await db.upsert_security(symbol, name=symbol, currency="EUR")
+        await db.save_prices(symbol, _bars(100.0 + i, 0.5 - 0.4 * i))
+
+
+@pytest.mark.asyncio
+async def test_data_changes_mark_scores_dirty(temp_db):
+    await _seed(temp_db)
+    await temp_db.update_security_metadata("AAA.EU", {"lot": "1"})
+    await temp_db.mark_scores_dirty(["AAA.EU"], "dividends")
+
+    states = await temp_db.get_score_states(SYMBOLS)
+    assert states["AAA.EU"]["dirty"] == ["dividends", "metadata", "prices"]
+    assert await temp_db.get_score_state_summary() == {
+        "cached": 0,
+        "dirty": 3,
+        "dirty_reasons": {"prices": 3, "metadata": 1, "dividends": 1},
+    }
+
+    # A flag raised after the state was read (while scores were being computed) survives the save
+    seen = {symbol: state["dirty_rev"] for symbol, state in states.items()}
+    await temp_db.mark_scores_dirty(["BBB.EU"], "prices")
+    await temp_db.save_score_states({s: {"signal": {}, "skipped": []} for s in ("AAA.EU", "BBB.EU")}, "k", seen)
+    states = await temp_db.get_score_states(SYMBOLS)
+    assert (states["AAA.EU"]["dirty"], states["AAA.EU"]["config_key"]) == ([], "k")
+    assert states["BBB.EU"]["dirty"] == ["prices"]
+
+
+@pytest.mark.asyncio
+async def test_only_dirty_or_reconfigured_scores_are_recomputed(temp_db):
+    await _seed(temp_db)
+    params = ScoringParams()
+
+    first, stats = await score_securities(temp_db, SYMBOLS, params)
+    assert list(first) == SYMBOLS
+    assert (stats["cached"], stats["recomputed"]) == (0, 3)
+
+    again, stats = await score_securities(temp_db, SYMBOLS, params)
+    assert again == first
+    assert (stats["cached"], stats["recomputed"]) == (3, 0)
+
+    await temp_db.save_prices("BBB.EU", [{"date": "2025-08-01", "close": 1.0}])
+    _, stats = await score_securities(temp_db, SYMBOLS, params)
+    assert (stats["cached"], stats["recomputed"]) == (2, 1)
+
+    _, stats = await score_securities(temp_db, SYMBOLS, ScoringParams(memory_max_boost=0.3))
+    assert stats["recomputed"] == 3
+
+    # Point-in-time runs never use or write the cache
+    await temp_db.mark_scores_dirty(["AAA.EU"], "prices")
+    _, stats = await score_securities(temp_db, SYMBOLS, params, as_of_date="2025-05-01")
+    assert stats["recomputed"] == 3
+    assert (await temp_db.get_score_states(["AAA.EU"]))["AAA.EU"]["dirty"] == ["prices"]
+
+
+@pytest.mark.asyncio
+async def test_worker_pool_output_matches_inline_and_keeps_symbol_order(temp_db):
+    await _seed(temp_db)
+    params = ScoringParams()
+
+    inline, _ = await score_securities(temp_db, SYMBOLS, params, as_of_date="2026-01-01")
+    with ThreadPoolExecutor(max_workers=3) as pool:
+        pooled, stats = await score_securities(
+            temp_db, SYMBOLS, params, as_of_date="2026-01-01", workers=3, executor=pool
+        )
+
+    assert list(pooled) == SYMBOLS
+    assert pooled == inline
+    assert stats["workers"] == 3
+    closes = [bar["close"] for bar in _bars(100.0, 0.5)]
+    assert inline["CCC.EU"] == score_closes(closes, params)
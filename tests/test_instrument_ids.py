@@ -0,0 +1,65 @@
+"""Tests for canonical instrument IDs (ISIN, Tradernet ticker, Yahoo symbol)."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.jobs.tasks import sync_trades
+from sentinel.services.instrument_ids import InstrumentIdService
+
+APPLE = "US0378331005"
+SAP = "DE0007164600"
+
+
+@pytest.mark.asyncio
+async def test_mappings_follow_securities_and_keep_history(temp_db):
+    await temp_db.upsert_security("OLD.US", name="Apple", isin=APPLE)
+    service = InstrumentIdService(db=temp_db)
+    await service.set_id("OLD.US", "yahoo", "AAPL")
+
+    await temp_db.rename_symbol("OLD.US", "NEW.US")
+
+    ids = await service.identifiers("NEW.US")
+    assert ids["ids"] == {"isin": APPLE, "tradernet": "NEW.US", "yahoo": "AAPL"}
+    assert [(h["scheme"], h["value"]) for h in ids["history"]] == [("tradernet", "OLD.US")]
+    for identifier in ("OLD.US", APPLE, "AAPL", "NEW.US"):
+        assert await service.resolve(identifier) == "NEW.US"
+    assert await service.resolve("OLD.US", scheme="yahoo") is None
+    assert await service.yahoo_symbols(["NEW.US", "MSFT.US"]) == {"NEW.US": "AAPL", "MSFT.US": "MSFT"}
+
+
+@pytest.mark.asyncio
+async def test_collisions_never_resolve_silently(temp_db):
+    await temp_db.upsert_security("SAP.EU", name="SAP", isin=SAP)
+    await temp_db.upsert_security("SAP.US", name="SAP ADR")
+    service = InstrumentIdService(db=temp_db)
+    assert await service.resolve(SAP) == "SAP.EU"
+
+    # A second listing with the same ISIN makes the ISIN ambiguous
+    await service.set_id("SAP.US", "isin", SAP.lower())
+    assert await service.resolve(SAP) is None
+    assert await service.collisions() == [{"scheme": "isin", "value": SAP, "symbols": ["SAP.EU", "SAP.US"]}]
+
+    await service.set_id("SAP.EU", "yahoo", "SAP.DE")
+    with pytest.raises(ValueError, match="already mapped to SAP.EU"):
+        await service.set_id("SAP.US", "yahoo", "SAP.DE")
+    with pytest.raises(ValueError, match="Invalid ISIN"):
+        await service.set_id("SAP.US", "isin", "DE0007164601")
+    with pytest.raises(ValueError):
+        await service.set_id("SAP.US", "tradernet", "SAP2.US")
+
+
+@pytest.mark.asyncio
+async def test_trades_under_former_ticker_join_current_security(temp_db):
+    await temp_db.upsert_security("OLD.US", name="Apple", isin=APPLE)
+    await temp_db.rename_symbol("OLD.US", "NEW.US")
+    broker = MagicMock()
+    broker.connected = True
+    broker.get_trades_history = AsyncMock(
+        return_value=[{"id": "1", "symbol": "OLD.US", "side": "BUY", "q": 2, "p": 100.0, "date": "2024-03-01"}]
+    )
+
+    await sync_trades(temp_db, broker)
+
+    trades = await temp_db.get_trades()
+    assert [t["symbol"] for t in trades] == ["NEW.US"]
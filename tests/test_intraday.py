@@ -0,0 +1,92 @@
+"""Tests for intraday snapshots and their downsampling retention."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.database.intraday import downsample_bars
+from sentinel.services.intraday import DAY_SECONDS, IntradayService
+from sentinel.settings import Settings
+
+# 2026-03-02 00:00:00 UTC
+DAY0 = 1772409600
+
+
+def _service(db, quotes: dict | None = None) -> IntradayService:
+    settings = Settings()
+    settings._db = db
+    broker = MagicMock()
+    broker.get_quotes = AsyncMock(return_value=quotes or {})
+    return IntradayService(db=db, settings=settings, broker=broker)
+
+
+def _bar(ts: int, price: float, symbol: str = "AAA.EU") -> dict:
+    return {"symbol": symbol, "ts": ts, "open": price, "high": price, "low": price, "close": price, "bid": None}
+
+
+def test_downsample_bars_keeps_ohlc_and_sample_counts():
+    rows = [_bar(DAY0 + 1800, 11.0), _bar(DAY0 + 900, 10.0), _bar(DAY0 + 2700, 9.0), _bar(DAY0 + 3600, 12.0)]
+    rows.append({**_bar(DAY0 + 3700, 5.0, "BBB.EU"), "bid": 4.9})
+
+    bars = downsample_bars(rows, 3600)
+
+    assert [(b["symbol"], b["ts"]) for b in bars] == [
+        ("AAA.EU", DAY0),
+        ("AAA.EU", DAY0 + 3600),
+        ("BBB.EU", DAY0 + 3600),
+    ]
+    first = bars[0]
+    assert (first["open"], first["high"], first["low"], first["close"], first["samples"]) == (10.0, 11.0, 9.0, 9.0, 3)
+    assert bars[2]["bid"] == 4.9
+    # Folding hourly bars into a day adds up their samples
+    assert downsample_bars(bars[:2], 86400)[0]["samples"] == 4
+
+
+@pytest.mark.asyncio
+async def test_snapshot_is_opt_in_and_covers_held_positions(temp_db):
+    await temp_db.upsert_security("AAA.EU", name="A", currency="EUR")
+    await temp_db.upsert_security("BBB.EU", name="B", currency="EUR")
+    await temp_db.upsert_position("AAA.EU", quantity=5)
+    await temp_db.upsert_position("BBB.EU", quantity=0)
+    quotes = {"AAA.EU": {"price": 10.5, "bid": 10.4, "ask": 10.6}}
+    service = _service(temp_db, quotes)
+
+    assert await service.snapshot(now=DAY0) == {"enabled": False, "saved": 0}
+
+    await temp_db.set_setting("intraday_snapshots_enabled", True)
+    assert await service.snapshot(now=DAY0) == {"enabled": True, "saved": 1}
+    service._broker.get_quotes.assert_awaited_with(["AAA.EU"])
+    [bar] = await service.history("AAA.EU", "raw", now=DAY0)
+    assert (bar["close"], bar["bid"], bar["ask"], bar["samples"]) == (10.5, 10.4, 10.6, 1)
+    with pytest.raises(ValueError):
+        await service.history("AAA.EU", "minute")
+
+
+@pytest.mark.asyncio
+async def test_compaction_applies_raw_hourly_and_daily_retention(temp_db):
+    service = _service(temp_db)
+    # Two snapshots in the first hour of DAY0, one in the second, one the next day
+    for offset, price in ((900, 10.0), (1800, 11.0), (3600 + 900, 12.0), (DAY_SECONDS + 900, 13.0)):
+        await temp_db.save_intraday_snapshots([{"symbol": "AAA.EU", "price": price}], DAY0 + offset)
+
+    # Eight days later, DAY0's snapshots are past the 7-day raw retention; the next day's are not
+    folded = await service.compact(now=DAY0 + 8 * DAY_SECONDS + 600)
+
+    assert folded == {"raw": 3, "hourly": 0}
+    bars = await temp_db.get_intraday_prices("AAA.EU")
+    assert [(b["resolution"], b["ts"], b["close"], b["samples"]) for b in bars] == [
+        ("hourly", DAY0, 11.0, 2),
+        ("hourly", DAY0 + 3600, 12.0, 1),
+        ("raw", DAY0 + DAY_SECONDS + 900, 13.0, 1),
+    ]
+
+    # A year on, everything is folded into daily bars, which are kept forever
+    folded = await service.compact(now=DAY0 + 400 * DAY_SECONDS)
+    assert folded == {"raw": 1, "hourly": 3}
+    bars = await temp_db.get_intraday_prices("AAA.EU")
+    assert [(b["resolution"], b["ts"], b["open"], b["close"], b["samples"]) for b in bars] == [
+        ("daily", DAY0, 10.0, 12.0, 3),
+        ("daily", DAY0 + DAY_SECONDS, 13.0, 13.0, 1),
+    ]
+    stats = await service.stats()
+    assert stats["resolutions"] == {"daily": {"bars": 2, "symbols": 1, "oldest": DAY0, "newest": DAY0 + DAY_SECONDS}}
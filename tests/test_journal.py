@@ -0,0 +1,80 @@
+"""Tests for the trade journal."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.services.journal import JournalService
+
+
+def _planner(*recommendations):
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=list(recommendations))
+    return planner
+
+
+def _rec(symbol: str, action: str) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=500.0,
+        value_delta_eur=500.0,
+        quantity=5,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.4,
+        priority=1.0,
+        reason="Underweight",
+    )
+
+
+@pytest.mark.asyncio
+async def test_annotate_trades_and_recommendations_then_search(temp_db):
+    trade_id = await temp_db.upsert_trade("T1", "AAPL.US", "BUY", 10, 150.0, 1_700_000_000, {})
+    service = JournalService(db=temp_db, planner=_planner(_rec("MSFT.US", "sell")))
+
+    trade_entry = await service.annotate_trade(
+        trade_id, "Bought the dip after earnings", tags=["Earnings", " dip "], conviction=4, created_by="alice"
+    )
+    rec_entry = await service.annotate_recommendation(
+        "MSFT.US", "sell", "Overrode: still like the cloud story", tags="override"
+    )
+
+    assert trade_entry["kind"] == "trade"
+    assert (trade_entry["symbol"], trade_entry["action"], trade_entry["trade_id"]) == ("AAPL.US", "buy", trade_id)
+    assert trade_entry["tags"] == ["dip", "earnings"]
+    assert rec_entry["recommendation"]["quantity"] == 5
+    assert [e["id"] for e in await service.search(text="cloud")] == [rec_entry["id"]]
+    assert [e["id"] for e in await service.search(tag="EARNINGS")] == [trade_entry["id"]]
+    assert [e["id"] for e in await service.search(min_conviction=3)] == [trade_entry["id"]]
+    assert [e["id"] for e in await service.search(kind="recommendation")] == [rec_entry["id"]]
+
+    updated = await service.update(trade_entry["id"], {"conviction": 2, "tags": []})
+    assert (updated["conviction"], updated["tags"], updated["note"]) == (2, [], "Bought the dip after earnings")
+    await service.delete(rec_entry["id"])
+    assert [e["id"] for e in await service.search()] == [trade_entry["id"]]
+
+
+@pytest.mark.asyncio
+async def test_invalid_annotations(temp_db):
+    service = JournalService(db=temp_db, planner=_planner())
+    with pytest.raises(LookupError):
+        await service.annotate_trade(99, "note")
+    with pytest.raises(LookupError, match="No current buy recommendation"):
+        await service.annotate_recommendation("AAPL.US", "buy", "note")
+
+    trade_id = await temp_db.upsert_trade("T1", "AAPL.US", "BUY", 10, 150.0, 1_700_000_000, {})
+    with pytest.raises(ValueError, match="required"):
+        await service.annotate_trade(trade_id, "  ")
+    with pytest.raises(ValueError, match="conviction"):
+        await service.annotate_trade(trade_id, "note", conviction=6)
+    with pytest.raises(ValueError, match="Cannot change symbol"):
+        await service.update(1, {"symbol": "MSFT.US"})
+    with pytest.raises(LookupError):
+        await service.delete(42)
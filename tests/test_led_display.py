@@ -0,0 +1,184 @@
+"""Tests for configurable indicator LED states."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from sentinel.led.display import (
+    COLORS,
+    PATTERNS,
+    DisplayDriver,
+    StateManager,
+    open_screen_driver,
+    parse_indicator_map,
+    resolve_indicators,
+    summary_lines,
+)
+
+
+def test_defaults_reproduce_builtin_layout():
+    leds = resolve_indicators({"heartbeat", "pnl_up", "pnl_up_strong", "recommendations"}, parse_indicator_map({}))
+
+    assert [led["state"] for led in leds] == ["heartbeat", "pnl_up_strong", "pnl_up", None, "recommendations"]
+    assert leds[0]["code"] == PATTERNS["heartbeat"]
+    assert leds[4]["color"] == COLORS["blue"]
+    assert leds[3]["pattern"] == "off"
+
+
+def test_highest_priority_state_wins_an_led():
+    mapping = parse_indicator_map({})
+    leds = resolve_indicators({"heartbeat", "sync_failure", "recommendations", "pending_approval"}, mapping)
+
+    assert leds[0]["state"] == "sync_failure"
+    assert leds[4]["state"] == "pending_approval"
+
+
+def test_overrides_merge_into_defaults():
+    mapping = parse_indicator_map(
+        {
+            "market_open": {"led": 1, "color": "#00aaff", "pattern": "solid", "priority": 20},
+            "pnl_up_strong": {"color": "cyan"},
+            "heartbeat": None,
+        }
+    )
+
+    assert mapping["heartbeat"] is None
+    assert mapping["pnl_up_strong"] == {"led": 1, "color": COLORS["cyan"], "pattern": "blink", "priority": 10}
+    leds = resolve_indicators({"heartbeat", "market_open", "pnl_up_strong"}, mapping)
+    assert leds[0]["state"] is None
+    assert leds[1] == {"led": 1, "state": "market_open", "color": 0x00AAFF, "pattern": "solid", "code": 1}
+
+
+@pytest.mark.parametrize(
+    "overrides",
+    [
+        {"moon_phase": {"led": 0}},
+        {"market_open": {"led": 5}},
+        {"risk_breach": {"pattern": "strobe"}},
+        {"risk_breach": {"color": "mauve"}},
+        ["heartbeat"],
+    ],
+)
+def test_invalid_overrides_are_rejected(overrides):
+    with pytest.raises(ValueError):
+        parse_indicator_map(overrides)
+
+
+def _db(notifications=(), sync_runs=()):
+    db = MagicMock()
+    db.get_notifications = AsyncMock(return_value=list(notifications))
+    db.get_job_history_for_type = AsyncMock(return_value=list(sync_runs))
+    db.get_setting = AsyncMock(return_value=None)
+    return db
+
+
+def _settings(values):
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    return settings
+
+
+@pytest.mark.asyncio
+async def test_frame_combines_state_sources():
+    db = _db(
+        notifications=[{"category": "balance"}],
+        sync_runs=[
+            {"job_type": "sync:prices", "status": "failed"},
+            {"job_type": "sync:quotes", "status": "completed"},
+            {"job_type": "sync:prices", "status": "completed"},
+        ],
+    )
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=[MagicMock()])
+    market = MagicMock()
+    market.ensure_fresh = AsyncMock()
+    market.is_any_market_open.return_value = True
+    manager = StateManager(
+        db=db,
+        settings=_settings({"led_indicator_map": {"market_open": {"led": 2, "color": "white", "priority": 99}}}),
+        planner=planner,
+        market_checker=market,
+    )
+
+    with patch("sentinel.services.portfolio.PortfolioService") as service:
+        service.return_value.get_portfolio_state = AsyncMock(return_value={"portfolio_return_pct": -12.5})
+        frame = await manager.frame()
+
+    assert frame["active"] == [
+        "heartbeat",
+        "pnl_down",
+        "pnl_down_strong",
+        "recommendations",
+        "risk_breach",
+        "sync_failure",
+        "market_open",
+    ]
+    assert [led["state"] for led in frame["leds"]] == [
+        "sync_failure",
+        None,
+        "market_open",
+        "risk_breach",
+        "recommendations",
+    ]
+
+
+@pytest.mark.asyncio
+async def test_failing_source_and_invalid_setting_fall_back():
+    db = _db()
+    db.get_job_history_for_type = AsyncMock(side_effect=RuntimeError("db locked"))
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=[])
+    manager = StateManager(db=db, settings=_settings({"led_indicator_map": {"bogus": {}}}), planner=planner)
+
+    with patch("sentinel.services.portfolio.PortfolioService") as service:
+        service.return_value.get_portfolio_state = AsyncMock(return_value={"portfolio_return_pct": 0})
+        frame = await manager.frame()
+
+    assert frame["active"] == ["heartbeat"]
+    assert frame["leds"][0]["state"] == "heartbeat"
+
+
+class _RecordingScreen(DisplayDriver):
+    def __init__(self):
+        self.frames = []
+
+    def show(self, lines):
+        self.frames.append(lines)
+
+
+def test_summary_lines():
+    assert summary_lines(
+        {"value_eur": 12345.6, "day_change_eur": -120.5, "day_change_pct": -0.97, "last_action": "BUY 10 AAPL.US"}
+    ) == ["EUR 12,346", "Day: -120.50 (-0.97%)", "BUY 10 AAPL.US"]
+    assert summary_lines({"value_eur": 950.0}) == ["EUR 950.00", "Day: n/a", "No trades yet"]
+
+
+def test_unknown_screen_driver_is_rejected():
+    with pytest.raises(ValueError):
+        open_screen_driver("lcd")
+
+
+@pytest.mark.asyncio
+async def test_screen_redraws_only_when_summary_changes():
+    import time
+
+    today = int(time.time()) // 86400 * 86400
+    db = _db()
+    db.get_portfolio_snapshots = AsyncMock(
+        return_value=[
+            {"date": today - 86400, "data": {"positions": {"A.EU": {"value_eur": 900.0}}, "cash_eur": 100.0}},
+            {"date": today, "data": {"positions": {"A.EU": {"value_eur": 2000.0}}, "cash_eur": 0.0}},
+        ]
+    )
+    db.get_trades = AsyncMock(return_value=[{"side": "sell", "quantity": 5.0, "symbol": "A.EU"}])
+    screen = _RecordingScreen()
+    manager = StateManager(db=db, settings=_settings({}), screen=screen)
+
+    with patch("sentinel.services.portfolio.PortfolioService") as service:
+        service.return_value.get_portfolio_state = AsyncMock(return_value={"total_value_eur": 1050.0})
+        summary = await manager.screen_summary()
+        await manager.refresh_screen()
+        await manager.refresh_screen()
+
+    assert summary == {"value_eur": 1050.0, "day_change_eur": 50.0, "day_change_pct": 5.0, "last_action": "SELL 5 A.EU"}
+    assert screen.frames == [["EUR 1,050.00", "Day: +50.00 (+5.00%)", "SELL 5 A.EU"]]
@@ -0,0 +1,93 @@
+"""Tests for the liquidity planner cash ladder."""
+
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.services.liquidity import LiquidityService
+
+TODAY = date(2026, 3, 2)
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    await temp_db.set_setting("transaction_fee_fixed", 0.0)
+    await temp_db.set_setting("transaction_fee_percent", 0.0)
+    return temp_db
+
+
+def _rec(symbol: str, action: str, value: float) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.0,
+        target_allocation=0.0,
+        allocation_delta=0.0,
+        current_value_eur=0.0,
+        target_value_eur=0.0,
+        value_delta_eur=value if action == "buy" else -value,
+        quantity=1,
+        price=value,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=1.0,
+        reason="test",
+    )
+
+
+def _service(db, cash: float, recommendations: list[TradeRecommendation]) -> LiquidityService:
+    portfolio = MagicMock()
+    portfolio.total_cash_eur = AsyncMock(return_value=cash)
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=recommendations)
+    currency = MagicMock()
+    currency.to_eur_for_date = AsyncMock(side_effect=lambda amount, curr, d: amount)
+    return LiquidityService(db=db, portfolio=portfolio, planner=planner, currency=currency)
+
+
+@pytest.mark.asyncio
+async def test_ladder_projects_dividends_and_recurring_deposits(temp_db):
+    await temp_db.upsert_security("A.EU", currency="EUR")
+    await temp_db.upsert_position("A.EU", quantity=10, current_price=10.0, currency="EUR")
+    await temp_db.upsert_dividend("div-1", "A.EU", "2025-03-20", 12.0, "EUR", 12.0, {})
+    for i, day in enumerate(["2025-12-01", "2026-01-01", "2026-02-01"]):
+        await temp_db.upsert_cash_flow(day, "card", 500.0, "EUR", None, {"i": i})
+
+    service = _service(temp_db, cash=100.0, recommendations=[])
+    ladder = await service.get_ladder(days=90, today=TODAY)
+
+    types = [(e["type"], e["date"]) for e in ladder["events"]]
+    assert ("dividend", "2026-03-20") in types
+    assert ("deposit", "2026-03-04") in types  # 2026-02-01 + 31 days (median interval)
+    assert len(ladder["buckets"]) == 13
+    assert ladder["ending_cash_eur"] == pytest.approx(100.0 + 12.0 + 500.0 * 3)
+    assert ladder["warnings"] == []
+    assert ladder["suggested_deposit"] is None
+
+
+@pytest.mark.asyncio
+async def test_warns_when_planned_buys_exceed_cash(temp_db):
+    recs = [_rec("A.EU", "buy", 800.0), _rec("B.EU", "sell", 200.0)]
+    service = _service(temp_db, cash=100.0, recommendations=recs)
+
+    ladder = await service.get_ladder(days=30, today=TODAY)
+
+    codes = {w["code"]: w for w in ladder["warnings"]}
+    assert codes["planned_buys_exceed_cash"]["shortfall_eur"] == pytest.approx(500.0)
+    assert codes["projected_cash_negative"]["date"] == TODAY.isoformat()
+    assert ladder["suggested_deposit"] == {"amount_eur": 500.0, "by_date": TODAY.isoformat()}
+
+
+@pytest.mark.asyncio
+async def test_liquidity_endpoint_validates_horizon():
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.planner import get_liquidity_ladder
+
+    with pytest.raises(HTTPException) as exc:
+        await get_liquidity_ladder(MagicMock(), days=0)
+    assert exc.value.status_code == 400
@@ -0,0 +1,85 @@
+"""Tests for the slim read-model API."""
+
+import json
+from datetime import datetime, timezone
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.lite import LiteService
+
+NOW = int(datetime(2026, 3, 10, 12, 0, tzinfo=timezone.utc).timestamp())
+
+
+def _service(db, value=1100.0, cash=100.0, recommendations=()):
+    portfolio = MagicMock()
+    portfolio.total_value = AsyncMock(return_value=value)
+    portfolio.total_cash_eur = AsyncMock(return_value=cash)
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, _ccy: amount)
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=list(recommendations))
+    return LiteService(db=db, portfolio=portfolio, planner=planner, currency=currency)
+
+
+@pytest.mark.asyncio
+async def test_summary(temp_db, monkeypatch):
+    monkeypatch.setattr("time.time", lambda: NOW)
+    await temp_db.upsert_position("AAA.EU", quantity=10, avg_cost=80.0, currency="EUR")
+    await temp_db.upsert_portfolio_snapshot(NOW - 86400 - 43200, {"positions": {}, "cash_eur": 1000.0})
+    # Today's own snapshot is not the baseline
+    await temp_db.upsert_portfolio_snapshot(NOW - 43200, {"positions": {}, "cash_eur": 1050.0})
+
+    summary = await _service(temp_db).summary(now=NOW)
+
+    assert summary == {
+        "value": 1100,
+        "cash": 100,
+        "day": 100,
+        "day_pct": 10.0,
+        "ret_pct": 25.0,
+        "n": 1,
+        "mode": "research",
+        "ts": NOW,
+    }
+
+
+@pytest.mark.asyncio
+async def test_alerts_and_pending_are_capped_and_short(temp_db):
+    for i in range(8):
+        await temp_db.add_notification("error", "job", f"Job number {i} failed with a rather long explanation")
+    recs = [
+        SimpleNamespace(symbol=f"S{i}.EU", action="sell", quantity=1.23456, value_delta_eur=-99.6) for i in range(7)
+    ]
+    service = _service(temp_db, recommendations=recs)
+
+    alerts = await service.alerts()
+    assert alerts["unread"] == 8
+    assert len(alerts["items"]) == 5
+    assert alerts["items"][0]["s"] == "e"
+    assert len(alerts["items"][0]["t"]) == 48
+
+    pending = await service.pending(limit=2)
+    assert pending == {
+        "count": 7,
+        "items": [
+            {"a": "S", "sym": "S0.EU", "qty": 1.2346, "eur": 100},
+            {"a": "S", "sym": "S1.EU", "qty": 1.2346, "eur": 100},
+        ],
+    }
+
+
+@pytest.mark.asyncio
+async def test_responses_are_minified_with_etag():
+    from sentinel.api.routers.lite import _compact
+
+    request = MagicMock(headers={})
+    response = _compact(request, {"unread": 0, "items": []})
+    assert response.body == b'{"unread":0,"items":[]}'
+    assert json.loads(response.body) == {"unread": 0, "items": []}
+
+    request = MagicMock(headers={"if-none-match": response.headers["etag"]})
+    cached = _compact(request, {"unread": 0, "items": []})
+    assert cached.status_code == 304
+    assert cached.body == b""
@@ -0,0 +1,65 @@
+"""Tests for the in-process log buffer behind /api/logs."""
+
+import asyncio
+import logging
+
+import pytest
+
+from sentinel.services.logs import LogBuffer, level_number
+
+
+def _logger(buffer: LogBuffer, name: str) -> logging.Logger:
+    logger = logging.getLogger(name)
+    logger.setLevel(logging.DEBUG)
+    logger.propagate = False
+    logger.handlers = [buffer]
+    return logger
+
+
+def test_recent_filters_by_level_and_module():
+    buffer = LogBuffer(size=10)
+    jobs = _logger(buffer, "sentinel.jobs.tasks")
+    broker = _logger(buffer, "sentinel.broker")
+    lookalike = _logger(buffer, "sentinel.jobsx")
+
+    jobs.debug("tick")
+    jobs.warning("slow job %s", "sync")
+    broker.error("order rejected")
+    lookalike.error("not a child")
+
+    assert [e["message"] for e in buffer.recent()] == ["tick", "slow job sync", "order rejected", "not a child"]
+    assert [e["message"] for e in buffer.recent(level_number("warning"), "sentinel.jobs")] == ["slow job sync"]
+    assert [e["module"] for e in buffer.recent(level_number("ERROR"))] == ["sentinel.broker", "sentinel.jobsx"]
+    first = buffer.recent()[0]["id"]
+    assert [e["message"] for e in buffer.recent(after=first + 1, limit=1)] == ["not a child"]
+
+
+def test_buffer_keeps_only_the_newest_entries():
+    buffer = LogBuffer(size=3)
+    logger = _logger(buffer, "sentinel.test.ring")
+    for i in range(5):
+        logger.info("entry %d", i)
+
+    assert [e["message"] for e in buffer.recent()] == ["entry 2", "entry 3", "entry 4"]
+
+
+def test_unknown_level_is_rejected():
+    with pytest.raises(ValueError):
+        level_number("verbose")
+
+
+@pytest.mark.asyncio
+async def test_subscribers_receive_records_from_other_threads():
+    buffer = LogBuffer()
+    logger = _logger(buffer, "sentinel.test.threads")
+    queue = buffer.subscribe()
+
+    await asyncio.to_thread(logger.info, "from a worker")
+    entry = await asyncio.wait_for(queue.get(), timeout=1)
+
+    assert entry["message"] == "from a worker"
+    assert entry["level"] == "INFO"
+    buffer.unsubscribe(queue)
+    logger.info("after unsubscribe")
+    await asyncio.sleep(0)
+    assert queue.empty()
@@ -0,0 +1,114 @@
+"""Tests for report emails over SMTP."""
+
+import smtplib
+from datetime import datetime, timezone
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.dry_run import dry_run
+from sentinel.services.mail import MailService, week_bounds
+
+# Wednesday of ISO week 11; the last full week is 2026-W10
+NOW = int(datetime(2026, 3, 11, 9, 0, tzinfo=timezone.utc).timestamp())
+
+
+async def _configure(db):
+    await db.set_setting("smtp_host", "mail.example.com")
+    await db.set_setting("smtp_from", "sentinel@example.com")
+    await db.set_setting("smtp_username", "sentinel")
+    await db.set_setting("smtp_password", "hunter2")
+    await db.set_setting("report_email_recipients", ["me@example.com"])
+
+
+def _service(db, sent: list, error: Exception | None = None):
+    async def mailer(config, message):
+        if error:
+            raise error
+        sent.append((config, message))
+
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=[])
+    return MailService(db=db, mailer=mailer, planner=planner)
+
+
+def test_week_bounds():
+    assert week_bounds(NOW) == ("2026-W10", int(datetime(2026, 3, 9, tzinfo=timezone.utc).timestamp()))
+
+
+@pytest.mark.asyncio
+async def test_weekly_report_and_reconciliation_are_sent_once(temp_db):
+    await _configure(temp_db)
+    await temp_db.save_reconciliation(
+        "2026-02",
+        "open",
+        {"trades": {"broker": 3, "ledger": 2}, "severity": {"high": 1, "medium": 0, "low": 0}},
+        [{"severity": "high", "message": "Trade 42 missing from the ledger"}],
+    )
+    sent = []
+    service = _service(temp_db, sent)
+
+    deliveries = await service.send_due(NOW)
+    assert [(d["kind"], d["period"], d["status"]) for d in deliveries] == [
+        ("weekly_report", "2026-W10", "sent"),
+        ("monthly_reconciliation", "2026-02", "sent"),
+    ]
+    config, report = sent[0]
+    assert (config["security"], config["port"], config["password"]) == ("starttls", 587, "hunter2")
+    assert report["To"] == "me@example.com"
+    assert report["Subject"] == "Sentinel weekly report 2026-W10"
+    assert [part.get_content_type() for part in report.iter_parts()] == ["text/plain", "text/html"]
+    assert (await temp_db.get_report(period="weekly"))["period_end"] == week_bounds(NOW)[1]
+    reconciliation = sent[1][1]
+    assert reconciliation["Subject"] == "Broker reconciliation 2026-02: open"
+    assert "[high] Trade 42 missing from the ledger" in reconciliation.get_body(("plain",)).get_content()
+
+    assert await service.send_due(NOW + 3600) == []
+    assert len(sent) == 2
+
+
+@pytest.mark.asyncio
+async def test_failed_send_is_recorded_and_retried(temp_db):
+    await _configure(temp_db)
+    await temp_db.set_setting("report_email_reconciliation", False)
+
+    failed = await _service(temp_db, [], smtplib.SMTPAuthenticationError(535, b"bad credentials")).send_due(NOW)
+    assert failed[0]["status"] == "failed"
+    assert "SMTPAuthenticationError" in failed[0]["error"]
+    notifications = await temp_db.get_notifications()
+    assert notifications[0]["title"] == "Report email failed: Sentinel weekly report 2026-W10"
+
+    sent = []
+    [retried] = await _service(temp_db, sent).send_due(NOW + 3600)
+    assert retried["status"] == "sent"
+    # The week's report generated by the failed attempt is reused
+    assert len(await temp_db.get_reports(period="weekly")) == 1
+    assert [d["status"] for d in await temp_db.get_email_deliveries()] == ["sent", "failed"]
+
+
+@pytest.mark.asyncio
+async def test_test_email_and_configuration_errors(temp_db):
+    sent = []
+    service = _service(temp_db, sent)
+    with pytest.raises(ValueError, match="SMTP is not configured"):
+        await service.send_test()
+
+    await _configure(temp_db)
+    with pytest.raises(ValueError, match="Not an email address"):
+        await service.send_test(["not an address"])
+
+    delivery = await service.send_test(["ops@example.com"])
+    assert (delivery["kind"], delivery["status"], delivery["recipients"]) == ("test", "sent", ["ops@example.com"])
+    assert sent[0][1]["Subject"] == "Sentinel test email"
+
+
+@pytest.mark.asyncio
+async def test_dry_run_records_instead_of_sending(temp_db):
+    await _configure(temp_db)
+    sent = []
+    async with dry_run(temp_db) as session:
+        delivery = await _service(temp_db, sent).send_test(["ops@example.com"])
+
+    assert delivery["status"] == "sent"
+    assert sent == []
+    assert [(e["kind"], e["recipients"]) for e in session.side_effects] == [("email", ["ops@example.com"])]
@@ -0,0 +1,98 @@
+"""Tests for market data providers and their failover."""
+
+from datetime import date
+
+import pytest
+
+from sentinel.config.schema import validate_setting
+from sentinel.errors import NotFound, RateLimited, UpstreamError
+from sentinel.services.market_data import MarketDataService, ProviderChain, parse_stooq_csv
+
+TODAY = date(2026, 3, 11)
+
+
+class FakeProvider:
+    def __init__(self, name, last_day="2026-03-10", fail=None, missing=()):
+        self.name = name
+        self.calls = []
+        self._last_day = last_day
+        self._fail = fail
+        self._missing = missing
+
+    async def history(self, symbol, start_date, end_date):
+        self.calls.append(symbol)
+        if self._fail:
+            raise self._fail
+        if symbol in self._missing:
+            return []
+        return [{"date": self._last_day, "close": 10.0 if self.name == "yahoo" else 20.0}]
+
+
+def test_parse_stooq_csv():
+    bars = parse_stooq_csv("Date,Open,High,Low,Close,Volume\n2026-03-10,2,3,1,2.5,100\n2026-03-09,1,2,1,1.5,\n")
+    assert [(b["date"], b["close"], b["volume"]) for b in bars] == [("2026-03-09", 1.5, 0), ("2026-03-10", 2.5, 100)]
+    with pytest.raises(UpstreamError, match="No data"):
+        parse_stooq_csv("No data")
+
+
+@pytest.mark.asyncio
+async def test_chain_fails_over_on_missing_stale_and_throttled_data():
+    yahoo = FakeProvider("yahoo", missing=("AAA.US",))
+    stooq = FakeProvider("stooq")
+    chain = ProviderChain([yahoo, stooq], {"CCC.US": ["stooq"]})
+
+    assert (await chain.history("AAA.US", "2026-01-01", "2026-03-11"))["provider"] == "stooq"
+    assert (await chain.history("BBB.US", "2026-01-01", "2026-03-11"))["provider"] == "yahoo"
+    assert (await chain.history("CCC.US", "2026-01-01", "2026-03-11"))["provider"] == "stooq"
+    assert "CCC.US" not in yahoo.calls
+
+    # Every provider stale: the freshest bars are kept
+    chain = ProviderChain([FakeProvider("yahoo", "2026-02-01"), FakeProvider("stooq", "2026-02-20")])
+    stale = await chain.history("AAA.US", "2026-01-01", "2026-03-11")
+    assert (stale["provider"], stale["stale"]) == ("stooq", True)
+    assert stale["errors"]["yahoo"] == "stale (last bar 2026-02-01)"
+
+    # A throttling provider is not asked again during the run
+    yahoo = FakeProvider("yahoo", fail=RateLimited("Yahoo is rate limiting requests"))
+    chain = ProviderChain([yahoo, FakeProvider("stooq")])
+    for symbol in ("AAA.US", "BBB.US"):
+        assert (await chain.history(symbol, "2026-01-01", "2026-03-11"))["provider"] == "stooq"
+    assert yahoo.calls == ["AAA.US"]
+
+    chain = ProviderChain([FakeProvider("yahoo", missing=("AAA.US",)), FakeProvider("stooq", fail=ValueError("down"))])
+    with pytest.raises(NotFound, match="yahoo: no data; stooq: ValueError: down"):
+        await chain.history("AAA.US", "2026-01-01", "2026-03-11")
+
+
+@pytest.mark.asyncio
+async def test_backfill_resumes_pending_symbols(temp_db):
+    for symbol in ("AAA.US", "BBB.US", "CCC.US"):
+        await temp_db.upsert_security(symbol, currency="USD")
+    yahoo = FakeProvider("yahoo", missing=("BBB.US",))
+    service = MarketDataService(db=temp_db, providers=[yahoo, FakeProvider("stooq", missing=("BBB.US",))])
+    # An earlier download was interrupted with CCC.US still to fetch
+    await temp_db.start_price_downloads(["CCC.US"], "2025-01-01")
+
+    resumed = await service.backfill(today=TODAY)
+    assert (resumed["resumed"], resumed["requested"], resumed["done"]) == (True, 1, 1)
+    assert yahoo.calls == ["CCC.US"]
+
+    full = await service.backfill(years=1, today=TODAY)
+    assert (full["resumed"], full["requested"], full["done"], full["failed"]) == (False, 3, 2, 1)
+    assert full["by_provider"] == {"yahoo": 2}
+    assert full["errors"] == {"BBB.US": "yahoo: no data; stooq: no data"}
+    states = {row["symbol"]: row for row in await temp_db.get_price_downloads()}
+    assert {s: row["status"] for s, row in states.items()} == {"AAA.US": "done", "BBB.US": "failed", "CCC.US": "done"}
+    assert (states["AAA.US"]["source"], states["AAA.US"]["bars"], states["AAA.US"]["last_date"]) == (
+        "yahoo",
+        1,
+        "2026-03-10",
+    )
+
+
+def test_provider_settings_are_validated():
+    [problem] = validate_setting("market_data_providers", ["yahoo", "bloomberg"])
+    assert "unknown provider bloomberg" in problem.message
+    [problem] = validate_setting("market_data_symbol_providers", {"SAP.EU": "stooq"})
+    assert problem.message == "market_data_symbol_providers: SAP.EU: must be a list of providers"
+    assert validate_setting("market_data_symbol_providers", {"SAP.EU": ["stooq"]}) == []
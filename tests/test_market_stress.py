@@ -0,0 +1,146 @@
+"""Tests for market-stress buy throttling."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.market_stress import (
+    HIGH_STRESS,
+    NO_THROTTLE,
+    NORMAL,
+    buy_throttle,
+    effective_limits,
+    stress_level,
+    stress_score,
+)
+from sentinel.services.market_stress import MarketStressService
+
+# Slow climb, then a 30% slide: deep dip with oversold RSI (oldest first)
+CRASH = [100.0 + i * 0.1 for i in range(275)] + [127.5 * (0.985**i) for i in range(25)]
+
+STATES = [
+    {"region": "Europe", "score": -0.8, "confidence": 1.0},
+    {"region": "US", "score": 0.6, "confidence": 0.5},
+]
+SECURITIES = {"SAP.EU": {"geography": "Europe"}, "AAPL.US": {"geography": "US, Europe"}}
+CONFIG = {
+    "market_stress_enabled": 1.0,
+    "market_stress_buy_size_pct": 50.0,
+    "market_stress_min_score_add": 0.1,
+    "market_stress_cooloff_factor": 2.0,
+    "strategy_min_opp_score": 0.55,
+    "strategy_core_new_min_score": 0.3,
+    "strategy_opportunity_cooloff_days": 7,
+    "strategy_core_cooloff_days": 21,
+}
+
+
+def test_stress_is_weighted_by_exposure():
+    # Mostly European holdings: Europe's -0.8 dominates the US's +0.3
+    score, regions = stress_score(STATES, {"SAP.EU": 9000.0, "AAPL.US": 1000.0}, SECURITIES)
+    assert regions == {"Europe": 0.9, "US": 0.1}
+    assert score == pytest.approx(0.69)
+    assert stress_level(score, 0.4) == HIGH_STRESS
+
+    # Mostly US holdings: risk-on overall
+    score, _ = stress_score(STATES, {"SAP.EU": 1000.0, "AAPL.US": 9000.0}, SECURITIES)
+    assert score == 0.0
+    assert stress_level(score, 0.4) == NORMAL
+
+    # Nothing held in a classified region: every region counts equally
+    score, regions = stress_score(STATES, {}, SECURITIES)
+    assert regions == {"Europe": 0.5, "US": 0.5}
+    assert score == pytest.approx(0.25)
+
+
+def test_throttle_and_effective_limits():
+    assert buy_throttle(NORMAL, CONFIG) == NO_THROTTLE
+    assert buy_throttle(HIGH_STRESS, {**CONFIG, "market_stress_enabled": 0.0}) == NO_THROTTLE
+    throttle = buy_throttle(HIGH_STRESS, CONFIG)
+    assert throttle == {"buy_size_factor": 0.5, "min_score_add": 0.1, "cooloff_factor": 2.0}
+
+    limits = effective_limits(throttle, CONFIG)
+    assert limits["buy_size_pct"] == {"base": 100.0, "effective": 50.0}
+    assert limits["strategy_min_opp_score"] == {"base": 0.55, "effective": 0.65}
+    assert limits["strategy_core_cooloff_days"] == {"base": 21, "effective": 42}
+
+
+@pytest.mark.asyncio
+async def test_status_reports_level_and_effective_limits(temp_db):
+    await temp_db.upsert_security("SAP.EU", currency="EUR", geography="Europe")
+    await temp_db.upsert_position("SAP.EU", quantity=10, avg_cost=100.0, current_price=100.0)
+    await temp_db.replace_regime_states(
+        {
+            "Europe": {"regime": "bear", "score": -0.6, "confidence": 0.9},
+            "US": {"regime": "bull", "score": 0.5, "confidence": 1.0},
+        }
+    )
+    settings = MagicMock()
+    values = {"market_stress_enabled": True}
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, ccy: amount)
+
+    status = await MarketStressService(db=temp_db, settings=settings, currency=currency).status()
+    assert (status["level"], status["score"], status["throttled"]) == (HIGH_STRESS, 0.54, True)
+    assert status["regions"] == {"Europe": 1.0}
+    assert status["limits"]["buy_size_pct"]["effective"] == 50.0
+    assert status["limits"]["strategy_opportunity_cooloff_days"] == {"base": 7.0, "effective": 14}
+
+    values["market_stress_enabled"] = False
+    status = await MarketStressService(db=temp_db, settings=settings, currency=currency).status()
+    assert (status["level"], status["throttled"]) == (HIGH_STRESS, False)
+    assert status["limits"]["buy_size_pct"]["effective"] == 100.0
+
+
+def _engine(stress_enabled: bool) -> RebalanceEngine:
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(return_value=[])
+    db.get_all_securities = AsyncMock(
+        return_value=[
+            {
+                "symbol": "SAP.EU",
+                "currency": "EUR",
+                "geography": "Europe",
+                "min_lot": 1,
+                "allow_buy": 1,
+                "allow_sell": 1,
+            }
+        ]
+    )
+    db.get_prices = AsyncMock(return_value=[{"date": i, "close": c} for i, c in enumerate(reversed(CRASH))])
+    db.get_regime_states = AsyncMock(return_value=[{"region": "Europe", "score": -0.8, "confidence": 1.0}])
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+
+    engine = RebalanceEngine(db=db)
+    engine._broker = MagicMock()
+    engine._broker.get_quotes = AsyncMock(return_value={"SAP.EU": {"price": CRASH[-1]}})
+    engine._settings = MagicMock()
+    settings_values = {
+        "min_trade_value": 100.0,
+        "trade_cooloff_days": 0,
+        "market_stress_enabled": stress_enabled,
+        "market_stress_min_score_add": 0.0,
+    }
+    engine._settings.get = AsyncMock(side_effect=lambda key, default=None: settings_values.get(key, default))
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=50_000.0)
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=lambda amt, curr: amt)
+    engine._get_deficit_sells = AsyncMock(return_value=[])
+    return engine
+
+
+@pytest.mark.asyncio
+async def test_planner_shrinks_buys_in_high_stress():
+    args = {"ideal": {"SAP.EU": 0.1}, "current": {"SAP.EU": 0.0}, "total_value": 20_000.0}
+    normal = await _engine(False).get_recommendations(**args)
+    engine = _engine(True)
+    stressed = await engine.get_recommendations(**args)
+
+    assert [r.action for r in normal] == [r.action for r in stressed] == ["buy"]
+    assert stressed[0].quantity == int(normal[0].quantity * 0.5)
+    assert engine.last_run_summary["market_stress"]["level"] == HIGH_STRESS
@@ -0,0 +1,95 @@
+"""Tests for multi-provider security metadata enrichment."""
+
+import json
+
+import pytest
+
+from sentinel.services.metadata_enrichment import (
+    MetadataEnrichmentService,
+    TradernetProvider,
+    YahooProfileProvider,
+    resolve_field,
+)
+
+
+class StaticProvider:
+    def __init__(self, name: str, values: dict):
+        self.name = name
+        self._values = values
+
+    async def fetch(self, security: dict) -> dict:
+        return self._values.get(security["symbol"], {})
+
+
+def test_resolve_field_rules():
+    priority = ["tradernet", "yahoo"]
+    assert resolve_field(None, None, {"yahoo": "Tech", "tradernet": "tech "}, priority)["rule"] == "agreement"
+    decision = resolve_field(None, None, {"yahoo": "Software", "tradernet": "Tech"}, priority)
+    assert (decision["value"], decision["rule"]) == ("Tech", "priority")
+    assert decision["conflict"] == {"yahoo": "Software", "tradernet": "Tech"}
+    assert resolve_field("Banks", None, {"tradernet": "Tech"}, priority)["rule"] == "manual"
+    assert resolve_field("Banks", None, {"tradernet": "Tech", "user": "Media"}, priority)["value"] == "Media"
+    assert resolve_field(None, None, {}, priority) is None
+
+
+@pytest.mark.asyncio
+async def test_enrich_tracks_provenance_and_queues_conflicts(temp_db):
+    await temp_db.upsert_security("AAPL.US", name="Apple", data=json.dumps({"sector": "Technology"}))
+    await temp_db.upsert_security("SAP.EU", name="SAP", industry="Software")
+    yahoo = StaticProvider(
+        "yahoo",
+        {
+            "AAPL.US": {"industry": "Consumer Electronics", "country": "United States", "exchange": "NasdaqGS"},
+            "SAP.EU": {"industry": "Software - Application"},
+        },
+    )
+    service = MetadataEnrichmentService(db=temp_db, providers=[yahoo, TradernetProvider()])
+
+    result = await service.enrich()
+    assert result == {"securities": 2, "applied": 3, "conflicts": 1, "errors": 0}
+    report = (await service.report("AAPL.US"))["fields"]
+    assert report["industry"]["value"] == "Technology"  # tradernet comes first in metadata_provider_priority
+    assert report["industry"]["provenance"]["rule"] == "priority"
+    assert report["country"]["provenance"]["provider"] == "yahoo"
+    # Manually entered values are left alone
+    assert (await service.report("SAP.EU"))["fields"]["industry"]["provenance"]["provider"] == "manual"
+
+    # Re-running does not queue the same conflict twice; resolving it stores a user override
+    assert (await service.enrich())["conflicts"] == 0
+    [review] = await service.reviews()
+    assert review["candidates"] == {"tradernet": "Technology", "yahoo": "Consumer Electronics"}
+    resolved = await service.resolve_review(review["id"], "Consumer Electronics", "admin")
+    assert resolved["status"] == "resolved"
+    assert (await temp_db.get_security("AAPL.US"))["industry"] == "Consumer Electronics"
+    with pytest.raises(ValueError, match="already resolved"):
+        await service.resolve_review(review["id"], "Technology")
+
+
+@pytest.mark.asyncio
+async def test_csv_overrides_win(temp_db):
+    await temp_db.upsert_security("AAPL.US", name="Apple", data=json.dumps({"sector": "Technology"}))
+    service = MetadataEnrichmentService(db=temp_db, providers=[TradernetProvider()])
+    await service.enrich()
+
+    result = await service.import_overrides("symbol,industry,country\nAAPL.US,Hardware,US\nNOPE.US,X,\n")
+    assert result == {"imported": 1, "unknown": ["NOPE.US"]}
+    security = await temp_db.get_security("AAPL.US")
+    assert (security["industry"], security["country"]) == ("Hardware", "US")
+    assert (await service.report("AAPL.US"))["fields"]["industry"]["provenance"]["rule"] == "override"
+    with pytest.raises(ValueError, match="symbol column"):
+        await service.import_overrides("ticker,industry\nAAPL.US,Hardware\n")
+
+
+@pytest.mark.asyncio
+async def test_yahoo_profile_provider():
+    urls = []
+
+    async def fetcher(url):
+        urls.append(url)
+        profile = {"industry": "Semiconductors", "country": ""}
+        return {"quoteSummary": {"result": [{"assetProfile": profile, "price": {"exchangeName": "NMS"}}]}}
+
+    values = await YahooProfileProvider(fetcher).fetch({"symbol": "NVDA.US"})
+    assert values == {"industry": "Semiconductors", "exchange": "NMS"}
+    assert "/quoteSummary/NVDA?" in urls[0]
+
@@ -0,0 +1,76 @@
+"""Tests for the new-entry grace period before a security becomes buy-eligible."""
+
+import time
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.rebalance_rules import is_new_entry
+
+DAY = 86400
+NOW = 1_760_000_000
+# Slow climb, then a 30% slide: deep dip with oversold RSI (oldest first)
+CRASH = [100.0 + i * 0.1 for i in range(275)] + [127.5 * (0.985**i) for i in range(25)]
+
+
+def test_is_new_entry():
+    rules = {"now_ts": NOW, "grace_days": 14, "min_history_days": 120}
+    assert is_new_entry({"added_at": NOW - 3 * DAY}, 250, **rules)
+    assert not is_new_entry({"added_at": NOW - 15 * DAY}, 250, **rules)
+    # Pre-existing entries have no added_at; only history length applies
+    assert not is_new_entry({"added_at": None}, 120, **rules)
+    assert is_new_entry({"added_at": None}, 119, **rules)
+    # The minimum is capped at the history the planner loads
+    assert not is_new_entry({}, 250, now_ts=NOW, grace_days=0, min_history_days=1000)
+
+
+@pytest.mark.asyncio
+async def test_new_securities_record_when_they_were_added(temp_db):
+    before = int(time.time())
+    await temp_db.upsert_security("NEW.EU", currency="EUR")
+    await temp_db.upsert_security("NEW.EU", name="Renamed")
+
+    security = await temp_db.get_security("NEW.EU")
+    assert security["added_at"] >= before
+    assert security["name"] == "Renamed"
+
+
+def _engine(security: dict, history_days: int) -> RebalanceEngine:
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(return_value=[])
+    db.get_all_securities = AsyncMock(
+        return_value=[{"currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1, **security}]
+    )
+    closes = CRASH[-history_days:]
+    db.get_prices = AsyncMock(return_value=[{"date": i, "close": c} for i, c in enumerate(reversed(closes))])
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+
+    engine = RebalanceEngine(db=db)
+    engine._broker = MagicMock()
+    engine._broker.get_quotes = AsyncMock(return_value={security["symbol"]: {"price": CRASH[-1]}})
+    engine._settings = MagicMock()
+    settings_values = {"min_trade_value": 100.0, "trade_cooloff_days": 0}
+    engine._settings.get = AsyncMock(side_effect=lambda key, default=None: settings_values.get(key, default))
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=50_000.0)
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=lambda amt, curr: amt)
+    engine._get_deficit_sells = AsyncMock(return_value=[])
+    return engine
+
+
+@pytest.mark.asyncio
+@pytest.mark.parametrize(
+    ("added_days_ago", "history_days", "buys"),
+    [(3, 300, 0), (30, 160, 0), (30, 300, 1), (None, 300, 1)],
+)
+async def test_planner_skips_buys_inside_new_entry_window(added_days_ago, history_days, buys):
+    added_at = int(time.time()) - added_days_ago * DAY if added_days_ago is not None else None
+    engine = _engine({"symbol": "NEW.EU", "added_at": added_at}, history_days)
+
+    recs = await engine.get_recommendations(ideal={"NEW.EU": 0.1}, current={"NEW.EU": 0.0}, total_value=20_000.0)
+
+    assert len([r for r in recs if r.action == "buy"]) == buys
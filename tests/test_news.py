@@ -0,0 +1,67 @@
+"""Tests for headline classification and news ingestion of held positions."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.news import NEWS_RISK_TAG, NewsService
+from sentinel.settings import Settings
+from sentinel.utils.sentiment import classify_headline
+
+NOW = 1772409600
+
+
+def _service(db, news: dict) -> NewsService:
+    settings = Settings()
+    settings._db = db
+    broker = MagicMock()
+    broker.get_news = AsyncMock(side_effect=lambda symbol, limit: news.get(symbol, []))
+    return NewsService(db=db, broker=broker, settings=settings)
+
+
+def test_classify_headline_uses_lexicons_negation_and_severe_words():
+    assert classify_headline("Acme beats estimates, raises guidance")["label"] == "positive"
+    assert classify_headline("Acme misses estimates as sales drop")["label"] == "negative"
+    assert classify_headline("Acme shares flat ahead of meeting") == {"score": 0.0, "label": "neutral", "severe": False}
+    # "not" flips the keyword after it
+    assert classify_headline("Regulator says Acme did not miss deadline")["label"] == "positive"
+    # A severe word is a risk whatever else the headline says
+    assert classify_headline("Acme beats estimates despite fraud investigation") == {
+        "score": -1.0,
+        "label": "negative",
+        "severe": True,
+    }
+
+
+@pytest.mark.asyncio
+async def test_refresh_classifies_headlines_of_held_positions(temp_db):
+    for symbol in ("AAA.EU", "BBB.EU", "CCC.EU"):
+        await temp_db.upsert_security(symbol, name=symbol, currency="EUR")
+    await temp_db.upsert_position("AAA.EU", quantity=5)
+    await temp_db.upsert_position("BBB.EU", quantity=3)
+    news = {
+        "AAA.EU": [
+            {"id": 1, "title": "AAA misses estimates", "date": NOW - 3600},
+            {"id": 2, "title": "AAA downgraded by broker", "date": "2026-03-01 22:00:00"},
+            {"id": 3, "title": "AAA beats on margins", "date": NOW - 7 * 86400},  # past the lookback
+        ],
+        "BBB.EU": [{"id": 4, "title": "BBB wins contract", "date": NOW - 60}, {"id": 5, "title": ""}],
+        "CCC.EU": [{"id": 6, "title": "CCC fraud probe"}],  # not held
+    }
+    service = _service(temp_db, news)
+
+    assert await service.refresh(now=NOW) == {"enabled": True, "symbols": 2, "new_headlines": 3}
+    # Refetching the same headlines adds nothing
+    assert (await service.refresh(now=NOW))["new_headlines"] == 0
+
+    aaa = await service.for_symbol("AAA.EU", now=NOW)
+    assert [h["id"] for h in aaa["headlines"]] == ["1", "2"]
+    assert (aaa["negative"], aaa["news_risk"], aaa["tags"]) == (2, True, [NEWS_RISK_TAG])
+
+    positions = {p["symbol"]: p for p in await service.positions(now=NOW)}
+    assert set(positions) == {"AAA.EU", "BBB.EU"}
+    assert (positions["BBB.EU"]["positive"], positions["BBB.EU"]["news_risk"]) == (1, False)
+    assert await service.risk_symbols(now=NOW) == {"AAA.EU"}
+
+    await temp_db.set_setting("news_enabled", False)
+    assert (await service.refresh(now=NOW))["enabled"] is False
@@ -0,0 +1,81 @@
+"""Tests for the notification inbox."""
+
+from unittest.mock import AsyncMock, MagicMock, patch
+
+import pytest
+
+from sentinel.services.notifications import NotificationService, entity_link, record_notification
+
+
+def test_entity_link():
+    assert entity_link("security", "BRK.B US") == "/?search=BRK.B%20US"
+    assert entity_link("unknown", "X") is None
+    assert entity_link("security", None) is None
+
+
+@pytest.mark.asyncio
+async def test_duplicates_merge_until_acknowledged(temp_db):
+    service = NotificationService(db=temp_db)
+    first = await service.notify("error", "job", "Job sync:prices failed", message="timeout", dedupe_key="job:x")
+    second = await service.notify("error", "job", "Job sync:prices failed", message="boom", dedupe_key="job:x")
+    await service.notify("info", "trade", "BUY 1 x AAPL.US", entity_type="security", entity_id="AAPL.US")
+
+    assert first == second
+    inbox = await service.list()
+    assert inbox["unread_count"] == 2
+    merged = next(n for n in inbox["notifications"] if n["id"] == first)
+    assert merged["occurrences"] == 2
+    assert merged["message"] == "boom"
+    trade = next(n for n in inbox["notifications"] if n["category"] == "trade")
+    assert trade["link"] == "/?search=AAPL.US"
+
+    assert await service.acknowledge(first) is True
+    assert await service.acknowledge(9999) is False
+    third = await service.notify("error", "job", "Job sync:prices failed", dedupe_key="job:x")
+    assert third != first
+
+    assert await service.acknowledge_all() == 2
+    assert (await service.list(unread_only=True))["notifications"] == []
+    assert len((await service.list(severity="error"))["notifications"]) == 2
+
+    with pytest.raises(ValueError):
+        await service.notify("critical", "job", "x")
+
+
+@pytest.mark.asyncio
+async def test_record_notification_never_raises():
+    db = MagicMock()
+    db.add_notification = AsyncMock(side_effect=RuntimeError("db locked"))
+    await record_notification(db, "error", "job", "Job failed")
+    await record_notification(object(), "error", "job", "Job failed")
+    db.add_notification.assert_awaited_once()
+
+
+@pytest.mark.asyncio
+async def test_failed_trade_lands_in_inbox(temp_db):
+    from sentinel.jobs.tasks import _execute_trade
+
+    rec = MagicMock(symbol="AAPL.US", action="buy", quantity=1, price=100.0, currency="USD")
+    with patch("sentinel.security.Security") as MockSecurity:
+        mock_security = AsyncMock()
+        mock_security.buy = AsyncMock(return_value=None)
+        MockSecurity.return_value = mock_security
+        assert await _execute_trade(AsyncMock(), rec, temp_db) is False
+
+    [notification] = await temp_db.get_notifications()
+    assert notification["severity"] == "error"
+    assert notification["title"] == "Failed to buy AAPL.US"
+    assert notification["link"] == "/?search=AAPL.US"
+
+
+@pytest.mark.asyncio
+async def test_acknowledge_endpoint_returns_404_for_unknown_id(temp_db):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.notifications import acknowledge_notification
+
+    deps = MagicMock()
+    deps.db = temp_db
+    with pytest.raises(HTTPException) as exc:
+        await acknowledge_notification(999, deps)
+    assert exc.value.status_code == 404
@@ -0,0 +1,178 @@
+"""Tests for limit pricing strategies and recording the pricing method."""
+
+import json
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.security import Security
+from sentinel.utils.order_pricing import (
+    adaptive_price,
+    buffered_price,
+    depth_limit_price,
+    midpoint_price,
+    parse_order_book,
+    parse_pricing_strategies,
+    pick_strategy,
+    vwap,
+)
+
+BOOK = {
+    "ins": [
+        {"p": 10.0, "q": 100, "s": "S", "k": 1},
+        {"p": 10.1, "q": 200, "s": "S", "k": 2},
+        {"p": 10.5, "q": 500, "s": "S", "k": 3},
+        {"p": 9.9, "q": 150, "s": "B", "k": 1},
+        {"p": 9.8, "q": 300, "s": "B", "k": 2},
+    ]
+}
+
+
+def test_parse_order_book_sorts_best_first():
+    book = parse_order_book({**BOOK, "upd": [{"p": 9.95, "q": 10, "s": "B"}, {"p": 0, "q": 5, "s": "S"}]})
+    assert book["asks"] == [(10.0, 100.0), (10.1, 200.0), (10.5, 500.0)]
+    assert book["bids"] == [(9.95, 10.0), (9.9, 150.0), (9.8, 300.0)]
+    assert parse_order_book(None) == {"bids": [], "asks": []}
+
+
+def test_depth_limit_price_walks_the_book():
+    book = parse_order_book(BOOK)
+    assert depth_limit_price("buy", 50, book) == 10.0
+    assert depth_limit_price("buy", 250, book) == 10.1
+    assert depth_limit_price("sell", 400, book) == 9.8
+
+
+def test_depth_limit_price_caps_slippage():
+    book = parse_order_book(BOOK)
+    # 10.5 is 5% above the best ask, so the 2% band edge is used
+    assert depth_limit_price("buy", 700, book, max_slippage_pct=2.0) == pytest.approx(10.2)
+    # Not enough depth on the bid side: price at the band edge
+    assert depth_limit_price("sell", 10_000, book, max_slippage_pct=1.0) == pytest.approx(9.801)
+    assert depth_limit_price("buy", 10, {"bids": [(9.9, 1.0)], "asks": []}) is None
+
+
+def test_pricing_strategies_pick_the_most_specific_key():
+    strategies = parse_pricing_strategies(
+        {"*": "market", "US": "midpoint", "thin": {"strategy": "vwap", "window_days": 10}, "US:thin": "adaptive"}
+    )
+    assert pick_strategy(strategies, "US", "thin") == {"strategy": "adaptive"}
+    assert pick_strategy(strategies, "US", "liquid") == {"strategy": "midpoint"}
+    assert pick_strategy(strategies, "EU", "thin") == {"strategy": "vwap", "window_days": 10}
+    assert pick_strategy(strategies, "EU", None) == {"strategy": "market"}
+    assert pick_strategy({}, "EU", "thin") is None
+    for bad in [
+        [],
+        {"US": "twap"},
+        {"US:tiny": "market"},
+        {"*:thin": "market"},
+        {"US": {"strategy": "vwap", "window_days": 0}},
+    ]:
+        with pytest.raises(ValueError):
+            parse_pricing_strategies(bad)
+
+
+def test_strategy_prices():
+    assert midpoint_price(9.7, 10.3) == 10.0
+    assert midpoint_price(None, 10.3) is None
+    assert buffered_price("buy", 10.0, 0.5) == 10.05
+    assert buffered_price("sell", 10.0, 0.5) == 9.95
+    bars = [{"high": 11, "low": 9, "close": 10, "volume": 100}, {"high": 21, "low": 19, "close": 20, "volume": 300}]
+    assert vwap(bars) == 17.5
+    assert vwap([{"close": 10, "volume": 0}]) is None
+    # Tight spread (0.1%): cross it; spread four times as wide: a quarter of the way from mid to the far side
+    assert adaptive_price("buy", 9.995, 10.005, tight_spread_pct=0.1) == 10.005
+    assert adaptive_price("buy", 9.98, 10.02, tight_spread_pct=0.1) == 10.005
+    assert adaptive_price("sell", 9.98, 10.02, tight_spread_pct=0.1) == 9.995
+
+
+def _security(symbol: str, prices: list[dict], book: dict | None, **settings) -> Security:
+    db = MagicMock()
+    db.get_prices = AsyncMock(return_value=prices)
+    broker = MagicMock()
+    broker.get_order_book = AsyncMock(return_value=book)
+    security = Security(symbol, db=db, broker=broker)
+    security._data = {"currency": "EUR", "quote_data": json.dumps({"ask": 10.3, "bid": 9.7})}
+    values = {"limit_pricing_depth_enabled": True, "limit_depth_thin_value_eur": 250000, **settings}
+    security._settings = MagicMock()
+    security._settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    return security
+
+
+@pytest.mark.asyncio
+async def test_thin_security_is_priced_from_depth():
+    security = _security("THIN.EU", [{"close": 10.0, "volume": 1000}] * 20, parse_order_book(BOOK))
+    assert await security._get_limit_price("buy", 250) == 10.1
+    assert security.last_pricing == {"method": "depth", "limit_price": 10.1, "quote_price": 10.3}
+
+
+@pytest.mark.asyncio
+async def test_liquid_security_keeps_market_order():
+    security = _security("BIG.EU", [{"close": 100.0, "volume": 1_000_000}] * 20, parse_order_book(BOOK))
+    assert await security._get_limit_price("buy", 250) is None
+    assert security.last_pricing == {"method": "market", "limit_price": None, "quote_price": 10.3}
+    security._broker.get_order_book.assert_not_called()
+
+
+@pytest.mark.asyncio
+async def test_falls_back_to_quote_when_depth_unavailable():
+    security = _security("THIN.AS", [], None)
+    assert await security._get_limit_price("sell", 10) == 9.7
+    assert security.last_pricing == {"method": "quote", "limit_price": 9.7, "quote_price": 9.7}
+
+    security = _security("THIN.AS", [], parse_order_book(BOOK), limit_pricing_depth_enabled=False)
+    assert await security._get_limit_price("buy", 10) == 10.3
+    assert security.last_pricing["method"] == "quote"
+
+
+@pytest.mark.asyncio
+async def test_falls_back_when_order_book_errors():
+    security = _security("THIN.EU", [], None)
+    security._broker.get_order_book = AsyncMock(side_effect=RuntimeError("ws closed"))
+    assert await security._get_limit_price("buy", 10) is None
+    assert security.last_pricing["method"] == "market"
+
+
+@pytest.mark.asyncio
+async def test_trade_decision_records_pricing(temp_db):
+    await temp_db.record_trade_decision(
+        "THIN.EU", "buy", 250, "manual", order_id="ORD-1", pricing_method="depth", limit_price=10.1
+    )
+    await temp_db.record_trade_decision("BIG.EU", "buy", 5, "manual", order_id="ORD-2", pricing_method="market")
+    decisions = {d["order_id"]: d for d in await temp_db.get_trade_decisions()}
+    assert decisions["ORD-1"]["pricing_method"] == "depth"
+    assert decisions["ORD-1"]["limit_price"] == 10.1
+    assert decisions["ORD-2"]["pricing_method"] == "market"
+    assert decisions["ORD-2"]["limit_price"] is None
+
+
+@pytest.mark.asyncio
+async def test_configured_strategy_prices_the_order():
+    prices = [{"close": 100.0, "volume": 1_000_000}] * 20
+    security = _security("BIG.EU", prices, parse_order_book(BOOK), order_pricing_strategies={"EU": "midpoint"})
+    assert await security._get_limit_price("buy", 250) == 10.0
+    assert security.last_pricing == {"method": "midpoint", "limit_price": 10.0, "quote_price": 10.3}
+
+    # Per-tier: the liquid name gets the buffered last price, the thin one stays on depth pricing
+    strategies = {"liquid": {"strategy": "last_buffer", "buffer_pct": 1.0}, "thin": "depth"}
+    security = _security("BIG.EU", prices, parse_order_book(BOOK), order_pricing_strategies=strategies)
+    security._data["quote_data"] = json.dumps({"ask": 10.3, "bid": 9.7, "ltp": 10.0})
+    assert await security._get_limit_price("sell", 10) == 9.9
+    assert security.last_pricing["method"] == "last_buffer"
+    thin = [{"close": 10.0, "volume": 1000}] * 20
+    security = _security("THIN.EU", thin, parse_order_book(BOOK), order_pricing_strategies=strategies)
+    assert await security._get_limit_price("buy", 250) == 10.1
+    assert security.last_pricing["method"] == "depth"
+
+
+@pytest.mark.asyncio
+async def test_strategy_without_data_falls_back():
+    # No two-sided quote for the midpoint: an Asian security still gets a quote limit
+    security = _security("THIN.AS", [], None, order_pricing_strategies={"*": "midpoint"})
+    security._data["quote_data"] = json.dumps({"bid": 9.7})
+    assert await security._get_limit_price("sell", 10) == 9.7
+    assert security.last_pricing["method"] == "quote"
+
+    # A market strategy cannot apply to Asian markets
+    security = _security("THIN.AS", [], None, order_pricing_strategies={"AS": "market"})
+    assert await security._get_limit_price("buy", 10) == 10.3
+    assert security.last_pricing["method"] == "quote"
@@ -0,0 +1,144 @@
+"""Tests for slicing large orders into child orders."""
+
+import pytest
+import pytest_asyncio
+
+from sentinel.errors import Conflict
+from sentinel.planner.models import TradeRecommendation
+from sentinel.services.order_slicing import OrderSlicingService, slice_count, slice_quantities
+
+NOW = 1_760_000_000  # 2025-10-09 08:53 UTC
+
+
+class _Settings:
+    def __init__(self, **values):
+        self._values = {
+            "order_slicing_enabled": True,
+            "order_slice_max_volume_pct": 5.0,
+            "order_slice_max_notional_eur": 25000.0,
+            "order_slice_max_children": 6,
+            "order_slice_interval_minutes": 30,
+            **values,
+        }
+
+    async def get(self, key, default=None):
+        return self._values.get(key, default)
+
+
+class _Currency:
+    async def to_eur(self, amount, currency):
+        return amount
+
+
+class _Placer:
+    def __init__(self, reject_after=None):
+        self.placed = []
+        self.reject_after = reject_after
+
+    async def __call__(self, rec, source):
+        if self.reject_after is not None and len(self.placed) >= self.reject_after:
+            return None
+        self.placed.append((rec.symbol, rec.quantity, source))
+        return str(1000 + len(self.placed))
+
+
+def _rec(quantity, price=100.0):
+    return TradeRecommendation(
+        symbol="AAPL.US",
+        action="buy",
+        current_allocation=1.0,
+        target_allocation=5.0,
+        allocation_delta=4.0,
+        current_value_eur=1000.0,
+        target_value_eur=1000.0 + quantity * price,
+        value_delta_eur=quantity * price,
+        quantity=quantity,
+        price=price,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.0,
+        priority=1.0,
+        reason="Underweight",
+    )
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="EUR", min_lot=1)
+    return temp_db
+
+
+def _service(db, **settings):
+    return OrderSlicingService(db=db, settings=_Settings(**settings), currency=_Currency())
+
+
+def test_slice_count_and_quantities():
+    # 1000 shares are 10% of a 10000 average volume: two children keep each at 5%
+    assert slice_count(1000, 10000, 10000, 5.0, 25000, 6) == 2
+    # EUR 100k against a EUR 25k ceiling needs four; the cap limits it to three
+    assert slice_count(100, 100000, None, 5.0, 25000, 3) == 3
+    assert slice_count(10, 1000, 10000, 5.0, 25000, 6) == 1
+    assert slice_quantities(10, 3, 1) == [4, 3, 3]
+    assert slice_quantities(2, 5, 1) == [1, 1]
+    assert slice_quantities(0.5, 2, 0.1) == [0.3, 0.2]
+
+
+@pytest.mark.asyncio
+async def test_plan_only_slices_large_orders(temp_db):
+    service = _service(temp_db)
+    assert await service.plan(_rec(10)) is None
+    plan = await service.plan(_rec(600))
+    assert plan["quantities"] == [200, 200, 200]
+    assert "ceiling" in plan["reason"]
+    assert await _service(temp_db, order_slicing_enabled=False).plan(_rec(600)) is None
+
+
+@pytest.mark.asyncio
+async def test_children_are_placed_as_they_fall_due(temp_db):
+    service = _service(temp_db)
+    order = await service.start(_rec(600), await service.plan(_rec(600)), "trading:execute", now=NOW)
+    assert [c["due_at"] - NOW for c in order["slices"]] == [0, 1800, 3600]
+    assert await service.active_symbols() == {"AAPL.US"}
+
+    place = _Placer()
+    summary = await service.run_due(place, {"AAPL.US"}, now=NOW)
+    assert (summary["placed"], summary["waiting"]) == (1, 1)
+    assert place.placed == [("AAPL.US", 200, f"slice:{order['id']}")]
+
+    # Nothing is placed while the market is closed
+    assert (await service.run_due(place, set(), now=NOW + 3600))["placed"] == 0
+    summary = await service.run_due(place, {"AAPL.US"}, now=NOW + 3600)
+    assert (summary["placed"], summary["completed"]) == (2, 1)
+
+    order = await service.get(order["id"])
+    assert order["status"] == "completed"
+    assert [c["order_id"] for c in order["slices"]] == ["1001", "1002", "1003"]
+    assert order["progress"]["placed_pct"] == 100.0
+    assert await service.active_symbols() == set()
+
+
+@pytest.mark.asyncio
+async def test_rejected_child_stops_the_order(temp_db):
+    service = _service(temp_db)
+    order = await service.start(_rec(600), await service.plan(_rec(600)), "trading:execute", now=NOW)
+    summary = await service.run_due(_Placer(reject_after=1), {"AAPL.US"}, now=NOW + 3600)
+    assert (summary["placed"], summary["failed"], summary["stopped"]) == (1, 1, 1)
+
+    order = await service.get(order["id"])
+    assert order["status"] == "failed"
+    assert [c["status"] for c in order["slices"]] == ["placed", "failed", "cancelled"]
+    with pytest.raises(Conflict):
+        await service.cancel(order["id"])
+
+
+@pytest.mark.asyncio
+async def test_pending_children_expire_after_the_session(temp_db):
+    service = _service(temp_db)
+    expired = await service.start(_rec(600), await service.plan(_rec(600)), "trading:execute", now=NOW)
+    cancelled = await service.start(_rec(300), await service.plan(_rec(300)), "trading:execute", now=NOW)
+    await service.cancel(cancelled["id"])
+
+    summary = await service.run_due(_Placer(), {"AAPL.US"}, now=NOW + 86400)
+    assert (summary["expired"], summary["placed"]) == (1, 0)
+    assert (await service.get(expired["id"]))["progress"]["expired"] == 3
+    assert [o["status"] for o in await service.orders()] == ["cancelled", "expired"]
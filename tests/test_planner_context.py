@@ -0,0 +1,105 @@
+"""Tests for the in-memory planner opportunity context history."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.planner import Planner
+from sentinel.planner.context import ContextLog, build_context, get_context_log
+from sentinel.planner.models import TradeRecommendation
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    get_context_log().clear()
+    yield temp_db
+    get_context_log().clear()
+
+
+def _rec() -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol="BBB.EU",
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.2,
+        allocation_delta=0.2,
+        current_value_eur=0.0,
+        target_value_eur=400.0,
+        value_delta_eur=400.0,
+        quantity=4,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.6,
+        priority=1.0,
+        reason="Underweight",
+    )
+
+
+def test_context_log_keeps_last_n():
+    log = ContextLog(keep=2)
+    for i in range(3):
+        log.record({"captured_at": i, "positions": [], "recommendations": []})
+
+    assert log.get(0)["captured_at"] == 2
+    assert log.get(1)["captured_at"] == 1
+    assert log.get(2) is None
+    assert [s["id"] for s in log.summaries()] == [3, 2]
+
+    log.record({"captured_at": 3}, keep=1)
+    assert [s["id"] for s in log.summaries()] == [4]
+
+
+def test_recorded_context_is_a_copy():
+    log = ContextLog()
+    context = {"captured_at": 1, "positions": [{"symbol": "AAA.EU"}]}
+    log.record(context)
+    context["positions"].append({"symbol": "BBB.EU"})
+
+    assert log.get()["positions"] == [{"symbol": "AAA.EU"}]
+
+
+def test_build_context_enriches_positions():
+    context = build_context(
+        {"ideal": {"AAA.EU": 0.5, "BBB.EU": 0.2}, "current": {"AAA.EU": 0.8}, "total_value": 2000.0},
+        [{"symbol": "AAA.EU", "quantity": 16, "price": 100.0, "value_eur": 1600.0}],
+        {"EUR": 400.0},
+        400.0,
+        {"AAA.EU": {"price": 100.0}},
+        [_rec()],
+    )
+
+    assert context["positions"] == [
+        {"symbol": "AAA.EU", "quantity": 16, "price": 100.0, "value_eur": 1600.0, "current_pct": 0.8, "ideal_pct": 0.5},
+        {"symbol": "BBB.EU", "quantity": 0, "current_pct": 0.0, "ideal_pct": 0.2},
+    ]
+    assert context["cash"] == {"balances": {"EUR": 400.0}, "total_eur": 400.0}
+    assert context["recommendations"][0]["symbol"] == "BBB.EU"
+
+
+@pytest.mark.asyncio
+async def test_live_computation_records_context(temp_db):
+    portfolio = MagicMock()
+    portfolio.get_cash_balances = AsyncMock(return_value={"EUR": 400.0})
+    portfolio.total_cash_eur = AsyncMock(return_value=400.0)
+    planner = Planner(db=temp_db, broker=MagicMock(), portfolio=portfolio)
+    planner._portfolio_analyzer.get_position_details = AsyncMock(return_value=[])
+
+    async def _compute(min_trade_value, as_of_date):
+        planner._last_inputs = {"ideal": {"BBB.EU": 0.2}, "current": {}, "total_value": 2000.0}
+        return [_rec()]
+
+    planner._compute_recommendations = _compute
+    await planner.get_recommendations(min_trade_value=50.0)
+    # Backtests do not touch the context history
+    await planner.get_recommendations(as_of_date="2026-01-01")
+
+    log = get_context_log()
+    assert len(log.summaries()) == 1
+    assert log.get()["min_trade_value"] == 50.0
+    assert log.get()["total_value_eur"] == 2000.0
+
+    await temp_db.set_setting("planner_context_history", 0)
+    await planner.get_recommendations(min_trade_value=60.0)
+    assert len(log.summaries()) == 1
@@ -0,0 +1,74 @@
+"""Tests for rebalancing strategies (calendar, threshold bands, opportunistic)."""
+
+from datetime import date
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.rebalance_strategy import (
+    RebalanceStrategySettings,
+    apply_rebalance_strategy,
+    in_calendar_window,
+    outside_band,
+    period_start,
+)
+
+
+def _rec(symbol: str, reason_code: str, current: float, target: float) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action="buy" if target > current else "sell",
+        current_allocation=current,
+        target_allocation=target,
+        allocation_delta=target - current,
+        current_value_eur=0.0,
+        target_value_eur=0.0,
+        value_delta_eur=0.0,
+        quantity=1,
+        price=10.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.0,
+        priority=1.0,
+        reason="",
+        reason_code=reason_code,
+    )
+
+
+RECS = [
+    _rec("DRIFT.EU", "rebalance_buy", 0.10, 0.20),  # 10 points, 50% of target
+    _rec("SMALL.EU", "rebalance_sell", 0.23, 0.20),  # 3 points, 15% of target
+    _rec("DIP.EU", "entry_t1", 0.00, 0.05),
+]
+
+
+def _symbols(strategy: str, today: date = date(2026, 5, 20), **kwargs) -> list[str]:
+    kept, _ = apply_rebalance_strategy(RECS, today, RebalanceStrategySettings(strategy=strategy, **kwargs))
+    return [r.symbol for r in kept]
+
+
+def test_calendar_periods_and_window():
+    assert period_start(date(2026, 5, 20), "quarterly") == date(2026, 4, 1)
+    assert period_start(date(2026, 12, 31), "semiannual") == date(2026, 7, 1)
+    assert period_start(date(2026, 5, 20), "annual") == date(2026, 1, 1)
+    config = RebalanceStrategySettings(strategy="calendar", calendar_window_days=7)
+    assert in_calendar_window(date(2026, 7, 7), config)
+    assert not in_calendar_window(date(2026, 7, 8), config)
+
+
+def test_bands():
+    absolute = RebalanceStrategySettings(threshold_pct=5, band_mode="absolute")
+    relative = RebalanceStrategySettings(threshold_pct=10, band_mode="relative")
+    assert outside_band(0.10, 0.20, absolute) and not outside_band(0.23, 0.20, absolute)
+    assert outside_band(0.23, 0.20, relative) and not outside_band(0.21, 0.20, relative)
+    assert outside_band(0.01, 0.0, relative)
+
+
+def test_strategies_only_gate_rebalance_trades():
+    assert _symbols("continuous") == ["DRIFT.EU", "SMALL.EU", "DIP.EU"]
+    assert _symbols("opportunistic") == ["DIP.EU"]
+    assert _symbols("threshold", threshold_pct=5) == ["DRIFT.EU", "DIP.EU"]
+    assert _symbols("threshold", threshold_pct=10, band_mode="relative") == ["DRIFT.EU", "SMALL.EU", "DIP.EU"]
+    assert _symbols("calendar") == ["DIP.EU"]
+    assert _symbols("calendar", today=date(2026, 4, 2)) == ["DRIFT.EU", "SMALL.EU", "DIP.EU"]
+
+    _, held = apply_rebalance_strategy(RECS, date(2026, 5, 20), RebalanceStrategySettings(strategy="calendar"))
+    assert held["DRIFT.EU"] == "outside the quarterly rebalancing window (period started 2026-04-01)"
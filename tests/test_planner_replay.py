@@ -0,0 +1,139 @@
+"""Tests for planner snapshots and decision replay."""
+
+from dataclasses import replace
+from datetime import datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.currency import Currency
+from sentinel.planner import Planner
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.rebalance_rules import frozen_planning_now, planning_now
+from sentinel.planner.replay import (
+    capture_context,
+    decode_context,
+    diff_recommendations,
+    encode_context,
+    record_planner_run,
+    replay_snapshot,
+)
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    Currency()._db = temp_db
+    return temp_db
+
+
+def _rec(symbol: str, action: str = "buy", quantity: float = 5, reason: str = "Underweight") -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=500.0,
+        value_delta_eur=500.0,
+        quantity=quantity,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.4,
+        priority=1.0,
+        reason=reason,
+    )
+
+
+async def _fake_compute(self, min_trade_value, as_of_date):
+    """Stand-in planner: buys a lot of every unheld security at the quoted price."""
+    held = {p["symbol"] for p in await self._db.get_all_positions() if p["quantity"] > 0}
+    quotes = await self._broker.get_quotes([s["symbol"] for s in await self._db.get_all_securities()])
+    self._rebalance_engine.last_quotes = quotes
+    stamp = planning_now().strftime("%Y-%m-%d")
+    return [
+        replace(_rec(symbol, reason=stamp), price=quote["price"], quantity=round(500 / quote["price"]))
+        for symbol, quote in sorted(quotes.items())
+        if symbol not in held
+    ]
+
+
+def test_frozen_planning_now_is_scoped():
+    moment = datetime(2026, 3, 2, 9, 30)
+    with frozen_planning_now(moment):
+        assert planning_now() == moment
+    assert planning_now() != moment
+
+
+def test_diff_reports_added_removed_changed_and_order():
+    recorded = [vars(_rec("AAA.EU")), vars(_rec("BBB.EU", "sell"))]
+    assert diff_recommendations(recorded, recorded)["identical"]
+
+    replayed = [vars(_rec("AAA.EU", quantity=6)), vars(_rec("CCC.EU"))]
+    diff = diff_recommendations(recorded, replayed)
+    assert not diff["identical"]
+    assert [r["symbol"] for r in diff["added"]] == ["CCC.EU"]
+    assert [r["symbol"] for r in diff["removed"]] == ["BBB.EU"]
+    assert diff["changed"] == [
+        {"symbol": "AAA.EU", "action": "buy", "changes": {"quantity": {"recorded": 5, "replayed": 6}}}
+    ]
+
+    reordered = diff_recommendations(recorded, list(reversed(recorded)))
+    assert not reordered["identical"]
+    assert not reordered["same_order"]
+
+
+@pytest.mark.asyncio
+async def test_capture_context_skips_secrets_and_raw_payloads(temp_db):
+    await temp_db.upsert_security("AAA.EU", currency="EUR", data='{"huge": "payload"}')
+    await temp_db.save_prices("AAA.EU", [{"date": "2026-05-29", "close": 100.0}])
+    await temp_db.set_setting("tradernet_api_secret", "hunter2")
+    await temp_db.upsert_trade("T1", "AAA.EU", "BUY", 1, 100.0, 1_700_000_000, {"raw": "payload"})
+
+    context = decode_context(encode_context(await capture_context(temp_db, rates={"USD": 0.9})))
+
+    assert "tradernet_api_secret" not in context["settings"]
+    assert context["tables"]["trades"][0]["raw_data"] == "{}"
+    assert context["prices"]["AAA.EU"][0]["close"] == 100.0
+    assert context["rates"] == {"USD": 0.9}
+
+
+@pytest.mark.asyncio
+async def test_replay_reproduces_recorded_run_from_snapshot_state(temp_db, monkeypatch):
+    monkeypatch.setattr(Planner, "_compute_recommendations", _fake_compute)
+    for symbol in ("AAA.EU", "BBB.EU", "CCC.EU"):
+        await temp_db.upsert_security(symbol, currency="EUR")
+    await temp_db.upsert_position("AAA.EU", quantity=3, avg_cost=90.0)
+    broker = MagicMock()
+    broker.get_quotes = AsyncMock(return_value={"AAA.EU": {"price": 100.0}, "BBB.EU": {"price": 50.0}})
+    await temp_db.set_setting("exchange_rates", {"EUR": 1.0})
+
+    planner = Planner(db=temp_db, broker=broker, portfolio=MagicMock())
+    recorded = await planner._compute_recommendations(None, None)
+    snapshot_id = await record_planner_run(temp_db, planner, recorded)
+    assert [s["id"] for s in await temp_db.get_planner_snapshots()] == [snapshot_id]
+
+    # Live state moving on after the run does not leak into the replay
+    await temp_db.upsert_position("BBB.EU", quantity=10, avg_cost=40.0)
+    await temp_db.set_setting("min_trade_value", 250.0)
+
+    report = await replay_snapshot(snapshot_id, db=temp_db)
+
+    assert report["identical"]
+    assert [r["symbol"] for r in report["replayed"]] == ["BBB.EU"]
+    assert report["replayed"][0]["reason"] == datetime.fromtimestamp(report["captured_at"]).strftime("%Y-%m-%d")
+    assert report["config_changes"]["min_trade_value"]["current"] == 250.0
+    assert await replay_snapshot(snapshot_id + 1, db=temp_db) is None
+
+
+@pytest.mark.asyncio
+async def test_snapshot_retention(temp_db):
+    planner = MagicMock(last_quotes={})
+    await temp_db.set_setting("planner_snapshot_retention", 2)
+    ids = [await record_planner_run(temp_db, planner, [_rec("AAA.EU")]) for _ in range(3)]
+    assert [s["id"] for s in await temp_db.get_planner_snapshots()] == [ids[2], ids[1]]
+
+    await temp_db.set_setting("planner_snapshot_retention", 0)
+    assert await record_planner_run(temp_db, planner, []) is None
@@ -0,0 +1,140 @@
+"""Tests for the resource-aware planner candidate cap."""
+
+from unittest.mock import MagicMock, patch
+
+from sentinel.planner import rebalance
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.rebalance import RebalanceEngine
+from sentinel.planner.resource_cap import (
+    CapSettings,
+    LatencyTracker,
+    available_memory_mb,
+    decide_candidate_cap,
+    select_candidates,
+)
+from sentinel.planner.swaps import SwapSettings, plan_swaps
+
+
+def test_available_memory_reads_meminfo(tmp_path):
+    meminfo = tmp_path / "meminfo"
+    meminfo.write_text("MemTotal:       8192000 kB\nMemAvailable:    2097152 kB\n")
+    assert available_memory_mb(meminfo) == 2048.0
+    assert available_memory_mb(tmp_path / "missing") is None
+
+
+def test_latency_tracker_keeps_rolling_mean():
+    tracker = LatencyTracker(window=2)
+    assert tracker.seconds_per_symbol() is None
+    tracker.record(10.0, 100)
+    tracker.record(30.0, 100)
+    tracker.record(50.0, 100)
+    tracker.record(1.0, 0)
+    assert tracker.seconds_per_symbol() == 0.4
+
+
+def test_no_cap_with_room_to_spare():
+    decision = decide_candidate_cap(500, CapSettings(), available_mb=4096.0, seconds_per_symbol=0.01)
+    assert decision.cap is None
+    assert not decision.capped
+    assert decision.as_dict()["reasons"] == []
+
+
+def test_memory_pressure_shrinks_candidates_proportionally():
+    decision = decide_candidate_cap(400, CapSettings(min_free_memory_mb=512), available_mb=128.0)
+    assert decision.cap == 100
+    assert decision.reasons == ["free memory 128MB < 512MB"]
+
+    # Never below the floor, however little memory is left
+    assert decide_candidate_cap(400, CapSettings(floor=20), available_mb=0.0).cap == 20
+
+
+def test_latency_cap_accounts_for_held_symbols():
+    decision = decide_candidate_cap(300, CapSettings(target_seconds=60), seconds_per_symbol=0.2, held=100)
+    # 60s / 0.2s = 300 symbols in budget, 100 of them held
+    assert decision.cap == 200
+    assert "exceeds 60s budget" in decision.reasons[0]
+
+
+def test_tightest_limit_wins_and_reports_every_binding_reason():
+    decision = decide_candidate_cap(
+        400,
+        CapSettings(max_candidates=150, min_free_memory_mb=512, target_seconds=60),
+        available_mb=256.0,
+        seconds_per_symbol=0.3,
+    )
+    assert decision.cap == 150
+    assert len(decision.reasons) == 3
+
+
+def test_select_candidates_keeps_held_and_highest_weights():
+    symbols = ["HELD", "A", "B", "C"]
+    kept, dropped = select_candidates(symbols, {"HELD"}, {"A": 0.01, "B": 0.05, "C": 0.03}, cap=1)
+    assert kept == ["HELD", "B"]
+    assert dropped == ["C", "A"]
+    assert select_candidates(symbols, {"HELD"}, {}, cap=None) == (symbols, [])
+
+
+def _rec(symbol: str, action: str, value: float, score: float, priority: float) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=value,
+        value_delta_eur=value,
+        quantity=value / 100.0,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=score,
+        priority=priority,
+        reason="Underweight",
+    )
+
+
+def test_swap_pairing_stops_at_evaluation_cap():
+    recs = [_rec("NEW1", "buy", 600.0, 0.8, 2.0), _rec("NEW2", "buy", 600.0, 0.8, 1.0)]
+    holdings = [
+        {
+            "symbol": symbol,
+            "quantity": 20,
+            "price": 50.0,
+            "avg_cost": 50.0,
+            "fx_rate": 1.0,
+            "currency": "EUR",
+            "lot_size": 1,
+            "score": 0.05,
+            "allow_sell": 1,
+            "current_allocation": 0.1,
+        }
+        for symbol in ("WEAK1", "WEAK2")
+    ]
+
+    unlimited = plan_swaps(recs, holdings, 0.0, SwapSettings(max_cost_pct=0.05))
+    capped = plan_swaps(recs, holdings, 0.0, SwapSettings(max_cost_pct=0.05, max_evaluations=1))
+
+    assert len([r for r in unlimited if r.reason_code == "swap_sell"]) == 2
+    assert [r.symbol for r in capped if r.reason_code == "swap_sell"] == ["WEAK1"]
+
+
+def test_engine_caps_candidates_and_logs_decision(monkeypatch):
+    monkeypatch.setattr(rebalance, "available_memory_mb", lambda: 64.0)
+    engine = RebalanceEngine(db=MagicMock(), broker=MagicMock(), portfolio=MagicMock(), settings=MagicMock())
+    settings_ctx = {
+        "planner_candidate_cap_max": 0,
+        "planner_min_free_memory_mb": 512,
+        "planner_target_eval_seconds": 120,
+        "planner_candidate_cap_floor": 2,
+    }
+    ideal = {f"C{i:02d}": 0.01 * i for i in range(1, 21)}
+    symbols = ["HELD"] + list(ideal)
+
+    with patch.object(rebalance, "logger") as logger:
+        kept, decision = engine._cap_candidates(symbols, ideal, {"HELD": 0.2}, settings_ctx)
+
+    # 64/512 of 20 candidates rounds down to 2, the floor
+    assert decision.cap == 2
+    assert kept == ["HELD", "C19", "C20"]
+    assert "Capping planner candidates at 2 of 20" in logger.warning.call_args.args[0]
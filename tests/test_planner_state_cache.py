@@ -0,0 +1,180 @@
+"""Tests for state-hash keyed caching of planner batches."""
+
+from datetime import date
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner import Planner
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.state_hash import batch_cache_key, compute_state_hash
+
+TODAY = date(2026, 6, 1)
+
+
+def _rec() -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol="AAA.EU",
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=500.0,
+        value_delta_eur=500.0,
+        quantity=5,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.4,
+        priority=1.0,
+        reason="Underweight",
+    )
+
+
+@pytest.mark.asyncio
+async def test_state_hash_tracks_planner_inputs_only(temp_db):
+    await temp_db.upsert_security("AAA.EU", currency="EUR")
+    await temp_db.save_prices("AAA.EU", [{"date": "2026-05-29", "close": 100.0}])
+    base = await compute_state_hash(temp_db, today=TODAY)
+
+    # Sync bookkeeping does not change the state
+    await temp_db.upsert_security("AAA.EU", last_synced=123)
+    assert await compute_state_hash(temp_db, today=TODAY) == base
+
+    # Reclassifying a security as a cash equivalent changes how it is planned
+    await temp_db.upsert_security("AAA.EU", asset_class="cash_equivalent")
+    assert await compute_state_hash(temp_db, today=TODAY) != base
+    await temp_db.upsert_security("AAA.EU", asset_class="equity")
+
+    await temp_db.upsert_position("AAA.EU", quantity=3, avg_cost=90.0)
+    with_position = await compute_state_hash(temp_db, today=TODAY)
+    assert with_position != base
+
+    await temp_db.save_prices("AAA.EU", [{"date": "2026-06-01", "close": 101.0}])
+    assert await compute_state_hash(temp_db, today=TODAY) != with_position
+    assert await compute_state_hash(temp_db, today=date(2026, 6, 2)) != await compute_state_hash(temp_db, today=TODAY)
+
+
+@pytest.mark.asyncio
+async def test_state_hash_tracks_correlations(temp_db):
+    pair = {"symbol_a": "AAA.EU", "symbol_b": "BBB.EU", "correlation": 0.4, "observations": 200}
+    await temp_db.replace_correlations([pair], window_days=252)
+    base = await compute_state_hash(temp_db, today=TODAY)
+
+    # Recomputing the same matrix later does not change the state
+    await temp_db.replace_correlations([pair], window_days=252)
+    assert await compute_state_hash(temp_db, today=TODAY) == base
+
+    await temp_db.replace_correlations([{**pair, "correlation": 0.9}], window_days=252)
+    assert await compute_state_hash(temp_db, today=TODAY) != base
+
+
+@pytest.mark.asyncio
+async def test_state_hash_tracks_regimes(temp_db):
+    state = {"regime": "bull", "score": 0.6, "confidence": 0.8, "signals": {"trend": 0.7}}
+    await temp_db.replace_regime_states({"EU": state})
+    base = await compute_state_hash(temp_db, today=TODAY)
+
+    # Only the classification matters, not the signals behind it
+    await temp_db.replace_regime_states({"EU": {**state, "signals": {"trend": 0.9}}})
+    assert await compute_state_hash(temp_db, today=TODAY) == base
+
+    await temp_db.replace_regime_states({"EU": {**state, "regime": "bear", "score": -0.4}})
+    assert await compute_state_hash(temp_db, today=TODAY) != base
+
+
+@pytest.mark.asyncio
+async def test_state_hash_tracks_external_holdings(temp_db):
+    base = await compute_state_hash(temp_db, today=TODAY)
+
+    holding_id = await temp_db.add_external_holding({"name": "House", "kind": "real_estate", "value": 300000.0})
+    with_holding = await compute_state_hash(temp_db, today=TODAY)
+    assert with_holding != base
+
+    await temp_db.update_external_holding(holding_id, value=310000.0)
+    assert await compute_state_hash(temp_db, today=TODAY) != with_holding
+
+
+@pytest.mark.asyncio
+async def test_state_hash_tracks_position_theses(temp_db):
+    await temp_db.upsert_position("AAA.EU", quantity=3, avg_cost=90.0)
+    base = await compute_state_hash(temp_db, today=TODAY)
+
+    await temp_db.set_position_thesis("AAA.EU", target_price=120.0, reviewed_at=1780000000)
+    with_thesis = await compute_state_hash(temp_db, today=TODAY)
+    assert with_thesis != base
+
+    # The written thesis itself does not affect the plan, its levels do
+    await temp_db.set_position_thesis("AAA.EU", thesis="Margins recover")
+    assert await compute_state_hash(temp_db, today=TODAY) == with_thesis
+    await temp_db.set_position_thesis("AAA.EU", stop_price=80.0)
+    assert await compute_state_hash(temp_db, today=TODAY) != with_thesis
+
+
+def test_batch_key_includes_parameters():
+    assert batch_cache_key("abc", {"min_trade_value": 100.0}) != batch_cache_key("abc", {"min_trade_value": 50.0})
+    assert batch_cache_key("abc", {"min_trade_value": 100.0}).startswith("planner:batch:abc:")
+
+
+@pytest.mark.asyncio
+async def test_planner_reuses_batch_until_state_changes(temp_db):
+    planner = Planner(db=temp_db, broker=MagicMock(), portfolio=MagicMock())
+    planner._compute_recommendations = AsyncMock(return_value=[_rec()])
+
+    first = await planner.get_recommendations(min_trade_value=100.0)
+    second = await planner.get_recommendations(min_trade_value=100.0)
+
+    assert second == first
+    assert planner._compute_recommendations.await_count == 1
+
+    await temp_db.set_setting("strategy_min_opp_score", 0.6)
+    await planner.get_recommendations(min_trade_value=100.0)
+    assert planner._compute_recommendations.await_count == 2
+
+    # Backtests (as-of runs) are never served from the batch cache
+    await planner.get_recommendations(min_trade_value=100.0, as_of_date="2026-01-01")
+    assert planner._compute_recommendations.await_count == 3
+
+
+@pytest.mark.asyncio
+async def test_truncated_batches_are_not_cached(temp_db):
+    planner = Planner(db=temp_db, broker=MagicMock(), portfolio=MagicMock())
+
+    async def compute(min_trade_value, as_of_date):
+        planner._rebalance_engine.last_run_summary = {"time_budget": {"truncated": True}}
+        return [_rec()]
+
+    planner._compute_recommendations = AsyncMock(side_effect=compute)
+    await planner.get_recommendations(min_trade_value=100.0)
+    await planner.get_recommendations(min_trade_value=100.0)
+    assert planner._compute_recommendations.await_count == 2
+
+
+@pytest.mark.asyncio
+async def test_state_checks_diff_components_and_securities(temp_db):
+    await temp_db.upsert_security("AAA.EU", currency="EUR")
+    await temp_db.upsert_security("BBB.EU", currency="EUR")
+    await temp_db.save_prices("AAA.EU", [{"date": "2026-05-29", "close": 100.0}])
+    planner = Planner(db=temp_db, broker=MagicMock(), portfolio=MagicMock())
+    planner._compute_recommendations = AsyncMock(return_value=[])
+
+    await planner.get_recommendations()
+    await planner.get_recommendations()
+    checks = await temp_db.get_state_checks()
+    assert len(checks) == 1 and checks[0]["checks"] == 2
+    assert set(checks[0]["components"]) == {"positions", "prices", "scores", "settings", "allocations", "market"}
+
+    await temp_db.upsert_position("BBB.EU", quantity=3, avg_cost=90.0)
+    await temp_db.set_setting("max_position_pct", 15)
+    await planner.get_recommendations()
+
+    from sentinel.api.routers.planner import diff_planner_state_checks
+
+    deps = MagicMock()
+    deps.db = temp_db
+    diff = await diff_planner_state_checks(deps)
+    assert diff["changed"] == ["positions", "settings"]
+    assert diff["components"]["positions"] == {"added": ["BBB.EU"], "removed": [], "modified": []}
+    assert diff["components"]["settings"]["added"] == ["setting:max_position_pct"]
+    assert diff["securities"] == ["BBB.EU"]
@@ -0,0 +1,44 @@
+"""Tests for chunked price-history streaming used by planner passes."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.streaming import stream_price_history
+
+
+@pytest.mark.asyncio
+async def test_streams_in_chunks_preserving_order():
+    db = MagicMock()
+    db.get_prices_for_symbols = AsyncMock(
+        side_effect=lambda symbols, days, end_date: {s: [{"close": float(len(s))}] for s in symbols}
+    )
+    symbols = ["A", "BB", "CCC", "DDDD", "EEEEE"]
+
+    seen = [(symbol, rows) async for symbol, rows in stream_price_history(db, symbols, days=10, chunk_size=2)]
+
+    assert [s for s, _ in seen] == symbols
+    assert seen[2] == ("CCC", [{"close": 3.0}])
+    assert db.get_prices_for_symbols.await_count == 3
+    assert db.get_prices_for_symbols.await_args_list[0].args[0] == ["A", "BB"]
+
+
+@pytest.mark.asyncio
+async def test_falls_back_to_per_symbol_queries():
+    db = MagicMock()  # get_prices_for_symbols returns a non-awaitable MagicMock
+    db.get_prices = AsyncMock(return_value=[{"close": 1.0}])
+
+    seen = [s async for s, _ in stream_price_history(db, ["A", "B", "C"], days=5, end_date="2025-01-01", chunk_size=2)]
+
+    assert seen == ["A", "B", "C"]
+    db.get_prices.assert_any_await("C", days=5, end_date="2025-01-01")
+
+
+@pytest.mark.asyncio
+async def test_missing_symbols_yield_empty_history():
+    db = MagicMock()
+    db.get_prices_for_symbols = AsyncMock(return_value={"A": [{"close": 1.0}]})
+
+    seen = dict([item async for item in stream_price_history(db, ["A", "B"], days=5)])
+
+    assert seen == {"A": [{"close": 1.0}], "B": []}
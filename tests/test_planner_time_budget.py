@@ -0,0 +1,145 @@
+"""Tests for the live planner run time budget."""
+
+import json
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.rebalance import RebalanceEngine
+from sentinel.planner.swaps import SwapSettings, plan_swaps
+from sentinel.planner.time_budget import (
+    STATS_CACHE_KEY,
+    TimeBudget,
+    budget_seconds,
+    evaluation_order,
+    update_stats,
+)
+
+
+class FakeClock:
+    def __init__(self):
+        self.now = 100.0
+
+    def __call__(self) -> float:
+        return self.now
+
+
+def test_budget_defaults_to_a_share_of_the_job_interval():
+    assert budget_seconds(45, 30) == 45.0
+    assert budget_seconds(0, 30) == 900.0
+    assert budget_seconds(0, None) == 0.0
+
+
+def test_budget_expires_and_keeps_the_first_cut():
+    clock = FakeClock()
+    budget = TimeBudget(10, clock=clock)
+    should_stop = budget.stop_check("swaps")
+    assert not budget.expired()
+    assert not should_stop()
+
+    clock.now += 10
+    assert budget.expired()
+    budget.truncate("symbols", 3, 8)
+    assert should_stop()
+    assert budget.as_dict() == {
+        "budget_seconds": 10.0,
+        "elapsed_seconds": 10.0,
+        "truncated": True,
+        "truncated_stage": "symbols",
+        "evaluated": 3,
+        "total": 8,
+    }
+
+    # An unlimited budget never expires
+    unlimited = TimeBudget(0, clock=clock)
+    clock.now += 1e6
+    assert not unlimited.expired()
+
+
+def test_held_symbols_are_evaluated_first_then_by_ideal_weight():
+    order = evaluation_order(["C", "HELD", "A", "B"], {"HELD"}, {"A": 0.01, "B": 0.05, "C": 0.05})
+    assert order == ["HELD", "B", "C", "A"]
+
+
+def test_stats_count_truncated_runs():
+    stats = update_stats(None, {"truncated": False}, now=1)
+    stats = update_stats(stats, {"truncated": True, "truncated_stage": "swaps"}, now=2)
+    stats = update_stats(stats, {"truncated": False}, now=3)
+
+    assert (stats["runs"], stats["truncated"], stats["last_truncated_at"]) == (3, 1, 2)
+    assert stats["truncation_rate"] == pytest.approx(0.3333)
+    assert stats["last_run"] == {"truncated": False}
+
+
+def _buy(symbol: str, priority: float) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=600.0,
+        value_delta_eur=600.0,
+        quantity=6,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.8,
+        priority=priority,
+        reason="Underweight",
+    )
+
+
+def test_swap_pairing_stops_when_the_budget_expires():
+    recs = [_buy("NEW1", 2.0), _buy("NEW2", 1.0)]
+    holdings = [
+        {
+            "symbol": symbol,
+            "quantity": 20,
+            "price": 50.0,
+            "avg_cost": 50.0,
+            "fx_rate": 1.0,
+            "currency": "EUR",
+            "lot_size": 1,
+            "score": 0.05,
+            "allow_sell": 1,
+            "current_allocation": 0.1,
+        }
+        for symbol in ("WEAK1", "WEAK2")
+    ]
+    calls = []
+
+    def should_stop() -> bool:
+        calls.append(1)
+        return len(calls) > 1
+
+    result = plan_swaps(recs, holdings, 0.0, SwapSettings(max_cost_pct=0.05, should_stop=should_stop))
+
+    # The highest-priority buy was paired before time ran out; the other is kept unswapped
+    assert [r.symbol for r in result if r.reason_code == "swap_sell"] == ["WEAK1"]
+    assert {r.symbol for r in result if r.action == "buy"} == {"NEW1", "NEW2"}
+
+
+@pytest.mark.asyncio
+async def test_engine_records_truncation_stats_and_reads_the_job_interval():
+    db = MagicMock()
+    cache = {}
+    db.cache_get = AsyncMock(side_effect=lambda key: cache.get(key))
+    db.cache_set = AsyncMock(side_effect=lambda key, value, ttl_seconds: cache.__setitem__(key, value))
+    db.get_job_schedule = AsyncMock(return_value={"interval_minutes": 60, "interval_market_open_minutes": 30})
+    engine = RebalanceEngine(db=db, broker=MagicMock(), portfolio=MagicMock(), settings=MagicMock())
+
+    assert await engine._planning_interval_minutes() == 30.0
+
+    clock = FakeClock()
+    budget = TimeBudget(5, clock=clock)
+    clock.now += 6
+    budget.truncate("symbols", 40, 100)
+    await engine._record_time_budget(budget)
+    await engine._record_time_budget(TimeBudget(5, clock=clock))
+
+    stats = json.loads(cache[STATS_CACHE_KEY])
+    assert (stats["runs"], stats["truncated"], stats["truncation_rate"]) == (2, 1, 0.5)
+    assert stats["last_run"]["truncated"] is False
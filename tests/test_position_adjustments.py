@@ -0,0 +1,99 @@
+"""Tests for manual position adjustments."""
+
+from datetime import datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.portfolio import Portfolio
+from sentinel.services.position_adjustments import PositionAdjustmentService
+from sentinel.services.reconciliation import ReconciliationService
+
+
+def _broker(*positions):
+    broker = MagicMock()
+    broker.get_portfolio = AsyncMock(return_value={"positions": list(positions), "cash": {}})
+    return broker
+
+
+async def _sync(db, *positions):
+    await Portfolio(db=db, broker=_broker(*positions), currency=MagicMock()).sync()
+    return await db.get_position("AAPL.US")
+
+
+@pytest.mark.asyncio
+async def test_adjustment_survives_sync_until_broker_changes(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="USD")
+    await _sync(temp_db, {"symbol": "AAPL.US", "quantity": 10, "avg_cost": 150.0})
+    service = PositionAdjustmentService(db=temp_db)
+
+    adjustment = await service.adjust("AAPL.US", "20 shares transferred in", quantity=30, created_by="alice")
+    assert (adjustment["quantity_before"], adjustment["quantity_after"]) == (10, 30)
+    assert (adjustment["broker_quantity"], adjustment["avg_cost_after"]) == (10, 150.0)
+    # A second correction keeps overriding the original broker figures
+    await service.adjust("AAPL.US", "Cost basis of transferred shares", avg_cost=120.0)
+
+    position = await _sync(temp_db, {"symbol": "AAPL.US", "quantity": 10, "avg_cost": 150.0})
+    assert (position["quantity"], position["avg_cost"]) == (30, 120.0)
+    assert [a["in_force"] for a in await service.list()] == [True, False]
+
+    position = await _sync(temp_db, {"symbol": "AAPL.US", "quantity": 30, "avg_cost": 121.0})
+    assert (position["quantity"], position["avg_cost"]) == (30, 121.0)
+    assert [a["in_force"] for a in await service.list()] == [False, False]
+
+
+@pytest.mark.asyncio
+async def test_adjustment_of_position_the_broker_does_not_report(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="USD")
+    await PositionAdjustmentService(db=temp_db).adjust("AAPL.US", "Held at another broker", 5, 100.0)
+
+    assert (await _sync(temp_db))["quantity"] == 5
+
+
+@pytest.mark.asyncio
+async def test_invalid_adjustments(temp_db):
+    service = PositionAdjustmentService(db=temp_db)
+    with pytest.raises(ValueError, match="reason is required"):
+        await service.adjust("AAPL.US", " ", quantity=1)
+    with pytest.raises(ValueError, match="quantity or avg_cost"):
+        await service.adjust("AAPL.US", "why")
+    with pytest.raises(ValueError, match="negative"):
+        await service.adjust("AAPL.US", "why", quantity=-1)
+    with pytest.raises(LookupError):
+        await service.adjust("AAPL.US", "why", quantity=1)
+    await temp_db.upsert_security("AAPL.US", currency="USD")
+    await temp_db.upsert_position("AAPL.US", quantity=1, avg_cost=10.0)
+    with pytest.raises(ValueError, match="already has"):
+        await service.adjust("AAPL.US", "why", quantity=1)
+
+
+@pytest.mark.asyncio
+async def test_reconciliation_flags_adjustments_of_the_month(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="USD")
+    await temp_db.add_position_adjustment(
+        {
+            "symbol": "AAPL.US",
+            "broker_quantity": 10,
+            "quantity_before": 10,
+            "quantity_after": 12,
+            "reason": "Split handled wrong",
+            "created_by": "alice",
+            "created_at": int(datetime(2024, 3, 15).timestamp()),
+        }
+    )
+    broker = MagicMock()
+    broker.connected = True
+    broker.get_trades_history = AsyncMock(return_value=[])
+    broker.get_cash_flows = AsyncMock(return_value=[])
+    broker.get_corporate_actions = AsyncMock(return_value=[])
+    service = ReconciliationService(db=temp_db, broker=broker, settings=MagicMock(get=AsyncMock(return_value=0.01)))
+
+    march = await service.reconcile("2024-03")
+    april = await service.reconcile("2024-04")
+
+    assert march["status"] == "open"
+    assert march["summary"]["adjustments"] == 1
+    [issue] = march["discrepancies"]
+    assert (issue["section"], issue["severity"]) == ("adjustments", "high")
+    assert "by alice (quantity 10 -> 12): Split handled wrong" in issue["message"]
+    assert april["status"] == "clean"
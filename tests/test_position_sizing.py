@@ -0,0 +1,91 @@
+"""Tests for the position sizing models."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.strategy import (
+    ScoreSizer,
+    VolatilityTargetSizer,
+    compute_symbol_targets,
+    make_sizer,
+    validate_sizing_overrides,
+)
+
+# Daily vol 0.01 and 0.02 annualize to ~15.9% and ~31.7%
+SIGNALS = {
+    "CALM": {"core_rank": 0.2, "opp_score": 0.8, "vol20": 0.01},
+    "WILD": {"core_rank": 0.4, "opp_score": 0.9, "vol20": 0.02},
+}
+
+
+def test_score_sizer_is_proportional_to_candidate_weights():
+    assert ScoreSizer().size({"A": 3.0, "B": 1.0, "C": 0.0}, {}) == {"A": 0.75, "B": 0.25}
+    assert ScoreSizer().size({}, {}) == {}
+
+
+def test_volatility_sizer_is_inverse_to_realized_vol():
+    sizes = VolatilityTargetSizer(target_vol=1.0, max_weight=1.0).size({"CALM": 1.0, "WILD": 5.0}, SIGNALS)
+    # Scores are ignored: the calmer stock gets twice the size of the one with twice the vol
+    assert sizes["CALM"] == pytest.approx(2 / 3)
+    assert sizes["WILD"] == pytest.approx(1 / 3)
+
+
+def test_volatility_sizer_caps_positions_and_scales_to_target():
+    sizer = VolatilityTargetSizer(target_vol=0.15, max_weight=0.5)
+    sizes = sizer.size({"CALM": 1.0, "WILD": 1.0}, SIGNALS)
+
+    # Capped at half the sleeve each, then scaled so 0.5 * 15.9% + 0.5 * 31.7% comes down to 15%
+    assert sizes["CALM"] == pytest.approx(sizes["WILD"])
+    sleeve_vol = sum(sizes[s] * sizer.annualized_vol(SIGNALS[s]) for s in sizes)
+    assert sleeve_vol == pytest.approx(0.15)
+    assert sum(sizes.values()) < 1.0
+
+    # A flat history is floored at min_vol rather than given an unbounded size
+    assert sizer.annualized_vol({"vol20": 0.0}) == sizer.min_vol
+
+
+def test_make_sizer_and_overrides_validation():
+    assert isinstance(make_sizer("volatility_target", 10.0, 20.0), VolatilityTargetSizer)
+    assert isinstance(make_sizer("unknown"), ScoreSizer)
+    assert validate_sizing_overrides({"opportunity": "volatility_target"}) == {"opportunity": "volatility_target"}
+    with pytest.raises(ValueError):
+        validate_sizing_overrides({"satellite": "score"})
+    with pytest.raises(ValueError):
+        validate_sizing_overrides({"core": "kelly"})
+
+
+def test_volatility_targeted_sleeve_leaves_cash_uninvested():
+    kwargs = {"core_target": 0.7, "opportunity_target": 0.3, "min_opp_score": 0.55}
+    score, _ = compute_symbol_targets(SIGNALS, {"CALM": 1.0, "WILD": 1.0}, **kwargs)
+    targeted, sleeves = compute_symbol_targets(
+        SIGNALS,
+        {"CALM": 1.0, "WILD": 1.0},
+        opportunity_sizer=VolatilityTargetSizer(target_vol=0.10, max_weight=1.0),
+        **kwargs,
+    )
+
+    assert sum(score.values()) == pytest.approx(1.0)
+    assert set(sleeves.values()) == {"opportunity"}
+    # Core stays fully invested; the opportunity sleeve targets 10% vol out of ~21% for the
+    # inverse-vol mix, so about half of it stays cash
+    assert sum(targeted.values()) == pytest.approx(0.7 + 0.3 * 0.10 / (0.01 * 252**0.5 * 4 / 3))
+
+
+@pytest.mark.asyncio
+async def test_calculator_selects_sizers_from_settings():
+    values = {
+        "position_sizing_mode": "volatility_target",
+        "position_sizing_overrides": {"opportunity": "score"},
+        "vol_target_annual_pct": 12.0,
+    }
+    settings = MagicMock()
+    settings.get = AsyncMock(side_effect=lambda key, default=None: values.get(key, default))
+    calculator = AllocationCalculator(db=MagicMock(), portfolio=MagicMock(), currency=MagicMock(), settings=settings)
+
+    sizers = await calculator._load_sizers()
+
+    assert sizers["core"].mode == "volatility_target"
+    assert sizers["core"].target_vol == pytest.approx(0.12)
+    assert sizers["opportunity"].mode == "score"
@@ -0,0 +1,114 @@
+"""Tests for position target prices, stop levels and theses."""
+
+import time
+
+import pytest
+
+from sentinel.errors import InvalidInput, NotFound
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.theses import DAY_SECONDS, apply_thesis_tags, thesis_tags
+from sentinel.services.position_theses import PositionThesisService
+
+
+class _Settings:
+    async def get(self, key, default=None):
+        return default
+
+
+def _rec(symbol: str, action: str, price: float) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action=action,
+        current_allocation=0.1,
+        target_allocation=0.05,
+        allocation_delta=-0.05,
+        current_value_eur=1000.0,
+        target_value_eur=500.0,
+        value_delta_eur=-500.0,
+        quantity=5,
+        price=price,
+        currency="USD",
+        lot_size=1,
+        contrarian_score=0.2,
+        priority=1.0,
+        reason="Overweight",
+    )
+
+
+def test_thesis_tags_and_planner_boost():
+    now = 1_800_000_000
+    thesis = {"target_price": 200.0, "stop_price": 120.0, "review_days": 30, "reviewed_at": now - 31 * DAY_SECONDS}
+    assert thesis_tags(thesis, 210.0, now) == ["target_reached", "review_due"]
+    assert thesis_tags(thesis, 110.0, now - 2 * DAY_SECONDS) == ["stop_reached"]
+    # No price, no price conditions
+    assert thesis_tags({**thesis, "review_days": None}, 0.0, now) == []
+
+    recs = [_rec("AAPL.US", "sell", 210.0), _rec("AAPL.US", "buy", 210.0), _rec("MSFT.US", "sell", 210.0)]
+    apply_thesis_tags(recs, {"AAPL.US": thesis}, now, sell_boost=0.5)
+    assert recs[0].priority == 1.5
+    assert recs[0].reason == "Overweight (target price 200 reached)"
+    assert recs[0].advisory_tags == ["target_reached", "review_due"]
+    # Buys are tagged but not reweighted; symbols without a thesis are untouched
+    assert (recs[1].priority, recs[1].advisory_tags) == (1.0, ["target_reached", "review_due"])
+    assert (recs[2].priority, recs[2].advisory_tags) == (1.0, None)
+
+
+@pytest.mark.asyncio
+async def test_set_validates_and_merges(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="USD")
+    service = PositionThesisService(db=temp_db, settings=_Settings())
+
+    with pytest.raises(NotFound):
+        await service.set("MSFT.US", {"target_price": 100})
+    with pytest.raises(InvalidInput):
+        await service.set("AAPL.US", {"quantity": 10})
+    with pytest.raises(InvalidInput):
+        await service.set("AAPL.US", {"target_price": -5})
+
+    thesis = await service.set("AAPL.US", {"target_price": 200, "thesis": "  Services margin expansion "})
+    assert (thesis["target_price"], thesis["thesis"], thesis["review_days"]) == (200.0, "Services margin expansion", 90)
+    with pytest.raises(InvalidInput, match="below target_price"):
+        await service.set("AAPL.US", {"stop_price": 250})
+
+    thesis = await service.set("AAPL.US", {"stop_price": 150})
+    assert (thesis["target_price"], thesis["stop_price"]) == (200.0, 150.0)
+    assert thesis["thesis"] == "Services margin expansion"
+    assert [entry["action"] for entry in await temp_db.get_audit_log()] == ["position.thesis", "position.thesis"]
+
+    await service.clear("AAPL.US")
+    with pytest.raises(NotFound):
+        await service.get("AAPL.US")
+
+
+@pytest.mark.asyncio
+async def test_check_notifies_once_per_crossing(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="USD")
+    await temp_db.upsert_position("AAPL.US", quantity=10, avg_cost=150.0, current_price=190.0)
+    service = PositionThesisService(db=temp_db, settings=_Settings())
+    await service.set("AAPL.US", {"target_price": 200, "stop_price": 120, "review_days": 30})
+    now = int(time.time())
+
+    assert (await service.check(now))["events"] == []
+
+    await temp_db.upsert_position("AAPL.US", current_price=205.0)
+    assert [e["event"] for e in (await service.check(now))["events"]] == ["target_reached"]
+    # Still above the target: nothing new
+    assert (await service.check(now))["events"] == []
+    assert (await service.get("AAPL.US"))["tags"] == ["target_reached"]
+
+    # Back below, then above again: a new crossing
+    await temp_db.upsert_position("AAPL.US", current_price=195.0)
+    await service.check(now)
+    await temp_db.upsert_position("AAPL.US", current_price=201.0)
+    assert [e["event"] for e in (await service.check(now))["events"]] == ["target_reached"]
+
+    later = now + 31 * DAY_SECONDS
+    assert [e["event"] for e in (await service.check(later))["events"]] == ["review_due"]
+    assert (await service.check(later))["events"] == []
+    # A review restarts the interval and re-arms the notification
+    await service.review("AAPL.US")
+    assert (await service.get("AAPL.US"))["tags"] == ["target_reached"]
+    assert [e["event"] for e in (await service.check(later + 31 * DAY_SECONDS))["events"]] == ["review_due"]
+
+    notifications = await temp_db.get_notifications()
+    assert {n["title"] for n in notifications} == {"AAPL.US reached its target price", "AAPL.US thesis review due"}
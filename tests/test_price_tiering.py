@@ -0,0 +1,102 @@
+"""Tests for hot/cold tiering of price history."""
+
+import os
+import tempfile
+from datetime import date, timedelta
+
+import pytest
+import pytest_asyncio
+
+from sentinel.dry_run import dry_run
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    cold_dir = tempfile.mkdtemp()
+    temp_db.cold_path = os.path.join(cold_dir, "cold.db")
+    yield temp_db
+    await temp_db.close()
+    for name in os.listdir(cold_dir):
+        os.unlink(os.path.join(cold_dir, name))
+    os.rmdir(cold_dir)
+
+
+def _bars(days: int) -> list[dict]:
+    today = date.today()
+    return [{"date": (today - timedelta(days=i)).isoformat(), "close": 100.0 + i} for i in range(days)]
+
+
+async def _tiered(db, hot_days: int = 10) -> dict:
+    await db.set_setting("price_cold_storage_path", db.cold_path)
+    await db.set_setting("price_hot_days", hot_days)
+    return await db.tier_prices()
+
+
+@pytest.mark.asyncio
+async def test_without_cold_storage_nothing_moves(temp_db):
+    await temp_db.save_prices("AAA.EU", _bars(30))
+
+    assert await temp_db.tier_prices() == {"attached": False, "moved": 0}
+    assert temp_db.price_source == "prices"
+    assert (await temp_db.get_price_tier_stats())["hot"]["bars"] == 30
+
+
+@pytest.mark.asyncio
+async def test_old_bars_move_to_cold_and_queries_merge_tiers(temp_db):
+    await temp_db.save_prices("AAA.EU", _bars(30))
+    await temp_db.save_prices("BBB.EU", _bars(5))
+
+    result = await _tiered(temp_db)
+
+    assert result["moved"] == 19
+    stats = await temp_db.get_price_tier_stats()
+    assert stats["hot"]["bars"] == 16
+    assert stats["cold"]["bars"] == 19
+    # Long lookbacks see both tiers, newest first
+    prices = await temp_db.get_prices("AAA.EU")
+    assert len(prices) == 30
+    assert prices[-1]["close"] == 129.0
+    assert len(await temp_db.get_prices("AAA.EU", days=12)) == 12
+    bulk = await temp_db.get_prices_bulk(["AAA.EU", "BBB.EU"], days=25)
+    assert len(bulk["AAA.EU"]) == 25 and len(bulk["BBB.EU"]) == 5
+    assert len((await temp_db.get_prices_for_symbols(["AAA.EU"]))["AAA.EU"]) == 30
+
+
+@pytest.mark.asyncio
+async def test_hot_row_wins_over_cold_duplicate(temp_db):
+    await temp_db.save_prices("AAA.EU", _bars(30))
+    await _tiered(temp_db)
+    old_day = (date.today() - timedelta(days=25)).isoformat()
+    # A backfill re-writes an old bar into the hot tier
+    await temp_db.save_prices("AAA.EU", [{"date": old_day, "close": 1.0}])
+
+    prices = await temp_db.get_prices("AAA.EU")
+    assert len(prices) == 30
+    assert [p["close"] for p in prices if p["date"] == old_day] == [1.0]
+    # The next run moves it out again
+    assert (await temp_db.tier_prices())["moved"] == 1
+
+
+@pytest.mark.asyncio
+async def test_rename_and_dry_run_cover_the_cold_tier(temp_db):
+    await temp_db.upsert_security("OLD.EU", currency="EUR")
+    await temp_db.save_prices("OLD.EU", _bars(30))
+    await _tiered(temp_db)
+
+    async with dry_run(temp_db):
+        assert len(await temp_db.get_prices("OLD.EU")) == 30
+
+    moved = await temp_db.rename_symbol("OLD.EU", "NEW.EU")
+    assert moved["prices"] == 30
+    assert await temp_db.get_prices("OLD.EU") == []
+    assert len(await temp_db.get_prices("NEW.EU")) == 30
+
+
+@pytest.mark.asyncio
+async def test_unavailable_cold_storage_falls_back_to_hot(temp_db):
+    await temp_db.save_prices("AAA.EU", _bars(30))
+    await temp_db.set_setting("price_cold_storage_path", "/nonexistent-mount/cold.db")
+
+    assert await temp_db.tier_prices() == {"attached": False, "moved": 0}
+    assert len(await temp_db.get_prices("AAA.EU")) == 30
+    assert not os.path.exists("/nonexistent-mount")
@@ -0,0 +1,96 @@
+"""Tests for on-demand runtime profiling."""
+
+import asyncio
+import pstats
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.profiling import KEEP_PROFILES, ProfilingService
+
+
+def _service(tmp_path, enabled=True):
+    settings = MagicMock()
+    settings.get = AsyncMock(return_value=enabled)
+    return ProfilingService(settings=settings, directory=tmp_path)
+
+
+async def _busy(seconds: float) -> None:
+    loop = asyncio.get_running_loop()
+    end = loop.time() + seconds
+    while loop.time() < end:
+        sum(i * i for i in range(1000))
+        await asyncio.sleep(0)
+
+
+@pytest.mark.asyncio
+async def test_cpu_capture_is_loadable_and_renders(tmp_path):
+    service = _service(tmp_path)
+    capture = asyncio.create_task(service.capture("cpu", 0.2))
+    await _busy(0.1)
+    profile = await capture
+
+    assert profile["kind"] == "cpu"
+    assert profile["size"] > 0
+    assert service.active() is None
+    stats = pstats.Stats(str(service.path(profile["name"])))
+    assert any(func[2] == "_busy" for func in stats.stats)
+    assert "_busy" in service.render_text(profile["name"])
+
+
+@pytest.mark.asyncio
+async def test_heap_capture_reports_allocations(tmp_path):
+    service = _service(tmp_path)
+    capture = asyncio.create_task(service.capture("heap", 0.1))
+    retained = [bytearray(1024) for _ in range(100)]
+    profile = await capture
+
+    assert profile["name"].endswith(".tracemalloc")
+    assert "Allocated during capture" in service.render_text(profile["name"])
+    assert len(retained) == 100
+
+
+@pytest.mark.asyncio
+async def test_one_capture_at_a_time_and_validation(tmp_path):
+    service = _service(tmp_path)
+    with pytest.raises(ValueError):
+        service.start("threads", 1)
+    with pytest.raises(ValueError):
+        service.start("cpu", 0)
+
+    active = service.start("cpu", 0.05)
+    assert service.active()["name"] == active["name"]
+    with pytest.raises(RuntimeError):
+        service.start("heap", 1)
+    await asyncio.sleep(0.2)
+    assert service.active() is None
+
+
+@pytest.mark.asyncio
+async def test_stored_profiles_are_pruned_and_names_checked(tmp_path):
+    service = _service(tmp_path)
+    for i in range(KEEP_PROFILES + 2):
+        (tmp_path / f"cpu-{1000 + i}.pstats").write_bytes(b"x")
+    (tmp_path / "notes.txt").write_text("ignored")
+    service._prune()
+
+    names = [p["name"] for p in service.list_profiles()]
+    assert len(names) == KEEP_PROFILES
+    assert names[0] == f"cpu-{1000 + KEEP_PROFILES + 1}.pstats"
+    with pytest.raises(LookupError):
+        service.path("../sentinel.db")
+    with pytest.raises(LookupError):
+        service.path("notes.txt")
+
+
+@pytest.mark.asyncio
+async def test_endpoints_are_hidden_while_disabled(tmp_path):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.profiling import _profiling
+
+    deps = MagicMock()
+    deps.settings.get = AsyncMock(return_value=False)
+    with pytest.raises(HTTPException) as exc:
+        await _profiling(deps)
+    assert exc.value.status_code == 404
@@ -0,0 +1,129 @@
+"""Tests for protective stop-loss and trailing-stop order policies."""
+
+import pytest
+
+from sentinel.services.protective_orders import (
+    ProtectiveOrderService,
+    matching_policy,
+    parse_protective_policies,
+)
+
+POLICIES = [
+    {"name": "tech-trailing", "kind": "trailing_stop", "percent": 15, "industries": ["Technology"]},
+    {"name": "default-stop", "kind": "stop_loss", "percent": 10},
+]
+
+
+class _Settings:
+    def __init__(self, **values):
+        self._values = {"protective_orders_enabled": True, "protective_order_policies": POLICIES, **values}
+
+    async def get(self, key, default=None):
+        return self._values.get(key, default)
+
+
+class _Broker:
+    def __init__(self):
+        self.placed = []
+        self.cancelled = []
+        self.reject = False
+
+    async def stop_loss(self, symbol, price):
+        return self._place("stop_loss", symbol, price)
+
+    async def trailing_stop(self, symbol, percent):
+        return self._place("trailing_stop", symbol, percent)
+
+    def _place(self, kind, symbol, value):
+        if self.reject:
+            return None
+        self.placed.append((kind, symbol, value))
+        return f"RESEARCH-{len(self.placed)}"
+
+    async def cancel_order(self, order_id):
+        self.cancelled.append(order_id)
+        return True
+
+
+def test_parse_and_match_policies():
+    policies = parse_protective_policies(POLICIES)
+    assert [p["percent"] for p in policies] == [15.0, 10.0]
+    for bad, message in [
+        ({"kind": "stop"}, "kind"),
+        ({"kind": "stop_loss", "percent": 0}, "percent"),
+        ({"kind": "stop_loss", "percent": 5, "industries": "Technology"}, "industries"),
+        ({"kind": "stop_loss", "percent": 5, "min_volatility": -1}, "min_volatility"),
+        ({"kind": "stop_loss", "percent": 5, "limit": 3}, "unknown keys"),
+    ]:
+        with pytest.raises(ValueError, match=message):
+            parse_protective_policies([bad])
+
+    tech = {"symbol": "AAPL.US", "industry": "Technology, Hardware", "geography": "US"}
+    bank = {"symbol": "JPM.US", "industry": "Financials", "geography": "US"}
+    assert matching_policy(policies, tech, None)["name"] == "tech-trailing"
+    assert matching_policy(policies, bank, None)["name"] == "default-stop"
+    volatile = [{"name": "volatile", "kind": "trailing_stop", "percent": 15, "min_volatility": 0.4}]
+    assert matching_policy(volatile, bank, 0.5)["name"] == "volatile"
+    assert matching_policy(volatile, bank, 0.2) is None
+    assert matching_policy(volatile, bank, None) is None
+
+
+@pytest.mark.asyncio
+async def test_reconcile_places_raises_and_cancels(temp_db):
+    await temp_db.upsert_security("AAPL.US", currency="USD", industry="Technology")
+    await temp_db.upsert_security("JPM.US", currency="USD", industry="Financials")
+    await temp_db.upsert_position("AAPL.US", quantity=10, avg_cost=150.0, current_price=200.0)
+    await temp_db.upsert_position("JPM.US", quantity=5, avg_cost=100.0, current_price=100.0)
+    broker = _Broker()
+    service = ProtectiveOrderService(db=temp_db, broker=broker, settings=_Settings())
+
+    summary = await service.reconcile()
+    assert (summary["placed"], summary["kept"]) == (2, 0)
+    assert broker.placed == [("trailing_stop", "AAPL.US", 15.0), ("stop_loss", "JPM.US", 90.0)]
+    assert (await service.reconcile())["kept"] == 2
+
+    # A small rise keeps the stop; a larger one raises it; a fall never lowers it
+    await temp_db.upsert_position("JPM.US", current_price=101.0)
+    assert (await service.reconcile())["replaced"] == 0
+    await temp_db.upsert_position("JPM.US", current_price=110.0)
+    assert (await service.reconcile())["replaced"] == 1
+    assert broker.placed[-1] == ("stop_loss", "JPM.US", 99.0)
+    await temp_db.upsert_position("JPM.US", current_price=95.0)
+    assert (await service.reconcile())["replaced"] == 0
+    assert (await temp_db.get_protective_orders())["JPM.US"]["stop_price"] == 99.0
+
+    # A closed position has its order cancelled
+    await temp_db.upsert_position("AAPL.US", quantity=0)
+    assert (await service.reconcile())["cancelled"] == 1
+    assert broker.cancelled == ["RESEARCH-2", "RESEARCH-1"]
+    assert list(await temp_db.get_protective_orders()) == ["JPM.US"]
+
+    adjustments = await service.adjustments()
+    assert [(a["symbol"], a["action"]) for a in adjustments] == [
+        ("AAPL.US", "cancel"),
+        ("JPM.US", "replace"),
+        ("JPM.US", "place"),
+        ("AAPL.US", "place"),
+    ]
+    assert adjustments[1]["previous_order_id"] == "RESEARCH-2"
+
+
+@pytest.mark.asyncio
+async def test_reconcile_leaves_orders_alone_on_bad_policies(temp_db):
+    await temp_db.upsert_security("JPM.US", currency="USD", industry="Financials")
+    await temp_db.upsert_position("JPM.US", quantity=5, avg_cost=100.0, current_price=100.0)
+    broker = _Broker()
+    await ProtectiveOrderService(db=temp_db, broker=broker, settings=_Settings()).reconcile()
+
+    bad = _Settings(protective_order_policies=[{"kind": "stop_loss"}])
+    summary = await ProtectiveOrderService(db=temp_db, broker=broker, settings=bad).reconcile()
+    assert summary["enabled"] is False
+    assert broker.cancelled == []
+    assert list(await temp_db.get_protective_orders()) == ["JPM.US"]
+
+    # A rejected replacement is recorded as a cancellation, so the next run places a new order
+    broker.reject = True
+    tighter = _Settings(protective_order_policies=[{"name": "default-stop", "kind": "stop_loss", "percent": 5}])
+    summary = await ProtectiveOrderService(db=temp_db, broker=broker, settings=tighter).reconcile()
+    assert summary["failed"] == 1
+    assert await temp_db.get_protective_orders() == {}
@@ -0,0 +1,65 @@
+"""Tests for the read-only public dashboard."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.public_dashboard import PublicDashboardService
+from sentinel.settings import Settings
+
+
+def _service(db):
+    portfolio = MagicMock()
+    portfolio.total_value = AsyncMock(return_value=2000.0)
+    portfolio.total_cash_eur = AsyncMock(return_value=100.0)
+    portfolio.get_allocations = AsyncMock(
+        return_value={
+            "by_security": {"AAA.US": 0.6, "BBB.EU": 0.35},
+            "by_geography": {"EU": 0.35, "US": 0.6},
+            "by_industry": {"Tech": 0.95},
+        }
+    )
+    return PublicDashboardService(db=db, portfolio=portfolio, currency=MagicMock())
+
+
+@pytest.mark.asyncio
+async def test_allocation_hides_symbols_by_default(temp_db):
+    service = _service(temp_db)
+    allocation = await service.allocation()
+    assert allocation == {"by_geography": {"US": 60.0, "EU": 35.0}, "by_industry": {"Tech": 95.0}}
+    assert list(allocation["by_geography"]) == ["US", "EU"]
+
+    await Settings().set("public_dashboard_hide_symbols", False)
+    assert (await service.allocation())["by_security"]["AAA.US"] == 60.0
+    assert (await service.summary())["cash_pct"] == 5.0
+
+
+@pytest.mark.asyncio
+async def test_performance_history(temp_db):
+    import time
+
+    today = int(time.time()) // 86400 * 86400
+    await temp_db.upsert_portfolio_snapshot(today - 86400, {"positions": {"A": {"value_eur": 900}}, "cash_eur": 100})
+    await temp_db.upsert_portfolio_snapshot(today, {"positions": {"A": {"value_eur": 1000}}, "cash_eur": 100})
+
+    performance = await _service(temp_db).performance(days=30)
+    assert [h["value_eur"] for h in performance["history"]] == [1000.0, 1100.0]
+    assert performance["change_pct"] == 10.0
+    assert (await _service(temp_db).performance(days=10**6))["days"] == 1825
+
+
+@pytest.mark.asyncio
+async def test_routes_404_until_enabled(temp_db):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.public import _dashboard
+
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.settings = Settings()
+    with pytest.raises(HTTPException) as exc:
+        await _dashboard(deps)
+    assert exc.value.status_code == 404
+
+    await deps.settings.set("public_dashboard_enabled", True)
+    assert isinstance(await _dashboard(deps), PublicDashboardService)
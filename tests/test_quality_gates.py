@@ -0,0 +1,84 @@
+"""Tests for the quality-gate backfill and its statistics."""
+
+from datetime import date, timedelta
+
+import pytest
+
+from sentinel.services.quality_gates import (
+    GATE_SETTINGS,
+    QualityGateService,
+    evaluate_gate_paths,
+    gate_statistics,
+    month_end_indices,
+)
+from sentinel.settings import DEFAULTS
+
+GATE_DEFAULTS = {key: float(DEFAULTS[key]) for key in GATE_SETTINGS}
+# Slow climb, then a 30% slide: deep dip with oversold RSI
+CRASH = [100.0 + i * 0.1 for i in range(200)] + [120 * (0.985**i) for i in range(25)]
+
+
+def test_evaluate_gate_paths():
+    assert evaluate_gate_paths([100.0] * 200, GATE_DEFAULTS)["paths"] == []
+
+    result = evaluate_gate_paths(CRASH, GATE_DEFAULTS)
+    assert result["paths"] == ["core_dip", "opportunity_t3"]
+    assert result["opp_score"] == pytest.approx(0.70, abs=0.01)
+
+    stricter = {**GATE_DEFAULTS, "strategy_min_opp_score": 0.9}
+    assert evaluate_gate_paths(CRASH, stricter)["paths"] == ["core_dip"]
+
+
+def test_month_end_indices():
+    dates = ["2024-01-30", "2024-01-31", "2024-02-01", "2024-02-29", "2024-03-01"]
+    assert month_end_indices(dates) == [("2024-01", 1), ("2024-02", 3), ("2024-03", 4)]
+
+
+def test_gate_statistics_usage_and_overlap():
+    rows = [
+        {"paths": ["core_dip", "opportunity_t3"]},
+        {"paths": ["core_dip"]},
+        {"paths": ["opportunity_t3", "core_dip"]},
+        {"paths": []},
+    ]
+    stats = gate_statistics(rows)
+    assert stats["evaluations"] == 4
+    assert stats["pass_rate"] == 0.75
+    assert stats["paths"]["core_dip"] == {
+        "count": 3,
+        "share_of_evaluations": 0.75,
+        "share_of_passes": 1.0,
+        "exclusive": 1,
+    }
+    assert stats["overlap"] == {"core_dip": {"opportunity_t3": {"count": 2, "jaccard": pytest.approx(0.6667)}}}
+    assert stats["redundant"] == ["opportunity_t3"]
+    assert "core_turn" in stats["unused"]
+    assert gate_statistics([])["pass_rate"] == 0.0
+
+
+@pytest.mark.asyncio
+async def test_backfill_stores_matrix_and_reports_stats(temp_db):
+    start = date(2023, 1, 2)
+    await temp_db.upsert_security("AAA.EU", currency="EUR")
+    await temp_db.save_prices(
+        "AAA.EU", [{"date": (start + timedelta(days=i)).isoformat(), "close": c} for i, c in enumerate(CRASH)]
+    )
+    await temp_db.upsert_security("BBB.EU", currency="EUR")
+    await temp_db.save_prices("BBB.EU", [{"date": "2023-01-02", "close": 10.0}])
+
+    service = QualityGateService(db=temp_db)
+    result = await service.backfill()
+
+    assert result["securities"] == 2
+    rows = await temp_db.get_quality_gate_backfill(symbol="AAA.EU")
+    # Only month ends with enough history for the signal are evaluated
+    assert rows[0]["month"] == "2023-05"
+    assert rows[-1]["month"] == "2023-08"
+    assert rows[-1]["paths"] == ["core_dip", "opportunity_t3"]
+    assert result["stats"]["evaluations"] == len(rows)
+    assert result["stats"]["settings"]["strategy_min_opp_score"] == 0.55
+
+    # Re-running replaces rather than duplicates
+    await service.backfill(["AAA.EU"])
+    assert len(await temp_db.get_quality_gate_backfill(symbol="AAA.EU")) == len(rows)
+    assert (await service.stats(since="2023-08"))["evaluations"] == 1
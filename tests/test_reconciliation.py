@@ -0,0 +1,137 @@
+"""Tests for the monthly broker reconciliation."""
+
+from datetime import date, datetime
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.reconciliation import (
+    ReconciliationService,
+    compare_cash_flows,
+    compare_dividends,
+    compare_trades,
+    month_bounds,
+    previous_period,
+)
+
+
+def _trade(trade_id: str, q: float = 10, p: float = 50.0, commission: float = 2.0) -> dict:
+    return {
+        "id": trade_id,
+        "symbol": "A.EU",
+        "side": "BUY",
+        "q": q,
+        "p": p,
+        "commission": commission,
+        "date": "2026-09-10 10:00:00",
+    }
+
+
+def _dividend(action_id: str, amount: float = 8.5, tax: float = 1.5) -> dict:
+    return {
+        "type_id": "dividend",
+        "corporate_action_id": action_id,
+        "ticker": "A.EU",
+        "date": "2026-09-15",
+        "amount": amount,
+        "currency": "EUR",
+        "tax_amount": 0,
+        "external_tax": tax,
+    }
+
+
+def _ledger_trade(trade_id: str, **fields) -> dict:
+    row = {"broker_trade_id": trade_id, "symbol": "A.EU", "side": "BUY", "quantity": 10, "price": 50.0}
+    row.update({"commission": 2.0, "executed_at": int(datetime(2026, 9, 10, 10).timestamp())}, **fields)
+    return row
+
+
+def test_periods():
+    assert month_bounds("2026-02") == ("2026-02-01", "2026-02-28")
+    assert previous_period(date(2026, 1, 3)) == "2025-12"
+    with pytest.raises(ValueError, match="YYYY-MM"):
+        month_bounds("2026-13")
+
+
+def test_compare_trades_by_severity():
+    broker = [_trade("1"), _trade("2", q=12, commission=3.0), _trade("3")]
+    ledger = [_ledger_trade("1"), _ledger_trade("2"), _ledger_trade("4")]
+    issues = compare_trades(broker, ledger, tolerance=0.01)
+    assert [(i["key"], i["field"], i["severity"]) for i in issues] == [
+        ("2", "quantity", "high"),
+        ("2", "commission", "medium"),
+        ("3", None, "high"),
+        ("4", None, "high"),
+    ]
+    assert issues[2]["message"] == "A.EU trade 3 is not in the ledger"
+    assert issues[3]["message"] == "A.EU trade 4 is not in the broker report"
+
+
+def test_compare_cash_flows_and_dividends():
+    deposit = {"date": "2026-09-01", "type_id": "card", "amount": 500.0, "currency": "EUR"}
+    fee = {"date": "2026-09-30", "type_id": "commission", "amount": -1.0, "currency": "EUR"}
+    issues = compare_cash_flows([deposit, deposit, fee], [deposit])
+    assert [(i["key"], i["severity"]) for i in issues] == [
+        ("2026-09-01/card", "high"),
+        ("2026-09-30/commission", "medium"),
+    ]
+
+    ledger = [{"id": "d1", "symbol": "A.EU", "date": "2026-09-15", "amount": 8.5, "currency": "EUR", "data": "{}"}]
+    issues = compare_dividends([_dividend("d1")], ledger, tolerance=0.01)
+    assert [(i["field"], i["severity"], i["broker"], i["ledger"]) for i in issues] == [
+        ("withholding_tax", "medium", 1.5, 0.0)
+    ]
+
+
+@pytest.mark.asyncio
+async def test_reconcile_stores_report_and_sign_off(temp_db):
+    await temp_db.upsert_security("A.EU", name="A", currency="EUR")
+    executed_at = int(datetime(2026, 9, 10, 10).timestamp())
+    await temp_db.upsert_trade("1", "A.EU", "BUY", 10, 50.0, executed_at, _trade("1"), commission=2.0)
+    await temp_db.upsert_dividend("d1", "A.EU", "2026-09-15", 8.5, "EUR", 8.5, _dividend("d1"))
+
+    broker = MagicMock()
+    broker.connected = True
+    broker.get_trades_history = AsyncMock(return_value=[_trade("1")])
+    broker.get_cash_flows = AsyncMock(return_value=[])
+    broker.get_corporate_actions = AsyncMock(return_value=[_dividend("d1", amount=9.0)])
+    service = ReconciliationService(db=temp_db, broker=broker)
+
+    report = await service.reconcile("2026-09")
+    broker.get_trades_history.assert_awaited_once_with(start_date="2026-09-01", end_date="2026-09-30")
+    assert report["status"] == "open"
+    assert report["summary"]["severity"] == {"high": 1, "medium": 0, "low": 0}
+    assert report["discrepancies"][0]["message"] == "A.EU dividend d1 amount differs"
+    assert (await temp_db.get_notifications())[0]["category"] == "reconciliation"
+
+    # The job does not run a month twice
+    assert await service.reconcile_if_due(today=date(2026, 10, 2)) is None
+
+    with pytest.raises(ValueError, match="note is required"):
+        await service.sign_off(report["id"], "admin")
+    signed = await service.sign_off(report["id"], "admin", "Broker corrected the amount on 2026-10-01")
+    assert (signed["status"], signed["signed_off_by"]) == ("signed_off", "admin")
+    with pytest.raises(ValueError, match="already signed off"):
+        await service.sign_off(report["id"], "admin", "again")
+
+    with pytest.raises(ValueError, match="has not ended"):
+        await service.reconcile(date.today().strftime("%Y-%m"))
+
+
+@pytest.mark.asyncio
+async def test_archived_trades_are_still_in_the_ledger(temp_db):
+    await temp_db.upsert_security("A.EU", name="A", currency="EUR")
+    executed_at = int(datetime(2026, 9, 10, 10).timestamp())
+    trade_id = await temp_db.upsert_trade("1", "A.EU", "BUY", 10, 50.0, executed_at, _trade("1"), commission=2.0)
+    await temp_db.archive_position(
+        {"symbol": "A.EU", "opened_at": executed_at, "closed_at": executed_at}, [trade_id], []
+    )
+
+    broker = MagicMock()
+    broker.connected = True
+    broker.get_trades_history = AsyncMock(return_value=[_trade("1")])
+    broker.get_cash_flows = AsyncMock(return_value=[])
+    broker.get_corporate_actions = AsyncMock(return_value=[])
+
+    report = await ReconciliationService(db=temp_db, broker=broker).reconcile("2026-09")
+    assert report["discrepancies"] == []
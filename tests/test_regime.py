@@ -0,0 +1,149 @@
+"""Tests for the ensemble market regime detector."""
+
+import math
+from datetime import date, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner.allocation import AllocationCalculator
+from sentinel.services.regime import RegimeService, region_index_symbol
+from sentinel.strategy import DETECTORS, SignalDetector, classify_regime, detect_regime, parse_detector_weights
+from sentinel.strategy.regime import breadth_signal, drawdown_signal, trend_signal, volatility_signal
+
+
+def _series(days: int, daily: float, wobble: float = 0.002) -> list[float]:
+    """Closes drifting by `daily` per day with a small oscillation (so volatility is not zero)."""
+    closes = [100.0]
+    for i in range(1, days):
+        closes.append(closes[-1] * (1.0 + daily + wobble * math.sin(i)))
+    return closes
+
+
+RISING = _series(300, 0.002)
+FALLING = _series(300, -0.003)
+
+
+def test_individual_signals():
+    assert trend_signal(RISING) == pytest.approx(1.0)
+    assert trend_signal(FALLING) == pytest.approx(-1.0)
+    assert trend_signal(RISING[:100]) is None
+
+    assert drawdown_signal(RISING) == 1.0
+    assert drawdown_signal(FALLING) == -1.0
+    assert drawdown_signal([100.0, 87.5]) == pytest.approx(0.0)
+
+    # A volatility spike at the end of a calm year is the most volatile of the year
+    spike = RISING[:-20] + [RISING[-21] * (1.0 + 0.05 * (-1) ** i) for i in range(20)]
+    assert volatility_signal(spike) == pytest.approx(-1.0)
+    assert volatility_signal(RISING[:50]) is None
+
+    assert breadth_signal([RISING, RISING, FALLING, RISING[:50]]) == pytest.approx(2 * 2 / 3 - 1)
+    assert breadth_signal([RISING[:50]]) is None
+
+
+def test_classification_and_confidence():
+    bull = classify_regime({"trend": 1.0, "volatility": 0.5, "drawdown": 1.0, "breadth": -0.5})
+    assert bull["regime"] == "bull"
+    # Breadth (weight 0.2) disagrees
+    assert bull["confidence"] == pytest.approx(0.8)
+
+    bear = classify_regime({"trend": -1.0, "volatility": None, "drawdown": -1.0, "breadth": None})
+    assert (bear["regime"], bear["score"]) == ("bear", -1.0)
+    # Unanimous, but only 60% of the ensemble could be computed
+    assert bear["confidence"] == pytest.approx(0.6)
+
+    neutral = classify_regime({"trend": 0.1, "volatility": -0.1, "drawdown": 0.0, "breadth": 0.0})
+    assert neutral["regime"] == "neutral"
+    assert neutral["confidence"] > 0.8
+
+    empty = classify_regime({"trend": None, "volatility": None, "drawdown": None, "breadth": None})
+    assert (empty["regime"], empty["confidence"]) == ("neutral", 0.0)
+
+    assert detect_regime(FALLING, [FALLING, FALLING])["regime"] == "bear"
+
+
+async def _save_closes(db, symbol: str, closes: list[float]) -> None:
+    start = date(2025, 1, 1)
+    await db.save_prices(
+        symbol, [{"date": (start + timedelta(days=i)).isoformat(), "close": c} for i, c in enumerate(closes)]
+    )
+
+
+@pytest.mark.asyncio
+async def test_service_classifies_regions_from_aggregates_and_breadth(temp_db):
+    for symbol, geography in (("US1", "US"), ("US2", "US, Europe"), ("EU1", "Europe"), ("NOGEO", "")):
+        await temp_db.upsert_security(symbol, name=symbol, currency="EUR", active=1, geography=geography)
+    await _save_closes(temp_db, region_index_symbol("US"), RISING)
+    await _save_closes(temp_db, "US1", RISING)
+    await _save_closes(temp_db, "US2", RISING)
+    await _save_closes(temp_db, region_index_symbol("Europe"), FALLING)
+    await _save_closes(temp_db, "EU1", FALLING)
+    service = RegimeService(db=temp_db)
+
+    states = await service.compute()
+
+    assert states["US"]["regime"] == "bull"
+    assert states["Europe"]["regime"] == "bear"
+    stored = (await service.status())["regions"]
+    assert [(s["region"], s["regime"]) for s in stored] == [("Europe", "bear"), ("US", "bull")]
+    assert set(stored[0]["signals"]) == {"trend", "volatility", "drawdown", "breadth"}
+
+
+@pytest.mark.asyncio
+async def test_calculator_scores_securities_by_region_regime():
+    db = MagicMock()
+    db.get_regime_states = AsyncMock(
+        return_value=[
+            {"region": "US", "score": 0.8, "confidence": 0.5},
+            {"region": "Europe", "score": -1.0, "confidence": 1.0},
+        ]
+    )
+    calculator = AllocationCalculator(db=db, portfolio=MagicMock(), currency=MagicMock(), settings=MagicMock())
+
+    regimes = await calculator._load_regimes()
+
+    assert regimes == {"US": pytest.approx(0.4), "Europe": -1.0}
+    assert calculator._regime_score({"geography": "US, Europe"}, regimes) == pytest.approx(-0.3)
+    assert calculator._regime_score({"geography": "Asia"}, regimes) == 0.0
+
+
+def test_majority_policy_and_weights():
+    signals = {"trend": 1.0, "volatility": -0.5, "drawdown": 0.1, "breadth": 0.5}
+    # Weighted average 0.375 is bull; the vote is bull 0.55 vs bear 0.2 vs neutral 0.25
+    majority = classify_regime(signals, policy="majority")
+    assert majority["regime"] == "bull"
+    assert majority["confidence"] == pytest.approx(0.55)
+
+    tied = classify_regime({"trend": 1.0, "drawdown": -1.0}, {"trend": 0.5, "drawdown": 0.5}, policy="majority")
+    assert tied["regime"] == "neutral"
+
+    # A stricter threshold turns the weak trend-only reading neutral
+    assert classify_regime({"trend": 0.3}, {"trend": 1.0}, threshold=0.5)["regime"] == "neutral"
+
+    assert parse_detector_weights({"trend": 1, "breadth": 0}) == {"trend": 1.0, "breadth": 0.0}
+    for bad in ({"momentum": 1}, {"trend": -1}, {"trend": 0}, []):
+        with pytest.raises(ValueError):
+            parse_detector_weights(bad)
+
+
+@pytest.mark.asyncio
+async def test_added_detector_is_compared_before_it_counts(temp_db, monkeypatch):
+    monkeypatch.setitem(
+        DETECTORS, "contrarian", SignalDetector("contrarian", "Always risk-off", lambda closes: -1.0)
+    )
+    await temp_db.upsert_security("US1", name="US1", currency="EUR", active=1, geography="US")
+    await _save_closes(temp_db, region_index_symbol("US"), RISING)
+    await _save_closes(temp_db, "US1", RISING)
+    service = RegimeService(db=temp_db)
+
+    states = await service.compute()
+    assert states["US"]["regime"] == "bull"
+    assert states["US"]["signals"]["contrarian"] == -1.0
+    detectors = await service.detectors()
+    assert {d["name"]: d["weight"] for d in detectors["detectors"]}["contrarian"] == 0.0
+
+    await temp_db.set_setting("regime_detector_weights", {"trend": 0.2, "contrarian": 1.0})
+    await temp_db.set_setting("regime_vote_policy", "majority")
+    states = await service.compute()
+    assert states["US"]["regime"] == "bear"
@@ -0,0 +1,116 @@
+"""Tests for the digest report generator."""
+
+from datetime import datetime, timezone
+from types import SimpleNamespace
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.reports import ReportService, render_html, render_markdown
+from sentinel.settings import Settings
+
+NOW = int(datetime(2026, 3, 10, 18, 0, tzinfo=timezone.utc).timestamp())
+DAY = 86400
+
+
+def _service(db, recommendations=()):
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, ccy: amount * (0.5 if ccy == "USD" else 1.0))
+    planner = MagicMock()
+    planner.get_recommendations = AsyncMock(return_value=list(recommendations))
+    return ReportService(db=db, currency=currency, planner=planner)
+
+
+def _rec(symbol, action="buy"):
+    return SimpleNamespace(symbol=symbol, action=action, quantity=2, value_delta_eur=-300.0, reason="dip entry")
+
+
+@pytest.mark.asyncio
+async def test_compile_collects_the_period(temp_db, monkeypatch):
+    monkeypatch.setattr("time.time", lambda: NOW)
+    await temp_db.upsert_security("AAA.US", currency="USD")
+    await temp_db.upsert_security("BBB.EU", currency="EUR")
+    await temp_db.upsert_position("AAA.US", quantity=10)
+    await temp_db.upsert_position("BBB.EU", quantity=5)
+    for symbol, (before, after) in {"AAA.US": (100.0, 110.0), "BBB.EU": (50.0, 49.0)}.items():
+        await temp_db.save_prices(
+            symbol, [{"date": "2026-03-09", "close": before}, {"date": "2026-03-10", "close": after}]
+        )
+    await temp_db.upsert_portfolio_snapshot(NOW - 2 * DAY, {"positions": {"A": {"value_eur": 900}}, "cash_eur": 100})
+    await temp_db.upsert_portfolio_snapshot(NOW - DAY, {"positions": {"A": {"value_eur": 950}}, "cash_eur": 50})
+    await temp_db.upsert_portfolio_snapshot(NOW, {"positions": {"A": {"value_eur": 1050}}, "cash_eur": 50})
+    await temp_db.upsert_trade("t1", "AAA.US", "BUY", 2, 105.0, NOW - 3600, {})
+    await temp_db.upsert_trade("t0", "AAA.US", "BUY", 1, 90.0, NOW - 3 * DAY, {})
+    await temp_db.upsert_dividend("d1", "BBB.EU", "2026-03-10", 1.5, "EUR", 1.5, {})
+    await temp_db.add_notification("warning", "balance", "Negative USD balance not fully covered")
+    await temp_db.add_notification("info", "trade", "BUY 2 x AAA.US")
+
+    data = await _service(temp_db, [_rec("AAA.US")]).compile("daily", now=NOW)
+
+    assert data["portfolio"] == {
+        "start_value_eur": 1000.0,
+        "end_value_eur": 1100.0,
+        "change_eur": 100.0,
+        "change_pct": 10.0,
+    }
+    [trade] = data["trades"]
+    assert trade["value_eur"] == 105.0  # 210 USD at 0.5
+    assert [d["symbol"] for d in data["dividends"]] == ["BBB.EU"]
+    assert data["recommendations"][0]["new"] is True
+    assert [w["title"] for w in data["risk_warnings"]] == ["Negative USD balance not fully covered"]
+    assert [(m["symbol"], m["change_pct"]) for m in data["top_movers"]] == [("AAA.US", 10.0), ("BBB.EU", -2.0)]
+
+    markdown = render_markdown(data)
+    assert markdown.startswith("# Daily digest 2026-03-10")
+    assert "- BUY 2 x AAA.US @ 105.00 USD (EUR 105.00)" in markdown
+    assert "## Dividends received (EUR 1.50)" in markdown
+    assert "<li>AAA.US: +10.00%</li>" in render_html(data)
+
+
+@pytest.mark.asyncio
+async def test_generate_stores_flags_new_and_notifies(temp_db):
+    service = _service(temp_db, [_rec("AAA.US")])
+    first = await service.generate("weekly", now=NOW)
+    assert first["data"]["portfolio"] is None
+    assert "## Recommendations (1 new)" in first["markdown"]
+
+    [notification] = await temp_db.get_notifications()
+    assert notification["category"] == "report"
+    assert notification["link"] == f"/api/reports/{first['id']}?format=html"
+
+    service._planner.get_recommendations.return_value = [_rec("AAA.US"), _rec("CCC.EU", "sell")]
+    second = await service.generate("weekly", notify=False, now=NOW + 7 * DAY)
+    assert [r["new"] for r in second["data"]["recommendations"]] == [False, True]
+    assert (await temp_db.get_report(period="weekly"))["id"] == second["id"]
+    assert len(await temp_db.get_notifications()) == 1
+
+
+@pytest.mark.asyncio
+async def test_generate_if_due_waits_a_full_period(temp_db):
+    await Settings().set("report_digest_period", "weekly")
+    service = _service(temp_db)
+    assert await service.generate_if_due(now=NOW) is not None
+    assert await service.generate_if_due(now=NOW + 3 * DAY) is None
+    assert await service.generate_if_due(now=NOW + 7 * DAY) is not None
+    assert len(await temp_db.get_reports(period="weekly")) == 2
+
+
+@pytest.mark.asyncio
+async def test_latest_endpoint_formats(temp_db):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.reports import get_latest_report
+
+    deps = MagicMock()
+    deps.db = temp_db
+    with pytest.raises(HTTPException) as exc:
+        await get_latest_report(deps)
+    assert exc.value.status_code == 404
+
+    report = await _service(temp_db).generate("daily", notify=False, now=NOW)
+    assert (await get_latest_report(deps))["id"] == report["id"]
+    response = await get_latest_report(deps, format="markdown", download=True)
+    assert response.body.decode() == report["markdown"]
+    assert response.headers["content-disposition"].endswith(f'-{report["id"]}.md"')
+    with pytest.raises(HTTPException):
+        await get_latest_report(deps, format="pdf")
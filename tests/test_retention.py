@@ -0,0 +1,78 @@
+"""Tests for table retention policies and database vacuuming."""
+
+import pytest
+
+from sentinel.services.retention import RetentionService, parse_retention_policies
+from sentinel.settings import Settings
+
+NOW = 1772409600
+DAY = 86400
+
+
+def _service(db) -> RetentionService:
+    settings = Settings()
+    settings._db = db
+    return RetentionService(db=db, settings=settings)
+
+
+def test_policies_are_validated():
+    assert parse_retention_policies({"job_history": 90, "cache": 0}) == {"job_history": 90, "cache": 0}
+    for bad in ({"trades": 30}, {"cache": -1}, {"cache": 1.5}, {"cache": "30"}, ["cache"]):
+        with pytest.raises(ValueError):
+            parse_retention_policies(bad)
+
+
+@pytest.mark.asyncio
+async def test_prune_deletes_only_rows_past_their_policy(temp_db):
+    for i, executed_at in enumerate((NOW - 100 * DAY, NOW - 10 * DAY)):
+        await temp_db.conn.execute(
+            "INSERT INTO job_history (job_id, job_type, status, executed_at) VALUES (?, 'sync:prices', 'completed', ?)",
+            (f"job-{i}", executed_at),
+        )
+    await temp_db.conn.executemany(
+        "INSERT INTO cache (key, value, expires_at) VALUES (?, 'x', ?)",
+        [("old", NOW - 40 * DAY), ("recent", NOW - DAY), ("forever", None)],
+    )
+    await temp_db.conn.executemany(
+        "INSERT INTO notifications (created_at, updated_at, severity, category, title, read_at) "
+        "VALUES (?, ?, 'info', 'job', 't', ?)",
+        [(NOW - 400 * DAY, NOW - 400 * DAY, NOW), (NOW - 400 * DAY, NOW - 400 * DAY, None)],
+    )
+    await temp_db.conn.commit()
+    service = _service(temp_db)
+    policies = {"job_history": 90, "cache": 30, "notifications": 180, "reports": 0}
+    await temp_db.set_setting("retention_policies", policies)
+
+    report = await service.prune(now=NOW)
+
+    assert report["tables"] == {
+        "cache": {"days": 30, "deleted": 1},
+        "job_history": {"days": 90, "deleted": 1},
+        "notifications": {"days": 180, "deleted": 1},
+    }
+    assert report["deleted"] == 3
+    cursor = await temp_db.conn.execute("SELECT key FROM cache WHERE key IN ('old', 'recent', 'forever') ORDER BY key")
+    assert [row["key"] for row in await cursor.fetchall()] == ["forever", "recent"]
+    # Unread notifications are never pruned
+    assert len(await temp_db.get_notifications()) == 1
+    assert (await service.status())["last_retention"] == report
+
+
+@pytest.mark.asyncio
+async def test_vacuum_switches_to_incremental_mode_and_reports_sizes(temp_db):
+    await temp_db.conn.executemany(
+        "INSERT INTO cache (key, value, expires_at) VALUES (?, ?, 1)", [(f"k{i}", "x" * 2000) for i in range(200)]
+    )
+    await temp_db.conn.commit()
+    await temp_db.prune_table("cache", NOW)
+    service = _service(temp_db)
+
+    first = await service.vacuum()
+    [main] = first["databases"]
+    assert (main["database"], main["mode"]) == ("main", "full")
+    assert main["bytes_after"] < main["bytes_before"]
+    assert first["freed_bytes"] == main["bytes_before"] - main["bytes_after"]
+
+    second = await service.vacuum()
+    assert second["databases"][0]["mode"] == "incremental"
+    assert (await service.status())["last_vacuum"] == second
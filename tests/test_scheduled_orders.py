@@ -0,0 +1,168 @@
+"""Tests for scheduled orders and their execution."""
+
+import json
+from datetime import date, datetime, timezone
+from unittest.mock import AsyncMock
+
+import pytest
+
+from sentinel.services.scheduled_orders import (
+    ScheduledOrderService,
+    next_monthly_date,
+    timing_allows,
+    validate_order,
+)
+
+# Session 09:00-17:30 local, one hour ahead of UTC (two hours behind Moscow)
+MARKET = {"i": 7, "s": "OPEN", "o": "09:00:00", "c": "17:30:00", "dt": "-120"}
+AT_OPEN = datetime(2026, 11, 5, 8, 10, tzinfo=timezone.utc)  # 09:10 local
+AT_CLOSE = datetime(2026, 11, 5, 16, 15, tzinfo=timezone.utc)  # 17:15 local
+
+
+def make_broker(price: float = 50.0):
+    broker = AsyncMock()
+    broker.get_market_status = AsyncMock(return_value={"m": [MARKET]})
+    broker.get_quote = AsyncMock(return_value={"price": price})
+    return broker
+
+
+async def add_security(db, symbol: str, **fields) -> None:
+    await db.upsert_security(
+        symbol, name=symbol, currency="EUR", active=1, data=json.dumps({"mrkt": {"mkt_id": 7}}), **fields
+    )
+
+
+def test_monthly_dates_and_timing_windows():
+    assert next_monthly_date(5, date(2026, 11, 5)) == date(2026, 11, 5)
+    assert next_monthly_date(5, date(2026, 11, 5), inclusive=False) == date(2026, 12, 5)
+    assert next_monthly_date(3, date(2026, 12, 20)) == date(2027, 1, 3)
+
+    assert timing_allows(MARKET, "open", AT_OPEN, 30)
+    assert not timing_allows(MARKET, "close", AT_OPEN, 30)
+    assert timing_allows(MARKET, "close", AT_CLOSE, 30)
+    assert not timing_allows(MARKET, "open", AT_CLOSE, 30)
+    assert timing_allows(MARKET, "any", AT_CLOSE, 30)
+    assert not timing_allows({**MARKET, "s": "CLOSE"}, "any", AT_OPEN, 30)
+
+
+def test_validate_order():
+    today = date(2026, 11, 10)
+    monthly = validate_order(
+        {"frequency": "monthly", "day_of_month": 5, "symbols": "A.EU, B.EU", "amount_eur": 300}, today
+    )
+    assert monthly["symbols"] == ["A.EU", "B.EU"]
+    assert monthly["next_run_date"] == "2026-12-05"
+
+    group = validate_order(
+        {"frequency": "once", "run_date": "2026-11-12", "group": {"type": "geography", "name": "US"}, "amount_eur": 1},
+        today,
+    )
+    assert (group["group_type"], group["group_name"], group["top_n"]) == ("geography", "US", 1)
+
+    with pytest.raises(ValueError) as exc:
+        validate_order({"frequency": "monthly", "day_of_month": 31, "symbols": ["A.EU", "B.EU"], "quantity": 2}, today)
+    assert "day_of_month must be between 1 and 28" in str(exc.value)
+    assert "quantity needs exactly one symbol" in str(exc.value)
+    with pytest.raises(ValueError, match="run_date must not be in the past"):
+        validate_order({"run_date": "2026-11-01", "symbols": ["A.EU"], "amount_eur": 10}, today)
+    with pytest.raises(ValueError, match="group orders can only buy"):
+        validate_order(
+            {"action": "sell", "run_date": "2026-11-12", "group": {"type": "industry", "name": "Tech"}, "quantity": 1},
+            today,
+        )
+
+
+@pytest.mark.asyncio
+async def test_monthly_group_order_waits_for_its_window_then_advances(temp_db):
+    await temp_db.set_setting("trading_mode", "live")
+    for symbol, rank in (("A.EU", 0.9), ("B.EU", 0.4), ("C.EU", 0.7)):
+        await add_security(temp_db, symbol, geography="Europe")
+        await temp_db.save_score_states({symbol: {"core_rank": rank}}, "key")
+    service = ScheduledOrderService(db=temp_db)
+    order = await service.create(
+        {
+            "frequency": "monthly",
+            "day_of_month": 5,
+            "group": {"type": "geography", "name": "Europe", "top_n": 2},
+            "amount_eur": 1000,
+            "timing": "close",
+        },
+        today=date(2026, 11, 1),
+    )
+    assert await service.top_scored("geography", "Europe", 2) == ["A.EU", "C.EU"]
+
+    place = AsyncMock(return_value=True)
+    summary = await service.run_due(make_broker(), place, now=AT_OPEN)
+    assert summary["waiting"] == 2
+    place.assert_not_awaited()
+
+    summary = await service.run_due(make_broker(), place, now=AT_CLOSE)
+    assert summary["placed"] == 2
+    placed = [(call.args[0].symbol, call.args[0].quantity, call.args[1]) for call in place.await_args_list]
+    assert placed == [("A.EU", 10, f"scheduled:{order['id']}"), ("C.EU", 10, f"scheduled:{order['id']}")]
+
+    stored = await temp_db.get_scheduled_order(order["id"])
+    assert stored["next_run_date"] == "2026-12-05"
+    assert stored["run_state"] is None
+    assert [leg["status"] for leg in stored["last_result"]["legs"]] == ["placed", "placed"]
+    # Nothing is due again this month
+    assert (await service.run_due(make_broker(), place, now=AT_CLOSE))["placed"] == 0
+
+
+@pytest.mark.asyncio
+async def test_safety_checks_at_execution(temp_db):
+    await add_security(temp_db, "A.EU")
+    await add_security(temp_db, "B.EU", allow_buy=0)
+    service = ScheduledOrderService(db=temp_db)
+    once = await service.create(
+        {"run_date": "2026-11-05", "symbols": ["A.EU", "B.EU"], "amount_eur": 120}, today=date(2026, 11, 1)
+    )
+    place = AsyncMock(return_value=True)
+
+    # Research mode: legs are sized and logged, never placed
+    summary = await service.run_due(make_broker(), place, now=AT_OPEN)
+    assert summary == {"placed": 0, "failed": 0, "simulated": 1, "skipped": 1, "waiting": 0, "missed": 0}
+    place.assert_not_awaited()
+    stored = await temp_db.get_scheduled_order(once["id"])
+    assert stored["status"] == "completed"
+    legs = {leg["symbol"]: leg for leg in stored["last_result"]["legs"]}
+    assert legs["A.EU"]["status"] == "simulated" and legs["A.EU"]["quantity"] == 1
+    assert legs["B.EU"] == {"symbol": "B.EU", "status": "skipped", "detail": "buy not allowed"}
+
+    # Not placed within the grace period: missed
+    await temp_db.set_setting("trading_mode", "live")
+    late = await service.create({"run_date": "2026-11-05", "symbols": ["A.EU"], "quantity": 2}, today=date(2026, 11, 1))
+    broker = make_broker()
+    broker.get_market_status = AsyncMock(return_value={"m": [{**MARKET, "s": "CLOSE"}]})
+    assert (await service.run_due(broker, place, now=datetime(2026, 11, 20, 12, tzinfo=timezone.utc)))["missed"] == 1
+    assert (await temp_db.get_scheduled_order(late["id"]))["last_result"]["legs"][0]["status"] == "missed"
+
+    with pytest.raises(ValueError, match="Unknown symbols: X.EU"):
+        await service.create({"run_date": "2030-01-01", "symbols": ["X.EU"], "amount_eur": 10})
+    with pytest.raises(ValueError, match="is completed"):
+        await service.set_status(once["id"], "paused")
+
+
+@pytest.mark.asyncio
+async def test_ticker_rename_carries_over_to_scheduled_orders(temp_db):
+    await temp_db.set_setting("trading_mode", "live")
+    await add_security(temp_db, "OLD.EU")
+    await add_security(temp_db, "B.EU")
+    service = ScheduledOrderService(db=temp_db)
+    order = await service.create(
+        {"frequency": "monthly", "day_of_month": 5, "symbols": ["OLD.EU", "B.EU"], "amount_eur": 200},
+        today=date(2026, 11, 1),
+    )
+    legs = [{"symbol": "OLD.EU", "status": "pending"}, {"symbol": "B.EU", "status": "placed"}]
+    await temp_db.update_scheduled_order(order["id"], run_state={"legs": legs})
+
+    moved = await temp_db.rename_symbol("OLD.EU", "NEW.EU")
+
+    assert moved["scheduled_orders"] == 1
+    stored = await temp_db.get_scheduled_order(order["id"])
+    assert stored["symbols"] == ["NEW.EU", "B.EU"]
+    assert [leg["symbol"] for leg in stored["run_state"]["legs"]] == ["NEW.EU", "B.EU"]
+
+    place = AsyncMock(return_value=True)
+    assert (await service.run_due(make_broker(), place, now=AT_OPEN))["placed"] == 1
+    assert place.await_args.args[0].symbol == "NEW.EU"
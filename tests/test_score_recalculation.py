@@ -0,0 +1,70 @@
+"""Tests for bulk score recalculation."""
+
+import asyncio
+from datetime import date, timedelta
+
+import pytest
+
+from sentinel.jobs import get_run
+from sentinel.services.score_recalculation import ScoreRecalculationService
+
+
+async def _seed(db):
+    start = date(2024, 1, 1)
+    for symbol, geography in (("AAPL.US", "US"), ("SAP.EU", "Europe"), ("MSFT.US", "US")):
+        await db.upsert_security(symbol, name=symbol, geography=geography)
+        closes = [{"date": (start + timedelta(days=i)).isoformat(), "close": 100.0 + i % 7} for i in range(60)]
+        await db.save_prices(symbol, closes)
+
+
+async def finished(service: ScoreRecalculationService, run: dict) -> dict:
+    manual_run = get_run(run["run_id"])
+    while manual_run.status in ("queued", "running"):
+        await asyncio.sleep(0.01)
+    return service.status(run["run_id"])
+
+
+@pytest.mark.asyncio
+async def test_filtered_recalculation_scores_selection(temp_db):
+    await _seed(temp_db)
+    service = ScoreRecalculationService(db=temp_db)
+    assert await service.select({"geography": "US"}) == ["AAPL.US", "MSFT.US"]
+
+    run = await finished(service, await service.start({"geography": "US"}))
+
+    assert run["status"] == "completed"
+    assert run["percent"] == 100.0
+    assert run["result"] == {"status": "completed", "total": 2, "done": 2, "errors": []}
+    states = await temp_db.get_score_states(["AAPL.US", "MSFT.US", "SAP.EU"])
+    assert states["AAPL.US"]["signal"] and not states["AAPL.US"]["dirty"]
+    assert states["MSFT.US"]["signal"] and not states["MSFT.US"]["dirty"]
+    assert states["SAP.EU"]["signal"] is None
+    assert await service.select({"dirty_only": True}) == ["SAP.EU"]
+
+
+@pytest.mark.asyncio
+async def test_cancelled_recalculation_leaves_remaining_dirty(temp_db):
+    await _seed(temp_db)
+    service = ScoreRecalculationService(db=temp_db)
+
+    started = await service.start()
+    assert service.cancel(started["run_id"])["cancel_requested"] is True
+    run = await finished(service, started)
+
+    assert run["status"] == "cancelled"
+    assert run["result"]["done"] == 0
+    states = await temp_db.get_score_states(["AAPL.US", "MSFT.US", "SAP.EU"])
+    assert all("recalculate" in state["dirty"] and state["signal"] is None for state in states.values())
+    with pytest.raises(ValueError, match="already finished"):
+        service.cancel(started["run_id"])
+
+
+@pytest.mark.asyncio
+async def test_invalid_requests(temp_db):
+    service = ScoreRecalculationService(db=temp_db)
+    with pytest.raises(ValueError, match="Unknown filter sector"):
+        await service.start({"sector": "Tech"})
+    with pytest.raises(ValueError, match="No active security"):
+        await service.start({"symbols": ["NOPE.US"]})
+    with pytest.raises(LookupError):
+        service.status("missing")
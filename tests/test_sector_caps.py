@@ -0,0 +1,102 @@
+"""Tests for the GICS taxonomy and hierarchical sector caps."""
+
+import pytest
+
+from sentinel.config.gics import classify_gics, gics_path
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.sector_caps import (
+    apply_sector_caps,
+    limit_buys_to_sector_caps,
+    node_exposures,
+    symbol_sector_paths,
+)
+
+PATHS = {
+    "NVDA": ["4530", "45"],
+    "ASML": ["4530", "45"],
+    "MSFT": ["4510", "45"],
+    "JPM": ["4010", "40"],
+    "KO": ["3020", "30"],
+}
+
+
+def test_classify_prefers_exact_names_and_specific_keywords():
+    assert classify_gics("Semiconductors & Semiconductor Equipment") == "4530"
+    assert classify_gics("Energy") == "1010"
+    assert classify_gics(None, "Technology, Semiconductors") == "45"
+    assert classify_gics("Chip design") == "4530"
+    assert classify_gics("Something else") is None
+    assert gics_path("4530") == ["4530", "45"]
+
+
+def test_symbol_paths_fall_back_to_industry():
+    paths = symbol_sector_paths(
+        [
+            {"symbol": "A", "gics_code": "4010", "industry": "Software"},
+            {"symbol": "B", "industry": "Software"},
+            {"symbol": "C", "industry": "Unknown"},
+        ]
+    )
+    assert paths == {"A": ["4010", "40"], "B": ["4510", "45"]}
+
+
+def test_hierarchical_caps_scale_nested_nodes_and_redistribute():
+    weights = {"NVDA": 0.2, "ASML": 0.2, "MSFT": 0.2, "JPM": 0.2, "KO": 0.2}
+
+    capped = apply_sector_caps(weights, PATHS, {"45": 0.25, "4530": 0.10}, max_weight=0.5)
+    exposures = node_exposures(capped, PATHS)
+
+    # Semiconductors is cut to 10% first, then Tech as a whole scales down to 25%
+    assert exposures["4530"] == pytest.approx(0.25 * 0.10 / 0.30)
+    assert exposures["45"] == pytest.approx(0.25)
+    assert capped["JPM"] == pytest.approx(0.375)
+    assert capped["KO"] == pytest.approx(0.375)
+    assert sum(capped.values()) == pytest.approx(1.0)
+
+
+def test_caps_leave_cash_when_nothing_can_absorb():
+    capped = apply_sector_caps({"NVDA": 0.5, "MSFT": 0.5}, PATHS, {"45": 0.6})
+    assert sum(capped.values()) == pytest.approx(0.6)
+
+
+def _buy(symbol: str, value: float, quantity: float) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=value,
+        value_delta_eur=value,
+        quantity=quantity,
+        price=value / quantity,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=0.5,
+        priority=1.0,
+        reason="Underweight",
+    )
+
+
+def test_planner_buys_shrink_to_remaining_headroom():
+    current = {"NVDA": 0.08, "JPM": 0.1}
+    recs = [_buy("ASML", 1000.0, 10), _buy("NVDA", 500.0, 5), _buy("JPM", 500.0, 5)]
+
+    limited = limit_buys_to_sector_caps(recs, current, 10000.0, PATHS, {"4530": 0.10}, min_trade_value=100.0)
+
+    assert [(r.symbol, r.quantity) for r in limited] == [("ASML", 2), ("JPM", 5)]
+    assert limited[0].value_delta_eur == pytest.approx(200.0)
+    assert "sector cap" in limited[0].reason
+
+
+@pytest.mark.asyncio
+async def test_taxonomy_is_seeded_and_caps_persist(temp_db):
+    taxonomy = {row["code"]: row for row in await temp_db.get_sector_taxonomy()}
+    assert taxonomy["45"]["level"] == "sector"
+    assert taxonomy["4530"]["parent_code"] == "45"
+
+    await temp_db.set_sector_cap("45", 0.25)
+    await temp_db.set_sector_cap("4530", 0.1)
+    await temp_db.delete_sector_cap("45")
+    assert await temp_db.get_sector_caps() == {"4530": 0.1}
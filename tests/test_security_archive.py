@@ -0,0 +1,54 @@
+"""Tests for archiving and reactivating securities."""
+
+import pytest
+
+from sentinel.services.security_archive import SecurityArchiveService
+
+
+async def _seed(db):
+    await db.upsert_security("AAPL.US", name="Apple", allow_buy=0, allow_sell=1, geography="US")
+    await db.upsert_security("MSFT.US", name="Microsoft")
+    await db.save_prices("AAPL.US", [{"date": "2024-01-02", "close": 185.0}, {"date": "2024-01-03", "close": 184.0}])
+    await db.upsert_trade("T1", "AAPL.US", "BUY", 10, 150.0, 1685613600, {})
+    await db.upsert_position("AAPL.US", quantity=10, avg_cost=150.0)
+
+
+@pytest.mark.asyncio
+async def test_archive_keeps_history_and_leaves_universe(temp_db):
+    await _seed(temp_db)
+    service = SecurityArchiveService(db=temp_db)
+
+    await service.archive("AAPL.US", "  Thesis broken  ", archived_by="alice")
+
+    assert [s["symbol"] for s in await temp_db.get_all_securities()] == ["MSFT.US"]
+    assert await temp_db.get_position("AAPL.US") is None
+    assert len(await temp_db.get_prices("AAPL.US")) == 2
+    [entry] = await service.archived()
+    assert entry["symbol"] == "AAPL.US"
+    assert entry["archive_reason"] == "Thesis broken"
+    assert entry["archived_by"] == "alice"
+    assert entry["price_count"] == 2
+    assert entry["last_price_date"] == "2024-01-03"
+    assert entry["trade_count"] == 1
+    with pytest.raises(ValueError, match="already archived"):
+        await service.archive("AAPL.US")
+    with pytest.raises(LookupError):
+        await service.archive("NOPE.US")
+
+
+@pytest.mark.asyncio
+async def test_reactivate_restores_flags_and_history(temp_db):
+    await _seed(temp_db)
+    service = SecurityArchiveService(db=temp_db)
+    await service.archive("AAPL.US", "Paused")
+
+    result = await service.reactivate("AAPL.US")
+
+    assert result == {"symbol": "AAPL.US", "active": 1, "allow_buy": 0, "allow_sell": 1}
+    security = await temp_db.get_security("AAPL.US")
+    assert security["geography"] == "US"
+    assert security["archived_at"] is None and security["archive_reason"] is None
+    assert len(await temp_db.get_prices("AAPL.US")) == 2
+    assert await service.archived() == []
+    with pytest.raises(ValueError, match="not archived"):
+        await service.reactivate("AAPL.US")
@@ -0,0 +1,80 @@
+"""Tests for ISIN identity and ticker changes."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.portfolio import Portfolio
+from sentinel.utils.identity import extract_isin, normalize_isin
+
+APPLE = "US0378331005"
+
+
+def test_normalize_isin_checks_format_and_check_digit():
+    assert normalize_isin(" us0378331005 ") == APPLE
+    assert normalize_isin("US0378331006") is None
+    assert normalize_isin("AAPL.US") is None
+    assert normalize_isin(None) is None
+    assert extract_isin({"issue_nb": "DE0007164600"}) == "DE0007164600"
+    assert extract_isin({"isin": "", "issue_nb": "bogus"}) is None
+
+
+async def _seed_old_ticker(db):
+    await db.upsert_security("OLD.US", name="Old Co", currency="USD", isin=APPLE)
+    await db.upsert_position("OLD.US", quantity=10, avg_cost=100.0)
+    await db.save_prices("OLD.US", [{"date": "2026-01-02", "open": 1, "high": 1, "low": 1, "close": 100.0}])
+    await db.upsert_strategy_state("OLD.US", sleeve="core")
+    await db.record_trade_decision("OLD.US", "buy", 10, "manual", order_id="ORD-1")
+
+
+@pytest.mark.asyncio
+async def test_rename_moves_history_and_keeps_old_symbol_resolving(temp_db):
+    await _seed_old_ticker(temp_db)
+
+    moved = await temp_db.rename_symbol("OLD.US", "NEW.US", reason="rename")
+
+    assert moved["positions"] == 1 and moved["prices"] == 1 and moved["trade_decisions"] == 1
+    assert await temp_db.get_security("OLD.US") is None
+    assert (await temp_db.get_security("NEW.US"))["isin"] == APPLE
+    assert (await temp_db.get_position("NEW.US"))["quantity"] == 10
+    assert (await temp_db.get_strategy_state("NEW.US"))["sleeve"] == "core"
+    assert await temp_db.resolve_symbol("OLD.US") == "NEW.US"
+    assert await temp_db.resolve_symbol(APPLE) == "NEW.US"
+    assert await temp_db.resolve_symbol("MISSING.US") is None
+    assert (await temp_db.get_symbol_history("NEW.US"))[0]["old_symbol"] == "OLD.US"
+
+
+@pytest.mark.asyncio
+async def test_rename_merges_into_existing_duplicate(temp_db):
+    await _seed_old_ticker(temp_db)
+    await temp_db.upsert_security("NEW.US", name="New Co", currency="USD")
+    await temp_db.save_prices("NEW.US", [{"date": "2026-01-02", "open": 2, "high": 2, "low": 2, "close": 101.0}])
+
+    await temp_db.rename_symbol("OLD.US", "NEW.US")
+
+    prices = await temp_db.get_prices("NEW.US")
+    assert [p["close"] for p in prices] == [101.0]
+    assert (await temp_db.get_security("NEW.US"))["isin"] == APPLE
+    assert await temp_db.get_prices("OLD.US") == []
+
+    with pytest.raises(LookupError):
+        await temp_db.rename_symbol("OLD.US", "NEWER.US")
+
+
+@pytest.mark.asyncio
+async def test_portfolio_sync_follows_broker_ticker_change(temp_db):
+    await _seed_old_ticker(temp_db)
+    broker = MagicMock()
+    broker.get_portfolio = AsyncMock(
+        return_value={
+            "positions": [{"symbol": "NEW.US", "isin": APPLE, "quantity": 10, "currency": "USD", "avg_cost": 100.0}],
+            "cash": {"EUR": 50.0},
+        }
+    )
+
+    await Portfolio(db=temp_db, broker=broker, currency=MagicMock()).sync()
+
+    securities = [s["symbol"] for s in await temp_db.get_all_securities(active_only=False)]
+    assert securities == ["NEW.US"]
+    assert (await temp_db.get_position("NEW.US"))["quantity"] == 10
+    assert await temp_db.resolve_symbol("OLD.US") == "NEW.US"
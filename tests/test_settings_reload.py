@@ -0,0 +1,111 @@
+"""Tests for settings change notifications and hot reload."""
+
+import os
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.database import Database
+from sentinel.dry_run import dry_run
+from sentinel.settings import Settings
+
+
+@pytest_asyncio.fixture
+async def settings():
+    fd, path = tempfile.mkstemp(suffix=".db")
+    os.close(fd)
+    db = Database(path)
+    await db.connect()
+    settings = Settings()
+    settings._db = db
+    settings._snapshot = None
+    yield settings
+    settings._listeners.clear()
+    settings._snapshot = None
+    await db.close()
+    db.remove_from_cache()
+    for ext in ["", "-wal", "-shm"]:
+        if os.path.exists(path + ext):
+            os.unlink(path + ext)
+
+
+class Recorder:
+    def __init__(self):
+        self.calls = []
+
+    async def __call__(self, changed):
+        self.calls.append(changed)
+
+
+@pytest.mark.asyncio
+async def test_set_notifies_only_listeners_of_the_key(settings):
+    led, everything = Recorder(), Recorder()
+    unsubscribe = settings.subscribe({"led_display_enabled"}, led)
+    settings.subscribe(None, everything)
+
+    await settings.set("led_display_enabled", True)
+    await settings.set("trading_mode", "live")
+    assert led.calls == [{"led_display_enabled": True}]
+    assert everything.calls == [{"led_display_enabled": True}, {"trading_mode": "live"}]
+
+    unsubscribe()
+    await settings.set("led_display_enabled", False)
+    assert len(led.calls) == 1
+
+
+@pytest.mark.asyncio
+async def test_reload_notifies_values_changed_outside_set(settings):
+    listener = Recorder()
+    settings.subscribe({"price_hot_days", "gpio_relay_pin"}, listener)
+
+    assert await settings.reload() == {}  # Baseline
+    await settings._db.set_settings_batch({"price_hot_days": 365, "max_position_pct": 20})
+    changed = await settings.reload()
+    assert changed == {"price_hot_days": 365, "max_position_pct": 20}
+    assert listener.calls == [{"price_hot_days": 365}]
+
+    # Values applied through set() are part of the baseline
+    await settings.set("gpio_relay_pin", 17)
+    assert await settings.reload() == {}
+    assert listener.calls == [{"price_hot_days": 365}, {"gpio_relay_pin": 17}]
+
+
+@pytest.mark.asyncio
+async def test_failing_listener_and_dry_run(settings):
+    async def broken(changed):
+        raise RuntimeError("no screen")
+
+    listener = Recorder()
+    settings.subscribe(None, broken)
+    settings.subscribe(None, listener)
+    await settings.set("display_screen_enabled", True)
+    assert listener.calls == [{"display_screen_enabled": True}]
+
+    # Dry-run writes are rolled back, so nothing is restarted for them
+    async with dry_run(settings._db):
+        await settings.set("display_screen_enabled", False)
+    assert len(listener.calls) == 1
+
+
+@pytest.mark.asyncio
+async def test_reload_endpoint_reschedules_jobs(settings, monkeypatch):
+    from sentinel.api.routers.settings import reload_settings
+
+    await settings._db.seed_default_job_schedules()
+    rescheduled = []
+
+    async def reschedule(job_type, db):
+        rescheduled.append(job_type)
+
+    monkeypatch.setattr("sentinel.jobs.reschedule", reschedule)
+    deps = MagicMock()
+    deps.db = settings._db
+    deps.settings = settings
+
+    await settings.reload()
+    await settings._db.set_setting("led_brightness", 100)
+    result = await reload_settings(deps)
+    assert result["changed"] == ["led_brightness"]
+    assert result["rescheduled"] == len(rescheduled) > 0
@@ -0,0 +1,102 @@
+"""Tests for shadow trials of proposed settings."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+import pytest_asyncio
+
+from sentinel.currency import Currency
+from sentinel.planner import Planner
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.replay import record_planner_run
+from sentinel.services.shadow import ShadowService
+
+BUYS_KEY = "strategy_max_opportunity_buys_per_cycle"
+
+
+@pytest_asyncio.fixture
+async def temp_db(temp_db):
+    Currency()._db = temp_db
+    return temp_db
+
+
+async def _fake_compute(self, min_trade_value, as_of_date):
+    """Stand-in planner: buys the quoted securities, as many as the buys-per-cycle setting allows."""
+    quotes = await self._broker.get_quotes([s["symbol"] for s in await self._db.get_all_securities()])
+    self._rebalance_engine.last_quotes = quotes
+    limit = int(await self._settings.get(BUYS_KEY))
+    return [
+        TradeRecommendation(
+            symbol=symbol,
+            action="buy",
+            current_allocation=0.0,
+            target_allocation=0.1,
+            allocation_delta=0.1,
+            current_value_eur=0.0,
+            target_value_eur=500.0,
+            value_delta_eur=500.0,
+            quantity=round(500 / quote["price"]),
+            price=quote["price"],
+            currency="EUR",
+            lot_size=1,
+            contrarian_score=0.4,
+            priority=1.0,
+            reason="Underweight",
+        )
+        for symbol, quote in sorted(quotes.items())
+    ][:limit]
+
+
+async def _live_run(db) -> int:
+    for symbol in ("AAA.EU", "BBB.EU"):
+        await db.upsert_security(symbol, currency="EUR")
+    broker = MagicMock()
+    broker.get_quotes = AsyncMock(return_value={"AAA.EU": {"price": 100.0}, "BBB.EU": {"price": 50.0}})
+    planner = Planner(db=db, broker=broker, portfolio=MagicMock())
+    return await record_planner_run(db, planner, await planner._compute_recommendations(None, None))
+
+
+@pytest.mark.asyncio
+async def test_trial_records_divergence_without_touching_live_settings(temp_db, monkeypatch):
+    monkeypatch.setattr(Planner, "_compute_recommendations", _fake_compute)
+    await temp_db.set_setting(BUYS_KEY, 1)
+    service = ShadowService(db=temp_db)
+    trial = await service.create("Two buys", {BUYS_KEY: 2}, created_by="alice")
+    snapshot_id = await _live_run(temp_db)
+
+    result = await service.run_trials(snapshot_id)
+    # A snapshot is only recorded once per trial
+    await service.run_trials(snapshot_id)
+
+    assert result == {"snapshot_id": snapshot_id, "runs": 1, "errors": []}
+    report = await service.report(trial["id"])
+    assert report["changes"] == {BUYS_KEY: {"live": 1, "proposed": 2}}
+    assert report["runs"] == 1
+    assert report["divergence_rate"] == 1.0
+    assert report["symbols"] == {"BBB.EU": {"added": 1, "removed": 0, "changed": 0}}
+    assert [r["symbol"] for r in report["recent"][0]["recommendations"]] == ["AAA.EU", "BBB.EU"]
+    assert await temp_db.get_setting(BUYS_KEY) == 1
+
+    promoted = await service.promote(trial["id"], ended_by="alice")
+    assert promoted["status"] == "promoted"
+    assert await temp_db.get_setting(BUYS_KEY) == 2
+    assert (await service.run_trials(snapshot_id))["runs"] == 0
+
+
+@pytest.mark.asyncio
+async def test_trial_validation_and_lifecycle(temp_db):
+    service = ShadowService(db=temp_db)
+    with pytest.raises(ValueError, match="non-empty"):
+        await service.create("Empty", {})
+    with pytest.raises(ValueError, match="Cannot trial setting no_such_setting"):
+        await service.create("Unknown", {"no_such_setting": 1})
+    with pytest.raises(ValueError, match=BUYS_KEY):
+        await service.create("Negative", {BUYS_KEY: -1})
+
+    trial = await service.create("Three buys", {BUYS_KEY: 3})
+    assert [t["id"] for t in await service.trials("active")] == [trial["id"]]
+    assert (await service.stop(trial["id"]))["status"] == "stopped"
+    with pytest.raises(ValueError, match="is stopped"):
+        await service.promote(trial["id"])
+    with pytest.raises(LookupError):
+        await service.report(trial["id"] + 1)
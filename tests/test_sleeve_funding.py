@@ -0,0 +1,78 @@
+"""Tests for risk-parity sleeve funding suggestions."""
+
+import json
+from datetime import date, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.sleeve_funding import SleeveFundingService, risk_parity_split, sleeve_returns, sleeve_risk
+from sentinel.settings import Settings
+
+
+def _series(swing: float, days: int = 60) -> list[dict]:
+    """Alternating up/down closes; larger swing means higher volatility."""
+    start = date(2026, 1, 1)
+    price, rows = 100.0, []
+    for i in range(days):
+        price *= 1 + (swing if i % 2 else -swing)
+        rows.append({"date": (start + timedelta(days=i)).isoformat(), "close": price})
+    return rows
+
+
+def test_risk_parity_split_is_inverse_to_risk():
+    split = risk_parity_split({"core": 0.1, "opportunity": 0.3})
+    assert split["core"] == pytest.approx(0.75)
+    assert split["opportunity"] == pytest.approx(0.25)
+    assert risk_parity_split({"core": 0.1, "opportunity": None}) == {}
+
+
+def test_sleeve_risk_measures():
+    returns = sleeve_returns({"A": 1.0}, {"A": _series(0.01)})
+    assert sleeve_risk(returns) == pytest.approx(0.01 * 252**0.5, rel=0.05)
+    assert 0 < sleeve_risk(returns, "drawdown") < 0.05
+    assert sleeve_risk(returns[:5]) is None
+
+
+@pytest.mark.asyncio
+async def test_preview_diffs_against_configured_split_and_apply_saves_it(temp_db):
+    await temp_db.save_prices("CORE.EU", _series(0.01))
+    await temp_db.save_prices("OPP.EU", _series(0.03))
+    await temp_db.cache_set("planner:contrarian_sleeves", json.dumps({"CORE.EU": "core", "OPP.EU": "opportunity"}))
+    planner = MagicMock()
+    planner.calculate_ideal_portfolio = AsyncMock(return_value={"CORE.EU": 0.8, "OPP.EU": 0.2})
+    service = SleeveFundingService(db=temp_db, settings=Settings(), planner=planner)
+
+    preview = await service.preview()
+
+    assert preview["available"] is True
+    assert preview["sleeves"]["opportunity"]["suggested_pct"] == pytest.approx(25.0, abs=0.5)
+    assert preview["sleeves"]["core"]["current_pct"] == 80
+    assert preview["sleeves"]["core"]["diff_pct"] == pytest.approx(-5.0, abs=0.5)
+
+    notified = []
+
+    async def listener(changed):
+        notified.append(changed)
+
+    unsubscribe = Settings().subscribe(["strategy_opportunity_target_pct"], listener)
+    try:
+        await service.apply()
+    finally:
+        unsubscribe()
+    assert await Settings().get("strategy_opportunity_target_pct") == preview["sleeves"]["opportunity"]["suggested_pct"]
+    assert notified == [{"strategy_opportunity_target_pct": preview["sleeves"]["opportunity"]["suggested_pct"]}]
+    assert await temp_db.cache_get("planner:contrarian_sleeves") is None
+
+
+@pytest.mark.asyncio
+async def test_apply_refuses_without_history(temp_db):
+    planner = MagicMock()
+    planner.calculate_ideal_portfolio = AsyncMock(return_value={})
+    service = SleeveFundingService(db=temp_db, settings=Settings(), planner=planner)
+
+    assert (await service.preview(measure="drawdown"))["available"] is False
+    with pytest.raises(ValueError, match="price history"):
+        await service.apply()
+    with pytest.raises(ValueError, match="Unknown risk measure"):
+        await service.preview(measure="beta")
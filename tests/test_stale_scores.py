@@ -0,0 +1,101 @@
+"""Tests for the planner's guardrail against stale scores and prices."""
+
+import json
+from datetime import datetime, timedelta
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.planner import RebalanceEngine
+from sentinel.planner.rebalance_rules import frozen_planning_now, stale_input_reason
+
+DAY = 86400
+NOW = datetime(2026, 3, 10, 12, 0)
+# Slow climb, then a 30% slide: deep dip with oversold RSI (oldest first)
+CRASH = [100.0 + i * 0.1 for i in range(275)] + [127.5 * (0.985**i) for i in range(25)]
+
+
+def test_stale_input_reason():
+    now_ts = NOW.timestamp()
+    limits = {"now_ts": now_ts, "max_score_age_days": 7, "max_price_age_days": 3}
+    assert stale_input_reason("2026-03-09", now_ts, **limits) is None
+    assert "10d old" in stale_input_reason("2026-02-28", now_ts, **limits)
+    # Fresh score, but the price used for sizing has not moved in days
+    assert stale_input_reason("2026-03-09", now_ts - 5 * DAY, **limits) == "price 5d old"
+    # Without a quote timestamp the newest close is the price
+    assert stale_input_reason("2026-03-05", None, **limits).startswith("price ")
+    assert stale_input_reason(None, now_ts, **limits) == "no price history"
+    assert stale_input_reason(42, now_ts, **limits) is None
+
+
+def _engine(last_close: datetime, **settings) -> RebalanceEngine:
+    db = MagicMock()
+    db.get_all_positions = AsyncMock(return_value=[])
+    db.get_all_securities = AsyncMock(
+        return_value=[
+            {"symbol": "DIP.EU", "currency": "EUR", "min_lot": 1, "allow_buy": 1, "allow_sell": 1, "added_at": None}
+        ]
+    )
+    prices = [
+        {"date": (last_close - timedelta(days=i)).strftime("%Y-%m-%d"), "close": c}
+        for i, c in enumerate(reversed(CRASH))
+    ]
+    db.get_prices = AsyncMock(return_value=prices)
+    db.cache_get = AsyncMock(return_value=None)
+    db.cache_set = AsyncMock()
+
+    engine = RebalanceEngine(db=db)
+    engine._broker = MagicMock()
+    engine._broker.get_quotes = AsyncMock(return_value={"DIP.EU": {"price": CRASH[-1]}})
+    engine._settings = MagicMock()
+    settings_values = {"min_trade_value": 100.0, "trade_cooloff_days": 0, **settings}
+    engine._settings.get = AsyncMock(side_effect=lambda key, default=None: settings_values.get(key, default))
+    engine._portfolio = MagicMock()
+    engine._portfolio.total_cash_eur = AsyncMock(return_value=50_000.0)
+    engine._currency = MagicMock()
+    engine._currency.get_rate = AsyncMock(return_value=1.0)
+    engine._currency.to_eur = AsyncMock(side_effect=lambda amt, curr: amt)
+    engine._get_deficit_sells = AsyncMock(return_value=[])
+    return engine
+
+
+async def _buys(engine: RebalanceEngine) -> list:
+    with frozen_planning_now(NOW):
+        recs = await engine.get_recommendations(ideal={"DIP.EU": 0.1}, current={"DIP.EU": 0.0}, total_value=20_000.0)
+    return [r for r in recs if r.action == "buy"]
+
+
+@pytest.mark.asyncio
+async def test_fresh_scores_are_not_touched():
+    engine = _engine(NOW - timedelta(days=1))
+    buys = await _buys(engine)
+
+    assert len(buys) == 1
+    assert "stale" not in buys[0].reason
+    assert engine.last_run_summary["excluded_stale"] == 0
+
+
+@pytest.mark.asyncio
+async def test_stale_scores_are_excluded_and_counted():
+    engine = _engine(NOW - timedelta(days=20))
+
+    assert await _buys(engine) == []
+    summary = engine.last_run_summary
+    assert summary["excluded_stale"] == 1
+    assert summary["penalized_stale"] == 0
+    assert "20d old" in summary["stale"]["DIP.EU"]
+    cached = [c for c in engine._db.cache_set.call_args_list if c.args[0] == "planner:run_summary"]
+    assert json.loads(cached[-1].args[1])["excluded_stale"] == 1
+
+
+@pytest.mark.asyncio
+async def test_stale_penalty_below_one_keeps_candidate():
+    fresh = await _buys(_engine(NOW - timedelta(days=1)))
+    engine = _engine(NOW - timedelta(days=20), planner_stale_penalty=0.5)
+    buys = await _buys(engine)
+
+    assert len(buys) == 1
+    assert buys[0].priority == pytest.approx(fresh[0].priority * 0.5)
+    assert "(stale: score based on closes" in buys[0].reason
+    assert engine.last_run_summary["penalized_stale"] == 1
+    assert engine.last_run_summary["excluded_stale"] == 0
@@ -2,6 +2,7 @@ from sentinel.strategy.contrarian import (
     classify_lot_size,
     compute_contrarian_signal,
     compute_symbol_targets,
+    contrarian_skipped_checks,
     effective_opportunity_score,
     recent_dd252_min,
 )
@@ -26,6 +27,14 @@ def test_compute_contrarian_signal_blocks_freefall():
     assert signal["opp_score"] == 0.0
 
 
+def test_contrarian_skipped_checks_flags_short_history_only():
+    skipped = contrarian_skipped_checks([100.0] * 40)
+    checks = {s["check"] for s in skipped}
+    assert checks == {"dip_score", "capitulation_score", "cycle_turn", "freefall_block", "core_rank"}
+    assert skipped[0] == {"check": "dip_score", "input": "price_history", "required": 130, "available": 40}
+    assert contrarian_skipped_checks([100.0] * 130) == []
+
+
 def test_classify_lot_size_coarse_for_small_portfolio():
     profile = classify_lot_size(
         price=50.0,
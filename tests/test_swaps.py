@@ -0,0 +1,103 @@
+"""Tests for swap-trade planning (sell a weak holding to fund a stronger buy)."""
+
+import pytest
+
+from sentinel.planner.models import TradeRecommendation
+from sentinel.planner.swaps import SwapSettings, drop_orphaned_swap_legs, evaluate_swap, plan_swaps
+
+
+def _buy(symbol: str, value: float, score: float, priority: float = 1.0) -> TradeRecommendation:
+    return TradeRecommendation(
+        symbol=symbol,
+        action="buy",
+        current_allocation=0.0,
+        target_allocation=0.1,
+        allocation_delta=0.1,
+        current_value_eur=0.0,
+        target_value_eur=value,
+        value_delta_eur=value,
+        quantity=value / 100.0,
+        price=100.0,
+        currency="EUR",
+        lot_size=1,
+        contrarian_score=score,
+        priority=priority,
+        reason="Underweight",
+    )
+
+
+def _holding(symbol: str, score: float, quantity: float = 20, price: float = 50.0, avg_cost: float = 50.0) -> dict:
+    return {
+        "symbol": symbol,
+        "quantity": quantity,
+        "price": price,
+        "avg_cost": avg_cost,
+        "fx_rate": 1.0,
+        "currency": "EUR",
+        "lot_size": 1,
+        "score": score,
+        "allow_sell": 1,
+        "current_allocation": 0.1,
+    }
+
+
+def test_unfunded_buy_is_paired_with_weakest_holding():
+    recs = [_buy("NEW", 600.0, score=0.8)]
+    holdings = [_holding("MID", 0.15), _holding("WEAK", 0.05), _holding("GOOD", 0.6)]
+
+    result = plan_swaps(recs, holdings, available_cash_eur=100.0, config=SwapSettings(max_cost_pct=0.05))
+
+    sell, buy = result
+    assert (sell.symbol, sell.action, sell.reason_code) == ("WEAK", "sell", "swap_sell")
+    assert sell.swap_group == buy.swap_group == "swap:WEAK->NEW"
+    # 600 EUR buy + fees minus 100 EUR cash -> ~10.2 shares at 50, rounded up to whole lots
+    assert sell.quantity == 11
+    assert buy.swap_score_delta == pytest.approx(0.75)
+    assert "funded by swap from WEAK" in buy.reason
+
+
+def test_funded_buys_and_strong_holdings_are_left_alone():
+    recs = [_buy("NEW", 300.0, score=0.8)]
+    assert plan_swaps(recs, [_holding("WEAK", 0.05)], available_cash_eur=1000.0, config=SwapSettings()) == recs
+    assert plan_swaps(recs, [_holding("OK", 0.7)], available_cash_eur=0.0, config=SwapSettings()) == recs
+
+
+def test_tax_on_gains_can_reject_a_swap():
+    buy = _buy("NEW", 500.0, score=0.8)
+    holding = _holding("WINNER", 0.05, price=50.0, avg_cost=10.0)
+    taxed = SwapSettings(tax_rate=0.3, max_cost_pct=0.05)
+
+    evaluation = evaluate_swap(holding, buy, 10, taxed)
+
+    assert evaluation["tax_eur"] == pytest.approx(120.0)
+    assert evaluation["accepted"] is False
+    assert evaluate_swap(holding, buy, 10, SwapSettings(max_cost_pct=0.05))["accepted"] is True
+
+
+def test_orphaned_swap_legs_are_dropped():
+    recs = plan_swaps(
+        [_buy("NEW", 600.0, score=0.8)],
+        [_holding("WEAK", 0.05)],
+        available_cash_eur=0.0,
+        config=SwapSettings(max_cost_pct=0.05),
+    )
+    sell_only = [r for r in recs if r.action == "sell"]
+    assert drop_orphaned_swap_legs(sell_only) == []
+    assert drop_orphaned_swap_legs(recs) == recs
+
+
+def test_dominated_and_duplicate_pairs_are_pruned_and_sell_legs_memoized():
+    recs = [_buy("NEW1", 600.0, score=0.4, priority=2.0), _buy("NEW2", 600.0, score=0.4)]
+    holdings = [
+        _holding("TAXED1", 0.05, quantity=10, avg_cost=10.0),
+        _holding("TAXED2", 0.05, quantity=10, avg_cost=10.0),
+        _holding("CLEAN", 0.1),
+        _holding("HIGH", 0.2),
+    ]
+    stats = {}
+
+    result = plan_swaps(recs, holdings, 0.0, SwapSettings(tax_rate=0.3, max_cost_pct=0.05), stats=stats)
+
+    assert [r.symbol for r in result if r.reason_code == "swap_sell"] == ["CLEAN"]
+    # TAXED2 matches the rejected TAXED1, HIGH is too close in score to NEW2, TAXED1's sell leg is reused for NEW2
+    assert stats == {"evaluated": 3, "pruned_dominated": 1, "deduplicated": 2, "memo_hits": 1}
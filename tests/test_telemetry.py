@@ -0,0 +1,100 @@
+"""Tests for the anonymized strategy fingerprint export."""
+
+import json
+import time
+from datetime import date, datetime, timedelta, timezone
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.telemetry import TelemetryService, risk_metrics, temperament_vector
+from sentinel.settings import Settings
+
+
+def _eur_currency() -> MagicMock:
+    currency = MagicMock()
+    currency.to_eur = AsyncMock(side_effect=lambda amount, _currency: amount)
+    currency.to_eur_for_date = AsyncMock(side_effect=lambda amount, _currency, _date: amount)
+    return currency
+
+
+def test_temperament_vector_is_clamped_to_unit_range():
+    vector = temperament_vector(
+        {"strategy_opportunity_target_pct": 20, "strategy_entry_t1_dd": -0.45, "max_position_pct": "bogus"}
+    )
+    assert vector["opportunism"] == 0.2
+    assert vector["dip_depth"] == 1.0
+    assert vector["concentration"] == 0.0
+    assert all(0.0 <= v <= 1.0 for v in vector.values())
+
+
+def test_risk_metrics_needs_enough_observations():
+    assert risk_metrics([0.01] * 5)["sharpe"] is None
+    metrics = risk_metrics([0.02, -0.01] * 20)
+    assert metrics["max_drawdown"] == pytest.approx(0.01, abs=1e-3)
+    assert metrics["volatility"] > 0
+    assert metrics["sharpe"] > 0
+
+
+@pytest.mark.asyncio
+async def test_export_contains_only_ratios_and_strips_deposits(temp_db):
+    today = datetime.now(timezone.utc).date()
+    start = today - timedelta(days=59)
+    await temp_db.upsert_security("AAA.EU", currency="EUR")
+    await temp_db.upsert_security("BBB.EU", currency="EUR")
+    await temp_db.upsert_strategy_state("BBB.EU", sleeve="opportunity")
+
+    # Alternating +2% / -1% days, plus a 1000 EUR deposit on day 30 that is not a return
+    value = 10_000.0
+    for i in range(60):
+        day = start + timedelta(days=i)
+        if i:
+            value *= 1.02 if i % 2 else 0.99
+        if i == 30:
+            value += 1000.0
+            await temp_db.upsert_cash_flow(day.isoformat(), "card", 1000.0, "EUR", None, {"id": 1})
+        positions = {"AAA.EU": {"value_eur": value * 0.6}, "BBB.EU": {"value_eur": value * 0.3}}
+        ts = int(datetime(day.year, day.month, day.day, tzinfo=timezone.utc).timestamp())
+        await temp_db.upsert_portfolio_snapshot(ts, {"positions": positions, "cash_eur": value * 0.1})
+    await temp_db.upsert_trade("T1", "AAA.EU", "BUY", 10, 100.0, int(time.time()) - 86400, {})
+
+    service = TelemetryService(db=temp_db, currency=_eur_currency())
+    assert not await service.enabled()
+    result = await service.export(days=60, today=today)
+
+    assert "AAA.EU" not in json.dumps(result)
+    assert result["structure"] == {
+        "positions": 2,
+        "cash_ratio": 0.1,
+        "top_weight": pytest.approx(0.667, abs=1e-3),
+        "hhi": pytest.approx(0.556, abs=1e-3),
+        "sleeves": {"core": 0.6, "opportunity": 0.3},
+    }
+    # Deposit stripped: drawdown is just the 1% down days
+    assert result["performance"]["max_drawdown"] == pytest.approx(0.01, abs=1e-3)
+    assert result["performance"]["buy_share"] == 1.0
+    assert result["performance"]["turnover"] > 0
+    assert result["config"]["strategy_min_opp_score"] == 0.55
+    assert "tradernet_api_key" not in result["config"]
+    assert result["period"] == {"month": today.strftime("%Y-%m"), "days": 60, "observations": 60}
+
+
+@pytest.mark.asyncio
+async def test_export_endpoint_is_opt_in(temp_db):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.telemetry import export_fingerprint
+
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.settings = Settings()
+    deps.currency = _eur_currency()
+    with pytest.raises(HTTPException) as exc:
+        await export_fingerprint(deps)
+    assert exc.value.status_code == 400
+
+    await temp_db.set_setting("telemetry_export_enabled", True)
+    result = await export_fingerprint(deps, days=90)
+    assert result["period"]["days"] == 90
+    assert result["structure"]["positions"] == 0
+    assert date.today().strftime("%Y-%m") == result["period"]["month"]
@@ -0,0 +1,75 @@
+"""Tests for server TLS configuration and startup validation."""
+
+import shutil
+import ssl
+import subprocess
+from datetime import datetime, timedelta, timezone
+
+import pytest
+
+from sentinel.tls import TLSConfig, TLSConfigError, certificate_expiry, server_ssl_options, validate
+
+
+@pytest.fixture
+def cert_pair(tmp_path):
+    if shutil.which("openssl") is None:
+        pytest.skip("openssl not available")
+    cert, key = tmp_path / "server.pem", tmp_path / "server.key"
+    command = "openssl req -x509 -newkey rsa:2048 -nodes -days 30 -subj /CN=localhost".split()
+    subprocess.run([*command, "-keyout", str(key), "-out", str(cert)], check=True, capture_output=True)  # noqa: S603
+    return str(cert), str(key)
+
+
+def test_from_env_reads_tls_variables():
+    config = TLSConfig.from_env(
+        {
+            "SENTINEL_TLS_CERT": "/certs/server.pem",
+            "SENTINEL_TLS_KEY": "/certs/server.key",
+            "SENTINEL_TLS_CLIENT_AUTH": "Optional",
+            "SENTINEL_TLS_REQUIRED": "true",
+            "SENTINEL_TLS_EXPIRY_WARN_DAYS": "soon",
+        }
+    )
+    assert config.client_auth == "optional"
+    assert config.required
+    assert config.expiry_warn_days == 14
+    assert not TLSConfig.from_env({}).requested
+
+
+def test_invalid_config_falls_back_to_http_unless_required(tmp_path):
+    config = TLSConfig(cert_file=str(tmp_path / "missing.pem"), key_file=str(tmp_path / "missing.key"))
+    assert server_ssl_options(TLSConfig()) == {}
+    assert server_ssl_options(config) == {}
+
+    config.required = True
+    with pytest.raises(TLSConfigError, match="not found"):
+        server_ssl_options(config)
+
+
+def test_valid_certificate_enables_tls_and_mutual_tls(cert_pair):
+    cert, key = cert_pair
+    assert server_ssl_options(TLSConfig(cert_file=cert, key_file=key)) == {"ssl_certfile": cert, "ssl_keyfile": key}
+
+    options = server_ssl_options(TLSConfig(cert_file=cert, key_file=key, client_ca_file=cert, client_auth="optional"))
+    assert options["ssl_ca_certs"] == cert
+    assert options["ssl_cert_reqs"] == ssl.CERT_OPTIONAL
+
+    errors, _ = validate(TLSConfig(cert_file=cert, key_file=key, client_auth="sometimes"))
+    assert errors == ["Unknown client auth mode 'sometimes' (use required or optional)"]
+
+
+def test_certificate_expiry_is_checked(cert_pair):
+    cert, key = cert_pair
+    expiry = certificate_expiry(cert)
+    if expiry is None:
+        pytest.skip("certificate decoding not supported by this Python build")
+    now = datetime.now(timezone.utc)
+    assert timedelta(days=29) < expiry - now <= timedelta(days=31)
+
+    errors, warnings = validate(TLSConfig(cert_file=cert, key_file=key, expiry_warn_days=60), now=now)
+    assert errors == []
+    assert warnings[0].startswith("TLS certificate expires in")
+
+    config = TLSConfig(cert_file=cert, key_file=key, required=True)
+    with pytest.raises(TLSConfigError, match="expired"):
+        server_ssl_options(config, now=expiry + timedelta(days=1))
@@ -0,0 +1,140 @@
+"""Tests for the encrypted credentials vault."""
+
+import os
+import sys
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.settings import Settings
+from sentinel.vault import Vault, VaultError, VaultKey
+
+pytest.importorskip("cryptography")
+
+
+def _key(passphrase: str = "correct horse battery") -> VaultKey:
+    return VaultKey(material=passphrase.encode(), source="passphrase")
+
+
+def test_key_from_env_prefers_passphrase_and_creates_keyfile(tmp_path):
+    key = VaultKey.from_env({"SENTINEL_VAULT_PASSPHRASE": "pw", "SENTINEL_VAULT_KEYFILE": str(tmp_path / "k")})
+    assert key.source == "passphrase"
+    assert not (tmp_path / "k").exists()
+
+    key = VaultKey.from_env({"SENTINEL_VAULT_KEYFILE": str(tmp_path / "k")})
+    assert key.source == "keyfile"
+    assert len(key.material) == 32
+    assert (tmp_path / "k").stat().st_mode & 0o777 == 0o600
+    assert VaultKey.from_env({"SENTINEL_VAULT_KEYFILE": str(tmp_path / "k")}).material == key.material
+
+    with pytest.raises(VaultError, match="not found"):
+        VaultKey.from_env({"SENTINEL_VAULT_KEYFILE": str(tmp_path / "missing")}, create=False)
+
+
+@pytest.mark.asyncio
+async def test_secrets_are_encrypted_at_rest(temp_db):
+    vault = Vault(db=temp_db, key=_key())
+    await vault.put("tradernet_api_secret", "hunter2")
+
+    record = await temp_db.get_secret("tradernet_api_secret")
+    assert b"hunter2" not in record["ciphertext"]
+    assert await vault.get("tradernet_api_secret") == "hunter2"
+
+    # A row copied under another name does not decrypt (the name is authenticated)
+    await temp_db.put_secret("tradernet_api_key", record["salt"], record["nonce"], record["ciphertext"])
+    with pytest.raises(VaultError):
+        await vault.get("tradernet_api_key")
+
+    with pytest.raises(VaultError, match="wrong vault key"):
+        await Vault(db=temp_db, key=_key("another passphrase")).get("tradernet_api_secret")
+
+
+@pytest.mark.asyncio
+async def test_migrate_settings_and_read_through(temp_db):
+    await temp_db.set_setting("tradernet_api_key", "public-key")
+    await temp_db.set_setting("r2_secret_key", "r2-secret")
+    vault = Vault(db=temp_db, key=_key())
+
+    assert await vault.migrate_settings() == ["tradernet_api_key", "r2_secret_key"]
+    assert await temp_db.get_setting("tradernet_api_key") == ""
+    assert await vault.get_secret("tradernet_api_key") == "public-key"
+    assert await vault.migrate_settings() == []
+
+    # Not yet in the vault: falls back to the settings row
+    await temp_db.set_setting("r2_access_key", "legacy")
+    assert await vault.get_secret("r2_access_key") == "legacy"
+
+    status = {s["name"]: s for s in await vault.status()}
+    assert status["r2_secret_key"]["stored"]
+    assert status["r2_access_key"] == {
+        "name": "r2_access_key",
+        "stored": False,
+        "plaintext_setting": True,
+        "created_at": None,
+        "rotated_at": None,
+    }
+
+
+@pytest.mark.asyncio
+async def test_rekey_rotates_keyfile(temp_db, tmp_path):
+    keyfile = tmp_path / "vault.key"
+    old_key = VaultKey.from_env({"SENTINEL_VAULT_KEYFILE": str(keyfile)})
+    vault = Vault(db=temp_db, key=old_key)
+    await vault.put("r2_access_key", "abc")
+    await vault.put("r2_secret_key", "xyz")
+
+    new_key = VaultKey(material=os.urandom(32), source="keyfile", path=str(keyfile))
+    assert await vault.rekey(new_key) == 2
+
+    assert keyfile.read_bytes() == new_key.material
+    assert not list(tmp_path.glob("vault.key.*"))
+    fresh = Vault(db=temp_db, key=VaultKey.from_env({"SENTINEL_VAULT_KEYFILE": str(keyfile)}))
+    assert await fresh.get("r2_secret_key") == "xyz"
+    with pytest.raises(VaultError):
+        await Vault(db=temp_db, key=old_key).get("r2_access_key")
+
+
+@pytest.mark.asyncio
+async def test_broker_reads_credentials_through_vault(temp_db, monkeypatch):
+    from sentinel import vault as vault_module
+    from sentinel.broker import Broker
+
+    monkeypatch.setattr(vault_module.VaultKey, "from_env", classmethod(lambda cls, env=None, create=True: _key()))
+    await Vault(db=temp_db).put("tradernet_api_key", "pub")
+    await Vault(db=temp_db).put("tradernet_api_secret", "priv")
+
+    tradernet = MagicMock()
+    monkeypatch.setitem(sys.modules, "tradernet", tradernet)
+    broker = Broker()
+    monkeypatch.setattr(broker, "_db", temp_db)
+    monkeypatch.setattr(broker, "_settings", Settings())
+    monkeypatch.setattr(broker, "_api", None)
+    monkeypatch.setattr(broker, "_trading", None)
+    assert await broker.connect()
+    tradernet.TraderNetAPI.assert_called_once_with(public="pub", private="priv")
+
+
+@pytest.mark.asyncio
+async def test_setting_a_credential_stores_it_in_the_vault(temp_db, monkeypatch):
+    from sentinel import vault as vault_module
+    from sentinel.api.routers.secrets import delete_secret, get_secrets
+    from sentinel.api.routers.settings import set_setting
+
+    monkeypatch.setattr(vault_module.VaultKey, "from_env", classmethod(lambda cls, env=None, create=True: _key()))
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.settings = Settings()
+
+    await set_setting("r2_secret_key", {"value": "s3cret"}, deps)
+    assert await temp_db.get_setting("r2_secret_key") == ""
+    assert await Vault(db=temp_db).get("r2_secret_key") == "s3cret"
+
+    listing = await get_secrets(deps)
+    assert listing["available"]
+    assert "s3cret" not in str(listing)
+    assert await delete_secret("r2_secret_key", deps) == {"status": "ok"}
+
+    settings = AsyncMock()
+    deps.settings = settings
+    await set_setting("r2_bucket_name", {"value": "bucket"}, deps)
+    settings.set.assert_awaited_once_with("r2_bucket_name", "bucket")
@@ -0,0 +1,107 @@
+"""Tests for the watchlist: signal change alerts, promotion and broker price alerts."""
+
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.services.watchlist import WatchlistService, signal_tags
+from sentinel.settings import Settings
+
+INFO = {"short_name": "Candidate", "currency": "USD", "mrkt": {"mkt_id": "7"}, "lot": "1", "isin": "US0378331005"}
+
+
+def _bars(closes: list[float]) -> list[dict]:
+    return [{"date": f"2025-{1 + i // 28:02d}-{1 + i % 28:02d}", "close": c} for i, c in enumerate(closes)]
+
+
+def _service(db) -> WatchlistService:
+    settings = Settings()
+    settings._db = db
+    broker = MagicMock()
+    broker.get_security_info = AsyncMock(return_value=INFO)
+    # A steady uptrend: no dip, nothing to tag
+    broker.get_historical_prices_bulk = AsyncMock(return_value={"CAND.US": _bars([100 + i for i in range(200)])})
+    broker.get_historical_prices = AsyncMock(return_value=[])
+    broker.add_price_alert = AsyncMock(return_value="42")
+    broker.delete_price_alert = AsyncMock(return_value=False)
+    return WatchlistService(db=db, broker=broker, settings=settings)
+
+
+def test_signal_tags_name_the_signal_conditions():
+    signal = {"opp_score": 0.6, "dip_score": 0.7, "capitulation_score": 0.1, "cycle_turn": 1, "freefall_block": 0}
+
+    assert signal_tags(signal, 0.55) == ["opportunity", "dip", "cycle_turn"]
+    assert signal_tags({}, 0.55) == []
+
+
+@pytest.mark.asyncio
+async def test_add_collects_history_and_rejects_universe_members(temp_db):
+    service = _service(temp_db)
+
+    added = await service.add("CAND.US", note="cheap?")
+
+    assert added["prices_count"] == 200
+    [item] = await service.list()
+    assert (item["symbol"], item["currency"], item["note"]) == ("CAND.US", "USD", "cheap?")
+    assert item["isin"] == "US0378331005"
+    assert item["score"] == added["score"]
+    assert "data" not in item
+
+    await temp_db.upsert_security("HELD.EU", name="Held", currency="EUR", active=True)
+    with pytest.raises(ValueError):
+        await service.add("HELD.EU")
+    service._broker.get_security_info.return_value = None
+    with pytest.raises(LookupError):
+        await service.add("NOPE.US")
+
+
+@pytest.mark.asyncio
+async def test_refresh_alerts_on_score_and_tag_changes(temp_db):
+    service = _service(temp_db)
+    await service.add("CAND.US")
+    assert (await service.refresh())["alerts"] == []
+
+    # A sharp selloff turns the uptrend into a dip
+    selloff = [299 - 8 * i for i in range(1, 21)]
+    service._broker.get_historical_prices.return_value = [
+        {"date": f"2025-08-{1 + i:02d}", "close": c} for i, c in enumerate(selloff)
+    ]
+    result = await service.refresh()
+
+    [alert] = result["alerts"]
+    assert alert["symbol"] == "CAND.US"
+    assert "dip" in alert["tags"]
+    assert alert["changes"][-1].startswith("+")
+    notifications = await temp_db.get_notifications()
+    assert [(n["category"], n["entity_id"]) for n in notifications] == [("watchlist", "CAND.US")]
+
+
+@pytest.mark.asyncio
+async def test_promote_carries_metadata_and_history_into_the_universe(temp_db):
+    service = _service(temp_db)
+    await service.add("CAND.US")
+
+    promoted = await service.promote("CAND.US", {"geography": "US", "allow_buy": 0})
+
+    assert promoted["prices_count"] == 200
+    security = await temp_db.get_security("CAND.US")
+    assert (security["name"], security["currency"], security["market_id"]) == ("Candidate", "USD", "7")
+    assert (security["geography"], security["allow_buy"], security["active"]) == ("US", 0, 1)
+    assert security["isin"] == "US0378331005"
+    assert await service.list() == []
+    with pytest.raises(LookupError):
+        await service.promote("CAND.US")
+
+
+@pytest.mark.asyncio
+async def test_price_alerts_go_through_the_broker(temp_db):
+    service = _service(temp_db)
+
+    assert await service.add_price_alert("CAND.US", 150.0, "crossing_down") == "42"
+    service._broker.add_price_alert.assert_awaited_with("CAND.US", 150.0, "crossing_down")
+    with pytest.raises(ValueError):
+        await service.add_price_alert("CAND.US", 0)
+    with pytest.raises(ValueError):
+        await service.add_price_alert("CAND.US", 150.0, "sideways")
+    with pytest.raises(LookupError):
+        await service.delete_price_alert("42")
@@ -0,0 +1,179 @@
+"""Tests for outbound webhook notifications."""
+
+import asyncio
+import hashlib
+import hmac
+import json
+from unittest.mock import AsyncMock, MagicMock
+
+import pytest
+
+from sentinel.dry_run import dry_run
+from sentinel.services.notifications import record_notification
+from sentinel.services.webhooks import (
+    MAX_ATTEMPTS,
+    WebhookService,
+    backoff_seconds,
+    build_request,
+    emit_event,
+    sign_payload,
+)
+
+PAYLOAD = {"event": "job_failed", "title": "Job sync:prices failed", "message": "timeout", "severity": "error"}
+
+
+class FakeSender:
+    def __init__(self, statuses):
+        self.statuses = list(statuses)
+        self.requests = []
+
+    async def __call__(self, url, headers, body):
+        self.requests.append((url, headers, body))
+        status = self.statuses.pop(0) if self.statuses else 200
+        if isinstance(status, Exception):
+            raise status
+        return status
+
+
+def test_webhook_request_is_signed():
+    url, headers, body = build_request({"kind": "webhook", "url": "https://x/hook", "secret": "s3cret"}, PAYLOAD, 1700)
+    assert url == "https://x/hook"
+    assert headers["X-Sentinel-Event"] == "job_failed"
+    expected = hmac.new(b"s3cret", b"1700." + body, hashlib.sha256).hexdigest()
+    assert headers["X-Sentinel-Signature"] == f"sha256={expected}"
+    assert sign_payload("s3cret", 1700, body) == expected
+    assert json.loads(body)["title"] == "Job sync:prices failed"
+
+    _, unsigned, _ = build_request({"kind": "webhook", "url": "https://x/hook"}, PAYLOAD)
+    assert "X-Sentinel-Signature" not in unsigned
+
+
+def test_adapter_requests():
+    url, _, body = build_request({"kind": "telegram", "url": "", "target": "42", "secret": "TOKEN"}, PAYLOAD)
+    assert url == "https://api.telegram.org/botTOKEN/sendMessage"
+    assert json.loads(body) == {"chat_id": "42", "text": "Job sync:prices failed\ntimeout"}
+
+    url, headers, body = build_request({"kind": "ntfy", "url": "https://ntfy.sh/topic", "secret": "tk"}, PAYLOAD)
+    assert url == "https://ntfy.sh/topic"
+    assert headers["Title"] == "Job sync:prices failed"
+    assert headers["Priority"] == "high"
+    assert headers["Authorization"] == "Bearer tk"
+    assert body == b"timeout"
+
+
+@pytest.mark.asyncio
+async def test_emit_respects_subscriptions_and_switches(temp_db, monkeypatch):
+    monkeypatch.setattr("sentinel.services.webhooks.http_post", FakeSender([]))
+    all_events = await temp_db.create_webhook("all", "webhook", "https://a")
+    await temp_db.create_webhook("trades", "webhook", "https://b", events=["trade_executed"])
+    await temp_db.create_webhook("off", "webhook", "https://c", enabled=False)
+
+    assert await emit_event(temp_db, "job_failed", "Job failed") == 1
+    assert await emit_event(temp_db, "trade_executed", "BUY 1 x AAPL.US") == 2
+
+    await temp_db.set_setting("webhook_events", {"trade_executed": False})
+    assert await emit_event(temp_db, "trade_executed", "BUY 1 x AAPL.US") == 0
+
+    [latest, *_] = await temp_db.get_webhook_deliveries(webhook_id=all_events)
+    assert latest["payload"]["title"] == "BUY 1 x AAPL.US"
+    await asyncio.gather(*asyncio.all_tasks() - {asyncio.current_task()}, return_exceptions=True)
+
+
+@pytest.mark.asyncio
+async def test_dry_run_records_instead_of_sending(temp_db):
+    webhook_id = await temp_db.create_webhook("all", "webhook", "https://a")
+    sender = FakeSender([])
+    async with dry_run(temp_db) as session:
+        assert await emit_event(temp_db, "job_failed", "Job failed") == 0
+        assert await WebhookService(db=temp_db, sender=sender).send_test(webhook_id) is None
+
+    assert sender.requests == []
+    assert [(e["kind"], e["event"]) for e in session.side_effects] == [("webhook", "job_failed"), ("webhook", "test")]
+    assert await temp_db.get_webhook_deliveries(webhook_id=webhook_id) == []
+
+
+@pytest.mark.asyncio
+async def test_emit_never_raises():
+    db = MagicMock()
+    db.get_webhooks = AsyncMock(return_value=[{"id": 1, "events": None}])
+    db.get_setting = AsyncMock(return_value=None)
+    db.add_webhook_delivery = AsyncMock(side_effect=RuntimeError("db locked"))
+    assert await emit_event(db, "job_failed", "Job failed") == 0
+    assert await emit_event(object(), "job_failed", "Job failed") == 0
+
+
+@pytest.mark.asyncio
+async def test_failed_delivery_retries_with_backoff(temp_db):
+    webhook_id = await temp_db.create_webhook("hook", "webhook", "https://a", secret="s")
+    delivery_id = await temp_db.add_webhook_delivery(webhook_id, "job_failed", PAYLOAD)
+    sender = FakeSender([500, ConnectionError("refused"), 204])
+    service = WebhookService(db=temp_db, sender=sender)
+
+    now = 10_000_000_000
+    assert await service.deliver_due(now) == {"delivered": 0, "retrying": 1, "failed": 0}
+    # Not due again until the backoff elapses
+    assert await service.deliver_due(now + 1) == {"delivered": 0, "retrying": 0, "failed": 0}
+    assert (await service.deliver_due(now + backoff_seconds(1)))["retrying"] == 1
+    [delivery] = await temp_db.get_webhook_deliveries()
+    assert delivery["attempts"] == 2
+    assert delivery["last_error"] == "ConnectionError: refused"
+    assert delivery["next_attempt_at"] == now + backoff_seconds(1) + backoff_seconds(2)
+
+    assert (await service.deliver_due(delivery["next_attempt_at"]))["delivered"] == 1
+    [delivery] = await temp_db.get_webhook_deliveries()
+    assert delivery["id"] == delivery_id
+    assert delivery["status"] == "delivered"
+    assert len(sender.requests) == 3
+
+
+@pytest.mark.asyncio
+async def test_delivery_gives_up_after_max_attempts(temp_db):
+    webhook_id = await temp_db.create_webhook("hook", "webhook", "https://a")
+    await temp_db.add_webhook_delivery(webhook_id, "job_failed", PAYLOAD)
+    service = WebhookService(db=temp_db, sender=FakeSender([503] * MAX_ATTEMPTS))
+
+    for attempt in range(MAX_ATTEMPTS):
+        result = await service.deliver_due(20_000_000_000 + attempt * 86400)
+    assert result["failed"] == 1
+    [delivery] = await temp_db.get_webhook_deliveries()
+    assert delivery["status"] == "failed"
+    assert delivery["last_error"] == "HTTP 503"
+
+
+@pytest.mark.asyncio
+async def test_record_notification_emits_event(temp_db, monkeypatch):
+    sender = FakeSender([])
+    monkeypatch.setattr("sentinel.services.webhooks.http_post", sender)
+    await temp_db.create_webhook("hook", "webhook", "https://a")
+    await record_notification(temp_db, "error", "job", "Job sync:prices failed", event="job_failed", message="x")
+    [delivery] = await temp_db.get_webhook_deliveries()
+    assert delivery["event"] == "job_failed"
+    assert delivery["payload"]["severity"] == "error"
+    assert len(await temp_db.get_notifications()) == 1
+    # Delivered in the background right away
+    await asyncio.gather(*asyncio.all_tasks() - {asyncio.current_task()}, return_exceptions=True)
+    assert len(sender.requests) == 1
+    assert (await temp_db.get_webhook_deliveries())[0]["status"] == "delivered"
+
+
+@pytest.mark.asyncio
+async def test_router_masks_secrets_and_validates(temp_db):
+    from fastapi import HTTPException
+
+    from sentinel.api.routers.webhooks import create_webhook, get_webhooks
+
+    deps = MagicMock()
+    deps.db = temp_db
+    deps.settings.get = AsyncMock(return_value={"job_failed": False})
+
+    created = await create_webhook({"name": "hook", "url": "https://a", "secret": "s"}, deps)
+    assert created["has_secret"] is True and "secret" not in created
+    listing = await get_webhooks(deps)
+    assert listing["events"]["job_failed"] is False
+    assert listing["events"]["trade_executed"] is True
+
+    with pytest.raises(HTTPException) as exc:
+        await create_webhook({"name": "x", "url": "https://a", "events": ["nope"]}, deps)
+    assert exc.value.status_code == 400
+    with pytest.raises(HTTPException):
+        await create_webhook({"name": "tg", "kind": "telegram"}, deps)
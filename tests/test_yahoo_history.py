@@ -0,0 +1,67 @@
+"""Tests for the Yahoo Finance price history client."""
+
+import asyncio
+
+import pytest
+
+from sentinel.errors import UpstreamError
+from sentinel.services.yahoo_history import YahooHistoryClient, parse_chart
+
+# 2026-03-09 and 2026-03-10 at 14:30 UTC (09:30 New York)
+TIMESTAMPS = [1773066600, 1773153000]
+
+
+def _chart(closes=(100.0, 102.0), adjclose=(98.0, 102.0)) -> dict:
+    return {
+        "chart": {
+            "result": [
+                {
+                    "meta": {"gmtoffset": -14400},
+                    "timestamp": TIMESTAMPS,
+                    "indicators": {
+                        "quote": [
+                            {
+                                "open": [99.0, 101.0],
+                                "high": [101.0, 103.0],
+                                "low": [98.0, None],
+                                "close": list(closes),
+                                "volume": [1000, None],
+                            }
+                        ],
+                        "adjclose": [{"adjclose": list(adjclose)}],
+                    },
+                }
+            ],
+            "error": None,
+        }
+    }
+
+
+def test_parse_chart_adjusts_bars():
+    bars = parse_chart(_chart())
+    # The dividend after the first day scales that day's bar by 98 / 100
+    assert bars[0] == {"date": "2026-03-09", "open": 97.02, "high": 98.98, "low": 96.04, "close": 98.0, "volume": 1000}
+    assert bars[1] == {"date": "2026-03-10", "open": 101.0, "high": 103.0, "low": None, "close": 102.0, "volume": 0}
+
+    with pytest.raises(UpstreamError, match="delisted"):
+        parse_chart({"chart": {"result": None, "error": {"code": "Not Found", "description": "delisted"}}})
+
+
+@pytest.mark.asyncio
+async def test_client_coalesces_identical_requests():
+    calls = []
+
+    async def fetcher(url):
+        calls.append(url)
+        await asyncio.sleep(0.01)
+        return _chart()
+
+    client = YahooHistoryClient(fetcher, concurrency=2)
+    first, second, other = await asyncio.gather(
+        client.history("AAPL", "2026-01-01", "2026-03-10"),
+        client.history("AAPL", "2026-01-01", "2026-03-10"),
+        client.history("MSFT", "2026-01-01", "2026-03-10"),
+    )
+    assert first == second == other
+    assert len(calls) == 2
+    assert "period1=1767225600" in calls[0]
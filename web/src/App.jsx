@@ -7,16 +7,26 @@ import { SchedulerModal } from './components/SchedulerModal';
 import { SettingsModal } from './components/SettingsModal';
 import { BacktestModal } from './components/BacktestModal';
 import { TradesModal } from './components/TradesModal';
+import { NotificationsInbox } from './components/NotificationsInbox';
+import { LoginModal } from './components/LoginModal';
 import { getSchedulerStatus, refreshAll, getSettings, updateSetting, getLedStatus, setLedEnabled, getVersion } from './api/client';
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 
 function App() {
   const [schedulerOpen, setSchedulerOpen] = useState(false);
   const [settingsOpen, setSettingsOpen] = useState(false);
   const [backtestOpen, setBacktestOpen] = useState(false);
   const [tradesOpen, setTradesOpen] = useState(false);
+  const [loginOpen, setLoginOpen] = useState(false);
   const queryClient = useQueryClient();
 
+  // Ask for credentials whenever the API rejects a request as unauthenticated
+  useEffect(() => {
+    const handleUnauthorized = () => setLoginOpen(true);
+    window.addEventListener('sentinel:unauthorized', handleUnauthorized);
+    return () => window.removeEventListener('sentinel:unauthorized', handleUnauthorized);
+  }, []);
+
   const { data: schedulerStatus } = useQuery({
     queryKey: ['scheduler'],
     queryFn: getSchedulerStatus,
@@ -101,6 +111,8 @@ function App() {
               </Group>
 
               <Group gap="xs" className="app__actions">
+                <NotificationsInbox />
+
                 <Tooltip label={ledEnabled ? (ledRunning ? 'LED Display Active' : 'LED Display Enabled') : 'LED Display Off'}>
                   <ActionIcon
                     variant={ledEnabled ? 'light' : 'subtle'}
@@ -197,6 +209,7 @@ function App() {
       </AppShell>
 
       <SchedulerModal opened={schedulerOpen} onClose={() => setSchedulerOpen(false)} />
+      <LoginModal opened={loginOpen} onClose={() => setLoginOpen(false)} />
       <SettingsModal opened={settingsOpen} onClose={() => setSettingsOpen(false)} />
       <BacktestModal opened={backtestOpen} onClose={() => setBacktestOpen(false)} />
       <TradesModal opened={tradesOpen} onClose={() => setTradesOpen(false)} />
@@ -3,16 +3,30 @@
  */
 
 const API_BASE = import.meta.env.VITE_MONOLITH_API_BASE || '/api';
+const TOKEN_KEY = 'sentinel_api_token';
+
+export const getAuthToken = () => localStorage.getItem(TOKEN_KEY);
+export const setAuthToken = (token) => {
+  if (token) localStorage.setItem(TOKEN_KEY, token);
+  else localStorage.removeItem(TOKEN_KEY);
+};
 
 async function requestFrom(base, endpoint, options = {}) {
+  const token = getAuthToken();
   const response = await fetch(`${base}${endpoint}`, {
+    ...options,
     headers: {
       'Content-Type': 'application/json',
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
       ...options.headers,
     },
-    ...options,
   });
 
+  if (response.status === 401) {
+    // Let the app ask for credentials
+    window.dispatchEvent(new Event('sentinel:unauthorized'));
+  }
+
   if (!response.ok) {
     // Try to extract error detail from response body
     let errorMessage = `API error: ${response.status} ${response.statusText}`;
@@ -72,6 +86,8 @@ export const getSchedulerStatus = () => request('/jobs');
 export const runJob = (jobName) => {
   return request(`/jobs/${encodeURIComponent(jobName)}/run`, { method: 'POST' });
 };
+export const releaseJob = (jobName) =>
+  request(`/jobs/${encodeURIComponent(jobName)}/release`, { method: 'POST' });
 
 export const refreshAll = async () => {
   await request('/jobs/refresh-all', { method: 'POST' });
@@ -198,3 +214,29 @@ export const getPortfolioPnLHistory = () => request('/portfolio/pnl-history');
 
 // Categories
 export const getCategories = () => request('/meta/categories');
+
+// Notifications
+export const getNotifications = (params = {}) => {
+  const searchParams = new URLSearchParams();
+  if (params.unread_only) searchParams.append('unread_only', 'true');
+  if (params.severity) searchParams.append('severity', params.severity);
+  if (params.limit) searchParams.append('limit', params.limit);
+  const query = searchParams.toString();
+  return request(`/notifications${query ? '?' + query : ''}`);
+};
+export const acknowledgeNotification = (id) => request(`/notifications/${id}/read`, { method: 'POST' });
+export const acknowledgeAllNotifications = () => request('/notifications/read-all', { method: 'POST' });
+
+// Auth
+export const login = (username, password) =>
+  request('/auth/login', {
+    method: 'POST',
+    body: JSON.stringify({ username, password }),
+  });
+export const getCurrentPrincipal = () => request('/auth/me');
+
+// Credentials vault
+export const getSecrets = () => request('/secrets');
+
+// Telemetry
+export const exportTelemetry = (days = 365) => request(`/telemetry/export?days=${days}`);
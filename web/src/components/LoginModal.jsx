@@ -0,0 +1,82 @@
+import { useState } from 'react';
+import { useQueryClient } from '@tanstack/react-query';
+import { Modal, Text, Stack, Button, TextInput, PasswordInput, Tabs } from '@mantine/core';
+import { login, setAuthToken } from '../api/client';
+
+/**
+ * Login Modal
+ *
+ * Shown when the API answers 401. Accepts either a local username/password
+ * (exchanged for a session token) or a pasted API token.
+ */
+export function LoginModal({ opened, onClose }) {
+  const queryClient = useQueryClient();
+  const [username, setUsername] = useState('');
+  const [password, setPassword] = useState('');
+  const [token, setToken] = useState('');
+  const [isLoading, setIsLoading] = useState(false);
+  const [error, setError] = useState(null);
+
+  const finish = (value) => {
+    setAuthToken(value);
+    setPassword('');
+    setToken('');
+    setError(null);
+    onClose();
+    queryClient.invalidateQueries();
+  };
+
+  const handleLogin = async () => {
+    setIsLoading(true);
+    setError(null);
+    try {
+      const session = await login(username, password);
+      finish(session.token);
+    } catch (err) {
+      setError(err.message || 'Login failed');
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  return (
+    <Modal opened={opened} onClose={onClose} title={<Text fw={600}>Sign in</Text>} className="login-modal">
+      <Tabs defaultValue="password">
+        <Tabs.List>
+          <Tabs.Tab value="password">Password</Tabs.Tab>
+          <Tabs.Tab value="token">API token</Tabs.Tab>
+        </Tabs.List>
+
+        <Tabs.Panel value="password" pt="md">
+          <Stack gap="sm">
+            <TextInput label="Username" value={username} onChange={(e) => setUsername(e.currentTarget.value)} />
+            <PasswordInput
+              label="Password"
+              value={password}
+              onChange={(e) => setPassword(e.currentTarget.value)}
+              onKeyDown={(e) => e.key === 'Enter' && handleLogin()}
+            />
+            <Button onClick={handleLogin} loading={isLoading} disabled={!username || !password}>
+              Sign in
+            </Button>
+          </Stack>
+        </Tabs.Panel>
+
+        <Tabs.Panel value="token" pt="md">
+          <Stack gap="sm">
+            <PasswordInput label="Token" value={token} onChange={(e) => setToken(e.currentTarget.value)} />
+            <Button onClick={() => finish(token.trim())} disabled={!token.trim()}>
+              Use token
+            </Button>
+          </Stack>
+        </Tabs.Panel>
+      </Tabs>
+
+      {error && (
+        <Text c="red" size="sm" mt="sm">
+          {error}
+        </Text>
+      )}
+    </Modal>
+  );
+}
@@ -0,0 +1,118 @@
+/**
+ * Notifications Inbox Component
+ *
+ * Bell icon with unread count; opens the server-side notification inbox.
+ * Clicking a notification acknowledges it and follows its deep link.
+ */
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
+import { useNavigate } from 'react-router-dom';
+import { ActionIcon, Badge, Button, Group, Popover, ScrollArea, Stack, Text, Tooltip } from '@mantine/core';
+import { IconBell } from '@tabler/icons-react';
+import { getNotifications, acknowledgeNotification, acknowledgeAllNotifications } from '../api/client';
+import { formatRelativeTime } from '../utils/dateFormatting';
+
+const SEVERITY_COLORS = {
+  info: 'blue',
+  warning: 'yellow',
+  error: 'red',
+};
+
+export function NotificationsInbox() {
+  const queryClient = useQueryClient();
+  const navigate = useNavigate();
+
+  const { data } = useQuery({
+    queryKey: ['notifications'],
+    queryFn: () => getNotifications({ limit: 30 }),
+    refetchInterval: 30000,
+  });
+
+  const invalidate = () => queryClient.invalidateQueries({ queryKey: ['notifications'] });
+  const ackMutation = useMutation({ mutationFn: acknowledgeNotification, onSuccess: invalidate });
+  const ackAllMutation = useMutation({ mutationFn: acknowledgeAllNotifications, onSuccess: invalidate });
+
+  const items = data?.notifications || [];
+  const unread = data?.unread_count || 0;
+
+  const handleOpen = (item) => {
+    if (!item.read_at) ackMutation.mutate(item.id);
+    if (item.link) navigate(item.link);
+  };
+
+  return (
+    <Popover width={360} position="bottom-end" shadow="md" withArrow>
+      <Popover.Target>
+        <Tooltip label={unread > 0 ? `${unread} unread notifications` : 'Notifications'}>
+          <ActionIcon
+            variant="subtle"
+            size="lg"
+            pos="relative"
+            className="app__action-btn app__action-btn--notifications"
+          >
+            <IconBell size={20} />
+            {unread > 0 && (
+              <Badge
+                size="sm"
+                color="red"
+                circle
+                pos="absolute"
+                top={-4}
+                right={-4}
+                className="app__unread-badge"
+              >
+                {unread}
+              </Badge>
+            )}
+          </ActionIcon>
+        </Tooltip>
+      </Popover.Target>
+      <Popover.Dropdown className="notifications">
+        <Group justify="space-between" mb="xs">
+          <Text size="xs" c="dimmed" fw={600} tt="uppercase">Notifications</Text>
+          <Button
+            size="compact-xs"
+            variant="subtle"
+            disabled={unread === 0}
+            loading={ackAllMutation.isPending}
+            onClick={() => ackAllMutation.mutate()}
+          >
+            Mark all read
+          </Button>
+        </Group>
+        {items.length === 0 && (
+          <Text size="xs" c="dimmed" fs="italic">No notifications</Text>
+        )}
+        <ScrollArea.Autosize mah={400}>
+          <Stack gap={6}>
+            {items.map((item) => (
+              <div
+                key={item.id}
+                className={`notifications__item ${item.read_at ? '' : 'notifications__item--unread'}`}
+                style={{ cursor: 'pointer', opacity: item.read_at ? 0.6 : 1 }}
+                onClick={() => handleOpen(item)}
+              >
+                <Group gap="xs" wrap="nowrap" justify="space-between">
+                  <Group gap="xs" wrap="nowrap" style={{ minWidth: 0 }}>
+                    <Badge size="xs" color={SEVERITY_COLORS[item.severity] || 'gray'} variant="light">
+                      {item.category}
+                    </Badge>
+                    <Text size="sm" fw={item.read_at ? 400 : 600} truncate>
+                      {item.title}
+                    </Text>
+                  </Group>
+                  <Text size="xs" c="dimmed" style={{ whiteSpace: 'nowrap' }}>
+                    {item.occurrences > 1 ? `${item.occurrences}× ` : ''}
+                    {formatRelativeTime(new Date(item.updated_at * 1000).toISOString())}
+                  </Text>
+                </Group>
+                {item.message && (
+                  <Text size="xs" c="dimmed" lineClamp={2}>{item.message}</Text>
+                )}
+              </div>
+            ))}
+          </Stack>
+        </ScrollArea.Autosize>
+      </Popover.Dropdown>
+    </Popover>
+  );
+}
@@ -17,11 +17,11 @@ import {
 } from '@mantine/core';
 import { useDebouncedCallback } from '@mantine/hooks';
 import { IconClock, IconActivity, IconHistory, IconPlayerPlay } from '@tabler/icons-react';
-import { getJobSchedules, updateJobSchedule, runJob, getSchedulerStatus, getJobHistory } from '../api/client';
+import { getJobSchedules, updateJobSchedule, runJob, releaseJob, getSchedulerStatus, getJobHistory } from '../api/client';
 import { IntervalPicker } from './IntervalPicker';
 import { formatTime, formatRelativeTime, formatDuration } from '../utils/dateFormatting';
 
-function JobScheduleRow({ job, onUpdate, onRun, isUpdating }) {
+function JobScheduleRow({ job, onUpdate, onRun, onRelease, isUpdating }) {
   const debouncedUpdateInterval = useDebouncedCallback((val) => {
     if (val && val !== job.interval_minutes) {
       onUpdate(job.job_type, { interval_minutes: val });
@@ -45,6 +45,23 @@ function JobScheduleRow({ job, onUpdate, onRun, isUpdating }) {
                 {job.last_status}
               </Badge>
             )}
+            {job.last_status === 'failed' && job.last_failure_class && (
+              <Badge color="red" variant="outline" size="sm">
+                {job.last_failure_class}
+              </Badge>
+            )}
+            {job.quarantined && (
+              <Tooltip label={`${job.quarantine_reason || 'Quarantined'} (click to release)`} multiline w={300}>
+                <Badge
+                  color="orange"
+                  size="sm"
+                  style={{ cursor: 'pointer' }}
+                  onClick={() => onRelease(job.job_type)}
+                >
+                  quarantined
+                </Badge>
+              </Tooltip>
+            )}
           </Group>
           <Group gap="xs" wrap="nowrap">
             <Select
@@ -97,7 +114,7 @@ function JobScheduleRow({ job, onUpdate, onRun, isUpdating }) {
   );
 }
 
-function JobScheduleList({ schedules, onUpdate, onRun, isUpdating }) {
+function JobScheduleList({ schedules, onUpdate, onRun, onRelease, isUpdating }) {
   const categories = [...new Set(schedules.map((s) => s.category).filter(Boolean))];
 
   return (
@@ -118,6 +135,7 @@ function JobScheduleList({ schedules, onUpdate, onRun, isUpdating }) {
                   job={job}
                   onUpdate={onUpdate}
                   onRun={onRun}
+                  onRelease={onRelease}
                   isUpdating={isUpdating}
                 />
               ))}
@@ -227,7 +245,7 @@ function JobHistoryList({ history }) {
               </Table.Td>
               <Table.Td>
                 {entry.error && (
-                  <Tooltip label={entry.error} multiline w={300}>
+                  <Tooltip label={entry.failure_class ? `[${entry.failure_class}] ${entry.error}` : entry.error} multiline w={300}>
                     <Text size="sm" c="red" truncate maw={200}>{entry.error}</Text>
                   </Tooltip>
                 )}
@@ -293,6 +311,13 @@ export function SchedulerModal({ opened, onClose }) {
     runJobMutation.mutate(jobType);
   };
 
+  const releaseMutation = useMutation({
+    mutationFn: releaseJob,
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: ['jobSchedules'] });
+    },
+  });
+
   const schedules = schedulesData?.schedules || [];
   const history = historyData?.history || [];
   const currentJob = statusData?.current;
@@ -347,6 +372,7 @@ export function SchedulerModal({ opened, onClose }) {
               schedules={schedules}
               onUpdate={handleUpdate}
               onRun={handleRun}
+              onRelease={(jobType) => releaseMutation.mutate(jobType)}
               isUpdating={updateMutation.isPending}
             />
             {updateMutation.isError && (
@@ -59,6 +59,7 @@ export function SecurityExpandedRow({ security, onUpdate, onDelete }) {
     aliases,
     allow_buy,
     allow_sell,
+    supports_fractional,
     user_multiplier,
     has_position,
     quantity,
@@ -188,6 +189,13 @@ export function SecurityExpandedRow({ security, onUpdate, onDelete }) {
                   onChange={(e) => handleUpdate('allow_sell', e.currentTarget.checked ? 1 : 0)}
                   disabled={isUpdating}
                 />
+                <Switch
+                  label="Fractional"
+                  size="xs"
+                  checked={supports_fractional === 1}
+                  onChange={(e) => handleUpdate('supports_fractional', e.currentTarget.checked ? 1 : 0)}
+                  disabled={isUpdating}
+                />
               </Group>
               <Tooltip label="Delete security">
                 <ActionIcon
@@ -13,9 +13,11 @@ import {
   Tabs,
   Group,
   Button,
+  Switch,
+  Divider,
 } from '@mantine/core';
 import { IconSettings, IconCoin, IconBrain, IconKey, IconCloudUpload } from '@tabler/icons-react';
-import { getSettings, updateSetting, updateSettingsBatch } from '../api/client';
+import { exportTelemetry, getSecrets, getSettings, updateSetting, updateSettingsBatch } from '../api/client';
 
 export function SettingsModal({ opened, onClose }) {
   const queryClient = useQueryClient();
@@ -27,13 +29,22 @@ export function SettingsModal({ opened, onClose }) {
     enabled: opened,
   });
 
+  const { data: secrets } = useQuery({
+    queryKey: ['secrets'],
+    queryFn: getSecrets,
+    enabled: opened,
+  });
+
   const updateMutation = useMutation({
     mutationFn: ({ key, value }) => updateSetting(key, value),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: ['settings'] });
+      queryClient.invalidateQueries({ queryKey: ['secrets'] });
     },
   });
 
+  const secretStored = (name) => !!secrets?.secrets?.find((s) => s.name === name)?.stored;
+
   const handleChange = (key, value) => {
     updateMutation.mutate({ key, value });
   };
@@ -74,6 +85,19 @@ export function SettingsModal({ opened, onClose }) {
     strategyMutation.mutate(strategyDraft);
   };
 
+  const telemetryMutation = useMutation({
+    mutationFn: () => exportTelemetry(),
+    onSuccess: (fingerprint) => {
+      const blob = new Blob([JSON.stringify(fingerprint, null, 2)], { type: 'application/json' });
+      const url = URL.createObjectURL(blob);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = `sentinel-fingerprint-${fingerprint.period.month}.json`;
+      link.click();
+      URL.revokeObjectURL(url);
+    },
+  });
+
   return (
     <Modal opened={opened} onClose={onClose} title={<Text fw={600}>Settings</Text>} size="lg">
       {isLoading ? (
@@ -423,22 +447,30 @@ export function SettingsModal({ opened, onClose }) {
 
           <Tabs.Panel value="api" pt="md">
             <Stack gap="md">
-              <TextInput
+              <SecretInput
                 label="Tradernet API Key"
                 description="Your Tradernet public API key"
-                value={settings?.tradernet_api_key || ''}
-                onChange={(e) => handleChange('tradernet_api_key', e.target.value)}
-                placeholder="Enter API key"
+                stored={secretStored('tradernet_api_key')}
+                legacyValue={settings?.tradernet_api_key}
+                onSave={(value) => handleChange('tradernet_api_key', value)}
+                saving={updateMutation.isPending}
               />
 
-              <PasswordInput
+              <SecretInput
                 label="Tradernet API Secret"
                 description="Your Tradernet private API secret"
-                value={settings?.tradernet_api_secret || ''}
-                onChange={(e) => handleChange('tradernet_api_secret', e.target.value)}
-                placeholder="Enter API secret"
+                stored={secretStored('tradernet_api_secret')}
+                legacyValue={settings?.tradernet_api_secret}
+                onSave={(value) => handleChange('tradernet_api_secret', value)}
+                saving={updateMutation.isPending}
               />
 
+              {secrets && !secrets.available && (
+                <Text size="xs" c="orange">
+                  Credentials vault unavailable: secrets are stored unencrypted
+                </Text>
+              )}
+
             </Stack>
           </Tabs.Panel>
 
@@ -456,20 +488,22 @@ export function SettingsModal({ opened, onClose }) {
                 placeholder="Enter account ID"
               />
 
-              <TextInput
+              <SecretInput
                 label="R2 Access Key"
                 description="R2 API token access key"
-                value={settings?.r2_access_key || ''}
-                onChange={(e) => handleChange('r2_access_key', e.target.value)}
-                placeholder="Enter access key"
+                stored={secretStored('r2_access_key')}
+                legacyValue={settings?.r2_access_key}
+                onSave={(value) => handleChange('r2_access_key', value)}
+                saving={updateMutation.isPending}
               />
 
-              <PasswordInput
+              <SecretInput
                 label="R2 Secret Key"
                 description="R2 API token secret key"
-                value={settings?.r2_secret_key || ''}
-                onChange={(e) => handleChange('r2_secret_key', e.target.value)}
-                placeholder="Enter secret key"
+                stored={secretStored('r2_secret_key')}
+                legacyValue={settings?.r2_secret_key}
+                onSave={(value) => handleChange('r2_secret_key', value)}
+                saving={updateMutation.isPending}
               />
 
               <TextInput
@@ -489,6 +523,32 @@ export function SettingsModal({ opened, onClose }) {
                 max={365}
                 suffix=" days"
               />
+
+              <Divider label="Strategy fingerprint" labelPosition="left" />
+              <Text size="sm" c="dimmed">
+                Anonymized export for comparing outcomes with other self-hosted users: ratios, risk metrics and
+                strategy settings only, no symbols or amounts. Nothing is sent automatically.
+              </Text>
+              <Switch
+                label="Allow anonymized export"
+                checked={!!settings?.telemetry_export_enabled}
+                onChange={(e) => handleChange('telemetry_export_enabled', e.currentTarget.checked)}
+              />
+              <Group>
+                <Button
+                  variant="light"
+                  disabled={!settings?.telemetry_export_enabled}
+                  loading={telemetryMutation.isPending}
+                  onClick={() => telemetryMutation.mutate()}
+                >
+                  Download fingerprint
+                </Button>
+              </Group>
+              {telemetryMutation.isError && (
+                <Text c="red" size="sm">
+                  {telemetryMutation.error.message}
+                </Text>
+              )}
             </Stack>
           </Tabs.Panel>
 
@@ -507,3 +567,30 @@ export function SettingsModal({ opened, onClose }) {
     </Modal>
   );
 }
+
+// Write-only credential field: stored values are never sent back, so a new value replaces (rotates) the old one
+function SecretInput({ label, description, stored, legacyValue, onSave, saving }) {
+  const [draft, setDraft] = useState('');
+  const configured = stored || !!legacyValue;
+
+  const save = () => {
+    onSave(draft);
+    setDraft('');
+  };
+
+  return (
+    <Group align="flex-end" gap="xs" wrap="nowrap">
+      <PasswordInput
+        style={{ flex: 1 }}
+        label={label}
+        description={description}
+        value={draft}
+        onChange={(e) => setDraft(e.target.value)}
+        placeholder={configured ? 'Saved - enter a new value to replace it' : 'Not set'}
+      />
+      <Button variant="light" disabled={!draft} loading={saving} onClick={save}>
+        Save
+      </Button>
+    </Group>
+  );
+}
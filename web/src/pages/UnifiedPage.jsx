@@ -7,6 +7,7 @@
 import { useEffect, useMemo, useState } from 'react';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { notifications } from '@mantine/notifications';
+import { useSearchParams } from 'react-router-dom';
 import {
   Stack,
   Group,
@@ -78,7 +79,14 @@ function UnifiedPage() {
   const [period, setPeriod] = useState('1Y');
   const [filter, setFilter] = useState('all');
   const [sort, setSort] = useState('priority');
-  const [search, setSearch] = useState('');
+  const [searchParams] = useSearchParams();
+  const [search, setSearch] = useState(searchParams.get('search') || '');
+  const linkedSearch = searchParams.get('search');
+
+  // Notification deep links (/?search=SYMBOL) focus the linked security
+  useEffect(() => {
+    if (linkedSearch) setSearch(linkedSearch);
+  }, [linkedSearch]);
   const [addModalOpen, setAddModalOpen] = useState(false);
   const [deleteModalOpen, setDeleteModalOpen] = useState(false);
   const [securityToDelete, setSecurityToDelete] = useState(null);